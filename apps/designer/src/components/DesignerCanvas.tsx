@@ -32,9 +32,13 @@ const edgeTypes = {
 const TYPE_MAP: Record<NodeTypeKey, string> = {
   trg_cron: 'triggerNode', trg_rest: 'triggerNode', trg_soap: 'triggerNode',
   trg_rabbitmq: 'triggerNode', trg_mcp: 'triggerNode', trg_manual: 'triggerNode',
+  trg_postgres_cdc: 'triggerNode', trg_email: 'triggerNode',
+  trg_sftp_poll: 'triggerNode', trg_s3_poll: 'triggerNode',
   http: 'activityNode', sftp: 'activityNode', s3: 'activityNode', smb: 'activityNode',
   mail: 'activityNode', rabbitmq: 'activityNode', sql: 'activityNode', code: 'activityNode',
-  log: 'activityNode', transform: 'activityNode', file: 'activityNode',
+  log: 'activityNode', transform: 'activityNode', mapping: 'activityNode', file: 'activityNode',
+  dedupe: 'activityNode', batcher: 'activityNode', faker: 'activityNode', assert: 'activityNode', mock_http: 'activityNode',
+  websocket_send: 'activityNode', callback_await: 'activityNode',
 }
 
 const TRANSITION_TYPES: TransitionTypeEdge[] = ['success', 'error', 'condition', 'nocondition']
@@ -47,6 +51,10 @@ function buildDefaultData(type: NodeTypeKey, id: string): NodeData {
     trg_rabbitmq: { type: 'rabbitmq', config: { url_amqp: 'amqp://localhost', queue: 'flow-queue' } },
     trg_mcp:      { type: 'mcp',      config: { version: '1.0' } },
     trg_manual:   { type: 'manual',   config: {} as never },
+    trg_postgres_cdc: { type: 'postgres_cdc', config: { dsn: 'postgres://localhost:5432/mydb', channel: 'flowjs_changes' } },
+    trg_email:    { type: 'email',    config: { host: 'imap.example.com', auth: { user: 'inbox@example.com', password: '' } } },
+    trg_sftp_poll: { type: 'sftp_poll', config: { server: 'sftp.example.com', folder: '/inbox', auth: { user: 'flowjs', password: '' } } },
+    trg_s3_poll:  { type: 's3_poll',  config: { bucket: 'my-bucket', region: 'us-east-1', folder: 'incoming/' } },
   }
   if (type in triggerMap) {
     const t = triggerMap[type as PaletteTriggerKey]
@@ -60,11 +68,19 @@ function buildDefaultData(type: NodeTypeKey, id: string): NodeData {
     smb:       { server: '\\\\server\\share', folder: '/files', method: 'get' },
     mail:      { host: 'smtp.example.com', port: 587, action: 'send' },
     rabbitmq:  { url_amqp: 'amqp://localhost', exchange: '', routing_key: 'flow.event' },
+    websocket_send: { url: 'wss://rt.example.com/feed', await_reply: false },
     sql:       { engine: 'postgres', host: 'localhost', port: 5432, database: 'mydb', query: 'SELECT 1' },
     code:      { script: 'export default (input) => input' },
     log:       { level: 'INFO', message: '' },
     transform: { transform_type: 'json2csv' },
+    mapping:   { mappings: [] },
     file:      { operation: 'read', path: '/tmp/file.txt' },
+    dedupe:    { ttl: '24h' },
+    batcher:   { max_size: 100, max_wait_ms: 30000 },
+    faker:     { count: 10, fields: { id: 'uuid', name: 'name', email: 'email' } },
+    assert:    { assertions: ['$.nodes.http.output.status_code === 200'] },
+    mock_http: { routes: [{ method: 'GET', path: '/', body: { ok: true } }] },
+    callback_await: { url: 'https://jobs.example.com/start', method: 'POST', await_timeout: '10m' },
   }
   return {
     ...baseProcess,
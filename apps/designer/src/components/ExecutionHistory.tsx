@@ -1,6 +1,6 @@
 import { useState, useEffect, useCallback } from 'react'
 import type { Execution, ActivityLog } from '../types/audit'
-import { fetchExecutions, fetchActivityLogs, fetchTriggerData, replayExecution, replayFromNode } from '../lib/api'
+import { fetchExecutions, fetchActivityLogs, fetchTriggerData, replayExecution, replayFromNode, retryExecution } from '../lib/api'
 import { toErrorMessage } from '../lib/errors'
 
 const LIMIT = 20
@@ -90,7 +90,9 @@ function StatusBadge({ status }: { status: string }) {
         ? 'bg-red-100 text-red-700'
         : status === 'STARTED'
           ? 'bg-blue-100 text-blue-700'
-          : 'bg-gray-100 text-gray-600'
+          : status === 'ORPHANED'
+            ? 'bg-amber-100 text-amber-700'
+            : 'bg-gray-100 text-gray-600'
   return (
     <span className={`px-2 py-0.5 rounded text-xs font-medium ${color}`}>{status || '—'}</span>
   )
@@ -231,6 +233,10 @@ function LogDetailPanel({ executionId, flowId, onClose }: LogDetailPanelProps) {
                   {/* Footer meta */}
                   <div className="px-4 py-2 border-t border-gray-200 flex gap-4 text-xs text-gray-400">
                     <span>Duration: {selectedLog.duration_ms}ms</span>
+                    {selectedLog.attempt !== undefined && selectedLog.attempt > 1 && (
+                      <span>Attempt: {selectedLog.attempt}</span>
+                    )}
+                    {selectedLog.engine_id && <span>Engine: {selectedLog.engine_id}</span>}
                     <span>Created: {new Date(selectedLog.created_at).toLocaleString()}</span>
                   </div>
                 </>
@@ -312,6 +318,17 @@ export function ExecutionHistory() {
     }
   }
 
+  async function handleRetry(e: React.MouseEvent, exec: Execution) {
+    e.stopPropagation()
+    setReplayMessage(null)
+    try {
+      const res = await retryExecution(exec.execution_id)
+      setReplayMessage({ type: 'success', text: `Retry of ${exec.execution_id} started as ${res.execution_id}` })
+    } catch (err) {
+      setReplayMessage({ type: 'error', text: `Retry execution failed: ${toErrorMessage(err)}` })
+    }
+  }
+
   return (
     <div className="flex-1 flex flex-col overflow-hidden bg-white">
       {/* Panel header */}
@@ -338,6 +355,7 @@ export function ExecutionHistory() {
             <option value="COMPLETED">COMPLETED</option>
             <option value="FAILED">FAILED</option>
             <option value="REPLAYED">REPLAYED</option>
+            <option value="ORPHANED">ORPHANED</option>
           </select>
           <input
             type="text"
@@ -433,6 +451,15 @@ export function ExecutionHistory() {
                     >
                       ↺ Replay
                     </button>
+                    {exec.status === 'FAILED' && (
+                      <button
+                        onClick={(e) => { void handleRetry(e, exec) }}
+                        className="text-orange-500 hover:text-orange-700 transition-colors"
+                        title="Re-run the failed node and everything after it"
+                      >
+                        ⟳ Retry failed
+                      </button>
+                    )}
                   </td>
                 </tr>
               ))}
@@ -11,6 +11,10 @@ const PALETTE_CATEGORIES: PaletteCategory[] = [
       { type: 'trg_rabbitmq', label: 'RabbitMQ', description: 'Queue consumer',           icon: '🐇', color: 'bg-green-500' },
       { type: 'trg_mcp',      label: 'MCP',      description: 'Model Context Protocol',   icon: '🤖', color: 'bg-emerald-500' },
       { type: 'trg_manual',   label: 'Manual',   description: 'Manual trigger',           icon: '👆', color: 'bg-teal-500' },
+      { type: 'trg_postgres_cdc', label: 'Postgres CDC', description: 'Database row changes', icon: '🐘', color: 'bg-teal-600' },
+      { type: 'trg_email',    label: 'Email',    description: 'IMAP mailbox poller',      icon: '📥', color: 'bg-teal-500' },
+      { type: 'trg_sftp_poll', label: 'SFTP Poll', description: 'New files in a folder',  icon: '📂', color: 'bg-teal-600' },
+      { type: 'trg_s3_poll',  label: 'S3 Poll',  description: 'New objects in a bucket',  icon: '☁️', color: 'bg-teal-500' },
     ],
   },
   {
@@ -22,11 +26,19 @@ const PALETTE_CATEGORIES: PaletteCategory[] = [
       { type: 'smb',       label: 'SMB',       description: 'SMB file share',        icon: '🗂️', color: 'bg-gray-500' },
       { type: 'mail',      label: 'Mail',      description: 'Send/receive email',    icon: '📧', color: 'bg-red-400' },
       { type: 'rabbitmq',  label: 'RabbitMQ',  description: 'Message producer',      icon: '🐇', color: 'bg-orange-400' },
+      { type: 'websocket_send', label: 'WebSocket', description: 'Push to a WebSocket', icon: '🔌', color: 'bg-cyan-500' },
       { type: 'sql',       label: 'SQL',       description: 'Database query',        icon: '🗄️', color: 'bg-orange-500' },
       { type: 'code',      label: 'Code',      description: 'JS/TS script',          icon: '📜', color: 'bg-purple-500' },
       { type: 'log',       label: 'Log',       description: 'Log a message',         icon: '📋', color: 'bg-gray-400' },
       { type: 'transform', label: 'Transform', description: 'Data transformation',   icon: '🔄', color: 'bg-indigo-500' },
+      { type: 'mapping',   label: 'Mapping',   description: 'Map fields without code', icon: '🔀', color: 'bg-indigo-400' },
       { type: 'file',      label: 'File',      description: 'Local file operation',  icon: '📄', color: 'bg-lime-500' },
+      { type: 'dedupe',    label: 'Dedupe',    description: 'Skip duplicate events', icon: '🧬', color: 'bg-pink-500' },
+      { type: 'batcher',   label: 'Batcher',   description: 'Group items for bulk APIs', icon: '📦', color: 'bg-amber-500' },
+      { type: 'callback_await', label: 'Await Callback', description: 'Wait for a webhook callback', icon: '⏳', color: 'bg-sky-500' },
+      { type: 'faker',     label: 'Faker',     description: 'Generate test data',    icon: '🎲', color: 'bg-fuchsia-500' },
+      { type: 'assert',    label: 'Assert',    description: 'Check the context',     icon: '✅', color: 'bg-emerald-500' },
+      { type: 'mock_http', label: 'Mock HTTP', description: 'Test-mode HTTP stub',   icon: '🧪', color: 'bg-teal-400' },
     ],
   },
 ]
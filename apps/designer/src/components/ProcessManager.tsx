@@ -19,12 +19,14 @@ const STATUS_BADGE: Record<ProcessStatus, string> = {
   draft: 'bg-gray-100 text-gray-600',
   deployed: 'bg-green-100 text-green-700',
   stopped: 'bg-yellow-100 text-yellow-700',
+  archived: 'bg-slate-100 text-slate-500',
 }
 
 const STATUS_ICON: Record<ProcessStatus, string> = {
   draft: '📄',
   deployed: '▶',
   stopped: '⏸',
+  archived: '🗄',
 }
 
 interface Props {
@@ -167,7 +169,7 @@ export function ProcessManager({ nodes, edges, definition, onEditProcess }: Prop
     async (id: string) => {
       // Truncate the id in the confirmation message to prevent excessively long prompts.
       const displayId = id.length > 80 ? id.slice(0, 80) + '…' : id
-      if (!window.confirm(`Delete process "${displayId}"? This cannot be undone.`)) return
+      if (!window.confirm(`Archive process "${displayId}"? It stops running and can be restored from the archive.`)) return
       setBusyId(id)
       setActionError(null)
       try {
@@ -4,7 +4,7 @@ import { listSecrets, createSecret, deleteSecret } from '../lib/api'
 import { toErrorMessage } from '../lib/errors'
 import { inputClass, selectClass, labelClass } from '../lib/classNames'
 
-const SECRET_TYPES: SecretType[] = ['basic_auth', 'token', 'certificate', 'connection_string', 'aws_credentials', 'ssh_key', 'amqp_url']
+const SECRET_TYPES: SecretType[] = ['basic_auth', 'token', 'certificate', 'connection_string', 'aws_credentials', 'ssh_key', 'amqp_url', 'service_account']
 
 /** Fields shown per secret type when creating a new secret */
 const SECRET_VALUE_FIELDS: Record<SecretType, string[]> = {
@@ -15,10 +15,12 @@ const SECRET_VALUE_FIELDS: Record<SecretType, string[]> = {
   connection_string: ['connection_string'],
   // aws_credentials: matches S3 activity (access_key_id, secret_access_key, session_token)
   aws_credentials: ['access_key_id', 'secret_access_key', 'session_token'],
-  // ssh_key: matches SFTP private-key auth (user, private_key)
+  // ssh_key: matches SFTP private-key auth and Snowflake key-pair auth (user, private_key)
   ssh_key: ['user', 'private_key'],
   // amqp_url: matches RabbitMQ activity (url_amqp with embedded credentials)
   amqp_url: ['url_amqp'],
+  // service_account: matches BigQuery SQL nodes (the service account JSON key)
+  service_account: ['service_account'],
 }
 
 /** Fields that are optional within their secret type */
@@ -1,5 +1,5 @@
 import { describe, it, expect, vi, beforeEach } from 'vitest'
-import { fetchExecutions, fetchActivityLogs, runFlow, listSecrets, createSecret, deleteSecret, listProcesses, saveProcess, deployProcess, stopProcess, deleteProcess, getProcess, fetchTriggerData, replayExecution, replayFromNode } from './api'
+import { fetchExecutions, fetchActivityLogs, runFlow, listSecrets, createSecret, deleteSecret, getSecret, getSecretReferences, getSecretAudit, listProcesses, saveProcess, deployProcess, promoteProcess, getProcessEnvironments, listCaptures, getCapture, replayCapture, stopProcess, deleteProcess, restoreProcess, getProcess, fetchTriggerData, getExecutionContext, replayExecution, replayFromNode, retryExecution, runProcess, listSnippets, getSnippet, saveSnippet, deleteSnippet } from './api'
 import type { Execution, ActivityLog } from '../types/audit'
 import type { SecretMeta } from '../types/secrets'
 import type { ProcessSummary } from '../types/deployment'
@@ -242,6 +242,95 @@ describe('deleteSecret', () => {
   })
 })
 
+describe('getSecretReferences', () => {
+  beforeEach(() => { vi.restoreAllMocks() })
+
+  it('returns the referencing nodes', async () => {
+    const refs = [{ process_id: 'p1', process_name: 'Orders', status: 'deployed', node_id: 'query', node_type: 'sql' }]
+    let capturedUrl = ''
+    vi.stubGlobal('fetch', vi.fn().mockImplementation((url: string) => {
+      capturedUrl = url
+      return Promise.resolve({ ok: true, json: () => Promise.resolve(refs) })
+    }))
+    await expect(getSecretReferences('sec_pg')).resolves.toEqual(refs)
+    expect(capturedUrl).toContain('/api/v1/secrets/sec_pg/references')
+  })
+
+  it('throws on non-ok response', async () => {
+    vi.stubGlobal('fetch', vi.fn().mockResolvedValue({ ok: false, status: 500, text: () => Promise.resolve('boom') }))
+    await expect(getSecretReferences('sec_pg')).rejects.toThrow('Failed to list secret references (500)')
+  })
+})
+
+describe('getSecret', () => {
+  beforeEach(() => { vi.restoreAllMocks() })
+
+  it('requests masked values by default', async () => {
+    let capturedUrl = ''
+    const view = { id: 'sec_pg', name: 'PG', type: 'basic_auth', created_at: '', updated_at: '', value: { password: 'co*********se' }, masked: true }
+    vi.stubGlobal('fetch', vi.fn().mockImplementation((url: string) => {
+      capturedUrl = url
+      return Promise.resolve({ ok: true, json: () => Promise.resolve(view) })
+    }))
+    await expect(getSecret('sec_pg')).resolves.toEqual(view)
+    expect(capturedUrl).toContain('/api/v1/secrets/sec_pg?reveal=masked')
+  })
+
+  it('throws when a full reveal is forbidden', async () => {
+    vi.stubGlobal('fetch', vi.fn().mockResolvedValue({ ok: false, status: 403, text: () => Promise.resolve('admin role required') }))
+    await expect(getSecret('sec_pg', 'full')).rejects.toThrow('Failed to fetch secret (403)')
+  })
+})
+
+describe('getSecretAudit', () => {
+  beforeEach(() => { vi.restoreAllMocks() })
+
+  it('passes the limit as a query parameter', async () => {
+    let capturedUrl = ''
+    vi.stubGlobal('fetch', vi.fn().mockImplementation((url: string) => {
+      capturedUrl = url
+      return Promise.resolve({ ok: true, json: () => Promise.resolve([]) })
+    }))
+    await expect(getSecretAudit('sec_pg', 20)).resolves.toEqual([])
+    expect(capturedUrl).toContain('/api/v1/secrets/sec_pg/audit?limit=20')
+  })
+
+  it('throws on non-ok response', async () => {
+    vi.stubGlobal('fetch', vi.fn().mockResolvedValue({ ok: false, status: 503, text: () => Promise.resolve('store not configured') }))
+    await expect(getSecretAudit('sec_pg')).rejects.toThrow('Failed to fetch secret audit (503)')
+  })
+})
+
+describe('getExecutionContext', () => {
+  beforeEach(() => { vi.restoreAllMocks() })
+
+  it('fetches the snapshot from the engine', async () => {
+    let capturedUrl = ''
+    const snapshot = { execution_id: 'exec-1', process_id: 'p1', context: { nodes: {} }, created_at: '2025-01-01T00:00:00Z' }
+    vi.stubGlobal('fetch', vi.fn().mockImplementation((url: string) => {
+      capturedUrl = url
+      return Promise.resolve({ ok: true, json: () => Promise.resolve(snapshot) })
+    }))
+    await expect(getExecutionContext('exec-1')).resolves.toEqual(snapshot)
+    expect(capturedUrl).toContain('/api/v1/executions/exec-1/context')
+  })
+
+  it('encodes the JSONPath as a query parameter', async () => {
+    let capturedUrl = ''
+    vi.stubGlobal('fetch', vi.fn().mockImplementation((url: string) => {
+      capturedUrl = url
+      return Promise.resolve({ ok: true, json: () => Promise.resolve({ path: '$.trigger.body', value: {} }) })
+    }))
+    await getExecutionContext('exec-1', '$.trigger.body')
+    expect(capturedUrl).toContain('/context?path=%24.trigger.body')
+  })
+
+  it('throws on non-ok response', async () => {
+    vi.stubGlobal('fetch', vi.fn().mockResolvedValue({ ok: false, status: 404, text: () => Promise.resolve('no context snapshot') }))
+    await expect(getExecutionContext('exec-1')).rejects.toThrow('Failed to fetch execution context (404)')
+  })
+})
+
 // ── Process & Deployment API ─────────────────────────────────────────────────
 
 describe('listProcesses', () => {
@@ -296,6 +385,44 @@ describe('saveProcess', () => {
     vi.stubGlobal('fetch', vi.fn().mockResolvedValue({ ok: false, status: 400, json: () => Promise.resolve({ error: 'bad request' }) }))
     await expect(saveProcess(sampleDSL)).rejects.toThrow('Failed to save process (400)')
   })
+
+  it('sends the change note as a query parameter', async () => {
+    const mockFetch = vi.fn().mockResolvedValue({ ok: true, json: () => Promise.resolve({ id: 'p1' }) })
+    vi.stubGlobal('fetch', mockFetch)
+
+    await saveProcess(sampleDSL, 3, 'raise timeout & retries')
+    const [url, init] = mockFetch.mock.calls[0] as [string, RequestInit]
+    expect(url).toContain('/api/v1/processes?note=raise%20timeout%20%26%20retries')
+    expect((init.headers as Record<string, string>)['If-Match']).toBe('"3"')
+  })
+})
+
+describe('promoteProcess', () => {
+  beforeEach(() => { vi.restoreAllMocks() })
+
+  it('posts the target environment and returns the promotion', async () => {
+    const fetchMock = vi.fn().mockResolvedValue({ ok: true, json: () => Promise.resolve({ id: 1, process_id: 'p1', from: 'dev', to: 'staging', revision: 4, promoted_by: 'alice', promoted_at: '2024-01-01T00:00:00Z', redeployed: false }) })
+    vi.stubGlobal('fetch', fetchMock)
+    const result = await promoteProcess('p1', 'staging')
+    expect(result.from).toBe('dev')
+    expect(fetchMock.mock.calls[0][0]).toContain('/api/v1/processes/p1/promote?to=staging')
+  })
+
+  it('throws with the engine error on conflict', async () => {
+    vi.stubGlobal('fetch', vi.fn().mockResolvedValue({ ok: false, status: 409, json: () => Promise.resolve({ error: 'must be promoted to staging before prod' }) }))
+    await expect(promoteProcess('p1', 'prod')).rejects.toThrow('Failed to promote process (409): must be promoted to staging before prod')
+  })
+})
+
+describe('getProcessEnvironments', () => {
+  beforeEach(() => { vi.restoreAllMocks() })
+
+  it('returns environments and promotion history', async () => {
+    vi.stubGlobal('fetch', vi.fn().mockResolvedValue({ ok: true, json: () => Promise.resolve({ current: 'prod', environments: [{ process_id: 'p1', environment: 'dev', revision: 3, promoted_by: '', promoted_at: '2024-01-01T00:00:00Z' }], promotions: [] }) }))
+    const result = await getProcessEnvironments('p1')
+    expect(result.current).toBe('prod')
+    expect(result.environments[0].revision).toBe(3)
+  })
 })
 
 describe('deployProcess', () => {
@@ -340,6 +467,31 @@ describe('deleteProcess', () => {
     vi.stubGlobal('fetch', vi.fn().mockResolvedValue({ ok: false, status: 500, text: () => Promise.resolve('error') }))
     await expect(deleteProcess('p1')).rejects.toThrow('Failed to delete process (500)')
   })
+
+  it('sends purge=true when purging', async () => {
+    const mockFetch = vi.fn().mockResolvedValue({ ok: true, text: () => Promise.resolve('') })
+    vi.stubGlobal('fetch', mockFetch)
+    await deleteProcess('p1', true)
+    expect(mockFetch.mock.calls[0][0]).toContain('/api/v1/processes/p1?purge=true')
+  })
+})
+
+describe('restoreProcess', () => {
+  beforeEach(() => { vi.restoreAllMocks() })
+
+  it('POSTs to the restore endpoint and returns the record', async () => {
+    const record = { id: 'p1', status: 'stopped' }
+    const mockFetch = vi.fn().mockResolvedValue({ ok: true, json: () => Promise.resolve(record) })
+    vi.stubGlobal('fetch', mockFetch)
+    await expect(restoreProcess('p1')).resolves.toEqual(record)
+    expect(mockFetch.mock.calls[0][0]).toContain('/api/v1/processes/p1/restore')
+    expect(mockFetch.mock.calls[0][1]).toMatchObject({ method: 'POST' })
+  })
+
+  it('throws on non-ok response', async () => {
+    vi.stubGlobal('fetch', vi.fn().mockResolvedValue({ ok: false, status: 409, text: () => Promise.resolve('not archived') }))
+    await expect(restoreProcess('p1')).rejects.toThrow('Failed to restore process (409)')
+  })
 })
 
 describe('getProcess', () => {
@@ -524,3 +676,128 @@ describe('replayFromNode', () => {
     await expect(replayFromNode('my-flow', 'node_1', {})).rejects.toThrow('Replay from node failed (422)')
   })
 })
+
+describe('retryExecution', () => {
+  beforeEach(() => { vi.restoreAllMocks() })
+
+  it('posts to the execution retry endpoint', async () => {
+    let capturedUrl = ''
+    let capturedMethod = ''
+    vi.stubGlobal('fetch', vi.fn().mockImplementation((url: string, opts: RequestInit) => {
+      capturedUrl = url
+      capturedMethod = opts.method ?? ''
+      return Promise.resolve({ ok: true, json: () => Promise.resolve({ execution_id: 'retry-1', nodes: {} }) })
+    }))
+    const result = await retryExecution('exec-1')
+    expect(result.execution_id).toBe('retry-1')
+    expect(capturedUrl).toContain('/api/v1/executions/exec-1/retry')
+    expect(capturedMethod).toBe('POST')
+  })
+
+  it('throws on non-ok response', async () => {
+    vi.stubGlobal('fetch', vi.fn().mockResolvedValue({ ok: false, status: 409, json: () => Promise.resolve({ error: 'execution has no failed node to retry' }) }))
+    await expect(retryExecution('exec-1')).rejects.toThrow('Retry failed (409)')
+  })
+})
+
+describe('runProcess', () => {
+  beforeEach(() => { vi.restoreAllMocks() })
+
+  it('posts trigger_data to the run endpoint', async () => {
+    let capturedUrl = ''
+    let capturedBody = ''
+    vi.stubGlobal('fetch', vi.fn().mockImplementation((url: string, opts: RequestInit) => {
+      capturedUrl = url
+      capturedBody = opts.body as string
+      return Promise.resolve({ ok: true, json: () => Promise.resolve({ execution_id: 'run-1', nodes: {} }) })
+    }))
+    const result = await runProcess('my-flow', { key: 'value' })
+    expect(result.execution_id).toBe('run-1')
+    expect(capturedUrl).toContain('/api/v1/processes/my-flow/run')
+    expect(JSON.parse(capturedBody)).toEqual({ trigger_data: { key: 'value' } })
+  })
+
+  it('omits trigger_data when none is given', async () => {
+    let capturedBody = ''
+    vi.stubGlobal('fetch', vi.fn().mockImplementation((_url: string, opts: RequestInit) => {
+      capturedBody = opts.body as string
+      return Promise.resolve({ ok: true, json: () => Promise.resolve({ execution_id: 'run-2', nodes: {} }) })
+    }))
+    await runProcess('my-flow')
+    expect(JSON.parse(capturedBody)).toEqual({})
+  })
+
+  it('throws on concurrency limit', async () => {
+    vi.stubGlobal('fetch', vi.fn().mockResolvedValue({ ok: false, status: 429, json: () => Promise.resolve({ error: 'process concurrency limit reached' }) }))
+    await expect(runProcess('my-flow')).rejects.toThrow('Run failed (429)')
+  })
+})
+
+describe('snippets', () => {
+  beforeEach(() => { vi.restoreAllMocks() })
+
+  it('lists snippets', async () => {
+    const snippets = [{ name: 'utils/dates', workspace: 'default', description: '', updated_at: '2025-01-01T00:00:00Z' }]
+    vi.stubGlobal('fetch', vi.fn().mockResolvedValue({ ok: true, json: () => Promise.resolve(snippets) }))
+    expect(await listSnippets()).toEqual(snippets)
+  })
+
+  it('keeps path separators in snippet names', async () => {
+    let capturedUrl = ''
+    vi.stubGlobal('fetch', vi.fn().mockImplementation((url: string) => {
+      capturedUrl = url
+      return Promise.resolve({ ok: true, json: () => Promise.resolve({ name: 'utils/my dates' }) })
+    }))
+    await getSnippet('utils/my dates')
+    expect(capturedUrl).toContain('/api/v1/snippets/utils/my%20dates')
+  })
+
+  it('posts the snippet on save', async () => {
+    let capturedBody = ''
+    vi.stubGlobal('fetch', vi.fn().mockImplementation((_url: string, opts: RequestInit) => {
+      capturedBody = opts.body as string
+      return Promise.resolve({ ok: true, json: () => Promise.resolve({ name: 'lib' }) })
+    }))
+    await saveSnippet({ name: 'lib', source: 'export const x = 1' })
+    expect(JSON.parse(capturedBody)).toEqual({ name: 'lib', source: 'export const x = 1' })
+  })
+
+  it('throws when delete fails', async () => {
+    vi.stubGlobal('fetch', vi.fn().mockResolvedValue({ ok: false, status: 500, text: () => Promise.resolve('boom') }))
+    await expect(deleteSnippet('lib')).rejects.toThrow('Failed to delete snippet (500)')
+  })
+})
+
+describe('captures', () => {
+  beforeEach(() => { vi.restoreAllMocks() })
+
+  it('lists captures with a limit', async () => {
+    const mockFetch = vi.fn().mockResolvedValue({ ok: true, json: () => Promise.resolve([{ id: 1, trigger_type: 'rest' }]) })
+    vi.stubGlobal('fetch', mockFetch)
+
+    const list = await listCaptures('p1', 10)
+    expect(list).toHaveLength(1)
+    expect(mockFetch.mock.calls[0][0]).toContain('/api/v1/processes/p1/captures?limit=10')
+  })
+
+  it('fetches one capture', async () => {
+    vi.stubGlobal('fetch', vi.fn().mockResolvedValue({ ok: true, json: () => Promise.resolve({ id: 7, body: '{}' }) }))
+    const capture = await getCapture('p1', 7)
+    expect(capture.id).toBe(7)
+  })
+
+  it('returns the trigger response of a replay, even when it failed', async () => {
+    const headers = new Headers({ 'X-Replayed-Capture': '7', 'Content-Type': 'application/json' })
+    const mockFetch = vi.fn().mockResolvedValue({ ok: false, status: 422, headers, text: () => Promise.resolve('{"error":"boom"}') })
+    vi.stubGlobal('fetch', mockFetch)
+
+    const replay = await replayCapture('p1', 7)
+    expect(replay).toEqual({ status: 422, contentType: 'application/json', body: '{"error":"boom"}' })
+    expect(mockFetch.mock.calls[0][0]).toContain('/api/v1/processes/p1/captures/7/replay')
+  })
+
+  it('throws when the engine refuses the replay', async () => {
+    vi.stubGlobal('fetch', vi.fn().mockResolvedValue({ ok: false, status: 409, statusText: 'Conflict', headers: new Headers(), json: () => Promise.resolve({ error: 'path changed' }) }))
+    await expect(replayCapture('p1', 7)).rejects.toThrow('Failed to replay capture (409): path changed')
+  })
+})
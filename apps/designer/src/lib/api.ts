@@ -1,7 +1,8 @@
-import type { Execution, ActivityLog } from '../types/audit'
-import type { InputMapping, FlowDSL } from '../types/dsl'
-import type { SecretMeta, SecretInput } from '../types/secrets'
-import type { ProcessSummary, DeploymentStatus } from '../types/deployment'
+import type { Execution, ActivityLog, ExecutionSnapshot, ExecutionContextValue, ExecutionDetail, TimelineEvent, NodeHistory } from '../types/audit'
+import type { InputMapping, FlowDSL, DeploymentEnvironment } from '../types/dsl'
+import type { SecretMeta, SecretInput, SecretReference, SecretAuditEvent, SecretView } from '../types/secrets'
+import type { ProcessSummary, DeploymentStatus, Promotion, ProcessEnvironments, CaptureSummary, CapturedRequest, CaptureReplay } from '../types/deployment'
+import type { Snippet, SnippetInput } from '../types/snippets'
 
 /** Full process record returned by GET /api/v1/processes/{id} */
 export interface ProcessRecord {
@@ -12,6 +13,12 @@ export interface ProcessRecord {
   /** The complete flow DSL */
   dsl: FlowDSL
   status: string
+  /** Optimistic-locking counter; pass it back to saveProcess to detect concurrent edits */
+  revision: number
+  owner: string
+  team: string
+  last_modified_by: string
+  change_note: string
   created_at: string
   updated_at: string
 }
@@ -137,6 +144,20 @@ export async function fetchTriggerData(executionId: string): Promise<Record<stri
   return res.json() as Promise<Record<string, unknown>>
 }
 
+/** Fetch the persisted final context of an execution from the engine */
+export async function getExecutionContext(executionId: string): Promise<ExecutionSnapshot>
+/** Resolve one JSONPath against the persisted final context of an execution */
+export async function getExecutionContext(executionId: string, path: string): Promise<ExecutionContextValue>
+export async function getExecutionContext(executionId: string, path?: string): Promise<ExecutionSnapshot | ExecutionContextValue> {
+  const query = path ? `?path=${encodeURIComponent(path)}` : ''
+  const res = await fetch(`${ENGINE_API_BASE}/api/v1/executions/${encodeURIComponent(executionId)}/context${query}`)
+  if (!res.ok) {
+    const body = await res.text()
+    throw new Error(`Failed to fetch execution context (${res.status}): ${body}`)
+  }
+  return res.json() as Promise<ExecutionSnapshot | ExecutionContextValue>
+}
+
 /** Full replay: re-execute a flow with the given trigger data */
 export async function replayExecution(
   processId: string,
@@ -178,6 +199,19 @@ export async function replayFromNode(
   return data
 }
 
+/** Retry a failed execution from its failed node, keeping earlier node outputs */
+export async function retryExecution(executionId: string): Promise<RunFlowResponse> {
+  const res = await fetch(
+    `${ENGINE_API_BASE}/api/v1/executions/${encodeURIComponent(executionId)}/retry`,
+    { method: 'POST' },
+  )
+  const data = await res.json() as RunFlowResponse
+  if (!res.ok) {
+    throw new Error(`Retry failed (${res.status}): ${data.error ?? res.statusText}`)
+  }
+  return data
+}
+
 /** Fetch activity logs for a given execution_id */
 export async function fetchActivityLogs(executionId: string): Promise<ActivityLog[]> {
   const res = await fetch(`${AUDIT_API_BASE}/executions/${encodeURIComponent(executionId)}/logs`)
@@ -188,6 +222,37 @@ export async function fetchActivityLogs(executionId: string): Promise<ActivityLo
   return res.json() as Promise<ActivityLog[]>
 }
 
+/** Fetch the header of an execution with its node counts and first error */
+export async function fetchExecutionDetail(executionId: string): Promise<ExecutionDetail> {
+  const res = await fetch(`${AUDIT_API_BASE}/executions/${encodeURIComponent(executionId)}`)
+  if (!res.ok) {
+    const body = await res.text()
+    throw new Error(`Failed to fetch execution (${res.status}): ${body}`)
+  }
+  return res.json() as Promise<ExecutionDetail>
+}
+
+/** Fetch the ordered node events of an execution with their durations */
+export async function fetchExecutionTimeline(executionId: string): Promise<TimelineEvent[]> {
+  const res = await fetch(`${AUDIT_API_BASE}/executions/${encodeURIComponent(executionId)}/timeline`)
+  if (!res.ok) {
+    const body = await res.text()
+    throw new Error(`Failed to fetch execution timeline (${res.status}): ${body}`)
+  }
+  return res.json() as Promise<TimelineEvent[]>
+}
+
+/** Fetch the latest runs of a node of a flow across executions, newest first */
+export async function fetchNodeHistory(flowId: string, nodeId: string, limit = 20): Promise<NodeHistory> {
+  const qs = new URLSearchParams({ flow_id: flowId, limit: String(limit) })
+  const res = await fetch(`${AUDIT_API_BASE}/nodes/${encodeURIComponent(nodeId)}/history?${qs}`)
+  if (!res.ok) {
+    const body = await res.text()
+    throw new Error(`Failed to fetch node history (${res.status}): ${body}`)
+  }
+  return res.json() as Promise<NodeHistory>
+}
+
 // ── Secrets API ──────────────────────────────────────────────────────────────
 
 /** Fetch metadata for all secrets (values are never returned) */
@@ -224,12 +289,105 @@ export async function deleteSecret(secretId: string): Promise<void> {
   }
 }
 
+/**
+ * Fetch a secret with its values masked. `reveal: 'full'` returns the plain
+ * values; it requires the admin role and is recorded in the secret audit.
+ */
+export async function getSecret(secretId: string, reveal: 'masked' | 'full' = 'masked'): Promise<SecretView> {
+  const res = await fetch(`${ENGINE_API_BASE}/api/v1/secrets/${encodeURIComponent(secretId)}?reveal=${reveal}`)
+  if (!res.ok) {
+    const body = await res.text()
+    throw new Error(`Failed to fetch secret (${res.status}): ${body}`)
+  }
+  return res.json() as Promise<SecretView>
+}
+
+/** List the stored process nodes that reference a secret via secret_ref */
+export async function getSecretReferences(secretId: string): Promise<SecretReference[]> {
+  const res = await fetch(`${ENGINE_API_BASE}/api/v1/secrets/${encodeURIComponent(secretId)}/references`)
+  if (!res.ok) {
+    const body = await res.text()
+    throw new Error(`Failed to list secret references (${res.status}): ${body}`)
+  }
+  return res.json() as Promise<SecretReference[]>
+}
+
+/** Fetch the most recent resolutions and changes of a secret */
+export async function getSecretAudit(secretId: string, limit?: number): Promise<SecretAuditEvent[]> {
+  const query = limit ? `?limit=${limit}` : ''
+  const res = await fetch(`${ENGINE_API_BASE}/api/v1/secrets/${encodeURIComponent(secretId)}/audit${query}`)
+  if (!res.ok) {
+    const body = await res.text()
+    throw new Error(`Failed to fetch secret audit (${res.status}): ${body}`)
+  }
+  return res.json() as Promise<SecretAuditEvent[]>
+}
+
+// ── Script Snippets API ──────────────────────────────────────────────────────
+
+/** Encode a snippet name for the URL, keeping its "/" separators */
+function snippetPath(name: string): string {
+  return name.split('/').map(encodeURIComponent).join('/')
+}
+
+/** List the snippet library (sources are omitted) */
+export async function listSnippets(): Promise<Snippet[]> {
+  const res = await fetch(`${ENGINE_API_BASE}/api/v1/snippets`)
+  if (!res.ok) {
+    const body = await res.text()
+    throw new Error(`Failed to list snippets (${res.status}): ${body}`)
+  }
+  return res.json() as Promise<Snippet[]>
+}
+
+/** Fetch a snippet with its source */
+export async function getSnippet(name: string): Promise<Snippet> {
+  const res = await fetch(`${ENGINE_API_BASE}/api/v1/snippets/${snippetPath(name)}`)
+  if (!res.ok) {
+    const body = await res.text()
+    throw new Error(`Failed to get snippet (${res.status}): ${body}`)
+  }
+  return res.json() as Promise<Snippet>
+}
+
+/** Create or replace a snippet */
+export async function saveSnippet(input: SnippetInput): Promise<Snippet> {
+  const res = await fetch(`${ENGINE_API_BASE}/api/v1/snippets`, {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify(input),
+  })
+  if (!res.ok) {
+    const body = await res.text()
+    throw new Error(`Failed to save snippet (${res.status}): ${body}`)
+  }
+  return res.json() as Promise<Snippet>
+}
+
+/** Delete a snippet by name */
+export async function deleteSnippet(name: string): Promise<void> {
+  const res = await fetch(`${ENGINE_API_BASE}/api/v1/snippets/${snippetPath(name)}`, {
+    method: 'DELETE',
+  })
+  if (!res.ok) {
+    const body = await res.text()
+    throw new Error(`Failed to delete snippet (${res.status}): ${body}`)
+  }
+}
+
 // ── Process & Deployment API ─────────────────────────────────────────────────
 
-/** List all saved processes, optionally filtered by status */
-export async function listProcesses(status?: string): Promise<ProcessSummary[]> {
-  const url = status
-    ? `${ENGINE_API_BASE}/api/v1/processes?status=${encodeURIComponent(status)}`
+/**
+ * List all saved processes, optionally filtered by status. Archived processes
+ * are only included with `includeArchived` or status "archived".
+ */
+export async function listProcesses(status?: string, includeArchived = false): Promise<ProcessSummary[]> {
+  const params = new URLSearchParams()
+  if (status) params.set('status', status)
+  if (includeArchived) params.set('include_archived', 'true')
+  const query = params.toString()
+  const url = query
+    ? `${ENGINE_API_BASE}/api/v1/processes?${query}`
     : `${ENGINE_API_BASE}/api/v1/processes`
   const res = await fetch(url)
   if (!res.ok) {
@@ -239,11 +397,21 @@ export async function listProcesses(status?: string): Promise<ProcessSummary[]>
   return res.json() as Promise<ProcessSummary[]>
 }
 
-/** Save (upsert) a flow DSL. Returns the persisted process summary. */
-export async function saveProcess(dsl: FlowDSL): Promise<ProcessSummary> {
-  const res = await fetch(`${ENGINE_API_BASE}/api/v1/processes`, {
+/**
+ * Save (upsert) a flow DSL. Returns the persisted process summary.
+ * When `revision` is given the engine rejects the save with 409 if another
+ * user saved the flow in the meantime. `note` is recorded as the change_note
+ * explaining why the flow changed.
+ */
+export async function saveProcess(dsl: FlowDSL, revision?: number, note?: string): Promise<ProcessSummary> {
+  const headers: Record<string, string> = { 'Content-Type': 'application/json' }
+  if (revision !== undefined) {
+    headers['If-Match'] = `"${revision}"`
+  }
+  const query = note ? `?note=${encodeURIComponent(note)}` : ''
+  const res = await fetch(`${ENGINE_API_BASE}/api/v1/processes${query}`, {
     method: 'POST',
-    headers: { 'Content-Type': 'application/json' },
+    headers,
     body: JSON.stringify(dsl),
   })
   const data = await res.json() as ProcessSummary
@@ -253,9 +421,14 @@ export async function saveProcess(dsl: FlowDSL): Promise<ProcessSummary> {
   return data
 }
 
-/** Delete a saved process by id */
-export async function deleteProcess(processId: string): Promise<void> {
-  const res = await fetch(`${ENGINE_API_BASE}/api/v1/processes/${encodeURIComponent(processId)}`, {
+/**
+ * Archive a saved process by id (stopping its trigger). With `purge` an
+ * archived process is deleted permanently; the engine refuses (409) when it
+ * ran within its purge protection window.
+ */
+export async function deleteProcess(processId: string, purge = false): Promise<void> {
+  const query = purge ? '?purge=true' : ''
+  const res = await fetch(`${ENGINE_API_BASE}/api/v1/processes/${encodeURIComponent(processId)}${query}`, {
     method: 'DELETE',
   })
   if (!res.ok) {
@@ -264,6 +437,19 @@ export async function deleteProcess(processId: string): Promise<void> {
   }
 }
 
+/** Restore an archived process to its status before archiving */
+export async function restoreProcess(processId: string): Promise<ProcessRecord> {
+  const res = await fetch(
+    `${ENGINE_API_BASE}/api/v1/processes/${encodeURIComponent(processId)}/restore`,
+    { method: 'POST' },
+  )
+  if (!res.ok) {
+    const body = await res.text()
+    throw new Error(`Failed to restore process (${res.status}): ${body}`)
+  }
+  return res.json() as Promise<ProcessRecord>
+}
+
 /** Fetch the full record (including DSL) for a saved process */
 export async function getProcess(processId: string): Promise<ProcessRecord> {
   const res = await fetch(`${ENGINE_API_BASE}/api/v1/processes/${encodeURIComponent(processId)}`)
@@ -287,6 +473,90 @@ export async function deployProcess(processId: string): Promise<DeploymentStatus
   return data
 }
 
+/** Run a deployed process now, whatever its trigger type */
+export async function runProcess(
+  processId: string,
+  triggerData?: Record<string, unknown>,
+): Promise<RunFlowResponse> {
+  const res = await fetch(
+    `${ENGINE_API_BASE}/api/v1/processes/${encodeURIComponent(processId)}/run`,
+    {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify(triggerData ? { trigger_data: triggerData } : {}),
+    },
+  )
+  const data = await res.json() as RunFlowResponse
+  if (!res.ok) {
+    throw new Error(`Run failed (${res.status}): ${data.error ?? res.statusText}`)
+  }
+  return data
+}
+
+/** Promote a process to the next environment (draft → dev → staging → prod) */
+export async function promoteProcess(processId: string, to: DeploymentEnvironment): Promise<Promotion> {
+  const res = await fetch(
+    `${ENGINE_API_BASE}/api/v1/processes/${encodeURIComponent(processId)}/promote?to=${encodeURIComponent(to)}`,
+    { method: 'POST' },
+  )
+  const data = await res.json() as Promotion & { error?: string }
+  if (!res.ok) {
+    throw new Error(`Failed to promote process (${res.status}): ${data.error ?? res.statusText}`)
+  }
+  return data
+}
+
+/** Fetch the revision promoted to each environment and the promotion history */
+export async function getProcessEnvironments(processId: string): Promise<ProcessEnvironments> {
+  const res = await fetch(
+    `${ENGINE_API_BASE}/api/v1/processes/${encodeURIComponent(processId)}/environments`,
+  )
+  if (!res.ok) {
+    throw new Error(`Failed to load environments (${res.status}): ${res.statusText}`)
+  }
+  return res.json() as Promise<ProcessEnvironments>
+}
+
+/** List the requests captured by a REST/SOAP trigger with capture_days, newest first */
+export async function listCaptures(processId: string, limit?: number): Promise<CaptureSummary[]> {
+  const query = limit ? `?limit=${limit}` : ''
+  const res = await fetch(
+    `${ENGINE_API_BASE}/api/v1/processes/${encodeURIComponent(processId)}/captures${query}`,
+  )
+  if (!res.ok) {
+    throw new Error(`Failed to list captures (${res.status}): ${res.statusText}`)
+  }
+  return res.json() as Promise<CaptureSummary[]>
+}
+
+/** Fetch one captured request with its headers and body */
+export async function getCapture(processId: string, captureId: number): Promise<CapturedRequest> {
+  const res = await fetch(
+    `${ENGINE_API_BASE}/api/v1/processes/${encodeURIComponent(processId)}/captures/${captureId}`,
+  )
+  if (!res.ok) {
+    throw new Error(`Failed to load capture (${res.status}): ${res.statusText}`)
+  }
+  return res.json() as Promise<CapturedRequest>
+}
+
+/**
+ * Re-fire a captured request through the current DSL. The trigger's own
+ * response is returned whatever its status; only a replay the engine
+ * refuses (missing capture, changed trigger) throws.
+ */
+export async function replayCapture(processId: string, captureId: number): Promise<CaptureReplay> {
+  const res = await fetch(
+    `${ENGINE_API_BASE}/api/v1/processes/${encodeURIComponent(processId)}/captures/${captureId}/replay`,
+    { method: 'POST' },
+  )
+  if (!res.headers.get('X-Replayed-Capture')) {
+    const data = await res.json().catch(() => ({})) as { error?: string }
+    throw new Error(`Failed to replay capture (${res.status}): ${data.error ?? res.statusText}`)
+  }
+  return { status: res.status, contentType: res.headers.get('Content-Type') ?? '', body: await res.text() }
+}
+
 /** Stop a deployed process */
 export async function stopProcess(processId: string): Promise<DeploymentStatus> {
   const res = await fetch(
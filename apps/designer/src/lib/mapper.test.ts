@@ -1,5 +1,5 @@
 import { describe, it, expect } from 'vitest'
-import { buildInputMapping, buildSourceFields, objectToSchemaFields } from './mapper'
+import { buildInputMapping, buildMappingRules, buildSourceFields, objectToSchemaFields } from './mapper'
 import type { MappingConnection, SchemaField } from '../types/mapper'
 import type { DesignerNode } from '../types/designer'
 import type { NodeData } from '../types/designer'
@@ -56,6 +56,28 @@ describe('buildInputMapping', () => {
   })
 })
 
+// ---------------------------------------------------------------------------
+// buildMappingRules
+// ---------------------------------------------------------------------------
+describe('buildMappingRules', () => {
+  it('maps each connection to a target/source rule in order', () => {
+    const connections: MappingConnection[] = [
+      {
+        sourceField: { key: 'email', path: '$.trigger.body.email', type: 'string' },
+        targetKey: 'contact.email',
+      },
+      {
+        sourceField: { key: 'id', path: '$.nodes.fetch.output.id', type: 'number' },
+        targetKey: 'id',
+      },
+    ]
+    expect(buildMappingRules(connections)).toEqual([
+      { target: 'contact.email', source: '$.trigger.body.email' },
+      { target: 'id', source: '$.nodes.fetch.output.id' },
+    ])
+  })
+})
+
 // ---------------------------------------------------------------------------
 // buildSourceFields
 // ---------------------------------------------------------------------------
@@ -1,4 +1,4 @@
-import type { InputMapping } from '../types/dsl'
+import type { InputMapping, MappingRule } from '../types/dsl'
 import type { SchemaField, MappingConnection } from '../types/mapper'
 import type { DesignerNode } from '../types/designer'
 
@@ -15,6 +15,15 @@ export function buildInputMapping(connections: MappingConnection[]): InputMappin
   }, {})
 }
 
+/**
+ * Converts visual mapping connections into the `mappings` rules of a `mapping`
+ * node: each connection copies its source JSONPath to the target key, which
+ * may be a dotted path such as `contact.email`.
+ */
+export function buildMappingRules(connections: MappingConnection[]): MappingRule[] {
+  return connections.map((conn) => ({ target: conn.targetKey, source: conn.sourceField.path }))
+}
+
 /**
  * Derives a flat list of source schema fields from all nodes that precede
  * `currentNodeId` in the flow. The fields are built from the known node ids
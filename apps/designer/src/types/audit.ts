@@ -20,5 +20,76 @@ export interface ActivityLog {
   output_data: Record<string, unknown> | null
   error_details: Record<string, unknown> | null
   duration_ms: number
+  /** retry_policy attempt the node run ended on; absent for other events */
+  attempt?: number
+  /** Engine instance that published the event */
+  engine_id?: string
+  /** definition.version of the process */
+  process_version?: string
   created_at: string
 }
+
+/** One event of an execution timeline — GET /executions/{id}/timeline */
+export interface TimelineEvent {
+  log_id: number
+  node_id: string
+  node_type: string
+  status: string
+  started_at: string
+  ended_at: string
+  duration_ms: number
+  /** Start of the event relative to the execution start */
+  offset_ms: number
+  error?: string
+  attempt?: number
+  engine_id?: string
+}
+
+/** One run of a node across executions — GET /nodes/{nodeId}/history */
+export interface NodeRun {
+  log_id: number
+  execution_id: string
+  status: string
+  started_at: string
+  ended_at: string
+  duration_ms: number
+  error?: string
+  attempt?: number
+  process_version?: string
+}
+
+/** Recent runs of a node, newest first, with their counts by status */
+export interface NodeHistory {
+  flow_id: string
+  node_id: string
+  counts: Record<string, number>
+  avg_duration_ms: number
+  runs: NodeRun[]
+}
+
+/** Execution header with node counts and first error — GET /executions/{id} */
+export interface ExecutionDetail extends Execution {
+  parent_execution_id?: string
+  end_time?: string
+  /** Wall time of a finished execution */
+  duration_ms?: number
+  /** Node runs by status, e.g. { SUCCESS: 4, ERROR: 1 } */
+  node_counts: Record<string, number>
+  first_error?: TimelineEvent
+  execution_stats?: Record<string, unknown>
+}
+
+/** Final execution context persisted by the engine — GET /api/v1/executions/{id}/context */
+export interface ExecutionSnapshot {
+  execution_id: string
+  process_id: string
+  /** The ExecutionContext every JSONPath resolved against ($.trigger, $.nodes, ...) */
+  context: Record<string, unknown>
+  created_at: string
+}
+
+/** Value a single JSONPath resolved to — GET /api/v1/executions/{id}/context?path= */
+export interface ExecutionContextValue {
+  path: string
+  value: unknown
+}
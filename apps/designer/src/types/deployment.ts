@@ -5,8 +5,10 @@
 // services/engine/internal/store/process_store.go
 // =============================================================================
 
+import type { DeploymentEnvironment } from './dsl'
+
 /** Process deployment status */
-export type ProcessStatus = 'draft' | 'deployed' | 'stopped'
+export type ProcessStatus = 'draft' | 'deployed' | 'stopped' | 'archived'
 
 /** Lightweight summary returned by GET /api/v1/processes */
 export interface ProcessSummary {
@@ -14,9 +16,27 @@ export interface ProcessSummary {
   version: string
   name: string
   status: ProcessStatus
+  /** Optimistic-locking counter, incremented on every save */
+  revision?: number
   /** DSL trigger type, e.g. "rest" | "soap" | "cron" | "rabbitmq" | "mcp" | "manual" */
   trigger_type: string
+  /** Subject of the caller that created the process */
+  owner?: string
+  /** Team of the owner */
+  team?: string
+  /** Subject of the latest save */
+  last_modified_by?: string
+  /** Why the latest save was made */
+  change_note?: string
   updated_at: string
+  /** Set while the process is archived */
+  archived_at?: string
+  /** Start of the latest execution, recorded at most once a minute */
+  last_run_at?: string
+  /** Why the trigger failed to restart when the engine started */
+  deploy_error?: string
+  /** Plain-text credentials found in the DSL, only in the save response */
+  warnings?: string[]
 }
 
 /** Response from POST /api/v1/processes/{id}/deploy and /stop */
@@ -25,3 +45,78 @@ export interface DeploymentStatus {
   status: ProcessStatus | 'error'
   message?: string
 }
+
+/** Response from GET /api/v1/processes/{id}/trigger */
+export interface TriggerStatus {
+  process_id: string
+  /** Whether the trigger runs on this engine; the other fields are only set when it does */
+  deployed: boolean
+  type?: string
+  state?: 'running' | 'reconnecting'
+  since?: string
+  /** Why a reconnecting trigger lost its connection, then why its last attempt failed */
+  error?: string
+  reconnects?: number
+}
+
+/** Response from POST /api/v1/processes/{id}/promote?to= */
+export interface Promotion {
+  id: number
+  process_id: string
+  /** "draft" for promotions to dev */
+  from: DeploymentEnvironment | 'draft'
+  to: DeploymentEnvironment
+  /** Draft revision the promoted DSL came from */
+  revision: number
+  promoted_by: string
+  promoted_at: string
+  /** True when this engine serves the target environment and restarted the trigger */
+  redeployed?: boolean
+}
+
+/** The DSL revision promoted to one environment */
+export interface EnvironmentDeployment {
+  process_id: string
+  environment: DeploymentEnvironment
+  revision: number
+  promoted_by: string
+  promoted_at: string
+}
+
+/** Response from GET /api/v1/processes/{id}/environments */
+export interface ProcessEnvironments {
+  /** Environment served by the engine answering the request; "" when none */
+  current: DeploymentEnvironment | ''
+  environments: EnvironmentDeployment[]
+  /** Promotion history, newest first */
+  promotions: Promotion[]
+}
+
+/** A REST/SOAP request kept by a trigger with capture_days (GET /api/v1/processes/{id}/captures) */
+export interface CaptureSummary {
+  id: number
+  trigger_type: 'rest' | 'soap'
+  method: string
+  /** Path relative to /triggers or /soap and the workspace prefix */
+  path: string
+  query?: string
+  body_bytes: number
+  captured_at: string
+  expires_at: string
+}
+
+/** Full captured request (GET /api/v1/processes/{id}/captures/{captureId}) */
+export interface CapturedRequest extends Omit<CaptureSummary, 'body_bytes'> {
+  workspace: string
+  process_id: string
+  /** Request headers without credentials */
+  headers: Record<string, string[]>
+  body?: string
+}
+
+/** The trigger response to a replayed capture */
+export interface CaptureReplay {
+  status: number
+  contentType: string
+  body: string
+}
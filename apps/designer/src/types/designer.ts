@@ -15,7 +15,7 @@ export type DesignerEdge = Edge<{ transitionType?: TransitionTypeEdge; condition
 
 /** Palette trigger keys (prefixed to avoid conflict with node type 'rabbitmq') */
 export type PaletteTriggerKey =
-  | 'trg_cron' | 'trg_rest' | 'trg_soap' | 'trg_rabbitmq' | 'trg_mcp' | 'trg_manual'
+  | 'trg_cron' | 'trg_rest' | 'trg_soap' | 'trg_rabbitmq' | 'trg_mcp' | 'trg_manual' | 'trg_postgres_cdc' | 'trg_email' | 'trg_sftp_poll' | 'trg_s3_poll'
 
 /** Node type keys used in the palette */
 export type NodeTypeKey = PaletteTriggerKey | NodeType
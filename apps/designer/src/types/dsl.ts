@@ -9,11 +9,56 @@
 
 /** Settings for a flow definition */
 export interface FlowSettings {
+  /**
+   * What the engine records: full payloads and snapshots, payload keys/sizes
+   * and node statuses only (minimal), or no payloads and no snapshot (none)
+   */
   persistence: 'full' | 'minimal' | 'none'
+  /** Budget for the whole execution in ms; node external calls are capped at what is left. 0 = none */
   timeout: number
   error_strategy: 'stop_and_rollback' | 'continue' | 'retry'
+  /** Max simultaneous executions of the deployed process (trigger + manual runs); 0 = unlimited */
+  max_concurrency?: number
+  /** Queue priority of trigger-fired runs when all engine workers are busy; higher runs first */
+  priority?: number
+  /** Execution lane (worker pool, see EXECUTION_LANES) running the process; default lane when empty */
+  lane?: string
+  /** Times one node may run per execution, allowing bounded loops; 0/1 = a revisit fails as a cycle */
+  max_node_visits?: number
+  /** Cap on total node runs per execution; 0 = no cap beyond max_node_visits */
+  max_steps?: number
+  /** A condition reading a path that does not resolve is false (default) or fails the execution */
+  on_undefined_path?: 'false' | 'error'
+  /** Max executions of the deployed process in any rolling hour; more are refused as quota_exceeded. 0 = unlimited */
+  max_executions_per_hour?: number
+  /** Max node runs of the deployed process per UTC day; once reached, executions are refused until midnight. 0 = unlimited */
+  max_node_executions_per_day?: number
+  /** How condition transitions of a node are followed; default for nodes without their own condition_mode */
+  condition_mode?: ConditionMode
+  /** Secret ids the nodes may resolve; any other secret_ref fails. Empty = any secret of the workspace */
+  secrets_allowed?: string[]
+  /** Inject latency, errors and truncated outputs into activities to test error handling */
+  fault_injection?: FaultInjection
 }
 
+/** Faults injected into each node attempt, each drawn with its probability (0–1) */
+export interface FaultInjection {
+  /** Probability of waiting latency_ms before the activity runs */
+  latency_rate?: number
+  latency_ms?: number
+  /** Probability of failing the attempt without running the activity */
+  error_rate?: number
+  /** Probability of keeping only the first half of the output's strings and arrays */
+  truncate_rate?: number
+  /** Node ids the faults apply to; empty = every node */
+  nodes?: string[]
+  /** Non-zero repeats the same faults every run */
+  seed?: number
+}
+
+/** exclusive: only the first matching condition (by priority) is followed; inclusive: every match is */
+export type ConditionMode = 'exclusive' | 'inclusive'
+
 /** Top-level definition metadata */
 export interface FlowDefinition {
   id: string
@@ -21,12 +66,31 @@ export interface FlowDefinition {
   name: string
   description: string
   settings: FlowSettings
+  /** Owning workspace; assigned by the engine from the authenticated principal */
+  workspace?: string
+  /** Variables ($.env.<name>) and secret_ref remapping per deployment environment */
+  environments?: Partial<Record<DeploymentEnvironment, EnvironmentOverrides>>
+  /** Environment the engine loaded the process for; assigned by the engine */
+  environment?: DeploymentEnvironment
+}
+
+/** Deployment environments, in promotion order */
+export type DeploymentEnvironment = 'dev' | 'staging' | 'prod'
+
+/** Values a process uses when it runs in one deployment environment */
+export interface EnvironmentOverrides {
+  /** Readable by input mappings as $.env.<name> */
+  variables?: Record<string, unknown>
+  /** Maps a node secret_ref to the secret used instead, e.g. { "crm-api": "crm-api-prod" } */
+  secrets?: Record<string, string>
+  /** Maps a node profile to the connection profile used instead */
+  profiles?: Record<string, string>
 }
 
 // ── Trigger Types ───────────────────────────────────────────────────────────
 
 /** All supported trigger types */
-export type TriggerType = 'cron' | 'rest' | 'soap' | 'rabbitmq' | 'mcp' | 'manual'
+export type TriggerType = 'cron' | 'rest' | 'soap' | 'rabbitmq' | 'mcp' | 'postgres_cdc' | 'email' | 'sftp_poll' | 's3_poll' | 'manual'
 
 /** Cron trigger configuration */
 export interface CronTriggerConfig {
@@ -35,15 +99,30 @@ export interface CronTriggerConfig {
 
 /** REST trigger configuration */
 export interface RestTriggerConfig {
+  /** Route path; {name} segments (e.g. /orders/{orderId}) are exposed as trigger.params */
   path: string
   method: string
   schema_validation?: string
+  response?: RestResponseMapping
+  /** Keep inbound requests this many days for replay (0 or absent disables capture) */
+  capture_days?: number
+  /** Largest request body accepted after decompression, in bytes (default 10 MiB); larger ones get 413 */
+  max_body_bytes?: number
+}
+
+/** Custom REST reply; strings starting with "$" are expressions over {execution_id, trigger, nodes} */
+export interface RestResponseMapping {
+  status?: number | string
+  body?: unknown
+  headers?: Record<string, string>
 }
 
 /** SOAP trigger configuration */
 export interface SoapTriggerConfig {
   path: string
   wsdl?: string
+  /** Keep inbound requests this many days for replay (0 or absent disables capture) */
+  capture_days?: number
 }
 
 /** RabbitMQ trigger configuration */
@@ -51,6 +130,30 @@ export interface RabbitMQTriggerConfig {
   url_amqp: string
   queue: string
   vhost?: string
+  /** Executions running in parallel (1–256, default 1), capped at settings.max_concurrency */
+  concurrency?: number
+  /** Unacknowledged messages the broker sends ahead (default concurrency) */
+  prefetch?: number
+  /** Messages with the same key are processed in order: "routing_key" or "headers.<name>" */
+  order_key?: string
+  /** Topology declared on every (re)connection */
+  declare?: RabbitMQDeclare
+}
+
+/** Queue, exchanges and bindings a RabbitMQ trigger declares */
+export interface RabbitMQDeclare {
+  /** Declare the trigger's queue; true takes the defaults */
+  queue?: boolean | { durable?: boolean; auto_delete?: boolean; arguments?: Record<string, unknown> }
+  exchanges?: {
+    name: string
+    /** direct (default), fanout, topic, headers or a plugin type x-* */
+    type?: string
+    durable?: boolean
+    auto_delete?: boolean
+    arguments?: Record<string, unknown>
+  }[]
+  /** Bindings of the trigger's queue */
+  bindings?: { exchange: string; routing_key?: string; arguments?: Record<string, unknown> }[]
 }
 
 /** MCP (Model Context Protocol) trigger configuration */
@@ -59,6 +162,101 @@ export interface McpTriggerConfig {
   capabilities?: Record<string, unknown>
 }
 
+/**
+ * Postgres change-data-capture trigger configuration. Each row change fires
+ * the flow with { schema, table, op, old, new } (plus lsn in logical mode).
+ */
+export interface PostgresCdcTriggerConfig {
+  dsn: string
+  /** notify (LISTEN/NOTIFY, default) or logical (wal2json replication slot) */
+  mode?: 'notify' | 'logical'
+  /** Notify mode: channel the table triggers pg_notify on */
+  channel?: string
+  /** Logical mode: replication slot name */
+  slot?: string
+  /** Logical mode: create the slot with wal2json when missing */
+  create_slot?: boolean
+  /** Logical mode: slot polling interval; defaults to 1000 */
+  poll_interval_ms?: number
+  /** Logical mode: max changes read per poll; defaults to 100 */
+  batch_size?: number
+  /** Only deliver changes of these tables ("orders" or "public.orders") */
+  tables?: string[]
+}
+
+/**
+ * Email (IMAP) trigger configuration. Each matching message fires the flow
+ * with { uid, folder, message_id, from, to, cc, subject, date, headers, text,
+ * html, attachments }.
+ */
+export interface EmailTriggerConfig {
+  host: string
+  /** Defaults to 993 for TLS, 143 otherwise */
+  port?: number
+  /** Defaults to TLS */
+  security?: 'TLS' | 'STARTTLS' | 'NONE'
+  auth: { user: string; password: string }
+  /** Mailbox folder to poll; defaults to INBOX */
+  folder?: string
+  search?: {
+    /** Only unseen messages; defaults to true */
+    unseen?: boolean
+    from?: string
+    to?: string
+    subject?: string
+    text?: string
+    /** YYYY-MM-DD */
+    since?: string
+  }
+  /** After a successful execution flag the message seen (default) or move it */
+  on_success?: 'seen' | 'move'
+  /** Destination folder for on_success "move" */
+  move_to?: string
+  /** Defaults to 60000 */
+  poll_interval_ms?: number
+  /** Max messages delivered per poll; defaults to 50 */
+  max_messages?: number
+  /** Larger attachments are listed without content; defaults to 10 MiB */
+  max_attachment_bytes?: number
+}
+
+/** Fields shared by the sftp_poll and s3_poll triggers */
+interface FilePollTriggerConfig {
+  /** Only file names matching this regular expression fire the flow */
+  regex_filter?: string
+  /** Skip files modified more recently, e.g. still being uploaded */
+  min_age_ms?: number
+  /** Defaults to 60000 */
+  poll_interval_ms?: number
+  /** Max files delivered per poll; defaults to 100 */
+  max_files?: number
+}
+
+/**
+ * SFTP folder poller. Each new or changed file fires the flow with
+ * { name, path, folder, server, size, modified }.
+ */
+export interface SftpPollTriggerConfig extends FilePollTriggerConfig {
+  server: string
+  /** Defaults to 22 */
+  port?: number
+  auth: { user: string; password?: string; private_key?: string }
+  folder: string
+}
+
+/**
+ * S3 prefix poller. Each new or changed object fires the flow with
+ * { name, key, folder, bucket, size, modified, etag }.
+ */
+export interface S3PollTriggerConfig extends FilePollTriggerConfig {
+  bucket: string
+  region: string
+  /** Omit to use the default AWS credential chain */
+  auth?: { access_key_id: string; secret_access_key: string; session_token?: string }
+  /** Key prefix to list */
+  folder?: string
+}
+
 /** Manual trigger has no required config */
 export type ManualTriggerConfig = Record<string, never>
 
@@ -69,14 +267,30 @@ export type TriggerConfigMap = {
   soap: SoapTriggerConfig
   rabbitmq: RabbitMQTriggerConfig
   mcp: McpTriggerConfig
+  postgres_cdc: PostgresCdcTriggerConfig
+  email: EmailTriggerConfig
+  sftp_poll: SftpPollTriggerConfig
+  s3_poll: S3PollTriggerConfig
   manual: ManualTriggerConfig
 }
 
+/**
+ * Audit sampling of a trigger's successful runs; failed runs are always recorded.
+ * Thins out the audit log of high-frequency flows.
+ */
+export interface TriggerAuditConfig {
+  /** Record only runs that fail or have a failing node */
+  log_errors_only?: boolean
+  /** Fraction of successful runs recorded, 0–1 (default 1) */
+  sample_rate?: number
+}
+
 /** Trigger node — config shape depends on type */
 export interface FlowTrigger<T extends TriggerType = TriggerType> {
   id: string
   type: T
-  config: TriggerConfigMap[T]
+  /** schema: registry schema the trigger payload must match (REST/SOAP body, RabbitMQ payload, else the whole trigger data) */
+  config: TriggerConfigMap[T] & { audit?: TriggerAuditConfig; schema?: string }
 }
 
 // ── Retry Policy ────────────────────────────────────────────────────────────
@@ -88,6 +302,27 @@ export interface RetryPolicy {
   type: 'fixed' | 'exponential'
 }
 
+/**
+ * Circuit breaker for nodes. While open, the node fails immediately with
+ * status "circuit_open" instead of calling its downstream system.
+ */
+export interface CircuitBreaker {
+  /** Consecutive failed runs that open the circuit (default 5) */
+  failure_threshold?: number
+  /** How long the circuit stays open before probing, e.g. "30s" (default 30s) */
+  open_duration?: string
+  /** Runs let through after open_duration; all must succeed to close (default 1) */
+  half_open_probes?: number
+}
+
+/** Callback notified when a node exceeds its sla_ms */
+export interface SlaAlert {
+  /** Receives the breach as a JSON POST */
+  url?: string
+  /** NATS subject the breach is published to */
+  subject?: string
+}
+
 // ── Input Mapping ───────────────────────────────────────────────────────────
 
 /** Input mapping values are JSONPath expressions (e.g. $.trigger.body) */
@@ -103,11 +338,19 @@ export type NodeType =
   | 'smb'
   | 'mail'
   | 'rabbitmq'
+  | 'websocket_send'
   | 'sql'
   | 'code'
   | 'log'
   | 'transform'
+  | 'mapping'
   | 'file'
+  | 'dedupe'
+  | 'batcher'
+  | 'faker'
+  | 'assert'
+  | 'mock_http'
+  | 'callback_await'
 
 // ── Node Config Interfaces ──────────────────────────────────────────────────
 
@@ -119,6 +362,27 @@ export interface HttpNodeConfig {
   data?: unknown
   auth?: string
   timeout?: number
+  /** Response checks; a violation fails the node instead of returning the response as data */
+  expect?: HttpExpect
+}
+
+/** A check on the parsed response body of an HTTP node */
+export interface HttpBodyAssertion {
+  /** JSONPath into the response body, e.g. "$.data.id" */
+  path: string
+  exists?: boolean
+  equals?: unknown
+  /** Go regular expression matched against the value as text */
+  matches?: string
+  type?: 'string' | 'number' | 'boolean' | 'object' | 'array' | 'null'
+}
+
+/** HTTP node response expectations */
+export interface HttpExpect {
+  /** 201, "2xx", "200-299" or a list of those */
+  status?: number | string | Array<number | string>
+  max_latency_ms?: number
+  body?: HttpBodyAssertion[]
 }
 
 /** SFTP / S3 / SMB shared file-transfer configuration */
@@ -137,6 +401,37 @@ export interface FileTransferNodeConfig {
   overwrite?: boolean
   /** PUT-specific: create target folder if missing */
   create_folder?: boolean
+  /** GET-specific: keep files in engine memory instead of writing them to local_folder */
+  in_memory?: boolean
+}
+
+/**
+ * File output by sftp/s3/smb get and file create/read nodes, and read by put,
+ * file and mail nodes through input_mapping (`files`, `file`, attachments)
+ */
+export interface FileRef {
+  /** mem://<execution_id>/<n> (in_memory) or file://<absolute path>; valid only within the execution that created it */
+  ref: string
+  name: string
+  size: number
+  /** Hex SHA-256 of the content; reading a ref whose content changed fails */
+  sha256: string
+  content_type: string
+}
+
+/** SMB configuration — adds tree operations to the shared file-transfer fields */
+export interface SmbNodeConfig extends Omit<FileTransferNodeConfig, 'method'> {
+  share: string
+  method: 'get' | 'put' | 'delete' | 'move'
+  /** get/put: descend into sub-directories, preserving the layout under local_folder; delete: remove whole trees */
+  recursive?: boolean
+  local_folder?: string
+  /** put/delete: paths relative to folder */
+  files?: string[]
+  /** move-specific: path relative to folder to move or rename */
+  source?: string
+  /** move-specific: new path relative to folder */
+  destination?: string
 }
 
 /** S3-specific configuration (extends file transfer) */
@@ -145,10 +440,42 @@ export interface S3NodeConfig {
   region: string
   auth?: string
   folder: string
-  method: 'get' | 'put'
+  method: 'get' | 'put' | 'presign' | 'delete' | 'copy'
   regex_filter?: string
   overwrite?: boolean
   create_folder?: boolean
+  /** GET-specific: keep objects in engine memory instead of writing them to local_folder */
+  in_memory?: boolean
+  /** PUT/COPY: Content-Type of the objects (put defaults to the file extension's) */
+  content_type?: string
+  /** PUT/COPY: custom x-amz-meta-* metadata */
+  metadata?: Record<string, string>
+  /** PUT: files from this size are uploaded in parts (default 100) */
+  multipart_threshold_mb?: number
+  /** PUT: multipart part size (default 16, min 5) */
+  part_size_mb?: number
+  /** PRESIGN/COPY/DELETE: object key under folder */
+  key?: string
+  /** DELETE: object keys under folder */
+  keys?: string[]
+  /** PRESIGN: operation the URL allows (default get) */
+  presign_method?: 'get' | 'put'
+  /** PRESIGN: URL validity in seconds (default 900, max 604800) */
+  expires_seconds?: number
+  /** COPY: source object; source_bucket defaults to bucket */
+  source_key?: string
+  source_bucket?: string
+}
+
+/** A mail attachment: a local path, base64 content or a file ref */
+export interface MailAttachment {
+  path?: string
+  /** Base64-encoded content */
+  content?: string
+  ref?: string
+  filename?: string
+  name?: string
+  content_type?: string
 }
 
 /** Mail node configuration — action determines sub-fields */
@@ -163,11 +490,15 @@ export interface MailNodeConfig {
   to?: string[]
   cc?: string[]
   bcc?: string[]
+  reply_to?: string[]
   subject?: string
   priority?: 'low' | 'normal' | 'high'
+  /** Plain-text body */
   body?: string
+  /** HTML body; sent as an alternative to body when both are set */
+  html?: string
   body_type?: 'text' | 'html'
-  attachments?: string[]
+  attachments?: Array<string | MailAttachment>
   /** Receive-specific fields */
   filter_subject?: string
   filter_content?: string
@@ -189,17 +520,61 @@ export interface RabbitMQNodeConfig {
   }
 }
 
+/**
+ * WebSocket send node configuration — sends input.message (or message) and
+ * optionally outputs the correlated reply as { sent, reply }
+ */
+export interface WebSocketSendNodeConfig {
+  /** ws:// or wss:// endpoint */
+  url: string
+  message?: unknown
+  headers?: Record<string, string>
+  /** Reuse a connection per url and headers; defaults to true */
+  pool?: boolean
+  await_reply?: boolean
+  /** Dotted field holding the same value in the message and its reply */
+  correlation_key?: string
+  /** Defaults to 10000 */
+  reply_timeout_ms?: number
+}
+
 /** SQL node configuration */
 export interface SqlNodeConfig {
-  engine: 'postgres' | 'mysql' | 'oracle'
+  engine: 'postgres' | 'mysql' | 'oracle' | 'snowflake' | 'bigquery'
   host: string
   port: number
   database: string
+  /** Snowflake account identifier, e.g. "myorg-myaccount" */
+  account?: string
+  /** Snowflake session context */
+  warehouse?: string
+  role?: string
+  /** BigQuery project running the job and dataset location */
+  project?: string
+  location?: string
+  /** Warehouse API endpoint override */
+  url?: string
+  /** Warehouses: submit the query and poll it every poll_interval_ms (default 1000) */
+  async?: boolean
+  poll_interval_ms?: number
+  /** Warehouses: stop fetching result pages after this many rows */
+  max_rows?: number
   schema?: string
   credentials?: string
+  /** :name placeholders bind named params; :name(col1, col2) expands an array into a multi-row VALUES list */
   query: string
-  params?: unknown[]
+  /** Positional values ($1 / ?) or named values for :name; input_mapping "params" overrides named values */
+  params?: unknown[] | Record<string, unknown>
   timeout?: number
+  /**
+   * postgres/mysql: "file" writes the rows to a file of local_folder as format
+   * (ndjson | csv) and outputs its ref; "chunks" runs the node's chunk
+   * transitions per chunk_size rows ({rows, chunk, offset})
+   */
+  stream?: 'file' | 'chunks'
+  format?: 'ndjson' | 'csv'
+  local_folder?: string
+  chunk_size?: number
   autocommit?: boolean
   ssl_mode?: string
 }
@@ -222,6 +597,29 @@ export interface TransformNodeConfig {
   spec?: unknown
 }
 
+/** One rule of a mapping node */
+export interface MappingRule {
+  /** Dotted output path, e.g. "contact.email" */
+  target: string
+  /** Context JSONPath of the value */
+  source?: string
+  /** concat only — JSONPaths and literals joined in order */
+  sources?: string[]
+  /** Literal used instead of a source */
+  value?: unknown
+  /** Used when the source does not resolve or is null */
+  default?: unknown
+  type?: 'string' | 'number' | 'integer' | 'boolean'
+  function?: 'concat' | 'split'
+  /** Defaults to "" for concat and "," for split */
+  separator?: string
+}
+
+/** Mapping node configuration — output is the object built by the rules */
+export interface MappingNodeConfig {
+  mappings: MappingRule[]
+}
+
 /** Local file operations node configuration */
 export interface FileNodeConfig {
   operation: 'create' | 'delete' | 'read'
@@ -230,19 +628,142 @@ export interface FileNodeConfig {
   mode?: 'overwrite' | 'append'
 }
 
+/** Dedupe node configuration — output is { duplicate, hash, first_seen } */
+export interface DedupeNodeConfig {
+  /** Input keys ("body.event_id") or context JSONPaths to hash; defaults to the whole input */
+  fields?: string[]
+  /** Go duration ("24h") or seconds; defaults to 24h */
+  ttl?: string | number
+  /** Hash namespace; defaults to the process id */
+  scope?: string
+}
+
+/**
+ * Batcher node configuration — the node input is the item. The execution that
+ * completes a batch continues with { released: true, reason, key, count, items };
+ * other executions stop at the node.
+ */
+export interface BatcherNodeConfig {
+  /** Batch key, literal or context JSONPath; defaults to "" */
+  key?: string
+  /** Items that release the batch; defaults to 100 */
+  max_size?: number
+  /** Max time the oldest item waits before release; defaults to 30000 */
+  max_wait_ms?: number
+}
+
+/**
+ * A faker field: a type name ("email") or an object with a type and its
+ * options. Types: uuid, name, first_name, last_name, email, phone, company,
+ * street, city, country, word, sentence, int, float, bool, date, choice,
+ * sequence, const, object, array.
+ */
+export type FakerFieldSpec =
+  | string
+  | {
+      type: string
+      /** int, float, array length */
+      min?: number
+      max?: number
+      /** float; defaults to 2 */
+      decimals?: number
+      /** date: a date, RFC 3339 time or duration from now ("-720h") */
+      from?: string
+      to?: string
+      /** date; defaults to datetime */
+      format?: 'datetime' | 'date' | 'unix'
+      /** choice */
+      values?: unknown[]
+      /** sequence; defaults to 1 */
+      start?: number
+      /** const */
+      value?: unknown
+      /** object, and each object of an array */
+      fields?: Record<string, FakerFieldSpec>
+      /** array length; defaults to 3 */
+      count?: number
+      /** array of values instead of objects */
+      items?: FakerFieldSpec
+    }
+
+/** Faker node configuration — output is { records, count } */
+export interface FakerNodeConfig {
+  fields: Record<string, FakerFieldSpec>
+  /** Records to generate; defaults to 1, at most 10000 */
+  count?: number
+  /** Non-zero repeats the same records on every run */
+  seed?: number
+}
+
+/** An assert node entry: a condition expression, or one with a name and message */
+export type Assertion = string | { expression: string; name?: string; message?: string }
+
+/**
+ * Assert node configuration — output is { passed, total, failed, results };
+ * a failed assertion fails the node unless continue_on_failure is set
+ */
+export interface AssertNodeConfig {
+  assertions: Assertion[]
+  continue_on_failure?: boolean
+}
+
+/** A route answered by a mock_http node */
+export interface MockHttpRoute {
+  /** Any method when empty */
+  method?: string
+  /** Exact path, or a prefix ending in "*" */
+  path: string
+  /** Defaults to 200 */
+  status?: number
+  headers?: Record<string, string>
+  /** Sent as is when a string, as JSON otherwise */
+  body?: unknown
+  delay_ms?: number
+}
+
+/**
+ * Mock HTTP node configuration (test mode only) — starts a server for the
+ * execution and outputs { url, routes }
+ */
+export interface MockHttpNodeConfig {
+  routes: MockHttpRoute[]
+}
+
+/** callback_await node configuration: the request announcing a one-time callback URL */
+export interface CallbackAwaitNodeConfig {
+  url: string
+  /** Defaults to POST */
+  method?: string
+  headers?: Record<string, string>
+  /** Request timeout in seconds */
+  timeout?: number
+  /** Body member set to the callback URL; defaults to "callback_url" */
+  callback_field?: string
+  /** Wait for the callback, e.g. "10m" or seconds; defaults to 5m, at most 15m */
+  await_timeout?: string | number
+}
+
 /** Union of all node config types */
 export type NodeConfigMap = {
   http: HttpNodeConfig
   sftp: FileTransferNodeConfig
   s3: S3NodeConfig
-  smb: FileTransferNodeConfig
+  smb: SmbNodeConfig
   mail: MailNodeConfig
   rabbitmq: RabbitMQNodeConfig
+  websocket_send: WebSocketSendNodeConfig
   sql: SqlNodeConfig
   code: CodeNodeConfig
   log: LogNodeConfig
   transform: TransformNodeConfig
+  mapping: MappingNodeConfig
   file: FileNodeConfig
+  dedupe: DedupeNodeConfig
+  batcher: BatcherNodeConfig
+  faker: FakerNodeConfig
+  assert: AssertNodeConfig
+  mock_http: MockHttpNodeConfig
+  callback_await: CallbackAwaitNodeConfig
 }
 
 // ── Flow Node ───────────────────────────────────────────────────────────────
@@ -253,33 +774,169 @@ export interface FlowNode<T extends NodeType = NodeType> {
   type: T
   description?: string
   input_mapping?: InputMapping
+  /** input_mapping keys passed to the activity but recorded only as "[sensitive:<key>]" */
+  sensitive_inputs?: string[]
+  /** Reshapes the activity output before it is stored: JSONPaths ($.body.id) into the output, nested objects or literals */
+  output_mapping?: Record<string, unknown>
+  /** Registry schema the resolved input must match */
+  input_schema?: string
+  /** Registry schema the (mapped) output must match */
+  output_schema?: string
+  /** Overrides definition.settings.condition_mode for the transitions leaving this node */
+  condition_mode?: ConditionMode
   config?: NodeConfigMap[T]
   /** Reference to a secret in the secrets store */
   secret_ref?: string
+  /** Connection profile supplying the config fields and secret the node does not set */
+  profile?: string
   retry_policy?: RetryPolicy
+  circuit_breaker?: CircuitBreaker
+  /** Expected duration in ms; slower runs emit an "sla_breach" audit event */
+  sla_ms?: number
+  /** Where sla_ms breaches are reported besides the audit log */
+  sla_alert?: SlaAlert
   next?: string[]
 }
 
+/**
+ * A use of a node library template, replaced by the template's nodes when
+ * the process is loaded. Its id names the template's exit node.
+ */
+export interface RefNode {
+  id: string
+  /** "name@version", or "name" for the latest version */
+  $ref: string
+  /** Layered over the input_mapping of the template's entry node */
+  input_mapping?: InputMapping
+  next?: string[]
+}
+
+/** A versioned chain of nodes of the node library (/api/v1/node-templates) */
+export interface NodeTemplate {
+  name: string
+  /** Assigned on publish; versions never change */
+  version: number
+  workspace: string
+  description: string
+  /** Omitted by list endpoints; the first node is the entry of the chain */
+  nodes?: FlowNode[]
+  /** Without transitions the nodes run in order */
+  transitions?: FlowTransition[]
+  /** Node whose output the $ref node exposes; the last node when empty */
+  output?: string
+  created_by: string
+  created_at: string
+}
+
+/** A config field of a node type, as listed by /api/v1/node-types */
+export interface ConfigField {
+  name: string
+  type: 'string' | 'boolean' | 'integer' | 'number' | 'duration' | 'string[]' | 'array' | 'object' | 'any'
+  required?: boolean
+  /** Used when the field is absent, null or "" */
+  default?: unknown
+  enum?: string[]
+  doc?: string
+}
+
+/** A node type the engine runs (/api/v1/node-types) */
+export interface NodeTypeInfo {
+  type: string
+  /** Omitted for node types without a typed config */
+  config?: ConfigField[]
+}
+
 // ── Transitions ─────────────────────────────────────────────────────────────
 
 /** Transition types between nodes */
-export type TransitionType = 'success' | 'error' | 'condition' | 'nocondition'
+export type TransitionType = 'success' | 'error' | 'condition' | 'nocondition' | 'dynamic' | 'chunk'
 
 /** A transition between nodes */
 export interface FlowTransition {
   from: string
+  /** Target node — empty when type is 'dynamic' */
   to: string
   type: TransitionType
   /** JSONPath expression — required when type is 'condition' */
   condition?: string
+  /** Condition transitions are evaluated highest priority first (default 0) */
+  priority?: number
+  /** Expression yielding the id of the node to follow — required when type is 'dynamic' */
+  expression?: string
+  /** Nodes a 'dynamic' expression may name */
+  targets?: string[]
 }
 
 // ── Complete Flow DSL ───────────────────────────────────────────────────────
 
 /** Complete flow DSL document */
 export interface FlowDSL {
+  /** Version of the DSL model; older documents are migrated when the engine loads them. Omitted means 1 */
+  dsl_version?: number
   definition: FlowDefinition
   trigger: FlowTrigger
   nodes: FlowNode[]
   transitions: FlowTransition[]
 }
+
+/** How a DSL document is upgraded to the current dsl_version (/api/v1/dsl-migrations) */
+export interface MigrationReport {
+  from_version: number
+  to_version: number
+  changes: { version: number; node_id?: string; change: string }[]
+}
+
+// ── Simulation ──────────────────────────────────────────────────────────────
+
+/** Simulated result of a node (SimulationRequest.mocks) */
+export interface NodeMock {
+  output?: Record<string, unknown>
+  /** Returned one per execution, in turn */
+  outputs?: Record<string, unknown>[]
+  error?: string
+  /** Share of runs that fail, 0–1 */
+  error_rate?: number
+}
+
+/** Body of POST /api/v1/processes/{id}/simulate */
+export interface SimulationRequest {
+  /** Default 10, at most 1000 */
+  executions?: number
+  seed?: number
+  schema?: Record<string, unknown>
+  schema_name?: string
+  samples?: Record<string, unknown>[]
+  captures?: number
+  mocks?: Record<string, NodeMock>
+}
+
+/** Branch coverage of a simulation */
+export interface SimulationReport {
+  executions: number
+  succeeded: number
+  failed: number
+  /** Share of transitions that fired at least once, 0–1 */
+  coverage: number
+  transitions: { from: string; to: string; type: string; condition?: string; fired: number }[]
+  nodes: { node_id: string; type: string; runs: number; errors: number; stubbed: boolean }[]
+  runs: {
+    index: number
+    status: 'completed' | 'failed'
+    error?: string
+    trigger: Record<string, unknown>
+    nodes: Record<string, string>
+  }[]
+}
+
+// ── Schema Registry ─────────────────────────────────────────────────────────
+
+/** A named JSON Schema of the registry (GET /api/v1/schemas) */
+export interface PayloadSchema {
+  name: string
+  workspace: string
+  description: string
+  schema: Record<string, unknown>
+  /** JSONPaths the schema describes, e.g. $.items[*].sku — offered for mapping autocomplete */
+  paths?: string[]
+  updated_at: string
+}
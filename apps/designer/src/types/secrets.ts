@@ -14,6 +14,7 @@ export type SecretType =
   | 'aws_credentials'
   | 'ssh_key'
   | 'amqp_url'
+  | 'service_account'
 
 /** Non-sensitive secret metadata returned by GET /api/v1/secrets */
 export interface SecretMeta {
@@ -33,3 +34,35 @@ export interface SecretInput {
   value: Record<string, string>
   metadata?: Record<string, string>
 }
+
+/**
+ * A secret with its value — GET /api/v1/secrets/{id}. Values are masked
+ * (first/last 2 characters) unless an admin requested ?reveal=full.
+ */
+export interface SecretView extends SecretMeta {
+  value: Record<string, unknown>
+  masked: boolean
+}
+
+/** A stored process node that uses a secret — GET /api/v1/secrets/{id}/references */
+export interface SecretReference {
+  process_id: string
+  process_name: string
+  status: string
+  node_id: string
+  node_type: string
+}
+
+/** One secret resolution or change — GET /api/v1/secrets/{id}/audit (never the value) */
+export interface SecretAuditEvent {
+  id: number
+  secret_id: string
+  action: 'resolve' | 'update' | 'delete' | 'reveal'
+  actor: string
+  execution_id?: string
+  process_id?: string
+  node_id?: string
+  success: boolean
+  error?: string
+  created_at: string
+}
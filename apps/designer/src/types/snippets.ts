@@ -0,0 +1,23 @@
+// =============================================================================
+// flowjs-works — Script Snippet Types
+// =============================================================================
+// These types mirror the Go snippet store in
+// services/engine/internal/store/snippet_store.go
+// =============================================================================
+
+/** Shared TypeScript module that code nodes import by name (e.g. "utils/dates") */
+export interface Snippet {
+  name: string
+  workspace: string
+  description: string
+  /** Omitted by GET /api/v1/snippets; present when fetching a single snippet */
+  source?: string
+  updated_at: string
+}
+
+/** Payload for POST /api/v1/snippets (create or replace) */
+export interface SnippetInput {
+  name: string
+  description?: string
+  source: string
+}
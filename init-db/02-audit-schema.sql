@@ -4,14 +4,17 @@
 -- 1. Tabla de Ejecuciones (Cabecera)
 CREATE TABLE IF NOT EXISTS executions (
     execution_id UUID PRIMARY KEY,
+    workspace VARCHAR(63) NOT NULL DEFAULT 'default', -- owning tenant
     flow_id VARCHAR(255) NOT NULL,
     version VARCHAR(50),
-    status VARCHAR(20),            -- STARTED, COMPLETED, FAILED, REPLAYED
+    status VARCHAR(20),            -- STARTED, COMPLETED, FAILED, REPLAYED, ORPHANED
     correlation_id VARCHAR(255),
     start_time TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     end_time TIMESTAMP WITH TIME ZONE,
     trigger_type VARCHAR(50),
-    main_error_message TEXT
+    main_error_message TEXT,
+    parent_execution_id UUID,      -- execution this one retries or replays
+    execution_stats JSONB          -- resource usage reported with the terminal event
 );
 
 -- 2. Tabla de Logs de Actividad (Detalle de cada Nodo)
@@ -23,8 +26,13 @@ CREATE TABLE IF NOT EXISTS activity_logs (
     status VARCHAR(20),            -- SUCCESS, ERROR
     input_data JSONB,
     output_data JSONB,
+    input_data_gz BYTEA,           -- input_data comprimido con gzip (AUDIT_PAYLOAD_COMPRESSION)
+    output_data_gz BYTEA,          -- output_data comprimido con gzip
     error_details JSONB,
     duration_ms INTEGER,
+    attempt INTEGER,               -- intento en que terminó el nodo (retry_policy)
+    engine_id VARCHAR(255),        -- instancia del engine que ejecutó el nodo
+    process_version VARCHAR(50),   -- definition.version del proceso
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
 );
 
@@ -32,3 +40,16 @@ CREATE TABLE IF NOT EXISTS activity_logs (
 CREATE INDEX IF NOT EXISTS idx_activity_input ON activity_logs USING GIN (input_data);
 CREATE INDEX IF NOT EXISTS idx_activity_output ON activity_logs USING GIN (output_data);
 CREATE INDEX IF NOT EXISTS idx_correlation_id ON executions (correlation_id);
+CREATE INDEX IF NOT EXISTS idx_exec_workspace ON executions (workspace, start_time DESC);
+CREATE INDEX IF NOT EXISTS idx_exec_parent ON executions (parent_execution_id) WHERE parent_execution_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_exec_started ON executions (start_time) WHERE status = 'STARTED'; -- watchdog de huérfanas
+CREATE INDEX IF NOT EXISTS idx_activity_node ON activity_logs (node_id, created_at DESC);
+
+-- 3. Dead letters: mensajes de audit.logs que no se pudieron parsear
+CREATE TABLE IF NOT EXISTS audit_dead_letters (
+    id BIGSERIAL PRIMARY KEY,
+    subject VARCHAR(255) NOT NULL, -- NATS subject del mensaje
+    payload BYTEA NOT NULL,        -- mensaje tal como se recibió
+    error TEXT NOT NULL,           -- motivo por el que no se pudo ingerir
+    received_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
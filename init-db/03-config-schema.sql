@@ -6,26 +6,341 @@
 -- ---------------------------------------------------------------------------
 CREATE TABLE IF NOT EXISTS processes (
     id          VARCHAR(255) PRIMARY KEY,
+    workspace   VARCHAR(63)  NOT NULL DEFAULT 'default',  -- owning tenant
     version     VARCHAR(50)  NOT NULL,
     name        VARCHAR(255) NOT NULL,
     description TEXT,
     dsl         JSONB        NOT NULL,
-    status      VARCHAR(20)  DEFAULT 'draft',  -- draft | deployed | stopped
+    status      VARCHAR(20)  DEFAULT 'draft',  -- draft | deployed | stopped | archived
+    revision    INTEGER      NOT NULL DEFAULT 1,  -- optimistic-locking counter (ETag)
+    owner       VARCHAR(255) NOT NULL DEFAULT '',  -- subject that created the process
+    team        VARCHAR(255) NOT NULL DEFAULT '',  -- team of the owner (API_KEYS)
+    last_modified_by VARCHAR(255) NOT NULL DEFAULT '',  -- subject of the latest save
+    change_note TEXT         NOT NULL DEFAULT '',  -- why the latest save was made
     created_at  TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
-    updated_at  TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+    updated_at  TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    archived_at TIMESTAMP WITH TIME ZONE,  -- set while status is archived
+    restore_status VARCHAR(20) NOT NULL DEFAULT '',  -- status a restore returns to
+    last_run_at TIMESTAMP WITH TIME ZONE,  -- latest execution start; guards purges
+    deploy_error TEXT NOT NULL DEFAULT ''  -- why the trigger failed to restart with the engine
 );
 
+-- Columns added since the table was first created: re-running this script
+-- upgrades an existing database.
+ALTER TABLE processes ADD COLUMN IF NOT EXISTS workspace VARCHAR(63) NOT NULL DEFAULT 'default';
+ALTER TABLE processes ADD COLUMN IF NOT EXISTS revision INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE processes ADD COLUMN IF NOT EXISTS owner VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE processes ADD COLUMN IF NOT EXISTS team VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE processes ADD COLUMN IF NOT EXISTS last_modified_by VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE processes ADD COLUMN IF NOT EXISTS change_note TEXT NOT NULL DEFAULT '';
+ALTER TABLE processes ADD COLUMN IF NOT EXISTS archived_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE processes ADD COLUMN IF NOT EXISTS restore_status VARCHAR(20) NOT NULL DEFAULT '';
+ALTER TABLE processes ADD COLUMN IF NOT EXISTS last_run_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE processes ADD COLUMN IF NOT EXISTS deploy_error TEXT NOT NULL DEFAULT '';
+
 CREATE INDEX IF NOT EXISTS idx_processes_status ON processes (status);
+CREATE INDEX IF NOT EXISTS idx_processes_workspace ON processes (workspace, status);
 
 -- ---------------------------------------------------------------------------
 -- Secrets table: AES-256-GCM encrypted credentials referenced by nodes
 -- ---------------------------------------------------------------------------
 CREATE TABLE IF NOT EXISTS secrets (
     id            VARCHAR(255) PRIMARY KEY,       -- e.g. sec_postgres_main
+    workspace     VARCHAR(63)  NOT NULL DEFAULT 'default',  -- owning tenant
     name          VARCHAR(255) NOT NULL,
     type          VARCHAR(50)  NOT NULL,          -- basic_auth | token | certificate | connection_string
     encrypted_val BYTEA        NOT NULL,          -- AES-256-GCM encrypted JSON blob
     metadata      JSONB,                          -- non-sensitive labels/tags
+    key_id        VARCHAR(32)  NOT NULL DEFAULT 'v1',  -- id of the AES key encrypted_val was sealed with
+    created_at    TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at    TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE secrets ADD COLUMN IF NOT EXISTS workspace VARCHAR(63) NOT NULL DEFAULT 'default';
+ALTER TABLE secrets ADD COLUMN IF NOT EXISTS key_id VARCHAR(32) NOT NULL DEFAULT 'v1';
+
+CREATE INDEX IF NOT EXISTS idx_secrets_workspace ON secrets (workspace);
+
+-- ---------------------------------------------------------------------------
+-- Secrets audit: who resolved or changed a secret, and for which execution.
+-- Values are never stored.
+-- ---------------------------------------------------------------------------
+CREATE TABLE IF NOT EXISTS secrets_audit (
+    id            BIGSERIAL    PRIMARY KEY,
+    workspace     VARCHAR(63)  NOT NULL DEFAULT 'default',
+    secret_id     VARCHAR(255) NOT NULL,          -- kept after the secret is deleted
+    action        VARCHAR(20)  NOT NULL,          -- resolve | update | delete | reveal
+    actor         VARCHAR(255) NOT NULL,          -- API key subject, or "system" for executions
+    execution_id  VARCHAR(64),
+    process_id    VARCHAR(255),
+    node_id       VARCHAR(255),
+    success       BOOLEAN      NOT NULL,
+    error         TEXT,
+    created_at    TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_secrets_audit_secret ON secrets_audit (workspace, secret_id, created_at DESC);
+
+-- ---------------------------------------------------------------------------
+-- Scheduled runs: one-shot executions planned for a given timestamp
+-- ---------------------------------------------------------------------------
+CREATE TABLE IF NOT EXISTS scheduled_runs (
+    id            UUID         PRIMARY KEY,
+    workspace     VARCHAR(63)  NOT NULL DEFAULT 'default',
+    process_id    VARCHAR(255) NOT NULL REFERENCES processes(id) ON DELETE CASCADE,
+    run_at        TIMESTAMP WITH TIME ZONE NOT NULL,
+    trigger_data  JSONB        NOT NULL DEFAULT '{}',
+    status        VARCHAR(20)  NOT NULL DEFAULT 'pending',  -- pending | running | done | failed | cancelled
+    execution_id  UUID,                                      -- set once the run has executed
+    error         TEXT,
+    claimed_by    VARCHAR(255) NOT NULL DEFAULT '',          -- ENGINE_ID of the engine running it
+    lease_expires_at TIMESTAMP WITH TIME ZONE,               -- renewed while it runs
     created_at    TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     updated_at    TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
 );
+
+ALTER TABLE scheduled_runs ADD COLUMN IF NOT EXISTS claimed_by VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE scheduled_runs ADD COLUMN IF NOT EXISTS lease_expires_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_runs_due ON scheduled_runs (status, run_at);
+CREATE INDEX IF NOT EXISTS idx_scheduled_runs_process ON scheduled_runs (workspace, process_id);
+
+-- ---------------------------------------------------------------------------
+-- Execution queue: trigger-fired runs waiting for an engine worker.
+-- Rows are deleted once executed; outcomes live in the audit trail.
+-- ---------------------------------------------------------------------------
+CREATE TABLE IF NOT EXISTS execution_queue (
+    id            UUID         PRIMARY KEY,
+    workspace     VARCHAR(63)  NOT NULL DEFAULT 'default',
+    process_id    VARCHAR(255) NOT NULL,
+    priority      INTEGER      NOT NULL DEFAULT 0,          -- higher runs first
+    lane          VARCHAR(63)  NOT NULL DEFAULT 'default',  -- execution lane whose workers run it
+    process       JSONB        NOT NULL,                    -- DSL snapshot taken at enqueue time
+    trigger_data  JSONB        NOT NULL DEFAULT '{}',
+    status        VARCHAR(20)  NOT NULL DEFAULT 'pending',  -- pending | running
+    enqueued_at   TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    started_at    TIMESTAMP WITH TIME ZONE,
+    claimed_by    VARCHAR(255) NOT NULL DEFAULT '',          -- ENGINE_ID of the engine running it, or waiting for it
+    lease_expires_at TIMESTAMP WITH TIME ZONE                -- renewed while it runs or is waited for
+);
+
+ALTER TABLE execution_queue ADD COLUMN IF NOT EXISTS claimed_by VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE execution_queue ADD COLUMN IF NOT EXISTS lease_expires_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_execution_queue_claim ON execution_queue (status, lane, priority DESC, enqueued_at);
+CREATE INDEX IF NOT EXISTS idx_execution_queue_process ON execution_queue (process_id, status);
+
+-- ---------------------------------------------------------------------------
+-- Script snippets: shared TypeScript modules imported by code nodes by name
+-- ---------------------------------------------------------------------------
+CREATE TABLE IF NOT EXISTS script_snippets (
+    workspace     VARCHAR(63)  NOT NULL DEFAULT 'default',
+    name          VARCHAR(255) NOT NULL,                    -- import path, e.g. utils/dates
+    description   TEXT         NOT NULL DEFAULT '',
+    source        TEXT         NOT NULL,
+    created_at    TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at    TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (workspace, name)
+);
+
+-- ---------------------------------------------------------------------------
+-- Payload schemas: named JSON Schemas that triggers and nodes validate against
+-- ---------------------------------------------------------------------------
+CREATE TABLE IF NOT EXISTS payload_schemas (
+    workspace     VARCHAR(63)  NOT NULL DEFAULT 'default',
+    name          VARCHAR(255) NOT NULL,                    -- e.g. orders/order-created
+    description   TEXT         NOT NULL DEFAULT '',
+    schema        JSONB        NOT NULL,
+    created_at    TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at    TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (workspace, name)
+);
+
+-- ---------------------------------------------------------------------------
+-- Dedupe keys: content hashes seen by dedupe nodes within their TTL window
+-- ---------------------------------------------------------------------------
+CREATE TABLE IF NOT EXISTS dedupe_keys (
+    workspace     VARCHAR(63)  NOT NULL DEFAULT 'default',
+    scope         VARCHAR(255) NOT NULL,                    -- defaults to the process id
+    hash          CHAR(64)     NOT NULL,                    -- hex SHA-256 of the hashed fields
+    first_seen    TIMESTAMP WITH TIME ZONE NOT NULL,
+    expires_at    TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (workspace, scope, hash)
+);
+
+CREATE INDEX IF NOT EXISTS idx_dedupe_keys_expires ON dedupe_keys (expires_at);
+
+-- ---------------------------------------------------------------------------
+-- Execution snapshots: final context of each execution, kept for debugging
+-- ---------------------------------------------------------------------------
+CREATE TABLE IF NOT EXISTS execution_snapshots (
+    execution_id  VARCHAR(64)  PRIMARY KEY,
+    workspace     VARCHAR(63)  NOT NULL DEFAULT 'default',
+    process_id    VARCHAR(255) NOT NULL,
+    context       JSONB        NOT NULL,                    -- final ExecutionContext (trigger + node outputs)
+    created_at    TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_execution_snapshots_created ON execution_snapshots (created_at);
+
+-- ---------------------------------------------------------------------------
+-- Process versions: the exact definitions executions ran, by content hash
+-- ---------------------------------------------------------------------------
+CREATE TABLE IF NOT EXISTS process_versions (
+    workspace     VARCHAR(63)  NOT NULL DEFAULT 'default',
+    process_id    VARCHAR(255) NOT NULL,
+    dsl_hash      CHAR(64)     NOT NULL,                    -- SHA-256 of the DSL as run
+    revision      INTEGER      NOT NULL DEFAULT 0,          -- draft revision it came from (0 = never saved)
+    dsl           JSONB        NOT NULL,
+    created_at    TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (workspace, process_id, dsl_hash)
+);
+
+-- ---------------------------------------------------------------------------
+-- Execution versions: the process version each execution is pinned to
+-- ---------------------------------------------------------------------------
+CREATE TABLE IF NOT EXISTS execution_versions (
+    execution_id  VARCHAR(64)  PRIMARY KEY,
+    workspace     VARCHAR(63)  NOT NULL DEFAULT 'default',
+    process_id    VARCHAR(255) NOT NULL,
+    dsl_hash      CHAR(64)     NOT NULL,
+    revision      INTEGER      NOT NULL DEFAULT 0,
+    created_at    TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_execution_versions_created ON execution_versions (created_at);
+CREATE INDEX IF NOT EXISTS idx_execution_versions_version ON execution_versions (workspace, process_id, dsl_hash);
+
+-- ---------------------------------------------------------------------------
+-- Trigger captures: raw REST/SOAP requests kept for replay (capture_days)
+-- ---------------------------------------------------------------------------
+CREATE TABLE IF NOT EXISTS trigger_captures (
+    id            BIGSERIAL    PRIMARY KEY,
+    workspace     VARCHAR(63)  NOT NULL DEFAULT 'default',
+    process_id    VARCHAR(255) NOT NULL,
+    trigger_type  VARCHAR(20)  NOT NULL,                    -- rest | soap
+    method        VARCHAR(16)  NOT NULL,
+    path          TEXT         NOT NULL,                    -- relative to /triggers or /soap and the workspace prefix
+    query         TEXT         NOT NULL DEFAULT '',
+    headers       JSONB        NOT NULL,                    -- without Authorization, Cookie, X-API-Key
+    body          BYTEA        NOT NULL,
+    captured_at   TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at    TIMESTAMP WITH TIME ZONE NOT NULL         -- captured_at + trigger config capture_days
+);
+
+CREATE INDEX IF NOT EXISTS idx_trigger_captures_process ON trigger_captures (workspace, process_id, captured_at DESC);
+CREATE INDEX IF NOT EXISTS idx_trigger_captures_expires ON trigger_captures (expires_at);
+
+-- ---------------------------------------------------------------------------
+-- Trigger file state: files sftp_poll and s3_poll triggers fired the flow for
+-- ---------------------------------------------------------------------------
+CREATE TABLE IF NOT EXISTS trigger_file_state (
+    workspace     VARCHAR(63)  NOT NULL DEFAULT 'default',
+    process_id    VARCHAR(255) NOT NULL,
+    file_key      TEXT         NOT NULL,                    -- remote path (SFTP) or object key (S3)
+    fingerprint   VARCHAR(255) NOT NULL,                    -- ETag, or size:mtime
+    fired_at      TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (workspace, process_id, file_key)
+);
+
+-- ---------------------------------------------------------------------------
+-- Process environments: the DSL each process runs with in dev, staging and prod
+-- ---------------------------------------------------------------------------
+CREATE TABLE IF NOT EXISTS process_environments (
+    workspace     VARCHAR(63)  NOT NULL DEFAULT 'default',
+    process_id    VARCHAR(255) NOT NULL REFERENCES processes(id) ON DELETE CASCADE,
+    environment   VARCHAR(20)  NOT NULL,                    -- dev | staging | prod
+    dsl           JSONB        NOT NULL,                    -- validated FlowDSL copied forward
+    revision      INTEGER      NOT NULL,                    -- draft revision the DSL came from
+    promoted_by   VARCHAR(255) NOT NULL DEFAULT '',
+    promoted_at   TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (process_id, environment)
+);
+
+-- ---------------------------------------------------------------------------
+-- Process promotions: history of DSL copies between environments
+-- ---------------------------------------------------------------------------
+CREATE TABLE IF NOT EXISTS process_promotions (
+    id            BIGSERIAL    PRIMARY KEY,
+    workspace     VARCHAR(63)  NOT NULL DEFAULT 'default',
+    process_id    VARCHAR(255) NOT NULL REFERENCES processes(id) ON DELETE CASCADE,
+    from_env      VARCHAR(20)  NOT NULL,                    -- draft | dev | staging
+    to_env        VARCHAR(20)  NOT NULL,                    -- dev | staging | prod
+    revision      INTEGER      NOT NULL,
+    promoted_by   VARCHAR(255) NOT NULL DEFAULT '',
+    promoted_at   TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_process_promotions_process ON process_promotions (process_id, promoted_at DESC);
+
+-- ---------------------------------------------------------------------------
+-- Notification channels: email, Slack and webhook destinations configured once
+-- ---------------------------------------------------------------------------
+CREATE TABLE IF NOT EXISTS notification_channels (
+    workspace     VARCHAR(63)  NOT NULL DEFAULT 'default',
+    id            VARCHAR(255) NOT NULL,                    -- e.g. ops-slack
+    type          VARCHAR(20)  NOT NULL,                    -- email | slack | webhook
+    description   TEXT         NOT NULL DEFAULT '',
+    config        JSONB        NOT NULL DEFAULT '{}',       -- non-sensitive settings
+    secret_ref    VARCHAR(255) NOT NULL DEFAULT '',         -- secret merged into config when sending
+    created_at    TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at    TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (workspace, id)
+);
+
+-- ---------------------------------------------------------------------------
+-- Notification subscriptions: which process events are sent to which channel
+-- ---------------------------------------------------------------------------
+CREATE TABLE IF NOT EXISTS notification_subscriptions (
+    workspace     VARCHAR(63)  NOT NULL DEFAULT 'default',
+    process_id    VARCHAR(255) NOT NULL REFERENCES processes(id) ON DELETE CASCADE,
+    channel_id    VARCHAR(255) NOT NULL,
+    events        JSONB        NOT NULL,                    -- ["failure", "sla_breach", "deploy"]
+    cooldown_s    INTEGER      NOT NULL DEFAULT 0,          -- minimum seconds between two notifications
+    PRIMARY KEY (process_id, channel_id),
+    FOREIGN KEY (workspace, channel_id) REFERENCES notification_channels (workspace, id) ON DELETE CASCADE
+);
+
+-- ---------------------------------------------------------------------------
+-- Connection profiles: shared node config (server, DSN, ...) nodes reference
+-- ---------------------------------------------------------------------------
+CREATE TABLE IF NOT EXISTS connection_profiles (
+    workspace     VARCHAR(63)  NOT NULL DEFAULT 'default',
+    name          VARCHAR(255) NOT NULL,                    -- referenced by node "profile"
+    type          VARCHAR(50)  NOT NULL DEFAULT '',         -- node type it configures, '' for any
+    description   TEXT         NOT NULL DEFAULT '',
+    config        JSONB        NOT NULL DEFAULT '{}',       -- non-sensitive settings
+    secret_ref    VARCHAR(255) NOT NULL DEFAULT '',         -- secret of nodes without their own
+    created_at    TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at    TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (workspace, name)
+);
+
+-- ---------------------------------------------------------------------------
+-- Node library: versioned node chains processes use with "$ref" nodes
+-- ---------------------------------------------------------------------------
+CREATE TABLE IF NOT EXISTS node_templates (
+    workspace     VARCHAR(63)  NOT NULL DEFAULT 'default',
+    name          VARCHAR(255) NOT NULL,                    -- referenced by node "$ref"
+    version       INTEGER      NOT NULL,                    -- 1, 2, ... never modified
+    description   TEXT         NOT NULL DEFAULT '',
+    definition    JSONB        NOT NULL,                    -- {nodes, transitions, output}
+    created_by    VARCHAR(255) NOT NULL DEFAULT '',
+    created_at    TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (workspace, name, version)
+);
+
+-- ---------------------------------------------------------------------------
+-- Process templates: gallery of standard flows instantiated per customer
+-- ---------------------------------------------------------------------------
+CREATE TABLE IF NOT EXISTS process_templates (
+    workspace     VARCHAR(63)  NOT NULL DEFAULT 'default',
+    name          VARCHAR(255) NOT NULL,
+    description   TEXT         NOT NULL DEFAULT '',
+    dsl           JSONB        NOT NULL,                    -- process DSL with {{param.<name>}} placeholders
+    parameters    JSONB        NOT NULL DEFAULT '[]',       -- placeholder names, sorted
+    created_by    VARCHAR(255) NOT NULL DEFAULT '',
+    created_at    TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at    TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (workspace, name)
+);
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"flowjs-works/audit-logger/internal/db"
+	"flowjs-works/audit-logger/internal/middleware"
+	"flowjs-works/audit-logger/internal/subscriber"
+)
+
+// maxReingestBody bounds the corrected payload accepted by a re-ingest.
+const maxReingestBody = 1 << 20
+
+// metricsHandler serves GET /metrics in the Prometheus text format:
+//
+//	flowjs_audit_messages_parsed_total        — audit messages handed to the batcher
+//	flowjs_audit_messages_dead_lettered_total — malformed messages kept in audit_dead_letters
+//	flowjs_audit_messages_dropped_total       — malformed messages lost (no postgres sink, insert failed)
+//	flowjs_audit_messages_reingested_total    — dead letters re-ingested through the admin API
+func metricsHandler(sub *subscriber.Subscriber) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		s := sub.Stats()
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		writeMetric(w, "flowjs_audit_messages_parsed_total", "counter", "Audit messages parsed and handed to the batcher.", s.Parsed)
+		writeMetric(w, "flowjs_audit_messages_dead_lettered_total", "counter", "Malformed audit messages stored as dead letters.", s.DeadLettered)
+		writeMetric(w, "flowjs_audit_messages_dropped_total", "counter", "Malformed audit messages lost without a dead letter.", s.Dropped)
+		writeMetric(w, "flowjs_audit_messages_reingested_total", "counter", "Dead letters re-ingested through the admin API.", s.Reingested)
+	}
+}
+
+func writeMetric(w http.ResponseWriter, name, kind, help string, value interface{}) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %v\n", name, help, name, kind, name, value)
+}
+
+// deadLettersHandler serves the admin API of the audit messages that could
+// not be parsed:
+//
+//	GET    /admin/dead-letters                 — list dead letters, oldest first (?limit, ?offset)
+//	GET    /admin/dead-letters/{id}            — retrieve one dead letter
+//	DELETE /admin/dead-letters/{id}            — discard a dead letter
+//	POST   /admin/dead-letters/{id}/reingest   — ingest the stored payload, or the corrected one in the body
+//
+// Dead letters are not scoped to a workspace, since a malformed message has
+// none: every route requires the admin role.
+func deadLettersHandler(rawDB *sql.DB, sub *subscriber.Subscriber) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !middleware.IsAdmin(r.Context()) {
+			jsonError(w, "admin role required", http.StatusForbidden)
+			return
+		}
+		if rawDB == nil {
+			jsonError(w, "dead letters are stored in PostgreSQL, which is not an audit sink (AUDIT_SINKS)", http.StatusNotImplemented)
+			return
+		}
+		rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/admin/dead-letters"), "/")
+		if rest == "" {
+			if r.Method != http.MethodGet {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			listDeadLetters(w, r, rawDB)
+			return
+		}
+		rawID, action, _ := strings.Cut(rest, "/")
+		id, err := strconv.ParseInt(rawID, 10, 64)
+		if err != nil || id < 1 {
+			jsonError(w, "dead letter id must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		switch {
+		case action == "" && r.Method == http.MethodGet:
+			d, ok := loadDeadLetter(w, r, rawDB, id)
+			if ok {
+				jsonOK(w, d)
+			}
+		case action == "" && r.Method == http.MethodDelete:
+			deleted, err := db.DeleteDeadLetter(r.Context(), rawDB, id)
+			if err != nil {
+				log.Printf("audit-logger: delete dead letter %d: %v", id, err)
+				jsonError(w, middleware.SanitizeError(err, "failed to delete dead letter"), http.StatusInternalServerError)
+				return
+			}
+			if !deleted {
+				jsonError(w, fmt.Sprintf("dead letter not found: %d", id), http.StatusNotFound)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		case action == "reingest" && r.Method == http.MethodPost:
+			reingestDeadLetter(w, r, rawDB, sub, id)
+		case action == "" || action == "reingest":
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		default:
+			jsonError(w, fmt.Sprintf("unknown sub-resource: %q", action), http.StatusNotFound)
+		}
+	}
+}
+
+// listDeadLetters writes a page of the dead letters with their total count
+// in X-Total-Count.
+func listDeadLetters(w http.ResponseWriter, r *http.Request, rawDB *sql.DB) {
+	limit, offset := parsePagination(r.URL.Query())
+	letters, total, err := db.ListDeadLetters(r.Context(), rawDB, limit, offset)
+	if err != nil {
+		log.Printf("audit-logger: list dead letters: %v", err)
+		jsonError(w, middleware.SanitizeError(err, "failed to list dead letters"), http.StatusInternalServerError)
+		return
+	}
+	if letters == nil {
+		letters = []db.DeadLetter{}
+	}
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	jsonOK(w, letters)
+}
+
+// loadDeadLetter returns dead letter id, writing the error response and
+// reporting false when it cannot.
+func loadDeadLetter(w http.ResponseWriter, r *http.Request, rawDB *sql.DB, id int64) (*db.DeadLetter, bool) {
+	d, err := db.GetDeadLetter(r.Context(), rawDB, id)
+	if err != nil {
+		log.Printf("audit-logger: query dead letter %d: %v", id, err)
+		jsonError(w, middleware.SanitizeError(err, "failed to query dead letter"), http.StatusInternalServerError)
+		return nil, false
+	}
+	if d == nil {
+		jsonError(w, fmt.Sprintf("dead letter not found: %d", id), http.StatusNotFound)
+		return nil, false
+	}
+	return d, true
+}
+
+// reingestDeadLetter hands dead letter id to the batcher and deletes it. A
+// non-empty request body replaces the stored payload, to ingest a corrected
+// version; a payload that still cannot be parsed is rejected with 422.
+func reingestDeadLetter(w http.ResponseWriter, r *http.Request, rawDB *sql.DB, sub *subscriber.Subscriber, id int64) {
+	d, ok := loadDeadLetter(w, r, rawDB, id)
+	if !ok {
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxReingestBody+1))
+	if err != nil {
+		jsonError(w, fmt.Sprintf("read request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	if len(body) > maxReingestBody {
+		jsonError(w, "request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	payload := []byte(d.Payload)
+	if len(bytes.TrimSpace(body)) > 0 {
+		payload = body
+	}
+	if err := sub.Reingest(payload); err != nil {
+		jsonError(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if _, err := db.DeleteDeadLetter(r.Context(), rawDB, id); err != nil {
+		// The event is already queued: a failed delete only leaves a
+		// duplicate dead letter behind.
+		log.Printf("audit-logger: delete re-ingested dead letter %d: %v", id, err)
+	}
+	log.Printf("audit-logger: dead letter %d re-ingested", id)
+	w.WriteHeader(http.StatusAccepted)
+}
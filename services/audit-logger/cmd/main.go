@@ -1,7 +1,7 @@
 // Package main is the entry point for the audit-logger microservice.
 // It reads configuration from environment variables, sets up the NATS subscriber,
-// batcher and PostgreSQL persistence layer, and exposes a small HTTP API for
-// querying execution history from the Designer frontend.
+// batcher and the PostgreSQL and/or OpenSearch persistence layers, and exposes a
+// small HTTP API for querying execution history from the Designer frontend.
 package main
 
 import (
@@ -31,16 +31,23 @@ func main() {
 		"host=localhost port=5432 user=admin password=flowjs_pass dbname=flowjs_audit sslmode=disable")
 	httpAddr := envOrDefault("HTTP_ADDR", ":8080")
 
-	// Connect to PostgreSQL.
-	dbClient, err := db.New(pgDSN)
+	// Connect to the persistence backends selected by AUDIT_SINKS.
+	sinkNames, err := sinksFromEnv()
+	if err != nil {
+		log.Fatalf("audit-logger: %v", err)
+	}
+	orphans, err := db.OrphanConfigFromEnv()
+	if err != nil {
+		log.Fatalf("audit-logger: %v", err)
+	}
+	sinks, dbClient, err := openSinks(sinkNames, pgDSN)
 	if err != nil {
 		log.Fatalf("audit-logger: %v", err)
 	}
 
-	// Create batcher that persists via dbClient.
+	// Create batcher that persists to every sink.
 	b := batcher.New(batcher.DefaultMaxBatchSize, batcher.DefaultFlushInterval, func(events []batcher.AuditEvent) error {
-		if err := dbClient.BatchInsertLogs(events); err != nil {
-			log.Printf("audit-logger: batch insert failed: %v", err)
+		if err := writeSinks(sinks, events); err != nil {
 			return err
 		}
 		log.Printf("audit-logger: persisted batch of %d events", len(events))
@@ -51,48 +58,69 @@ func main() {
 	// All defers are registered *after* every log.Fatalf call so that gocritic
 	// exitAfterDefer is not triggered (os.Exit skips deferred functions).
 	// Resources created before a fatal path are closed explicitly on that path.
-	sub, err := subscriber.New(natsURL, b)
+	// Malformed messages are kept in PostgreSQL when it is a sink.
+	var deadLetter subscriber.DeadLetterFunc
+	if dbClient != nil {
+		deadLetter = dbClient.InsertDeadLetter
+	}
+	sub, err := subscriber.New(natsURL, b, deadLetter)
 	if err != nil {
 		b.Stop()
-		dbClient.Close()
+		closeSinks(sinks)
 		log.Fatalf("audit-logger: could not connect to NATS: %v", err)
 	}
 	if err := sub.Start(); err != nil {
 		b.Stop()
-		dbClient.Close()
+		closeSinks(sinks)
 		log.Fatalf("audit-logger: could not subscribe to NATS: %v", err)
 	}
-	// HTTP API for the Designer frontend.
-	rawDB, err := sql.Open("postgres", pgDSN)
-	if err != nil {
-		sub.Stop()
-		b.Stop()
-		dbClient.Close()
-		log.Fatalf("audit-logger: open raw db for http: %v", err)
+	// HTTP API for the Designer frontend. It queries PostgreSQL, so without
+	// the postgres sink only /health is served.
+	var rawDB *sql.DB
+	if dbClient != nil {
+		rawDB, err = sql.Open("postgres", pgDSN)
+		if err != nil {
+			sub.Stop()
+			b.Stop()
+			closeSinks(sinks)
+			log.Fatalf("audit-logger: open raw db for http: %v", err)
+		}
 	}
 	defer sub.Stop()
 	defer b.Stop()
-	defer dbClient.Close()
+	defer closeSinks(sinks)
 	defer func() {
+		if rawDB == nil {
+			return
+		}
 		if err := rawDB.Close(); err != nil {
 			log.Printf("audit-logger: close raw db: %v", err)
 		}
 	}()
+	// Executions left STARTED by an engine that stopped are marked ORPHANED.
+	if rawDB != nil && orphans.Timeout > 0 {
+		stopWatchdog := make(chan struct{})
+		go runOrphanWatchdog(rawDB, orphans, stopWatchdog)
+		defer close(stopWatchdog)
+	}
 
 	mux := http.NewServeMux()
-	registerRoutes(mux, rawDB)
+	registerRoutes(mux, rawDB, sub)
 
 	// Security middleware chain (OWASP hardening — ADR 0002):
 	//   RequestLogger  → A09 audit trail
 	//   RateLimiter    → A04 brute-force / DoS protection
-	//   CORS           → A05 restrictive origin policy
+	//   CORS           → A05 restrictive origin policy (ALLOWED_ORIGINS, CORS_*)
 	//   SecurityHeaders → A02/A05 HSTS + defensive headers
+	//   Authenticate   → A01 API-key gate; scopes queries to the caller's workspace
 	rateLimiter := middleware.NewRateLimiter()
 	defer rateLimiter.Stop()
-	allowedOrigins := middleware.AllowedOrigins()
+	corsConfig := middleware.CORSConfigFromEnv()
+	apiKeys := middleware.APIKeys()
 
 	var handler http.Handler = mux
-	handler = middleware.CORS(allowedOrigins)(handler)
+	handler = middleware.Authenticate(apiKeys, "/health")(handler)
+	handler = middleware.CORSWithConfig(corsConfig)(handler)
 	handler = rateLimiter.Middleware(handler)
 	handler = middleware.SecurityHeaders(handler)
 	handler = middleware.RequestLogger(handler)
@@ -124,10 +152,22 @@ func main() {
 
 // registerRoutes wires all HTTP handlers onto mux. Each handler is extracted
 // into its own function to keep cyclomatic complexity below the project limit.
-func registerRoutes(mux *http.ServeMux, rawDB *sql.DB) {
+// A nil rawDB (postgres is not an audit sink) leaves the execution history
+// and dead letter APIs unavailable.
+func registerRoutes(mux *http.ServeMux, rawDB *sql.DB, sub *subscriber.Subscriber) {
 	mux.HandleFunc("/health", healthHandler(rawDB))
+	mux.HandleFunc("/metrics", metricsHandler(sub))
+	mux.HandleFunc("/admin/dead-letters", deadLettersHandler(rawDB, sub))
+	mux.HandleFunc("/admin/dead-letters/", deadLettersHandler(rawDB, sub))
+	if rawDB == nil {
+		mux.HandleFunc("/executions", historyUnavailableHandler)
+		mux.HandleFunc("/executions/", historyUnavailableHandler)
+		mux.HandleFunc("/nodes/", historyUnavailableHandler)
+		return
+	}
 	mux.HandleFunc("/executions", listExecutionsHandler(rawDB))
 	mux.HandleFunc("/executions/", executionDetailHandler(rawDB))
+	mux.HandleFunc("/nodes/", nodeHistoryHandler(rawDB))
 }
 
 // healthHandler returns a liveness-probe handler.
@@ -137,6 +177,10 @@ func healthHandler(rawDB *sql.DB) http.HandlerFunc {
 			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
+		if rawDB == nil {
+			jsonOK(w, map[string]string{"status": "ok", "service": "audit-logger"})
+			return
+		}
 		if err := rawDB.Ping(); err != nil {
 			log.Printf("audit-logger: health check db ping: %v", err)
 			jsonError(w, middleware.SanitizeError(err, "database unreachable"), http.StatusServiceUnavailable)
@@ -146,6 +190,12 @@ func healthHandler(rawDB *sql.DB) http.HandlerFunc {
 	}
 }
 
+// historyUnavailableHandler answers the execution history API when audit
+// events are not stored in PostgreSQL.
+func historyUnavailableHandler(w http.ResponseWriter, _ *http.Request) {
+	jsonError(w, "execution history is not stored in PostgreSQL (AUDIT_SINKS); query the OpenSearch indexes instead", http.StatusNotImplemented)
+}
+
 // parsePagination reads ?limit and ?offset from the query string and applies
 // safe bounds (max 200 for limit, non-negative for offset).
 func parsePagination(q map[string][]string) (limit, offset int) {
@@ -167,10 +217,12 @@ func parsePagination(q map[string][]string) (limit, offset int) {
 }
 
 // buildWhereClause constructs the SQL WHERE fragment and positional args for the
-// optional status and full-text search filters.
-func buildWhereClause(statusFilter, searchFilter string) (string, []interface{}) {
-	var parts []string
-	var args []interface{}
+// mandatory workspace scope plus the optional status and full-text search filters.
+// The search does not look into payloads stored compressed or as fingerprints
+// (AUDIT_PAYLOAD_*).
+func buildWhereClause(workspace, statusFilter, searchFilter string) (string, []interface{}) {
+	args := []interface{}{workspace}
+	parts := []string{"e.workspace = $1"}
 
 	if statusFilter != "" {
 		args = append(args, statusFilter)
@@ -186,14 +238,12 @@ func buildWhereClause(statusFilter, searchFilter string) (string, []interface{})
 		))
 	}
 
-	if len(parts) == 0 {
-		return "", args
-	}
 	return "WHERE " + strings.Join(parts, " AND "), args
 }
 
 // listExecutionsHandler returns a handler that lists execution headers with
-// optional filtering and pagination.
+// optional filtering and pagination. ?status is case-insensitive, e.g.
+// ?status=orphaned lists the executions the orphan watchdog gave up on.
 func listExecutionsHandler(rawDB *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
@@ -203,7 +253,7 @@ func listExecutionsHandler(rawDB *sql.DB) http.HandlerFunc {
 
 		q := r.URL.Query()
 		limit, offset := parsePagination(q)
-		whereSQL, args := buildWhereClause(q.Get("status"), q.Get("search"))
+		whereSQL, args := buildWhereClause(middleware.WorkspaceFromContext(r.Context()), strings.ToUpper(q.Get("status")), q.Get("search"))
 
 		// Total matching count for X-Total-Count header.
 		var total int
@@ -221,7 +271,8 @@ func listExecutionsHandler(rawDB *sql.DB) http.HandlerFunc {
 		dataQuery := fmt.Sprintf(`
 			SELECT e.execution_id, e.flow_id, COALESCE(e.version,''), e.status,
 			       COALESCE(e.correlation_id,''), e.start_time,
-			       COALESCE(e.trigger_type,''), COALESCE(e.main_error_message,'')
+			       COALESCE(e.trigger_type,''), COALESCE(e.main_error_message,''),
+			       e.execution_stats
 			FROM executions e
 			%s
 			ORDER BY e.start_time DESC
@@ -248,20 +299,26 @@ func listExecutionsHandler(rawDB *sql.DB) http.HandlerFunc {
 			StartTime        string `json:"start_time"`
 			TriggerType      string `json:"trigger_type"`
 			MainErrorMessage string `json:"main_error_message"`
+			// ExecutionStats is the resource usage reported by the engine
+			// when the execution finished.
+			ExecutionStats json.RawMessage `json:"execution_stats,omitempty"`
 		}
 		var results []ExecutionRow
 		for rows.Next() {
 			var exec ExecutionRow
 			var startTime time.Time
+			var stats []byte
 			if err := rows.Scan(
 				&exec.ExecutionID, &exec.FlowID, &exec.Version, &exec.Status,
 				&exec.CorrelationID, &startTime, &exec.TriggerType, &exec.MainErrorMessage,
+				&stats,
 			); err != nil {
 				log.Printf("audit-logger: scan execution row: %v", err)
 				jsonError(w, middleware.SanitizeError(err, "failed to read execution data"), http.StatusInternalServerError)
 				return
 			}
 			exec.StartTime = startTime.Format(time.RFC3339)
+			exec.ExecutionStats = stats
 			results = append(results, exec)
 		}
 		if results == nil {
@@ -273,7 +330,9 @@ func listExecutionsHandler(rawDB *sql.DB) http.HandlerFunc {
 	}
 }
 
-// executionDetailHandler handles /executions/{id}/logs and /executions/{id}/trigger-data.
+// executionDetailHandler handles /executions/{id}, /executions/{id}/logs,
+// /executions/{id}/logs/export, /executions/{id}/timeline,
+// /executions/{id}/trigger-data and /executions/{id}/tree.
 func executionDetailHandler(rawDB *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
@@ -288,7 +347,6 @@ func executionDetailHandler(rawDB *sql.DB) http.HandlerFunc {
 			subResource = strings.ToLower(rest[idx+1:])
 		} else {
 			executionID = rest
-			subResource = "logs"
 		}
 		if executionID == "" {
 			http.Error(w, "missing execution_id", http.StatusBadRequest)
@@ -296,102 +354,191 @@ func executionDetailHandler(rawDB *sql.DB) http.HandlerFunc {
 		}
 
 		switch subResource {
-		case "logs", "":
+		case "":
+			serveExecutionDetail(w, r, rawDB, executionID)
+		case "logs":
 			serveExecutionLogs(w, r, rawDB, executionID)
+		case "logs/export":
+			serveExecutionLogsExport(w, r, rawDB, executionID)
+		case "timeline":
+			serveExecutionTimeline(w, r, rawDB, executionID)
 		case "trigger-data":
 			serveExecutionTriggerData(w, r, rawDB, executionID)
+		case "tree":
+			serveExecutionTree(w, r, rawDB, executionID)
 		default:
 			jsonError(w, fmt.Sprintf("unknown sub-resource: %q", subResource), http.StatusNotFound)
 		}
 	}
 }
 
-// serveExecutionLogs writes the activity-log rows for a given execution.
+// serveExecutionDetail writes the header of an execution of the caller's
+// workspace with its node counts by status, duration and first error node.
+func serveExecutionDetail(w http.ResponseWriter, r *http.Request, rawDB *sql.DB, executionID string) {
+	detail, err := db.GetExecutionDetail(r.Context(), rawDB, middleware.WorkspaceFromContext(r.Context()), executionID)
+	if err != nil {
+		log.Printf("audit-logger: query execution %q: %v", executionID, err)
+		jsonError(w, middleware.SanitizeError(err, "failed to query execution"), http.StatusInternalServerError)
+		return
+	}
+	if detail == nil {
+		jsonError(w, "execution not found: "+executionID, http.StatusNotFound)
+		return
+	}
+	jsonOK(w, detail)
+}
+
+// serveExecutionTimeline writes the events of an execution of the caller's
+// workspace in order, each with its start, end, duration and offset from the
+// execution start.
+func serveExecutionTimeline(w http.ResponseWriter, r *http.Request, rawDB *sql.DB, executionID string) {
+	events, err := db.ExecutionTimeline(r.Context(), rawDB, middleware.WorkspaceFromContext(r.Context()), executionID)
+	if err != nil {
+		log.Printf("audit-logger: query execution timeline for %q: %v", executionID, err)
+		jsonError(w, middleware.SanitizeError(err, "failed to query execution timeline"), http.StatusInternalServerError)
+		return
+	}
+	if events == nil {
+		events = []db.TimelineEvent{}
+	}
+	jsonOK(w, events)
+}
+
+// serveExecutionLogs writes the activity-log rows for a given execution of the
+// caller's workspace.
 func serveExecutionLogs(w http.ResponseWriter, r *http.Request, rawDB *sql.DB, executionID string) {
-	rows, err := rawDB.QueryContext(r.Context(), `
-		SELECT log_id, node_id, COALESCE(node_type,''), status,
-		       input_data, output_data, error_details,
-		       COALESCE(duration_ms,0), created_at
-		FROM activity_logs
-		WHERE execution_id = $1
-		ORDER BY created_at ASC`, executionID)
+	logs, err := db.ExecutionLogs(r.Context(), rawDB, middleware.WorkspaceFromContext(r.Context()), executionID)
 	if err != nil {
 		log.Printf("audit-logger: query activity_logs for %q: %v", executionID, err)
 		jsonError(w, middleware.SanitizeError(err, "failed to query activity logs"), http.StatusInternalServerError)
 		return
 	}
-	defer func() {
-		if err := rows.Close(); err != nil {
-			log.Printf("audit-logger: close activity_logs rows: %v", err)
-		}
-	}()
+	if logs == nil {
+		logs = []db.LogEntry{}
+	}
+	jsonOK(w, logs)
+}
 
-	type LogRow struct {
-		LogID        int64           `json:"log_id"`
-		NodeID       string          `json:"node_id"`
-		NodeType     string          `json:"node_type"`
-		Status       string          `json:"status"`
-		InputData    json.RawMessage `json:"input_data"`
-		OutputData   json.RawMessage `json:"output_data"`
-		ErrorDetails json.RawMessage `json:"error_details"`
-		DurationMs   int             `json:"duration_ms"`
-		CreatedAt    string          `json:"created_at"`
-	}
-	var results []LogRow
-	for rows.Next() {
-		var lr LogRow
-		var inputRaw, outputRaw, errorRaw []byte
-		var createdAt time.Time
-		if err := rows.Scan(
-			&lr.LogID, &lr.NodeID, &lr.NodeType, &lr.Status,
-			&inputRaw, &outputRaw, &errorRaw, &lr.DurationMs, &createdAt,
-		); err != nil {
-			log.Printf("audit-logger: scan activity_log row: %v", err)
-			jsonError(w, middleware.SanitizeError(err, "failed to read log data"), http.StatusInternalServerError)
-			return
-		}
-		lr.CreatedAt = createdAt.Format(time.RFC3339)
-		lr.InputData = nullableJSON(inputRaw)
-		lr.OutputData = nullableJSON(outputRaw)
-		lr.ErrorDetails = nullableJSON(errorRaw)
-		results = append(results, lr)
+// exportContentTypes maps the export formats to their Content-Type.
+var exportContentTypes = map[string]string{
+	db.ExportCSV:    "text/csv; charset=utf-8",
+	db.ExportNDJSON: "application/x-ndjson",
+	db.ExportZip:    "application/zip",
+}
+
+// serveExecutionLogsExport writes every node input, output and error of an
+// execution of the caller's workspace as a downloadable file:
+// ?format=ndjson (default), csv, or zip with the execution detail and both.
+func serveExecutionLogsExport(w http.ResponseWriter, r *http.Request, rawDB *sql.DB, executionID string) {
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	if format == "" {
+		format = db.ExportNDJSON
 	}
-	if results == nil {
-		results = []LogRow{}
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		jsonError(w, fmt.Sprintf("format must be one of %s, %s, %s", db.ExportCSV, db.ExportNDJSON, db.ExportZip), http.StatusBadRequest)
+		return
 	}
-	jsonOK(w, results)
-}
 
-// serveExecutionTriggerData writes the original trigger payload for a given execution.
-func serveExecutionTriggerData(w http.ResponseWriter, r *http.Request, rawDB *sql.DB, executionID string) {
-	var inputRaw []byte
-	err := rawDB.QueryRowContext(r.Context(), `
-		SELECT COALESCE(input_data->'trigger', '{}')
-		FROM activity_logs
-		WHERE execution_id = $1
-		  AND node_type = 'process'
-		  AND status = 'STARTED'
-		ORDER BY created_at ASC
-		LIMIT 1`, executionID).Scan(&inputRaw)
-	if err == sql.ErrNoRows {
-		jsonError(w, "trigger data not found for execution "+executionID, http.StatusNotFound)
+	workspace := middleware.WorkspaceFromContext(r.Context())
+	detail, err := db.GetExecutionDetail(r.Context(), rawDB, workspace, executionID)
+	if err != nil {
+		log.Printf("audit-logger: query execution %q: %v", executionID, err)
+		jsonError(w, middleware.SanitizeError(err, "failed to query execution"), http.StatusInternalServerError)
+		return
+	}
+	if detail == nil {
+		jsonError(w, "execution not found: "+executionID, http.StatusNotFound)
+		return
+	}
+	logs, err := db.ExecutionLogs(r.Context(), rawDB, workspace, executionID)
+	if err != nil {
+		log.Printf("audit-logger: query activity_logs for %q: %v", executionID, err)
+		jsonError(w, middleware.SanitizeError(err, "failed to query activity logs"), http.StatusInternalServerError)
 		return
 	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="execution-%s.%s"`, executionID, format))
+	switch format {
+	case db.ExportCSV:
+		err = db.WriteLogsCSV(w, logs)
+	case db.ExportNDJSON:
+		err = db.WriteLogsNDJSON(w, logs)
+	case db.ExportZip:
+		err = db.WriteExportZip(w, detail, logs)
+	}
+	if err != nil {
+		log.Printf("audit-logger: write %s export of %q: %v", format, executionID, err)
+	}
+}
+
+// serveExecutionTriggerData writes the original trigger payload for a given
+// execution of the caller's workspace.
+func serveExecutionTriggerData(w http.ResponseWriter, r *http.Request, rawDB *sql.DB, executionID string) {
+	payload, err := db.ExecutionTriggerData(r.Context(), rawDB, middleware.WorkspaceFromContext(r.Context()), executionID)
 	if err != nil {
 		log.Printf("audit-logger: query trigger data for %q: %v", executionID, err)
 		jsonError(w, middleware.SanitizeError(err, "failed to query trigger data"), http.StatusInternalServerError)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	payload := inputRaw
-	if len(payload) == 0 {
-		payload = []byte("{}")
+	if payload == nil {
+		jsonError(w, "trigger data not found for execution "+executionID, http.StatusNotFound)
+		return
 	}
+	w.Header().Set("Content-Type", "application/json")
 	if _, writeErr := w.Write(payload); writeErr != nil {
 		log.Printf("audit-logger: write trigger-data response: %v", writeErr)
 	}
 }
 
+// serveExecutionTree writes the retry/replay hierarchy that an execution of
+// the caller's workspace belongs to, from its root execution down.
+func serveExecutionTree(w http.ResponseWriter, r *http.Request, rawDB *sql.DB, executionID string) {
+	tree, err := db.ExecutionTree(r.Context(), rawDB, middleware.WorkspaceFromContext(r.Context()), executionID)
+	if err != nil {
+		log.Printf("audit-logger: query execution tree for %q: %v", executionID, err)
+		jsonError(w, middleware.SanitizeError(err, "failed to query execution tree"), http.StatusInternalServerError)
+		return
+	}
+	if tree == nil {
+		jsonError(w, "execution not found: "+executionID, http.StatusNotFound)
+		return
+	}
+	jsonOK(w, tree)
+}
+
+// nodeHistoryHandler handles /nodes/{nodeId}/history?flow_id=: the latest
+// runs of a node of a flow of the caller's workspace, newest first, with
+// their status, duration and error (?limit, default 50, max 200; ?offset).
+func nodeHistoryHandler(rawDB *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		nodeID, sub, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/nodes/"), "/")
+		if nodeID == "" || sub != "history" {
+			jsonError(w, "not found; use /nodes/{nodeId}/history", http.StatusNotFound)
+			return
+		}
+		q := r.URL.Query()
+		flowID := q.Get("flow_id")
+		if flowID == "" {
+			jsonError(w, "flow_id is required", http.StatusBadRequest)
+			return
+		}
+		limit, offset := parsePagination(q)
+		history, err := db.GetNodeHistory(r.Context(), rawDB, middleware.WorkspaceFromContext(r.Context()), flowID, nodeID, limit, offset)
+		if err != nil {
+			log.Printf("audit-logger: query history of node %q of %q: %v", nodeID, flowID, err)
+			jsonError(w, middleware.SanitizeError(err, "failed to query node history"), http.StatusInternalServerError)
+			return
+		}
+		jsonOK(w, history)
+	}
+}
+
 // ---------------------------------------------------------------------------
 // Helpers
 // ---------------------------------------------------------------------------
@@ -407,14 +554,6 @@ func jsonError(w http.ResponseWriter, msg string, code int) {
 	_ = json.NewEncoder(w).Encode(map[string]string{"error": msg})
 }
 
-// nullableJSON returns a null JSON token when the raw bytes are nil or empty.
-func nullableJSON(b []byte) json.RawMessage {
-	if len(b) == 0 {
-		return json.RawMessage("null")
-	}
-	return json.RawMessage(b)
-}
-
 func envOrDefault(key, def string) string {
 	if v := os.Getenv(key); v != "" {
 		return v
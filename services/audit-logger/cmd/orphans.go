@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"flowjs-works/audit-logger/internal/db"
+)
+
+// runOrphanWatchdog marks orphaned executions (see db.MarkOrphanedExecutions)
+// every cfg.Interval until stop is closed.
+func runOrphanWatchdog(rawDB *sql.DB, cfg db.OrphanConfig, stop <-chan struct{}) {
+	log.Printf("audit-logger: marking executions without events for %s as %s (checked every %s)",
+		cfg.Timeout, db.StatusOrphaned, cfg.Interval)
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			markOrphans(rawDB, cfg)
+		case <-stop:
+			return
+		}
+	}
+}
+
+// markOrphans runs one pass of the orphan watchdog.
+func markOrphans(rawDB *sql.DB, cfg db.OrphanConfig) {
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Interval)
+	defer cancel()
+	n, err := db.MarkOrphanedExecutions(ctx, rawDB, cfg.Timeout)
+	if err != nil {
+		log.Printf("audit-logger: orphan watchdog: %v", err)
+		return
+	}
+	if n > 0 {
+		log.Printf("audit-logger: marked %d execution(s) as %s", n, db.StatusOrphaned)
+	}
+}
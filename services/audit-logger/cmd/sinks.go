@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"flowjs-works/audit-logger/internal/batcher"
+	"flowjs-works/audit-logger/internal/db"
+	"flowjs-works/audit-logger/internal/opensearch"
+)
+
+// Persistence backends selectable with AUDIT_SINKS.
+const (
+	sinkPostgres   = "postgres"
+	sinkOpenSearch = "opensearch"
+)
+
+// sink is one persistence backend of the audit batches.
+type sink struct {
+	name  string
+	write func([]batcher.AuditEvent) error
+	close func()
+}
+
+// sinksFromEnv parses AUDIT_SINKS, a comma-separated list of postgres and
+// opensearch ("elasticsearch" is an alias). It defaults to postgres.
+func sinksFromEnv() (map[string]bool, error) {
+	raw := strings.TrimSpace(os.Getenv("AUDIT_SINKS"))
+	if raw == "" {
+		return map[string]bool{sinkPostgres: true}, nil
+	}
+	names := make(map[string]bool)
+	for _, part := range strings.Split(raw, ",") {
+		switch name := strings.ToLower(strings.TrimSpace(part)); name {
+		case "":
+		case sinkPostgres, sinkOpenSearch:
+			names[name] = true
+		case "elasticsearch":
+			names[sinkOpenSearch] = true
+		default:
+			return nil, fmt.Errorf("unknown AUDIT_SINKS entry %q (want postgres, opensearch or elasticsearch)", name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, errors.New("AUDIT_SINKS lists no sink")
+	}
+	return names, nil
+}
+
+// openSinks connects the selected backends. The PostgreSQL client is also
+// returned (nil when postgres is not selected) because the HTTP query API
+// reads from it.
+func openSinks(names map[string]bool, pgDSN string) ([]sink, *db.Client, error) {
+	var (
+		sinks    []sink
+		dbClient *db.Client
+	)
+	if names[sinkPostgres] {
+		payload, err := db.PayloadConfigFromEnv()
+		if err != nil {
+			return nil, nil, err
+		}
+		client, err := db.New(pgDSN, payload)
+		if err != nil {
+			return nil, nil, err
+		}
+		if payload.Compression != db.CompressionNone || payload.FingerprintAboveBytes > 0 {
+			log.Printf("audit-logger: storing node payloads with compression %s from %d bytes, fingerprints above %d bytes (0 = never)",
+				payload.Compression, payload.CompressMinBytes, payload.FingerprintAboveBytes)
+		}
+		dbClient = client
+		sinks = append(sinks, sink{name: sinkPostgres, write: client.BatchInsertLogs, close: client.Close})
+	}
+	if names[sinkOpenSearch] {
+		cfg := opensearch.ConfigFromEnv()
+		client, err := opensearch.New(cfg)
+		if err != nil {
+			closeSinks(sinks)
+			return nil, nil, err
+		}
+		log.Printf("audit-logger: writing audit events to OpenSearch at %s (index %s)", cfg.URL, client.IndexName(time.Now()))
+		sinks = append(sinks, sink{name: sinkOpenSearch, write: client.BatchIndexLogs, close: func() {}})
+	}
+	return sinks, dbClient, nil
+}
+
+// writeSinks persists events to every sink. A failing sink does not keep the
+// batch from the others; the failures are joined.
+func writeSinks(sinks []sink, events []batcher.AuditEvent) error {
+	var errs []error
+	for _, s := range sinks {
+		if err := s.write(events); err != nil {
+			log.Printf("audit-logger: %s batch insert failed: %v", s.name, err)
+			errs = append(errs, fmt.Errorf("%s: %w", s.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// closeSinks releases the connections of sinks.
+func closeSinks(sinks []sink) {
+	for _, s := range sinks {
+		s.close()
+	}
+}
@@ -19,6 +19,7 @@ const (
 // AuditEvent represents a single audit log entry received from NATS.
 type AuditEvent struct {
 	ExecutionID string                 `json:"execution_id"`
+	Workspace   string                 `json:"workspace"`
 	FlowID      string                 `json:"flow_id"`
 	NodeID      string                 `json:"node_id"`
 	NodeType    string                 `json:"node_type"`
@@ -28,6 +29,19 @@ type AuditEvent struct {
 	ErrorMsg    string                 `json:"error"`
 	DurationMs  int                    `json:"duration_ms"`
 	Timestamp   string                 `json:"timestamp"`
+	// ParentExecutionID is the execution this one retries or replays; empty
+	// for trigger-fired runs.
+	ParentExecutionID string `json:"parent_execution_id"`
+	// ExecutionStats is the resource usage carried by terminal process
+	// events (wall_ms, cpu_ms, bytes_transferred, rows_processed, nodes).
+	ExecutionStats map[string]interface{} `json:"execution_stats,omitempty"`
+	// EngineID is the engine instance that published the event and
+	// ProcessVersion the definition.version of the process it ran.
+	EngineID       string `json:"engine_id,omitempty"`
+	ProcessVersion string `json:"process_version,omitempty"`
+	// Attempt is the retry_policy attempt a node run ended on; zero for
+	// events that are not node runs.
+	Attempt int `json:"attempt,omitempty"`
 }
 
 // FlushFunc is called with a batch of events to be persisted.
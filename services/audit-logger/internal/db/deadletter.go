@@ -0,0 +1,93 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DeadLetter is an audit message that could not be parsed, kept in
+// audit_dead_letters until it is re-ingested or deleted.
+type DeadLetter struct {
+	ID         int64     `json:"id"`
+	Subject    string    `json:"subject"`
+	Payload    string    `json:"payload"`
+	Error      string    `json:"error"`
+	ReceivedAt time.Time `json:"received_at"`
+}
+
+// InsertDeadLetter stores payload, received on subject, with the reason it
+// could not be ingested.
+func (c *Client) InsertDeadLetter(subject string, payload []byte, reason string) error {
+	_, err := c.db.Exec(`
+		INSERT INTO audit_dead_letters (subject, payload, error, received_at)
+		VALUES ($1, $2, $3, NOW())`, subject, payload, reason)
+	if err != nil {
+		return fmt.Errorf("insert dead letter: %w", err)
+	}
+	return nil
+}
+
+// ListDeadLetters returns a page of the dead letters, oldest first, with
+// their total count.
+func ListDeadLetters(ctx context.Context, rawDB *sql.DB, limit, offset int) ([]DeadLetter, int, error) {
+	var total int
+	if err := rawDB.QueryRowContext(ctx, `SELECT COUNT(*) FROM audit_dead_letters`).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("count dead letters: %w", err)
+	}
+	rows, err := rawDB.QueryContext(ctx, `
+		SELECT id, subject, payload, error, received_at FROM audit_dead_letters
+		ORDER BY id LIMIT $1 OFFSET $2`, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("query dead letters: %w", err)
+	}
+	defer rows.Close()
+
+	var letters []DeadLetter
+	for rows.Next() {
+		var (
+			d       DeadLetter
+			payload []byte
+		)
+		if err := rows.Scan(&d.ID, &d.Subject, &payload, &d.Error, &d.ReceivedAt); err != nil {
+			return nil, 0, fmt.Errorf("scan dead letter: %w", err)
+		}
+		d.Payload = string(payload)
+		letters = append(letters, d)
+	}
+	return letters, total, rows.Err()
+}
+
+// GetDeadLetter returns dead letter id, or nil when it does not exist.
+func GetDeadLetter(ctx context.Context, rawDB *sql.DB, id int64) (*DeadLetter, error) {
+	var (
+		d       DeadLetter
+		payload []byte
+	)
+	err := rawDB.QueryRowContext(ctx, `
+		SELECT id, subject, payload, error, received_at FROM audit_dead_letters WHERE id = $1`,
+		id).Scan(&d.ID, &d.Subject, &payload, &d.Error, &d.ReceivedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query dead letter %d: %w", id, err)
+	}
+	d.Payload = string(payload)
+	return &d, nil
+}
+
+// DeleteDeadLetter removes dead letter id, reporting whether it existed.
+func DeleteDeadLetter(ctx context.Context, rawDB *sql.DB, id int64) (bool, error) {
+	res, err := rawDB.ExecContext(ctx, `DELETE FROM audit_dead_letters WHERE id = $1`, id)
+	if err != nil {
+		return false, fmt.Errorf("delete dead letter %d: %w", id, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("delete dead letter %d: %w", id, err)
+	}
+	return n > 0, nil
+}
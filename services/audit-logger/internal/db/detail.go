@@ -0,0 +1,181 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// maxTimelineEvents bounds the events returned by ExecutionTimeline, so a
+// looping flow cannot produce an unbounded response.
+const maxTimelineEvents = 5000
+
+// ExecutionDetail is the header of one execution with what a run detail view
+// shows at a glance: node counts by status, duration and first failing node.
+type ExecutionDetail struct {
+	ExecutionID       string     `json:"execution_id"`
+	ParentExecutionID string     `json:"parent_execution_id,omitempty"`
+	FlowID            string     `json:"flow_id"`
+	Version           string     `json:"version"`
+	Status            string     `json:"status"`
+	CorrelationID     string     `json:"correlation_id"`
+	TriggerType       string     `json:"trigger_type"`
+	StartTime         time.Time  `json:"start_time"`
+	EndTime           *time.Time `json:"end_time,omitempty"`
+	// DurationMs is the wall time of a finished execution: the execution
+	// stats when reported, otherwise end_time minus start_time.
+	DurationMs *int64 `json:"duration_ms,omitempty"`
+	Error      string `json:"main_error_message,omitempty"`
+	// NodeCounts counts the node runs by status (SUCCESS, ERROR, ...).
+	NodeCounts map[string]int `json:"node_counts"`
+	// FirstError is the earliest node run that ended in error, if any.
+	FirstError     *TimelineEvent  `json:"first_error,omitempty"`
+	ExecutionStats json.RawMessage `json:"execution_stats,omitempty"`
+}
+
+// TimelineEvent is one event of an execution timeline. Node events are
+// recorded when the run ends; StartedAt is derived from their duration.
+type TimelineEvent struct {
+	LogID      int64     `json:"log_id"`
+	NodeID     string    `json:"node_id"`
+	NodeType   string    `json:"node_type"`
+	Status     string    `json:"status"`
+	StartedAt  time.Time `json:"started_at"`
+	EndedAt    time.Time `json:"ended_at"`
+	DurationMs int64     `json:"duration_ms"`
+	// OffsetMs is when the event started, relative to the execution start.
+	OffsetMs int64  `json:"offset_ms"`
+	Error    string `json:"error,omitempty"`
+	// Attempt is the retry_policy attempt a node run ended on and EngineID
+	// the engine instance that ran it, when recorded.
+	Attempt  int    `json:"attempt,omitempty"`
+	EngineID string `json:"engine_id,omitempty"`
+}
+
+// GetExecutionDetail returns the detail of executionID in workspace, or nil
+// when it does not exist there.
+func GetExecutionDetail(ctx context.Context, rawDB *sql.DB, workspace, executionID string) (*ExecutionDetail, error) {
+	d := &ExecutionDetail{ExecutionID: executionID}
+	var (
+		endTime sql.NullTime
+		stats   []byte
+	)
+	err := rawDB.QueryRowContext(ctx, `
+		SELECT COALESCE(parent_execution_id::text, ''), flow_id, COALESCE(version, ''), COALESCE(status, ''),
+		       COALESCE(correlation_id, ''), COALESCE(trigger_type, ''), start_time, end_time,
+		       COALESCE(main_error_message, ''), execution_stats
+		FROM executions WHERE execution_id = $1 AND workspace = $2`,
+		executionID, workspace).Scan(&d.ParentExecutionID, &d.FlowID, &d.Version, &d.Status,
+		&d.CorrelationID, &d.TriggerType, &d.StartTime, &endTime, &d.Error, &stats)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query execution: %w", err)
+	}
+	if endTime.Valid {
+		d.EndTime = &endTime.Time
+	}
+	if len(stats) > 0 {
+		d.ExecutionStats = stats
+	}
+	d.DurationMs = executionDuration(d.StartTime, d.EndTime, stats)
+
+	events, err := ExecutionTimeline(ctx, rawDB, workspace, executionID)
+	if err != nil {
+		return nil, err
+	}
+	d.NodeCounts, d.FirstError = SummarizeTimeline(events)
+	return d, nil
+}
+
+// ExecutionTimeline returns the node events of executionID in workspace in
+// the order they happened. It returns nil when the execution has no events.
+func ExecutionTimeline(ctx context.Context, rawDB *sql.DB, workspace, executionID string) ([]TimelineEvent, error) {
+	rows, err := rawDB.QueryContext(ctx, `
+		SELECT al.log_id, al.node_id, COALESCE(al.node_type, ''), COALESCE(al.status, ''),
+		       COALESCE(al.duration_ms, 0), al.created_at, COALESCE(al.error_details->>'message', ''),
+		       COALESCE(al.attempt, 0), COALESCE(al.engine_id, '')
+		FROM activity_logs al
+		JOIN executions e ON e.execution_id = al.execution_id
+		WHERE al.execution_id = $1 AND e.workspace = $2
+		ORDER BY al.created_at ASC, al.log_id ASC
+		LIMIT $3`, executionID, workspace, maxTimelineEvents)
+	if err != nil {
+		return nil, fmt.Errorf("query execution timeline: %w", err)
+	}
+	defer rows.Close()
+
+	var events []TimelineEvent
+	for rows.Next() {
+		var ev TimelineEvent
+		if err := rows.Scan(&ev.LogID, &ev.NodeID, &ev.NodeType, &ev.Status, &ev.DurationMs, &ev.EndedAt, &ev.Error, &ev.Attempt, &ev.EngineID); err != nil {
+			return nil, fmt.Errorf("scan execution timeline row: %w", err)
+		}
+		events = append(events, ev)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read execution timeline: %w", err)
+	}
+	return BuildTimeline(events), nil
+}
+
+// BuildTimeline fills in StartedAt and OffsetMs of events, which are ordered
+// by EndedAt. The execution starts with its first event (the process STARTED
+// event), or with the earliest node start when that is missing.
+func BuildTimeline(events []TimelineEvent) []TimelineEvent {
+	if len(events) == 0 {
+		return events
+	}
+	start := events[0].EndedAt
+	for i := range events {
+		ev := &events[i]
+		ev.StartedAt = ev.EndedAt.Add(-time.Duration(ev.DurationMs) * time.Millisecond)
+		if ev.StartedAt.Before(start) {
+			start = ev.StartedAt
+		}
+	}
+	for i := range events {
+		events[i].OffsetMs = events[i].StartedAt.Sub(start).Milliseconds()
+	}
+	return events
+}
+
+// SummarizeTimeline counts the node runs of events by status and returns the
+// first one that ended in error. Process and lifecycle events are not node
+// runs.
+func SummarizeTimeline(events []TimelineEvent) (map[string]int, *TimelineEvent) {
+	counts := map[string]int{}
+	var firstError *TimelineEvent
+	for i := range events {
+		ev := events[i]
+		if ev.NodeType == "process" || ev.NodeType == "lifecycle" {
+			continue
+		}
+		status := strings.ToUpper(ev.Status)
+		counts[status]++
+		if status == "ERROR" && firstError == nil {
+			firstError = &ev
+		}
+	}
+	return counts, firstError
+}
+
+// executionDuration returns the wall time of a finished execution, preferring
+// the wall_ms of its execution stats.
+func executionDuration(start time.Time, end *time.Time, stats []byte) *int64 {
+	var s struct {
+		WallMs *int64 `json:"wall_ms"`
+	}
+	if len(stats) > 0 && json.Unmarshal(stats, &s) == nil && s.WallMs != nil {
+		return s.WallMs
+	}
+	if end == nil {
+		return nil
+	}
+	ms := end.Sub(start).Milliseconds()
+	return &ms
+}
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildTimeline(t *testing.T) {
+	t0 := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	events := BuildTimeline([]TimelineEvent{
+		{NodeID: "flow", NodeType: "process", Status: "STARTED", EndedAt: t0},
+		{NodeID: "fetch", NodeType: "http", Status: "SUCCESS", EndedAt: t0.Add(250 * time.Millisecond), DurationMs: 200},
+		{NodeID: "save", NodeType: "sql", Status: "ERROR", EndedAt: t0.Add(400 * time.Millisecond), DurationMs: 100},
+	})
+
+	require.Len(t, events, 3)
+	assert.Equal(t, int64(0), events[0].OffsetMs)
+	assert.Equal(t, t0.Add(50*time.Millisecond), events[1].StartedAt)
+	assert.Equal(t, int64(50), events[1].OffsetMs)
+	assert.Equal(t, int64(300), events[2].OffsetMs)
+
+	assert.Empty(t, BuildTimeline(nil))
+}
+
+func TestBuildTimeline_NodeStartedBeforeFirstEvent(t *testing.T) {
+	// Without the process STARTED event the earliest node start is the origin.
+	t0 := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	events := BuildTimeline([]TimelineEvent{
+		{NodeID: "fetch", Status: "SUCCESS", EndedAt: t0, DurationMs: 500},
+		{NodeID: "save", Status: "SUCCESS", EndedAt: t0.Add(100 * time.Millisecond), DurationMs: 50},
+	})
+	assert.Equal(t, int64(0), events[0].OffsetMs)
+	assert.Equal(t, int64(550), events[1].OffsetMs)
+}
+
+func TestSummarizeTimeline(t *testing.T) {
+	counts, first := SummarizeTimeline([]TimelineEvent{
+		{NodeID: "flow", NodeType: "process", Status: "STARTED"},
+		{NodeID: "a", NodeType: "http", Status: "SUCCESS"},
+		{NodeID: "b", NodeType: "sql", Status: "ERROR", Error: "deadlock"},
+		{NodeID: "c", NodeType: "logger", Status: "error", Error: "later"},
+		{NodeID: "d", NodeType: "http", Status: "CIRCUIT_OPEN"},
+		{NodeID: "flow", NodeType: "process", Status: "FAILED"},
+	})
+	assert.Equal(t, map[string]int{"SUCCESS": 1, "ERROR": 2, "CIRCUIT_OPEN": 1}, counts)
+	require.NotNil(t, first)
+	assert.Equal(t, "b", first.NodeID)
+	assert.Equal(t, "deadlock", first.Error)
+
+	counts, first = SummarizeTimeline(nil)
+	assert.Empty(t, counts)
+	assert.Nil(t, first)
+}
+
+func TestExecutionDuration(t *testing.T) {
+	start := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	end := start.Add(1500 * time.Millisecond)
+
+	assert.Nil(t, executionDuration(start, nil, nil), "running executions have no duration")
+	require.NotNil(t, executionDuration(start, &end, nil))
+	assert.Equal(t, int64(1500), *executionDuration(start, &end, nil))
+	assert.Equal(t, int64(1320), *executionDuration(start, &end, []byte(`{"wall_ms":1320}`)), "the engine's wall time wins")
+}
+
+func TestEventTimestamp(t *testing.T) {
+	assert.Nil(t, eventTimestamp(""))
+	assert.Nil(t, eventTimestamp("yesterday"))
+	assert.Equal(t, time.Date(2026, 3, 1, 10, 0, 0, 123456789, time.UTC), eventTimestamp("2026-03-01T10:00:00.123456789Z"))
+}
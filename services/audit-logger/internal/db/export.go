@@ -0,0 +1,204 @@
+package db
+
+import (
+	"archive/zip"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Export formats of an execution's logs.
+const (
+	ExportCSV    = "csv"
+	ExportNDJSON = "ndjson"
+	ExportZip    = "zip"
+)
+
+// LogEntry is one activity-log row of an execution with the node's input,
+// output and error as recorded.
+type LogEntry struct {
+	LogID        int64           `json:"log_id"`
+	NodeID       string          `json:"node_id"`
+	NodeType     string          `json:"node_type"`
+	Status       string          `json:"status"`
+	InputData    json.RawMessage `json:"input_data"`
+	OutputData   json.RawMessage `json:"output_data"`
+	ErrorDetails json.RawMessage `json:"error_details"`
+	DurationMs   int             `json:"duration_ms"`
+	// Attempt is the retry_policy attempt a node run ended on, zero for
+	// other events. EngineID and ProcessVersion are empty for events of
+	// engines that did not report them.
+	Attempt        int    `json:"attempt,omitempty"`
+	EngineID       string `json:"engine_id,omitempty"`
+	ProcessVersion string `json:"process_version,omitempty"`
+	CreatedAt      string `json:"created_at"`
+}
+
+// ExecutionLogs returns the activity-log rows of executionID in workspace in
+// the order they were recorded, with compressed inputs and outputs
+// decompressed.
+func ExecutionLogs(ctx context.Context, rawDB *sql.DB, workspace, executionID string) ([]LogEntry, error) {
+	rows, err := rawDB.QueryContext(ctx, `
+		SELECT al.log_id, al.node_id, COALESCE(al.node_type,''), al.status,
+		       al.input_data, al.output_data, al.error_details,
+		       COALESCE(al.duration_ms,0), COALESCE(al.attempt,0), COALESCE(al.engine_id,''),
+		       COALESCE(al.process_version,''), al.created_at, al.input_data_gz, al.output_data_gz
+		FROM activity_logs al
+		JOIN executions e ON e.execution_id = al.execution_id
+		WHERE al.execution_id = $1 AND e.workspace = $2
+		ORDER BY al.created_at ASC, al.log_id ASC`, executionID, workspace)
+	if err != nil {
+		return nil, fmt.Errorf("query activity logs: %w", err)
+	}
+	defer rows.Close()
+
+	var logs []LogEntry
+	for rows.Next() {
+		var (
+			e                           LogEntry
+			inputRaw, outputRaw, errRaw []byte
+			inputGz, outputGz           []byte
+			createdAt                   time.Time
+		)
+		if err := rows.Scan(&e.LogID, &e.NodeID, &e.NodeType, &e.Status,
+			&inputRaw, &outputRaw, &errRaw, &e.DurationMs, &e.Attempt, &e.EngineID,
+			&e.ProcessVersion, &createdAt, &inputGz, &outputGz); err != nil {
+			return nil, fmt.Errorf("scan activity log row: %w", err)
+		}
+		var err error
+		if inputRaw, err = decodePayload(inputRaw, inputGz); err != nil {
+			return nil, fmt.Errorf("input of activity log %d: %w", e.LogID, err)
+		}
+		if outputRaw, err = decodePayload(outputRaw, outputGz); err != nil {
+			return nil, fmt.Errorf("output of activity log %d: %w", e.LogID, err)
+		}
+		e.CreatedAt = createdAt.Format(time.RFC3339)
+		e.InputData = nullableJSON(inputRaw)
+		e.OutputData = nullableJSON(outputRaw)
+		e.ErrorDetails = nullableJSON(errRaw)
+		logs = append(logs, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read activity logs: %w", err)
+	}
+	return logs, nil
+}
+
+// ExecutionTriggerData returns the trigger payload of executionID in
+// workspace, as recorded with its process STARTED event, or nil when the
+// execution has no such event. It is {} when the event has no trigger.
+func ExecutionTriggerData(ctx context.Context, rawDB *sql.DB, workspace, executionID string) (json.RawMessage, error) {
+	var trigger, inputGz []byte
+	err := rawDB.QueryRowContext(ctx, `
+		SELECT al.input_data->'trigger', al.input_data_gz
+		FROM activity_logs al
+		JOIN executions e ON e.execution_id = al.execution_id
+		WHERE al.execution_id = $1
+		  AND e.workspace = $2
+		  AND al.node_type = 'process'
+		  AND al.status = 'STARTED'
+		ORDER BY al.created_at ASC
+		LIMIT 1`, executionID, workspace).Scan(&trigger, &inputGz)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query trigger data: %w", err)
+	}
+	if len(inputGz) > 0 {
+		input, err := decodePayload(nil, inputGz)
+		if err != nil {
+			return nil, fmt.Errorf("input of process event: %w", err)
+		}
+		var v struct {
+			Trigger json.RawMessage `json:"trigger"`
+		}
+		if err := json.Unmarshal(input, &v); err != nil {
+			return nil, fmt.Errorf("decode input of process event: %w", err)
+		}
+		trigger = v.Trigger
+	}
+	if len(trigger) == 0 || string(trigger) == "null" {
+		return json.RawMessage("{}"), nil
+	}
+	return trigger, nil
+}
+
+// nullableJSON returns a null JSON token when b is empty.
+func nullableJSON(b []byte) json.RawMessage {
+	if len(b) == 0 {
+		return json.RawMessage("null")
+	}
+	return json.RawMessage(b)
+}
+
+// csvHeader is the header row written by WriteLogsCSV.
+var csvHeader = []string{"log_id", "created_at", "node_id", "node_type", "status", "duration_ms", "attempt", "engine_id", "process_version", "input_data", "output_data", "error_details"}
+
+// WriteLogsCSV writes logs as CSV with a header row. Inputs, outputs and
+// errors are JSON-encoded cells.
+func WriteLogsCSV(w io.Writer, logs []LogEntry) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, e := range logs {
+		if err := cw.Write([]string{
+			strconv.FormatInt(e.LogID, 10), e.CreatedAt, e.NodeID, e.NodeType, e.Status,
+			strconv.Itoa(e.DurationMs), strconv.Itoa(e.Attempt), e.EngineID, e.ProcessVersion,
+			string(e.InputData), string(e.OutputData), string(e.ErrorDetails),
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteLogsNDJSON writes logs as one JSON object per line.
+func WriteLogsNDJSON(w io.Writer, logs []LogEntry) error {
+	enc := json.NewEncoder(w)
+	for _, e := range logs {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteExportZip writes a zip archive holding the execution detail
+// (execution.json) and its logs as logs.ndjson and logs.csv.
+func WriteExportZip(w io.Writer, detail *ExecutionDetail, logs []LogEntry) error {
+	zw := zip.NewWriter(w)
+	modified := time.Now().UTC()
+	if detail.EndTime != nil {
+		modified = *detail.EndTime
+	}
+	files := []struct {
+		name  string
+		write func(io.Writer) error
+	}{
+		{"execution.json", func(w io.Writer) error {
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			return enc.Encode(detail)
+		}},
+		{"logs.ndjson", func(w io.Writer) error { return WriteLogsNDJSON(w, logs) }},
+		{"logs.csv", func(w io.Writer) error { return WriteLogsCSV(w, logs) }},
+	}
+	for _, f := range files {
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: f.name, Method: zip.Deflate, Modified: modified})
+		if err != nil {
+			return err
+		}
+		if err := f.write(fw); err != nil {
+			return fmt.Errorf("write %s: %w", f.name, err)
+		}
+	}
+	return zw.Close()
+}
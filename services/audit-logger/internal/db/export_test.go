@@ -0,0 +1,73 @@
+package db
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func exportLogs() []LogEntry {
+	return []LogEntry{
+		{LogID: 1, NodeID: "flow", NodeType: "process", Status: "STARTED", CreatedAt: "2026-03-01T10:00:00Z",
+			InputData: json.RawMessage(`{"trigger":{"id":7}}`), OutputData: json.RawMessage("null"), ErrorDetails: json.RawMessage("null")},
+		{LogID: 2, NodeID: "save", NodeType: "sql", Status: "ERROR", DurationMs: 12, Attempt: 3, EngineID: "engine-a", ProcessVersion: "1.2.0", CreatedAt: "2026-03-01T10:00:01Z",
+			InputData: json.RawMessage(`{"name":"a, \"b\""}`), OutputData: json.RawMessage("null"), ErrorDetails: json.RawMessage(`{"message":"duplicate key"}`)},
+	}
+}
+
+func TestWriteLogsCSV(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteLogsCSV(&buf, exportLogs()))
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, csvHeader, records[0])
+	assert.Equal(t, []string{"2", "2026-03-01T10:00:01Z", "save", "sql", "ERROR", "12", "3", "engine-a", "1.2.0",
+		`{"name":"a, \"b\""}`, "null", `{"message":"duplicate key"}`}, records[2])
+}
+
+func TestWriteLogsNDJSON(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteLogsNDJSON(&buf, exportLogs()))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	var e LogEntry
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &e))
+	assert.Equal(t, "save", e.NodeID)
+	assert.JSONEq(t, `{"message":"duplicate key"}`, string(e.ErrorDetails))
+}
+
+func TestWriteExportZip(t *testing.T) {
+	end := time.Date(2026, 3, 1, 10, 0, 2, 0, time.UTC)
+	detail := &ExecutionDetail{ExecutionID: "exec-1", FlowID: "orders", Status: "FAILED", EndTime: &end}
+	var buf bytes.Buffer
+	require.NoError(t, WriteExportZip(&buf, detail, exportLogs()))
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		data, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		rc.Close()
+		files[f.Name] = string(data)
+	}
+	require.Len(t, files, 3)
+	var got ExecutionDetail
+	require.NoError(t, json.Unmarshal([]byte(files["execution.json"]), &got))
+	assert.Equal(t, "exec-1", got.ExecutionID)
+	assert.Len(t, strings.Split(strings.TrimSpace(files["logs.ndjson"]), "\n"), 2)
+	assert.True(t, strings.HasPrefix(files["logs.csv"], "log_id,created_at,"))
+}
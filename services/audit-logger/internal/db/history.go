@@ -0,0 +1,89 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// NodeRun is one run of a node, as listed by the node history.
+type NodeRun struct {
+	LogID       int64  `json:"log_id"`
+	ExecutionID string `json:"execution_id"`
+	Status      string `json:"status"`
+	// StartedAt is derived from EndedAt, when the run was recorded, and its
+	// duration.
+	StartedAt  time.Time `json:"started_at"`
+	EndedAt    time.Time `json:"ended_at"`
+	DurationMs int64     `json:"duration_ms"`
+	Error      string    `json:"error,omitempty"`
+	Attempt    int       `json:"attempt,omitempty"`
+	// ProcessVersion is the definition.version the run belonged to, when
+	// recorded.
+	ProcessVersion string `json:"process_version,omitempty"`
+}
+
+// NodeHistory is the recent runs of one node of a flow, newest first, with a
+// summary of those runs.
+type NodeHistory struct {
+	FlowID string `json:"flow_id"`
+	NodeID string `json:"node_id"`
+	// Counts counts the listed runs by status (SUCCESS, ERROR, ...).
+	Counts map[string]int `json:"counts"`
+	// AvgDurationMs is the mean duration of the listed runs.
+	AvgDurationMs int64     `json:"avg_duration_ms"`
+	Runs          []NodeRun `json:"runs"`
+}
+
+// GetNodeHistory returns the latest limit runs of node nodeID of flow flowID
+// in workspace, skipping offset. Process and lifecycle events are not node
+// runs.
+func GetNodeHistory(ctx context.Context, rawDB *sql.DB, workspace, flowID, nodeID string, limit, offset int) (*NodeHistory, error) {
+	rows, err := rawDB.QueryContext(ctx, `
+		SELECT al.log_id, al.execution_id, COALESCE(al.status, ''), COALESCE(al.duration_ms, 0), al.created_at,
+		       COALESCE(al.error_details->>'message', ''), COALESCE(al.attempt, 0), COALESCE(al.process_version, '')
+		FROM activity_logs al
+		JOIN executions e ON e.execution_id = al.execution_id
+		WHERE e.workspace = $1 AND e.flow_id = $2 AND al.node_id = $3
+		  AND COALESCE(al.node_type, '') NOT IN ('process', 'lifecycle')
+		ORDER BY al.created_at DESC, al.log_id DESC
+		LIMIT $4 OFFSET $5`, workspace, flowID, nodeID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("query node history: %w", err)
+	}
+	defer rows.Close()
+
+	var runs []NodeRun
+	for rows.Next() {
+		var run NodeRun
+		if err := rows.Scan(&run.LogID, &run.ExecutionID, &run.Status, &run.DurationMs, &run.EndedAt, &run.Error, &run.Attempt, &run.ProcessVersion); err != nil {
+			return nil, fmt.Errorf("scan node history row: %w", err)
+		}
+		run.StartedAt = run.EndedAt.Add(-time.Duration(run.DurationMs) * time.Millisecond)
+		runs = append(runs, run)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read node history: %w", err)
+	}
+	return SummarizeNodeRuns(flowID, nodeID, runs), nil
+}
+
+// SummarizeNodeRuns returns the history of runs, counted by upper-cased
+// status and averaged.
+func SummarizeNodeRuns(flowID, nodeID string, runs []NodeRun) *NodeHistory {
+	h := &NodeHistory{FlowID: flowID, NodeID: nodeID, Counts: map[string]int{}, Runs: runs}
+	if h.Runs == nil {
+		h.Runs = []NodeRun{}
+	}
+	var total int64
+	for _, run := range runs {
+		h.Counts[strings.ToUpper(run.Status)]++
+		total += run.DurationMs
+	}
+	if len(runs) > 0 {
+		h.AvgDurationMs = total / int64(len(runs))
+	}
+	return h
+}
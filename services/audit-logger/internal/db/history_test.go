@@ -0,0 +1,24 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSummarizeNodeRuns(t *testing.T) {
+	h := SummarizeNodeRuns("orders", "fetch", []NodeRun{
+		{LogID: 3, Status: "SUCCESS", DurationMs: 100},
+		{LogID: 2, Status: "error", DurationMs: 300, Error: "timeout"},
+		{LogID: 1, Status: "SUCCESS", DurationMs: 50},
+	})
+	assert.Equal(t, "orders", h.FlowID)
+	assert.Equal(t, "fetch", h.NodeID)
+	assert.Equal(t, map[string]int{"SUCCESS": 2, "ERROR": 1}, h.Counts)
+	assert.Equal(t, int64(150), h.AvgDurationMs)
+	assert.Len(t, h.Runs, 3)
+
+	empty := SummarizeNodeRuns("orders", "fetch", nil)
+	assert.Equal(t, []NodeRun{}, empty.Runs, "no runs encode as an empty list")
+	assert.Zero(t, empty.AvgDurationMs)
+}
@@ -0,0 +1,91 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// StatusOrphaned is the status of an execution that stayed STARTED with no
+// audit event for the orphan timeout, typically because the engine running
+// it stopped. A terminal event that arrives later still sets its status.
+const StatusOrphaned = "ORPHANED"
+
+// Defaults of OrphanConfigFromEnv.
+const (
+	DefaultOrphanTimeout       = time.Hour
+	DefaultOrphanCheckInterval = time.Minute
+)
+
+// MinOrphanTimeout is the smallest non-zero orphan timeout. A live execution
+// sends no audit event while a callback_await node waits, for up to the
+// engine's 15m await maximum; the timeout keeps a margin above it so such an
+// execution is never marked ORPHANED.
+const MinOrphanTimeout = 30 * time.Minute
+
+// OrphanConfig controls the watchdog that marks orphaned executions.
+type OrphanConfig struct {
+	// Timeout is how long a STARTED execution may go without audit events
+	// before it is marked ORPHANED; 0 disables the watchdog.
+	Timeout time.Duration
+	// Interval is how often the watchdog looks for orphaned executions.
+	Interval time.Duration
+}
+
+// OrphanConfigFromEnv reads AUDIT_ORPHAN_TIMEOUT (default
+// DefaultOrphanTimeout, at least MinOrphanTimeout, 0 disables the watchdog)
+// and AUDIT_ORPHAN_CHECK_INTERVAL (default DefaultOrphanCheckInterval).
+func OrphanConfigFromEnv() (OrphanConfig, error) {
+	cfg := OrphanConfig{Timeout: DefaultOrphanTimeout, Interval: DefaultOrphanCheckInterval}
+	if v := strings.TrimSpace(os.Getenv("AUDIT_ORPHAN_TIMEOUT")); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return OrphanConfig{}, fmt.Errorf("AUDIT_ORPHAN_TIMEOUT must be a non-negative duration, got %q", v)
+		}
+		if d > 0 && d < MinOrphanTimeout {
+			return OrphanConfig{}, fmt.Errorf("AUDIT_ORPHAN_TIMEOUT must be 0 or at least %s, got %q", MinOrphanTimeout, v)
+		}
+		cfg.Timeout = d
+	}
+	if v := strings.TrimSpace(os.Getenv("AUDIT_ORPHAN_CHECK_INTERVAL")); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return OrphanConfig{}, fmt.Errorf("AUDIT_ORPHAN_CHECK_INTERVAL must be a positive duration, got %q", v)
+		}
+		cfg.Interval = d
+	}
+	return cfg, nil
+}
+
+// MarkOrphanedExecutions marks as ORPHANED the executions that are STARTED
+// and have had no audit event for timeout, and returns how many it marked.
+// Lifecycle (deploy/stop) executions never finish and are left alone.
+func MarkOrphanedExecutions(ctx context.Context, rawDB *sql.DB, timeout time.Duration) (int64, error) {
+	res, err := rawDB.ExecContext(ctx, `
+		UPDATE executions e
+		SET status = $1, end_time = NOW(), main_error_message = $2
+		WHERE e.status = 'STARTED'
+		  AND COALESCE(e.trigger_type, '') <> 'lifecycle'
+		  AND e.start_time < NOW() - $3 * INTERVAL '1 second'
+		  AND NOT EXISTS (
+		      SELECT 1 FROM activity_logs al
+		      WHERE al.execution_id = e.execution_id
+		        AND al.created_at >= NOW() - $3 * INTERVAL '1 second')`,
+		StatusOrphaned, orphanMessage(timeout), timeout.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("mark orphaned executions: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("count orphaned executions: %w", err)
+	}
+	return n, nil
+}
+
+// orphanMessage is the main_error_message of an orphaned execution.
+func orphanMessage(timeout time.Duration) string {
+	return fmt.Sprintf("no audit event for %s and no terminal event; the engine running the execution probably stopped", timeout)
+}
@@ -0,0 +1,108 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrphanConfigFromEnv(t *testing.T) {
+	cfg, err := OrphanConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, OrphanConfig{Timeout: DefaultOrphanTimeout, Interval: DefaultOrphanCheckInterval}, cfg)
+
+	t.Setenv("AUDIT_ORPHAN_TIMEOUT", "30m")
+	t.Setenv("AUDIT_ORPHAN_CHECK_INTERVAL", "15s")
+	cfg, err = OrphanConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, OrphanConfig{Timeout: 30 * time.Minute, Interval: 15 * time.Second}, cfg)
+
+	t.Setenv("AUDIT_ORPHAN_TIMEOUT", "0")
+	cfg, err = OrphanConfigFromEnv()
+	require.NoError(t, err)
+	assert.Zero(t, cfg.Timeout, "0 disables the watchdog")
+
+	t.Setenv("AUDIT_ORPHAN_TIMEOUT", "20m")
+	_, err = OrphanConfigFromEnv()
+	assert.ErrorContains(t, err, "at least 30m0s", "a live callback_await must outlast the timeout")
+
+	t.Setenv("AUDIT_ORPHAN_TIMEOUT", "soon")
+	_, err = OrphanConfigFromEnv()
+	assert.ErrorContains(t, err, "AUDIT_ORPHAN_TIMEOUT")
+
+	t.Setenv("AUDIT_ORPHAN_TIMEOUT", "1h")
+	t.Setenv("AUDIT_ORPHAN_CHECK_INTERVAL", "0s")
+	_, err = OrphanConfigFromEnv()
+	assert.ErrorContains(t, err, "AUDIT_ORPHAN_CHECK_INTERVAL")
+}
+
+// execRecorder is a database/sql connector recording the last statement
+// executed; executions report rowsAffected, or fail with err.
+type execRecorder struct {
+	rowsAffected int64
+	err          error
+	query        string
+	args         []driver.NamedValue
+}
+
+func (r *execRecorder) Connect(context.Context) (driver.Conn, error) { return recorderConn{r}, nil }
+func (r *execRecorder) Driver() driver.Driver                        { return nil }
+
+type recorderConn struct{ r *execRecorder }
+
+func (c recorderConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c recorderConn) Close() error                        { return nil }
+func (c recorderConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func (c recorderConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.r.query, c.r.args = query, args
+	if c.r.err != nil {
+		return nil, c.r.err
+	}
+	return driver.RowsAffected(c.r.rowsAffected), nil
+}
+
+func TestMarkOrphanedExecutions(t *testing.T) {
+	rec := &execRecorder{rowsAffected: 3}
+	rawDB := sql.OpenDB(rec)
+	t.Cleanup(func() { _ = rawDB.Close() })
+
+	n, err := MarkOrphanedExecutions(context.Background(), rawDB, DefaultOrphanTimeout)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+
+	query := strings.Join(strings.Fields(rec.query), " ")
+	for _, clause := range []string{
+		"SET status = $1, end_time = NOW(), main_error_message = $2",
+		"WHERE e.status = 'STARTED'",
+		"AND COALESCE(e.trigger_type, '') <> 'lifecycle'",
+		"AND e.start_time < NOW() - $3 * INTERVAL '1 second'",
+		"AND NOT EXISTS ( SELECT 1 FROM activity_logs al WHERE al.execution_id = e.execution_id AND al.created_at >= NOW() - $3 * INTERVAL '1 second')",
+	} {
+		assert.Contains(t, query, clause)
+	}
+	require.Len(t, rec.args, 3)
+	assert.Equal(t, StatusOrphaned, rec.args[0].Value)
+	assert.Equal(t, "no audit event for 1h0m0s and no terminal event; the engine running the execution probably stopped", rec.args[1].Value)
+	assert.Equal(t, float64(3600), rec.args[2].Value, "both windows are the timeout, in seconds")
+
+	rec.err = errors.New("connection reset")
+	_, err = MarkOrphanedExecutions(context.Background(), rawDB, DefaultOrphanTimeout)
+	assert.ErrorContains(t, err, "mark orphaned executions: connection reset")
+}
+
+// TestOrphanTimeouts_OutlastCallbackAwait guards the invariant behind
+// MinOrphanTimeout: the engine's callback_await waits up to 15m without
+// sending audit events.
+func TestOrphanTimeouts_OutlastCallbackAwait(t *testing.T) {
+	const maxCallbackAwait = 15 * time.Minute
+	assert.Greater(t, MinOrphanTimeout, maxCallbackAwait)
+	assert.GreaterOrEqual(t, DefaultOrphanTimeout, MinOrphanTimeout)
+}
@@ -0,0 +1,146 @@
+package db
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Payload compression modes of AUDIT_PAYLOAD_COMPRESSION.
+const (
+	CompressionNone = "none"
+	CompressionGzip = "gzip"
+)
+
+// DefaultCompressMinBytes is the smallest payload gzip compression applies
+// to; smaller payloads gain little and stay searchable as JSONB.
+const DefaultCompressMinBytes = 1024
+
+// omittedPayloadKey is the only key of a payload stored as a fingerprint.
+const omittedPayloadKey = "_payload_omitted"
+
+// PayloadConfig controls how node inputs and outputs are stored in
+// activity_logs. The zero value stores every payload as JSONB.
+type PayloadConfig struct {
+	// Compression is CompressionNone or CompressionGzip. With gzip, payloads
+	// of at least CompressMinBytes go to input_data_gz / output_data_gz
+	// instead of the JSONB columns, unless compressing does not shrink them.
+	Compression      string
+	CompressMinBytes int
+	// FingerprintAboveBytes, when positive, replaces payloads larger than it
+	// with {"_payload_omitted": {"sha256", "size_bytes"}}: the payload can be
+	// matched against a copy but is not kept.
+	FingerprintAboveBytes int
+}
+
+// PayloadConfigFromEnv reads AUDIT_PAYLOAD_COMPRESSION (none or gzip, default
+// none), AUDIT_PAYLOAD_COMPRESS_MIN_BYTES (default DefaultCompressMinBytes)
+// and AUDIT_PAYLOAD_FINGERPRINT_ABOVE_BYTES (default 0, disabled).
+func PayloadConfigFromEnv() (PayloadConfig, error) {
+	cfg := PayloadConfig{
+		Compression:      strings.ToLower(strings.TrimSpace(os.Getenv("AUDIT_PAYLOAD_COMPRESSION"))),
+		CompressMinBytes: DefaultCompressMinBytes,
+	}
+	switch cfg.Compression {
+	case "":
+		cfg.Compression = CompressionNone
+	case CompressionNone, CompressionGzip:
+	default:
+		return PayloadConfig{}, fmt.Errorf("AUDIT_PAYLOAD_COMPRESSION must be none or gzip, got %q", cfg.Compression)
+	}
+	var err error
+	if cfg.CompressMinBytes, err = envBytes("AUDIT_PAYLOAD_COMPRESS_MIN_BYTES", cfg.CompressMinBytes); err != nil {
+		return PayloadConfig{}, err
+	}
+	if cfg.FingerprintAboveBytes, err = envBytes("AUDIT_PAYLOAD_FINGERPRINT_ABOVE_BYTES", 0); err != nil {
+		return PayloadConfig{}, err
+	}
+	return cfg, nil
+}
+
+// envBytes reads a non-negative byte count from the environment variable key.
+func envBytes(key string, def int) (int, error) {
+	raw := strings.TrimSpace(os.Getenv(key))
+	if raw == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative number of bytes, got %q", key, raw)
+	}
+	return n, nil
+}
+
+// encodePayload returns how m is stored: as JSONB (nil for an empty map or a
+// compressed payload) and as gzip-compressed JSON (nil unless compressed).
+func (cfg PayloadConfig) encodePayload(m map[string]interface{}) (jsonb, gz []byte, err error) {
+	jsonb, err = marshalJSONB(m)
+	if err != nil || jsonb == nil {
+		return nil, nil, err
+	}
+	if cfg.FingerprintAboveBytes > 0 && len(jsonb) > cfg.FingerprintAboveBytes {
+		jsonb, err = fingerprintPayload(jsonb)
+		return jsonb, nil, err
+	}
+	if cfg.Compression != CompressionGzip || len(jsonb) < cfg.CompressMinBytes {
+		return jsonb, nil, nil
+	}
+	gz, err = gzipPayload(jsonb)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(gz) >= len(jsonb) {
+		return jsonb, nil, nil
+	}
+	return nil, gz, nil
+}
+
+// fingerprintPayload returns the JSONB stored in place of payload.
+func fingerprintPayload(payload []byte) ([]byte, error) {
+	sum := sha256.Sum256(payload)
+	b, err := json.Marshal(map[string]interface{}{omittedPayloadKey: map[string]interface{}{
+		"sha256":     hex.EncodeToString(sum[:]),
+		"size_bytes": len(payload),
+	}})
+	if err != nil {
+		return nil, fmt.Errorf("marshal payload fingerprint: %w", err)
+	}
+	return b, nil
+}
+
+func gzipPayload(payload []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(payload); err != nil {
+		return nil, fmt.Errorf("gzip payload: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("gzip payload: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// decodePayload returns the JSON of a payload stored as jsonb or, when
+// compressed, as gz.
+func decodePayload(jsonb, gz []byte) ([]byte, error) {
+	if len(gz) == 0 {
+		return jsonb, nil
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(gz))
+	if err != nil {
+		return nil, fmt.Errorf("decompress payload: %w", err)
+	}
+	defer zr.Close()
+	b, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("decompress payload: %w", err)
+	}
+	return b, nil
+}
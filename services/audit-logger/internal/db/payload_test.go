@@ -0,0 +1,86 @@
+package db
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func largePayload() map[string]interface{} {
+	return map[string]interface{}{"rows": strings.Repeat(`{"sku":"A-1","qty":2},`, 200)}
+}
+
+func TestEncodePayload_Gzip(t *testing.T) {
+	cfg := PayloadConfig{Compression: CompressionGzip, CompressMinBytes: 512}
+
+	jsonb, gz, err := cfg.encodePayload(largePayload())
+	require.NoError(t, err)
+	assert.Nil(t, jsonb)
+	require.NotNil(t, gz)
+	decoded, err := decodePayload(jsonb, gz)
+	require.NoError(t, err)
+	want, _ := json.Marshal(largePayload())
+	assert.JSONEq(t, string(want), string(decoded))
+
+	jsonb, gz, err = cfg.encodePayload(map[string]interface{}{"id": 7})
+	require.NoError(t, err)
+	assert.Nil(t, gz, "small payloads stay JSONB")
+	assert.JSONEq(t, `{"id":7}`, string(jsonb))
+
+	jsonb, gz, err = cfg.encodePayload(nil)
+	require.NoError(t, err)
+	assert.Nil(t, jsonb)
+	assert.Nil(t, gz)
+}
+
+func TestEncodePayload_Fingerprint(t *testing.T) {
+	cfg := PayloadConfig{Compression: CompressionGzip, CompressMinBytes: 512, FingerprintAboveBytes: 1024}
+
+	jsonb, gz, err := cfg.encodePayload(largePayload())
+	require.NoError(t, err)
+	assert.Nil(t, gz, "a fingerprinted payload is not compressed")
+	var stored map[string]map[string]interface{}
+	require.NoError(t, json.Unmarshal(jsonb, &stored))
+	omitted := stored[omittedPayloadKey]
+	require.NotNil(t, omitted)
+	assert.Len(t, omitted["sha256"], 64)
+	raw, _ := json.Marshal(largePayload())
+	assert.Equal(t, float64(len(raw)), omitted["size_bytes"])
+
+	again, _, err := cfg.encodePayload(largePayload())
+	require.NoError(t, err)
+	assert.Equal(t, jsonb, again, "the fingerprint identifies the payload")
+}
+
+func TestEncodePayload_Disabled(t *testing.T) {
+	jsonb, gz, err := PayloadConfig{}.encodePayload(largePayload())
+	require.NoError(t, err)
+	assert.Nil(t, gz)
+	want, _ := json.Marshal(largePayload())
+	assert.Equal(t, want, jsonb)
+}
+
+func TestPayloadConfigFromEnv(t *testing.T) {
+	cfg, err := PayloadConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, PayloadConfig{Compression: CompressionNone, CompressMinBytes: DefaultCompressMinBytes}, cfg)
+
+	t.Setenv("AUDIT_PAYLOAD_COMPRESSION", "GZIP")
+	t.Setenv("AUDIT_PAYLOAD_COMPRESS_MIN_BYTES", "256")
+	t.Setenv("AUDIT_PAYLOAD_FINGERPRINT_ABOVE_BYTES", "1048576")
+	cfg, err = PayloadConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, PayloadConfig{Compression: CompressionGzip, CompressMinBytes: 256, FingerprintAboveBytes: 1048576}, cfg)
+
+	t.Setenv("AUDIT_PAYLOAD_COMPRESSION", "zstd")
+	_, err = PayloadConfigFromEnv()
+	assert.ErrorContains(t, err, "AUDIT_PAYLOAD_COMPRESSION")
+
+	t.Setenv("AUDIT_PAYLOAD_COMPRESSION", "gzip")
+	t.Setenv("AUDIT_PAYLOAD_FINGERPRINT_ABOVE_BYTES", "-1")
+	_, err = PayloadConfigFromEnv()
+	assert.ErrorContains(t, err, "AUDIT_PAYLOAD_FINGERPRINT_ABOVE_BYTES")
+}
@@ -16,12 +16,14 @@ import (
 
 // Client wraps a PostgreSQL connection and provides batch insert operations.
 type Client struct {
-	db *sql.DB
+	db      *sql.DB
+	payload PayloadConfig
 }
 
 // New opens a connection to PostgreSQL and verifies it with a ping.
-// It retries up to maxRetries times with an exponential back-off.
-func New(dsn string) (*Client, error) {
+// It retries up to maxRetries times with an exponential back-off. Node
+// inputs and outputs are stored as payload says.
+func New(dsn string, payload PayloadConfig) (*Client, error) {
 	const maxRetries = 5
 	var (
 		db  *sql.DB
@@ -35,7 +37,7 @@ func New(dsn string) (*Client, error) {
 		}
 		if err == nil {
 			log.Printf("audit-logger: connected to PostgreSQL (attempt %d)", attempt)
-			return &Client{db: db}, nil
+			return &Client{db: db, payload: payload}, nil
 		}
 		wait := time.Duration(attempt*attempt) * time.Second
 		log.Printf("audit-logger: postgres not ready (attempt %d/%d): %v — retrying in %s",
@@ -93,7 +95,7 @@ func (c *Client) BatchInsertLogs(events []batcher.AuditEvent) error {
 		}
 	}()
 
-	if err = insertActivityLogs(tx2, events); err != nil {
+	if err = insertActivityLogs(tx2, events, c.payload); err != nil {
 		return err
 	}
 
@@ -105,14 +107,15 @@ func (c *Client) BatchInsertLogs(events []batcher.AuditEvent) error {
 
 // upsertExecutions ensures that every execution_id referenced by the batch
 // has a corresponding row in the executions table, and updates the status
-// to COMPLETED, FAILED, or REPLAYED when a terminal process event is present.
+// to COMPLETED, FAILED, or REPLAYED when a terminal process event is present,
+// also when the orphan watchdog has already marked the execution ORPHANED.
 func upsertExecutions(tx *sql.Tx, events []batcher.AuditEvent) error {
 	infos := classifyExecutions(events)
 
 	// Insert new execution rows (idempotent).
 	insertStmt, err := tx.Prepare(`
-		INSERT INTO executions (execution_id, flow_id, status, start_time, trigger_type)
-		VALUES ($1, $2, 'STARTED', NOW(), NULLIF($3, ''))
+		INSERT INTO executions (execution_id, workspace, flow_id, status, start_time, trigger_type, parent_execution_id, version)
+		VALUES ($1, $2, $3, 'STARTED', NOW(), NULLIF($4, ''), NULLIF($5, '')::uuid, NULLIF($6, ''))
 		ON CONFLICT (execution_id) DO NOTHING`)
 	if err != nil {
 		return fmt.Errorf("prepare insert executions: %w", err)
@@ -126,7 +129,8 @@ func upsertExecutions(tx *sql.Tx, events []batcher.AuditEvent) error {
 	// Update terminal status for finished executions.
 	updateStmt, err := tx.Prepare(`
 		UPDATE executions
-		SET status = $1, end_time = NOW(), main_error_message = NULLIF($2, '')
+		SET status = $1, end_time = NOW(), main_error_message = NULLIF($2, ''),
+		    execution_stats = COALESCE($4::jsonb, execution_stats)
 		WHERE execution_id = $3`)
 	if err != nil {
 		return fmt.Errorf("prepare update executions: %w", err)
@@ -138,11 +142,15 @@ func upsertExecutions(tx *sql.Tx, events []batcher.AuditEvent) error {
 	}()
 
 	for id, info := range infos {
-		if _, err := insertStmt.Exec(id, info.flowID, info.triggerType); err != nil {
+		if _, err := insertStmt.Exec(id, info.workspace, info.flowID, info.triggerType, info.parentID, info.version); err != nil {
 			return fmt.Errorf("insert execution %s: %w", id, err)
 		}
 		if info.terminalStatus != "" {
-			if _, err := updateStmt.Exec(info.terminalStatus, info.errorMsg, id); err != nil {
+			stats, err := marshalJSONB(info.stats)
+			if err != nil {
+				return err
+			}
+			if _, err := updateStmt.Exec(info.terminalStatus, info.errorMsg, id, stats); err != nil {
 				return fmt.Errorf("update execution %s status: %w", id, err)
 			}
 		}
@@ -152,10 +160,16 @@ func upsertExecutions(tx *sql.Tx, events []batcher.AuditEvent) error {
 
 // execInfo tracks the execution header data needed to upsert the executions row.
 type execInfo struct {
+	workspace      string // owning tenant; "default" when the event carries none
 	flowID         string
 	terminalStatus string // COMPLETED | FAILED | REPLAYED, or ""
 	errorMsg       string
 	triggerType    string // "lifecycle" for deploy/stop events, empty otherwise
+	parentID       string // execution retried or replayed, or ""
+	version        string // definition.version of the process, or ""
+
+	// stats is the execution_stats of the terminal event, if it carried any.
+	stats map[string]interface{}
 }
 
 // classifyExecutions scans a batch of events and returns per-execution metadata:
@@ -173,16 +187,28 @@ func classifyExecutions(events []batcher.AuditEvent) map[string]*execInfo {
 			if flowID == "" {
 				flowID = "unknown"
 			}
-			info = &execInfo{flowID: flowID}
+			info = &execInfo{flowID: flowID, workspace: eventWorkspace(e), parentID: e.ParentExecutionID}
 			infos[e.ExecutionID] = info
 		} else if info.flowID == "unknown" && e.FlowID != "" {
 			info.flowID = e.FlowID
 		}
+		if info.version == "" {
+			info.version = e.ProcessVersion
+		}
 		updateExecInfo(info, e)
 	}
 	return infos
 }
 
+// eventWorkspace returns the workspace carried by e, defaulting to "default"
+// for events published by engines that predate multi-tenancy.
+func eventWorkspace(e batcher.AuditEvent) string {
+	if e.Workspace == "" {
+		return "default"
+	}
+	return e.Workspace
+}
+
 // updateExecInfo applies process- and lifecycle-level status updates from a
 // single audit event onto an existing execInfo. Extracted from classifyExecutions
 // to keep cyclomatic complexity below the project limit of 10.
@@ -193,6 +219,7 @@ func updateExecInfo(info *execInfo, e batcher.AuditEvent) {
 		if status == "COMPLETED" || status == "FAILED" || status == "REPLAYED" {
 			info.terminalStatus = status
 			info.errorMsg = e.ErrorMsg
+			info.stats = e.ExecutionStats
 		}
 	}
 	// Mark executions that originate from lifecycle (deploy/stop) events so
@@ -204,9 +231,12 @@ func updateExecInfo(info *execInfo, e batcher.AuditEvent) {
 
 // insertActivityLogs inserts all events in a single parameterised multi-row INSERT.
 // Events with an empty ExecutionID are skipped to avoid invalid-UUID errors on the
-// activity_logs.execution_id UUID column.
-func insertActivityLogs(tx *sql.Tx, events []batcher.AuditEvent) error {
-	const cols = 7 // execution_id, node_id, node_type, status, input_data, output_data, error_details
+// activity_logs.execution_id UUID column. created_at is the event timestamp, so
+// timelines keep the order and spacing of the events rather than of the batch.
+// Inputs and outputs are stored as JSONB, compressed or fingerprinted as
+// payload says.
+func insertActivityLogs(tx *sql.Tx, events []batcher.AuditEvent, payload PayloadConfig) error {
+	const cols = 14 // execution_id, node_id, node_type, status, input_data, output_data, error_details, duration_ms, attempt, engine_id, process_version, created_at, input_data_gz, output_data_gz
 	placeholders := make([]string, 0, len(events))
 	args := make([]interface{}, 0, len(events)*cols)
 
@@ -218,15 +248,15 @@ func insertActivityLogs(tx *sql.Tx, events []batcher.AuditEvent) error {
 		base := idx * cols
 		idx++
 		placeholders = append(placeholders, fmt.Sprintf(
-			"($%d,$%d,$%d,$%d,$%d,$%d,$%d)",
-			base+1, base+2, base+3, base+4, base+5, base+6, base+7,
+			"($%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d,NULLIF($%d::int, 0),NULLIF($%d, ''),NULLIF($%d, ''),COALESCE($%d::timestamptz, NOW()),$%d,$%d)",
+			base+1, base+2, base+3, base+4, base+5, base+6, base+7, base+8, base+9, base+10, base+11, base+12, base+13, base+14,
 		))
 
-		inputJSON, err := marshalJSONB(e.InputData)
+		inputJSON, inputGz, err := payload.encodePayload(e.InputData)
 		if err != nil {
 			return err
 		}
-		outputJSON, err := marshalJSONB(e.OutputData)
+		outputJSON, outputGz, err := payload.encodePayload(e.OutputData)
 		if err != nil {
 			return err
 		}
@@ -246,6 +276,13 @@ func insertActivityLogs(tx *sql.Tx, events []batcher.AuditEvent) error {
 			inputJSON,
 			outputJSON,
 			errorJSON,
+			e.DurationMs,
+			e.Attempt,
+			e.EngineID,
+			e.ProcessVersion,
+			eventTimestamp(e.Timestamp),
+			inputGz,
+			outputGz,
 		)
 	}
 
@@ -255,7 +292,8 @@ func insertActivityLogs(tx *sql.Tx, events []batcher.AuditEvent) error {
 
 	query := fmt.Sprintf(
 		`INSERT INTO activity_logs
-			(execution_id, node_id, node_type, status, input_data, output_data, error_details)
+			(execution_id, node_id, node_type, status, input_data, output_data, error_details, duration_ms,
+			 attempt, engine_id, process_version, created_at, input_data_gz, output_data_gz)
 		 VALUES %s`,
 		strings.Join(placeholders, ","),
 	)
@@ -266,6 +304,16 @@ func insertActivityLogs(tx *sql.Tx, events []batcher.AuditEvent) error {
 	return nil
 }
 
+// eventTimestamp returns the time of an event for activity_logs.created_at,
+// or nil (the insert time) when the event carries no valid timestamp.
+func eventTimestamp(ts string) interface{} {
+	t, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return nil
+	}
+	return t.UTC()
+}
+
 // marshalJSONB converts a map to a JSON byte slice suitable for a JSONB column.
 // Returns nil when the map is nil or empty (stores SQL NULL).
 func marshalJSONB(m map[string]interface{}) ([]byte, error) {
@@ -217,6 +217,32 @@ func TestClassifyExecutions_UnknownFlowIDResolvedLater(t *testing.T) {
 // insertActivityLogs argument-count tests (no DB required)
 // ---------------------------------------------------------------------------
 
+// TestClassifyExecutions_Workspace verifies that the execution header takes the
+// workspace of its events and falls back to "default" for legacy events.
+func TestClassifyExecutions_Workspace(t *testing.T) {
+	scoped := makeProcessEvent("exec-ws-1", "flow-a", "started")
+	scoped.Workspace = "team-a"
+	events := []batcher.AuditEvent{scoped, makeProcessEvent("exec-ws-2", "flow-b", "started")}
+
+	infos := classifyExecutions(events)
+
+	require.Contains(t, infos, "exec-ws-1")
+	require.Contains(t, infos, "exec-ws-2")
+	assert.Equal(t, "team-a", infos["exec-ws-1"].workspace)
+	assert.Equal(t, "default", infos["exec-ws-2"].workspace)
+}
+
+// TestClassifyExecutions_ParentExecution verifies that the parent of a retry
+// or replay is recorded on its execution row.
+func TestClassifyExecutions_ParentExecution(t *testing.T) {
+	child := makeProcessEvent("exec-child", "flow-a", "started")
+	child.ParentExecutionID = "exec-parent"
+	infos := classifyExecutions([]batcher.AuditEvent{child, makeProcessEvent("exec-root", "flow-a", "started")})
+
+	assert.Equal(t, "exec-parent", infos["exec-child"].parentID)
+	assert.Empty(t, infos["exec-root"].parentID)
+}
+
 // TestInsertActivityLogs_SkipsEmptyExecutionID ensures that events without an
 // ExecutionID are excluded from the placeholder list that would later be sent to
 // the database — preventing "invalid input syntax for type uuid" errors that
@@ -236,3 +262,31 @@ func TestInsertActivityLogs_SkipsEmptyExecutionID(t *testing.T) {
 	assert.NotContains(t, infos, "", "empty ExecutionID must not appear in the infos map")
 	assert.Len(t, infos, 2, "only the two valid-uuid entries must be present")
 }
+
+// TestClassifyExecutions_TerminalEventStats verifies that the execution_stats
+// of the terminal process event are kept for the executions row.
+func TestClassifyExecutions_TerminalEventStats(t *testing.T) {
+	completed := makeProcessEvent("exec-1", "flow-1", "completed")
+	completed.ExecutionStats = map[string]interface{}{"wall_ms": float64(12), "rows_processed": float64(3)}
+	events := []batcher.AuditEvent{
+		makeProcessEvent("exec-1", "flow-1", "started"),
+		completed,
+	}
+
+	infos := classifyExecutions(events)
+
+	require.Contains(t, infos, "exec-1")
+	assert.Equal(t, completed.ExecutionStats, infos["exec-1"].stats)
+}
+
+// TestClassifyExecutions_ProcessVersion verifies that the executions row gets
+// the process version of the first event carrying one.
+func TestClassifyExecutions_ProcessVersion(t *testing.T) {
+	started := makeProcessEvent("exec-1", "flow-1", "started")
+	node := makeNodeEvent("exec-1", "flow-1", "n1", "logger", "success")
+	node.ProcessVersion = "1.2.0"
+	infos := classifyExecutions([]batcher.AuditEvent{started, node, makeProcessEvent("exec-2", "flow-1", "started")})
+
+	assert.Equal(t, "1.2.0", infos["exec-1"].version)
+	assert.Empty(t, infos["exec-2"].version)
+}
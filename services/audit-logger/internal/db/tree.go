@@ -0,0 +1,100 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// maxTreeExecutions bounds the executions returned by ExecutionTree, so a
+// replay chain gone wrong cannot produce an unbounded response.
+const maxTreeExecutions = 1000
+
+// ExecutionNode is one execution of an execution tree with the executions
+// that retried or replayed it.
+type ExecutionNode struct {
+	ExecutionID       string           `json:"execution_id"`
+	ParentExecutionID string           `json:"parent_execution_id,omitempty"`
+	FlowID            string           `json:"flow_id"`
+	Status            string           `json:"status"`
+	StartTime         time.Time        `json:"start_time"`
+	EndTime           *time.Time       `json:"end_time,omitempty"`
+	Error             string           `json:"main_error_message,omitempty"`
+	Children          []*ExecutionNode `json:"children"`
+}
+
+// ExecutionTree returns the tree that executionID belongs to in workspace:
+// its root ancestor with every descendant, children ordered by start time.
+// It returns nil when the execution does not exist in workspace.
+func ExecutionTree(ctx context.Context, rawDB *sql.DB, workspace, executionID string) (*ExecutionNode, error) {
+	rows, err := rawDB.QueryContext(ctx, `
+		WITH RECURSIVE ancestors AS (
+		    SELECT execution_id, parent_execution_id, 0 AS depth
+		    FROM executions WHERE execution_id = $1 AND workspace = $2
+		  UNION ALL
+		    SELECT e.execution_id, e.parent_execution_id, a.depth + 1
+		    FROM executions e JOIN ancestors a ON e.execution_id = a.parent_execution_id
+		    WHERE e.workspace = $2 AND a.depth < $3
+		), root AS (
+		    SELECT execution_id FROM ancestors ORDER BY depth DESC LIMIT 1
+		), tree AS (
+		    SELECT e.execution_id, e.parent_execution_id, 0 AS depth
+		    FROM executions e JOIN root r ON e.execution_id = r.execution_id
+		  UNION ALL
+		    SELECT e.execution_id, e.parent_execution_id, t.depth + 1
+		    FROM executions e JOIN tree t ON e.parent_execution_id = t.execution_id
+		    WHERE e.workspace = $2 AND t.depth < $3
+		)
+		SELECT e.execution_id, COALESCE(e.parent_execution_id::text, ''), e.flow_id,
+		       COALESCE(e.status, ''), e.start_time, e.end_time, COALESCE(e.main_error_message, '')
+		FROM executions e JOIN tree t ON e.execution_id = t.execution_id
+		LIMIT $3`, executionID, workspace, maxTreeExecutions)
+	if err != nil {
+		return nil, fmt.Errorf("query execution tree: %w", err)
+	}
+	defer rows.Close()
+
+	var nodes []*ExecutionNode
+	for rows.Next() {
+		n := &ExecutionNode{}
+		var endTime sql.NullTime
+		if err := rows.Scan(&n.ExecutionID, &n.ParentExecutionID, &n.FlowID, &n.Status, &n.StartTime, &endTime, &n.Error); err != nil {
+			return nil, fmt.Errorf("scan execution tree row: %w", err)
+		}
+		if endTime.Valid {
+			n.EndTime = &endTime.Time
+		}
+		nodes = append(nodes, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read execution tree: %w", err)
+	}
+	return BuildExecutionTree(nodes), nil
+}
+
+// BuildExecutionTree links nodes to their parents and returns the root: the
+// node whose parent is not among nodes. Children are ordered by start time.
+// It returns nil for no nodes.
+func BuildExecutionTree(nodes []*ExecutionNode) *ExecutionNode {
+	byID := make(map[string]*ExecutionNode, len(nodes))
+	for _, n := range nodes {
+		n.Children = []*ExecutionNode{}
+		byID[n.ExecutionID] = n
+	}
+	var root *ExecutionNode
+	for _, n := range nodes {
+		if parent, ok := byID[n.ParentExecutionID]; ok && parent != n {
+			parent.Children = append(parent.Children, n)
+		} else if root == nil {
+			root = n
+		}
+	}
+	for _, n := range nodes {
+		sort.SliceStable(n.Children, func(i, j int) bool {
+			return n.Children[i].StartTime.Before(n.Children[j].StartTime)
+		})
+	}
+	return root
+}
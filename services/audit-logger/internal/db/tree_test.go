@@ -0,0 +1,38 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildExecutionTree(t *testing.T) {
+	t0 := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	nodes := []*ExecutionNode{
+		{ExecutionID: "retry-2", ParentExecutionID: "root", StartTime: t0.Add(2 * time.Hour)},
+		{ExecutionID: "root", StartTime: t0},
+		{ExecutionID: "replay", ParentExecutionID: "retry-1", StartTime: t0.Add(3 * time.Hour)},
+		{ExecutionID: "retry-1", ParentExecutionID: "root", StartTime: t0.Add(time.Hour)},
+	}
+
+	root := BuildExecutionTree(nodes)
+	require.NotNil(t, root)
+	assert.Equal(t, "root", root.ExecutionID)
+	require.Len(t, root.Children, 2)
+	assert.Equal(t, "retry-1", root.Children[0].ExecutionID, "children are ordered by start time")
+	assert.Equal(t, "retry-2", root.Children[1].ExecutionID)
+	require.Len(t, root.Children[0].Children, 1)
+	assert.Equal(t, "replay", root.Children[0].Children[0].ExecutionID)
+	assert.NotNil(t, root.Children[1].Children, "leaves encode as []")
+}
+
+func TestBuildExecutionTree_ParentOutsideResult(t *testing.T) {
+	// The parent may live in another workspace or have been purged.
+	root := BuildExecutionTree([]*ExecutionNode{{ExecutionID: "orphan", ParentExecutionID: "gone"}})
+	require.NotNil(t, root)
+	assert.Equal(t, "orphan", root.ExecutionID)
+
+	assert.Nil(t, BuildExecutionTree(nil))
+}
@@ -0,0 +1,153 @@
+package middleware
+
+import (
+	"context"
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// API-key authentication  (A01 Broken Access Control)
+// ──────────────────────────────────────────────────────────────────────────────
+
+// DefaultWorkspace mirrors the engine's default tenant; it is used when no API
+// keys are configured (development) and for events published without one.
+const DefaultWorkspace = "default"
+
+// validWorkspaceRe matches the engine's workspace naming rule.
+var validWorkspaceRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+// RoleAdmin is the API key role allowed to use the admin API, as in the engine.
+const RoleAdmin = "admin"
+
+// Caller is what an API key grants: the workspace queries are scoped to and
+// whether the admin API is allowed.
+type Caller struct {
+	Workspace string
+	Admin     bool
+}
+
+// devCaller is the caller of every request when no API keys are configured.
+var devCaller = Caller{Workspace: DefaultWorkspace, Admin: true}
+
+type callerKey struct{}
+
+// WorkspaceFromContext returns the workspace resolved by Authenticate, or
+// DefaultWorkspace when none is present.
+func WorkspaceFromContext(ctx context.Context) string {
+	if c, ok := ctx.Value(callerKey{}).(Caller); ok && c.Workspace != "" {
+		return c.Workspace
+	}
+	return DefaultWorkspace
+}
+
+// IsAdmin reports whether the caller resolved by Authenticate has the admin
+// role. Without API keys (development) every caller is an admin.
+func IsAdmin(ctx context.Context) bool {
+	c, ok := ctx.Value(callerKey{}).(Caller)
+	return ok && c.Admin
+}
+
+// APIKeys reads the API_KEYS environment variable (same format as the engine:
+// comma-separated "key:workspace:subject[:role[:team]]" entries) and returns the
+// caller bound to each key. An empty result in a non-development
+// environment terminates the process (log.Fatalf).
+func APIKeys() map[string]Caller {
+	keys := ParseAPIKeys(os.Getenv("API_KEYS"))
+	if len(keys) == 0 {
+		if os.Getenv("APP_ENV") != "development" {
+			log.Fatalf("middleware: API_KEYS must be set in non-development environments")
+		}
+		log.Printf("middleware: WARNING — API_KEYS not set; all requests use the %q workspace (development only)", DefaultWorkspace)
+	}
+	return keys
+}
+
+// ParseAPIKeys parses the API_KEYS format described in APIKeys. Only the
+// workspace and the admin role are retained; the audit API has no
+// per-subject permissions.
+func ParseAPIKeys(raw string) map[string]Caller {
+	keys := make(map[string]Caller)
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.Split(entry, ":")
+		if len(parts) < 3 || parts[0] == "" || parts[2] == "" || !validWorkspaceRe.MatchString(parts[1]) {
+			log.Printf("middleware: WARNING — ignoring malformed API_KEYS entry")
+			continue
+		}
+		keys[parts[0]] = Caller{Workspace: parts[1], Admin: len(parts) > 3 && parts[3] == RoleAdmin}
+	}
+	return keys
+}
+
+// Authenticate returns a middleware that resolves the caller's workspace from
+// the "Authorization: Bearer <key>" or "X-API-Key" header and stores it in the
+// request context. Requests without a valid key get HTTP 401. Paths starting
+// with one of publicPrefixes skip authentication. When keys is empty every
+// request is scoped to DefaultWorkspace with the admin role.
+func Authenticate(keys map[string]Caller, publicPrefixes ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if len(keys) == 0 {
+				next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), callerKey{}, devCaller)))
+				return
+			}
+			if r.Method == http.MethodOptions || hasAnyPrefix(r.URL.Path, publicPrefixes) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			caller, ok := lookupAPIKey(keys, requestAPIKey(r))
+			if !ok {
+				SecurityLog("AUTH_FAILED", clientIP(r), r.Method, r.URL.Path, http.StatusUnauthorized)
+				http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), callerKey{}, caller)))
+		})
+	}
+}
+
+// requestAPIKey extracts the API key from the request headers.
+func requestAPIKey(r *http.Request) string {
+	if k := r.Header.Get("X-API-Key"); k != "" {
+		return k
+	}
+	auth := r.Header.Get("Authorization")
+	if strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+	}
+	return ""
+}
+
+// lookupAPIKey compares candidate against every configured key in constant time.
+func lookupAPIKey(keys map[string]Caller, candidate string) (Caller, bool) {
+	var (
+		found Caller
+		ok    bool
+	)
+	if candidate == "" {
+		return Caller{}, false
+	}
+	for k, c := range keys {
+		if subtle.ConstantTimeCompare([]byte(k), []byte(candidate)) == 1 {
+			found, ok = c, true
+		}
+	}
+	return found, ok
+}
+
+func hasAnyPrefix(path string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(path, p) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"flowjs-works/audit-logger/internal/middleware"
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// API-key authentication tests (A01)
+// ──────────────────────────────────────────────────────────────────────────────
+
+func workspaceEcho() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(middleware.WorkspaceFromContext(r.Context())))
+	})
+}
+
+func TestParseAPIKeys(t *testing.T) {
+	keys := middleware.ParseAPIKeys("k1:team-a:alice:admin,k2:team-b:bot,broken")
+	require.Len(t, keys, 2)
+	assert.Equal(t, "team-a", keys["k1"].Workspace)
+	assert.True(t, keys["k1"].Admin)
+	assert.Equal(t, "team-b", keys["k2"].Workspace)
+	assert.False(t, keys["k2"].Admin)
+}
+
+func TestAuthenticateRejectsUnknownKey(t *testing.T) {
+	handler := middleware.Authenticate(middleware.ParseAPIKeys("k1:team-a:alice"))(workspaceEcho())
+
+	req := httptest.NewRequest(http.MethodGet, "/executions", nil)
+	req.Header.Set("Authorization", "Bearer nope")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestAuthenticateScopesWorkspace(t *testing.T) {
+	handler := middleware.Authenticate(middleware.ParseAPIKeys("k1:team-a:alice"), "/health")(workspaceEcho())
+
+	req := httptest.NewRequest(http.MethodGet, "/executions", nil)
+	req.Header.Set("X-API-Key", "k1")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, "team-a", rec.Body.String())
+
+	req = httptest.NewRequest(http.MethodGet, "/health", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestAuthenticateWithoutKeysUsesDefaultWorkspace(t *testing.T) {
+	handler := middleware.Authenticate(nil)(workspaceEcho())
+
+	req := httptest.NewRequest(http.MethodGet, "/executions", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, middleware.DefaultWorkspace, rec.Body.String())
+}
+
+func TestAuthenticateAdminRole(t *testing.T) {
+	admin := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if middleware.IsAdmin(r.Context()) {
+			_, _ = w.Write([]byte("admin"))
+		}
+	})
+	handler := middleware.Authenticate(middleware.ParseAPIKeys("k1:team-a:alice:admin,k2:team-a:bob"))(admin)
+
+	for key, want := range map[string]string{"k1": "admin", "k2": ""} {
+		req := httptest.NewRequest(http.MethodGet, "/admin/dead-letters", nil)
+		req.Header.Set("X-API-Key", key)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, want, rec.Body.String(), key)
+	}
+
+	rec := httptest.NewRecorder()
+	middleware.Authenticate(nil)(admin).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/dead-letters", nil))
+	assert.Equal(t, "admin", rec.Body.String())
+}
@@ -1,5 +1,6 @@
 // Package middleware provides HTTP middleware for security hardening.
 // It implements mitigations for OWASP Top 10 (2021):
+//   - A01 Broken Access Control  — API-key gate scoping requests to a workspace
 //   - A02 Cryptographic Failures — HSTS header (enforces HTTPS in production)
 //   - A04 Insecure Design        — IP-based rate limiting (token bucket)
 //   - A05 Security Misconfiguration — restrictive CORS, security headers
@@ -12,6 +13,7 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -24,6 +26,9 @@ import (
 const (
 	// defaultAllowedOrigin is used only in development when ALLOWED_ORIGINS is unset.
 	defaultAllowedOrigin = "http://localhost:5173"
+	// defaultCORSMaxAge is how long browsers cache preflight responses when
+	// CORS_MAX_AGE is unset.
+	defaultCORSMaxAge = 24 * time.Hour
 
 	// rateLimitRequests is the maximum number of requests per window per IP.
 	rateLimitRequests = 100
@@ -37,25 +42,108 @@ const (
 // CORS
 // ──────────────────────────────────────────────────────────────────────────────
 
-// CORS returns a middleware that validates the Origin header against the list of
-// allowed origins read from the ALLOWED_ORIGINS environment variable
-// (comma-separated). When the variable is not set and APP_ENV is "development",
-// it defaults to localhost:5173. In any other environment an empty or missing
-// ALLOWED_ORIGINS causes the server to log a fatal error at startup.
+// CORSConfig is the cross-origin policy applied by CORSWithConfig. Origins
+// are matched exactly; a "*" entry is never honoured, so a misconfiguration
+// cannot open the API to every site.
+type CORSConfig struct {
+	Origins          []string
+	Methods          []string
+	Headers          []string
+	ExposeHeaders    []string
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache a preflight response.
+	MaxAge time.Duration
+}
+
+// DefaultCORSConfig returns the policy used when only the origins are configured.
+func DefaultCORSConfig(origins []string) CORSConfig {
+	return CORSConfig{
+		Origins: origins,
+		Methods: []string{"GET", "POST", "OPTIONS"},
+		Headers: []string{"Content-Type", "Authorization", "X-API-Key"},
+		MaxAge:  defaultCORSMaxAge,
+	}
+}
+
+// CORSConfigFromEnv builds the CORS policy from the environment:
+//
+//	ALLOWED_ORIGINS         comma-separated origins (see AllowedOrigins)
+//	CORS_ALLOWED_METHODS    comma-separated methods (default GET, POST, OPTIONS)
+//	CORS_ALLOWED_HEADERS    comma-separated request headers (default Content-Type, Authorization, X-API-Key)
+//	CORS_EXPOSED_HEADERS    comma-separated response headers
+//	CORS_ALLOW_CREDENTIALS  "true" to send Access-Control-Allow-Credentials
+//	CORS_MAX_AGE            preflight cache duration, go format (default 24h)
+//
+// Like AllowedOrigins it terminates the process on invalid values rather than
+// starting with a policy other than the one configured.
+func CORSConfigFromEnv() CORSConfig {
+	cfg := DefaultCORSConfig(AllowedOrigins())
+	if v := os.Getenv("CORS_ALLOWED_METHODS"); v != "" {
+		cfg.Methods = splitList(v)
+	}
+	if v := os.Getenv("CORS_ALLOWED_HEADERS"); v != "" {
+		cfg.Headers = splitList(v)
+	}
+	if v := os.Getenv("CORS_EXPOSED_HEADERS"); v != "" {
+		cfg.ExposeHeaders = splitList(v)
+	}
+	if v := os.Getenv("CORS_ALLOW_CREDENTIALS"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			log.Fatalf("middleware: invalid CORS_ALLOW_CREDENTIALS %q: %v", v, err)
+		}
+		cfg.AllowCredentials = b
+	}
+	if v := os.Getenv("CORS_MAX_AGE"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			log.Fatalf("middleware: invalid CORS_MAX_AGE %q", v)
+		}
+		cfg.MaxAge = d
+	}
+	return cfg
+}
+
+// CORS returns a middleware that applies DefaultCORSConfig(origins).
+//
+// Usage: wrap your mux with CORS(mux) after calling AllowedOrigins(), or use
+// CORSWithConfig(CORSConfigFromEnv()) to honour the CORS_* variables.
 func CORS(origins []string) func(http.Handler) http.Handler {
-	allowed := make(map[string]bool, len(origins))
-	for _, o := range origins {
+	return CORSWithConfig(DefaultCORSConfig(origins))
+}
+
+// CORSWithConfig returns a middleware that validates the Origin header against
+// cfg.Origins and, for allowed origins, sets the configured CORS headers.
+// Preflight (OPTIONS) requests are answered with 204 without reaching next.
+func CORSWithConfig(cfg CORSConfig) func(http.Handler) http.Handler {
+	allowed := make(map[string]bool, len(cfg.Origins))
+	for _, o := range cfg.Origins {
+		if o == "*" {
+			log.Printf("middleware: WARNING — ignoring wildcard CORS origin; list origins explicitly")
+			continue
+		}
 		allowed[strings.TrimSuffix(o, "/")] = true
 	}
+	methods := strings.Join(cfg.Methods, ", ")
+	headers := strings.Join(cfg.Headers, ", ")
+	expose := strings.Join(cfg.ExposeHeaders, ", ")
+	maxAge := strconv.Itoa(int(cfg.MaxAge.Seconds()))
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			origin := r.Header.Get("Origin")
 			if origin != "" && allowed[origin] {
-				w.Header().Set("Access-Control-Allow-Origin", origin)
-				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-				w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-				w.Header().Set("Access-Control-Max-Age", "86400")
-				w.Header().Set("Vary", "Origin")
+				h := w.Header()
+				h.Set("Access-Control-Allow-Origin", origin)
+				h.Set("Access-Control-Allow-Methods", methods)
+				h.Set("Access-Control-Allow-Headers", headers)
+				if expose != "" {
+					h.Set("Access-Control-Expose-Headers", expose)
+				}
+				if cfg.AllowCredentials {
+					h.Set("Access-Control-Allow-Credentials", "true")
+				}
+				h.Set("Access-Control-Max-Age", maxAge)
+				h.Set("Vary", "Origin")
 			}
 			if r.Method == http.MethodOptions {
 				w.WriteHeader(http.StatusNoContent)
@@ -66,6 +154,17 @@ func CORS(origins []string) func(http.Handler) http.Handler {
 	}
 }
 
+// splitList splits a comma-separated value, dropping blank entries.
+func splitList(raw string) []string {
+	var out []string
+	for _, v := range strings.Split(raw, ",") {
+		if trimmed := strings.TrimSpace(v); trimmed != "" {
+			out = append(out, trimmed)
+		}
+	}
+	return out
+}
+
 // AllowedOrigins reads and validates the ALLOWED_ORIGINS environment variable.
 // It terminates the process (log.Fatalf) when the variable is empty in non-development
 // environments, preventing accidental wildcard CORS in production.
@@ -78,13 +177,7 @@ func AllowedOrigins() []string {
 		log.Printf("middleware: WARNING — ALLOWED_ORIGINS not set; defaulting to %s (development only)", defaultAllowedOrigin)
 		return []string{defaultAllowedOrigin}
 	}
-	var origins []string
-	for _, o := range strings.Split(raw, ",") {
-		if trimmed := strings.TrimSpace(o); trimmed != "" {
-			origins = append(origins, trimmed)
-		}
-	}
-	return origins
+	return splitList(raw)
 }
 
 // ──────────────────────────────────────────────────────────────────────────────
@@ -99,6 +99,50 @@ func TestCORSWildcardNotEmitted(t *testing.T) {
 	assert.NotEqual(t, "*", rec.Header().Get("Access-Control-Allow-Origin"))
 }
 
+func TestCORSWithConfigAppliesPolicy(t *testing.T) {
+	cfg := middleware.CORSConfig{
+		Origins:          []string{"https://app.example.com", "*"},
+		Methods:          []string{"GET", "PUT"},
+		Headers:          []string{"Content-Type"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	}
+	handler := middleware.CORSWithConfig(cfg)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodOptions, "/x", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, "GET, PUT", rec.Header().Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "Content-Type", rec.Header().Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "true", rec.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, "600", rec.Header().Get("Access-Control-Max-Age"))
+
+	// The "*" entry is ignored: other origins still get no CORS headers.
+	req = httptest.NewRequest(http.MethodGet, "/x", nil)
+	req.Header.Set("Origin", "https://other.example.com")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestCORSConfigFromEnv(t *testing.T) {
+	t.Setenv("ALLOWED_ORIGINS", "https://app.example.com")
+	t.Setenv("CORS_ALLOWED_METHODS", "GET, OPTIONS")
+	t.Setenv("CORS_ALLOW_CREDENTIALS", "true")
+	t.Setenv("CORS_MAX_AGE", "1h")
+	cfg := middleware.CORSConfigFromEnv()
+	assert.Equal(t, []string{"https://app.example.com"}, cfg.Origins)
+	assert.Equal(t, []string{"GET", "OPTIONS"}, cfg.Methods)
+	assert.Equal(t, middleware.DefaultCORSConfig(nil).Headers, cfg.Headers)
+	assert.True(t, cfg.AllowCredentials)
+	assert.Equal(t, time.Hour, cfg.MaxAge)
+}
+
 // ──────────────────────────────────────────────────────────────────────────────
 // AllowedOrigins tests
 // ──────────────────────────────────────────────────────────────────────────────
@@ -0,0 +1,329 @@
+// Package opensearch persists audit batches to OpenSearch or Elasticsearch
+// through the _bulk API, as an alternative (or addition) to PostgreSQL for
+// deployments that centralise execution history in a search cluster.
+//
+// Events are written with the "create" action to time-based indexes named
+// <index>-YYYY.MM.DD (or <index>-YYYY.MM), which an index template and an
+// ILM / ISM policy matching "<index>-*" can manage. With rollover "none" every
+// event goes to <index> itself, which should be a write alias or a data stream.
+package opensearch
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"flowjs-works/audit-logger/internal/batcher"
+)
+
+// Index rollover periods.
+const (
+	RolloverDaily   = "daily"
+	RolloverMonthly = "monthly"
+	RolloverNone    = "none"
+)
+
+// DefaultIndex is the index prefix used when OPENSEARCH_INDEX is unset.
+const DefaultIndex = "flowjs-audit"
+
+// maxAttempts is how many times a bulk request is sent when the cluster is
+// unreachable or answers 429 / 5xx.
+const maxAttempts = 3
+
+// Config configures a Client.
+type Config struct {
+	// URL is the base URL of the cluster, e.g. https://search:9200.
+	URL string
+	// Index is the index prefix, or the alias / data stream with RolloverNone.
+	Index string
+	// Rollover is daily (default), monthly or none.
+	Rollover string
+	// Username and Password enable basic authentication.
+	Username string
+	Password string
+	// APIKey is sent as "Authorization: ApiKey <key>" and takes precedence
+	// over basic authentication.
+	APIKey string
+}
+
+// ConfigFromEnv reads OPENSEARCH_URL, OPENSEARCH_INDEX,
+// OPENSEARCH_INDEX_ROLLOVER, OPENSEARCH_USERNAME, OPENSEARCH_PASSWORD and
+// OPENSEARCH_API_KEY.
+func ConfigFromEnv() Config {
+	return Config{
+		URL:      strings.TrimSpace(os.Getenv("OPENSEARCH_URL")),
+		Index:    strings.TrimSpace(os.Getenv("OPENSEARCH_INDEX")),
+		Rollover: strings.ToLower(strings.TrimSpace(os.Getenv("OPENSEARCH_INDEX_ROLLOVER"))),
+		Username: os.Getenv("OPENSEARCH_USERNAME"),
+		Password: os.Getenv("OPENSEARCH_PASSWORD"),
+		APIKey:   os.Getenv("OPENSEARCH_API_KEY"),
+	}
+}
+
+// Client writes audit events to OpenSearch / Elasticsearch.
+type Client struct {
+	cfg     Config
+	bulkURL string
+	http    *http.Client
+	backoff time.Duration
+}
+
+// New validates cfg and returns a Client. Unset Index and Rollover default to
+// DefaultIndex and RolloverDaily.
+func New(cfg Config) (*Client, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("opensearch: URL is required")
+	}
+	u, err := url.Parse(cfg.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("opensearch: invalid URL %q", cfg.URL)
+	}
+	if cfg.Index == "" {
+		cfg.Index = DefaultIndex
+	}
+	if cfg.Index != strings.ToLower(cfg.Index) || strings.ContainsAny(cfg.Index, ` "*\<|,>/?#`) {
+		return nil, fmt.Errorf("opensearch: invalid index name %q", cfg.Index)
+	}
+	switch cfg.Rollover {
+	case "":
+		cfg.Rollover = RolloverDaily
+	case RolloverDaily, RolloverMonthly, RolloverNone:
+	default:
+		return nil, fmt.Errorf("opensearch: rollover must be daily, monthly or none, got %q", cfg.Rollover)
+	}
+	return &Client{
+		cfg:     cfg,
+		bulkURL: strings.TrimRight(cfg.URL, "/") + "/_bulk",
+		http:    &http.Client{Timeout: 30 * time.Second},
+		backoff: time.Second,
+	}, nil
+}
+
+// IndexName returns the index an event recorded at ts is written to.
+func (c *Client) IndexName(ts time.Time) string {
+	switch c.cfg.Rollover {
+	case RolloverMonthly:
+		return c.cfg.Index + "-" + ts.UTC().Format("2006.01")
+	case RolloverNone:
+		return c.cfg.Index
+	default:
+		return c.cfg.Index + "-" + ts.UTC().Format("2006.01.02")
+	}
+}
+
+// document is the indexed form of a batcher.AuditEvent. Input and output are
+// stored as JSON strings so arbitrary node payloads cannot cause mapping
+// conflicts between events.
+type document struct {
+	Timestamp   string `json:"@timestamp"`
+	ExecutionID string `json:"execution_id"`
+	Workspace   string `json:"workspace"`
+	FlowID      string `json:"flow_id"`
+	NodeID      string `json:"node_id"`
+	NodeType    string `json:"node_type"`
+	Status      string `json:"status"`
+	Input       string `json:"input,omitempty"`
+	Output      string `json:"output,omitempty"`
+	Error       string `json:"error,omitempty"`
+	DurationMs  int    `json:"duration_ms"`
+	// ParentExecutionID links retries and replays to the execution they rerun.
+	ParentExecutionID string `json:"parent_execution_id,omitempty"`
+	Attempt           int    `json:"attempt,omitempty"`
+	EngineID          string `json:"engine_id,omitempty"`
+	ProcessVersion    string `json:"process_version,omitempty"`
+}
+
+// BatchIndexLogs writes events with one bulk request. Every event gets a
+// document ID up front, so a retried request does not duplicate the events
+// the cluster already stored.
+func (c *Client) BatchIndexLogs(events []batcher.AuditEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	body, err := c.bulkBody(events)
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		var retry bool
+		retry, lastErr = c.send(body)
+		if lastErr == nil || !retry {
+			return lastErr
+		}
+		if attempt < maxAttempts {
+			time.Sleep(time.Duration(attempt) * c.backoff)
+		}
+	}
+	return fmt.Errorf("opensearch: bulk request failed after %d attempts: %w", maxAttempts, lastErr)
+}
+
+// bulkBody renders events as the NDJSON body of a _bulk request.
+func (c *Client) bulkBody(events []batcher.AuditEvent) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, ev := range events {
+		ts := eventTime(ev.Timestamp)
+		id, err := newDocumentID()
+		if err != nil {
+			return nil, err
+		}
+		action := map[string]map[string]string{"create": {"_index": c.IndexName(ts), "_id": id}}
+		doc := document{
+			Timestamp:   ts.Format(time.RFC3339Nano),
+			ExecutionID: ev.ExecutionID,
+			Workspace:   ev.Workspace,
+			FlowID:      ev.FlowID,
+			NodeID:      ev.NodeID,
+			NodeType:    ev.NodeType,
+			Status:      strings.ToUpper(ev.Status),
+			Input:       payloadJSON(ev.InputData),
+			Output:      payloadJSON(ev.OutputData),
+			Error:       ev.ErrorMsg,
+			DurationMs:  ev.DurationMs,
+		}
+		doc.ParentExecutionID = ev.ParentExecutionID
+		doc.Attempt, doc.EngineID, doc.ProcessVersion = ev.Attempt, ev.EngineID, ev.ProcessVersion
+		if doc.Workspace == "" {
+			doc.Workspace = "default"
+		}
+		if err := enc.Encode(action); err != nil {
+			return nil, fmt.Errorf("opensearch: encode bulk action: %w", err)
+		}
+		if err := enc.Encode(doc); err != nil {
+			return nil, fmt.Errorf("opensearch: encode event %s/%s: %w", ev.ExecutionID, ev.NodeID, err)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// bulkResponse is the part of the _bulk response inspected for failures.
+type bulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int `json:"status"`
+		Error  *struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error"`
+	} `json:"items"`
+}
+
+// send posts body to _bulk once. retry reports whether a failure is worth
+// another attempt: the cluster was unreachable, throttled or overloaded.
+func (c *Client) send(body []byte) (retry bool, err error) {
+	req, err := http.NewRequest(http.MethodPost, c.bulkURL, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("opensearch: build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	switch {
+	case c.cfg.APIKey != "":
+		req.Header.Set("Authorization", "ApiKey "+c.cfg.APIKey)
+	case c.cfg.Username != "":
+		req.SetBasicAuth(c.cfg.Username, c.cfg.Password)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("opensearch: bulk request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return true, fmt.Errorf("opensearch: read bulk response: %w", err)
+	}
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return true, fmt.Errorf("opensearch: bulk request: status %d: %s", resp.StatusCode, truncate(raw))
+	}
+	if resp.StatusCode >= 300 {
+		return false, fmt.Errorf("opensearch: bulk request: status %d: %s", resp.StatusCode, truncate(raw))
+	}
+
+	var br bulkResponse
+	if err := json.Unmarshal(raw, &br); err != nil {
+		return false, fmt.Errorf("opensearch: decode bulk response: %w", err)
+	}
+	if !br.Errors {
+		return false, nil
+	}
+	return bulkItemErrors(br)
+}
+
+// bulkItemErrors summarises the failed items of a bulk response. Version
+// conflicts are documents stored by an earlier attempt and do not count.
+// The request is retried when every failure is a rejection (429).
+func bulkItemErrors(br bulkResponse) (retry bool, err error) {
+	var (
+		failed   int
+		rejected int
+		first    string
+	)
+	for _, item := range br.Items {
+		for _, res := range item {
+			if res.Error == nil || res.Status == http.StatusConflict {
+				continue
+			}
+			failed++
+			if res.Status == http.StatusTooManyRequests {
+				rejected++
+			}
+			if first == "" {
+				first = fmt.Sprintf("%s: %s", res.Error.Type, res.Error.Reason)
+			}
+		}
+	}
+	if failed == 0 {
+		return false, nil
+	}
+	return rejected == failed, fmt.Errorf("opensearch: %d of %d events not indexed (%s)", failed, len(br.Items), first)
+}
+
+// eventTime parses an event timestamp, falling back to the current time for
+// events that carry none.
+func eventTime(ts string) time.Time {
+	if t, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+		return t.UTC()
+	}
+	return time.Now().UTC()
+}
+
+// payloadJSON encodes an event payload, or returns "" for an empty one.
+func payloadJSON(m map[string]interface{}) string {
+	if len(m) == 0 {
+		return ""
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+// newDocumentID returns a random 128-bit hex document ID.
+func newDocumentID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("opensearch: generate document id: %w", err)
+	}
+	return hex.EncodeToString(b[:]), nil
+}
+
+// truncate shortens a response body for error messages.
+func truncate(b []byte) string {
+	const max = 512
+	s := strings.TrimSpace(string(b))
+	if len(s) > max {
+		return s[:max] + "…"
+	}
+	return s
+}
@@ -0,0 +1,163 @@
+package opensearch
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"flowjs-works/audit-logger/internal/batcher"
+)
+
+func TestNew_Validation(t *testing.T) {
+	_, err := New(Config{})
+	assert.Error(t, err, "URL is required")
+
+	_, err = New(Config{URL: "search:9200"})
+	assert.Error(t, err, "URL needs a scheme")
+
+	_, err = New(Config{URL: "http://search:9200", Index: "Audit"})
+	assert.Error(t, err, "index names are lowercase")
+
+	_, err = New(Config{URL: "http://search:9200", Rollover: "weekly"})
+	assert.Error(t, err)
+
+	c, err := New(Config{URL: "http://search:9200/"})
+	require.NoError(t, err)
+	assert.Equal(t, "http://search:9200/_bulk", c.bulkURL)
+}
+
+func TestIndexName(t *testing.T) {
+	ts := time.Date(2024, 3, 7, 23, 30, 0, 0, time.FixedZone("CET", 3600))
+
+	daily, err := New(Config{URL: "http://search:9200"})
+	require.NoError(t, err)
+	assert.Equal(t, "flowjs-audit-2024.03.07", daily.IndexName(ts), "indexes are named by UTC date")
+
+	monthly, err := New(Config{URL: "http://search:9200", Index: "audit", Rollover: RolloverMonthly})
+	require.NoError(t, err)
+	assert.Equal(t, "audit-2024.03", monthly.IndexName(ts))
+
+	alias, err := New(Config{URL: "http://search:9200", Index: "audit-write", Rollover: RolloverNone})
+	require.NoError(t, err)
+	assert.Equal(t, "audit-write", alias.IndexName(ts))
+}
+
+// bulkLines decodes an NDJSON bulk body into alternating action and document maps.
+func bulkLines(t *testing.T, body []byte) []map[string]interface{} {
+	t.Helper()
+	var lines []map[string]interface{}
+	sc := bufio.NewScanner(bytes.NewReader(body))
+	for sc.Scan() {
+		var m map[string]interface{}
+		require.NoError(t, json.Unmarshal(sc.Bytes(), &m))
+		lines = append(lines, m)
+	}
+	return lines
+}
+
+func TestBatchIndexLogs_BulkBody(t *testing.T) {
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/_bulk", r.URL.Path)
+		assert.Equal(t, "application/x-ndjson", r.Header.Get("Content-Type"))
+		assert.Equal(t, "ApiKey secret", r.Header.Get("Authorization"))
+		body, _ = io.ReadAll(r.Body)
+		_, _ = w.Write([]byte(`{"errors":false,"items":[]}`))
+	}))
+	defer srv.Close()
+
+	c, err := New(Config{URL: srv.URL, APIKey: "secret"})
+	require.NoError(t, err)
+	err = c.BatchIndexLogs([]batcher.AuditEvent{{
+		ExecutionID: "exec-1",
+		FlowID:      "flow-1",
+		NodeID:      "n1",
+		NodeType:    "http",
+		Status:      "success",
+		InputData:   map[string]interface{}{"url": "https://example.com"},
+		DurationMs:  42,
+		Timestamp:   "2024-03-07T10:00:00Z",
+		Attempt:     2,
+		EngineID:    "engine-a",
+	}})
+	require.NoError(t, err)
+
+	lines := bulkLines(t, body)
+	require.Len(t, lines, 2)
+	action := lines[0]["create"].(map[string]interface{})
+	assert.Equal(t, "flowjs-audit-2024.03.07", action["_index"])
+	assert.Len(t, action["_id"], 32)
+
+	doc := lines[1]
+	assert.Equal(t, "2024-03-07T10:00:00Z", doc["@timestamp"])
+	assert.Equal(t, "default", doc["workspace"])
+	assert.Equal(t, "SUCCESS", doc["status"])
+	assert.Equal(t, `{"url":"https://example.com"}`, doc["input"])
+	assert.NotContains(t, doc, "output")
+	assert.Equal(t, float64(42), doc["duration_ms"])
+	assert.Equal(t, float64(2), doc["attempt"])
+	assert.Equal(t, "engine-a", doc["engine_id"])
+	assert.NotContains(t, doc, "process_version")
+}
+
+func TestBatchIndexLogs_ItemErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"errors":true,"items":[
+			{"create":{"status":201}},
+			{"create":{"status":409,"error":{"type":"version_conflict_engine_exception","reason":"exists"}}},
+			{"create":{"status":400,"error":{"type":"mapper_parsing_exception","reason":"bad field"}}}
+		]}`))
+	}))
+	defer srv.Close()
+
+	c, err := New(Config{URL: srv.URL})
+	require.NoError(t, err)
+	err = c.BatchIndexLogs(make([]batcher.AuditEvent, 3))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "1 of 3 events not indexed")
+	assert.Contains(t, err.Error(), "mapper_parsing_exception")
+}
+
+func TestBatchIndexLogs_RetriesThrottling(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte(`{"errors":false,"items":[]}`))
+	}))
+	defer srv.Close()
+
+	c, err := New(Config{URL: srv.URL})
+	require.NoError(t, err)
+	c.backoff = time.Millisecond
+	require.NoError(t, c.BatchIndexLogs(make([]batcher.AuditEvent, 1)))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestBatchIndexLogs_ClientErrorNotRetried(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	c, err := New(Config{URL: srv.URL, Username: "u", Password: "p"})
+	require.NoError(t, err)
+	c.backoff = time.Millisecond
+	err = c.BatchIndexLogs(make([]batcher.AuditEvent, 1))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "status 401")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
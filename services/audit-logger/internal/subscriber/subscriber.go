@@ -1,10 +1,13 @@
 // Package subscriber handles NATS connectivity and routes incoming audit messages
-// to the Batcher for accumulation before bulk database persistence.
+// to the Batcher for accumulation before bulk database persistence. Messages
+// that cannot be parsed are handed to a dead-letter store instead of being lost.
 package subscriber
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
+	"sync/atomic"
 	"time"
 
 	"github.com/nats-io/nats.go"
@@ -14,16 +17,40 @@ import (
 
 const auditSubject = "audit.logs"
 
+// DeadLetterFunc stores a message received on subject that could not be
+// ingested, with the reason why.
+type DeadLetterFunc func(subject string, payload []byte, reason string) error
+
+// Stats counts the audit messages handled since the Subscriber was created.
+type Stats struct {
+	// Parsed is the messages handed to the batcher.
+	Parsed uint64
+	// DeadLettered is the malformed messages kept in the dead-letter store.
+	DeadLettered uint64
+	// Dropped is the malformed messages lost: without a dead-letter store or
+	// when storing them failed.
+	Dropped uint64
+	// Reingested is the dead letters handed to the batcher by Reingest.
+	Reingested uint64
+}
+
 // Subscriber wraps a NATS connection and forwards messages to a Batcher.
 type Subscriber struct {
-	conn    *nats.Conn
-	batcher *batcher.Batcher
-	sub     *nats.Subscription
+	conn       *nats.Conn
+	batcher    *batcher.Batcher
+	deadLetter DeadLetterFunc
+	sub        *nats.Subscription
+
+	parsed       atomic.Uint64
+	deadLettered atomic.Uint64
+	dropped      atomic.Uint64
+	reingested   atomic.Uint64
 }
 
 // New connects to NATS with automatic reconnection enabled and returns a Subscriber.
-// It retries the initial connection up to maxRetries times.
-func New(natsURL string, b *batcher.Batcher) (*Subscriber, error) {
+// It retries the initial connection up to maxRetries times. Malformed messages
+// are passed to deadLetter; with a nil deadLetter they are only logged.
+func New(natsURL string, b *batcher.Batcher, deadLetter DeadLetterFunc) (*Subscriber, error) {
 	const maxRetries = 10
 
 	opts := []nats.Option{
@@ -58,7 +85,7 @@ func New(natsURL string, b *batcher.Batcher) (*Subscriber, error) {
 		return nil, err
 	}
 
-	return &Subscriber{conn: nc, batcher: b}, nil
+	return &Subscriber{conn: nc, batcher: b, deadLetter: deadLetter}, nil
 }
 
 // Start registers the subscription on audit.logs and begins processing messages.
@@ -82,12 +109,61 @@ func (s *Subscriber) Stop() {
 	}
 }
 
-// handleMessage parses an incoming NATS message and enqueues it in the batcher.
-func (s *Subscriber) handleMessage(msg *nats.Msg) {
+// Stats returns the message counters.
+func (s *Subscriber) Stats() Stats {
+	return Stats{
+		Parsed:       s.parsed.Load(),
+		DeadLettered: s.deadLettered.Load(),
+		Dropped:      s.dropped.Load(),
+		Reingested:   s.reingested.Load(),
+	}
+}
+
+// ParseEvent decodes an audit message.
+func ParseEvent(data []byte) (batcher.AuditEvent, error) {
 	var event batcher.AuditEvent
-	if err := json.Unmarshal(msg.Data, &event); err != nil {
-		log.Printf("audit-logger: failed to parse audit event: %v — payload: %s", err, string(msg.Data))
+	if err := json.Unmarshal(data, &event); err != nil {
+		return batcher.AuditEvent{}, fmt.Errorf("parse audit event: %w", err)
+	}
+	return event, nil
+}
+
+// Reingest parses payload, typically a dead letter or its corrected version,
+// and enqueues it in the batcher.
+func (s *Subscriber) Reingest(payload []byte) error {
+	event, err := ParseEvent(payload)
+	if err != nil {
+		return err
+	}
+	s.batcher.Add(event)
+	s.reingested.Add(1)
+	return nil
+}
+
+// handleMessage parses an incoming NATS message and enqueues it in the
+// batcher, or stores it as a dead letter when it cannot be parsed.
+func (s *Subscriber) handleMessage(msg *nats.Msg) {
+	event, err := ParseEvent(msg.Data)
+	if err != nil {
+		s.handleMalformed(msg.Subject, msg.Data, err)
 		return
 	}
 	s.batcher.Add(event)
+	s.parsed.Add(1)
+}
+
+// handleMalformed stores a message that could not be parsed in the
+// dead-letter store, and logs it when that is not possible.
+func (s *Subscriber) handleMalformed(subject string, data []byte, parseErr error) {
+	if s.deadLetter != nil {
+		err := s.deadLetter(subject, data, parseErr.Error())
+		if err == nil {
+			s.deadLettered.Add(1)
+			log.Printf("audit-logger: %v — message stored as a dead letter", parseErr)
+			return
+		}
+		log.Printf("audit-logger: store dead letter: %v", err)
+	}
+	s.dropped.Add(1)
+	log.Printf("audit-logger: dropping audit message: %v — payload: %s", parseErr, string(data))
 }
@@ -0,0 +1,80 @@
+package subscriber
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"flowjs-works/audit-logger/internal/batcher"
+)
+
+// newTestSubscriber returns a Subscriber without a NATS connection whose
+// batcher collects the flushed events in flushed once it is stopped.
+func newTestSubscriber(deadLetter DeadLetterFunc, flushed *[]batcher.AuditEvent) (*Subscriber, *batcher.Batcher) {
+	b := batcher.New(100, time.Hour, func(events []batcher.AuditEvent) error {
+		*flushed = append(*flushed, events...)
+		return nil
+	})
+	return &Subscriber{batcher: b, deadLetter: deadLetter}, b
+}
+
+// TestHandleMessage_DeadLettersMalformed verifies that a message that cannot
+// be parsed is stored as a dead letter instead of reaching the batcher.
+func TestHandleMessage_DeadLettersMalformed(t *testing.T) {
+	var (
+		flushed []batcher.AuditEvent
+		stored  []string
+	)
+	s, b := newTestSubscriber(func(subject string, payload []byte, reason string) error {
+		assert.Equal(t, auditSubject, subject)
+		assert.NotEmpty(t, reason)
+		stored = append(stored, string(payload))
+		return nil
+	}, &flushed)
+
+	s.handleMessage(&nats.Msg{Subject: auditSubject, Data: []byte(`{"execution_id":"e1","node_id":"n1"}`)})
+	s.handleMessage(&nats.Msg{Subject: auditSubject, Data: []byte(`{"execution_id":`)})
+	b.Stop()
+
+	require.Len(t, flushed, 1)
+	assert.Equal(t, "n1", flushed[0].NodeID)
+	assert.Equal(t, []string{`{"execution_id":`}, stored)
+	assert.Equal(t, Stats{Parsed: 1, DeadLettered: 1}, s.Stats())
+}
+
+// TestHandleMessage_DropsWhenDeadLetterFails verifies that a malformed
+// message is counted as dropped when it cannot be dead-lettered.
+func TestHandleMessage_DropsWhenDeadLetterFails(t *testing.T) {
+	var flushed []batcher.AuditEvent
+	s, b := newTestSubscriber(func(string, []byte, string) error {
+		return errors.New("database unreachable")
+	}, &flushed)
+	s.handleMessage(&nats.Msg{Subject: auditSubject, Data: []byte("not json")})
+	b.Stop()
+	assert.Equal(t, Stats{Dropped: 1}, s.Stats())
+
+	s, b = newTestSubscriber(nil, &flushed)
+	s.handleMessage(&nats.Msg{Subject: auditSubject, Data: []byte("not json")})
+	b.Stop()
+	assert.Equal(t, Stats{Dropped: 1}, s.Stats())
+	assert.Empty(t, flushed)
+}
+
+// TestReingest verifies that a corrected dead letter reaches the batcher and
+// a still malformed one is rejected.
+func TestReingest(t *testing.T) {
+	var flushed []batcher.AuditEvent
+	s, b := newTestSubscriber(nil, &flushed)
+
+	require.Error(t, s.Reingest([]byte(`{"execution_id":`)))
+	require.NoError(t, s.Reingest([]byte(`{"execution_id":"e1","node_id":"n1"}`)))
+	b.Stop()
+
+	require.Len(t, flushed, 1)
+	assert.Equal(t, "e1", flushed[0].ExecutionID)
+	assert.Equal(t, Stats{Reingested: 1}, s.Stats())
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"flowjs-works/engine/internal/engine"
+	"flowjs-works/engine/internal/logging"
+	"flowjs-works/engine/internal/models"
+	"flowjs-works/engine/internal/triggers"
+)
+
+const batchUsage = `usage: runner batch -process <file> [-input <file>] [flags]
+
+Runs a process once per line of newline-delimited JSON trigger payloads read
+from -input (default stdin) and writes one JSON result per execution to
+stdout, in completion order. Blank lines are skipped. Audit logging is off
+unless -nats is set; logs go to stderr.
+
+Example:
+  runner batch -process flow.json -input orders.ndjson -concurrency 8 > results.ndjson
+
+`
+
+// maxBatchLineBytes bounds one trigger payload line.
+const maxBatchLineBytes = 16 << 20
+
+// runBatch runs "runner batch ..." and returns the process exit code: 0 when
+// every execution succeeded, 1 when any failed or had an invalid payload.
+func runBatch(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("runner batch", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	processFile := fs.String("process", "", "Path to the process JSON or YAML file (required)")
+	inputFile := fs.String("input", "-", "File of newline-delimited trigger payloads (\"-\" reads stdin)")
+	concurrency := fs.Int("concurrency", 1, "Executions running at the same time")
+	natsURL := fs.String("nats", "", "NATS server URL for audit logging (off by default)")
+	testMode := fs.Bool("test", false, "Enable test-only node types such as mock_http")
+	withNodes := fs.Bool("nodes", true, "Include the node statuses and outputs in each result")
+	fs.Usage = func() {
+		fmt.Fprint(stderr, batchUsage)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if *processFile == "" || *concurrency < 1 {
+		fmt.Fprintln(stderr, "runner batch: -process is required; -concurrency must be positive")
+		return 2
+	}
+
+	proc, err := readProcessFile(*processFile)
+	if err != nil {
+		fmt.Fprintf(stderr, "runner batch: %s: %v\n", *processFile, err)
+		return 2
+	}
+	in := stdin
+	if *inputFile != "-" {
+		f, err := os.Open(*inputFile)
+		if err != nil {
+			fmt.Fprintf(stderr, "runner batch: %v\n", err)
+			return 2
+		}
+		defer f.Close()
+		in = f
+	}
+
+	logging.Setup()
+	executor, err := engine.NewProcessExecutor(*natsURL)
+	if err != nil {
+		fmt.Fprintf(stderr, "runner batch: %v\n", err)
+		return 1
+	}
+	defer executor.Close()
+	if *testMode {
+		executor.EnableTestMode()
+	}
+
+	b := &batch{executor: executor, proc: proc, out: json.NewEncoder(stdout), withNodes: *withNodes}
+	start := time.Now()
+	if err := b.run(in, *concurrency); err != nil {
+		fmt.Fprintf(stderr, "runner batch: read input: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(stderr, "runner batch: %d executions, %d failed, %d invalid payloads in %s\n",
+		b.total, b.failed, b.invalid, time.Since(start).Round(time.Millisecond))
+	if b.failed > 0 || b.invalid > 0 {
+		return 1
+	}
+	return 0
+}
+
+// batch runs the process for every payload line and writes the results.
+type batch struct {
+	executor  triggers.Executor
+	proc      *models.Process
+	withNodes bool
+
+	mu      sync.Mutex // guards out and the counters
+	out     *json.Encoder
+	total   int
+	failed  int
+	invalid int
+}
+
+// batchLine is one payload line, numbered from 1.
+type batchLine struct {
+	n    int
+	data []byte
+}
+
+// batchResult is the outcome of one payload line.
+type batchResult struct {
+	Line        int    `json:"line"`
+	ExecutionID string `json:"execution_id,omitempty"`
+	// Status is "success", "error" or "invalid" (the line is not a JSON
+	// object and nothing ran).
+	Status     string                            `json:"status"`
+	Error      string                            `json:"error,omitempty"`
+	DurationMs float64                           `json:"duration_ms"`
+	Nodes      map[string]map[string]interface{} `json:"nodes,omitempty"`
+}
+
+// run reads payload lines from in and executes them on concurrency workers,
+// returning once every execution finished. Lines read before a read error
+// still run.
+func (b *batch) run(in io.Reader, concurrency int) error {
+	lines := make(chan batchLine)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for line := range lines {
+				b.write(b.once(line))
+			}
+		}()
+	}
+
+	sc := bufio.NewScanner(in)
+	sc.Buffer(make([]byte, 64*1024), maxBatchLineBytes)
+	n := 0
+	for sc.Scan() {
+		n++
+		data := bytes.TrimSpace(sc.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		lines <- batchLine{n: n, data: append([]byte(nil), data...)}
+	}
+	close(lines)
+	wg.Wait()
+	return sc.Err()
+}
+
+// once executes the process with the payload of line.
+func (b *batch) once(line batchLine) batchResult {
+	res := batchResult{Line: line.n}
+	var trigger map[string]interface{}
+	if err := json.Unmarshal(line.data, &trigger); err != nil || trigger == nil {
+		res.Status = "invalid"
+		res.Error = "payload is not a JSON object"
+		if err != nil {
+			res.Error = fmt.Sprintf("payload is not a JSON object: %v", err)
+		}
+		return res
+	}
+	start := time.Now()
+	ctx, err := b.executor.Execute(b.proc, trigger)
+	res.DurationMs = ms(time.Since(start))
+	res.Status = "success"
+	if err != nil {
+		res.Status = "error"
+		res.Error = err.Error()
+	}
+	if ctx != nil {
+		res.ExecutionID = ctx.ExecutionID
+		if b.withNodes {
+			res.Nodes = ctx.NodeStates()
+		}
+	}
+	return res
+}
+
+// write prints res as one JSON line and counts it.
+func (b *batch) write(res batchResult) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if res.Status == "invalid" {
+		b.invalid++
+	} else {
+		b.total++
+		if res.Status == "error" {
+			b.failed++
+		}
+	}
+	_ = b.out.Encode(res)
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"flowjs-works/engine/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeExecutor fails the payloads with "fail": true and records how many
+// executions ran at the same time.
+type fakeExecutor struct {
+	delay time.Duration
+
+	mu          sync.Mutex
+	inFlight    int
+	maxInFlight int
+	payloads    []map[string]interface{}
+}
+
+func (f *fakeExecutor) Execute(_ *models.Process, triggerData map[string]interface{}) (*models.ExecutionContext, error) {
+	f.mu.Lock()
+	f.inFlight++
+	f.maxInFlight = max(f.maxInFlight, f.inFlight)
+	f.payloads = append(f.payloads, triggerData)
+	f.mu.Unlock()
+
+	time.Sleep(f.delay)
+
+	f.mu.Lock()
+	f.inFlight--
+	f.mu.Unlock()
+
+	ctx := models.NewExecutionContext("exec-" + triggerData["id"].(string))
+	ctx.SetNodeState("step", map[string]interface{}{"status": "success"})
+	if triggerData["fail"] == true {
+		return ctx, errors.New("step failed")
+	}
+	return ctx, nil
+}
+
+// decodeResults parses the JSON lines written by a batch, ordered by line.
+func decodeResults(t *testing.T, out []byte) []batchResult {
+	t.Helper()
+	var results []batchResult
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		var res batchResult
+		require.NoError(t, json.Unmarshal(sc.Bytes(), &res), "line %q", sc.Text())
+		results = append(results, res)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Line < results[j].Line })
+	return results
+}
+
+func TestBatch_ParsesLines(t *testing.T) {
+	exec := &fakeExecutor{}
+	var out bytes.Buffer
+	b := &batch{executor: exec, proc: &models.Process{}, out: json.NewEncoder(&out)}
+
+	input := `{"id":"a"}
+
+   {"id":"b"}
+not json
+[1, 2]
+null
+`
+	require.NoError(t, b.run(strings.NewReader(input), 1))
+
+	results := decodeResults(t, out.Bytes())
+	require.Len(t, results, 5, "blank lines are skipped")
+	assert.Equal(t, 1, results[0].Line)
+	assert.Equal(t, "success", results[0].Status)
+	assert.Equal(t, 3, results[1].Line, "lines keep their number in the input")
+	assert.Equal(t, "success", results[1].Status)
+	for _, res := range results[2:] {
+		assert.Equal(t, "invalid", res.Status, "line %d", res.Line)
+		assert.Contains(t, res.Error, "payload is not a JSON object", "line %d", res.Line)
+		assert.Empty(t, res.ExecutionID, "nothing runs for line %d", res.Line)
+	}
+	assert.Equal(t, []map[string]interface{}{{"id": "a"}, {"id": "b"}}, exec.payloads)
+	assert.Equal(t, 2, b.total)
+	assert.Equal(t, 3, b.invalid)
+}
+
+func TestBatch_BoundsConcurrency(t *testing.T) {
+	exec := &fakeExecutor{delay: 20 * time.Millisecond}
+	var out bytes.Buffer
+	b := &batch{executor: exec, proc: &models.Process{}, out: json.NewEncoder(&out)}
+
+	var input strings.Builder
+	for i := 0; i < 12; i++ {
+		input.WriteString(`{"id":"` + string(rune('a'+i)) + `"}` + "\n")
+	}
+	require.NoError(t, b.run(strings.NewReader(input.String()), 3))
+
+	assert.Len(t, decodeResults(t, out.Bytes()), 12)
+	assert.LessOrEqual(t, exec.maxInFlight, 3, "no more than -concurrency executions run at once")
+	assert.Greater(t, exec.maxInFlight, 1, "executions run in parallel")
+}
+
+func TestBatch_OneLineFails(t *testing.T) {
+	exec := &fakeExecutor{}
+	var out bytes.Buffer
+	b := &batch{executor: exec, proc: &models.Process{}, out: json.NewEncoder(&out), withNodes: true}
+
+	input := `{"id":"a"}
+{"id":"b","fail":true}
+{"id":"c"}
+`
+	require.NoError(t, b.run(strings.NewReader(input), 2))
+
+	results := decodeResults(t, out.Bytes())
+	require.Len(t, results, 3)
+	assert.Equal(t, "success", results[0].Status)
+	assert.Equal(t, "success", results[2].Status, "a failed line does not stop the batch")
+
+	failed := results[1]
+	assert.Equal(t, 2, failed.Line)
+	assert.Equal(t, "error", failed.Status)
+	assert.Equal(t, "step failed", failed.Error)
+	assert.Equal(t, "exec-b", failed.ExecutionID)
+	assert.Equal(t, map[string]map[string]interface{}{"step": {"status": "success"}}, failed.Nodes)
+	assert.Equal(t, 3, b.total)
+	assert.Equal(t, 1, b.failed)
+}
+
+func TestBatch_ResultJSON(t *testing.T) {
+	exec := &fakeExecutor{}
+	var out bytes.Buffer
+	b := &batch{executor: exec, proc: &models.Process{}, out: json.NewEncoder(&out)}
+
+	require.NoError(t, b.run(strings.NewReader(`{"id":"a","fail":true}`+"\n"), 1))
+
+	var raw map[string]interface{}
+	require.NoError(t, json.Unmarshal(bytes.TrimSpace(out.Bytes()), &raw))
+	assert.Equal(t, float64(1), raw["line"])
+	assert.Equal(t, "exec-a", raw["execution_id"])
+	assert.Equal(t, "error", raw["status"])
+	assert.Equal(t, "step failed", raw["error"])
+	assert.Contains(t, raw, "duration_ms")
+	assert.NotContains(t, raw, "nodes", "node states are left out without -nodes")
+}
+
+func TestRunBatch_ExitCodes(t *testing.T) {
+	dir := t.TempDir()
+	procFile := filepath.Join(dir, "flow.json")
+	require.NoError(t, os.WriteFile(procFile, []byte(`{
+		"definition": {"id": "batch-test", "version": "1.0.0", "name": "batch test"},
+		"trigger": {"id": "trg", "type": "manual"},
+		"nodes": [{"id": "log", "type": "log", "config": {"level": "INFO", "message": "hello"}}]
+	}`), 0o600))
+
+	var stdout, stderr bytes.Buffer
+	code := runBatch([]string{"-process", procFile}, strings.NewReader(`{"n":1}`+"\n"+`{"n":2}`+"\n"), &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	results := decodeResults(t, stdout.Bytes())
+	require.Len(t, results, 2)
+	for _, res := range results {
+		assert.Equal(t, "success", res.Status)
+		assert.NotEmpty(t, res.ExecutionID)
+		assert.Contains(t, res.Nodes, "log")
+	}
+	assert.Contains(t, stderr.String(), "2 executions, 0 failed, 0 invalid payloads")
+
+	stdout.Reset()
+	stderr.Reset()
+	code = runBatch([]string{"-process", procFile}, strings.NewReader(`{"n":1}`+"\n"+`oops`+"\n"), &stdout, &stderr)
+	assert.Equal(t, 1, code, "an invalid line fails the batch")
+	assert.Len(t, decodeResults(t, stdout.Bytes()), 2)
+
+	assert.Equal(t, 2, runBatch(nil, strings.NewReader(""), &stdout, &stderr), "-process is required")
+	assert.Equal(t, 2, runBatch([]string{"-process", procFile, "-concurrency", "0"}, strings.NewReader(""), &stdout, &stderr))
+}
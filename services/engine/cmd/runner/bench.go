@@ -0,0 +1,316 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+
+	"flowjs-works/engine/internal/engine"
+	"flowjs-works/engine/internal/models"
+)
+
+const benchUsage = `usage: runner bench -process <file> [flags]
+
+Runs a process repeatedly in-process and prints latency percentiles of the
+whole execution and of every node type, so activity regressions show up
+before deployment. Audit logging is off unless -nats is set.
+
+Example:
+  runner bench -process flow.json -concurrency 20 -iterations 1000 -cpuprofile cpu.out
+
+`
+
+// runBench runs "runner bench ..." and returns the process exit code.
+func runBench(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("runner bench", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	processFile := fs.String("process", "", "Path to the process JSON or YAML file (required)")
+	triggerFile := fs.String("trigger", "", "Path to the trigger data JSON file")
+	concurrency := fs.Int("concurrency", 1, "Executions running at the same time")
+	iterations := fs.Int("iterations", 100, "Total executions")
+	warmup := fs.Int("warmup", 0, "Executions run first and left out of the results")
+	natsURL := fs.String("nats", "", "NATS server URL for audit logging (off by default)")
+	testMode := fs.Bool("test", false, "Enable test-only node types such as mock_http")
+	cpuProfile := fs.String("cpuprofile", "", "Write a CPU profile of the measured executions to this file")
+	memProfile := fs.String("memprofile", "", "Write a heap profile to this file after the run")
+	asJSON := fs.Bool("json", false, "Print the results as JSON")
+	fs.Usage = func() {
+		fmt.Fprint(stderr, benchUsage)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if *processFile == "" || *concurrency < 1 || *iterations < 1 || *warmup < 0 {
+		fmt.Fprintln(stderr, "runner bench: -process is required; -concurrency and -iterations must be positive")
+		return 2
+	}
+
+	proc, err := readProcessFile(*processFile)
+	if err != nil {
+		fmt.Fprintf(stderr, "runner bench: %s: %v\n", *processFile, err)
+		return 2
+	}
+	trigger := []byte("{}")
+	if *triggerFile != "" {
+		if trigger, err = os.ReadFile(*triggerFile); err != nil {
+			fmt.Fprintf(stderr, "runner bench: %v\n", err)
+			return 2
+		}
+		if !json.Valid(trigger) {
+			fmt.Fprintf(stderr, "runner bench: %s is not valid JSON\n", *triggerFile)
+			return 2
+		}
+	}
+
+	// Node logs would drown the results.
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	executor, err := engine.NewProcessExecutor(*natsURL)
+	if err != nil {
+		fmt.Fprintf(stderr, "runner bench: %v\n", err)
+		return 1
+	}
+	defer executor.Close()
+	if *testMode {
+		executor.EnableTestMode()
+	}
+
+	b := newBench(executor, proc, trigger)
+	b.run(*warmup, *concurrency)
+	b.reset()
+
+	if *cpuProfile != "" {
+		f, err := os.Create(*cpuProfile)
+		if err != nil {
+			fmt.Fprintf(stderr, "runner bench: %v\n", err)
+			return 1
+		}
+		defer f.Close()
+		if err := pprof.StartCPUProfile(f); err != nil {
+			fmt.Fprintf(stderr, "runner bench: %v\n", err)
+			return 1
+		}
+	}
+	elapsed := b.run(*iterations, *concurrency)
+	if *cpuProfile != "" {
+		pprof.StopCPUProfile()
+	}
+	if *memProfile != "" {
+		if err := writeHeapProfile(*memProfile); err != nil {
+			fmt.Fprintf(stderr, "runner bench: %v\n", err)
+			return 1
+		}
+	}
+
+	result := b.result(elapsed, *concurrency)
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(result)
+	} else {
+		printBench(stdout, result)
+	}
+	if result.Failed > 0 {
+		fmt.Fprintf(stderr, "runner bench: %d of %d executions failed; first error: %s\n", result.Failed, result.Iterations, b.firstErr)
+		return 1
+	}
+	return 0
+}
+
+// bench drives the executor and collects the latencies of the executions
+// and of their nodes, by node type.
+type bench struct {
+	executor *engine.ProcessExecutor
+	proc     *models.Process
+	trigger  []byte
+	types    map[string]string // node id → node type
+
+	mu        sync.Mutex
+	execs     []time.Duration
+	nodes     map[string][]time.Duration
+	failed    int
+	firstErr  string
+	completed atomic.Int64
+}
+
+func newBench(executor *engine.ProcessExecutor, proc *models.Process, trigger []byte) *bench {
+	b := &bench{executor: executor, proc: proc, trigger: trigger, types: make(map[string]string, len(proc.Nodes))}
+	for _, node := range proc.Nodes {
+		b.types[node.ID] = node.Type
+	}
+	b.reset()
+	return b
+}
+
+func (b *bench) reset() {
+	b.execs, b.nodes, b.failed, b.firstErr = nil, make(map[string][]time.Duration), 0, ""
+}
+
+// run executes the process n times on concurrency workers and returns the
+// elapsed time.
+func (b *bench) run(n, concurrency int) time.Duration {
+	jobs := make(chan struct{})
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range jobs {
+				b.once()
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		jobs <- struct{}{}
+	}
+	close(jobs)
+	wg.Wait()
+	return time.Since(start)
+}
+
+// once runs one execution with a fresh copy of the trigger data, since nodes
+// may modify what they read.
+func (b *bench) once() {
+	var trigger map[string]interface{}
+	_ = json.Unmarshal(b.trigger, &trigger)
+	start := time.Now()
+	ctx, err := b.executor.Execute(b.proc, trigger)
+	b.record(time.Since(start), err, ctx.NodeTimings())
+}
+
+// record adds one execution that took took and the timings of its nodes,
+// each node run counted under the node's type.
+func (b *bench) record(took time.Duration, err error, timings map[string]models.NodeTiming) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.execs = append(b.execs, took)
+	if err != nil {
+		b.failed++
+		if b.firstErr == "" {
+			b.firstErr = err.Error()
+		}
+	}
+	for id, t := range timings {
+		typ := b.types[id]
+		if typ == "" || t.Runs == 0 {
+			continue
+		}
+		per := t.Wall / time.Duration(t.Runs)
+		for i := 0; i < t.Runs; i++ {
+			b.nodes[typ] = append(b.nodes[typ], per)
+		}
+	}
+}
+
+// benchResult is what runner bench reports.
+type benchResult struct {
+	Iterations  int          `json:"iterations"`
+	Concurrency int          `json:"concurrency"`
+	Failed      int          `json:"failed"`
+	ElapsedMs   float64      `json:"elapsed_ms"`
+	PerSecond   float64      `json:"executions_per_second"`
+	Execution   latencyStats `json:"execution"`
+	// NodeTypes holds the latencies of single node runs by node type.
+	NodeTypes map[string]latencyStats `json:"node_types"`
+}
+
+// latencyStats summarizes a set of durations, in milliseconds.
+type latencyStats struct {
+	Count int     `json:"count"`
+	Mean  float64 `json:"mean_ms"`
+	P50   float64 `json:"p50_ms"`
+	P90   float64 `json:"p90_ms"`
+	P95   float64 `json:"p95_ms"`
+	P99   float64 `json:"p99_ms"`
+	Max   float64 `json:"max_ms"`
+}
+
+func (b *bench) result(elapsed time.Duration, concurrency int) benchResult {
+	r := benchResult{
+		Iterations:  len(b.execs),
+		Concurrency: concurrency,
+		Failed:      b.failed,
+		ElapsedMs:   ms(elapsed),
+		Execution:   summarize(b.execs),
+		NodeTypes:   make(map[string]latencyStats, len(b.nodes)),
+	}
+	if elapsed > 0 {
+		r.PerSecond = float64(len(b.execs)) / elapsed.Seconds()
+	}
+	for typ, ds := range b.nodes {
+		r.NodeTypes[typ] = summarize(ds)
+	}
+	return r
+}
+
+// summarize computes the nearest-rank percentiles of ds, sorting it.
+func summarize(ds []time.Duration) latencyStats {
+	if len(ds) == 0 {
+		return latencyStats{}
+	}
+	sort.Slice(ds, func(i, j int) bool { return ds[i] < ds[j] })
+	var total time.Duration
+	for _, d := range ds {
+		total += d
+	}
+	// rank returns the smallest duration at or above pct percent of ds.
+	rank := func(pct int) time.Duration {
+		return ds[max((pct*len(ds)+99)/100-1, 0)]
+	}
+	return latencyStats{
+		Count: len(ds),
+		Mean:  ms(total / time.Duration(len(ds))),
+		P50:   ms(rank(50)),
+		P90:   ms(rank(90)),
+		P95:   ms(rank(95)),
+		P99:   ms(rank(99)),
+		Max:   ms(ds[len(ds)-1]),
+	}
+}
+
+func ms(d time.Duration) float64 { return float64(d.Microseconds()) / 1000 }
+
+func printBench(w io.Writer, r benchResult) {
+	fmt.Fprintf(w, "%d executions, concurrency %d, %.0f ms, %.1f exec/s, %d failed\n\n",
+		r.Iterations, r.Concurrency, r.ElapsedMs, r.PerSecond, r.Failed)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "\tRUNS\tMEAN ms\tP50 ms\tP90 ms\tP95 ms\tP99 ms\tMAX ms\t")
+	row := func(name string, s latencyStats) {
+		fmt.Fprintf(tw, "%s\t%d\t%.3f\t%.3f\t%.3f\t%.3f\t%.3f\t%.3f\t\n", name, s.Count, s.Mean, s.P50, s.P90, s.P95, s.P99, s.Max)
+	}
+	row("execution", r.Execution)
+	types := make([]string, 0, len(r.NodeTypes))
+	for typ := range r.NodeTypes {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+	for _, typ := range types {
+		row(typ, r.NodeTypes[typ])
+	}
+	_ = tw.Flush()
+}
+
+func writeHeapProfile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	runtime.GC()
+	return pprof.WriteHeapProfile(f)
+}
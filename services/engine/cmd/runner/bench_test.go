@@ -0,0 +1,102 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"flowjs-works/engine/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// millis returns durations of the given milliseconds.
+func millis(values ...int) []time.Duration {
+	ds := make([]time.Duration, len(values))
+	for i, v := range values {
+		ds[i] = time.Duration(v) * time.Millisecond
+	}
+	return ds
+}
+
+func TestSummarize(t *testing.T) {
+	hundred := make([]int, 100)
+	for i := range hundred {
+		hundred[i] = 100 - i // 100..1, unsorted
+	}
+	tests := []struct {
+		name string
+		ds   []time.Duration
+		want latencyStats
+	}{
+		{name: "empty", ds: nil, want: latencyStats{}},
+		{
+			name: "single sample",
+			ds:   millis(7),
+			want: latencyStats{Count: 1, Mean: 7, P50: 7, P90: 7, P95: 7, P99: 7, Max: 7},
+		},
+		{
+			name: "even number of samples",
+			ds:   millis(40, 10, 30, 20),
+			want: latencyStats{Count: 4, Mean: 25, P50: 20, P90: 40, P95: 40, P99: 40, Max: 40},
+		},
+		{
+			name: "odd number of samples",
+			ds:   millis(5, 1, 3, 2, 4),
+			want: latencyStats{Count: 5, Mean: 3, P50: 3, P90: 5, P95: 5, P99: 5, Max: 5},
+		},
+		{
+			name: "hundred samples",
+			ds:   millis(hundred...),
+			want: latencyStats{Count: 100, Mean: 50.5, P50: 50, P90: 90, P95: 95, P99: 99, Max: 100},
+		},
+		{
+			name: "outlier",
+			ds:   millis(append(make([]int, 19), 1000)...),
+			want: latencyStats{Count: 20, Mean: 50, P50: 0, P90: 0, P95: 0, P99: 1000, Max: 1000},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, summarize(tt.ds))
+		})
+	}
+}
+
+func TestBench_AggregatesByNodeType(t *testing.T) {
+	proc := &models.Process{Nodes: []models.Node{
+		{ID: "fetch", Type: "http"},
+		{ID: "notify", Type: "http"},
+		{ID: "shape", Type: "transform"},
+	}}
+	b := newBench(nil, proc, nil)
+
+	b.record(10*time.Millisecond, nil, map[string]models.NodeTiming{
+		"fetch": {Runs: 1, Wall: 4 * time.Millisecond},
+		"shape": {Runs: 2, Wall: 2 * time.Millisecond},
+	})
+	b.record(20*time.Millisecond, errors.New("notify failed"), map[string]models.NodeTiming{
+		"fetch":  {Runs: 1, Wall: 6 * time.Millisecond},
+		"notify": {Runs: 1, Wall: 8 * time.Millisecond},
+		"gone":   {Runs: 1, Wall: time.Second},
+		"shape":  {Runs: 0},
+	})
+	b.record(30*time.Millisecond, errors.New("later failure"), nil)
+
+	r := b.result(time.Second, 2)
+	assert.Equal(t, 3, r.Iterations)
+	assert.Equal(t, 2, r.Concurrency)
+	assert.Equal(t, 2, r.Failed)
+	assert.Equal(t, "notify failed", b.firstErr, "the first error is kept")
+	assert.InDelta(t, 3.0, r.PerSecond, 1e-9)
+	assert.Equal(t, latencyStats{Count: 3, Mean: 20, P50: 20, P90: 30, P95: 30, P99: 30, Max: 30}, r.Execution)
+
+	assert.Len(t, r.NodeTypes, 2, "nodes of unknown ids are left out")
+	assert.Equal(t, latencyStats{Count: 3, Mean: 6, P50: 6, P90: 8, P95: 8, P99: 8, Max: 8}, r.NodeTypes["http"],
+		"nodes of the same type are aggregated")
+	assert.Equal(t, latencyStats{Count: 2, Mean: 1, P50: 1, P90: 1, P95: 1, P99: 1, Max: 1}, r.NodeTypes["transform"],
+		"a node run several times counts each run at its mean wall time")
+
+	b.reset()
+	assert.Equal(t, 0, b.result(0, 1).Iterations)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"flowjs-works/engine/internal/lint"
+	"flowjs-works/engine/internal/models"
+)
+
+const lintUsage = `usage: runner lint [flags] <process file>...
+
+Checks process definitions (JSON or YAML) against the lint rules. Rule
+severities come from -config, or else from the nearest .flowlint in the
+directory of each process file or its parents.
+
+Rules:
+%s
+Exits 1 when a finding has error severity.
+`
+
+// runLint runs "runner lint ..." and returns the process exit code.
+func runLint(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("runner lint", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	configPath := fs.String("config", "", "Lint config file (default: nearest "+lint.ConfigFile+")")
+	asJSON := fs.Bool("json", false, "Print the reports as JSON")
+	fs.Usage = func() {
+		var rules string
+		for _, r := range lint.Rules {
+			rules += fmt.Sprintf("  %-26s %-8s %s\n", r.Name, r.Default, r.Description)
+		}
+		fmt.Fprintf(stderr, lintUsage, rules)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	var fixed *lint.Config
+	if *configPath != "" {
+		cfg, err := lint.LoadConfig(*configPath)
+		if err != nil {
+			fmt.Fprintf(stderr, "runner lint: %v\n", err)
+			return 2
+		}
+		fixed = &cfg
+	}
+
+	reports := make(map[string]lint.Report, fs.NArg())
+	failed := false
+	for _, path := range fs.Args() {
+		cfg := fixed
+		if cfg == nil {
+			loaded, err := nearestLintConfig(path)
+			if err != nil {
+				fmt.Fprintf(stderr, "runner lint: %v\n", err)
+				return 2
+			}
+			cfg = &loaded
+		}
+		proc, err := readProcessFile(path)
+		if err != nil {
+			fmt.Fprintf(stderr, "runner lint: %s: %v\n", path, err)
+			return 2
+		}
+		report := lint.Lint(proc, *cfg)
+		reports[path] = report
+		failed = failed || report.Failed()
+		if !*asJSON {
+			for _, f := range report.Findings {
+				fmt.Fprintf(stdout, "%s: %s\n", path, f)
+			}
+		}
+	}
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(reports)
+	}
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+// nearestLintConfig loads the .flowlint closest to the process file at path,
+// or returns the default configuration when there is none.
+func nearestLintConfig(path string) (lint.Config, error) {
+	found := lint.FindConfig(filepath.Dir(path))
+	if found == "" {
+		return lint.Config{}, nil
+	}
+	return lint.LoadConfig(found)
+}
+
+// readProcessFile reads a JSON or YAML (.yaml/.yml) process definition.
+func readProcessFile(path string) (*models.Process, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if models.IsYAMLFile(path) {
+		return models.ProcessFromYAML(data)
+	}
+	var proc models.Process
+	if err := json.Unmarshal(data, &proc); err != nil {
+		return nil, fmt.Errorf("invalid process definition: %w", err)
+	}
+	return &proc, nil
+}
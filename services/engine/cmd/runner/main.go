@@ -8,14 +8,31 @@ import (
 	"os"
 
 	"flowjs-works/engine/internal/engine"
+	"flowjs-works/engine/internal/logging"
+	"flowjs-works/engine/internal/models"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "secrets" {
+		os.Exit(runSecrets(os.Args[2:], os.Stdin, os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "lint" {
+		os.Exit(runLint(os.Args[2:], os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "bench" {
+		os.Exit(runBench(os.Args[2:], os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "batch" {
+		os.Exit(runBatch(os.Args[2:], os.Stdin, os.Stdout, os.Stderr))
+	}
+
 	// Parse command line flags
-	processFile := flag.String("process", "", "Path to the process JSON file")
+	processFile := flag.String("process", "", "Path to the process JSON or YAML (.yaml/.yml) file")
 	triggerFile := flag.String("trigger", "", "Path to the trigger data JSON file (optional)")
 	natsURL := flag.String("nats", "nats://localhost:4222", "NATS server URL for audit logging")
+	testMode := flag.Bool("test", false, "Enable test-only node types such as mock_http")
 	flag.Parse()
+	logging.Setup()
 
 	// Use example data if no process file specified
 	var processJSON []byte
@@ -29,6 +46,11 @@ func main() {
 		if err != nil {
 			log.Fatalf("Failed to read process file: %v", err)
 		}
+		if models.IsYAMLFile(*processFile) {
+			if processJSON, err = models.YAMLToJSON(processJSON); err != nil {
+				log.Fatalf("Failed to parse process YAML: %v", err)
+			}
+		}
 	}
 
 	// Load trigger data
@@ -61,6 +83,9 @@ func main() {
 		log.Fatalf("Failed to create executor: %v", err)
 	}
 	defer executor.Close()
+	if *testMode {
+		executor.EnableTestMode()
+	}
 
 	// Execute the process
 	ctx, err := executor.ExecuteFromJSON(processJSON, triggerData)
@@ -0,0 +1,377 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	_ "github.com/lib/pq"
+
+	"flowjs-works/engine/internal/secrets"
+	"flowjs-works/engine/internal/tenant"
+)
+
+const secretsUsage = `usage: runner secrets <command> [flags]
+
+Commands:
+  set <id>     create or update a secret
+  list         list secret metadata (never values)
+  delete <id>  delete a secret
+  rotate       re-encrypt every secret with the current key (admin)
+
+By default the commands call the engine API at -api (FLOWJS_API_URL) with
+-api-key (FLOWJS_API_KEY). With -db (or FLOWJS_SECRETS_DB) they write the
+config database directly, encrypting with SECRETS_AES_KEY.
+
+To rotate the key, set the new key in SECRETS_AES_KEY with a new
+SECRETS_AES_KEY_ID, move the old one to SECRETS_AES_KEY_PREVIOUS as id:key
+(the id is v1 unless SECRETS_AES_KEY_ID was set), restart the engine and run
+"runner secrets rotate". Once it reports no failures the previous key can be
+dropped.
+
+Examples:
+  runner secrets set stripe -type token -field token="$STRIPE_TOKEN"
+  runner secrets set warehouse -type basic_auth -value-file creds.json
+  echo '{"token":"..."}' | runner secrets set crm -type token -value-file -
+  runner secrets list -json
+  runner secrets delete stripe
+  SECRETS_AES_KEY_PREVIOUS="v1:$OLD_KEY" runner secrets rotate -db "$DATABASE_URL"
+`
+
+// secretsBackend is where the secrets commands read and write: the engine
+// API or the config database.
+type secretsBackend interface {
+	List(ctx context.Context) ([]secrets.SecretMeta, error)
+	Set(ctx context.Context, input secrets.SecretInput) error
+	Delete(ctx context.Context, id string) error
+	Rotate(ctx context.Context) (secrets.ReEncryptResult, error)
+}
+
+// runSecrets runs "runner secrets ..." and returns the process exit code.
+func runSecrets(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "help" {
+		fmt.Fprint(stderr, secretsUsage)
+		return 2
+	}
+	cmd, args := args[0], args[1:]
+
+	fs := flag.NewFlagSet("runner secrets "+cmd, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	apiURL := fs.String("api", envOr("FLOWJS_API_URL", "http://localhost:9090"), "Engine API base URL")
+	apiKey := fs.String("api-key", os.Getenv("FLOWJS_API_KEY"), "Engine API key (sent as Bearer token)")
+	dbURL := fs.String("db", os.Getenv("FLOWJS_SECRETS_DB"), "Config database URL; writes directly instead of calling the API")
+	workspace := fs.String("workspace", "", "Workspace for -db mode (the API key decides it otherwise)")
+	timeout := fs.Duration("timeout", 30*time.Second, "Timeout of the whole command")
+
+	var (
+		secretType, name, value, valueFile string
+		fields                             fieldFlags
+		asJSON                             bool
+	)
+	switch cmd {
+	case "set":
+		fs.StringVar(&secretType, "type", "", "Secret type: basic_auth, token, certificate, connection_string, aws_credentials, ssh_key, amqp_url, service_account")
+		fs.StringVar(&name, "name", "", "Display name (defaults to the id)")
+		fs.StringVar(&value, "value", "", "Secret value as a JSON object")
+		fs.StringVar(&valueFile, "value-file", "", `File holding the value as a JSON object ("-" reads stdin)`)
+		fs.Var(&fields, "field", "Value field as key=value (repeatable; applied after -value/-value-file)")
+	case "list":
+		fs.BoolVar(&asJSON, "json", false, "Print JSON instead of a table")
+	case "delete", "rotate":
+	default:
+		fmt.Fprintf(stderr, "runner secrets: unknown command %q\n\n%s", cmd, secretsUsage)
+		return 2
+	}
+	id, err := parseWithID(fs, args, cmd != "list" && cmd != "rotate")
+	if err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintf(stderr, "runner secrets %s: %v\n", cmd, err)
+		}
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	var backend secretsBackend
+	if *dbURL != "" {
+		if *workspace != "" && !tenant.Valid(*workspace) {
+			fmt.Fprintf(stderr, "runner secrets: invalid workspace %q\n", *workspace)
+			return 2
+		}
+		d, err := openSecretsDB(*dbURL)
+		if err != nil {
+			fmt.Fprintf(stderr, "runner secrets: %v\n", err)
+			return 1
+		}
+		defer d.db.Close()
+		ctx = tenant.WithWorkspace(ctx, tenant.Normalize(*workspace))
+		ctx = tenant.WithPrincipal(ctx, tenant.Principal{Workspace: tenant.Normalize(*workspace), Subject: "runner-cli"})
+		backend = d
+	} else {
+		backend = &apiSecrets{base: strings.TrimRight(*apiURL, "/"), key: *apiKey, client: http.DefaultClient}
+	}
+
+	switch cmd {
+	case "set":
+		input := secrets.SecretInput{ID: id, Name: name, Type: secrets.SecretType(secretType)}
+		if input.Name == "" {
+			input.Name = id
+		}
+		if secretType == "" {
+			fmt.Fprintln(stderr, "runner secrets set: -type is required")
+			return 2
+		}
+		if input.Value, err = secretValue(value, valueFile, fields, stdin); err != nil {
+			fmt.Fprintf(stderr, "runner secrets set: %v\n", err)
+			return 2
+		}
+		err = backend.Set(ctx, input)
+		if err == nil {
+			fmt.Fprintf(stdout, "secret %s saved\n", id)
+		}
+	case "list":
+		var list []secrets.SecretMeta
+		if list, err = backend.List(ctx); err == nil {
+			err = printSecrets(stdout, list, asJSON)
+		}
+	case "delete":
+		err = backend.Delete(ctx, id)
+		if err == nil {
+			fmt.Fprintf(stdout, "secret %s deleted\n", id)
+		}
+	case "rotate":
+		var result secrets.ReEncryptResult
+		if result, err = backend.Rotate(ctx); err == nil {
+			fmt.Fprintf(stdout, "%d of %d secrets re-encrypted with key %s\n", result.Rotated, result.Scanned, result.KeyID)
+			if len(result.Failed) > 0 {
+				err = fmt.Errorf("%d secrets could not be decrypted with the keyring: %s", len(result.Failed), strings.Join(result.Failed, ", "))
+			}
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "runner secrets %s: %v\n", cmd, err)
+		return 1
+	}
+	return 0
+}
+
+// parseWithID parses flags placed before and after the single positional
+// id argument, so both "set -type token stripe" and "set stripe -type token"
+// work.
+func parseWithID(fs *flag.FlagSet, args []string, wantID bool) (string, error) {
+	if err := fs.Parse(args); err != nil {
+		return "", err
+	}
+	var id string
+	if fs.NArg() > 0 {
+		id = fs.Arg(0)
+		if err := fs.Parse(fs.Args()[1:]); err != nil {
+			return "", err
+		}
+	}
+	switch {
+	case fs.NArg() > 0:
+		return "", fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	case wantID && id == "":
+		return "", errors.New("secret id is required")
+	case !wantID && id != "":
+		return "", fmt.Errorf("unexpected argument %q", id)
+	}
+	return id, nil
+}
+
+// fieldFlags collects repeated -field key=value flags.
+type fieldFlags []string
+
+func (f *fieldFlags) String() string { return strings.Join(*f, ",") }
+
+func (f *fieldFlags) Set(v string) error {
+	if k, _, ok := strings.Cut(v, "="); !ok || k == "" {
+		return fmt.Errorf("field must be key=value, got %q", v)
+	}
+	*f = append(*f, v)
+	return nil
+}
+
+// secretValue builds the secret value from -value or -value-file, then the
+// -field flags.
+func secretValue(value, valueFile string, fields fieldFlags, stdin io.Reader) (map[string]interface{}, error) {
+	if value != "" && valueFile != "" {
+		return nil, errors.New("use either -value or -value-file, not both")
+	}
+	raw := []byte(value)
+	if valueFile != "" {
+		var err error
+		if valueFile == "-" {
+			raw, err = io.ReadAll(stdin)
+		} else {
+			raw, err = os.ReadFile(valueFile)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read value: %w", err)
+		}
+	}
+	result := map[string]interface{}{}
+	if len(bytes.TrimSpace(raw)) > 0 {
+		if err := json.Unmarshal(raw, &result); err != nil {
+			return nil, fmt.Errorf("value must be a JSON object: %w", err)
+		}
+	}
+	for _, f := range fields {
+		k, v, _ := strings.Cut(f, "=")
+		result[k] = v
+	}
+	if len(result) == 0 {
+		return nil, errors.New("a value is required (-value, -value-file or -field)")
+	}
+	return result, nil
+}
+
+func printSecrets(w io.Writer, list []secrets.SecretMeta, asJSON bool) error {
+	if list == nil {
+		list = []secrets.SecretMeta{}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	if asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(list)
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tTYPE\tNAME\tUPDATED")
+	for _, s := range list {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", s.ID, s.Type, s.Name, s.UpdatedAt.UTC().Format(time.RFC3339))
+	}
+	return tw.Flush()
+}
+
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// apiSecrets implements secretsBackend with the engine's /api/v1/secrets
+// endpoints.
+type apiSecrets struct {
+	base   string
+	key    string
+	client *http.Client
+}
+
+func (a *apiSecrets) List(ctx context.Context) ([]secrets.SecretMeta, error) {
+	var list []secrets.SecretMeta
+	err := a.do(ctx, http.MethodGet, "/api/v1/secrets", nil, &list)
+	return list, err
+}
+
+func (a *apiSecrets) Set(ctx context.Context, input secrets.SecretInput) error {
+	body, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+	return a.do(ctx, http.MethodPost, "/api/v1/secrets", body, nil)
+}
+
+func (a *apiSecrets) Delete(ctx context.Context, id string) error {
+	return a.do(ctx, http.MethodDelete, "/api/v1/secrets/"+url.PathEscape(id), nil, nil)
+}
+
+func (a *apiSecrets) Rotate(ctx context.Context) (secrets.ReEncryptResult, error) {
+	var result secrets.ReEncryptResult
+	err := a.do(ctx, http.MethodPost, "/api/v1/secrets/rotate", nil, &result)
+	return result, err
+}
+
+func (a *apiSecrets) do(ctx context.Context, method, path string, body []byte, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, a.base+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if a.key != "" {
+		req.Header.Set("Authorization", "Bearer "+a.key)
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error != "" {
+			return fmt.Errorf("%s %s: %s (HTTP %d)", method, path, apiErr.Error, resp.StatusCode)
+		}
+		return fmt.Errorf("%s %s: HTTP %d: %s", method, path, resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("%s %s: decode response: %w", method, path, err)
+		}
+	}
+	return nil
+}
+
+// dbSecrets implements secretsBackend on the config database. Changes are
+// recorded in the secret audit like changes made through the API.
+type dbSecrets struct {
+	db    *sql.DB
+	ss    *secrets.SecretStore
+	audit *secrets.AuditLog
+}
+
+func openSecretsDB(dsn string) (*dbSecrets, error) {
+	ring, _, err := secrets.KeyringFromEnv("SECRETS_AES_KEY")
+	if err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open database: %w", err)
+	}
+	return &dbSecrets{db: db, ss: secrets.NewSecretStoreWithKeyring(db, ring), audit: secrets.NewAuditLog(db)}, nil
+}
+
+func (d *dbSecrets) List(ctx context.Context) ([]secrets.SecretMeta, error) {
+	return d.ss.List(ctx)
+}
+
+func (d *dbSecrets) Set(ctx context.Context, input secrets.SecretInput) error {
+	if err := d.ss.Upsert(ctx, input); err != nil {
+		return err
+	}
+	return d.audit.Record(ctx, secrets.AuditEvent{SecretID: input.ID, Action: secrets.AuditActionUpdate, Success: true})
+}
+
+func (d *dbSecrets) Delete(ctx context.Context, id string) error {
+	if err := d.ss.Delete(ctx, id); err != nil {
+		return err
+	}
+	return d.audit.Record(ctx, secrets.AuditEvent{SecretID: id, Action: secrets.AuditActionDelete, Success: true})
+}
+
+func (d *dbSecrets) Rotate(ctx context.Context) (secrets.ReEncryptResult, error) {
+	return d.ss.ReEncrypt(ctx)
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"flowjs-works/engine/internal/secrets"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// apiRequest is a request received by the fake engine API.
+type apiRequest struct {
+	method string
+	path   string
+	auth   string
+	body   []byte
+}
+
+// secretsAPI is a fake engine API: it records the requests and answers each
+// "METHOD path" with its canned status and body (200 {} otherwise).
+type secretsAPI struct {
+	url     string
+	answers map[string]cannedAnswer
+
+	mu       sync.Mutex
+	requests []apiRequest
+}
+
+type cannedAnswer struct {
+	status int
+	body   string
+}
+
+func newSecretsAPI(t *testing.T, answers map[string]cannedAnswer) *secretsAPI {
+	t.Helper()
+	api := &secretsAPI{answers: answers}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		api.mu.Lock()
+		api.requests = append(api.requests, apiRequest{method: r.Method, path: r.URL.EscapedPath(), auth: r.Header.Get("Authorization"), body: body})
+		api.mu.Unlock()
+		answer, ok := api.answers[r.Method+" "+r.URL.EscapedPath()]
+		if !ok {
+			answer = cannedAnswer{http.StatusOK, `{}`}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(answer.status)
+		_, _ = io.WriteString(w, answer.body)
+	}))
+	t.Cleanup(srv.Close)
+	api.url = srv.URL
+	return api
+}
+
+func (a *secretsAPI) received() []apiRequest {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]apiRequest(nil), a.requests...)
+}
+
+// runSecretsCmd runs "runner secrets args..." against api and returns the
+// exit code, stdout and stderr.
+func runSecretsCmd(t *testing.T, api *secretsAPI, stdin string, args ...string) (int, string, string) {
+	t.Helper()
+	t.Setenv("FLOWJS_SECRETS_DB", "")
+	t.Setenv("FLOWJS_API_KEY", "")
+	if len(args) > 0 {
+		args = append([]string{args[0], "-api", api.url, "-api-key", "k-123"}, args[1:]...)
+	}
+	var stdout, stderr bytes.Buffer
+	code := runSecrets(args, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRunSecrets_Set(t *testing.T) {
+	api := newSecretsAPI(t, nil)
+
+	code, stdout, stderr := runSecretsCmd(t, api, "", "set", "stripe", "-type", "token", "-field", "token=sk_live")
+
+	require.Equal(t, 0, code, stderr)
+	assert.Equal(t, "secret stripe saved\n", stdout)
+	reqs := api.received()
+	require.Len(t, reqs, 1)
+	assert.Equal(t, http.MethodPost, reqs[0].method)
+	assert.Equal(t, "/api/v1/secrets", reqs[0].path)
+	assert.Equal(t, "Bearer k-123", reqs[0].auth)
+	var input secrets.SecretInput
+	require.NoError(t, json.Unmarshal(reqs[0].body, &input))
+	assert.Equal(t, secrets.SecretInput{
+		ID:    "stripe",
+		Name:  "stripe",
+		Type:  secrets.SecretType("token"),
+		Value: map[string]interface{}{"token": "sk_live"},
+	}, input, "the name defaults to the id")
+}
+
+func TestRunSecrets_SetValueFromStdin(t *testing.T) {
+	api := newSecretsAPI(t, nil)
+
+	code, _, stderr := runSecretsCmd(t, api, `{"user": "etl", "password": "old"}`,
+		"set", "-type", "basic_auth", "-name", "Warehouse", "-value-file", "-", "-field", "password=new", "warehouse")
+
+	require.Equal(t, 0, code, stderr)
+	reqs := api.received()
+	require.Len(t, reqs, 1)
+	var input secrets.SecretInput
+	require.NoError(t, json.Unmarshal(reqs[0].body, &input))
+	assert.Equal(t, "warehouse", input.ID, "flags before and after the id are parsed")
+	assert.Equal(t, "Warehouse", input.Name)
+	assert.Equal(t, map[string]interface{}{"user": "etl", "password": "new"}, input.Value, "-field is applied after -value-file")
+}
+
+func TestRunSecrets_List(t *testing.T) {
+	updated := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	list, err := json.Marshal([]secrets.SecretMeta{
+		{ID: "stripe", Name: "Stripe", Type: "token", UpdatedAt: updated},
+		{ID: "crm", Name: "CRM", Type: "basic_auth", UpdatedAt: updated},
+	})
+	require.NoError(t, err)
+	api := newSecretsAPI(t, map[string]cannedAnswer{"GET /api/v1/secrets": {http.StatusOK, string(list)}})
+
+	t.Run("table", func(t *testing.T) {
+		code, stdout, stderr := runSecretsCmd(t, api, "", "list")
+
+		require.Equal(t, 0, code, stderr)
+		lines := strings.Split(strings.TrimSpace(stdout), "\n")
+		require.Len(t, lines, 3)
+		assert.Equal(t, []string{"ID", "TYPE", "NAME", "UPDATED"}, strings.Fields(lines[0]))
+		assert.Equal(t, []string{"crm", "basic_auth", "CRM", "2026-03-01T12:00:00Z"}, strings.Fields(lines[1]), "sorted by id")
+		assert.Equal(t, []string{"stripe", "token", "Stripe", "2026-03-01T12:00:00Z"}, strings.Fields(lines[2]))
+	})
+
+	t.Run("json", func(t *testing.T) {
+		code, stdout, stderr := runSecretsCmd(t, api, "", "list", "-json")
+
+		require.Equal(t, 0, code, stderr)
+		var got []secrets.SecretMeta
+		require.NoError(t, json.Unmarshal([]byte(stdout), &got))
+		require.Len(t, got, 2)
+		assert.Equal(t, "crm", got[0].ID)
+		assert.Equal(t, "stripe", got[1].ID)
+	})
+}
+
+func TestRunSecrets_Delete(t *testing.T) {
+	api := newSecretsAPI(t, map[string]cannedAnswer{"DELETE /api/v1/secrets/missing": {http.StatusNotFound, `{"error": "secret not found"}`}})
+
+	code, stdout, stderr := runSecretsCmd(t, api, "", "delete", "a b")
+	require.Equal(t, 0, code, stderr)
+	assert.Equal(t, "secret a b deleted\n", stdout)
+
+	code, _, stderr = runSecretsCmd(t, api, "", "delete", "missing")
+	assert.Equal(t, 1, code)
+	assert.Equal(t, "runner secrets delete: DELETE /api/v1/secrets/missing: secret not found (HTTP 404)\n", stderr)
+
+	reqs := api.received()
+	require.Len(t, reqs, 2)
+	assert.Equal(t, http.MethodDelete, reqs[0].method)
+	assert.Equal(t, "/api/v1/secrets/a%20b", reqs[0].path, "the id is path-escaped")
+}
+
+func TestRunSecrets_Rotate(t *testing.T) {
+	t.Run("all rotated", func(t *testing.T) {
+		api := newSecretsAPI(t, map[string]cannedAnswer{"POST /api/v1/secrets/rotate": {http.StatusOK, `{"key_id": "v2", "scanned": 3, "rotated": 2}`}})
+
+		code, stdout, stderr := runSecretsCmd(t, api, "", "rotate")
+
+		require.Equal(t, 0, code, stderr)
+		assert.Equal(t, "2 of 3 secrets re-encrypted with key v2\n", stdout)
+		reqs := api.received()
+		require.Len(t, reqs, 1)
+		assert.Equal(t, "Bearer k-123", reqs[0].auth)
+	})
+
+	t.Run("failures", func(t *testing.T) {
+		api := newSecretsAPI(t, map[string]cannedAnswer{"POST /api/v1/secrets/rotate": {http.StatusOK, `{"key_id": "v2", "scanned": 3, "rotated": 1, "failed": ["crm", "stripe"]}`}})
+
+		code, stdout, stderr := runSecretsCmd(t, api, "", "rotate")
+
+		assert.Equal(t, 1, code, "secrets left on the old key fail the command")
+		assert.Equal(t, "1 of 3 secrets re-encrypted with key v2\n", stdout)
+		assert.Contains(t, stderr, "2 secrets could not be decrypted with the keyring: crm, stripe")
+	})
+
+	t.Run("API error without JSON", func(t *testing.T) {
+		api := newSecretsAPI(t, map[string]cannedAnswer{"POST /api/v1/secrets/rotate": {http.StatusForbidden, "forbidden"}})
+
+		code, _, stderr := runSecretsCmd(t, api, "", "rotate")
+
+		assert.Equal(t, 1, code)
+		assert.Contains(t, stderr, "POST /api/v1/secrets/rotate: HTTP 403: forbidden")
+	})
+}
+
+func TestRunSecrets_FlagErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		stdin     string
+		wantError string
+	}{
+		{"no command", nil, "", "usage: runner secrets"},
+		{"unknown command", []string{"show"}, "", `unknown command "show"`},
+		{"set without id", []string{"set", "-type", "token", "-field", "token=x"}, "", "secret id is required"},
+		{"set without type", []string{"set", "stripe", "-field", "token=x"}, "", "-type is required"},
+		{"set without value", []string{"set", "stripe", "-type", "token"}, "", "a value is required"},
+		{"set with value and value-file", []string{"set", "stripe", "-type", "token", "-value", `{"token": "x"}`, "-value-file", "-"}, "", "either -value or -value-file"},
+		{"set with a non-object value", []string{"set", "stripe", "-type", "token", "-value-file", "-"}, `["x"]`, "value must be a JSON object"},
+		{"malformed field", []string{"set", "stripe", "-type", "token", "-field", "token"}, "", `field must be key=value, got "token"`},
+		{"two ids", []string{"delete", "stripe", "crm"}, "", "unexpected arguments: crm"},
+		{"list with an id", []string{"list", "stripe"}, "", `unexpected argument "stripe"`},
+		{"rotate with an id", []string{"rotate", "stripe"}, "", `unexpected argument "stripe"`},
+		{"unknown flag", []string{"list", "-type", "token"}, "", "flag provided but not defined: -type"},
+		{"invalid workspace", []string{"list", "-db", "postgres://localhost/config", "-workspace", "Team A"}, "", `invalid workspace "Team A"`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			api := newSecretsAPI(t, nil)
+
+			code, stdout, stderr := runSecretsCmd(t, api, tc.stdin, tc.args...)
+
+			assert.Equal(t, 2, code)
+			assert.Empty(t, stdout)
+			assert.Contains(t, stderr, tc.wantError)
+			assert.Empty(t, api.received(), "the API is not called")
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"flowjs-works/engine/internal/engine"
+	"flowjs-works/engine/internal/logging"
+	"flowjs-works/engine/internal/middleware"
+	procstore "flowjs-works/engine/internal/store"
+	"flowjs-works/engine/internal/tenant"
+	"flowjs-works/engine/internal/triggers"
+)
+
+// purgeProtection is how long after its last execution an archived process
+// cannot be purged, read from PROCESS_PURGE_PROTECTION (default 30 days, "0"
+// disables it).
+var purgeProtection = 30 * 24 * time.Hour
+
+// handleDeleteProcess serves DELETE /api/v1/processes/{id}. By default it
+// archives the process after stopping its trigger; ?purge=true permanently
+// removes an archived process that has not run within purgeProtection.
+func handleDeleteProcess(w http.ResponseWriter, r *http.Request, processID string, procStore *procstore.ProcessStore, triggerMgr *triggers.Manager, executor *engine.ProcessExecutor) {
+	// Only processes of the caller's workspace may be touched.
+	if _, err := procStore.Get(r.Context(), processID); err != nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if r.URL.Query().Get("purge") == "true" {
+		err := procStore.Purge(r.Context(), processID, purgeProtection)
+		switch {
+		case errors.Is(err, procstore.ErrNotArchived):
+			jsonError(w, fmt.Sprintf("process %q must be archived before it is purged", processID), http.StatusConflict)
+		case errors.Is(err, procstore.ErrRecentlyExecuted):
+			jsonError(w, fmt.Sprintf("%v; processes cannot be purged within %s of their last execution", err, purgeProtection), http.StatusConflict)
+		case err != nil:
+			slog.Error("engine-server: purge process", logging.KeyProcessID, processID, logging.KeyError, err)
+			jsonError(w, middleware.SanitizeError(err, "failed to purge process"), http.StatusInternalServerError)
+		default:
+			slog.Info("engine-server: process purged", logging.KeyProcessID, processID)
+			w.WriteHeader(http.StatusNoContent)
+		}
+		return
+	}
+
+	// Stop the trigger first if running.
+	if triggerMgr.IsRunning(processID) {
+		triggerType := triggerMgr.TriggerType(processID)
+		if err := triggerMgr.Stop(processID); err == nil {
+			executor.SendLifecycleAuditLog(tenant.Workspace(r.Context()), processID, triggerType, "stopped", "")
+		}
+	}
+	if err := procStore.Archive(r.Context(), processID); err != nil {
+		slog.Error("engine-server: archive process", logging.KeyProcessID, processID, logging.KeyError, err)
+		jsonError(w, middleware.SanitizeError(err, "failed to archive process"), http.StatusInternalServerError)
+		return
+	}
+	slog.Info("engine-server: process archived", logging.KeyProcessID, processID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleRestore serves POST /api/v1/processes/{id}/restore, returning an
+// archived process to the status it had before (deployed comes back stopped).
+func handleRestore(w http.ResponseWriter, r *http.Request, processID string, procStore *procstore.ProcessStore) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	rec, err := procStore.Restore(r.Context(), processID)
+	switch {
+	case errors.Is(err, procstore.ErrProcessNotFound):
+		jsonError(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, procstore.ErrNotArchived):
+		jsonError(w, fmt.Sprintf("process %q is not archived", processID), http.StatusConflict)
+	case err != nil:
+		slog.Error("engine-server: restore process", logging.KeyProcessID, processID, logging.KeyError, err)
+		jsonError(w, middleware.SanitizeError(err, "failed to restore process"), http.StatusInternalServerError)
+	default:
+		slog.Info("engine-server: process restored", logging.KeyProcessID, processID, "status", rec.Status)
+		w.Header().Set("ETag", processETag(rec.Revision))
+		jsonOK(w, rec)
+	}
+}
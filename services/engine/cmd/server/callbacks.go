@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"flowjs-works/engine/internal/activities"
+)
+
+// maxCallbackBody bounds the body of a callback request.
+const maxCallbackBody = 10 << 20
+
+// handleCallback serves /callbacks/{token}: it resumes the callback_await node
+// waiting on token with the request. The route is public; the token, random
+// and valid once, is what authorises the caller.
+func handleCallback(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimPrefix(r.URL.Path, "/callbacks/")
+	if token == "" || strings.Contains(token, "/") {
+		jsonError(w, "callback not found", http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodPost && r.Method != http.MethodPut && r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	raw, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxCallbackBody))
+	if err != nil {
+		jsonError(w, "callback body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	cb := activities.Callback{Method: r.Method, Headers: map[string]interface{}{}, Query: map[string]interface{}{}}
+	for k, vv := range r.Header {
+		if len(vv) > 0 {
+			cb.Headers[k] = vv[0]
+		}
+	}
+	for k, vv := range r.URL.Query() {
+		if len(vv) > 0 {
+			cb.Query[k] = vv[0]
+		}
+	}
+	if len(raw) > 0 {
+		if json.Unmarshal(raw, &cb.Body) != nil {
+			cb.Body = string(raw)
+		}
+	}
+
+	if err := activities.DeliverCallback(token, cb); errors.Is(err, activities.ErrUnknownCallback) {
+		jsonError(w, "callback not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"flowjs-works/engine/internal/engine"
+	"flowjs-works/engine/internal/logging"
+	"flowjs-works/engine/internal/middleware"
+	procstore "flowjs-works/engine/internal/store"
+	"flowjs-works/engine/internal/triggers"
+)
+
+// handleCaptures serves the requests captured by a REST or SOAP trigger with
+// capture_days:
+//
+//	GET  /api/v1/processes/{id}/captures                 — newest first (?limit, default 50, max 200)
+//	GET  /api/v1/processes/{id}/captures/{captureId}     — headers and body
+//	POST /api/v1/processes/{id}/captures/{captureId}/replay — re-fire through the current DSL
+//
+// sub is the path after "captures/".
+func handleCaptures(w http.ResponseWriter, r *http.Request, processID, sub string, procStore *procstore.ProcessStore, capStore *procstore.CaptureStore, executor *engine.ProcessExecutor) {
+	if capStore == nil {
+		jsonError(w, "capture store not configured (DATABASE_URL missing)", http.StatusServiceUnavailable)
+		return
+	}
+	if sub == "" {
+		listCaptures(w, r, processID, capStore)
+		return
+	}
+	idPart, action, _ := strings.Cut(sub, "/")
+	captureID, err := strconv.ParseInt(idPart, 10, 64)
+	if err != nil || captureID <= 0 {
+		jsonError(w, "capture id must be a positive integer", http.StatusBadRequest)
+		return
+	}
+	switch action {
+	case "":
+		getCapture(w, r, processID, captureID, capStore)
+	case "replay":
+		replayCapture(w, r, processID, captureID, procStore, capStore, executor)
+	default:
+		jsonError(w, fmt.Sprintf("unknown capture sub-resource: %q", action), http.StatusNotFound)
+	}
+}
+
+func listCaptures(w http.ResponseWriter, r *http.Request, processID string, capStore *procstore.CaptureStore) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	limit := 50
+	if n, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && n > 0 {
+		limit = min(n, 200)
+	}
+	list, err := capStore.ListCaptures(r.Context(), processID, limit)
+	if err != nil {
+		slog.Error("engine-server: list captures", logging.KeyProcessID, processID, logging.KeyError, err)
+		jsonError(w, middleware.SanitizeError(err, "failed to list captures"), http.StatusInternalServerError)
+		return
+	}
+	if list == nil {
+		list = []procstore.CaptureSummary{}
+	}
+	jsonOK(w, list)
+}
+
+func getCapture(w http.ResponseWriter, r *http.Request, processID string, captureID int64, capStore *procstore.CaptureStore) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	c, err := capStore.GetCapture(r.Context(), processID, captureID)
+	switch {
+	case errors.Is(err, procstore.ErrCaptureNotFound):
+		jsonError(w, fmt.Sprintf("capture %d of process %q not found or expired", captureID, processID), http.StatusNotFound)
+	case err != nil:
+		slog.Error("engine-server: get capture", logging.KeyProcessID, processID, "capture_id", captureID, logging.KeyError, err)
+		jsonError(w, middleware.SanitizeError(err, "failed to load capture"), http.StatusInternalServerError)
+	default:
+		jsonOK(w, c)
+	}
+}
+
+// replayCapture re-fires a captured request through the DSL the process
+// deploys with now (see loadDeployable), whether or not it is deployed, and
+// answers with the trigger's own response.
+func replayCapture(w http.ResponseWriter, r *http.Request, processID string, captureID int64, procStore *procstore.ProcessStore, capStore *procstore.CaptureStore, executor *engine.ProcessExecutor) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	c, err := capStore.GetCapture(r.Context(), processID, captureID)
+	if errors.Is(err, procstore.ErrCaptureNotFound) {
+		jsonError(w, fmt.Sprintf("capture %d of process %q not found or expired", captureID, processID), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		slog.Error("engine-server: get capture", logging.KeyProcessID, processID, "capture_id", captureID, logging.KeyError, err)
+		jsonError(w, middleware.SanitizeError(err, "failed to load capture"), http.StatusInternalServerError)
+		return
+	}
+	proc, ok := loadDeployable(w, r, processID, procStore)
+	if !ok {
+		return
+	}
+
+	slog.Info("engine-server: replaying captured request", logging.KeyProcessID, processID, "capture_id", captureID)
+	w.Header().Set("X-Replayed-Capture", strconv.FormatInt(captureID, 10))
+	if err := triggers.Replay(w, proc, executor, c); err != nil {
+		w.Header().Del("X-Replayed-Capture")
+		if errors.Is(err, triggers.ErrCaptureMismatch) {
+			jsonError(w, err.Error(), http.StatusConflict)
+			return
+		}
+		jsonError(w, err.Error(), http.StatusUnprocessableEntity)
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"flowjs-works/engine/internal/engine"
+	"flowjs-works/engine/internal/models"
+	"flowjs-works/engine/internal/tenant"
+)
+
+// debugCommand is the body of POST /v1/flow/debug/{sessionId}.
+type debugCommand struct {
+	// Command is "step", "continue", "modify" or "breakpoints".
+	Command string `json:"command"`
+	// Set maps JSONPaths of the context to their new value (modify).
+	Set map[string]interface{} `json:"set,omitempty"`
+	// Breakpoints replaces the nodes the session pauses before (breakpoints).
+	Breakpoints []string `json:"breakpoints,omitempty"`
+}
+
+// handleDebug serves the step-through debugging API:
+//
+//	POST   /v1/flow/debug              — start a session: {dsl, trigger_data, breakpoints}
+//	GET    /v1/flow/debug/{sessionId}  — current state
+//	POST   /v1/flow/debug/{sessionId}  — {command: step|continue|modify|breakpoints}
+//	DELETE /v1/flow/debug/{sessionId}  — abort the session
+//
+// Every response is an engine.DebugState. Sessions belong to the caller's
+// workspace.
+func handleDebug(executor *engine.ProcessExecutor) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1/flow/debug"), "/")
+		if id == "" {
+			if r.Method != http.MethodPost {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			startDebug(w, r, executor)
+			return
+		}
+
+		s := executor.DebugSession(id)
+		if s == nil || s.Workspace != tenant.Workspace(r.Context()) {
+			jsonError(w, "debug session not found: "+id, http.StatusNotFound)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			jsonOK(w, s.State())
+		case http.MethodPost:
+			var cmd debugCommand
+			if err := decodeBody(r, &cmd); err != nil {
+				jsonError(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+				return
+			}
+			var (
+				st  engine.DebugState
+				err error
+			)
+			switch cmd.Command {
+			case "step":
+				st, err = s.Step(r.Context())
+			case "continue":
+				st, err = s.Continue(r.Context())
+			case "modify":
+				st, err = s.Modify(cmd.Set)
+				if err != nil && !errors.Is(err, engine.ErrDebugNotPaused) {
+					jsonError(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+			case "breakpoints":
+				st = s.SetBreakpoints(cmd.Breakpoints)
+			default:
+				jsonError(w, "command must be one of step, continue, modify, breakpoints", http.StatusBadRequest)
+				return
+			}
+			if err != nil {
+				jsonError(w, fmt.Sprintf("%v (status %s)", err, st.Status), http.StatusConflict)
+				return
+			}
+			jsonOK(w, st)
+		case http.MethodDelete:
+			executor.AbortDebug(id)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// startDebug starts a debug session of the posted DSL and writes its first
+// state.
+func startDebug(w http.ResponseWriter, r *http.Request, executor *engine.ProcessExecutor) {
+	var req struct {
+		DSL         models.Process         `json:"dsl"`
+		TriggerData map[string]interface{} `json:"trigger_data"`
+		Breakpoints []string               `json:"breakpoints"`
+	}
+	if err := decodeBody(r, &req); err != nil {
+		jsonError(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	if req.TriggerData == nil {
+		req.TriggerData = map[string]interface{}{}
+	}
+	// The workspace always comes from the caller, never from the posted DSL.
+	req.DSL.Definition.Workspace = tenant.Workspace(r.Context())
+
+	s, err := executor.StartDebug(r.Context(), &req.DSL, req.TriggerData, req.Breakpoints)
+	if errors.Is(err, engine.ErrTooManyDebugSessions) {
+		jsonError(w, err.Error(), http.StatusTooManyRequests)
+		return
+	}
+	if err != nil {
+		jsonError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(s.State())
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"flowjs-works/engine/internal/deps"
+	"flowjs-works/engine/internal/logging"
+	"flowjs-works/engine/internal/middleware"
+	"flowjs-works/engine/internal/models"
+	"flowjs-works/engine/internal/secrets"
+	procstore "flowjs-works/engine/internal/store"
+)
+
+// handleDependencies serves GET /api/v1/processes/{id}/dependencies: the
+// secrets, schemas, snippets, external hosts and trigger resources the stored
+// draft depends on. With ?env=dev|staging|prod the DSL promoted to that
+// environment is analysed instead, with its secret and profile mappings
+// applied. Secrets and profiles that do not exist in the workspace are
+// flagged as missing.
+func handleDependencies(w http.ResponseWriter, r *http.Request, processID string, procStore *procstore.ProcessStore, secretStore *secrets.SecretStore, profStore *procstore.ProfileStore) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	env := r.URL.Query().Get("env")
+	if env != "" && !models.ValidEnvironment(env) {
+		jsonError(w, fmt.Sprintf("env must be one of %s", strings.Join(models.Environments, ", ")), http.StatusBadRequest)
+		return
+	}
+
+	var proc *models.Process
+	rec, err := procStore.Get(r.Context(), processID)
+	if err == nil {
+		if env == "" {
+			proc, err = rec.ParseDSL()
+		} else {
+			var envRec *procstore.EnvironmentRecord
+			if envRec, err = procStore.GetEnvironment(r.Context(), processID, env); err == nil {
+				proc, err = envRec.ParseDSL()
+			}
+		}
+	}
+	switch {
+	case errors.Is(err, procstore.ErrNotPromoted):
+		jsonError(w, fmt.Sprintf("process %q has not been promoted to %s", processID, env), http.StatusConflict)
+		return
+	case err != nil:
+		jsonError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	graph := deps.Analyze(proc)
+	if secretStore != nil {
+		list, err := secretStore.List(r.Context())
+		if err != nil {
+			slog.Error("engine-server: list secrets for dependencies", logging.KeyProcessID, processID, logging.KeyError, err)
+			jsonError(w, middleware.SanitizeError(err, "failed to list secrets"), http.StatusInternalServerError)
+			return
+		}
+		known := make(map[string]bool, len(list))
+		for _, s := range list {
+			known[s.ID] = true
+		}
+		graph.MarkMissing(deps.KindSecret, func(id string) bool { return known[id] })
+	}
+	if profStore != nil {
+		list, err := profStore.List(r.Context())
+		if err != nil {
+			slog.Error("engine-server: list profiles for dependencies", logging.KeyProcessID, processID, logging.KeyError, err)
+			jsonError(w, middleware.SanitizeError(err, "failed to list profiles"), http.StatusInternalServerError)
+			return
+		}
+		known := make(map[string]bool, len(list))
+		for _, p := range list {
+			known[p.Name] = true
+		}
+		graph.MarkMissing(deps.KindProfile, func(name string) bool { return known[name] })
+	}
+	jsonOK(w, graph)
+}
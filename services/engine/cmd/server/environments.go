@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+
+	"flowjs-works/engine/internal/engine"
+	"flowjs-works/engine/internal/logging"
+	"flowjs-works/engine/internal/middleware"
+	"flowjs-works/engine/internal/models"
+	procstore "flowjs-works/engine/internal/store"
+	"flowjs-works/engine/internal/tenant"
+	"flowjs-works/engine/internal/triggers"
+)
+
+// engineEnvironment is the deployment environment this engine serves, read
+// from ENGINE_ENVIRONMENT. When set, deploys, scheduled runs and replays use
+// the DSL promoted to it instead of the draft, so dev, staging and prod
+// engines keep separate trigger registrations of the same process.
+var engineEnvironment string
+
+// environmentFromEnv reads ENGINE_ENVIRONMENT, refusing to start on an
+// unknown environment rather than silently serving drafts.
+func environmentFromEnv() string {
+	env := strings.ToLower(strings.TrimSpace(os.Getenv("ENGINE_ENVIRONMENT")))
+	if env != "" && !models.ValidEnvironment(env) {
+		slog.Error("engine-server: ENGINE_ENVIRONMENT must be one of "+strings.Join(models.Environments, ", "), "value", env)
+		os.Exit(1)
+	}
+	return env
+}
+
+// loadDeployable returns process id as this engine runs it (see
+// engineEnvironment), writing the error response when it cannot be loaded.
+func loadDeployable(w http.ResponseWriter, r *http.Request, processID string, procStore *procstore.ProcessStore) (*models.Process, bool) {
+	proc, err := procStore.Deployable(r.Context(), processID, engineEnvironment)
+	switch {
+	case errors.Is(err, procstore.ErrNotPromoted):
+		jsonError(w, fmt.Sprintf("process %q has not been promoted to %s", processID, engineEnvironment), http.StatusConflict)
+		return nil, false
+	case errors.Is(err, procstore.ErrArchived):
+		jsonError(w, fmt.Sprintf("process %q is archived; restore it first", processID), http.StatusConflict)
+		return nil, false
+	case errors.Is(err, procstore.ErrNodeTemplateNotFound):
+		jsonError(w, err.Error(), http.StatusUnprocessableEntity)
+		return nil, false
+	case err != nil:
+		jsonError(w, err.Error(), http.StatusNotFound)
+		return nil, false
+	}
+	return proc, true
+}
+
+// handlePromote serves POST /api/v1/processes/{id}/promote?to=dev|staging|prod.
+// It copies the validated DSL of the preceding environment (the draft for dev)
+// into the target and records the promotion. A process already deployed on
+// this engine is redeployed when the target is engineEnvironment.
+func handlePromote(w http.ResponseWriter, r *http.Request, processID string, procStore *procstore.ProcessStore, triggerMgr *triggers.Manager, executor *engine.ProcessExecutor) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	to := r.URL.Query().Get("to")
+	if !models.ValidEnvironment(to) {
+		jsonError(w, fmt.Sprintf("to must be one of %s", strings.Join(models.Environments, ", ")), http.StatusBadRequest)
+		return
+	}
+	rec, err := procStore.Get(r.Context(), processID)
+	if err != nil {
+		jsonError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if rec.Status == procstore.StatusArchived {
+		jsonError(w, fmt.Sprintf("process %q is archived; restore it first", processID), http.StatusConflict)
+		return
+	}
+	var promotedBy string
+	if p, ok := tenant.PrincipalFromContext(r.Context()); ok {
+		promotedBy = p.Subject
+	}
+
+	promotion, err := procStore.Promote(r.Context(), processID, to, promotedBy)
+	switch {
+	case errors.Is(err, procstore.ErrInvalidDSL):
+		jsonError(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	case errors.Is(err, procstore.ErrNotPromoted):
+		jsonError(w, fmt.Sprintf("process %q must be promoted to %s before %s", processID, models.PreviousEnvironment(to), to), http.StatusConflict)
+		return
+	case err != nil:
+		slog.Error("engine-server: promote process", logging.KeyProcessID, processID, "to", to, logging.KeyError, err)
+		jsonError(w, middleware.SanitizeError(err, "failed to promote process"), http.StatusInternalServerError)
+		return
+	}
+	slog.Info("engine-server: process promoted", logging.KeyProcessID, processID, "from", promotion.FromEnv, "to", to, "revision", promotion.Revision)
+
+	resp := struct {
+		*procstore.Promotion
+		Redeployed bool `json:"redeployed"`
+	}{Promotion: promotion}
+	if to == engineEnvironment && triggerMgr.IsRunning(processID) {
+		proc, err := procStore.Deployable(r.Context(), processID, to)
+		if err == nil {
+			err = triggerMgr.Deploy(proc)
+		}
+		if err != nil {
+			slog.Error("engine-server: redeploy promoted process", logging.KeyProcessID, processID, logging.KeyError, err)
+		} else {
+			executor.SendLifecycleAuditLog(tenant.Workspace(r.Context()), processID, proc.Trigger.Type, "deployed", "")
+			resp.Redeployed = true
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// handleEnvironments serves GET /api/v1/processes/{id}/environments: the
+// revision promoted to each environment and the promotion history.
+func handleEnvironments(w http.ResponseWriter, r *http.Request, processID string, procStore *procstore.ProcessStore) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if _, err := procStore.Get(r.Context(), processID); err != nil {
+		jsonError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	envs, err := procStore.ListEnvironments(r.Context(), processID)
+	if err != nil {
+		slog.Error("engine-server: list environments", logging.KeyProcessID, processID, logging.KeyError, err)
+		jsonError(w, middleware.SanitizeError(err, "failed to list environments"), http.StatusInternalServerError)
+		return
+	}
+	history, err := procStore.Promotions(r.Context(), processID)
+	if err != nil {
+		slog.Error("engine-server: list promotions", logging.KeyProcessID, processID, logging.KeyError, err)
+		jsonError(w, middleware.SanitizeError(err, "failed to list promotions"), http.StatusInternalServerError)
+		return
+	}
+	if envs == nil {
+		envs = []procstore.EnvironmentRecord{}
+	}
+	if history == nil {
+		history = []procstore.Promotion{}
+	}
+	jsonOK(w, map[string]interface{}{
+		"current":      engineEnvironment,
+		"environments": envs,
+		"promotions":   history,
+	})
+}
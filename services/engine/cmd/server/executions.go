@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"flowjs-works/engine/internal/engine"
+	"flowjs-works/engine/internal/logging"
+	"flowjs-works/engine/internal/middleware"
+	procstore "flowjs-works/engine/internal/store"
+)
+
+// handleExecution serves the per-execution endpoints:
+//
+//	GET  /api/v1/executions/{id}/context         — final ExecutionContext snapshot
+//	GET  /api/v1/executions/{id}/context?path=$. — value one JSONPath resolves to
+//	GET  /api/v1/executions/{id}/version         — process revision and DSL hash it ran
+//	POST /api/v1/executions/{id}/retry           — re-run a failed execution from its failed node
+func handleExecution(snapStore *procstore.SnapshotStore, verStore *procstore.VersionStore, procStore *procstore.ProcessStore, executor *engine.ProcessExecutor) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if snapStore == nil {
+			jsonError(w, "snapshot store not configured (DATABASE_URL missing)", http.StatusServiceUnavailable)
+			return
+		}
+		executionID, sub, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/v1/executions/"), "/")
+		if executionID == "" {
+			jsonError(w, "execution id is required", http.StatusBadRequest)
+			return
+		}
+		switch {
+		case sub == "context" && r.Method == http.MethodGet:
+			executionContext(w, r, snapStore, executionID)
+		case sub == "version" && r.Method == http.MethodGet:
+			executionVersion(w, r, verStore, executionID)
+		case sub == "retry" && r.Method == http.MethodPost:
+			retryExecution(w, r, snapStore, verStore, procStore, executor, executionID)
+		case sub == "context" || sub == "version" || sub == "retry":
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		default:
+			jsonError(w, "not found", http.StatusNotFound)
+		}
+	}
+}
+
+// loadSnapshot writes the error response and returns nil when executionID
+// has no readable snapshot.
+func loadSnapshot(w http.ResponseWriter, r *http.Request, snapStore *procstore.SnapshotStore, executionID string) *procstore.Snapshot {
+	snap, err := snapStore.Get(r.Context(), executionID)
+	if errors.Is(err, procstore.ErrSnapshotNotFound) {
+		jsonError(w, "no context snapshot for execution "+executionID, http.StatusNotFound)
+		return nil
+	}
+	if err != nil {
+		slog.Error("engine-server: get execution snapshot", logging.KeyExecutionID, executionID, logging.KeyError, err)
+		jsonError(w, middleware.SanitizeError(err, "failed to load execution context"), http.StatusInternalServerError)
+		return nil
+	}
+	return snap
+}
+
+func executionContext(w http.ResponseWriter, r *http.Request, snapStore *procstore.SnapshotStore, executionID string) {
+	snap := loadSnapshot(w, r, snapStore, executionID)
+	if snap == nil {
+		return
+	}
+	path := r.URL.Query().Get("path")
+	if path == "" {
+		jsonOK(w, snap)
+		return
+	}
+	value, err := snap.Context.GetValue(path)
+	if err != nil {
+		jsonError(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	jsonOK(w, map[string]interface{}{"path": path, "value": value})
+}
+
+// retryExecution re-runs the failed node of executionID and everything after
+// it, reusing the persisted trigger data and successful node outputs. It runs
+// the definition executionID ran unless ?version=current (see rerunProcess).
+func retryExecution(w http.ResponseWriter, r *http.Request, snapStore *procstore.SnapshotStore, verStore *procstore.VersionStore, procStore *procstore.ProcessStore, executor *engine.ProcessExecutor, executionID string) {
+	if procStore == nil {
+		jsonError(w, "process store not configured (DATABASE_URL missing)", http.StatusServiceUnavailable)
+		return
+	}
+	snap := loadSnapshot(w, r, snapStore, executionID)
+	if snap == nil {
+		return
+	}
+	rec, err := procStore.Get(r.Context(), snap.ProcessID)
+	if err != nil {
+		jsonError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	proc, err := rec.ParseDSL()
+	if err != nil {
+		jsonError(w, fmt.Sprintf("parse DSL: %v", err), http.StatusInternalServerError)
+		return
+	}
+	proc, ok := rerunProcess(w, r, verStore, executionID, proc)
+	if !ok {
+		return
+	}
+
+	ctx, execErr := executor.RetryExecution(proc, snap.Context)
+	if errors.Is(execErr, engine.ErrNothingToRetry) || errors.Is(execErr, engine.ErrSnapshotIncomplete) {
+		jsonError(w, fmt.Sprintf("execution %s: %v", executionID, execErr), http.StatusConflict)
+		return
+	}
+	writeFlowResponse(w, ctx, execErr)
+}
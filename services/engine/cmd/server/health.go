@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"flowjs-works/engine/internal/engine"
+	"flowjs-works/engine/internal/logging"
+	"flowjs-works/engine/internal/middleware"
+	"flowjs-works/engine/internal/secrets"
+	"flowjs-works/engine/internal/triggers"
+)
+
+// healthCheckTimeout bounds each component check of GET /health/deep.
+const healthCheckTimeout = 2 * time.Second
+
+// Component and overall statuses of GET /health/deep.
+const (
+	healthOK       = "ok"
+	healthDegraded = "degraded" // working, with reduced guarantees
+	healthDown     = "down"     // not ready to serve
+	healthDisabled = "disabled" // not configured
+)
+
+// componentHealth is one entry of the GET /health/deep report.
+type componentHealth struct {
+	Status    string                 `json:"status"`
+	LatencyMs int64                  `json:"latency_ms,omitempty"`
+	Error     string                 `json:"error,omitempty"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+}
+
+// deepHealth checks the dependencies of the engine for GET /health/deep.
+type deepHealth struct {
+	executor    *engine.ProcessExecutor
+	configDB    *sql.DB // nil without DATABASE_URL
+	secretStore *secrets.SecretStore
+	triggerMgr  *triggers.Manager
+	// auditLoggerURL is the base URL of the audit-logger, whose /health is
+	// checked when set (AUDIT_LOGGER_URL).
+	auditLoggerURL string
+	client         *http.Client
+	// timeout bounds each check; healthCheckTimeout when zero.
+	timeout time.Duration
+}
+
+// handleDeepHealth serves GET /health/deep: a readiness report covering NATS,
+// the config DB, the secret store, deployed triggers and the audit-logger.
+// It answers 503 when a component the engine cannot serve without is down;
+// NATS and the audit-logger only degrade it, since audit events are buffered.
+func handleDeepHealth(h *deepHealth) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		components := h.check(r.Context())
+		overall := healthOK
+		for name, c := range components {
+			switch {
+			case c.Status == healthDown && (name == "config_db" || name == "secret_store"):
+				overall = healthDown
+			case (c.Status == healthDown || c.Status == healthDegraded) && overall == healthOK:
+				overall = healthDegraded
+			}
+		}
+		status := http.StatusOK
+		if overall == healthDown {
+			status = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":     overall,
+			"service":    "engine",
+			"components": components,
+		})
+	}
+}
+
+// check runs the component checks concurrently.
+func (h *deepHealth) check(ctx context.Context) map[string]componentHealth {
+	checks := map[string]func(context.Context) componentHealth{
+		"nats":         h.checkNATS,
+		"config_db":    h.checkConfigDB,
+		"secret_store": h.checkSecretStore,
+		"triggers":     h.checkTriggers,
+		"audit_logger": h.checkAuditLogger,
+	}
+	timeout := h.timeout
+	if timeout <= 0 {
+		timeout = healthCheckTimeout
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	out := make(map[string]componentHealth, len(checks))
+	for name, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			start := time.Now()
+			c := check(cctx)
+			if c.Status != healthDisabled && name != "triggers" {
+				c.LatencyMs = time.Since(start).Milliseconds()
+			}
+			mu.Lock()
+			out[name] = c
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return out
+}
+
+func (h *deepHealth) checkNATS(context.Context) componentHealth {
+	conn := h.executor.AuditConnection()
+	stats := h.executor.AuditStats()
+	details := map[string]interface{}{"connection": conn, "buffered": stats.Buffered, "spilled_bytes": stats.SpilledBytes}
+	switch conn {
+	case "disabled":
+		return componentHealth{Status: healthDisabled}
+	case "CONNECTED":
+		return componentHealth{Status: healthOK, Details: details}
+	}
+	return componentHealth{Status: healthDegraded, Error: "not connected; audit events are buffered", Details: details}
+}
+
+func (h *deepHealth) checkConfigDB(ctx context.Context) componentHealth {
+	if h.configDB == nil {
+		return componentHealth{Status: healthDisabled}
+	}
+	if err := h.configDB.PingContext(ctx); err != nil {
+		slog.Warn("engine-server: health check config db ping", logging.KeyError, err)
+		return componentHealth{Status: healthDown, Error: middleware.SanitizeError(err, "database unreachable")}
+	}
+	return componentHealth{Status: healthOK}
+}
+
+func (h *deepHealth) checkSecretStore(ctx context.Context) componentHealth {
+	switch {
+	case h.configDB == nil:
+		return componentHealth{Status: healthDisabled}
+	case h.secretStore == nil:
+		return componentHealth{Status: healthDown, Error: "secret store not initialised (check SECRETS_AES_KEY)"}
+	}
+	if err := h.secretStore.Ping(ctx); err != nil {
+		slog.Warn("engine-server: health check secret store", logging.KeyError, err)
+		return componentHealth{Status: healthDown, Error: middleware.SanitizeError(err, "secret store unreachable")}
+	}
+	return componentHealth{Status: healthOK}
+}
+
+func (h *deepHealth) checkTriggers(context.Context) componentHealth {
+	counts := h.triggerMgr.Counts()
+	total := 0
+	for _, n := range counts {
+		total += n
+	}
+	details := map[string]interface{}{"deployed": total, "by_type": counts}
+	// Triggers reconnecting to their broker fire nothing until they are back.
+	var reconnecting []string
+	for id, s := range h.triggerMgr.Statuses() {
+		if s.State == triggers.TriggerReconnecting {
+			reconnecting = append(reconnecting, id)
+		}
+	}
+	if len(reconnecting) > 0 {
+		slices.Sort(reconnecting)
+		details["reconnecting"] = reconnecting
+		return componentHealth{Status: healthDegraded, Details: details}
+	}
+	return componentHealth{Status: healthOK, Details: details}
+}
+
+func (h *deepHealth) checkAuditLogger(ctx context.Context) componentHealth {
+	if h.auditLoggerURL == "" {
+		return componentHealth{Status: healthDisabled}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(h.auditLoggerURL, "/")+"/health", nil)
+	if err != nil {
+		return componentHealth{Status: healthDown, Error: "invalid AUDIT_LOGGER_URL"}
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		slog.Warn("engine-server: health check audit-logger", logging.KeyError, err)
+		return componentHealth{Status: healthDown, Error: middleware.SanitizeError(err, "audit-logger unreachable")}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return componentHealth{Status: healthDown, Error: fmt.Sprintf("audit-logger health returned %d", resp.StatusCode)}
+	}
+	return componentHealth{Status: healthOK}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"flowjs-works/engine/internal/engine"
+	"flowjs-works/engine/internal/triggers"
+
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// deepHealthReport is the body of GET /health/deep.
+type deepHealthReport struct {
+	Status     string                     `json:"status"`
+	Components map[string]componentHealth `json:"components"`
+}
+
+func newDeepHealth(t *testing.T, auditLoggerURL string) *deepHealth {
+	t.Helper()
+	executor, err := engine.NewProcessExecutor("")
+	require.NoError(t, err)
+	t.Cleanup(executor.Close)
+	return &deepHealth{
+		executor:       executor,
+		triggerMgr:     triggers.NewManager(executor),
+		auditLoggerURL: auditLoggerURL,
+		client:         &http.Client{},
+		timeout:        100 * time.Millisecond,
+	}
+}
+
+func getDeepHealth(t *testing.T, h *deepHealth) (int, deepHealthReport) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handleDeepHealth(h)(rec, httptest.NewRequest(http.MethodGet, "/health/deep", nil))
+	var report deepHealthReport
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	return rec.Code, report
+}
+
+// auditLogger serves /health with status after delay.
+func auditLogger(t *testing.T, status int, delay time.Duration) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func TestDeepHealth_AllOK(t *testing.T) {
+	code, report := getDeepHealth(t, newDeepHealth(t, auditLogger(t, http.StatusOK, 0)))
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, healthOK, report.Status)
+	assert.Equal(t, healthOK, report.Components["audit_logger"].Status)
+	assert.Equal(t, healthOK, report.Components["triggers"].Status)
+	assert.Equal(t, healthDisabled, report.Components["nats"].Status)
+	assert.Equal(t, healthDisabled, report.Components["config_db"].Status)
+}
+
+func TestDeepHealth_FailingCheckDegrades(t *testing.T) {
+	code, report := getDeepHealth(t, newDeepHealth(t, auditLogger(t, http.StatusInternalServerError, 0)))
+
+	assert.Equal(t, http.StatusOK, code, "the engine still serves without the audit-logger")
+	assert.Equal(t, healthDegraded, report.Status)
+	assert.Equal(t, healthDown, report.Components["audit_logger"].Status)
+	assert.Equal(t, "audit-logger health returned 500", report.Components["audit_logger"].Error)
+}
+
+func TestDeepHealth_TimedOutCheck(t *testing.T) {
+	h := newDeepHealth(t, auditLogger(t, http.StatusOK, 5*time.Second))
+
+	start := time.Now()
+	code, report := getDeepHealth(t, h)
+
+	assert.Less(t, time.Since(start), 2*time.Second, "a hanging check is abandoned at its timeout")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, healthDegraded, report.Status)
+	audit := report.Components["audit_logger"]
+	assert.Equal(t, healthDown, audit.Status)
+	assert.NotEmpty(t, audit.Error)
+	assert.GreaterOrEqual(t, audit.LatencyMs, int64(100))
+}
+
+func TestDeepHealth_ConfigDBDownIsUnavailable(t *testing.T) {
+	// Nothing listens on port 1: the ping fails at once.
+	db, err := sql.Open("postgres", "host=127.0.0.1 port=1 user=x dbname=x sslmode=disable connect_timeout=1")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	h := newDeepHealth(t, auditLogger(t, http.StatusOK, 0))
+	h.configDB = db
+
+	code, report := getDeepHealth(t, h)
+
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, healthDown, report.Status)
+	assert.Equal(t, healthDown, report.Components["config_db"].Status)
+	assert.Equal(t, healthDown, report.Components["secret_store"].Status, "no secret store without its database")
+	assert.Equal(t, healthOK, report.Components["audit_logger"].Status)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"flowjs-works/engine/internal/models"
+	procstore "flowjs-works/engine/internal/store"
+	"flowjs-works/engine/internal/store/storetest"
+
+	"github.com/stretchr/testify/require"
+)
+
+// storedProcess is a row of the processes table served by a storetest DB.
+type storedProcess struct {
+	workspace   string
+	proc        *models.Process
+	status      string
+	revision    int
+	deployError string
+}
+
+// recordCols mirrors the columns of a process record; storetest only needs
+// their number.
+var recordCols = []string{"id", "workspace", "version", "name", "description", "dsl", "status", "revision",
+	"owner", "team", "last_modified_by", "change_note", "created_at", "updated_at", "archived_at", "last_run_at", "deploy_error"}
+
+// summaryCols mirrors the columns of a process summary.
+var summaryCols = []string{"id", "workspace", "version", "name", "status", "revision", "trigger_type",
+	"owner", "team", "last_modified_by", "change_note", "updated_at", "archived_at", "last_run_at", "deploy_error"}
+
+func (p storedProcess) id() string { return p.proc.Definition.ID }
+
+func (p storedProcess) record(t *testing.T) []driver.Value {
+	t.Helper()
+	dsl, err := json.Marshal(p.proc)
+	require.NoError(t, err)
+	now := time.Now()
+	return []driver.Value{p.id(), p.workspace, p.proc.Definition.Version, p.proc.Definition.Name, "", dsl, p.status, int64(p.revision),
+		"", "", "", "", now, now, nil, nil, p.deployError}
+}
+
+func (p storedProcess) summary() []driver.Value {
+	return []driver.Value{p.id(), p.workspace, p.proc.Definition.Version, p.proc.Definition.Name, p.status, int64(p.revision), p.proc.Trigger.Type,
+		"", "", "", "", time.Now(), nil, nil, p.deployError}
+}
+
+// processStoreWith returns a process store answering Get for procs (by id and
+// workspace) and ListDeployed with the deployed ones, and the fake database
+// for further rules.
+func processStoreWith(t *testing.T, procs ...storedProcess) (*procstore.ProcessStore, *storetest.DB) {
+	t.Helper()
+	db, fake := storetest.Open(t)
+	fake.On("FROM processes WHERE id = $1 AND workspace = $2", func(args []driver.Value) storetest.Result {
+		for _, p := range procs {
+			if p.id() == args[0] && p.workspace == args[1] {
+				return storetest.Result{Columns: recordCols, Rows: [][]driver.Value{p.record(t)}}
+			}
+		}
+		return storetest.Result{Columns: recordCols}
+	})
+	fake.On("FROM processes WHERE status = 'deployed'", func([]driver.Value) storetest.Result {
+		res := storetest.Result{Columns: summaryCols}
+		for _, p := range procs {
+			if p.status == "deployed" {
+				res.Rows = append(res.Rows, p.summary())
+			}
+		}
+		return res
+	})
+	return procstore.NewProcessStore(db), fake
+}
+
+// savedRecord is the record the process upsert returns for its args: the
+// DSL it was sent as a draft at revision rev.
+func savedRecord(args []driver.Value, rev int) []driver.Value {
+	now := time.Now()
+	return []driver.Value{args[0], args[1], args[2], args[3], args[4], args[5], "draft", int64(rev),
+		args[7], args[8], args[7], args[9], now, now, nil, nil, ""}
+}
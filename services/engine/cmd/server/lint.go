@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+
+	"flowjs-works/engine/internal/lint"
+	"flowjs-works/engine/internal/logging"
+	"flowjs-works/engine/internal/models"
+	procstore "flowjs-works/engine/internal/store"
+)
+
+// lintConfig holds the rule severities of the lint endpoints, read at start
+// from the file named by FLOWLINT_CONFIG or the nearest .flowlint.
+var lintConfig lint.Config
+
+// lintConfigFromEnv loads the lint configuration. An unreadable or invalid
+// file stops the server rather than silently linting with the defaults.
+func lintConfigFromEnv() lint.Config {
+	path := os.Getenv("FLOWLINT_CONFIG")
+	if path == "" {
+		if path = lint.FindConfig("."); path == "" {
+			return lint.Config{}
+		}
+	}
+	cfg, err := lint.LoadConfig(path)
+	if err != nil {
+		slog.Error("engine-server: invalid lint config", "path", path, logging.KeyError, err)
+		os.Exit(1)
+	}
+	slog.Info("engine-server: lint config loaded", "path", path)
+	return cfg
+}
+
+// lintRule is a rule as listed by GET /api/v1/lint.
+type lintRule struct {
+	lint.Rule
+	Severity lint.Severity `json:"severity"`
+}
+
+// handleLint serves the lint endpoints:
+//
+//	GET  /api/v1/lint — the rules and their configured severities
+//	POST /api/v1/lint — lint the process DSL in the body (JSON or YAML)
+func handleLint(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		rules := make([]lintRule, 0, len(lint.Rules))
+		for _, rule := range lint.Rules {
+			sev := rule.Default
+			if s, ok := lintConfig.Rules[rule.Name]; ok {
+				sev = s
+			}
+			rules = append(rules, lintRule{Rule: rule, Severity: sev})
+		}
+		jsonOK(w, rules)
+	case http.MethodPost:
+		var proc models.Process
+		if err := decodeBody(r, &proc); err != nil {
+			jsonError(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+		jsonOK(w, lint.Lint(&proc, lintConfig))
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleProcessLint serves GET /api/v1/processes/{id}/lint, linting the
+// stored draft of the process.
+func handleProcessLint(w http.ResponseWriter, r *http.Request, processID string, procStore *procstore.ProcessStore) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	rec, err := procStore.Get(r.Context(), processID)
+	if err != nil {
+		jsonError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	var proc models.Process
+	if err := json.Unmarshal(rec.DSL, &proc); err != nil {
+		jsonError(w, fmt.Sprintf("stored DSL is invalid: %v", err), http.StatusUnprocessableEntity)
+		return
+	}
+	jsonOK(w, lint.Lint(&proc, lintConfig))
+}
@@ -10,22 +10,32 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
+	"mime"
 	"net/http"
 	"os"
 	"os/signal"
 	"regexp"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
+	"flowjs-works/engine/internal/egress"
 	"flowjs-works/engine/internal/engine"
+	"flowjs-works/engine/internal/logging"
 	"flowjs-works/engine/internal/middleware"
 	"flowjs-works/engine/internal/models"
+	"flowjs-works/engine/internal/notify"
+	"flowjs-works/engine/internal/queue"
+	"flowjs-works/engine/internal/scheduler"
+	"flowjs-works/engine/internal/scratch"
 	"flowjs-works/engine/internal/secrets"
 	procstore "flowjs-works/engine/internal/store"
+	"flowjs-works/engine/internal/tenant"
 	"flowjs-works/engine/internal/triggers"
 
+	"github.com/google/uuid"
 	_ "github.com/lib/pq"
 )
 
@@ -33,25 +43,53 @@ import (
 // characters, hyphens, and underscores, to prevent path traversal or injection.
 var validProcessIDRe = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,255}$`)
 
+// defaultProcessCacheTTL is how long process lookups are cached
+// (PROCESS_CACHE_TTL; 0 disables the cache). It bounds how long a change made
+// through another engine replica goes unnoticed.
+const defaultProcessCacheTTL = 30 * time.Second
+
+// staleScratchAge is how old a scratch directory must be for the engine to
+// remove it at startup, as left behind by an execution that never ended.
+const staleScratchAge = 24 * time.Hour
+
+// readHeaderTimeout bounds how long a client may take to send request headers.
+const readHeaderTimeout = 10 * time.Second
+
+// writeTimeoutGrace is added to REQUEST_TIMEOUT for the default
+// HTTP_WRITE_TIMEOUT: the time left to write the response of an execution
+// stopped at its deadline.
+const writeTimeoutGrace = 10 * time.Second
+
 // flowResponse is the shared response shape returned by /v1/flow, /replay, and /replay-from.
 type flowResponse struct {
 	ExecutionID string                            `json:"execution_id"`
 	Nodes       map[string]map[string]interface{} `json:"nodes"`
 	Error       string                            `json:"error,omitempty"`
+	// ProcessRevision and DSLHash identify the definition the execution ran.
+	ProcessRevision int    `json:"process_revision,omitempty"`
+	DSLHash         string `json:"dsl_hash,omitempty"`
 }
 
 // writeFlowResponse writes an execution result to w using the shared flowResponse shape.
-// On execution error it sets HTTP 422 Unprocessable Entity.
+// On execution error it sets HTTP 422 Unprocessable Entity, or 504 Gateway
+// Timeout when the execution outlived the request (REQUEST_TIMEOUT or the
+// client disconnecting); the execution_id then points at the audit trail.
 func writeFlowResponse(w http.ResponseWriter, ctx *models.ExecutionContext, execErr error) {
 	resp := flowResponse{Nodes: map[string]map[string]interface{}{}}
 	if ctx != nil {
 		resp.ExecutionID = ctx.ExecutionID
 		resp.Nodes = ctx.Nodes
+		resp.ProcessRevision = ctx.ProcessRevision
+		resp.DSLHash = ctx.DSLHash
 	}
 	if execErr != nil {
 		resp.Error = execErr.Error()
+		status := http.StatusUnprocessableEntity
+		if errors.Is(execErr, models.ErrExecutionCancelled) {
+			status = http.StatusGatewayTimeout
+		}
 		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.WriteHeader(status)
 		_ = json.NewEncoder(w).Encode(resp)
 		return
 	}
@@ -59,71 +97,264 @@ func writeFlowResponse(w http.ResponseWriter, ctx *models.ExecutionContext, exec
 }
 
 func main() {
+	logging.Setup()
+
 	natsURL := envOrDefault("NATS_URL", "nats://localhost:4222")
 	httpAddr := envOrDefault("HTTP_ADDR", ":9090")
 	requestTimeout := parseDurationEnv("REQUEST_TIMEOUT", 60*time.Second)
+	readTimeout := parseDurationEnv("HTTP_READ_TIMEOUT", 60*time.Second)
+	// The write timeout outlasts the request budget so a timed-out
+	// synchronous execution still gets its 504 response written.
+	writeTimeout := parseDurationEnv("HTTP_WRITE_TIMEOUT", requestTimeout+writeTimeoutGrace)
+	idleTimeout := parseDurationEnv("HTTP_IDLE_TIMEOUT", 120*time.Second)
+	engineEnvironment = environmentFromEnv()
+	purgeProtection = parseDurationEnv("PROCESS_PURGE_PROTECTION", purgeProtection)
+	lintConfig = lintConfigFromEnv()
+	egressPolicy, err := egress.PolicyFromEnv()
+	if err != nil {
+		slog.Error("engine-server: invalid egress policy", logging.KeyError, err)
+		os.Exit(1)
+	}
+	egress.SetPolicy(egressPolicy)
+	scratchPolicy, err := scratch.PolicyFromEnv()
+	if err == nil {
+		err = scratch.SetPolicy(scratchPolicy)
+	}
+	if err != nil {
+		slog.Error("engine-server: invalid scratch policy", logging.KeyError, err)
+		os.Exit(1)
+	}
+	// Scratch directories a crashed engine left behind; an execution keeps
+	// its directory for as long as it runs.
+	if n, err := scratch.RemoveStale(staleScratchAge); err != nil {
+		slog.Warn("engine-server: failed to remove stale scratch directories", logging.KeyError, err)
+	} else if n > 0 {
+		slog.Info("engine-server: removed stale scratch directories", "count", n)
+	}
 
 	executor, err := engine.NewProcessExecutor(natsURL)
 	if err != nil {
-		log.Fatalf("engine-server: failed to create executor: %v", err)
+		slog.Error("engine-server: failed to create executor", logging.KeyError, err)
+		os.Exit(1)
 	}
 	defer executor.Close()
-
-	// Trigger manager handles deploy/stop lifecycle for all trigger types.
-	triggerMgr := triggers.NewManager(executor)
-	defer triggerMgr.StopAll()
+	// callback_await nodes hand out URLs under the address external systems
+	// reach the engine at.
+	executor.SetCallbackBaseURL(envOrDefault("CALLBACK_BASE_URL", "http://localhost"+httpAddr))
+	// Audit events name the engine instance that ran them; the hostname
+	// unless ENGINE_ID is set.
+	if id := os.Getenv("ENGINE_ID"); id != "" {
+		executor.SetEngineID(id)
+	}
+	// Trigger requests may turn on fault injection with the X-Fault-Injection
+	// header only where FAULT_INJECTION_HEADER allows it, never in production.
+	if os.Getenv("FAULT_INJECTION_HEADER") == "true" {
+		executor.AllowFaultInjectionHeader()
+	}
+	// Audit events that fail to publish are retried from memory and, with
+	// AUDIT_SPILL_DIR, from disk across restarts.
+	executor.SetAuditBuffer(engine.AuditBufferConfig{
+		Size:          parseIntEnv("AUDIT_BUFFER_SIZE", engine.DefaultAuditBufferSize),
+		SpillDir:      os.Getenv("AUDIT_SPILL_DIR"),
+		SpillMaxBytes: int64(parseIntEnv("AUDIT_SPILL_MAX_BYTES", engine.DefaultAuditSpillMaxBytes)),
+	})
 
 	// Optional: connect to the config DB for secrets management and process storage.
 	// When DATABASE_URL is not set the secrets and process endpoints return 503.
 	var secretStore *secrets.SecretStore
+	var secretAudit *secrets.AuditLog
 	var processStore *procstore.ProcessStore
+	var scheduleStore *procstore.ScheduleStore
+	var snippetStore *procstore.SnippetStore
+	var profileStore *procstore.ProfileStore
+	var nodeTemplateStore *procstore.NodeTemplateStore
+	var processTemplateStore *procstore.ProcessTemplateStore
+	var schemaStore *procstore.SchemaStore
+	var snapshotStore *procstore.SnapshotStore
+	var versionStore *procstore.VersionStore
+	var captureStore *procstore.CaptureStore
+	var fileStateStore *procstore.FileStateStore
+	var notificationStore *procstore.NotificationStore
+	var dispatcher *notify.Dispatcher
+	var jobStore queue.JobStore = queue.NewMemoryStore()
+	var configDB *sql.DB
 	if dbURL := os.Getenv("DATABASE_URL"); dbURL != "" {
 		db, dbErr := sql.Open("postgres", dbURL)
 		if dbErr != nil {
-			log.Printf("engine-server: config DB unavailable: %v", dbErr)
+			slog.Error("engine-server: config DB unavailable", logging.KeyError, dbErr)
 		} else {
-			aesKey := aesKeyFromEnv("SECRETS_AES_KEY")
-			ss, storeErr := secrets.NewSecretStore(db, aesKey)
-			if storeErr != nil {
-				log.Printf("engine-server: failed to create secret store: %v", storeErr)
-			} else {
-				secretStore = ss
-				executor.SetSecretResolver(ss)
-				log.Printf("engine-server: DB-backed secret store enabled")
-			}
+			configDB = db
+			secretStore = secrets.NewSecretStoreWithKeyring(db, aesKeyringFromEnv("SECRETS_AES_KEY"))
+			// Every resolution is recorded in secrets_audit (never the value).
+			secretAudit = secrets.NewAuditLog(db)
+			executor.SetSecretResolver(secrets.NewAuditingResolver(secretStore, secretAudit, func(err error) {
+				slog.Warn("engine-server: record secret usage", logging.KeyError, err)
+			}))
+			slog.Info("engine-server: DB-backed secret store enabled", "key_id", secretStore.KeyID())
 			processStore = procstore.NewProcessStore(db)
-			log.Printf("engine-server: DB-backed process store enabled")
+			// Process lookups (deploys, replays, scheduled runs) are cached
+			// and, with DATABASE_REPLICA_URL, read from a replica.
+			processStore.EnableCache(parseDurationEnv("PROCESS_CACHE_TTL", defaultProcessCacheTTL))
+			// Saves are scanned for plain-text credentials (SECRET_SCAN=warn|reject|off).
+			if mode := os.Getenv("SECRET_SCAN"); mode != "" {
+				if err := processStore.SetSecretScan(mode); err != nil {
+					slog.Error("engine-server: invalid SECRET_SCAN; saves only warn", logging.KeyError, err)
+				}
+			}
+			if replicaURL := os.Getenv("DATABASE_REPLICA_URL"); replicaURL != "" {
+				if replica, err := sql.Open("postgres", replicaURL); err != nil {
+					slog.Error("engine-server: config DB replica unavailable; reading from the primary", logging.KeyError, err)
+				} else {
+					processStore.SetReadReplica(replica)
+					defer replica.Close()
+					slog.Info("engine-server: process reads use the config DB replica")
+				}
+			}
+			// $ref nodes are replaced by the templates of the node library
+			// when a process is loaded to deploy or run.
+			nodeTemplateStore = procstore.NewNodeTemplateStore(db)
+			processStore.SetNodeTemplates(nodeTemplateStore)
+			processTemplateStore = procstore.NewProcessTemplateStore(db)
+			slog.Info("engine-server: DB-backed process store enabled")
+			// last_run_at guards archived processes still in use from purges.
+			executor.SetRunRecorder(processStore)
+			// Claimed runs and jobs are leased to this engine (ENGINE_ID,
+			// else the hostname), so a restarting replica only fails its
+			// own and those of replicas that stopped renewing their claims.
+			scheduleStore = procstore.NewScheduleStore(db)
+			queueStore := procstore.NewQueueStore(db)
+			if id := os.Getenv("ENGINE_ID"); id != "" {
+				scheduleStore.SetOwner(id)
+				queueStore.SetOwner(id)
+			}
+			jobStore = queueStore
+			snippetStore = procstore.NewSnippetStore(db)
+			executor.SetSnippetSource(snippetStore)
+			// Nodes with a "profile" read its config and secret at run time.
+			profileStore = procstore.NewProfileStore(db)
+			executor.SetProfileSource(profileStore)
+			schemaStore = procstore.NewSchemaStore(db)
+			executor.SetSchemaSource(schemaStore)
+			executor.SetDedupeStore(procstore.NewDedupeStore(db))
+			// Final contexts are kept for SNAPSHOT_RETENTION so support can
+			// inspect them via GET /api/v1/executions/{id}/context.
+			snapshotStore = procstore.NewSnapshotStore(db, parseDurationEnv("SNAPSHOT_RETENTION", 7*24*time.Hour))
+			executor.SetSnapshotSaver(snapshotStore)
+			// Every execution is pinned to the definition it ran, so retries
+			// and replays are unaffected by later deploys.
+			versionStore = procstore.NewVersionStore(db, parseDurationEnv("EXECUTION_VERSION_RETENTION", 30*24*time.Hour))
+			executor.SetVersionRecorder(versionStore)
+			// REST/SOAP triggers with capture_days keep raw requests for replay.
+			captureStore = procstore.NewCaptureStore(db)
+			// sftp_poll and s3_poll triggers record the files they fired for.
+			fileStateStore = procstore.NewFileStateStore(db)
+			// Failures, SLA breaches and deploys go to the channels each
+			// process subscribes to, delivered by a worker of their own.
+			notificationStore = procstore.NewNotificationStore(db)
+			dispatcher = notify.NewDispatcher(notificationStore, secretStore)
+			dispatcher.Start()
+			defer dispatcher.Close()
+			executor.SetNotifier(dispatcher)
 		}
 	}
 
+	// Trigger-fired runs go through a priority queue drained by a fixed worker
+	// pool, so urgent flows are not delayed by low-priority batch backlogs.
+	// EXECUTION_LANES ("interactive=8,batch=2") adds pools drained only by
+	// the processes assigned to them with definition.settings.lane.
+	execQueue := queue.New(jobStore, executor, parseIntEnv("EXECUTION_WORKERS", queue.DefaultWorkers))
+	if lanes, err := queue.ParseLanes(os.Getenv("EXECUTION_LANES")); err != nil {
+		slog.Error("engine-server: invalid EXECUTION_LANES; every process runs in the default lane", logging.KeyError, err)
+	} else {
+		for name, workers := range lanes {
+			execQueue.AddLane(name, workers)
+		}
+	}
+	execQueue.Start()
+	defer execQueue.Stop()
+
+	// Trigger manager handles deploy/stop lifecycle for all trigger types.
+	triggerMgr := triggers.NewManager(execQueue)
+	if captureStore != nil {
+		triggerMgr.SetRequestCapturer(captureStore)
+	}
+	if fileStateStore != nil {
+		triggerMgr.SetFileStateStore(fileStateStore)
+	}
+	defer triggerMgr.StopAll()
+	// Triggers live in memory: restart those of the processes left deployed.
+	if processStore != nil {
+		redeployTriggers(processStore, triggerMgr, executor)
+	}
+	// Triggers drifting from the deployed status in the config DB are
+	// started or stopped (TRIGGER_RECONCILE_INTERVAL, "0" disables) once
+	// the drift lasted TRIGGER_RECONCILE_GRACE.
+	if processStore != nil {
+		if interval := parseDurationEnv("TRIGGER_RECONCILE_INTERVAL", defaultReconcileInterval); interval > 0 {
+			stopReconciler := startReconciler(processStore, triggerMgr, executor, interval, parseDurationEnv("TRIGGER_RECONCILE_GRACE", defaultReconcileGrace))
+			defer stopReconciler()
+		}
+	}
+	// REST/SOAP routes no deployed process serves (TRIGGER_ORPHAN_SWEEP,
+	// "0" disables) are removed once orphaned for TRIGGER_ORPHAN_GRACE.
+	if interval := parseDurationEnv("TRIGGER_ORPHAN_SWEEP", defaultOrphanSweepInterval); interval > 0 {
+		stopSweep := startOrphanSweep(processStore, triggerMgr, interval, parseDurationEnv("TRIGGER_ORPHAN_GRACE", defaultOrphanGrace))
+		defer stopSweep()
+	}
+
+	// One-shot scheduled runs are persisted in the config DB and picked up by
+	// a polling loop, so pending runs survive restarts.
+	if scheduleStore != nil {
+		sched := scheduler.New(scheduleStore, loadProcess(processStore), execQueue)
+		sched.Start()
+		defer sched.Stop()
+	}
+
 	// Security middleware chain (OWASP hardening — ADR 0002):
 	//   RequestLogger  → A09 audit trail
 	//   RateLimiter    → A04 brute-force / DoS protection
-	//   CORS           → A05 restrictive origin policy
+	//   CORS           → A05 restrictive origin policy (ALLOWED_ORIGINS, CORS_*)
 	//   SecurityHeaders → A02/A05 HSTS + defensive headers
+	//   Authenticate   → A01 API-key gate; resolves the caller's workspace
+	//   RequestDeadline → bounds synchronous executions to REQUEST_TIMEOUT
 	rateLimiter := middleware.NewRateLimiter()
-	allowedOrigins := middleware.AllowedOrigins()
+	corsConfig := middleware.CORSConfigFromEnv()
+	apiKeys := middleware.APIKeys()
 
 	mux := http.NewServeMux()
-	registerRoutes(mux, executor, secretStore, processStore, triggerMgr)
+	registerRoutes(mux, executor, secretStore, secretAudit, processStore, scheduleStore, snippetStore, profileStore, nodeTemplateStore, processTemplateStore, schemaStore, snapshotStore, versionStore, captureStore, notificationStore, dispatcher, triggerMgr)
+	// GET /health/deep — readiness probe covering the engine's dependencies
+	mux.HandleFunc("/health/deep", handleDeepHealth(&deepHealth{
+		executor:       executor,
+		configDB:       configDB,
+		secretStore:    secretStore,
+		triggerMgr:     triggerMgr,
+		auditLoggerURL: os.Getenv("AUDIT_LOGGER_URL"),
+		client:         &http.Client{Timeout: healthCheckTimeout},
+	}))
 
 	var handler http.Handler = mux
-	handler = middleware.CORS(allowedOrigins)(handler)
+	handler = middleware.RequestDeadline(requestTimeout)(handler)
+	handler = middleware.Authenticate(apiKeys, "/health", "/triggers/", "/soap/", "/callbacks/")(handler)
+	handler = middleware.CORSWithConfig(corsConfig)(handler)
 	handler = rateLimiter.Middleware(handler)
 	handler = middleware.SecurityHeaders(handler)
 	handler = middleware.RequestLogger(handler)
 
 	server := &http.Server{
-		Addr:         httpAddr,
-		Handler:      handler,
-		ReadTimeout:  requestTimeout,
-		WriteTimeout: requestTimeout,
+		Addr:              httpAddr,
+		Handler:           handler,
+		ReadHeaderTimeout: readHeaderTimeout,
+		ReadTimeout:       readTimeout,
+		WriteTimeout:      writeTimeout,
+		IdleTimeout:       idleTimeout,
 	}
 
 	go func() {
-		log.Printf("engine-server: HTTP API listening on %s", httpAddr)
+		slog.Info("engine-server: HTTP API listening", "addr", httpAddr)
 		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			log.Fatalf("engine-server: %v", err)
+			slog.Error("engine-server: HTTP server failed", logging.KeyError, err)
+			os.Exit(1)
 		}
 	}()
 
@@ -131,12 +362,12 @@ func main() {
 	sig := make(chan os.Signal, 1)
 	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
 	<-sig
-	log.Println("engine-server: shutting down")
+	slog.Info("engine-server: shutting down")
 	rateLimiter.Stop()
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 	if err := server.Shutdown(shutdownCtx); err != nil {
-		log.Printf("engine-server: shutdown error: %v", err)
+		slog.Error("engine-server: shutdown", logging.KeyError, err)
 	}
 }
 
@@ -144,7 +375,7 @@ func main() {
 // Route registration
 // ---------------------------------------------------------------------------
 
-func registerRoutes(mux *http.ServeMux, executor *engine.ProcessExecutor, store *secrets.SecretStore, procStore *procstore.ProcessStore, triggerMgr *triggers.Manager) {
+func registerRoutes(mux *http.ServeMux, executor *engine.ProcessExecutor, store *secrets.SecretStore, secretAudit *secrets.AuditLog, procStore *procstore.ProcessStore, schedStore *procstore.ScheduleStore, snipStore *procstore.SnippetStore, profStore *procstore.ProfileStore, tmplStore *procstore.NodeTemplateStore, procTmplStore *procstore.ProcessTemplateStore, schemaStore *procstore.SchemaStore, snapStore *procstore.SnapshotStore, verStore *procstore.VersionStore, capStore *procstore.CaptureStore, notifStore *procstore.NotificationStore, dispatcher *notify.Dispatcher, triggerMgr *triggers.Manager) {
 	// GET /health — liveness probe
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
@@ -154,6 +385,9 @@ func registerRoutes(mux *http.ServeMux, executor *engine.ProcessExecutor, store
 		jsonOK(w, map[string]string{"status": "ok", "service": "engine"})
 	})
 
+	// GET /metrics — Prometheus metrics (audit publishing)
+	mux.HandleFunc("/metrics", handleMetrics(executor, procStore, dispatcher))
+
 	// POST /v1/flow — execute a complete DSL flow
 	mux.HandleFunc("/v1/flow", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
@@ -165,7 +399,7 @@ func registerRoutes(mux *http.ServeMux, executor *engine.ProcessExecutor, store
 			DSL         models.Process         `json:"dsl"`
 			TriggerData map[string]interface{} `json:"trigger_data"`
 		}
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		if err := decodeBody(r, &req); err != nil {
 			jsonError(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
 			return
 		}
@@ -173,11 +407,26 @@ func registerRoutes(mux *http.ServeMux, executor *engine.ProcessExecutor, store
 		if req.TriggerData == nil {
 			req.TriggerData = map[string]interface{}{}
 		}
+		// The workspace always comes from the caller, never from the posted DSL.
+		req.DSL.Definition.Workspace = tenant.Workspace(r.Context())
+		proc := &req.DSL
+		if tmplStore != nil {
+			expanded, err := tmplStore.Expand(r.Context(), proc)
+			if err != nil {
+				jsonError(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			proc = expanded
+		}
 
-		ctx, execErr := executor.Execute(&req.DSL, req.TriggerData)
+		ctx, execErr := executor.ExecuteContext(r.Context(), proc, req.TriggerData)
 		writeFlowResponse(w, ctx, execErr)
 	})
 
+	// /v1/flow/debug — step-through debug sessions of a flow DSL
+	mux.HandleFunc("/v1/flow/debug", handleDebug(executor))
+	mux.HandleFunc("/v1/flow/debug/", handleDebug(executor))
+
 	// POST /v1/test — live test a single script/mapping node
 	mux.HandleFunc("/v1/test", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
@@ -226,7 +475,7 @@ func registerRoutes(mux *http.ServeMux, executor *engine.ProcessExecutor, store
 		}
 
 		process := &models.Process{
-			Definition: models.Definition{ID: "live-test", Version: "1.0.0", Name: "live-test"},
+			Definition: models.Definition{ID: "live-test", Version: "1.0.0", Name: "live-test", Workspace: tenant.Workspace(r.Context())},
 			Trigger:    models.Trigger{ID: "trg_test", Type: "manual"},
 			Nodes: []models.Node{
 				{
@@ -270,7 +519,7 @@ func registerRoutes(mux *http.ServeMux, executor *engine.ProcessExecutor, store
 		case http.MethodGet:
 			list, err := store.List(r.Context())
 			if err != nil {
-				log.Printf("engine-server: list secrets: %v", err)
+				slog.Error("engine-server: list secrets", logging.KeyError, err)
 				jsonError(w, middleware.SanitizeError(err, "failed to list secrets"), http.StatusInternalServerError)
 				return
 			}
@@ -289,6 +538,7 @@ func registerRoutes(mux *http.ServeMux, executor *engine.ProcessExecutor, store
 				jsonError(w, err.Error(), http.StatusBadRequest)
 				return
 			}
+			recordSecretChange(r, secretAudit, input.ID, secrets.AuditActionUpdate)
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusCreated)
 			_ = json.NewEncoder(w).Encode(map[string]string{"id": input.ID, "status": "saved"})
@@ -299,84 +549,91 @@ func registerRoutes(mux *http.ServeMux, executor *engine.ProcessExecutor, store
 	})
 
 	// DELETE /api/v1/secrets/{secretId}
-	mux.HandleFunc("/api/v1/secrets/", func(w http.ResponseWriter, r *http.Request) {
-		if store == nil {
-			jsonError(w, "secrets store not configured (DATABASE_URL missing)", http.StatusServiceUnavailable)
-			return
-		}
-		if r.Method != http.MethodDelete {
-			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
-			return
-		}
-		secretID := strings.TrimPrefix(r.URL.Path, "/api/v1/secrets/")
-		if secretID == "" {
-			jsonError(w, "secret id is required", http.StatusBadRequest)
-			return
-		}
-		if err := store.Delete(r.Context(), secretID); err != nil {
-			log.Printf("engine-server: delete secret %q: %v", secretID, err)
-			jsonError(w, middleware.SanitizeError(err, "failed to delete secret"), http.StatusInternalServerError)
-			return
-		}
-		w.WriteHeader(http.StatusNoContent)
-	})
+	// GET    /api/v1/secrets/{secretId}/references — stored process nodes using the secret
+	// GET    /api/v1/secrets/{secretId}/audit      — recent resolutions and changes
+	// POST   /api/v1/secrets/rotate                — re-encrypt all secrets with the current key (admin)
+	mux.HandleFunc("/api/v1/secrets/", handleSecret(store, secretAudit, procStore))
 
-	// ── Process Management API ───────────────────────────────────────────────
+	// GET  /api/v1/lint — lint rules and their severities
+	// POST /api/v1/lint — lint a process DSL against the .flowlint rules
+	mux.HandleFunc("/api/v1/lint", handleLint)
 
-	// GET  /api/v1/processes        — list all processes (optionally ?status=draft|deployed|stopped)
-	// POST /api/v1/processes        — create or update a process (upsert by definition.id)
-	mux.HandleFunc("/api/v1/processes", func(w http.ResponseWriter, r *http.Request) {
-		if procStore == nil {
-			jsonError(w, "process store not configured (DATABASE_URL missing)", http.StatusServiceUnavailable)
-			return
-		}
-		switch r.Method {
-		case http.MethodGet:
-			statusFilter := r.URL.Query().Get("status")
-			list, err := procStore.List(r.Context(), statusFilter)
-			if err != nil {
-				log.Printf("engine-server: list processes: %v", err)
-				jsonError(w, middleware.SanitizeError(err, "failed to list processes"), http.StatusInternalServerError)
-				return
-			}
-			if list == nil {
-				list = []procstore.ProcessSummary{}
-			}
-			jsonOK(w, list)
+	// GET  /api/v1/dsl-migrations — stored processes older than the current dsl_version (dry run)
+	// POST /api/v1/dsl-migrations — migrate a DSL document without saving it
+	mux.HandleFunc("/api/v1/dsl-migrations", handleDSLMigrations(procStore))
 
-		case http.MethodPost:
-			var proc models.Process
-			if err := json.NewDecoder(r.Body).Decode(&proc); err != nil {
-				jsonError(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
-				return
-			}
-			if proc.Definition.ID == "" {
-				jsonError(w, "definition.id is required", http.StatusBadRequest)
-				return
-			}
-			rec, err := procStore.Upsert(r.Context(), &proc)
-			if err != nil {
-				log.Printf("engine-server: upsert process: %v", err)
-				jsonError(w, middleware.SanitizeError(err, "failed to save process"), http.StatusInternalServerError)
-				return
-			}
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusCreated)
-			_ = json.NewEncoder(w).Encode(rec)
+	// GET    /api/v1/admin/triggers/orphans — REST/SOAP routes no deployed process serves (admin)
+	// DELETE /api/v1/admin/triggers/orphans — remove them now (admin)
+	mux.HandleFunc("/api/v1/admin/triggers/orphans", handleTriggerOrphans(procStore, triggerMgr))
 
-		default:
-			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
-		}
-	})
+	// GET  /api/v1/executions/{id}/context — persisted final context of an execution
+	// GET  /api/v1/executions/{id}/version — process revision and DSL hash the execution ran
+	// POST /api/v1/executions/{id}/retry   — re-run a failed execution from its failed node
+	mux.HandleFunc("/api/v1/executions/", handleExecution(snapStore, verStore, procStore, executor))
+
+	// ── Script Snippet Library ───────────────────────────────────────────────
+
+	mux.HandleFunc("/api/v1/snippets", handleSnippets(snipStore))
+	mux.HandleFunc("/api/v1/snippets/", handleSnippets(snipStore))
+
+	// ── Connection Profiles ──────────────────────────────────────────────────
+
+	mux.HandleFunc("/api/v1/profiles", handleProfiles(profStore, executor))
+	mux.HandleFunc("/api/v1/profiles/", handleProfiles(profStore, executor))
+
+	// ── Node Library ─────────────────────────────────────────────────────────
+
+	// GET /api/v1/node-types — registered node types and their config fields
+	mux.HandleFunc("/api/v1/node-types", handleNodeTypes(executor))
+	mux.HandleFunc("/api/v1/node-templates", handleNodeTemplates(tmplStore, executor))
+	mux.HandleFunc("/api/v1/node-templates/", handleNodeTemplates(tmplStore, executor))
+
+	// ── Process Templates ────────────────────────────────────────────────────
+
+	mux.HandleFunc("/api/v1/process-templates", handleProcessTemplates(procTmplStore, procStore))
+	mux.HandleFunc("/api/v1/process-templates/", handleProcessTemplates(procTmplStore, procStore))
+
+	// ── Payload Schema Registry ──────────────────────────────────────────────
+
+	mux.HandleFunc("/api/v1/schemas", handleSchemas(schemaStore))
+	mux.HandleFunc("/api/v1/schemas/", handleSchemas(schemaStore))
+
+	// ── Notification Channels ────────────────────────────────────────────────
+
+	mux.HandleFunc("/api/v1/notifications/channels", handleNotificationChannels(notifStore, dispatcher))
+	mux.HandleFunc("/api/v1/notifications/channels/", handleNotificationChannels(notifStore, dispatcher))
+
+	// ── Process Management API ───────────────────────────────────────────────
+
+	// GET  /api/v1/processes        — list all processes (optionally ?status=draft|deployed|stopped|archived;
+	//                                 archived ones only with ?include_archived=true or ?status=archived)
+	// POST /api/v1/processes        — create or update a process (upsert by definition.id);
+	//                                 send If-Match: "<revision>" to reject concurrent edits with 409
+	//                                 and ?note=<text> to record why the flow changed;
+	//                                 plain-text credentials are returned as warnings
+	//                                 or, with SECRET_SCAN=reject, refused with 422
+	mux.HandleFunc("/api/v1/processes", handleProcesses(procStore))
 
 	// GET    /api/v1/processes/{processId}  — retrieve full DSL
-	// DELETE /api/v1/processes/{processId}  — delete process
+	// DELETE /api/v1/processes/{processId}  — archive process (?purge=true permanently deletes an archived one)
+	// POST   /api/v1/processes/{processId}/restore — restore an archived process
+	// POST   /api/v1/processes/{processId}/clone — copy under a new id with {{param.<name>}} values substituted
+	// POST   /api/v1/processes/{processId}/promote?to=dev|staging|prod — copy the DSL forward
+	// GET    /api/v1/processes/{processId}/environments — promoted revisions and promotion history
+	// GET    /api/v1/processes/{processId}/lint — lint the stored DSL
+	// GET    /api/v1/processes/{processId}/dependencies[?env=] — secrets, hosts and resources the DSL depends on
+	// GET    /api/v1/processes/{processId}/captures[/{captureId}] — captured REST/SOAP requests
+	// POST   /api/v1/processes/{processId}/captures/{captureId}/replay — re-fire a captured request
+	// POST   /api/v1/processes/{processId}/simulate — dry runs with generated payloads and branch coverage
+	// GET    /api/v1/processes/{processId}/subscriptions — notification subscriptions
+	// PUT    /api/v1/processes/{processId}/subscriptions — replace the notification subscriptions
+	// GET    /api/v1/processes/{processId}/trigger — whether the trigger runs here and its health
 	mux.HandleFunc("/api/v1/processes/", func(w http.ResponseWriter, r *http.Request) {
 		if procStore == nil {
 			jsonError(w, "process store not configured (DATABASE_URL missing)", http.StatusServiceUnavailable)
 			return
 		}
-		// Strip prefix and split off optional sub-resource (deploy / stop / replay / replay-from)
+		// Strip prefix and split off optional sub-resource (deploy / stop / run / trigger / replay / replay-from / schedule / promote / environments / captures / restore / clone / lint / dependencies / subscriptions / simulate)
 		rest := strings.TrimPrefix(r.URL.Path, "/api/v1/processes/")
 		parts := strings.SplitN(rest, "/", 3)
 		processID := parts[0]
@@ -394,16 +651,48 @@ func registerRoutes(mux *http.ServeMux, executor *engine.ProcessExecutor, store
 			switch parts[1] {
 			case "deploy":
 				handleDeploy(w, r, processID, procStore, triggerMgr, executor)
+			case "promote":
+				handlePromote(w, r, processID, procStore, triggerMgr, executor)
+			case "environments":
+				handleEnvironments(w, r, processID, procStore)
+			case "lint":
+				handleProcessLint(w, r, processID, procStore)
+			case "dependencies":
+				handleDependencies(w, r, processID, procStore, store, profStore)
+			case "captures":
+				sub := ""
+				if len(parts) == 3 {
+					sub = parts[2]
+				}
+				handleCaptures(w, r, processID, sub, procStore, capStore, executor)
+			case "restore":
+				handleRestore(w, r, processID, procStore)
+			case "clone":
+				handleClone(w, r, processID, procStore)
+			case "subscriptions":
+				handleSubscriptions(w, r, processID, procStore, notifStore)
 			case "stop":
 				handleStop(w, r, processID, procStore, triggerMgr, executor)
+			case "run":
+				handleRun(w, r, processID, procStore, triggerMgr)
+			case "trigger":
+				handleTriggerStatus(w, r, processID, procStore, triggerMgr)
+			case "simulate":
+				handleSimulate(w, r, processID, procStore, capStore, executor)
 			case "replay":
-				handleReplay(w, r, processID, procStore, executor)
+				handleReplay(w, r, processID, procStore, verStore, executor)
 			case "replay-from":
 				if len(parts) < 3 || parts[2] == "" {
 					jsonError(w, "node id is required for replay-from", http.StatusBadRequest)
 					return
 				}
-				handleReplayFrom(w, r, processID, parts[2], procStore, executor)
+				handleReplayFrom(w, r, processID, parts[2], procStore, verStore, executor)
+			case "schedule":
+				runID := ""
+				if len(parts) == 3 {
+					runID = parts[2]
+				}
+				handleSchedule(w, r, processID, runID, procStore, schedStore)
 			default:
 				jsonError(w, fmt.Sprintf("unknown sub-resource: %q", parts[1]), http.StatusNotFound)
 			}
@@ -419,64 +708,134 @@ func registerRoutes(mux *http.ServeMux, executor *engine.ProcessExecutor, store
 				return
 			}
 			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("ETag", processETag(rec.Revision))
 			_ = json.NewEncoder(w).Encode(rec)
 
 		case http.MethodDelete:
-			// Stop the trigger first if running.
-			if triggerMgr.IsRunning(processID) {
-				_ = triggerMgr.Stop(processID)
-			}
-			if err := procStore.Delete(r.Context(), processID); err != nil {
-				log.Printf("engine-server: delete process %q: %v", processID, err)
-				jsonError(w, middleware.SanitizeError(err, "failed to delete process"), http.StatusInternalServerError)
-				return
-			}
-			w.WriteHeader(http.StatusNoContent)
+			handleDeleteProcess(w, r, processID, procStore, triggerMgr, executor)
 
 		default:
 			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		}
 	})
 
+	// /callbacks/{token} — resumes the callback_await node waiting on token
+	mux.HandleFunc("/callbacks/", handleCallback)
+
 	// Mount the REST trigger registry so deployed REST-triggered processes
 	// receive inbound HTTP calls at /triggers/{path}.
 	mux.Handle("/triggers/", triggers.GetRegistryHandler())
 
+	// GET /triggers/openapi.json — OpenAPI document of the deployed REST
+	// triggers (?workspace= limits it to one), public like the triggers.
+	mux.HandleFunc("/triggers/openapi.json", handleTriggerOpenAPI(schemaStore, triggerMgr))
+
 	// Mount the SOAP trigger registry so deployed SOAP-triggered processes
 	// receive inbound SOAP/XML calls at /soap/{path}.
 	mux.Handle("/soap/", triggers.GetSOAPRegistryHandler())
 }
 
+// loadProcess adapts ProcessStore to scheduler.ProcessLoader. Scheduled runs
+// use the DSL of engineEnvironment.
+func loadProcess(procStore *procstore.ProcessStore) scheduler.ProcessLoader {
+	return func(ctx context.Context, processID string) (*models.Process, error) {
+		return procStore.Deployable(ctx, processID, engineEnvironment)
+	}
+}
+
+// handleProcesses serves GET (list) and POST (upsert) /api/v1/processes.
+func handleProcesses(procStore *procstore.ProcessStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if procStore == nil {
+			jsonError(w, "process store not configured (DATABASE_URL missing)", http.StatusServiceUnavailable)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			statusFilter := r.URL.Query().Get("status")
+			includeArchived := r.URL.Query().Get("include_archived") == "true"
+			list, err := procStore.List(r.Context(), statusFilter, includeArchived)
+			if err != nil {
+				slog.Error("engine-server: list processes", logging.KeyError, err)
+				jsonError(w, middleware.SanitizeError(err, "failed to list processes"), http.StatusInternalServerError)
+				return
+			}
+			if list == nil {
+				list = []procstore.ProcessSummary{}
+			}
+			jsonOK(w, list)
+
+		case http.MethodPost:
+			var proc models.Process
+			if err := decodeBody(r, &proc); err != nil {
+				jsonError(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+				return
+			}
+			if proc.Definition.ID == "" {
+				jsonError(w, "definition.id is required", http.StatusBadRequest)
+				return
+			}
+			ifRevision, err := parseIfMatch(r.Header.Get("If-Match"))
+			if err != nil {
+				jsonError(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			rec, err := procStore.Upsert(r.Context(), &proc, ifRevision, strings.TrimSpace(r.URL.Query().Get("note")))
+			if errors.Is(err, procstore.ErrRevisionConflict) {
+				jsonError(w, err.Error(), http.StatusConflict)
+				return
+			}
+			if errors.Is(err, procstore.ErrPlaintextCredential) {
+				jsonError(w, err.Error(), http.StatusUnprocessableEntity)
+				return
+			}
+			if err != nil {
+				slog.Error("engine-server: upsert process", logging.KeyError, err)
+				jsonError(w, middleware.SanitizeError(err, "failed to save process"), http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("ETag", processETag(rec.Revision))
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(rec)
+
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
 // handleDeploy starts the trigger for a process and updates its status to "deployed".
 func handleDeploy(w http.ResponseWriter, r *http.Request, processID string, procStore *procstore.ProcessStore, triggerMgr *triggers.Manager, executor *engine.ProcessExecutor) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
-	rec, err := procStore.Get(r.Context(), processID)
-	if err != nil {
-		jsonError(w, err.Error(), http.StatusNotFound)
-		return
-	}
-	proc, err := rec.ParseDSL()
-	if err != nil {
-		jsonError(w, fmt.Sprintf("parse DSL: %v", err), http.StatusInternalServerError)
+	proc, ok := loadDeployable(w, r, processID, procStore)
+	if !ok {
 		return
 	}
+	workspace := tenant.Workspace(r.Context())
 	if err := triggerMgr.Deploy(proc); err != nil {
-		executor.SendLifecycleAuditLog(processID, proc.Trigger.Type, "deployed", err.Error())
+		executor.SendLifecycleAuditLog(workspace, processID, proc.Trigger.Type, "deployed", err.Error())
 		jsonError(w, fmt.Sprintf("deploy trigger: %v", err), http.StatusBadRequest)
 		return
 	}
 	if err := procStore.UpdateStatus(r.Context(), processID, "deployed"); err != nil {
-		log.Printf("engine-server: warning: update status for %q: %v", processID, err)
+		slog.Warn("engine-server: update status", logging.KeyProcessID, processID, logging.KeyError, err)
 	}
-	executor.SendLifecycleAuditLog(processID, proc.Trigger.Type, "deployed", "")
-	jsonOK(w, map[string]string{
+	executor.SendLifecycleAuditLog(workspace, processID, proc.Trigger.Type, "deployed", "")
+	resp := map[string]interface{}{
 		"process_id": processID,
 		"status":     "deployed",
 		"message":    fmt.Sprintf("%s trigger started", proc.Trigger.Type),
-	})
+	}
+	// Ambiguous routing does not block a deployment but is reported.
+	if warnings := proc.TransitionWarnings(); len(warnings) > 0 {
+		slog.Warn("engine-server: deployed with transition warnings", logging.KeyProcessID, processID, "warnings", warnings)
+		resp["warnings"] = warnings
+	}
+	jsonOK(w, resp)
 }
 
 // handleStop deactivates the trigger for a process and updates its status to "stopped".
@@ -485,25 +844,65 @@ func handleStop(w http.ResponseWriter, r *http.Request, processID string, procSt
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
+	// The trigger manager is keyed by process id only, so verify ownership
+	// before touching another workspace's trigger.
+	if _, err := procStore.Get(r.Context(), processID); err != nil {
+		jsonError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	workspace := tenant.Workspace(r.Context())
 	// Capture the trigger type before stopping so audit logs carry full context.
 	triggerType := triggerMgr.TriggerType(processID)
 	if err := triggerMgr.Stop(processID); err != nil {
-		executor.SendLifecycleAuditLog(processID, triggerType, "stopped", err.Error())
+		executor.SendLifecycleAuditLog(workspace, processID, triggerType, "stopped", err.Error())
 		jsonError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 	if err := procStore.UpdateStatus(r.Context(), processID, "stopped"); err != nil {
-		log.Printf("engine-server: warning: update status for %q: %v", processID, err)
+		slog.Warn("engine-server: update status", logging.KeyProcessID, processID, logging.KeyError, err)
 	}
-	executor.SendLifecycleAuditLog(processID, triggerType, "stopped", "")
+	executor.SendLifecycleAuditLog(workspace, processID, triggerType, "stopped", "")
 	jsonOK(w, map[string]string{
 		"process_id": processID,
 		"status":     "stopped",
 	})
 }
 
+// handleRun fires a deployed process immediately with optional trigger_data.
+// The run shares the process's max_concurrency gate with its trigger.
+func handleRun(w http.ResponseWriter, r *http.Request, processID string, procStore *procstore.ProcessStore, triggerMgr *triggers.Manager) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	// The trigger manager is keyed by process id only, so verify ownership first.
+	if _, err := procStore.Get(r.Context(), processID); err != nil {
+		jsonError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	var req struct {
+		TriggerData map[string]interface{} `json:"trigger_data"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		jsonError(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	ctx, execErr := triggerMgr.Run(r.Context(), processID, req.TriggerData)
+	switch {
+	case errors.Is(execErr, triggers.ErrNotDeployed):
+		jsonError(w, fmt.Sprintf("process %q is not deployed", processID), http.StatusConflict)
+	case errors.Is(execErr, triggers.ErrConcurrencyLimit), errors.Is(execErr, triggers.ErrQuotaExceeded):
+		jsonError(w, execErr.Error(), http.StatusTooManyRequests)
+	default:
+		writeFlowResponse(w, ctx, execErr)
+	}
+}
+
 // handleReplay executes a stored process using new trigger data (full re-run).
-func handleReplay(w http.ResponseWriter, r *http.Request, processID string, procStore *procstore.ProcessStore, executor *engine.ProcessExecutor) {
+// With a parent_execution_id it runs the definition the parent ran (see
+// rerunProcess).
+func handleReplay(w http.ResponseWriter, r *http.Request, processID string, procStore *procstore.ProcessStore, verStore *procstore.VersionStore, executor *engine.ProcessExecutor) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -512,24 +911,22 @@ func handleReplay(w http.ResponseWriter, r *http.Request, processID string, proc
 		jsonError(w, "process store not configured (DATABASE_URL missing)", http.StatusServiceUnavailable)
 		return
 	}
-	rec, err := procStore.Get(r.Context(), processID)
-	if err != nil {
-		jsonError(w, err.Error(), http.StatusNotFound)
-		return
-	}
-	proc, err := rec.ParseDSL()
-	if err != nil {
-		jsonError(w, fmt.Sprintf("parse DSL: %v", err), http.StatusInternalServerError)
+	proc, ok := loadDeployable(w, r, processID, procStore)
+	if !ok {
 		return
 	}
 
 	var reqRaw struct {
-		TriggerData json.RawMessage `json:"trigger_data"`
+		TriggerData       json.RawMessage `json:"trigger_data"`
+		ParentExecutionID string          `json:"parent_execution_id"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&reqRaw); err != nil && !errors.Is(err, io.EOF) {
 		jsonError(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
 		return
 	}
+	if !validParentExecutionID(w, reqRaw.ParentExecutionID) {
+		return
+	}
 	// Accept any JSON value for trigger_data; default to {} if absent or null.
 	// Return 400 if trigger_data is present but not a JSON object.
 	var triggerData map[string]interface{}
@@ -542,14 +939,34 @@ func handleReplay(w http.ResponseWriter, r *http.Request, processID string, proc
 	if triggerData == nil {
 		triggerData = map[string]interface{}{}
 	}
+	if reqRaw.ParentExecutionID != "" {
+		if proc, ok = rerunProcess(w, r, verStore, reqRaw.ParentExecutionID, proc); !ok {
+			return
+		}
+	}
 
-	ctx, execErr := executor.Execute(proc, triggerData)
+	ctx, execErr := executor.ReplayExecution(r.Context(), proc, triggerData, reqRaw.ParentExecutionID)
 	writeFlowResponse(w, ctx, execErr)
 }
 
+// validParentExecutionID rejects a parent_execution_id that is not an
+// execution id, since the audit-logger stores it as a UUID. An empty id is
+// valid: the run then has no parent.
+func validParentExecutionID(w http.ResponseWriter, id string) bool {
+	if id == "" {
+		return true
+	}
+	if _, err := uuid.Parse(id); err != nil {
+		jsonError(w, "parent_execution_id must be an execution id (UUID)", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
 // handleReplayFrom re-executes a stored process starting from a specific node,
-// injecting nodeInput as the pre-resolved output of that node.
-func handleReplayFrom(w http.ResponseWriter, r *http.Request, processID, nodeID string, procStore *procstore.ProcessStore, executor *engine.ProcessExecutor) {
+// injecting nodeInput as the pre-resolved output of that node. With a
+// parent_execution_id it runs the definition the parent ran.
+func handleReplayFrom(w http.ResponseWriter, r *http.Request, processID, nodeID string, procStore *procstore.ProcessStore, verStore *procstore.VersionStore, executor *engine.ProcessExecutor) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -558,32 +975,55 @@ func handleReplayFrom(w http.ResponseWriter, r *http.Request, processID, nodeID
 		jsonError(w, "process store not configured (DATABASE_URL missing)", http.StatusServiceUnavailable)
 		return
 	}
-	rec, err := procStore.Get(r.Context(), processID)
-	if err != nil {
-		jsonError(w, err.Error(), http.StatusNotFound)
-		return
-	}
-	proc, err := rec.ParseDSL()
-	if err != nil {
-		jsonError(w, fmt.Sprintf("parse DSL: %v", err), http.StatusInternalServerError)
+	proc, ok := loadDeployable(w, r, processID, procStore)
+	if !ok {
 		return
 	}
 
 	var req struct {
-		NodeInput map[string]interface{} `json:"node_input"`
+		NodeInput         map[string]interface{} `json:"node_input"`
+		ParentExecutionID string                 `json:"parent_execution_id"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
 		jsonError(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
 		return
 	}
+	if !validParentExecutionID(w, req.ParentExecutionID) {
+		return
+	}
 	if req.NodeInput == nil {
 		req.NodeInput = map[string]interface{}{}
 	}
+	if req.ParentExecutionID != "" {
+		if proc, ok = rerunProcess(w, r, verStore, req.ParentExecutionID, proc); !ok {
+			return
+		}
+	}
 
-	ctx, execErr := executor.ExecuteFromNode(proc, nodeID, req.NodeInput, "")
+	ctx, execErr := executor.ExecuteFromNodeContext(r.Context(), proc, nodeID, req.NodeInput, "", req.ParentExecutionID)
 	writeFlowResponse(w, ctx, execErr)
 }
 
+// decodeBody decodes a JSON request body into v, or a YAML one when the
+// Content-Type is application/yaml (also application/x-yaml, text/yaml).
+func decodeBody(r *http.Request, v interface{}) error {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml":
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			return err
+		}
+		raw, err := models.YAMLToJSON(data)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(raw, v)
+	default:
+		return json.NewDecoder(r.Body).Decode(v)
+	}
+}
+
 func jsonOK(w http.ResponseWriter, v interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(v)
@@ -595,6 +1035,26 @@ func jsonError(w http.ResponseWriter, msg string, code int) {
 	_ = json.NewEncoder(w).Encode(map[string]string{"error": msg})
 }
 
+// processETag formats a process revision as a strong HTTP entity tag.
+func processETag(revision int) string {
+	return `"` + strconv.Itoa(revision) + `"`
+}
+
+// parseIfMatch extracts the revision from an If-Match header produced by
+// processETag. An empty header or "*" disables the revision check (0).
+func parseIfMatch(header string) (int, error) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+	tag := strings.Trim(strings.TrimPrefix(header, "W/"), `"`)
+	rev, err := strconv.Atoi(tag)
+	if err != nil || rev < 1 {
+		return 0, fmt.Errorf("If-Match must be an ETag returned by the process API (e.g. \"3\")")
+	}
+	return rev, nil
+}
+
 func envOrDefault(key, def string) string {
 	if v := os.Getenv(key); v != "" {
 		return v
@@ -603,6 +1063,19 @@ func envOrDefault(key, def string) string {
 }
 
 // parseDurationEnv reads a duration from an environment variable, defaulting to def on parse error.
+func parseIntEnv(key string, def int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		slog.Warn("engine-server: invalid setting; using default", "key", key, "value", v, "default", def)
+		return def
+	}
+	return n
+}
+
 func parseDurationEnv(key string, def time.Duration) time.Duration {
 	v := os.Getenv(key)
 	if v == "" {
@@ -610,26 +1083,28 @@ func parseDurationEnv(key string, def time.Duration) time.Duration {
 	}
 	d, err := time.ParseDuration(v)
 	if err != nil {
-		log.Printf("engine-server: invalid %s=%q, using default %s", key, v, def)
+		slog.Warn("engine-server: invalid setting; using default", "key", key, "value", v, "default", def.String())
 		return def
 	}
 	return d
 }
 
-// aesKeyFromEnv reads a 32-byte AES key from the SECRETS_AES_KEY environment
-// variable. When the variable is absent or too short in non-development environments,
-// the server refuses to start to prevent accidental use of an insecure key.
-// Production deployments MUST set SECRETS_AES_KEY to a random 32-byte value.
-func aesKeyFromEnv(envKey string) []byte {
-	v := os.Getenv(envKey)
-	if len(v) >= 32 {
-		return []byte(v[:32])
-	}
-	// Dev fallback — never use in production
-	if os.Getenv("APP_ENV") != "development" {
-		log.Fatalf("engine-server: %s must be set to a value of at least 32 bytes in non-development environments", envKey)
-	}
-	const devKey = "flowjs-dev-key-00000000000000000"
-	log.Printf("engine-server: WARNING — using insecure dev AES key; set %s in production", envKey)
-	return []byte(devKey[:32])
+// aesKeyringFromEnv reads the secrets keyring: the 32-byte AES key from the
+// SECRETS_AES_KEY environment variable, its id from SECRETS_AES_KEY_ID and the
+// retired keys from SECRETS_AES_KEY_PREVIOUS (see secrets.KeyringFromEnv).
+// When the key is absent or too short in non-development environments, or the
+// keyring is malformed, the server refuses to start to prevent accidental use
+// of an insecure key. Production deployments MUST set SECRETS_AES_KEY to a
+// random 32-byte value.
+func aesKeyringFromEnv(envKey string) *secrets.Keyring {
+	ring, insecure, err := secrets.KeyringFromEnv(envKey)
+	if err != nil {
+		slog.Error("engine-server: invalid secrets keyring; the key must be set to a value of at least 32 bytes in non-development environments", "key", envKey, logging.KeyError, err)
+		os.Exit(1)
+	}
+	if insecure {
+		// Dev fallback — never use in production
+		slog.Warn("engine-server: using insecure dev AES key; set it in production", "key", envKey)
+	}
+	return ring
 }
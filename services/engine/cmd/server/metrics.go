@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"flowjs-works/engine/internal/engine"
+	"flowjs-works/engine/internal/notify"
+	procstore "flowjs-works/engine/internal/store"
+)
+
+// handleMetrics serves GET /metrics in the Prometheus text format:
+//
+//	flowjs_audit_events_published_total  — audit events delivered to NATS
+//	flowjs_audit_events_retried_total    — events buffered for republishing
+//	flowjs_audit_events_dropped_total    — events lost (buffer/spill full, too large, shutdown)
+//	flowjs_audit_events_buffered         — events waiting in memory now
+//	flowjs_audit_spill_bytes             — size of the audit spill file
+//	flowjs_audit_events_suppressed_total — events of successful runs left out by trigger audit sampling
+//	flowjs_process_cache_hits_total      — process lookups served from memory (with a process store)
+//	flowjs_process_cache_misses_total    — process lookups that queried the config DB
+//	flowjs_notifications_sent_total      — notifications delivered to a channel (with a config DB)
+//	flowjs_notifications_failed_total    — notification deliveries or subscription lookups that failed
+//	flowjs_notifications_dropped_total   — events discarded because the notification queue was full
+//	flowjs_notifications_throttled_total — notifications skipped by a subscription cooldown
+func handleMetrics(executor *engine.ProcessExecutor, procStore *procstore.ProcessStore, dispatcher *notify.Dispatcher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		s := executor.AuditStats()
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		writeMetric(w, "flowjs_audit_events_published_total", "counter", "Audit events published to NATS.", s.Published)
+		writeMetric(w, "flowjs_audit_events_retried_total", "counter", "Audit events buffered for republishing after a failed publish.", s.Retried)
+		writeMetric(w, "flowjs_audit_events_dropped_total", "counter", "Audit events lost without reaching NATS.", s.Dropped)
+		writeMetric(w, "flowjs_audit_events_buffered", "gauge", "Audit events waiting in memory for republishing.", s.Buffered)
+		writeMetric(w, "flowjs_audit_spill_bytes", "gauge", "Size of the audit spill file.", s.SpilledBytes)
+		writeMetric(w, "flowjs_audit_events_suppressed_total", "counter", "Audit events of successful runs not recorded due to trigger audit sampling.", s.Suppressed)
+		if procStore != nil {
+			c := procStore.CacheStats()
+			writeMetric(w, "flowjs_process_cache_hits_total", "counter", "Process lookups served from the in-memory cache.", c.Hits)
+			writeMetric(w, "flowjs_process_cache_misses_total", "counter", "Process lookups that queried the config DB.", c.Misses)
+		}
+		if dispatcher != nil {
+			n := dispatcher.Stats()
+			writeMetric(w, "flowjs_notifications_sent_total", "counter", "Notifications delivered to a channel.", n.Sent)
+			writeMetric(w, "flowjs_notifications_failed_total", "counter", "Notification deliveries or subscription lookups that failed.", n.Failed)
+			writeMetric(w, "flowjs_notifications_dropped_total", "counter", "Notification events discarded because the queue was full.", n.Dropped)
+			writeMetric(w, "flowjs_notifications_throttled_total", "counter", "Notifications skipped by a subscription cooldown.", n.Throttled)
+		}
+	}
+}
+
+func writeMetric(w http.ResponseWriter, name, kind, help string, value interface{}) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %v\n", name, help, name, kind, name, value)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"flowjs-works/engine/internal/logging"
+	"flowjs-works/engine/internal/middleware"
+	"flowjs-works/engine/internal/models"
+	procstore "flowjs-works/engine/internal/store"
+)
+
+// processMigration is the migration report of a stored process, as listed
+// by GET /api/v1/dsl-migrations.
+type processMigration struct {
+	ProcessID string `json:"process_id"`
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	Revision  int    `json:"revision"`
+	models.MigrationReport
+	// Error is set instead of the changes when the stored DSL does not
+	// migrate.
+	Error string `json:"error,omitempty"`
+}
+
+// dslMigration is the response of POST /api/v1/dsl-migrations.
+type dslMigration struct {
+	models.MigrationReport
+	DSL json.RawMessage `json:"dsl"`
+}
+
+// handleDSLMigrations reports, without saving anything, how DSL documents
+// are upgraded to the current dsl_version:
+//
+//	GET  /api/v1/dsl-migrations — the stored processes of the workspace older than the current version
+//	POST /api/v1/dsl-migrations — migrate the DSL in the body (JSON or YAML) and return it with its report
+//
+// Stored processes are migrated whenever they load and saved at the current
+// version the next time they are saved.
+func handleDSLMigrations(procStore *procstore.ProcessStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			if procStore == nil {
+				jsonError(w, "process store not configured (DATABASE_URL missing)", http.StatusServiceUnavailable)
+				return
+			}
+			list, err := storedMigrations(r, procStore)
+			if err != nil {
+				slog.Error("engine-server: list DSL migrations", logging.KeyError, err)
+				jsonError(w, middleware.SanitizeError(err, "failed to list DSL migrations"), http.StatusInternalServerError)
+				return
+			}
+			jsonOK(w, list)
+		case http.MethodPost:
+			var dsl json.RawMessage
+			if err := decodeBody(r, &dsl); err != nil {
+				jsonError(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+				return
+			}
+			migrated, report, err := models.MigrateDSL(dsl)
+			if err != nil {
+				jsonError(w, err.Error(), http.StatusUnprocessableEntity)
+				return
+			}
+			jsonOK(w, dslMigration{MigrationReport: *report, DSL: migrated})
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// storedMigrations migrates the draft of every process of the workspace,
+// archived ones included, and returns those below the current version.
+func storedMigrations(r *http.Request, procStore *procstore.ProcessStore) ([]processMigration, error) {
+	summaries, err := procStore.List(r.Context(), "", true)
+	if err != nil {
+		return nil, err
+	}
+	list := []processMigration{}
+	for _, s := range summaries {
+		rec, err := procStore.Get(r.Context(), s.ID)
+		if err != nil {
+			return nil, err
+		}
+		m := processMigration{ProcessID: rec.ID, Name: rec.Name, Status: rec.Status, Revision: rec.Revision}
+		_, report, err := models.MigrateDSL(rec.DSL)
+		switch {
+		case err != nil:
+			m.Error = err.Error()
+		case report.FromVersion == models.CurrentDSLVersion:
+			continue
+		default:
+			m.MigrationReport = *report
+		}
+		list = append(list, m)
+	}
+	return list, nil
+}
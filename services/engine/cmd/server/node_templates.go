@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"flowjs-works/engine/internal/engine"
+	"flowjs-works/engine/internal/logging"
+	"flowjs-works/engine/internal/middleware"
+	"flowjs-works/engine/internal/models"
+	procstore "flowjs-works/engine/internal/store"
+)
+
+// handleNodeTypes serves GET /api/v1/node-types, the node types the engine
+// runs with the config fields, defaults and allowed values of each, for the
+// designer's node palette.
+func handleNodeTypes(executor *engine.ProcessExecutor) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		jsonOK(w, executor.NodeCatalog())
+	}
+}
+
+// handleNodeTemplates serves the node library, the templates processes use
+// with "$ref" nodes:
+//
+//	GET    /api/v1/node-templates                     — list templates (latest version, without nodes)
+//	POST   /api/v1/node-templates                     — publish the next version of {name, description, nodes, transitions, output}
+//	GET    /api/v1/node-templates/{name}              — retrieve the latest version
+//	GET    /api/v1/node-templates/{name}/versions     — list the versions, newest first
+//	GET    /api/v1/node-templates/{name}/{version}    — retrieve one version
+//	DELETE /api/v1/node-templates/{name}              — delete every version
+func handleNodeTemplates(tmplStore *procstore.NodeTemplateStore, executor *engine.ProcessExecutor) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if tmplStore == nil {
+			jsonError(w, "node template store not configured (DATABASE_URL missing)", http.StatusServiceUnavailable)
+			return
+		}
+		name, sub, _ := strings.Cut(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/node-templates"), "/"), "/")
+		switch {
+		case name == "" && r.Method == http.MethodGet:
+			list, err := tmplStore.List(r.Context())
+			if err != nil {
+				slog.Error("engine-server: list node templates", logging.KeyError, err)
+				jsonError(w, middleware.SanitizeError(err, "failed to list node templates"), http.StatusInternalServerError)
+				return
+			}
+			if list == nil {
+				list = []models.NodeTemplate{}
+			}
+			jsonOK(w, list)
+		case name == "" && r.Method == http.MethodPost:
+			publishNodeTemplate(w, r, tmplStore, executor)
+		case sub == "versions" && r.Method == http.MethodGet:
+			list, err := tmplStore.Versions(r.Context(), name)
+			if !nodeTemplateFound(w, name, err) {
+				return
+			}
+			if len(list) == 0 {
+				jsonError(w, fmt.Sprintf("%v: %q", procstore.ErrNodeTemplateNotFound, name), http.StatusNotFound)
+				return
+			}
+			jsonOK(w, list)
+		case r.Method == http.MethodGet:
+			version := 0
+			if sub != "" {
+				v, err := strconv.Atoi(sub)
+				if err != nil || v < 1 {
+					jsonError(w, "version must be a positive integer", http.StatusBadRequest)
+					return
+				}
+				version = v
+			}
+			t, err := tmplStore.Get(r.Context(), name, version)
+			if !nodeTemplateFound(w, name, err) {
+				return
+			}
+			jsonOK(w, t)
+		case sub == "" && r.Method == http.MethodDelete:
+			if !nodeTemplateFound(w, name, tmplStore.Delete(r.Context(), name)) {
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// nodeTemplateFound writes the error response for err, reporting whether
+// there was none.
+func nodeTemplateFound(w http.ResponseWriter, name string, err error) bool {
+	switch {
+	case errors.Is(err, procstore.ErrNodeTemplateNotFound):
+		jsonError(w, err.Error(), http.StatusNotFound)
+		return false
+	case err != nil:
+		slog.Error("engine-server: node template", "template", name, logging.KeyError, err)
+		jsonError(w, middleware.SanitizeError(err, "failed to access node template"), http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
+// publishNodeTemplate validates the request body and stores it as the next
+// version of its template.
+func publishNodeTemplate(w http.ResponseWriter, r *http.Request, tmplStore *procstore.NodeTemplateStore, executor *engine.ProcessExecutor) {
+	var t models.NodeTemplate
+	if err := decodeBody(r, &t); err != nil {
+		jsonError(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	if err := t.Validate(); err != nil {
+		jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	for _, node := range t.Nodes {
+		if !slices.Contains(executor.Activities(), node.Type) {
+			jsonError(w, fmt.Sprintf("node %s: unknown node type %q", node.ID, node.Type), http.StatusBadRequest)
+			return
+		}
+	}
+	saved, err := tmplStore.Publish(r.Context(), &t)
+	if err != nil {
+		slog.Error("engine-server: publish node template", "template", t.Name, logging.KeyError, err)
+		jsonError(w, middleware.SanitizeError(err, "failed to publish node template"), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(saved)
+}
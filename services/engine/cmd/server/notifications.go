@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"flowjs-works/engine/internal/logging"
+	"flowjs-works/engine/internal/middleware"
+	"flowjs-works/engine/internal/notify"
+	procstore "flowjs-works/engine/internal/store"
+	"flowjs-works/engine/internal/tenant"
+)
+
+// handleNotificationChannels serves the notification channels processes
+// subscribe to:
+//
+//	GET    /api/v1/notifications/channels            — list channels
+//	POST   /api/v1/notifications/channels            — create or replace {id, type, description, config, secret_ref}
+//	GET    /api/v1/notifications/channels/{id}       — retrieve a channel
+//	DELETE /api/v1/notifications/channels/{id}       — delete a channel and its subscriptions
+//	POST   /api/v1/notifications/channels/{id}/test  — send a test notification now
+func handleNotificationChannels(notifStore *procstore.NotificationStore, dispatcher *notify.Dispatcher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if notifStore == nil {
+			jsonError(w, "notification store not configured (DATABASE_URL missing)", http.StatusServiceUnavailable)
+			return
+		}
+		rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/notifications/channels"), "/")
+		id, sub, _ := strings.Cut(rest, "/")
+		switch {
+		case id == "" && r.Method == http.MethodGet:
+			list, err := notifStore.ListChannels(r.Context())
+			if err != nil {
+				slog.Error("engine-server: list notification channels", logging.KeyError, err)
+				jsonError(w, middleware.SanitizeError(err, "failed to list channels"), http.StatusInternalServerError)
+				return
+			}
+			if list == nil {
+				list = []notify.Channel{}
+			}
+			jsonOK(w, list)
+		case id == "" && r.Method == http.MethodPost:
+			saveChannel(w, r, notifStore)
+		case id != "" && sub == "test" && r.Method == http.MethodPost:
+			testChannel(w, r, id, notifStore, dispatcher)
+		case id != "" && sub != "":
+			jsonError(w, fmt.Sprintf("unknown sub-resource: %q", sub), http.StatusNotFound)
+		case id != "" && r.Method == http.MethodGet:
+			ch, err := notifStore.GetChannel(r.Context(), id)
+			if !channelFound(w, id, err) {
+				return
+			}
+			jsonOK(w, ch)
+		case id != "" && r.Method == http.MethodDelete:
+			if !channelFound(w, id, notifStore.DeleteChannel(r.Context(), id)) {
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// channelFound writes the error response for err, reporting whether there
+// was none.
+func channelFound(w http.ResponseWriter, id string, err error) bool {
+	switch {
+	case errors.Is(err, procstore.ErrChannelNotFound):
+		jsonError(w, err.Error(), http.StatusNotFound)
+		return false
+	case err != nil:
+		slog.Error("engine-server: notification channel", "channel", id, logging.KeyError, err)
+		jsonError(w, middleware.SanitizeError(err, "failed to access channel"), http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
+// saveChannel validates the request body and upserts the channel.
+func saveChannel(w http.ResponseWriter, r *http.Request, notifStore *procstore.NotificationStore) {
+	var ch notify.Channel
+	if err := decodeBody(r, &ch); err != nil {
+		jsonError(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	if err := ch.Validate(); err != nil {
+		jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if ch.Config == nil {
+		ch.Config = map[string]interface{}{}
+	}
+	saved, err := notifStore.UpsertChannel(r.Context(), &ch)
+	if err != nil {
+		slog.Error("engine-server: save notification channel", "channel", ch.ID, logging.KeyError, err)
+		jsonError(w, middleware.SanitizeError(err, "failed to save channel"), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(saved)
+}
+
+// testChannel sends a test event through channel id and reports the outcome,
+// so a misconfigured channel is found before a real failure goes unnoticed.
+func testChannel(w http.ResponseWriter, r *http.Request, id string, notifStore *procstore.NotificationStore, dispatcher *notify.Dispatcher) {
+	ch, err := notifStore.GetChannel(r.Context(), id)
+	if !channelFound(w, id, err) {
+		return
+	}
+	ev := notify.Event{
+		Type:      "test",
+		Workspace: tenant.Workspace(r.Context()),
+		ProcessID: "notification-test",
+		Message:   fmt.Sprintf("test notification for channel %s", id),
+	}
+	if err := dispatcher.Send(r.Context(), *ch, ev); err != nil {
+		jsonError(w, fmt.Sprintf("delivery failed: %v", err), http.StatusBadGateway)
+		return
+	}
+	jsonOK(w, map[string]string{"channel": id, "status": "sent"})
+}
+
+// handleSubscriptions serves GET and PUT /api/v1/processes/{id}/subscriptions:
+// the notification channels a process reports failures, SLA breaches and
+// deploys to. PUT replaces the list with {"subscriptions": [{channel_id,
+// events, cooldown_s}]}.
+func handleSubscriptions(w http.ResponseWriter, r *http.Request, processID string, procStore *procstore.ProcessStore, notifStore *procstore.NotificationStore) {
+	if notifStore == nil {
+		jsonError(w, "notification store not configured (DATABASE_URL missing)", http.StatusServiceUnavailable)
+		return
+	}
+	if _, err := procStore.Get(r.Context(), processID); err != nil {
+		jsonError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var req struct {
+			Subscriptions []notify.Subscription `json:"subscriptions"`
+		}
+		if err := decodeBody(r, &req); err != nil {
+			jsonError(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+		seen := make(map[string]bool, len(req.Subscriptions))
+		for _, sub := range req.Subscriptions {
+			if err := sub.Validate(); err != nil {
+				jsonError(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if seen[sub.ChannelID] {
+				jsonError(w, fmt.Sprintf("channel %s is listed more than once", sub.ChannelID), http.StatusBadRequest)
+				return
+			}
+			seen[sub.ChannelID] = true
+		}
+		err := notifStore.SetSubscriptions(r.Context(), processID, req.Subscriptions)
+		if errors.Is(err, procstore.ErrChannelNotFound) {
+			jsonError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			slog.Error("engine-server: set subscriptions", logging.KeyProcessID, processID, logging.KeyError, err)
+			jsonError(w, middleware.SanitizeError(err, "failed to save subscriptions"), http.StatusInternalServerError)
+			return
+		}
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	subs, err := notifStore.Subscriptions(r.Context(), processID)
+	if err != nil {
+		slog.Error("engine-server: list subscriptions", logging.KeyProcessID, processID, logging.KeyError, err)
+		jsonError(w, middleware.SanitizeError(err, "failed to list subscriptions"), http.StatusInternalServerError)
+		return
+	}
+	if subs == nil {
+		subs = []notify.Subscription{}
+	}
+	jsonOK(w, map[string]interface{}{"process_id": processID, "subscriptions": subs})
+}
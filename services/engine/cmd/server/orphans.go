@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"flowjs-works/engine/internal/logging"
+	"flowjs-works/engine/internal/middleware"
+	procstore "flowjs-works/engine/internal/store"
+	"flowjs-works/engine/internal/tenant"
+	"flowjs-works/engine/internal/triggers"
+)
+
+const (
+	// defaultOrphanSweepInterval is how often REST and SOAP routes are
+	// checked against the deployed processes (TRIGGER_ORPHAN_SWEEP).
+	defaultOrphanSweepInterval = 5 * time.Minute
+	// defaultOrphanGrace is how long a route stays orphaned before the sweep
+	// removes it (TRIGGER_ORPHAN_GRACE).
+	defaultOrphanGrace = 10 * time.Minute
+)
+
+// deployedProcessIDs returns the ids of the processes deployed in the config
+// DB, or nil without one.
+func deployedProcessIDs(ctx context.Context, procStore *procstore.ProcessStore) (map[string]bool, error) {
+	if procStore == nil {
+		return nil, nil
+	}
+	deployed, err := procStore.ListDeployed(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[string]bool, len(deployed))
+	for _, p := range deployed {
+		ids[p.ID] = true
+	}
+	return ids, nil
+}
+
+// startOrphanSweep removes, every interval, the REST and SOAP routes no
+// deployed process has served for grace, such as the ghost paths of a
+// process stopped by another engine. It returns a function stopping the
+// sweep.
+func startOrphanSweep(procStore *procstore.ProcessStore, triggerMgr *triggers.Manager, interval, grace time.Duration) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), interval)
+				deployed, err := deployedProcessIDs(ctx, procStore)
+				cancel()
+				if err != nil {
+					// Without the deployed processes every route would look
+					// orphaned: skip this sweep.
+					slog.Warn("engine-server: orphan sweep: list deployed processes", logging.KeyError, err)
+					continue
+				}
+				if removed := triggerMgr.RemoveOrphans(deployed, grace); len(removed) > 0 {
+					slog.Info("engine-server: orphaned trigger routes removed", "removed", len(removed))
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// handleTriggerOrphans serves the REST and SOAP routes no deployed process
+// serves any more. Only admins may use it:
+//
+//	GET    /api/v1/admin/triggers/orphans — list them, with why and since when
+//	DELETE /api/v1/admin/triggers/orphans — remove them now, without waiting for the sweep
+func handleTriggerOrphans(procStore *procstore.ProcessStore, triggerMgr *triggers.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if p, _ := tenant.PrincipalFromContext(r.Context()); !p.IsAdmin() {
+			jsonError(w, "trigger orphans require the admin role", http.StatusForbidden)
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodDelete {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		deployed, err := deployedProcessIDs(r.Context(), procStore)
+		if err != nil {
+			slog.Error("engine-server: list deployed processes", logging.KeyError, err)
+			jsonError(w, middleware.SanitizeError(err, "failed to list deployed processes"), http.StatusInternalServerError)
+			return
+		}
+		var orphans []triggers.Orphan
+		if r.Method == http.MethodGet {
+			orphans = triggerMgr.Orphans(deployed)
+		} else {
+			orphans = triggerMgr.RemoveOrphans(deployed, 0)
+		}
+		if orphans == nil {
+			orphans = []triggers.Orphan{}
+		}
+		jsonOK(w, orphans)
+	}
+}
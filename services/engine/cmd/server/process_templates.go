@@ -0,0 +1,209 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"flowjs-works/engine/internal/logging"
+	"flowjs-works/engine/internal/middleware"
+	"flowjs-works/engine/internal/models"
+	procstore "flowjs-works/engine/internal/store"
+)
+
+// cloneRequest is the body of a clone or a template instantiation: the id
+// and optional name of the new process and the values of the
+// {{param.<name>}} placeholders.
+type cloneRequest struct {
+	ID         string                 `json:"id"`
+	Name       string                 `json:"name"`
+	Parameters map[string]interface{} `json:"parameters"`
+}
+
+// handleClone serves POST /api/v1/processes/{processId}/clone: it saves a
+// draft copy of the stored process under a new id, with its placeholders
+// replaced by the request parameters.
+func handleClone(w http.ResponseWriter, r *http.Request, processID string, procStore *procstore.ProcessStore) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	rec, err := procStore.Get(r.Context(), processID)
+	if errors.Is(err, procstore.ErrProcessNotFound) {
+		jsonError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		slog.Error("engine-server: get process to clone", logging.KeyProcessID, processID, logging.KeyError, err)
+		jsonError(w, middleware.SanitizeError(err, "failed to get process"), http.StatusInternalServerError)
+		return
+	}
+	src, err := rec.ParseDSL()
+	if err != nil {
+		slog.Error("engine-server: parse process to clone", logging.KeyProcessID, processID, logging.KeyError, err)
+		jsonError(w, middleware.SanitizeError(err, "failed to parse process"), http.StatusInternalServerError)
+		return
+	}
+	createFromProcess(w, r, src, "cloned from process "+processID, procStore)
+}
+
+// createFromProcess decodes a cloneRequest and saves src cloned by it as a
+// new draft process, recording note as its change note.
+func createFromProcess(w http.ResponseWriter, r *http.Request, src *models.Process, note string, procStore *procstore.ProcessStore) {
+	var req cloneRequest
+	if err := decodeBody(r, &req); err != nil {
+		jsonError(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	if !validProcessIDRe.MatchString(req.ID) {
+		jsonError(w, "id is required and must contain only alphanumeric characters, hyphens, and underscores", http.StatusBadRequest)
+		return
+	}
+	proc, err := src.Clone(req.ID, req.Name, req.Parameters)
+	if err != nil {
+		jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rec, err := procStore.Create(r.Context(), proc, note)
+	switch {
+	case errors.Is(err, procstore.ErrProcessExists):
+		jsonError(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, procstore.ErrPlaintextCredential):
+		jsonError(w, err.Error(), http.StatusUnprocessableEntity)
+	case err != nil:
+		slog.Error("engine-server: create process", logging.KeyProcessID, req.ID, logging.KeyError, err)
+		jsonError(w, middleware.SanitizeError(err, "failed to save process"), http.StatusInternalServerError)
+	default:
+		slog.Info("engine-server: process created", logging.KeyProcessID, rec.ID, "note", note)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("ETag", processETag(rec.Revision))
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(rec)
+	}
+}
+
+// processTemplateRequest is the body of a template save: the DSL, or the id
+// of a stored process to take it from.
+type processTemplateRequest struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	ProcessID   string          `json:"process_id"`
+	DSL         *models.Process `json:"dsl"`
+}
+
+// handleProcessTemplates serves the template gallery, the standard flows
+// teams stamp out per-customer variants of:
+//
+//	GET    /api/v1/process-templates                    — list templates (without DSL)
+//	POST   /api/v1/process-templates                    — create or replace {name, description, dsl | process_id}
+//	GET    /api/v1/process-templates/{name}             — retrieve a template with its DSL and parameters
+//	DELETE /api/v1/process-templates/{name}             — delete a template
+//	POST   /api/v1/process-templates/{name}/instantiate — create a process {id, name, parameters} from it
+func handleProcessTemplates(tmplStore *procstore.ProcessTemplateStore, procStore *procstore.ProcessStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if tmplStore == nil {
+			jsonError(w, "process template store not configured (DATABASE_URL missing)", http.StatusServiceUnavailable)
+			return
+		}
+		name, sub, _ := strings.Cut(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/process-templates"), "/"), "/")
+		switch {
+		case name == "" && r.Method == http.MethodGet:
+			list, err := tmplStore.List(r.Context())
+			if err != nil {
+				slog.Error("engine-server: list process templates", logging.KeyError, err)
+				jsonError(w, middleware.SanitizeError(err, "failed to list process templates"), http.StatusInternalServerError)
+				return
+			}
+			if list == nil {
+				list = []procstore.ProcessTemplate{}
+			}
+			jsonOK(w, list)
+		case name == "" && r.Method == http.MethodPost:
+			saveProcessTemplate(w, r, tmplStore, procStore)
+		case sub == "instantiate" && r.Method == http.MethodPost:
+			src, err := tmplStore.Process(r.Context(), name)
+			if !processTemplateFound(w, name, err) {
+				return
+			}
+			createFromProcess(w, r, src, "created from template "+name, procStore)
+		case sub == "" && r.Method == http.MethodGet:
+			t, err := tmplStore.Get(r.Context(), name)
+			if !processTemplateFound(w, name, err) {
+				return
+			}
+			jsonOK(w, t)
+		case sub == "" && r.Method == http.MethodDelete:
+			if !processTemplateFound(w, name, tmplStore.Delete(r.Context(), name)) {
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// processTemplateFound writes the error response for err, reporting whether
+// there was none.
+func processTemplateFound(w http.ResponseWriter, name string, err error) bool {
+	switch {
+	case errors.Is(err, procstore.ErrProcessTemplateNotFound):
+		jsonError(w, err.Error(), http.StatusNotFound)
+		return false
+	case err != nil:
+		slog.Error("engine-server: process template", "template", name, logging.KeyError, err)
+		jsonError(w, middleware.SanitizeError(err, "failed to access process template"), http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
+// saveProcessTemplate validates the request body and saves its DSL, or the
+// DSL of the stored process it names, as a template.
+func saveProcessTemplate(w http.ResponseWriter, r *http.Request, tmplStore *procstore.ProcessTemplateStore, procStore *procstore.ProcessStore) {
+	var req processTemplateRequest
+	if err := decodeBody(r, &req); err != nil {
+		jsonError(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	if !models.ValidNodeTemplateName(req.Name) {
+		jsonError(w, "name must be 1-255 alphanumeric characters, hyphens or underscores", http.StatusBadRequest)
+		return
+	}
+	proc := req.DSL
+	switch {
+	case (proc == nil) == (req.ProcessID == ""):
+		jsonError(w, "exactly one of dsl and process_id is required", http.StatusBadRequest)
+		return
+	case req.ProcessID != "":
+		if procStore == nil {
+			jsonError(w, "process store not configured (DATABASE_URL missing)", http.StatusServiceUnavailable)
+			return
+		}
+		rec, err := procStore.Get(r.Context(), req.ProcessID)
+		if errors.Is(err, procstore.ErrProcessNotFound) {
+			jsonError(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err == nil {
+			proc, err = rec.ParseDSL()
+		}
+		if err != nil {
+			slog.Error("engine-server: load process for template", logging.KeyProcessID, req.ProcessID, logging.KeyError, err)
+			jsonError(w, middleware.SanitizeError(err, "failed to load process"), http.StatusInternalServerError)
+			return
+		}
+	}
+	saved, err := tmplStore.Save(r.Context(), req.Name, req.Description, proc)
+	if err != nil {
+		slog.Error("engine-server: save process template", "template", req.Name, logging.KeyError, err)
+		jsonError(w, middleware.SanitizeError(err, "failed to save process template"), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(saved)
+}
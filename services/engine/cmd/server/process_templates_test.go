@@ -0,0 +1,174 @@
+package main
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"flowjs-works/engine/internal/models"
+	procstore "flowjs-works/engine/internal/store"
+	"flowjs-works/engine/internal/store/storetest"
+	"flowjs-works/engine/internal/tenant"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sftpTemplate is a flow with a placeholder inside a string and one that is
+// a whole config value.
+func sftpTemplate() *models.Process {
+	return &models.Process{
+		Definition: models.Definition{ID: "sftp-pickup", Version: "1.0.0", Name: "SFTP pickup"},
+		Trigger:    models.Trigger{ID: "trg", Type: "manual"},
+		Nodes: []models.Node{{ID: "fetch", Type: "sftp", Config: map[string]interface{}{
+			"host":   "sftp.example.com",
+			"port":   "{{param.port}}",
+			"folder": "/inbound/{{param.customer}}",
+		}}},
+	}
+}
+
+// cloneStore serves the processes of processStoreWith and creates new ones,
+// reporting the ids in existing as already taken.
+func cloneStore(t *testing.T, existing []string, procs ...storedProcess) (*procstore.ProcessStore, *storetest.DB) {
+	t.Helper()
+	procStore, fake := processStoreWith(t, procs...)
+	fake.On("INSERT INTO processes", func(args []driver.Value) storetest.Result {
+		res := storetest.Result{Columns: recordCols}
+		for _, id := range existing {
+			if args[0] == id {
+				return res
+			}
+		}
+		res.Rows = [][]driver.Value{savedRecord(args, 1)}
+		return res
+	})
+	return procStore, fake
+}
+
+func postAs(workspace, path, body string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	return r.WithContext(tenant.WithWorkspace(r.Context(), workspace))
+}
+
+// savedDSL returns the DSL of the process created through fake.
+func savedDSL(t *testing.T, fake *storetest.DB) *models.Process {
+	t.Helper()
+	insert, ok := fake.Find("INSERT INTO processes")
+	require.True(t, ok, "the process is saved")
+	var proc models.Process
+	require.NoError(t, json.Unmarshal(insert.Args[5].([]byte), &proc))
+	return &proc
+}
+
+func TestClone(t *testing.T) {
+	src := storedProcess{workspace: "team-a", proc: sftpTemplate(), status: "deployed", revision: 4}
+	procStore, fake := cloneStore(t, nil, src)
+
+	rec := httptest.NewRecorder()
+	handleClone(rec, postAs("team-a", "/api/v1/processes/sftp-pickup/clone",
+		`{"id": "acme-pickup", "parameters": {"port": 2222, "customer": "acme"}}`), "sftp-pickup", procStore)
+
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	assert.Equal(t, `"1"`, rec.Header().Get("ETag"))
+	insert, _ := fake.Find("INSERT INTO processes")
+	assert.Equal(t, "team-a", insert.Args[1])
+	assert.Equal(t, "cloned from process sftp-pickup", insert.Args[9])
+	proc := savedDSL(t, fake)
+	assert.Equal(t, "acme-pickup", proc.Definition.ID)
+	assert.Equal(t, "SFTP pickup", proc.Definition.Name, "the name is kept when none is given")
+	assert.Equal(t, float64(2222), proc.Nodes[0].Config["port"], "a single placeholder keeps the parameter's type")
+	assert.Equal(t, "/inbound/acme", proc.Nodes[0].Config["folder"])
+}
+
+func TestClone_Errors(t *testing.T) {
+	src := storedProcess{workspace: "team-a", proc: sftpTemplate(), status: "draft", revision: 1}
+	full := `"parameters": {"port": 22, "customer": "acme"}`
+
+	tests := []struct {
+		name      string
+		workspace string
+		body      string
+		wantCode  int
+		wantError string
+	}{
+		{"missing parameter", "team-a", `{"id": "acme-pickup", "parameters": {"port": 22}}`, http.StatusBadRequest, "missing parameters: customer"},
+		{"no parameters", "team-a", `{"id": "acme-pickup"}`, http.StatusBadRequest, "missing parameters: customer, port"},
+		{"invalid id", "team-a", `{"id": "acme pickup", ` + full + `}`, http.StatusBadRequest, "id is required"},
+		{"existing id", "team-a", `{"id": "taken", ` + full + `}`, http.StatusConflict, "already in use"},
+		{"source in another workspace", "team-b", `{"id": "acme-pickup", ` + full + `}`, http.StatusNotFound, "sftp-pickup"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			procStore, fake := cloneStore(t, []string{"taken"}, src)
+
+			rec := httptest.NewRecorder()
+			handleClone(rec, postAs(tc.workspace, "/api/v1/processes/sftp-pickup/clone", tc.body), "sftp-pickup", procStore)
+
+			assert.Equal(t, tc.wantCode, rec.Code)
+			assert.Contains(t, rec.Body.String(), tc.wantError)
+			if tc.wantCode != http.StatusConflict {
+				_, saved := fake.Find("INSERT INTO processes")
+				assert.False(t, saved, "nothing is saved")
+			}
+		})
+	}
+}
+
+// templateStoreWith returns a template store holding proc as template name
+// of workspace.
+func templateStoreWith(t *testing.T, workspace, name string, proc *models.Process) *procstore.ProcessTemplateStore {
+	t.Helper()
+	db, fake := storetest.Open(t)
+	dsl, err := json.Marshal(proc)
+	require.NoError(t, err)
+	fake.On("FROM process_templates", func(args []driver.Value) storetest.Result {
+		res := storetest.Result{Columns: []string{"description", "dsl", "parameters", "created_by", "updated_at"}}
+		if args[0] == workspace && args[1] == name {
+			res.Rows = [][]driver.Value{{"", dsl, []byte(`["customer","port"]`), "alice", time.Now()}}
+		}
+		return res
+	})
+	return procstore.NewProcessTemplateStore(db)
+}
+
+func TestProcessTemplates_Instantiate(t *testing.T) {
+	tmplStore := templateStoreWith(t, "team-a", "sftp", sftpTemplate())
+	body := `{"id": "acme-pickup", "name": "Acme pickup", "parameters": {"port": 2222, "customer": "acme"}}`
+
+	t.Run("creates the process", func(t *testing.T) {
+		procStore, fake := cloneStore(t, nil)
+		rec := httptest.NewRecorder()
+		handleProcessTemplates(tmplStore, procStore)(rec, postAs("team-a", "/api/v1/process-templates/sftp/instantiate", body))
+
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+		proc := savedDSL(t, fake)
+		assert.Equal(t, "acme-pickup", proc.Definition.ID)
+		assert.Equal(t, "Acme pickup", proc.Definition.Name)
+		assert.Equal(t, float64(2222), proc.Nodes[0].Config["port"], "a single placeholder keeps the parameter's type")
+		insert, _ := fake.Find("INSERT INTO processes")
+		assert.Equal(t, "created from template sftp", insert.Args[9])
+	})
+
+	t.Run("template of another workspace", func(t *testing.T) {
+		procStore, fake := cloneStore(t, nil)
+		rec := httptest.NewRecorder()
+		handleProcessTemplates(tmplStore, procStore)(rec, postAs("team-b", "/api/v1/process-templates/sftp/instantiate", body))
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		_, saved := fake.Find("INSERT INTO processes")
+		assert.False(t, saved, "nothing is saved")
+	})
+
+	t.Run("existing id", func(t *testing.T) {
+		procStore, _ := cloneStore(t, []string{"acme-pickup"})
+		rec := httptest.NewRecorder()
+		handleProcessTemplates(tmplStore, procStore)(rec, postAs("team-a", "/api/v1/process-templates/sftp/instantiate", body))
+
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	})
+}
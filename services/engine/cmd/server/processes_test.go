@@ -0,0 +1,112 @@
+package main
+
+import (
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"flowjs-works/engine/internal/store/storetest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseIfMatch(t *testing.T) {
+	tests := []struct {
+		header  string
+		want    int
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"*", 0, false},
+		{`"3"`, 3, false},
+		{`W/"3"`, 3, false},
+		{` "12" `, 12, false},
+		{"7", 7, false},
+		{`"0"`, 0, true},
+		{`"-1"`, 0, true},
+		{`"abc"`, 0, true},
+		{`"3", "4"`, 0, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.header, func(t *testing.T) {
+			got, err := parseIfMatch(tc.header)
+			if tc.wantErr {
+				assert.ErrorContains(t, err, "If-Match must be an ETag")
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestProcessETag_RoundTrip(t *testing.T) {
+	assert.Equal(t, `"5"`, processETag(5))
+	rev, err := parseIfMatch(processETag(5))
+	require.NoError(t, err)
+	assert.Equal(t, 5, rev)
+}
+
+// postProcess posts a process with ifMatch to a store whose stored process
+// is at revision current, and returns the response and the revision the
+// upsert was conditioned on.
+func postProcess(t *testing.T, ifMatch string, current int) (*httptest.ResponseRecorder, driver.Value) {
+	t.Helper()
+	procStore, fake := processStoreWith(t)
+	fake.On("INSERT INTO processes", func(args []driver.Value) storetest.Result {
+		res := storetest.Result{Columns: recordCols}
+		if ifRev := args[6].(int); ifRev == 0 || ifRev == current {
+			res.Rows = [][]driver.Value{savedRecord(args, current+1)}
+		}
+		return res
+	})
+	fake.On("SELECT workspace, revision FROM processes WHERE id = $1", func([]driver.Value) storetest.Result {
+		return storetest.Result{Columns: []string{"workspace", "revision"}, Rows: [][]driver.Value{{"default", int64(current)}}}
+	})
+
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/processes",
+		strings.NewReader(`{"definition": {"id": "orders", "version": "1.0.0", "name": "Orders"}, "trigger": {"id": "trg", "type": "manual"}, "nodes": []}`))
+	if ifMatch != "" {
+		r.Header.Set("If-Match", ifMatch)
+	}
+	rec := httptest.NewRecorder()
+	handleProcesses(procStore)(rec, r)
+
+	var ifRevision driver.Value
+	if insert, ok := fake.Find("INSERT INTO processes"); ok {
+		ifRevision = insert.Args[6]
+	}
+	return rec, ifRevision
+}
+
+func TestHandleProcesses_IfMatch(t *testing.T) {
+	t.Run("current revision", func(t *testing.T) {
+		rec, ifRevision := postProcess(t, `"3"`, 3)
+		assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+		assert.Equal(t, `"4"`, rec.Header().Get("ETag"))
+		assert.Equal(t, 3, ifRevision)
+	})
+
+	t.Run("stale revision", func(t *testing.T) {
+		rec, ifRevision := postProcess(t, `"2"`, 3)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assert.Contains(t, rec.Body.String(), "is at revision 3")
+		assert.Empty(t, rec.Header().Get("ETag"))
+		assert.Equal(t, 2, ifRevision)
+	})
+
+	t.Run("no If-Match", func(t *testing.T) {
+		rec, ifRevision := postProcess(t, "", 3)
+		assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+		assert.Equal(t, 0, ifRevision, "the revision check is skipped")
+	})
+
+	t.Run("malformed If-Match", func(t *testing.T) {
+		rec, ifRevision := postProcess(t, `"latest"`, 3)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Nil(t, ifRevision, "nothing is saved")
+	})
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	"flowjs-works/engine/internal/engine"
+	"flowjs-works/engine/internal/lint"
+	"flowjs-works/engine/internal/logging"
+	"flowjs-works/engine/internal/middleware"
+	"flowjs-works/engine/internal/models"
+	procstore "flowjs-works/engine/internal/store"
+)
+
+// handleProfiles serves the connection profiles nodes reference by name:
+//
+//	GET    /api/v1/profiles         — list profiles
+//	POST   /api/v1/profiles         — create or replace {name, type, description, config, secret_ref}
+//	GET    /api/v1/profiles/{name}  — retrieve a profile
+//	DELETE /api/v1/profiles/{name}  — delete a profile
+func handleProfiles(profStore *procstore.ProfileStore, executor *engine.ProcessExecutor) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if profStore == nil {
+			jsonError(w, "profile store not configured (DATABASE_URL missing)", http.StatusServiceUnavailable)
+			return
+		}
+		name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/profiles"), "/")
+		switch {
+		case name == "" && r.Method == http.MethodGet:
+			list, err := profStore.List(r.Context())
+			if err != nil {
+				slog.Error("engine-server: list profiles", logging.KeyError, err)
+				jsonError(w, middleware.SanitizeError(err, "failed to list profiles"), http.StatusInternalServerError)
+				return
+			}
+			if list == nil {
+				list = []models.ConnectionProfile{}
+			}
+			jsonOK(w, list)
+		case name == "" && r.Method == http.MethodPost:
+			saveProfile(w, r, profStore, executor)
+		case r.Method == http.MethodGet:
+			p, err := profStore.Profile(r.Context(), name)
+			if !profileFound(w, name, err) {
+				return
+			}
+			jsonOK(w, p)
+		case r.Method == http.MethodDelete:
+			if !profileFound(w, name, profStore.Delete(r.Context(), name)) {
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// profileFound writes the error response for err, reporting whether there
+// was none.
+func profileFound(w http.ResponseWriter, name string, err error) bool {
+	switch {
+	case errors.Is(err, procstore.ErrProfileNotFound):
+		jsonError(w, err.Error(), http.StatusNotFound)
+		return false
+	case err != nil:
+		slog.Error("engine-server: connection profile", "profile", name, logging.KeyError, err)
+		jsonError(w, middleware.SanitizeError(err, "failed to access profile"), http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
+// saveProfile validates the request body and upserts the profile.
+// Credentials belong in the secret named by secret_ref, so a config holding
+// one in plain text is rejected.
+func saveProfile(w http.ResponseWriter, r *http.Request, profStore *procstore.ProfileStore, executor *engine.ProcessExecutor) {
+	var p models.ConnectionProfile
+	if err := decodeBody(r, &p); err != nil {
+		jsonError(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	if err := p.Validate(); err != nil {
+		jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if p.Type != "" && !slices.Contains(executor.Activities(), p.Type) {
+		jsonError(w, fmt.Sprintf("unknown node type %q", p.Type), http.StatusBadRequest)
+		return
+	}
+	if paths := lint.PlaintextCredentials(p.Config); len(paths) > 0 {
+		jsonError(w, fmt.Sprintf("config.%s holds a plain-text credential; put it in the secret named by secret_ref", paths[0]), http.StatusBadRequest)
+		return
+	}
+	if p.Config == nil {
+		p.Config = map[string]interface{}{}
+	}
+	saved, err := profStore.Upsert(r.Context(), &p)
+	if err != nil {
+		slog.Error("engine-server: save profile", "profile", p.Name, logging.KeyError, err)
+		jsonError(w, middleware.SanitizeError(err, "failed to save profile"), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(saved)
+}
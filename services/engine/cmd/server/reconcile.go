@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"flowjs-works/engine/internal/engine"
+	"flowjs-works/engine/internal/logging"
+	"flowjs-works/engine/internal/models"
+	procstore "flowjs-works/engine/internal/store"
+	"flowjs-works/engine/internal/tenant"
+	"flowjs-works/engine/internal/triggers"
+)
+
+const (
+	// defaultReconcileInterval is how often the running triggers are compared
+	// with the processes deployed in the config DB (TRIGGER_RECONCILE_INTERVAL).
+	defaultReconcileInterval = time.Minute
+	// defaultReconcileGrace is how long a drift must last before it is
+	// corrected (TRIGGER_RECONCILE_GRACE).
+	defaultReconcileGrace = 30 * time.Second
+)
+
+// startReconciler corrects, every interval, the drift between the processes
+// the config DB records as deployed and the triggers running here, which a
+// crash between the two updates of a deploy or a stop leaves behind. Each
+// correction is audited; a trigger that fails to start is recorded as the
+// process's deploy_error and audited again only when its error changes. It
+// returns a function stopping the loop.
+func startReconciler(procStore *procstore.ProcessStore, triggerMgr *triggers.Manager, executor *engine.ProcessExecutor, interval, grace time.Duration) func() {
+	reconciler := triggers.NewReconciler(triggerMgr, func(ctx context.Context, workspace, processID string) (*models.Process, error) {
+		return procStore.Deployable(tenant.WithWorkspace(ctx, workspace), processID, engineEnvironment)
+	}, grace)
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), interval)
+				reconcile(ctx, reconciler, procStore, executor)
+				cancel()
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// reconcile runs one pass of reconciler and records its corrections.
+func reconcile(ctx context.Context, reconciler *triggers.Reconciler, procStore *procstore.ProcessStore, executor *engine.ProcessExecutor) {
+	list, err := procStore.ListDeployed(ctx)
+	if err != nil {
+		// Without the deployed processes every trigger would look stale:
+		// skip this pass.
+		slog.Warn("engine-server: reconcile: list deployed processes", logging.KeyError, err)
+		return
+	}
+	deployed := make([]triggers.DeployedProcess, len(list))
+	deployErrors := make(map[string]string, len(list))
+	for i, p := range list {
+		deployed[i] = triggers.DeployedProcess{ID: p.ID, Workspace: p.Workspace, TriggerType: p.TriggerType}
+		deployErrors[p.ID] = p.DeployError
+	}
+	for _, d := range reconciler.Reconcile(ctx, deployed) {
+		if d.Kind == triggers.DriftNotRunning {
+			if d.Error != "" && d.Error == deployErrors[d.ProcessID] {
+				continue
+			}
+			if err := procStore.SetDeployError(tenant.WithWorkspace(ctx, d.Workspace), d.ProcessID, d.Error); err != nil {
+				slog.Warn("engine-server: record deploy error", logging.KeyProcessID, d.ProcessID, logging.KeyError, err)
+			}
+		}
+		executor.SendDriftAuditLog(d.Workspace, d.ProcessID, d.TriggerType, d.Kind, d.Error)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"flowjs-works/engine/internal/engine"
+	"flowjs-works/engine/internal/logging"
+	procstore "flowjs-works/engine/internal/store"
+	"flowjs-works/engine/internal/tenant"
+	"flowjs-works/engine/internal/triggers"
+)
+
+// redeployTimeout bounds restoring the triggers of deployed processes on
+// startup.
+const redeployTimeout = 2 * time.Minute
+
+// redeployTriggers restarts the triggers of every process still deployed in
+// the config DB, of every workspace, with the DSL of engineEnvironment, so a
+// restart of the engine does not silently stop them. A trigger that fails to
+// start is logged, recorded as the process's deploy_error and audited (and
+// notified) as a failed deploy; the process stays deployed so the next
+// startup or deploy retries it.
+func redeployTriggers(procStore *procstore.ProcessStore, triggerMgr *triggers.Manager, executor *engine.ProcessExecutor) {
+	ctx, cancel := context.WithTimeout(context.Background(), redeployTimeout)
+	defer cancel()
+	deployed, err := procStore.ListDeployed(ctx)
+	if err != nil {
+		slog.Error("engine-server: list deployed processes; triggers not restored", logging.KeyError, err)
+		return
+	}
+
+	failed := 0
+	for _, p := range deployed {
+		wsCtx := tenant.WithWorkspace(ctx, p.Workspace)
+		proc, err := procStore.Deployable(wsCtx, p.ID, engineEnvironment)
+		if err == nil {
+			err = triggerMgr.Deploy(proc)
+		}
+		if err != nil {
+			failed++
+			slog.Error("engine-server: restore trigger", logging.KeyWorkspace, p.Workspace, logging.KeyProcessID, p.ID, logging.KeyTrigger, p.TriggerType, logging.KeyError, err)
+			executor.SendLifecycleAuditLog(p.Workspace, p.ID, p.TriggerType, "deployed", err.Error())
+			if err := procStore.SetDeployError(wsCtx, p.ID, err.Error()); err != nil {
+				slog.Warn("engine-server: record deploy error", logging.KeyProcessID, p.ID, logging.KeyError, err)
+			}
+			continue
+		}
+		if p.DeployError != "" {
+			if err := procStore.SetDeployError(wsCtx, p.ID, ""); err != nil {
+				slog.Warn("engine-server: clear deploy error", logging.KeyProcessID, p.ID, logging.KeyError, err)
+			}
+		}
+	}
+	slog.Info("engine-server: triggers restored", "restored", len(deployed)-failed, "failed", failed)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"database/sql/driver"
+	"testing"
+
+	"flowjs-works/engine/internal/engine"
+	"flowjs-works/engine/internal/models"
+	"flowjs-works/engine/internal/store/storetest"
+	"flowjs-works/engine/internal/triggers"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func deployedProcess(id, triggerType, deployError string) storedProcess {
+	return storedProcess{
+		workspace: "team-a",
+		proc: &models.Process{
+			Definition: models.Definition{ID: id, Version: "1.0.0", Name: id},
+			Trigger:    models.Trigger{ID: "trg", Type: triggerType},
+		},
+		status:      "deployed",
+		revision:    1,
+		deployError: deployError,
+	}
+}
+
+// redeploy runs redeployTriggers over procs and returns the deploy_error
+// updates it made, by process id.
+func redeploy(t *testing.T, procs ...storedProcess) (map[string]string, *triggers.Manager) {
+	t.Helper()
+	procStore, fake := processStoreWith(t, procs...)
+	updates := map[string]string{}
+	fake.On("SET deploy_error", func(args []driver.Value) storetest.Result {
+		assert.Equal(t, "team-a", args[2], "the error is recorded in the process's workspace")
+		updates[args[1].(string)] = args[0].(string)
+		return storetest.Result{RowsAffected: 1}
+	})
+	executor, err := engine.NewProcessExecutor("")
+	require.NoError(t, err)
+	t.Cleanup(executor.Close)
+	triggerMgr := triggers.NewManager(executor)
+	t.Cleanup(triggerMgr.StopAll)
+
+	redeployTriggers(procStore, triggerMgr, executor)
+	return updates, triggerMgr
+}
+
+func TestRedeployTriggers_RecordsDeployError(t *testing.T) {
+	updates, triggerMgr := redeploy(t,
+		deployedProcess("broken", "no-such-trigger", ""),
+		deployedProcess("fine", "manual", ""),
+	)
+
+	require.Contains(t, updates, "broken")
+	assert.Contains(t, updates["broken"], "no-such-trigger")
+	assert.NotContains(t, updates, "fine", "a healthy process without a previous error is not updated")
+	assert.False(t, triggerMgr.IsRunning("broken"))
+	assert.True(t, triggerMgr.IsRunning("fine"))
+}
+
+func TestRedeployTriggers_ClearsDeployErrorOnSuccess(t *testing.T) {
+	updates, triggerMgr := redeploy(t, deployedProcess("healed", "manual", "triggers: start manual trigger: boom"))
+
+	assert.Equal(t, map[string]string{"healed": ""}, updates)
+	assert.True(t, triggerMgr.IsRunning("healed"))
+}
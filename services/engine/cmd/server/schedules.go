@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"flowjs-works/engine/internal/logging"
+	"flowjs-works/engine/internal/middleware"
+	procstore "flowjs-works/engine/internal/store"
+)
+
+// handleSchedule serves the one-shot scheduling sub-resource of a process:
+//
+//	POST   /api/v1/processes/{id}/schedule          — schedule a run {run_at, trigger_data}
+//	GET    /api/v1/processes/{id}/schedule          — list scheduled runs
+//	DELETE /api/v1/processes/{id}/schedule/{runId}  — cancel a pending run
+func handleSchedule(w http.ResponseWriter, r *http.Request, processID, runID string, procStore *procstore.ProcessStore, schedStore *procstore.ScheduleStore) {
+	if schedStore == nil {
+		jsonError(w, "schedule store not configured (DATABASE_URL missing)", http.StatusServiceUnavailable)
+		return
+	}
+	switch {
+	case r.Method == http.MethodPost && runID == "":
+		createScheduledRun(w, r, processID, procStore, schedStore)
+	case r.Method == http.MethodGet && runID == "":
+		list, err := schedStore.List(r.Context(), processID)
+		if err != nil {
+			slog.Error("engine-server: list scheduled runs", logging.KeyProcessID, processID, logging.KeyError, err)
+			jsonError(w, middleware.SanitizeError(err, "failed to list scheduled runs"), http.StatusInternalServerError)
+			return
+		}
+		if list == nil {
+			list = []procstore.ScheduledRun{}
+		}
+		jsonOK(w, list)
+	case r.Method == http.MethodDelete && runID != "":
+		if err := schedStore.Cancel(r.Context(), processID, runID); err != nil {
+			jsonError(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// createScheduledRun validates the request and persists a pending run.
+func createScheduledRun(w http.ResponseWriter, r *http.Request, processID string, procStore *procstore.ProcessStore, schedStore *procstore.ScheduleStore) {
+	var req struct {
+		RunAt       string                 `json:"run_at"`
+		TriggerData map[string]interface{} `json:"trigger_data"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	runAt, err := time.Parse(time.RFC3339, req.RunAt)
+	if err != nil {
+		jsonError(w, "run_at must be an RFC 3339 timestamp (e.g. 2025-01-31T09:00:00Z)", http.StatusBadRequest)
+		return
+	}
+	if _, err := procStore.Get(r.Context(), processID); err != nil {
+		jsonError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	run, err := schedStore.Create(r.Context(), processID, runAt, req.TriggerData)
+	if err != nil {
+		slog.Error("engine-server: schedule run", logging.KeyProcessID, processID, logging.KeyError, err)
+		jsonError(w, middleware.SanitizeError(err, "failed to schedule run"), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(run)
+}
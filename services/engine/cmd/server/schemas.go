@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"flowjs-works/engine/internal/logging"
+	"flowjs-works/engine/internal/middleware"
+	"flowjs-works/engine/internal/schema"
+	procstore "flowjs-works/engine/internal/store"
+)
+
+// handleSchemas serves the payload schema registry that triggers
+// (config.schema) and nodes (input_schema/output_schema) validate against:
+//
+//	GET    /api/v1/schemas         — list schemas with the JSONPaths they describe
+//	POST   /api/v1/schemas         — create or replace {name, description, schema}
+//	GET    /api/v1/schemas/{name}  — retrieve a schema
+//	DELETE /api/v1/schemas/{name}  — delete a schema
+func handleSchemas(schemaStore *procstore.SchemaStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if schemaStore == nil {
+			jsonError(w, "schema store not configured (DATABASE_URL missing)", http.StatusServiceUnavailable)
+			return
+		}
+		name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/schemas"), "/")
+		switch {
+		case r.Method == http.MethodGet && name == "":
+			list, err := schemaStore.List(r.Context())
+			if err != nil {
+				slog.Error("engine-server: list schemas", logging.KeyError, err)
+				jsonError(w, middleware.SanitizeError(err, "failed to list schemas"), http.StatusInternalServerError)
+				return
+			}
+			if list == nil {
+				list = []procstore.PayloadSchema{}
+			}
+			// The Designer offers these paths when mapping from a payload
+			// that declares the schema.
+			for i := range list {
+				list[i].Paths = schema.Paths(list[i].Schema)
+			}
+			jsonOK(w, list)
+		case r.Method == http.MethodPost && name == "":
+			saveSchema(w, r, schemaStore)
+		case r.Method == http.MethodGet:
+			getSchema(w, r, name, schemaStore)
+		case r.Method == http.MethodDelete && name != "":
+			if err := schemaStore.Delete(r.Context(), name); err != nil {
+				slog.Error("engine-server: delete schema", "schema", name, logging.KeyError, err)
+				jsonError(w, middleware.SanitizeError(err, "failed to delete schema"), http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// getSchema writes a single schema with its paths.
+func getSchema(w http.ResponseWriter, r *http.Request, name string, schemaStore *procstore.SchemaStore) {
+	ps, err := schemaStore.Get(r.Context(), name)
+	if errors.Is(err, procstore.ErrSchemaNotFound) {
+		jsonError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		slog.Error("engine-server: get schema", "schema", name, logging.KeyError, err)
+		jsonError(w, middleware.SanitizeError(err, "failed to get schema"), http.StatusInternalServerError)
+		return
+	}
+	ps.Paths = schema.Paths(ps.Schema)
+	jsonOK(w, ps)
+}
+
+// saveSchema validates the request body and upserts the schema.
+func saveSchema(w http.ResponseWriter, r *http.Request, schemaStore *procstore.SchemaStore) {
+	var ps procstore.PayloadSchema
+	if err := json.NewDecoder(r.Body).Decode(&ps); err != nil {
+		jsonError(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	if !procstore.ValidSnippetName(ps.Name) {
+		jsonError(w, "name must be one or more path segments of alphanumeric characters, hyphens and underscores (e.g. orders/created)", http.StatusBadRequest)
+		return
+	}
+	if ps.Schema == nil {
+		jsonError(w, "schema is required", http.StatusBadRequest)
+		return
+	}
+	if err := schema.Check(ps.Schema); err != nil {
+		jsonError(w, fmt.Sprintf("invalid schema: %v", err), http.StatusBadRequest)
+		return
+	}
+	saved, err := schemaStore.Upsert(r.Context(), &ps)
+	if err != nil {
+		slog.Error("engine-server: save schema", "schema", ps.Name, logging.KeyError, err)
+		jsonError(w, middleware.SanitizeError(err, "failed to save schema"), http.StatusInternalServerError)
+		return
+	}
+	saved.Paths = schema.Paths(saved.Schema)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(saved)
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"flowjs-works/engine/internal/logging"
+	"flowjs-works/engine/internal/middleware"
+	"flowjs-works/engine/internal/secrets"
+	procstore "flowjs-works/engine/internal/store"
+	"flowjs-works/engine/internal/tenant"
+)
+
+const (
+	// defaultSecretAuditLimit is the number of audit events returned when the
+	// request has no ?limit.
+	defaultSecretAuditLimit = 100
+	maxSecretAuditLimit     = 1000
+)
+
+// handleSecret serves the per-secret endpoints:
+//
+//	GET    /api/v1/secrets/{id}             — metadata and masked value (?reveal=full for admins)
+//	DELETE /api/v1/secrets/{id}             — delete a secret
+//	GET    /api/v1/secrets/{id}/references  — stored process nodes whose secret_ref is id
+//	GET    /api/v1/secrets/{id}/audit       — recent resolutions and changes (?limit=, default 100)
+//	POST   /api/v1/secrets/rotate           — re-encrypt every secret with the current key (admin)
+func handleSecret(store *secrets.SecretStore, audit *secrets.AuditLog, procStore *procstore.ProcessStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if store == nil {
+			jsonError(w, "secrets store not configured (DATABASE_URL missing)", http.StatusServiceUnavailable)
+			return
+		}
+		secretID, sub, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/v1/secrets/"), "/")
+		if secretID == "" {
+			jsonError(w, "secret id is required", http.StatusBadRequest)
+			return
+		}
+		switch {
+		case secretID == "rotate" && sub == "" && r.Method == http.MethodPost:
+			rotateSecrets(w, r, store)
+		case sub == "" && r.Method == http.MethodGet:
+			getSecret(w, r, store, audit, secretID)
+		case sub == "" && r.Method == http.MethodDelete:
+			deleteSecret(w, r, store, audit, secretID)
+		case sub == "references" && r.Method == http.MethodGet:
+			secretReferences(w, r, procStore, secretID)
+		case sub == "audit" && r.Method == http.MethodGet:
+			secretAuditEvents(w, r, audit, secretID)
+		case sub == "" || sub == "references" || sub == "audit":
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		default:
+			jsonError(w, "not found", http.StatusNotFound)
+		}
+	}
+}
+
+// getSecret returns a secret with its value masked, so operators can confirm
+// which fields it holds. ?reveal=full returns the plain value to admins only;
+// every full reveal, allowed or not, is recorded in the secret audit.
+func getSecret(w http.ResponseWriter, r *http.Request, store *secrets.SecretStore, audit *secrets.AuditLog, secretID string) {
+	var full bool
+	switch r.URL.Query().Get("reveal") {
+	case "", "masked":
+	case "full":
+		full = true
+	default:
+		jsonError(w, "reveal must be masked or full", http.StatusBadRequest)
+		return
+	}
+	if full {
+		if p, _ := tenant.PrincipalFromContext(r.Context()); !p.IsAdmin() {
+			_ = recordSecretReveal(r, audit, secretID, "admin role required")
+			jsonError(w, "revealing secret values requires the admin role", http.StatusForbidden)
+			return
+		}
+	}
+
+	meta, value, err := store.Get(r.Context(), secretID)
+	if errors.Is(err, secrets.ErrNotFound) {
+		jsonError(w, "secret not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		slog.Error("engine-server: get secret", "secret_id", secretID, logging.KeyError, err)
+		jsonError(w, middleware.SanitizeError(err, "failed to get secret"), http.StatusInternalServerError)
+		return
+	}
+	if !full {
+		jsonOK(w, secrets.SecretView{SecretMeta: meta, Value: secrets.MaskValue(value), Masked: true})
+		return
+	}
+	// A reveal that cannot be audited is refused.
+	if err := recordSecretReveal(r, audit, secretID, ""); err != nil {
+		jsonError(w, "failed to record secret reveal", http.StatusInternalServerError)
+		return
+	}
+	jsonOK(w, secrets.SecretView{SecretMeta: meta, Value: value})
+}
+
+// rotateSecrets re-encrypts with the current key the secrets, of every
+// workspace, still stored with a previous one. Only admins may run it.
+func rotateSecrets(w http.ResponseWriter, r *http.Request, store *secrets.SecretStore) {
+	if p, _ := tenant.PrincipalFromContext(r.Context()); !p.IsAdmin() {
+		jsonError(w, "rotating the secrets key requires the admin role", http.StatusForbidden)
+		return
+	}
+	result, err := store.ReEncrypt(r.Context())
+	if err != nil {
+		slog.Error("engine-server: re-encrypt secrets", "key_id", result.KeyID, "rotated", result.Rotated, logging.KeyError, err)
+		jsonError(w, middleware.SanitizeError(err, "failed to re-encrypt secrets"), http.StatusInternalServerError)
+		return
+	}
+	slog.Info("engine-server: secrets re-encrypted", "key_id", result.KeyID, "rotated", result.Rotated, "failed", len(result.Failed))
+	jsonOK(w, result)
+}
+
+func deleteSecret(w http.ResponseWriter, r *http.Request, store *secrets.SecretStore, audit *secrets.AuditLog, secretID string) {
+	if err := store.Delete(r.Context(), secretID); err != nil {
+		slog.Error("engine-server: delete secret", "secret_id", secretID, logging.KeyError, err)
+		jsonError(w, middleware.SanitizeError(err, "failed to delete secret"), http.StatusInternalServerError)
+		return
+	}
+	recordSecretChange(r, audit, secretID, secrets.AuditActionDelete)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func secretReferences(w http.ResponseWriter, r *http.Request, procStore *procstore.ProcessStore, secretID string) {
+	if procStore == nil {
+		jsonError(w, "process store not configured (DATABASE_URL missing)", http.StatusServiceUnavailable)
+		return
+	}
+	refs, err := procStore.SecretReferences(r.Context(), secretID)
+	if err != nil {
+		slog.Error("engine-server: secret references", "secret_id", secretID, logging.KeyError, err)
+		jsonError(w, middleware.SanitizeError(err, "failed to list secret references"), http.StatusInternalServerError)
+		return
+	}
+	if refs == nil {
+		refs = []procstore.SecretReference{}
+	}
+	jsonOK(w, refs)
+}
+
+func secretAuditEvents(w http.ResponseWriter, r *http.Request, audit *secrets.AuditLog, secretID string) {
+	limit := defaultSecretAuditLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxSecretAuditLimit {
+			jsonError(w, "limit must be between 1 and 1000", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	events, err := audit.List(r.Context(), secretID, limit)
+	if err != nil {
+		slog.Error("engine-server: secret audit", "secret_id", secretID, logging.KeyError, err)
+		jsonError(w, middleware.SanitizeError(err, "failed to list secret audit"), http.StatusInternalServerError)
+		return
+	}
+	if events == nil {
+		events = []secrets.AuditEvent{}
+	}
+	jsonOK(w, events)
+}
+
+// recordSecretReveal records a full reveal request, attributed to the caller.
+// A non-empty denied reason records a rejected attempt.
+func recordSecretReveal(r *http.Request, audit *secrets.AuditLog, secretID, denied string) error {
+	if audit == nil {
+		return nil
+	}
+	ev := secrets.AuditEvent{SecretID: secretID, Action: secrets.AuditActionReveal, Success: denied == "", Error: denied}
+	err := audit.Record(r.Context(), ev)
+	if err != nil {
+		slog.Warn("engine-server: record secret reveal", "secret_id", secretID, logging.KeyError, err)
+	}
+	return err
+}
+
+// recordSecretChange records an API change to a secret, attributed to the
+// caller. Failures are logged and never fail the request.
+func recordSecretChange(r *http.Request, audit *secrets.AuditLog, secretID, action string) {
+	if audit == nil {
+		return
+	}
+	ev := secrets.AuditEvent{SecretID: secretID, Action: action, Success: true}
+	if err := audit.Record(r.Context(), ev); err != nil {
+		slog.Warn("engine-server: record secret change", "secret_id", secretID, logging.KeyError, err)
+	}
+}
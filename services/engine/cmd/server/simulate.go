@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"flowjs-works/engine/internal/engine"
+	"flowjs-works/engine/internal/logging"
+	"flowjs-works/engine/internal/middleware"
+	procstore "flowjs-works/engine/internal/store"
+	"flowjs-works/engine/internal/triggers"
+)
+
+// defaultSimulatedExecutions is the number of runs of a simulation that does
+// not set executions.
+const defaultSimulatedExecutions = 10
+
+// maxSimulatedCaptures caps the captured requests a simulation replays, like
+// the capture list.
+const maxSimulatedCaptures = 200
+
+// simulateRequest is the body of POST /api/v1/processes/{id}/simulate.
+type simulateRequest struct {
+	Executions int   `json:"executions"`
+	Seed       int64 `json:"seed"`
+	// Schema or SchemaName describe the trigger payload to generate; by
+	// default the trigger's config "schema".
+	Schema     map[string]interface{} `json:"schema"`
+	SchemaName string                 `json:"schema_name"`
+	// Samples are trigger data objects used in turn instead of generated
+	// payloads; Captures adds the trigger data of the latest captured
+	// requests.
+	Samples  []map[string]interface{}   `json:"samples"`
+	Captures int                        `json:"captures"`
+	Mocks    map[string]engine.NodeMock `json:"mocks"`
+}
+
+// handleSimulate serves POST /api/v1/processes/{id}/simulate: it runs the
+// DSL the process deploys with now (see loadDeployable) in dry-run mode, with
+// generated or sampled trigger payloads, and reports the transitions and
+// nodes the runs covered (see engine.Simulate). Nothing is called, audited
+// or saved.
+func handleSimulate(w http.ResponseWriter, r *http.Request, processID string, procStore *procstore.ProcessStore, capStore *procstore.CaptureStore, executor *engine.ProcessExecutor) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req simulateRequest
+	if err := decodeBody(r, &req); err != nil {
+		jsonError(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	if req.Executions == 0 {
+		req.Executions = defaultSimulatedExecutions
+	}
+	if req.Captures < 0 || req.Captures > maxSimulatedCaptures {
+		jsonError(w, fmt.Sprintf("captures must be between 0 and %d", maxSimulatedCaptures), http.StatusBadRequest)
+		return
+	}
+	proc, ok := loadDeployable(w, r, processID, procStore)
+	if !ok {
+		return
+	}
+
+	samples := req.Samples
+	if req.Captures > 0 {
+		if capStore == nil {
+			jsonError(w, "capture store not configured (DATABASE_URL missing)", http.StatusServiceUnavailable)
+			return
+		}
+		list, err := capStore.ListCaptures(r.Context(), processID, req.Captures)
+		if err != nil {
+			slog.Error("engine-server: list captures", logging.KeyProcessID, processID, logging.KeyError, err)
+			jsonError(w, middleware.SanitizeError(err, "failed to list captures"), http.StatusInternalServerError)
+			return
+		}
+		for _, summary := range list {
+			c, err := capStore.GetCapture(r.Context(), processID, summary.ID)
+			if err != nil {
+				// Captures expire while the list is read.
+				continue
+			}
+			data, err := triggers.TriggerData(proc, c)
+			if err != nil {
+				slog.Warn("engine-server: capture skipped by simulation", logging.KeyProcessID, processID, "capture_id", summary.ID, logging.KeyError, err)
+				continue
+			}
+			samples = append(samples, data)
+		}
+		if len(samples) == 0 {
+			jsonError(w, fmt.Sprintf("no captured request of process %q fits its trigger", processID), http.StatusUnprocessableEntity)
+			return
+		}
+	}
+
+	report, err := executor.Simulate(r.Context(), proc, engine.Simulation{
+		Executions: req.Executions,
+		Seed:       req.Seed,
+		Samples:    samples,
+		Schema:     req.Schema,
+		SchemaName: req.SchemaName,
+		Mocks:      req.Mocks,
+	})
+	if err != nil {
+		jsonError(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	jsonOK(w, report)
+}
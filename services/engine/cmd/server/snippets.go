@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"flowjs-works/engine/internal/logging"
+	"flowjs-works/engine/internal/middleware"
+	procstore "flowjs-works/engine/internal/store"
+)
+
+// handleSnippets serves the script snippet library that code nodes import from:
+//
+//	GET    /api/v1/snippets         — list snippets (without source)
+//	POST   /api/v1/snippets         — create or replace {name, description, source}
+//	GET    /api/v1/snippets/{name}  — retrieve a snippet with its source
+//	DELETE /api/v1/snippets/{name}  — delete a snippet
+func handleSnippets(snipStore *procstore.SnippetStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if snipStore == nil {
+			jsonError(w, "snippet store not configured (DATABASE_URL missing)", http.StatusServiceUnavailable)
+			return
+		}
+		name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/snippets"), "/")
+		switch {
+		case r.Method == http.MethodGet && name == "":
+			list, err := snipStore.List(r.Context())
+			if err != nil {
+				slog.Error("engine-server: list snippets", logging.KeyError, err)
+				jsonError(w, middleware.SanitizeError(err, "failed to list snippets"), http.StatusInternalServerError)
+				return
+			}
+			if list == nil {
+				list = []procstore.Snippet{}
+			}
+			jsonOK(w, list)
+		case r.Method == http.MethodPost && name == "":
+			saveSnippet(w, r, snipStore)
+		case r.Method == http.MethodGet:
+			getSnippet(w, r, name, snipStore)
+		case r.Method == http.MethodDelete && name != "":
+			if err := snipStore.Delete(r.Context(), name); err != nil {
+				slog.Error("engine-server: delete snippet", "snippet", name, logging.KeyError, err)
+				jsonError(w, middleware.SanitizeError(err, "failed to delete snippet"), http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// getSnippet writes a single snippet including its source.
+func getSnippet(w http.ResponseWriter, r *http.Request, name string, snipStore *procstore.SnippetStore) {
+	snip, err := snipStore.Get(r.Context(), name)
+	if errors.Is(err, procstore.ErrSnippetNotFound) {
+		jsonError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		slog.Error("engine-server: get snippet", "snippet", name, logging.KeyError, err)
+		jsonError(w, middleware.SanitizeError(err, "failed to get snippet"), http.StatusInternalServerError)
+		return
+	}
+	jsonOK(w, snip)
+}
+
+// saveSnippet validates the request body and upserts the snippet.
+func saveSnippet(w http.ResponseWriter, r *http.Request, snipStore *procstore.SnippetStore) {
+	var snip procstore.Snippet
+	if err := json.NewDecoder(r.Body).Decode(&snip); err != nil {
+		jsonError(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	if !procstore.ValidSnippetName(snip.Name) {
+		jsonError(w, "name must be one or more path segments of alphanumeric characters, hyphens and underscores (e.g. utils/dates)", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(snip.Source) == "" {
+		jsonError(w, "source is required", http.StatusBadRequest)
+		return
+	}
+	saved, err := snipStore.Upsert(r.Context(), &snip)
+	if err != nil {
+		slog.Error("engine-server: save snippet", "snippet", snip.Name, logging.KeyError, err)
+		jsonError(w, middleware.SanitizeError(err, "failed to save snippet"), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(saved)
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"net/http"
+
+	"flowjs-works/engine/internal/schema"
+	procstore "flowjs-works/engine/internal/store"
+	"flowjs-works/engine/internal/triggers"
+)
+
+// handleTriggerOpenAPI serves the OpenAPI document of the REST triggers
+// deployed in this engine, so the consumers of the webhooks always read the
+// paths, parameters and body schemas currently served:
+//
+//	GET /triggers/openapi.json[?workspace=]
+//
+// It shadows a REST trigger deployed at /openapi.json of the default workspace.
+func handleTriggerOpenAPI(schemaStore *procstore.SchemaStore, triggerMgr *triggers.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var schemas schema.Source
+		if schemaStore != nil {
+			schemas = schemaStore
+		}
+		jsonOK(w, triggerMgr.OpenAPI(r.Context(), schemas, r.URL.Query().Get("workspace")))
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"net/http"
+
+	procstore "flowjs-works/engine/internal/store"
+	"flowjs-works/engine/internal/triggers"
+)
+
+// triggerStatusResponse is the body of GET /api/v1/processes/{id}/trigger.
+type triggerStatusResponse struct {
+	ProcessID string `json:"process_id"`
+	Deployed  bool   `json:"deployed"`
+	*triggers.TriggerStatus
+}
+
+// handleTriggerStatus serves GET /api/v1/processes/{id}/trigger: whether the
+// process's trigger runs on this engine and its health, such as a RabbitMQ
+// trigger reconnecting to its broker.
+func handleTriggerStatus(w http.ResponseWriter, r *http.Request, processID string, procStore *procstore.ProcessStore, triggerMgr *triggers.Manager) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	// The trigger manager is keyed by process id only, so verify ownership first.
+	if _, err := procStore.Get(r.Context(), processID); err != nil {
+		jsonError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	resp := triggerStatusResponse{ProcessID: processID}
+	if status, ok := triggerMgr.Status(processID); ok {
+		resp.Deployed = true
+		resp.TriggerStatus = &status
+	}
+	jsonOK(w, resp)
+}
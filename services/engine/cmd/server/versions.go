@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"flowjs-works/engine/internal/logging"
+	"flowjs-works/engine/internal/middleware"
+	"flowjs-works/engine/internal/models"
+	procstore "flowjs-works/engine/internal/store"
+)
+
+// Values of the version query parameter of retries and replays.
+const (
+	versionPinned  = "pinned"
+	versionCurrent = "current"
+)
+
+// rerunProcess returns the definition a retry or replay of executionID runs:
+// the exact one executionID ran, so a redeploy in between cannot break it.
+// ?version=current runs current, the process as deployed now, instead.
+// Executions without a pin (run before pinning, or past
+// EXECUTION_VERSION_RETENTION) run current unless ?version=pinned insists.
+// It writes the error response and returns false when there is nothing to run.
+func rerunProcess(w http.ResponseWriter, r *http.Request, verStore *procstore.VersionStore, executionID string, current *models.Process) (*models.Process, bool) {
+	version := r.URL.Query().Get("version")
+	switch version {
+	case "", versionPinned:
+	case versionCurrent:
+		return current, true
+	default:
+		jsonError(w, fmt.Sprintf("version must be %s or %s", versionPinned, versionCurrent), http.StatusBadRequest)
+		return nil, false
+	}
+	if verStore == nil {
+		return current, true
+	}
+	proc, v, err := verStore.Process(r.Context(), executionID)
+	switch {
+	case errors.Is(err, procstore.ErrVersionNotFound) && version == "":
+		return current, true
+	case errors.Is(err, procstore.ErrVersionNotFound):
+		jsonError(w, fmt.Sprintf("execution %s has no pinned process version", executionID), http.StatusConflict)
+		return nil, false
+	case err != nil:
+		slog.Error("engine-server: load pinned process version", logging.KeyExecutionID, executionID, logging.KeyError, err)
+		jsonError(w, middleware.SanitizeError(err, "failed to load the process version"), http.StatusInternalServerError)
+		return nil, false
+	}
+	if v.ProcessID != current.Definition.ID {
+		jsonError(w, fmt.Sprintf("execution %s belongs to process %q", executionID, v.ProcessID), http.StatusConflict)
+		return nil, false
+	}
+	return proc, true
+}
+
+// executionVersion serves GET /api/v1/executions/{id}/version: the revision
+// and DSL hash executionID is pinned to.
+func executionVersion(w http.ResponseWriter, r *http.Request, verStore *procstore.VersionStore, executionID string) {
+	if verStore == nil {
+		jsonError(w, "version store not configured (DATABASE_URL missing)", http.StatusServiceUnavailable)
+		return
+	}
+	v, err := verStore.Get(r.Context(), executionID)
+	if errors.Is(err, procstore.ErrVersionNotFound) {
+		jsonError(w, "no pinned process version for execution "+executionID, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		slog.Error("engine-server: get execution version", logging.KeyExecutionID, executionID, logging.KeyError, err)
+		jsonError(w, middleware.SanitizeError(err, "failed to load the execution version"), http.StatusInternalServerError)
+		return
+	}
+	jsonOK(w, v)
+}
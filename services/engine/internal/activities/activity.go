@@ -1,6 +1,12 @@
 package activities
 
-import "flowjs-works/engine/internal/models"
+import (
+	"sort"
+	"sync"
+
+	"flowjs-works/engine/internal/models"
+	"flowjs-works/engine/internal/scratch"
+)
 
 // Activity defines the interface that all activity nodes must implement
 type Activity interface {
@@ -11,8 +17,10 @@ type Activity interface {
 	Name() string
 }
 
-// ActivityRegistry manages the available activities
+// ActivityRegistry manages the available activities. It is safe for
+// concurrent use, so activities can be registered while flows run.
 type ActivityRegistry struct {
+	mu         sync.RWMutex
 	activities map[string]Activity
 }
 
@@ -23,38 +31,89 @@ func NewActivityRegistry() *ActivityRegistry {
 	}
 
 	// Register built-in activities
+	httpActivity := NewHTTPActivity()
 	registry.Register(&LoggerActivity{})
-	registry.Register(NewHTTPActivity())
+	registry.Register(httpActivity)
+	// "http_request" is the node type emitted by the Designer palette; it shares
+	// the same client (and connection pool) as "http".
+	registry.RegisterAs("http_request", httpActivity)
 	registry.Register(&LogActivity{})
 	registry.Register(&CodeActivity{})
 	registry.Register(&FileActivity{})
 	registry.Register(&TransformActivity{})
+	registry.Register(&MappingActivity{})
 	registry.Register(&SQLActivity{})
 	registry.Register(&MailActivity{})
 	registry.Register(&RabbitMQActivity{})
+	registry.Register(NewWebSocketSendActivity())
 	registry.Register(&SFTPActivity{})
 	registry.Register(&S3Activity{})
 	registry.Register(&SMBActivity{})
+	registry.Register(NewDedupeActivity(nil))
+	registry.Register(NewBatcherActivity())
+	registry.Register(NewAssertActivity(nil))
+	registry.Register(&FakerActivity{})
+	registry.Register(NewMockHTTPActivity(false))
+	registry.Register(NewCallbackAwaitActivity(""))
 
 	return registry
 }
 
+// ReleaseExecution frees what activities hold for executionID beyond a
+// node run: in-memory files, mock servers and the scratch directory. The
+// executor calls it when an execution ends.
+func ReleaseExecution(executionID string) {
+	ReleaseFileRefs(executionID)
+	ReleaseMockServers(executionID)
+	scratch.Release(executionID)
+}
+
 // Register adds an activity to the registry
 func (r *ActivityRegistry) Register(activity Activity) {
-	r.activities[activity.Name()] = activity
+	r.RegisterAs(activity.Name(), activity)
+}
+
+// RegisterAs adds an activity under an alias name in addition to (or instead of)
+// the name reported by activity.Name().
+func (r *ActivityRegistry) RegisterAs(name string, activity Activity) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.activities[name] = activity
 }
 
 // Get retrieves an activity by name
 func (r *ActivityRegistry) Get(name string) (Activity, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	activity, ok := r.activities[name]
 	return activity, ok
 }
 
-// List returns all registered activity names
+// List returns all registered activity names, sorted
 func (r *ActivityRegistry) List() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	names := make([]string, 0, len(r.activities))
 	for name := range r.activities {
 		names = append(names, name)
 	}
+	sort.Strings(names)
 	return names
 }
+
+// Catalog describes every registered node type, sorted, with the config
+// fields of the activities that implement Configurable.
+func (r *ActivityRegistry) Catalog() []NodeType {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	catalog := make([]NodeType, 0, len(r.activities))
+	for name, activity := range r.activities {
+		nt := NodeType{Type: name}
+		if c, ok := activity.(Configurable); ok {
+			nt.Config = describeConfig(c.ConfigSpec())
+		}
+		catalog = append(catalog, nt)
+	}
+	sort.Slice(catalog, func(i, j int) bool { return catalog[i].Type < catalog[j].Type })
+	return catalog
+}
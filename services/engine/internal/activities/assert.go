@@ -0,0 +1,162 @@
+package activities
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"flowjs-works/engine/internal/models"
+)
+
+// ErrAssertionFailed is the error of an assert node with a false assertion.
+var ErrAssertionFailed = errors.New("assertion failed")
+
+// AssertEvaluator evaluates the expression of an assertion against ctx. It
+// returns whether the assertion holds and the values of the context paths
+// the expression reads, for the report.
+type AssertEvaluator func(expr string, ctx *models.ExecutionContext) (bool, map[string]interface{}, error)
+
+// assertConfig is the config of an assert node.
+type assertConfig struct {
+	Assertions        []interface{} `config:"assertions,required" doc:"condition expressions, or {expression, name, message} objects"`
+	ContinueOnFailure bool          `config:"continue_on_failure" doc:"succeed with passed false instead of failing the node"`
+	assertions        []assertion
+}
+
+// assertion is one entry of assertions.
+type assertion struct {
+	Name       string
+	Expression string
+	Message    string
+}
+
+func (c *assertConfig) validate() []FieldError {
+	c.assertions = make([]assertion, 0, len(c.Assertions))
+	for i, raw := range c.Assertions {
+		var a assertion
+		switch v := raw.(type) {
+		case string:
+			a.Expression = v
+		case map[string]interface{}:
+			a.Expression, _ = v["expression"].(string)
+			a.Name, _ = v["name"].(string)
+			a.Message, _ = v["message"].(string)
+		default:
+			return []FieldError{{"assertions", fmt.Sprintf("item %d must be an expression or an object, got %s", i, configTypeName(raw))}}
+		}
+		if strings.TrimSpace(a.Expression) == "" {
+			return []FieldError{{"assertions", fmt.Sprintf("item %d has no expression", i)}}
+		}
+		if a.Name == "" {
+			a.Name = a.Expression
+		}
+		c.assertions = append(c.assertions, a)
+	}
+	return nil
+}
+
+// AssertActivity implements the `assert` node type. It evaluates a list of
+// condition expressions against the execution context, so monitoring flows
+// (cron → http → assert → alert) can check a response without a code node.
+//
+// config: assertConfig.
+//
+// Output: {passed, total, failed, results}, one result per assertion with
+// its name, expression, passed, the values of the paths it reads and its
+// message or evaluation error. When an assertion fails the node fails with
+// ErrAssertionFailed and keeps the output for error transitions, unless
+// continue_on_failure is set.
+type AssertActivity struct {
+	eval AssertEvaluator
+}
+
+// NewAssertActivity returns an AssertActivity that evaluates expressions with
+// eval. The executor provides the transition condition language; without an
+// evaluator every assert node fails.
+func NewAssertActivity(eval AssertEvaluator) *AssertActivity {
+	return &AssertActivity{eval: eval}
+}
+
+// Name returns the DSL type identifier for this activity.
+func (a *AssertActivity) Name() string { return "assert" }
+
+// ConfigSpec returns the config struct of assert nodes.
+func (a *AssertActivity) ConfigSpec() interface{} { return &assertConfig{} }
+
+// Execute evaluates every assertion, also after one has failed, so the
+// report is complete.
+func (a *AssertActivity) Execute(_ map[string]interface{}, config map[string]interface{}, ctx *models.ExecutionContext) (map[string]interface{}, error) {
+	if a.eval == nil {
+		return nil, fmt.Errorf("assert activity: no expression evaluator configured")
+	}
+	if ctx == nil {
+		return nil, fmt.Errorf("assert activity: execution context is required")
+	}
+	var cfg assertConfig
+	if err := decodeConfig(a.Name(), config, &cfg); err != nil {
+		return nil, err
+	}
+
+	results := make([]interface{}, 0, len(cfg.assertions))
+	var failures []string
+	for _, as := range cfg.assertions {
+		passed, values, err := a.eval(as.Expression, ctx)
+		result := map[string]interface{}{
+			"name":       as.Name,
+			"expression": as.Expression,
+			"passed":     passed && err == nil,
+		}
+		if len(values) > 0 {
+			result["values"] = values
+		}
+		if err != nil {
+			result["error"] = err.Error()
+		}
+		if !passed || err != nil {
+			if as.Message != "" {
+				result["message"] = as.Message
+			}
+			failures = append(failures, assertFailure(as, values, err))
+		}
+		results = append(results, result)
+	}
+
+	output := map[string]interface{}{
+		"passed":  len(failures) == 0,
+		"total":   len(results),
+		"failed":  len(failures),
+		"results": results,
+	}
+	if len(failures) > 0 && !cfg.ContinueOnFailure {
+		return output, fmt.Errorf("assert activity: %w: %d of %d: %s", ErrAssertionFailed, len(failures), len(results), strings.Join(failures, "; "))
+	}
+	return output, nil
+}
+
+// assertFailure describes a failed assertion in the node error: its name or
+// message, and the evaluation error or the values it saw.
+func assertFailure(as assertion, values map[string]interface{}, err error) string {
+	desc := as.Name
+	if as.Message != "" {
+		desc += ": " + as.Message
+	}
+	if err != nil {
+		return desc + " (" + err.Error() + ")"
+	}
+	if len(values) == 0 {
+		return desc
+	}
+	paths := make([]string, 0, len(values))
+	for path := range values {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	seen := make([]string, len(paths))
+	for i, path := range paths {
+		b, _ := json.Marshal(values[path])
+		seen[i] = path + "=" + string(b)
+	}
+	return desc + " (got " + strings.Join(seen, ", ") + ")"
+}
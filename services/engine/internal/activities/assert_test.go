@@ -0,0 +1,67 @@
+package activities
+
+import (
+	"errors"
+	"testing"
+
+	"flowjs-works/engine/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubEvaluator holds the assertions whose expression is a key of results,
+// with the result's value, and fails every other one.
+func stubEvaluator(results map[string]interface{}) AssertEvaluator {
+	return func(expr string, _ *models.ExecutionContext) (bool, map[string]interface{}, error) {
+		v, ok := results[expr]
+		if !ok {
+			return false, nil, errors.New("$.nodes.ping.output.body is undefined")
+		}
+		return v == true, map[string]interface{}{"$.nodes.ping.output.status": v}, nil
+	}
+}
+
+func TestAssertActivity_Report(t *testing.T) {
+	a := NewAssertActivity(stubEvaluator(map[string]interface{}{"ok": true, "slow": float64(503)}))
+	cfg := map[string]interface{}{"assertions": []interface{}{
+		"ok",
+		map[string]interface{}{"name": "status", "expression": "slow", "message": "ping is down"},
+		"missing",
+	}}
+
+	out, err := a.Execute(nil, cfg, models.NewExecutionContext("exec-1"))
+	require.ErrorIs(t, err, ErrAssertionFailed)
+	assert.EqualError(t, err, `assert activity: assertion failed: 2 of 3: status: ping is down (got $.nodes.ping.output.status=503); missing ($.nodes.ping.output.body is undefined)`)
+	assert.Equal(t, false, out["passed"])
+	assert.Equal(t, 3, out["total"])
+	assert.Equal(t, 2, out["failed"])
+	results := out["results"].([]interface{})
+	assert.Equal(t, map[string]interface{}{
+		"name": "ok", "expression": "ok", "passed": true,
+		"values": map[string]interface{}{"$.nodes.ping.output.status": true},
+	}, results[0])
+	assert.Equal(t, "ping is down", results[1].(map[string]interface{})["message"])
+	assert.Equal(t, "$.nodes.ping.output.body is undefined", results[2].(map[string]interface{})["error"])
+
+	cfg["continue_on_failure"] = true
+	out, err = a.Execute(nil, cfg, models.NewExecutionContext("exec-1"))
+	require.NoError(t, err)
+	assert.Equal(t, false, out["passed"])
+}
+
+func TestAssertActivity_InvalidConfig(t *testing.T) {
+	a := NewAssertActivity(stubEvaluator(nil))
+	ctx := models.NewExecutionContext("exec-1")
+	for want, cfg := range map[string]map[string]interface{}{
+		"'assertions' is required":     {},
+		"item 0 has no expression":     {"assertions": []interface{}{map[string]interface{}{"name": "x"}}},
+		"item 1 must be an expression": {"assertions": []interface{}{"ok", float64(1)}},
+	} {
+		_, err := a.Execute(nil, cfg, ctx)
+		assert.ErrorContains(t, err, want)
+	}
+
+	_, err := NewAssertActivity(nil).Execute(nil, map[string]interface{}{"assertions": []interface{}{"true"}}, ctx)
+	assert.ErrorContains(t, err, "no expression evaluator")
+}
@@ -0,0 +1,198 @@
+package activities
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"flowjs-works/engine/internal/models"
+)
+
+// batcherConfig is the config of a batcher node.
+type batcherConfig struct {
+	Key       string `config:"key" doc:"batch key, literal or context JSONPath such as $.trigger.body.tenant"`
+	MaxSize   int    `config:"max_size,default=100" doc:"number of items that releases the batch"`
+	MaxWaitMS int    `config:"max_wait_ms,default=30000" doc:"max time in milliseconds the oldest item waits before release"`
+}
+
+func (c *batcherConfig) validate() []FieldError {
+	var problems []FieldError
+	if c.MaxSize <= 0 {
+		problems = append(problems, FieldError{"max_size", "must be positive"})
+	}
+	if c.MaxWaitMS <= 0 {
+		problems = append(problems, FieldError{"max_wait_ms", "must be positive"})
+	}
+	return problems
+}
+
+// BatchRelease describes a batch released because its max_wait_ms elapsed.
+// Output has the same shape as the node output of a size-triggered release.
+type BatchRelease struct {
+	Workspace string
+	ProcessID string
+	NodeID    string
+	Output    map[string]interface{}
+}
+
+// BatcherActivity implements the `batcher` node type. It accumulates items
+// across executions of the same process and releases them as one array, for
+// downstream APIs that only accept bulk uploads.
+//
+// config: batcherConfig.
+//
+// The node input is the item. When the item completes a batch the output is
+// {released: true, reason: "size", key, count, items} and the execution
+// continues downstream; otherwise it is {released: false, key, pending} and
+// the executor stops following the node's success transitions. Batches
+// released by max_wait_ms are handed to the release handler, which the
+// executor uses to run the downstream nodes in a new execution.
+//
+// Buffers live in process memory: they are not shared between replicas and
+// pending items are released by Flush on graceful shutdown.
+type BatcherActivity struct {
+	mu        sync.Mutex
+	batches   map[string]*pendingBatch
+	onRelease func(BatchRelease)
+}
+
+type pendingBatch struct {
+	workspace string
+	processID string
+	nodeID    string
+	key       string
+	items     []interface{}
+	timer     *time.Timer
+}
+
+// NewBatcherActivity returns a BatcherActivity with no release handler;
+// batches released by max_wait_ms are dropped until SetReleaseHandler is called.
+func NewBatcherActivity() *BatcherActivity {
+	return &BatcherActivity{batches: make(map[string]*pendingBatch)}
+}
+
+// SetReleaseHandler registers fn to receive batches released by max_wait_ms
+// or Flush. fn runs on its own goroutine per release.
+func (a *BatcherActivity) SetReleaseHandler(fn func(BatchRelease)) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.onRelease = fn
+}
+
+// Name returns the DSL type identifier for this activity.
+func (a *BatcherActivity) Name() string { return "batcher" }
+
+// ConfigSpec returns the config struct of batcher nodes.
+func (a *BatcherActivity) ConfigSpec() interface{} { return &batcherConfig{} }
+
+// Execute adds the input to its batch and releases the batch when full.
+func (a *BatcherActivity) Execute(input map[string]interface{}, config map[string]interface{}, ctx *models.ExecutionContext) (map[string]interface{}, error) {
+	if ctx == nil {
+		return nil, fmt.Errorf("batcher activity: execution context is required")
+	}
+	var cfg batcherConfig
+	if err := decodeConfig(a.Name(), config, &cfg); err != nil {
+		return nil, err
+	}
+	key, err := batcherKey(cfg.Key, ctx)
+	if err != nil {
+		return nil, err
+	}
+	nodeID, _ := config["node_id"].(string)
+	maxWait := time.Duration(cfg.MaxWaitMS) * time.Millisecond
+	id := strings.Join([]string{ctx.Workspace, ctx.ProcessID, nodeID, key}, "\x00")
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	b, ok := a.batches[id]
+	if !ok {
+		b = &pendingBatch{workspace: ctx.Workspace, processID: ctx.ProcessID, nodeID: nodeID, key: key}
+		a.batches[id] = b
+		b.timer = time.AfterFunc(maxWait, func() { a.expire(id, b) })
+	}
+	b.items = append(b.items, input)
+	if len(b.items) < cfg.MaxSize {
+		return map[string]interface{}{"released": false, "key": key, "pending": len(b.items)}, nil
+	}
+	b.timer.Stop()
+	delete(a.batches, id)
+	return b.output("size"), nil
+}
+
+// Flush releases every pending batch to the release handler and waits for
+// the handlers to return. Call it on shutdown so buffered items are not lost.
+func (a *BatcherActivity) Flush() {
+	a.mu.Lock()
+	pending := make([]*pendingBatch, 0, len(a.batches))
+	for id, b := range a.batches {
+		b.timer.Stop()
+		delete(a.batches, id)
+		pending = append(pending, b)
+	}
+	onRelease := a.onRelease
+	a.mu.Unlock()
+
+	if onRelease == nil {
+		return
+	}
+	var wg sync.WaitGroup
+	for _, b := range pending {
+		wg.Add(1)
+		go func(b *pendingBatch) {
+			defer wg.Done()
+			onRelease(b.release("flush"))
+		}(b)
+	}
+	wg.Wait()
+}
+
+// expire releases batch b when its max_wait_ms elapses, unless it was
+// already released by size in the meantime.
+func (a *BatcherActivity) expire(id string, b *pendingBatch) {
+	a.mu.Lock()
+	if a.batches[id] != b {
+		a.mu.Unlock()
+		return
+	}
+	delete(a.batches, id)
+	onRelease := a.onRelease
+	a.mu.Unlock()
+
+	if onRelease == nil {
+		return
+	}
+	onRelease(b.release("max_wait"))
+}
+
+func (b *pendingBatch) output(reason string) map[string]interface{} {
+	return map[string]interface{}{
+		"released": true,
+		"reason":   reason,
+		"key":      b.key,
+		"count":    len(b.items),
+		"items":    b.items,
+	}
+}
+
+func (b *pendingBatch) release(reason string) BatchRelease {
+	return BatchRelease{
+		Workspace: b.workspace,
+		ProcessID: b.processID,
+		NodeID:    b.nodeID,
+		Output:    b.output(reason),
+	}
+}
+
+// batcherKey resolves the key config value; "$." values are read from the
+// execution context and stringified.
+func batcherKey(key string, ctx *models.ExecutionContext) (string, error) {
+	if !strings.HasPrefix(key, "$.") {
+		return key, nil
+	}
+	val, err := ctx.GetValue(key)
+	if err != nil {
+		return "", fmt.Errorf("batcher activity: key %q: %w", key, err)
+	}
+	return fmt.Sprint(val), nil
+}
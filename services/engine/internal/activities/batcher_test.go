@@ -0,0 +1,112 @@
+package activities
+
+import (
+	"testing"
+	"time"
+
+	"flowjs-works/engine/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func batcherCtx(processID string) *models.ExecutionContext {
+	ctx := models.NewExecutionContext("exec-1")
+	ctx.ProcessID = processID
+	ctx.SetTriggerData(map[string]interface{}{"body": map[string]interface{}{"tenant": "acme"}})
+	return ctx
+}
+
+func TestBatcherActivity_ReleasesOnMaxSize(t *testing.T) {
+	a := NewBatcherActivity()
+	cfg := map[string]interface{}{"node_id": "batch", "max_size": float64(3), "max_wait_ms": float64(60000)}
+
+	for i := 1; i <= 2; i++ {
+		out, err := a.Execute(map[string]interface{}{"n": i}, cfg, batcherCtx("p1"))
+		require.NoError(t, err)
+		assert.Equal(t, false, out["released"])
+		assert.Equal(t, i, out["pending"])
+	}
+	out, err := a.Execute(map[string]interface{}{"n": 3}, cfg, batcherCtx("p1"))
+	require.NoError(t, err)
+	assert.Equal(t, true, out["released"])
+	assert.Equal(t, "size", out["reason"])
+	assert.Equal(t, 3, out["count"])
+	items := out["items"].([]interface{})
+	assert.Equal(t, map[string]interface{}{"n": 1}, items[0])
+	assert.Equal(t, map[string]interface{}{"n": 3}, items[2])
+
+	// The next item starts a new batch.
+	out, err = a.Execute(map[string]interface{}{"n": 4}, cfg, batcherCtx("p1"))
+	require.NoError(t, err)
+	assert.Equal(t, 1, out["pending"])
+}
+
+func TestBatcherActivity_SeparatesKeysAndProcesses(t *testing.T) {
+	a := NewBatcherActivity()
+	cfg := map[string]interface{}{"node_id": "batch", "max_size": 2, "key": "$.trigger.body.tenant"}
+
+	_, err := a.Execute(map[string]interface{}{}, cfg, batcherCtx("p1"))
+	require.NoError(t, err)
+	out, err := a.Execute(map[string]interface{}{}, cfg, batcherCtx("p2"))
+	require.NoError(t, err)
+	assert.Equal(t, false, out["released"])
+	assert.Equal(t, "acme", out["key"])
+
+	out, err = a.Execute(map[string]interface{}{}, map[string]interface{}{"node_id": "batch", "max_size": 2, "key": "other"}, batcherCtx("p1"))
+	require.NoError(t, err)
+	assert.Equal(t, false, out["released"])
+
+	out, err = a.Execute(map[string]interface{}{}, cfg, batcherCtx("p1"))
+	require.NoError(t, err)
+	assert.Equal(t, true, out["released"])
+}
+
+func TestBatcherActivity_ReleasesOnMaxWait(t *testing.T) {
+	a := NewBatcherActivity()
+	released := make(chan BatchRelease, 1)
+	a.SetReleaseHandler(func(r BatchRelease) { released <- r })
+
+	cfg := map[string]interface{}{"node_id": "batch", "max_size": 10, "max_wait_ms": 20}
+	_, err := a.Execute(map[string]interface{}{"n": 1}, cfg, batcherCtx("p1"))
+	require.NoError(t, err)
+
+	select {
+	case r := <-released:
+		assert.Equal(t, "p1", r.ProcessID)
+		assert.Equal(t, "batch", r.NodeID)
+		assert.Equal(t, "max_wait", r.Output["reason"])
+		assert.Equal(t, 1, r.Output["count"])
+	case <-time.After(2 * time.Second):
+		t.Fatal("batch was not released after max_wait_ms")
+	}
+}
+
+func TestBatcherActivity_FlushReleasesPending(t *testing.T) {
+	a := NewBatcherActivity()
+	released := make(chan BatchRelease, 2)
+	a.SetReleaseHandler(func(r BatchRelease) { released <- r })
+
+	cfg := map[string]interface{}{"node_id": "batch", "max_size": 10}
+	_, err := a.Execute(map[string]interface{}{}, cfg, batcherCtx("p1"))
+	require.NoError(t, err)
+	_, err = a.Execute(map[string]interface{}{}, cfg, batcherCtx("p2"))
+	require.NoError(t, err)
+
+	a.Flush()
+	require.Len(t, released, 2)
+	r := <-released
+	assert.Equal(t, "flush", r.Output["reason"])
+}
+
+func TestBatcherActivity_InvalidSettings(t *testing.T) {
+	a := NewBatcherActivity()
+	for _, cfg := range []map[string]interface{}{
+		{"max_size": float64(0)},
+		{"max_size": "ten"},
+		{"max_wait_ms": float64(-1)},
+	} {
+		_, err := a.Execute(map[string]interface{}{}, cfg, batcherCtx("p1"))
+		assert.Error(t, err, "%v", cfg)
+	}
+}
@@ -0,0 +1,220 @@
+package activities
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"flowjs-works/engine/internal/models"
+)
+
+const (
+	// defaultCallbackTimeout is how long a callback_await node waits when no
+	// timeout is configured.
+	defaultCallbackTimeout = 5 * time.Minute
+	// MaxCallbackTimeout caps await_timeout. A waiting node holds an
+	// execution worker and lives in engine memory, so callbacks are kept
+	// short enough not to starve the queue. Keep it below the audit-logger's
+	// minimum orphan timeout (30m): the wait sends no audit events.
+	MaxCallbackTimeout = 15 * time.Minute
+)
+
+// ErrCallbackTimeout is returned by a callback_await node whose callback did
+// not arrive in time.
+var ErrCallbackTimeout = errors.New("callback timed out")
+
+// ErrUnknownCallback is returned by DeliverCallback for a token that is not
+// awaited: unknown, already delivered or timed out.
+var ErrUnknownCallback = errors.New("callback not awaited")
+
+// Callback is a request received on a callback URL.
+type Callback struct {
+	Method string
+	// Headers and Query hold the first value of each header and parameter.
+	Headers map[string]interface{}
+	Query   map[string]interface{}
+	// Body is the decoded JSON body, or the raw body as a string.
+	Body interface{}
+}
+
+// callbacks holds the channels of the callbacks being awaited, by token.
+var callbacks sync.Map
+
+// DeliverCallback hands cb to the callback_await node waiting on token. A
+// token is delivered at most once.
+func DeliverCallback(token string, cb Callback) error {
+	v, ok := callbacks.LoadAndDelete(token)
+	if !ok {
+		return ErrUnknownCallback
+	}
+	v.(chan Callback) <- cb
+	return nil
+}
+
+// callbackAwaitConfig is the config of a callback_await node: the request
+// announcing the callback, as an http node, and how the callback is awaited.
+type callbackAwaitConfig struct {
+	Method string `config:"method,default=POST"`
+	httpRequestConfig
+	CallbackField string      `config:"callback_field,default=callback_url" doc:"body member set to the callback URL when the body is an object"`
+	AwaitTimeout  interface{} `config:"await_timeout" doc:"Go duration string or number of seconds to wait for the callback (default 5m, at most 15m), capped by the process timeout"`
+
+	// wait is the parsed AwaitTimeout.
+	wait time.Duration
+}
+
+func (c *callbackAwaitConfig) validate() []FieldError {
+	problems := c.httpTransportOptions.validate()
+	var err error
+	if c.wait, err = callbackTimeout(c.AwaitTimeout); err != nil {
+		problems = append(problems, FieldError{"await_timeout", err.Error()})
+	}
+	return problems
+}
+
+// CallbackAwaitActivity implements the `callback_await` node type (config:
+// callbackAwaitConfig): it registers a one-time callback URL, sends it to an
+// external system in an HTTP request and suspends the execution until a
+// request arrives on the URL or the timeout elapses, for third-party jobs
+// finishing within minutes. The waiting execution keeps its queue worker and
+// is not persisted, so it does not survive a restart.
+//
+// The input url, headers and body apply to the request as for an http node.
+// The callback URL is also sent in the X-Callback-Url header. A request that
+// fails or answers 4xx/5xx fails the node. Output: {callback_url, response,
+// callback: {method, headers, query, body}}; on timeout the node fails with
+// ErrCallbackTimeout and outputs {callback_url, response}.
+type CallbackAwaitActivity struct {
+	http    *HTTPActivity
+	baseURL string
+}
+
+// NewCallbackAwaitActivity returns a callback_await activity whose callback
+// URLs start with baseURL, the address the engine is reachable at from the
+// systems called back (e.g. "https://engine.example.com"). With an empty
+// baseURL the node fails.
+func NewCallbackAwaitActivity(baseURL string) *CallbackAwaitActivity {
+	return &CallbackAwaitActivity{http: NewHTTPActivity(), baseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+// Name returns the DSL type identifier for this activity.
+func (a *CallbackAwaitActivity) Name() string { return "callback_await" }
+
+// ConfigSpec returns the config struct of callback_await nodes.
+func (a *CallbackAwaitActivity) ConfigSpec() interface{} { return &callbackAwaitConfig{} }
+
+// Execute sends the callback URL and waits for the callback.
+func (a *CallbackAwaitActivity) Execute(input map[string]interface{}, config map[string]interface{}, ctx *models.ExecutionContext) (map[string]interface{}, error) {
+	if a.baseURL == "" {
+		return nil, fmt.Errorf("callback_await activity: no callback base URL configured (CALLBACK_BASE_URL)")
+	}
+	var cfg callbackAwaitConfig
+	if err := decodeConfig(a.Name(), config, &cfg); err != nil {
+		return nil, err
+	}
+	wait := ctx.Budget(cfg.wait)
+
+	token, err := callbackToken()
+	if err != nil {
+		return nil, fmt.Errorf("callback_await activity: %w", err)
+	}
+	callbackURL := a.baseURL + "/callbacks/" + token
+
+	// Register before sending: the callback may arrive before the response.
+	ch := make(chan Callback, 1)
+	callbacks.Store(token, ch)
+	defer callbacks.Delete(token)
+
+	response, err := a.announce(callbackURL, input, &cfg, ctx)
+	if err != nil {
+		return nil, err
+	}
+	output := map[string]interface{}{"callback_url": callbackURL, "response": response}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case cb := <-ch:
+		output["callback"] = map[string]interface{}{
+			"method":  cb.Method,
+			"headers": cb.Headers,
+			"query":   cb.Query,
+			"body":    cb.Body,
+		}
+		return output, nil
+	case <-timer.C:
+		return output, fmt.Errorf("callback_await activity: %w after %s", ErrCallbackTimeout, wait)
+	}
+}
+
+// announce sends the request carrying callbackURL and returns its output.
+func (a *CallbackAwaitActivity) announce(callbackURL string, input map[string]interface{}, cfg *callbackAwaitConfig, ctx *models.ExecutionContext) (map[string]interface{}, error) {
+	reqInput := make(map[string]interface{}, len(input)+1)
+	for k, v := range input {
+		reqInput[k] = v
+	}
+	if body, ok := input["body"].(map[string]interface{}); ok {
+		withURL := make(map[string]interface{}, len(body)+1)
+		for k, v := range body {
+			withURL[k] = v
+		}
+		withURL[cfg.CallbackField] = callbackURL
+		reqInput["body"] = withURL
+	}
+	headers := map[string]interface{}{}
+	if h, ok := input["headers"].(map[string]interface{}); ok {
+		for k, v := range h {
+			headers[k] = v
+		}
+	}
+	headers["X-Callback-Url"] = callbackURL
+	reqInput["headers"] = headers
+
+	response, err := a.http.send(reqInput, cfg.Method, &cfg.httpRequestConfig, nil, ctx)
+	if err != nil {
+		return nil, fmt.Errorf("callback_await activity: %w", err)
+	}
+	if msg, _ := response["error"].(string); msg != "" {
+		return nil, fmt.Errorf("callback_await activity: request failed: %s", msg)
+	}
+	if status, _ := response["status_code"].(int); status >= 400 {
+		return nil, fmt.Errorf("callback_await activity: request failed with status %d", status)
+	}
+	return response, nil
+}
+
+// callbackTimeout parses the await_timeout config value.
+func callbackTimeout(raw interface{}) (time.Duration, error) {
+	var d time.Duration
+	switch v := raw.(type) {
+	case nil:
+		return defaultCallbackTimeout, nil
+	case float64:
+		d = time.Duration(v * float64(time.Second))
+	case int:
+		d = time.Duration(v) * time.Second
+	case string:
+		d, _ = time.ParseDuration(v)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("must be a positive duration (e.g. \"10m\") or number of seconds, got %v", raw)
+	}
+	if d > MaxCallbackTimeout {
+		return 0, fmt.Errorf("exceeds the maximum of %s: %s", MaxCallbackTimeout, d)
+	}
+	return d, nil
+}
+
+// callbackToken returns a random token that cannot be guessed: it is the
+// only thing authorising a callback.
+func callbackToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,94 @@
+package activities
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCallbackAwaitActivity_Resumes verifies that the callback URL is sent in
+// the body and header of the request and that the node outputs the callback
+// delivered on it, even one arriving before the response.
+func TestCallbackAwaitActivity_Resumes(t *testing.T) {
+	var gotHeader string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		gotHeader = r.Header.Get("X-Callback-Url")
+		assert.Equal(t, "nightly", body["job"])
+		token := strings.TrimPrefix(body["notify"].(string), "https://engine.example.com/callbacks/")
+		require.NoError(t, DeliverCallback(token, Callback{Method: "POST", Body: map[string]interface{}{"state": "done"}}))
+		assert.ErrorIs(t, DeliverCallback(token, Callback{}), ErrUnknownCallback, "a token is delivered once")
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	a := NewCallbackAwaitActivity("https://engine.example.com/")
+	out, err := a.Execute(
+		map[string]interface{}{"body": map[string]interface{}{"job": "nightly"}},
+		map[string]interface{}{"url": srv.URL, "callback_field": "notify"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, gotHeader, out["callback_url"])
+	assert.Equal(t, http.StatusAccepted, out["response"].(map[string]interface{})["status_code"])
+	assert.Equal(t, map[string]interface{}{"state": "done"}, out["callback"].(map[string]interface{})["body"])
+}
+
+// TestCallbackAwaitActivity_Timeout verifies that the node fails when no
+// callback arrives and that its URL is no longer awaited afterwards.
+func TestCallbackAwaitActivity_Timeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	out, err := NewCallbackAwaitActivity("http://engine").Execute(nil,
+		map[string]interface{}{"url": srv.URL, "await_timeout": "20ms"}, nil)
+	require.ErrorIs(t, err, ErrCallbackTimeout)
+	token := strings.TrimPrefix(out["callback_url"].(string), "http://engine/callbacks/")
+	assert.ErrorIs(t, DeliverCallback(token, Callback{}), ErrUnknownCallback)
+}
+
+func TestCallbackAwaitActivity_Errors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	_, err := NewCallbackAwaitActivity("").Execute(nil, map[string]interface{}{"url": srv.URL}, nil)
+	assert.ErrorContains(t, err, "CALLBACK_BASE_URL")
+	_, err = NewCallbackAwaitActivity("http://engine").Execute(nil, map[string]interface{}{"url": srv.URL}, nil)
+	assert.ErrorContains(t, err, "status 400")
+	_, err = NewCallbackAwaitActivity("http://engine").Execute(nil, map[string]interface{}{"url": srv.URL, "await_timeout": "soon"}, nil)
+	assert.ErrorContains(t, err, "await_timeout")
+}
+
+func TestCallbackTimeout(t *testing.T) {
+	tests := []struct {
+		raw     interface{}
+		want    time.Duration
+		wantErr string
+	}{
+		{raw: nil, want: defaultCallbackTimeout},
+		{raw: "10m", want: 10 * time.Minute},
+		{raw: float64(90), want: 90 * time.Second},
+		{raw: 30, want: 30 * time.Second},
+		{raw: "15m", want: MaxCallbackTimeout},
+		{raw: "1h", wantErr: "exceeds the maximum of 15m0s"},
+		{raw: float64(3600), wantErr: "exceeds the maximum"},
+		{raw: "-1m", wantErr: "positive duration"},
+		{raw: true, wantErr: "positive duration"},
+	}
+	for _, tt := range tests {
+		got, err := callbackTimeout(tt.raw)
+		if tt.wantErr != "" {
+			assert.ErrorContains(t, err, tt.wantErr, "raw=%v", tt.raw)
+			continue
+		}
+		require.NoError(t, err, "raw=%v", tt.raw)
+		assert.Equal(t, tt.want, got, "raw=%v", tt.raw)
+	}
+}
@@ -0,0 +1,416 @@
+package activities
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Activities decode their node config into a typed struct with decodeConfig
+// instead of reading the map field by field. Each struct field names its
+// config key in a `config` tag, followed by options:
+//
+//	Server string        `config:"server,required"`         must be set and not empty
+//	Port   int           `config:"port,default=22"`          value when the key is absent or ""
+//	Method string        `config:"method,enum=get|put"`      one of the listed values
+//	Wait   time.Duration `config:"wait,default=30s"`         a Go duration string
+//
+// and may describe itself in a `doc` tag for the node catalog. Supported
+// field types are string, bool, int, int64, float64, time.Duration,
+// []string, []interface{}, map[string]interface{} and interface{} (the raw
+// value); embedded structs share their fields. Keys without a field are left
+// to the activity, which still receives the config map. Checks the tags
+// cannot express go in a validate method (see configValidator).
+
+// ConfigError is the error of a node config that does not decode, listing
+// every field at fault.
+type ConfigError struct {
+	// Activity is the node type, e.g. "sftp".
+	Activity string
+	Fields   []FieldError
+}
+
+// FieldError is one invalid config field.
+type FieldError struct {
+	Field   string `json:"field"`
+	Problem string `json:"problem"`
+}
+
+func (e *ConfigError) Error() string {
+	problems := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		problems[i] = fmt.Sprintf("config field '%s' %s", f.Field, f.Problem)
+	}
+	return e.Activity + " activity: " + strings.Join(problems, "; ")
+}
+
+// ConfigField describes one config field of a node type in the catalog.
+type ConfigField struct {
+	Name string `json:"name"`
+	// Type is string, boolean, integer, number, duration, string[], array,
+	// object or any.
+	Type     string      `json:"type"`
+	Required bool        `json:"required,omitempty"`
+	Default  interface{} `json:"default,omitempty"`
+	Enum     []string    `json:"enum,omitempty"`
+	Doc      string      `json:"doc,omitempty"`
+}
+
+// NodeType is a registered node type with the config fields it decodes.
+// Config is empty for activities without a typed config.
+type NodeType struct {
+	Type   string        `json:"type"`
+	Config []ConfigField `json:"config,omitempty"`
+}
+
+// Configurable is implemented by activities that decode their config with
+// decodeConfig. ConfigSpec returns a pointer to a zero config struct, from
+// which the catalog describes the node type.
+type Configurable interface {
+	ConfigSpec() interface{}
+}
+
+// configValidator is implemented by config structs with checks beyond their
+// tags, such as compiling a pattern. decodeConfig calls validate once the
+// fields decoded without error.
+type configValidator interface {
+	validate() []FieldError
+}
+
+// configTag is a parsed `config` struct tag.
+type configTag struct {
+	name     string
+	required bool
+	def      string
+	hasDef   bool
+	enum     []string
+}
+
+func parseConfigTag(tag string) configTag {
+	parts := strings.Split(tag, ",")
+	t := configTag{name: parts[0]}
+	for _, opt := range parts[1:] {
+		switch {
+		case opt == "required":
+			t.required = true
+		case strings.HasPrefix(opt, "default="):
+			t.def, t.hasDef = strings.TrimPrefix(opt, "default="), true
+		case strings.HasPrefix(opt, "enum="):
+			t.enum = strings.Split(strings.TrimPrefix(opt, "enum="), "|")
+		}
+	}
+	return t
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// decodeConfig fills out, a pointer to a config struct, from config and
+// returns a *ConfigError naming every missing, mistyped or out-of-enum
+// field of the activity.
+func decodeConfig(activity string, config map[string]interface{}, out interface{}) error {
+	cerr := &ConfigError{Activity: activity}
+	decodeStruct(reflect.ValueOf(out).Elem(), config, cerr)
+	if v, ok := out.(configValidator); ok && len(cerr.Fields) == 0 {
+		cerr.Fields = v.validate()
+	}
+	if len(cerr.Fields) > 0 {
+		return cerr
+	}
+	return nil
+}
+
+func decodeStruct(v reflect.Value, config map[string]interface{}, cerr *ConfigError) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+			decodeStruct(v.Field(i), config, cerr)
+			continue
+		}
+		tag, ok := sf.Tag.Lookup("config")
+		if !ok {
+			continue
+		}
+		ct := parseConfigTag(tag)
+		raw, present := config[ct.name]
+		if s, isString := raw.(string); raw == nil || (isString && s == "" && sf.Type.Kind() != reflect.Interface) {
+			present = false
+		}
+		if !present {
+			switch {
+			case ct.required:
+				cerr.Fields = append(cerr.Fields, FieldError{ct.name, "is required"})
+			case ct.hasDef:
+				if problem := setConfigDefault(v.Field(i), ct.def); problem != "" {
+					cerr.Fields = append(cerr.Fields, FieldError{ct.name, problem})
+				}
+			}
+			continue
+		}
+		if problem := setConfigValue(v.Field(i), raw); problem != "" {
+			cerr.Fields = append(cerr.Fields, FieldError{ct.name, problem})
+			continue
+		}
+		if ct.required && isEmptyConfigValue(v.Field(i)) {
+			cerr.Fields = append(cerr.Fields, FieldError{ct.name, "is required"})
+			continue
+		}
+		if len(ct.enum) > 0 && v.Field(i).Kind() == reflect.String {
+			if s := v.Field(i).String(); !containsString(ct.enum, s) {
+				cerr.Fields = append(cerr.Fields, FieldError{ct.name, fmt.Sprintf("must be one of %s, got %q", strings.Join(ct.enum, ", "), s)})
+			}
+		}
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func isEmptyConfigValue(f reflect.Value) bool {
+	switch f.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map:
+		return f.Len() == 0
+	}
+	return false
+}
+
+// setConfigValue stores raw, a JSON-decoded config value, in f and returns
+// the problem when its type does not fit.
+func setConfigValue(f reflect.Value, raw interface{}) string {
+	switch {
+	case f.Type() == durationType:
+		s, ok := raw.(string)
+		if !ok {
+			return fmt.Sprintf("must be a duration string such as \"30s\", got %s", configTypeName(raw))
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Sprintf("must be a duration such as \"30s\", got %q", s)
+		}
+		f.SetInt(int64(d))
+		return ""
+	case f.Kind() == reflect.Interface:
+		f.Set(reflect.ValueOf(&raw).Elem())
+		return ""
+	}
+	switch f.Kind() {
+	case reflect.String:
+		s, ok := raw.(string)
+		if !ok {
+			return "must be a string, got " + configTypeName(raw)
+		}
+		f.SetString(s)
+	case reflect.Bool:
+		b, ok := raw.(bool)
+		if !ok {
+			return "must be a boolean, got " + configTypeName(raw)
+		}
+		f.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, ok := configInt(raw)
+		if !ok {
+			return "must be an integer, got " + configTypeName(raw)
+		}
+		f.SetInt(n)
+	case reflect.Float64:
+		x, ok := configFloat(raw)
+		if !ok {
+			return "must be a number, got " + configTypeName(raw)
+		}
+		f.SetFloat(x)
+	case reflect.Slice:
+		return setConfigSlice(f, raw)
+	case reflect.Map:
+		m, ok := raw.(map[string]interface{})
+		if !ok {
+			return "must be an object, got " + configTypeName(raw)
+		}
+		f.Set(reflect.ValueOf(m))
+	default:
+		return "has an unsupported field type " + f.Type().String()
+	}
+	return ""
+}
+
+func setConfigSlice(f reflect.Value, raw interface{}) string {
+	if f.Type().Elem().Kind() == reflect.Interface {
+		list, ok := raw.([]interface{})
+		if !ok {
+			return "must be an array, got " + configTypeName(raw)
+		}
+		f.Set(reflect.ValueOf(list))
+		return ""
+	}
+	switch list := raw.(type) {
+	case []string:
+		f.Set(reflect.ValueOf(append([]string(nil), list...)))
+	case []interface{}:
+		out := make([]string, len(list))
+		for i, item := range list {
+			s, ok := item.(string)
+			if !ok {
+				return fmt.Sprintf("must be an array of strings, got %s at [%d]", configTypeName(item), i)
+			}
+			out[i] = s
+		}
+		f.Set(reflect.ValueOf(out))
+	default:
+		return "must be an array of strings, got " + configTypeName(raw)
+	}
+	return ""
+}
+
+// setConfigDefault stores the default of a config tag in f.
+func setConfigDefault(f reflect.Value, def string) string {
+	switch {
+	case f.Type() == durationType:
+		d, err := time.ParseDuration(def)
+		if err != nil {
+			return "has an invalid default " + def
+		}
+		f.SetInt(int64(d))
+		return ""
+	}
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(def)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(def)
+		if err != nil {
+			return "has an invalid default " + def
+		}
+		f.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(def, 10, 64)
+		if err != nil {
+			return "has an invalid default " + def
+		}
+		f.SetInt(n)
+	case reflect.Float64:
+		x, err := strconv.ParseFloat(def, 64)
+		if err != nil {
+			return "has an invalid default " + def
+		}
+		f.SetFloat(x)
+	default:
+		return "cannot have a default"
+	}
+	return ""
+}
+
+func configInt(raw interface{}) (int64, bool) {
+	switch n := raw.(type) {
+	case int:
+		return int64(n), true
+	case int64:
+		return n, true
+	case float64:
+		if n != math.Trunc(n) || math.IsInf(n, 0) {
+			return 0, false
+		}
+		return int64(n), true
+	case json.Number:
+		i, err := n.Int64()
+		return i, err == nil
+	}
+	return 0, false
+}
+
+func configFloat(raw interface{}) (float64, bool) {
+	switch n := raw.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case json.Number:
+		x, err := n.Float64()
+		return x, err == nil
+	}
+	return 0, false
+}
+
+// configTypeName names the JSON type of a config value in errors.
+func configTypeName(raw interface{}) string {
+	switch raw.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case int, int64, float64, json.Number:
+		return "number"
+	case []interface{}, []string:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", raw)
+}
+
+// describeConfig lists the fields of the config struct spec points to.
+func describeConfig(spec interface{}) []ConfigField {
+	return describeStruct(reflect.TypeOf(spec).Elem())
+}
+
+func describeStruct(t reflect.Type) []ConfigField {
+	var fields []ConfigField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+			fields = append(fields, describeStruct(sf.Type)...)
+			continue
+		}
+		tag, ok := sf.Tag.Lookup("config")
+		if !ok {
+			continue
+		}
+		ct := parseConfigTag(tag)
+		field := ConfigField{Name: ct.name, Type: configFieldType(sf.Type), Required: ct.required, Enum: ct.enum, Doc: sf.Tag.Get("doc")}
+		if ct.hasDef {
+			def := reflect.New(sf.Type).Elem()
+			if setConfigDefault(def, ct.def) == "" {
+				field.Default = def.Interface()
+				if sf.Type == durationType {
+					field.Default = ct.def
+				}
+			}
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+func configFieldType(t reflect.Type) string {
+	if t == durationType {
+		return "duration"
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int64:
+		return "integer"
+	case reflect.Float64:
+		return "number"
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.String {
+			return "string[]"
+		}
+		return "array"
+	case reflect.Map:
+		return "object"
+	}
+	return "any"
+}
@@ -0,0 +1,123 @@
+package activities
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testEmbeddedConfig struct {
+	Tags []string `config:"tags"`
+}
+
+type testConfig struct {
+	Name    string                 `config:"name,required" doc:"the name"`
+	Mode    string                 `config:"mode,default=fast,enum=fast|slow"`
+	Retries int                    `config:"retries,default=3"`
+	Ratio   float64                `config:"ratio"`
+	Enabled bool                   `config:"enabled,default=true"`
+	Wait    time.Duration          `config:"wait,default=30s"`
+	Extra   map[string]interface{} `config:"extra"`
+	Raw     interface{}            `config:"raw"`
+	ignored string
+	testEmbeddedConfig
+}
+
+func (c *testConfig) validate() []FieldError {
+	if c.Retries > 10 {
+		return []FieldError{{"retries", "must be at most 10"}}
+	}
+	return nil
+}
+
+func TestDecodeConfig(t *testing.T) {
+	var cfg testConfig
+	err := decodeConfig("test", map[string]interface{}{
+		"name":    "job",
+		"ratio":   float64(2),
+		"wait":    "",
+		"extra":   map[string]interface{}{"a": 1},
+		"raw":     "",
+		"tags":    []interface{}{"x", "y"},
+		"unknown": true,
+	}, &cfg)
+	require.NoError(t, err)
+	assert.Equal(t, testConfig{
+		Name:               "job",
+		Mode:               "fast",
+		Retries:            3,
+		Ratio:              2,
+		Enabled:            true,
+		Wait:               30 * time.Second,
+		Extra:              map[string]interface{}{"a": 1},
+		Raw:                "",
+		testEmbeddedConfig: testEmbeddedConfig{Tags: []string{"x", "y"}},
+	}, cfg)
+
+	cfg = testConfig{}
+	require.NoError(t, decodeConfig("test", map[string]interface{}{
+		"name": "job", "mode": "slow", "retries": float64(0), "enabled": false, "wait": "1m",
+	}, &cfg))
+	assert.Equal(t, "slow", cfg.Mode)
+	assert.Equal(t, 0, cfg.Retries)
+	assert.False(t, cfg.Enabled)
+	assert.Equal(t, time.Minute, cfg.Wait)
+}
+
+func TestDecodeConfig_Errors(t *testing.T) {
+	var cfg testConfig
+	err := decodeConfig("test", map[string]interface{}{
+		"mode":    "medium",
+		"retries": 1.5,
+		"enabled": "yes",
+		"wait":    "soon",
+		"tags":    []interface{}{"x", 2},
+	}, &cfg)
+	var cerr *ConfigError
+	require.ErrorAs(t, err, &cerr)
+	assert.Equal(t, []FieldError{
+		{"name", "is required"},
+		{"mode", `must be one of fast, slow, got "medium"`},
+		{"retries", "must be an integer, got number"},
+		{"enabled", "must be a boolean, got string"},
+		{"wait", `must be a duration such as "30s", got "soon"`},
+		{"tags", "must be an array of strings, got number at [1]"},
+	}, cerr.Fields)
+	assert.Equal(t, `test activity: config field 'name' is required; config field 'mode' must be one of fast, slow, got "medium"; `+
+		`config field 'retries' must be an integer, got number; config field 'enabled' must be a boolean, got string; `+
+		`config field 'wait' must be a duration such as "30s", got "soon"; config field 'tags' must be an array of strings, got number at [1]`,
+		err.Error())
+
+	// validate runs once the fields decode.
+	err = decodeConfig("test", map[string]interface{}{"name": "job", "retries": 11}, &testConfig{})
+	assert.EqualError(t, err, "test activity: config field 'retries' must be at most 10")
+}
+
+func TestDescribeConfig(t *testing.T) {
+	assert.Equal(t, []ConfigField{
+		{Name: "name", Type: "string", Required: true, Doc: "the name"},
+		{Name: "mode", Type: "string", Default: "fast", Enum: []string{"fast", "slow"}},
+		{Name: "retries", Type: "integer", Default: 3},
+		{Name: "ratio", Type: "number"},
+		{Name: "enabled", Type: "boolean", Default: true},
+		{Name: "wait", Type: "duration", Default: "30s"},
+		{Name: "extra", Type: "object"},
+		{Name: "raw", Type: "any"},
+		{Name: "tags", Type: "string[]"},
+	}, describeConfig(&testConfig{}))
+}
+
+func TestActivityRegistry_Catalog(t *testing.T) {
+	types := make(map[string]NodeType)
+	for _, nt := range NewActivityRegistry().Catalog() {
+		types[nt.Type] = nt
+	}
+	assert.Equal(t, []ConfigField{{Name: "mappings", Type: "array", Doc: "{target, source, sources, value, default, type, function, separator} rules, applied in order"}}, types["mapping"].Config)
+	assert.Equal(t, types["http"].Config, types["http_request"].Config, "an alias lists the fields of its activity")
+	assert.Contains(t, types["http"].Config, ConfigField{Name: "method", Type: "string", Default: "GET"})
+	assert.Contains(t, types["sql"].Config, ConfigField{Name: "timeout", Type: "integer", Default: 30, Doc: "query timeout in seconds"})
+	require.NotEmpty(t, types["transform"].Config)
+	assert.Equal(t, ConfigField{Name: "transform_type", Type: "string", Required: true, Enum: []string{"json2csv", "xml2json", "json2xml"}}, types["transform"].Config[0])
+}
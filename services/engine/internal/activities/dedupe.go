@@ -0,0 +1,218 @@
+package activities
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"flowjs-works/engine/internal/models"
+	"flowjs-works/engine/internal/tenant"
+)
+
+const (
+	// defaultDedupeTTL is how long a hash is remembered when no ttl is configured.
+	defaultDedupeTTL = 24 * time.Hour
+	// dedupeStoreTimeout bounds a single DedupeStore round-trip.
+	dedupeStoreTimeout = 5 * time.Second
+)
+
+// DedupeStore records content hashes for the dedupe node. Seen atomically
+// records hash under scope for ttl and reports whether an unexpired record
+// already existed, together with the time the hash was first recorded.
+// store.DedupeStore implements it on the config DB.
+type DedupeStore interface {
+	Seen(ctx context.Context, scope, hash string, ttl time.Duration) (duplicate bool, firstSeen time.Time, err error)
+}
+
+// dedupeConfig is the config of a dedupe node.
+type dedupeConfig struct {
+	Fields []string `config:"fields" doc:"input keys (dotted, e.g. body.event_id) or context JSONPaths to hash; default the whole input"`
+	// TTL is a Go duration string or a number of seconds.
+	TTL   interface{} `config:"ttl" doc:"Go duration string (e.g. 24h) or number of seconds; default 24h"`
+	Scope string      `config:"scope" doc:"namespace for hashes; default the process id"`
+	ttl   time.Duration
+}
+
+func (c *dedupeConfig) validate() []FieldError {
+	ttl, err := dedupeTTL(c.TTL)
+	if err != nil {
+		return []FieldError{{"ttl", fmt.Sprintf("must be a positive duration (e.g. \"24h\") or number of seconds, got %v", c.TTL)}}
+	}
+	c.ttl = ttl
+	return nil
+}
+
+// DedupeActivity implements the `dedupe` node type. It hashes configured
+// fields of its input and reports whether the same content was already seen
+// within the TTL window, so replayed webhooks are not processed twice.
+//
+// config: dedupeConfig.
+//
+// Output: {duplicate, hash, first_seen}. When duplicate is true the executor
+// does not follow success transitions; condition transitions on
+// $.nodes.<id>.output.duplicate can route duplicates to a dedicated branch.
+type DedupeActivity struct {
+	store DedupeStore
+}
+
+// NewDedupeActivity returns a DedupeActivity backed by store. A nil store
+// keeps hashes in process memory.
+func NewDedupeActivity(store DedupeStore) *DedupeActivity {
+	if store == nil {
+		store = newMemoryDedupeStore()
+	}
+	return &DedupeActivity{store: store}
+}
+
+// Name returns the DSL type identifier for this activity.
+func (a *DedupeActivity) Name() string { return "dedupe" }
+
+// ConfigSpec returns the config struct of dedupe nodes.
+func (a *DedupeActivity) ConfigSpec() interface{} { return &dedupeConfig{} }
+
+// Execute computes the content hash and checks it against the store.
+func (a *DedupeActivity) Execute(input map[string]interface{}, config map[string]interface{}, ctx *models.ExecutionContext) (map[string]interface{}, error) {
+	var cfg dedupeConfig
+	if err := decodeConfig(a.Name(), config, &cfg); err != nil {
+		return nil, err
+	}
+	hash, err := dedupeHash(input, cfg.Fields, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	scope := cfg.Scope
+	workspace := ""
+	if ctx != nil {
+		workspace = ctx.Workspace
+		if scope == "" {
+			scope = ctx.ProcessID
+		}
+	}
+
+	storeCtx, cancel := context.WithTimeout(tenant.WithWorkspace(context.Background(), workspace), dedupeStoreTimeout)
+	defer cancel()
+	duplicate, firstSeen, err := a.store.Seen(storeCtx, scope, hash, cfg.ttl)
+	if err != nil {
+		return nil, fmt.Errorf("dedupe activity: %w", err)
+	}
+	return map[string]interface{}{
+		"duplicate":  duplicate,
+		"hash":       hash,
+		"first_seen": firstSeen.UTC().Format(time.RFC3339),
+	}, nil
+}
+
+// dedupeTTL parses the ttl config value.
+func dedupeTTL(raw interface{}) (time.Duration, error) {
+	switch v := raw.(type) {
+	case nil:
+		return defaultDedupeTTL, nil
+	case float64:
+		if v > 0 {
+			return time.Duration(v * float64(time.Second)), nil
+		}
+	case int:
+		if v > 0 {
+			return time.Duration(v) * time.Second, nil
+		}
+	case string:
+		d, err := time.ParseDuration(v)
+		if err == nil && d > 0 {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("dedupe activity: ttl must be a positive duration (e.g. \"24h\") or number of seconds, got %v", raw)
+}
+
+// dedupeHash returns the hex SHA-256 of the canonical JSON of the selected
+// fields (or of the whole input when no fields are configured). Map keys are
+// sorted by encoding/json, so key order in the payload does not matter.
+func dedupeHash(input map[string]interface{}, fields []string, ctx *models.ExecutionContext) (string, error) {
+	var subject interface{} = input
+	if len(fields) > 0 {
+		selected := make(map[string]interface{}, len(fields))
+		for _, field := range fields {
+			if field == "" {
+				return "", fmt.Errorf("dedupe activity: fields must be non-empty strings")
+			}
+			val, err := dedupeField(input, field, ctx)
+			if err != nil {
+				return "", err
+			}
+			selected[field] = val
+		}
+		subject = selected
+	}
+	data, err := json.Marshal(subject)
+	if err != nil {
+		return "", fmt.Errorf("dedupe activity: hash input: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// dedupeField resolves one configured field. A missing field is an error:
+// hashing it as null would make unrelated events collide.
+func dedupeField(input map[string]interface{}, field string, ctx *models.ExecutionContext) (interface{}, error) {
+	if strings.HasPrefix(field, "$.") {
+		if ctx == nil {
+			return nil, fmt.Errorf("dedupe activity: field %q needs an execution context", field)
+		}
+		val, err := ctx.GetValue(field)
+		if err != nil {
+			return nil, fmt.Errorf("dedupe activity: field %q: %w", field, err)
+		}
+		return val, nil
+	}
+	var current interface{} = input
+	for _, part := range strings.Split(field, ".") {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("dedupe activity: field %q not found in input", field)
+		}
+		if current, ok = m[part]; !ok {
+			return nil, fmt.Errorf("dedupe activity: field %q not found in input", field)
+		}
+	}
+	return current, nil
+}
+
+// memoryDedupeStore is the DedupeStore used when no database is configured.
+// Hashes do not survive restarts and are not shared between replicas.
+type memoryDedupeStore struct {
+	mu      sync.Mutex
+	entries map[string]memoryDedupeEntry
+}
+
+type memoryDedupeEntry struct {
+	firstSeen time.Time
+	expiresAt time.Time
+}
+
+func newMemoryDedupeStore() *memoryDedupeStore {
+	return &memoryDedupeStore{entries: make(map[string]memoryDedupeEntry)}
+}
+
+// Seen implements DedupeStore. Expired entries are dropped as they are found.
+func (m *memoryDedupeStore) Seen(ctx context.Context, scope, hash string, ttl time.Duration) (bool, time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	key := tenant.Workspace(ctx) + "\x00" + scope + "\x00" + hash
+	if e, ok := m.entries[key]; ok && now.Before(e.expiresAt) {
+		return true, e.firstSeen, nil
+	}
+	for k, e := range m.entries {
+		if !now.Before(e.expiresAt) {
+			delete(m.entries, k)
+		}
+	}
+	m.entries[key] = memoryDedupeEntry{firstSeen: now, expiresAt: now.Add(ttl)}
+	return false, now, nil
+}
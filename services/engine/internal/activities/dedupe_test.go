@@ -0,0 +1,111 @@
+package activities
+
+import (
+	"testing"
+	"time"
+
+	"flowjs-works/engine/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func dedupeCtx(processID, workspace string) *models.ExecutionContext {
+	ctx := models.NewExecutionContext("exec-1")
+	ctx.ProcessID = processID
+	ctx.Workspace = workspace
+	ctx.SetTriggerData(map[string]interface{}{"headers": map[string]interface{}{"X-Event-Id": "evt-1"}})
+	return ctx
+}
+
+func TestDedupeActivity_DetectsDuplicateByFields(t *testing.T) {
+	a := NewDedupeActivity(nil)
+	cfg := map[string]interface{}{"fields": []interface{}{"body.event_id"}, "ttl": "1h"}
+
+	first, err := a.Execute(map[string]interface{}{"body": map[string]interface{}{"event_id": "e1", "attempt": 1}}, cfg, dedupeCtx("p1", ""))
+	require.NoError(t, err)
+	assert.Equal(t, false, first["duplicate"])
+	assert.Len(t, first["hash"], 64)
+
+	// Other fields differ, but the hashed field is the same.
+	second, err := a.Execute(map[string]interface{}{"body": map[string]interface{}{"event_id": "e1", "attempt": 2}}, cfg, dedupeCtx("p1", ""))
+	require.NoError(t, err)
+	assert.Equal(t, true, second["duplicate"])
+	assert.Equal(t, first["hash"], second["hash"])
+	assert.Equal(t, first["first_seen"], second["first_seen"])
+
+	third, err := a.Execute(map[string]interface{}{"body": map[string]interface{}{"event_id": "e2"}}, cfg, dedupeCtx("p1", ""))
+	require.NoError(t, err)
+	assert.Equal(t, false, third["duplicate"])
+}
+
+func TestDedupeActivity_ScopedByProcessAndWorkspace(t *testing.T) {
+	a := NewDedupeActivity(nil)
+	input := map[string]interface{}{"id": 1}
+
+	_, err := a.Execute(input, nil, dedupeCtx("p1", "team-a"))
+	require.NoError(t, err)
+	for _, ctx := range []*models.ExecutionContext{dedupeCtx("p2", "team-a"), dedupeCtx("p1", "team-b")} {
+		out, err := a.Execute(input, nil, ctx)
+		require.NoError(t, err)
+		assert.Equal(t, false, out["duplicate"])
+	}
+
+	// An explicit scope is shared across processes.
+	cfg := map[string]interface{}{"scope": "orders"}
+	_, err = a.Execute(input, cfg, dedupeCtx("p1", "team-a"))
+	require.NoError(t, err)
+	out, err := a.Execute(input, cfg, dedupeCtx("p2", "team-a"))
+	require.NoError(t, err)
+	assert.Equal(t, true, out["duplicate"])
+}
+
+func TestDedupeActivity_ContextPathField(t *testing.T) {
+	a := NewDedupeActivity(nil)
+	cfg := map[string]interface{}{"fields": []interface{}{"$.trigger.headers.X-Event-Id"}}
+	_, err := a.Execute(map[string]interface{}{"n": 1}, cfg, dedupeCtx("p1", ""))
+	require.NoError(t, err)
+	out, err := a.Execute(map[string]interface{}{"n": 2}, cfg, dedupeCtx("p1", ""))
+	require.NoError(t, err)
+	assert.Equal(t, true, out["duplicate"])
+}
+
+func TestDedupeActivity_MissingFieldFails(t *testing.T) {
+	_, err := NewDedupeActivity(nil).Execute(map[string]interface{}{"body": map[string]interface{}{}},
+		map[string]interface{}{"fields": []interface{}{"body.event_id"}}, dedupeCtx("p1", ""))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "body.event_id")
+}
+
+func TestDedupeTTL(t *testing.T) {
+	cases := map[interface{}]time.Duration{nil: defaultDedupeTTL, "90m": 90 * time.Minute, float64(30): 30 * time.Second, 5: 5 * time.Second}
+	for raw, want := range cases {
+		got, err := dedupeTTL(raw)
+		require.NoError(t, err, "%v", raw)
+		assert.Equal(t, want, got)
+	}
+	for _, raw := range []interface{}{"soon", "-1h", float64(0), true} {
+		_, err := dedupeTTL(raw)
+		assert.Error(t, err, "%v", raw)
+	}
+}
+
+func TestDedupeHash_KeyOrderIndependent(t *testing.T) {
+	a, err := dedupeHash(map[string]interface{}{"x": 1, "y": map[string]interface{}{"b": 2, "a": 1}}, nil, nil)
+	require.NoError(t, err)
+	b, err := dedupeHash(map[string]interface{}{"y": map[string]interface{}{"a": 1, "b": 2}, "x": 1}, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, a, b)
+}
+
+func TestMemoryDedupeStore_Expiry(t *testing.T) {
+	m := newMemoryDedupeStore()
+	dup, _, err := m.Seen(t.Context(), "s", "h", 20*time.Millisecond)
+	require.NoError(t, err)
+	assert.False(t, dup)
+	dup, _, _ = m.Seen(t.Context(), "s", "h", 20*time.Millisecond)
+	assert.True(t, dup)
+	time.Sleep(30 * time.Millisecond)
+	dup, _, _ = m.Seen(t.Context(), "s", "h", 20*time.Millisecond)
+	assert.False(t, dup)
+}
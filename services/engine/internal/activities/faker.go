@@ -0,0 +1,419 @@
+package activities
+
+import (
+	crand "crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"time"
+
+	"flowjs-works/engine/internal/models"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// fakerMaxRecords caps the records of one faker node.
+	fakerMaxRecords = 10000
+	// fakerMaxItems caps the items of one array field.
+	fakerMaxItems = 1000
+	// fakerDefaultItems is the length of an array field without count.
+	fakerDefaultItems = 3
+)
+
+// fakerTypes are the field types of a faker spec.
+var fakerTypes = []string{
+	"uuid", "name", "first_name", "last_name", "email", "phone", "company", "street", "city", "country", "word", "sentence",
+	"int", "float", "bool", "date", "choice", "sequence", "const", "object", "array",
+}
+
+// fakerConfig is the config of a faker node.
+type fakerConfig struct {
+	Fields map[string]interface{} `config:"fields,required" doc:"field name → type (e.g. \"email\") or {type, ...options}; see the DSL reference"`
+	Count  int                    `config:"count,default=1" doc:"number of records, at most 10000"`
+	Seed   int64                  `config:"seed" doc:"non-zero generates the same records on every run"`
+	spec   []fakerField
+}
+
+func (c *fakerConfig) validate() []FieldError {
+	var problems []FieldError
+	if c.Count < 1 || c.Count > fakerMaxRecords {
+		problems = append(problems, FieldError{"count", fmt.Sprintf("must be between 1 and %d, got %d", fakerMaxRecords, c.Count)})
+	}
+	spec, err := parseFakerFields(c.Fields, "", time.Now())
+	if err != nil {
+		problems = append(problems, FieldError{"fields", err.Error()})
+	}
+	c.spec = spec
+	return problems
+}
+
+// FakerActivity implements the `faker` node type. It generates synthetic
+// records from a field spec, so load-test and demo flows have data without
+// reaching external systems.
+//
+// config: fakerConfig.
+//
+// Output: {records, count}.
+type FakerActivity struct{}
+
+// Name returns the DSL type identifier for this activity.
+func (a *FakerActivity) Name() string { return "faker" }
+
+// ConfigSpec returns the config struct of faker nodes.
+func (a *FakerActivity) ConfigSpec() interface{} { return &fakerConfig{} }
+
+// Execute generates cfg.Count records.
+func (a *FakerActivity) Execute(_ map[string]interface{}, config map[string]interface{}, _ *models.ExecutionContext) (map[string]interface{}, error) {
+	var cfg fakerConfig
+	if err := decodeConfig(a.Name(), config, &cfg); err != nil {
+		return nil, err
+	}
+	g := newFakerGen(cfg.Seed)
+	records := make([]interface{}, cfg.Count)
+	for i := range records {
+		records[i] = g.record(cfg.spec, i)
+	}
+	return map[string]interface{}{"records": records, "count": len(records)}, nil
+}
+
+// fakerField is one parsed field of a faker spec.
+type fakerField struct {
+	name string
+	kind string
+	// min and max bound int, float and the start of sequence.
+	min, max float64
+	decimals int
+	values   []interface{}
+	from, to time.Time
+	format   string
+	value    interface{}
+	// fields are the fields of an object, or of each object of an array.
+	fields []fakerField
+	// items is the spec of each value of an array without fields.
+	items *fakerField
+	// minItems and maxItems bound the length of an array.
+	minItems, maxItems int
+}
+
+// parseFakerFields parses the fields of a spec in name order, so a seed
+// draws the same values for the same spec. path prefixes field names in
+// errors.
+func parseFakerFields(fields map[string]interface{}, path string, now time.Time) ([]fakerField, error) {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	parsed := make([]fakerField, 0, len(names))
+	for _, name := range names {
+		f, err := parseFakerField(name, fields[name], path+name, now)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, f)
+	}
+	return parsed, nil
+}
+
+// parseFakerField parses the spec raw of field name: a type name, or an
+// object with a type and its options.
+func parseFakerField(name string, raw interface{}, path string, now time.Time) (fakerField, error) {
+	f := fakerField{name: name}
+	opts := map[string]interface{}{}
+	switch v := raw.(type) {
+	case string:
+		f.kind = v
+	case map[string]interface{}:
+		f.kind, _ = v["type"].(string)
+		opts = v
+	default:
+		return f, fmt.Errorf("%s: must be a type name or an object with a type, got %s", path, configTypeName(raw))
+	}
+
+	var err error
+	switch f.kind {
+	case "":
+		return f, fmt.Errorf("%s: type is required", path)
+	case "int", "float":
+		max := 100.0
+		if f.kind == "float" {
+			max = 1
+		}
+		if f.min, err = fakerNumber(opts, "min", 0, path); err != nil {
+			return f, err
+		}
+		if f.max, err = fakerNumber(opts, "max", max, path); err != nil {
+			return f, err
+		}
+		if f.min > f.max {
+			return f, fmt.Errorf("%s: min %v is greater than max %v", path, f.min, f.max)
+		}
+		decimals, err := fakerNumber(opts, "decimals", 2, path)
+		if err != nil {
+			return f, err
+		}
+		f.decimals = int(decimals)
+	case "date":
+		if f.from, err = fakerTime(opts, "from", now.AddDate(-1, 0, 0), now, path); err != nil {
+			return f, err
+		}
+		if f.to, err = fakerTime(opts, "to", now, now, path); err != nil {
+			return f, err
+		}
+		if f.from.After(f.to) {
+			return f, fmt.Errorf("%s: from is after to", path)
+		}
+		f.format, _ = opts["format"].(string)
+		if f.format == "" {
+			f.format = "datetime"
+		}
+		if !slices.Contains([]string{"datetime", "date", "unix"}, f.format) {
+			return f, fmt.Errorf("%s: format must be one of datetime, date, unix, got %q", path, f.format)
+		}
+	case "choice":
+		f.values, _ = opts["values"].([]interface{})
+		if len(f.values) == 0 {
+			return f, fmt.Errorf("%s: choice needs a non-empty values array", path)
+		}
+	case "sequence":
+		if f.min, err = fakerNumber(opts, "start", 1, path); err != nil {
+			return f, err
+		}
+	case "const":
+		f.value = opts["value"]
+	case "object":
+		fields, ok := opts["fields"].(map[string]interface{})
+		if !ok || len(fields) == 0 {
+			return f, fmt.Errorf("%s: object needs a fields object", path)
+		}
+		if f.fields, err = parseFakerFields(fields, path+".", now); err != nil {
+			return f, err
+		}
+	case "array":
+		return parseFakerArray(f, opts, path, now)
+	default:
+		if !slices.Contains(fakerTypes, f.kind) {
+			return f, fmt.Errorf("%s: unknown type %q (one of %s)", path, f.kind, strings.Join(fakerTypes, ", "))
+		}
+	}
+	return f, nil
+}
+
+// parseFakerArray parses the options of an array field: count, or min and
+// max for a random length, and fields (an array of objects) or items (an
+// array of values).
+func parseFakerArray(f fakerField, opts map[string]interface{}, path string, now time.Time) (fakerField, error) {
+	count, err := fakerNumber(opts, "count", fakerDefaultItems, path)
+	if err != nil {
+		return f, err
+	}
+	lo, err := fakerNumber(opts, "min", count, path)
+	if err != nil {
+		return f, err
+	}
+	hi, err := fakerNumber(opts, "max", math.Max(lo, count), path)
+	if err != nil {
+		return f, err
+	}
+	f.minItems, f.maxItems = int(lo), int(hi)
+	if f.minItems < 0 || f.minItems > f.maxItems || f.maxItems > fakerMaxItems {
+		return f, fmt.Errorf("%s: array length must be between 0 and %d with min not above max", path, fakerMaxItems)
+	}
+
+	if fields, ok := opts["fields"].(map[string]interface{}); ok && len(fields) > 0 {
+		f.fields, err = parseFakerFields(fields, path+"[].", now)
+		return f, err
+	}
+	items, ok := opts["items"]
+	if !ok {
+		return f, fmt.Errorf("%s: array needs fields or items", path)
+	}
+	item, err := parseFakerField("", items, path+"[]", now)
+	if err != nil {
+		return f, err
+	}
+	f.items = &item
+	return f, nil
+}
+
+// fakerNumber returns the number option key, or def when it is absent.
+func fakerNumber(opts map[string]interface{}, key string, def float64, path string) (float64, error) {
+	raw, ok := opts[key]
+	if !ok || raw == nil {
+		return def, nil
+	}
+	x, ok := configFloat(raw)
+	if !ok {
+		return 0, fmt.Errorf("%s: %s must be a number, got %s", path, key, configTypeName(raw))
+	}
+	return x, nil
+}
+
+// fakerTime returns the time option key: a date (2006-01-02), an RFC 3339
+// timestamp or a Go duration relative to now ("-720h"). def applies when it
+// is absent.
+func fakerTime(opts map[string]interface{}, key string, def, now time.Time, path string) (time.Time, error) {
+	s, _ := opts[key].(string)
+	if s == "" {
+		return def, nil
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(d), nil
+	}
+	return time.Time{}, fmt.Errorf("%s: %s must be a date, an RFC 3339 time or a duration from now, got %q", path, key, s)
+}
+
+// fakerGen draws the values of a faker node.
+type fakerGen struct {
+	src *rand.ChaCha8
+	rng *rand.Rand
+}
+
+// newFakerGen returns a generator seeded with seed, or randomly when seed
+// is 0.
+func newFakerGen(seed int64) *fakerGen {
+	var key [32]byte
+	if seed != 0 {
+		binary.LittleEndian.PutUint64(key[:], uint64(seed))
+	} else {
+		_, _ = crand.Read(key[:])
+	}
+	src := rand.NewChaCha8(key)
+	return &fakerGen{src: src, rng: rand.New(src)}
+}
+
+// record generates one object of fields; index is its position in the
+// records or the array holding it.
+func (g *fakerGen) record(fields []fakerField, index int) map[string]interface{} {
+	rec := make(map[string]interface{}, len(fields))
+	for i := range fields {
+		rec[fields[i].name] = g.value(&fields[i], index)
+	}
+	return rec
+}
+
+func (g *fakerGen) pick(list []string) string {
+	return list[g.rng.IntN(len(list))]
+}
+
+func (g *fakerGen) value(f *fakerField, index int) interface{} {
+	switch f.kind {
+	case "uuid":
+		id, err := uuid.NewRandomFromReader(g.src)
+		if err != nil {
+			return uuid.NewString()
+		}
+		return id.String()
+	case "first_name":
+		return g.pick(fakerFirstNames)
+	case "last_name":
+		return g.pick(fakerLastNames)
+	case "name":
+		return g.pick(fakerFirstNames) + " " + g.pick(fakerLastNames)
+	case "email":
+		return fmt.Sprintf("%s.%s%d@%s", strings.ToLower(g.pick(fakerFirstNames)), strings.ToLower(g.pick(fakerLastNames)), g.rng.IntN(100), g.pick(fakerDomains))
+	case "phone":
+		return fmt.Sprintf("+1-555-%03d-%04d", g.rng.IntN(1000), g.rng.IntN(10000))
+	case "company":
+		return g.pick(fakerLastNames) + " " + g.pick(fakerCompanySuffixes)
+	case "street":
+		return fmt.Sprintf("%d %s %s", 1+g.rng.IntN(9999), g.pick(fakerLastNames), g.pick(fakerStreetSuffixes))
+	case "city":
+		return g.pick(fakerCities)
+	case "country":
+		return g.pick(fakerCountries)
+	case "word":
+		return g.pick(fakerWords)
+	case "sentence":
+		words := make([]string, 4+g.rng.IntN(7))
+		for i := range words {
+			words[i] = g.pick(fakerWords)
+		}
+		s := strings.Join(words, " ")
+		return strings.ToUpper(s[:1]) + s[1:] + "."
+	case "int":
+		lo, hi := int64(math.Ceil(f.min)), int64(math.Floor(f.max))
+		if hi < lo {
+			return lo
+		}
+		return int(lo + g.rng.Int64N(hi-lo+1))
+	case "float":
+		x := f.min + g.rng.Float64()*(f.max-f.min)
+		scale := math.Pow(10, float64(f.decimals))
+		return math.Round(x*scale) / scale
+	case "bool":
+		return g.rng.IntN(2) == 1
+	case "date":
+		span := f.to.Unix() - f.from.Unix()
+		t := time.Unix(f.from.Unix()+g.rng.Int64N(span+1), 0).UTC()
+		switch f.format {
+		case "date":
+			return t.Format(time.DateOnly)
+		case "unix":
+			return int(t.Unix())
+		}
+		return t.Format(time.RFC3339)
+	case "choice":
+		return f.values[g.rng.IntN(len(f.values))]
+	case "sequence":
+		return int(f.min) + index
+	case "const":
+		return f.value
+	case "object":
+		return g.record(f.fields, index)
+	case "array":
+		n := f.minItems
+		if f.maxItems > f.minItems {
+			n += g.rng.IntN(f.maxItems - f.minItems + 1)
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			if f.items != nil {
+				items[i] = g.value(f.items, i)
+			} else {
+				items[i] = g.record(f.fields, i)
+			}
+		}
+		return items
+	}
+	return nil
+}
+
+var (
+	fakerFirstNames = []string{
+		"Ada", "Alan", "Amara", "Bruno", "Carmen", "Chen", "Diego", "Elena", "Farah", "Grace",
+		"Hugo", "Ines", "Jamal", "Kenji", "Lena", "Lucas", "Maya", "Nadia", "Omar", "Priya",
+		"Rafael", "Sofia", "Tomas", "Yara",
+	}
+	fakerLastNames = []string{
+		"Alvarez", "Becker", "Costa", "Dubois", "Evans", "Fischer", "Garcia", "Hansen", "Ito", "Jensen",
+		"Kowalski", "Lopez", "Martin", "Nakamura", "Okafor", "Patel", "Rossi", "Schmidt", "Silva", "Tanaka",
+		"Novak", "Walker", "Yilmaz", "Zhang",
+	}
+	fakerDomains         = []string{"example.com", "example.org", "example.net", "mail.test"}
+	fakerCompanySuffixes = []string{"Inc", "LLC", "Ltd", "GmbH", "Group", "Labs", "Systems", "Logistics"}
+	fakerStreetSuffixes  = []string{"Street", "Avenue", "Road", "Lane", "Boulevard", "Way"}
+	fakerCities          = []string{
+		"Amsterdam", "Austin", "Barcelona", "Berlin", "Buenos Aires", "Cape Town", "Dublin", "Lagos", "Lisbon", "London",
+		"Madrid", "Melbourne", "Mexico City", "Montreal", "Mumbai", "Osaka", "Paris", "Seoul", "Singapore", "Toronto",
+	}
+	fakerCountries = []string{
+		"Argentina", "Australia", "Brazil", "Canada", "France", "Germany", "India", "Ireland", "Japan", "Mexico",
+		"Netherlands", "Nigeria", "Portugal", "Singapore", "South Africa", "South Korea", "Spain", "United Kingdom", "United States",
+	}
+	fakerWords = []string{
+		"account", "batch", "cloud", "data", "delivery", "event", "flow", "invoice", "item", "ledger",
+		"message", "order", "payment", "pipeline", "queue", "record", "report", "request", "service", "shipment",
+		"signal", "stream", "ticket", "update",
+	}
+)
@@ -0,0 +1,104 @@
+package activities
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fakerSpec() map[string]interface{} {
+	return map[string]interface{}{
+		"id":      "uuid",
+		"name":    "name",
+		"email":   "email",
+		"n":       "sequence",
+		"age":     map[string]interface{}{"type": "int", "min": float64(18), "max": float64(65)},
+		"score":   map[string]interface{}{"type": "float", "min": float64(0), "max": float64(10), "decimals": float64(1)},
+		"tier":    map[string]interface{}{"type": "choice", "values": []interface{}{"gold", "silver"}},
+		"signup":  map[string]interface{}{"type": "date", "from": "2024-01-01", "to": "2024-01-31", "format": "date"},
+		"address": map[string]interface{}{"type": "object", "fields": map[string]interface{}{"city": "city", "country": "country"}},
+		"orders": map[string]interface{}{"type": "array", "count": float64(2), "fields": map[string]interface{}{
+			"line": map[string]interface{}{"type": "sequence", "start": float64(0)},
+			"qty":  map[string]interface{}{"type": "int", "min": float64(1), "max": float64(3)},
+		}},
+		"tags":   map[string]interface{}{"type": "array", "min": float64(1), "max": float64(4), "items": "word"},
+		"source": map[string]interface{}{"type": "const", "value": "demo"},
+	}
+}
+
+func TestFakerActivity_GeneratesRecords(t *testing.T) {
+	a := &FakerActivity{}
+	out, err := a.Execute(nil, map[string]interface{}{"fields": fakerSpec(), "count": float64(5)}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 5, out["count"])
+	records := out["records"].([]interface{})
+	require.Len(t, records, 5)
+
+	for i, r := range records {
+		rec := r.(map[string]interface{})
+		_, err := uuid.Parse(rec["id"].(string))
+		assert.NoError(t, err)
+		assert.Contains(t, rec["email"], "@")
+		assert.Len(t, strings.Fields(rec["name"].(string)), 2)
+		assert.Equal(t, i+1, rec["n"])
+		assert.GreaterOrEqual(t, rec["age"], 18)
+		assert.LessOrEqual(t, rec["age"], 65)
+		assert.Contains(t, []interface{}{"gold", "silver"}, rec["tier"])
+		signup, err := time.Parse(time.DateOnly, rec["signup"].(string))
+		require.NoError(t, err)
+		assert.Equal(t, time.January, signup.Month())
+		assert.NotEmpty(t, rec["address"].(map[string]interface{})["city"])
+		orders := rec["orders"].([]interface{})
+		require.Len(t, orders, 2)
+		assert.Equal(t, 1, orders[1].(map[string]interface{})["line"])
+		tags := rec["tags"].([]interface{})
+		assert.True(t, len(tags) >= 1 && len(tags) <= 4)
+		assert.Equal(t, "demo", rec["source"])
+	}
+}
+
+func TestFakerActivity_SeedRepeats(t *testing.T) {
+	a := &FakerActivity{}
+	cfg := map[string]interface{}{"fields": fakerSpec(), "count": float64(3), "seed": float64(42)}
+	first, err := a.Execute(nil, cfg, nil)
+	require.NoError(t, err)
+	second, err := a.Execute(nil, cfg, nil)
+	require.NoError(t, err)
+	assert.Equal(t, first, second)
+
+	cfg["seed"] = float64(7)
+	other, err := a.Execute(nil, cfg, nil)
+	require.NoError(t, err)
+	assert.NotEqual(t, first, other)
+}
+
+func TestFakerActivity_InvalidSpec(t *testing.T) {
+	a := &FakerActivity{}
+	cases := map[string]map[string]interface{}{
+		"is required":           {},
+		"must be between 1":     {"fields": map[string]interface{}{"id": "uuid"}, "count": float64(0)},
+		`unknown type "zip"`:    {"fields": map[string]interface{}{"code": "zip"}},
+		"user.age: min 9":       {"fields": map[string]interface{}{"user": map[string]interface{}{"type": "object", "fields": map[string]interface{}{"age": map[string]interface{}{"type": "int", "min": float64(9), "max": float64(1)}}}}},
+		"choice needs":          {"fields": map[string]interface{}{"tier": map[string]interface{}{"type": "choice"}}},
+		"array needs fields or": {"fields": map[string]interface{}{"tags": map[string]interface{}{"type": "array"}}},
+		"array length must be":  {"fields": map[string]interface{}{"tags": map[string]interface{}{"type": "array", "count": float64(5000), "items": "word"}}},
+		"from must be a date":   {"fields": map[string]interface{}{"at": map[string]interface{}{"type": "date", "from": "yesterday"}}},
+		"type is required":      {"fields": map[string]interface{}{"x": map[string]interface{}{"min": float64(1)}}},
+		"must be a type name":   {"fields": map[string]interface{}{"x": float64(1)}},
+	}
+	for want, cfg := range cases {
+		_, err := a.Execute(nil, cfg, nil)
+		assert.ErrorContains(t, err, want)
+	}
+}
+
+func TestFakerTime_RelativeDuration(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	from, err := fakerTime(map[string]interface{}{"from": "-48h"}, "from", now, now, "at")
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(-48*time.Hour), from)
+}
@@ -2,44 +2,56 @@ package activities
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 
 	"flowjs-works/engine/internal/models"
 )
 
-// FileActivity implements the `file` node type.
-// config fields:
+// fileConfig is the config of a file node.
+type fileConfig struct {
+	Operation string `config:"operation,required,enum=create|read|delete"`
+	Path      string `config:"path" doc:"file path, required unless a read receives input.file"`
+	Content   string `config:"content" doc:"content written by create"`
+	Mode      string `config:"mode,default=overwrite,enum=overwrite|append" doc:"how create writes an existing file"`
+}
+
+// FileActivity implements the `file` node type (config: fileConfig).
 //
-//	operation: "create" | "read" | "delete"
-//	path:      file path (required)
-//	content:   string content (for create)
-//	mode:      "overwrite" (default) | "append" (for create)
+// create and read output the file as a file ref, "file" (see FileRef). read
+// also reads the ref it receives as input["file"], in memory or on disk, when
+// path is not set.
 type FileActivity struct{}
 
 func (a *FileActivity) Name() string { return "file" }
 
+// ConfigSpec returns the config struct of file nodes.
+func (a *FileActivity) ConfigSpec() interface{} { return &fileConfig{} }
+
 func (a *FileActivity) Execute(input map[string]interface{}, config map[string]interface{}, ctx *models.ExecutionContext) (map[string]interface{}, error) {
-	operation, ok := config["operation"].(string)
-	if !ok || operation == "" {
-		return nil, fmt.Errorf("file activity: missing required config field 'operation'")
+	var cfg fileConfig
+	if err := decodeConfig(a.Name(), config, &cfg); err != nil {
+		return nil, err
+	}
+	path := cfg.Path
+	if path == "" && cfg.Operation == "read" {
+		if m, ok := input["file"].(map[string]interface{}); ok {
+			return readFileRef(m, ctx)
+		}
 	}
-	path, ok := config["path"].(string)
-	if !ok || path == "" {
-		return nil, fmt.Errorf("file activity: missing required config field 'path'")
+	if path == "" {
+		return nil, fmt.Errorf("file activity: config field 'path' is required")
+	}
+	path, err := localFilePath(ctx, path)
+	if err != nil {
+		return nil, fmt.Errorf("file activity: %w", err)
 	}
 
-	switch operation {
+	switch cfg.Operation {
 	case "create":
-		content := ""
-		if c, ok := config["content"].(string); ok {
-			content = c
-		}
-		mode := "overwrite"
-		if m, ok := config["mode"].(string); ok {
-			mode = m
-		}
 		flag := os.O_CREATE | os.O_WRONLY
-		if mode == "append" {
+		if cfg.Mode == "append" {
 			flag |= os.O_APPEND
 		} else {
 			flag |= os.O_TRUNC
@@ -48,18 +60,29 @@ func (a *FileActivity) Execute(input map[string]interface{}, config map[string]i
 		if err != nil {
 			return nil, fmt.Errorf("file activity: failed to open file %q: %w", path, err)
 		}
-		defer f.Close()
-		if _, err := f.WriteString(content); err != nil {
+		n, err := io.WriteString(limitLocalWrite(ctx, path, f), cfg.Content)
+		f.Close()
+		if err != nil {
 			return nil, fmt.Errorf("file activity: failed to write file %q: %w", path, err)
 		}
-		return map[string]interface{}{"created": true, "path": path}, nil
+		ctx.AddBytes(int64(n))
+		ref, err := localFileRef(ctx, path, filepath.Base(path))
+		if err != nil {
+			return nil, fmt.Errorf("file activity: failed to hash file %q: %w", path, err)
+		}
+		return map[string]interface{}{"created": true, "path": path, "file": ref.Map()}, nil
 
 	case "read":
 		data, err := os.ReadFile(path)
 		if err != nil {
 			return nil, fmt.Errorf("file activity: failed to read file %q: %w", path, err)
 		}
-		return map[string]interface{}{"content": string(data)}, nil
+		ctx.AddBytes(int64(len(data)))
+		ref, err := localFileRef(ctx, path, filepath.Base(path))
+		if err != nil {
+			return nil, fmt.Errorf("file activity: failed to hash file %q: %w", path, err)
+		}
+		return map[string]interface{}{"content": string(data), "file": ref.Map()}, nil
 
 	case "delete":
 		if err := os.Remove(path); err != nil {
@@ -68,6 +91,25 @@ func (a *FileActivity) Execute(input map[string]interface{}, config map[string]i
 		return map[string]interface{}{"deleted": true}, nil
 
 	default:
-		return nil, fmt.Errorf("file activity: unknown operation %q (use create, read, delete)", operation)
+		return nil, fmt.Errorf("file activity: unknown operation %q (use create, read, delete)", cfg.Operation)
+	}
+}
+
+// readFileRef reads the content of the file ref m of the execution of ctx.
+func readFileRef(m map[string]interface{}, ctx *models.ExecutionContext) (map[string]interface{}, error) {
+	ref, err := fileRefFromMap(m)
+	if err != nil {
+		return nil, fmt.Errorf("file activity: input file: %w", err)
+	}
+	r, _, err := openFileRef(ctx, ref)
+	if err != nil {
+		return nil, fmt.Errorf("file activity: %w", err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("file activity: %w", err)
 	}
+	ctx.AddBytes(int64(len(data)))
+	return map[string]interface{}{"content": string(data), "file": ref.Map()}, nil
 }
@@ -3,10 +3,14 @@ package activities
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"flowjs-works/engine/internal/models"
+	"flowjs-works/engine/internal/scratch"
 )
 
 func TestFileActivity_CreateReadDelete(t *testing.T) {
@@ -68,7 +72,7 @@ func TestFileActivity_UnknownOperation(t *testing.T) {
 		"path":      "/tmp/x.txt",
 	}, nil)
 	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "unknown operation")
+	assert.Contains(t, err.Error(), "config field 'operation' must be one of create, read, delete")
 }
 
 func TestFileActivity_AppendMode(t *testing.T) {
@@ -85,3 +89,60 @@ func TestFileActivity_AppendMode(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, "line1line2", out["content"])
 }
+
+// TestFileActivity_FileRefs verifies that create and read output a file ref
+// and that read accepts a ref, in memory or on disk, as input.
+func TestFileActivity_FileRefs(t *testing.T) {
+	a := &FileActivity{}
+	ctx := models.NewExecutionContext("exec-file-refs")
+	defer ReleaseFileRefs(ctx.ExecutionID)
+	path := filepath.Join(t.TempDir(), "out.txt")
+
+	out, err := a.Execute(nil, map[string]interface{}{"operation": "create", "path": path, "content": "hello"}, ctx)
+	require.NoError(t, err)
+	file := out["file"].(map[string]interface{})
+	assert.Equal(t, "out.txt", file["name"])
+	assert.EqualValues(t, 5, file["size"])
+	assert.Equal(t, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", file["sha256"])
+
+	out, err = a.Execute(map[string]interface{}{"file": file}, map[string]interface{}{"operation": "read"}, ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", out["content"])
+
+	mem, err := storeFileRef(ctx, "in.txt", strings.NewReader("from memory"))
+	require.NoError(t, err)
+	out, err = a.Execute(map[string]interface{}{"file": mem.Map()}, map[string]interface{}{"operation": "read"}, ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "from memory", out["content"])
+
+	_, err = a.Execute(map[string]interface{}{"file": map[string]interface{}{"ref": "file:///etc/hosts", "name": "hosts"}},
+		map[string]interface{}{"operation": "read"}, ctx)
+	assert.ErrorContains(t, err, "does not belong to this execution")
+}
+
+// TestFileActivity_ScratchPolicy verifies that under a scratch policy a
+// relative path lands in the execution's scratch directory, removed when the
+// execution ends, and a path outside it is refused.
+func TestFileActivity_ScratchPolicy(t *testing.T) {
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, scratch.SetPolicy(&scratch.Policy{Root: root, MaxBytes: 8}))
+	t.Cleanup(func() { _ = scratch.SetPolicy(nil) })
+	a := &FileActivity{}
+	ctx := models.NewExecutionContext("exec-scratch")
+
+	_, err = a.Execute(nil, map[string]interface{}{"operation": "create", "path": "out/a.txt", "content": "hello"}, ctx)
+	require.Error(t, err, "out/ does not exist yet")
+	require.NoError(t, os.MkdirAll(filepath.Join(root, ctx.ExecutionID, "out"), 0o700))
+	out, err := a.Execute(nil, map[string]interface{}{"operation": "create", "path": "out/a.txt", "content": "hello"}, ctx)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(root, ctx.ExecutionID, "out", "a.txt"), out["path"])
+
+	_, err = a.Execute(nil, map[string]interface{}{"operation": "create", "path": "b.txt", "content": "too much"}, ctx)
+	assert.ErrorIs(t, err, scratch.ErrQuota)
+	_, err = a.Execute(nil, map[string]interface{}{"operation": "read", "path": "/etc/hosts"}, ctx)
+	assert.ErrorIs(t, err, scratch.ErrDenied)
+
+	ReleaseExecution(ctx.ExecutionID)
+	assert.NoDirExists(t, filepath.Join(root, ctx.ExecutionID))
+}
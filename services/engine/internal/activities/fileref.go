@@ -0,0 +1,370 @@
+package activities
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"flowjs-works/engine/internal/models"
+	"flowjs-works/engine/internal/scratch"
+)
+
+const (
+	// fileRefScheme prefixes the refs of files held in engine memory.
+	fileRefScheme = "mem://"
+	// localFileRefScheme prefixes the refs of files written to the engine's
+	// disk, followed by their absolute path.
+	localFileRefScheme = "file://"
+	// maxFileRefBytes caps the in-memory files of one execution, so a large
+	// transfer fails the node instead of exhausting the engine's memory.
+	maxFileRefBytes = 256 << 20
+)
+
+// FileRef is a file produced by a node: downloaded by an sftp, s3 or smb get,
+// or written or read by a file node. Its map form ({ref, name, size, sha256,
+// content_type}) is what the node outputs; a following node receives it
+// through input_mapping, a put node as the list input["files"], and reads
+// the content by ref instead of by a file name under local_folder.
+//
+// Ref is mem://<execution>/<n> for a file held in engine memory (in_memory
+// set), else file://<absolute path>. Refs belong to the execution that
+// created them: another execution, or a ref written into trigger data,
+// cannot read them. In-memory files are released when the execution ends;
+// files on disk stay, unless they are in the execution's scratch directory
+// (see localFilePath).
+type FileRef struct {
+	Ref  string
+	Name string
+	Size int64
+	// Checksum is the hex SHA-256 of the content. Nodes reading the ref fail
+	// when the content no longer matches it.
+	Checksum    string
+	ContentType string
+}
+
+// Map returns the node output form of the ref.
+func (f FileRef) Map() map[string]interface{} {
+	return map[string]interface{}{
+		"ref":          f.Ref,
+		"name":         f.Name,
+		"size":         f.Size,
+		"sha256":       f.Checksum,
+		"content_type": f.ContentType,
+	}
+}
+
+// fileTransferConfig holds the config fields sftp, s3 and smb nodes share to
+// move files between the engine and a remote store.
+type fileTransferConfig struct {
+	LocalFolder string   `config:"local_folder,default=." doc:"local directory put reads from and get writes to"`
+	Files       []string `config:"files" doc:"local file names put uploads, besides the refs in input.files"`
+	RegexFilter string   `config:"regex_filter" doc:"regular expression get matches the remote file names against"`
+	Overwrite   bool     `config:"overwrite,default=true" doc:"put replaces existing remote files"`
+	InMemory    bool     `config:"in_memory" doc:"get keeps the files in engine memory instead of writing them to local_folder"`
+
+	// filter is RegexFilter compiled by validate, nil without one.
+	filter *regexp.Regexp
+}
+
+func (c *fileTransferConfig) validate() []FieldError {
+	if c.RegexFilter == "" {
+		return nil
+	}
+	filter, err := regexp.Compile(c.RegexFilter)
+	if err != nil {
+		return []FieldError{{"regex_filter", fmt.Sprintf("is not a valid regular expression: %v", err)}}
+	}
+	c.filter = filter
+	return nil
+}
+
+// fileRefStore holds in-memory files, and the refs of local files, per
+// execution.
+type fileRefStore struct {
+	mu    sync.Mutex
+	execs map[string]*execFiles
+}
+
+type execFiles struct {
+	size  int64
+	next  int
+	files map[string][]byte
+	// local holds the file:// refs the execution created.
+	local map[string]bool
+}
+
+var fileRefs = &fileRefStore{execs: make(map[string]*execFiles)}
+
+// exec returns the files of executionID, creating them. The caller holds mu.
+func (s *fileRefStore) exec(executionID string) *execFiles {
+	ef := s.execs[executionID]
+	if ef == nil {
+		ef = &execFiles{files: make(map[string][]byte), local: make(map[string]bool)}
+		s.execs[executionID] = ef
+	}
+	return ef
+}
+
+// ReleaseFileRefs drops the in-memory files and file refs of executionID.
+// The executor calls it when an execution ends.
+func ReleaseFileRefs(executionID string) {
+	fileRefs.mu.Lock()
+	defer fileRefs.mu.Unlock()
+	delete(fileRefs.execs, executionID)
+}
+
+// fileContentType returns the content type of a file by its name extension.
+func fileContentType(name string) string {
+	if ct := mime.TypeByExtension(path.Ext(name)); ct != "" {
+		return ct
+	}
+	return "application/octet-stream"
+}
+
+// storeFileRef reads r into memory under the execution of ctx and returns its ref.
+func storeFileRef(ctx *models.ExecutionContext, name string, r io.Reader) (FileRef, error) {
+	if ctx == nil || ctx.ExecutionID == "" {
+		return FileRef{}, fmt.Errorf("in-memory file %q: execution context is required", name)
+	}
+	fileRefs.mu.Lock()
+	used := int64(0)
+	if ef := fileRefs.execs[ctx.ExecutionID]; ef != nil {
+		used = ef.size
+	}
+	fileRefs.mu.Unlock()
+
+	// Read one byte past the remaining budget to detect oversized files.
+	data, err := io.ReadAll(io.LimitReader(r, maxFileRefBytes-used+1))
+	if err != nil {
+		return FileRef{}, err
+	}
+
+	fileRefs.mu.Lock()
+	defer fileRefs.mu.Unlock()
+	ef := fileRefs.exec(ctx.ExecutionID)
+	if ef.size+int64(len(data)) > maxFileRefBytes {
+		return FileRef{}, fmt.Errorf("in-memory file %q: execution exceeds %d bytes of in-memory files", name, maxFileRefBytes)
+	}
+	ef.next++
+	ref := fileRefScheme + ctx.ExecutionID + "/" + strconv.Itoa(ef.next)
+	ef.files[ref] = data
+	ef.size += int64(len(data))
+	ctx.AddBytes(int64(len(data)))
+	sum := sha256.Sum256(data)
+	return FileRef{Ref: ref, Name: name, Size: int64(len(data)), Checksum: hex.EncodeToString(sum[:]), ContentType: fileContentType(name)}, nil
+}
+
+// ctxExecutionID returns the execution id of ctx, empty without one.
+func ctxExecutionID(ctx *models.ExecutionContext) string {
+	if ctx == nil {
+		return ""
+	}
+	return ctx.ExecutionID
+}
+
+// localFilePath returns the local path the nodes of the execution of ctx use
+// for p. Under the scratch policy (SCRATCH_ROOT) a relative p is inside the
+// execution's scratch directory, removed when it ends, and any other path
+// outside it and the allowed directories is refused; without one p is
+// relative to the engine's working directory.
+func localFilePath(ctx *models.ExecutionContext, p string) (string, error) {
+	return scratch.Resolve(ctxExecutionID(ctx), p)
+}
+
+// limitLocalWrite returns w, the writer of the local file localPath, failing
+// writes past the size limit of the execution's scratch directory.
+func limitLocalWrite(ctx *models.ExecutionContext, localPath string, w io.Writer) io.Writer {
+	return scratch.Limit(ctxExecutionID(ctx), localPath, w)
+}
+
+// writeLocalFileRef writes r to localPath, creating the file, and returns its
+// ref under the execution of ctx. name is the file name the ref carries.
+func writeLocalFileRef(ctx *models.ExecutionContext, localPath, name string, r io.Reader) (FileRef, error) {
+	f, err := os.Create(localPath)
+	if err != nil {
+		return FileRef{}, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(limitLocalWrite(ctx, localPath, f), h), r)
+	ctx.AddBytes(n)
+	if err != nil {
+		return FileRef{}, err
+	}
+	return registerLocalFileRef(ctx, localPath, name, n, hex.EncodeToString(h.Sum(nil)))
+}
+
+// localFileRef hashes the file at localPath and returns its ref under the
+// execution of ctx.
+func localFileRef(ctx *models.ExecutionContext, localPath, name string) (FileRef, error) {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return FileRef{}, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return FileRef{}, err
+	}
+	return registerLocalFileRef(ctx, localPath, name, n, hex.EncodeToString(h.Sum(nil)))
+}
+
+// registerLocalFileRef records the ref of a local file so the execution of
+// ctx, and only it, can read it.
+func registerLocalFileRef(ctx *models.ExecutionContext, localPath, name string, size int64, checksum string) (FileRef, error) {
+	abs, err := filepath.Abs(localPath)
+	if err != nil {
+		return FileRef{}, err
+	}
+	ref := FileRef{Ref: localFileRefScheme + filepath.ToSlash(abs), Name: name, Size: size, Checksum: checksum, ContentType: fileContentType(name)}
+	if ctx == nil || ctx.ExecutionID == "" {
+		return ref, nil
+	}
+	fileRefs.mu.Lock()
+	defer fileRefs.mu.Unlock()
+	fileRefs.exec(ctx.ExecutionID).local[ref.Ref] = true
+	return ref, nil
+}
+
+// openFileRef opens the content of ref, which must belong to the execution of
+// ctx, and returns it with its size. When ref has a checksum, reading the
+// content to its end fails if it does not match.
+func openFileRef(ctx *models.ExecutionContext, ref FileRef) (io.ReadCloser, int64, error) {
+	content, size, err := openFileContent(ctx, ref.Ref)
+	if err != nil {
+		return nil, 0, err
+	}
+	if ref.Checksum != "" {
+		content = &checksumReader{ReadCloser: content, ref: ref.Ref, want: strings.ToLower(ref.Checksum), h: sha256.New()}
+	}
+	return content, size, nil
+}
+
+// openFileContent opens the content of a mem:// or file:// ref of the
+// execution of ctx.
+func openFileContent(ctx *models.ExecutionContext, ref string) (io.ReadCloser, int64, error) {
+	if ctx == nil || ctx.ExecutionID == "" {
+		return nil, 0, fmt.Errorf("file ref %q does not belong to this execution", ref)
+	}
+	if localPath, ok := strings.CutPrefix(ref, localFileRefScheme); ok {
+		fileRefs.mu.Lock()
+		ef := fileRefs.execs[ctx.ExecutionID]
+		owned := ef != nil && ef.local[ref]
+		fileRefs.mu.Unlock()
+		if !owned {
+			return nil, 0, fmt.Errorf("file ref %q does not belong to this execution", ref)
+		}
+		f, err := os.Open(filepath.FromSlash(localPath))
+		if err != nil {
+			return nil, 0, fmt.Errorf("file ref %q: %w", ref, err)
+		}
+		info, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, 0, fmt.Errorf("file ref %q: %w", ref, err)
+		}
+		return f, info.Size(), nil
+	}
+	if !strings.HasPrefix(ref, fileRefScheme+ctx.ExecutionID+"/") {
+		return nil, 0, fmt.Errorf("file ref %q does not belong to this execution", ref)
+	}
+	fileRefs.mu.Lock()
+	defer fileRefs.mu.Unlock()
+	ef := fileRefs.execs[ctx.ExecutionID]
+	if ef == nil {
+		return nil, 0, fmt.Errorf("file ref %q not found", ref)
+	}
+	data, ok := ef.files[ref]
+	if !ok {
+		return nil, 0, fmt.Errorf("file ref %q not found", ref)
+	}
+	return io.NopCloser(bytes.NewReader(data)), int64(len(data)), nil
+}
+
+// checksumReader hashes the content it reads and fails at its end when the
+// hash is not the ref's checksum, e.g. for a local file changed since.
+type checksumReader struct {
+	io.ReadCloser
+	ref  string
+	want string
+	h    hash.Hash
+}
+
+func (c *checksumReader) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.h.Write(p[:n])
+	if err == io.EOF {
+		if got := hex.EncodeToString(c.h.Sum(nil)); got != c.want {
+			return n, fmt.Errorf("file ref %q: checksum mismatch (sha256 %s, want %s)", c.ref, got, c.want)
+		}
+	}
+	return n, err
+}
+
+// fileRefFromMap parses the map form of a ref.
+func fileRefFromMap(m map[string]interface{}) (FileRef, error) {
+	ref, _ := m["ref"].(string)
+	name, _ := m["name"].(string)
+	if ref == "" || name == "" {
+		return FileRef{}, fmt.Errorf("file ref entries need 'ref' and 'name'")
+	}
+	f := FileRef{Ref: ref, Name: name}
+	switch size := m["size"].(type) {
+	case float64:
+		f.Size = int64(size)
+	case int64:
+		f.Size = size
+	case int:
+		f.Size = int64(size)
+	}
+	f.Checksum, _ = m["sha256"].(string)
+	f.ContentType, _ = m["content_type"].(string)
+	return f, nil
+}
+
+// fileRefsFromInput returns the refs in input["files"], a list of refs or a
+// single one. Plain strings are left to the local_folder handling of
+// config["files"] and ignored here.
+func fileRefsFromInput(input map[string]interface{}) ([]FileRef, error) {
+	var list []interface{}
+	switch v := input["files"].(type) {
+	case []interface{}:
+		list = v
+	case map[string]interface{}:
+		list = []interface{}{v}
+	}
+	var refs []FileRef
+	for _, item := range list {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		ref, err := fileRefFromMap(m)
+		if err != nil {
+			return nil, err
+		}
+		refs = append(refs, ref)
+	}
+	return refs, nil
+}
+
+// fileRefMaps converts refs to their node output form.
+func fileRefMaps(refs []FileRef) []interface{} {
+	out := make([]interface{}, len(refs))
+	for i, f := range refs {
+		out[i] = f.Map()
+	}
+	return out
+}
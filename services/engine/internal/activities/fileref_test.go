@@ -0,0 +1,102 @@
+package activities
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"flowjs-works/engine/internal/models"
+)
+
+func TestFileRef_StoreOpenRelease(t *testing.T) {
+	ctx := models.NewExecutionContext("exec-fileref")
+	defer ReleaseFileRefs(ctx.ExecutionID)
+
+	ref, err := storeFileRef(ctx, "a.csv", strings.NewReader("id,name\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "a.csv", ref.Name)
+	assert.EqualValues(t, 8, ref.Size)
+	assert.True(t, strings.HasPrefix(ref.Ref, "mem://exec-fileref/"))
+	assert.Equal(t, "text/csv; charset=utf-8", ref.ContentType)
+	assert.Len(t, ref.Checksum, 64)
+
+	content, size, err := openFileRef(ctx, ref)
+	require.NoError(t, err)
+	assert.EqualValues(t, 8, size)
+	data, _ := io.ReadAll(content)
+	assert.Equal(t, "id,name\n", string(data))
+
+	// Another execution cannot read the ref.
+	_, _, err = openFileRef(models.NewExecutionContext("exec-other"), ref)
+	assert.Error(t, err)
+
+	ReleaseFileRefs(ctx.ExecutionID)
+	_, _, err = openFileRef(ctx, ref)
+	assert.Error(t, err)
+}
+
+func TestFileRef_RequiresExecutionContext(t *testing.T) {
+	_, err := storeFileRef(nil, "a.csv", strings.NewReader("x"))
+	assert.Error(t, err)
+}
+
+func TestFileRefsFromInput(t *testing.T) {
+	refs, err := fileRefsFromInput(map[string]interface{}{"files": []interface{}{
+		map[string]interface{}{"ref": "mem://e/1", "name": "a.csv", "size": float64(3), "sha256": "ab", "content_type": "text/csv"},
+		"local.txt",
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, []FileRef{{Ref: "mem://e/1", Name: "a.csv", Size: 3, Checksum: "ab", ContentType: "text/csv"}}, refs)
+
+	// A single ref, e.g. the file output of a file node, is a list of one.
+	refs, err = fileRefsFromInput(map[string]interface{}{"files": map[string]interface{}{"ref": "file:///tmp/b.txt", "name": "b.txt"}})
+	require.NoError(t, err)
+	assert.Equal(t, []FileRef{{Ref: "file:///tmp/b.txt", Name: "b.txt"}}, refs)
+
+	_, err = fileRefsFromInput(map[string]interface{}{"files": []interface{}{map[string]interface{}{"ref": "mem://e/1"}}})
+	assert.Error(t, err)
+}
+
+// TestFileRef_LocalFiles verifies the refs of files written to disk: only the
+// execution that wrote them can read them, and a changed file fails its
+// checksum.
+func TestFileRef_LocalFiles(t *testing.T) {
+	ctx := models.NewExecutionContext("exec-local-ref")
+	defer ReleaseFileRefs(ctx.ExecutionID)
+	localPath := filepath.Join(t.TempDir(), "report.json")
+
+	ref, err := writeLocalFileRef(ctx, localPath, "report.json", strings.NewReader(`{"ok":true}`))
+	require.NoError(t, err)
+	abs, _ := filepath.Abs(localPath)
+	assert.Equal(t, "file://"+filepath.ToSlash(abs), ref.Ref)
+	assert.EqualValues(t, 11, ref.Size)
+	assert.Equal(t, "application/json", ref.ContentType)
+	assert.Equal(t, "4062edaf750fb8074e7e83e0c9028c94e32468a8b6f1614774328ef045150f93", ref.Checksum)
+
+	r, size, err := openFileRef(ctx, ref)
+	require.NoError(t, err)
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	r.Close()
+	assert.EqualValues(t, 11, size)
+	assert.Equal(t, `{"ok":true}`, string(data))
+
+	// A ref another execution created, or one written into trigger data,
+	// cannot be read.
+	_, _, err = openFileRef(models.NewExecutionContext("exec-other"), ref)
+	assert.ErrorContains(t, err, "does not belong to this execution")
+	_, _, err = openFileRef(ctx, FileRef{Ref: "file:///etc/passwd", Name: "passwd"})
+	assert.ErrorContains(t, err, "does not belong to this execution")
+
+	require.NoError(t, os.WriteFile(localPath, []byte(`{"ok":false}`), 0o644))
+	r, _, err = openFileRef(ctx, ref)
+	require.NoError(t, err)
+	_, err = io.ReadAll(r)
+	r.Close()
+	assert.ErrorContains(t, err, "checksum mismatch")
+}
@@ -4,31 +4,55 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"time"
 
+	"flowjs-works/engine/internal/egress"
 	"flowjs-works/engine/internal/models"
 )
 
 const (
-	defaultHTTPTimeout     = 30 * time.Second
-	defaultNetDialTimeout  = 30 * time.Second
-	defaultSSHTimeout      = 30 * time.Second
-	defaultScriptTimeoutMs = 5_000
+	defaultHTTPTimeout    = 30 * time.Second
+	defaultNetDialTimeout = 30 * time.Second
+	defaultSSHTimeout     = 30 * time.Second
 )
 
-// HTTPActivity makes HTTP requests.
-// It reuses a shared http.Client to benefit from TCP keep-alive and connection pooling.
+// httpRequestConfig is the request part of an http node config, which
+// callback_await nodes share.
+type httpRequestConfig struct {
+	URL        string                 `config:"url" doc:"request URL; an input url overrides it"`
+	Headers    map[string]interface{} `config:"headers" doc:"request headers; they override input headers"`
+	TimeoutSec float64                `config:"timeout" doc:"request timeout in seconds, capped by the process timeout"`
+	// Token, or User and Password, usually injected from a secret, set the
+	// Authorization header unless a header overrides it.
+	Token    string `config:"token" doc:"bearer token"`
+	User     string `config:"user" doc:"basic auth user"`
+	Password string `config:"password" doc:"basic auth password"`
+	httpTransportOptions
+}
+
+// httpConfig is the config of an http node.
+type httpConfig struct {
+	Method string `config:"method,default=GET"`
+	httpRequestConfig
+	// Expect turns the response into a checked contract (see httpExpect).
+	Expect map[string]interface{} `config:"expect" doc:"status, latency and body assertions; a violation fails the node"`
+}
+
+// HTTPActivity implements the `http` node type (config: httpConfig).
+// It reuses a shared http.Client to benefit from TCP keep-alive and connection pooling;
+// nodes with a proxy, client certificate or CA bundle get a client per distinct setting.
 type HTTPActivity struct {
-	client *http.Client
+	clients *httpClientPool
 }
 
 // NewHTTPActivity returns an HTTPActivity with a shared, reusable HTTP client.
 func NewHTTPActivity() *HTTPActivity {
 	return &HTTPActivity{
-		client: &http.Client{Timeout: defaultHTTPTimeout},
+		clients: newHTTPClientPool(&http.Client{Timeout: defaultHTTPTimeout, Transport: defaultHTTPTransport()}),
 	}
 }
 
@@ -37,21 +61,49 @@ func (a *HTTPActivity) Name() string {
 	return "http"
 }
 
+// ConfigSpec returns the config struct of http nodes.
+func (a *HTTPActivity) ConfigSpec() interface{} { return &httpConfig{} }
+
 // Execute performs an HTTP request.
 // Network and transport errors are captured in the output under the "error" key rather
 // than propagated as fatal Go errors, so the flow can continue and the caller can inspect
 // the result via transitions/conditions.  HTTP 4xx/5xx responses are also returned as
 // data (not errors) — only the status_code distinguishes success from failure.
+//
+// An optional "expect" config turns the response into a checked contract: when the
+// status, latency or body assertions are violated (or the request fails), the output
+// is returned together with an error wrapping ErrExpectationFailed, so the node ends
+// in "error" and its error transitions run.
 func (a *HTTPActivity) Execute(input map[string]interface{}, config map[string]interface{}, ctx *models.ExecutionContext) (map[string]interface{}, error) {
-	// Extract configuration
-	url, ok := config["url"].(string)
-	if !ok || url == "" {
+	var cfg httpConfig
+	if err := decodeConfig(a.Name(), config, &cfg); err != nil {
+		return nil, err
+	}
+	var expect *httpExpect
+	if cfg.Expect != nil {
+		var err error
+		if expect, err = parseHTTPExpect(cfg.Expect); err != nil {
+			return nil, err
+		}
+	}
+	return a.send(input, cfg.Method, &cfg.httpRequestConfig, expect, ctx)
+}
+
+// send performs the request of cfg with method, checking the response
+// against expect when it is not nil.
+func (a *HTTPActivity) send(input map[string]interface{}, method string, cfg *httpRequestConfig, expect *httpExpect, ctx *models.ExecutionContext) (map[string]interface{}, error) {
+	// An input url (e.g. a mock_http node's url) overrides the configured one.
+	url := cfg.URL
+	if u, ok := input["url"].(string); ok && u != "" {
+		url = u
+	}
+	if url == "" {
 		return nil, fmt.Errorf("url is required in config")
 	}
 
-	method := "GET"
-	if methodVal, ok := config["method"].(string); ok && methodVal != "" {
-		method = methodVal
+	client, err := a.clients.client(cfg.httpTransportOptions)
+	if err != nil {
+		return nil, fmt.Errorf("http transport: %w", err)
 	}
 
 	// Prepare request body
@@ -64,12 +116,14 @@ func (a *HTTPActivity) Execute(input map[string]interface{}, config map[string]i
 		bodyReader = bytes.NewReader(bodyBytes)
 	}
 
-	// Build the request context. When a per-request timeout is specified, wrap with
-	// context.WithTimeout so the shared Transport (and its connection pool) is reused.
-	reqCtx := context.Background()
-	if timeoutVal, ok := config["timeout"].(float64); ok && timeoutVal > 0 {
+	// Build the request context. It ends with the process timeout budget; when a
+	// per-request timeout is specified, wrap with context.WithTimeout so the shared
+	// Transport (and its connection pool) is reused.
+	reqCtx, cancelBudget := ctx.Context(context.Background())
+	defer cancelBudget()
+	if cfg.TimeoutSec > 0 {
 		var cancel context.CancelFunc
-		reqCtx, cancel = context.WithTimeout(reqCtx, time.Duration(timeoutVal)*time.Second)
+		reqCtx, cancel = context.WithTimeout(reqCtx, time.Duration(cfg.TimeoutSec*float64(time.Second)))
 		defer cancel()
 	}
 
@@ -85,12 +139,10 @@ func (a *HTTPActivity) Execute(input map[string]interface{}, config map[string]i
 	// Auth injection from secrets: token → Bearer header, user+password → Basic auth.
 	// Headers set via input["headers"] or config["headers"] below take priority and can
 	// override this injected Authorization header.
-	if token, ok := config["token"].(string); ok && token != "" {
-		req.Header.Set("Authorization", "Bearer "+token)
-	} else if user, ok := config["user"].(string); ok && user != "" {
-		if pass, _ := config["password"].(string); pass != "" {
-			req.SetBasicAuth(user, pass)
-		}
+	if cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+cfg.Token)
+	} else if cfg.User != "" && cfg.Password != "" {
+		req.SetBasicAuth(cfg.User, cfg.Password)
 	}
 
 	if headers, ok := input["headers"].(map[string]interface{}); ok {
@@ -102,35 +154,47 @@ func (a *HTTPActivity) Execute(input map[string]interface{}, config map[string]i
 	}
 
 	// Override headers from config
-	if headers, ok := config["headers"].(map[string]interface{}); ok {
-		for key, value := range headers {
-			if strVal, ok := value.(string); ok {
-				req.Header.Set(key, strVal)
-			}
+	for key, value := range cfg.Headers {
+		if strVal, ok := value.(string); ok {
+			req.Header.Set(key, strVal)
 		}
 	}
 
 	// Execute request — transport errors are captured as output, not fatal errors.
-	resp, err := a.client.Do(req)
+	start := time.Now()
+	resp, err := client.Do(req)
+	if errors.Is(err, egress.ErrDenied) {
+		// A refused destination is a flow error, not a response to inspect.
+		return nil, err
+	}
 	if err != nil {
-		return map[string]interface{}{
+		output := map[string]interface{}{
 			"status_code": 0,
 			"body":        nil,
 			"headers":     map[string]interface{}{},
 			"error":       err.Error(),
-		}, nil
+		}
+		if expect != nil {
+			return output, fmt.Errorf("%w: request failed: %v", ErrExpectationFailed, err)
+		}
+		return output, nil
 	}
 	defer resp.Body.Close()
 
 	// Read response body
 	respBody, err := io.ReadAll(resp.Body)
+	latency := time.Since(start)
 	if err != nil {
-		return map[string]interface{}{
+		output := map[string]interface{}{
 			"status_code": resp.StatusCode,
 			"body":        nil,
 			"headers":     map[string]interface{}{},
 			"error":       fmt.Sprintf("failed to read response body: %v", err),
-		}, nil
+		}
+		if expect != nil {
+			return output, fmt.Errorf("%w: %s", ErrExpectationFailed, output["error"])
+		}
+		return output, nil
 	}
 
 	// Try to parse as JSON, fall back to string
@@ -141,9 +205,15 @@ func (a *HTTPActivity) Execute(input map[string]interface{}, config map[string]i
 
 	// Return full response as output — HTTP 4xx/5xx are data, not fatal errors.
 	// The caller can inspect status_code via transitions/conditions.
-	return map[string]interface{}{
+	output := map[string]interface{}{
 		"status_code": resp.StatusCode,
 		"headers":     resp.Header,
 		"body":        responseData,
-	}, nil
+	}
+	if expect != nil {
+		if err := expect.check(resp.StatusCode, latency, responseData); err != nil {
+			return output, err
+		}
+	}
+	return output, nil
 }
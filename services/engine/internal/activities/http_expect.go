@@ -0,0 +1,257 @@
+package activities
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"flowjs-works/engine/internal/models"
+)
+
+// ErrExpectationFailed is returned by the http activity when the response
+// violates the node's "expect" config.
+var ErrExpectationFailed = errors.New("http: response expectation failed")
+
+// httpExpect is the parsed "expect" config of an http node:
+//
+//	"expect": {
+//	  "status": "2xx" | 201 | "200-299" | ["2xx", 304],
+//	  "max_latency_ms": 500,
+//	  "body": [{"path": "$.data.id", "exists": true},
+//	           {"path": "$.state", "equals": "ok"},
+//	           {"path": "$.ref", "matches": "^R-\\d+$"},
+//	           {"path": "$.items", "type": "array"}]
+//	}
+type httpExpect struct {
+	status     []statusRange
+	maxLatency time.Duration
+	body       []bodyAssertion
+}
+
+type statusRange struct{ min, max int }
+
+type bodyAssertion struct {
+	path      string
+	exists    *bool
+	equals    interface{}
+	hasEquals bool
+	matches   *regexp.Regexp
+	typ       string
+}
+
+var bodyAssertionTypes = map[string]bool{"string": true, "number": true, "boolean": true, "object": true, "array": true, "null": true}
+
+// parseHTTPExpect parses the "expect" key of an http node config. It returns
+// nil when the key is absent.
+func parseHTTPExpect(raw interface{}) (*httpExpect, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	m, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expect must be an object, got %T", raw)
+	}
+	exp := &httpExpect{}
+	if s, ok := m["status"]; ok && s != nil {
+		items, isList := s.([]interface{})
+		if !isList {
+			items = []interface{}{s}
+		}
+		for _, item := range items {
+			r, err := parseStatusRange(item)
+			if err != nil {
+				return nil, fmt.Errorf("expect.status: %w", err)
+			}
+			exp.status = append(exp.status, r)
+		}
+	}
+	if l, ok := m["max_latency_ms"]; ok && l != nil {
+		ms, ok := l.(float64)
+		if !ok || ms <= 0 {
+			return nil, fmt.Errorf("expect.max_latency_ms must be a positive number, got %v", l)
+		}
+		exp.maxLatency = time.Duration(ms * float64(time.Millisecond))
+	}
+	if b, ok := m["body"]; ok && b != nil {
+		items, ok := b.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expect.body must be an array of assertions, got %T", b)
+		}
+		for i, item := range items {
+			a, err := parseBodyAssertion(item)
+			if err != nil {
+				return nil, fmt.Errorf("expect.body[%d]: %w", i, err)
+			}
+			exp.body = append(exp.body, a)
+		}
+	}
+	return exp, nil
+}
+
+// parseStatusRange accepts a status code (201), a class ("2xx") or an
+// inclusive range ("200-299").
+func parseStatusRange(v interface{}) (statusRange, error) {
+	switch s := v.(type) {
+	case float64:
+		if s != float64(int(s)) || s < 100 || s > 599 {
+			return statusRange{}, fmt.Errorf("invalid status code %v", s)
+		}
+		return statusRange{int(s), int(s)}, nil
+	case string:
+		s = strings.TrimSpace(strings.ToLower(s))
+		if len(s) == 3 && strings.HasSuffix(s, "xx") && s[0] >= '1' && s[0] <= '5' {
+			base := int(s[0]-'0') * 100
+			return statusRange{base, base + 99}, nil
+		}
+		if lo, hi, ok := strings.Cut(s, "-"); ok {
+			from, err1 := strconv.Atoi(strings.TrimSpace(lo))
+			to, err2 := strconv.Atoi(strings.TrimSpace(hi))
+			if err1 == nil && err2 == nil && from >= 100 && to <= 599 && from <= to {
+				return statusRange{from, to}, nil
+			}
+		}
+		if code, err := strconv.Atoi(s); err == nil && code >= 100 && code <= 599 {
+			return statusRange{code, code}, nil
+		}
+	}
+	return statusRange{}, fmt.Errorf("invalid status %v (want 201, \"2xx\" or \"200-299\")", v)
+}
+
+func parseBodyAssertion(v interface{}) (bodyAssertion, error) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return bodyAssertion{}, fmt.Errorf("assertion must be an object, got %T", v)
+	}
+	path, _ := m["path"].(string)
+	if path == "" {
+		return bodyAssertion{}, errors.New("path is required")
+	}
+	a := bodyAssertion{path: path}
+	if e, ok := m["exists"]; ok {
+		b, ok := e.(bool)
+		if !ok {
+			return bodyAssertion{}, fmt.Errorf("exists must be a boolean, got %T", e)
+		}
+		a.exists = &b
+	}
+	a.equals, a.hasEquals = m["equals"]
+	if p, ok := m["matches"]; ok {
+		s, ok := p.(string)
+		if !ok {
+			return bodyAssertion{}, fmt.Errorf("matches must be a string, got %T", p)
+		}
+		re, err := regexp.Compile(s)
+		if err != nil {
+			return bodyAssertion{}, fmt.Errorf("matches: %w", err)
+		}
+		a.matches = re
+	}
+	if t, ok := m["type"]; ok {
+		s, _ := t.(string)
+		if !bodyAssertionTypes[s] {
+			return bodyAssertion{}, fmt.Errorf("type must be one of string, number, boolean, object, array, null; got %v", t)
+		}
+		a.typ = s
+	}
+	if a.exists == nil && !a.hasEquals && a.matches == nil && a.typ == "" {
+		return bodyAssertion{}, fmt.Errorf("assertion on %s needs exists, equals, matches or type", path)
+	}
+	return a, nil
+}
+
+// check returns an error wrapping ErrExpectationFailed that lists every
+// violated expectation, or nil.
+func (e *httpExpect) check(status int, latency time.Duration, body interface{}) error {
+	var violations []string
+	if len(e.status) > 0 {
+		ok := false
+		for _, r := range e.status {
+			if status >= r.min && status <= r.max {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			violations = append(violations, fmt.Sprintf("status %d not in %s", status, e.statusString()))
+		}
+	}
+	if e.maxLatency > 0 && latency > e.maxLatency {
+		violations = append(violations, fmt.Sprintf("latency %dms exceeds %dms", latency.Milliseconds(), e.maxLatency.Milliseconds()))
+	}
+	for _, a := range e.body {
+		if msg := a.check(body); msg != "" {
+			violations = append(violations, msg)
+		}
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrExpectationFailed, strings.Join(violations, "; "))
+}
+
+func (e *httpExpect) statusString() string {
+	parts := make([]string, len(e.status))
+	for i, r := range e.status {
+		if r.min == r.max {
+			parts[i] = strconv.Itoa(r.min)
+		} else {
+			parts[i] = fmt.Sprintf("%d-%d", r.min, r.max)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// check returns a description of the violation, or "" when body satisfies a.
+func (a bodyAssertion) check(body interface{}) string {
+	val, found := models.LookupPath(body, a.path)
+	if a.exists != nil && *a.exists != found {
+		if found {
+			return fmt.Sprintf("%s should not exist", a.path)
+		}
+		return fmt.Sprintf("%s does not exist", a.path)
+	}
+	if !found {
+		if a.hasEquals || a.matches != nil || a.typ != "" {
+			return fmt.Sprintf("%s does not exist", a.path)
+		}
+		return ""
+	}
+	if a.hasEquals && !reflect.DeepEqual(val, a.equals) {
+		return fmt.Sprintf("%s is %v, expected %v", a.path, val, a.equals)
+	}
+	if a.matches != nil {
+		s, ok := val.(string)
+		if !ok {
+			s = fmt.Sprint(val)
+		}
+		if !a.matches.MatchString(s) {
+			return fmt.Sprintf("%s (%q) does not match %q", a.path, s, a.matches.String())
+		}
+	}
+	if a.typ != "" && jsonTypeOf(val) != a.typ {
+		return fmt.Sprintf("%s is a %s, expected %s", a.path, jsonTypeOf(val), a.typ)
+	}
+	return ""
+}
+
+func jsonTypeOf(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case float64, int, int64:
+		return "number"
+	case bool:
+		return "boolean"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	}
+	return fmt.Sprintf("%T", v)
+}
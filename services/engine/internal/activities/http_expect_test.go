@@ -0,0 +1,110 @@
+package activities
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseHTTPExpect(t *testing.T) {
+	exp, err := parseHTTPExpect(nil)
+	require.NoError(t, err)
+	assert.Nil(t, exp)
+
+	exp, err = parseHTTPExpect(map[string]interface{}{
+		"status":         []interface{}{"2xx", float64(304), "400-404"},
+		"max_latency_ms": float64(250),
+		"body":           []interface{}{map[string]interface{}{"path": "$.id", "exists": true}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []statusRange{{200, 299}, {304, 304}, {400, 404}}, exp.status)
+	assert.Equal(t, 250*time.Millisecond, exp.maxLatency)
+	require.Len(t, exp.body, 1)
+
+	for name, raw := range map[string]interface{}{
+		"not an object":   "2xx",
+		"bad status":      map[string]interface{}{"status": "6xx"},
+		"reversed range":  map[string]interface{}{"status": "299-200"},
+		"bad latency":     map[string]interface{}{"max_latency_ms": float64(-1)},
+		"body not array":  map[string]interface{}{"body": map[string]interface{}{}},
+		"missing path":    map[string]interface{}{"body": []interface{}{map[string]interface{}{"exists": true}}},
+		"no operator":     map[string]interface{}{"body": []interface{}{map[string]interface{}{"path": "$.id"}}},
+		"bad regex":       map[string]interface{}{"body": []interface{}{map[string]interface{}{"path": "$.id", "matches": "("}}},
+		"unknown type":    map[string]interface{}{"body": []interface{}{map[string]interface{}{"path": "$.id", "type": "date"}}},
+		"exists not bool": map[string]interface{}{"body": []interface{}{map[string]interface{}{"path": "$.id", "exists": "yes"}}},
+	} {
+		_, err := parseHTTPExpect(raw)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestHTTPExpect_Check(t *testing.T) {
+	exp, err := parseHTTPExpect(map[string]interface{}{
+		"status":         "2xx",
+		"max_latency_ms": float64(100),
+		"body": []interface{}{
+			map[string]interface{}{"path": "$.data.id", "exists": true},
+			map[string]interface{}{"path": "$.state", "equals": "ok"},
+			map[string]interface{}{"path": "$.items[1].ref", "matches": `^R-\d+$`},
+			map[string]interface{}{"path": "$.items", "type": "array"},
+			map[string]interface{}{"path": "$.error", "exists": false},
+		},
+	})
+	require.NoError(t, err)
+
+	body := map[string]interface{}{
+		"data":  map[string]interface{}{"id": float64(7)},
+		"state": "ok",
+		"items": []interface{}{map[string]interface{}{"ref": "R-1"}, map[string]interface{}{"ref": "R-2"}},
+	}
+	assert.NoError(t, exp.check(200, 10*time.Millisecond, body))
+
+	err = exp.check(503, 300*time.Millisecond, map[string]interface{}{"state": "down", "items": "none", "error": "boom"})
+	require.ErrorIs(t, err, ErrExpectationFailed)
+	for _, want := range []string{"status 503 not in 200-299", "latency 300ms exceeds 100ms", "$.data.id does not exist", "$.state is down", "$.items[1].ref does not exist", "$.items is a string", "$.error should not exist"} {
+		assert.Contains(t, err.Error(), want)
+	}
+}
+
+func TestHTTPActivity_ExpectFailureReturnsOutputAndError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(`{"error":"db down"}`))
+	}))
+	defer srv.Close()
+
+	a := NewHTTPActivity()
+	out, err := a.Execute(nil, map[string]interface{}{
+		"url":    srv.URL,
+		"expect": map[string]interface{}{"status": "2xx"},
+	}, nil)
+	require.True(t, errors.Is(err, ErrExpectationFailed))
+	require.NotNil(t, out, "the response is kept for error transitions")
+	assert.Equal(t, http.StatusInternalServerError, out["status_code"])
+	assert.Equal(t, map[string]interface{}{"error": "db down"}, out["body"])
+
+	// Without expect the same response is plain data.
+	_, err = a.Execute(nil, map[string]interface{}{"url": srv.URL}, nil)
+	assert.NoError(t, err)
+}
+
+func TestHTTPActivity_ExpectTransportError(t *testing.T) {
+	a := NewHTTPActivity()
+	out, err := a.Execute(nil, map[string]interface{}{
+		"url":    "http://127.0.0.1:1",
+		"expect": map[string]interface{}{"max_latency_ms": float64(1000)},
+	}, nil)
+	assert.ErrorIs(t, err, ErrExpectationFailed)
+	assert.Equal(t, 0, out["status_code"])
+}
+
+func TestHTTPActivity_InvalidExpect(t *testing.T) {
+	a := NewHTTPActivity()
+	_, err := a.Execute(nil, map[string]interface{}{"url": "http://example.invalid", "expect": "2xx"}, nil)
+	assert.EqualError(t, err, "http activity: config field 'expect' must be an object, got string")
+}
@@ -5,6 +5,7 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"flowjs-works/engine/internal/egress"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -81,3 +82,16 @@ func TestHTTPActivity_ExplicitAuthHeaderOverridesSecret(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, "Bearer explicit-token", gotAuth)
 }
+
+// TestHTTPActivity_EgressDenied verifies that a request refused by the egress
+// policy fails the node instead of returning a status_code 0 output.
+func TestHTTPActivity_EgressDenied(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	egress.SetPolicy(&egress.Policy{DenyInternal: true})
+	t.Cleanup(func() { egress.SetPolicy(nil) })
+
+	out, err := NewHTTPActivity().Execute(nil, map[string]interface{}{"url": srv.URL}, nil)
+	assert.ErrorIs(t, err, egress.ErrDenied)
+	assert.Nil(t, out)
+}
@@ -0,0 +1,146 @@
+package activities
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"flowjs-works/engine/internal/egress"
+)
+
+// httpTransportOptions are the per-node connection settings of an HTTP
+// client: an outbound proxy, a client certificate for mutual TLS and extra
+// CA certificates to trust. The zero value uses the shared default client,
+// which honours HTTP_PROXY, HTTPS_PROXY and NO_PROXY. Cert, Key and CA are
+// usually injected from a "certificate" secret, at the top level or in Auth.
+type httpTransportOptions struct {
+	Proxy    string                 `config:"proxy" doc:"proxy URL (http, https or socks5); none connects directly even when HTTPS_PROXY is set"`
+	Cert     string                 `config:"cert" doc:"PEM client certificate for mutual TLS"`
+	Key      string                 `config:"key" doc:"PEM private key of cert"`
+	CABundle string                 `config:"ca_bundle" doc:"PEM CA certificates trusted in addition to the system pool"`
+	CA       string                 `config:"ca" doc:"CA certificates of a certificate secret, used without ca_bundle"`
+	Auth     map[string]interface{} `config:"auth" doc:"cert, key and ca of a certificate secret; they take precedence over the top-level fields"`
+}
+
+func (o *httpTransportOptions) validate() []FieldError {
+	for key, field := range map[string]*string{"cert": &o.Cert, "key": &o.Key, "ca": &o.CA} {
+		if v, ok := o.Auth[key].(string); ok {
+			*field = v
+		}
+	}
+	if (o.Cert == "") != (o.Key == "") {
+		return []FieldError{{"cert", "requires key, and key requires cert"}}
+	}
+	return nil
+}
+
+// trusted returns the extra CA certificates to trust.
+func (o httpTransportOptions) trusted() string {
+	if o.CABundle != "" {
+		return o.CABundle
+	}
+	return o.CA
+}
+
+// isDefault reports whether o needs no dedicated transport.
+func (o httpTransportOptions) isDefault() bool {
+	return o.Proxy == "" && o.Cert == "" && o.trusted() == ""
+}
+
+// cacheKey identifies o without keeping key material in map keys.
+func (o httpTransportOptions) cacheKey() string {
+	sum := sha256.Sum256([]byte(o.Proxy + "\x00" + o.Cert + "\x00" + o.Key + "\x00" + o.trusted()))
+	return hex.EncodeToString(sum[:])
+}
+
+// defaultHTTPTransport returns a copy of http.DefaultTransport whose
+// connections go through the egress policy.
+func defaultHTTPTransport() *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.DialContext = egress.Dialer(defaultNetDialTimeout).DialContext
+	return t
+}
+
+// newTransport builds a transport applying o on top of defaultHTTPTransport.
+func (o httpTransportOptions) newTransport() (*http.Transport, error) {
+	t := defaultHTTPTransport()
+	switch strings.ToLower(o.Proxy) {
+	case "":
+	case "none", "direct":
+		t.Proxy = nil
+	default:
+		u, err := url.Parse(o.Proxy)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL %q", o.Proxy)
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return nil, fmt.Errorf("proxy scheme must be http, https or socks5; got %q", u.Scheme)
+		}
+		t.Proxy = http.ProxyURL(u)
+	}
+	caBundle := o.trusted()
+	if o.Cert == "" && caBundle == "" {
+		return t, nil
+	}
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if o.Cert != "" {
+		pair, err := tls.X509KeyPair([]byte(o.Cert), []byte(o.Key))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{pair}
+	}
+	if caBundle != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM([]byte(caBundle)) {
+			return nil, fmt.Errorf("ca_bundle contains no valid PEM certificate")
+		}
+		tlsConfig.RootCAs = pool
+	}
+	t.TLSClientConfig = tlsConfig
+	return t, nil
+}
+
+// httpClientPool hands out HTTP clients per set of transport options, so
+// nodes sharing a proxy or client certificate also share a connection pool.
+type httpClientPool struct {
+	base *http.Client
+
+	mu      sync.Mutex
+	clients map[string]*http.Client
+}
+
+func newHTTPClientPool(base *http.Client) *httpClientPool {
+	return &httpClientPool{base: base, clients: make(map[string]*http.Client)}
+}
+
+// client returns the client for the transport settings opts.
+func (p *httpClientPool) client(opts httpTransportOptions) (*http.Client, error) {
+	if opts.isDefault() {
+		return p.base, nil
+	}
+	key := opts.cacheKey()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if c, ok := p.clients[key]; ok {
+		return c, nil
+	}
+	transport, err := opts.newTransport()
+	if err != nil {
+		return nil, err
+	}
+	c := &http.Client{Timeout: p.base.Timeout, Transport: transport}
+	p.clients[key] = c
+	return c, nil
+}
@@ -0,0 +1,147 @@
+package activities
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCert issues a certificate signed by parent (self-signed when nil) and
+// returns it with its PEM certificate and key.
+func testCert(t *testing.T, cn string, isCA bool, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey, string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	if parent == nil {
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	certPEM := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	keyPEM := string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+	return cert, key, certPEM, keyPEM
+}
+
+// TestHTTPActivity_MutualTLS verifies that a client certificate and CA bundle
+// from a certificate secret let the node call a server requiring mTLS.
+func TestHTTPActivity_MutualTLS(t *testing.T) {
+	ca, caKey, caPEM, _ := testCert(t, "test-ca", true, nil, nil)
+	_, _, serverPEM, serverKeyPEM := testCert(t, "server", false, ca, caKey)
+	_, _, clientPEM, clientKeyPEM := testCert(t, "partner-client", false, ca, caKey)
+
+	serverPair, err := tls.X509KeyPair([]byte(serverPEM), []byte(serverKeyPEM))
+	require.NoError(t, err)
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+
+	var gotCN string
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotCN = r.TLS.PeerCertificates[0].Subject.CommonName
+		w.WriteHeader(http.StatusOK)
+	}))
+	srv.TLS = &tls.Config{Certificates: []tls.Certificate{serverPair}, ClientCAs: pool, ClientAuth: tls.RequireAndVerifyClientCert}
+	srv.StartTLS()
+	defer srv.Close()
+
+	a := NewHTTPActivity()
+	out, err := a.Execute(nil, map[string]interface{}{
+		"url":  srv.URL,
+		"cert": clientPEM,
+		"key":  clientKeyPEM,
+		"ca":   caPEM,
+	}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, out["status_code"])
+	assert.Equal(t, "partner-client", gotCN)
+
+	// A certificate secret resolved into the auth map works the same way.
+	gotCN = ""
+	out, err = a.Execute(nil, map[string]interface{}{
+		"url":  srv.URL,
+		"auth": map[string]interface{}{"cert": clientPEM, "key": clientKeyPEM, "ca": caPEM},
+	}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, out["status_code"])
+	assert.Equal(t, "partner-client", gotCN)
+
+	// Without the client certificate the handshake is refused.
+	out, err = a.Execute(nil, map[string]interface{}{"url": srv.URL, "ca_bundle": caPEM}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 0, out["status_code"])
+	assert.NotEmpty(t, out["error"])
+}
+
+// TestHTTPActivity_NodeProxy verifies that requests go through the proxy set
+// on the node.
+func TestHTTPActivity_NodeProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer proxy.Close()
+
+	a := NewHTTPActivity()
+	out, err := a.Execute(nil, map[string]interface{}{
+		"url":   "http://partner.invalid/orders",
+		"proxy": proxy.URL,
+	}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusAccepted, out["status_code"])
+	assert.Equal(t, "http://partner.invalid/orders", proxied)
+}
+
+func TestHTTPTransportOptions_Invalid(t *testing.T) {
+	a := NewHTTPActivity()
+	for name, config := range map[string]map[string]interface{}{
+		"cert without key": {"url": "http://x", "cert": "pem"},
+		"auth without key": {"url": "http://x", "auth": map[string]interface{}{"cert": "pem"}},
+		"bad proxy scheme": {"url": "http://x", "proxy": "ftp://proxy:21"},
+		"bad ca bundle":    {"url": "http://x", "ca_bundle": "not pem"},
+	} {
+		_, err := a.Execute(nil, config, nil)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestHTTPClientPool_ReusesClients(t *testing.T) {
+	p := newHTTPClientPool(&http.Client{Timeout: time.Second})
+	base, err := p.client(httpTransportOptions{})
+	require.NoError(t, err)
+	assert.Same(t, p.base, base)
+
+	c1, err := p.client(httpTransportOptions{Proxy: "http://proxy:3128"})
+	require.NoError(t, err)
+	c2, err := p.client(httpTransportOptions{Proxy: "http://proxy:3128"})
+	require.NoError(t, err)
+	assert.Same(t, c1, c2)
+	assert.Equal(t, time.Second, c1.Timeout)
+}
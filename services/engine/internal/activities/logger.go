@@ -1,14 +1,23 @@
 package activities
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"strings"
 
+	"flowjs-works/engine/internal/logging"
 	"flowjs-works/engine/internal/models"
 )
 
+// loggerConfig is the config of logger and log nodes.
+type loggerConfig struct {
+	Level string `config:"level,default=info" doc:"debug, info, warn or error; other levels log at info"`
+	// Message is only read by log nodes.
+	Message string `config:"message" doc:"message logged when input.message is not mapped (log only)"`
+}
+
 // LoggerActivity logs messages to the console
 type LoggerActivity struct{}
 
@@ -17,15 +26,16 @@ func (a *LoggerActivity) Name() string {
 	return "logger"
 }
 
+// ConfigSpec returns the config struct of logger nodes.
+func (a *LoggerActivity) ConfigSpec() interface{} { return &loggerConfig{} }
+
 // Execute logs the input data
 func (a *LoggerActivity) Execute(input map[string]interface{}, config map[string]interface{}, ctx *models.ExecutionContext) (map[string]interface{}, error) {
-	// Get the log level from config (default to "info")
-	level := "info"
-	if levelVal, ok := config["level"]; ok {
-		if levelStr, ok := levelVal.(string); ok {
-			level = levelStr
-		}
+	var cfg loggerConfig
+	if err := decodeConfig(a.Name(), config, &cfg); err != nil {
+		return nil, err
 	}
+	level := cfg.Level
 
 	// Get the message from input
 	message := ""
@@ -51,7 +61,7 @@ func (a *LoggerActivity) Execute(input map[string]interface{}, config map[string
 	}
 
 	// Log the message
-	log.Printf("[%s] %s", level, message)
+	logFlowMessage(ctx, level, message)
 
 	// Return the logged data as output
 	return map[string]interface{}{
@@ -68,13 +78,15 @@ type LogActivity struct{}
 
 func (a *LogActivity) Name() string { return "log" }
 
+// ConfigSpec returns the config struct of log nodes.
+func (a *LogActivity) ConfigSpec() interface{} { return &loggerConfig{} }
+
 func (a *LogActivity) Execute(input map[string]interface{}, config map[string]interface{}, ctx *models.ExecutionContext) (map[string]interface{}, error) {
-	level := "INFO"
-	if levelVal, ok := config["level"]; ok {
-		if levelStr, ok := levelVal.(string); ok {
-			level = strings.ToUpper(levelStr)
-		}
+	var cfg loggerConfig
+	if err := decodeConfig(a.Name(), config, &cfg); err != nil {
+		return nil, err
 	}
+	level := strings.ToUpper(cfg.Level)
 
 	message := ""
 	if msgVal, ok := input["message"]; ok {
@@ -88,10 +100,8 @@ func (a *LogActivity) Execute(input map[string]interface{}, config map[string]in
 			}
 			message = string(jsonBytes)
 		}
-	} else if cfgMsg, ok := config["message"]; ok {
-		if s, ok := cfgMsg.(string); ok {
-			message = s
-		}
+	} else if _, ok := config["message"]; ok {
+		message = cfg.Message
 	} else {
 		jsonBytes, err := json.Marshal(input)
 		if err != nil {
@@ -100,10 +110,29 @@ func (a *LogActivity) Execute(input map[string]interface{}, config map[string]in
 		message = string(jsonBytes)
 	}
 
-	log.Printf("[%s] %s", level, message)
+	logFlowMessage(ctx, level, message)
 	return map[string]interface{}{
 		"logged":  true,
 		"level":   level,
 		"message": message,
 	}, nil
 }
+
+// logFlowMessage writes a message emitted by a logger/log node through the
+// engine logger, tagged with the execution it belongs to.
+func logFlowMessage(ctx *models.ExecutionContext, level, message string) {
+	logging.ForExecution(ctx).Log(context.Background(), flowLogLevel(level), message, "source", "flow")
+}
+
+// flowLogLevel maps a node's level config to a slog level; unknown levels log at info.
+func flowLogLevel(level string) slog.Level {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	}
+	return slog.LevelInfo
+}
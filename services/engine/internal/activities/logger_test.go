@@ -0,0 +1,26 @@
+package activities
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlowLogLevel(t *testing.T) {
+	cases := map[string]slog.Level{
+		"debug": slog.LevelDebug, "INFO": slog.LevelInfo, "WARNING": slog.LevelWarn,
+		"warn": slog.LevelWarn, "error": slog.LevelError, "verbose": slog.LevelInfo,
+	}
+	for in, want := range cases {
+		assert.Equal(t, want, flowLogLevel(in), in)
+	}
+}
+
+func TestLogActivity_NormalizesLevel(t *testing.T) {
+	out, err := (&LogActivity{}).Execute(nil, map[string]interface{}{"level": "warning", "message": "hi"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "WARNING", out["level"])
+	assert.Equal(t, "hi", out["message"])
+}
@@ -5,198 +5,236 @@ import (
 	"fmt"
 	"net"
 	"net/smtp"
+	"strconv"
 	"strings"
+	"time"
 
-	"github.com/google/uuid"
-
+	"flowjs-works/engine/internal/egress"
 	fmodels "flowjs-works/engine/internal/models"
 )
 
-// MailActivity implements the `mail` node type.
-// config fields:
-//
-//	action: "send" | "receive"
-//
-// Send: host, port(int), security("TLS"|"STARTTLS"|"NONE"), auth(map: user, password),
+// mailConfig is the config of a mail node. The message fields can also come
+// from the node input, which overrides config.
+type mailConfig struct {
+	Action   string                 `config:"action,default=send,enum=send|receive"`
+	Host     string                 `config:"host" doc:"SMTP server; required to send"`
+	Port     int                    `config:"port,default=587"`
+	Security string                 `config:"security,default=STARTTLS" doc:"TLS, STARTTLS or NONE"`
+	Auth     map[string]interface{} `config:"auth" doc:"SMTP user and password"`
+
+	From        string      `config:"from" doc:"sender; the SMTP user when empty"`
+	To          interface{} `config:"to" doc:"recipient address or list of addresses"`
+	Cc          interface{} `config:"cc" doc:"address or list of addresses"`
+	Bcc         interface{} `config:"bcc" doc:"address or list of addresses"`
+	ReplyTo     interface{} `config:"reply_to" doc:"address or list of addresses"`
+	Subject     string      `config:"subject"`
+	Body        string      `config:"body" doc:"plain text body, or HTML with content_type text/html"`
+	HTML        string      `config:"html" doc:"HTML body, sent as an alternative to body"`
+	ContentType string      `config:"content_type" doc:"text/plain or text/html; applies to body"`
+	Priority    string      `config:"priority" doc:"high, normal or low"`
+	Attachments interface{} `config:"attachments" doc:"list of local paths, or objects with a path, base64 content or file ref"`
+}
+
+func (c *mailConfig) validate() []FieldError {
+	var problems []FieldError
+	if c.Action == "send" && c.Host == "" {
+		problems = append(problems, FieldError{"host", "is required"})
+	}
+	switch strings.ToUpper(c.Security) {
+	case "TLS", "STARTTLS", "NONE":
+	default:
+		problems = append(problems, FieldError{"security", fmt.Sprintf("must be one of TLS, STARTTLS, NONE, got %q", c.Security)})
+	}
+	return problems
+}
+
+// MailActivity implements the `mail` node type (config: mailConfig).
 //
-//	to([]string), cc([]string), subject, body, content_type("text/plain"|"text/html")
+// Send delivers the message over SMTP; attachments are described in
+// mailAttachments. The output carries the Message-ID header that was sent
+// and the server's reply to the message, which holds its queue id on most
+// servers.
 //
 // Receive: returns stub {"messages": [], "note": "imap receive not yet implemented"}
 type MailActivity struct{}
 
 func (a *MailActivity) Name() string { return "mail" }
 
+// ConfigSpec returns the config struct of mail nodes.
+func (a *MailActivity) ConfigSpec() interface{} { return &mailConfig{} }
+
 func (a *MailActivity) Execute(input map[string]interface{}, config map[string]interface{}, ctx *fmodels.ExecutionContext) (map[string]interface{}, error) {
-	action, _ := config["action"].(string)
-	if action == "" {
-		action = "send"
-	}
-	switch action {
-	case "send":
-		return mailSend(config)
-	case "receive":
+	var cfg mailConfig
+	if err := decodeConfig(a.Name(), config, &cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Action == "receive" {
 		return map[string]interface{}{
 			"messages": []interface{}{},
 			"note":     "imap receive not yet implemented",
 		}, nil
-	default:
-		return nil, fmt.Errorf("mail activity: unknown action %q", action)
 	}
+	return mailSend(input, &cfg, config, ctx)
 }
 
-func mailSend(config map[string]interface{}) (map[string]interface{}, error) {
-	host, _ := config["host"].(string)
-	if host == "" {
-		return nil, fmt.Errorf("mail activity: missing required config field 'host'")
+// mailField returns input[key] when the node input sets it, def otherwise.
+func mailField(input map[string]interface{}, key string, def interface{}) interface{} {
+	if v, ok := input[key]; ok && v != nil {
+		return v
 	}
+	return def
+}
 
-	port := 587
-	switch v := config["port"].(type) {
-	case int:
-		port = v
-	case float64:
-		port = int(v)
-	}
+func mailSend(input map[string]interface{}, cfg *mailConfig, config map[string]interface{}, ctx *fmodels.ExecutionContext) (map[string]interface{}, error) {
+	host := cfg.Host
 
-	security, _ := config["security"].(string)
-	if security == "" {
-		security = "STARTTLS"
+	// Credentials are read from config["auth"] (nested map) when present, or from
+	// flat top-level keys (user, password) injected by the secret resolver.
+	fromUser := getCredential(config, "user")
+	fromPass := getCredential(config, "password")
+
+	msg := &mailMessage{
+		To:      mailAddresses(mailField(input, "to", cfg.To)),
+		Cc:      mailAddresses(mailField(input, "cc", cfg.Cc)),
+		Bcc:     mailAddresses(mailField(input, "bcc", cfg.Bcc)),
+		ReplyTo: mailAddresses(mailField(input, "reply_to", cfg.ReplyTo)),
 	}
-
-	contentType, _ := config["content_type"].(string)
-	if contentType == "" {
-		contentType = "text/plain"
+	explicitFrom, _ := mailField(input, "from", cfg.From).(string)
+	msg.From = explicitFrom
+	if msg.From == "" {
+		msg.From = fromUser
+	}
+	msg.Subject, _ = mailField(input, "subject", cfg.Subject).(string)
+	msg.Priority, _ = mailField(input, "priority", cfg.Priority).(string)
+	msg.HTML, _ = mailField(input, "html", cfg.HTML).(string)
+	body, _ := mailField(input, "body", cfg.Body).(string)
+	if contentType, _ := mailField(input, "content_type", cfg.ContentType).(string); strings.EqualFold(contentType, "text/html") && msg.HTML == "" {
+		msg.HTML = body
+	} else {
+		msg.Text = body
+	}
+	if len(msg.recipients()) == 0 {
+		return nil, fmt.Errorf("mail activity: at least one of to, cc or bcc is required")
+	}
+	var err error
+	if msg.Attach, err = mailAttachments(mailField(input, "attachments", cfg.Attachments), ctx); err != nil {
+		return nil, fmt.Errorf("mail activity: %w", err)
 	}
 
-	subject, _ := config["subject"].(string)
-	body, _ := config["body"].(string)
-
-	var toList []string
-	if to, ok := config["to"].([]interface{}); ok {
-		for _, t := range to {
-			if s, ok := t.(string); ok {
-				toList = append(toList, s)
-			}
+	envelopeFrom := msg.From
+	if explicitFrom != "" {
+		if envelopeFrom, err = envelopeAddress(msg.From); err != nil {
+			return nil, fmt.Errorf("mail activity: from: %w", err)
 		}
+	} else if addr, err := envelopeAddress(msg.From); err == nil {
+		// Without from the SMTP user is the sender. It is not always an
+		// address (e.g. "apikey") and is then used as-is.
+		envelopeFrom = addr
 	}
-	var ccList []string
-	if cc, ok := config["cc"].([]interface{}); ok {
-		for _, c := range cc {
-			if s, ok := c.(string); ok {
-				ccList = append(ccList, s)
-			}
+	rcpts := make([]string, 0, len(msg.recipients()))
+	for _, r := range msg.recipients() {
+		addr, err := envelopeAddress(r)
+		if err != nil {
+			return nil, fmt.Errorf("mail activity: %w", err)
 		}
+		rcpts = append(rcpts, addr)
 	}
 
-	var fromUser, fromPass string
-	// Credentials are read from config["auth"] (nested map) when present, or from
-	// flat top-level keys (user, password) injected by the secret resolver.
-	fromUser = getCredential(config, "user")
-	fromPass = getCredential(config, "password")
-
-	addr := fmt.Sprintf("%s:%d", host, port)
-	headers := fmt.Sprintf("From: %s\r\nTo: %s\r\nCc: %s\r\nSubject: %s\r\nContent-Type: %s\r\n\r\n%s",
-		fromUser,
-		strings.Join(toList, ", "),
-		strings.Join(ccList, ", "),
-		subject,
-		contentType,
-		body,
-	)
-	msgBytes := []byte(headers)
+	messageID := newMessageID(envelopeFrom, host)
+	msgBytes, err := msg.build(messageID, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("mail activity: build message: %w", err)
+	}
 
 	var auth smtp.Auth
 	if fromUser != "" {
 		auth = smtp.PlainAuth("", fromUser, fromPass, host)
 	}
 
-	var sendErr error
-	switch strings.ToUpper(security) {
-	case "TLS":
-		tlsCfg := &tls.Config{ServerName: host}
-		conn, err := tls.Dial("tcp", addr, tlsCfg)
+	addr := net.JoinHostPort(host, strconv.Itoa(cfg.Port))
+	var conn net.Conn
+	mode := strings.ToUpper(cfg.Security)
+	if mode == "TLS" {
+		conn, err = tls.DialWithDialer(egress.Dialer(0), "tcp", addr, &tls.Config{ServerName: host})
 		if err != nil {
 			return nil, fmt.Errorf("mail activity: TLS dial failed: %w", err)
 		}
-		client, err := smtp.NewClient(conn, host)
-		if err != nil {
-			return nil, fmt.Errorf("mail activity: SMTP client failed: %w", err)
-		}
-		defer client.Close()
-		if auth != nil {
-			if err := client.Auth(auth); err != nil {
-				return nil, fmt.Errorf("mail activity: SMTP auth failed: %w", err)
-			}
-		}
-		if err := client.Mail(fromUser); err != nil {
-			return nil, fmt.Errorf("mail activity: MAIL FROM failed: %w", err)
-		}
-		recipients := append(toList, ccList...)
-		for _, r := range recipients {
-			if err := client.Rcpt(r); err != nil {
-				return nil, fmt.Errorf("mail activity: RCPT TO failed: %w", err)
-			}
-		}
-		w, err := client.Data()
+	} else {
+		conn, err = egress.Dialer(0).Dial("tcp", addr)
 		if err != nil {
-			return nil, fmt.Errorf("mail activity: DATA failed: %w", err)
-		}
-		if _, err := w.Write(msgBytes); err != nil {
-			return nil, fmt.Errorf("mail activity: write failed: %w", err)
+			return nil, fmt.Errorf("mail activity: dial failed: %w", err)
 		}
-		w.Close()
+	}
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("mail activity: SMTP client failed: %w", err)
+	}
+	defer client.Close()
 
+	switch mode {
+	case "TLS":
 	case "NONE":
-		recipients := append(toList, ccList...)
-		sendErr = smtp.SendMail(addr, nil, fromUser, recipients, msgBytes)
-
+		// Plain connections never send credentials.
+		auth = nil
 	default: // STARTTLS
-		tlsCfg := &tls.Config{ServerName: host}
-		conn, err := net.Dial("tcp", addr)
-		if err != nil {
-			return nil, fmt.Errorf("mail activity: dial failed: %w", err)
-		}
-		client, err := smtp.NewClient(conn, host)
-		if err != nil {
-			return nil, fmt.Errorf("mail activity: SMTP client failed: %w", err)
-		}
-		defer client.Close()
 		if ok, _ := client.Extension("STARTTLS"); ok {
-			if err := client.StartTLS(tlsCfg); err != nil {
+			if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
 				return nil, fmt.Errorf("mail activity: STARTTLS failed: %w", err)
 			}
 		}
-		if auth != nil {
-			if err := client.Auth(auth); err != nil {
-				return nil, fmt.Errorf("mail activity: SMTP auth failed: %w", err)
-			}
-		}
-		if err := client.Mail(fromUser); err != nil {
-			return nil, fmt.Errorf("mail activity: MAIL FROM failed: %w", err)
-		}
-		recipients := append(toList, ccList...)
-		for _, r := range recipients {
-			if err := client.Rcpt(r); err != nil {
-				return nil, fmt.Errorf("mail activity: RCPT TO failed: %w", err)
-			}
-		}
-		w, err := client.Data()
-		if err != nil {
-			return nil, fmt.Errorf("mail activity: DATA failed: %w", err)
-		}
-		if _, err := w.Write(msgBytes); err != nil {
-			return nil, fmt.Errorf("mail activity: write failed: %w", err)
+	}
+	if auth != nil {
+		if err := client.Auth(auth); err != nil {
+			return nil, fmt.Errorf("mail activity: SMTP auth failed: %w", err)
 		}
-		w.Close()
 	}
-
-	if sendErr != nil {
-		return nil, fmt.Errorf("mail activity: send failed: %w", sendErr)
+	reply, err := smtpSend(client, envelopeFrom, rcpts, msgBytes)
+	if err != nil {
+		return nil, fmt.Errorf("mail activity: %w", err)
 	}
+	_ = client.Quit()
 
-	messageID := uuid.New().String()
 	return map[string]interface{}{
-		"sent":       true,
-		"message_id": messageID,
+		"sent":            true,
+		"message_id":      messageID,
+		"server_response": reply,
+		"recipients":      len(rcpts),
 	}, nil
 }
+
+// smtpSend runs MAIL, RCPT and DATA on client and returns the server's reply
+// to the message (e.g. "2.0.0 Ok: queued as 4Bq1x"), which net/smtp's Data
+// writer discards.
+func smtpSend(client *smtp.Client, from string, rcpts []string, msg []byte) (string, error) {
+	if err := client.Mail(from); err != nil {
+		return "", fmt.Errorf("MAIL FROM failed: %w", err)
+	}
+	for _, r := range rcpts {
+		if err := client.Rcpt(r); err != nil {
+			return "", fmt.Errorf("RCPT TO %s failed: %w", r, err)
+		}
+	}
+	id, err := client.Text.Cmd("DATA")
+	if err != nil {
+		return "", fmt.Errorf("DATA failed: %w", err)
+	}
+	client.Text.StartResponse(id)
+	_, _, err = client.Text.ReadResponse(354)
+	client.Text.EndResponse(id)
+	if err != nil {
+		return "", fmt.Errorf("DATA failed: %w", err)
+	}
+	w := client.Text.DotWriter()
+	if _, err := w.Write(msg); err != nil {
+		return "", fmt.Errorf("write failed: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return "", fmt.Errorf("write failed: %w", err)
+	}
+	_, reply, err := client.Text.ReadResponse(250)
+	if err != nil {
+		return "", fmt.Errorf("message rejected: %w", err)
+	}
+	return reply, nil
+}
@@ -0,0 +1,351 @@
+package activities
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	fmodels "flowjs-works/engine/internal/models"
+)
+
+// maxMailAttachmentBytes caps the attachments of one message; most servers
+// reject larger messages anyway.
+const maxMailAttachmentBytes = 25 << 20
+
+// mailMessage is an outgoing message before MIME encoding.
+type mailMessage struct {
+	From     string
+	To       []string
+	Cc       []string
+	Bcc      []string
+	ReplyTo  []string
+	Subject  string
+	Text     string
+	HTML     string
+	Priority string
+	Attach   []mailAttachment
+}
+
+type mailAttachment struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+// recipients returns every envelope recipient, Bcc included.
+func (m *mailMessage) recipients() []string {
+	all := make([]string, 0, len(m.To)+len(m.Cc)+len(m.Bcc))
+	all = append(all, m.To...)
+	all = append(all, m.Cc...)
+	return append(all, m.Bcc...)
+}
+
+// mailAddresses reads a string or string list, accepting comma-separated
+// addresses in a string.
+func mailAddresses(v interface{}) []string {
+	var out []string
+	add := func(s string) {
+		for _, a := range strings.Split(s, ",") {
+			if a = strings.TrimSpace(a); a != "" {
+				out = append(out, a)
+			}
+		}
+	}
+	switch t := v.(type) {
+	case string:
+		add(t)
+	case []interface{}:
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				add(s)
+			}
+		}
+	case []string:
+		for _, s := range t {
+			add(s)
+		}
+	}
+	return out
+}
+
+// envelopeAddress returns the bare address of a header address such as
+// `"Ops" <ops@example.com>`.
+func envelopeAddress(addr string) (string, error) {
+	a, err := mail.ParseAddress(addr)
+	if err != nil {
+		return "", fmt.Errorf("invalid address %q: %w", addr, err)
+	}
+	return a.Address, nil
+}
+
+// mailAttachments loads the attachment list. Each entry is a local path, or
+// an object with one of:
+//
+//	{"path": "/data/report.pdf", "filename": "...", "content_type": "..."}
+//	{"content": "<base64>", "filename": "a.csv", "content_type": "text/csv"}
+//	{"ref": "mem://...", "name": "a.csv"}  — an in-memory file (see FileRef)
+//
+// The {filename, content_type, content} entries of an email trigger can be
+// passed through unchanged.
+func mailAttachments(v interface{}, ctx *fmodels.ExecutionContext) ([]mailAttachment, error) {
+	var list []interface{}
+	switch t := v.(type) {
+	case nil:
+		return nil, nil
+	case []interface{}:
+		list = t
+	case string:
+		list = []interface{}{t}
+	default:
+		return nil, fmt.Errorf("attachments must be a list, got %T", v)
+	}
+	var out []mailAttachment
+	total := 0
+	for i, item := range list {
+		att, err := loadMailAttachment(item, ctx)
+		if err != nil {
+			return nil, fmt.Errorf("attachments[%d]: %w", i, err)
+		}
+		total += len(att.Data)
+		if total > maxMailAttachmentBytes {
+			return nil, fmt.Errorf("attachments exceed %d bytes", maxMailAttachmentBytes)
+		}
+		if att.ContentType == "" {
+			att.ContentType = mime.TypeByExtension(filepath.Ext(att.Name))
+		}
+		if att.ContentType == "" {
+			att.ContentType = "application/octet-stream"
+		}
+		out = append(out, att)
+	}
+	return out, nil
+}
+
+func loadMailAttachment(item interface{}, ctx *fmodels.ExecutionContext) (mailAttachment, error) {
+	if path, ok := item.(string); ok {
+		item = map[string]interface{}{"path": path}
+	}
+	m, ok := item.(map[string]interface{})
+	if !ok {
+		return mailAttachment{}, fmt.Errorf("must be a path or an object, got %T", item)
+	}
+	var att mailAttachment
+	att.ContentType, _ = m["content_type"].(string)
+	att.Name, _ = m["filename"].(string)
+	if att.Name == "" {
+		att.Name, _ = m["name"].(string)
+	}
+	path, _ := m["path"].(string)
+	content, hasContent := m["content"].(string)
+	ref, _ := m["ref"].(string)
+
+	switch {
+	case path != "":
+		localPath, err := localFilePath(ctx, path)
+		if err != nil {
+			return mailAttachment{}, err
+		}
+		data, err := os.ReadFile(localPath)
+		if err != nil {
+			return mailAttachment{}, err
+		}
+		att.Data = data
+		if att.Name == "" {
+			att.Name = filepath.Base(path)
+		}
+	case hasContent:
+		data, err := base64.StdEncoding.DecodeString(content)
+		if err != nil {
+			return mailAttachment{}, fmt.Errorf("content is not valid base64: %w", err)
+		}
+		att.Data = data
+	case ref != "":
+		checksum, _ := m["sha256"].(string)
+		r, _, err := openFileRef(ctx, FileRef{Ref: ref, Checksum: checksum})
+		if err != nil {
+			return mailAttachment{}, err
+		}
+		defer r.Close()
+		if att.Data, err = io.ReadAll(r); err != nil {
+			return mailAttachment{}, err
+		}
+	default:
+		return mailAttachment{}, fmt.Errorf("needs path, content (base64) or ref")
+	}
+	if att.Name == "" {
+		return mailAttachment{}, fmt.Errorf("filename is required for content and ref attachments")
+	}
+	return att, nil
+}
+
+// mailPriorities maps the priority config to X-Priority values.
+var mailPriorities = map[string]string{"high": "1 (Highest)", "normal": "3 (Normal)", "low": "5 (Lowest)"}
+
+// build encodes m as an RFC 5322 message with messageID as its Message-ID.
+// The body is text/plain, text/html or multipart/alternative with both,
+// wrapped in multipart/mixed when there are attachments.
+func (m *mailMessage) build(messageID string, now time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	header := func(k, v string) {
+		if v != "" {
+			fmt.Fprintf(&buf, "%s: %s\r\n", k, v)
+		}
+	}
+	header("From", m.From)
+	header("To", strings.Join(m.To, ", "))
+	header("Cc", strings.Join(m.Cc, ", "))
+	header("Reply-To", strings.Join(m.ReplyTo, ", "))
+	header("Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	header("Date", now.Format(time.RFC1123Z))
+	header("Message-ID", messageID)
+	if p, ok := mailPriorities[strings.ToLower(m.Priority)]; ok {
+		header("X-Priority", p)
+	}
+	header("MIME-Version", "1.0")
+
+	if len(m.Attach) == 0 {
+		if err := m.writeBody(topLevel{&buf}); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	mixed := multipart.NewWriter(&buf)
+	header("Content-Type", mime.FormatMediaType("multipart/mixed", map[string]string{"boundary": mixed.Boundary()}))
+	buf.WriteString("\r\n")
+	body := &partBuffer{}
+	if err := m.writeBody(body); err != nil {
+		return nil, err
+	}
+	part, err := mixed.CreatePart(body.header)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := part.Write(body.Bytes()); err != nil {
+		return nil, err
+	}
+	for _, att := range m.Attach {
+		h := textproto.MIMEHeader{}
+		h.Set("Content-Type", mime.FormatMediaType(att.ContentType, map[string]string{"name": att.Name}))
+		h.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": att.Name}))
+		h.Set("Content-Transfer-Encoding", "base64")
+		part, err := mixed.CreatePart(h)
+		if err != nil {
+			return nil, err
+		}
+		if err := writeBase64Lines(part, att.Data); err != nil {
+			return nil, err
+		}
+	}
+	if err := mixed.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// bodyWriter receives a body part: its headers, then its content.
+type bodyWriter interface {
+	io.Writer
+	setHeader(h textproto.MIMEHeader)
+}
+
+// partBuffer collects a body part that becomes one part of multipart/mixed.
+type partBuffer struct {
+	bytes.Buffer
+	header textproto.MIMEHeader
+}
+
+func (p *partBuffer) setHeader(h textproto.MIMEHeader) { p.header = h }
+
+// topLevel writes a body part's headers straight into the message headers.
+type topLevel struct{ *bytes.Buffer }
+
+func (t topLevel) setHeader(h textproto.MIMEHeader) {
+	for _, k := range []string{"Content-Type", "Content-Transfer-Encoding"} {
+		if v := h.Get(k); v != "" {
+			fmt.Fprintf(t.Buffer, "%s: %s\r\n", k, v)
+		}
+	}
+	t.Buffer.WriteString("\r\n")
+}
+
+func (m *mailMessage) writeBody(w bodyWriter) error {
+	switch {
+	case m.Text != "" && m.HTML != "":
+		var alt bytes.Buffer
+		mw := multipart.NewWriter(&alt)
+		for _, p := range []struct{ ct, body string }{{"text/plain", m.Text}, {"text/html", m.HTML}} {
+			part, err := mw.CreatePart(textHeader(p.ct))
+			if err != nil {
+				return err
+			}
+			if err := writeQuotedPrintable(part, p.body); err != nil {
+				return err
+			}
+		}
+		if err := mw.Close(); err != nil {
+			return err
+		}
+		h := textproto.MIMEHeader{}
+		h.Set("Content-Type", mime.FormatMediaType("multipart/alternative", map[string]string{"boundary": mw.Boundary()}))
+		w.setHeader(h)
+		_, err := w.Write(alt.Bytes())
+		return err
+	case m.HTML != "":
+		w.setHeader(textHeader("text/html"))
+		return writeQuotedPrintable(w, m.HTML)
+	default:
+		w.setHeader(textHeader("text/plain"))
+		return writeQuotedPrintable(w, m.Text)
+	}
+}
+
+func textHeader(contentType string) textproto.MIMEHeader {
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Type", mime.FormatMediaType(contentType, map[string]string{"charset": "utf-8"}))
+	h.Set("Content-Transfer-Encoding", "quoted-printable")
+	return h
+}
+
+func writeQuotedPrintable(w io.Writer, s string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(s)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// writeBase64Lines writes data base64-encoded in 76-character lines.
+func writeBase64Lines(w io.Writer, data []byte) error {
+	enc := base64.StdEncoding.EncodeToString(data)
+	for len(enc) > 76 {
+		if _, err := io.WriteString(w, enc[:76]+"\r\n"); err != nil {
+			return err
+		}
+		enc = enc[76:]
+	}
+	_, err := io.WriteString(w, enc+"\r\n")
+	return err
+}
+
+// newMessageID returns a Message-ID in the domain of from, e.g.
+// <3f2c...@example.com>.
+func newMessageID(from, host string) string {
+	domain := host
+	if at := strings.LastIndex(from, "@"); at >= 0 {
+		domain = strings.TrimRight(from[at+1:], ">")
+	}
+	return "<" + uuid.New().String() + "@" + domain + ">"
+}
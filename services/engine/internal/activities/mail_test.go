@@ -1,7 +1,17 @@
 package activities
 
 import (
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -34,6 +44,21 @@ func TestMailActivity_SendMissingHost(t *testing.T) {
 	assert.Contains(t, err.Error(), "host")
 }
 
+func TestMailActivity_ConfigErrors(t *testing.T) {
+	a := &MailActivity{}
+	_, err := a.Execute(nil, map[string]interface{}{"action": "fax"}, nil)
+	assert.EqualError(t, err, `mail activity: config field 'action' must be one of send, receive, got "fax"`)
+
+	_, err = a.Execute(nil, map[string]interface{}{"security": "ssl", "port": "587"}, nil)
+	assert.EqualError(t, err, "mail activity: config field 'port' must be an integer, got string")
+
+	_, err = a.Execute(nil, map[string]interface{}{"security": "ssl"}, nil)
+	assert.EqualError(t, err, `mail activity: config field 'host' is required; config field 'security' must be one of TLS, STARTTLS, NONE, got "ssl"`)
+
+	_, err = a.Execute(nil, map[string]interface{}{"action": "receive"}, nil)
+	assert.NoError(t, err, "receiving needs no host")
+}
+
 func TestMailActivity_SendIntegration(t *testing.T) {
 	if os.Getenv("FLOWJS_RUN_EXTERNAL_TESTS") != "1" {
 		t.Skip("skipping external test; set FLOWJS_RUN_EXTERNAL_TESTS=1 to enable")
@@ -52,3 +77,160 @@ func TestMailActivity_SendIntegration(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, true, out["sent"])
 }
+
+// fakeSMTP is a minimal SMTP server that records the envelope and message of
+// one delivery and answers the DATA with a queue id.
+type fakeSMTP struct {
+	addr  string
+	from  string
+	rcpts []string
+	data  string
+	done  chan struct{}
+}
+
+func startFakeSMTP(t *testing.T) *fakeSMTP {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+	s := &fakeSMTP{addr: ln.Addr().String(), done: make(chan struct{})}
+	go func() {
+		defer close(s.done)
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		tp := textproto.NewConn(conn)
+		_ = tp.PrintfLine("220 fake ESMTP")
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(line)
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				_ = tp.PrintfLine("250 fake")
+			case strings.HasPrefix(cmd, "MAIL FROM:"):
+				s.from = strings.Trim(line[len("MAIL FROM:"):], "<>")
+				_ = tp.PrintfLine("250 ok")
+			case strings.HasPrefix(cmd, "RCPT TO:"):
+				s.rcpts = append(s.rcpts, strings.Trim(line[len("RCPT TO:"):], "<>"))
+				_ = tp.PrintfLine("250 ok")
+			case cmd == "DATA":
+				_ = tp.PrintfLine("354 go ahead")
+				data, _ := tp.ReadDotBytes()
+				s.data = string(data)
+				_ = tp.PrintfLine("250 2.0.0 Ok: queued as ABC123")
+			case cmd == "QUIT":
+				_ = tp.PrintfLine("221 bye")
+				return
+			default:
+				_ = tp.PrintfLine("502 unsupported")
+			}
+		}
+	}()
+	return s
+}
+
+func (s *fakeSMTP) hostPort(t *testing.T) (string, float64) {
+	host, port, err := net.SplitHostPort(s.addr)
+	require.NoError(t, err)
+	p, err := strconv.Atoi(port)
+	require.NoError(t, err)
+	return host, float64(p)
+}
+
+func TestMailActivity_SendMultipartWithAttachments(t *testing.T) {
+	srv := startFakeSMTP(t)
+	host, port := srv.hostPort(t)
+	path := filepath.Join(t.TempDir(), "report.csv")
+	require.NoError(t, os.WriteFile(path, []byte("id,total\n1,10\n"), 0o600))
+
+	a := &MailActivity{}
+	out, err := a.Execute(map[string]interface{}{
+		"to":      []interface{}{"Dest <dest@example.com>"},
+		"subject": "Résumé du jour",
+		"attachments": []interface{}{
+			path,
+			map[string]interface{}{"filename": "note.txt", "content": base64.StdEncoding.EncodeToString([]byte("hello"))},
+		},
+	}, map[string]interface{}{
+		"action":   "send",
+		"host":     host,
+		"port":     port,
+		"security": "NONE",
+		"from":     "Flows <flows@example.com>",
+		"bcc":      "audit@example.com",
+		"reply_to": "support@example.com",
+		"body":     "plain body",
+		"html":     "<p>html body</p>",
+	}, nil)
+	require.NoError(t, err)
+	<-srv.done
+
+	assert.Equal(t, true, out["sent"])
+	assert.Equal(t, "2.0.0 Ok: queued as ABC123", out["server_response"])
+	messageID, _ := out["message_id"].(string)
+	assert.Regexp(t, `^<[0-9a-f-]+@example\.com>$`, messageID)
+
+	assert.Equal(t, "flows@example.com", srv.from)
+	assert.Equal(t, []string{"dest@example.com", "audit@example.com"}, srv.rcpts)
+
+	msg, err := mail.ReadMessage(strings.NewReader(srv.data))
+	require.NoError(t, err)
+	assert.Equal(t, messageID, msg.Header.Get("Message-ID"))
+	assert.Equal(t, "support@example.com", msg.Header.Get("Reply-To"))
+	assert.Empty(t, msg.Header.Get("Bcc"), "bcc recipients are not disclosed")
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, "Résumé du jour", subject)
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	require.Equal(t, "multipart/mixed", mediaType)
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+
+	body, err := mr.NextPart()
+	require.NoError(t, err)
+	altType, altParams, _ := mime.ParseMediaType(body.Header.Get("Content-Type"))
+	require.Equal(t, "multipart/alternative", altType)
+	alt := multipart.NewReader(body, altParams["boundary"])
+	for _, want := range []struct{ ct, text string }{{"text/plain", "plain body"}, {"text/html", "<p>html body</p>"}} {
+		p, err := alt.NextPart()
+		require.NoError(t, err)
+		assert.Contains(t, p.Header.Get("Content-Type"), want.ct)
+		text, _ := io.ReadAll(p)
+		assert.Equal(t, want.text, string(text))
+	}
+
+	for _, want := range []struct{ name, content string }{{"report.csv", "id,total\n1,10\n"}, {"note.txt", "hello"}} {
+		p, err := mr.NextPart()
+		require.NoError(t, err)
+		assert.Equal(t, want.name, p.FileName())
+		raw, _ := io.ReadAll(p)
+		decoded, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(string(raw), "\r\n", ""))
+		require.NoError(t, err)
+		assert.Equal(t, want.content, string(decoded))
+	}
+}
+
+func TestMailActivity_SendRequiresRecipient(t *testing.T) {
+	a := &MailActivity{}
+	_, err := a.Execute(nil, map[string]interface{}{"host": "127.0.0.1"}, nil)
+	assert.ErrorContains(t, err, "to, cc or bcc")
+}
+
+func TestMailAttachments_Invalid(t *testing.T) {
+	for name, v := range map[string]interface{}{
+		"not a list":      float64(1),
+		"missing source":  []interface{}{map[string]interface{}{"filename": "a.txt"}},
+		"bad base64":      []interface{}{map[string]interface{}{"filename": "a.txt", "content": "!!"}},
+		"content no name": []interface{}{map[string]interface{}{"content": "aGk="}},
+		"missing file":    []interface{}{"/does/not/exist.pdf"},
+	} {
+		_, err := mailAttachments(v, nil)
+		assert.Error(t, err, name)
+	}
+}
@@ -0,0 +1,276 @@
+package activities
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"flowjs-works/engine/internal/models"
+)
+
+// mappingConfig is the config of a mapping node. Each rule is an object,
+// applied in order:
+//
+//	target:    dotted output path ("customer.name"), nested objects are created
+//	source:    context JSONPath ("$.trigger.body.name") of the value
+//	sources:   concat only — JSONPaths and literals joined in order
+//	value:     literal used instead of a source
+//	default:   used when the source does not resolve or is null
+//	type:      "string" | "number" | "integer" | "boolean" — coerces the value
+//	function:  "concat" | "split"
+//	separator: concat/split separator (default "" for concat, "," for split)
+type mappingConfig struct {
+	Mappings []interface{} `config:"mappings" doc:"{target, source, sources, value, default, type, function, separator} rules, applied in order"`
+	rules    []mappingRule
+}
+
+// mappingRule is one entry of mappings.
+type mappingRule struct {
+	Target    string
+	Source    interface{}
+	Sources   []interface{}
+	Value     interface{}
+	HasValue  bool
+	Default   interface{}
+	Type      string
+	Function  string
+	Separator string
+}
+
+func (c *mappingConfig) validate() []FieldError {
+	if c.Mappings == nil {
+		return []FieldError{{"mappings", "is required"}}
+	}
+	c.rules = make([]mappingRule, 0, len(c.Mappings))
+	for i, raw := range c.Mappings {
+		rule, err := parseMappingRule(raw)
+		if err != nil {
+			return []FieldError{{"mappings", fmt.Sprintf("item %d %s", i, err)}}
+		}
+		c.rules = append(c.rules, rule)
+	}
+	return nil
+}
+
+// parseMappingRule converts one mappings item into a mappingRule.
+func parseMappingRule(raw interface{}) (mappingRule, error) {
+	var r mappingRule
+	m, ok := raw.(map[string]interface{})
+	if !ok {
+		return r, fmt.Errorf("must be an object, got %s", configTypeName(raw))
+	}
+	target, _ := m["target"].(string)
+	r.Target = strings.TrimPrefix(strings.TrimPrefix(target, "$"), ".")
+	if r.Target == "" {
+		return r, errors.New("has no target")
+	}
+	r.Source = m["source"]
+	r.Value, r.HasValue = m["value"]
+	r.Default = m["default"]
+	r.Function, _ = m["function"].(string)
+	r.Separator, _ = m["separator"].(string)
+	r.Type, _ = m["type"].(string)
+	if sources, ok := m["sources"]; ok {
+		if r.Sources, ok = sources.([]interface{}); !ok {
+			return r, fmt.Errorf("(%s): sources must be an array, got %s", r.Target, configTypeName(sources))
+		}
+	}
+	switch r.Function {
+	case "", "split":
+	case "concat":
+		if r.Sources == nil {
+			if _, single := m["source"]; !single {
+				return r, fmt.Errorf("(%s): concat requires 'sources'", r.Target)
+			}
+			r.Sources = []interface{}{r.Source}
+		}
+	default:
+		return r, fmt.Errorf("(%s): unknown function %q", r.Target, r.Function)
+	}
+	switch r.Type {
+	case "", "string", "number", "integer", "boolean":
+	default:
+		return r, fmt.Errorf("(%s): unknown type %q", r.Target, r.Type)
+	}
+	return r, nil
+}
+
+// MappingActivity implements the `mapping` node type (config:
+// mappingConfig): it builds its output from a declarative field-mapping
+// spec, as produced by the Designer's visual mapper, so simple transforms
+// need no script.
+type MappingActivity struct{}
+
+func (a *MappingActivity) Name() string { return "mapping" }
+
+// ConfigSpec returns the config struct of mapping nodes.
+func (a *MappingActivity) ConfigSpec() interface{} { return &mappingConfig{} }
+
+func (a *MappingActivity) Execute(input map[string]interface{}, config map[string]interface{}, ctx *models.ExecutionContext) (map[string]interface{}, error) {
+	var cfg mappingConfig
+	if err := decodeConfig(a.Name(), config, &cfg); err != nil {
+		return nil, err
+	}
+	output := make(map[string]interface{})
+	for i := range cfg.rules {
+		rule := &cfg.rules[i]
+		value, err := mapValue(rule, ctx)
+		if err != nil {
+			return nil, fmt.Errorf("mapping activity: target %q: %w", rule.Target, err)
+		}
+		if err := setPath(output, rule.Target, value); err != nil {
+			return nil, fmt.Errorf("mapping activity: target %q: %w", rule.Target, err)
+		}
+	}
+	return output, nil
+}
+
+// mapValue computes the value of one mapping rule.
+func mapValue(rule *mappingRule, ctx *models.ExecutionContext) (interface{}, error) {
+	var value interface{}
+	switch rule.Function {
+	case "concat":
+		var parts []string
+		for _, src := range rule.Sources {
+			for _, v := range flatten(resolveSource(src, ctx)) {
+				if v != nil {
+					parts = append(parts, fmt.Sprintf("%v", v))
+				}
+			}
+		}
+		value = strings.Join(parts, rule.Separator)
+	case "split":
+		sep := ","
+		if rule.Separator != "" {
+			sep = rule.Separator
+		}
+		switch v := resolveSource(rule.Source, ctx).(type) {
+		case string:
+			items := []interface{}{}
+			if v != "" {
+				for _, item := range strings.Split(v, sep) {
+					items = append(items, strings.TrimSpace(item))
+				}
+			}
+			value = items
+		case nil:
+		default:
+			return nil, fmt.Errorf("split requires a string, got %T", v)
+		}
+	default:
+		if rule.HasValue {
+			value = rule.Value
+		} else {
+			value = resolveSource(rule.Source, ctx)
+		}
+	}
+
+	if value == nil {
+		value = rule.Default
+	}
+	if rule.Type != "" && value != nil {
+		return coerce(value, rule.Type)
+	}
+	return value, nil
+}
+
+// resolveSource returns the context value of a "$" path, or src itself when
+// it is a literal. A path that does not resolve yields nil.
+func resolveSource(src interface{}, ctx *models.ExecutionContext) interface{} {
+	path, ok := src.(string)
+	if !ok || !strings.HasPrefix(path, "$") {
+		return src
+	}
+	v, err := ctx.GetValue(path)
+	if err != nil {
+		return nil
+	}
+	return v
+}
+
+// flatten returns the items of an array, or v as a single item.
+func flatten(v interface{}) []interface{} {
+	if arr, ok := v.([]interface{}); ok {
+		return arr
+	}
+	return []interface{}{v}
+}
+
+// coerce converts v to the mapping type typ.
+func coerce(v interface{}, typ string) (interface{}, error) {
+	switch typ {
+	case "string":
+		if s, ok := v.(string); ok {
+			return s, nil
+		}
+		return fmt.Sprintf("%v", v), nil
+	case "number", "integer":
+		var f float64
+		switch n := v.(type) {
+		case float64:
+			f = n
+		case int:
+			f = float64(n)
+		case int64:
+			f = float64(n)
+		case bool:
+			if n {
+				f = 1
+			}
+		case string:
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+			if err != nil {
+				return nil, fmt.Errorf("cannot convert %q to %s", n, typ)
+			}
+			f = parsed
+		default:
+			return nil, fmt.Errorf("cannot convert %T to %s", v, typ)
+		}
+		if typ == "integer" {
+			return int64(f), nil
+		}
+		return f, nil
+	case "boolean":
+		switch b := v.(type) {
+		case bool:
+			return b, nil
+		case float64:
+			return b != 0, nil
+		case int:
+			return b != 0, nil
+		case int64:
+			return b != 0, nil
+		case string:
+			parsed, err := strconv.ParseBool(strings.TrimSpace(b))
+			if err != nil {
+				return nil, fmt.Errorf("cannot convert %q to boolean", b)
+			}
+			return parsed, nil
+		}
+		return nil, fmt.Errorf("cannot convert %T to boolean", v)
+	}
+	return nil, fmt.Errorf("unknown type %q", typ)
+}
+
+// setPath stores v at the dotted path of out, creating intermediate objects.
+func setPath(out map[string]interface{}, path string, v interface{}) error {
+	parts := strings.Split(path, ".")
+	current := out
+	for _, part := range parts[:len(parts)-1] {
+		next, exists := current[part]
+		if !exists {
+			child := make(map[string]interface{})
+			current[part] = child
+			current = child
+			continue
+		}
+		child, ok := next.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%q is already set to a non-object value", part)
+		}
+		current = child
+	}
+	current[parts[len(parts)-1]] = v
+	return nil
+}
@@ -0,0 +1,91 @@
+package activities
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"flowjs-works/engine/internal/models"
+)
+
+func mappingContext() *models.ExecutionContext {
+	ctx := models.NewExecutionContext("exec-mapping")
+	ctx.SetTriggerData(map[string]interface{}{
+		"body": map[string]interface{}{
+			"first": "Ada",
+			"last":  "Lovelace",
+			"age":   "36",
+			"vip":   "true",
+			"tags":  "math, poetry,engines",
+		},
+	})
+	ctx.SetNodeOutput("fetch", map[string]interface{}{"lines": []interface{}{"a", "b"}})
+	return ctx
+}
+
+func TestMappingActivity_Execute(t *testing.T) {
+	a := &MappingActivity{}
+	out, err := a.Execute(nil, map[string]interface{}{
+		"mappings": []interface{}{
+			map[string]interface{}{"target": "customer.first_name", "source": "$.trigger.body.first"},
+			map[string]interface{}{"target": "customer.age", "source": "$.trigger.body.age", "type": "integer"},
+			map[string]interface{}{"target": "customer.vip", "source": "$.trigger.body.vip", "type": "boolean"},
+			map[string]interface{}{"target": "customer.country", "source": "$.trigger.body.country", "default": "ES"},
+			map[string]interface{}{"target": "full_name", "function": "concat", "sources": []interface{}{"$.trigger.body.first", "$.trigger.body.last"}, "separator": " "},
+			map[string]interface{}{"target": "lines", "function": "concat", "source": "$.nodes.fetch.output.lines", "separator": "|"},
+			map[string]interface{}{"target": "tags", "function": "split", "source": "$.trigger.body.tags"},
+			map[string]interface{}{"target": "channel", "value": "web"},
+		},
+	}, mappingContext())
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]interface{}{
+		"customer": map[string]interface{}{
+			"first_name": "Ada",
+			"age":        int64(36),
+			"vip":        true,
+			"country":    "ES",
+		},
+		"full_name": "Ada Lovelace",
+		"lines":     "a|b",
+		"tags":      []interface{}{"math", "poetry", "engines"},
+		"channel":   "web",
+	}, out)
+
+	out, err = a.Execute(nil, map[string]interface{}{"mappings": []interface{}{}}, mappingContext())
+	require.NoError(t, err, "a new mapping node has no rules yet")
+	assert.Empty(t, out)
+}
+
+func TestMappingActivity_Errors(t *testing.T) {
+	a := &MappingActivity{}
+	tests := []struct {
+		name     string
+		mappings interface{}
+		wantErr  string
+	}{
+		{name: "missing mappings", mappings: nil, wantErr: "'mappings'"},
+		{name: "missing target", mappings: []interface{}{map[string]interface{}{"source": "$.trigger.body.first"}}, wantErr: "config field 'mappings' item 0 has no target"},
+		{name: "bad coercion", mappings: []interface{}{map[string]interface{}{"target": "n", "source": "$.trigger.body.first", "type": "number"}}, wantErr: `cannot convert "Ada" to number`},
+		{name: "unknown function", mappings: []interface{}{map[string]interface{}{"target": "n", "function": "upper"}}, wantErr: `config field 'mappings' item 0 (n): unknown function "upper"`},
+		{name: "unknown type", mappings: []interface{}{map[string]interface{}{"target": "n", "type": "date"}}, wantErr: `item 0 (n): unknown type "date"`},
+		{name: "concat without sources", mappings: []interface{}{map[string]interface{}{"target": "n", "function": "concat"}}, wantErr: "concat requires 'sources'"},
+		{name: "not a list", mappings: "a=b", wantErr: "config field 'mappings' must be an array, got string"},
+		{name: "target conflict", mappings: []interface{}{
+			map[string]interface{}{"target": "a", "value": 1},
+			map[string]interface{}{"target": "a.b", "value": 2},
+		}, wantErr: "non-object"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := map[string]interface{}{}
+			if tt.mappings != nil {
+				config["mappings"] = tt.mappings
+			}
+			_, err := a.Execute(nil, config, mappingContext())
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
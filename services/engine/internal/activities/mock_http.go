@@ -0,0 +1,196 @@
+package activities
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"flowjs-works/engine/internal/models"
+)
+
+// mockHTTPConfig is the config of a mock_http node. Each route is an object:
+//
+//	method:   HTTP method, any when empty
+//	path:     exact request path, or a prefix ending in "*"
+//	status:   response status (default 200)
+//	headers:  response headers
+//	body:     string sent as is, anything else as JSON
+//	delay_ms: wait before answering
+type mockHTTPConfig struct {
+	Routes []interface{} `config:"routes,required" doc:"{method, path, status, headers, body, delay_ms} objects, matched in order"`
+	routes []mockRoute
+}
+
+func (c *mockHTTPConfig) validate() []FieldError {
+	var err error
+	if c.routes, err = parseMockRoutes(c.Routes); err != nil {
+		return []FieldError{{"routes", err.Error()}}
+	}
+	return nil
+}
+
+// MockHTTPActivity implements the `mock_http` node type (config:
+// mockHTTPConfig): it starts an ephemeral HTTP server answering the
+// configured routes and outputs its URL, so downstream http nodes can be
+// pointed at it through input_mapping and a multi-node flow can be tested
+// without the systems it calls.
+//
+// A request matching no route gets 404. The server lives until the execution
+// that started it ends. The node only runs in test mode (runner -test,
+// flowengine.Options.TestMode); otherwise it fails.
+type MockHTTPActivity struct {
+	enabled bool
+}
+
+// NewMockHTTPActivity returns the mock_http activity. When enabled is false
+// the node fails instead of starting a server.
+func NewMockHTTPActivity(enabled bool) *MockHTTPActivity {
+	return &MockHTTPActivity{enabled: enabled}
+}
+
+func (a *MockHTTPActivity) Name() string { return "mock_http" }
+
+// ConfigSpec returns the config struct of mock_http nodes.
+func (a *MockHTTPActivity) ConfigSpec() interface{} { return &mockHTTPConfig{} }
+
+// mockRoute is one configured route of a mock server.
+type mockRoute struct {
+	method  string
+	path    string
+	status  int
+	headers map[string]string
+	body    []byte
+	delay   time.Duration
+}
+
+func (r mockRoute) matches(req *http.Request) bool {
+	if r.method != "" && !strings.EqualFold(r.method, req.Method) {
+		return false
+	}
+	if prefix, ok := strings.CutSuffix(r.path, "*"); ok {
+		return strings.HasPrefix(req.URL.Path, prefix)
+	}
+	return r.path == req.URL.Path
+}
+
+func (a *MockHTTPActivity) Execute(input map[string]interface{}, config map[string]interface{}, ctx *models.ExecutionContext) (map[string]interface{}, error) {
+	if !a.enabled {
+		return nil, fmt.Errorf("mock_http activity: only available in test mode")
+	}
+	if ctx == nil || ctx.ExecutionID == "" {
+		return nil, fmt.Errorf("mock_http activity: execution context is required")
+	}
+	var cfg mockHTTPConfig
+	if err := decodeConfig(a.Name(), config, &cfg); err != nil {
+		return nil, err
+	}
+	routes := cfg.routes
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		for _, route := range routes {
+			if !route.matches(req) {
+				continue
+			}
+			if route.delay > 0 {
+				select {
+				case <-time.After(route.delay):
+				case <-req.Context().Done():
+					return
+				}
+			}
+			for k, v := range route.headers {
+				w.Header().Set(k, v)
+			}
+			w.WriteHeader(route.status)
+			_, _ = w.Write(route.body)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "no mock route for " + req.Method + " " + req.URL.Path})
+	}))
+	mockServers.add(ctx.ExecutionID, srv)
+
+	return map[string]interface{}{"url": srv.URL, "routes": len(routes)}, nil
+}
+
+// parseMockRoutes converts the routes config into mockRoutes.
+func parseMockRoutes(list []interface{}) ([]mockRoute, error) {
+	routes := make([]mockRoute, 0, len(list))
+	for i, item := range list {
+		cfg, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("item %d must be an object, got %s", i, configTypeName(item))
+		}
+		route := mockRoute{status: http.StatusOK, headers: map[string]string{}}
+		route.method, _ = cfg["method"].(string)
+		route.path, _ = cfg["path"].(string)
+		if route.path == "" {
+			return nil, fmt.Errorf("item %d has no path", i)
+		}
+		if raw, ok := cfg["status"]; ok {
+			status, ok := configInt(raw)
+			if !ok || status < 100 || status > 999 {
+				return nil, fmt.Errorf("item %d: status must be an HTTP status code, got %v", i, raw)
+			}
+			route.status = int(status)
+		}
+		if headers, ok := cfg["headers"].(map[string]interface{}); ok {
+			for k, v := range headers {
+				route.headers[k] = fmt.Sprintf("%v", v)
+			}
+		}
+		if raw, ok := cfg["delay_ms"]; ok {
+			ms, ok := configInt(raw)
+			if !ok {
+				return nil, fmt.Errorf("item %d: delay_ms must be an integer, got %v", i, raw)
+			}
+			route.delay = time.Duration(ms) * time.Millisecond
+		}
+		switch body := cfg["body"].(type) {
+		case nil:
+		case string:
+			route.body = []byte(body)
+		default:
+			data, err := json.Marshal(body)
+			if err != nil {
+				return nil, fmt.Errorf("item %d: body: %w", i, err)
+			}
+			route.body = data
+			if _, set := route.headers["Content-Type"]; !set {
+				route.headers["Content-Type"] = "application/json"
+			}
+		}
+		routes = append(routes, route)
+	}
+	return routes, nil
+}
+
+// mockServerStore holds the running mock servers per execution.
+type mockServerStore struct {
+	mu    sync.Mutex
+	execs map[string][]*httptest.Server
+}
+
+var mockServers = &mockServerStore{execs: make(map[string][]*httptest.Server)}
+
+func (s *mockServerStore) add(executionID string, srv *httptest.Server) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.execs[executionID] = append(s.execs[executionID], srv)
+}
+
+// ReleaseMockServers shuts down the mock servers started by executionID.
+func ReleaseMockServers(executionID string) {
+	mockServers.mu.Lock()
+	servers := mockServers.execs[executionID]
+	delete(mockServers.execs, executionID)
+	mockServers.mu.Unlock()
+	for _, srv := range servers {
+		srv.Close()
+	}
+}
@@ -0,0 +1,82 @@
+package activities
+
+import (
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"flowjs-works/engine/internal/models"
+)
+
+func TestMockHTTPActivity_ServesRoutes(t *testing.T) {
+	ctx := models.NewExecutionContext("exec-mock")
+	defer ReleaseMockServers(ctx.ExecutionID)
+
+	out, err := NewMockHTTPActivity(true).Execute(nil, map[string]interface{}{
+		"routes": []interface{}{
+			map[string]interface{}{"method": "GET", "path": "/orders/1", "body": map[string]interface{}{"id": float64(1)}},
+			map[string]interface{}{"method": "POST", "path": "/orders", "status": float64(201), "body": "created", "headers": map[string]interface{}{"X-Mock": "yes"}},
+			map[string]interface{}{"path": "/files/*", "status": float64(204)},
+		},
+	}, ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, out["routes"])
+	url := out["url"].(string)
+
+	get := func(method, path string) (*http.Response, string) {
+		req, err := http.NewRequest(method, url+path, nil)
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp, string(body)
+	}
+
+	resp, body := get("GET", "/orders/1")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	assert.JSONEq(t, `{"id":1}`, body)
+
+	resp, body = get("POST", "/orders")
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, "yes", resp.Header.Get("X-Mock"))
+	assert.Equal(t, "created", body)
+
+	resp, _ = get("DELETE", "/files/a.csv")
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	resp, body = get("GET", "/orders")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Contains(t, body, "no mock route for GET /orders")
+
+	ReleaseMockServers(ctx.ExecutionID)
+	_, err = http.Get(url + "/orders/1")
+	assert.Error(t, err, "server must be closed once the execution is released")
+}
+
+func TestMockHTTPActivity_Errors(t *testing.T) {
+	ctx := models.NewExecutionContext("exec-mock-errors")
+	defer ReleaseMockServers(ctx.ExecutionID)
+
+	routes := []interface{}{map[string]interface{}{"path": "/"}}
+	_, err := NewMockHTTPActivity(false).Execute(nil, map[string]interface{}{"routes": routes}, ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "test mode")
+
+	_, err = NewMockHTTPActivity(true).Execute(nil, map[string]interface{}{}, ctx)
+	assert.EqualError(t, err, "mock_http activity: config field 'routes' is required")
+
+	_, err = NewMockHTTPActivity(true).Execute(nil, map[string]interface{}{
+		"routes": []interface{}{map[string]interface{}{"method": "GET"}},
+	}, ctx)
+	assert.EqualError(t, err, "mock_http activity: config field 'routes' item 0 has no path")
+
+	_, err = NewMockHTTPActivity(true).Execute(nil, map[string]interface{}{
+		"routes": []interface{}{map[string]interface{}{"path": "/", "status": "ok"}},
+	}, ctx)
+	assert.EqualError(t, err, "mock_http activity: config field 'routes' item 0: status must be an HTTP status code, got ok")
+}
@@ -9,28 +9,32 @@ import (
 	fmodels "flowjs-works/engine/internal/models"
 )
 
-// RabbitMQActivity implements the `rabbitmq` producer node type.
-// config fields:
-//
-//	url_amqp:    AMQP URL (required)
-//	vhost:       virtual host (default "/")
-//	exchange:    exchange name (default "")
-//	routing_key: routing key (required)
-//	payload:     message body (any — serialised to JSON)
-//	properties:  map with optional delivery_mode(int), content_type(string)
+// rabbitmqConfig is the config of a rabbitmq node.
+type rabbitmqConfig struct {
+	URLAMQP    string      `config:"url_amqp,required" doc:"AMQP URL"`
+	Exchange   string      `config:"exchange" doc:"exchange name (default the default exchange)"`
+	RoutingKey string      `config:"routing_key"`
+	Payload    interface{} `config:"payload" doc:"message body, serialised to JSON"`
+	// Properties holds the optional delivery_mode (int) and content_type
+	// (string) of the message.
+	Properties map[string]interface{} `config:"properties" doc:"delivery_mode and content_type of the message"`
+}
+
+// RabbitMQActivity implements the `rabbitmq` producer node type (config:
+// rabbitmqConfig).
 type RabbitMQActivity struct{}
 
 func (a *RabbitMQActivity) Name() string { return "rabbitmq" }
 
+// ConfigSpec returns the config struct of rabbitmq nodes.
+func (a *RabbitMQActivity) ConfigSpec() interface{} { return &rabbitmqConfig{} }
+
 func (a *RabbitMQActivity) Execute(input map[string]interface{}, config map[string]interface{}, ctx *fmodels.ExecutionContext) (map[string]interface{}, error) {
-	urlAMQP, ok := config["url_amqp"].(string)
-	if !ok || urlAMQP == "" {
-		return nil, fmt.Errorf("rabbitmq activity: missing required config field 'url_amqp'")
+	var cfg rabbitmqConfig
+	if err := decodeConfig(a.Name(), config, &cfg); err != nil {
+		return nil, err
 	}
-	routingKey, _ := config["routing_key"].(string)
-	exchange, _ := config["exchange"].(string)
-
-	payload := config["payload"]
+	urlAMQP, routingKey, exchange, payload := cfg.URLAMQP, cfg.RoutingKey, cfg.Exchange, cfg.Payload
 	payloadBytes, err := json.Marshal(payload)
 	if err != nil {
 		return nil, fmt.Errorf("rabbitmq activity: failed to marshal payload: %w", err)
@@ -38,7 +42,7 @@ func (a *RabbitMQActivity) Execute(input map[string]interface{}, config map[stri
 
 	contentType := "application/json"
 	var deliveryMode uint8 = 1
-	if props, ok := config["properties"].(map[string]interface{}); ok {
+	if props := cfg.Properties; props != nil {
 		if ct, ok := props["content_type"].(string); ok {
 			contentType = ct
 		}
@@ -0,0 +1,109 @@
+package activities
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+
+	"flowjs-works/engine/internal/egress"
+)
+
+// RemoteFile describes a file listed on an SFTP server or in an S3 bucket,
+// as seen by the sftp_poll and s3_poll triggers.
+type RemoteFile struct {
+	// Name is the base name of the file.
+	Name string
+	// Path is the remote path (SFTP) or the object key (S3).
+	Path     string
+	Size     int64
+	Modified time.Time
+	// ETag is the entity tag of an S3 object, without quotes.
+	ETag string
+}
+
+// ListSFTPFolder lists the regular files of folder on the SFTP server at
+// addr (host:port). Credentials are read from config like those of sftp
+// nodes: config["auth"] or flat user, password and private_key.
+func ListSFTPFolder(ctx context.Context, addr, folder string, config map[string]interface{}) ([]RemoteFile, error) {
+	sshCfg, err := buildSSHClientConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := egress.Dialer(defaultNetDialTimeout).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("TCP dial failed: %w", err)
+	}
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, addr, sshCfg)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("SSH handshake failed: %w", err)
+	}
+	sshClient := ssh.NewClient(sshConn, chans, reqs)
+	defer sshClient.Close()
+
+	client, err := sftp.NewClient(sshClient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create SFTP client: %w", err)
+	}
+	defer client.Close()
+
+	entries, err := client.ReadDir(folder)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list remote folder %q: %w", folder, err)
+	}
+	files := make([]RemoteFile, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.Mode().IsRegular() {
+			continue
+		}
+		files = append(files, RemoteFile{
+			Name:     entry.Name(),
+			Path:     path.Join(folder, entry.Name()),
+			Size:     entry.Size(),
+			Modified: entry.ModTime().UTC(),
+		})
+	}
+	return files, nil
+}
+
+// ListS3Prefix lists the objects under prefix in bucket, skipping folder
+// placeholder keys ending in "/". Credentials are read from config like
+// those of s3 nodes, falling back to the default AWS credential chain.
+func ListS3Prefix(ctx context.Context, region, bucket, prefix string, config map[string]interface{}) ([]RemoteFile, error) {
+	client, err := buildS3Client(region, config)
+	if err != nil {
+		return nil, err
+	}
+	paginator := s3.NewListObjectsV2Paginator(client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	})
+	var files []RemoteFile
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list objects: %w", err)
+		}
+		for _, obj := range page.Contents {
+			key := aws.ToString(obj.Key)
+			if strings.HasSuffix(key, "/") {
+				continue
+			}
+			files = append(files, RemoteFile{
+				Name:     path.Base(key),
+				Path:     key,
+				Size:     aws.ToInt64(obj.Size),
+				Modified: aws.ToTime(obj.LastModified).UTC(),
+				ETag:     strings.Trim(aws.ToString(obj.ETag), `"`),
+			})
+		}
+	}
+	return files, nil
+}
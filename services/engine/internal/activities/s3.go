@@ -1,13 +1,10 @@
 package activities
 
 import (
-	"bytes"
 	"context"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
-	"regexp"
 	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -18,81 +15,86 @@ import (
 	fmodels "flowjs-works/engine/internal/models"
 )
 
-// S3Activity implements the `s3` node type.
-//
-// config fields:
+// s3Config is the config of an s3 node. key, keys, source_key,
+// source_bucket and presign_method can also come from the input, which
+// overrides the config.
+type s3Config struct {
+	Bucket string                 `config:"bucket,required"`
+	Region string                 `config:"region,required" doc:"AWS region, e.g. us-east-1"`
+	Auth   map[string]interface{} `config:"auth" doc:"access_key_id, secret_access_key and optional session_token; the default AWS credential chain without it"`
+	Folder string                 `config:"folder" doc:"key prefix inside the bucket"`
+	Method string                 `config:"method,required,enum=get|put|presign|delete|copy"`
+	// ContentType and Metadata apply to put and copy.
+	ContentType string                 `config:"content_type" doc:"Content-Type of put or copied objects; put defaults to the type of the file extension"`
+	Metadata    map[string]interface{} `config:"metadata" doc:"custom x-amz-meta-* metadata of put or copied objects"`
+	// MultipartThresholdMB and PartSizeMB default to 100 and 16 when not
+	// positive.
+	MultipartThresholdMB float64 `config:"multipart_threshold_mb" doc:"size from which put uploads in parts (default 100)"`
+	PartSizeMB           float64 `config:"part_size_mb" doc:"part size of multipart uploads, at least 5 (default 16)"`
+	Key                  string  `config:"key" doc:"object key under folder (presign, copy destination, delete)"`
+	// Keys is a key or a list of keys.
+	Keys           interface{} `config:"keys" doc:"object keys under folder to delete"`
+	PresignMethod  string      `config:"presign_method" doc:"get (default) or put"`
+	ExpiresSeconds int         `config:"expires_seconds" doc:"validity of a presigned URL (default 900, at most 7 days)"`
+	SourceBucket   string      `config:"source_bucket" doc:"bucket of the copied object (default bucket)"`
+	SourceKey      string      `config:"source_key" doc:"key of the copied object"`
+	fileTransferConfig
+}
+
+func (c *s3Config) validate() []FieldError {
+	if c.PartSizeMB > 0 && int64(c.PartSizeMB*(1<<20)) < s3MinPartSize {
+		return []FieldError{{"part_size_mb", "must be at least 5"}}
+	}
+	return c.fileTransferConfig.validate()
+}
+
+// S3Activity implements the `s3` node type (config: s3Config).
 //
-//	bucket:        S3 bucket name (required)
-//	region:        AWS region, e.g. "us-east-1" (required)
-//	auth:          map — access_key_id (string), secret_access_key (string), session_token (string, optional)
-//	               If auth is omitted the default AWS credential chain is used.
-//	folder:        key prefix / "folder" inside the bucket
-//	method:        "get" | "put" (required)
-//	regex_filter:  regex to filter object keys during get
-//	overwrite:     bool — overwrite existing destination objects (put only, default true)
-//	local_folder:  local directory used as source (put) or destination (get)
-//	files:         []interface{} of filenames to upload (put only)
+// A get node outputs the downloaded objects as file refs (see FileRef), and a
+// put node also uploads the refs it receives as input["files"], so an
+// in_memory sftp or smb get can feed an s3 put without touching local disk.
 type S3Activity struct{}
 
 // Name returns the DSL type identifier for this activity.
 func (a *S3Activity) Name() string { return "s3" }
 
-// Execute runs the S3 get or put operation.
-func (a *S3Activity) Execute(input map[string]interface{}, cfg map[string]interface{}, ctx *fmodels.ExecutionContext) (map[string]interface{}, error) {
-	bucket, ok := cfg["bucket"].(string)
-	if !ok || bucket == "" {
-		return nil, fmt.Errorf("s3 activity: missing required config field 'bucket'")
-	}
-
-	region, ok := cfg["region"].(string)
-	if !ok || region == "" {
-		return nil, fmt.Errorf("s3 activity: missing required config field 'region'")
-	}
+// ConfigSpec returns the config struct of s3 nodes.
+func (a *S3Activity) ConfigSpec() interface{} { return &s3Config{} }
 
-	method, ok := cfg["method"].(string)
-	if !ok || (method != "get" && method != "put") {
-		return nil, fmt.Errorf("s3 activity: config field 'method' must be 'get' or 'put'")
+// Execute runs the S3 operation selected by method.
+func (a *S3Activity) Execute(input map[string]interface{}, config map[string]interface{}, ctx *fmodels.ExecutionContext) (map[string]interface{}, error) {
+	var cfg s3Config
+	if err := decodeConfig(a.Name(), config, &cfg); err != nil {
+		return nil, err
 	}
+	bucket, folder := cfg.Bucket, cfg.Folder
 
-	folder, _ := cfg["folder"].(string)
-
-	// Validate regex_filter early so callers get a clear error before any network I/O.
-	if rf, ok := cfg["regex_filter"].(string); ok && rf != "" {
-		if _, err := regexp.Compile(rf); err != nil {
-			return nil, fmt.Errorf("s3 activity: invalid regex_filter %q: %w", rf, err)
-		}
-	}
-
-	s3Client, err := buildS3Client(region, cfg)
+	s3Client, err := buildS3Client(cfg.Region, config)
 	if err != nil {
 		return nil, fmt.Errorf("s3 activity: failed to build S3 client: %w", err)
 	}
 
-	goCtx := contextFromCtx(ctx)
-	switch method {
+	goCtx, cancel := ctx.Context(context.Background())
+	defer cancel()
+	switch cfg.Method {
 	case "get":
-		return s3Get(goCtx, s3Client, bucket, folder, cfg)
+		return s3Get(goCtx, s3Client, bucket, folder, &cfg, ctx)
 	case "put":
-		return s3Put(goCtx, s3Client, bucket, folder, cfg)
+		return s3Put(goCtx, s3Client, bucket, folder, input, &cfg, ctx)
+	case "presign":
+		return s3Presign(goCtx, s3Client, bucket, folder, input, &cfg)
+	case "delete":
+		return s3Delete(goCtx, s3Client, bucket, folder, input, &cfg)
+	case "copy":
+		return s3Copy(goCtx, s3Client, bucket, folder, input, &cfg)
 	default:
-		return nil, fmt.Errorf("s3 activity: unknown method %q", method)
+		return nil, fmt.Errorf("s3 activity: unknown method %q", cfg.Method)
 	}
 }
 
-// s3Get downloads objects from the bucket/folder to local_folder.
-func s3Get(goCtx context.Context, client *s3.Client, bucket, prefix string, cfg map[string]interface{}) (map[string]interface{}, error) {
-	localFolder, _ := cfg["local_folder"].(string)
-	if localFolder == "" {
-		localFolder = "."
-	}
-
-	// regex_filter was already validated in Execute; compile here to apply it.
-	var filter *regexp.Regexp
-	if rf, ok := cfg["regex_filter"].(string); ok && rf != "" {
-		// Error is ignored — compilation was already validated in Execute.
-		filter, _ = regexp.Compile(rf)
-	}
-
+// s3Get downloads objects from the bucket/folder to local_folder, or into
+// memory when in_memory is set, and outputs them as file refs.
+func s3Get(goCtx context.Context, client *s3.Client, bucket, prefix string, cfg *s3Config, ctx *fmodels.ExecutionContext) (map[string]interface{}, error) {
 	// List objects under prefix
 	paginator := s3.NewListObjectsV2Paginator(client, &s3.ListObjectsV2Input{
 		Bucket: aws.String(bucket),
@@ -100,6 +102,7 @@ func s3Get(goCtx context.Context, client *s3.Client, bucket, prefix string, cfg
 	})
 
 	var downloaded []string
+	var refs []FileRef
 	for paginator.HasMorePages() {
 		page, err := paginator.NextPage(goCtx)
 		if err != nil {
@@ -108,9 +111,15 @@ func s3Get(goCtx context.Context, client *s3.Client, bucket, prefix string, cfg
 		for _, obj := range page.Contents {
 			key := aws.ToString(obj.Key)
 			name := filepath.Base(key)
-			if filter != nil && !filter.MatchString(name) {
+			if cfg.filter != nil && !cfg.filter.MatchString(name) {
 				continue
 			}
+			localPath := ""
+			if !cfg.InMemory {
+				if localPath, err = localFilePath(ctx, filepath.Join(cfg.LocalFolder, name)); err != nil {
+					return nil, fmt.Errorf("s3 activity: %w", err)
+				}
+			}
 
 			resp, err := client.GetObject(goCtx, &s3.GetObjectInput{
 				Bucket: aws.String(bucket),
@@ -120,12 +129,22 @@ func s3Get(goCtx context.Context, client *s3.Client, bucket, prefix string, cfg
 				return nil, fmt.Errorf("s3 activity: failed to get object %q: %w", key, err)
 			}
 
-			localPath := filepath.Join(localFolder, name)
-			if err := writeLocalFile(localPath, resp.Body); err != nil {
+			if cfg.InMemory {
+				ref, err := storeFileRef(ctx, name, resp.Body)
 				resp.Body.Close()
-				return nil, fmt.Errorf("s3 activity: failed to write local file %q: %w", localPath, err)
+				if err != nil {
+					return nil, fmt.Errorf("s3 activity: failed to read object %q: %w", key, err)
+				}
+				refs = append(refs, ref)
+				downloaded = append(downloaded, name)
+				continue
 			}
+			ref, err := writeLocalFileRef(ctx, localPath, name, resp.Body)
 			resp.Body.Close()
+			if err != nil {
+				return nil, fmt.Errorf("s3 activity: failed to write local file %q: %w", localPath, err)
+			}
+			refs = append(refs, ref)
 			downloaded = append(downloaded, name)
 		}
 	}
@@ -135,67 +154,55 @@ func s3Get(goCtx context.Context, client *s3.Client, bucket, prefix string, cfg
 	}
 	return map[string]interface{}{
 		"files_downloaded": downloaded,
+		"files":            fileRefMaps(refs),
 		"count":            len(downloaded),
 	}, nil
 }
 
-// s3Put uploads files from config["files"] to the bucket/folder.
-func s3Put(goCtx context.Context, client *s3.Client, bucket, prefix string, cfg map[string]interface{}) (map[string]interface{}, error) {
-	localFolder, _ := cfg["local_folder"].(string)
-	if localFolder == "" {
-		localFolder = "."
-	}
-
-	overwrite := true
-	if ow, ok := cfg["overwrite"].(bool); ok {
-		overwrite = ow
-	}
-
-	var fileNames []string
-	if flist, ok := cfg["files"].([]interface{}); ok {
-		for _, f := range flist {
-			if s, ok := f.(string); ok {
-				fileNames = append(fileNames, s)
-			}
-		}
+// s3Put uploads the local files in config["files"] and the file refs in
+// input["files"] to the bucket/folder.
+func s3Put(goCtx context.Context, client s3API, bucket, prefix string, input map[string]interface{}, cfg *s3Config, ctx *fmodels.ExecutionContext) (map[string]interface{}, error) {
+	refs, err := fileRefsFromInput(input)
+	if err != nil {
+		return nil, fmt.Errorf("s3 activity: %w", err)
 	}
+	opts := parseS3PutOptions(cfg)
 
 	var uploaded []string
-	for _, name := range fileNames {
-		var key string
-		if prefix == "" {
-			key = name
-		} else {
-			key = strings.TrimRight(prefix, "/") + "/" + name
-		}
-
-		if !overwrite {
-			_, err := client.HeadObject(goCtx, &s3.HeadObjectInput{
-				Bucket: aws.String(bucket),
-				Key:    aws.String(key),
-			})
-			if err == nil {
-				// Object exists; skip
-				continue
-			}
+	for _, name := range cfg.Files {
+		key := s3Key(prefix, name)
+		if !cfg.Overwrite && s3ObjectExists(goCtx, client, bucket, key) {
+			continue
 		}
 
-		localPath := filepath.Join(localFolder, name)
-		data, err := os.ReadFile(localPath)
+		localPath, err := localFilePath(ctx, filepath.Join(cfg.LocalFolder, name))
 		if err != nil {
-			return nil, fmt.Errorf("s3 activity: failed to read local file %q: %w", localPath, err)
+			return nil, fmt.Errorf("s3 activity: %w", err)
 		}
-
-		_, err = client.PutObject(goCtx, &s3.PutObjectInput{
-			Bucket: aws.String(bucket),
-			Key:    aws.String(key),
-			Body:   bytes.NewReader(data),
-		})
+		n, err := s3UploadFile(goCtx, client, bucket, key, localPath, opts)
 		if err != nil {
 			return nil, fmt.Errorf("s3 activity: failed to upload %q: %w", key, err)
 		}
+		ctx.AddBytes(n)
 		uploaded = append(uploaded, name)
 	}
+	for _, ref := range refs {
+		key := s3Key(prefix, ref.Name)
+		if !cfg.Overwrite && s3ObjectExists(goCtx, client, bucket, key) {
+			continue
+		}
+		content, size, err := openFileRef(ctx, ref)
+		if err != nil {
+			return nil, fmt.Errorf("s3 activity: failed to upload %q: %w", key, err)
+		}
+		err = s3Upload(goCtx, client, bucket, key, content, size, opts)
+		content.Close()
+		if err != nil {
+			return nil, fmt.Errorf("s3 activity: failed to upload %q: %w", key, err)
+		}
+		ctx.AddBytes(size)
+		uploaded = append(uploaded, ref.Name)
+	}
 
 	if uploaded == nil {
 		uploaded = []string{}
@@ -206,6 +213,40 @@ func s3Put(goCtx context.Context, client *s3.Client, bucket, prefix string, cfg
 	}, nil
 }
 
+// s3Key joins the folder prefix and a file name into an object key.
+func s3Key(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return strings.TrimRight(prefix, "/") + "/" + name
+}
+
+// s3UploadFile uploads the local file at path to key and returns its size.
+func s3UploadFile(goCtx context.Context, client s3API, bucket, key, path string, opts s3PutOptions) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("read local file: %w", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("read local file: %w", err)
+	}
+	if err := s3Upload(goCtx, client, bucket, key, f, info.Size(), opts); err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// s3ObjectExists reports whether key exists in bucket.
+func s3ObjectExists(goCtx context.Context, client s3API, bucket, key string) bool {
+	_, err := client.HeadObject(goCtx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	return err == nil
+}
+
 // buildS3Client creates an AWS S3 client for the given region.
 // Credentials are read from cfg["auth"] (nested map) when present, or from
 // flat top-level keys (access_key_id, secret_access_key, session_token) injected
@@ -231,20 +272,3 @@ func buildS3Client(region string, cfg map[string]interface{}) (*s3.Client, error
 
 	return s3.NewFromConfig(awsCfg), nil
 }
-
-// writeLocalFile writes data from r to the given path, creating the file.
-func writeLocalFile(path string, r io.ReadCloser) error {
-	f, err := os.Create(path)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-	_, err = io.Copy(f, r)
-	return err
-}
-
-// contextFromCtx returns a Go context.Context for use in external API calls.
-// Currently returns context.Background(); can be extended to propagate deadlines.
-func contextFromCtx(_ *fmodels.ExecutionContext) context.Context {
-	return context.Background()
-}
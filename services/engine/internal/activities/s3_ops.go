@@ -0,0 +1,349 @@
+package activities
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+const (
+	// s3DefaultPresignExpiry is how long a presigned URL is valid by default.
+	s3DefaultPresignExpiry = 15 * time.Minute
+	// s3MaxPresignExpiry is the longest validity SigV4 allows.
+	s3MaxPresignExpiry = 7 * 24 * time.Hour
+	// s3DefaultMultipartThreshold is the size from which put uses a multipart
+	// upload; a single PUT is limited to 5 GiB.
+	s3DefaultMultipartThreshold = 100 << 20
+	// s3DefaultPartSize and s3MinPartSize bound multipart part sizes; S3
+	// rejects parts under 5 MiB except the last.
+	s3DefaultPartSize = 16 << 20
+	s3MinPartSize     = 5 << 20
+	// s3MaxParts is the most parts one multipart upload may have.
+	s3MaxParts = 10000
+	// s3DeleteBatch is the most keys one DeleteObjects call accepts.
+	s3DeleteBatch = 1000
+)
+
+// s3API is the subset of *s3.Client used by the put, delete and copy
+// methods, so they can be tested without S3.
+type s3API interface {
+	HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error)
+	UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error)
+	CompleteMultipartUpload(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error)
+	AbortMultipartUpload(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error)
+	DeleteObjects(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error)
+	CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error)
+}
+
+// s3Param returns the string parameter name from the node input, falling
+// back to its config value, so keys can be computed by earlier nodes.
+func s3Param(input map[string]interface{}, name, config string) string {
+	if s, ok := input[name].(string); ok && s != "" {
+		return s
+	}
+	return config
+}
+
+// s3PutOptions are the object attributes and upload tuning of a put.
+type s3PutOptions struct {
+	contentType        string
+	metadata           map[string]string
+	multipartThreshold int64
+	partSize           int64
+}
+
+func parseS3PutOptions(cfg *s3Config) s3PutOptions {
+	opts := s3PutOptions{contentType: cfg.ContentType, multipartThreshold: s3DefaultMultipartThreshold, partSize: s3DefaultPartSize}
+	if cfg.Metadata != nil {
+		opts.metadata = make(map[string]string, len(cfg.Metadata))
+		for k, v := range cfg.Metadata {
+			opts.metadata[k] = fmt.Sprint(v)
+		}
+	}
+	if mb := cfg.MultipartThresholdMB; mb > 0 {
+		opts.multipartThreshold = int64(mb * (1 << 20))
+	}
+	if mb := cfg.PartSizeMB; mb > 0 {
+		opts.partSize = int64(mb * (1 << 20))
+	}
+	return opts
+}
+
+// s3Upload writes size bytes of body to key, as a single PUT below the
+// multipart threshold and as a multipart upload above it.
+func s3Upload(goCtx context.Context, client s3API, bucket, key string, body io.Reader, size int64, opts s3PutOptions) error {
+	contentType := opts.contentType
+	if contentType == "" {
+		contentType = mime.TypeByExtension(filepath.Ext(key))
+	}
+	if size < opts.multipartThreshold {
+		in := &s3.PutObjectInput{
+			Bucket:        aws.String(bucket),
+			Key:           aws.String(key),
+			Body:          body,
+			ContentLength: aws.Int64(size),
+			Metadata:      opts.metadata,
+		}
+		if contentType != "" {
+			in.ContentType = aws.String(contentType)
+		}
+		_, err := client.PutObject(goCtx, in)
+		return err
+	}
+	return s3MultipartUpload(goCtx, client, bucket, key, contentType, body, size, opts)
+}
+
+// s3MultipartUpload uploads body in parts of opts.partSize (grown when the
+// object would need more than s3MaxParts), aborting the upload on failure
+// so no orphaned parts are billed.
+func s3MultipartUpload(goCtx context.Context, client s3API, bucket, key, contentType string, body io.Reader, size int64, opts s3PutOptions) error {
+	partSize := opts.partSize
+	if minPart := (size + s3MaxParts - 1) / s3MaxParts; partSize < minPart {
+		partSize = minPart
+	}
+	create := &s3.CreateMultipartUploadInput{
+		Bucket:   aws.String(bucket),
+		Key:      aws.String(key),
+		Metadata: opts.metadata,
+	}
+	if contentType != "" {
+		create.ContentType = aws.String(contentType)
+	}
+	mp, err := client.CreateMultipartUpload(goCtx, create)
+	if err != nil {
+		return fmt.Errorf("start multipart upload: %w", err)
+	}
+	abort := func(cause error) error {
+		_, _ = client.AbortMultipartUpload(goCtx, &s3.AbortMultipartUploadInput{
+			Bucket:   aws.String(bucket),
+			Key:      aws.String(key),
+			UploadId: mp.UploadId,
+		})
+		return cause
+	}
+
+	var parts []types.CompletedPart
+	buf := make([]byte, partSize)
+	for number := int32(1); ; number++ {
+		n, readErr := io.ReadFull(body, buf)
+		if n > 0 {
+			out, err := client.UploadPart(goCtx, &s3.UploadPartInput{
+				Bucket:        aws.String(bucket),
+				Key:           aws.String(key),
+				UploadId:      mp.UploadId,
+				PartNumber:    aws.Int32(number),
+				Body:          bytes.NewReader(buf[:n]),
+				ContentLength: aws.Int64(int64(n)),
+			})
+			if err != nil {
+				return abort(fmt.Errorf("upload part %d: %w", number, err))
+			}
+			parts = append(parts, types.CompletedPart{ETag: out.ETag, PartNumber: aws.Int32(number)})
+		}
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+		if readErr != nil {
+			return abort(fmt.Errorf("read part %d: %w", number, readErr))
+		}
+	}
+	_, err = client.CompleteMultipartUpload(goCtx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(bucket),
+		Key:             aws.String(key),
+		UploadId:        mp.UploadId,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+	})
+	if err != nil {
+		return abort(fmt.Errorf("complete multipart upload: %w", err))
+	}
+	return nil
+}
+
+// s3Presign returns a presigned URL for key. config: key (or input.key),
+// presign_method ("get" default, or "put"), expires_seconds (default 900,
+// at most 7 days) and, for put, content_type, which the uploader must send.
+func s3Presign(goCtx context.Context, client *s3.Client, bucket, prefix string, input map[string]interface{}, cfg *s3Config) (map[string]interface{}, error) {
+	key := s3Param(input, "key", cfg.Key)
+	if key == "" {
+		return nil, fmt.Errorf("s3 activity: presign needs 'key'")
+	}
+	key = s3Key(prefix, key)
+	expiry := s3DefaultPresignExpiry
+	if secs := cfg.ExpiresSeconds; secs > 0 {
+		expiry = time.Duration(secs) * time.Second
+	}
+	if expiry > s3MaxPresignExpiry {
+		return nil, fmt.Errorf("s3 activity: expires_seconds must be at most %d", int(s3MaxPresignExpiry.Seconds()))
+	}
+	withExpiry := s3.WithPresignExpires(expiry)
+	presigner := s3.NewPresignClient(client)
+
+	method := strings.ToLower(s3Param(input, "presign_method", cfg.PresignMethod))
+	var (
+		signed string
+		err    error
+	)
+	switch method {
+	case "", "get":
+		method = "get"
+		req, perr := presigner.PresignGetObject(goCtx, &s3.GetObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)}, withExpiry)
+		if perr == nil {
+			signed = req.URL
+		}
+		err = perr
+	case "put":
+		in := &s3.PutObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)}
+		if cfg.ContentType != "" {
+			in.ContentType = aws.String(cfg.ContentType)
+		}
+		req, perr := presigner.PresignPutObject(goCtx, in, withExpiry)
+		if perr == nil {
+			signed = req.URL
+		}
+		err = perr
+	default:
+		return nil, fmt.Errorf("s3 activity: presign_method must be 'get' or 'put'")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("s3 activity: presign %q: %w", key, err)
+	}
+	return map[string]interface{}{
+		"url":        signed,
+		"key":        key,
+		"method":     strings.ToUpper(method),
+		"expires_at": time.Now().Add(expiry).UTC().Format(time.RFC3339),
+	}, nil
+}
+
+// s3Delete deletes the objects named by input.keys or config.keys (relative
+// to folder, or a single "key"). Keys S3 reports as failed are listed in
+// "errors" without failing the node; a failed request fails it.
+func s3Delete(goCtx context.Context, client s3API, bucket, prefix string, input map[string]interface{}, cfg *s3Config) (map[string]interface{}, error) {
+	names := s3KeyList(input["keys"])
+	if len(names) == 0 {
+		names = s3KeyList(cfg.Keys)
+	}
+	if key := s3Param(input, "key", cfg.Key); len(names) == 0 && key != "" {
+		names = []string{key}
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("s3 activity: delete needs 'keys' or 'key'")
+	}
+
+	deleted := []string{}
+	failures := []interface{}{}
+	for start := 0; start < len(names); start += s3DeleteBatch {
+		end := min(start+s3DeleteBatch, len(names))
+		objects := make([]types.ObjectIdentifier, 0, end-start)
+		for _, name := range names[start:end] {
+			objects = append(objects, types.ObjectIdentifier{Key: aws.String(s3Key(prefix, name))})
+		}
+		out, err := client.DeleteObjects(goCtx, &s3.DeleteObjectsInput{
+			Bucket: aws.String(bucket),
+			Delete: &types.Delete{Objects: objects, Quiet: aws.Bool(false)},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("s3 activity: delete objects: %w", err)
+		}
+		for _, d := range out.Deleted {
+			deleted = append(deleted, aws.ToString(d.Key))
+		}
+		for _, e := range out.Errors {
+			failures = append(failures, map[string]interface{}{
+				"key":     aws.ToString(e.Key),
+				"code":    aws.ToString(e.Code),
+				"message": aws.ToString(e.Message),
+			})
+		}
+	}
+	return map[string]interface{}{
+		"deleted": deleted,
+		"errors":  failures,
+		"count":   len(deleted),
+	}, nil
+}
+
+// s3Copy copies source_key (in source_bucket, default bucket) to key under
+// folder without downloading it. metadata and content_type replace the
+// source object's; otherwise they are copied.
+func s3Copy(goCtx context.Context, client s3API, bucket, prefix string, input map[string]interface{}, cfg *s3Config) (map[string]interface{}, error) {
+	sourceKey := s3Param(input, "source_key", cfg.SourceKey)
+	if sourceKey == "" {
+		return nil, fmt.Errorf("s3 activity: copy needs 'source_key'")
+	}
+	sourceBucket := s3Param(input, "source_bucket", cfg.SourceBucket)
+	if sourceBucket == "" {
+		sourceBucket = bucket
+	}
+	key := s3Param(input, "key", cfg.Key)
+	if key == "" {
+		key = filepath.Base(sourceKey)
+	}
+	key = s3Key(prefix, key)
+
+	opts := parseS3PutOptions(cfg)
+	in := &s3.CopyObjectInput{
+		Bucket:     aws.String(bucket),
+		Key:        aws.String(key),
+		CopySource: aws.String(sourceBucket + "/" + s3EscapeKey(sourceKey)),
+	}
+	if opts.metadata != nil || opts.contentType != "" {
+		in.MetadataDirective = types.MetadataDirectiveReplace
+		in.Metadata = opts.metadata
+		if opts.contentType != "" {
+			in.ContentType = aws.String(opts.contentType)
+		}
+	}
+	out, err := client.CopyObject(goCtx, in)
+	if err != nil {
+		return nil, fmt.Errorf("s3 activity: copy %s/%s to %q: %w", sourceBucket, sourceKey, key, err)
+	}
+	result := map[string]interface{}{
+		"key":    key,
+		"source": sourceBucket + "/" + sourceKey,
+	}
+	if out.CopyObjectResult != nil {
+		result["etag"] = strings.Trim(aws.ToString(out.CopyObjectResult.ETag), `"`)
+	}
+	return result, nil
+}
+
+// s3KeyList reads a key or a list of keys.
+func s3KeyList(v interface{}) []string {
+	switch t := v.(type) {
+	case string:
+		if t != "" {
+			return []string{t}
+		}
+	case []interface{}:
+		var keys []string
+		for _, item := range t {
+			if s, ok := item.(string); ok && s != "" {
+				keys = append(keys, s)
+			}
+		}
+		return keys
+	}
+	return nil
+}
+
+// s3EscapeKey URL-encodes each segment of key for x-amz-copy-source.
+func s3EscapeKey(key string) string {
+	segments := strings.Split(key, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return strings.Join(segments, "/")
+}
@@ -1,74 +1,102 @@
 package activities
 
 import (
+	"context"
 	"fmt"
 	"time"
 
 	"flowjs-works/engine/internal/models"
+	"flowjs-works/engine/internal/tenant"
 
 	"github.com/dop251/goja"
 )
 
-// CodeActivity executes JavaScript/TypeScript code using Goja (registered as "code").
+// scriptConfig is the config of a code node.
+type scriptConfig struct {
+	Script    string `config:"script,required" doc:"TypeScript or JavaScript source; input holds the node input"`
+	TimeoutMS int    `config:"timeout_ms,default=5000" doc:"max run time in milliseconds"`
+}
+
+// CodeActivity executes TypeScript/JavaScript code using Goja (registered as "code").
+// Scripts are transpiled with esbuild, can call the standard library installed
+// by installStdlib and may import shared snippets from a SnippetSource.
 // The legacy "script_ts" type has been deprecated in favour of "code" (ADR 0001).
-type CodeActivity struct{}
+type CodeActivity struct {
+	snippets SnippetSource
+}
+
+// NewCodeActivity returns a CodeActivity that resolves imports through
+// snippets. A nil source makes every import fail.
+func NewCodeActivity(snippets SnippetSource) *CodeActivity {
+	return &CodeActivity{snippets: snippets}
+}
 
 func (a *CodeActivity) Name() string { return "code" }
 
+// ConfigSpec returns the config struct of code nodes.
+func (a *CodeActivity) ConfigSpec() interface{} { return &scriptConfig{} }
+
 func (a *CodeActivity) Execute(input map[string]interface{}, config map[string]interface{}, ctx *models.ExecutionContext) (map[string]interface{}, error) {
-	return executeScript(input, config, ctx)
+	return executeScript(input, config, ctx, a.snippets)
 }
 
-// executeScript runs JS code with timeout support.
-func executeScript(input map[string]interface{}, config map[string]interface{}, ctx *models.ExecutionContext) (map[string]interface{}, error) {
-	scriptCode, ok := config["script"]
-	if !ok {
-		return nil, fmt.Errorf("script not found in config")
+// executeScript runs the script with timeout support.
+func executeScript(input map[string]interface{}, config map[string]interface{}, ctx *models.ExecutionContext, snippets SnippetSource) (map[string]interface{}, error) {
+	var cfg scriptConfig
+	if err := decodeConfig("code", config, &cfg); err != nil {
+		return nil, err
 	}
-	scriptStr, ok := scriptCode.(string)
-	if !ok {
-		return nil, fmt.Errorf("script must be a string")
-	}
-	if scriptStr == "" {
-		return nil, fmt.Errorf("script cannot be empty")
+	prog, err := compileScript(cfg.Script, "script.ts", "")
+	if err != nil {
+		return nil, err
 	}
 
-	timeoutMs := defaultScriptTimeoutMs
-	if tmVal, ok := config["timeout_ms"]; ok {
-		switch v := tmVal.(type) {
-		case int:
-			timeoutMs = v
-		case float64:
-			timeoutMs = int(v)
-		}
+	workspace := ""
+	if ctx != nil {
+		workspace = ctx.Workspace
 	}
+	// reqCtx is cancelled on timeout so blocking stdlib calls (fetch) return too.
+	reqCtx, cancel := context.WithCancel(tenant.WithWorkspace(context.Background(), workspace))
+	defer cancel()
 
 	vm := goja.New()
 	if err := vm.Set("input", input); err != nil {
 		return nil, fmt.Errorf("failed to set input in JS environment: %w", err)
 	}
+	if err := installStdlib(vm, reqCtx); err != nil {
+		return nil, err
+	}
+	if err := vm.Set("require", newModuleLoader(vm, reqCtx, snippets).require); err != nil {
+		return nil, fmt.Errorf("failed to set require in JS environment: %w", err)
+	}
 
-	timer := time.AfterFunc(time.Duration(timeoutMs)*time.Millisecond, func() {
+	timer := time.AfterFunc(ctx.Budget(time.Duration(cfg.TimeoutMS)*time.Millisecond), func() {
 		vm.Interrupt("timeout")
+		cancel()
 	})
 	defer timer.Stop()
 
-	result, err := vm.RunString(scriptStr)
+	result, err := vm.RunProgram(prog)
 	if err != nil {
 		return nil, fmt.Errorf("JavaScript execution error: %w", err)
 	}
+	return scriptOutput(result), nil
+}
 
+// scriptOutput converts the script's completion value into node output:
+// objects are used as-is and any other value is wrapped as {"result": value}.
+func scriptOutput(result goja.Value) map[string]interface{} {
 	if result == nil || goja.IsUndefined(result) || goja.IsNull(result) {
-		return map[string]interface{}{}, nil
+		return map[string]interface{}{}
 	}
 
 	exportedResult := result.Export()
 	switch v := exportedResult.(type) {
 	case map[string]interface{}:
-		return v, nil
+		return v
 	case nil:
-		return map[string]interface{}{}, nil
+		return map[string]interface{}{}
 	default:
-		return map[string]interface{}{"result": v}, nil
+		return map[string]interface{}{"result": v}
 	}
 }
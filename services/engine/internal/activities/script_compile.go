@@ -0,0 +1,79 @@
+package activities
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/dop251/goja"
+	"github.com/evanw/esbuild/pkg/api"
+)
+
+// maxCompiledScripts bounds the compiled-program cache; it is cleared when full.
+const maxCompiledScripts = 512
+
+// scriptCache maps the SHA-256 of a transpiled source to its goja program.
+// Programs are immutable and may be run by many runtimes concurrently.
+var scriptCache = struct {
+	sync.Mutex
+	programs map[[32]byte]*goja.Program
+}{programs: make(map[[32]byte]*goja.Program)}
+
+// transpileScript turns TypeScript (or plain JavaScript) into code goja can
+// run. Type annotations are stripped and import/export statements become
+// CommonJS require calls so snippets can be resolved at run time. Top-level
+// statements are kept in place, so the value of the last expression is still
+// the script's result.
+func transpileScript(source, filename string) (string, error) {
+	res := api.Transform(source, api.TransformOptions{
+		Loader:     api.LoaderTS,
+		Format:     api.FormatCommonJS,
+		Target:     api.ES2017,
+		Sourcefile: filename,
+	})
+	if len(res.Errors) > 0 {
+		msgs := make([]string, 0, len(res.Errors))
+		for _, m := range res.Errors {
+			if m.Location != nil {
+				msgs = append(msgs, fmt.Sprintf("%s:%d:%d: %s", m.Location.File, m.Location.Line, m.Location.Column, m.Text))
+			} else {
+				msgs = append(msgs, m.Text)
+			}
+		}
+		return "", fmt.Errorf("TypeScript compile error: %s", strings.Join(msgs, "; "))
+	}
+	return string(res.Code), nil
+}
+
+// compileScript transpiles source, wraps it with wrap (identity when empty)
+// and compiles it, reusing a cached program for identical input.
+func compileScript(source, filename, wrap string) (*goja.Program, error) {
+	key := sha256.Sum256([]byte(wrap + "\x00" + source))
+	scriptCache.Lock()
+	prog, ok := scriptCache.programs[key]
+	scriptCache.Unlock()
+	if ok {
+		return prog, nil
+	}
+
+	code, err := transpileScript(source, filename)
+	if err != nil {
+		return nil, err
+	}
+	if wrap != "" {
+		code = fmt.Sprintf(wrap, code)
+	}
+	prog, err = goja.Compile(filename, code, false)
+	if err != nil {
+		return nil, fmt.Errorf("JavaScript compile error: %w", err)
+	}
+
+	scriptCache.Lock()
+	if len(scriptCache.programs) >= maxCompiledScripts {
+		scriptCache.programs = make(map[[32]byte]*goja.Program)
+	}
+	scriptCache.programs[key] = prog
+	scriptCache.Unlock()
+	return prog, nil
+}
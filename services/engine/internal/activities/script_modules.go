@@ -0,0 +1,90 @@
+package activities
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dop251/goja"
+)
+
+// SnippetSource returns the source of a shared script snippet by name in the
+// workspace carried by ctx. store.SnippetStore implements it.
+type SnippetSource interface {
+	Source(ctx context.Context, name string) (string, error)
+}
+
+// moduleWrapper turns a transpiled CommonJS snippet into a factory function.
+const moduleWrapper = "(function(exports, require, module) {\n%s\n})"
+
+// moduleLoader implements require() for one script run. Each snippet is
+// evaluated at most once per run; circular imports are rejected.
+type moduleLoader struct {
+	vm      *goja.Runtime
+	ctx     context.Context
+	source  SnippetSource
+	exports map[string]goja.Value
+	loading map[string]bool
+}
+
+func newModuleLoader(vm *goja.Runtime, ctx context.Context, source SnippetSource) *moduleLoader {
+	return &moduleLoader{
+		vm:      vm,
+		ctx:     ctx,
+		source:  source,
+		exports: make(map[string]goja.Value),
+		loading: make(map[string]bool),
+	}
+}
+
+// require is exposed to scripts; import statements compile down to it.
+func (l *moduleLoader) require(call goja.FunctionCall) goja.Value {
+	name := call.Argument(0).String()
+	exports, err := l.load(name)
+	if err != nil {
+		panic(l.vm.NewGoError(err))
+	}
+	return exports
+}
+
+func (l *moduleLoader) load(name string) (goja.Value, error) {
+	if v, ok := l.exports[name]; ok {
+		return v, nil
+	}
+	if l.source == nil {
+		return nil, fmt.Errorf("import %q: script snippets are not configured", name)
+	}
+	if l.loading[name] {
+		return nil, fmt.Errorf("import %q: circular import", name)
+	}
+	l.loading[name] = true
+	defer delete(l.loading, name)
+
+	src, err := l.source.Source(l.ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("import %q: %w", name, err)
+	}
+	prog, err := compileScript(src, name+".ts", moduleWrapper)
+	if err != nil {
+		return nil, fmt.Errorf("import %q: %w", name, err)
+	}
+	factory, err := l.vm.RunProgram(prog)
+	if err != nil {
+		return nil, fmt.Errorf("import %q: %w", name, err)
+	}
+	fn, ok := goja.AssertFunction(factory)
+	if !ok {
+		return nil, fmt.Errorf("import %q: snippet did not compile to a module", name)
+	}
+
+	module := l.vm.NewObject()
+	exports := l.vm.NewObject()
+	if err := module.Set("exports", exports); err != nil {
+		return nil, err
+	}
+	if _, err := fn(goja.Undefined(), exports, l.vm.Get("require"), module); err != nil {
+		return nil, fmt.Errorf("import %q: %w", name, err)
+	}
+	result := module.Get("exports")
+	l.exports[name] = result
+	return result, nil
+}
@@ -0,0 +1,226 @@
+package activities
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/google/uuid"
+)
+
+// scriptFetchMaxBody caps the response body a script can read through fetch.
+const scriptFetchMaxBody = 10 << 20
+
+// scriptHTTPClient is shared by every script so fetch calls reuse connections.
+var scriptHTTPClient = &http.Client{Timeout: defaultHTTPTimeout, Transport: defaultHTTPTransport()}
+
+// installStdlib exposes the script standard library on vm:
+//
+//	base64.encode(s) / base64.decode(s)
+//	crypto.hash(alg, data) / crypto.hmac(alg, key, data) / crypto.uuid()
+//	dates.now() / dates.add(iso, duration) / dates.diff(a, b) / dates.format(iso, layout) / dates.unix(iso)
+//	fetch(url, {method, headers, body}) → {status, ok, headers, body, json()}
+//
+// reqCtx is cancelled when the script times out so blocking fetch calls return.
+func installStdlib(vm *goja.Runtime, reqCtx context.Context) error {
+	libs := map[string]interface{}{
+		"base64": map[string]interface{}{
+			"encode": func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) },
+			"decode": func(s string) (string, error) {
+				b, err := base64.StdEncoding.DecodeString(s)
+				return string(b), err
+			},
+		},
+		"crypto": map[string]interface{}{
+			"hash": scriptHash,
+			"hmac": scriptHMAC,
+			"uuid": func() string { return uuid.New().String() },
+		},
+		"dates": map[string]interface{}{
+			"now":    func() string { return time.Now().UTC().Format(time.RFC3339Nano) },
+			"add":    scriptDateAdd,
+			"diff":   scriptDateDiff,
+			"format": scriptDateFormat,
+			"unix":   scriptDateUnix,
+		},
+		"fetch": func(call goja.FunctionCall) goja.Value { return scriptFetch(vm, reqCtx, call) },
+	}
+	for name, lib := range libs {
+		if err := vm.Set(name, lib); err != nil {
+			return fmt.Errorf("failed to set %s in JS environment: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// newScriptHash returns the hash constructor for alg (md5, sha1, sha256, sha512).
+func newScriptHash(alg string) (func() hash.Hash, error) {
+	switch strings.ToLower(alg) {
+	case "md5":
+		return md5.New, nil
+	case "sha1":
+		return sha1.New, nil
+	case "sha256":
+		return sha256.New, nil
+	case "sha512":
+		return sha512.New, nil
+	default:
+		return nil, fmt.Errorf("crypto: unsupported hash algorithm %q", alg)
+	}
+}
+
+// scriptHash returns the hex digest of data.
+func scriptHash(alg, data string) (string, error) {
+	newHash, err := newScriptHash(alg)
+	if err != nil {
+		return "", err
+	}
+	h := newHash()
+	h.Write([]byte(data))
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// scriptHMAC returns the hex HMAC of data keyed with key.
+func scriptHMAC(alg, key, data string) (string, error) {
+	newHash, err := newScriptHash(alg)
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(newHash, []byte(key))
+	mac.Write([]byte(data))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// scriptDateAdd adds a Go duration ("90m", "-36h") to an RFC 3339 timestamp.
+func scriptDateAdd(iso, duration string) (string, error) {
+	t, err := time.Parse(time.RFC3339Nano, iso)
+	if err != nil {
+		return "", fmt.Errorf("dates.add: %w", err)
+	}
+	d, err := time.ParseDuration(duration)
+	if err != nil {
+		return "", fmt.Errorf("dates.add: %w", err)
+	}
+	return t.Add(d).Format(time.RFC3339Nano), nil
+}
+
+// scriptDateDiff returns b - a in milliseconds.
+func scriptDateDiff(a, b string) (int64, error) {
+	ta, err := time.Parse(time.RFC3339Nano, a)
+	if err != nil {
+		return 0, fmt.Errorf("dates.diff: %w", err)
+	}
+	tb, err := time.Parse(time.RFC3339Nano, b)
+	if err != nil {
+		return 0, fmt.Errorf("dates.diff: %w", err)
+	}
+	return tb.Sub(ta).Milliseconds(), nil
+}
+
+// scriptDateFormat formats an RFC 3339 timestamp with a Go reference layout
+// (e.g. "2006-01-02").
+func scriptDateFormat(iso, layout string) (string, error) {
+	t, err := time.Parse(time.RFC3339Nano, iso)
+	if err != nil {
+		return "", fmt.Errorf("dates.format: %w", err)
+	}
+	return t.Format(layout), nil
+}
+
+// scriptDateUnix returns the Unix time in seconds of an RFC 3339 timestamp.
+func scriptDateUnix(iso string) (int64, error) {
+	t, err := time.Parse(time.RFC3339Nano, iso)
+	if err != nil {
+		return 0, fmt.Errorf("dates.unix: %w", err)
+	}
+	return t.Unix(), nil
+}
+
+// scriptFetch performs a synchronous, fetch-like HTTP request. Transport
+// errors throw in the script; HTTP error statuses are returned with ok=false.
+func scriptFetch(vm *goja.Runtime, reqCtx context.Context, call goja.FunctionCall) goja.Value {
+	url := call.Argument(0).String()
+	opts, _ := call.Argument(1).Export().(map[string]interface{})
+	method, _ := opts["method"].(string)
+	headers := map[string]string{}
+	if h, ok := opts["headers"].(map[string]interface{}); ok {
+		for k, v := range h {
+			headers[k] = fmt.Sprint(v)
+		}
+	}
+	req, err := newFetchRequest(reqCtx, url, method, headers, opts["body"])
+	if err != nil {
+		panic(vm.NewGoError(err))
+	}
+	resp, err := scriptHTTPClient.Do(req)
+	if err != nil {
+		panic(vm.NewGoError(fmt.Errorf("fetch %s: %w", url, err)))
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, scriptFetchMaxBody))
+	if err != nil {
+		panic(vm.NewGoError(fmt.Errorf("fetch %s: read body: %w", url, err)))
+	}
+
+	respHeaders := make(map[string]interface{}, len(resp.Header))
+	for k := range resp.Header {
+		respHeaders[strings.ToLower(k)] = resp.Header.Get(k)
+	}
+	return vm.ToValue(map[string]interface{}{
+		"status":  resp.StatusCode,
+		"ok":      resp.StatusCode >= 200 && resp.StatusCode < 300,
+		"headers": respHeaders,
+		"body":    string(body),
+		"json": func() (interface{}, error) {
+			var v interface{}
+			err := json.Unmarshal(body, &v)
+			return v, err
+		},
+	})
+}
+
+// newFetchRequest builds the request for scriptFetch. Non-string bodies are
+// sent as JSON.
+func newFetchRequest(ctx context.Context, url, method string, headers map[string]string, body interface{}) (*http.Request, error) {
+	if method == "" {
+		method = http.MethodGet
+	}
+	var reader io.Reader
+	isJSON := false
+	switch b := body.(type) {
+	case nil:
+	case string:
+		reader = strings.NewReader(b)
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			return nil, fmt.Errorf("fetch: marshal body: %w", err)
+		}
+		reader = strings.NewReader(string(data))
+		isJSON = true
+	}
+	req, err := http.NewRequestWithContext(ctx, strings.ToUpper(method), url, reader)
+	if err != nil {
+		return nil, fmt.Errorf("fetch: %w", err)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	if isJSON && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req, nil
+}
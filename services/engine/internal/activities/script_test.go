@@ -0,0 +1,155 @@
+package activities
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"flowjs-works/engine/internal/models"
+	"flowjs-works/engine/internal/tenant"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSnippets serves snippet sources keyed by workspace and name.
+type fakeSnippets map[string]string
+
+func (f fakeSnippets) Source(ctx context.Context, name string) (string, error) {
+	src, ok := f[tenant.Workspace(ctx)+":"+name]
+	if !ok {
+		return "", fmt.Errorf("snippet %q not found", name)
+	}
+	return src, nil
+}
+
+func runCode(t *testing.T, script string, input map[string]interface{}, snippets SnippetSource) (map[string]interface{}, error) {
+	t.Helper()
+	ctx := models.NewExecutionContext("exec-1")
+	return NewCodeActivity(snippets).Execute(input, map[string]interface{}{"script": script}, ctx)
+}
+
+func TestCodeActivity_TypeScript(t *testing.T) {
+	script := `
+interface Order { id: string; total: number }
+const order = input.order as Order;
+const vat = (n: number): number => Math.round(n * 0.21 * 100) / 100;
+({ id: order.id, vat: vat(order.total), tag: input.tag?.name ?? "none" })`
+	out, err := runCode(t, script, map[string]interface{}{"order": map[string]interface{}{"id": "A-1", "total": 100}}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "A-1", out["id"])
+	assert.EqualValues(t, 21, out["vat"])
+	assert.Equal(t, "none", out["tag"])
+}
+
+func TestCodeActivity_CompileError(t *testing.T) {
+	_, err := runCode(t, "const x: = ;", nil, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "TypeScript compile error")
+}
+
+func TestCodeActivity_Stdlib(t *testing.T) {
+	script := `({
+		b64: base64.encode("hi"),
+		plain: base64.decode("aGk="),
+		sha: crypto.hash("sha256", "abc"),
+		mac: crypto.hmac("sha256", "key", "abc"),
+		id: crypto.uuid().length,
+		later: dates.add("2025-01-31T09:00:00Z", "36h"),
+		diff: dates.diff("2025-01-31T09:00:00Z", "2025-01-31T09:00:01.5Z"),
+		day: dates.format("2025-01-31T09:00:00Z", "2006-01-02"),
+		unix: dates.unix("1970-01-01T00:01:00Z"),
+	})`
+	out, err := runCode(t, script, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "aGk=", out["b64"])
+	assert.Equal(t, "hi", out["plain"])
+	assert.Equal(t, "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad", out["sha"])
+	assert.Len(t, out["mac"], 64)
+	assert.EqualValues(t, 36, out["id"])
+	assert.Equal(t, "2025-02-01T21:00:00Z", out["later"])
+	assert.EqualValues(t, 1500, out["diff"])
+	assert.Equal(t, "2025-01-31", out["day"])
+	assert.EqualValues(t, 60, out["unix"])
+}
+
+func TestCodeActivity_StdlibErrorsThrow(t *testing.T) {
+	out, err := runCode(t, `try { crypto.hash("sha3", "x"); ({ caught: false }) } catch (e) { ({ caught: true }) }`, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, true, out["caught"])
+}
+
+func TestCodeActivity_Fetch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Seen-Type", r.Header.Get("Content-Type"))
+		fmt.Fprintf(w, `{"method":%q,"echo":%s}`, r.Method, body)
+	}))
+	defer srv.Close()
+
+	script := `
+const res = fetch(input.url, { method: "post", body: { n: 1 } });
+({ status: res.status, ok: res.ok, seen: res.headers["x-seen-type"], data: res.json() })`
+	out, err := runCode(t, script, map[string]interface{}{"url": srv.URL}, nil)
+	require.NoError(t, err)
+	assert.EqualValues(t, 200, out["status"])
+	assert.Equal(t, true, out["ok"])
+	assert.Equal(t, "application/json", out["seen"])
+	assert.Equal(t, map[string]interface{}{"method": "POST", "echo": map[string]interface{}{"n": float64(1)}}, out["data"])
+}
+
+func TestCodeActivity_ImportSnippets(t *testing.T) {
+	snippets := fakeSnippets{
+		"default:utils/money": `export const round2 = (n: number) => Math.round(n * 100) / 100;`,
+		"default:utils/vat": `import { round2 } from "utils/money";
+export default function vat(n: number): number { return round2(n * 0.21); }`,
+	}
+	script := `import vat from "utils/vat";
+import { round2 } from "utils/money";
+({ vat: vat(input.total), rounded: round2(1.005) })`
+	out, err := runCode(t, script, map[string]interface{}{"total": 10.5}, snippets)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2.21, out["vat"])
+	assert.EqualValues(t, 1, out["rounded"])
+}
+
+func TestCodeActivity_ImportIsWorkspaceScoped(t *testing.T) {
+	snippets := fakeSnippets{"team-a:lib": `export const who = "a";`}
+	ctx := models.NewExecutionContext("exec-1")
+	ctx.Workspace = "team-b"
+	_, err := NewCodeActivity(snippets).Execute(nil, map[string]interface{}{"script": `import { who } from "lib"; who`}, ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `import "lib"`)
+
+	ctx.Workspace = "team-a"
+	out, err := NewCodeActivity(snippets).Execute(nil, map[string]interface{}{"script": `import { who } from "lib"; who`}, ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "a", out["result"])
+}
+
+func TestCodeActivity_CircularImport(t *testing.T) {
+	snippets := fakeSnippets{
+		"default:a": `import { b } from "b"; export const a = b + 1;`,
+		"default:b": `import { a } from "a"; export const b = a + 1;`,
+	}
+	_, err := runCode(t, `import { a } from "a"; a`, nil, snippets)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "circular import")
+}
+
+func TestCodeActivity_ImportWithoutSource(t *testing.T) {
+	_, err := runCode(t, `import { x } from "lib"; x`, nil, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "script snippets are not configured")
+}
+
+func TestCodeActivity_Timeout(t *testing.T) {
+	ctx := models.NewExecutionContext("exec-1")
+	_, err := NewCodeActivity(nil).Execute(nil, map[string]interface{}{"script": "while (true) {}", "timeout_ms": float64(50)}, ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "timeout")
+}
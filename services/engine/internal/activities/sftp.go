@@ -6,63 +6,45 @@ import (
 	"net"
 	"os"
 	"path"
-	"regexp"
+	"strconv"
 
 	"github.com/pkg/sftp"
 	"golang.org/x/crypto/ssh"
 
+	"flowjs-works/engine/internal/egress"
 	fmodels "flowjs-works/engine/internal/models"
 )
 
-// SFTPActivity implements the `sftp` node type.
-//
-// config fields (all string unless noted):
+// sftpConfig is the config of an sftp node.
+type sftpConfig struct {
+	Server string                 `config:"server,required" doc:"hostname or IP"`
+	Port   int                    `config:"port,default=22"`
+	Auth   map[string]interface{} `config:"auth" doc:"user, and password or private_key (PEM)"`
+	Folder string                 `config:"folder,required" doc:"remote directory"`
+	Method string                 `config:"method,required,enum=get|put"`
+	// CreateFolder creates the remote folder before a put.
+	CreateFolder bool `config:"create_folder" doc:"put creates the remote folder when missing"`
+	fileTransferConfig
+}
+
+// SFTPActivity implements the `sftp` node type (config: sftpConfig).
 //
-//	server:        hostname or IP (required)
-//	port:          int, default 22
-//	auth:          map — user (string), password (string) OR private_key (PEM string)
-//	folder:        remote directory (required)
-//	method:        "get" | "put" (required)
-//	regex_filter:  regex to filter remote filenames (get only)
-//	overwrite:     bool — overwrite existing destination files (put only, default true)
-//	create_folder: bool — create destination folder if missing (put only)
-//	local_folder:  local directory used as source (put) or destination (get)
-//	files:         []interface{} of local filenames to upload (put only)
+// A get node outputs the downloaded files as file refs (see FileRef), and a
+// put node also uploads the refs it receives as input["files"], e.g.
+// input_mapping {"files": "$.nodes.fetch.output.files"}.
 type SFTPActivity struct{}
 
 // Name returns the DSL type identifier for this activity.
 func (a *SFTPActivity) Name() string { return "sftp" }
 
+// ConfigSpec returns the config struct of sftp nodes.
+func (a *SFTPActivity) ConfigSpec() interface{} { return &sftpConfig{} }
+
 // Execute runs the SFTP get or put operation.
 func (a *SFTPActivity) Execute(input map[string]interface{}, config map[string]interface{}, ctx *fmodels.ExecutionContext) (map[string]interface{}, error) {
-	server, ok := config["server"].(string)
-	if !ok || server == "" {
-		return nil, fmt.Errorf("sftp activity: missing required config field 'server'")
-	}
-
-	port := 22
-	switch v := config["port"].(type) {
-	case int:
-		port = v
-	case float64:
-		port = int(v)
-	}
-
-	method, ok := config["method"].(string)
-	if !ok || (method != "get" && method != "put") {
-		return nil, fmt.Errorf("sftp activity: config field 'method' must be 'get' or 'put'")
-	}
-
-	folder, ok := config["folder"].(string)
-	if !ok || folder == "" {
-		return nil, fmt.Errorf("sftp activity: missing required config field 'folder'")
-	}
-
-	// Validate regex_filter early so callers get a clear error before any network I/O.
-	if rf, ok := config["regex_filter"].(string); ok && rf != "" {
-		if _, err := regexp.Compile(rf); err != nil {
-			return nil, fmt.Errorf("sftp activity: invalid regex_filter %q: %w", rf, err)
-		}
+	var cfg sftpConfig
+	if err := decodeConfig(a.Name(), config, &cfg); err != nil {
+		return nil, err
 	}
 
 	sshCfg, err := buildSSHClientConfig(config)
@@ -70,8 +52,8 @@ func (a *SFTPActivity) Execute(input map[string]interface{}, config map[string]i
 		return nil, fmt.Errorf("sftp activity: failed to build SSH config: %w", err)
 	}
 
-	addr := fmt.Sprintf("%s:%d", server, port)
-	conn, err := net.DialTimeout("tcp", addr, defaultNetDialTimeout)
+	addr := net.JoinHostPort(cfg.Server, strconv.Itoa(cfg.Port))
+	conn, err := egress.Dialer(ctx.Budget(defaultNetDialTimeout)).Dial("tcp", addr)
 	if err != nil {
 		return nil, fmt.Errorf("sftp activity: TCP dial failed: %w", err)
 	}
@@ -90,30 +72,16 @@ func (a *SFTPActivity) Execute(input map[string]interface{}, config map[string]i
 	}
 	defer sftpClient.Close()
 
-	switch method {
-	case "get":
-		return sftpGet(sftpClient, config, folder)
-	case "put":
-		return sftpPut(sftpClient, config, folder)
-	default:
-		return nil, fmt.Errorf("sftp activity: unknown method %q", method)
+	if cfg.Method == "get" {
+		return sftpGet(sftpClient, &cfg, ctx)
 	}
+	return sftpPut(sftpClient, input, &cfg, ctx)
 }
 
-// sftpGet downloads files from the remote folder to local_folder, optionally
-// filtered by regex_filter.
-func sftpGet(client *sftp.Client, config map[string]interface{}, remoteFolder string) (map[string]interface{}, error) {
-	localFolder, _ := config["local_folder"].(string)
-	if localFolder == "" {
-		localFolder = "."
-	}
-
-	// regex_filter was already validated in Execute; compile here to apply it.
-	var filter *regexp.Regexp
-	if rf, ok := config["regex_filter"].(string); ok && rf != "" {
-		// Error is ignored — compilation was already validated in Execute.
-		filter, _ = regexp.Compile(rf)
-	}
+// sftpGet downloads files from the remote folder to local_folder, or into
+// memory when in_memory is set, optionally filtered by regex_filter.
+func sftpGet(client *sftp.Client, cfg *sftpConfig, ctx *fmodels.ExecutionContext) (map[string]interface{}, error) {
+	remoteFolder := cfg.Folder
 
 	entries, err := client.ReadDir(remoteFolder)
 	if err != nil {
@@ -121,21 +89,28 @@ func sftpGet(client *sftp.Client, config map[string]interface{}, remoteFolder st
 	}
 
 	var downloaded []string
+	var refs []FileRef
 	for _, entry := range entries {
 		if entry.IsDir() {
 			continue
 		}
 		name := entry.Name()
-		if filter != nil && !filter.MatchString(name) {
+		if cfg.filter != nil && !cfg.filter.MatchString(name) {
 			continue
 		}
 
 		remotePath := path.Join(remoteFolder, name)
-		localPath := localFolder + "/" + name
-
-		if err := downloadFile(client, remotePath, localPath); err != nil {
+		localPath := ""
+		if !cfg.InMemory {
+			if localPath, err = localFilePath(ctx, cfg.LocalFolder+"/"+name); err != nil {
+				return nil, fmt.Errorf("sftp activity: %w", err)
+			}
+		}
+		ref, err := downloadFile(client, remotePath, localPath, name, ctx)
+		if err != nil {
 			return nil, fmt.Errorf("sftp activity: failed to download %q: %w", name, err)
 		}
+		refs = append(refs, ref)
 		downloaded = append(downloaded, name)
 	}
 
@@ -144,55 +119,61 @@ func sftpGet(client *sftp.Client, config map[string]interface{}, remoteFolder st
 	}
 	return map[string]interface{}{
 		"files_downloaded": downloaded,
+		"files":            fileRefMaps(refs),
 		"count":            len(downloaded),
 	}, nil
 }
 
-// sftpPut uploads files from input["files"] (or config["files"]) to the remote folder.
-func sftpPut(client *sftp.Client, config map[string]interface{}, remoteFolder string) (map[string]interface{}, error) {
-	createFolder, _ := config["create_folder"].(bool)
-	overwrite := true
-	if ow, ok := config["overwrite"].(bool); ok {
-		overwrite = ow
-	}
-	localFolder, _ := config["local_folder"].(string)
-	if localFolder == "" {
-		localFolder = "."
-	}
+// sftpPut uploads the local files in config["files"] and the file refs in
+// input["files"] to the remote folder.
+func sftpPut(client *sftp.Client, input map[string]interface{}, cfg *sftpConfig, ctx *fmodels.ExecutionContext) (map[string]interface{}, error) {
+	remoteFolder := cfg.Folder
 
-	// Collect filenames to upload
-	var fileNames []string
-	if flist, ok := config["files"].([]interface{}); ok {
-		for _, f := range flist {
-			if s, ok := f.(string); ok {
-				fileNames = append(fileNames, s)
-			}
-		}
+	refs, err := fileRefsFromInput(input)
+	if err != nil {
+		return nil, fmt.Errorf("sftp activity: %w", err)
 	}
 
-	if createFolder {
+	if cfg.CreateFolder {
 		if err := client.MkdirAll(remoteFolder); err != nil {
 			return nil, fmt.Errorf("sftp activity: failed to create remote folder %q: %w", remoteFolder, err)
 		}
 	}
 
 	var uploaded []string
-	for _, name := range fileNames {
-		localPath := localFolder + "/" + name
+	for _, name := range cfg.Files {
+		localPath, err := localFilePath(ctx, cfg.LocalFolder+"/"+name)
+		if err != nil {
+			return nil, fmt.Errorf("sftp activity: %w", err)
+		}
 		remotePath := path.Join(remoteFolder, name)
 
-		if !overwrite {
+		if !cfg.Overwrite {
 			if _, err := client.Stat(remotePath); err == nil {
 				// File exists; skip
 				continue
 			}
 		}
 
-		if err := uploadFile(client, localPath, remotePath); err != nil {
+		n, err := uploadFile(client, localPath, remotePath)
+		if err != nil {
 			return nil, fmt.Errorf("sftp activity: failed to upload %q: %w", name, err)
 		}
+		ctx.AddBytes(n)
 		uploaded = append(uploaded, name)
 	}
+	for _, ref := range refs {
+		remotePath := path.Join(remoteFolder, path.Base(ref.Name))
+		if !cfg.Overwrite {
+			if _, err := client.Stat(remotePath); err == nil {
+				continue
+			}
+		}
+		if err := uploadFileRef(client, ctx, ref, remotePath); err != nil {
+			return nil, fmt.Errorf("sftp activity: failed to upload %q: %w", ref.Name, err)
+		}
+		uploaded = append(uploaded, path.Base(ref.Name))
+	}
 
 	if uploaded == nil {
 		uploaded = []string{}
@@ -203,44 +184,53 @@ func sftpPut(client *sftp.Client, config map[string]interface{}, remoteFolder st
 	}, nil
 }
 
-// downloadFile copies a single remote file to a local path.
-func downloadFile(client *sftp.Client, remotePath, localPath string) error {
+// downloadFile copies a single remote file to localPath, or into memory when
+// localPath is empty, and returns its ref.
+func downloadFile(client *sftp.Client, remotePath, localPath, name string, ctx *fmodels.ExecutionContext) (FileRef, error) {
 	remote, err := client.Open(remotePath)
 	if err != nil {
-		return err
+		return FileRef{}, err
 	}
 	defer remote.Close()
+	if localPath == "" {
+		return storeFileRef(ctx, name, remote)
+	}
+	return writeLocalFileRef(ctx, localPath, name, remote)
+}
 
-	local, err := os.Create(localPath)
+// uploadFileRef copies the file of a ref to a remote path.
+func uploadFileRef(client *sftp.Client, ctx *fmodels.ExecutionContext, ref FileRef, remotePath string) error {
+	content, _, err := openFileRef(ctx, ref)
 	if err != nil {
 		return err
 	}
-	defer local.Close()
-
-	if _, err := io.Copy(local, remote); err != nil {
+	defer content.Close()
+	remote, err := client.Create(remotePath)
+	if err != nil {
 		return err
 	}
-	return nil
+	defer remote.Close()
+
+	n, err := io.Copy(remote, content)
+	ctx.AddBytes(n)
+	return err
 }
 
-// uploadFile copies a local file to a remote path.
-func uploadFile(client *sftp.Client, localPath, remotePath string) error {
+// uploadFile copies a local file to a remote path and returns the bytes copied.
+func uploadFile(client *sftp.Client, localPath, remotePath string) (int64, error) {
 	local, err := os.Open(localPath)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer local.Close()
 
 	remote, err := client.Create(remotePath)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer remote.Close()
 
-	if _, err := io.Copy(remote, local); err != nil {
-		return err
-	}
-	return nil
+	return io.Copy(remote, local)
 }
 
 // buildSSHClientConfig builds an ssh.ClientConfig from the activity config.
@@ -3,77 +3,99 @@ package activities
 import (
 	"fmt"
 	"io"
+	iofs "io/fs"
 	"net"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
+	"strconv"
+	"strings"
 
 	"github.com/hirochachacha/go-smb2"
 
+	"flowjs-works/engine/internal/egress"
 	fmodels "flowjs-works/engine/internal/models"
 )
 
-// SMBActivity implements the `smb` node type (SMB2/3 protocol).
-//
-// config fields:
+// smbConfig is the config of an smb node.
+type smbConfig struct {
+	Server string                 `config:"server,required" doc:"hostname or IP"`
+	Port   int                    `config:"port,default=445"`
+	Share  string                 `config:"share,required" doc:"SMB share name, e.g. shared"`
+	Auth   map[string]interface{} `config:"auth" doc:"user, password and optional domain"`
+	Folder string                 `config:"folder,default=." doc:"directory inside the share"`
+	Method string                 `config:"method,required,enum=get|put|delete|move"`
+	// Recursive descends into sub-directories, preserving the layout under
+	// local_folder (get/put) or removing whole trees (delete).
+	Recursive   bool   `config:"recursive" doc:"descend into sub-directories"`
+	Source      string `config:"source" doc:"path relative to folder to move (move only)"`
+	Destination string `config:"destination" doc:"new path relative to folder (move only)"`
+	fileTransferConfig
+}
+
+func (c *smbConfig) validate() []FieldError {
+	if c.Method == "move" {
+		var problems []FieldError
+		if c.Source == "" {
+			problems = append(problems, FieldError{"source", "is required by method 'move'"})
+		}
+		if c.Destination == "" {
+			problems = append(problems, FieldError{"destination", "is required by method 'move'"})
+		}
+		if problems != nil {
+			return problems
+		}
+	}
+	return c.fileTransferConfig.validate()
+}
+
+// SMBActivity implements the `smb` node type (SMB2/3 protocol; config:
+// smbConfig). files lists the paths relative to folder a put uploads or a
+// delete removes, and regex_filter also selects what a delete removes.
 //
-//	server:        hostname or IP (required)
-//	port:          int, default 445
-//	share:         SMB share name, e.g. "shared" (required)
-//	auth:          map — user (string), password (string), domain (string, optional)
-//	folder:        directory path inside the share (default "/")
-//	method:        "get" | "put" (required)
-//	regex_filter:  regex to filter filenames (get only)
-//	overwrite:     bool — overwrite existing destination files (put only, default true)
-//	local_folder:  local directory used as source (put) or destination (get)
-//	files:         []interface{} of filenames to upload (put only)
+// A get node outputs the downloaded files as file refs (see FileRef), and a
+// put node also uploads the refs it receives as input["files"]; ref names
+// keep the relative layout of a recursive get.
 type SMBActivity struct{}
 
-// Name returns the DSL type identifier for this activity.
-func (a *SMBActivity) Name() string { return "smb" }
-
-// Execute runs the SMB get or put operation.
-func (a *SMBActivity) Execute(input map[string]interface{}, config map[string]interface{}, ctx *fmodels.ExecutionContext) (map[string]interface{}, error) {
-	server, ok := config["server"].(string)
-	if !ok || server == "" {
-		return nil, fmt.Errorf("smb activity: missing required config field 'server'")
-	}
+// smbFS is the subset of *smb2.Share used by the activity. Paths use "/" as
+// separator; go-smb2 normalises them to "\".
+type smbFS interface {
+	ReadDir(name string) ([]os.FileInfo, error)
+	Stat(name string) (os.FileInfo, error)
+	OpenReader(name string) (io.ReadCloser, error)
+	CreateWriter(name string) (io.WriteCloser, error)
+	MkdirAll(name string, perm os.FileMode) error
+	Remove(name string) error
+	RemoveAll(name string) error
+	Rename(oldname, newname string) error
+}
 
-	port := 445
-	switch v := config["port"].(type) {
-	case int:
-		port = v
-	case float64:
-		port = int(v)
-	}
+// shareFS adapts *smb2.Share to smbFS.
+type shareFS struct{ *smb2.Share }
 
-	share, ok := config["share"].(string)
-	if !ok || share == "" {
-		return nil, fmt.Errorf("smb activity: missing required config field 'share'")
-	}
+func (s shareFS) OpenReader(name string) (io.ReadCloser, error)    { return s.Open(name) }
+func (s shareFS) CreateWriter(name string) (io.WriteCloser, error) { return s.Create(name) }
 
-	method, ok := config["method"].(string)
-	if !ok || (method != "get" && method != "put") {
-		return nil, fmt.Errorf("smb activity: config field 'method' must be 'get' or 'put'")
-	}
+// Name returns the DSL type identifier for this activity.
+func (a *SMBActivity) Name() string { return "smb" }
 
-	folder, _ := config["folder"].(string)
-	if folder == "" {
-		folder = "."
-	}
+// ConfigSpec returns the config struct of smb nodes.
+func (a *SMBActivity) ConfigSpec() interface{} { return &smbConfig{} }
 
-	// Validate regex_filter early so callers get a clear error before any network I/O.
-	if rf, ok := config["regex_filter"].(string); ok && rf != "" {
-		if _, err := regexp.Compile(rf); err != nil {
-			return nil, fmt.Errorf("smb activity: invalid regex_filter %q: %w", rf, err)
-		}
+// Execute runs the SMB get, put, delete or move operation.
+func (a *SMBActivity) Execute(input map[string]interface{}, config map[string]interface{}, ctx *fmodels.ExecutionContext) (map[string]interface{}, error) {
+	var cfg smbConfig
+	if err := decodeConfig(a.Name(), config, &cfg); err != nil {
+		return nil, err
 	}
 
 	// Extract auth
 	user, password, domain := extractSMBAuth(config)
 
-	addr := fmt.Sprintf("%s:%d", server, port)
-	conn, err := net.DialTimeout("tcp", addr, defaultNetDialTimeout)
+	addr := net.JoinHostPort(cfg.Server, strconv.Itoa(cfg.Port))
+	conn, err := egress.Dialer(defaultNetDialTimeout).Dial("tcp", addr)
 	if err != nil {
 		return nil, fmt.Errorf("smb activity: TCP dial failed: %w", err)
 	}
@@ -93,150 +115,382 @@ func (a *SMBActivity) Execute(input map[string]interface{}, config map[string]in
 	}
 	defer session.Logoff()
 
-	fs, err := session.Mount(share)
+	fs, err := session.Mount(cfg.Share)
 	if err != nil {
-		return nil, fmt.Errorf("smb activity: failed to mount share %q: %w", share, err)
+		return nil, fmt.Errorf("smb activity: failed to mount share %q: %w", cfg.Share, err)
 	}
 	defer fs.Umount()
 
-	switch method {
+	return runSMBMethod(shareFS{fs}, input, &cfg, ctx)
+}
+
+// runSMBMethod dispatches to the method implementation.
+func runSMBMethod(fs smbFS, input map[string]interface{}, cfg *smbConfig, ctx *fmodels.ExecutionContext) (map[string]interface{}, error) {
+	switch cfg.Method {
 	case "get":
-		return smbGet(fs, config, folder)
+		return smbGet(fs, cfg, ctx)
 	case "put":
-		return smbPut(fs, config, folder)
+		return smbPut(fs, input, cfg, ctx)
+	case "delete":
+		return smbDelete(fs, cfg)
+	case "move":
+		return smbMove(fs, cfg)
 	default:
-		return nil, fmt.Errorf("smb activity: unknown method %q", method)
+		return nil, fmt.Errorf("smb activity: unknown method %q", cfg.Method)
 	}
 }
 
-// smbGet downloads files from the SMB share/folder to local_folder.
-func smbGet(fs *smb2.Share, config map[string]interface{}, remoteFolder string) (map[string]interface{}, error) {
-	localFolder, _ := config["local_folder"].(string)
-	if localFolder == "" {
-		localFolder = "."
+// smbLocalFolder returns local_folder as the local path of the execution of
+// ctx (see localFilePath).
+func smbLocalFolder(cfg *smbConfig, ctx *fmodels.ExecutionContext) (string, error) {
+	folder, err := localFilePath(ctx, cfg.LocalFolder)
+	if err != nil {
+		return "", fmt.Errorf("smb activity: %w", err)
 	}
+	return folder, nil
+}
 
-	// regex_filter was already validated in Execute; compile here to apply it.
-	var filter *regexp.Regexp
-	if rf, ok := config["regex_filter"].(string); ok && rf != "" {
-		// Error is ignored — compilation was already validated in Execute.
-		filter, _ = regexp.Compile(rf)
+// smbRelPath validates a path relative to folder supplied by the DSL or read
+// from a directory listing and returns it in slash form. Absolute paths and
+// ".." segments are rejected so operations stay inside folder and local_folder.
+func smbRelPath(name string) (string, error) {
+	rel := path.Clean(strings.ReplaceAll(name, "\\", "/"))
+	if rel == "." || path.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", fmt.Errorf("smb activity: invalid relative path %q", name)
 	}
+	return rel, nil
+}
 
-	entries, err := fs.ReadDir(remoteFolder)
+// smbWalk lists the files under remoteFolder as slash-separated paths relative
+// to it. Sub-directories are descended only when recursive is true. filter is
+// matched against the base file name.
+func smbWalk(fs smbFS, remoteFolder, rel string, recursive bool, filter *regexp.Regexp) ([]string, error) {
+	dir := path.Join(remoteFolder, rel)
+	entries, err := fs.ReadDir(dir)
 	if err != nil {
-		return nil, fmt.Errorf("smb activity: failed to list remote folder %q: %w", remoteFolder, err)
+		return nil, fmt.Errorf("smb activity: failed to list remote folder %q: %w", dir, err)
 	}
-
-	var downloaded []string
+	var files []string
 	for _, entry := range entries {
+		name := entry.Name()
+		if name == "." || name == ".." || strings.ContainsAny(name, "/\\") {
+			continue
+		}
+		child := path.Join(rel, name)
 		if entry.IsDir() {
+			if !recursive {
+				continue
+			}
+			sub, err := smbWalk(fs, remoteFolder, child, recursive, filter)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, sub...)
 			continue
 		}
-		name := entry.Name()
 		if filter != nil && !filter.MatchString(name) {
 			continue
 		}
+		files = append(files, child)
+	}
+	return files, nil
+}
 
-		remotePath := filepath.Join(remoteFolder, name)
-		localPath := filepath.Join(localFolder, name)
+// smbGet downloads files from the SMB share/folder to local_folder, or into
+// memory when in_memory is set. With recursive set, nested directories are
+// recreated under local_folder (or kept in the ref names).
+func smbGet(fs smbFS, cfg *smbConfig, ctx *fmodels.ExecutionContext) (map[string]interface{}, error) {
+	remoteFolder := cfg.Folder
+	localFolder, err := smbLocalFolder(cfg, ctx)
+	if err != nil {
+		return nil, err
+	}
 
-		if err := smbDownloadFile(fs, remotePath, localPath); err != nil {
-			return nil, fmt.Errorf("smb activity: failed to download %q: %w", name, err)
-		}
-		downloaded = append(downloaded, name)
+	files, err := smbWalk(fs, remoteFolder, "", cfg.Recursive, cfg.filter)
+	if err != nil {
+		return nil, err
 	}
 
-	if downloaded == nil {
-		downloaded = []string{}
+	downloaded := []string{}
+	var refs []FileRef
+	for _, rel := range files {
+		if cfg.InMemory {
+			ref, err := smbDownloadFileRef(fs, path.Join(remoteFolder, rel), rel, ctx)
+			if err != nil {
+				return nil, fmt.Errorf("smb activity: failed to download %q: %w", rel, err)
+			}
+			refs = append(refs, ref)
+			downloaded = append(downloaded, rel)
+			continue
+		}
+		localPath := filepath.Join(localFolder, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(localPath), 0o755); err != nil {
+			return nil, fmt.Errorf("smb activity: failed to create local folder for %q: %w", rel, err)
+		}
+		ref, err := smbDownloadFile(fs, path.Join(remoteFolder, rel), localPath, rel, ctx)
+		if err != nil {
+			return nil, fmt.Errorf("smb activity: failed to download %q: %w", rel, err)
+		}
+		refs = append(refs, ref)
+		downloaded = append(downloaded, rel)
 	}
+
 	return map[string]interface{}{
 		"files_downloaded": downloaded,
+		"files":            fileRefMaps(refs),
 		"count":            len(downloaded),
 	}, nil
 }
 
-// smbPut uploads files from config["files"] (or input["files"]) to the SMB share/folder.
-func smbPut(fs *smb2.Share, config map[string]interface{}, remoteFolder string) (map[string]interface{}, error) {
-	localFolder, _ := config["local_folder"].(string)
-	if localFolder == "" {
-		localFolder = "."
+// smbPut uploads files from config["files"] and the file refs in
+// input["files"] to the SMB share/folder. With recursive set, listed
+// directories are uploaded with their whole tree, and the entire local_folder
+// is uploaded when no files or refs are given.
+func smbPut(fs smbFS, input map[string]interface{}, cfg *smbConfig, ctx *fmodels.ExecutionContext) (map[string]interface{}, error) {
+	remoteFolder := cfg.Folder
+	localFolder, err := smbLocalFolder(cfg, ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	overwrite := true
-	if ow, ok := config["overwrite"].(bool); ok {
-		overwrite = ow
+	refs, err := fileRefsFromInput(input)
+	if err != nil {
+		return nil, fmt.Errorf("smb activity: %w", err)
 	}
-
 	var fileNames []string
-	if flist, ok := config["files"].([]interface{}); ok {
-		for _, f := range flist {
-			if s, ok := f.(string); ok {
-				fileNames = append(fileNames, s)
-			}
+	if len(cfg.Files) > 0 || len(refs) == 0 {
+		fileNames, err = smbLocalFiles(localFolder, cfg.Files, cfg.Recursive)
+		if err != nil {
+			return nil, err
 		}
 	}
 
-	var uploaded []string
-	for _, name := range fileNames {
-		remotePath := filepath.Join(remoteFolder, name)
-		localPath := filepath.Join(localFolder, name)
+	uploaded := []string{}
+	for _, rel := range fileNames {
+		remotePath := path.Join(remoteFolder, rel)
+		localPath := filepath.Join(localFolder, filepath.FromSlash(rel))
 
-		if !overwrite {
+		if !cfg.Overwrite {
 			if _, err := fs.Stat(remotePath); err == nil {
 				continue
 			}
 		}
+		if dir := path.Dir(rel); dir != "." {
+			if err := fs.MkdirAll(path.Join(remoteFolder, dir), 0o755); err != nil {
+				return nil, fmt.Errorf("smb activity: failed to create remote folder for %q: %w", rel, err)
+			}
+		}
 
-		if err := smbUploadFile(fs, localPath, remotePath); err != nil {
-			return nil, fmt.Errorf("smb activity: failed to upload %q: %w", name, err)
+		n, err := smbUploadFile(fs, localPath, remotePath)
+		if err != nil {
+			return nil, fmt.Errorf("smb activity: failed to upload %q: %w", rel, err)
 		}
-		uploaded = append(uploaded, name)
+		ctx.AddBytes(n)
+		uploaded = append(uploaded, rel)
 	}
-
-	if uploaded == nil {
-		uploaded = []string{}
+	for _, ref := range refs {
+		rel, err := smbRelPath(ref.Name)
+		if err != nil {
+			return nil, err
+		}
+		remotePath := path.Join(remoteFolder, rel)
+		if !cfg.Overwrite {
+			if _, err := fs.Stat(remotePath); err == nil {
+				continue
+			}
+		}
+		if dir := path.Dir(rel); dir != "." {
+			if err := fs.MkdirAll(path.Join(remoteFolder, dir), 0o755); err != nil {
+				return nil, fmt.Errorf("smb activity: failed to create remote folder for %q: %w", rel, err)
+			}
+		}
+		if err := smbUploadFileRef(fs, ctx, ref, remotePath); err != nil {
+			return nil, fmt.Errorf("smb activity: failed to upload %q: %w", rel, err)
+		}
+		uploaded = append(uploaded, rel)
 	}
+
 	return map[string]interface{}{
 		"files_uploaded": uploaded,
 		"count":          len(uploaded),
 	}, nil
 }
 
-// smbDownloadFile copies a single file from the SMB share to a local path.
-func smbDownloadFile(fs *smb2.Share, remotePath, localPath string) error {
-	remote, err := fs.Open(remotePath)
+// smbLocalFiles expands the put file list into slash-separated paths relative
+// to localFolder. Directories are expanded only when recursive is true.
+func smbLocalFiles(localFolder string, names []string, recursive bool) ([]string, error) {
+	if len(names) == 0 && recursive {
+		names = []string{"."}
+	}
+	var files []string
+	for _, name := range names {
+		if name != "." {
+			rel, err := smbRelPath(name)
+			if err != nil {
+				return nil, err
+			}
+			name = rel
+		}
+		root := filepath.Join(localFolder, filepath.FromSlash(name))
+		info, err := os.Stat(root)
+		if err != nil || !info.IsDir() {
+			// Missing files surface as upload errors, matching the flat behaviour.
+			files = append(files, name)
+			continue
+		}
+		if !recursive {
+			return nil, fmt.Errorf("smb activity: %q is a directory; set recursive to upload it", name)
+		}
+		err = filepath.WalkDir(root, func(p string, d iofs.DirEntry, walkErr error) error {
+			if walkErr != nil || d.IsDir() {
+				return walkErr
+			}
+			rel, err := filepath.Rel(localFolder, p)
+			if err != nil {
+				return err
+			}
+			files = append(files, filepath.ToSlash(rel))
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("smb activity: failed to walk local folder %q: %w", root, err)
+		}
+	}
+	return files, nil
+}
+
+// smbDelete removes the paths listed in config["files"] or, when none are
+// listed, every file in folder matching regex_filter. Directories are removed
+// with their contents only when recursive is true.
+func smbDelete(fs smbFS, cfg *smbConfig) (map[string]interface{}, error) {
+	remoteFolder, recursive := cfg.Folder, cfg.Recursive
+	targets := cfg.Files
+	if len(targets) == 0 {
+		if cfg.filter == nil {
+			return nil, fmt.Errorf("smb activity: method 'delete' requires 'files' or 'regex_filter'")
+		}
+		walked, err := smbWalk(fs, remoteFolder, "", recursive, cfg.filter)
+		if err != nil {
+			return nil, err
+		}
+		targets = walked
+	}
+
+	deleted := []string{}
+	for _, name := range targets {
+		rel, err := smbRelPath(name)
+		if err != nil {
+			return nil, err
+		}
+		remotePath := path.Join(remoteFolder, rel)
+		remove := fs.Remove
+		if recursive {
+			remove = fs.RemoveAll
+		}
+		if err := remove(remotePath); err != nil {
+			return nil, fmt.Errorf("smb activity: failed to delete %q: %w", rel, err)
+		}
+		deleted = append(deleted, rel)
+	}
+
+	return map[string]interface{}{
+		"files_deleted": deleted,
+		"count":         len(deleted),
+	}, nil
+}
+
+// smbMove renames source to destination inside folder, creating the parent
+// directories of destination. An existing destination is replaced only when
+// overwrite is true (the default).
+func smbMove(fs smbFS, cfg *smbConfig) (map[string]interface{}, error) {
+	remoteFolder := cfg.Folder
+	src, err := smbRelPath(cfg.Source)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	dst, err := smbRelPath(cfg.Destination)
+	if err != nil {
+		return nil, err
+	}
+
+	dstPath := path.Join(remoteFolder, dst)
+	if _, err := fs.Stat(dstPath); err == nil {
+		if !cfg.Overwrite {
+			return nil, fmt.Errorf("smb activity: destination %q already exists", dst)
+		}
+		if err := fs.Remove(dstPath); err != nil {
+			return nil, fmt.Errorf("smb activity: failed to replace %q: %w", dst, err)
+		}
+	}
+	if dir := path.Dir(dst); dir != "." {
+		if err := fs.MkdirAll(path.Join(remoteFolder, dir), 0o755); err != nil {
+			return nil, fmt.Errorf("smb activity: failed to create remote folder for %q: %w", dst, err)
+		}
+	}
+	if err := fs.Rename(path.Join(remoteFolder, src), dstPath); err != nil {
+		return nil, fmt.Errorf("smb activity: failed to move %q to %q: %w", src, dst, err)
+	}
+	return map[string]interface{}{
+		"moved_from": src,
+		"moved_to":   dst,
+	}, nil
+}
+
+// smbDownloadFile copies a single file from the SMB share to a local path and
+// returns its ref.
+func smbDownloadFile(fs smbFS, remotePath, localPath, name string, ctx *fmodels.ExecutionContext) (FileRef, error) {
+	remote, err := fs.OpenReader(remotePath)
+	if err != nil {
+		return FileRef{}, err
 	}
 	defer remote.Close()
+	return writeLocalFileRef(ctx, localPath, name, remote)
+}
 
-	local, err := os.Create(localPath)
+// smbDownloadFileRef reads a single file from the SMB share into memory.
+func smbDownloadFileRef(fs smbFS, remotePath, name string, ctx *fmodels.ExecutionContext) (FileRef, error) {
+	remote, err := fs.OpenReader(remotePath)
+	if err != nil {
+		return FileRef{}, err
+	}
+	defer remote.Close()
+	return storeFileRef(ctx, name, remote)
+}
+
+// smbUploadFileRef copies the file of a ref to the SMB share.
+func smbUploadFileRef(fs smbFS, ctx *fmodels.ExecutionContext, ref FileRef, remotePath string) error {
+	content, _, err := openFileRef(ctx, ref)
 	if err != nil {
 		return err
 	}
-	defer local.Close()
+	defer content.Close()
+	remote, err := fs.CreateWriter(remotePath)
+	if err != nil {
+		return err
+	}
+	defer remote.Close()
 
-	_, err = io.Copy(local, remote)
+	n, err := io.Copy(remote, content)
+	ctx.AddBytes(n)
 	return err
 }
 
-// smbUploadFile copies a local file to the SMB share.
-func smbUploadFile(fs *smb2.Share, localPath, remotePath string) error {
+// smbUploadFile copies a local file to the SMB share and returns the bytes
+// copied.
+func smbUploadFile(fs smbFS, localPath, remotePath string) (int64, error) {
 	local, err := os.Open(localPath)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer local.Close()
 
-	remote, err := fs.Create(remotePath)
+	remote, err := fs.CreateWriter(remotePath)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer remote.Close()
 
-	_, err = io.Copy(remote, local)
-	return err
+	return io.Copy(remote, local)
 }
 
 // extractSMBAuth reads user / password / domain from config.
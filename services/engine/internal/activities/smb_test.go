@@ -1,11 +1,16 @@
 package activities
 
 import (
+	"io"
 	"os"
+	"path/filepath"
+	"sort"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"flowjs-works/engine/internal/models"
 )
 
 // TestSMBActivity_Name verifies the activity type identifier.
@@ -56,7 +61,7 @@ func TestSMBActivity_InvalidMethod(t *testing.T) {
 	_, err := a.Execute(nil, map[string]interface{}{
 		"server": "fileserver",
 		"share":  "shared",
-		"method": "delete",
+		"method": "chmod",
 		"folder": "/files",
 	}, nil)
 	require.Error(t, err)
@@ -97,3 +102,209 @@ func TestSMBActivity_IntegrationGet(t *testing.T) {
 	require.NoError(t, err)
 	assert.NotNil(t, out["files_downloaded"])
 }
+
+// TestSMBActivity_MoveRequiresPaths ensures move is rejected before any network I/O.
+func TestSMBActivity_MoveRequiresPaths(t *testing.T) {
+	a := &SMBActivity{}
+	_, err := a.Execute(nil, map[string]interface{}{
+		"server": "fileserver",
+		"share":  "shared",
+		"method": "move",
+		"source": "a.txt",
+	}, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "destination")
+}
+
+// smbTestConfig decodes config as the config of an smb node running method
+// on folder.
+func smbTestConfig(t *testing.T, method, folder string, config map[string]interface{}) *smbConfig {
+	t.Helper()
+	config["server"], config["share"], config["method"], config["folder"] = "fileserver", "shared", method, folder
+	var cfg smbConfig
+	require.NoError(t, decodeConfig("smb", config, &cfg))
+	return &cfg
+}
+
+// localSMBFS implements smbFS on a local directory standing in for the share.
+type localSMBFS struct{ root string }
+
+func (l localSMBFS) abs(name string) string { return filepath.Join(l.root, filepath.FromSlash(name)) }
+
+func (l localSMBFS) ReadDir(name string) ([]os.FileInfo, error) {
+	entries, err := os.ReadDir(l.abs(name))
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]os.FileInfo, 0, len(entries))
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+func (l localSMBFS) Stat(name string) (os.FileInfo, error)            { return os.Stat(l.abs(name)) }
+func (l localSMBFS) OpenReader(name string) (io.ReadCloser, error)    { return os.Open(l.abs(name)) }
+func (l localSMBFS) CreateWriter(name string) (io.WriteCloser, error) { return os.Create(l.abs(name)) }
+func (l localSMBFS) MkdirAll(name string, perm os.FileMode) error {
+	return os.MkdirAll(l.abs(name), perm)
+}
+func (l localSMBFS) Remove(name string) error    { return os.Remove(l.abs(name)) }
+func (l localSMBFS) RemoveAll(name string) error { return os.RemoveAll(l.abs(name)) }
+func (l localSMBFS) Rename(oldname, newname string) error {
+	return os.Rename(l.abs(oldname), l.abs(newname))
+}
+
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0o755))
+		require.NoError(t, os.WriteFile(p, []byte(content), 0o644))
+	}
+}
+
+func sortedStrings(v interface{}) []string {
+	s := append([]string(nil), v.([]string)...)
+	sort.Strings(s)
+	return s
+}
+
+func TestSMBGet_Recursive(t *testing.T) {
+	share, local := t.TempDir(), t.TempDir()
+	writeTree(t, share, map[string]string{
+		"in/a.csv":          "a",
+		"in/2025/01/b.csv":  "b",
+		"in/2025/notes.txt": "skip",
+	})
+	fs := localSMBFS{root: share}
+
+	out, err := runSMBMethod(fs, nil, smbTestConfig(t, "get", "in", map[string]interface{}{
+		"local_folder": local, "recursive": true, "regex_filter": `\.csv$`,
+	}), nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2025/01/b.csv", "a.csv"}, sortedStrings(out["files_downloaded"]))
+
+	data, err := os.ReadFile(filepath.Join(local, "2025", "01", "b.csv"))
+	require.NoError(t, err)
+	assert.Equal(t, "b", string(data))
+	assert.NoFileExists(t, filepath.Join(local, "2025", "notes.txt"))
+
+	// Without recursive only the top level is fetched.
+	out, err = runSMBMethod(fs, nil, smbTestConfig(t, "get", "in", map[string]interface{}{"local_folder": t.TempDir()}), nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.csv"}, out["files_downloaded"])
+}
+
+func TestSMBPut_RecursiveWholeFolder(t *testing.T) {
+	share, local := t.TempDir(), t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(share, "out"), 0o755))
+	writeTree(t, local, map[string]string{"top.txt": "t", "nested/deep/x.txt": "x"})
+
+	out, err := runSMBMethod(localSMBFS{root: share}, nil, smbTestConfig(t, "put", "out", map[string]interface{}{
+		"local_folder": local, "recursive": true,
+	}), nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"nested/deep/x.txt", "top.txt"}, sortedStrings(out["files_uploaded"]))
+	data, err := os.ReadFile(filepath.Join(share, "out", "nested", "deep", "x.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "x", string(data))
+}
+
+func TestSMBPut_DirectoryNeedsRecursive(t *testing.T) {
+	local := t.TempDir()
+	writeTree(t, local, map[string]string{"dir/x.txt": "x"})
+	_, err := runSMBMethod(localSMBFS{root: t.TempDir()}, nil, smbTestConfig(t, "put", ".", map[string]interface{}{
+		"local_folder": local, "files": []interface{}{"dir"},
+	}), nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "recursive")
+}
+
+func TestSMBDelete(t *testing.T) {
+	share := t.TempDir()
+	writeTree(t, share, map[string]string{"a.tmp": "", "b.txt": "", "sub/c.tmp": "", "old/d.txt": ""})
+	fs := localSMBFS{root: share}
+
+	out, err := runSMBMethod(fs, nil, smbTestConfig(t, "delete", ".", map[string]interface{}{"regex_filter": `\.tmp$`, "recursive": true}), nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.tmp", "sub/c.tmp"}, sortedStrings(out["files_deleted"]))
+	assert.FileExists(t, filepath.Join(share, "b.txt"))
+
+	// A non-empty directory is only removed recursively.
+	_, err = runSMBMethod(fs, nil, smbTestConfig(t, "delete", ".", map[string]interface{}{"files": []interface{}{"old"}}), nil)
+	require.Error(t, err)
+	_, err = runSMBMethod(fs, nil, smbTestConfig(t, "delete", ".", map[string]interface{}{"files": []interface{}{"old"}, "recursive": true}), nil)
+	require.NoError(t, err)
+	assert.NoDirExists(t, filepath.Join(share, "old"))
+
+	_, err = runSMBMethod(fs, nil, smbTestConfig(t, "delete", ".", map[string]interface{}{"files": []interface{}{"../escape"}}), nil)
+	require.Error(t, err)
+	_, err = runSMBMethod(fs, nil, smbTestConfig(t, "delete", ".", map[string]interface{}{}), nil)
+	require.Error(t, err)
+}
+
+func TestSMBMove(t *testing.T) {
+	share := t.TempDir()
+	writeTree(t, share, map[string]string{"inbox/report.csv": "r", "archive/existing.csv": "e"})
+	fs := localSMBFS{root: share}
+
+	out, err := runSMBMethod(fs, nil, smbTestConfig(t, "move", ".", map[string]interface{}{
+		"source": "inbox/report.csv", "destination": "archive/2025/report.csv",
+	}), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "archive/2025/report.csv", out["moved_to"])
+	assert.FileExists(t, filepath.Join(share, "archive", "2025", "report.csv"))
+	assert.NoFileExists(t, filepath.Join(share, "inbox", "report.csv"))
+
+	writeTree(t, share, map[string]string{"inbox/existing.csv": "new"})
+	_, err = runSMBMethod(fs, nil, smbTestConfig(t, "move", ".", map[string]interface{}{
+		"source": "inbox/existing.csv", "destination": "archive/existing.csv", "overwrite": false,
+	}), nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "already exists")
+}
+
+func TestSMB_InMemoryPassThrough(t *testing.T) {
+	src, dst := t.TempDir(), t.TempDir()
+	writeTree(t, src, map[string]string{"in/a.csv": "a", "in/2025/b.csv": "b"})
+	ctx := models.NewExecutionContext("exec-smb-mem")
+	defer ReleaseFileRefs(ctx.ExecutionID)
+
+	out, err := runSMBMethod(localSMBFS{root: src}, nil, smbTestConfig(t, "get", "in", map[string]interface{}{
+		"in_memory": true, "recursive": true,
+	}), ctx)
+	require.NoError(t, err)
+	files := out["files"].([]interface{})
+	require.Len(t, files, 2)
+
+	out, err = runSMBMethod(localSMBFS{root: dst}, map[string]interface{}{"files": files}, smbTestConfig(t, "put", ".", map[string]interface{}{}), ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2025/b.csv", "a.csv"}, sortedStrings(out["files_uploaded"]))
+	data, err := os.ReadFile(filepath.Join(dst, "2025", "b.csv"))
+	require.NoError(t, err)
+	assert.Equal(t, "b", string(data))
+}
+
+// TestSMB_LocalFileRefs verifies that a get to local_folder outputs refs a
+// put node uploads without naming the files again.
+func TestSMB_LocalFileRefs(t *testing.T) {
+	src, local, dst := t.TempDir(), t.TempDir(), t.TempDir()
+	writeTree(t, src, map[string]string{"in/a.csv": "a"})
+	ctx := models.NewExecutionContext("exec-smb-local")
+	defer ReleaseFileRefs(ctx.ExecutionID)
+
+	out, err := runSMBMethod(localSMBFS{root: src}, nil, smbTestConfig(t, "get", "in", map[string]interface{}{"local_folder": local}), ctx)
+	require.NoError(t, err)
+	files := out["files"].([]interface{})
+	require.Len(t, files, 1)
+	assert.Equal(t, "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb", files[0].(map[string]interface{})["sha256"])
+
+	out, err = runSMBMethod(localSMBFS{root: dst}, map[string]interface{}{"files": files}, smbTestConfig(t, "put", ".", map[string]interface{}{}), ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.csv"}, out["files_uploaded"])
+	assert.FileExists(t, filepath.Join(dst, "a.csv"))
+}
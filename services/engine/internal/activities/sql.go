@@ -4,70 +4,128 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"net"
+	"strconv"
 	"time"
 
-	_ "github.com/go-sql-driver/mysql"
-	_ "github.com/lib/pq"
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
 
+	"flowjs-works/engine/internal/egress"
 	fmodels "flowjs-works/engine/internal/models"
 )
 
-// SQLActivity implements the `sql` node type.
-// config fields:
+// sqlConfig is the config of a sql node. The snowflake and bigquery engines
+// read their connection fields from the config map (see runSnowflakeQuery
+// and runBigQueryQuery).
+type sqlConfig struct {
+	Engine string `config:"engine,required,enum=postgres|mysql|snowflake|bigquery"`
+	Query  string `config:"query,required"`
+	// DSN, or a connection_string secret, takes precedence over the
+	// individual connection fields.
+	DSN              string      `config:"dsn" doc:"full DSN; host, port, database, user and password otherwise"`
+	ConnectionString string      `config:"connection_string" doc:"DSN injected by a connection_string secret"`
+	Host             string      `config:"host,default=localhost"`
+	Port             interface{} `config:"port" doc:"number or numeric string; 5432 for postgres and 3306 for mysql when empty"`
+	Database         string      `config:"database"`
+	User             string      `config:"user"`
+	Password         string      `config:"password"`
+	Params           interface{} `config:"params" doc:"positional parameters ($1 / ?) as an array, or named parameters (:name) as an object"`
+	TimeoutSec       int         `config:"timeout,default=30" doc:"query timeout in seconds"`
+	sqlStreamConfig
+	sqlWarehouseConfig
+
+	// port is Port as a string.
+	port string
+}
+
+func (c *sqlConfig) validate() []FieldError {
+	var problems []FieldError
+	switch p := c.Port.(type) {
+	case nil:
+	case string:
+		if _, err := strconv.ParseUint(p, 10, 16); p != "" && err != nil {
+			problems = append(problems, FieldError{"port", fmt.Sprintf("must be a port number, got %q", p)})
+		}
+		c.port = p
+	default:
+		if n, ok := configInt(p); ok && n > 0 && n <= 65535 {
+			c.port = strconv.FormatInt(n, 10)
+		} else {
+			problems = append(problems, FieldError{"port", "must be a port number, got " + configTypeName(p)})
+		}
+	}
+	switch c.Params.(type) {
+	case nil, []interface{}, map[string]interface{}:
+	default:
+		problems = append(problems, FieldError{"params", "must be an array or an object, got " + configTypeName(c.Params)})
+	}
+	if c.ChunkSize <= 0 {
+		problems = append(problems, FieldError{"chunk_size", "must be positive"})
+	}
+	if _, ok := warehouses[c.Engine]; ok && c.Stream != "" {
+		problems = append(problems, FieldError{"stream", "is not supported by the " + c.Engine + " engine"})
+	}
+	return problems
+}
+
+// SQLActivity implements the `sql` node type (config: sqlConfig).
+//
+// By default every row is returned in the output {rows, rows_affected}. With
+// stream "file" the rows are written to a new file of local_folder (default
+// the temp directory) as format "ndjson" (default) or "csv", and the output
+// is {file, rows_affected, columns} with file a file ref (see FileRef). With
+// stream "chunks" the rows are handed chunk_size (default 1000) at a time to
+// the chunk transitions of the node, whose chain runs once per chunk with the
+// node output {rows, chunk, offset}; the output is then {rows_affected,
+// chunks}. Streaming is supported by postgres and mysql only.
+//
+// The snowflake and bigquery engines call the warehouse HTTP APIs; their
+// output adds query_id and truncated (max_rows left rows unfetched).
 //
-//	engine:   "postgres" | "mysql" (required)
-//	dsn:      full DSN string OR individual host/port/database/user/password fields
-//	query:    SQL query string (required)
-//	params:   []interface{} query parameters
-//	timeout:  int seconds (default 30)
+// Named parameter values may also come from input["params"] (input_mapping),
+// which overrides config. :name(col1, col2) expands an array parameter into
+// a multi-row VALUES list. Objects and arrays are bound as JSON text and
+// RFC 3339 strings as timestamps; see bindNamedParams.
 type SQLActivity struct{}
 
 func (a *SQLActivity) Name() string { return "sql" }
 
-func (a *SQLActivity) Execute(input map[string]interface{}, config map[string]interface{}, ctx *fmodels.ExecutionContext) (map[string]interface{}, error) {
-	engine, ok := config["engine"].(string)
-	if !ok || engine == "" {
-		return nil, fmt.Errorf("sql activity: missing required config field 'engine'")
-	}
-	query, ok := config["query"].(string)
-	if !ok || query == "" {
-		return nil, fmt.Errorf("sql activity: missing required config field 'query'")
-	}
-
-	dsn := buildDSN(engine, config)
+// ConfigSpec returns the config struct of sql nodes.
+func (a *SQLActivity) ConfigSpec() interface{} { return &sqlConfig{} }
 
-	timeoutSec := 30
-	if tv, ok := config["timeout"]; ok {
-		switch v := tv.(type) {
-		case int:
-			timeoutSec = v
-		case float64:
-			timeoutSec = int(v)
-		}
+func (a *SQLActivity) Execute(input map[string]interface{}, config map[string]interface{}, ctx *fmodels.ExecutionContext) (map[string]interface{}, error) {
+	var cfg sqlConfig
+	if err := decodeConfig(a.Name(), config, &cfg); err != nil {
+		return nil, err
 	}
+	query := cfg.Query
 
 	var params []interface{}
-	if p, ok := config["params"].([]interface{}); ok {
-		params = p
+	if named, ok := sqlNamedParams(input, cfg.Params); ok {
+		var err error
+		if query, params, err = bindNamedParams(cfg.Engine, query, named); err != nil {
+			return nil, err
+		}
+	} else if p, ok := cfg.Params.([]interface{}); ok {
+		params = make([]interface{}, len(p))
+		for i, v := range p {
+			params[i] = coerceSQLParam(v)
+		}
 	}
 
-	var driverName string
-	switch engine {
-	case "postgres":
-		driverName = "postgres"
-	case "mysql":
-		driverName = "mysql"
-	default:
-		return nil, fmt.Errorf("sql activity: unsupported engine %q", engine)
+	// The query never outlives the process timeout budget.
+	deadline := ctx.Budget(time.Duration(cfg.TimeoutSec) * time.Second)
+	if run, ok := warehouses[cfg.Engine]; ok {
+		return runWarehouseQuery(run, cfg.Engine, query, params, &cfg.sqlWarehouseConfig, config, ctx, deadline)
 	}
 
-	db, err := sql.Open(driverName, dsn)
+	db, err := openSQLDB(cfg.Engine, buildDSN(&cfg))
 	if err != nil {
-		return nil, fmt.Errorf("sql activity: failed to open DB: %w", err)
+		return nil, err
 	}
 	defer db.Close()
 
-	deadline := time.Duration(timeoutSec) * time.Second
 	ctx2, cancel := context.WithTimeout(context.Background(), deadline)
 	defer cancel()
 
@@ -82,63 +140,76 @@ func (a *SQLActivity) Execute(input map[string]interface{}, config map[string]in
 		return nil, fmt.Errorf("sql activity: failed to get columns: %w", err)
 	}
 
-	var result []map[string]interface{}
-	for rows.Next() {
-		vals := make([]interface{}, len(cols))
-		ptrs := make([]interface{}, len(cols))
-		for i := range vals {
-			ptrs[i] = &vals[i]
-		}
-		if err := rows.Scan(ptrs...); err != nil {
-			return nil, fmt.Errorf("sql activity: failed to scan row: %w", err)
-		}
-		row := make(map[string]interface{}, len(cols))
-		for i, col := range cols {
-			row[col] = vals[i]
-		}
-		result = append(result, row)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("sql activity: rows error: %w", err)
-	}
-
-	if result == nil {
-		result = []map[string]interface{}{}
-	}
-
-	return map[string]interface{}{
-		"rows":          result,
-		"rows_affected": len(result),
-	}, nil
+	return readSQLRows(rows, cols, &cfg.sqlStreamConfig, ctx)
 }
 
-func buildDSN(engine string, config map[string]interface{}) string {
-	if dsn, ok := config["dsn"].(string); ok && dsn != "" {
-		return dsn
+// buildDSN returns the dsn or connection_string of cfg, or one built from
+// its connection fields.
+func buildDSN(cfg *sqlConfig) string {
+	if cfg.DSN != "" {
+		return cfg.DSN
 	}
 	// Also support secrets of type connection_string whose value field is named "connection_string".
-	if dsn, ok := config["connection_string"].(string); ok && dsn != "" {
-		return dsn
+	if cfg.ConnectionString != "" {
+		return cfg.ConnectionString
 	}
-	host, _ := config["host"].(string)
-	port, _ := config["port"].(string)
-	database, _ := config["database"].(string)
-	user, _ := config["user"].(string)
-	password, _ := config["password"].(string)
+	host, port := cfg.Host, cfg.port
 	if host == "" {
 		host = "localhost"
 	}
-	switch engine {
+	switch cfg.Engine {
 	case "postgres":
 		if port == "" {
 			port = "5432"
 		}
-		return fmt.Sprintf("host=%s port=%s dbname=%s user=%s password=%s sslmode=disable", host, port, database, user, password)
+		return fmt.Sprintf("host=%s port=%s dbname=%s user=%s password=%s sslmode=disable", host, port, cfg.Database, cfg.User, cfg.Password)
 	case "mysql":
 		if port == "" {
 			port = "3306"
 		}
-		return fmt.Sprintf("%s:%s@tcp(%s:%s)/%s", user, password, host, port, database)
+		return fmt.Sprintf("%s:%s@tcp(%s:%s)/%s", cfg.User, cfg.Password, host, port, cfg.Database)
 	}
 	return ""
 }
+
+// openSQLDB opens a database handle for engine whose connections go through
+// the egress policy.
+func openSQLDB(engine, dsn string) (*sql.DB, error) {
+	switch engine {
+	case "postgres":
+		connector, err := pq.NewConnector(dsn)
+		if err != nil {
+			return nil, fmt.Errorf("sql activity: failed to open DB: %w", err)
+		}
+		connector.Dialer(egressPQDialer{})
+		return sql.OpenDB(connector), nil
+	case "mysql":
+		cfg, err := mysql.ParseDSN(dsn)
+		if err != nil {
+			return nil, fmt.Errorf("sql activity: failed to open DB: %w", err)
+		}
+		cfg.DialFunc = egress.DialContext
+		connector, err := mysql.NewConnector(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("sql activity: failed to open DB: %w", err)
+		}
+		return sql.OpenDB(connector), nil
+	default:
+		return nil, fmt.Errorf("sql activity: unsupported engine %q", engine)
+	}
+}
+
+// egressPQDialer is the lib/pq dialer applying the egress policy.
+type egressPQDialer struct{}
+
+func (egressPQDialer) Dial(network, address string) (net.Conn, error) {
+	return egress.Dialer(0).Dial(network, address)
+}
+
+func (egressPQDialer) DialTimeout(network, address string, timeout time.Duration) (net.Conn, error) {
+	return egress.Dialer(timeout).Dial(network, address)
+}
+
+func (egressPQDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	return egress.DialContext(ctx, network, address)
+}
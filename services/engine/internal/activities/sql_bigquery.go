@@ -0,0 +1,347 @@
+package activities
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultBigQueryURL is the BigQuery API endpoint.
+const defaultBigQueryURL = "https://bigquery.googleapis.com"
+
+// bigQueryScope is the OAuth scope asked for with service account keys.
+const bigQueryScope = "https://www.googleapis.com/auth/bigquery"
+
+// bigQueryField is a column of a BigQuery result schema.
+type bigQueryField struct {
+	Name   string          `json:"name"`
+	Type   string          `json:"type"`
+	Mode   string          `json:"mode"`
+	Fields []bigQueryField `json:"fields"`
+}
+
+// bigQueryResponse is a page of results, or the status of a running job, of
+// the jobs.query and jobs.getQueryResults APIs.
+type bigQueryResponse struct {
+	JobReference struct {
+		JobID    string `json:"jobId"`
+		Location string `json:"location"`
+	} `json:"jobReference"`
+	JobComplete bool `json:"jobComplete"`
+	Schema      struct {
+		Fields []bigQueryField `json:"fields"`
+	} `json:"schema"`
+	Rows []struct {
+		F []struct {
+			V interface{} `json:"v"`
+		} `json:"f"`
+	} `json:"rows"`
+	PageToken          string `json:"pageToken"`
+	NumDMLAffectedRows string `json:"numDmlAffectedRows"`
+}
+
+// runBigQueryQuery runs q (standard SQL) through the BigQuery jobs API.
+//
+// config fields:
+//
+//	project:  project running the job (default: the service account's)
+//	location: dataset location, e.g. "EU"
+//	url:      API base URL (default https://bigquery.googleapis.com)
+//	service_account: service account key JSON (string or object)
+//	token:    OAuth access token, used instead of a service account
+//
+// Parameters are bound to ? placeholders. The job is polled until complete,
+// then its result pages are fetched until max_rows.
+func runBigQueryQuery(ctx context.Context, q warehouseQuery, config map[string]interface{}) (warehouseResult, error) {
+	token, project, err := bigQueryToken(ctx, config)
+	if err != nil {
+		return warehouseResult{}, err
+	}
+	if p, _ := config["project"].(string); p != "" {
+		project = p
+	}
+	if project == "" {
+		return warehouseResult{}, fmt.Errorf("missing required config field 'project'")
+	}
+	base, _ := config["url"].(string)
+	if base == "" {
+		base = defaultBigQueryURL
+	}
+	jobs := strings.TrimSuffix(base, "/") + "/bigquery/v2/projects/" + url.PathEscape(project) + "/queries"
+	location, _ := config["location"].(string)
+
+	// waitMs is how long each call may wait for the job server-side; an
+	// async query returns at once and is polled.
+	waitMs := 10000
+	if q.async {
+		waitMs = 1
+	}
+	send := func(method, target string, body interface{}, out *bigQueryResponse) error {
+		req, err := newWarehouseRequest(ctx, method, target, body)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		_, err = doWarehouseJSON(req, out)
+		return err
+	}
+
+	job := map[string]interface{}{
+		"query":        q.query,
+		"useLegacySql": false,
+		"timeoutMs":    waitMs,
+		"maxResults":   warehousePageSize,
+	}
+	if location != "" {
+		job["location"] = location
+	}
+	if len(q.params) > 0 {
+		params := make([]interface{}, len(q.params))
+		for i, p := range q.params {
+			params[i] = bigQueryParameter(p)
+		}
+		job["parameterMode"] = "POSITIONAL"
+		job["queryParameters"] = params
+	}
+	var res bigQueryResponse
+	if err := send(http.MethodPost, jobs, job, &res); err != nil {
+		return warehouseResult{}, err
+	}
+	out := warehouseResult{id: res.JobReference.JobID, affected: -1}
+	if res.JobReference.Location != "" {
+		location = res.JobReference.Location
+	}
+	results := func(pageToken string) string {
+		v := url.Values{"timeoutMs": {strconv.Itoa(waitMs)}, "maxResults": {strconv.Itoa(warehousePageSize)}}
+		if location != "" {
+			v.Set("location", location)
+		}
+		if pageToken != "" {
+			v.Set("pageToken", pageToken)
+		}
+		return jobs + "/" + url.PathEscape(out.id) + "?" + v.Encode()
+	}
+	for !res.JobComplete {
+		if err := waitPoll(ctx, q.pollInterval); err != nil {
+			return out, err
+		}
+		res = bigQueryResponse{}
+		if err := send(http.MethodGet, results(""), nil, &res); err != nil {
+			return out, err
+		}
+	}
+
+	if n, err := strconv.ParseInt(res.NumDMLAffectedRows, 10, 64); err == nil {
+		out.affected = n
+	}
+	fields := res.Schema.Fields
+	for {
+		page := make([]map[string]interface{}, len(res.Rows))
+		for i, r := range res.Rows {
+			cells := make([]interface{}, len(r.F))
+			for c, cell := range r.F {
+				cells[c] = cell.V
+			}
+			page[i] = bigQueryRecord(fields, cells)
+		}
+		var full, cut bool
+		out.rows, full, cut = appendRows(out.rows, page, q.maxRows)
+		last := res.PageToken == ""
+		if last || full {
+			out.truncated = cut || !last
+			return out, nil
+		}
+		token := res.PageToken
+		res = bigQueryResponse{}
+		if err := send(http.MethodGet, results(token), nil, &res); err != nil {
+			return out, fmt.Errorf("fetch result page: %w", err)
+		}
+	}
+}
+
+// bigQueryParameter returns the positional query parameter of a value.
+func bigQueryParameter(v interface{}) map[string]interface{} {
+	kind, text := warehouseParam(v)
+	types := map[string]string{"bool": "BOOL", "int": "INT64", "float": "FLOAT64", "timestamp": "TIMESTAMP"}
+	typ, ok := types[kind]
+	if !ok {
+		typ = "STRING"
+	}
+	param := map[string]interface{}{"parameterType": map[string]string{"type": typ}}
+	if kind == "null" {
+		param["parameterValue"] = map[string]interface{}{}
+	} else {
+		param["parameterValue"] = map[string]string{"value": text}
+	}
+	return param
+}
+
+// bigQueryRecord converts the cells of a result row, or of a RECORD value,
+// into an object by fields.
+func bigQueryRecord(fields []bigQueryField, cells []interface{}) map[string]interface{} {
+	row := make(map[string]interface{}, len(fields))
+	for i, f := range fields {
+		if i < len(cells) {
+			row[f.Name] = bigQueryValue(f, cells[i])
+		}
+	}
+	return row
+}
+
+// bigQueryValue converts a result cell, which the API sends as text or as
+// nested {f}/{v} objects, by its field type and mode.
+func bigQueryValue(f bigQueryField, v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	if f.Mode == "REPEATED" {
+		items, _ := v.([]interface{})
+		out := make([]interface{}, len(items))
+		single := f
+		single.Mode = ""
+		for i, item := range items {
+			if m, ok := item.(map[string]interface{}); ok {
+				out[i] = bigQueryValue(single, m["v"])
+			}
+		}
+		return out
+	}
+	if f.Type == "RECORD" || f.Type == "STRUCT" {
+		m, _ := v.(map[string]interface{})
+		list, _ := m["f"].([]interface{})
+		cells := make([]interface{}, len(list))
+		for i, c := range list {
+			if cm, ok := c.(map[string]interface{}); ok {
+				cells[i] = cm["v"]
+			}
+		}
+		return bigQueryRecord(f.Fields, cells)
+	}
+	s, ok := v.(string)
+	if !ok {
+		return v
+	}
+	switch f.Type {
+	case "INTEGER", "INT64":
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return n
+		}
+	case "FLOAT", "FLOAT64", "NUMERIC", "BIGNUMERIC":
+		if n, err := strconv.ParseFloat(s, 64); err == nil {
+			return n
+		}
+	case "BOOLEAN", "BOOL":
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b
+		}
+	case "TIMESTAMP":
+		// Seconds since the epoch, with a fraction.
+		if secs, err := strconv.ParseFloat(s, 64); err == nil {
+			return time.UnixMicro(int64(secs * 1e6)).UTC().Format(time.RFC3339Nano)
+		}
+	case "JSON":
+		var j interface{}
+		if json.Unmarshal([]byte(s), &j) == nil {
+			return j
+		}
+	}
+	return s
+}
+
+// serviceAccountKey holds the fields of a Google service account key used to
+// obtain access tokens.
+type serviceAccountKey struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+	ProjectID   string `json:"project_id"`
+}
+
+// cachedToken is an access token and when it stops being used.
+type cachedToken struct {
+	token   string
+	expires time.Time
+}
+
+// serviceAccountTokens caches the access tokens of service account keys by a
+// hash of the key, until shortly before they expire.
+var serviceAccountTokens sync.Map
+
+// bigQueryToken returns the access token authorising BigQuery calls, and the
+// project of the service account when one is configured.
+func bigQueryToken(ctx context.Context, config map[string]interface{}) (string, string, error) {
+	var raw string
+	switch sa := config["service_account"].(type) {
+	case string:
+		raw = sa
+	case map[string]interface{}:
+		b, _ := json.Marshal(sa)
+		raw = string(b)
+	}
+	if raw == "" {
+		if token := getCredential(config, "token"); token != "" {
+			return token, "", nil
+		}
+		return "", "", fmt.Errorf("bigquery needs a service_account key or an OAuth token")
+	}
+	var key serviceAccountKey
+	if err := json.Unmarshal([]byte(raw), &key); err != nil {
+		return "", "", fmt.Errorf("invalid service_account JSON: %w", err)
+	}
+	if key.ClientEmail == "" || key.PrivateKey == "" {
+		return "", "", fmt.Errorf("service_account needs client_email and private_key")
+	}
+	if key.TokenURI == "" {
+		key.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+
+	sum := sha256.Sum256([]byte(raw))
+	cacheKey := hex.EncodeToString(sum[:])
+	if v, ok := serviceAccountTokens.Load(cacheKey); ok && time.Now().Before(v.(cachedToken).expires) {
+		return v.(cachedToken).token, key.ProjectID, nil
+	}
+
+	rsaKey, err := parseRSAPrivateKey(key.PrivateKey)
+	if err != nil {
+		return "", "", fmt.Errorf("service_account: %w", err)
+	}
+	now := time.Now()
+	assertion, err := signJWT(rsaKey, map[string]interface{}{
+		"iss":   key.ClientEmail,
+		"scope": bigQueryScope,
+		"aud":   key.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("sign service account JWT: %w", err)
+	}
+	form := url.Values{"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"}, "assertion": {assertion}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, key.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	var tok struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if _, err := doWarehouseJSON(req, &tok); err != nil {
+		return "", "", fmt.Errorf("service account token: %w", err)
+	}
+	if tok.AccessToken == "" {
+		return "", "", fmt.Errorf("service account token: no access_token in response")
+	}
+	// Renew a minute early so a token never expires during a query.
+	expires := now.Add(time.Duration(tok.ExpiresIn)*time.Second - time.Minute)
+	serviceAccountTokens.Store(cacheKey, cachedToken{token: tok.AccessToken, expires: expires})
+	return tok.AccessToken, key.ProjectID, nil
+}
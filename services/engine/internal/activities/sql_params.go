@@ -0,0 +1,207 @@
+package activities
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sqlNamedParams returns the named parameter values of a sql node: the
+// config params map overlaid with the input["params"] map resolved by
+// input_mapping. ok is false when neither is a map, so the query keeps using
+// positional params.
+func sqlNamedParams(input map[string]interface{}, params interface{}) (map[string]interface{}, bool) {
+	cfgParams, cfgOK := params.(map[string]interface{})
+	inParams, inOK := input["params"].(map[string]interface{})
+	if !cfgOK && !inOK {
+		return nil, false
+	}
+	values := make(map[string]interface{}, len(cfgParams)+len(inParams))
+	for k, v := range cfgParams {
+		values[k] = v
+	}
+	for k, v := range inParams {
+		values[k] = v
+	}
+	return values, true
+}
+
+// bindNamedParams rewrites the :name placeholders of query into the
+// positional placeholders of engine ($1 for postgres, ? for the others) and
+// returns the matching arguments. A postgres parameter used twice is bound
+// once.
+//
+// :name(col1, col2) expands an array parameter into a multi-row value list,
+// "(…, …), (…, …)", taking col1 and col2 from every item (an object, or an
+// array in column order), for INSERT … VALUES :rows(id, total).
+//
+// Colons inside quoted strings, quoted identifiers and comments, and
+// postgres "::" casts, are left untouched.
+func bindNamedParams(engine, query string, values map[string]interface{}) (string, []interface{}, error) {
+	var (
+		out     strings.Builder
+		args    []interface{}
+		indexOf = make(map[string]int)
+	)
+	placeholder := func(v interface{}) string {
+		args = append(args, v)
+		if engine != "postgres" {
+			return "?"
+		}
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			end := closingQuote(query, i)
+			out.WriteString(query[i:end])
+			i = end - 1
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query) - i
+			}
+			out.WriteString(query[i : i+end])
+			i += end - 1
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				end = len(query) - i - 2
+			} else {
+				end += 2
+			}
+			out.WriteString(query[i : i+2+end])
+			i += 1 + end
+		case c == ':' && i+1 < len(query) && query[i+1] == ':':
+			out.WriteString("::")
+			i++
+		case c == ':' && i+1 < len(query) && isIdentStart(query[i+1]):
+			j := i + 1
+			for j < len(query) && isIdentPart(query[j]) {
+				j++
+			}
+			name := query[i+1 : j]
+			val, ok := values[name]
+			if !ok {
+				return "", nil, fmt.Errorf("sql activity: missing value for parameter :%s", name)
+			}
+			if j < len(query) && query[j] == '(' {
+				end := strings.IndexByte(query[j:], ')')
+				if end < 0 {
+					return "", nil, fmt.Errorf("sql activity: unclosed column list for :%s", name)
+				}
+				list, err := rowValues(name, val, splitColumns(query[j+1:j+end]), placeholder)
+				if err != nil {
+					return "", nil, err
+				}
+				out.WriteString(list)
+				i = j + end
+				continue
+			}
+			if n, seen := indexOf[name]; seen && engine == "postgres" {
+				out.WriteString("$" + strconv.Itoa(n))
+			} else {
+				out.WriteString(placeholder(coerceSQLParam(val)))
+				indexOf[name] = len(args)
+			}
+			i = j - 1
+		default:
+			out.WriteByte(c)
+		}
+	}
+	return out.String(), args, nil
+}
+
+// rowValues builds the "(…), (…)" value list of a :name(cols) expansion.
+func rowValues(name string, val interface{}, cols []string, placeholder func(interface{}) string) (string, error) {
+	items, ok := val.([]interface{})
+	if !ok || len(items) == 0 {
+		return "", fmt.Errorf("sql activity: parameter :%s must be a non-empty array", name)
+	}
+	if len(cols) == 0 {
+		return "", fmt.Errorf("sql activity: parameter :%s needs a column list", name)
+	}
+	rows := make([]string, len(items))
+	for r, item := range items {
+		cells := make([]string, len(cols))
+		for c, col := range cols {
+			var v interface{}
+			switch it := item.(type) {
+			case map[string]interface{}:
+				v = it[col]
+			case []interface{}:
+				if c < len(it) {
+					v = it[c]
+				}
+			default:
+				return "", fmt.Errorf("sql activity: parameter :%s item %d must be an object or array", name, r)
+			}
+			cells[c] = placeholder(coerceSQLParam(v))
+		}
+		rows[r] = "(" + strings.Join(cells, ", ") + ")"
+	}
+	return strings.Join(rows, ", "), nil
+}
+
+// coerceSQLParam converts JSON-decoded values the drivers cannot bind:
+// objects and arrays are encoded as JSON text (for json/jsonb columns), and
+// RFC 3339 strings become time.Time so timestamp columns receive the format
+// each driver expects.
+func coerceSQLParam(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}, []interface{}:
+		b, err := json.Marshal(val)
+		if err != nil {
+			return v
+		}
+		return string(b)
+	case string:
+		if len(val) >= len("2006-01-02T15:04:05Z") && val[4] == '-' && val[10] == 'T' {
+			if t, err := time.Parse(time.RFC3339Nano, val); err == nil {
+				return t
+			}
+		}
+		return val
+	default:
+		return v
+	}
+}
+
+// closingQuote returns the index just past the quoted section starting at
+// start, treating a doubled quote as an escaped one.
+func closingQuote(s string, start int) int {
+	q := s[start]
+	for i := start + 1; i < len(s); i++ {
+		if s[i] != q {
+			continue
+		}
+		if i+1 < len(s) && s[i+1] == q {
+			i++
+			continue
+		}
+		return i + 1
+	}
+	return len(s)
+}
+
+func splitColumns(list string) []string {
+	var cols []string
+	for _, c := range strings.Split(list, ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			cols = append(cols, c)
+		}
+	}
+	return cols
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,66 @@
+package activities
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBindNamedParams_Postgres(t *testing.T) {
+	query, args, err := bindNamedParams("postgres",
+		"SELECT id::text, ':skip' FROM users WHERE email = :email AND (:email <> '' OR org = :org) -- :ignored",
+		map[string]interface{}{"email": "a@b.c", "org": float64(7)})
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT id::text, ':skip' FROM users WHERE email = $1 AND ($1 <> '' OR org = $2) -- :ignored", query)
+	assert.Equal(t, []interface{}{"a@b.c", float64(7)}, args)
+}
+
+func TestBindNamedParams_MySQLRepeatsArgs(t *testing.T) {
+	query, args, err := bindNamedParams("mysql", "SELECT * FROM t WHERE a = :v OR b = :v", map[string]interface{}{"v": "x"})
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM t WHERE a = ? OR b = ?", query)
+	assert.Equal(t, []interface{}{"x", "x"}, args)
+}
+
+func TestBindNamedParams_MultiRowInsert(t *testing.T) {
+	rows := []interface{}{
+		map[string]interface{}{"id": "o1", "total": 10.5, "meta": map[string]interface{}{"vip": true}},
+		[]interface{}{"o2", float64(3), nil},
+	}
+	query, args, err := bindNamedParams("postgres",
+		"INSERT INTO orders (id, total, meta) VALUES :rows(id, total, meta) ON CONFLICT DO NOTHING",
+		map[string]interface{}{"rows": rows})
+	require.NoError(t, err)
+	assert.Equal(t, "INSERT INTO orders (id, total, meta) VALUES ($1, $2, $3), ($4, $5, $6) ON CONFLICT DO NOTHING", query)
+	assert.Equal(t, []interface{}{"o1", 10.5, `{"vip":true}`, "o2", float64(3), nil}, args)
+
+	_, _, err = bindNamedParams("postgres", "INSERT INTO t VALUES :rows(a)", map[string]interface{}{"rows": []interface{}{}})
+	assert.ErrorContains(t, err, "non-empty array")
+}
+
+func TestBindNamedParams_MissingValue(t *testing.T) {
+	_, _, err := bindNamedParams("postgres", "SELECT :missing", map[string]interface{}{})
+	assert.ErrorContains(t, err, "missing value for parameter :missing")
+}
+
+func TestCoerceSQLParam(t *testing.T) {
+	ts := coerceSQLParam("2024-03-01T10:00:00Z")
+	assert.Equal(t, time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC), ts)
+	assert.Equal(t, "2024-03-01", coerceSQLParam("2024-03-01"))
+	assert.Equal(t, "hello", coerceSQLParam("hello"))
+	assert.Equal(t, `[1,2]`, coerceSQLParam([]interface{}{float64(1), float64(2)}))
+	assert.Equal(t, true, coerceSQLParam(true))
+}
+
+func TestSQLNamedParams_InputOverridesConfig(t *testing.T) {
+	values, ok := sqlNamedParams(
+		map[string]interface{}{"params": map[string]interface{}{"a": "input"}},
+		map[string]interface{}{"a": "config", "b": "config"})
+	require.True(t, ok)
+	assert.Equal(t, map[string]interface{}{"a": "input", "b": "config"}, values)
+
+	_, ok = sqlNamedParams(map[string]interface{}{}, []interface{}{"x"})
+	assert.False(t, ok)
+}
@@ -0,0 +1,255 @@
+package activities
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// snowflakeResponse is a result set, or the status of a running statement,
+// of the Snowflake SQL API.
+type snowflakeResponse struct {
+	StatementHandle    string      `json:"statementHandle"`
+	StatementStatusURL string      `json:"statementStatusUrl"`
+	Data               [][]*string `json:"data"`
+	ResultSetMetaData  struct {
+		RowType []struct {
+			Name  string `json:"name"`
+			Type  string `json:"type"`
+			Scale int    `json:"scale"`
+		} `json:"rowType"`
+		PartitionInfo []struct {
+			RowCount int `json:"rowCount"`
+		} `json:"partitionInfo"`
+	} `json:"resultSetMetaData"`
+	Stats *struct {
+		Inserted int64 `json:"numRowsInserted"`
+		Updated  int64 `json:"numRowsUpdated"`
+		Deleted  int64 `json:"numRowsDeleted"`
+	} `json:"stats"`
+}
+
+// runSnowflakeQuery runs q through the Snowflake SQL API.
+//
+// config fields:
+//
+//	account:  account identifier, e.g. "myorg-myaccount" or "xy12345.eu-west-1"
+//	url:      API base URL (default https://<account>.snowflakecomputing.com)
+//	user, private_key: key-pair authentication (unencrypted PEM RSA key),
+//	          usually from an ssh_key secret
+//	token:    OAuth access token, used instead of a key pair
+//	warehouse, database, schema, role: session context of the statement
+//
+// Parameters are bound to ? placeholders. The result partitions are fetched
+// one by one until max_rows.
+func runSnowflakeQuery(ctx context.Context, q warehouseQuery, config map[string]interface{}) (warehouseResult, error) {
+	account, _ := config["account"].(string)
+	base, _ := config["url"].(string)
+	if base == "" {
+		if account == "" {
+			return warehouseResult{}, fmt.Errorf("missing required config field 'account'")
+		}
+		base = "https://" + account + ".snowflakecomputing.com"
+	}
+	base = strings.TrimSuffix(base, "/")
+	auth, err := snowflakeAuth(account, config)
+	if err != nil {
+		return warehouseResult{}, err
+	}
+	send := func(method, path string, body interface{}, out *snowflakeResponse) (int, error) {
+		req, err := newWarehouseRequest(ctx, method, base+path, body)
+		if err != nil {
+			return 0, err
+		}
+		auth(req)
+		return doWarehouseJSON(req, out)
+	}
+
+	stmt := map[string]interface{}{"statement": q.query}
+	if deadline, ok := ctx.Deadline(); ok {
+		stmt["timeout"] = int(time.Until(deadline).Seconds()) + 1
+	}
+	for _, key := range []string{"warehouse", "database", "schema", "role"} {
+		if v, _ := config[key].(string); v != "" {
+			stmt[key] = v
+		}
+	}
+	if len(q.params) > 0 {
+		bindings := make(map[string]interface{}, len(q.params))
+		for i, p := range q.params {
+			bindings[strconv.Itoa(i+1)] = snowflakeBinding(p)
+		}
+		stmt["bindings"] = bindings
+	}
+
+	var res snowflakeResponse
+	path := "/api/v2/statements?requestId=" + uuid.New().String() + "&async=" + strconv.FormatBool(q.async)
+	status, err := send(http.MethodPost, path, stmt, &res)
+	for err == nil && status == http.StatusAccepted {
+		// Still running: poll its status URL.
+		handle, statusURL := res.StatementHandle, res.StatementStatusURL
+		if err = waitPoll(ctx, q.pollInterval); err != nil {
+			snowflakeCancel(base, handle, auth)
+			return warehouseResult{id: handle}, err
+		}
+		if statusURL == "" {
+			statusURL = "/api/v2/statements/" + url.PathEscape(handle)
+		}
+		res = snowflakeResponse{}
+		status, err = send(http.MethodGet, statusURL, nil, &res)
+		if res.StatementHandle == "" {
+			res.StatementHandle = handle
+		}
+	}
+	if err != nil {
+		return warehouseResult{id: res.StatementHandle}, err
+	}
+
+	out := warehouseResult{id: res.StatementHandle, affected: -1}
+	if s := res.Stats; s != nil {
+		out.affected = s.Inserted + s.Updated + s.Deleted
+	}
+	meta := res.ResultSetMetaData
+	data := res.Data
+	for partition := 0; ; partition++ {
+		page := make([]map[string]interface{}, len(data))
+		for i, cells := range data {
+			row := make(map[string]interface{}, len(cells))
+			for c, cell := range cells {
+				if c < len(meta.RowType) {
+					col := meta.RowType[c]
+					row[col.Name] = snowflakeValue(col.Type, col.Scale, cell)
+				}
+			}
+			page[i] = row
+		}
+		var full, cut bool
+		out.rows, full, cut = appendRows(out.rows, page, q.maxRows)
+		last := partition+1 >= len(meta.PartitionInfo)
+		if last || full {
+			out.truncated = cut || !last
+			return out, nil
+		}
+		var next snowflakeResponse
+		if _, err := send(http.MethodGet, "/api/v2/statements/"+url.PathEscape(out.id)+"?partition="+strconv.Itoa(partition+1), nil, &next); err != nil {
+			return out, fmt.Errorf("fetch partition %d: %w", partition+1, err)
+		}
+		data = next.Data
+	}
+}
+
+// snowflakeAuth returns the function authorising a Snowflake API request:
+// key-pair JWT when the config has a private_key, else an OAuth token.
+func snowflakeAuth(account string, config map[string]interface{}) (func(*http.Request), error) {
+	if pemKey := getCredential(config, "private_key"); pemKey != "" {
+		user := getCredential(config, "user")
+		if account == "" || user == "" {
+			return nil, fmt.Errorf("key-pair authentication needs account and user")
+		}
+		key, err := parseRSAPrivateKey(pemKey)
+		if err != nil {
+			return nil, err
+		}
+		pub, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(pub)
+		// The JWT names the account without its region or cloud suffix.
+		qualified := strings.ToUpper(strings.SplitN(account, ".", 2)[0]) + "." + strings.ToUpper(user)
+		now := time.Now()
+		jwt, err := signJWT(key, map[string]interface{}{
+			"iss": qualified + ".SHA256:" + base64.StdEncoding.EncodeToString(sum[:]),
+			"sub": qualified,
+			"iat": now.Unix(),
+			"exp": now.Add(time.Hour).Unix(),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("sign key-pair JWT: %w", err)
+		}
+		return func(req *http.Request) {
+			req.Header.Set("Authorization", "Bearer "+jwt)
+			req.Header.Set("X-Snowflake-Authorization-Token-Type", "KEYPAIR_JWT")
+		}, nil
+	}
+	if token := getCredential(config, "token"); token != "" {
+		return func(req *http.Request) {
+			req.Header.Set("Authorization", "Bearer "+token)
+			req.Header.Set("X-Snowflake-Authorization-Token-Type", "OAUTH")
+		}, nil
+	}
+	return nil, fmt.Errorf("snowflake needs a private_key (key-pair) or an OAuth token")
+}
+
+// snowflakeCancel asks Snowflake to stop a statement the node gave up on.
+func snowflakeCancel(base, handle string, auth func(*http.Request)) {
+	if handle == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := newWarehouseRequest(ctx, http.MethodPost, base+"/api/v2/statements/"+url.PathEscape(handle)+"/cancel", nil)
+	if err != nil {
+		return
+	}
+	auth(req)
+	_, _ = doWarehouseJSON(req, nil)
+}
+
+// snowflakeBinding returns the SQL API binding of a parameter value.
+func snowflakeBinding(v interface{}) map[string]interface{} {
+	kind, text := warehouseParam(v)
+	types := map[string]string{"bool": "BOOLEAN", "int": "FIXED", "float": "REAL"}
+	typ, ok := types[kind]
+	if !ok {
+		typ = "TEXT"
+	}
+	if kind == "null" {
+		return map[string]interface{}{"type": typ, "value": nil}
+	}
+	return map[string]interface{}{"type": typ, "value": text}
+}
+
+// snowflakeValue converts a result cell, which the SQL API sends as text, by
+// its column type: numbers, booleans and semi-structured values are decoded.
+func snowflakeValue(typ string, scale int, cell *string) interface{} {
+	if cell == nil {
+		return nil
+	}
+	s := *cell
+	switch strings.ToLower(typ) {
+	case "fixed":
+		if scale == 0 {
+			if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+				return n
+			}
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f
+		}
+	case "real":
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b
+		}
+	case "variant", "object", "array":
+		var v interface{}
+		if json.Unmarshal([]byte(s), &v) == nil {
+			return v
+		}
+	}
+	return s
+}
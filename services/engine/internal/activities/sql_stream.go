@@ -0,0 +1,238 @@
+package activities
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	fmodels "flowjs-works/engine/internal/models"
+	"flowjs-works/engine/internal/scratch"
+)
+
+// Values of the sql node config field stream.
+const (
+	// sqlStreamFile writes the rows to a local file and outputs its ref.
+	sqlStreamFile = "file"
+	// sqlStreamChunks hands the rows, chunk_size at a time, to the chunk
+	// transitions of the node.
+	sqlStreamChunks = "chunks"
+)
+
+// sqlStreamConfig is the part of the sql node config that selects how the
+// rows of a postgres or mysql query are read.
+type sqlStreamConfig struct {
+	Stream      string `config:"stream,enum=file|chunks" doc:"read large results without holding them in memory; every row in the output when empty"`
+	Format      string `config:"format,default=ndjson,enum=ndjson|csv" doc:"file format of stream file"`
+	LocalFolder string `config:"local_folder" doc:"folder of the stream file (default the temp directory)"`
+	ChunkSize   int    `config:"chunk_size,default=1000" doc:"rows per chunk of stream chunks"`
+}
+
+// sqlRows is the part of *sql.Rows the row readers use.
+type sqlRows interface {
+	Next() bool
+	Scan(dest ...interface{}) error
+	Err() error
+}
+
+// scanSQLRow scans the current row of rows into a map keyed by column.
+func scanSQLRow(rows sqlRows, cols []string) (map[string]interface{}, error) {
+	vals := make([]interface{}, len(cols))
+	ptrs := make([]interface{}, len(cols))
+	for i := range vals {
+		ptrs[i] = &vals[i]
+	}
+	if err := rows.Scan(ptrs...); err != nil {
+		return nil, fmt.Errorf("sql activity: failed to scan row: %w", err)
+	}
+	row := make(map[string]interface{}, len(cols))
+	for i, col := range cols {
+		row[col] = vals[i]
+	}
+	return row, nil
+}
+
+// eachSQLRow calls fn with every row of rows and returns how many there were.
+func eachSQLRow(rows sqlRows, cols []string, fn func(row map[string]interface{}) error) (int, error) {
+	n := 0
+	for rows.Next() {
+		row, err := scanSQLRow(rows, cols)
+		if err != nil {
+			return n, err
+		}
+		if err := fn(row); err != nil {
+			return n, err
+		}
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		return n, fmt.Errorf("sql activity: rows error: %w", err)
+	}
+	return n, nil
+}
+
+// readSQLRows returns the node output for the rows of a query, according to
+// cfg.Stream: every row in "rows" by default, a file ref with "file",
+// or the row count once the chunks went through the chunk transitions with
+// "chunks".
+func readSQLRows(rows sqlRows, cols []string, cfg *sqlStreamConfig, ctx *fmodels.ExecutionContext) (map[string]interface{}, error) {
+	switch cfg.Stream {
+	case "":
+		result := []map[string]interface{}{}
+		n, err := eachSQLRow(rows, cols, func(row map[string]interface{}) error {
+			result = append(result, row)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		ctx.AddRows(int64(n))
+		return map[string]interface{}{"rows": result, "rows_affected": n}, nil
+	case sqlStreamFile:
+		return streamSQLRowsToFile(rows, cols, cfg, ctx)
+	case sqlStreamChunks:
+		return streamSQLRowChunks(rows, cols, cfg, ctx)
+	default:
+		return nil, fmt.Errorf("sql activity: unknown stream mode %q (want %q or %q)", cfg.Stream, sqlStreamFile, sqlStreamChunks)
+	}
+}
+
+// streamSQLRowsToFile writes rows to a new file of cfg.LocalFolder (default
+// the temp directory, or the execution's scratch directory under a scratch
+// policy) as cfg.Format, without holding them in memory. The output is
+// {file, rows_affected, columns}.
+func streamSQLRowsToFile(rows sqlRows, cols []string, cfg *sqlStreamConfig, ctx *fmodels.ExecutionContext) (map[string]interface{}, error) {
+	format := cfg.Format
+	if format != "ndjson" && format != "csv" {
+		return nil, fmt.Errorf("sql activity: unknown stream format %q (want ndjson or csv)", format)
+	}
+	folder := cfg.LocalFolder
+	if folder == "" && scratch.Current() == nil {
+		folder = os.TempDir()
+	}
+	folder, err := localFilePath(ctx, folder)
+	if err != nil {
+		return nil, fmt.Errorf("sql activity: %w", err)
+	}
+	f, err := os.CreateTemp(folder, "sql-rows-*."+format)
+	if err != nil {
+		return nil, fmt.Errorf("sql activity: create result file: %w", err)
+	}
+	h := sha256.New()
+	counter := &countingWriter{w: io.MultiWriter(limitLocalWrite(ctx, f.Name(), f), h)}
+	buf := bufio.NewWriter(counter)
+	n, err := writeSQLRows(buf, format, rows, cols)
+	if err == nil {
+		err = buf.Flush()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	ctx.AddBytes(counter.n)
+	if err != nil {
+		os.Remove(f.Name())
+		return nil, err
+	}
+	ctx.AddRows(int64(n))
+	ref, err := registerLocalFileRef(ctx, f.Name(), filepath.Base(f.Name()), counter.n, hex.EncodeToString(h.Sum(nil)))
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"file": ref.Map(), "rows_affected": n, "columns": cols}, nil
+}
+
+// writeSQLRows writes every row of rows to w in format, a CSV file starting
+// with a header of cols.
+func writeSQLRows(w io.Writer, format string, rows sqlRows, cols []string) (int, error) {
+	if format == "ndjson" {
+		enc := json.NewEncoder(w)
+		return eachSQLRow(rows, cols, func(row map[string]interface{}) error {
+			return enc.Encode(row)
+		})
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write(cols); err != nil {
+		return 0, err
+	}
+	record := make([]string, len(cols))
+	n, err := eachSQLRow(rows, cols, func(row map[string]interface{}) error {
+		for i, col := range cols {
+			record[i] = sqlCSVValue(row[col])
+		}
+		return cw.Write(record)
+	})
+	if err != nil {
+		return n, err
+	}
+	cw.Flush()
+	return n, cw.Error()
+}
+
+// sqlCSVValue formats a scanned column value as a CSV field; NULL is empty.
+func sqlCSVValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// streamSQLRowChunks hands rows, cfg.ChunkSize at a time, to the chunk
+// transitions of the node as its output {rows, chunk, offset}, holding one
+// chunk in memory at once. The output is {rows_affected, chunks}.
+func streamSQLRowChunks(rows sqlRows, cols []string, cfg *sqlStreamConfig, ctx *fmodels.ExecutionContext) (map[string]interface{}, error) {
+	if !ctx.StreamsChunks() {
+		return nil, fmt.Errorf("sql activity: stream %q needs chunk transitions leaving the node", sqlStreamChunks)
+	}
+	size := cfg.ChunkSize
+
+	chunks, offset := 0, 0
+	chunk := make([]map[string]interface{}, 0, size)
+	emit := func() error {
+		chunks++
+		ctx.AddRows(int64(len(chunk)))
+		if err := ctx.EmitChunk(map[string]interface{}{"rows": chunk, "chunk": chunks, "offset": offset}); err != nil {
+			return fmt.Errorf("sql activity: chunk %d: %w", chunks, err)
+		}
+		offset += len(chunk)
+		chunk = make([]map[string]interface{}, 0, size)
+		return nil
+	}
+	n, err := eachSQLRow(rows, cols, func(row map[string]interface{}) error {
+		chunk = append(chunk, row)
+		if len(chunk) < size {
+			return nil
+		}
+		return emit()
+	})
+	if err == nil && len(chunk) > 0 {
+		err = emit()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"rows_affected": n, "chunks": chunks}, nil
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
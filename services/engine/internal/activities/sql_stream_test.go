@@ -0,0 +1,111 @@
+package activities
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"flowjs-works/engine/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSQLRows serves fixed rows through the sqlRows interface.
+type fakeSQLRows struct {
+	data [][]interface{}
+	pos  int
+}
+
+func (r *fakeSQLRows) Next() bool {
+	r.pos++
+	return r.pos <= len(r.data)
+}
+
+func (r *fakeSQLRows) Scan(dest ...interface{}) error {
+	for i, v := range r.data[r.pos-1] {
+		*dest[i].(*interface{}) = v
+	}
+	return nil
+}
+
+func (r *fakeSQLRows) Err() error { return nil }
+
+func sqlTestRows(n int) *fakeSQLRows {
+	rows := &fakeSQLRows{}
+	for i := 1; i <= n; i++ {
+		rows.data = append(rows.data, []interface{}{int64(i), []byte("name, " + string(rune('a'+i-1)))})
+	}
+	return rows
+}
+
+// sqlStreamTestConfig decodes the stream fields of a sql node config.
+func sqlStreamTestConfig(t *testing.T, config map[string]interface{}) *sqlStreamConfig {
+	t.Helper()
+	var cfg sqlStreamConfig
+	require.NoError(t, decodeConfig("sql", config, &cfg))
+	return &cfg
+}
+
+func TestReadSQLRows_Default(t *testing.T) {
+	out, err := readSQLRows(sqlTestRows(2), []string{"id", "name"}, sqlStreamTestConfig(t, nil), nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, out["rows_affected"])
+	require.Len(t, out["rows"], 2)
+	assert.Equal(t, int64(2), out["rows"].([]map[string]interface{})[1]["id"])
+
+	_, err = readSQLRows(sqlTestRows(1), []string{"id", "name"}, &sqlStreamConfig{Stream: "memory"}, nil)
+	assert.ErrorContains(t, err, `unknown stream mode "memory"`)
+}
+
+// TestReadSQLRows_StreamFile verifies that stream "file" writes the rows to a
+// file ref the execution can read, as NDJSON or CSV.
+func TestReadSQLRows_StreamFile(t *testing.T) {
+	ctx := models.NewExecutionContext("exec-sql-stream")
+	t.Cleanup(func() { ReleaseFileRefs(ctx.ExecutionID) })
+	dir := t.TempDir()
+
+	read := func(format, want string) {
+		out, err := readSQLRows(sqlTestRows(2), []string{"id", "name"}, sqlStreamTestConfig(t, map[string]interface{}{"stream": "file", "format": format, "local_folder": dir}), ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 2, out["rows_affected"])
+		assert.Nil(t, out["rows"])
+		ref, err := fileRefFromMap(out["file"].(map[string]interface{}))
+		require.NoError(t, err)
+		r, size, err := openFileRef(ctx, ref)
+		require.NoError(t, err)
+		defer r.Close()
+		data, err := io.ReadAll(r)
+		require.NoError(t, err)
+		assert.Equal(t, want, string(data))
+		assert.Equal(t, int64(len(want)), size)
+	}
+	read("csv", "id,name\n1,\"name, a\"\n2,\"name, b\"\n")
+	read("", `{"id":1,"name":"bmFtZSwgYQ=="}`+"\n"+`{"id":2,"name":"bmFtZSwgYg=="}`+"\n")
+	assert.Equal(t, int64(4), ctx.Stats().RowsProcessed)
+}
+
+// TestReadSQLRows_StreamChunks verifies that stream "chunks" hands the rows
+// to the chunk handler chunk_size at a time and outputs only the counts.
+func TestReadSQLRows_StreamChunks(t *testing.T) {
+	config := sqlStreamTestConfig(t, map[string]interface{}{"stream": "chunks", "chunk_size": float64(2)})
+	_, err := readSQLRows(sqlTestRows(1), []string{"id", "name"}, config, models.NewExecutionContext("exec-1"))
+	assert.ErrorContains(t, err, "needs chunk transitions")
+
+	ctx := models.NewExecutionContext("exec-2")
+	var sizes, offsets []int
+	ctx.SetChunkHandler(func(output map[string]interface{}) error {
+		sizes = append(sizes, len(output["rows"].([]map[string]interface{})))
+		offsets = append(offsets, output["offset"].(int))
+		return nil
+	})
+	out, err := readSQLRows(sqlTestRows(5), []string{"id", "name"}, config, ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"rows_affected": 5, "chunks": 3}, out)
+	assert.Equal(t, []int{2, 2, 1}, sizes)
+	assert.Equal(t, []int{0, 2, 4}, offsets)
+
+	ctx.SetChunkHandler(func(map[string]interface{}) error { return errors.New("upsert failed") })
+	_, err = readSQLRows(sqlTestRows(5), []string{"id", "name"}, config, ctx)
+	assert.ErrorContains(t, err, "chunk 1: upsert failed")
+}
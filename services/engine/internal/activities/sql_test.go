@@ -32,8 +32,30 @@ func TestSQLActivity_UnsupportedEngine(t *testing.T) {
 		"engine": "oracle",
 		"query":  "SELECT 1",
 	}, nil)
-	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "unsupported engine")
+	assert.EqualError(t, err, `sql activity: config field 'engine' must be one of postgres, mysql, snowflake, bigquery, got "oracle"`)
+}
+
+func TestSQLActivity_ConfigErrors(t *testing.T) {
+	a := &SQLActivity{}
+	_, err := a.Execute(nil, map[string]interface{}{
+		"engine":     "postgres",
+		"query":      "SELECT 1",
+		"params":     "x",
+		"timeout":    "30s",
+		"stream":     "memory",
+		"chunk_size": float64(0),
+	}, nil)
+	assert.EqualError(t, err, "sql activity: config field 'timeout' must be an integer, got string; "+
+		"config field 'stream' must be one of file, chunks, got \"memory\"")
+
+	_, err = a.Execute(nil, map[string]interface{}{"engine": "postgres", "query": "SELECT 1", "params": "x", "chunk_size": float64(0)}, nil)
+	assert.EqualError(t, err, "sql activity: config field 'params' must be an array or an object, got string; config field 'chunk_size' must be positive")
+
+	_, err = a.Execute(nil, map[string]interface{}{"engine": "mysql", "query": "SELECT 1", "port": "db"}, nil)
+	assert.EqualError(t, err, `sql activity: config field 'port' must be a port number, got "db"`)
+
+	_, err = a.Execute(nil, map[string]interface{}{"engine": "bigquery", "query": "SELECT 1", "stream": "file"}, nil)
+	assert.EqualError(t, err, "sql activity: config field 'stream' is not supported by the bigquery engine")
 }
 
 // TestBuildDSN_ConnectionStringField verifies that a secret of type connection_string
@@ -42,7 +64,7 @@ func TestBuildDSN_ConnectionStringField(t *testing.T) {
 	config := map[string]interface{}{
 		"connection_string": "host=db port=5432 dbname=mydb user=sa password=pass sslmode=disable",
 	}
-	dsn := buildDSN("postgres", config)
+	dsn := buildDSN(sqlTestConfig(t, config))
 	assert.Equal(t, "host=db port=5432 dbname=mydb user=sa password=pass sslmode=disable", dsn)
 }
 
@@ -52,10 +74,32 @@ func TestBuildDSN_DSNFieldTakesPriority(t *testing.T) {
 		"dsn":               "host=explicit",
 		"connection_string": "host=fallback",
 	}
-	dsn := buildDSN("postgres", config)
+	dsn := buildDSN(sqlTestConfig(t, config))
 	assert.Equal(t, "host=explicit", dsn)
 }
 
+func TestBuildDSN_Fields(t *testing.T) {
+	config := map[string]interface{}{"database": "orders", "user": "etl", "password": "pw"}
+	assert.Equal(t, "host=localhost port=5432 dbname=orders user=etl password=pw sslmode=disable", buildDSN(sqlTestConfig(t, config)))
+	config["engine"], config["host"], config["port"] = "mysql", "db", "3307"
+	assert.Equal(t, "etl:pw@tcp(db:3307)/orders", buildDSN(sqlTestConfig(t, config)))
+	config["port"] = float64(3308)
+	assert.Equal(t, "etl:pw@tcp(db:3308)/orders", buildDSN(sqlTestConfig(t, config)), "the designer sends numeric ports")
+}
+
+// sqlTestConfig decodes config as a postgres node config unless it names
+// another engine.
+func sqlTestConfig(t *testing.T, config map[string]interface{}) *sqlConfig {
+	t.Helper()
+	full := map[string]interface{}{"engine": "postgres", "query": "SELECT 1"}
+	for k, v := range config {
+		full[k] = v
+	}
+	var cfg sqlConfig
+	require.NoError(t, decodeConfig("sql", full, &cfg))
+	return &cfg
+}
+
 func TestSQLActivity_PostgresIntegration(t *testing.T) {
 	if os.Getenv("FLOWJS_RUN_EXTERNAL_TESTS") != "1" {
 		t.Skip("skipping external test; set FLOWJS_RUN_EXTERNAL_TESTS=1 to enable")
@@ -0,0 +1,266 @@
+package activities
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	fmodels "flowjs-works/engine/internal/models"
+)
+
+// defaultWarehousePollInterval is how often a running warehouse query is
+// polled when the node sets no poll_interval_ms.
+const defaultWarehousePollInterval = time.Second
+
+// warehousePageSize is the number of rows asked for per result page.
+const warehousePageSize = 10000
+
+// sqlWarehouseConfig is the part of the sql node config read by the
+// snowflake and bigquery engines.
+type sqlWarehouseConfig struct {
+	Async          bool `config:"async" doc:"submit the query and poll it instead of waiting on it"`
+	PollIntervalMS int  `config:"poll_interval_ms" doc:"poll interval of a running query (default 1000)"`
+	MaxRows        int  `config:"max_rows" doc:"stop fetching result pages after this many rows"`
+}
+
+// warehouseClient sends the HTTP calls of the snowflake and bigquery engines;
+// their deadline comes from the request context.
+var warehouseClient = &http.Client{Transport: defaultHTTPTransport()}
+
+// warehouseQuery is a sql node query run by a data warehouse engine.
+type warehouseQuery struct {
+	query  string
+	params []interface{}
+	// async submits the query without waiting for it and polls it every
+	// pollInterval.
+	async        bool
+	pollInterval time.Duration
+	// maxRows caps the rows fetched; 0 fetches every page.
+	maxRows int
+}
+
+// warehouseResult is the outcome of a warehouseQuery.
+type warehouseResult struct {
+	// id is the statement handle or job id of the query.
+	id   string
+	rows []map[string]interface{}
+	// affected is the row count reported for DML, or -1.
+	affected  int64
+	truncated bool
+}
+
+// warehouseRunner runs a query on one warehouse engine.
+type warehouseRunner func(ctx context.Context, q warehouseQuery, config map[string]interface{}) (warehouseResult, error)
+
+// warehouses holds the sql engines that run queries over a warehouse HTTP
+// API instead of a database/sql driver.
+var warehouses = map[string]warehouseRunner{
+	"snowflake": runSnowflakeQuery,
+	"bigquery":  runBigQueryQuery,
+}
+
+// runWarehouseQuery runs query with params on a warehouse engine within
+// deadline and returns the sql node output. config holds the connection
+// fields of the engine.
+func runWarehouseQuery(run warehouseRunner, engine, query string, params []interface{}, cfg *sqlWarehouseConfig, config map[string]interface{}, ctx *fmodels.ExecutionContext, deadline time.Duration) (map[string]interface{}, error) {
+	q := warehouseQuery{query: query, params: params, async: cfg.Async, pollInterval: defaultWarehousePollInterval}
+	if cfg.PollIntervalMS > 0 {
+		q.pollInterval = time.Duration(cfg.PollIntervalMS) * time.Millisecond
+	}
+	if cfg.MaxRows > 0 {
+		q.maxRows = cfg.MaxRows
+	}
+
+	reqCtx, cancel := context.WithTimeout(context.Background(), deadline)
+	defer cancel()
+	res, err := run(reqCtx, q, config)
+	if err != nil {
+		return nil, fmt.Errorf("sql activity: %s query failed: %w", engine, err)
+	}
+	if res.rows == nil {
+		res.rows = []map[string]interface{}{}
+	}
+	ctx.AddRows(int64(len(res.rows)))
+	affected := int64(len(res.rows))
+	if res.affected >= 0 {
+		affected = res.affected
+	}
+	return map[string]interface{}{
+		"rows":          res.rows,
+		"rows_affected": affected,
+		"query_id":      res.id,
+		"truncated":     res.truncated,
+	}, nil
+}
+
+// appendRows adds page to rows up to maxRows (0: no cap). full reports that
+// rows reached maxRows, cut that rows of page were left out.
+func appendRows(rows, page []map[string]interface{}, maxRows int) (out []map[string]interface{}, full, cut bool) {
+	if maxRows > 0 && len(rows)+len(page) >= maxRows {
+		n := maxRows - len(rows)
+		return append(rows, page[:n]...), true, n < len(page)
+	}
+	return append(rows, page...), false, false
+}
+
+// warehouseParam returns the kind of a bound parameter value ("null",
+// "bool", "int", "float", "timestamp" or "string") and its text.
+func warehouseParam(v interface{}) (string, string) {
+	switch val := v.(type) {
+	case nil:
+		return "null", ""
+	case bool:
+		return "bool", strconv.FormatBool(val)
+	case int:
+		return "int", strconv.Itoa(val)
+	case int64:
+		return "int", strconv.FormatInt(val, 10)
+	case float64:
+		if val == float64(int64(val)) {
+			return "int", strconv.FormatInt(int64(val), 10)
+		}
+		return "float", strconv.FormatFloat(val, 'g', -1, 64)
+	case time.Time:
+		return "timestamp", val.UTC().Format(time.RFC3339Nano)
+	case string:
+		return "string", val
+	default:
+		return "string", fmt.Sprint(val)
+	}
+}
+
+// doWarehouseJSON sends req and decodes its JSON response into out. A 4xx or
+// 5xx response is an error carrying the message of its body.
+func doWarehouseJSON(req *http.Request, out interface{}) (int, error) {
+	req.Header.Set("Accept", "application/json")
+	resp, err := warehouseClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, err
+	}
+	if resp.StatusCode >= 400 {
+		return resp.StatusCode, fmt.Errorf("status %d: %s", resp.StatusCode, warehouseErrorMessage(body))
+	}
+	if out != nil && len(body) > 0 {
+		if err := json.Unmarshal(body, out); err != nil {
+			return resp.StatusCode, fmt.Errorf("decode response: %w", err)
+		}
+	}
+	return resp.StatusCode, nil
+}
+
+// warehouseErrorMessage extracts the message of an error response: Snowflake
+// answers {message}, Google APIs {error: {message}} or {error_description}.
+func warehouseErrorMessage(body []byte) string {
+	var e struct {
+		Message string          `json:"message"`
+		Error   json.RawMessage `json:"error"`
+		Desc    string          `json:"error_description"`
+	}
+	if json.Unmarshal(body, &e) == nil {
+		var nested struct {
+			Message string `json:"message"`
+		}
+		switch {
+		case e.Message != "":
+			return e.Message
+		case json.Unmarshal(e.Error, &nested) == nil && nested.Message != "":
+			return nested.Message
+		case e.Desc != "":
+			return e.Desc
+		}
+	}
+	msg := strings.TrimSpace(string(body))
+	if len(msg) > 512 {
+		msg = msg[:512]
+	}
+	return msg
+}
+
+// newWarehouseRequest builds a request with a JSON body, or none when body
+// is nil.
+func newWarehouseRequest(ctx context.Context, method, url string, body interface{}) (*http.Request, error) {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		r = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, r)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req, nil
+}
+
+// waitPoll waits interval before the next poll of a running query.
+func waitPoll(ctx context.Context, interval time.Duration) error {
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("query still running at the node timeout: %w", ctx.Err())
+	case <-timer.C:
+		return nil
+	}
+}
+
+// parseRSAPrivateKey parses an unencrypted PEM RSA private key, PKCS #8 or
+// PKCS #1.
+func parseRSAPrivateKey(pemKey string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(strings.ReplaceAll(pemKey, `\n`, "\n")))
+	if block == nil {
+		return nil, errors.New("private_key is not a PEM key")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse private_key (an unencrypted PKCS #8 or PKCS #1 RSA key): %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private_key is not an RSA key")
+	}
+	return key, nil
+}
+
+// signJWT returns claims as a JWT signed with key (RS256).
+func signJWT(key *rsa.PrivateKey, claims map[string]interface{}) (string, error) {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signed := header + "." + base64.RawURLEncoding.EncodeToString(payload)
+	sum := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", err
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
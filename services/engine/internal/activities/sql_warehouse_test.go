@@ -0,0 +1,161 @@
+package activities
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testRSAKeyPEM(t *testing.T) string {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+}
+
+// TestSQLActivity_Snowflake verifies key-pair authentication, bindings, the
+// polling of an async statement and the fetching of its partitions.
+func TestSQLActivity_Snowflake(t *testing.T) {
+	var polls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "KEYPAIR_JWT", r.Header.Get("X-Snowflake-Authorization-Token-Type"))
+		assert.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ey"))
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v2/statements":
+			assert.Equal(t, "true", r.URL.Query().Get("async"))
+			var stmt map[string]interface{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&stmt))
+			assert.Equal(t, "SELECT id, name, active FROM users WHERE region = ?", stmt["statement"])
+			assert.Equal(t, "ANALYTICS", stmt["warehouse"])
+			assert.Equal(t, map[string]interface{}{"1": map[string]interface{}{"type": "TEXT", "value": "eu"}}, stmt["bindings"])
+			w.WriteHeader(http.StatusAccepted)
+			_, _ = w.Write([]byte(`{"statementHandle":"h1","statementStatusUrl":"/api/v2/statements/h1"}`))
+		case r.URL.Query().Get("partition") == "1":
+			_, _ = w.Write([]byte(`{"data":[["3","carol",null]]}`))
+		case r.URL.Path == "/api/v2/statements/h1":
+			if polls.Add(1) == 1 {
+				w.WriteHeader(http.StatusAccepted)
+				_, _ = w.Write([]byte(`{"statementHandle":"h1"}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"statementHandle":"h1",
+				"resultSetMetaData":{"rowType":[{"name":"ID","type":"fixed","scale":0},{"name":"NAME","type":"text"},{"name":"ACTIVE","type":"boolean"}],
+					"partitionInfo":[{"rowCount":2},{"rowCount":1}]},
+				"data":[["1","alice","true"],["2","bob","false"]]}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer srv.Close()
+
+	config := map[string]interface{}{
+		"engine": "snowflake", "account": "xy12345.eu-west-1", "url": srv.URL, "warehouse": "ANALYTICS",
+		"user": "etl", "private_key": testRSAKeyPEM(t),
+		"query": "SELECT id, name, active FROM users WHERE region = :region", "params": map[string]interface{}{"region": "eu"},
+		"async": true, "poll_interval_ms": float64(1),
+	}
+	out, err := (&SQLActivity{}).Execute(nil, config, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "h1", out["query_id"])
+	assert.Equal(t, []map[string]interface{}{
+		{"ID": int64(1), "NAME": "alice", "ACTIVE": true},
+		{"ID": int64(2), "NAME": "bob", "ACTIVE": false},
+		{"ID": int64(3), "NAME": "carol", "ACTIVE": nil},
+	}, out["rows"])
+	assert.Equal(t, false, out["truncated"])
+
+	polls.Store(0)
+	config["max_rows"] = float64(2)
+	out, err = (&SQLActivity{}).Execute(nil, config, nil)
+	require.NoError(t, err)
+	assert.Len(t, out["rows"], 2)
+	assert.Equal(t, true, out["truncated"], "the second partition is not fetched")
+}
+
+// TestSQLActivity_BigQuery verifies service account authentication,
+// positional parameters, polling an incomplete job, result pages and value
+// conversion.
+func TestSQLActivity_BigQuery(t *testing.T) {
+	serviceTokens := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			serviceTokens++
+			require.NoError(t, r.ParseForm())
+			assert.Equal(t, "urn:ietf:params:oauth:grant-type:jwt-bearer", r.PostForm.Get("grant_type"))
+			_, _ = w.Write([]byte(`{"access_token":"tok","expires_in":3600}`))
+			return
+		}
+		assert.Equal(t, "Bearer tok", r.Header.Get("Authorization"))
+		schema := `"schema":{"fields":[{"name":"id","type":"INTEGER"},{"name":"at","type":"TIMESTAMP"},
+			{"name":"tags","type":"STRING","mode":"REPEATED"},{"name":"geo","type":"RECORD","fields":[{"name":"lat","type":"FLOAT"}]}]}`
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/bigquery/v2/projects/my-proj/queries":
+			var job map[string]interface{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&job))
+			assert.Equal(t, "SELECT * FROM t WHERE id > ?", job["query"])
+			assert.Equal(t, "POSITIONAL", job["parameterMode"])
+			assert.Equal(t, []interface{}{map[string]interface{}{
+				"parameterType": map[string]interface{}{"type": "INT64"}, "parameterValue": map[string]interface{}{"value": "5"},
+			}}, job["queryParameters"])
+			_, _ = w.Write([]byte(`{"jobReference":{"jobId":"job_1","location":"EU"},"jobComplete":false}`))
+		case r.URL.Query().Get("pageToken") == "p2":
+			_, _ = w.Write([]byte(`{"jobReference":{"jobId":"job_1"},"jobComplete":true,` + schema + `,
+				"rows":[{"f":[{"v":"7"},{"v":null},{"v":[]},{"v":null}]}]}`))
+		case r.URL.Path == "/bigquery/v2/projects/my-proj/queries/job_1":
+			assert.Equal(t, "EU", r.URL.Query().Get("location"))
+			_, _ = w.Write([]byte(`{"jobReference":{"jobId":"job_1"},"jobComplete":true,` + schema + `,"pageToken":"p2",
+				"rows":[{"f":[{"v":"6"},{"v":"1.7e9"},{"v":[{"v":"a"},{"v":"b"}]},{"v":{"f":[{"v":"40.5"}]}}]}]}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer srv.Close()
+
+	sa, _ := json.Marshal(map[string]string{
+		"client_email": "etl@my-proj.iam.gserviceaccount.com", "private_key": testRSAKeyPEM(t),
+		"token_uri": srv.URL + "/token", "project_id": "my-proj",
+	})
+	config := map[string]interface{}{
+		"engine": "bigquery", "url": srv.URL, "service_account": string(sa),
+		"query": "SELECT * FROM t WHERE id > ?", "params": []interface{}{float64(5)}, "poll_interval_ms": float64(1),
+	}
+	out, err := (&SQLActivity{}).Execute(nil, config, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "job_1", out["query_id"])
+	assert.Equal(t, []map[string]interface{}{
+		{"id": int64(6), "at": "2023-11-14T22:13:20Z", "tags": []interface{}{"a", "b"}, "geo": map[string]interface{}{"lat": 40.5}},
+		{"id": int64(7), "at": nil, "tags": []interface{}{}, "geo": nil},
+	}, out["rows"])
+
+	_, err = (&SQLActivity{}).Execute(nil, config, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, serviceTokens, "the access token is reused")
+}
+
+func TestSQLActivity_WarehouseErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_, _ = w.Write([]byte(`{"code":"002003","message":"Table 'T' does not exist"}`))
+	}))
+	defer srv.Close()
+
+	a := &SQLActivity{}
+	_, err := a.Execute(nil, map[string]interface{}{"engine": "snowflake", "url": srv.URL, "token": "t", "query": "SELECT * FROM t"}, nil)
+	assert.ErrorContains(t, err, "sql activity: snowflake query failed: status 422: Table 'T' does not exist")
+	_, err = a.Execute(nil, map[string]interface{}{"engine": "snowflake", "account": "acme", "query": "SELECT 1"}, nil)
+	assert.ErrorContains(t, err, "private_key (key-pair) or an OAuth token")
+	_, err = a.Execute(nil, map[string]interface{}{"engine": "bigquery", "token": "t", "query": "SELECT 1"}, nil)
+	assert.ErrorContains(t, err, "'project'")
+}
@@ -12,24 +12,30 @@ import (
 	"flowjs-works/engine/internal/models"
 )
 
-// TransformActivity implements the `transform` node type.
-// config fields:
-//
-//	transform_type: "json2csv" | "xml2json" | "json2xml"
-//	data:           the input data (map, slice, or string)
-//	spec:           optional spec/hints (string, may be empty)
+// transformConfig is the config of a transform node.
+type transformConfig struct {
+	TransformType string      `config:"transform_type,required,enum=json2csv|xml2json|json2xml"`
+	Data          interface{} `config:"data" doc:"the data to convert (object, array or string)"`
+	Spec          string      `config:"spec" doc:"optional spec or hints"`
+}
+
+// TransformActivity implements the `transform` node type (config:
+// transformConfig).
 type TransformActivity struct{}
 
 func (a *TransformActivity) Name() string { return "transform" }
 
+// ConfigSpec returns the config struct of transform nodes.
+func (a *TransformActivity) ConfigSpec() interface{} { return &transformConfig{} }
+
 func (a *TransformActivity) Execute(input map[string]interface{}, config map[string]interface{}, ctx *models.ExecutionContext) (map[string]interface{}, error) {
-	transformType, ok := config["transform_type"].(string)
-	if !ok || transformType == "" {
-		return nil, fmt.Errorf("transform activity: missing required config field 'transform_type'")
+	var cfg transformConfig
+	if err := decodeConfig(a.Name(), config, &cfg); err != nil {
+		return nil, err
 	}
-	data := config["data"]
+	data := cfg.Data
 
-	switch transformType {
+	switch cfg.TransformType {
 	case "json2csv":
 		return transformJSON2CSV(data)
 	case "xml2json":
@@ -37,7 +43,7 @@ func (a *TransformActivity) Execute(input map[string]interface{}, config map[str
 	case "json2xml":
 		return transformJSON2XML(data)
 	default:
-		return nil, fmt.Errorf("transform activity: unknown transform_type %q", transformType)
+		return nil, fmt.Errorf("transform activity: unknown transform_type %q", cfg.TransformType)
 	}
 }
 
@@ -110,7 +110,7 @@ func TestTransformActivity_UnknownType(t *testing.T) {
 		"data":           "x",
 	}, nil)
 	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "unknown transform_type")
+	assert.Contains(t, err.Error(), "config field 'transform_type' must be one of json2csv, xml2json, json2xml")
 }
 
 func TestTransformActivity_MissingType(t *testing.T) {
@@ -0,0 +1,340 @@
+package activities
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	neturl "net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"flowjs-works/engine/internal/egress"
+	"flowjs-works/engine/internal/models"
+)
+
+const (
+	defaultWSDialTimeout  = 10 * time.Second
+	defaultWSReplyTimeout = 10 * time.Second
+)
+
+// wsDialer opens WebSocket connections through the egress policy.
+var wsDialer = &websocket.Dialer{
+	Proxy:            http.ProxyFromEnvironment,
+	NetDialContext:   egress.DialContext,
+	HandshakeTimeout: 45 * time.Second,
+}
+
+// websocketSendConfig is the config of a websocket_send node.
+type websocketSendConfig struct {
+	URL     string                 `config:"url" doc:"ws:// or wss:// endpoint; an input url overrides it"`
+	Headers map[string]interface{} `config:"headers" doc:"handshake headers"`
+	// Token, or User and Password, add an Authorization header.
+	Token    string `config:"token" doc:"bearer token of the handshake"`
+	User     string `config:"user" doc:"basic auth user of the handshake"`
+	Password string `config:"password" doc:"basic auth password of the handshake"`
+	// Message is sent when the input has none; strings are sent as text
+	// frames as is, anything else as JSON.
+	Message        interface{} `config:"message" doc:"message to send when the input has none"`
+	Pool           bool        `config:"pool,default=true" doc:"reuse a connection per url and headers"`
+	AwaitReply     bool        `config:"await_reply" doc:"wait for a reply after sending"`
+	CorrelationKey string      `config:"correlation_key" doc:"dotted field holding the same value in the message and its reply; without it the next message is the reply"`
+	ReplyTimeoutMS int         `config:"reply_timeout_ms" doc:"reply wait (default 10000), capped by the process timeout"`
+}
+
+// WebSocketSendActivity implements the `websocket_send` node type (config:
+// websocketSendConfig): it sends a message to a WebSocket endpoint and,
+// optionally, waits for the reply that correlates with it.
+//
+// Output: {sent: true} plus {reply} when a reply was awaited; replies that are
+// valid JSON are decoded.
+type WebSocketSendActivity struct {
+	pool *wsPool
+}
+
+// NewWebSocketSendActivity returns the websocket_send activity with its own
+// connection pool.
+func NewWebSocketSendActivity() *WebSocketSendActivity {
+	return &WebSocketSendActivity{pool: &wsPool{conns: make(map[string]*wsConn)}}
+}
+
+func (a *WebSocketSendActivity) Name() string { return "websocket_send" }
+
+// ConfigSpec returns the config struct of websocket_send nodes.
+func (a *WebSocketSendActivity) ConfigSpec() interface{} { return &websocketSendConfig{} }
+
+func (a *WebSocketSendActivity) Execute(input map[string]interface{}, config map[string]interface{}, ctx *models.ExecutionContext) (map[string]interface{}, error) {
+	var cfg websocketSendConfig
+	if err := decodeConfig(a.Name(), config, &cfg); err != nil {
+		return nil, err
+	}
+	url := cfg.URL
+	if u, ok := input["url"].(string); ok && u != "" {
+		url = u
+	}
+	if url == "" {
+		return nil, fmt.Errorf("websocket_send activity: config field 'url' is required")
+	}
+	message, ok := input["message"]
+	if !ok {
+		message = cfg.Message
+	}
+	if message == nil {
+		return nil, fmt.Errorf("websocket_send activity: missing 'message' in input or config")
+	}
+	payload, err := wsPayload(message)
+	if err != nil {
+		return nil, fmt.Errorf("websocket_send activity: %w", err)
+	}
+
+	var correlationID interface{}
+	if cfg.AwaitReply && cfg.CorrelationKey != "" {
+		correlationID = lookupField(message, cfg.CorrelationKey)
+		if correlationID == nil {
+			return nil, fmt.Errorf("websocket_send activity: message has no %q to correlate the reply with", cfg.CorrelationKey)
+		}
+	}
+	replyTimeout := defaultWSReplyTimeout
+	if ms := cfg.ReplyTimeoutMS; ms > 0 {
+		replyTimeout = time.Duration(ms) * time.Millisecond
+	}
+
+	header := wsHeader(&cfg)
+	pooled := cfg.Pool
+
+	conn, reused, err := a.connect(ctx, url, header, pooled)
+	if err != nil {
+		return nil, fmt.Errorf("websocket_send activity: failed to connect to %s: %w", url, err)
+	}
+	conn.mu.Lock()
+	err = conn.send(ctx, payload)
+	if err != nil && reused {
+		// A pooled connection may have been closed by the peer while idle;
+		// a fresh one gets a single retry.
+		conn.mu.Unlock()
+		a.pool.discard(conn)
+		if conn, _, err = a.connect(ctx, url, header, pooled); err != nil {
+			return nil, fmt.Errorf("websocket_send activity: failed to connect to %s: %w", url, err)
+		}
+		conn.mu.Lock()
+		err = conn.send(ctx, payload)
+	}
+	if err != nil {
+		conn.mu.Unlock()
+		a.release(conn, pooled, false)
+		return nil, fmt.Errorf("websocket_send activity: failed to send: %w", err)
+	}
+
+	output := map[string]interface{}{"sent": true}
+	if cfg.AwaitReply {
+		reply, err := conn.awaitReply(ctx.Budget(replyTimeout), cfg.CorrelationKey, correlationID)
+		if err != nil {
+			conn.mu.Unlock()
+			// A timed-out read leaves the connection unusable.
+			a.release(conn, pooled, false)
+			return nil, fmt.Errorf("websocket_send activity: %w", err)
+		}
+		output["reply"] = reply
+	}
+	conn.mu.Unlock()
+	a.release(conn, pooled, true)
+	return output, nil
+}
+
+// connect returns a pooled connection for url and header, dialling one when
+// there is none. reused reports whether the connection was already open.
+func (a *WebSocketSendActivity) connect(ctx *models.ExecutionContext, url string, header http.Header, pooled bool) (conn *wsConn, reused bool, err error) {
+	key := wsPoolKey(url, header)
+	if pooled {
+		if conn := a.pool.get(key); conn != nil {
+			return conn, true, nil
+		}
+	}
+	dialCtx, cancel := context.WithTimeout(context.Background(), ctx.Budget(defaultWSDialTimeout))
+	defer cancel()
+	if err := checkWSEgress(dialCtx, url); err != nil {
+		return nil, false, err
+	}
+	c, resp, err := wsDialer.DialContext(dialCtx, url, header)
+	if err != nil {
+		if resp != nil {
+			return nil, false, fmt.Errorf("%w (handshake status %d)", err, resp.StatusCode)
+		}
+		return nil, false, err
+	}
+	conn = &wsConn{key: key, c: c}
+	if pooled {
+		conn = a.pool.put(conn)
+	}
+	return conn, false, nil
+}
+
+// checkWSEgress checks the egress policy for the host of rawURL before it is
+// dialed, which may be through a proxy.
+func checkWSEgress(ctx context.Context, rawURL string) error {
+	u, err := neturl.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "wss" {
+			port = "443"
+		}
+	}
+	n, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return fmt.Errorf("invalid port %q", port)
+	}
+	return egress.CheckHost(ctx, u.Hostname(), uint16(n))
+}
+
+// release closes conn unless it is pooled and still healthy.
+func (a *WebSocketSendActivity) release(conn *wsConn, pooled, healthy bool) {
+	if pooled && healthy {
+		return
+	}
+	if pooled {
+		a.pool.discard(conn)
+		return
+	}
+	_ = conn.c.Close()
+}
+
+// wsConn is one WebSocket connection. mu serializes the send and reply wait
+// of one node run, so concurrent executions never read each other's replies.
+type wsConn struct {
+	mu  sync.Mutex
+	key string
+	c   *websocket.Conn
+}
+
+// send writes payload as a text frame.
+func (w *wsConn) send(ctx *models.ExecutionContext, payload []byte) error {
+	if err := w.c.SetWriteDeadline(time.Now().Add(ctx.Budget(defaultWSDialTimeout))); err != nil {
+		return err
+	}
+	return w.c.WriteMessage(websocket.TextMessage, payload)
+}
+
+// awaitReply reads messages until one correlates with correlationID (any
+// message when key is empty) or timeout passes. Messages that do not
+// correlate are dropped.
+func (w *wsConn) awaitReply(timeout time.Duration, key string, correlationID interface{}) (interface{}, error) {
+	if err := w.c.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, err
+	}
+	for {
+		_, data, err := w.c.ReadMessage()
+		if err != nil {
+			return nil, fmt.Errorf("no reply within %s: %w", timeout, err)
+		}
+		var reply interface{}
+		if json.Unmarshal(data, &reply) != nil {
+			reply = string(data)
+		}
+		if key == "" || fmt.Sprint(lookupField(reply, key)) == fmt.Sprint(correlationID) {
+			_ = w.c.SetReadDeadline(time.Time{})
+			return reply, nil
+		}
+	}
+}
+
+// wsPool keeps one open connection per url and handshake headers.
+type wsPool struct {
+	mu    sync.Mutex
+	conns map[string]*wsConn
+}
+
+func (p *wsPool) get(key string) *wsConn {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.conns[key]
+}
+
+// put pools conn, or returns the connection another run pooled meanwhile and
+// closes conn.
+func (p *wsPool) put(conn *wsConn) *wsConn {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if existing := p.conns[conn.key]; existing != nil {
+		_ = conn.c.Close()
+		return existing
+	}
+	p.conns[conn.key] = conn
+	return conn
+}
+
+// discard closes conn and drops it from the pool.
+func (p *wsPool) discard(conn *wsConn) {
+	p.mu.Lock()
+	if p.conns[conn.key] == conn {
+		delete(p.conns, conn.key)
+	}
+	p.mu.Unlock()
+	_ = conn.c.Close()
+}
+
+// wsPayload returns the frame payload of message: strings as is, anything
+// else JSON-encoded.
+func wsPayload(message interface{}) ([]byte, error) {
+	if s, ok := message.(string); ok {
+		return []byte(s), nil
+	}
+	data, err := json.Marshal(message)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal message: %w", err)
+	}
+	return data, nil
+}
+
+// wsHeader builds the handshake headers from the config.
+func wsHeader(cfg *websocketSendConfig) http.Header {
+	header := http.Header{}
+	if cfg.Token != "" {
+		header.Set("Authorization", "Bearer "+cfg.Token)
+	} else if cfg.User != "" && cfg.Password != "" {
+		header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(cfg.User+":"+cfg.Password)))
+	}
+	for k, v := range cfg.Headers {
+		if s, ok := v.(string); ok {
+			header.Set(k, s)
+		}
+	}
+	return header
+}
+
+// wsPoolKey identifies the connections that can be shared: same url and
+// same handshake headers.
+func wsPoolKey(url string, header http.Header) string {
+	keys := make([]string, 0, len(header))
+	for k := range header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	b.WriteString(url)
+	for _, k := range keys {
+		b.WriteString("\n" + k + ":" + strings.Join(header[k], ","))
+	}
+	return b.String()
+}
+
+// lookupField returns the value at the dotted path of v, or nil.
+func lookupField(v interface{}, path string) interface{} {
+	for _, part := range strings.Split(path, ".") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = m[part]
+	}
+	return v
+}
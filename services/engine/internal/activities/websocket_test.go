@@ -0,0 +1,117 @@
+package activities
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"flowjs-works/engine/internal/egress"
+	"flowjs-works/engine/internal/models"
+)
+
+// newWSGateway starts a WebSocket server that, for every JSON message,
+// first sends an unrelated event and then acknowledges the message's id.
+// It counts the connections it accepted.
+func newWSGateway(t *testing.T) (string, *int32) {
+	t.Helper()
+	var conns int32
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		atomic.AddInt32(&conns, 1)
+		defer c.Close()
+		for {
+			var msg map[string]interface{}
+			if err := c.ReadJSON(&msg); err != nil {
+				return
+			}
+			_ = c.WriteJSON(map[string]interface{}{"id": "other", "event": "tick"})
+			_ = c.WriteJSON(map[string]interface{}{"id": msg["id"], "ack": true})
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return "ws" + strings.TrimPrefix(srv.URL, "http"), &conns
+}
+
+func TestWebSocketSendActivity_AwaitsCorrelatedReply(t *testing.T) {
+	url, conns := newWSGateway(t)
+	a := NewWebSocketSendActivity()
+	config := map[string]interface{}{"url": url, "await_reply": true, "correlation_key": "id", "reply_timeout_ms": float64(2000)}
+
+	for _, id := range []string{"m-1", "m-2"} {
+		out, err := a.Execute(map[string]interface{}{"message": map[string]interface{}{"id": id, "price": 10}}, config, models.NewExecutionContext("exec-ws"))
+		require.NoError(t, err)
+		assert.Equal(t, true, out["sent"])
+		assert.Equal(t, map[string]interface{}{"id": id, "ack": true}, out["reply"])
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(conns), "the connection is pooled")
+}
+
+func TestWebSocketSendActivity_WithoutPool(t *testing.T) {
+	url, conns := newWSGateway(t)
+	a := NewWebSocketSendActivity()
+	config := map[string]interface{}{"url": url, "pool": false, "message": map[string]interface{}{"id": "m-1"}}
+
+	for i := 0; i < 2; i++ {
+		out, err := a.Execute(map[string]interface{}{}, config, models.NewExecutionContext("exec-ws"))
+		require.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"sent": true}, out)
+	}
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(conns) == 2 }, time.Second, 10*time.Millisecond)
+}
+
+func TestWebSocketSendActivity_Errors(t *testing.T) {
+	url, _ := newWSGateway(t)
+	a := NewWebSocketSendActivity()
+	tests := []struct {
+		name    string
+		input   map[string]interface{}
+		config  map[string]interface{}
+		wantErr string
+	}{
+		{name: "missing url", config: map[string]interface{}{"message": "hi"}, wantErr: "'url'"},
+		{name: "missing message", config: map[string]interface{}{"url": url}, wantErr: "'message'"},
+		{name: "no correlation id", config: map[string]interface{}{"url": url, "message": map[string]interface{}{}, "await_reply": true, "correlation_key": "id"}, wantErr: `no "id"`},
+		{name: "reply timeout", config: map[string]interface{}{"url": url, "message": map[string]interface{}{"ref": "r-1"}, "await_reply": true, "correlation_key": "ref", "reply_timeout_ms": float64(100)}, wantErr: "no reply within"},
+		{name: "unreachable", config: map[string]interface{}{"url": "ws://127.0.0.1:1/", "message": "hi"}, wantErr: "failed to connect"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := tt.input
+			if input == nil {
+				input = map[string]interface{}{}
+			}
+			_, err := a.Execute(input, tt.config, models.NewExecutionContext("exec-ws"))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+// TestWebSocketSendActivity_EgressDenied verifies that a connection refused
+// by the egress policy fails the node.
+func TestWebSocketSendActivity_EgressDenied(t *testing.T) {
+	url, conns := newWSGateway(t)
+	egress.SetPolicy(&egress.Policy{DenyInternal: true})
+	t.Cleanup(func() { egress.SetPolicy(nil) })
+
+	out, err := NewWebSocketSendActivity().Execute(map[string]interface{}{},
+		map[string]interface{}{"url": url, "message": "hi"}, models.NewExecutionContext("exec-ws"))
+	assert.ErrorIs(t, err, egress.ErrDenied)
+	assert.Nil(t, out)
+	assert.Zero(t, atomic.LoadInt32(conns), "nothing reached the endpoint")
+
+	_, err = NewWebSocketSendActivity().Execute(map[string]interface{}{},
+		map[string]interface{}{"url": "ws://169.254.169.254/latest", "message": "hi"}, models.NewExecutionContext("exec-ws"))
+	assert.ErrorIs(t, err, egress.ErrDenied, "cloud metadata endpoint")
+}
@@ -0,0 +1,321 @@
+// Package deps analyses a process definition for what it depends on outside
+// itself: secrets, connection profiles, schemas, script snippets, external
+// hosts and the trigger resources it claims. The graph is built from the DSL
+// alone, without resolving secrets or running anything, for impact analysis
+// ("what breaks if this secret or host changes") and promotion checks.
+package deps
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"flowjs-works/engine/internal/models"
+)
+
+// Kinds of Dependency.
+const (
+	KindSecret  = "secret"
+	KindSchema  = "schema"
+	KindSnippet = "snippet"
+	KindHost    = "host"
+	KindBucket  = "bucket"
+	KindProfile = "profile"
+	// KindTrigger is a resource the trigger claims, such as a REST route or a
+	// RabbitMQ queue, which two deployed processes cannot share.
+	KindTrigger = "trigger"
+)
+
+// TriggerUser is the UsedBy entry of dependencies of the process trigger.
+const TriggerUser = "trigger"
+
+// Dependency is one external thing the process depends on.
+type Dependency struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	// UsedBy lists the ids of the nodes depending on it, or TriggerUser.
+	UsedBy []string `json:"used_by"`
+	// Missing is set by callers that found the dependency does not exist,
+	// e.g. a secret absent from the workspace.
+	Missing bool `json:"missing,omitempty"`
+}
+
+// Graph is the dependency graph of one process: the process depends on every
+// Dependency through the nodes in its UsedBy.
+type Graph struct {
+	ProcessID    string       `json:"process_id"`
+	Environment  string       `json:"environment,omitempty"`
+	Dependencies []Dependency `json:"dependencies"`
+	// DynamicHosts lists the nodes whose destination is only known at run
+	// time: it comes from the input mapping, the node's secret or its
+	// connection profile.
+	DynamicHosts []string `json:"dynamic_hosts"`
+}
+
+// MarkMissing sets Missing on the dependencies of kind for which exists
+// returns false.
+func (g *Graph) MarkMissing(kind string, exists func(name string) bool) {
+	for i := range g.Dependencies {
+		if d := &g.Dependencies[i]; d.Kind == kind {
+			d.Missing = !exists(d.Name)
+		}
+	}
+}
+
+// importRe matches the snippet imports of a code node script:
+// import ... from "name", import "name" and require("name").
+var importRe = regexp.MustCompile(`(?:\bfrom\s*|\bimport\s*|\brequire\s*\(\s*)["']([^"'./][^"']*)["']`)
+
+// mysqlAddrRe matches the address of a MySQL DSN: user:pass@tcp(host:port)/db.
+var mysqlAddrRe = regexp.MustCompile(`@tcp\(([^)]+)\)`)
+
+// Analyze builds the dependency graph of p. Run it on the result of
+// models.Process.ForEnvironment to see the secrets an environment uses.
+func Analyze(p *models.Process) *Graph {
+	b := &builder{index: map[string]int{}}
+	b.trigger(p.Trigger)
+	for _, node := range p.Nodes {
+		b.node(node)
+	}
+	sort.Slice(b.deps, func(i, j int) bool {
+		if b.deps[i].Kind != b.deps[j].Kind {
+			return b.deps[i].Kind < b.deps[j].Kind
+		}
+		return b.deps[i].Name < b.deps[j].Name
+	})
+	g := &Graph{
+		ProcessID:    p.Definition.ID,
+		Environment:  p.Definition.Environment,
+		Dependencies: b.deps,
+		DynamicHosts: b.dynamic,
+	}
+	if g.Dependencies == nil {
+		g.Dependencies = []Dependency{}
+	}
+	if g.DynamicHosts == nil {
+		g.DynamicHosts = []string{}
+	}
+	return g
+}
+
+type builder struct {
+	deps    []Dependency
+	index   map[string]int // kind + "\x00" + name → position in deps
+	dynamic []string
+}
+
+func (b *builder) add(kind, name, user string) {
+	if name == "" {
+		return
+	}
+	key := kind + "\x00" + name
+	i, ok := b.index[key]
+	if !ok {
+		i = len(b.deps)
+		b.index[key] = i
+		b.deps = append(b.deps, Dependency{Kind: kind, Name: name})
+	}
+	if users := b.deps[i].UsedBy; len(users) == 0 || users[len(users)-1] != user {
+		b.deps[i].UsedBy = append(users, user)
+	}
+}
+
+func (b *builder) trigger(t models.Trigger) {
+	cfg := t.Config
+	b.add(KindSchema, str(cfg, "schema"), TriggerUser)
+	switch t.Type {
+	case "rest":
+		method := strings.ToUpper(str(cfg, "method"))
+		if method == "" {
+			method = "POST"
+		}
+		if path := str(cfg, "path"); path != "" {
+			b.add(KindTrigger, fmt.Sprintf("rest %s %s", method, path), TriggerUser)
+		}
+	case "soap":
+		if path := str(cfg, "path"); path != "" {
+			b.add(KindTrigger, "soap "+path, TriggerUser)
+		}
+	case "rabbitmq":
+		if queue := str(cfg, "queue"); queue != "" {
+			b.add(KindTrigger, "rabbitmq queue "+queue, TriggerUser)
+		}
+		b.add(KindHost, urlHost(str(cfg, "url_amqp")), TriggerUser)
+	case "postgres_cdc":
+		if tables, ok := cfg["tables"].([]interface{}); ok {
+			for _, item := range tables {
+				if table, ok := item.(string); ok && table != "" {
+					b.add(KindTrigger, "postgres_cdc table "+table, TriggerUser)
+				}
+			}
+		}
+		b.add(KindHost, dsnHost(str(cfg, "dsn")), TriggerUser)
+	case "email":
+		host := hostPort(str(cfg, "host"), cfg["port"])
+		b.add(KindHost, host, TriggerUser)
+		if host != "" {
+			folder := str(cfg, "folder")
+			if folder == "" {
+				folder = "INBOX"
+			}
+			b.add(KindTrigger, "email "+host+"/"+folder, TriggerUser)
+		}
+	case "sftp_poll":
+		host := hostPort(str(cfg, "server"), cfg["port"])
+		b.add(KindHost, host, TriggerUser)
+		if host != "" {
+			b.add(KindTrigger, "sftp_poll "+host+":"+str(cfg, "folder"), TriggerUser)
+		}
+	case "s3_poll":
+		if bucket := str(cfg, "bucket"); bucket != "" {
+			b.add(KindBucket, "s3://"+bucket, TriggerUser)
+			b.add(KindTrigger, "s3_poll s3://"+bucket+"/"+str(cfg, "folder"), TriggerUser)
+		}
+	case "mcp":
+		if addr := str(cfg, "addr"); addr != "" {
+			b.add(KindTrigger, "mcp "+addr, TriggerUser)
+		}
+	}
+}
+
+func (b *builder) node(n models.Node) {
+	b.add(KindSecret, n.SecretRef, n.ID)
+	b.add(KindProfile, n.Profile, n.ID)
+	b.add(KindSchema, n.InputSchema, n.ID)
+	b.add(KindSchema, n.OutputSchema, n.ID)
+	if n.Type == "code" {
+		for _, m := range importRe.FindAllStringSubmatch(n.Script, -1) {
+			b.add(KindSnippet, m[1], n.ID)
+		}
+	}
+
+	cfg := n.Config
+	var host string
+	switch n.Type {
+	case "http", "websocket_send", "callback_await":
+		host = urlHost(literal(n, "url"))
+	case "sftp", "smb":
+		host = hostPort(str(cfg, "server"), cfg["port"])
+	case "mail":
+		if str(cfg, "action") != "receive" {
+			host = hostPort(str(cfg, "host"), cfg["port"])
+		}
+	case "sql":
+		host = warehouseHost(cfg)
+		if host == "" {
+			host = dsnHost(str(cfg, "dsn"))
+		}
+		if host == "" {
+			host = dsnHost(str(cfg, "connection_string"))
+		}
+		if host == "" {
+			host = hostPort(str(cfg, "host"), cfg["port"])
+		}
+	case "rabbitmq":
+		host = urlHost(str(cfg, "url_amqp"))
+	case "s3":
+		if bucket := str(cfg, "bucket"); bucket != "" {
+			b.add(KindBucket, "s3://"+bucket, n.ID)
+		}
+		return
+	default:
+		return
+	}
+	if host == "" {
+		b.dynamic = append(b.dynamic, n.ID)
+		return
+	}
+	b.add(KindHost, host, n.ID)
+}
+
+// literal returns the value of key the way http and websocket_send nodes
+// read it: input_mapping[key] overrides config[key]. It returns "" when the
+// input is not a constant.
+func literal(n models.Node, key string) string {
+	if _, ok := n.InputMapping[key]; ok {
+		if v := str(n.InputMapping, key); !strings.HasPrefix(v, "$") {
+			return v
+		}
+		return ""
+	}
+	return str(n.Config, key)
+}
+
+func str(m map[string]interface{}, key string) string {
+	v, _ := m[key].(string)
+	return strings.TrimSpace(v)
+}
+
+// hostPort joins host with a non-empty port. Hosts written as JSONPaths or
+// templates are not static and yield "".
+func hostPort(host string, port interface{}) string {
+	if host == "" || strings.ContainsAny(host, "${}") {
+		return ""
+	}
+	switch p := port.(type) {
+	case float64:
+		return net.JoinHostPort(host, fmt.Sprint(int(p)))
+	case string:
+		if p != "" {
+			return net.JoinHostPort(host, p)
+		}
+	}
+	return host
+}
+
+// urlHost returns the host (and explicit port) of raw, never its credentials.
+func urlHost(raw string) string {
+	if strings.ContainsAny(raw, "${}") {
+		return ""
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+// warehouseHost returns the API host of a snowflake or bigquery sql node, or
+// "" for other engines.
+func warehouseHost(cfg map[string]interface{}) string {
+	if u := str(cfg, "url"); u != "" {
+		return urlHost(u)
+	}
+	switch str(cfg, "engine") {
+	case "snowflake":
+		if account := str(cfg, "account"); account != "" && !strings.ContainsAny(account, "${}") {
+			return account + ".snowflakecomputing.com"
+		}
+	case "bigquery":
+		return "bigquery.googleapis.com"
+	}
+	return ""
+}
+
+// dsnHost returns the host of a PostgreSQL URL or key=value DSN or of a MySQL
+// DSN.
+func dsnHost(dsn string) string {
+	if dsn == "" {
+		return ""
+	}
+	if strings.Contains(dsn, "://") {
+		return urlHost(dsn)
+	}
+	if m := mysqlAddrRe.FindStringSubmatch(dsn); m != nil {
+		return m[1]
+	}
+	var host, port string
+	for _, field := range strings.Fields(dsn) {
+		k, v, _ := strings.Cut(field, "=")
+		switch k {
+		case "host":
+			host = v
+		case "port":
+			port = v
+		}
+	}
+	return hostPort(host, port)
+}
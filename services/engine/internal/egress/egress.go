@@ -0,0 +1,217 @@
+// Package egress enforces the engine's outbound network policy: which
+// addresses and ports activities may connect to. It keeps flows with
+// user-controlled URLs or hosts from reaching internal infrastructure (SSRF).
+//
+// The policy is checked by the dialer after DNS resolution, on the address
+// actually connected to, so redirects and DNS rebinding cannot bypass it.
+package egress
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// ErrDenied is returned when a connection is refused by the egress policy.
+var ErrDenied = errors.New("egress: destination denied by policy")
+
+// internalRanges are the ranges DenyInternal blocks besides loopback,
+// private, link-local and unspecified addresses.
+var internalRanges = []netip.Prefix{
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),  // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"), // benchmarking
+	netip.MustParsePrefix("0.0.0.0/8"),
+}
+
+// Policy decides which destinations activities may connect to. A nil
+// *Policy allows everything.
+type Policy struct {
+	// Allow, when not empty, lists the only ranges that may be reached.
+	// Addresses inside it are also exempt from DenyInternal.
+	Allow []netip.Prefix
+	// Deny lists ranges that are never reached, even when allowed.
+	Deny []netip.Prefix
+	// BlockedPorts are destination ports that are never reached.
+	BlockedPorts map[uint16]bool
+	// DenyInternal blocks loopback, private, link-local (including cloud
+	// metadata endpoints), CGNAT and unspecified addresses.
+	DenyInternal bool
+}
+
+// PolicyFromEnv builds the policy from EGRESS_ALLOW_CIDRS, EGRESS_DENY_CIDRS,
+// EGRESS_BLOCKED_PORTS (comma-separated) and EGRESS_DENY_INTERNAL. It
+// returns nil when none is set.
+func PolicyFromEnv() (*Policy, error) {
+	allow, deny, ports := os.Getenv("EGRESS_ALLOW_CIDRS"), os.Getenv("EGRESS_DENY_CIDRS"), os.Getenv("EGRESS_BLOCKED_PORTS")
+	denyInternal := false
+	if v := os.Getenv("EGRESS_DENY_INTERNAL"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("egress: EGRESS_DENY_INTERNAL: %w", err)
+		}
+		denyInternal = b
+	}
+	if allow == "" && deny == "" && ports == "" && !denyInternal {
+		return nil, nil
+	}
+	p := &Policy{DenyInternal: denyInternal, BlockedPorts: make(map[uint16]bool)}
+	var err error
+	if p.Allow, err = parsePrefixes(allow); err != nil {
+		return nil, fmt.Errorf("egress: EGRESS_ALLOW_CIDRS: %w", err)
+	}
+	if p.Deny, err = parsePrefixes(deny); err != nil {
+		return nil, fmt.Errorf("egress: EGRESS_DENY_CIDRS: %w", err)
+	}
+	for _, s := range splitList(ports) {
+		port, err := strconv.ParseUint(s, 10, 16)
+		if err != nil || port == 0 {
+			return nil, fmt.Errorf("egress: EGRESS_BLOCKED_PORTS: invalid port %q", s)
+		}
+		p.BlockedPorts[uint16(port)] = true
+	}
+	return p, nil
+}
+
+// parsePrefixes parses a comma-separated list of CIDRs; a bare address is a
+// single-address range.
+func parsePrefixes(list string) ([]netip.Prefix, error) {
+	var out []netip.Prefix
+	for _, s := range splitList(list) {
+		if !strings.Contains(s, "/") {
+			addr, err := netip.ParseAddr(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid CIDR %q", s)
+			}
+			out = append(out, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q", s)
+		}
+		out = append(out, prefix.Masked())
+	}
+	return out, nil
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// Check returns an error wrapping ErrDenied when p does not allow connecting
+// to addr.
+func (p *Policy) Check(addr netip.AddrPort) error {
+	if p == nil {
+		return nil
+	}
+	ip := addr.Addr().Unmap()
+	deny := func(reason string) error {
+		return fmt.Errorf("%w: %s (%s)", ErrDenied, addr, reason)
+	}
+	if contains(p.Deny, ip) {
+		return deny("denied range")
+	}
+	if p.BlockedPorts[addr.Port()] {
+		return deny("blocked port")
+	}
+	allowed := contains(p.Allow, ip)
+	if len(p.Allow) > 0 && !allowed {
+		return deny("not in allowed ranges")
+	}
+	if p.DenyInternal && !allowed && isInternal(ip) {
+		return deny("internal address")
+	}
+	return nil
+}
+
+func contains(prefixes []netip.Prefix, ip netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func isInternal(ip netip.Addr) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsUnspecified() || contains(internalRanges, ip)
+}
+
+// current is the policy enforced by Dialer; nil allows everything.
+var current atomic.Pointer[Policy]
+
+// SetPolicy makes p the policy enforced on every connection made through
+// Dialer. Nil lifts the policy.
+func SetPolicy(p *Policy) {
+	current.Store(p)
+}
+
+// Current returns the policy set by SetPolicy.
+func Current() *Policy {
+	return current.Load()
+}
+
+// Dialer returns a TCP dialer with timeout that checks the current policy
+// on every address it connects to.
+func Dialer(timeout time.Duration) *net.Dialer {
+	return &net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second, Control: control}
+}
+
+// DialContext connects to address like net.Dialer.DialContext, checking the
+// current policy.
+func DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	return Dialer(0).DialContext(ctx, network, address)
+}
+
+// CheckHost checks the current policy for every address host resolves to.
+// Clients that may connect through a proxy call it before dialing: their
+// dialer then only sees the proxy's address.
+func CheckHost(ctx context.Context, host string, port uint16) error {
+	p := Current()
+	if p == nil {
+		return nil
+	}
+	if ip, err := netip.ParseAddr(host); err == nil {
+		return p.Check(netip.AddrPortFrom(ip, port))
+	}
+	ips, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("egress: resolve %q: %w", host, err)
+	}
+	for _, ip := range ips {
+		if err := p.Check(netip.AddrPortFrom(ip, port)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// control is the net.Dialer hook run on the resolved address right before
+// connecting. Unix sockets are local and not checked.
+func control(network, address string, _ syscall.RawConn) error {
+	p := Current()
+	if p == nil || !strings.HasPrefix(network, "tcp") && !strings.HasPrefix(network, "udp") {
+		return nil
+	}
+	addr, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: unparseable address %q", ErrDenied, address)
+	}
+	return p.Check(addr)
+}
@@ -0,0 +1,107 @@
+package egress
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicyFromEnv(t *testing.T) {
+	p, err := PolicyFromEnv()
+	require.NoError(t, err)
+	assert.Nil(t, p)
+
+	t.Setenv("EGRESS_DENY_INTERNAL", "true")
+	t.Setenv("EGRESS_ALLOW_CIDRS", "10.20.0.0/16, 192.168.1.5")
+	t.Setenv("EGRESS_DENY_CIDRS", "10.20.30.0/24")
+	t.Setenv("EGRESS_BLOCKED_PORTS", "25,6379")
+	p, err = PolicyFromEnv()
+	require.NoError(t, err)
+	assert.True(t, p.DenyInternal)
+	assert.Equal(t, []netip.Prefix{netip.MustParsePrefix("10.20.0.0/16"), netip.MustParsePrefix("192.168.1.5/32")}, p.Allow)
+	assert.Equal(t, []netip.Prefix{netip.MustParsePrefix("10.20.30.0/24")}, p.Deny)
+	assert.Equal(t, map[uint16]bool{25: true, 6379: true}, p.BlockedPorts)
+
+	for name, env := range map[string][2]string{
+		"bad bool": {"EGRESS_DENY_INTERNAL", "maybe"},
+		"bad cidr": {"EGRESS_ALLOW_CIDRS", "10.0.0.0/33"},
+		"bad port": {"EGRESS_BLOCKED_PORTS", "70000"},
+		"zero":     {"EGRESS_BLOCKED_PORTS", "0"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(env[0], env[1])
+			_, err := PolicyFromEnv()
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestPolicyCheck(t *testing.T) {
+	p := &Policy{
+		Allow:        []netip.Prefix{netip.MustParsePrefix("10.20.0.0/16"), netip.MustParsePrefix("203.0.113.0/24")},
+		Deny:         []netip.Prefix{netip.MustParsePrefix("10.20.30.0/24")},
+		BlockedPorts: map[uint16]bool{25: true},
+		DenyInternal: true,
+	}
+	for addr, allowed := range map[string]bool{
+		"10.20.1.1:443":          true, // allowed range exempt from DenyInternal
+		"203.0.113.7:443":        true,
+		"10.20.30.1:443":         false, // denied inside an allowed range
+		"203.0.113.7:25":         false, // blocked port
+		"198.51.100.1:443":       false, // outside the allowed ranges
+		"[::ffff:10.20.1.1]:443": true,
+	} {
+		err := p.Check(netip.MustParseAddrPort(addr))
+		if allowed {
+			assert.NoError(t, err, addr)
+		} else {
+			assert.ErrorIs(t, err, ErrDenied, addr)
+		}
+	}
+
+	internal := &Policy{DenyInternal: true}
+	for _, addr := range []string{"127.0.0.1:80", "169.254.169.254:80", "192.168.0.1:80", "100.64.0.1:80", "0.0.0.0:80", "[::1]:80", "[fe80::1]:80"} {
+		assert.ErrorIs(t, internal.Check(netip.MustParseAddrPort(addr)), ErrDenied, addr)
+	}
+	assert.NoError(t, internal.Check(netip.MustParseAddrPort("93.184.216.34:443")))
+
+	var none *Policy
+	assert.NoError(t, none.Check(netip.MustParseAddrPort("127.0.0.1:80")))
+}
+
+// TestDialer_EnforcesPolicy verifies that connections made through Dialer
+// are refused once a policy denies their address.
+func TestDialer_EnforcesPolicy(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	t.Cleanup(func() { SetPolicy(nil) })
+
+	transport := &http.Transport{DialContext: Dialer(0).DialContext}
+	client := &http.Client{Transport: transport}
+	resp, err := client.Get(srv.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	SetPolicy(&Policy{DenyInternal: true})
+	transport.CloseIdleConnections()
+	_, err = client.Get(srv.URL)
+	assert.ErrorIs(t, err, ErrDenied)
+}
+
+func TestCheckHost(t *testing.T) {
+	ctx := context.Background()
+	assert.NoError(t, CheckHost(ctx, "127.0.0.1", 80), "no policy allows everything")
+
+	SetPolicy(&Policy{DenyInternal: true})
+	t.Cleanup(func() { SetPolicy(nil) })
+	assert.ErrorIs(t, CheckHost(ctx, "127.0.0.1", 80), ErrDenied)
+	assert.ErrorIs(t, CheckHost(ctx, "169.254.169.254", 80), ErrDenied, "cloud metadata endpoint")
+	assert.ErrorIs(t, CheckHost(ctx, "::1", 443), ErrDenied)
+	assert.ErrorIs(t, CheckHost(ctx, "localhost", 80), ErrDenied, "names are checked on the addresses they resolve to")
+	assert.NoError(t, CheckHost(ctx, "93.184.216.34", 443))
+}
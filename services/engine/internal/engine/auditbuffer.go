@@ -0,0 +1,434 @@
+package engine
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"flowjs-works/engine/internal/logging"
+)
+
+const (
+	// auditSubject is the NATS subject the audit-logger consumes.
+	auditSubject = "audit.logs"
+	// DefaultAuditBufferSize is how many unpublished audit events are kept in
+	// memory while NATS is unavailable.
+	DefaultAuditBufferSize = 10_000
+	// DefaultAuditSpillMaxBytes caps the spill file.
+	DefaultAuditSpillMaxBytes = 256 << 20
+	// auditRetryInterval is how often buffered events are republished.
+	auditRetryInterval = time.Second
+	// auditSpillFile is the name of the spill file inside SpillDir.
+	auditSpillFile = "audit-spill.jsonl"
+)
+
+// AuditBufferConfig configures how audit events that fail to publish are
+// kept for republishing.
+type AuditBufferConfig struct {
+	// Size is the number of events held in memory (DefaultAuditBufferSize
+	// when zero). When it is full the oldest event is spilled to disk, or
+	// dropped without SpillDir.
+	Size int
+	// SpillDir, when set, holds a JSON-lines file of events that overflowed
+	// the memory buffer or were pending at shutdown. It is republished once
+	// NATS is back, including after a restart.
+	SpillDir string
+	// SpillMaxBytes caps the spill file (DefaultAuditSpillMaxBytes when zero);
+	// events beyond it are dropped.
+	SpillMaxBytes int64
+}
+
+// AuditStats counts audit events since the executor started.
+type AuditStats struct {
+	// Published events reached NATS, directly or after buffering.
+	Published uint64 `json:"published"`
+	// Retried events were buffered for republishing, because they failed to
+	// publish or queued behind events that did.
+	Retried uint64 `json:"retried"`
+	// Dropped events are lost: the buffer and spill file were full, the
+	// event exceeded the NATS payload limit, or it was pending at shutdown
+	// without a spill dir.
+	Dropped uint64 `json:"dropped"`
+	// Buffered is the number of events waiting in memory now.
+	Buffered int `json:"buffered"`
+	// SpilledBytes is the current size of the spill file.
+	SpilledBytes int64 `json:"spilled_bytes"`
+	// Suppressed events belonged to successful runs that the trigger's
+	// audit sampling left unrecorded.
+	Suppressed uint64 `json:"suppressed"`
+}
+
+// auditBuffer publishes audit events to NATS, keeping those that fail in a
+// ring buffer (and optionally a spill file) that a background loop
+// republishes, so a NATS blip does not leave gaps in execution history.
+// Republished events may arrive out of order; each carries its timestamp.
+type auditBuffer struct {
+	publish func(subject string, data []byte) error
+	cfg     AuditBufferConfig
+
+	mu         sync.Mutex
+	ring       [][]byte
+	head, size int
+	// popped counts events removed from the head, so drain can tell whether
+	// the event it published was spilled by enqueue meanwhile.
+	popped     uint64
+	spillBytes int64
+
+	published, retried, dropped atomic.Uint64
+
+	wake     chan struct{}
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+func newAuditBuffer(publish func(subject string, data []byte) error, cfg AuditBufferConfig) *auditBuffer {
+	if cfg.Size <= 0 {
+		cfg.Size = DefaultAuditBufferSize
+	}
+	if cfg.SpillMaxBytes <= 0 {
+		cfg.SpillMaxBytes = DefaultAuditSpillMaxBytes
+	}
+	b := &auditBuffer{
+		publish: publish,
+		cfg:     cfg,
+		ring:    make([][]byte, cfg.Size),
+		wake:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	if cfg.SpillDir != "" {
+		if _, err := os.Stat(b.replayPath()); err == nil {
+			// The previous run stopped while republishing the spill file.
+			if err := b.restoreReplayLocked(0); err != nil {
+				slog.Error("audit: restore spill file", logging.KeyError, err)
+			}
+		}
+		if info, err := os.Stat(b.spillPath()); err == nil {
+			b.spillBytes = info.Size()
+			slog.Info("audit: republishing events spilled by a previous run", "bytes", b.spillBytes)
+		}
+	}
+	go b.loop()
+	return b
+}
+
+// Publish sends msg, buffering it when NATS rejects it. Events queue behind
+// earlier buffered ones so a recovering connection is not overtaken.
+func (b *auditBuffer) Publish(msg []byte) {
+	b.mu.Lock()
+	pending := b.size > 0 || b.spillBytes > 0
+	b.mu.Unlock()
+	if !pending {
+		err := b.publish(auditSubject, msg)
+		if err == nil {
+			b.published.Add(1)
+			return
+		}
+		if errors.Is(err, nats.ErrMaxPayload) {
+			b.dropped.Add(1)
+			slog.Error("audit: event exceeds the NATS payload limit; dropped", "bytes", len(msg))
+			return
+		}
+		slog.Warn("audit: publish failed; buffering event for retry", logging.KeyError, err)
+	}
+	b.retried.Add(1)
+	b.enqueue(msg)
+	select {
+	case b.wake <- struct{}{}:
+	default:
+	}
+}
+
+// enqueue appends msg to the ring, spilling or dropping the oldest event
+// when it is full.
+func (b *auditBuffer) enqueue(msg []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.size == len(b.ring) {
+		b.spillLocked([][]byte{b.popLocked()})
+	}
+	b.ring[(b.head+b.size)%len(b.ring)] = msg
+	b.size++
+}
+
+// popLocked removes and returns the oldest buffered event.
+func (b *auditBuffer) popLocked() []byte {
+	msg := b.ring[b.head]
+	b.ring[b.head] = nil
+	b.head = (b.head + 1) % len(b.ring)
+	b.size--
+	b.popped++
+	return msg
+}
+
+// spillLocked appends msgs to the spill file, dropping them when spilling
+// is disabled, the file is full or cannot be written.
+func (b *auditBuffer) spillLocked(msgs [][]byte) {
+	if len(msgs) == 0 {
+		return
+	}
+	if b.cfg.SpillDir == "" {
+		b.dropped.Add(uint64(len(msgs)))
+		return
+	}
+	var data bytes.Buffer
+	written := 0
+	for _, m := range msgs {
+		if b.spillBytes+int64(data.Len()+len(m)+1) > b.cfg.SpillMaxBytes {
+			break
+		}
+		data.Write(m)
+		data.WriteByte('\n')
+		written++
+	}
+	if lost := len(msgs) - written; lost > 0 {
+		b.dropped.Add(uint64(lost))
+	}
+	if written == 0 {
+		return
+	}
+	if err := appendFile(b.spillPath(), data.Bytes()); err != nil {
+		slog.Error("audit: write spill file", logging.KeyError, err)
+		b.dropped.Add(uint64(written))
+		return
+	}
+	b.spillBytes += int64(data.Len())
+}
+
+func (b *auditBuffer) spillPath() string {
+	return filepath.Join(b.cfg.SpillDir, auditSpillFile)
+}
+
+func (b *auditBuffer) loop() {
+	defer close(b.done)
+	ticker := time.NewTicker(auditRetryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-b.stop:
+			return
+		case <-b.wake:
+		case <-ticker.C:
+		}
+		b.drain()
+	}
+}
+
+// drain republishes buffered events oldest first, then the spill file,
+// stopping at the first failure.
+func (b *auditBuffer) drain() {
+	for {
+		b.mu.Lock()
+		if b.size == 0 {
+			b.mu.Unlock()
+			break
+		}
+		msg, seq := b.ring[b.head], b.popped
+		b.mu.Unlock()
+
+		err := b.publish(auditSubject, msg)
+		switch {
+		case err == nil:
+			b.published.Add(1)
+		case errors.Is(err, nats.ErrMaxPayload):
+			b.dropped.Add(1)
+		default:
+			return
+		}
+		b.mu.Lock()
+		if b.popped == seq {
+			b.popLocked()
+		}
+		b.mu.Unlock()
+	}
+	b.drainSpill()
+}
+
+// drainSpill republishes the spill file. It is renamed to a replay file
+// first, so events spilled meanwhile start a new one; the events it cannot
+// publish go back in front of them.
+func (b *auditBuffer) drainSpill() {
+	if b.cfg.SpillDir == "" {
+		return
+	}
+	b.mu.Lock()
+	if _, err := os.Stat(b.replayPath()); err == nil {
+		// A previous drain could not put its replay file back.
+		if err := b.restoreReplayLocked(0); err != nil {
+			b.mu.Unlock()
+			slog.Error("audit: restore spill file", logging.KeyError, err)
+			return
+		}
+	}
+	if b.spillBytes == 0 {
+		b.mu.Unlock()
+		return
+	}
+	err := os.Rename(b.spillPath(), b.replayPath())
+	b.spillBytes = 0
+	b.mu.Unlock()
+	if err != nil {
+		slog.Error("audit: claim spill file", logging.KeyError, err)
+		return
+	}
+
+	off, done, err := b.publishReplay()
+	if err != nil {
+		slog.Error("audit: read spill file", logging.KeyError, err)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if done {
+		if err := os.Remove(b.replayPath()); err != nil {
+			slog.Error("audit: remove spill file", logging.KeyError, err)
+		}
+		return
+	}
+	if err := b.restoreReplayLocked(off); err != nil {
+		// The replay file stays; the next drain or restart retries.
+		slog.Error("audit: restore spill file", logging.KeyError, err)
+	}
+}
+
+// publishReplay publishes the events of the replay file until one fails. It
+// returns the offset of the first event left unpublished and whether the
+// whole file was read.
+func (b *auditBuffer) publishReplay() (off int64, done bool, err error) {
+	f, err := os.Open(b.replayPath())
+	if err != nil {
+		return 0, false, err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	for {
+		line, readErr := r.ReadBytes('\n')
+		if readErr != nil && readErr != io.EOF {
+			return off, false, readErr
+		}
+		if msg := bytes.TrimSuffix(line, []byte("\n")); len(msg) > 0 {
+			err := b.publish(auditSubject, msg)
+			switch {
+			case err == nil:
+				b.published.Add(1)
+			case errors.Is(err, nats.ErrMaxPayload):
+				b.dropped.Add(1)
+			default:
+				return off, false, nil
+			}
+		}
+		off += int64(len(line))
+		if readErr == io.EOF {
+			return off, true, nil
+		}
+	}
+}
+
+// restoreReplayLocked puts the replay file, from offset off, back in front
+// of the spill file and removes it. A crash before the removal republishes
+// those events twice rather than losing them.
+func (b *auditBuffer) restoreReplayLocked(off int64) error {
+	tmp := b.spillPath() + ".tmp"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	size, err := copyFileFrom(out, b.replayPath(), off)
+	if err == nil {
+		var n int64
+		n, err = copyFileFrom(out, b.spillPath(), 0)
+		size += n
+		if errors.Is(err, fs.ErrNotExist) {
+			err = nil
+		}
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, b.spillPath())
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	b.spillBytes = size
+	return os.Remove(b.replayPath())
+}
+
+func (b *auditBuffer) replayPath() string {
+	return b.spillPath() + ".replay"
+}
+
+// Stats returns the event counters.
+func (b *auditBuffer) Stats() AuditStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return AuditStats{
+		Published:    b.published.Load(),
+		Retried:      b.retried.Load(),
+		Dropped:      b.dropped.Load(),
+		Buffered:     b.size,
+		SpilledBytes: b.spillBytes,
+	}
+}
+
+// Close stops the republisher after a last attempt to publish what is
+// buffered; what is still pending is spilled to disk, or dropped.
+func (b *auditBuffer) Close() {
+	b.stopOnce.Do(func() {
+		close(b.stop)
+		<-b.done
+		b.drain()
+
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		pending := make([][]byte, 0, b.size)
+		for b.size > 0 {
+			pending = append(pending, b.popLocked())
+		}
+		b.spillLocked(pending)
+		if len(pending) > 0 {
+			slog.Warn("audit: events still unpublished at shutdown", "count", len(pending), "spill_dir", b.cfg.SpillDir)
+		}
+	})
+}
+
+func appendFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("create spill dir: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// copyFileFrom appends the file at path, from offset off, to w.
+func copyFileFrom(w io.Writer, path string, off int64) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	if _, err := f.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return io.Copy(w, f)
+}
@@ -0,0 +1,187 @@
+package engine
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyPublisher fails every publish while down is set.
+type flakyPublisher struct {
+	mu   sync.Mutex
+	down bool
+	got  []string
+}
+
+func (p *flakyPublisher) publish(_ string, data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.down {
+		return nats.ErrConnectionReconnecting
+	}
+	p.got = append(p.got, string(data))
+	return nil
+}
+
+func (p *flakyPublisher) setDown(down bool) {
+	p.mu.Lock()
+	p.down = down
+	p.mu.Unlock()
+}
+
+func (p *flakyPublisher) received() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.got...)
+}
+
+func TestAuditBuffer_RepublishesAfterOutage(t *testing.T) {
+	pub := &flakyPublisher{}
+	b := newAuditBuffer(pub.publish, AuditBufferConfig{Size: 10})
+	defer b.Close()
+
+	b.Publish([]byte("e1"))
+	pub.setDown(true)
+	b.Publish([]byte("e2"))
+	b.Publish([]byte("e3"))
+	assert.Equal(t, 2, b.Stats().Buffered)
+
+	pub.setDown(false)
+	b.Publish([]byte("e4")) // queues behind e2 and e3
+	require.Eventually(t, func() bool { return len(pub.received()) == 4 }, 3*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"e1", "e2", "e3", "e4"}, pub.received())
+
+	stats := b.Stats()
+	assert.Equal(t, uint64(4), stats.Published)
+	assert.Equal(t, uint64(3), stats.Retried)
+	assert.Zero(t, stats.Dropped)
+	assert.Zero(t, stats.Buffered)
+}
+
+func TestAuditBuffer_DropsOldestWhenFullWithoutSpill(t *testing.T) {
+	pub := &flakyPublisher{down: true}
+	b := newAuditBuffer(pub.publish, AuditBufferConfig{Size: 2})
+	for _, e := range []string{"e1", "e2", "e3"} {
+		b.Publish([]byte(e))
+	}
+	stats := b.Stats()
+	assert.Equal(t, uint64(1), stats.Dropped)
+	assert.Equal(t, 2, stats.Buffered)
+
+	b.Close()
+	assert.Equal(t, uint64(3), b.Stats().Dropped, "pending events are dropped at shutdown without a spill dir")
+}
+
+func TestAuditBuffer_SpillsToDiskAndReplaysAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+	pub := &flakyPublisher{down: true}
+	b := newAuditBuffer(pub.publish, AuditBufferConfig{Size: 2, SpillDir: dir})
+	for _, e := range []string{"e1", "e2", "e3"} {
+		b.Publish([]byte(e))
+	}
+	assert.Zero(t, b.Stats().Dropped)
+	assert.Positive(t, b.Stats().SpilledBytes, "the oldest event overflowed to disk")
+	b.Close()
+
+	data, err := os.ReadFile(filepath.Join(dir, auditSpillFile))
+	require.NoError(t, err)
+	assert.Equal(t, "e1\ne2\ne3\n", string(data))
+
+	// A new executor republishes the spill file once NATS is reachable.
+	pub.setDown(false)
+	b2 := newAuditBuffer(pub.publish, AuditBufferConfig{SpillDir: dir})
+	defer b2.Close()
+	require.Eventually(t, func() bool { return len(pub.received()) == 3 }, 3*time.Second, 10*time.Millisecond)
+	assert.ElementsMatch(t, []string{"e1", "e2", "e3"}, pub.received())
+	assert.Zero(t, b2.Stats().SpilledBytes)
+	_, err = os.Stat(filepath.Join(dir, auditSpillFile))
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+}
+
+func TestAuditBuffer_RestoresReplayLeftByACrash(t *testing.T) {
+	dir := t.TempDir()
+	// The previous run stopped while republishing e1 and e2, after spilling e3.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, auditSpillFile+".replay"), []byte("e1\ne2\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, auditSpillFile), []byte("e3\n"), 0o600))
+
+	pub := &flakyPublisher{down: true}
+	b := newAuditBuffer(pub.publish, AuditBufferConfig{SpillDir: dir})
+	defer b.Close()
+	assert.Equal(t, int64(9), b.Stats().SpilledBytes)
+	_, err := os.Stat(filepath.Join(dir, auditSpillFile+".replay"))
+	assert.True(t, errors.Is(err, os.ErrNotExist), "the replay file is merged into the spill file")
+
+	pub.setDown(false)
+	require.Eventually(t, func() bool { return len(pub.received()) == 3 }, 3*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"e1", "e2", "e3"}, pub.received(), "replayed events keep their place")
+}
+
+func TestAuditBuffer_UnpublishedReplayGoesBeforeNewSpills(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, auditSpillFile), []byte("e1\ne2\ne3\n"), 0o600))
+	b := &auditBuffer{cfg: AuditBufferConfig{SpillDir: dir, SpillMaxBytes: DefaultAuditSpillMaxBytes}, spillBytes: 9}
+	var got []string
+	b.publish = func(_ string, data []byte) error {
+		if string(data) == "e2" {
+			// e4 overflows to disk while the spill file is being republished.
+			b.mu.Lock()
+			b.spillLocked([][]byte{[]byte("e4")})
+			b.mu.Unlock()
+			return nats.ErrConnectionReconnecting
+		}
+		got = append(got, string(data))
+		return nil
+	}
+
+	b.drainSpill()
+
+	assert.Equal(t, []string{"e1"}, got)
+	data, err := os.ReadFile(filepath.Join(dir, auditSpillFile))
+	require.NoError(t, err)
+	assert.Equal(t, "e2\ne3\ne4\n", string(data))
+	assert.Equal(t, int64(9), b.Stats().SpilledBytes)
+	assert.Zero(t, b.Stats().Dropped)
+	_, err = os.Stat(filepath.Join(dir, auditSpillFile+".replay"))
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+}
+
+func TestAuditBuffer_ReplaysLongLines(t *testing.T) {
+	dir := t.TempDir()
+	long := strings.Repeat("x", 128<<10)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, auditSpillFile), []byte(long+"\ne2\n"), 0o600))
+	pub := &flakyPublisher{}
+	b := &auditBuffer{publish: pub.publish, cfg: AuditBufferConfig{SpillDir: dir}, spillBytes: int64(len(long) + 4)}
+
+	b.drainSpill()
+
+	assert.Equal(t, []string{long, "e2"}, pub.received())
+	assert.Zero(t, b.Stats().Dropped)
+}
+
+func TestAuditBuffer_SpillLimit(t *testing.T) {
+	pub := &flakyPublisher{down: true}
+	b := newAuditBuffer(pub.publish, AuditBufferConfig{Size: 1, SpillDir: t.TempDir(), SpillMaxBytes: 4})
+	defer b.Close()
+	for _, e := range []string{"e1", "e2", "e3"} {
+		b.Publish([]byte(e))
+	}
+	stats := b.Stats()
+	assert.Equal(t, int64(3), stats.SpilledBytes)
+	assert.Equal(t, uint64(1), stats.Dropped)
+}
+
+func TestAuditBuffer_MaxPayloadIsDropped(t *testing.T) {
+	b := newAuditBuffer(func(string, []byte) error { return nats.ErrMaxPayload }, AuditBufferConfig{})
+	defer b.Close()
+	b.Publish([]byte("huge"))
+	assert.Equal(t, uint64(1), b.Stats().Dropped)
+	assert.Zero(t, b.Stats().Buffered)
+}
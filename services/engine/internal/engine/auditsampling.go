@@ -0,0 +1,110 @@
+package engine
+
+import (
+	"math/rand/v2"
+	"sync"
+
+	"flowjs-works/engine/internal/models"
+)
+
+// auditSampling is the "audit" block of a trigger config, which thins out
+// the audit events of successful runs of high-frequency flows:
+//
+//	"trigger": {"type": "cron", "config": {"expression": "* * * * * *",
+//	  "audit": {"log_errors_only": true}}}
+//	"audit": {"sample_rate": 0.01}
+//
+// Runs that fail or have a failing node are always recorded in full.
+type auditSampling struct {
+	errorsOnly bool
+	// rate is the fraction of successful runs recorded, in [0, 1].
+	rate float64
+}
+
+// auditSamplingFor returns the sampling configured on trigger, and false
+// when every run is recorded.
+func auditSamplingFor(trigger models.Trigger) (auditSampling, bool) {
+	cfg, _ := trigger.Config["audit"].(map[string]interface{})
+	if cfg == nil {
+		return auditSampling{}, false
+	}
+	s := auditSampling{rate: 1}
+	s.errorsOnly, _ = cfg["log_errors_only"].(bool)
+	if r, ok := cfg["sample_rate"].(float64); ok {
+		s.rate = min(max(r, 0), 1)
+	}
+	if !s.errorsOnly && s.rate >= 1 {
+		return auditSampling{}, false
+	}
+	return s, true
+}
+
+// auditHold keeps the audit events of one execution until it is known
+// whether the run failed.
+type auditHold struct {
+	mu     sync.Mutex
+	events [][]byte
+	failed bool
+}
+
+// auditFailureStatuses are the event statuses that make a held execution
+// recorded.
+var auditFailureStatuses = map[string]bool{"error": true, "failed": true, StatusCircuitOpen: true}
+
+func (h *auditHold) add(msg []byte, status string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.events = append(h.events, msg)
+	if auditFailureStatuses[status] {
+		h.failed = true
+	}
+}
+
+// holdAudit decides whether the execution is recorded as it runs. When the
+// trigger samples it out or logs errors only, its events are held until
+// releaseAudit and it returns true.
+func (e *ProcessExecutor) holdAudit(process *models.Process, executionID string) bool {
+	if !e.auditEnabled {
+		return false
+	}
+	s, ok := auditSamplingFor(process.Trigger)
+	if !ok {
+		return false
+	}
+	if !s.errorsOnly && e.sampleRand() < s.rate {
+		return false
+	}
+	e.auditHolds.Store(executionID, &auditHold{})
+	return true
+}
+
+// releaseAudit publishes the held events of a failed execution and drops
+// those of a successful one.
+func (e *ProcessExecutor) releaseAudit(executionID string) {
+	v, ok := e.auditHolds.LoadAndDelete(executionID)
+	if !ok {
+		return
+	}
+	h := v.(*auditHold)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.failed {
+		e.auditSuppressed.Add(uint64(len(h.events)))
+		return
+	}
+	for _, msg := range h.events {
+		e.auditBuf.Publish(msg)
+	}
+}
+
+// heldAudit returns the hold of executionID, or nil when its events are
+// published directly.
+func (e *ProcessExecutor) heldAudit(executionID string) *auditHold {
+	if v, ok := e.auditHolds.Load(executionID); ok {
+		return v.(*auditHold)
+	}
+	return nil
+}
+
+// defaultSampleRand draws the sampling decision of an execution.
+func defaultSampleRand() float64 { return rand.Float64() }
@@ -0,0 +1,77 @@
+package engine
+
+import (
+	"testing"
+
+	"flowjs-works/engine/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newAuditingExecutor returns an executor whose audit events go to pub.
+func newAuditingExecutor(t *testing.T, pub *flakyPublisher) *ProcessExecutor {
+	t.Helper()
+	exec := newTestExecutor(t)
+	exec.auditEnabled = true
+	exec.auditBuf = newAuditBuffer(pub.publish, AuditBufferConfig{})
+	t.Cleanup(exec.auditBuf.Close)
+	return exec
+}
+
+func sampledProcess(audit map[string]interface{}, script string) *models.Process {
+	return &models.Process{
+		Definition: models.Definition{ID: "sampled", Version: "1.0.0", Name: "sampled"},
+		Trigger:    models.Trigger{ID: "trg", Type: "cron", Config: map[string]interface{}{"audit": audit}},
+		Nodes:      []models.Node{{ID: "step", Type: "code", Script: script}},
+	}
+}
+
+func TestAuditSamplingFor(t *testing.T) {
+	_, ok := auditSamplingFor(models.Trigger{})
+	assert.False(t, ok)
+	_, ok = auditSamplingFor(models.Trigger{Config: map[string]interface{}{"audit": map[string]interface{}{"sample_rate": float64(1)}}})
+	assert.False(t, ok, "a rate of 1 records every run")
+
+	s, ok := auditSamplingFor(models.Trigger{Config: map[string]interface{}{"audit": map[string]interface{}{"sample_rate": float64(-2)}}})
+	assert.True(t, ok)
+	assert.Equal(t, float64(0), s.rate)
+	s, ok = auditSamplingFor(models.Trigger{Config: map[string]interface{}{"audit": map[string]interface{}{"log_errors_only": true}}})
+	assert.True(t, ok)
+	assert.True(t, s.errorsOnly)
+}
+
+func TestExecute_LogErrorsOnlySuppressesSuccessfulRuns(t *testing.T) {
+	pub := &flakyPublisher{}
+	exec := newAuditingExecutor(t, pub)
+	audit := map[string]interface{}{"log_errors_only": true}
+
+	_, err := exec.Execute(sampledProcess(audit, `({ ok: true })`), map[string]interface{}{})
+	require.NoError(t, err)
+	assert.Empty(t, pub.received())
+	assert.Equal(t, uint64(3), exec.AuditStats().Suppressed, "started, node and completed events")
+
+	_, err = exec.Execute(sampledProcess(audit, `throw new Error("boom")`), map[string]interface{}{})
+	require.Error(t, err)
+	got := pub.received()
+	require.Len(t, got, 3, "a failed run is recorded in full")
+	assert.Contains(t, got[0], `"status":"started"`)
+	assert.Contains(t, got[2], `"status":"failed"`)
+}
+
+func TestExecute_SampleRateRecordsSampledRuns(t *testing.T) {
+	pub := &flakyPublisher{}
+	exec := newAuditingExecutor(t, pub)
+	audit := map[string]interface{}{"sample_rate": 0.5}
+
+	exec.sampleRand = func() float64 { return 0.2 }
+	_, err := exec.Execute(sampledProcess(audit, `({ ok: true })`), map[string]interface{}{})
+	require.NoError(t, err)
+	assert.Len(t, pub.received(), 3)
+
+	exec.sampleRand = func() float64 { return 0.7 }
+	_, err = exec.Execute(sampledProcess(audit, `({ ok: true })`), map[string]interface{}{})
+	require.NoError(t, err)
+	assert.Len(t, pub.received(), 3, "the unsampled run is not recorded")
+	assert.Equal(t, uint64(3), exec.AuditStats().Suppressed)
+}
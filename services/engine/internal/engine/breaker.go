@@ -0,0 +1,121 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"flowjs-works/engine/internal/models"
+)
+
+// StatusCircuitOpen is the node status of a run rejected by an open circuit.
+const StatusCircuitOpen = "circuit_open"
+
+// ErrCircuitOpen is returned for a node whose circuit breaker is open, so the
+// node fails without calling its downstream system.
+var ErrCircuitOpen = errors.New("circuit open")
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// circuitBreakers tracks the circuit of every node with a circuit_breaker
+// across executions, keyed by workspace, process and node. State lives in
+// engine memory and is not shared between replicas.
+type circuitBreakers struct {
+	mu       sync.Mutex
+	circuits map[string]*circuit
+	now      func() time.Time
+}
+
+type circuit struct {
+	state    breakerState
+	failures int
+	openedAt time.Time
+	// probes is the number of half-open runs in flight, successes the number
+	// of half-open runs that succeeded since the circuit left open.
+	probes    int
+	successes int
+}
+
+func newCircuitBreakers() *circuitBreakers {
+	return &circuitBreakers{circuits: make(map[string]*circuit), now: time.Now}
+}
+
+func breakerKey(ctx *models.ExecutionContext, nodeID string) string {
+	return strings.Join([]string{ctx.Workspace, ctx.ProcessID, nodeID}, "\x00")
+}
+
+// allow reports whether a run of the node may proceed. An open circuit whose
+// open duration elapsed turns half-open and admits up to HalfOpenProbes runs
+// at a time; every admitted run must be reported with done.
+func (b *circuitBreakers) allow(key string, cfg *models.CircuitBreaker) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := b.circuits[key]
+	if c == nil {
+		return true
+	}
+	if c.state == breakerOpen {
+		if b.now().Sub(c.openedAt) < cfg.OpenFor() {
+			return false
+		}
+		c.state = breakerHalfOpen
+		c.probes, c.successes = 0, 0
+	}
+	if c.state == breakerHalfOpen {
+		if c.probes >= cfg.Probes() {
+			return false
+		}
+		c.probes++
+	}
+	return true
+}
+
+// done records the outcome of a run admitted by allow. FailureThreshold
+// consecutive failures open a closed circuit; any half-open failure reopens
+// it, and HalfOpenProbes half-open successes close it.
+func (b *circuitBreakers) done(key string, cfg *models.CircuitBreaker, success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := b.circuits[key]
+	if c == nil {
+		if success {
+			return
+		}
+		c = &circuit{}
+		b.circuits[key] = c
+	}
+	switch c.state {
+	case breakerHalfOpen:
+		c.probes--
+		if !success {
+			c.state, c.openedAt = breakerOpen, b.now()
+			return
+		}
+		c.successes++
+		if c.successes >= cfg.Probes() {
+			delete(b.circuits, key)
+		}
+	case breakerClosed:
+		if success {
+			delete(b.circuits, key)
+			return
+		}
+		c.failures++
+		if c.failures >= cfg.Threshold() {
+			c.state, c.openedAt = breakerOpen, b.now()
+		}
+	}
+}
+
+// circuitOpenError describes a run rejected by the circuit of node.
+func circuitOpenError(node *models.Node) error {
+	return fmt.Errorf("node %s: %w after %d consecutive failures", node.ID, ErrCircuitOpen, node.CircuitBreaker.Threshold())
+}
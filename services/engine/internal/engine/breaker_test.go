@@ -0,0 +1,87 @@
+package engine
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"flowjs-works/engine/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyActivity fails while fail is set and counts its calls.
+type flakyActivity struct {
+	fail  bool
+	calls int
+}
+
+func (a *flakyActivity) Name() string { return "flaky" }
+
+func (a *flakyActivity) Execute(map[string]interface{}, map[string]interface{}, *models.ExecutionContext) (map[string]interface{}, error) {
+	a.calls++
+	if a.fail {
+		return nil, errors.New("downstream timeout")
+	}
+	return map[string]interface{}{"ok": true}, nil
+}
+
+// TestCircuitBreaker_OpensAndRecovers verifies the closed → open → half-open
+// → closed cycle, and that a failed probe reopens the circuit.
+func TestCircuitBreaker_OpensAndRecovers(t *testing.T) {
+	now := time.Now()
+	b := newCircuitBreakers()
+	b.now = func() time.Time { return now }
+	cfg := &models.CircuitBreaker{FailureThreshold: 2, OpenDuration: "10s", HalfOpenProbes: 2}
+
+	for i := 0; i < 2; i++ {
+		require.True(t, b.allow("k", cfg))
+		b.done("k", cfg, false)
+	}
+	assert.False(t, b.allow("k", cfg), "threshold reached: circuit must be open")
+
+	now = now.Add(11 * time.Second)
+	require.True(t, b.allow("k", cfg))
+	b.done("k", cfg, false)
+	assert.False(t, b.allow("k", cfg), "failed probe must reopen the circuit")
+
+	now = now.Add(11 * time.Second)
+	require.True(t, b.allow("k", cfg))
+	require.True(t, b.allow("k", cfg))
+	assert.False(t, b.allow("k", cfg), "only half_open_probes runs at a time")
+	b.done("k", cfg, true)
+	b.done("k", cfg, true)
+	assert.True(t, b.allow("k", cfg))
+	assert.Empty(t, b.circuits, "closed circuits are not kept")
+}
+
+// TestExecute_CircuitOpenFailsFast verifies that a node fails with status
+// circuit_open without calling its activity once its circuit trips, and that
+// the rejection still takes the node's error transitions.
+func TestExecute_CircuitOpenFailsFast(t *testing.T) {
+	exec := newTestExecutor(t)
+	flaky := &flakyActivity{fail: true}
+	exec.activityRegistry.Register(flaky)
+
+	process := &models.Process{
+		Definition: models.Definition{ID: "p_breaker", Version: "1.0.0"},
+		Nodes: []models.Node{
+			{ID: "call", Type: "flaky", CircuitBreaker: &models.CircuitBreaker{FailureThreshold: 2, OpenDuration: "1h"}},
+			{ID: "fallback", Type: "logger", Config: map[string]interface{}{"message": "fallback"}},
+		},
+		Transitions: []models.Transition{{From: "call", To: "fallback", Type: "error"}},
+	}
+	for i := 0; i < 2; i++ {
+		ctx, err := exec.Execute(process, map[string]interface{}{})
+		require.NoError(t, err)
+		assert.Equal(t, "error", ctx.Nodes["call"]["status"])
+	}
+
+	flaky.fail = false
+	ctx, err := exec.Execute(process, map[string]interface{}{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, flaky.calls, "an open circuit must not call the activity")
+	assert.Equal(t, StatusCircuitOpen, ctx.Nodes["call"]["status"])
+	assert.Equal(t, "success", ctx.Nodes["fallback"]["status"])
+}
@@ -0,0 +1,318 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"flowjs-works/engine/internal/models"
+
+	"github.com/dop251/goja"
+)
+
+// jsonPathRe matches the context paths written bare in a condition: dotted
+// names, indexes, slices, wildcards and recursive descent. Filters contain
+// operators and spaces, so they have to be quoted helper arguments.
+var jsonPathRe = regexp.MustCompile(`^\$(?:\.\.?(?:[a-zA-Z0-9_]+|\*)|\[(?:-?\d+|\*|-?\d*:-?\d*(?::-?\d+)?)\])+`)
+
+// conditionHelpers are the functions available in transition conditions.
+// A JSONPath passed directly as one of their arguments is handed over as the
+// path itself rather than its value, so exists($.a.b) can test presence.
+var conditionHelpers = map[string]bool{"exists": true, "len": true, "matches": true, "daysBetween": true}
+
+// evaluateCondition evaluates a transition condition against ctx. JSONPath
+// tokens are replaced by their JSON values. With strict set, a token that
+// does not resolve or an expression that fails returns an error; otherwise
+// the token becomes undefined and a failing expression is false.
+func evaluateCondition(expr string, ctx *models.ExecutionContext, strict bool) (bool, error) {
+	result, err := evaluateExpression(expr, ctx, strict)
+	if err != nil || result == nil {
+		return false, err
+	}
+	return result.ToBoolean(), nil
+}
+
+// evaluateExpression evaluates expr like evaluateCondition and returns its
+// value, or nil when it fails without strict set.
+func evaluateExpression(expr string, ctx *models.ExecutionContext, strict bool) (goja.Value, error) {
+	replaced, err := substituteConditionPaths(expr, ctx, strict)
+	if err != nil {
+		return nil, err
+	}
+	vm := goja.New()
+	setConditionHelpers(vm, ctx, strict)
+	result, err := vm.RunString(replaced)
+	if err != nil {
+		if strict {
+			return nil, fmt.Errorf("condition %q: %w", expr, err)
+		}
+		return nil, nil
+	}
+	return result, nil
+}
+
+// evaluateAssertion evaluates the expression of an assert node strictly
+// against ctx, so a path that does not resolve fails the assertion, and
+// returns the values of the context paths it reads.
+func evaluateAssertion(expr string, ctx *models.ExecutionContext) (bool, map[string]interface{}, error) {
+	values := make(map[string]interface{})
+	for _, path := range expressionPaths(expr) {
+		if val, err := ctx.GetValue(path); err == nil {
+			values[path] = val
+		}
+	}
+	ok, err := evaluateCondition(expr, ctx, true)
+	return ok, values, err
+}
+
+// expressionPaths returns the JSONPath tokens of expr outside string
+// literals, once each.
+func expressionPaths(expr string) []string {
+	var paths []string
+	for i := 0; i < len(expr); i++ {
+		switch c := expr[i]; {
+		case c == '\'' || c == '"' || c == '`':
+			i = closingQuoteEscaped(expr, i) - 1
+		case c == '$' && jsonPathRe.MatchString(expr[i:]):
+			token := jsonPathRe.FindString(expr[i:])
+			if !slices.Contains(paths, token) {
+				paths = append(paths, token)
+			}
+			i += len(token) - 1
+		}
+	}
+	return paths
+}
+
+// dynamicTarget evaluates the expression of dynamic transition t and returns
+// the node name it yields and whether that name is one of t.Targets. An
+// undefined or null value yields "".
+func dynamicTarget(t models.Transition, ctx *models.ExecutionContext, strict bool) (string, bool, error) {
+	result, err := evaluateExpression(t.Expression, ctx, strict)
+	if err != nil || result == nil || goja.IsUndefined(result) || goja.IsNull(result) {
+		return "", false, err
+	}
+	name := result.String()
+	for _, target := range t.Targets {
+		if target == name {
+			return name, true, nil
+		}
+	}
+	return name, false, nil
+}
+
+// substituteConditionPaths replaces the JSONPath tokens of expr outside string
+// literals with their values, and the tokens passed directly to a condition
+// helper with quoted paths.
+func substituteConditionPaths(expr string, ctx *models.ExecutionContext, strict bool) (string, error) {
+	var (
+		out strings.Builder
+		// calls holds, for every open parenthesis, the helper it calls or "".
+		calls []string
+	)
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			end := closingQuoteEscaped(expr, i)
+			out.WriteString(expr[i:end])
+			i = end - 1
+		case c == '(':
+			calls = append(calls, identBefore(expr, i))
+			out.WriteByte(c)
+		case c == ')':
+			if len(calls) > 0 {
+				calls = calls[:len(calls)-1]
+			}
+			out.WriteByte(c)
+		case c == '$' && jsonPathRe.MatchString(expr[i:]):
+			token := jsonPathRe.FindString(expr[i:])
+			end := i + len(token)
+			if len(calls) > 0 && conditionHelpers[calls[len(calls)-1]] && isWholeArgument(expr, i, end) {
+				b, _ := json.Marshal(token)
+				out.Write(b)
+			} else {
+				val, err := ctx.GetValue(token)
+				switch {
+				case err != nil && strict:
+					return "", fmt.Errorf("condition %q: %s is undefined", expr, token)
+				case err != nil:
+					out.WriteString("undefined")
+				default:
+					out.WriteString(conditionLiteral(val))
+				}
+			}
+			i = end - 1
+		default:
+			out.WriteByte(c)
+		}
+	}
+	return out.String(), nil
+}
+
+// conditionLiteral renders val as a JavaScript literal.
+func conditionLiteral(val interface{}) string {
+	switch v := val.(type) {
+	case string:
+		b, _ := json.Marshal(v)
+		return string(b)
+	case float64:
+		if v == float64(int64(v)) {
+			return fmt.Sprintf("%d", int64(v))
+		}
+		return fmt.Sprintf("%g", v)
+	case bool:
+		if v {
+			return "true"
+		}
+		return "false"
+	case nil:
+		return "null"
+	default:
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+}
+
+// setConditionHelpers defines the condition helpers in vm. Path arguments
+// that do not resolve make exists false, len 0, matches false and
+// daysBetween NaN, or throw with strict set (except in exists).
+func setConditionHelpers(vm *goja.Runtime, ctx *models.ExecutionContext, strict bool) {
+	// arg returns the value of argument i: the value at a JSONPath string, or
+	// the argument itself.
+	arg := func(call goja.FunctionCall, i int) (interface{}, bool) {
+		v := call.Argument(i).Export()
+		if s, ok := v.(string); ok && strings.HasPrefix(s, "$.") {
+			val, err := ctx.GetValue(s)
+			if err != nil {
+				if strict {
+					panic(vm.NewTypeError("%s is undefined", s))
+				}
+				return nil, false
+			}
+			return val, true
+		}
+		return v, v != nil
+	}
+
+	_ = vm.Set("exists", func(call goja.FunctionCall) goja.Value {
+		v := call.Argument(0).Export()
+		if s, ok := v.(string); ok && strings.HasPrefix(s, "$.") {
+			val, err := ctx.GetValue(s)
+			if list, ok := val.([]interface{}); ok && len(list) == 0 && models.IsQueryPath(s) {
+				// A query exists when it selects something.
+				return vm.ToValue(false)
+			}
+			return vm.ToValue(err == nil && val != nil)
+		}
+		return vm.ToValue(v != nil)
+	})
+	_ = vm.Set("len", func(call goja.FunctionCall) goja.Value {
+		v, _ := arg(call, 0)
+		switch val := v.(type) {
+		case string:
+			return vm.ToValue(utf8.RuneCountInString(val))
+		case []interface{}:
+			return vm.ToValue(len(val))
+		case map[string]interface{}:
+			return vm.ToValue(len(val))
+		}
+		return vm.ToValue(0)
+	})
+	_ = vm.Set("matches", func(call goja.FunctionCall) goja.Value {
+		v, ok := arg(call, 0)
+		pattern := call.Argument(1).String()
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			panic(vm.NewTypeError("matches: invalid regular expression %q: %v", pattern, err))
+		}
+		if !ok {
+			return vm.ToValue(false)
+		}
+		s, isString := v.(string)
+		if !isString {
+			s = strings.Trim(conditionLiteral(v), `"`)
+		}
+		return vm.ToValue(re.MatchString(s))
+	})
+	_ = vm.Set("daysBetween", func(call goja.FunctionCall) goja.Value {
+		a, okA := arg(call, 0)
+		b, okB := arg(call, 1)
+		if !okA || !okB {
+			return vm.ToValue(goja.NaN())
+		}
+		from, err := conditionTime(a)
+		if err != nil {
+			panic(vm.NewTypeError("daysBetween: %v", err))
+		}
+		to, err := conditionTime(b)
+		if err != nil {
+			panic(vm.NewTypeError("daysBetween: %v", err))
+		}
+		return vm.ToValue(to.Sub(from).Hours() / 24)
+	})
+}
+
+// conditionTime parses a daysBetween argument: "now", an RFC 3339 timestamp,
+// a YYYY-MM-DD date, or epoch milliseconds.
+func conditionTime(v interface{}) (time.Time, error) {
+	switch val := v.(type) {
+	case string:
+		if val == "now" {
+			return time.Now(), nil
+		}
+		if t, err := time.Parse(time.RFC3339Nano, val); err == nil {
+			return t, nil
+		}
+		if t, err := time.Parse("2006-01-02", val); err == nil {
+			return t, nil
+		}
+		return time.Time{}, fmt.Errorf("%q is not a date (want RFC 3339, YYYY-MM-DD or \"now\")", val)
+	case int64:
+		return time.UnixMilli(val), nil
+	case float64:
+		return time.UnixMilli(int64(val)), nil
+	}
+	return time.Time{}, fmt.Errorf("%v is not a date", v)
+}
+
+// identBefore returns the identifier that ends right before expr[i], or "".
+func identBefore(expr string, i int) string {
+	j := i
+	for j > 0 && (isConditionIdent(expr[j-1])) {
+		j--
+	}
+	return expr[j:i]
+}
+
+func isConditionIdent(c byte) bool {
+	return c == '_' || c == '$' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// isWholeArgument reports whether expr[start:end] is a complete call
+// argument: preceded by "(" or "," and followed by "," or ")".
+func isWholeArgument(expr string, start, end int) bool {
+	before := strings.TrimRight(expr[:start], " \t\n")
+	after := strings.TrimLeft(expr[end:], " \t\n")
+	return (strings.HasSuffix(before, "(") || strings.HasSuffix(before, ",")) &&
+		(strings.HasPrefix(after, ",") || strings.HasPrefix(after, ")"))
+}
+
+// closingQuoteEscaped returns the index just past the string literal starting
+// at start, honouring backslash escapes.
+func closingQuoteEscaped(s string, start int) int {
+	q := s[start]
+	for i := start + 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case q:
+			return i + 1
+		}
+	}
+	return len(s)
+}
@@ -0,0 +1,142 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"flowjs-works/engine/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func conditionCtx() *models.ExecutionContext {
+	ctx := models.NewExecutionContext("exec-1")
+	ctx.SetTriggerData(map[string]interface{}{
+		"body": map[string]interface{}{
+			"email":      "ana@example.com",
+			"items":      []interface{}{"a", "b", "c"},
+			"created_at": "2024-01-01T00:00:00Z",
+			"due":        "2024-01-31",
+			"note":       nil,
+		},
+	})
+	return ctx
+}
+
+func TestEvaluateCondition_Helpers(t *testing.T) {
+	ctx := conditionCtx()
+	for _, expr := range []string{
+		`exists($.trigger.body.email)`,
+		`!exists($.trigger.body.phone)`,
+		`!exists($.trigger.body.note)`,
+		`len($.trigger.body.items) === 3`,
+		`len($.trigger.body.email) > 5 && len($.trigger.body.phone) === 0`,
+		`matches($.trigger.body.email, "@example\\.com$")`,
+		`!matches($.trigger.body.phone, ".*")`,
+		`daysBetween($.trigger.body.created_at, $.trigger.body.due) === 30`,
+		`daysBetween("2024-01-01", "now") > 30`,
+		`exists("$.trigger.body.email")`,
+		`$.trigger.body.email === "ana@example.com"`,
+		`"$.trigger.body.email".length === 20`,
+		`$.trigger.body.items[-1] === "c"`,
+		`len($.trigger.body.items[1:]) === 2`,
+		`len($.trigger.body.items[*]) === 3`,
+		`exists("$.trigger.body.items[?(@ == 'b')]")`,
+		`!exists("$.trigger.body.items[?(@ == 'z')]")`,
+	} {
+		ok, err := evaluateCondition(expr, ctx, false)
+		require.NoError(t, err, expr)
+		assert.True(t, ok, expr)
+	}
+}
+
+// TestEvaluateCondition_UndefinedPaths verifies that an undefined path makes
+// the condition false by default and fails it in strict mode, except in exists.
+func TestEvaluateCondition_UndefinedPaths(t *testing.T) {
+	ctx := conditionCtx()
+
+	ok, err := evaluateCondition(`$.trigger.body.phone.length > 3`, ctx, false)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	_, err = evaluateCondition(`$.trigger.body.phone === "1"`, ctx, true)
+	assert.ErrorContains(t, err, "$.trigger.body.phone is undefined")
+	_, err = evaluateCondition(`len($.trigger.body.phone) > 0`, ctx, true)
+	assert.ErrorContains(t, err, "$.trigger.body.phone is undefined")
+	_, err = evaluateCondition(`$.trigger.body.email ===`, ctx, true)
+	assert.Error(t, err, "syntax errors fail in strict mode")
+
+	ok, err = evaluateCondition(`!exists($.trigger.body.phone)`, ctx, true)
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestConditionTime(t *testing.T) {
+	ts, err := conditionTime("2024-05-01T10:00:00+02:00")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC), ts.UTC())
+	ts, err = conditionTime(float64(0))
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), ts.Unix())
+	_, err = conditionTime("yesterday")
+	assert.Error(t, err)
+}
+
+// TestExecute_StrictConditionFailsExecution verifies on_undefined_path "error".
+func TestExecute_StrictConditionFailsExecution(t *testing.T) {
+	exec := newTestExecutor(t)
+	process := &models.Process{
+		Definition: models.Definition{ID: "p_strict", Version: "1.0.0", Settings: models.ProcessSettings{OnUndefinedPath: models.UndefinedPathError}},
+		Nodes: []models.Node{
+			{ID: "start", Type: "logger", Config: map[string]interface{}{"message": "start"}},
+			{ID: "vip", Type: "logger", Config: map[string]interface{}{"message": "vip"}},
+		},
+		Transitions: []models.Transition{{From: "start", To: "vip", Type: "condition", Condition: "$.trigger.body.tier === 'vip'"}},
+	}
+	_, err := exec.Execute(process, map[string]interface{}{})
+	assert.ErrorContains(t, err, "$.trigger.body.tier is undefined")
+
+	process.Definition.Settings.OnUndefinedPath = ""
+	ctx, err := exec.Execute(process, map[string]interface{}{})
+	require.NoError(t, err)
+	assert.NotContains(t, ctx.Nodes, "vip")
+}
+
+func TestExpressionPaths(t *testing.T) {
+	assert.Equal(t,
+		[]string{"$.nodes.ping.output.status", "$.trigger.body.items"},
+		expressionPaths(`$.nodes.ping.output.status === 200 && len($.trigger.body.items) > 0 && "$.not.a.path" && $.nodes.ping.output.status < 300`))
+}
+
+// TestExecute_AssertNodeRoutesFailures verifies that a failed assert node
+// reports the values it saw and that its error transition runs the alert.
+func TestExecute_AssertNodeRoutesFailures(t *testing.T) {
+	exec := newTestExecutor(t)
+	process := &models.Process{
+		Definition: models.Definition{ID: "p_assert", Version: "1.0.0"},
+		Trigger:    models.Trigger{ID: "trg", Type: "manual"},
+		Nodes: []models.Node{
+			{ID: "check", Type: "assert", Config: map[string]interface{}{"assertions": []interface{}{
+				"$.trigger.body.status === 200",
+				map[string]interface{}{"name": "fast", "expression": "$.trigger.body.latency_ms < 500", "message": "too slow"},
+			}}},
+			{ID: "alert", Type: "logger", InputMapping: map[string]interface{}{"failed": "$.nodes.check.output.failed"}},
+		},
+		Transitions: []models.Transition{{From: "check", To: "alert", Type: "error"}},
+	}
+
+	ctx, err := exec.Execute(process, map[string]interface{}{"body": map[string]interface{}{"status": float64(200), "latency_ms": float64(900)}})
+	require.NoError(t, err)
+	assert.Equal(t, "error", ctx.Nodes["check"]["status"])
+	assert.Equal(t, "success", ctx.Nodes["alert"]["status"])
+	failed, _ := ctx.GetValue("$.nodes.check.output.failed")
+	assert.Equal(t, 1, failed)
+	values, _ := ctx.GetValue("$.nodes.check.output.results[1].values")
+	assert.Equal(t, map[string]interface{}{"$.trigger.body.latency_ms": float64(900)}, values)
+
+	ctx, err = exec.Execute(process, map[string]interface{}{"body": map[string]interface{}{"status": float64(200), "latency_ms": float64(120)}})
+	require.NoError(t, err)
+	assert.Equal(t, "success", ctx.Nodes["check"]["status"])
+	assert.NotContains(t, ctx.Nodes, "alert")
+}
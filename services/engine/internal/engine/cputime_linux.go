@@ -0,0 +1,18 @@
+//go:build linux
+
+package engine
+
+import (
+	"syscall"
+	"time"
+)
+
+// threadCPUTime returns the user and system CPU time consumed so far by the
+// calling OS thread. The caller must be locked to its thread.
+func threadCPUTime() (time.Duration, bool) {
+	var ru syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_THREAD, &ru); err != nil {
+		return 0, false
+	}
+	return time.Duration(ru.Utime.Nano() + ru.Stime.Nano()), true
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package engine
+
+import "time"
+
+// threadCPUTime is not available on this platform; node CPU time is reported
+// as zero.
+func threadCPUTime() (time.Duration, bool) {
+	return 0, false
+}
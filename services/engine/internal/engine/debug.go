@@ -0,0 +1,337 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"flowjs-works/engine/internal/models"
+	"flowjs-works/engine/internal/tenant"
+)
+
+// MaxDebugSessions caps the debug sessions open at the same time.
+const MaxDebugSessions = 32
+
+// DebugIdleTimeout is how long a debug session waits for a command before it
+// is aborted and forgotten.
+const DebugIdleTimeout = 10 * time.Minute
+
+// ErrDebugAborted ends an execution whose debug session was aborted.
+var ErrDebugAborted = errors.New("debug session aborted")
+
+// ErrDebugNotPaused is returned for a command that needs a paused session.
+var ErrDebugNotPaused = errors.New("debug session is not paused")
+
+// ErrTooManyDebugSessions is returned by StartDebug beyond MaxDebugSessions.
+var ErrTooManyDebugSessions = errors.New("too many debug sessions")
+
+// Statuses of a DebugState.
+const (
+	DebugRunning   = "running"
+	DebugPaused    = "paused"
+	DebugCompleted = "completed"
+	DebugFailed    = "failed"
+	DebugAborted   = "aborted"
+)
+
+// DebugState is where a debug session stands.
+type DebugState struct {
+	SessionID string `json:"session_id"`
+	Status    string `json:"status"`
+	// At is "before" when paused on a breakpoint before NodeID runs, or
+	// "after" when paused after NodeID ran and before its transitions are
+	// followed.
+	At     string `json:"at,omitempty"`
+	NodeID string `json:"node_id,omitempty"`
+	// Context is the execution context when the state was reached.
+	Context json.RawMessage `json:"context,omitempty"`
+	Error   string          `json:"error,omitempty"`
+}
+
+// DebugSession is an execution run one step at a time. The execution pauses
+// after every node while stepping, and before the nodes in its breakpoints;
+// while paused its context can be read and modified.
+type DebugSession struct {
+	ID        string
+	Workspace string
+
+	exec     *ProcessExecutor
+	cmd      sync.Mutex // serialises commands
+	notify   chan struct{}
+	resume   chan struct{}
+	stop     sync.Once
+	stopping chan struct{}
+	idle     *time.Timer
+
+	mu          sync.Mutex // guards the fields below
+	state       DebugState
+	stepping    bool
+	breakpoints map[string]bool
+	ctx         *models.ExecutionContext // set while paused
+}
+
+// StartDebug starts executing process as a debug session and returns once
+// it first pauses or ends. Without breakpoints it pauses after the first
+// node; with them it runs to the first breakpoint.
+func (e *ProcessExecutor) StartDebug(ctx context.Context, process *models.Process, triggerData map[string]interface{}, breakpoints []string) (*DebugSession, error) {
+	if e.debugCount.Add(1) > MaxDebugSessions {
+		e.debugCount.Add(-1)
+		return nil, ErrTooManyDebugSessions
+	}
+	s := &DebugSession{
+		ID:        uuid.New().String(),
+		Workspace: tenant.Normalize(process.Definition.Workspace),
+		exec:      e,
+		notify:    make(chan struct{}, 1),
+		resume:    make(chan struct{}),
+		stopping:  make(chan struct{}),
+		stepping:  len(breakpoints) == 0,
+	}
+	s.state = DebugState{SessionID: s.ID, Status: DebugRunning}
+	s.setBreakpoints(breakpoints)
+	s.idle = time.AfterFunc(DebugIdleTimeout, func() { e.AbortDebug(s.ID) })
+	e.debugSessions.Store(s.ID, s)
+
+	go func() {
+		execCtx, err := e.execute(context.Background(), s.ID, process, triggerData, "")
+		s.finish(execCtx, err)
+	}()
+	s.wait(ctx)
+	return s, nil
+}
+
+// DebugSession returns the open debug session id, or nil.
+func (e *ProcessExecutor) DebugSession(id string) *DebugSession {
+	if v, ok := e.debugSessions.Load(id); ok {
+		return v.(*DebugSession)
+	}
+	return nil
+}
+
+// AbortDebug stops debug session id, failing its execution with
+// ErrDebugAborted at the next pause, and forgets it.
+func (e *ProcessExecutor) AbortDebug(id string) {
+	v, ok := e.debugSessions.LoadAndDelete(id)
+	if !ok {
+		return
+	}
+	e.debugCount.Add(-1)
+	s := v.(*DebugSession)
+	s.idle.Stop()
+	s.stop.Do(func() { close(s.stopping) })
+}
+
+// debugSession returns the debug session running executionID, or nil.
+func (e *ProcessExecutor) debugSession(executionID string) *DebugSession {
+	if e.debugCount.Load() == 0 {
+		return nil
+	}
+	return e.DebugSession(executionID)
+}
+
+// State returns the current state of s.
+func (s *DebugSession) State() DebugState {
+	s.touch()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state
+}
+
+// Step resumes a paused session until the next node has run.
+func (s *DebugSession) Step(ctx context.Context) (DebugState, error) {
+	return s.resumeWith(ctx, true)
+}
+
+// Continue resumes a paused session until a breakpoint or the end.
+func (s *DebugSession) Continue(ctx context.Context) (DebugState, error) {
+	return s.resumeWith(ctx, false)
+}
+
+// SetBreakpoints replaces the nodes s pauses before.
+func (s *DebugSession) SetBreakpoints(nodeIDs []string) DebugState {
+	s.touch()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.setBreakpoints(nodeIDs)
+	return s.state
+}
+
+// Modify sets the values of a paused session's context, keyed by JSONPath
+// (see models.ExecutionContext.SetValue). Nothing is set when a path is
+// invalid.
+func (s *DebugSession) Modify(values map[string]interface{}) (DebugState, error) {
+	s.cmd.Lock()
+	defer s.cmd.Unlock()
+	s.touch()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state.Status != DebugPaused {
+		return s.state, ErrDebugNotPaused
+	}
+	// Apply to a copy first so an invalid path leaves the context unchanged.
+	trial, err := copyContext(s.ctx)
+	if err != nil {
+		return s.state, err
+	}
+	for path, v := range values {
+		if err := trial.SetValue(path, v); err != nil {
+			return s.state, err
+		}
+	}
+	for path, v := range values {
+		_ = s.ctx.SetValue(path, v)
+	}
+	s.state.Context = marshalContext(s.ctx)
+	return s.state, nil
+}
+
+func (s *DebugSession) resumeWith(ctx context.Context, step bool) (DebugState, error) {
+	s.cmd.Lock()
+	defer s.cmd.Unlock()
+	s.touch()
+	s.mu.Lock()
+	if s.state.Status != DebugPaused {
+		defer s.mu.Unlock()
+		return s.state, ErrDebugNotPaused
+	}
+	s.stepping = step
+	s.state = DebugState{SessionID: s.ID, Status: DebugRunning}
+	s.ctx = nil
+	s.mu.Unlock()
+
+	// Drop a signal left by a pause nobody waited for.
+	select {
+	case <-s.notify:
+	default:
+	}
+
+	select {
+	case s.resume <- struct{}{}:
+	case <-s.stopping:
+		return s.State(), ErrDebugAborted
+	}
+	s.wait(ctx)
+	return s.State(), nil
+}
+
+// wait blocks until the execution pauses or ends, or ctx is done.
+func (s *DebugSession) wait(ctx context.Context) {
+	select {
+	case <-s.notify:
+	case <-ctx.Done():
+	}
+}
+
+func (s *DebugSession) touch() {
+	s.idle.Reset(DebugIdleTimeout)
+}
+
+func (s *DebugSession) setBreakpoints(nodeIDs []string) {
+	s.breakpoints = make(map[string]bool, len(nodeIDs))
+	for _, id := range nodeIDs {
+		s.breakpoints[id] = true
+	}
+}
+
+// before is called by the executor before nodeID runs.
+func (s *DebugSession) before(nodeID string, ctx *models.ExecutionContext) error {
+	s.mu.Lock()
+	hit := s.breakpoints[nodeID]
+	s.mu.Unlock()
+	if !hit {
+		return s.aborted()
+	}
+	return s.pause("before", nodeID, ctx)
+}
+
+// after is called by the executor after nodeID ran.
+func (s *DebugSession) after(nodeID string, ctx *models.ExecutionContext) error {
+	s.mu.Lock()
+	stepping := s.stepping
+	s.mu.Unlock()
+	if !stepping {
+		return s.aborted()
+	}
+	return s.pause("after", nodeID, ctx)
+}
+
+func (s *DebugSession) aborted() error {
+	select {
+	case <-s.stopping:
+		return ErrDebugAborted
+	default:
+		return nil
+	}
+}
+
+// pause publishes the paused state and blocks the execution until a command
+// resumes it. Time spent paused does not count against the process timeout.
+func (s *DebugSession) pause(at, nodeID string, ctx *models.ExecutionContext) error {
+	if err := s.aborted(); err != nil {
+		return err
+	}
+	pausedAt := time.Now()
+	s.mu.Lock()
+	s.ctx = ctx
+	s.state = DebugState{SessionID: s.ID, Status: DebugPaused, At: at, NodeID: nodeID, Context: marshalContext(ctx)}
+	s.mu.Unlock()
+	s.signal()
+
+	select {
+	case <-s.resume:
+	case <-s.stopping:
+		return ErrDebugAborted
+	}
+	if !ctx.Deadline.IsZero() {
+		ctx.Deadline = ctx.Deadline.Add(time.Since(pausedAt))
+	}
+	return nil
+}
+
+// finish records the end of the execution.
+func (s *DebugSession) finish(ctx *models.ExecutionContext, err error) {
+	st := DebugState{SessionID: s.ID, Status: DebugCompleted, Context: marshalContext(ctx)}
+	switch {
+	case errors.Is(err, ErrDebugAborted):
+		st.Status, st.Error = DebugAborted, err.Error()
+	case err != nil:
+		st.Status, st.Error = DebugFailed, err.Error()
+	}
+	s.mu.Lock()
+	s.state = st
+	s.ctx = nil
+	s.mu.Unlock()
+	s.signal()
+}
+
+func (s *DebugSession) signal() {
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+func marshalContext(ctx *models.ExecutionContext) json.RawMessage {
+	if ctx == nil {
+		return nil
+	}
+	data, err := json.Marshal(ctx)
+	if err != nil {
+		return json.RawMessage(fmt.Sprintf(`{"error":%q}`, err.Error()))
+	}
+	return data
+}
+
+func copyContext(ctx *models.ExecutionContext) (*models.ExecutionContext, error) {
+	out := models.NewExecutionContext(ctx.ExecutionID)
+	data, err := json.Marshal(ctx)
+	if err == nil {
+		err = json.Unmarshal(data, out)
+	}
+	return out, err
+}
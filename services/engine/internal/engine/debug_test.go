@@ -0,0 +1,93 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"flowjs-works/engine/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// debugContext decodes the context of st.
+func debugContext(t *testing.T, st DebugState) *models.ExecutionContext {
+	t.Helper()
+	ctx := models.NewExecutionContext("")
+	require.NoError(t, json.Unmarshal(st.Context, ctx))
+	return ctx
+}
+
+// TestDebug_StepModifyContinue verifies that a session pauses after each
+// node and that a modified output decides the transition taken next.
+func TestDebug_StepModifyContinue(t *testing.T) {
+	exec := newTestExecutor(t)
+	bg := context.Background()
+	s, err := exec.StartDebug(bg, priorityProcess(""), map[string]interface{}{}, nil)
+	require.NoError(t, err)
+	defer exec.AbortDebug(s.ID)
+
+	st := s.State()
+	assert.Equal(t, DebugState{SessionID: s.ID, Status: DebugPaused, At: "after", NodeID: "script_node", Context: st.Context}, st)
+	v, err := debugContext(t, st).GetValue("$.nodes.script_node.output.value")
+	require.NoError(t, err)
+	assert.EqualValues(t, 42, v)
+
+	st, err = s.Modify(map[string]interface{}{"$.nodes.script_node.output.value": 1})
+	require.NoError(t, err)
+	v, _ = debugContext(t, st).GetValue("$.nodes.script_node.output.value")
+	assert.EqualValues(t, 1, v)
+
+	_, err = s.Modify(map[string]interface{}{"$.nodes.script_node.output.value": 5, "$.items[0]": 1})
+	assert.Error(t, err)
+	v, _ = debugContext(t, s.State()).GetValue("$.nodes.script_node.output.value")
+	assert.EqualValues(t, 1, v, "an invalid path leaves the context unchanged")
+
+	st, err = s.Step(bg)
+	require.NoError(t, err)
+	assert.Equal(t, DebugPaused, st.Status)
+	assert.Equal(t, "fallback", st.NodeID, "no condition matches the modified value")
+
+	st, err = s.Continue(bg)
+	require.NoError(t, err)
+	assert.Equal(t, DebugCompleted, st.Status)
+	_, err = s.Step(bg)
+	assert.ErrorIs(t, err, ErrDebugNotPaused)
+}
+
+// TestDebug_Breakpoints verifies that a session with breakpoints runs to the
+// first one and pauses before the node.
+func TestDebug_Breakpoints(t *testing.T) {
+	exec := newTestExecutor(t)
+	s, err := exec.StartDebug(context.Background(), priorityProcess(""), map[string]interface{}{}, []string{"high"})
+	require.NoError(t, err)
+	defer exec.AbortDebug(s.ID)
+
+	st := s.State()
+	assert.Equal(t, DebugPaused, st.Status)
+	assert.Equal(t, "before", st.At)
+	assert.Equal(t, "high", st.NodeID)
+	_, err = debugContext(t, st).GetValue("$.nodes.high.status")
+	assert.Error(t, err, "the node has not run yet")
+
+	st, err = s.Continue(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, DebugCompleted, st.Status)
+}
+
+// TestDebug_Abort verifies that aborting a paused session fails its
+// execution without running the remaining nodes.
+func TestDebug_Abort(t *testing.T) {
+	exec := newTestExecutor(t)
+	s, err := exec.StartDebug(context.Background(), priorityProcess(""), map[string]interface{}{}, nil)
+	require.NoError(t, err)
+
+	exec.AbortDebug(s.ID)
+	assert.Nil(t, exec.DebugSession(s.ID))
+	require.Eventually(t, func() bool { return s.State().Status == DebugAborted }, time.Second, 5*time.Millisecond)
+	_, err = debugContext(t, s.State()).GetValue("$.nodes.high.status")
+	assert.Error(t, err)
+	assert.Zero(t, exec.debugCount.Load())
+}
@@ -3,16 +3,23 @@ package engine
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
-	"regexp"
+	"log/slog"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"flowjs-works/engine/internal/activities"
+	"flowjs-works/engine/internal/logging"
 	"flowjs-works/engine/internal/models"
+	"flowjs-works/engine/internal/notify"
+	"flowjs-works/engine/internal/schema"
 	"flowjs-works/engine/internal/secrets"
+	"flowjs-works/engine/internal/tenant"
 
-	"github.com/dop251/goja"
 	"github.com/google/uuid"
 	nats "github.com/nats-io/nats.go"
 )
@@ -20,12 +27,71 @@ import (
 // retryBaseInterval is the delay between consecutive retry attempts for a node execution.
 const retryBaseInterval = 2 * time.Second
 
+// snapshotTimeout bounds saving the final context of one execution.
+const snapshotTimeout = 5 * time.Second
+
+// ErrNothingToRetry is returned by RetryExecution when the prior context has
+// no node whose error stopped the execution.
+var ErrNothingToRetry = errors.New("execution has no failed node to retry")
+
+// ErrProcessTimeout is returned when a node would start after the process
+// timeout (settings.timeout) has run out.
+var ErrProcessTimeout = errors.New("process timeout exceeded")
+
+// ErrSnapshotIncomplete is returned by RetryExecution when the prior context
+// was saved with "minimal" persistence and so lacks the node outputs a retry
+// resumes from.
+var ErrSnapshotIncomplete = errors.New("execution snapshot has no node outputs (persistence minimal)")
+
+// SnapshotSaver persists the final ExecutionContext of an execution so it can
+// be inspected later. store.SnapshotStore implements it on the config DB.
+type SnapshotSaver interface {
+	Save(ctx context.Context, execCtx *models.ExecutionContext) error
+}
+
 // ProcessExecutor executes a workflow process
 type ProcessExecutor struct {
 	activityRegistry *activities.ActivityRegistry
 	natsConn         *nats.Conn
 	auditEnabled     bool
+	auditBuf         *auditBuffer
 	secretResolver   secrets.SecretResolver
+	snapshots        SnapshotSaver
+	breakers         *circuitBreakers
+	schemas          schema.Source
+	profiles         ProfileSource
+	runs             *runTracker
+	notifier         Notifier
+	versions         VersionRecorder
+	// engineID identifies this engine instance in audit events.
+	engineID string
+
+	batcher *activities.BatcherActivity
+	// batchProcesses holds the latest definition of every process that ran a
+	// batcher node, keyed by workspace and process id, so batches released by
+	// max_wait_ms can continue through the downstream nodes.
+	batchProcesses sync.Map
+
+	// auditHolds holds the events of executions whose trigger samples or
+	// suppresses successful runs, keyed by execution id (see holdAudit).
+	auditHolds      sync.Map
+	auditSuppressed atomic.Uint64
+	sampleRand      func() float64
+
+	// debugSessions holds the open debug sessions by execution id; debugCount
+	// counts them so executions without one skip the lookup.
+	debugSessions sync.Map
+	debugCount    atomic.Int64
+
+	// faults holds the fault injectors of executions by execution id;
+	// faultHeader honours the X-Fault-Injection trigger header.
+	faults      sync.Map
+	faultHeader bool
+
+	// onTransition, when set, is told of every transition the executor
+	// follows, with the node it leads to; simulations measure branch
+	// coverage with it.
+	onTransition func(ctx *models.ExecutionContext, t models.Transition, to string)
 }
 
 // NewProcessExecutor creates a new process executor
@@ -34,30 +100,128 @@ func NewProcessExecutor(natsURL string) (*ProcessExecutor, error) {
 		activityRegistry: activities.NewActivityRegistry(),
 		auditEnabled:     natsURL != "",
 		secretResolver:   &secrets.NoopResolver{},
+		batcher:          activities.NewBatcherActivity(),
+		breakers:         newCircuitBreakers(),
+		sampleRand:       defaultSampleRand,
 	}
+	executor.engineID, _ = os.Hostname()
+	executor.activityRegistry.Register(executor.batcher)
+	executor.batcher.SetReleaseHandler(executor.releaseBatch)
+	executor.activityRegistry.Register(activities.NewAssertActivity(evaluateAssertion))
 
 	// Connect to NATS if URL is provided
 	if executor.auditEnabled {
-		nc, err := nats.Connect(natsURL)
+		// Keep reconnecting for as long as the engine runs; events published
+		// while NATS is away are buffered by auditBuf.
+		nc, err := nats.Connect(natsURL, nats.MaxReconnects(-1))
 		if err != nil {
-			log.Printf("Warning: Failed to connect to NATS at %s: %v. Audit logging disabled.", natsURL, err)
+			slog.Warn("failed to connect to NATS; audit logging disabled", "url", natsURL, logging.KeyError, err)
 			executor.auditEnabled = false
 		} else {
 			executor.natsConn = nc
-			log.Printf("Connected to NATS at %s for audit logging", natsURL)
+			executor.auditBuf = newAuditBuffer(nc.Publish, AuditBufferConfig{})
+			slog.Info("connected to NATS for audit logging", "url", natsURL)
 		}
 	}
 
 	return executor, nil
 }
 
-// Close closes the NATS connection
+// Close releases pending batches and closes the NATS connection
 func (e *ProcessExecutor) Close() {
+	e.batcher.Flush()
+	if e.auditBuf != nil {
+		e.auditBuf.Close()
+	}
 	if e.natsConn != nil {
 		e.natsConn.Close()
 	}
 }
 
+// AuditConnection reports the state of the NATS connection audit events are
+// published on: "disabled" without NATS, otherwise the connection status
+// ("CONNECTED", "RECONNECTING", ...).
+func (e *ProcessExecutor) AuditConnection() string {
+	if !e.auditEnabled || e.natsConn == nil {
+		return "disabled"
+	}
+	return e.natsConn.Status().String()
+}
+
+// RegisterActivity makes activity available as the node type activity.Name(),
+// replacing any activity already registered under that name.
+func (e *ProcessExecutor) RegisterActivity(activity activities.Activity) {
+	e.activityRegistry.Register(activity)
+}
+
+// Activities returns the registered node types, sorted.
+func (e *ProcessExecutor) Activities() []string {
+	return e.activityRegistry.List()
+}
+
+// NodeCatalog returns the registered node types with the config fields each
+// decodes, sorted by type.
+func (e *ProcessExecutor) NodeCatalog() []activities.NodeType {
+	return e.activityRegistry.Catalog()
+}
+
+// SetAuditBuffer replaces the default buffer for audit events that fail to
+// publish. Call it before the first execution; it is a no-op while audit
+// logging is disabled.
+func (e *ProcessExecutor) SetAuditBuffer(cfg AuditBufferConfig) {
+	if e.auditBuf == nil {
+		return
+	}
+	e.auditBuf.Close()
+	e.auditBuf = newAuditBuffer(e.natsConn.Publish, cfg)
+}
+
+// AuditStats reports how many audit events were published, retried,
+// dropped and suppressed by trigger sampling. It is zero while audit logging
+// is disabled.
+func (e *ProcessExecutor) AuditStats() AuditStats {
+	if e.auditBuf == nil {
+		return AuditStats{}
+	}
+	s := e.auditBuf.Stats()
+	s.Suppressed = e.auditSuppressed.Load()
+	return s
+}
+
+// SetSnippetSource lets code nodes import shared script snippets from s.
+func (e *ProcessExecutor) SetSnippetSource(s activities.SnippetSource) {
+	e.activityRegistry.Register(activities.NewCodeActivity(s))
+}
+
+// SetDedupeStore makes dedupe nodes record hashes in s instead of process memory.
+func (e *ProcessExecutor) SetDedupeStore(s activities.DedupeStore) {
+	e.activityRegistry.Register(activities.NewDedupeActivity(s))
+}
+
+// SetCallbackBaseURL makes callback_await nodes hand out callback URLs under
+// baseURL, the address external systems reach the engine at.
+func (e *ProcessExecutor) SetCallbackBaseURL(baseURL string) {
+	e.activityRegistry.Register(activities.NewCallbackAwaitActivity(baseURL))
+}
+
+// SetEngineID sets the identifier of this engine instance recorded as
+// engine_id with every audit event. It defaults to the hostname.
+func (e *ProcessExecutor) SetEngineID(id string) {
+	e.engineID = id
+}
+
+// EnableTestMode enables test-only node types such as mock_http, which
+// starts an ephemeral HTTP server for downstream nodes to call.
+func (e *ProcessExecutor) EnableTestMode() {
+	e.activityRegistry.Register(activities.NewMockHTTPActivity(true))
+}
+
+// SetSnapshotSaver makes the executor persist the final context of every
+// execution whose process persistence is not "none".
+func (e *ProcessExecutor) SetSnapshotSaver(s SnapshotSaver) {
+	e.snapshots = s
+}
+
 // SetSecretResolver replaces the default NoopResolver with a real implementation.
 // Call this after connecting to the config DB.
 func (e *ProcessExecutor) SetSecretResolver(r secrets.SecretResolver) {
@@ -74,19 +238,57 @@ func (e *ProcessExecutor) ExecuteFromJSON(jsonData []byte, triggerData map[strin
 }
 
 // Execute executes a process with the given trigger data
-func (e *ProcessExecutor) Execute(process *models.Process, triggerData map[string]interface{}) (ctx *models.ExecutionContext, err error) {
-	executionID := uuid.New().String()
+func (e *ProcessExecutor) Execute(process *models.Process, triggerData map[string]interface{}) (*models.ExecutionContext, error) {
+	return e.execute(context.Background(), uuid.New().String(), process, triggerData, "")
+}
+
+// ExecuteContext executes a process for a caller waiting on it, such as an
+// HTTP request: the execution is bound to caller (see
+// models.ExecutionContext.Bind), and fails with
+// models.ErrExecutionCancelled once caller is done.
+func (e *ProcessExecutor) ExecuteContext(caller context.Context, process *models.Process, triggerData map[string]interface{}) (*models.ExecutionContext, error) {
+	return e.execute(caller, uuid.New().String(), process, triggerData, "")
+}
+
+// ReplayExecution re-runs process with new trigger data as a child of
+// parentExecutionID, the execution being replayed, bound to caller like
+// ExecuteContext.
+func (e *ProcessExecutor) ReplayExecution(caller context.Context, process *models.Process, triggerData map[string]interface{}, parentExecutionID string) (*models.ExecutionContext, error) {
+	return e.execute(caller, uuid.New().String(), process, triggerData, parentExecutionID)
+}
+
+func (e *ProcessExecutor) execute(caller context.Context, executionID string, process *models.Process, triggerData map[string]interface{}, parentExecutionID string) (ctx *models.ExecutionContext, err error) {
 	processID := process.Definition.ID
-	log.Printf("Starting execution %s for process %s (v%s)", executionID, processID, process.Definition.Version)
 
 	ctx = models.NewExecutionContext(executionID)
 	ctx.ProcessID = processID
+	ctx.ParentExecutionID = parentExecutionID
+	ctx.Workspace = tenant.Normalize(process.Definition.Workspace)
+	ctx.Persistence = process.Definition.Settings.Persistence
+	ctx.SecretsAllowed = process.Definition.Settings.SecretsAllowed
+	ctx.Env = process.EnvironmentVariables()
+	ctx.SetTimeout(process.Definition.Settings.Timeout)
+	ctx.Bind(caller)
 	ctx.SetTriggerData(triggerData)
+	e.pinVersion(ctx, process)
+	logger := logging.ForExecution(ctx)
+	logger.Info("execution started", "version", process.Definition.Version, "revision", ctx.ProcessRevision)
+	e.rememberBatchProcess(process)
+
+	// A trigger may sample or suppress the audit of successful runs; their
+	// events are held and released after the terminal event below.
+	if e.holdAudit(process, executionID) {
+		defer e.releaseAudit(executionID)
+	}
 
 	// Emit execution-start audit event so there is always at least one record
 	// per triggered execution, even when no nodes run.
-	e.sendAuditLog(executionID, processID, processID, "process", "started",
-		map[string]interface{}{"trigger": triggerData}, nil, "")
+	e.auditExecution(ctx, processID, "process", "started",
+		map[string]interface{}{"trigger": auditPayload(ctx.Persistence, triggerData)}, nil, "", nil)
+	e.recordRun(ctx)
+	if e.startFaults(process, ctx) {
+		defer e.stopFaults(ctx.ExecutionID)
+	}
 
 	// Emit terminal audit event (COMPLETED or FAILED) when the function returns.
 	defer func() {
@@ -96,9 +298,15 @@ func (e *ProcessExecutor) Execute(process *models.Process, triggerData map[strin
 			status = "failed"
 			errMsg = err.Error()
 		}
-		e.sendAuditLog(executionID, processID, processID, "process", status,
-			map[string]interface{}{"trigger": triggerData}, nil, errMsg)
+		e.sendTerminalAuditLog(ctx, status, map[string]interface{}{"trigger": auditPayload(ctx.Persistence, triggerData)}, errMsg)
+		e.saveSnapshot(process, ctx)
+		activities.ReleaseExecution(executionID)
 	}()
+	defer func() { err = cancelledErr(ctx, err) }()
+
+	if err = e.validateTrigger(process, ctx, triggerData); err != nil {
+		return ctx, err
+	}
 
 	// Sequential mode: backward-compatible when no transitions and no Next fields
 	if isSequentialMode(process) {
@@ -107,8 +315,12 @@ func (e *ProcessExecutor) Execute(process *models.Process, triggerData map[strin
 			if err = e.executeNode(&nodeCopy, ctx); err != nil {
 				return ctx, fmt.Errorf("node %s failed: %w", node.ID, err)
 			}
+			if haltsFlow(&nodeCopy, ctx) {
+				logger.Info("node halted the flow; skipping remaining nodes", logging.KeyNodeID, node.ID)
+				break
+			}
 		}
-		log.Printf("Execution %s completed successfully", executionID)
+		logger.Info("execution completed")
 		return ctx, nil
 	}
 
@@ -123,55 +335,102 @@ func (e *ProcessExecutor) Execute(process *models.Process, triggerData map[strin
 	// From is a real node (not a trigger). A trigger→node transition must NOT
 	// disqualify that node from being treated as a start node.
 	incomingFromNode := make(map[string]bool)
+	fromTrigger := make(map[string]bool)
 	for _, t := range process.Transitions {
 		transMap[t.From] = append(transMap[t.From], t)
-		if _, fromIsNode := nodeMap[t.From]; fromIsNode {
-			incomingFromNode[t.To] = true
+		for _, to := range t.Destinations() {
+			if _, fromIsNode := nodeMap[t.From]; fromIsNode {
+				incomingFromNode[to] = true
+			} else {
+				fromTrigger[to] = true
+			}
 		}
 	}
 
 	// Start nodes: real nodes with no incoming edge from another real node.
+	// When loops are allowed, trigger targets are start nodes too, since a
+	// loop may lead back to them.
+	loops := process.Definition.Settings.MaxNodeVisits > 1
 	var startNodes []string
 	for _, node := range process.Nodes {
-		if !incomingFromNode[node.ID] {
+		if !incomingFromNode[node.ID] || (loops && fromTrigger[node.ID]) {
 			startNodes = append(startNodes, node.ID)
 		}
 	}
 
-	visited := make(map[string]bool)
+	w := newWalk(process)
 	for _, startID := range startNodes {
-		if err = e.executeChain(startID, nodeMap, transMap, ctx, visited); err != nil {
+		if err = e.executeChain(startID, nodeMap, transMap, ctx, w); err != nil {
 			return ctx, err
 		}
 	}
 
-	log.Printf("Execution %s completed successfully", executionID)
+	logger.Info("execution completed")
 	return ctx, nil
 }
 
+// cancelledErr reports err as models.ErrExecutionCancelled when the caller
+// of ctx is done: a node cut short by the caller fails with the error of its
+// call, yet the execution was cancelled.
+func cancelledErr(ctx *models.ExecutionContext, err error) error {
+	if cause := ctx.Err(); err != nil && cause != nil && !errors.Is(err, models.ErrExecutionCancelled) {
+		return fmt.Errorf("%w (%w): %w", models.ErrExecutionCancelled, cause, err)
+	}
+	return err
+}
+
 // ExecuteFromNode re-executes the process starting from startNodeID,
 // injecting nodeInput as the pre-resolved input for that node.
 // A new execution_id is generated unless executionIDHint is non-empty.
+// parentExecutionID, when set, is the execution being replayed.
 func (e *ProcessExecutor) ExecuteFromNode(
 	process *models.Process,
 	startNodeID string,
 	nodeInput map[string]interface{},
 	executionIDHint string,
+	parentExecutionID string,
+) (*models.ExecutionContext, error) {
+	return e.ExecuteFromNodeContext(context.Background(), process, startNodeID, nodeInput, executionIDHint, parentExecutionID)
+}
+
+// ExecuteFromNodeContext is ExecuteFromNode bound to caller like
+// ExecuteContext.
+func (e *ProcessExecutor) ExecuteFromNodeContext(
+	caller context.Context,
+	process *models.Process,
+	startNodeID string,
+	nodeInput map[string]interface{},
+	executionIDHint string,
+	parentExecutionID string,
 ) (ctx *models.ExecutionContext, err error) {
 	executionID := executionIDHint
 	if executionID == "" {
 		executionID = uuid.New().String()
 	}
 	processID := process.Definition.ID
-	log.Printf("Starting replay execution %s for process %s from node %s", executionID, processID, startNodeID)
 
 	ctx = models.NewExecutionContext(executionID)
 	ctx.ProcessID = processID
+	ctx.ParentExecutionID = parentExecutionID
+	ctx.Workspace = tenant.Normalize(process.Definition.Workspace)
+	ctx.Persistence = process.Definition.Settings.Persistence
+	ctx.SecretsAllowed = process.Definition.Settings.SecretsAllowed
+	ctx.Env = process.EnvironmentVariables()
+	ctx.SetTimeout(process.Definition.Settings.Timeout)
+	ctx.Bind(caller)
 	ctx.SetTriggerData(map[string]interface{}{})
+	e.pinVersion(ctx, process)
+	logger := logging.ForExecution(ctx).With("replay_from", startNodeID)
+	logger.Info("replay execution started")
+	e.rememberBatchProcess(process)
 
 	// Emit execution-start audit event.
-	e.sendAuditLog(executionID, processID, processID, "process", "started",
-		map[string]interface{}{"replay_from": startNodeID}, nil, "")
+	e.auditExecution(ctx, processID, "process", "started",
+		map[string]interface{}{"replay_from": startNodeID}, nil, "", nil)
+	e.recordRun(ctx)
+	if e.startFaults(process, ctx) {
+		defer e.stopFaults(ctx.ExecutionID)
+	}
 
 	// Emit terminal audit event (REPLAYED or FAILED) when the function returns.
 	defer func() {
@@ -181,9 +440,11 @@ func (e *ProcessExecutor) ExecuteFromNode(
 			status = "failed"
 			errMsg = err.Error()
 		}
-		e.sendAuditLog(executionID, processID, processID, "process", status,
-			map[string]interface{}{"replay_from": startNodeID}, nil, errMsg)
+		e.sendTerminalAuditLog(ctx, status, map[string]interface{}{"replay_from": startNodeID}, errMsg)
+		e.saveSnapshot(process, ctx)
+		activities.ReleaseExecution(executionID)
 	}()
+	defer func() { err = cancelledErr(ctx, err) }()
 
 	// Build nodeMap and transMap.
 	nodeMap := make(map[string]*models.Node, len(process.Nodes))
@@ -201,47 +462,330 @@ func (e *ProcessExecutor) ExecuteFromNode(
 
 	// Follow transitions from the start node (mirroring executeChain routing,
 	// but without re-executing the start node itself).
-	visited := make(map[string]bool)
-	visited[startNodeID] = true
+	w := newWalk(process)
+	w.mark(startNodeID)
 
-	var condTrans, noCondTrans, successTrans []models.Transition
-	// errorT is intentionally discarded: the start node in a replay is injected
+	// Error transitions are not followed: the start node in a replay is injected
 	// with a synthetic "replayed" status, so there is no live error to route.
-	condTrans, noCondTrans, successTrans, _ = classifyTransitions(transMap[startNodeID])
+	if err = e.followFrom(startNodeID, nodeMap, transMap, ctx, w); err != nil {
+		return ctx, err
+	}
+	logger.Info("replay execution completed")
+	return ctx, nil
+}
+
+// RetryExecution starts a new execution of process from the node that made
+// prior fail. Trigger data and the outputs of every node that succeeded in
+// prior are kept, so only the failed node and the nodes after it run again.
+// It returns ErrNothingToRetry when prior did not fail on a node and
+// ErrSnapshotIncomplete when prior holds no node outputs.
+func (e *ProcessExecutor) RetryExecution(process *models.Process, prior *models.ExecutionContext) (ctx *models.ExecutionContext, err error) {
+	if prior.Persistence == models.PersistenceMinimal {
+		return nil, ErrSnapshotIncomplete
+	}
+	failedNodeID := failedNode(process, prior)
+	if failedNodeID == "" {
+		return nil, ErrNothingToRetry
+	}
+	executionID := uuid.New().String()
+	processID := process.Definition.ID
+
+	ctx = models.NewExecutionContext(executionID)
+	ctx.ProcessID = processID
+	ctx.ParentExecutionID = prior.ExecutionID
+	ctx.Workspace = tenant.Normalize(process.Definition.Workspace)
+	ctx.Persistence = process.Definition.Settings.Persistence
+	ctx.SecretsAllowed = process.Definition.Settings.SecretsAllowed
+	ctx.Env = process.EnvironmentVariables()
+	ctx.SetTimeout(process.Definition.Settings.Timeout)
+	ctx.SetTriggerData(prior.Trigger)
+	for id, state := range prior.NodeStates() {
+		if state["status"] == "success" || state["status"] == "replayed" {
+			ctx.SetNodeState(id, state)
+		}
+	}
+	e.pinVersion(ctx, process)
+	logger := logging.ForExecution(ctx).With("retry_of", prior.ExecutionID, "retry_from", failedNodeID)
+	logger.Info("retry execution started")
+	e.rememberBatchProcess(process)
+
+	auditInput := map[string]interface{}{"retry_of": prior.ExecutionID, "retry_from": failedNodeID}
+	e.auditExecution(ctx, processID, "process", "started", auditInput, nil, "", nil)
+	e.recordRun(ctx)
+	if e.startFaults(process, ctx) {
+		defer e.stopFaults(ctx.ExecutionID)
+	}
+	defer func() {
+		status := "replayed"
+		errMsg := ""
+		if err != nil {
+			status = "failed"
+			errMsg = err.Error()
+		}
+		e.sendTerminalAuditLog(ctx, status, auditInput, errMsg)
+		e.saveSnapshot(process, ctx)
+		activities.ReleaseExecution(executionID)
+	}()
+
+	if isSequentialMode(process) {
+		for i := range process.Nodes {
+			if process.Nodes[i].ID != failedNodeID {
+				continue
+			}
+			node := process.Nodes[i]
+			if err = e.executeNode(&node, ctx); err != nil {
+				return ctx, fmt.Errorf("node %s failed: %w", node.ID, err)
+			}
+			if !haltsFlow(&node, ctx) {
+				if err = e.executeSequentialAfter(process, failedNodeID, ctx); err != nil {
+					return ctx, err
+				}
+			}
+			break
+		}
+		logger.Info("retry execution completed")
+		return ctx, nil
+	}
+
+	nodeMap := make(map[string]*models.Node, len(process.Nodes))
+	for i := range process.Nodes {
+		nodeMap[process.Nodes[i].ID] = &process.Nodes[i]
+	}
+	transMap := make(map[string][]models.Transition)
+	for _, t := range process.Transitions {
+		transMap[t.From] = append(transMap[t.From], t)
+	}
+	if err = e.executeChain(failedNodeID, nodeMap, transMap, ctx, newWalk(process)); err != nil {
+		return ctx, err
+	}
+	logger.Info("retry execution completed")
+	return ctx, nil
+}
+
+// failedNode returns the node of process whose error ended prior: a node in
+// "error" or "circuit_open" status with no error transition to handle it. It
+// returns "" when there is none.
+func failedNode(process *models.Process, prior *models.ExecutionContext) string {
+	handled := make(map[string]bool)
+	for _, t := range process.Transitions {
+		if t.Type == "error" {
+			handled[t.From] = true
+		}
+	}
+	for _, node := range process.Nodes {
+		status := prior.NodeStatus(node.ID)
+		if (status == "error" || status == StatusCircuitOpen) && !handled[node.ID] {
+			return node.ID
+		}
+	}
+	return ""
+}
+
+// followFrom routes from startNodeID, whose output is already in ctx, the
+// same way executeChain would after running it.
+func (e *ProcessExecutor) followFrom(startNodeID string, nodeMap map[string]*models.Node, transMap map[string][]models.Transition, ctx *models.ExecutionContext, w *walk) error {
+	condTrans, noCondTrans, successTrans, _ := classifyTransitions(transMap[startNodeID])
+
+	for _, t := range transMap[startNodeID] {
+		if t.Type != models.TransitionDynamic {
+			continue
+		}
+		// A dynamic transition follows the node its expression names; the
+		// nocondition branches run when it names none of its targets.
+		target, ok, err := dynamicTarget(t, ctx, w.strictConditions)
+		if err != nil {
+			return fmt.Errorf("dynamic transition from %s: %w", t.From, err)
+		}
+		if ok {
+			e.follow(ctx, t, target)
+			return e.executeChain(target, nodeMap, transMap, ctx, w)
+		}
+		if len(noCondTrans) == 0 {
+			return fmt.Errorf("dynamic transition from %s: %q is not one of its targets %v", t.From, target, t.Targets)
+		}
+		logging.ForExecution(ctx).Info("dynamic transition matched no target; following nocondition", logging.KeyNodeID, startNodeID, "value", target)
+		for _, nc := range noCondTrans {
+			e.follow(ctx, nc, nc.To)
+			if err := e.executeChain(nc.To, nodeMap, transMap, ctx, w); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
 
 	if len(condTrans) > 0 || len(noCondTrans) > 0 {
-		dispatched := false
+		// Conditions are evaluated by descending priority. Exclusive mode
+		// follows the first match; inclusive mode follows every match. The
+		// nocondition branches run only when nothing matched.
+		models.SortByPriority(condTrans)
+		inclusive := w.process.ConditionMode(nodeMap[startNodeID]) == models.ConditionInclusive
+		matched := false
 		for _, t := range condTrans {
-			if evaluateCondition(t.Condition, ctx) {
-				err = e.executeChain(t.To, nodeMap, transMap, ctx, visited)
-				dispatched = true
-				break
+			ok, err := evaluateCondition(t.Condition, ctx, w.strictConditions)
+			if err != nil {
+				return fmt.Errorf("transition %s → %s: %w", t.From, t.To, err)
 			}
-		}
-		if !dispatched {
-			for _, t := range noCondTrans {
-				if chainErr := e.executeChain(t.To, nodeMap, transMap, ctx, visited); chainErr != nil {
-					err = chainErr
-					break
-				}
+			if !ok {
+				continue
+			}
+			e.follow(ctx, t, t.To)
+			if !inclusive {
+				return e.executeChain(t.To, nodeMap, transMap, ctx, w)
+			}
+			matched = true
+			if err := e.executeChain(t.To, nodeMap, transMap, ctx, w); err != nil {
+				return err
 			}
 		}
-	} else {
-		for _, t := range successTrans {
-			if chainErr := e.executeChain(t.To, nodeMap, transMap, ctx, visited); chainErr != nil {
-				err = chainErr
-				break
+		if matched {
+			return nil
+		}
+		for _, t := range noCondTrans {
+			e.follow(ctx, t, t.To)
+			if err := e.executeChain(t.To, nodeMap, transMap, ctx, w); err != nil {
+				return err
 			}
 		}
+		return nil
 	}
 
-	if err != nil {
+	// Dedupe and batcher nodes can hold back their success branch.
+	if node, ok := nodeMap[startNodeID]; ok && haltsFlow(node, ctx) {
+		logging.ForExecution(ctx).Info("node halted the flow; skipping success transitions", logging.KeyNodeID, startNodeID)
+		return nil
+	}
+	for _, t := range successTrans {
+		e.follow(ctx, t, t.To)
+		if err := e.executeChain(t.To, nodeMap, transMap, ctx, w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// saveSnapshot persists the final context of an execution at the persistence
+// level of the process: whole with "full", node statuses only with "minimal",
+// and not at all with "none". Failures are logged and never fail the
+// execution.
+func (e *ProcessExecutor) saveSnapshot(process *models.Process, ctx *models.ExecutionContext) {
+	if e.snapshots == nil || process.Definition.Settings.Persistence == models.PersistenceNone {
+		return
+	}
+	saveCtx, cancel := context.WithTimeout(tenant.WithWorkspace(context.Background(), ctx.Workspace), snapshotTimeout)
+	defer cancel()
+	if err := e.snapshots.Save(saveCtx, snapshotContext(ctx)); err != nil {
+		logging.ForExecution(ctx).Warn("failed to save execution snapshot", logging.KeyError, err)
+	}
+}
+
+// rememberBatchProcess records process for releaseBatch when it contains a
+// batcher node.
+func (e *ProcessExecutor) rememberBatchProcess(process *models.Process) {
+	for _, node := range process.Nodes {
+		if node.Type == "batcher" {
+			e.batchProcesses.Store(batchProcessKey(tenant.Normalize(process.Definition.Workspace), process.Definition.ID), process)
+			return
+		}
+	}
+}
+
+func batchProcessKey(workspace, processID string) string {
+	return workspace + "\x00" + processID
+}
+
+// releaseBatch runs the nodes downstream of a batcher node for a batch
+// released by max_wait_ms or on shutdown.
+func (e *ProcessExecutor) releaseBatch(r activities.BatchRelease) {
+	v, ok := e.batchProcesses.Load(batchProcessKey(r.Workspace, r.ProcessID))
+	if !ok {
+		slog.Warn("dropping batch of unknown process", logging.KeyWorkspace, r.Workspace, logging.KeyProcessID, r.ProcessID, logging.KeyNodeID, r.NodeID)
+		return
+	}
+	if _, err := e.ExecuteBatch(v.(*models.Process), r.NodeID, r.Output); err != nil {
+		slog.Error("batch execution failed", logging.KeyWorkspace, r.Workspace, logging.KeyProcessID, r.ProcessID, logging.KeyNodeID, r.NodeID, logging.KeyError, err)
+	}
+}
+
+// ExecuteBatch starts a new execution of process whose batcher node
+// batchNodeID produced output, and runs the nodes after it.
+func (e *ProcessExecutor) ExecuteBatch(process *models.Process, batchNodeID string, output map[string]interface{}) (ctx *models.ExecutionContext, err error) {
+	executionID := uuid.New().String()
+	processID := process.Definition.ID
+
+	ctx = models.NewExecutionContext(executionID)
+	ctx.ProcessID = processID
+	ctx.ProcessVersion = process.Definition.Version
+	ctx.Workspace = tenant.Normalize(process.Definition.Workspace)
+	ctx.Persistence = process.Definition.Settings.Persistence
+	ctx.SecretsAllowed = process.Definition.Settings.SecretsAllowed
+	ctx.Env = process.EnvironmentVariables()
+	ctx.SetTimeout(process.Definition.Settings.Timeout)
+	ctx.SetTriggerData(map[string]interface{}{})
+	logger := logging.ForExecution(ctx).With("batch_from", batchNodeID)
+	logger.Info("batch execution started")
+
+	auditInput := map[string]interface{}{"batch_from": batchNodeID}
+	e.auditExecution(ctx, processID, "process", "started", auditInput, nil, "", nil)
+	e.recordRun(ctx)
+	if e.startFaults(process, ctx) {
+		defer e.stopFaults(ctx.ExecutionID)
+	}
+	defer func() {
+		status := "completed"
+		errMsg := ""
+		if err != nil {
+			status = "failed"
+			errMsg = err.Error()
+		}
+		e.sendTerminalAuditLog(ctx, status, auditInput, errMsg)
+		e.saveSnapshot(process, ctx)
+		activities.ReleaseExecution(executionID)
+	}()
+
+	ctx.SetNodeOutput(batchNodeID, output)
+	ctx.SetNodeStatus(batchNodeID, "success")
+
+	if isSequentialMode(process) {
+		return ctx, e.executeSequentialAfter(process, batchNodeID, ctx)
+	}
+
+	nodeMap := make(map[string]*models.Node, len(process.Nodes))
+	for i := range process.Nodes {
+		nodeMap[process.Nodes[i].ID] = &process.Nodes[i]
+	}
+	transMap := make(map[string][]models.Transition)
+	for _, t := range process.Transitions {
+		transMap[t.From] = append(transMap[t.From], t)
+	}
+	w := newWalk(process)
+	w.mark(batchNodeID)
+	if err = e.followFrom(batchNodeID, nodeMap, transMap, ctx, w); err != nil {
 		return ctx, err
 	}
-	log.Printf("Replay execution %s completed successfully", executionID)
+	logger.Info("batch execution completed")
 	return ctx, nil
 }
 
+// executeSequentialAfter runs, in order, the nodes listed after nodeID in a
+// sequential-mode process.
+func (e *ProcessExecutor) executeSequentialAfter(process *models.Process, nodeID string, ctx *models.ExecutionContext) error {
+	started := false
+	for _, node := range process.Nodes {
+		if !started {
+			started = node.ID == nodeID
+			continue
+		}
+		nodeCopy := node
+		if err := e.executeNode(&nodeCopy, ctx); err != nil {
+			return fmt.Errorf("node %s failed: %w", node.ID, err)
+		}
+		if haltsFlow(&nodeCopy, ctx) {
+			break
+		}
+	}
+	return nil
+}
+
 func isSequentialMode(process *models.Process) bool {
 	if len(process.Transitions) > 0 {
 		return false
@@ -254,16 +798,37 @@ func isSequentialMode(process *models.Process) bool {
 	return true
 }
 
-func (e *ProcessExecutor) executeChain(nodeID string, nodeMap map[string]*models.Node, transMap map[string][]models.Transition, ctx *models.ExecutionContext, visited map[string]bool) error {
-	if visited[nodeID] {
-		return fmt.Errorf("cycle detected: node %s", nodeID)
+func (e *ProcessExecutor) executeChain(nodeID string, nodeMap map[string]*models.Node, transMap map[string][]models.Transition, ctx *models.ExecutionContext, w *walk) error {
+	if err := w.enter(nodeID); err != nil {
+		return err
+	}
+	if w.maxVisits > 1 {
+		// Loop conditions can read how often the node ran: $.nodes.<id>.visits.
+		ctx.SetNodeVisits(nodeID, w.visits[nodeID])
 	}
-	visited[nodeID] = true
 
 	node := nodeMap[nodeID]
-	nodeErr := e.executeNode(node, ctx)
 	transitions := transMap[nodeID]
+	var chunkTrans []models.Transition
+	for _, t := range transitions {
+		if t.Type == models.TransitionChunk {
+			chunkTrans = append(chunkTrans, t)
+		}
+	}
+	var nodeErr error
+	if len(chunkTrans) > 0 {
+		prev := ctx.SetChunkHandler(func(output map[string]interface{}) error {
+			return e.runChunk(nodeID, output, chunkTrans, nodeMap, transMap, ctx, w)
+		})
+		nodeErr = e.executeNode(node, ctx)
+		ctx.SetChunkHandler(prev)
+	} else {
+		nodeErr = e.executeNode(node, ctx)
+	}
 
+	if errors.Is(nodeErr, ErrDebugAborted) {
+		return nodeErr
+	}
 	if nodeErr != nil {
 		var errorTrans []models.Transition
 		for _, t := range transitions {
@@ -275,39 +840,108 @@ func (e *ProcessExecutor) executeChain(nodeID string, nodeMap map[string]*models
 			return nodeErr
 		}
 		for _, t := range errorTrans {
-			if err := e.executeChain(t.To, nodeMap, transMap, ctx, visited); err != nil {
+			e.follow(ctx, t, t.To)
+			if err := e.executeChain(t.To, nodeMap, transMap, ctx, w); err != nil {
 				return err
 			}
 		}
 		return nil
 	}
 
-	// Collect transitions by type
-	condTrans, noCondTrans, successTrans, _ := classifyTransitions(transitions)
+	return e.followFrom(nodeID, nodeMap, transMap, ctx, w)
+}
 
-	if len(condTrans) > 0 || len(noCondTrans) > 0 {
-		for _, t := range condTrans {
-			if evaluateCondition(t.Condition, ctx) {
-				return e.executeChain(t.To, nodeMap, transMap, ctx, visited)
-			}
-		}
-		for _, t := range noCondTrans {
-			if err := e.executeChain(t.To, nodeMap, transMap, ctx, visited); err != nil {
-				return err
-			}
+// runChunk runs the chunk transitions of nodeID for one chunk of its output
+// while the node is still running. Every chunk gets a walk of its own, so the
+// nodes of the chunk chain run once per chunk.
+func (e *ProcessExecutor) runChunk(nodeID string, output map[string]interface{}, chunkTrans []models.Transition, nodeMap map[string]*models.Node, transMap map[string][]models.Transition, ctx *models.ExecutionContext, w *walk) error {
+	ctx.SetNodeOutput(nodeID, output)
+	chunkWalk := newWalk(w.process)
+	chunkWalk.mark(nodeID)
+	for _, t := range chunkTrans {
+		e.follow(ctx, t, t.To)
+		if err := e.executeChain(t.To, nodeMap, transMap, ctx, chunkWalk); err != nil {
+			return err
 		}
-		return nil
 	}
+	return nil
+}
 
-	for _, t := range successTrans {
-		if err := e.executeChain(t.To, nodeMap, transMap, ctx, visited); err != nil {
-			return err
+// follow tells the transition observer of e, if any, that t is followed to
+// the node to.
+func (e *ProcessExecutor) follow(ctx *models.ExecutionContext, t models.Transition, to string) {
+	if e.onTransition != nil {
+		e.onTransition(ctx, t, to)
+	}
+}
+
+// walk bounds how often the transition graph of one execution may revisit
+// nodes. By default every node runs at most once and a revisit is reported as
+// a cycle; settings.max_node_visits allows bounded retry loops and
+// settings.max_steps caps the total number of node runs.
+type walk struct {
+	// process is the definition being run, for its condition modes.
+	process   *models.Process
+	visits    map[string]int
+	steps     int
+	maxVisits int
+	maxSteps  int
+	// strictConditions fails the execution when a condition reads an
+	// undefined path instead of treating the condition as false.
+	strictConditions bool
+}
+
+func newWalk(process *models.Process) *walk {
+	settings := process.Definition.Settings
+	maxVisits := settings.MaxNodeVisits
+	if maxVisits <= 0 {
+		maxVisits = 1
+	}
+	return &walk{
+		process:          process,
+		visits:           make(map[string]int),
+		maxVisits:        maxVisits,
+		maxSteps:         settings.MaxSteps,
+		strictConditions: settings.OnUndefinedPath == models.UndefinedPathError,
+	}
+}
+
+// enter records a run of nodeID and fails when it exceeds a limit.
+func (w *walk) enter(nodeID string) error {
+	w.visits[nodeID]++
+	w.steps++
+	if w.visits[nodeID] > w.maxVisits {
+		if w.maxVisits == 1 {
+			return fmt.Errorf("cycle detected: node %s", nodeID)
 		}
+		return fmt.Errorf("node %s exceeded max_node_visits (%d)", nodeID, w.maxVisits)
+	}
+	if w.maxSteps > 0 && w.steps > w.maxSteps {
+		return fmt.Errorf("execution exceeded max_steps (%d) at node %s", w.maxSteps, nodeID)
 	}
 	return nil
 }
 
-var jsonPathRe = regexp.MustCompile(`\$\.[a-zA-Z0-9_.\[\]]+`)
+// mark counts nodeID as visited without running it, for the injected start
+// node of a replay or batch execution.
+func (w *walk) mark(nodeID string) {
+	w.visits[nodeID]++
+}
+
+// haltsFlow reports whether node's output asks the executor not to continue
+// past it: a dedupe node that saw a duplicate, or a batcher node that buffered
+// its item without releasing a batch.
+func haltsFlow(node *models.Node, ctx *models.ExecutionContext) bool {
+	switch node.Type {
+	case "dedupe":
+		dup, _ := ctx.GetValue("$.nodes." + node.ID + ".output.duplicate")
+		return dup == true
+	case "batcher":
+		released, _ := ctx.GetValue("$.nodes." + node.ID + ".output.released")
+		return released != true
+	}
+	return false
+}
 
 // classifyTransitions partitions a slice of transitions into buckets by type.
 func classifyTransitions(transitions []models.Transition) (cond, noCond, success, errorT []models.Transition) {
@@ -326,47 +960,47 @@ func classifyTransitions(transitions []models.Transition) (cond, noCond, success
 	return
 }
 
-func evaluateCondition(expr string, ctx *models.ExecutionContext) bool {
-	replaced := jsonPathRe.ReplaceAllStringFunc(expr, func(token string) string {
-		val, err := ctx.GetValue(token)
-		if err != nil {
-			return "undefined"
-		}
-		switch v := val.(type) {
-		case string:
-			b, _ := json.Marshal(v)
-			return string(b)
-		case float64:
-			if v == float64(int64(v)) {
-				return fmt.Sprintf("%d", int64(v))
-			}
-			return fmt.Sprintf("%g", v)
-		case bool:
-			if v {
-				return "true"
-			}
-			return "false"
-		case nil:
-			return "null"
-		default:
-			b, _ := json.Marshal(v)
-			return string(b)
-		}
-	})
-	vm := goja.New()
-	result, err := vm.RunString(replaced)
-	if err != nil {
-		return false
+// executeNode runs node, pausing around it when the execution is a debug
+// session.
+func (e *ProcessExecutor) executeNode(node *models.Node, ctx *models.ExecutionContext) error {
+	s := e.debugSession(ctx.ExecutionID)
+	if s == nil {
+		return e.runNode(node, ctx)
+	}
+	if err := s.before(node.ID, ctx); err != nil {
+		return err
+	}
+	err := e.runNode(node, ctx)
+	if pauseErr := s.after(node.ID, ctx); pauseErr != nil {
+		return pauseErr
 	}
-	return result.ToBoolean()
+	return err
 }
 
-// executeNode executes a single node
-func (e *ProcessExecutor) executeNode(node *models.Node, ctx *models.ExecutionContext) error {
-	log.Printf("Executing node %s (type: %s)", node.ID, node.Type)
+// runNode executes a single node
+func (e *ProcessExecutor) runNode(node *models.Node, ctx *models.ExecutionContext) error {
+	logger := logging.ForExecution(ctx).With(logging.KeyNodeID, node.ID, logging.KeyNodeType, node.Type)
+	logger.Debug("executing node")
 
 	startTime := time.Now()
 
+	// Nothing is left to run for a caller that went away.
+	if cause := ctx.Err(); cause != nil {
+		cancelErr := fmt.Errorf("%w before node %s: %w", models.ErrExecutionCancelled, node.ID, cause)
+		ctx.SetNodeStatus(node.ID, "error")
+		e.auditNode(ctx, node, "error", nil, nil, cancelErr.Error())
+		return cancelErr
+	}
+	// settings.timeout bounds the whole execution, not each node: once it has
+	// run out no further node starts, and activities cap their own timeouts
+	// at what is left (see ExecutionContext.Budget).
+	if left, ok := ctx.Remaining(); ok && left <= 0 {
+		timeoutErr := fmt.Errorf("%w before node %s", ErrProcessTimeout, node.ID)
+		ctx.SetNodeStatus(node.ID, "error")
+		e.auditNode(ctx, node, "error", nil, nil, timeoutErr.Error())
+		return timeoutErr
+	}
+
 	// Resolve input mapping
 	var input map[string]interface{}
 	var err error
@@ -375,12 +1009,24 @@ func (e *ProcessExecutor) executeNode(node *models.Node, ctx *models.ExecutionCo
 		input, err = ctx.ResolveInputMapping(node.InputMapping)
 		if err != nil {
 			ctx.SetNodeStatus(node.ID, "error")
-			e.sendAuditLog(ctx.ExecutionID, ctx.ProcessID, node.ID, node.Type, "error", nil, nil, err.Error())
+			e.auditNode(ctx, node, "error", nil, nil, err.Error())
 			return fmt.Errorf("failed to resolve input mapping: %w", err)
 		}
 	} else {
 		input = make(map[string]interface{})
 	}
+	// Sensitive inputs reach the activity, but only their references are
+	// recorded in audit events, outputs and errors.
+	sensitive := models.NewSensitiveInputs(node.SensitiveInputs, input)
+	auditInput := sensitive.RedactInput(input)
+	if node.InputSchema != "" {
+		if err = e.validatePayload(ctx, node.InputSchema, input); err != nil {
+			err = sensitive.ScrubError(err)
+			ctx.SetNodeStatus(node.ID, "error")
+			e.auditNode(ctx, node, "error", auditInput, nil, err.Error())
+			return fmt.Errorf("input: %w", err)
+		}
+	}
 
 	// Copy node.Config to avoid mutation on secret injection
 	config := make(map[string]interface{})
@@ -393,14 +1039,38 @@ func (e *ProcessExecutor) executeNode(node *models.Node, ctx *models.ExecutionCo
 	if node.Type == "code" && node.Script != "" {
 		config["script"] = node.Script
 	}
+	// Batcher nodes keep one buffer per node, so they need to know which one
+	// they are.
+	if node.Type == "batcher" {
+		config["node_id"] = node.ID
+	}
+
+	// A connection profile supplies the fields the node does not set, and
+	// its secret when the node has none.
+	config, secretRef, err := e.applyProfile(ctx, node, config)
+	if err != nil {
+		ctx.SetNodeStatus(node.ID, "error")
+		e.auditNode(ctx, node, "error", auditInput, nil, err.Error())
+		return err
+	}
 
 	// Secret injection
-	if node.SecretRef != "" {
-		secretData, secretErr := e.secretResolver.Resolve(context.Background(), node.SecretRef)
+	if secretRef != "" {
+		if !ctx.AllowsSecret(secretRef) {
+			policyErr := fmt.Errorf("%w: %q is not in settings.secrets_allowed of process %s", secrets.ErrNotAllowed, secretRef, ctx.ProcessID)
+			ctx.SetNodeStatus(node.ID, "error")
+			e.auditNode(ctx, node, "error", auditInput, nil, policyErr.Error())
+			return policyErr
+		}
+		// Secrets are resolved in the workspace of the running process so a
+		// flow can never read credentials owned by another tenant.
+		secretCtx := tenant.WithWorkspace(context.Background(), ctx.Workspace)
+		secretCtx = secrets.WithUsage(secretCtx, secrets.Usage{ExecutionID: ctx.ExecutionID, ProcessID: ctx.ProcessID, NodeID: node.ID})
+		secretData, secretErr := e.secretResolver.Resolve(secretCtx, secretRef)
 		if secretErr != nil {
 			ctx.SetNodeStatus(node.ID, "error")
-			e.sendAuditLog(ctx.ExecutionID, ctx.ProcessID, node.ID, node.Type, "error", input, nil, secretErr.Error())
-			return fmt.Errorf("failed to resolve secret %s: %w", node.SecretRef, secretErr)
+			e.auditNode(ctx, node, "error", auditInput, nil, secretErr.Error())
+			return fmt.Errorf("failed to resolve secret %s: %w", secretRef, secretErr)
 		}
 		for k, v := range secretData {
 			config[k] = v
@@ -411,11 +1081,27 @@ func (e *ProcessExecutor) executeNode(node *models.Node, ctx *models.ExecutionCo
 	activity, ok := e.activityRegistry.Get(node.Type)
 	if !ok {
 		execErr := fmt.Errorf("unknown activity type: %s", node.Type)
+		if node.Ref != "" {
+			// $ref nodes are expanded by the process store, not here.
+			execErr = fmt.Errorf("node template %q was not expanded", node.Ref)
+		}
 		ctx.SetNodeStatus(node.ID, "error")
-		e.sendAuditLog(ctx.ExecutionID, ctx.ProcessID, node.ID, node.Type, "error", input, nil, execErr.Error())
+		e.auditNode(ctx, node, "error", auditInput, nil, execErr.Error())
 		return execErr
 	}
 
+	// A node whose circuit is open fails fast without calling the activity.
+	if cb := node.CircuitBreaker; cb != nil {
+		key := breakerKey(ctx, node.ID)
+		if !e.breakers.allow(key, cb) {
+			openErr := circuitOpenError(node)
+			ctx.SetNodeStatus(node.ID, StatusCircuitOpen)
+			e.auditNode(ctx, node, StatusCircuitOpen, auditInput, nil, openErr.Error())
+			return openErr
+		}
+		defer func() { e.breakers.done(key, cb, err == nil) }()
+	}
+
 	// Execute the activity with retry logic
 	var output map[string]interface{}
 	maxAttempts := 1
@@ -423,47 +1109,120 @@ func (e *ProcessExecutor) executeNode(node *models.Node, ctx *models.ExecutionCo
 		maxAttempts = node.RetryPolicy.MaxAttempts
 	}
 
+	// The activity runs on a locked OS thread so its thread CPU time can be
+	// measured for the execution stats.
+	runtime.LockOSThread()
+	cpuStart, cpuOK := threadCPUTime()
+	attempts := 0
+	faults := e.faultsFor(ctx.ExecutionID)
 	for attempt := 1; attempt <= maxAttempts; attempt++ {
-		output, err = activity.Execute(input, config, ctx)
+		attempts = attempt
+		// An injected fault fails the attempt before, or truncates the output
+		// after, the activity runs.
+		var injected nodeFaults
+		err = nil
+		if faults != nil {
+			injected = faults.draw(node.ID)
+			err = injectBefore(injected, node, ctx, attempt, logger)
+		}
+		if err == nil {
+			output, err = activity.Execute(input, config, ctx)
+			if err == nil && injected.truncate {
+				logger.Warn("injecting truncated output", "attempt", attempt)
+				output = truncateOutput(output)
+			}
+		}
 		if err == nil {
 			break
 		}
+		// A retry that would start after the timeout budget is not attempted.
+		if left, ok := ctx.Remaining(); ok && left < retryBaseInterval {
+			break
+		}
 		if attempt < maxAttempts {
-			log.Printf("Node %s attempt %d/%d failed: %v. Retrying...", node.ID, attempt, maxAttempts, err)
+			logger.Warn("node attempt failed; retrying", "attempt", attempt, "max_attempts", maxAttempts, logging.KeyError, sensitive.ScrubError(err))
 			time.Sleep(retryBaseInterval)
 		}
 	}
 
+	var cpu time.Duration
+	if cpuEnd, ok := threadCPUTime(); ok && cpuOK {
+		cpu = cpuEnd - cpuStart
+	}
+	runtime.UnlockOSThread()
+
 	duration := time.Since(startTime)
+	ctx.RecordNodeRun(node.ID, duration, cpu)
+	e.checkSLA(node, ctx, duration, err)
+
+	// output_mapping keeps only what downstream nodes need, so the context
+	// stays small and their paths do not depend on the activity's raw shape.
+	if node.OutputMapping != nil && output != nil {
+		output = models.ApplyOutputMapping(node.OutputMapping, output)
+	}
+	output = sensitive.ScrubOutput(output)
+	err = sensitive.ScrubError(err)
 
 	if err != nil {
+		// An activity may return its output alongside the error, e.g. the
+		// response of an http node whose expect failed; keep it so error
+		// transitions can inspect it.
+		if output != nil {
+			ctx.SetNodeOutput(node.ID, output)
+		}
 		ctx.SetNodeStatus(node.ID, "error")
-		e.sendAuditLog(ctx.ExecutionID, ctx.ProcessID, node.ID, node.Type, "error", input, nil, err.Error())
+		e.auditNodeRun(ctx, node, "error", auditInput, output, err.Error(), duration, attempts)
 		return err
 	}
 
 	ctx.SetNodeOutput(node.ID, output)
+	// A schema violation is the node's fault, not its downstream system's, so
+	// it does not count against the circuit breaker.
+	if node.OutputSchema != "" {
+		if schemaErr := e.validatePayload(ctx, node.OutputSchema, output); schemaErr != nil {
+			ctx.SetNodeStatus(node.ID, "error")
+			e.auditNodeRun(ctx, node, "error", auditInput, output, schemaErr.Error(), duration, attempts)
+			return fmt.Errorf("output: %w", schemaErr)
+		}
+	}
 	ctx.SetNodeStatus(node.ID, "success")
-	log.Printf("Node %s completed successfully in %v", node.ID, duration)
-	e.sendAuditLog(ctx.ExecutionID, ctx.ProcessID, node.ID, node.Type, "success", input, output, "")
+	logger.Info("node completed", "duration_ms", duration.Milliseconds())
+	e.auditNodeRun(ctx, node, "success", auditInput, output, "", duration, attempts)
 
 	return nil
 }
 
 // sendAuditLog sends an audit message to NATS
-func (e *ProcessExecutor) sendAuditLog(executionID, flowID, nodeID, nodeType, status string, input, output map[string]interface{}, errorMsg string) {
-	if !e.auditEnabled || e.natsConn == nil {
+func (e *ProcessExecutor) sendAuditLog(workspace, executionID, parentExecutionID, flowID, nodeID, nodeType, status string, input, output map[string]interface{}, errorMsg string) {
+	e.sendAuditMessage(workspace, executionID, parentExecutionID, flowID, nodeID, nodeType, status, input, output, errorMsg, nil)
+}
+
+// sendTerminalAuditLog sends the terminal process event of ctx's execution,
+// carrying the execution's resource usage as execution_stats, and notifies
+// the subscribers of a failed execution.
+func (e *ProcessExecutor) sendTerminalAuditLog(ctx *models.ExecutionContext, status string, input map[string]interface{}, errorMsg string) {
+	if status == "failed" {
+		e.notifyFailure(ctx, errorMsg)
+	}
+	e.auditExecution(ctx, ctx.ProcessID, "process", status, input, nil, errorMsg,
+		map[string]interface{}{"execution_stats": ctx.Stats()})
+}
+
+// sendAuditMessage builds an audit message, adds extra to it and publishes it.
+func (e *ProcessExecutor) sendAuditMessage(workspace, executionID, parentExecutionID, flowID, nodeID, nodeType, status string, input, output map[string]interface{}, errorMsg string, extra map[string]interface{}) {
+	if !e.auditEnabled || e.auditBuf == nil {
 		return
 	}
-	log.Printf("[audit] publishing event: executionID=%s flowID=%s nodeID=%s nodeType=%s status=%s", executionID, flowID, nodeID, nodeType, status)
+	slog.Debug("publishing audit event", logging.KeyExecutionID, executionID, logging.KeyProcessID, flowID, logging.KeyNodeID, nodeID, logging.KeyNodeType, nodeType, "status", status)
 
 	auditMsg := map[string]interface{}{
 		"execution_id": executionID,
+		"workspace":    tenant.Normalize(workspace),
 		"flow_id":      flowID,
 		"node_id":      nodeID,
 		"node_type":    nodeType,
 		"status":       status,
-		"timestamp":    time.Now().UTC().Format(time.RFC3339),
+		"timestamp":    time.Now().UTC().Format(time.RFC3339Nano),
 		"input":        input,
 		"output":       output,
 	}
@@ -471,31 +1230,51 @@ func (e *ProcessExecutor) sendAuditLog(executionID, flowID, nodeID, nodeType, st
 	if errorMsg != "" {
 		auditMsg["error"] = errorMsg
 	}
+	if parentExecutionID != "" {
+		auditMsg["parent_execution_id"] = parentExecutionID
+	}
+	if e.engineID != "" {
+		auditMsg["engine_id"] = e.engineID
+	}
+	for k, v := range extra {
+		auditMsg[k] = v
+	}
 
 	msgBytes, err := json.Marshal(auditMsg)
 	if err != nil {
 		// If full marshal fails (e.g. non-JSON-serializable output), retry without input/output data
 		// so the event metadata is still recorded.
-		log.Printf("Failed to marshal full audit message for node %s: %v — retrying without data fields", nodeID, err)
+		slog.Warn("failed to marshal full audit message; retrying without data fields", logging.KeyExecutionID, executionID, logging.KeyNodeID, nodeID, logging.KeyError, err)
 		auditMsg["input"] = nil
 		auditMsg["output"] = nil
 		msgBytes, err = json.Marshal(auditMsg)
 		if err != nil {
-			log.Printf("Failed to marshal audit message for node %s (fallback): %v", nodeID, err)
+			slog.Error("failed to marshal audit message", logging.KeyExecutionID, executionID, logging.KeyNodeID, nodeID, logging.KeyError, err)
 			return
 		}
 	}
 
-	if err := e.natsConn.Publish("audit.logs", msgBytes); err != nil {
-		log.Printf("Failed to publish audit log: %v", err)
+	if h := e.heldAudit(executionID); h != nil {
+		h.add(msgBytes, status)
+		return
 	}
+	e.auditBuf.Publish(msgBytes)
 }
 
 // SendLifecycleAuditLog emits a NATS audit event for deployment lifecycle
-// actions (deploy / stop). processID is used as the node_id; action should
-// be "deployed" or "stopped". When errorMsg is non-empty the status is set to
-// "error", otherwise to "success".
-func (e *ProcessExecutor) SendLifecycleAuditLog(processID, triggerType, action, errorMsg string) {
+// actions (deploy / stop) in the given workspace. processID is used as the
+// node_id; action should be "deployed" or "stopped". When errorMsg is non-empty the status is set to
+// "error", otherwise to "success". Deploys, failed or not, are also notified.
+func (e *ProcessExecutor) SendLifecycleAuditLog(workspace, processID, triggerType, action, errorMsg string) {
+	if action == "deployed" {
+		e.notify(notify.Event{
+			Type:      notify.EventDeploy,
+			Workspace: workspace,
+			ProcessID: processID,
+			Message:   errorMsg,
+			Details:   map[string]interface{}{"trigger_type": triggerType},
+		})
+	}
 	status := "success"
 	if errorMsg != "" {
 		status = "error"
@@ -505,5 +1284,41 @@ func (e *ProcessExecutor) SendLifecycleAuditLog(processID, triggerType, action,
 		"process_id":   processID,
 		"trigger_type": triggerType,
 	}
-	e.sendAuditLog(uuid.New().String(), processID, processID, "lifecycle", status, input, nil, errorMsg)
+	e.sendAuditLog(workspace, uuid.New().String(), "", processID, processID, "lifecycle", status, input, nil, errorMsg)
+}
+
+// SendDriftAuditLog emits a "lifecycle" audit event for a trigger found
+// running while its process is not deployed, or the reverse (drift, see
+// triggers.Drift), and corrected by being stopped or started. When errorMsg
+// is non-empty the correction failed and the status is "error".
+func (e *ProcessExecutor) SendDriftAuditLog(workspace, processID, triggerType, drift, errorMsg string) {
+	status := "success"
+	if errorMsg != "" {
+		status = "error"
+	}
+	input := map[string]interface{}{
+		"action":       "reconciled",
+		"drift":        drift,
+		"process_id":   processID,
+		"trigger_type": triggerType,
+	}
+	e.sendAuditLog(workspace, uuid.New().String(), "", processID, processID, "lifecycle", status, input, nil, errorMsg)
+}
+
+// StatusQuotaExceeded is the audit status of the event emitted when a
+// process quota refuses an execution.
+const StatusQuotaExceeded = "quota_exceeded"
+
+// SendQuotaAuditLog emits a "quota_exceeded" audit event for a run of
+// processID that quota (e.g. "max_executions_per_hour") refused. It
+// implements triggers.QuotaAuditor.
+func (e *ProcessExecutor) SendQuotaAuditLog(workspace, processID, triggerType, quota string, limit int) {
+	input := map[string]interface{}{
+		"process_id":   processID,
+		"trigger_type": triggerType,
+		"quota":        quota,
+		"limit":        limit,
+	}
+	msg := fmt.Sprintf("execution refused: %s of %d reached", quota, limit)
+	e.sendAuditLog(workspace, uuid.New().String(), "", processID, processID, "process", StatusQuotaExceeded, input, nil, msg)
 }
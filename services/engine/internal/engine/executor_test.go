@@ -1,12 +1,18 @@
 package engine
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"flowjs-works/engine/internal/models"
+	"flowjs-works/engine/internal/secrets"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -25,9 +31,9 @@ func newTestExecutor(t *testing.T) *ProcessExecutor {
 func TestSendLifecycleAuditLog_AuditDisabled(t *testing.T) {
 	exec := newTestExecutor(t)
 	// Should not panic or return any error when audit is disabled.
-	exec.SendLifecycleAuditLog("my-flow", "rest", "deployed", "")
-	exec.SendLifecycleAuditLog("my-flow", "cron", "stopped", "")
-	exec.SendLifecycleAuditLog("my-flow", "rest", "deployed", "some error occurred")
+	exec.SendLifecycleAuditLog("", "my-flow", "rest", "deployed", "")
+	exec.SendLifecycleAuditLog("", "my-flow", "cron", "stopped", "")
+	exec.SendLifecycleAuditLog("", "my-flow", "rest", "deployed", "some error occurred")
 }
 
 // TestSendLifecycleAuditLog_StatusMapping verifies the error/success status logic:
@@ -36,8 +42,8 @@ func TestSendLifecycleAuditLog_AuditDisabled(t *testing.T) {
 func TestSendLifecycleAuditLog_StatusMapping(t *testing.T) {
 	exec := newTestExecutor(t)
 	// Both calls must be no-ops (audit disabled) — no panic expected.
-	exec.SendLifecycleAuditLog("proc-1", "rest", "deployed", "")           // success
-	exec.SendLifecycleAuditLog("proc-2", "cron", "deployed", "bad config") // error
+	exec.SendLifecycleAuditLog("", "proc-1", "rest", "deployed", "")           // success
+	exec.SendLifecycleAuditLog("", "proc-2", "cron", "deployed", "bad config") // error
 }
 
 // buildProcess is a test helper that creates a minimal process JSON from its parts.
@@ -248,6 +254,39 @@ func TestExecute_ScriptNodeTransformsPropagated(t *testing.T) {
 	assert.Equal(t, "success", logStatus)
 }
 
+func TestExecute_OutputMappingReshapesStoredOutput(t *testing.T) {
+	exec := newTestExecutor(t)
+	process := buildProcess("p-outmap", []models.Node{
+		{
+			ID:     "transform",
+			Type:   "code",
+			Script: `(function() { return { body: { id: "ord-1", lines: [{ sku: "A" }] }, headers: { "x-big": "..." } }; })()`,
+			OutputMapping: map[string]interface{}{
+				"order_id": "$.body.id",
+				"first":    map[string]interface{}{"sku": "$.body.lines[0].sku"},
+				"source":   "code",
+			},
+		},
+		{
+			ID:           "log_result",
+			Type:         "logger",
+			InputMapping: map[string]interface{}{"message": "$.nodes.transform.output.order_id"},
+		},
+	})
+
+	ctx, err := exec.ExecuteFromJSON(process, map[string]interface{}{})
+	require.NoError(t, err)
+	out, err := ctx.GetValue("$.nodes.transform.output")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"order_id": "ord-1",
+		"first":    map[string]interface{}{"sku": "A"},
+		"source":   "code",
+	}, out)
+	status, _ := ctx.GetValue("$.nodes.log_result.status")
+	assert.Equal(t, "success", status)
+}
+
 // ---------------------------------------------------------------------------
 // Error / edge cases
 // ---------------------------------------------------------------------------
@@ -473,6 +512,38 @@ func TestTransition_ErrorPath(t *testing.T) {
 	assert.Equal(t, "success", s2)
 }
 
+// TestTransition_ErrorPathKeepsOutput verifies that the output an activity
+// returns alongside its error (an http node whose expect failed) is readable
+// by the error branch.
+func TestTransition_ErrorPathKeepsOutput(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	exec := newTestExecutor(t)
+	process := models.Process{
+		Definition: models.Definition{ID: "trans-expect", Version: "1.0.0", Name: "trans-expect"},
+		Trigger:    models.Trigger{ID: "trg", Type: "manual"},
+		Nodes: []models.Node{
+			{ID: "call", Type: "http", Config: map[string]interface{}{"url": srv.URL, "expect": map[string]interface{}{"status": "2xx"}}},
+			{ID: "on_error", Type: "logger", InputMapping: map[string]interface{}{"code": "$.nodes.call.output.status_code"}},
+		},
+		Transitions: []models.Transition{
+			{From: "call", To: "on_error", Type: "error"},
+		},
+	}
+	data, _ := json.Marshal(process)
+	ctx, err := exec.ExecuteFromJSON(data, map[string]interface{}{})
+	require.NoError(t, err)
+	status, _ := ctx.GetValue("$.nodes.call.status")
+	assert.Equal(t, "error", status)
+	code, _ := ctx.GetValue("$.nodes.call.output.status_code")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	s2, _ := ctx.GetValue("$.nodes.on_error.status")
+	assert.Equal(t, "success", s2)
+}
+
 func TestTransition_ConditionTrue(t *testing.T) {
 	exec := newTestExecutor(t)
 	process := models.Process{
@@ -527,6 +598,94 @@ func TestTransition_NoConditionFallback(t *testing.T) {
 	assert.Equal(t, "success", s2)
 }
 
+// priorityProcess branches from a code node returning {value: 42} to two
+// matching conditions and a nocondition fallback.
+func priorityProcess(mode string) *models.Process {
+	logger := map[string]interface{}{"level": "info"}
+	return &models.Process{
+		Definition: models.Definition{ID: "trans-prio", Version: "1.0.0", Name: "trans-prio"},
+		Trigger:    models.Trigger{ID: "trg", Type: "manual"},
+		Nodes: []models.Node{
+			{ID: "script_node", Type: "code", Script: "({ value: 42 })", ConditionMode: mode},
+			{ID: "low", Type: "logger", Config: logger},
+			{ID: "high", Type: "logger", Config: logger},
+			{ID: "fallback", Type: "logger", Config: logger},
+		},
+		Transitions: []models.Transition{
+			{From: "script_node", To: "fallback", Type: "nocondition"},
+			{From: "script_node", To: "low", Type: "condition", Condition: "$.nodes.script_node.output.value > 10"},
+			{From: "script_node", To: "high", Type: "condition", Condition: "$.nodes.script_node.output.value === 42", Priority: 10},
+		},
+	}
+}
+
+func TestTransition_PriorityOrdersExclusiveConditions(t *testing.T) {
+	exec := newTestExecutor(t)
+	ctx, err := exec.Execute(priorityProcess(""), map[string]interface{}{})
+	require.NoError(t, err)
+	s, _ := ctx.GetValue("$.nodes.high.status")
+	assert.Equal(t, "success", s)
+	_, errLow := ctx.GetValue("$.nodes.low.status")
+	assert.Error(t, errLow, "only the highest-priority match is followed")
+	_, errElse := ctx.GetValue("$.nodes.fallback.status")
+	assert.Error(t, errElse, "nocondition is not followed when a condition matched")
+}
+
+func TestTransition_InclusiveFollowsEveryMatch(t *testing.T) {
+	exec := newTestExecutor(t)
+	ctx, err := exec.Execute(priorityProcess(models.ConditionInclusive), map[string]interface{}{})
+	require.NoError(t, err)
+	for _, id := range []string{"high", "low"} {
+		s, _ := ctx.GetValue("$.nodes." + id + ".status")
+		assert.Equal(t, "success", s, id)
+	}
+	_, errElse := ctx.GetValue("$.nodes.fallback.status")
+	assert.Error(t, errElse)
+}
+
+// TestTransition_DynamicRoutesToNamedNode verifies that a dynamic transition
+// follows the target its expression names, falls back to nocondition when it
+// names none, and fails the execution without a fallback.
+func TestTransition_DynamicRoutesToNamedNode(t *testing.T) {
+	exec := newTestExecutor(t)
+	logger := map[string]interface{}{"level": "info"}
+	process := &models.Process{
+		Definition: models.Definition{ID: "trans-dynamic", Version: "1.0.0", Name: "trans-dynamic"},
+		Trigger:    models.Trigger{ID: "trg", Type: "manual"},
+		Nodes: []models.Node{
+			{ID: "route", Type: "logger", Config: logger},
+			{ID: "ship_es", Type: "logger", Config: logger},
+			{ID: "ship_fr", Type: "logger", Config: logger},
+			{ID: "manual", Type: "logger", Config: logger},
+		},
+		Transitions: []models.Transition{
+			{From: "route", Type: models.TransitionDynamic, Expression: `"ship_" + $.trigger.body.country`, Targets: []string{"ship_es", "ship_fr"}},
+			{From: "route", To: "manual", Type: "nocondition"},
+		},
+	}
+	ran := func(ctx *models.ExecutionContext) []string {
+		var ids []string
+		for _, id := range []string{"ship_es", "ship_fr", "manual"} {
+			if s, _ := ctx.GetValue("$.nodes." + id + ".status"); s == "success" {
+				ids = append(ids, id)
+			}
+		}
+		return ids
+	}
+
+	ctx, err := exec.Execute(process, map[string]interface{}{"body": map[string]interface{}{"country": "fr"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ship_fr"}, ran(ctx))
+
+	ctx, err = exec.Execute(process, map[string]interface{}{"body": map[string]interface{}{"country": "de"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"manual"}, ran(ctx))
+
+	process.Transitions = process.Transitions[:1]
+	_, err = exec.Execute(process, map[string]interface{}{"body": map[string]interface{}{"country": "de"}})
+	assert.ErrorContains(t, err, `dynamic transition from route: "ship_de" is not one of its targets`)
+}
+
 // TestExecuteFromNode_SkipsStartNodeAndRunsDownstream verifies that ExecuteFromNode
 // injects nodeInput for the start node (marking it "replayed") and runs downstream nodes.
 func TestExecuteFromNode_SkipsStartNodeAndRunsDownstream(t *testing.T) {
@@ -543,7 +702,7 @@ func TestExecuteFromNode_SkipsStartNodeAndRunsDownstream(t *testing.T) {
 		},
 	}
 	injected := map[string]interface{}{"key": "injected_value"}
-	ctx, err := exec.ExecuteFromNode(&process, "start_node", injected, "")
+	ctx, err := exec.ExecuteFromNode(&process, "start_node", injected, "", "")
 	require.NoError(t, err)
 	require.NotNil(t, ctx)
 
@@ -573,7 +732,7 @@ func TestExecuteFromNode_WithExecutionIDHint(t *testing.T) {
 		},
 	}
 	hint := "fixed-execution-id-1234"
-	ctx, err := exec.ExecuteFromNode(&process, "only_node", map[string]interface{}{}, hint)
+	ctx, err := exec.ExecuteFromNode(&process, "only_node", map[string]interface{}{}, hint, "")
 	require.NoError(t, err)
 	require.NotNil(t, ctx)
 	assert.Equal(t, hint, ctx.ExecutionID)
@@ -598,7 +757,7 @@ func TestExecuteFromNode_ConditionRouting(t *testing.T) {
 	}
 
 	// Inject a score > 50 — on_true branch should be taken.
-	ctx, err := exec.ExecuteFromNode(&process, "start_node", map[string]interface{}{"score": 75}, "")
+	ctx, err := exec.ExecuteFromNode(&process, "start_node", map[string]interface{}{"score": 75}, "", "")
 	require.NoError(t, err)
 
 	trueStatus, _ := ctx.GetValue("$.nodes.on_true.status")
@@ -658,7 +817,470 @@ func TestExecuteFromNode_SuccessReturnsNilError(t *testing.T) {
 			{From: "injected", To: "next_log", Type: "success"},
 		},
 	}
-	ctx, err := exec.ExecuteFromNode(&process, "injected", map[string]interface{}{"ok": true}, "")
+	ctx, err := exec.ExecuteFromNode(&process, "injected", map[string]interface{}{"ok": true}, "", "")
 	require.NoError(t, err, "successful ExecuteFromNode must return nil error (triggers REPLAYED event)")
 	assert.NotNil(t, ctx)
 }
+
+// TestExecute_DedupeDuplicateStopsFlow verifies that a duplicate detected by a
+// dedupe node prevents downstream nodes from running.
+func TestExecute_DedupeDuplicateStopsFlow(t *testing.T) {
+	exec := newTestExecutor(t)
+
+	process := buildProcess("p_dedupe", []models.Node{
+		{
+			ID:           "dedupe",
+			Type:         "dedupe",
+			InputMapping: map[string]interface{}{"id": "$.trigger.body.id"},
+			Config:       map[string]interface{}{"ttl": "1h"},
+		},
+		{
+			ID:     "log_result",
+			Type:   "logger",
+			Config: map[string]interface{}{"level": "info", "message": "processed"},
+		},
+	})
+	triggerData := map[string]interface{}{"body": map[string]interface{}{"id": "order-1"}}
+
+	first, err := exec.ExecuteFromJSON(process, triggerData)
+	require.NoError(t, err)
+	status, err := first.GetValue("$.nodes.log_result.status")
+	require.NoError(t, err)
+	assert.Equal(t, "success", status)
+
+	second, err := exec.ExecuteFromJSON(process, triggerData)
+	require.NoError(t, err)
+	dup, err := second.GetValue("$.nodes.dedupe.output.duplicate")
+	require.NoError(t, err)
+	assert.Equal(t, true, dup)
+	_, err = second.GetValue("$.nodes.log_result.status")
+	assert.Error(t, err, "downstream node must not run for a duplicate")
+}
+
+// TestExecute_BatcherHoldsUntilReleased verifies that nodes after a batcher
+// only run for the execution that completes a batch, with all items.
+func TestExecute_BatcherHoldsUntilReleased(t *testing.T) {
+	exec := newTestExecutor(t)
+
+	process := buildProcess("p_batch", []models.Node{
+		{
+			ID:           "batch",
+			Type:         "batcher",
+			InputMapping: map[string]interface{}{"id": "$.trigger.body.id"},
+			Config:       map[string]interface{}{"max_size": float64(2)},
+		},
+		{
+			ID:           "bulk",
+			Type:         "code",
+			InputMapping: map[string]interface{}{"items": "$.nodes.batch.output.items"},
+			Script:       `({ count: input.items.length })`,
+		},
+	})
+
+	first, err := exec.ExecuteFromJSON(process, map[string]interface{}{"body": map[string]interface{}{"id": 1}})
+	require.NoError(t, err)
+	_, err = first.GetValue("$.nodes.bulk.status")
+	assert.Error(t, err, "downstream node must wait for the batch")
+
+	second, err := exec.ExecuteFromJSON(process, map[string]interface{}{"body": map[string]interface{}{"id": 2}})
+	require.NoError(t, err)
+	count, err := second.GetValue("$.nodes.bulk.output.count")
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, count)
+}
+
+// TestExecuteBatch_RunsDownstreamNodes verifies that a batch released outside
+// an execution continues through the transitions after the batcher node.
+func TestExecuteBatch_RunsDownstreamNodes(t *testing.T) {
+	exec := newTestExecutor(t)
+
+	process := &models.Process{
+		Definition: models.Definition{ID: "p_batch_wait", Version: "1.0.0"},
+		Nodes: []models.Node{
+			{ID: "batch", Type: "batcher"},
+			{
+				ID:           "bulk",
+				Type:         "code",
+				InputMapping: map[string]interface{}{"count": "$.nodes.batch.output.count"},
+				Script:       `({ seen: input.count })`,
+			},
+		},
+		Transitions: []models.Transition{{From: "batch", To: "bulk", Type: "success"}},
+	}
+
+	ctx, err := exec.ExecuteBatch(process, "batch", map[string]interface{}{"released": true, "count": 3, "items": []interface{}{1, 2, 3}})
+	require.NoError(t, err)
+	seen, err := ctx.GetValue("$.nodes.bulk.output.seen")
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, seen)
+}
+
+// usageResolver records the secrets.Usage carried by each Resolve call.
+type usageResolver struct {
+	usages []secrets.Usage
+}
+
+func (r *usageResolver) Resolve(ctx context.Context, _ string) (map[string]interface{}, error) {
+	u, _ := secrets.UsageFromContext(ctx)
+	r.usages = append(r.usages, u)
+	return map[string]interface{}{"level": "info"}, nil
+}
+
+// TestExecute_SecretResolutionCarriesUsage verifies that the resolver learns
+// which execution and node a secret is resolved for, so it can be audited.
+func TestExecute_SecretResolutionCarriesUsage(t *testing.T) {
+	exec := newTestExecutor(t)
+	resolver := &usageResolver{}
+	exec.SetSecretResolver(resolver)
+
+	process := buildProcess("p_secret", []models.Node{
+		{ID: "log", Type: "logger", SecretRef: "sec_log", Config: map[string]interface{}{"message": "x"}},
+	})
+	ctx, err := exec.ExecuteFromJSON(process, map[string]interface{}{})
+	require.NoError(t, err)
+
+	require.Len(t, resolver.usages, 1)
+	assert.Equal(t, secrets.Usage{ExecutionID: ctx.ExecutionID, ProcessID: "p_secret", NodeID: "log"}, resolver.usages[0])
+}
+
+// recordingSnapshots captures the contexts passed to Save.
+type recordingSnapshots struct {
+	saved []*models.ExecutionContext
+}
+
+func (s *recordingSnapshots) Save(_ context.Context, execCtx *models.ExecutionContext) error {
+	s.saved = append(s.saved, execCtx)
+	return nil
+}
+
+// TestExecute_SavesSnapshotUnlessPersistenceNone verifies that the final
+// context is persisted after execution, except for persistence "none".
+func TestExecute_SavesSnapshotUnlessPersistenceNone(t *testing.T) {
+	exec := newTestExecutor(t)
+	snaps := &recordingSnapshots{}
+	exec.SetSnapshotSaver(snaps)
+
+	nodes := []models.Node{{ID: "log", Type: "logger", Config: map[string]interface{}{"message": "x"}}}
+	ctx, err := exec.ExecuteFromJSON(buildProcess("p_snap", nodes), map[string]interface{}{"k": "v"})
+	require.NoError(t, err)
+	require.Len(t, snaps.saved, 1)
+	assert.Equal(t, ctx.ExecutionID, snaps.saved[0].ExecutionID)
+	assert.Contains(t, snaps.saved[0].Nodes, "log")
+
+	var process models.Process
+	require.NoError(t, json.Unmarshal(buildProcess("p_nosnap", nodes), &process))
+	process.Definition.Settings.Persistence = "none"
+	_, err = exec.Execute(&process, map[string]interface{}{})
+	require.NoError(t, err)
+	assert.Len(t, snaps.saved, 1)
+}
+
+// TestRetryExecution_RerunsOnlyFailedChain verifies that a retry keeps the
+// outputs of nodes that succeeded and runs the failed node and its successors.
+func TestRetryExecution_RerunsOnlyFailedChain(t *testing.T) {
+	exec := newTestExecutor(t)
+	resolver := &usageResolver{}
+	exec.SetSecretResolver(resolver)
+
+	process := models.Process{
+		Definition: models.Definition{ID: "p_retry", Version: "1.0.0"},
+		Nodes: []models.Node{
+			{ID: "a", Type: "logger", SecretRef: "sec_log", Config: map[string]interface{}{"message": "a"}},
+			{ID: "b", Type: "not_a_node_type"},
+			{ID: "c", Type: "logger", Config: map[string]interface{}{"message": "c"}},
+		},
+		Transitions: []models.Transition{
+			{From: "a", To: "b", Type: "success"},
+			{From: "b", To: "c", Type: "success"},
+		},
+	}
+	prior, err := exec.Execute(&process, map[string]interface{}{"body": "x"})
+	require.Error(t, err)
+	require.Len(t, resolver.usages, 1)
+
+	process.Nodes[1].Type = "logger"
+	ctx, err := exec.RetryExecution(&process, prior)
+	require.NoError(t, err)
+	assert.NotEqual(t, prior.ExecutionID, ctx.ExecutionID)
+	assert.Len(t, resolver.usages, 1, "node a must not run again")
+	assert.Equal(t, prior.Trigger, ctx.Trigger)
+	assert.Equal(t, "success", ctx.Nodes["b"]["status"])
+	assert.Equal(t, "success", ctx.Nodes["c"]["status"])
+	assert.Equal(t, prior.ExecutionID, ctx.ParentExecutionID)
+
+	_, err = exec.RetryExecution(&process, ctx)
+	assert.ErrorIs(t, err, ErrNothingToRetry)
+}
+
+// TestReplayExecution_AuditsParent verifies that every audit event of a
+// replay names the execution it replays, and that plain runs name none.
+func TestReplayExecution_AuditsParent(t *testing.T) {
+	pub := &flakyPublisher{}
+	exec := newAuditingExecutor(t, pub)
+	var process models.Process
+	require.NoError(t, json.Unmarshal(buildProcess("p_parent", []models.Node{{ID: "log", Type: "logger"}}), &process))
+
+	parent, err := exec.Execute(&process, map[string]interface{}{})
+	require.NoError(t, err)
+	for _, msg := range pub.received() {
+		assert.NotContains(t, msg, "parent_execution_id")
+	}
+
+	child, err := exec.ReplayExecution(context.Background(), &process, map[string]interface{}{}, parent.ExecutionID)
+	require.NoError(t, err)
+	assert.Equal(t, parent.ExecutionID, child.ParentExecutionID)
+	events := pub.received()[3:]
+	require.Len(t, events, 3)
+	for _, msg := range events {
+		assert.Contains(t, msg, `"parent_execution_id":"`+parent.ExecutionID+`"`)
+	}
+}
+
+// loopProcess builds a process whose "work" node, reached from the trigger,
+// loops back to itself while cond holds and then continues to "done".
+func loopProcess(cond string, settings models.ProcessSettings) *models.Process {
+	return &models.Process{
+		Definition: models.Definition{ID: "p_loop", Version: "1.0.0", Settings: settings},
+		Nodes: []models.Node{
+			{ID: "work", Type: "logger", Config: map[string]interface{}{"message": "try"}},
+			{ID: "done", Type: "logger", Config: map[string]interface{}{"message": "done"}},
+		},
+		Transitions: []models.Transition{
+			{From: "trg_01", To: "work", Type: "success"},
+			{From: "work", To: "work", Type: "condition", Condition: cond},
+			{From: "work", To: "done", Type: "nocondition"},
+		},
+	}
+}
+
+// TestExecute_BoundedLoopWithMaxNodeVisits verifies that max_node_visits
+// allows a node to loop and exposes its visit count to conditions.
+func TestExecute_BoundedLoopWithMaxNodeVisits(t *testing.T) {
+	exec := newTestExecutor(t)
+
+	ctx, err := exec.Execute(loopProcess("$.nodes.work.visits < 3", models.ProcessSettings{MaxNodeVisits: 5}), map[string]interface{}{})
+	require.NoError(t, err)
+	assert.Equal(t, 3, ctx.Nodes["work"]["visits"])
+	assert.Equal(t, "success", ctx.Nodes["done"]["status"])
+}
+
+// TestExecute_LoopLimits verifies that runaway loops still fail: as a cycle by
+// default, and at max_node_visits or max_steps when configured.
+func TestExecute_LoopLimits(t *testing.T) {
+	exec := newTestExecutor(t)
+
+	// By default a revisit is reported as a cycle.
+	process := loopProcess("true", models.ProcessSettings{})
+	process.Nodes = append(process.Nodes, models.Node{ID: "begin", Type: "logger", Config: map[string]interface{}{"message": "begin"}})
+	process.Transitions[0] = models.Transition{From: "begin", To: "work", Type: "success"}
+	_, err := exec.Execute(process, map[string]interface{}{})
+	assert.ErrorContains(t, err, "cycle detected: node work")
+
+	_, err = exec.Execute(loopProcess("true", models.ProcessSettings{MaxNodeVisits: 4}), map[string]interface{}{})
+	assert.ErrorContains(t, err, "node work exceeded max_node_visits (4)")
+
+	_, err = exec.Execute(loopProcess("true", models.ProcessSettings{MaxNodeVisits: 100, MaxSteps: 10}), map[string]interface{}{})
+	assert.ErrorContains(t, err, "execution exceeded max_steps (10)")
+}
+
+// TestExecute_ProcessTimeoutBudget verifies that settings.timeout bounds the
+// whole execution: the code node is interrupted when the budget runs out,
+// well before its own 5s timeout, and its error branch does not start.
+func TestExecute_ProcessTimeoutBudget(t *testing.T) {
+	exec := newTestExecutor(t)
+	process := &models.Process{
+		Definition: models.Definition{ID: "budget", Version: "1.0.0", Name: "budget",
+			Settings: models.ProcessSettings{Timeout: 100}},
+		Trigger: models.Trigger{ID: "trg", Type: "manual"},
+		Nodes: []models.Node{
+			{ID: "spin", Type: "code", Script: "while (true) {}"},
+			{ID: "on_error", Type: "logger", Config: map[string]interface{}{"level": "error"}},
+		},
+		Transitions: []models.Transition{
+			{From: "spin", To: "on_error", Type: "error"},
+		},
+	}
+	start := time.Now()
+	ctx, err := exec.Execute(process, map[string]interface{}{})
+	require.Error(t, err)
+	assert.Less(t, time.Since(start), 2*time.Second)
+	assert.True(t, errors.Is(err, ErrProcessTimeout))
+	assert.Equal(t, "error", ctx.Nodes["spin"]["status"])
+	assert.Equal(t, "error", ctx.Nodes["on_error"]["status"])
+}
+
+// TestExecuteContext_CallerDeadline verifies that an execution bound to a
+// request stops when the request budget runs out, although the process has
+// no timeout of its own, and still reports its execution id.
+func TestExecuteContext_CallerDeadline(t *testing.T) {
+	exec := newTestExecutor(t)
+	process := &models.Process{
+		Definition: models.Definition{ID: "bound", Version: "1.0.0", Name: "bound"},
+		Trigger:    models.Trigger{ID: "trg", Type: "manual"},
+		Nodes: []models.Node{
+			{ID: "spin", Type: "code", Script: "while (true) {}"},
+			{ID: "on_error", Type: "logger", Config: map[string]interface{}{"level": "error"}},
+		},
+		Transitions: []models.Transition{
+			{From: "spin", To: "on_error", Type: "error"},
+		},
+	}
+	caller, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	ctx, err := exec.ExecuteContext(caller, process, map[string]interface{}{})
+	require.Error(t, err)
+	assert.Less(t, time.Since(start), 2*time.Second)
+	assert.ErrorIs(t, err, models.ErrExecutionCancelled)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.NotEmpty(t, ctx.ExecutionID)
+	assert.Equal(t, "error", ctx.Nodes["on_error"]["status"], "the error branch does not run")
+}
+
+// TestExecute_MockHTTPInTestMode verifies that an http node can call the
+// server of an upstream mock_http node, which is closed when the run ends.
+func TestExecute_MockHTTPInTestMode(t *testing.T) {
+	exec := newTestExecutor(t)
+	process := &models.Process{
+		Definition: models.Definition{ID: "mock", Version: "1.0.0", Name: "mock"},
+		Trigger:    models.Trigger{ID: "trg", Type: "manual"},
+		Nodes: []models.Node{
+			{ID: "api", Type: "mock_http", Config: map[string]interface{}{
+				"routes": []interface{}{map[string]interface{}{"path": "/", "body": map[string]interface{}{"ok": true}}},
+			}},
+			{ID: "call", Type: "http", Config: map[string]interface{}{"method": "GET"},
+				InputMapping: map[string]interface{}{"url": "$.nodes.api.output.url"}},
+		},
+		Transitions: []models.Transition{{From: "api", To: "call", Type: "success"}},
+	}
+
+	_, err := exec.Execute(process, map[string]interface{}{})
+	require.Error(t, err, "mock_http must fail outside test mode")
+
+	exec.EnableTestMode()
+	ctx, err := exec.Execute(process, map[string]interface{}{})
+	require.NoError(t, err)
+	status, err := ctx.GetValue("$.nodes.call.output.status_code")
+	require.NoError(t, err)
+	assert.Equal(t, 200, status)
+	ok, err := ctx.GetValue("$.nodes.call.output.body.ok")
+	require.NoError(t, err)
+	assert.Equal(t, true, ok)
+}
+
+func TestExecute_TerminalAuditEventCarriesExecutionStats(t *testing.T) {
+	pub := &flakyPublisher{}
+	exec := newAuditingExecutor(t, pub)
+	process := &models.Process{
+		Definition: models.Definition{ID: "stats", Version: "1.0.0", Name: "stats"},
+		Trigger:    models.Trigger{ID: "trg", Type: "manual"},
+		Nodes: []models.Node{
+			{ID: "first", Type: "code", Script: `({ n: 1 })`},
+			{ID: "second", Type: "code", Script: `({ n: 2 })`},
+		},
+	}
+
+	_, err := exec.Execute(process, map[string]interface{}{})
+	require.NoError(t, err)
+	got := pub.received()
+	require.NotEmpty(t, got)
+
+	var terminal struct {
+		Status string                 `json:"status"`
+		Stats  *models.ExecutionStats `json:"execution_stats"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(got[len(got)-1]), &terminal))
+	assert.Equal(t, "completed", terminal.Status)
+	require.NotNil(t, terminal.Stats)
+	assert.Equal(t, 1, terminal.Stats.Nodes["first"].Runs)
+	assert.Equal(t, 1, terminal.Stats.Nodes["second"].Runs)
+	assert.Zero(t, terminal.Stats.BytesTransferred)
+
+	var started map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(got[0]), &started))
+	assert.NotContains(t, started, "execution_stats", "only the terminal event carries stats")
+}
+
+// TestExecute_SecretsAllowedRefusesOtherSecrets verifies that a node cannot
+// resolve a secret missing from settings.secrets_allowed, and that the
+// resolver is never asked for it.
+func TestExecute_SecretsAllowedRefusesOtherSecrets(t *testing.T) {
+	exec := newTestExecutor(t)
+	resolver := &usageResolver{}
+	exec.SetSecretResolver(resolver)
+
+	var process models.Process
+	require.NoError(t, json.Unmarshal(buildProcess("p_policy", []models.Node{
+		{ID: "log", Type: "logger", SecretRef: "payroll_db", Config: map[string]interface{}{"message": "x"}},
+	}), &process))
+	process.Definition.Settings.SecretsAllowed = []string{"sec_log"}
+
+	ctx, err := exec.Execute(&process, map[string]interface{}{})
+	require.Error(t, err)
+	assert.ErrorIs(t, err, secrets.ErrNotAllowed)
+	assert.ErrorContains(t, err, `"payroll_db" is not in settings.secrets_allowed`)
+	assert.Empty(t, resolver.usages)
+	assert.Equal(t, "error", ctx.Nodes["log"]["status"])
+
+	process.Nodes[0].SecretRef = "sec_log"
+	_, err = exec.Execute(&process, map[string]interface{}{})
+	require.NoError(t, err)
+	assert.Len(t, resolver.usages, 1)
+}
+
+// chunkingActivity stands in for a sql node streaming three chunks.
+type chunkingActivity struct{}
+
+func (chunkingActivity) Name() string { return "sql" }
+
+func (chunkingActivity) Execute(_ map[string]interface{}, _ map[string]interface{}, ctx *models.ExecutionContext) (map[string]interface{}, error) {
+	for i := 1; i <= 3; i++ {
+		if err := ctx.EmitChunk(map[string]interface{}{"chunk": i}); err != nil {
+			return nil, err
+		}
+	}
+	return map[string]interface{}{"chunks": 3}, nil
+}
+
+// TestTransition_ChunkRunsChainPerChunk verifies that the chain behind a
+// chunk transition runs once per chunk while the node runs, and the success
+// transitions once after it.
+func TestTransition_ChunkRunsChainPerChunk(t *testing.T) {
+	exec := newTestExecutor(t)
+	exec.activityRegistry.Register(chunkingActivity{})
+	counter := &countingActivity{}
+	exec.activityRegistry.Register(counter)
+	process := &models.Process{
+		Definition: models.Definition{ID: "trans-chunk", Version: "1.0.0", Name: "trans-chunk"},
+		Trigger:    models.Trigger{ID: "trg", Type: "manual"},
+		Nodes: []models.Node{
+			{ID: "read", Type: "sql"},
+			{ID: "upsert", Type: "counting", InputMapping: map[string]interface{}{"chunk": "$.nodes.read.output.chunk"}},
+			{ID: "report", Type: "logger", Config: map[string]interface{}{"level": "info"}},
+		},
+		Transitions: []models.Transition{
+			{From: "read", To: "upsert", Type: models.TransitionChunk},
+			{From: "read", To: "report", Type: "success"},
+		},
+	}
+
+	ctx, err := exec.Execute(process, map[string]interface{}{})
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{1, 2, 3}, counter.seen)
+	out, _ := ctx.GetValue("$.nodes.read.output.chunks")
+	assert.Equal(t, 3, out)
+	s, _ := ctx.GetValue("$.nodes.report.status")
+	assert.Equal(t, "success", s)
+}
+
+// countingActivity records the chunk input of every run.
+type countingActivity struct {
+	seen []interface{}
+}
+
+func (*countingActivity) Name() string { return "counting" }
+
+func (a *countingActivity) Execute(input map[string]interface{}, _ map[string]interface{}, _ *models.ExecutionContext) (map[string]interface{}, error) {
+	a.seen = append(a.seen, input["chunk"])
+	return map[string]interface{}{}, nil
+}
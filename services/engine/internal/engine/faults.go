@@ -0,0 +1,176 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"time"
+
+	"flowjs-works/engine/internal/logging"
+	"flowjs-works/engine/internal/models"
+)
+
+// FaultInjectionHeader is the trigger request header that turns on fault
+// injection for one execution when AllowFaultInjectionHeader was called. Its
+// value is parsed by models.ParseFaultInjection.
+const FaultInjectionHeader = "X-Fault-Injection"
+
+// ErrInjectedFault is the error of a node attempt failed by fault injection.
+var ErrInjectedFault = errors.New("injected fault")
+
+// faultInjector draws the faults of one execution.
+type faultInjector struct {
+	cfg models.FaultInjection
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// nodeFaults are the faults drawn for one node attempt.
+type nodeFaults struct {
+	latency  time.Duration
+	fail     bool
+	truncate bool
+}
+
+func newFaultInjector(cfg models.FaultInjection) *faultInjector {
+	var src rand.Source
+	if cfg.Seed != 0 {
+		src = rand.NewPCG(uint64(cfg.Seed), uint64(cfg.Seed))
+	} else {
+		src = rand.NewPCG(rand.Uint64(), rand.Uint64())
+	}
+	return &faultInjector{cfg: cfg, rng: rand.New(src)}
+}
+
+// draw returns the faults of the next attempt of node nodeID. Every fault is
+// drawn, in a fixed order, so a seed repeats the same faults.
+func (f *faultInjector) draw(nodeID string) nodeFaults {
+	if !f.cfg.Targets(nodeID) {
+		return nodeFaults{}
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out nodeFaults
+	if f.rng.Float64() < f.cfg.LatencyRate {
+		out.latency = time.Duration(f.cfg.LatencyMs) * time.Millisecond
+	}
+	out.fail = f.rng.Float64() < f.cfg.ErrorRate
+	out.truncate = f.rng.Float64() < f.cfg.TruncateRate
+	return out
+}
+
+// AllowFaultInjectionHeader lets trigger requests turn on fault injection
+// with the X-Fault-Injection header. Without it only
+// settings.fault_injection does, so callers cannot break production runs.
+func (e *ProcessExecutor) AllowFaultInjectionHeader() {
+	e.faultHeader = true
+}
+
+// startFaults registers the fault injection of an execution: the
+// X-Fault-Injection header of its trigger data when allowed, else the process
+// settings. It returns false when the execution runs without faults.
+func (e *ProcessExecutor) startFaults(process *models.Process, ctx *models.ExecutionContext) bool {
+	cfg := process.Definition.Settings.FaultInjection
+	if e.faultHeader {
+		if value := faultHeader(ctx.Trigger); value != "" {
+			parsed, err := models.ParseFaultInjection(value)
+			if err != nil {
+				logging.ForExecution(ctx).Warn("ignoring invalid fault injection header", logging.KeyError, err)
+			} else {
+				cfg = parsed
+			}
+		}
+	}
+	if cfg == nil {
+		return false
+	}
+	logging.ForExecution(ctx).Warn("fault injection enabled", "latency_rate", cfg.LatencyRate, "error_rate", cfg.ErrorRate, "truncate_rate", cfg.TruncateRate)
+	e.faults.Store(ctx.ExecutionID, newFaultInjector(*cfg))
+	return true
+}
+
+// stopFaults forgets the fault injection of an execution.
+func (e *ProcessExecutor) stopFaults(executionID string) {
+	e.faults.Delete(executionID)
+}
+
+// faultsFor returns the fault injector of an execution, or nil.
+func (e *ProcessExecutor) faultsFor(executionID string) *faultInjector {
+	if v, ok := e.faults.Load(executionID); ok {
+		return v.(*faultInjector)
+	}
+	return nil
+}
+
+// faultHeader returns the X-Fault-Injection header of trigger data, matching
+// its name case-insensitively.
+func faultHeader(triggerData map[string]interface{}) string {
+	headers, _ := triggerData["headers"].(map[string]interface{})
+	for k, v := range headers {
+		if strings.EqualFold(k, FaultInjectionHeader) {
+			s, _ := v.(string)
+			return s
+		}
+	}
+	return ""
+}
+
+// injectBefore applies the faults drawn for an attempt before its activity
+// runs: it sleeps the latency, capped by what is left of the timeout, and
+// returns ErrInjectedFault for an injected error.
+func injectBefore(f nodeFaults, node *models.Node, ctx *models.ExecutionContext, attempt int, logger *slog.Logger) error {
+	if f.latency > 0 {
+		d := f.latency
+		if left, ok := ctx.Remaining(); ok && left < d {
+			d = max(left, 0)
+		}
+		logger.Warn("injecting latency", "attempt", attempt, "latency_ms", d.Milliseconds())
+		time.Sleep(d)
+	}
+	if f.fail {
+		logger.Warn("injecting error", "attempt", attempt)
+		return fmt.Errorf("%w: node %s attempt %d", ErrInjectedFault, node.ID, attempt)
+	}
+	return nil
+}
+
+// truncateValue returns a copy of v whose strings and arrays, at any depth,
+// keep their first half, as a response cut off in transit would.
+func truncateValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case string:
+		r := []rune(val)
+		return string(r[:len(r)/2])
+	case []interface{}:
+		out := make([]interface{}, len(val)/2)
+		for i := range out {
+			out[i] = truncateValue(val[i])
+		}
+		return out
+	case []map[string]interface{}:
+		out := make([]map[string]interface{}, len(val)/2)
+		for i := range out {
+			out[i] = truncateOutput(val[i])
+		}
+		return out
+	case map[string]interface{}:
+		return truncateOutput(val)
+	default:
+		return v
+	}
+}
+
+// truncateOutput applies truncateValue to every field of an activity output.
+func truncateOutput(output map[string]interface{}) map[string]interface{} {
+	if output == nil {
+		return nil
+	}
+	out := make(map[string]interface{}, len(output))
+	for k, v := range output {
+		out[k] = truncateValue(v)
+	}
+	return out
+}
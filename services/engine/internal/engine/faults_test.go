@@ -0,0 +1,114 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"flowjs-works/engine/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// payloadActivity returns a fixed output and counts its calls.
+type payloadActivity struct {
+	calls int
+}
+
+func (a *payloadActivity) Name() string { return "payload" }
+
+func (a *payloadActivity) Execute(map[string]interface{}, map[string]interface{}, *models.ExecutionContext) (map[string]interface{}, error) {
+	a.calls++
+	return map[string]interface{}{
+		"body":  "abcdef",
+		"items": []interface{}{map[string]interface{}{"sku": "ab"}, "b", "c"},
+		"code":  200,
+	}, nil
+}
+
+func faultProcess(faults *models.FaultInjection) *models.Process {
+	return &models.Process{
+		Definition: models.Definition{ID: "faults", Settings: models.ProcessSettings{FaultInjection: faults}},
+		Trigger:    models.Trigger{ID: "trg", Type: "rest"},
+		Nodes: []models.Node{
+			{ID: "call", Type: "payload"},
+			{ID: "on_error", Type: "logger"},
+		},
+		Transitions: []models.Transition{{From: "call", To: "on_error", Type: "error"}},
+	}
+}
+
+// TestFaultInjection_ErrorTakesErrorTransition verifies that an injected
+// error fails the node without running the activity, so the error
+// transition is taken.
+func TestFaultInjection_ErrorTakesErrorTransition(t *testing.T) {
+	exec := newTestExecutor(t)
+	act := &payloadActivity{}
+	exec.RegisterActivity(act)
+
+	ctx, err := exec.Execute(faultProcess(&models.FaultInjection{ErrorRate: 1, Nodes: []string{"call"}}), map[string]interface{}{})
+	require.NoError(t, err)
+	assert.Equal(t, 0, act.calls)
+	assert.Equal(t, "error", ctx.Nodes["call"]["status"])
+	assert.Equal(t, "success", ctx.Nodes["on_error"]["status"])
+
+	// Faults are limited to the listed nodes.
+	ctx, err = exec.Execute(faultProcess(&models.FaultInjection{ErrorRate: 1, Nodes: []string{"other"}}), map[string]interface{}{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, act.calls)
+	assert.Equal(t, "success", ctx.Nodes["call"]["status"])
+	assert.Empty(t, exec.faultsFor(ctx.ExecutionID), "the injector is released with the execution")
+}
+
+func TestFaultInjection_TruncatesOutput(t *testing.T) {
+	exec := newTestExecutor(t)
+	exec.RegisterActivity(&payloadActivity{})
+
+	ctx, err := exec.Execute(faultProcess(&models.FaultInjection{TruncateRate: 1}), map[string]interface{}{})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"body":  "abc",
+		"items": []interface{}{map[string]interface{}{"sku": "a"}},
+		"code":  200,
+	}, ctx.Nodes["call"]["output"])
+}
+
+// TestFaultInjection_Header verifies that the X-Fault-Injection header only
+// turns faults on when the engine allows it, and replaces the settings.
+func TestFaultInjection_Header(t *testing.T) {
+	exec := newTestExecutor(t)
+	act := &payloadActivity{}
+	exec.RegisterActivity(act)
+	trigger := map[string]interface{}{"headers": map[string]interface{}{"X-Fault-Injection": "error=1,nodes=call"}}
+
+	ctx, err := exec.Execute(faultProcess(nil), trigger)
+	require.NoError(t, err)
+	assert.Equal(t, "success", ctx.Nodes["call"]["status"], "header ignored unless allowed")
+
+	exec.AllowFaultInjectionHeader()
+	ctx, err = exec.Execute(faultProcess(&models.FaultInjection{TruncateRate: 1}), trigger)
+	require.NoError(t, err)
+	assert.Equal(t, "error", ctx.Nodes["call"]["status"])
+
+	trigger["headers"] = map[string]interface{}{"x-fault-injection": "error=2"}
+	ctx, err = exec.Execute(faultProcess(nil), trigger)
+	require.NoError(t, err)
+	assert.Equal(t, "success", ctx.Nodes["call"]["status"], "an invalid header is ignored")
+}
+
+func TestFaultInjector_SeedRepeatsDraws(t *testing.T) {
+	cfg := models.FaultInjection{LatencyRate: 0.5, LatencyMs: 10, ErrorRate: 0.5, TruncateRate: 0.5, Seed: 7}
+	a, b := newFaultInjector(cfg), newFaultInjector(cfg)
+	var failures int
+	for i := 0; i < 100; i++ {
+		fa := a.draw("n")
+		require.Equal(t, fa, b.draw("n"))
+		if fa.fail {
+			failures++
+		}
+		if fa.latency != 0 {
+			assert.Equal(t, 10*time.Millisecond, fa.latency)
+		}
+	}
+	assert.InDelta(t, 50, failures, 20)
+}
@@ -0,0 +1,36 @@
+package engine
+
+import (
+	"flowjs-works/engine/internal/models"
+	"flowjs-works/engine/internal/notify"
+)
+
+// Notifier delivers process events to the notification channels subscribed
+// to them. notify.Dispatcher implements it; Notify must not block.
+type Notifier interface {
+	Notify(ev notify.Event)
+}
+
+// SetNotifier makes the executor report failed executions, SLA breaches and
+// deploys to n.
+func (e *ProcessExecutor) SetNotifier(n Notifier) {
+	e.notifier = n
+}
+
+// notify passes ev to the notifier, if any.
+func (e *ProcessExecutor) notify(ev notify.Event) {
+	if e.notifier != nil {
+		e.notifier.Notify(ev)
+	}
+}
+
+// notifyFailure reports the failed execution of ctx.
+func (e *ProcessExecutor) notifyFailure(ctx *models.ExecutionContext, errorMsg string) {
+	e.notify(notify.Event{
+		Type:        notify.EventFailure,
+		Workspace:   ctx.Workspace,
+		ProcessID:   ctx.ProcessID,
+		ExecutionID: ctx.ExecutionID,
+		Message:     errorMsg,
+	})
+}
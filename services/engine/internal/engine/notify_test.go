@@ -0,0 +1,67 @@
+package engine
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"flowjs-works/engine/internal/models"
+	"flowjs-works/engine/internal/notify"
+)
+
+type fakeNotifier struct {
+	mu     sync.Mutex
+	events []notify.Event
+}
+
+func (f *fakeNotifier) Notify(ev notify.Event) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.events = append(f.events, ev)
+}
+
+func (f *fakeNotifier) types() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var types []string
+	for _, ev := range f.events {
+		types = append(types, ev.Type)
+	}
+	return types
+}
+
+func TestNotifier_FailureSLABreachAndDeploy(t *testing.T) {
+	exec := newTestExecutor(t)
+	n := &fakeNotifier{}
+	exec.SetNotifier(n)
+	exec.activityRegistry.Register(&slowActivity{delay: 10 * time.Millisecond})
+
+	ok := &models.Process{
+		Definition: models.Definition{ID: "p_notify", Version: "1.0.0", Workspace: "team-a"},
+		Nodes:      []models.Node{{ID: "call", Type: "slow", SLAMs: 1}},
+	}
+	_, err := exec.Execute(ok, map[string]interface{}{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{notify.EventSLABreach}, n.types(), "a successful run only reports the breach")
+	assert.Equal(t, "call", n.events[0].NodeID)
+	assert.Equal(t, "team-a", n.events[0].Workspace)
+
+	failing := &models.Process{
+		Definition: models.Definition{ID: "p_notify", Version: "1.0.0"},
+		Nodes:      []models.Node{{ID: "bad", Type: "no_such_activity"}},
+	}
+	ctx, err := exec.Execute(failing, map[string]interface{}{})
+	require.Error(t, err)
+	require.Len(t, n.events, 2)
+	failure := n.events[1]
+	assert.Equal(t, notify.EventFailure, failure.Type)
+	assert.Equal(t, ctx.ExecutionID, failure.ExecutionID)
+	assert.Contains(t, failure.Message, "unknown activity type")
+
+	exec.SendLifecycleAuditLog("default", "p_notify", "rest", "stopped", "")
+	exec.SendLifecycleAuditLog("default", "p_notify", "rest", "deployed", "")
+	assert.Equal(t, []string{notify.EventSLABreach, notify.EventFailure, notify.EventDeploy}, n.types())
+}
@@ -0,0 +1,90 @@
+package engine
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"flowjs-works/engine/internal/models"
+)
+
+// auditNode publishes the audit event of a node run, with its input and
+// output reduced to the persistence level of the execution.
+func (e *ProcessExecutor) auditNode(ctx *models.ExecutionContext, node *models.Node, status string, input, output map[string]interface{}, errorMsg string) {
+	e.auditExecution(ctx, node.ID, node.Type, status,
+		auditPayload(ctx.Persistence, input), auditPayload(ctx.Persistence, output), errorMsg, nil)
+}
+
+// auditNodeRun is auditNode for a node whose activity ran, recording how long
+// the run took as duration_ms and how many times it was attempted as attempt.
+func (e *ProcessExecutor) auditNodeRun(ctx *models.ExecutionContext, node *models.Node, status string, input, output map[string]interface{}, errorMsg string, duration time.Duration, attempt int) {
+	e.auditExecution(ctx, node.ID, node.Type, status,
+		auditPayload(ctx.Persistence, input), auditPayload(ctx.Persistence, output), errorMsg,
+		map[string]interface{}{"duration_ms": duration.Milliseconds(), "attempt": attempt})
+}
+
+// auditExecution publishes an audit event of ctx's execution, adding extra
+// and the version of the process it runs as process_version.
+func (e *ProcessExecutor) auditExecution(ctx *models.ExecutionContext, nodeID, nodeType, status string, input, output map[string]interface{}, errorMsg string, extra map[string]interface{}) {
+	if ctx.ProcessVersion != "" {
+		withVersion := make(map[string]interface{}, len(extra)+1)
+		for k, v := range extra {
+			withVersion[k] = v
+		}
+		withVersion["process_version"] = ctx.ProcessVersion
+		extra = withVersion
+	}
+	e.sendAuditMessage(ctx.Workspace, ctx.ExecutionID, ctx.ParentExecutionID, ctx.ProcessID, nodeID, nodeType, status, input, output, errorMsg, extra)
+}
+
+// auditPayload returns the form of data recorded in the audit log at level:
+// the data itself for "full" (or unset), its sorted keys and JSON size for
+// "minimal", and nil for "none".
+func auditPayload(level string, data map[string]interface{}) map[string]interface{} {
+	switch level {
+	case models.PersistenceNone:
+		return nil
+	case models.PersistenceMinimal:
+		if data == nil {
+			return nil
+		}
+		keys := make([]string, 0, len(data))
+		for k := range data {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		size := 0
+		if b, err := json.Marshal(data); err == nil {
+			size = len(b)
+		}
+		return map[string]interface{}{"keys": keys, "bytes": size}
+	default:
+		return data
+	}
+}
+
+// snapshotContext returns the form of ctx saved as the execution snapshot.
+// With "minimal" persistence only node statuses and visit counts are kept;
+// trigger data and node outputs are dropped.
+func snapshotContext(ctx *models.ExecutionContext) *models.ExecutionContext {
+	if ctx.Persistence != models.PersistenceMinimal {
+		return ctx
+	}
+	snap := models.NewExecutionContext(ctx.ExecutionID)
+	snap.ProcessID = ctx.ProcessID
+	snap.Workspace = ctx.Workspace
+	snap.Persistence = ctx.Persistence
+	snap.ProcessVersion = ctx.ProcessVersion
+	snap.ProcessRevision = ctx.ProcessRevision
+	snap.DSLHash = ctx.DSLHash
+	for id, state := range ctx.NodeStates() {
+		kept := make(map[string]interface{})
+		for _, field := range []string{"status", "visits"} {
+			if v, ok := state[field]; ok {
+				kept[field] = v
+			}
+		}
+		snap.Nodes[id] = kept
+	}
+	return snap
+}
@@ -0,0 +1,101 @@
+package engine
+
+import (
+	"testing"
+
+	"flowjs-works/engine/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditPayload_Levels(t *testing.T) {
+	data := map[string]interface{}{"user": "ana", "amount": 12.5}
+
+	assert.Equal(t, data, auditPayload("", data))
+	assert.Equal(t, data, auditPayload(models.PersistenceFull, data))
+	assert.Nil(t, auditPayload(models.PersistenceNone, data))
+	assert.Equal(t, map[string]interface{}{"keys": []string{"amount", "user"}, "bytes": 28},
+		auditPayload(models.PersistenceMinimal, data))
+	assert.Nil(t, auditPayload(models.PersistenceMinimal, nil))
+}
+
+// TestExecute_MinimalPersistenceSnapshot verifies that a "minimal" snapshot
+// keeps node statuses but no payloads, and cannot be retried.
+func TestExecute_MinimalPersistenceSnapshot(t *testing.T) {
+	exec := newTestExecutor(t)
+	snaps := &recordingSnapshots{}
+	exec.SetSnapshotSaver(snaps)
+
+	process := &models.Process{
+		Definition: models.Definition{ID: "p_minimal", Version: "1.0.0", Settings: models.ProcessSettings{Persistence: models.PersistenceMinimal}},
+		Nodes: []models.Node{
+			{ID: "log", Type: "logger", Config: map[string]interface{}{"message": "x"}},
+			{ID: "bad", Type: "not_a_node_type"},
+		},
+		Transitions: []models.Transition{{From: "log", To: "bad", Type: "success"}},
+	}
+	ctx, err := exec.Execute(process, map[string]interface{}{"card": "4111"})
+	require.Error(t, err)
+	require.NotNil(t, ctx.Nodes["log"]["output"], "the live context keeps outputs")
+
+	require.Len(t, snaps.saved, 1)
+	snap := snaps.saved[0]
+	assert.Empty(t, snap.Trigger)
+	assert.Equal(t, map[string]interface{}{"status": "success"}, snap.Nodes["log"])
+	assert.Equal(t, map[string]interface{}{"status": "error"}, snap.Nodes["bad"])
+
+	_, err = exec.RetryExecution(process, snap)
+	assert.ErrorIs(t, err, ErrSnapshotIncomplete)
+}
+
+// TestAuditNodeRun_RecordsRunMetadata verifies that node events carry their
+// duration and attempt, and every event the engine and process version.
+func TestAuditNodeRun_RecordsRunMetadata(t *testing.T) {
+	pub := &flakyPublisher{}
+	exec := newAuditingExecutor(t, pub)
+	exec.SetEngineID("engine-a")
+
+	_, err := exec.Execute(sampledProcess(nil, `({ ok: true })`), map[string]interface{}{})
+	require.NoError(t, err)
+	got := pub.received()
+	require.Len(t, got, 3)
+	assert.NotContains(t, got[0], `"duration_ms"`, "process events carry no duration")
+	assert.NotContains(t, got[0], `"attempt"`)
+	assert.Contains(t, got[1], `"node_id":"step"`)
+	assert.Contains(t, got[1], `"duration_ms":`)
+	assert.Contains(t, got[1], `"attempt":1`)
+	for _, msg := range got {
+		assert.Contains(t, msg, `"engine_id":"engine-a"`)
+		assert.Contains(t, msg, `"process_version":"1.0.0"`)
+	}
+}
+
+// TestExecute_SensitiveInputsAreNotRecorded verifies that a sensitive input
+// reaches the activity but appears only as a reference in its audit event,
+// its stored output and its error.
+func TestExecute_SensitiveInputsAreNotRecorded(t *testing.T) {
+	pub := &flakyPublisher{}
+	exec := newAuditingExecutor(t, pub)
+	process := sampledProcess(nil, `({ header: "Bearer " + input.token, length: input.token.length })`)
+	process.Nodes[0].InputMapping = map[string]interface{}{"token": "$.trigger.token", "user": "$.trigger.user"}
+	process.Nodes[0].SensitiveInputs = []string{"token"}
+
+	ctx, err := exec.Execute(process, map[string]interface{}{"token": "s3cr3t-token", "user": "ana"})
+	require.NoError(t, err)
+	out := ctx.Nodes["step"]["output"].(map[string]interface{})
+	assert.Equal(t, "Bearer [sensitive:token]", out["header"])
+	assert.EqualValues(t, 12, out["length"], "the activity saw the real value")
+
+	got := pub.received()
+	require.Len(t, got, 3)
+	assert.NotContains(t, got[1], "s3cr3t-token")
+	assert.Contains(t, got[1], `"token":"[sensitive:token]"`)
+	assert.Contains(t, got[1], `"user":"ana"`)
+
+	process.Nodes[0].Script = `throw new Error("rejected " + input.token)`
+	_, err = exec.Execute(process, map[string]interface{}{"token": "s3cr3t-token", "user": "ana"})
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "s3cr3t-token")
+	assert.Contains(t, err.Error(), "rejected [sensitive:token]")
+}
@@ -0,0 +1,49 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"flowjs-works/engine/internal/models"
+	"flowjs-works/engine/internal/tenant"
+)
+
+// errNoProfileSource is returned when a node references a connection profile
+// but the engine runs without a config DB.
+var errNoProfileSource = errors.New("connection profiles not configured (DATABASE_URL missing)")
+
+// ProfileSource returns a connection profile by name in the workspace carried
+// by ctx. store.ProfileStore implements it on the config DB.
+type ProfileSource interface {
+	Profile(ctx context.Context, name string) (*models.ConnectionProfile, error)
+}
+
+// SetProfileSource lets nodes reference the connection profiles of s.
+func (e *ProcessExecutor) SetProfileSource(s ProfileSource) {
+	e.profiles = s
+}
+
+// applyProfile layers config over the profile of node, read when the node
+// runs so profile changes apply to every process without a redeploy. It
+// returns the secret the node uses: its own secret_ref, else the profile's.
+func (e *ProcessExecutor) applyProfile(ctx *models.ExecutionContext, node *models.Node, config map[string]interface{}) (map[string]interface{}, string, error) {
+	if node.Profile == "" {
+		return config, node.SecretRef, nil
+	}
+	if e.profiles == nil {
+		return nil, "", errNoProfileSource
+	}
+	p, err := e.profiles.Profile(tenant.WithWorkspace(context.Background(), ctx.Workspace), node.Profile)
+	if err != nil {
+		return nil, "", fmt.Errorf("load profile %q: %w", node.Profile, err)
+	}
+	if p.Type != "" && p.Type != node.Type {
+		return nil, "", fmt.Errorf("profile %q is for %s nodes, not %s", node.Profile, p.Type, node.Type)
+	}
+	secretRef := node.SecretRef
+	if secretRef == "" {
+		secretRef = p.SecretRef
+	}
+	return p.Apply(config), secretRef, nil
+}
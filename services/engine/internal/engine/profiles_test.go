@@ -0,0 +1,83 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"flowjs-works/engine/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// configActivity records the config of each call.
+type configActivity struct {
+	configs []map[string]interface{}
+}
+
+func (a *configActivity) Name() string { return "sftp" }
+
+func (a *configActivity) Execute(_ map[string]interface{}, config map[string]interface{}, _ *models.ExecutionContext) (map[string]interface{}, error) {
+	a.configs = append(a.configs, config)
+	return map[string]interface{}{}, nil
+}
+
+// mapProfiles is a ProfileSource over a map.
+type mapProfiles map[string]*models.ConnectionProfile
+
+func (m mapProfiles) Profile(_ context.Context, name string) (*models.ConnectionProfile, error) {
+	if p, ok := m[name]; ok {
+		return p, nil
+	}
+	return nil, fmt.Errorf("profile %q not found", name)
+}
+
+// secretIDResolver records the ids it resolves.
+type secretIDResolver struct {
+	ids []string
+}
+
+func (r *secretIDResolver) Resolve(_ context.Context, id string) (map[string]interface{}, error) {
+	r.ids = append(r.ids, id)
+	return map[string]interface{}{"password": "pw-" + id}, nil
+}
+
+// TestExecute_ConnectionProfile verifies that a node's config is layered over
+// its profile's and that the profile's secret is used unless the node has one.
+func TestExecute_ConnectionProfile(t *testing.T) {
+	exec := newTestExecutor(t)
+	act := &configActivity{}
+	exec.RegisterActivity(act)
+	resolver := &secretIDResolver{}
+	exec.SetSecretResolver(resolver)
+	exec.SetProfileSource(mapProfiles{
+		"partner": {Name: "partner", Type: "sftp", SecretRef: "sec_partner",
+			Config: map[string]interface{}{"server": "sftp.partner.com", "port": 22, "folder": "/in"}},
+		"mailer": {Name: "mailer", Type: "mail"},
+	})
+
+	process := &models.Process{
+		Definition: models.Definition{ID: "p_profile"},
+		Nodes: []models.Node{
+			{ID: "a", Type: "sftp", Profile: "partner", Config: map[string]interface{}{"folder": "/out"}},
+			{ID: "b", Type: "sftp", Profile: "partner", SecretRef: "sec_own"},
+		},
+		Transitions: []models.Transition{{From: "a", To: "b", Type: "success"}},
+	}
+	_, err := exec.Execute(process, map[string]interface{}{})
+	require.NoError(t, err)
+	require.Len(t, act.configs, 2)
+	assert.Equal(t, map[string]interface{}{"server": "sftp.partner.com", "port": 22, "folder": "/out", "password": "pw-sec_partner"}, act.configs[0])
+	assert.Equal(t, "pw-sec_own", act.configs[1]["password"])
+	assert.Equal(t, []string{"sec_partner", "sec_own"}, resolver.ids)
+
+	process.Nodes = []models.Node{{ID: "a", Type: "sftp", Profile: "mailer"}}
+	process.Transitions = nil
+	_, err = exec.Execute(process, map[string]interface{}{})
+	assert.ErrorContains(t, err, `profile "mailer" is for mail nodes`)
+
+	process.Nodes = []models.Node{{ID: "a", Type: "sftp", Profile: "missing"}}
+	_, err = exec.Execute(process, map[string]interface{}{})
+	assert.ErrorContains(t, err, `load profile "missing"`)
+}
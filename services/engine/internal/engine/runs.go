@@ -0,0 +1,61 @@
+package engine
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"flowjs-works/engine/internal/logging"
+	"flowjs-works/engine/internal/models"
+	"flowjs-works/engine/internal/tenant"
+)
+
+// runRecordInterval is the minimum time between two recorded runs of the
+// same process, so busy flows do not write on every execution.
+const runRecordInterval = time.Minute
+
+// RunRecorder records when a process last started an execution, so archived
+// processes still in use are not purged. store.ProcessStore implements it on
+// the config DB.
+type RunRecorder interface {
+	RecordRun(ctx context.Context, processID string, at time.Time) error
+}
+
+// runTracker throttles the runs passed to a RunRecorder per process.
+type runTracker struct {
+	recorder RunRecorder
+	mu       sync.Mutex
+	last     map[string]time.Time
+}
+
+// SetRunRecorder makes the executor record the start of every execution in
+// r, at most once per runRecordInterval per process.
+func (e *ProcessExecutor) SetRunRecorder(r RunRecorder) {
+	e.runs = &runTracker{recorder: r, last: make(map[string]time.Time)}
+}
+
+// recordRun reports the execution of ctx to the run recorder, if any.
+func (e *ProcessExecutor) recordRun(ctx *models.ExecutionContext) {
+	t := e.runs
+	if t == nil || ctx.ProcessID == "" {
+		return
+	}
+	now := time.Now()
+	t.mu.Lock()
+	if now.Sub(t.last[ctx.ProcessID]) < runRecordInterval {
+		t.mu.Unlock()
+		return
+	}
+	t.last[ctx.ProcessID] = now
+	t.mu.Unlock()
+
+	recordCtx, cancel := context.WithTimeout(tenant.WithWorkspace(context.Background(), ctx.Workspace), snapshotTimeout)
+	defer cancel()
+	if err := t.recorder.RecordRun(recordCtx, ctx.ProcessID, now); err != nil {
+		logging.ForExecution(ctx).Warn("failed to record process run", logging.KeyError, err)
+		// Try again on the next execution.
+		t.mu.Lock()
+		delete(t.last, ctx.ProcessID)
+		t.mu.Unlock()
+	}
+}
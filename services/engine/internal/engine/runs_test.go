@@ -0,0 +1,61 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"flowjs-works/engine/internal/models"
+)
+
+type fakeRunRecorder struct {
+	mu   sync.Mutex
+	runs []string
+	err  error
+}
+
+func (f *fakeRunRecorder) RecordRun(_ context.Context, processID string, _ time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.runs = append(f.runs, processID)
+	return f.err
+}
+
+func TestRecordRun_ThrottledPerProcess(t *testing.T) {
+	exec := newTestExecutor(t)
+	rec := &fakeRunRecorder{}
+	exec.SetRunRecorder(rec)
+
+	process := buildProcess("p_runs", []models.Node{
+		{ID: "log", Type: "logger", Config: map[string]interface{}{"level": "info"}},
+	})
+	for i := 0; i < 3; i++ {
+		_, err := exec.ExecuteFromJSON(process, map[string]interface{}{})
+		require.NoError(t, err)
+	}
+	assert.Equal(t, []string{"p_runs"}, rec.runs, "runs within runRecordInterval are recorded once")
+
+	other := buildProcess("p_other", []models.Node{
+		{ID: "log", Type: "logger", Config: map[string]interface{}{"level": "info"}},
+	})
+	_, err := exec.ExecuteFromJSON(other, map[string]interface{}{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"p_runs", "p_other"}, rec.runs)
+}
+
+func TestRecordRun_RetriesAfterFailure(t *testing.T) {
+	exec := newTestExecutor(t)
+	rec := &fakeRunRecorder{err: errors.New("db down")}
+	exec.SetRunRecorder(rec)
+
+	ctx := models.NewExecutionContext("exec-1")
+	ctx.ProcessID = "p_fail"
+	exec.recordRun(ctx)
+	exec.recordRun(ctx)
+	assert.Len(t, rec.runs, 2, "a failed record must not be throttled")
+}
@@ -0,0 +1,69 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"flowjs-works/engine/internal/models"
+	"flowjs-works/engine/internal/schema"
+	"flowjs-works/engine/internal/tenant"
+)
+
+// errNoSchemaSource is returned when a process references a registry schema
+// but the engine runs without a config DB.
+var errNoSchemaSource = errors.New("schema registry not configured (DATABASE_URL missing)")
+
+// SetSchemaSource lets triggers and nodes validate payloads against the
+// named schemas of s.
+func (e *ProcessExecutor) SetSchemaSource(s schema.Source) {
+	e.schemas = s
+}
+
+// validatePayload checks v against the registry schema name in the
+// workspace of ctx.
+func (e *ProcessExecutor) validatePayload(ctx *models.ExecutionContext, name string, v interface{}) error {
+	if e.schemas == nil {
+		return errNoSchemaSource
+	}
+	s, err := e.schemas.Schema(tenant.WithWorkspace(context.Background(), ctx.Workspace), name)
+	if err != nil {
+		return fmt.Errorf("load schema %q: %w", name, err)
+	}
+	if err := schema.Validate(s, v); err != nil {
+		return fmt.Errorf("schema %q: %w", name, err)
+	}
+	return nil
+}
+
+// triggerPayload returns the part of the trigger data that trigger.config
+// "schema" describes: the request body of REST triggers, the operation
+// element of SOAP triggers (the raw body when there is none), the message
+// payload of RabbitMQ triggers and the whole trigger data otherwise.
+func triggerPayload(trigger models.Trigger, data map[string]interface{}) interface{} {
+	switch trigger.Type {
+	case "soap":
+		if payload, ok := data["payload"]; ok {
+			return payload
+		}
+		return data["body"]
+	case "rest":
+		return data["body"]
+	case "rabbitmq":
+		return data["payload"]
+	}
+	return data
+}
+
+// validateTrigger checks the trigger data against trigger.config "schema",
+// when set.
+func (e *ProcessExecutor) validateTrigger(process *models.Process, ctx *models.ExecutionContext, data map[string]interface{}) error {
+	name, _ := process.Trigger.Config["schema"].(string)
+	if name == "" {
+		return nil
+	}
+	if err := e.validatePayload(ctx, name, triggerPayload(process.Trigger, data)); err != nil {
+		return fmt.Errorf("trigger payload: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"flowjs-works/engine/internal/models"
+	"flowjs-works/engine/internal/schema"
+	"flowjs-works/engine/internal/tenant"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mapSchemas is a schema.Source keyed by workspace/name.
+type mapSchemas map[string]map[string]interface{}
+
+func (m mapSchemas) Schema(ctx context.Context, name string) (map[string]interface{}, error) {
+	s, ok := m[tenant.Workspace(ctx)+"/"+name]
+	if !ok {
+		return nil, fmt.Errorf("schema %q not found", name)
+	}
+	return s, nil
+}
+
+var requireID = map[string]interface{}{
+	"type":       "object",
+	"required":   []interface{}{"id"},
+	"properties": map[string]interface{}{"id": map[string]interface{}{"type": "string"}},
+}
+
+func schemaProcess(triggerSchema string, node models.Node) *models.Process {
+	return &models.Process{
+		Definition: models.Definition{ID: "schemas", Version: "1.0.0", Name: "schemas"},
+		Trigger:    models.Trigger{ID: "trg", Type: "rest", Config: map[string]interface{}{"schema": triggerSchema}},
+		Nodes:      []models.Node{node},
+	}
+}
+
+func TestExecute_TriggerSchema(t *testing.T) {
+	exec := newTestExecutor(t)
+	exec.SetSchemaSource(mapSchemas{"default/order": requireID})
+	proc := schemaProcess("order", models.Node{ID: "step", Type: "code", Script: `({ ok: true })`})
+
+	ctx, err := exec.Execute(proc, map[string]interface{}{"body": map[string]interface{}{"id": "o-1"}})
+	require.NoError(t, err)
+	assert.Equal(t, "success", ctx.Nodes["step"]["status"])
+
+	ctx, err = exec.Execute(proc, map[string]interface{}{"body": map[string]interface{}{"qty": float64(1)}})
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, schema.ErrViolation))
+	assert.Contains(t, err.Error(), `trigger payload: schema "order"`)
+	assert.NotContains(t, ctx.Nodes, "step", "no node runs")
+}
+
+func TestExecuteNode_InputAndOutputSchema(t *testing.T) {
+	exec := newTestExecutor(t)
+	exec.SetSchemaSource(mapSchemas{"default/order": requireID})
+
+	node := models.Node{ID: "step", Type: "code", Script: `({ id: 7 })`,
+		InputMapping: map[string]interface{}{"id": "$.trigger.id"}, InputSchema: "order"}
+	_, err := exec.Execute(schemaProcess("", node), map[string]interface{}{"id": float64(5)})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `input: schema "order": schema violation: $.id: expected string, got integer`)
+
+	ctx, err := exec.Execute(schemaProcess("", node), map[string]interface{}{"id": "o-1"})
+	require.NoError(t, err, "input matches")
+	assert.Equal(t, "success", ctx.Nodes["step"]["status"])
+
+	node.OutputSchema = "order"
+	ctx, err = exec.Execute(schemaProcess("", node), map[string]interface{}{"id": "o-1"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "$.id: expected string, got integer")
+	assert.Equal(t, "error", ctx.Nodes["step"]["status"])
+}
+
+func TestExecute_SchemaWithoutRegistry(t *testing.T) {
+	exec := newTestExecutor(t)
+	_, err := exec.Execute(schemaProcess("order", models.Node{ID: "step", Type: "code", Script: `({})`}), map[string]interface{}{})
+	assert.ErrorIs(t, err, errNoSchemaSource)
+}
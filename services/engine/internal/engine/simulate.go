@@ -0,0 +1,435 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+
+	"flowjs-works/engine/internal/activities"
+	"flowjs-works/engine/internal/models"
+	"flowjs-works/engine/internal/schema"
+	"flowjs-works/engine/internal/secrets"
+	"flowjs-works/engine/internal/tenant"
+
+	"github.com/google/uuid"
+)
+
+// MaxSimulatedExecutions caps the executions of one simulation.
+const MaxSimulatedExecutions = 1000
+
+// ErrSimulatedFault is the error of a node failed by the error_rate of its
+// simulation mock.
+var ErrSimulatedFault = errors.New("simulated error")
+
+// simulatedActivities are the node types a simulation really runs: they
+// compute their output from their input without side effects. Every other
+// node is stubbed.
+var simulatedActivities = []string{"assert", "code", "faker", "log", "logger", "mapping", "mock_http", "transform"}
+
+// simulatedNodeKey is the config key that marks a node whose output a
+// simulation stubs, holding the node id.
+const simulatedNodeKey = "simulated_node"
+
+// Simulation describes a dry run of a process: Executions runs, each with
+// trigger data taken in turn from Samples or, without samples, generated from
+// a JSON Schema.
+type Simulation struct {
+	Executions int
+	// Seed makes the generated payloads and mock errors repeatable; zero
+	// picks a random seed.
+	Seed int64
+	// Samples are whole trigger data objects, used in turn.
+	Samples []map[string]interface{}
+	// Schema describes the trigger payload to generate, in the shape the
+	// trigger's config "schema" validates (see triggerPayload). Without
+	// Schema, SchemaName names a schema of the registry; without either the
+	// trigger's config "schema" is used, and without that the trigger data
+	// is empty.
+	Schema     map[string]interface{}
+	SchemaName string
+	// Mocks replace the output of nodes by id.
+	Mocks map[string]NodeMock
+}
+
+// NodeMock is the simulated result of a node. A node without a mock that has
+// side effects returns {"simulated": true}.
+type NodeMock struct {
+	// Output is returned by every run; Outputs, when set, are returned in
+	// turn, one per execution.
+	Output  map[string]interface{}   `json:"output,omitempty"`
+	Outputs []map[string]interface{} `json:"outputs,omitempty"`
+	// Error fails every run with this message; ErrorRate fails that share
+	// of runs, from 0 to 1.
+	Error     string  `json:"error,omitempty"`
+	ErrorRate float64 `json:"error_rate,omitempty"`
+}
+
+// SimulationReport is the result of Simulate.
+type SimulationReport struct {
+	Executions int `json:"executions"`
+	Succeeded  int `json:"succeeded"`
+	Failed     int `json:"failed"`
+	// Coverage is the share of transitions that fired at least once, from
+	// 0 to 1. A process without transitions is covered by the share of its
+	// nodes that ran.
+	Coverage    float64              `json:"coverage"`
+	Transitions []TransitionCoverage `json:"transitions"`
+	Nodes       []NodeCoverage       `json:"nodes"`
+	Runs        []SimulatedRun       `json:"runs"`
+}
+
+// TransitionCoverage counts the executions that followed one transition. A
+// dynamic transition is listed once per target.
+type TransitionCoverage struct {
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Type      string `json:"type"`
+	Condition string `json:"condition,omitempty"`
+	Fired     int    `json:"fired"`
+}
+
+// NodeCoverage counts the runs of one node. Stubbed nodes did not run their
+// activity but returned their mock.
+type NodeCoverage struct {
+	NodeID  string `json:"node_id"`
+	Type    string `json:"type"`
+	Runs    int    `json:"runs"`
+	Errors  int    `json:"errors"`
+	Stubbed bool   `json:"stubbed"`
+}
+
+// SimulatedRun is the outcome of one simulated execution: its status, error
+// and the final status of every node that ran.
+type SimulatedRun struct {
+	Index   int                    `json:"index"`
+	Status  string                 `json:"status"`
+	Error   string                 `json:"error,omitempty"`
+	Trigger map[string]interface{} `json:"trigger"`
+	Nodes   map[string]string      `json:"nodes"`
+}
+
+// simulation is the state of one Simulate call.
+type simulation struct {
+	mocks map[string]NodeMock
+	rng   *rand.Rand
+	// run is the index of the running execution.
+	run   int
+	fired map[transitionKey]int
+}
+
+type transitionKey struct {
+	index int
+	to    string
+}
+
+// Simulate runs process sim.Executions times in dry-run mode and reports
+// which transitions and nodes the runs covered, so authors can see the
+// untested paths of a flow. Only side-effect-free nodes (assert, code,
+// faker, log, logger, mapping, mock_http and transform) run; every other node
+// returns its mock.
+// Nothing is audited, persisted or retried, and circuit breakers, SLAs and
+// fault injection are off.
+func (e *ProcessExecutor) Simulate(ctx context.Context, process *models.Process, sim Simulation) (*SimulationReport, error) {
+	if sim.Executions < 1 || sim.Executions > MaxSimulatedExecutions {
+		return nil, fmt.Errorf("executions must be between 1 and %d, got %d", MaxSimulatedExecutions, sim.Executions)
+	}
+	nodes := make(map[string]bool, len(process.Nodes))
+	for _, n := range process.Nodes {
+		nodes[n.ID] = true
+	}
+	for id, m := range sim.Mocks {
+		if !nodes[id] {
+			return nil, fmt.Errorf("mock of unknown node %q", id)
+		}
+		if m.ErrorRate < 0 || m.ErrorRate > 1 {
+			return nil, fmt.Errorf("mock of node %q: error_rate must be between 0 and 1", id)
+		}
+	}
+	seed := uint64(sim.Seed)
+	if seed == 0 {
+		seed = rand.Uint64()
+	}
+	s := &simulation{mocks: sim.Mocks, rng: rand.New(rand.NewPCG(seed, seed)), fired: make(map[transitionKey]int)}
+
+	payloadSchema, err := e.simulationSchema(ctx, process, sim)
+	if err != nil {
+		return nil, err
+	}
+	simulated, stubbed := s.process(process, e.activityRegistry)
+	simulator := e.simulator(s)
+	index := transitionIndex(process)
+	simulator.onTransition = func(_ *models.ExecutionContext, t models.Transition, to string) {
+		if i, ok := index[transitionID(t)]; ok {
+			s.fired[transitionKey{i, to}]++
+		}
+	}
+
+	report := &SimulationReport{Executions: sim.Executions, Runs: make([]SimulatedRun, 0, sim.Executions)}
+	runs := make(map[string]int)
+	errs := make(map[string]int)
+	for i := 0; i < sim.Executions; i++ {
+		s.run = i
+		var data map[string]interface{}
+		switch {
+		case len(sim.Samples) > 0:
+			data = sim.Samples[i%len(sim.Samples)]
+		case payloadSchema != nil:
+			if data, err = simulatedTriggerData(process.Trigger, schema.Generate(payloadSchema, s.rng)); err != nil {
+				return nil, err
+			}
+		default:
+			data = map[string]interface{}{}
+		}
+		execCtx, execErr := simulator.execute(context.Background(), uuid.New().String(), simulated, data, "")
+		run := SimulatedRun{Index: i, Status: "completed", Trigger: data, Nodes: make(map[string]string)}
+		if execErr != nil {
+			run.Status = "failed"
+			run.Error = execErr.Error()
+			report.Failed++
+		} else {
+			report.Succeeded++
+		}
+		timings := execCtx.NodeTimings()
+		for id, timing := range timings {
+			runs[id] += timing.Runs
+		}
+		for id, node := range execCtx.Nodes {
+			status, _ := node["status"].(string)
+			if status == "" {
+				continue
+			}
+			run.Nodes[id] = status
+			if status == "error" {
+				errs[id]++
+			}
+			if _, ok := timings[id]; !ok {
+				// A node that failed before its activity ran has no timing.
+				runs[id]++
+			}
+		}
+		// Transitions from the trigger are not followed by the executor:
+		// they fired when their node ran.
+		for i, t := range process.Transitions {
+			if !nodes[t.From] && run.Nodes[t.To] != "" {
+				s.fired[transitionKey{i, t.To}]++
+			}
+		}
+		report.Runs = append(report.Runs, run)
+	}
+
+	covered := 0
+	report.Transitions = []TransitionCoverage{}
+	for i, t := range process.Transitions {
+		for _, to := range t.Destinations() {
+			c := TransitionCoverage{From: t.From, To: to, Type: t.Type, Condition: t.Condition, Fired: s.fired[transitionKey{i, to}]}
+			if t.Type == models.TransitionDynamic {
+				c.Condition = t.Expression
+			}
+			if c.Fired > 0 {
+				covered++
+			}
+			report.Transitions = append(report.Transitions, c)
+		}
+	}
+	report.Nodes = make([]NodeCoverage, 0, len(process.Nodes))
+	ran := 0
+	for _, n := range process.Nodes {
+		if runs[n.ID] > 0 {
+			ran++
+		}
+		report.Nodes = append(report.Nodes, NodeCoverage{NodeID: n.ID, Type: n.Type, Runs: runs[n.ID], Errors: errs[n.ID], Stubbed: stubbed[n.ID]})
+	}
+	switch {
+	case len(report.Transitions) > 0:
+		report.Coverage = float64(covered) / float64(len(report.Transitions))
+	case len(process.Nodes) > 0:
+		report.Coverage = float64(ran) / float64(len(process.Nodes))
+	}
+	return report, nil
+}
+
+// simulationSchema returns the schema payloads are generated from, or nil
+// when sim has samples or no schema applies.
+func (e *ProcessExecutor) simulationSchema(ctx context.Context, process *models.Process, sim Simulation) (map[string]interface{}, error) {
+	if len(sim.Samples) > 0 {
+		return nil, nil
+	}
+	if sim.Schema != nil {
+		if err := schema.Check(sim.Schema); err != nil {
+			return nil, fmt.Errorf("schema: %w", err)
+		}
+		return sim.Schema, nil
+	}
+	name := sim.SchemaName
+	if name == "" {
+		name, _ = process.Trigger.Config["schema"].(string)
+	}
+	if name == "" {
+		return nil, nil
+	}
+	if e.schemas == nil {
+		return nil, errNoSchemaSource
+	}
+	s, err := e.schemas.Schema(tenant.WithWorkspace(ctx, process.Definition.Workspace), name)
+	if err != nil {
+		return nil, fmt.Errorf("load schema %q: %w", name, err)
+	}
+	return s, nil
+}
+
+// simulatedTriggerData wraps a generated payload into the trigger data of
+// trigger, the inverse of triggerPayload.
+func simulatedTriggerData(trigger models.Trigger, payload interface{}) (map[string]interface{}, error) {
+	switch trigger.Type {
+	case "rest":
+		method, _ := trigger.Config["method"].(string)
+		if method == "" {
+			method = "POST"
+		}
+		return map[string]interface{}{
+			"method":  method,
+			"headers": map[string]interface{}{},
+			"params":  map[string]interface{}{},
+			"query":   map[string]interface{}{},
+			"body":    payload,
+		}, nil
+	case "soap", "rabbitmq":
+		return map[string]interface{}{"payload": payload}, nil
+	}
+	data, ok := payload.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("the schema of a %s trigger must describe an object", trigger.Type)
+	}
+	return data, nil
+}
+
+// process returns the copy of process a simulation runs: nodes whose type
+// has side effects or that have a mock keep their type but have their
+// config replaced by simulatedNodeKey, and nothing retries, breaks a
+// circuit, checks an SLA, reads a secret or injects faults. stubbed lists
+// the nodes that return their mock.
+func (s *simulation) process(process *models.Process, registry *activities.ActivityRegistry) (*models.Process, map[string]bool) {
+	p := *process
+	p.Definition.Settings.FaultInjection = nil
+	p.Nodes = make([]models.Node, len(process.Nodes))
+	stubbed := make(map[string]bool)
+	for i, n := range process.Nodes {
+		n.RetryPolicy = nil
+		n.CircuitBreaker = nil
+		n.SLAMs = 0
+		n.SLAAlert = nil
+		_, mocked := s.mocks[n.ID]
+		if _, known := registry.Get(n.Type); known && (mocked || !slices.Contains(simulatedActivities, n.Type)) {
+			n.Config = map[string]interface{}{simulatedNodeKey: n.ID}
+			n.Script = ""
+			n.SecretRef = ""
+			n.Profile = ""
+			stubbed[n.ID] = true
+		}
+		p.Nodes[i] = n
+	}
+	return &p, stubbed
+}
+
+// simulator returns an executor for the runs of s: it shares the schemas and
+// side-effect-free activities of e, stubs every other node type, and audits,
+// persists and records nothing.
+func (e *ProcessExecutor) simulator(s *simulation) *ProcessExecutor {
+	registry := activities.NewActivityRegistry()
+	for _, name := range e.activityRegistry.List() {
+		a := &simulatedActivity{name: name, sim: s}
+		switch {
+		case name == "mock_http":
+			// Mock servers are ephemeral, so simulations always allow them.
+			a.run = activities.NewMockHTTPActivity(true)
+		case slices.Contains(simulatedActivities, name):
+			a.run, _ = e.activityRegistry.Get(name)
+		}
+		registry.RegisterAs(name, a)
+	}
+	return &ProcessExecutor{
+		activityRegistry: registry,
+		secretResolver:   &secrets.NoopResolver{},
+		schemas:          e.schemas,
+		breakers:         newCircuitBreakers(),
+		sampleRand:       defaultSampleRand,
+	}
+}
+
+// simulatedActivity runs the activity run, or returns the mock of the node
+// for nodes marked with simulatedNodeKey.
+type simulatedActivity struct {
+	name string
+	run  activities.Activity
+	sim  *simulation
+}
+
+func (a *simulatedActivity) Name() string { return a.name }
+
+func (a *simulatedActivity) Execute(input map[string]interface{}, config map[string]interface{}, ctx *models.ExecutionContext) (map[string]interface{}, error) {
+	nodeID, stubbed := config[simulatedNodeKey].(string)
+	if !stubbed {
+		if a.run == nil {
+			return nil, fmt.Errorf("%s activity: not available in simulations", a.name)
+		}
+		return a.run.Execute(input, config, ctx)
+	}
+	return a.sim.output(a.name, nodeID, input)
+}
+
+// output returns the mocked result of node nodeID of type nodeType for the
+// running execution.
+func (s *simulation) output(nodeType, nodeID string, input map[string]interface{}) (map[string]interface{}, error) {
+	m, ok := s.mocks[nodeID]
+	if !ok {
+		switch nodeType {
+		case "batcher":
+			// A stubbed batcher releases every item at once.
+			return map[string]interface{}{"simulated": true, "released": true, "items": []interface{}{input}}, nil
+		case "dedupe":
+			return map[string]interface{}{"simulated": true, "duplicate": false}, nil
+		}
+		return map[string]interface{}{"simulated": true}, nil
+	}
+	if m.Error != "" {
+		return nil, fmt.Errorf("%w: %s", ErrSimulatedFault, m.Error)
+	}
+	// The draw is made for every run so a seed repeats the same errors.
+	if fail := s.rng.Float64() < m.ErrorRate; fail {
+		return nil, ErrSimulatedFault
+	}
+	if len(m.Outputs) > 0 {
+		return copyOutput(m.Outputs[s.run%len(m.Outputs)]), nil
+	}
+	if m.Output != nil {
+		return copyOutput(m.Output), nil
+	}
+	return map[string]interface{}{"simulated": true}, nil
+}
+
+// copyOutput copies the top level of a mock output, so nodes that set keys
+// in their output do not change the mock.
+func copyOutput(out map[string]interface{}) map[string]interface{} {
+	c := make(map[string]interface{}, len(out))
+	for k, v := range out {
+		c[k] = v
+	}
+	return c
+}
+
+// transitionIndex maps every transition of process, by its fields, to its
+// index in process.Transitions.
+func transitionIndex(process *models.Process) map[string]int {
+	index := make(map[string]int, len(process.Transitions))
+	for i := len(process.Transitions) - 1; i >= 0; i-- {
+		index[transitionID(process.Transitions[i])] = i
+	}
+	return index
+}
+
+// transitionID identifies a transition by the fields that route it.
+func transitionID(t models.Transition) string {
+	return t.From + "\x00" + t.To + "\x00" + t.Type + "\x00" + t.Condition + "\x00" + t.Expression
+}
@@ -0,0 +1,100 @@
+package engine
+
+import (
+	"context"
+	"testing"
+
+	"flowjs-works/engine/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func simulationProcess() *models.Process {
+	return &models.Process{
+		Definition: models.Definition{ID: "sim", Version: "1.0.0"},
+		Trigger:    models.Trigger{ID: "trg", Type: "rest", Config: map[string]interface{}{"path": "/orders"}},
+		Nodes: []models.Node{
+			{ID: "fetch", Type: "http", Config: map[string]interface{}{"url": "http://unreachable.invalid"}},
+			{ID: "ok", Type: "code", Script: "({ amount: input.amount })", InputMapping: map[string]interface{}{"amount": "$.trigger.body.amount"}},
+			{ID: "fail", Type: "logger", Config: map[string]interface{}{"level": "warn"}},
+			{ID: "notify", Type: "mail", Config: map[string]interface{}{"to": "ops@example.com"}},
+			{ID: "on_error", Type: "logger"},
+		},
+		Transitions: []models.Transition{
+			{From: "trg", To: "fetch", Type: "success"},
+			{From: "fetch", To: "ok", Type: "condition", Condition: "$.nodes.fetch.output.status === 200"},
+			{From: "fetch", To: "fail", Type: "nocondition"},
+			{From: "fail", To: "notify", Type: "success"},
+			{From: "fetch", To: "on_error", Type: "error"},
+		},
+	}
+}
+
+func TestSimulate_ReportsCoverage(t *testing.T) {
+	exec := newTestExecutor(t)
+	report, err := exec.Simulate(context.Background(), simulationProcess(), Simulation{
+		Executions: 4,
+		Seed:       1,
+		Schema: map[string]interface{}{
+			"type":       "object",
+			"required":   []interface{}{"amount"},
+			"properties": map[string]interface{}{"amount": map[string]interface{}{"type": "integer", "minimum": 1}},
+		},
+		Mocks: map[string]NodeMock{"fetch": {Outputs: []map[string]interface{}{{"status": 200}, {"status": 500}}}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 4, report.Succeeded)
+	assert.Equal(t, 0, report.Failed)
+
+	fired := make(map[string]int)
+	for _, c := range report.Transitions {
+		fired[c.From+"→"+c.To] = c.Fired
+	}
+	assert.Equal(t, map[string]int{"trg→fetch": 4, "fetch→ok": 2, "fetch→fail": 2, "fail→notify": 2, "fetch→on_error": 0}, fired)
+	assert.InDelta(t, 0.8, report.Coverage, 1e-9)
+
+	assert.Equal(t, NodeCoverage{NodeID: "fetch", Type: "http", Runs: 4, Stubbed: true}, report.Nodes[0])
+	assert.Equal(t, NodeCoverage{NodeID: "ok", Type: "code", Runs: 2}, report.Nodes[1])
+	assert.Equal(t, NodeCoverage{NodeID: "notify", Type: "mail", Runs: 2, Stubbed: true}, report.Nodes[3])
+
+	// The generated body reached the code node, which really ran.
+	run := report.Runs[0]
+	assert.Equal(t, "success", run.Nodes["ok"])
+	body := run.Trigger["body"].(map[string]interface{})
+	assert.GreaterOrEqual(t, body["amount"], float64(1))
+}
+
+func TestSimulate_MockErrors(t *testing.T) {
+	exec := newTestExecutor(t)
+	sim := Simulation{
+		Executions: 20,
+		Seed:       7,
+		Samples:    []map[string]interface{}{{"body": map[string]interface{}{"amount": 5}}},
+		Mocks:      map[string]NodeMock{"fetch": {Output: map[string]interface{}{"status": 200}, ErrorRate: 0.5}},
+	}
+	report, err := exec.Simulate(context.Background(), simulationProcess(), sim)
+	require.NoError(t, err)
+	errors := report.Nodes[0].Errors
+	assert.Greater(t, errors, 0)
+	assert.Less(t, errors, 20)
+	assert.Equal(t, errors, report.Transitions[4].Fired, "every error took the error transition")
+
+	// The seed repeats the same errors.
+	again, err := exec.Simulate(context.Background(), simulationProcess(), sim)
+	require.NoError(t, err)
+	assert.Equal(t, errors, again.Nodes[0].Errors)
+}
+
+func TestSimulate_Errors(t *testing.T) {
+	exec := newTestExecutor(t)
+	_, err := exec.Simulate(context.Background(), simulationProcess(), Simulation{})
+	assert.ErrorContains(t, err, "executions must be between 1 and 1000")
+
+	_, err = exec.Simulate(context.Background(), simulationProcess(), Simulation{Executions: 1, Mocks: map[string]NodeMock{"missing": {}}})
+	assert.ErrorContains(t, err, `mock of unknown node "missing"`)
+
+	// Without a schema registry a named schema cannot be loaded.
+	_, err = exec.Simulate(context.Background(), simulationProcess(), Simulation{Executions: 1, SchemaName: "order"})
+	assert.ErrorIs(t, err, errNoSchemaSource)
+}
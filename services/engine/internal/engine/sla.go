@@ -0,0 +1,94 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"flowjs-works/engine/internal/logging"
+	"flowjs-works/engine/internal/models"
+	"flowjs-works/engine/internal/notify"
+	"flowjs-works/engine/internal/tenant"
+)
+
+// StatusSLABreach is the audit status of the event emitted when a node runs
+// longer than its sla_ms. It is recorded next to the node's own status event.
+const StatusSLABreach = "sla_breach"
+
+// slaAlertClient posts breaches to sla_alert.url.
+var slaAlertClient = &http.Client{Timeout: 10 * time.Second}
+
+// checkSLA reports a run of node that took longer than its sla_ms: it emits
+// an sla_breach audit event and notifies the node's sla_alert and the
+// process's sla_breach subscribers in the background, so a slow node never
+// slows the flow further. nodeErr is the
+// outcome of the run, which a breach does not change.
+func (e *ProcessExecutor) checkSLA(node *models.Node, ctx *models.ExecutionContext, elapsed time.Duration, nodeErr error) {
+	if node.SLAMs <= 0 || elapsed <= time.Duration(node.SLAMs)*time.Millisecond {
+		return
+	}
+	status := "success"
+	if nodeErr != nil {
+		status = "error"
+	}
+	breach := map[string]interface{}{
+		"event":        StatusSLABreach,
+		"execution_id": ctx.ExecutionID,
+		"workspace":    tenant.Normalize(ctx.Workspace),
+		"process_id":   ctx.ProcessID,
+		"node_id":      node.ID,
+		"node_type":    node.Type,
+		"node_status":  status,
+		"sla_ms":       node.SLAMs,
+		"duration_ms":  elapsed.Milliseconds(),
+		"timestamp":    time.Now().UTC().Format(time.RFC3339),
+	}
+	msg := fmt.Sprintf("node %s took %dms, over its sla_ms of %d", node.ID, elapsed.Milliseconds(), node.SLAMs)
+	logging.ForExecution(ctx).Warn("node exceeded sla", logging.KeyNodeID, node.ID, "sla_ms", node.SLAMs, "duration_ms", elapsed.Milliseconds())
+	e.auditExecution(ctx, node.ID, node.Type, StatusSLABreach,
+		map[string]interface{}{"sla_ms": node.SLAMs, "duration_ms": elapsed.Milliseconds(), "node_status": status}, nil, msg, nil)
+
+	if alert := node.SLAAlert; alert != nil {
+		go e.notifySLABreach(alert, breach)
+	}
+	e.notify(notify.Event{
+		Type:        notify.EventSLABreach,
+		Workspace:   ctx.Workspace,
+		ProcessID:   ctx.ProcessID,
+		ExecutionID: ctx.ExecutionID,
+		NodeID:      node.ID,
+		Message:     msg,
+		Details:     map[string]interface{}{"sla_ms": node.SLAMs, "duration_ms": elapsed.Milliseconds(), "node_status": status},
+	})
+}
+
+// notifySLABreach delivers breach to the URL and NATS subject of alert.
+// Failures are logged; an alert never fails the execution.
+func (e *ProcessExecutor) notifySLABreach(alert *models.SLAAlert, breach map[string]interface{}) {
+	body, err := json.Marshal(breach)
+	if err != nil {
+		slog.Error("sla: marshal breach", logging.KeyError, err)
+		return
+	}
+	if alert.Subject != "" {
+		if e.natsConn == nil {
+			slog.Warn("sla: NATS not connected; breach not published", "subject", alert.Subject)
+		} else if err := e.natsConn.Publish(alert.Subject, body); err != nil {
+			slog.Error("sla: publish breach", "subject", alert.Subject, logging.KeyError, err)
+		}
+	}
+	if alert.URL != "" {
+		resp, err := slaAlertClient.Post(alert.URL, "application/json", bytes.NewReader(body))
+		if err != nil {
+			slog.Error("sla: post breach", "url", alert.URL, logging.KeyError, err)
+			return
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			slog.Error("sla: breach callback rejected", "url", alert.URL, "status", resp.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package engine
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"flowjs-works/engine/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// slowActivity sleeps for delay before succeeding.
+type slowActivity struct{ delay time.Duration }
+
+func (a *slowActivity) Name() string { return "slow" }
+
+func (a *slowActivity) Execute(map[string]interface{}, map[string]interface{}, *models.ExecutionContext) (map[string]interface{}, error) {
+	time.Sleep(a.delay)
+	return map[string]interface{}{"ok": true}, nil
+}
+
+// TestExecute_SLABreachNotifiesCallback verifies that a node slower than its
+// sla_ms posts a breach to sla_alert.url without failing the node, and that a
+// node within its SLA posts nothing.
+func TestExecute_SLABreachNotifiesCallback(t *testing.T) {
+	breaches := make(chan map[string]interface{}, 2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var breach map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&breach)
+		breaches <- breach
+	}))
+	defer srv.Close()
+
+	exec := newTestExecutor(t)
+	exec.activityRegistry.Register(&slowActivity{delay: 20 * time.Millisecond})
+	process := &models.Process{
+		Definition: models.Definition{ID: "p_sla", Version: "1.0.0"},
+		Nodes: []models.Node{
+			{ID: "call", Type: "slow", SLAMs: 5, SLAAlert: &models.SLAAlert{URL: srv.URL}},
+			{ID: "fast", Type: "logger", SLAMs: 60000, SLAAlert: &models.SLAAlert{URL: srv.URL}, Config: map[string]interface{}{"message": "done"}},
+		},
+		Transitions: []models.Transition{{From: "call", To: "fast", Type: "success"}},
+	}
+
+	ctx, err := exec.Execute(process, map[string]interface{}{})
+	require.NoError(t, err)
+	assert.Equal(t, "success", ctx.Nodes["call"]["status"], "a breach does not fail the node")
+
+	select {
+	case breach := <-breaches:
+		assert.Equal(t, StatusSLABreach, breach["event"])
+		assert.Equal(t, "call", breach["node_id"])
+		assert.Equal(t, "p_sla", breach["process_id"])
+		assert.Equal(t, float64(5), breach["sla_ms"])
+		assert.GreaterOrEqual(t, breach["duration_ms"], float64(20))
+	case <-time.After(5 * time.Second):
+		t.Fatal("no sla breach was posted")
+	}
+	select {
+	case breach := <-breaches:
+		t.Fatalf("unexpected breach for %v", breach["node_id"])
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,39 @@
+package engine
+
+import (
+	"context"
+
+	"flowjs-works/engine/internal/logging"
+	"flowjs-works/engine/internal/models"
+	"flowjs-works/engine/internal/tenant"
+)
+
+// VersionRecorder stores the process definition each execution runs, so a
+// retry or replay can use it after the process was redeployed.
+// store.VersionStore implements it on the config DB.
+type VersionRecorder interface {
+	RecordVersion(ctx context.Context, execCtx *models.ExecutionContext, process *models.Process) error
+}
+
+// SetVersionRecorder makes the executor pin every execution to its process
+// definition in r.
+func (e *ProcessExecutor) SetVersionRecorder(r VersionRecorder) {
+	e.versions = r
+}
+
+// pinVersion records on ctx the version, revision and hash of the process it runs and
+// stores the definition with the version recorder, if any. A failure is
+// logged: the execution then cannot be retried on its own DSL, but still runs.
+func (e *ProcessExecutor) pinVersion(ctx *models.ExecutionContext, process *models.Process) {
+	ctx.ProcessVersion = process.Definition.Version
+	ctx.ProcessRevision = process.Definition.Revision
+	ctx.DSLHash = process.Hash()
+	if e.versions == nil || ctx.ProcessID == "" {
+		return
+	}
+	recordCtx, cancel := context.WithTimeout(tenant.WithWorkspace(context.Background(), ctx.Workspace), snapshotTimeout)
+	defer cancel()
+	if err := e.versions.RecordVersion(recordCtx, ctx, process); err != nil {
+		logging.ForExecution(ctx).Warn("failed to record process version", logging.KeyError, err)
+	}
+}
@@ -0,0 +1,52 @@
+package engine
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"flowjs-works/engine/internal/models"
+)
+
+type fakeVersionRecorder struct {
+	mu     sync.Mutex
+	hashes map[string]string // execution id → DSL hash
+}
+
+func (f *fakeVersionRecorder) RecordVersion(_ context.Context, execCtx *models.ExecutionContext, process *models.Process) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.hashes[execCtx.ExecutionID] = process.Hash()
+	return nil
+}
+
+func TestPinVersion_RecordsEveryExecution(t *testing.T) {
+	exec := newTestExecutor(t)
+	rec := &fakeVersionRecorder{hashes: map[string]string{}}
+	exec.SetVersionRecorder(rec)
+
+	process := &models.Process{
+		Definition: models.Definition{ID: "p_pinned", Revision: 4},
+		Trigger:    models.Trigger{Type: "manual"},
+		Nodes:      []models.Node{{ID: "log", Type: "logger", Config: map[string]interface{}{"level": "info"}}},
+	}
+	ctx, err := exec.Execute(process, map[string]interface{}{})
+	require.NoError(t, err)
+	assert.Equal(t, 4, ctx.ProcessRevision)
+	assert.Equal(t, process.Hash(), ctx.DSLHash)
+	assert.Equal(t, ctx.DSLHash, rec.hashes[ctx.ExecutionID])
+
+	replay, err := exec.ExecuteFromNode(process, "log", map[string]interface{}{}, "", ctx.ExecutionID)
+	require.NoError(t, err)
+	assert.Equal(t, ctx.DSLHash, replay.DSLHash)
+	assert.Equal(t, ctx.DSLHash, rec.hashes[replay.ExecutionID])
+
+	changed := *process
+	changed.Nodes = []models.Node{{ID: "log", Type: "logger", Config: map[string]interface{}{"level": "warn"}}}
+	next, err := exec.Execute(&changed, map[string]interface{}{})
+	require.NoError(t, err)
+	assert.NotEqual(t, ctx.DSLHash, next.DSLHash, "a redeployed definition gets a new hash")
+}
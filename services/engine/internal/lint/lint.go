@@ -0,0 +1,186 @@
+// Package lint checks process definitions against style and safety rules
+// that go beyond the structural checks of models.Process.Validate: naming
+// conventions, mandatory descriptions, credentials written in plain text and
+// side-effecting nodes without an error transition. Each rule has a default
+// severity that a .flowlint file can change or turn off.
+package lint
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"flowjs-works/engine/internal/models"
+)
+
+// ConfigFile is the name of the lint configuration file, looked up from the
+// directory of the linted process upwards.
+const ConfigFile = ".flowlint"
+
+// Severity is how a rule finding is reported.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityInfo    Severity = "info"
+	// SeverityOff disables a rule.
+	SeverityOff Severity = "off"
+)
+
+func validSeverity(s Severity) bool {
+	switch s {
+	case SeverityError, SeverityWarning, SeverityInfo, SeverityOff:
+		return true
+	}
+	return false
+}
+
+// Finding is one rule violation.
+type Finding struct {
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	// NodeID is the node the finding is about, empty for process-level ones.
+	NodeID  string `json:"node_id,omitempty"`
+	Message string `json:"message"`
+}
+
+// String formats f as "severity rule [node]: message".
+func (f Finding) String() string {
+	if f.NodeID != "" {
+		return fmt.Sprintf("%s %s [%s]: %s", f.Severity, f.Rule, f.NodeID, f.Message)
+	}
+	return fmt.Sprintf("%s %s: %s", f.Severity, f.Rule, f.Message)
+}
+
+// Report is the result of linting one process.
+type Report struct {
+	Findings []Finding `json:"findings"`
+	Errors   int       `json:"errors"`
+	Warnings int       `json:"warnings"`
+}
+
+// Failed reports whether any finding has error severity.
+func (r Report) Failed() bool { return r.Errors > 0 }
+
+// Config is the content of a .flowlint file:
+//
+//	{
+//	  "rules": {"description-required": "error", "node-id-naming": "off"},
+//	  "node_id_pattern": "^[a-z][a-z0-9_]*$"
+//	}
+//
+// YAML is accepted as well. Rules not listed keep their default severity.
+type Config struct {
+	Rules map[string]Severity `json:"rules,omitempty"`
+	// NodeIDPattern is the regular expression node ids must match for the
+	// node-id-naming rule (default snake_case).
+	NodeIDPattern string `json:"node_id_pattern,omitempty"`
+
+	nodeIDRe *regexp.Regexp
+}
+
+// ParseConfig parses a .flowlint file, JSON or YAML, and checks that it only
+// names known rules and severities.
+func ParseConfig(data []byte) (Config, error) {
+	var cfg Config
+	raw, err := models.YAMLToJSON(data)
+	if err != nil {
+		return cfg, fmt.Errorf("lint: %s: %w", ConfigFile, err)
+	}
+	if string(raw) == "null" {
+		return cfg, nil
+	}
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return cfg, fmt.Errorf("lint: %s: %w", ConfigFile, err)
+	}
+	for name, sev := range cfg.Rules {
+		if ruleByName(name) == nil {
+			return cfg, fmt.Errorf("lint: %s: unknown rule %q", ConfigFile, name)
+		}
+		if !validSeverity(sev) {
+			return cfg, fmt.Errorf("lint: %s: rule %s: unknown severity %q (error, warning, info or off)", ConfigFile, name, sev)
+		}
+	}
+	if cfg.NodeIDPattern != "" {
+		if cfg.nodeIDRe, err = regexp.Compile(cfg.NodeIDPattern); err != nil {
+			return cfg, fmt.Errorf("lint: %s: node_id_pattern: %w", ConfigFile, err)
+		}
+	}
+	return cfg, nil
+}
+
+// LoadConfig reads the .flowlint file at path.
+func LoadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("lint: %w", err)
+	}
+	return ParseConfig(data)
+}
+
+// FindConfig returns the path of the nearest .flowlint in dir or one of its
+// parents, or "" when there is none.
+func FindConfig(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for {
+		path := filepath.Join(dir, ConfigFile)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// severity returns the configured severity of rule.
+func (c Config) severity(rule *Rule) Severity {
+	if sev, ok := c.Rules[rule.Name]; ok {
+		return sev
+	}
+	return rule.Default
+}
+
+// Lint runs every rule not turned off by cfg against p. Findings are ordered
+// by severity, then rule, then node.
+func Lint(p *models.Process, cfg Config) Report {
+	report := Report{Findings: []Finding{}}
+	for i := range Rules {
+		rule := &Rules[i]
+		sev := cfg.severity(rule)
+		if sev == SeverityOff {
+			continue
+		}
+		for _, f := range rule.check(p, cfg) {
+			f.Rule, f.Severity = rule.Name, sev
+			report.Findings = append(report.Findings, f)
+			switch sev {
+			case SeverityError:
+				report.Errors++
+			case SeverityWarning:
+				report.Warnings++
+			}
+		}
+	}
+	rank := map[Severity]int{SeverityError: 0, SeverityWarning: 1, SeverityInfo: 2}
+	sort.SliceStable(report.Findings, func(i, j int) bool {
+		a, b := report.Findings[i], report.Findings[j]
+		if rank[a.Severity] != rank[b.Severity] {
+			return rank[a.Severity] < rank[b.Severity]
+		}
+		if a.Rule != b.Rule {
+			return a.Rule < b.Rule
+		}
+		return a.NodeID < b.NodeID
+	})
+	return report
+}
@@ -0,0 +1,255 @@
+package lint
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"flowjs-works/engine/internal/models"
+)
+
+// Rule is one lint check.
+type Rule struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Default     Severity `json:"default_severity"`
+
+	check func(p *models.Process, cfg Config) []Finding
+}
+
+// Rules lists the lint rules in the order they run.
+var Rules = []Rule{
+	{
+		Name:        "node-id-naming",
+		Description: "node and trigger ids match node_id_pattern (default snake_case)",
+		Default:     SeverityWarning,
+		check:       checkNodeIDNaming,
+	},
+	{
+		Name:        "description-required",
+		Description: "the process and every node have a description",
+		Default:     SeverityWarning,
+		check:       checkDescriptions,
+	},
+	{
+		Name:        "plaintext-credential",
+		Description: "no password, token or key is written in a config instead of a secret_ref",
+		Default:     SeverityError,
+		check:       checkPlaintextCredentials,
+	},
+	{
+		Name:        "error-transition-required",
+		Description: "nodes that change external systems have an error transition",
+		Default:     SeverityWarning,
+		check:       checkErrorTransitions,
+	},
+}
+
+func ruleByName(name string) *Rule {
+	for i := range Rules {
+		if Rules[i].Name == name {
+			return &Rules[i]
+		}
+	}
+	return nil
+}
+
+var defaultNodeIDRe = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+func checkNodeIDNaming(p *models.Process, cfg Config) []Finding {
+	re, pattern := defaultNodeIDRe, defaultNodeIDRe.String()
+	if cfg.nodeIDRe != nil {
+		re, pattern = cfg.nodeIDRe, cfg.NodeIDPattern
+	}
+	var findings []Finding
+	if p.Trigger.ID != "" && !re.MatchString(p.Trigger.ID) {
+		findings = append(findings, Finding{Message: fmt.Sprintf("trigger id %q does not match %s", p.Trigger.ID, pattern)})
+	}
+	for _, node := range p.Nodes {
+		if node.ID != "" && !re.MatchString(node.ID) {
+			findings = append(findings, Finding{NodeID: node.ID, Message: fmt.Sprintf("node id does not match %s", pattern)})
+		}
+	}
+	return findings
+}
+
+func checkDescriptions(p *models.Process, _ Config) []Finding {
+	var findings []Finding
+	if strings.TrimSpace(p.Definition.Description) == "" {
+		findings = append(findings, Finding{Message: "definition.description is empty"})
+	}
+	for _, node := range p.Nodes {
+		if strings.TrimSpace(node.Description) == "" {
+			findings = append(findings, Finding{NodeID: node.ID, Message: "description is empty"})
+		}
+	}
+	return findings
+}
+
+// credentialKeys are config fields that hold a credential and must come from
+// a secret_ref.
+var credentialKeys = map[string]bool{
+	"password": true, "passwd": true, "token": true, "secret": true,
+	"api_key": true, "apikey": true, "access_token": true, "client_secret": true,
+	"private_key": true, "secret_access_key": true, "session_token": true,
+}
+
+// credentialValueRes match values that are credentials whatever the field
+// holding them: cloud and SaaS keys, authorization header values, JWTs,
+// private keys and passwords inside connection strings.
+var credentialValueRes = []*regexp.Regexp{
+	regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),                                   // AWS access key id
+	regexp.MustCompile(`(?i)^(?:bearer|basic)\s+[A-Za-z0-9._~+/-]{16,}=*$`),               // Authorization header
+	regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`), // JWT
+	regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`),                              // PEM private key
+	regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36}|github_pat_[A-Za-z0-9_]{22,})`),    // GitHub token
+	regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`),                                 // Slack token
+	regexp.MustCompile(`\b[sr]k_live_[A-Za-z0-9]{16,}`),                                   // Stripe live key
+	regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}`),                                         // Google API key
+	regexp.MustCompile(`(?i)(?:^|[\s;?&])(?:password|pwd)=[^\s;&${'"][^\s;&]*`),           // key=value DSN
+	regexp.MustCompile(`^[^:/\s]+:[^@/\s$]+@tcp\(`),                                       // MySQL DSN
+}
+
+// looksLikeCredential reports whether s matches one of credentialValueRes.
+func looksLikeCredential(s string) bool {
+	for _, re := range credentialValueRes {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+func checkPlaintextCredentials(p *models.Process, _ Config) []Finding {
+	return CredentialFindings(p)
+}
+
+// CredentialFindings returns the plain-text credentials of p: in the trigger
+// config, and in node configs, literal input_mapping values and scripts.
+// Findings have no rule or severity set.
+func CredentialFindings(p *models.Process) []Finding {
+	var findings []Finding
+	for _, path := range credentialPaths(p.Trigger.Config, "") {
+		findings = append(findings, Finding{Message: fmt.Sprintf("trigger config.%s holds a plain-text credential; use a secret_ref", path)})
+	}
+	for _, node := range p.Nodes {
+		var paths []string
+		for _, path := range credentialPaths(node.Config, "") {
+			paths = append(paths, "config."+path)
+		}
+		for _, path := range credentialPaths(node.InputMapping, "") {
+			paths = append(paths, "input_mapping."+path)
+		}
+		if looksLikeCredential(node.Script) {
+			paths = append(paths, "script")
+		}
+		for _, path := range paths {
+			findings = append(findings, Finding{NodeID: node.ID, Message: fmt.Sprintf("%s holds a plain-text credential; use a secret_ref", path)})
+		}
+	}
+	return findings
+}
+
+// PlaintextCredentials returns the dotted paths of config, sorted, that hold
+// a literal credential instead of getting it from a secret.
+func PlaintextCredentials(config map[string]interface{}) []string {
+	return credentialPaths(config, "")
+}
+
+// credentialPaths returns the dotted paths of config, sorted, whose value is
+// a literal credential: a credential field with a non-empty string, a URL
+// with a password in it, or a value that looks like a credential (see
+// credentialValueRes). Array items are named path[i].
+func credentialPaths(config map[string]interface{}, prefix string) []string {
+	var paths []string
+	for key, v := range config {
+		paths = append(paths, credentialValuePaths(key, v, prefix+key)...)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// credentialValuePaths returns the paths of the credentials in v, the value of
+// key at path.
+func credentialValuePaths(key string, v interface{}, path string) []string {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		return credentialPaths(val, path+".")
+	case []interface{}:
+		var paths []string
+		for i, item := range val {
+			paths = append(paths, credentialValuePaths(key, item, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+		return paths
+	case string:
+		if isReference(val) {
+			return nil
+		}
+		if credentialKeys[strings.ToLower(key)] && val != "" {
+			return []string{path}
+		}
+		if u, err := url.Parse(val); err == nil && u.User != nil {
+			if _, set := u.User.Password(); set {
+				return []string{path}
+			}
+		}
+		if looksLikeCredential(val) {
+			return []string{path}
+		}
+	}
+	return nil
+}
+
+// isReference reports whether s is resolved at run time rather than being a
+// literal value: a JSONPath or a ${...} / {{...}} placeholder.
+func isReference(s string) bool {
+	return strings.HasPrefix(s, "$.") || strings.HasPrefix(s, "${") || strings.HasPrefix(s, "{{")
+}
+
+func checkErrorTransitions(p *models.Process, _ Config) []Finding {
+	handled := make(map[string]bool)
+	for _, t := range p.Transitions {
+		if t.Type == "error" {
+			handled[t.From] = true
+		}
+	}
+	var findings []Finding
+	for _, node := range p.Nodes {
+		if !handled[node.ID] && sideEffecting(node) {
+			findings = append(findings, Finding{NodeID: node.ID, Message: fmt.Sprintf("%s node changes an external system but has no error transition", node.Type)})
+		}
+	}
+	return findings
+}
+
+// sideEffecting reports whether node writes to a system outside the engine,
+// judging by its type and operation.
+func sideEffecting(node models.Node) bool {
+	str := func(key string) string {
+		s, _ := node.Config[key].(string)
+		return strings.ToLower(s)
+	}
+	switch node.Type {
+	case "rabbitmq", "websocket_send", "callback_await":
+		return true
+	case "http":
+		switch strings.ToUpper(str("method")) {
+		case "", "GET", "HEAD", "OPTIONS":
+			return false
+		}
+		return true
+	case "sftp", "smb", "s3":
+		method := str("method")
+		return method != "" && method != "get" && method != "presign"
+	case "file":
+		return str("operation") != "read"
+	case "mail":
+		action := str("action")
+		return action == "" || action == "send"
+	case "sql":
+		return !strings.HasPrefix(strings.ToUpper(strings.TrimSpace(str("query"))), "SELECT")
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+// Package logging configures the engine's structured logger.
+//
+// All engine packages log through log/slog. Setup installs a handler chosen
+// by environment variables as the slog default (which also captures output of
+// the standard log package), and the attribute keys below keep field names
+// consistent so records can be correlated in Loki or ELK.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"flowjs-works/engine/internal/models"
+)
+
+// Attribute keys shared by every package that logs about an execution.
+const (
+	KeyExecutionID = "execution_id"
+	KeyProcessID   = "process_id"
+	KeyNodeID      = "node_id"
+	KeyNodeType    = "node_type"
+	KeyWorkspace   = "workspace"
+	KeyTrigger     = "trigger"
+	KeyError       = "error"
+)
+
+// Config selects the log handler.
+type Config struct {
+	// Level is one of debug, info, warn or error.
+	Level slog.Level
+	// JSON selects the JSON handler; otherwise records are written as logfmt-style text.
+	JSON bool
+}
+
+// ConfigFromEnv reads LOG_LEVEL (default info) and LOG_FORMAT (json or text).
+// LOG_FORMAT defaults to text when APP_ENV is "development" and json otherwise.
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{Level: slog.LevelInfo, JSON: os.Getenv("APP_ENV") != "development"}
+	if v := os.Getenv("LOG_LEVEL"); v != "" {
+		if err := cfg.Level.UnmarshalText([]byte(v)); err != nil {
+			return cfg, fmt.Errorf("logging: invalid LOG_LEVEL %q", v)
+		}
+	}
+	switch v := strings.ToLower(os.Getenv("LOG_FORMAT")); v {
+	case "":
+	case "json":
+		cfg.JSON = true
+	case "text":
+		cfg.JSON = false
+	default:
+		return cfg, fmt.Errorf("logging: invalid LOG_FORMAT %q (want json or text)", v)
+	}
+	return cfg, nil
+}
+
+// New returns a logger writing to w according to cfg.
+func New(w io.Writer, cfg Config) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: cfg.Level}
+	if cfg.JSON {
+		return slog.New(slog.NewJSONHandler(w, opts))
+	}
+	return slog.New(slog.NewTextHandler(w, opts))
+}
+
+// Setup configures the default logger from the environment and returns it.
+// An invalid setting is reported through the new logger, which then falls
+// back to the default for that setting.
+func Setup() *slog.Logger {
+	cfg, err := ConfigFromEnv()
+	logger := New(os.Stderr, cfg)
+	slog.SetDefault(logger)
+	if err != nil {
+		logger.Warn("using default log settings", KeyError, err)
+	}
+	return logger
+}
+
+// ForExecution returns the default logger annotated with the execution,
+// process and workspace of ctx. A nil ctx returns the default logger.
+func ForExecution(ctx *models.ExecutionContext) *slog.Logger {
+	if ctx == nil {
+		return slog.Default()
+	}
+	return slog.With(KeyExecutionID, ctx.ExecutionID, KeyProcessID, ctx.ProcessID, KeyWorkspace, ctx.Workspace)
+}
+
+// Err returns the standard attribute for err.
+func Err(err error) slog.Attr {
+	return slog.Any(KeyError, err)
+}
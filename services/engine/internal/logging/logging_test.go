@@ -0,0 +1,82 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"flowjs-works/engine/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigFromEnv_Defaults(t *testing.T) {
+	t.Setenv("LOG_LEVEL", "")
+	t.Setenv("LOG_FORMAT", "")
+	t.Setenv("APP_ENV", "production")
+	cfg, err := ConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, slog.LevelInfo, cfg.Level)
+	assert.True(t, cfg.JSON)
+
+	t.Setenv("APP_ENV", "development")
+	cfg, err = ConfigFromEnv()
+	require.NoError(t, err)
+	assert.False(t, cfg.JSON)
+}
+
+func TestConfigFromEnv_Overrides(t *testing.T) {
+	t.Setenv("APP_ENV", "development")
+	t.Setenv("LOG_LEVEL", "DEBUG")
+	t.Setenv("LOG_FORMAT", "json")
+	cfg, err := ConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, slog.LevelDebug, cfg.Level)
+	assert.True(t, cfg.JSON)
+}
+
+func TestConfigFromEnv_Invalid(t *testing.T) {
+	t.Setenv("LOG_FORMAT", "")
+	t.Setenv("LOG_LEVEL", "loud")
+	cfg, err := ConfigFromEnv()
+	assert.Error(t, err)
+	assert.Equal(t, slog.LevelInfo, cfg.Level)
+
+	t.Setenv("LOG_LEVEL", "")
+	t.Setenv("LOG_FORMAT", "xml")
+	_, err = ConfigFromEnv()
+	assert.Error(t, err)
+}
+
+func TestNew_JSONRespectsLevel(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, Config{Level: slog.LevelWarn, JSON: true})
+	logger.Info("hidden")
+	logger.Warn("shown", KeyNodeID, "n1")
+
+	var rec map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &rec))
+	assert.Equal(t, "shown", rec["msg"])
+	assert.Equal(t, "WARN", rec["level"])
+	assert.Equal(t, "n1", rec[KeyNodeID])
+}
+
+func TestForExecution_AddsCorrelationFields(t *testing.T) {
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(New(&buf, Config{Level: slog.LevelInfo, JSON: true}))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	ctx := models.NewExecutionContext("exec-1")
+	ctx.ProcessID = "p1"
+	ctx.Workspace = "team-a"
+	ForExecution(ctx).Info("hello")
+
+	var rec map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &rec))
+	assert.Equal(t, "exec-1", rec[KeyExecutionID])
+	assert.Equal(t, "p1", rec[KeyProcessID])
+	assert.Equal(t, "team-a", rec[KeyWorkspace])
+}
@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+
+	"flowjs-works/engine/internal/tenant"
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// API-key authentication  (A01 Broken Access Control)
+// ──────────────────────────────────────────────────────────────────────────────
+
+// devPrincipal is attached to every request when no API keys are configured in
+// development. It owns the default workspace with full privileges.
+var devPrincipal = tenant.Principal{Subject: "anonymous", Workspace: tenant.DefaultWorkspace, Role: tenant.RoleAdmin}
+
+// APIKeys reads the API_KEYS environment variable and returns the principal
+// bound to each key. The format is a comma-separated list of
+// "key:workspace:subject[:role[:team]]" entries, e.g.
+//
+//	API_KEYS=k1:team-a:alice:admin:payments,k2:team-b:ci-bot
+//
+// Malformed entries are skipped with a warning. An empty result in a
+// non-development environment terminates the process (log.Fatalf), so the
+// management API is never exposed without authentication in production.
+func APIKeys() map[string]tenant.Principal {
+	keys := ParseAPIKeys(os.Getenv("API_KEYS"))
+	if len(keys) == 0 {
+		if os.Getenv("APP_ENV") != "development" {
+			log.Fatalf("middleware: API_KEYS must be set in non-development environments")
+		}
+		slog.Warn("middleware: API_KEYS not set; all requests use the default workspace (development only)", "workspace", tenant.DefaultWorkspace)
+	}
+	return keys
+}
+
+// ParseAPIKeys parses the API_KEYS format described in APIKeys.
+func ParseAPIKeys(raw string) map[string]tenant.Principal {
+	keys := make(map[string]tenant.Principal)
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.Split(entry, ":")
+		if len(parts) < 3 || parts[0] == "" || parts[2] == "" || !tenant.Valid(parts[1]) {
+			slog.Warn("middleware: ignoring malformed API_KEYS entry")
+			continue
+		}
+		p := tenant.Principal{Workspace: parts[1], Subject: parts[2], Role: "member"}
+		if len(parts) > 3 && parts[3] != "" {
+			p.Role = parts[3]
+		}
+		if len(parts) > 4 {
+			p.Team = parts[4]
+		}
+		keys[parts[0]] = p
+	}
+	return keys
+}
+
+// Authenticate returns a middleware that resolves the caller's principal from
+// the "Authorization: Bearer <key>" or "X-API-Key" header and stores it in the
+// request context (see tenant.WithPrincipal). Requests without a valid key get
+// HTTP 401.
+//
+// Paths starting with one of publicPrefixes (health probes, inbound trigger
+// routes) skip authentication and run in the default workspace context; trigger
+// routes carry their own workspace in the URL prefix.
+//
+// When keys is empty every request is attached to the development principal.
+func Authenticate(keys map[string]tenant.Principal, publicPrefixes ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if len(keys) == 0 {
+				next.ServeHTTP(w, r.WithContext(tenant.WithPrincipal(r.Context(), devPrincipal)))
+				return
+			}
+			if r.Method == http.MethodOptions || hasAnyPrefix(r.URL.Path, publicPrefixes) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			p, ok := lookupAPIKey(keys, requestAPIKey(r))
+			if !ok {
+				SecurityLog("AUTH_FAILED", clientIP(r), r.Method, r.URL.Path, http.StatusUnauthorized)
+				http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(tenant.WithPrincipal(r.Context(), p)))
+		})
+	}
+}
+
+// requestAPIKey extracts the API key from the request headers.
+func requestAPIKey(r *http.Request) string {
+	if k := r.Header.Get("X-API-Key"); k != "" {
+		return k
+	}
+	auth := r.Header.Get("Authorization")
+	if strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+	}
+	return ""
+}
+
+// lookupAPIKey compares candidate against every configured key in constant
+// time so response latency does not leak key prefixes.
+func lookupAPIKey(keys map[string]tenant.Principal, candidate string) (tenant.Principal, bool) {
+	var (
+		found tenant.Principal
+		ok    bool
+	)
+	if candidate == "" {
+		return found, false
+	}
+	for k, p := range keys {
+		if subtle.ConstantTimeCompare([]byte(k), []byte(candidate)) == 1 {
+			found, ok = p, true
+		}
+	}
+	return found, ok
+}
+
+func hasAnyPrefix(path string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(path, p) {
+			return true
+		}
+	}
+	return false
+}
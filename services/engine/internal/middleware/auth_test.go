@@ -0,0 +1,95 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"flowjs-works/engine/internal/middleware"
+	"flowjs-works/engine/internal/tenant"
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// API-key authentication tests (A01)
+// ──────────────────────────────────────────────────────────────────────────────
+
+func workspaceEcho() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(tenant.Workspace(r.Context())))
+	})
+}
+
+func TestParseAPIKeys(t *testing.T) {
+	keys := middleware.ParseAPIKeys("k1:team-a:alice:admin, k2:team-b:bot,bad-entry,k3:Bad Space:x")
+	require.Len(t, keys, 2)
+	assert.Equal(t, tenant.Principal{Subject: "alice", Workspace: "team-a", Role: "admin"}, keys["k1"])
+	assert.Equal(t, "member", keys["k2"].Role)
+}
+
+func TestParseAPIKeysTeam(t *testing.T) {
+	keys := middleware.ParseAPIKeys("k1:team-a:alice:member:payments,k2:team-a:bob::ops")
+	assert.Equal(t, tenant.Principal{Subject: "alice", Workspace: "team-a", Role: "member", Team: "payments"}, keys["k1"])
+	assert.Equal(t, "member", keys["k2"].Role)
+	assert.Equal(t, "ops", keys["k2"].Team)
+}
+
+func TestAuthenticateRejectsMissingKey(t *testing.T) {
+	keys := middleware.ParseAPIKeys("k1:team-a:alice")
+	handler := middleware.Authenticate(keys)(workspaceEcho())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/processes", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestAuthenticateBearerKeySetsWorkspace(t *testing.T) {
+	keys := middleware.ParseAPIKeys("k1:team-a:alice,k2:team-b:bob")
+	handler := middleware.Authenticate(keys)(workspaceEcho())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/processes", nil)
+	req.Header.Set("Authorization", "Bearer k2")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "team-b", rec.Body.String())
+}
+
+func TestAuthenticateXAPIKeyHeader(t *testing.T) {
+	keys := middleware.ParseAPIKeys("k1:team-a:alice")
+	handler := middleware.Authenticate(keys)(workspaceEcho())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/secrets", nil)
+	req.Header.Set("X-API-Key", "k1")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, "team-a", rec.Body.String())
+}
+
+func TestAuthenticatePublicPrefixSkipsCheck(t *testing.T) {
+	keys := middleware.ParseAPIKeys("k1:team-a:alice")
+	handler := middleware.Authenticate(keys, "/health", "/triggers/")(workspaceEcho())
+
+	req := httptest.NewRequest(http.MethodPost, "/triggers/ws/team-a/orders", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestAuthenticateWithoutKeysUsesDefaultWorkspace(t *testing.T) {
+	handler := middleware.Authenticate(nil)(workspaceEcho())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/processes", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, tenant.DefaultWorkspace, rec.Body.String())
+}
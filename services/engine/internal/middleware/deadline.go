@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Request deadline
+// ──────────────────────────────────────────────────────────────────────────────
+
+// RequestDeadline bounds the context of every request to timeout. Handlers
+// that execute a flow synchronously pass the request context to the engine,
+// so the execution stops at the deadline and the handler can still answer
+// (504 Gateway Timeout) instead of the server dropping the connection. The
+// context is also cancelled when the client disconnects. A timeout of zero
+// or less leaves requests unbounded.
+func RequestDeadline(timeout time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if timeout <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
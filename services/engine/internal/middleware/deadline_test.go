@@ -0,0 +1,29 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"flowjs-works/engine/internal/middleware"
+)
+
+func TestRequestDeadline(t *testing.T) {
+	var deadline time.Time
+	var bounded bool
+	handler := middleware.RequestDeadline(time.Minute)(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		deadline, bounded = r.Context().Deadline()
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/v1/flow", nil))
+	assert.True(t, bounded)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, 5*time.Second)
+
+	handler = middleware.RequestDeadline(0)(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		_, bounded = r.Context().Deadline()
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/v1/flow", nil))
+	assert.False(t, bounded, "no timeout leaves the request unbounded")
+}
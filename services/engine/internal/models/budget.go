@@ -0,0 +1,86 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrExecutionCancelled is returned by a synchronous execution stopped
+// because its caller went away or the request budget ran out (see Bind).
+var ErrExecutionCancelled = errors.New("execution cancelled")
+
+// SetTimeout starts the process timeout budget of the execution: after ms
+// milliseconds from now no further node runs and external calls are cut
+// short. Zero or a negative value means no timeout.
+func (ctx *ExecutionContext) SetTimeout(ms int) {
+	if ms > 0 {
+		ctx.Deadline = time.Now().Add(time.Duration(ms) * time.Millisecond)
+	}
+}
+
+// Remaining returns the time left of the process timeout budget, and false
+// when the execution has no timeout.
+func (ctx *ExecutionContext) Remaining() (time.Duration, bool) {
+	if ctx == nil || ctx.Deadline.IsZero() {
+		return 0, false
+	}
+	return time.Until(ctx.Deadline), true
+}
+
+// Budget caps timeout, the limit an activity would use on its own, at the
+// time left of the process timeout budget. Once the budget is spent it
+// returns a minimal positive duration, so the call fails immediately instead
+// of disabling its timeout.
+func (ctx *ExecutionContext) Budget(timeout time.Duration) time.Duration {
+	left, ok := ctx.Remaining()
+	if !ok || (timeout > 0 && timeout <= left) {
+		return timeout
+	}
+	return max(left, time.Millisecond)
+}
+
+// Context returns a child of parent that is cancelled when the process
+// timeout budget runs out or the context the execution is bound to is done.
+func (ctx *ExecutionContext) Context(parent context.Context) (context.Context, context.CancelFunc) {
+	var (
+		c      context.Context
+		cancel context.CancelFunc
+	)
+	if ctx == nil || ctx.Deadline.IsZero() {
+		c, cancel = context.WithCancel(parent)
+	} else {
+		c, cancel = context.WithDeadline(parent, ctx.Deadline)
+	}
+	if ctx == nil || ctx.caller == nil {
+		return c, cancel
+	}
+	stop := context.AfterFunc(ctx.caller, cancel)
+	return c, func() {
+		stop()
+		cancel()
+	}
+}
+
+// Bind ties the execution to the context of the request waiting for it. A
+// deadline of caller earlier than the process timeout becomes the
+// execution's deadline, and once caller is done no further node starts and
+// calls made under Context are cancelled.
+func (ctx *ExecutionContext) Bind(caller context.Context) {
+	if caller.Done() == nil {
+		return
+	}
+	ctx.caller = caller
+	if d, ok := caller.Deadline(); ok && (ctx.Deadline.IsZero() || d.Before(ctx.Deadline)) {
+		ctx.Deadline = d
+	}
+}
+
+// Err returns the error of the context the execution is bound to once it is
+// done, and nil otherwise.
+func (ctx *ExecutionContext) Err() error {
+	if ctx == nil || ctx.caller == nil {
+		return nil
+	}
+	return ctx.caller.Err()
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecutionContext_NoTimeout(t *testing.T) {
+	ctx := NewExecutionContext("exec")
+	ctx.SetTimeout(0)
+	_, ok := ctx.Remaining()
+	assert.False(t, ok)
+	assert.Equal(t, 30*time.Second, ctx.Budget(30*time.Second))
+
+	var nilCtx *ExecutionContext
+	assert.Equal(t, time.Second, nilCtx.Budget(time.Second))
+	goCtx, cancel := nilCtx.Context(context.Background())
+	defer cancel()
+	_, hasDeadline := goCtx.Deadline()
+	assert.False(t, hasDeadline)
+}
+
+func TestExecutionContext_Budget(t *testing.T) {
+	ctx := NewExecutionContext("exec")
+	ctx.SetTimeout(10_000)
+	left, ok := ctx.Remaining()
+	assert.True(t, ok)
+	assert.InDelta(t, 10*time.Second, left, float64(time.Second))
+
+	assert.Equal(t, 2*time.Second, ctx.Budget(2*time.Second), "a shorter own timeout is kept")
+	assert.LessOrEqual(t, ctx.Budget(30*time.Second), 10*time.Second)
+	assert.LessOrEqual(t, ctx.Budget(0), 10*time.Second, "no own timeout gets the budget")
+
+	ctx.Deadline = time.Now().Add(-time.Second)
+	assert.Equal(t, time.Millisecond, ctx.Budget(30*time.Second), "a spent budget fails fast")
+
+	goCtx, cancel := ctx.Context(context.Background())
+	defer cancel()
+	deadline, _ := goCtx.Deadline()
+	assert.Equal(t, ctx.Deadline, deadline)
+}
+
+func TestExecutionContext_Bind(t *testing.T) {
+	ctx := NewExecutionContext("exec")
+	ctx.Bind(context.Background())
+	assert.NoError(t, ctx.Err())
+	assert.True(t, ctx.Deadline.IsZero(), "a context that is never done is ignored")
+
+	ctx.SetTimeout(60_000)
+	caller, cancel := context.WithTimeout(context.Background(), time.Second)
+	ctx.Bind(caller)
+	deadline, _ := caller.Deadline()
+	assert.Equal(t, deadline, ctx.Deadline, "an earlier caller deadline wins")
+
+	goCtx, stop := ctx.Context(context.Background())
+	defer stop()
+	cancel()
+	<-goCtx.Done()
+	assert.ErrorIs(t, ctx.Err(), context.Canceled)
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// CapturedRequest is a raw inbound REST or SOAP trigger request kept so it
+// can be re-fired later (see the trigger config key "capture_days").
+type CapturedRequest struct {
+	ID          int64  `json:"id"`
+	Workspace   string `json:"workspace"`
+	ProcessID   string `json:"process_id"`
+	TriggerType string `json:"trigger_type"` // rest | soap
+	Method      string `json:"method"`
+	// Path is the request path relative to the trigger mount point and the
+	// workspace prefix, e.g. "/orders/42" for /triggers/ws/acme/orders/42.
+	Path  string `json:"path"`
+	Query string `json:"query,omitempty"` // raw query string
+	// Headers omits credentials (Authorization, Cookie, X-API-Key).
+	Headers    map[string][]string `json:"headers"`
+	Body       string              `json:"body,omitempty"`
+	CapturedAt time.Time           `json:"captured_at"`
+	ExpiresAt  time.Time           `json:"expires_at"`
+}
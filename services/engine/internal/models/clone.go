@@ -0,0 +1,119 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// processParamRe matches the {{param.<name>}} placeholders of a process used
+// as a template.
+var processParamRe = regexp.MustCompile(`\{\{\s*param\.([A-Za-z0-9_-]+)\s*\}\}`)
+
+// Parameters returns the names of the {{param.<name>}} placeholders in the
+// trigger and node configs of p, sorted.
+func (p *Process) Parameters() []string {
+	seen := make(map[string]bool)
+	lookup := func(name string) (interface{}, bool) {
+		seen[name] = true
+		return nil, false
+	}
+	substituteParams(p.Trigger.Config, lookup)
+	for _, node := range p.Nodes {
+		substituteParams(node.Config, lookup)
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Clone returns a copy of p as process id, named name unless it is empty,
+// with the {{param.<name>}} placeholders of its trigger and node configs
+// replaced by params. A config value that is a single placeholder takes the
+// parameter as is, so {"port": "{{param.port}}"} can become a number; a
+// placeholder within a longer string is replaced by the parameter as text.
+// Every placeholder needs a parameter; unused parameters are ignored. p is
+// not modified.
+func (p *Process) Clone(id, name string, params map[string]interface{}) (*Process, error) {
+	b, err := json.Marshal(p)
+	if err != nil {
+		return nil, fmt.Errorf("clone process: %w", err)
+	}
+	var out Process
+	if err := json.Unmarshal(b, &out); err != nil {
+		return nil, fmt.Errorf("clone process: %w", err)
+	}
+	out.Definition.ID = id
+	if name != "" {
+		out.Definition.Name = name
+	}
+	out.Definition.Workspace = ""
+	out.Definition.Environment = ""
+
+	var missing []string
+	lookup := func(name string) (interface{}, bool) {
+		v, ok := params[name]
+		if !ok && !slices.Contains(missing, name) {
+			missing = append(missing, name)
+		}
+		return v, ok
+	}
+	if out.Trigger.Config != nil {
+		out.Trigger.Config = substituteParams(out.Trigger.Config, lookup).(map[string]interface{})
+	}
+	for i := range out.Nodes {
+		if out.Nodes[i].Config != nil {
+			out.Nodes[i].Config = substituteParams(out.Nodes[i].Config, lookup).(map[string]interface{})
+		}
+	}
+	if len(missing) > 0 {
+		slices.Sort(missing)
+		return nil, fmt.Errorf("missing parameters: %s", strings.Join(missing, ", "))
+	}
+	return &out, nil
+}
+
+// substituteParams returns a copy of v with the placeholders of its strings,
+// nested ones included, replaced by the values lookup finds. Placeholders
+// lookup does not resolve are left as they are.
+func substituteParams(v interface{}, lookup func(name string) (interface{}, bool)) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			out[k] = substituteParams(item, lookup)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, item := range val {
+			out[i] = substituteParams(item, lookup)
+		}
+		return out
+	case string:
+		if m := processParamRe.FindStringSubmatch(val); m != nil && m[0] == val {
+			if param, ok := lookup(m[1]); ok {
+				return param
+			}
+			return val
+		}
+		return processParamRe.ReplaceAllStringFunc(val, func(placeholder string) string {
+			param, ok := lookup(processParamRe.FindStringSubmatch(placeholder)[1])
+			if !ok {
+				return placeholder
+			}
+			if s, isString := param.(string); isString {
+				return s
+			}
+			b, _ := json.Marshal(param)
+			return string(b)
+		})
+	default:
+		return v
+	}
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func templateProcess() *Process {
+	return &Process{
+		Definition: Definition{ID: "orders_template", Name: "Orders", Workspace: "team-a", Environment: "prod"},
+		Trigger:    Trigger{Type: "rest", Config: map[string]interface{}{"path": "/orders/{{param.customer}}", "method": "POST"}},
+		Nodes: []Node{
+			{ID: "upload", Type: "sftp", Config: map[string]interface{}{
+				"server": "{{ param.host }}",
+				"port":   "{{param.port}}",
+				"folder": "/inbound/{{param.customer}}",
+				"tags":   []interface{}{"{{param.customer}}", "orders"},
+			}},
+			{ID: "log", Type: "log", Config: map[string]interface{}{"message": "batch {{param.port}} for {{other}}"}},
+		},
+	}
+}
+
+func TestProcess_Parameters(t *testing.T) {
+	assert.Equal(t, []string{"customer", "host", "port"}, templateProcess().Parameters())
+	assert.Empty(t, (&Process{}).Parameters())
+}
+
+func TestProcess_Clone(t *testing.T) {
+	src := templateProcess()
+	out, err := src.Clone("orders_acme", "Orders (ACME)", map[string]interface{}{
+		"customer": "acme", "host": "sftp.acme.com", "port": float64(2222), "unused": true,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "orders_acme", out.Definition.ID)
+	assert.Equal(t, "Orders (ACME)", out.Definition.Name)
+	assert.Empty(t, out.Definition.Workspace)
+	assert.Empty(t, out.Definition.Environment)
+	assert.Equal(t, "/orders/acme", out.Trigger.Config["path"])
+	cfg := out.Nodes[0].Config
+	assert.Equal(t, "sftp.acme.com", cfg["server"])
+	assert.Equal(t, float64(2222), cfg["port"], "a whole-value placeholder keeps the parameter type")
+	assert.Equal(t, "/inbound/acme", cfg["folder"])
+	assert.Equal(t, []interface{}{"acme", "orders"}, cfg["tags"])
+	assert.Equal(t, "batch 2222 for {{other}}", out.Nodes[1].Config["message"], "other braces are not placeholders")
+
+	assert.Equal(t, "/orders/{{param.customer}}", src.Trigger.Config["path"], "the source is not modified")
+	assert.Equal(t, "orders_template", src.Definition.ID)
+
+	kept, err := src.Clone("orders_copy", "", map[string]interface{}{"customer": "x", "host": "h", "port": 1})
+	require.NoError(t, err)
+	assert.Equal(t, "Orders", kept.Definition.Name)
+}
+
+func TestProcess_CloneMissingParameters(t *testing.T) {
+	_, err := templateProcess().Clone("orders_acme", "", map[string]interface{}{"customer": "acme"})
+	assert.EqualError(t, err, "missing parameters: host, port")
+}
@@ -1,122 +1,288 @@
 package models
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"regexp"
-	"strconv"
+	"maps"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 )
 
-// arrayIndexRe matches a path part like "items[0]"
-var arrayIndexRe = regexp.MustCompile(`^(.+)\[(\d+)\]$`)
-
-// ExecutionContext holds the state during process execution
+// ExecutionContext holds the state during process execution. It is safe for
+// concurrent use through its methods: trigger, node and env state are
+// guarded by a lock, so nodes running at the same time (parallel branches,
+// batch releases, a debugger reading a paused run) can record their results
+// and read each other's. Trigger, Nodes and Env may be read directly only
+// once the execution has ended; while it runs, use the methods. Outputs are
+// stored by reference and must not be modified after SetNodeOutput.
 type ExecutionContext struct {
-	ExecutionID string                            `json:"execution_id"`
-	ProcessID   string                            `json:"process_id"`
-	Trigger     map[string]interface{}            `json:"trigger"`
-	Nodes       map[string]map[string]interface{} `json:"nodes"`
+	ExecutionID string `json:"execution_id"`
+	ProcessID   string `json:"process_id"`
+	Workspace   string `json:"workspace,omitempty"`
+	// Persistence is the process persistence level the execution ran with;
+	// it decides what reaches the audit log and the snapshot.
+	Persistence string `json:"persistence,omitempty"`
+	// SecretsAllowed is the settings.secrets_allowed of the process; nodes
+	// may only resolve these secrets. Empty allows any.
+	SecretsAllowed []string `json:"-"`
+	// Deadline is when the process timeout (settings.timeout) runs out; zero
+	// means no timeout. Activities bound their external calls by it.
+	Deadline time.Time `json:"-"`
+	// ParentExecutionID is the execution this one retries or replays, so
+	// composed runs can be traced as a tree. Empty for trigger-fired runs.
+	ParentExecutionID string `json:"parent_execution_id,omitempty"`
+	// ProcessRevision and DSLHash identify the exact process definition the
+	// execution runs (see Process.Hash), so a retry or replay can use it
+	// after the process was redeployed.
+	ProcessRevision int    `json:"process_revision,omitempty"`
+	DSLHash         string `json:"dsl_hash,omitempty"`
+	// ProcessVersion is the definition.version of the process, recorded
+	// with every audit event of the execution.
+	ProcessVersion string `json:"process_version,omitempty"`
+	// Env holds the variables of the deployment environment the process runs
+	// in, readable as $.env.<name>.
+	Env     map[string]interface{}            `json:"env,omitempty"`
+	Trigger map[string]interface{}            `json:"trigger"`
+	Nodes   map[string]map[string]interface{} `json:"nodes"`
+
+	// mu guards Env, Trigger, Nodes and chunks.
+	mu sync.RWMutex
+
+	// usage accumulates the resources used by the execution (see Stats).
+	usage *usage
+	// chunks receives the chunks the running node streams (see EmitChunk).
+	chunks ChunkHandler
+	// caller is the context of the request the execution answers (see Bind).
+	caller context.Context
 }
 
+// ChunkHandler runs the chunk transitions of a node for one chunk of its
+// output.
+type ChunkHandler func(output map[string]interface{}) error
+
 // NewExecutionContext creates a new execution context
 func NewExecutionContext(executionID string) *ExecutionContext {
 	return &ExecutionContext{
 		ExecutionID: executionID,
 		Trigger:     make(map[string]interface{}),
 		Nodes:       make(map[string]map[string]interface{}),
+		usage:       &usage{started: time.Now(), nodes: make(map[string]*nodeUsage)},
 	}
 }
 
 // SetTriggerData stores the trigger payload
 func (ctx *ExecutionContext) SetTriggerData(data map[string]interface{}) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
 	ctx.Trigger = data
 }
 
+// AllowsSecret reports whether the nodes of the execution may resolve secret id.
+func (ctx *ExecutionContext) AllowsSecret(id string) bool {
+	return ProcessSettings{SecretsAllowed: ctx.SecretsAllowed}.AllowsSecret(id)
+}
+
 // SetNodeOutput stores the output of a node execution
 func (ctx *ExecutionContext) SetNodeOutput(nodeID string, output map[string]interface{}) {
+	ctx.setNodeField(nodeID, "output", output)
+}
+
+// setNodeField sets one field of the state of node nodeID.
+func (ctx *ExecutionContext) setNodeField(nodeID, field string, v interface{}) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	if ctx.Nodes == nil {
+		ctx.Nodes = make(map[string]map[string]interface{})
+	}
 	if ctx.Nodes[nodeID] == nil {
 		ctx.Nodes[nodeID] = make(map[string]interface{})
 	}
-	ctx.Nodes[nodeID]["output"] = output
+	ctx.Nodes[nodeID][field] = v
 }
 
-// SetNodeStatus stores the status of a node execution
-func (ctx *ExecutionContext) SetNodeStatus(nodeID string, status string) {
-	if ctx.Nodes[nodeID] == nil {
-		ctx.Nodes[nodeID] = make(map[string]interface{})
+// SetNodeState replaces the state of node nodeID (status, output, visits)
+// with a copy of state, as a retry does with the nodes that succeeded before.
+func (ctx *ExecutionContext) SetNodeState(nodeID string, state map[string]interface{}) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	if ctx.Nodes == nil {
+		ctx.Nodes = make(map[string]map[string]interface{})
 	}
-	ctx.Nodes[nodeID]["status"] = status
+	ctx.Nodes[nodeID] = maps.Clone(state)
 }
 
-// GetValue retrieves a value using a simplified JSONPath syntax
-// Supports paths like:
-//   - $.trigger.body
-//   - $.trigger.headers.date
-//   - $.nodes.nodeId.output
-//   - $.nodes.nodeId.status
-func (ctx *ExecutionContext) GetValue(path string) (interface{}, error) {
-	// Remove leading $. if present
-	path = strings.TrimPrefix(path, "$.")
+// NodeState returns a copy of the state of node nodeID, or nil when the
+// node has not run.
+func (ctx *ExecutionContext) NodeState(nodeID string) map[string]interface{} {
+	ctx.mu.RLock()
+	defer ctx.mu.RUnlock()
+	return maps.Clone(ctx.Nodes[nodeID])
+}
 
-	// Split the path into parts
-	parts := strings.Split(path, ".")
+// NodeStatus returns the status of node nodeID, or "" when it has not run.
+func (ctx *ExecutionContext) NodeStatus(nodeID string) string {
+	ctx.mu.RLock()
+	defer ctx.mu.RUnlock()
+	status, _ := ctx.Nodes[nodeID]["status"].(string)
+	return status
+}
 
-	if len(parts) == 0 {
-		return nil, fmt.Errorf("invalid path: %s", path)
+// NodeStates returns a copy of the state of every node, by node id.
+func (ctx *ExecutionContext) NodeStates() map[string]map[string]interface{} {
+	ctx.mu.RLock()
+	defer ctx.mu.RUnlock()
+	states := make(map[string]map[string]interface{}, len(ctx.Nodes))
+	for id, state := range ctx.Nodes {
+		states[id] = maps.Clone(state)
 	}
+	return states
+}
+
+// SetChunkHandler makes fn receive the chunks the next node streams and
+// returns the handler it replaces. The executor sets it around a node that
+// has chunk transitions.
+func (ctx *ExecutionContext) SetChunkHandler(fn ChunkHandler) ChunkHandler {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	prev := ctx.chunks
+	ctx.chunks = fn
+	return prev
+}
 
-	// Start with the root context
-	var current interface{} = map[string]interface{}{
+// StreamsChunks reports whether the running node has chunk transitions to
+// stream its output to.
+func (ctx *ExecutionContext) StreamsChunks() bool {
+	return ctx.chunkHandler() != nil
+}
+
+func (ctx *ExecutionContext) chunkHandler() ChunkHandler {
+	if ctx == nil {
+		return nil
+	}
+	ctx.mu.RLock()
+	defer ctx.mu.RUnlock()
+	return ctx.chunks
+}
+
+// EmitChunk runs the chunk transitions of the running node with output as
+// its output. It fails when the node has none (see StreamsChunks).
+func (ctx *ExecutionContext) EmitChunk(output map[string]interface{}) error {
+	chunks := ctx.chunkHandler()
+	if chunks == nil {
+		return fmt.Errorf("node has no chunk transitions")
+	}
+	return chunks(output)
+}
+
+// SetNodeStatus stores the status of a node execution
+func (ctx *ExecutionContext) SetNodeStatus(nodeID string, status string) {
+	ctx.setNodeField(nodeID, "status", status)
+}
+
+// SetNodeVisits stores how many times a node has run in a process that
+// allows loops
+func (ctx *ExecutionContext) SetNodeVisits(nodeID string, visits int) {
+	ctx.setNodeField(nodeID, "visits", visits)
+}
+
+// GetValue resolves a JSONPath against the execution context, whose root
+// holds trigger, nodes and env. Besides dotted names and indexes
+// ($.trigger.body.items[0].id) it supports:
+//   - wildcards: $.nodes.*.status, $.trigger.body.items[*].sku
+//   - filters: $.trigger.body.items[?(@.qty > 0 && @.sku =~ '^A')]
+//   - slices and unions: $.items[1:3], $.items[-1], $.items[0,2], $.a['b','c']
+//   - recursive descent: $..price
+//
+// A path of only names and single indexes returns the value it points to, or
+// an error when it does not resolve. Any other path returns the list of
+// values it selects, empty when there are none.
+func (ctx *ExecutionContext) GetValue(path string) (interface{}, error) {
+	jp, err := compileJSONPath(path)
+	if err != nil {
+		return nil, err
+	}
+	ctx.mu.RLock()
+	defer ctx.mu.RUnlock()
+	root := map[string]interface{}{
 		"trigger": ctx.Trigger,
 		"nodes":   ctx.Nodes,
+		"env":     ctx.Env,
+	}
+	if !jp.definite {
+		matches := jp.eval(root)
+		if matches == nil {
+			matches = []interface{}{}
+		}
+		return matches, nil
 	}
+	val, part, ok := jp.lookup(root)
+	if !ok {
+		return nil, fmt.Errorf("path not found: %s at %s", path, part)
+	}
+	return val, nil
+}
 
-	// Traverse the path
-	for _, part := range parts {
-		// Check for array index syntax, e.g. "items[0]"
-		if m := arrayIndexRe.FindStringSubmatch(part); m != nil {
-			key := m[1]
-			idx, _ := strconv.Atoi(m[2])
-			switch v := current.(type) {
-			case map[string]interface{}:
-				val, ok := v[key]
-				if !ok {
-					return nil, fmt.Errorf("path not found: %s at part %s", path, key)
-				}
-				slice, ok := val.([]interface{})
-				if !ok {
-					return nil, fmt.Errorf("path %s: %s is not a slice (type: %T)", path, key, val)
-				}
-				if idx < 0 || idx >= len(slice) {
-					return nil, fmt.Errorf("path %s: index %d out of range (len %d)", path, idx, len(slice))
-				}
-				current = slice[idx]
-			default:
-				return nil, fmt.Errorf("cannot traverse path %s: not a map at part %s (type: %T)", path, part, current)
-			}
+// SetValue stores v at a dotted JSONPath under trigger, nodes or env
+// ($.trigger.body.id, $.nodes.fetch.output.status), creating missing
+// objects on the way, which modifies stored outputs in place: it is meant
+// for a paused execution (see engine.DebugSession.Modify). Indexes and
+// queries are not supported.
+func (ctx *ExecutionContext) SetValue(path string, v interface{}) error {
+	rest, ok := strings.CutPrefix(path, "$.")
+	if !ok || strings.ContainsAny(rest, "[]*?") {
+		return fmt.Errorf("cannot set %s: only dotted paths below $ are supported", path)
+	}
+	parts := strings.Split(rest, ".")
+	if slices.Contains(parts, "") || len(parts) < 2 {
+		return fmt.Errorf("cannot set %s: path must name a field of trigger, nodes or env", path)
+	}
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	var current map[string]interface{}
+	switch parts[0] {
+	case "trigger":
+		if ctx.Trigger == nil {
+			ctx.Trigger = make(map[string]interface{})
+		}
+		current = ctx.Trigger
+	case "env":
+		if ctx.Env == nil {
+			ctx.Env = make(map[string]interface{})
+		}
+		current = ctx.Env
+	case "nodes":
+		if len(parts) < 3 {
+			return fmt.Errorf("cannot set %s: path must name a field of a node", path)
+		}
+		if ctx.Nodes == nil {
+			ctx.Nodes = make(map[string]map[string]interface{})
+		}
+		if ctx.Nodes[parts[1]] == nil {
+			ctx.Nodes[parts[1]] = make(map[string]interface{})
+		}
+		current, parts = ctx.Nodes[parts[1]], parts[1:]
+	default:
+		return fmt.Errorf("cannot set %s: root must be trigger, nodes or env", path)
+	}
+	for _, part := range parts[1 : len(parts)-1] {
+		next, exists := current[part]
+		if !exists || next == nil {
+			child := make(map[string]interface{})
+			current[part] = child
+			current = child
 			continue
 		}
-		switch v := current.(type) {
-		case map[string]interface{}:
-			val, ok := v[part]
-			if !ok {
-				return nil, fmt.Errorf("path not found: %s at part %s", path, part)
-			}
-			current = val
-		case map[string]map[string]interface{}:
-			// Handle nested map structure (e.g., nodes map)
-			val, ok := v[part]
-			if !ok {
-				return nil, fmt.Errorf("path not found: %s at part %s", path, part)
-			}
-			current = val
-		default:
-			return nil, fmt.Errorf("cannot traverse path %s: not a map at part %s (type: %T)", path, part, current)
+		child, ok := next.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("cannot set %s: %q is not an object", path, part)
 		}
+		current = child
 	}
-
-	return current, nil
+	current[parts[len(parts)-1]] = v
+	return nil
 }
 
 // ResolveInputMapping resolves all input mappings for a node
@@ -145,6 +311,18 @@ func (ctx *ExecutionContext) ResolveInputMapping(inputMapping map[string]interfa
 	return result, nil
 }
 
+// executionContextJSON is ExecutionContext without its methods, so
+// MarshalJSON can encode the fields with the default encoding.
+type executionContextJSON ExecutionContext
+
+// MarshalJSON encodes the context while holding its lock, so a running
+// execution can be encoded (debug sessions, snapshots).
+func (ctx *ExecutionContext) MarshalJSON() ([]byte, error) {
+	ctx.mu.RLock()
+	defer ctx.mu.RUnlock()
+	return json.Marshal((*executionContextJSON)(ctx))
+}
+
 // ToJSON converts the context to JSON string
 func (ctx *ExecutionContext) ToJSON() (string, error) {
 	data, err := json.MarshalIndent(ctx, "", "  ")
@@ -2,6 +2,8 @@ package models
 
 import (
 	"encoding/json"
+	"fmt"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -123,6 +125,18 @@ func TestGetValue_NodeStatus(t *testing.T) {
 	assert.Equal(t, "success", val)
 }
 
+// TestGetValue_EnvVariable verifies that $.env.name resolves environment variables.
+func TestGetValue_EnvVariable(t *testing.T) {
+	ctx := NewExecutionContext("exec-1")
+	_, err := ctx.GetValue("$.env.base_url")
+	assert.Error(t, err, "no environment variables outside an environment")
+
+	ctx.Env = map[string]interface{}{"base_url": "https://staging.example.com"}
+	val, err := ctx.GetValue("$.env.base_url")
+	require.NoError(t, err)
+	assert.Equal(t, "https://staging.example.com", val)
+}
+
 // TestGetValue_InvalidPath verifies that an invalid path returns an error.
 func TestGetValue_InvalidPath(t *testing.T) {
 	ctx := NewExecutionContext("exec-1")
@@ -286,3 +300,89 @@ func TestGetValue_ArrayIndexOutOfRange(t *testing.T) {
 	_, err := ctx.GetValue("$.trigger.body.items[5]")
 	assert.Error(t, err)
 }
+
+func TestSetValue(t *testing.T) {
+	ctx := NewExecutionContext("exec-1")
+	ctx.SetTriggerData(map[string]interface{}{"body": map[string]interface{}{"id": 1}})
+	ctx.SetNodeOutput("fetch", map[string]interface{}{"status": 500})
+
+	require.NoError(t, ctx.SetValue("$.trigger.body.id", 7))
+	require.NoError(t, ctx.SetValue("$.nodes.fetch.output.status", 200))
+	require.NoError(t, ctx.SetValue("$.nodes.skipped.output.note", "set by hand"))
+	require.NoError(t, ctx.SetValue("$.env.region", "eu"))
+
+	for path, want := range map[string]interface{}{
+		"$.trigger.body.id":           7,
+		"$.nodes.fetch.output.status": 200,
+		"$.nodes.skipped.output.note": "set by hand",
+		"$.env.region":                "eu",
+	} {
+		got, err := ctx.GetValue(path)
+		require.NoError(t, err, path)
+		assert.Equal(t, want, got, path)
+	}
+
+	for _, path := range []string{"$", "$.trigger", "$.nodes.fetch", "$.items[0]", "$.other.x", "trigger.id", "$.trigger.body.id.x"} {
+		assert.Error(t, ctx.SetValue(path, 1), path)
+	}
+}
+
+func TestExecutionContext_EmitChunk(t *testing.T) {
+	ctx := NewExecutionContext("exec-1")
+	assert.False(t, ctx.StreamsChunks())
+	assert.Error(t, ctx.EmitChunk(map[string]interface{}{}))
+
+	var got []interface{}
+	prev := ctx.SetChunkHandler(func(output map[string]interface{}) error {
+		got = append(got, output["chunk"])
+		return nil
+	})
+	assert.Nil(t, prev)
+	require.True(t, ctx.StreamsChunks())
+	require.NoError(t, ctx.EmitChunk(map[string]interface{}{"chunk": 1}))
+	assert.Equal(t, []interface{}{1}, got)
+
+	ctx.SetChunkHandler(prev)
+	assert.False(t, ctx.StreamsChunks())
+}
+
+// TestExecutionContext_ConcurrentAccess records and reads node state from
+// several goroutines; run with -race to check the locking.
+func TestExecutionContext_ConcurrentAccess(t *testing.T) {
+	ctx := NewExecutionContext("exec-1")
+	ctx.SetTriggerData(map[string]interface{}{"id": "A-1"})
+
+	const workers = 8
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		id := fmt.Sprintf("node_%d", i)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for visit := 1; visit <= 50; visit++ {
+				ctx.SetNodeStatus(id, "running")
+				ctx.SetNodeOutput(id, map[string]interface{}{"visit": visit})
+				ctx.SetNodeVisits(id, visit)
+				ctx.SetNodeStatus(id, "success")
+
+				_, _ = ctx.GetValue("$.nodes.node_0.output.visit")
+				_, _ = ctx.ResolveInputMapping(map[string]interface{}{"id": "$.trigger.id"})
+				_ = ctx.NodeStates()
+				_ = ctx.NodeStatus("node_0")
+				_, _ = ctx.ToJSON()
+				assert.NoError(t, ctx.SetValue("$.env.last", id))
+			}
+		}()
+	}
+	wg.Wait()
+
+	states := ctx.NodeStates()
+	require.Len(t, states, workers)
+	for id, state := range states {
+		assert.Equal(t, "success", state["status"], id)
+		assert.Equal(t, 50, state["visits"], id)
+	}
+
+	states["node_0"]["status"] = "changed"
+	assert.Equal(t, "success", ctx.NodeStatus("node_0"), "NodeStates returns a copy")
+}
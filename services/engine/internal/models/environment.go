@@ -0,0 +1,178 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Deployment environments, in promotion order. A process is promoted from
+// its draft to dev, then to staging, then to prod.
+const (
+	EnvironmentDev     = "dev"
+	EnvironmentStaging = "staging"
+	EnvironmentProd    = "prod"
+)
+
+// Environments lists the deployment environments in promotion order.
+var Environments = []string{EnvironmentDev, EnvironmentStaging, EnvironmentProd}
+
+// ValidEnvironment reports whether env is a known deployment environment.
+func ValidEnvironment(env string) bool {
+	for _, e := range Environments {
+		if e == env {
+			return true
+		}
+	}
+	return false
+}
+
+// PreviousEnvironment returns the environment a promotion to env copies
+// from, or "" for dev, which is promoted from the process draft.
+func PreviousEnvironment(env string) string {
+	for i, e := range Environments {
+		if e == env && i > 0 {
+			return Environments[i-1]
+		}
+	}
+	return ""
+}
+
+// EnvironmentOverrides holds the values a process uses when it runs in one
+// deployment environment.
+type EnvironmentOverrides struct {
+	// Variables are readable by input mappings as $.env.<name>.
+	Variables map[string]interface{} `json:"variables,omitempty"`
+	// Secrets maps a secret_ref used by the nodes to the secret id used
+	// instead in this environment, e.g. {"crm-api": "crm-api-prod"}.
+	Secrets map[string]string `json:"secrets,omitempty"`
+	// Profiles maps a connection profile used by the nodes to the profile
+	// used instead in this environment, e.g. {"sftp-partner": "sftp-partner-prod"}.
+	Profiles map[string]string `json:"profiles,omitempty"`
+}
+
+// ForEnvironment returns a copy of p configured for env: node secret_refs and
+// settings.secrets_allowed are remapped by the environment's Secrets, node
+// profiles by its Profiles, and
+// Definition.Environment is set so executions expose the environment's
+// Variables. p is not modified.
+func (p *Process) ForEnvironment(env string) *Process {
+	out := *p
+	out.Definition.Environment = env
+	overrides := p.Definition.Environments[env]
+	out.Nodes = make([]Node, len(p.Nodes))
+	for i, node := range p.Nodes {
+		if id, ok := overrides.Secrets[node.SecretRef]; ok && node.SecretRef != "" {
+			node.SecretRef = id
+		}
+		if name, ok := overrides.Profiles[node.Profile]; ok && node.Profile != "" {
+			node.Profile = name
+		}
+		out.Nodes[i] = node
+	}
+	if allowed := p.Definition.Settings.SecretsAllowed; len(allowed) > 0 {
+		out.Definition.Settings.SecretsAllowed = make([]string, len(allowed))
+		for i, ref := range allowed {
+			if id, ok := overrides.Secrets[ref]; ok {
+				ref = id
+			}
+			out.Definition.Settings.SecretsAllowed[i] = ref
+		}
+	}
+	return &out
+}
+
+// EnvironmentVariables returns the variables of the environment the process
+// was configured for by ForEnvironment, or nil outside an environment.
+func (p *Process) EnvironmentVariables() map[string]interface{} {
+	if p.Definition.Environment == "" {
+		return nil
+	}
+	return p.Definition.Environments[p.Definition.Environment].Variables
+}
+
+// Validate checks the structural consistency of the process: a definition id,
+// a trigger type, unique node ids, a type or a valid $ref for each node, transitions between existing nodes
+// (every target of a dynamic transition included), chunk transitions leaving sql nodes, known condition modes, a valid lane and secret_refs within settings.secrets_allowed. It is run before a process is promoted to another
+// environment.
+func (p *Process) Validate() error {
+	var errs []error
+	if p.Definition.ID == "" {
+		errs = append(errs, errors.New("definition.id is required"))
+	}
+	if p.Trigger.Type == "" {
+		errs = append(errs, errors.New("trigger.type is required"))
+	}
+	nodes := make(map[string]bool, len(p.Nodes))
+	types := make(map[string]string, len(p.Nodes))
+	for i, node := range p.Nodes {
+		switch {
+		case node.ID == "":
+			errs = append(errs, fmt.Errorf("nodes[%d]: id is required", i))
+		case nodes[node.ID]:
+			errs = append(errs, fmt.Errorf("nodes[%d]: duplicate node id %q", i, node.ID))
+		}
+		switch {
+		case node.Ref != "":
+			if node.Type != "" {
+				errs = append(errs, fmt.Errorf("nodes[%d]: type and $ref are exclusive", i))
+			}
+			if _, _, err := ParseNodeRef(node.Ref); err != nil {
+				errs = append(errs, fmt.Errorf("nodes[%d]: %w", i, err))
+			}
+		case node.Type == "":
+			errs = append(errs, fmt.Errorf("nodes[%d]: type is required", i))
+		}
+		nodes[node.ID] = true
+		types[node.ID] = node.Type
+	}
+	for i, t := range p.Transitions {
+		if !nodes[t.From] && t.From != p.Trigger.ID {
+			errs = append(errs, fmt.Errorf("transitions[%d]: unknown source node %q", i, t.From))
+		}
+		if t.Type == TransitionDynamic {
+			if !nodes[t.From] && t.From == p.Trigger.ID {
+				// Trigger transitions only mark start nodes; every target
+				// would run.
+				errs = append(errs, fmt.Errorf("transitions[%d]: a dynamic transition cannot leave the trigger", i))
+			}
+			errs = append(errs, validateDynamic(i, t, nodes, p.Transitions)...)
+			continue
+		}
+		if !nodes[t.To] {
+			errs = append(errs, fmt.Errorf("transitions[%d]: unknown target node %q", i, t.To))
+		}
+		if t.Type == TransitionChunk && types[t.From] != "sql" {
+			errs = append(errs, fmt.Errorf("transitions[%d]: chunk transitions must leave a sql node", i))
+		}
+	}
+	if lane := p.Definition.Settings.Lane; lane != "" && !ValidLane(lane) {
+		errs = append(errs, fmt.Errorf("definition.settings.lane: invalid lane %q", lane))
+	}
+	if !validConditionMode(p.Definition.Settings.ConditionMode) {
+		errs = append(errs, fmt.Errorf("definition.settings.condition_mode: unknown mode %q", p.Definition.Settings.ConditionMode))
+	}
+	if f := p.Definition.Settings.FaultInjection; f != nil {
+		if err := f.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("definition.settings.fault_injection: %w", err))
+		}
+	}
+	for _, node := range p.Nodes {
+		if !validConditionMode(node.ConditionMode) {
+			errs = append(errs, fmt.Errorf("node %s: unknown condition_mode %q", node.ID, node.ConditionMode))
+		}
+		for _, key := range node.SensitiveInputs {
+			if _, ok := node.InputMapping[key]; !ok {
+				errs = append(errs, fmt.Errorf("node %s: sensitive_inputs key %q is not in input_mapping", node.ID, key))
+			}
+		}
+		if node.SecretRef != "" && !p.Definition.Settings.AllowsSecret(node.SecretRef) {
+			errs = append(errs, fmt.Errorf("node %s: secret_ref %q is not in definition.settings.secrets_allowed", node.ID, node.SecretRef))
+		}
+	}
+	for env := range p.Definition.Environments {
+		if !ValidEnvironment(env) {
+			errs = append(errs, fmt.Errorf("definition.environments: unknown environment %q", env))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,107 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvironmentOrder(t *testing.T) {
+	assert.True(t, ValidEnvironment("staging"))
+	assert.False(t, ValidEnvironment("qa"))
+	assert.False(t, ValidEnvironment(""))
+	assert.Equal(t, "", PreviousEnvironment(EnvironmentDev))
+	assert.Equal(t, EnvironmentDev, PreviousEnvironment(EnvironmentStaging))
+	assert.Equal(t, EnvironmentStaging, PreviousEnvironment(EnvironmentProd))
+}
+
+func TestProcess_ForEnvironment(t *testing.T) {
+	proc := &Process{
+		Definition: Definition{ID: "p1", Environments: map[string]EnvironmentOverrides{
+			"prod": {
+				Variables: map[string]interface{}{"base_url": "https://api.example.com"},
+				Secrets:   map[string]string{"crm": "crm-prod"},
+				Profiles:  map[string]string{"crm-api": "crm-api-prod"},
+			},
+		}},
+		Nodes: []Node{{ID: "call", Type: "http", SecretRef: "crm", Profile: "crm-api"}, {ID: "log", Type: "log"}},
+	}
+
+	prod := proc.ForEnvironment("prod")
+	assert.Equal(t, "prod", prod.Definition.Environment)
+	assert.Equal(t, "crm-prod", prod.Nodes[0].SecretRef)
+	assert.Equal(t, "", prod.Nodes[1].SecretRef)
+	assert.Equal(t, "crm-api-prod", prod.Nodes[0].Profile)
+	assert.Equal(t, "https://api.example.com", prod.EnvironmentVariables()["base_url"])
+	assert.Equal(t, "crm", proc.Nodes[0].SecretRef, "the original process is not modified")
+	assert.Nil(t, proc.EnvironmentVariables())
+
+	dev := proc.ForEnvironment("dev")
+	assert.Equal(t, "crm", dev.Nodes[0].SecretRef)
+	assert.Nil(t, dev.EnvironmentVariables())
+}
+
+func TestProcess_Validate(t *testing.T) {
+	valid := &Process{
+		Definition:  Definition{ID: "p1"},
+		Trigger:     Trigger{ID: "trg", Type: "manual"},
+		Nodes:       []Node{{ID: "a", Type: "log"}, {ID: "b", Type: "log"}},
+		Transitions: []Transition{{From: "a", To: "b", Type: "success"}},
+	}
+	require.NoError(t, valid.Validate())
+
+	invalid := &Process{
+		Definition:  Definition{Environments: map[string]EnvironmentOverrides{"qa": {}}, Settings: ProcessSettings{Lane: "Batch Jobs"}},
+		Nodes:       []Node{{ID: "a", Type: "log"}, {ID: "a"}},
+		Transitions: []Transition{{From: "a", To: "missing"}},
+	}
+	err := invalid.Validate()
+	require.Error(t, err)
+	for _, msg := range []string{"definition.id", "trigger.type", "duplicate node id \"a\"", "nodes[1]: type", "unknown target node \"missing\"", "unknown environment \"qa\"", "invalid lane \"Batch Jobs\""} {
+		assert.ErrorContains(t, err, msg)
+	}
+
+	valid.Nodes[1].SensitiveInputs = []string{"token"}
+	assert.ErrorContains(t, valid.Validate(), `node b: sensitive_inputs key "token" is not in input_mapping`)
+	valid.Nodes[1].SensitiveInputs = nil
+
+	valid.Nodes[0].SecretRef = "crm"
+	valid.Definition.Settings.SecretsAllowed = []string{"erp"}
+	assert.ErrorContains(t, valid.Validate(), `node a: secret_ref "crm" is not in definition.settings.secrets_allowed`)
+	valid.Definition.Settings.SecretsAllowed = nil
+
+	valid.Nodes[1] = Node{ID: "b", Ref: "notify-ops@2"}
+	assert.NoError(t, valid.Validate(), "a $ref node has no type")
+	valid.Nodes[1].Type = "log"
+	assert.ErrorContains(t, valid.Validate(), "nodes[1]: type and $ref are exclusive")
+	valid.Nodes[1] = Node{ID: "b", Ref: "notify-ops@latest"}
+	assert.ErrorContains(t, valid.Validate(), "nodes[1]: invalid $ref")
+}
+
+func TestProcess_ForEnvironmentRemapsSecretsAllowed(t *testing.T) {
+	proc := &Process{Definition: Definition{
+		Settings:     ProcessSettings{SecretsAllowed: []string{"crm", "smtp"}},
+		Environments: map[string]EnvironmentOverrides{"prod": {Secrets: map[string]string{"crm": "crm-prod"}}},
+	}}
+	prod := proc.ForEnvironment("prod")
+	assert.Equal(t, []string{"crm-prod", "smtp"}, prod.Definition.Settings.SecretsAllowed)
+	assert.Equal(t, []string{"crm", "smtp"}, proc.Definition.Settings.SecretsAllowed, "the original process is not modified")
+	assert.True(t, prod.Definition.Settings.AllowsSecret("crm-prod"))
+	assert.False(t, prod.Definition.Settings.AllowsSecret("crm"))
+	assert.True(t, ProcessSettings{}.AllowsSecret("anything"), "no list allows every secret")
+}
+
+func TestProcess_ValidateFaultInjection(t *testing.T) {
+	p := Process{
+		Definition: Definition{ID: "p"},
+		Trigger:    Trigger{ID: "trg", Type: "manual"},
+		Nodes:      []Node{{ID: "a", Type: "log"}},
+	}
+	p.Definition.Settings.FaultInjection = &FaultInjection{ErrorRate: 0.5}
+	require.NoError(t, p.Validate())
+	p.Definition.Settings.FaultInjection = &FaultInjection{ErrorRate: 1.5, LatencyRate: 0.2}
+	err := p.Validate()
+	assert.ErrorContains(t, err, "definition.settings.fault_injection: error_rate must be between 0 and 1, got 1.5")
+	assert.ErrorContains(t, err, "latency_rate needs latency_ms")
+}
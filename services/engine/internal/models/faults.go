@@ -0,0 +1,113 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FaultInjection makes the activities of an execution fail on purpose, so
+// error transitions, retry policies and circuit breakers can be verified
+// before production. Each attempt of a node draws every fault independently
+// with its rate, a probability in [0, 1].
+type FaultInjection struct {
+	// LatencyRate delays an attempt by LatencyMs before the activity runs.
+	LatencyRate float64 `json:"latency_rate,omitempty"`
+	LatencyMs   int     `json:"latency_ms,omitempty"`
+	// ErrorRate fails an attempt without running the activity.
+	ErrorRate float64 `json:"error_rate,omitempty"`
+	// TruncateRate cuts the output of a successful attempt: strings and
+	// arrays keep their first half.
+	TruncateRate float64 `json:"truncate_rate,omitempty"`
+	// Nodes limits the faults to these node ids; empty means every node.
+	Nodes []string `json:"nodes,omitempty"`
+	// Seed makes the draws repeatable; zero draws differently every run.
+	Seed int64 `json:"seed,omitempty"`
+}
+
+// Validate checks that the rates are probabilities and that a latency rate
+// comes with a latency.
+func (f *FaultInjection) Validate() error {
+	var errs []error
+	for name, rate := range map[string]float64{"latency_rate": f.LatencyRate, "error_rate": f.ErrorRate, "truncate_rate": f.TruncateRate} {
+		if rate < 0 || rate > 1 {
+			errs = append(errs, fmt.Errorf("%s must be between 0 and 1, got %v", name, rate))
+		}
+	}
+	if f.LatencyMs < 0 {
+		errs = append(errs, fmt.Errorf("latency_ms must not be negative"))
+	}
+	if f.LatencyRate > 0 && f.LatencyMs == 0 {
+		errs = append(errs, fmt.Errorf("latency_rate needs latency_ms"))
+	}
+	slices.SortFunc(errs, func(a, b error) int { return strings.Compare(a.Error(), b.Error()) })
+	return errors.Join(errs...)
+}
+
+// Targets reports whether faults are injected into node nodeID.
+func (f *FaultInjection) Targets(nodeID string) bool {
+	return len(f.Nodes) == 0 || slices.Contains(f.Nodes, nodeID)
+}
+
+// ParseFaultInjection parses the value of the X-Fault-Injection header:
+// either a FaultInjection JSON object or comma-separated settings such as
+//
+//	error=0.3,latency=0.5:250ms,truncate=0.1,nodes=fetch|save,seed=7
+//
+// where latency is a rate and a Go duration.
+func ParseFaultInjection(s string) (*FaultInjection, error) {
+	s = strings.TrimSpace(s)
+	f := &FaultInjection{}
+	if strings.HasPrefix(s, "{") {
+		if err := json.Unmarshal([]byte(s), f); err != nil {
+			return nil, fmt.Errorf("fault injection: %w", err)
+		}
+	} else if err := parseFaultSettings(s, f); err != nil {
+		return nil, err
+	}
+	if err := f.Validate(); err != nil {
+		return nil, fmt.Errorf("fault injection: %w", err)
+	}
+	return f, nil
+}
+
+// parseFaultSettings sets f from the comma-separated form of
+// ParseFaultInjection.
+func parseFaultSettings(s string, f *FaultInjection) error {
+	for _, part := range strings.Split(s, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		var err error
+		switch key {
+		case "error":
+			f.ErrorRate, err = strconv.ParseFloat(value, 64)
+		case "truncate":
+			f.TruncateRate, err = strconv.ParseFloat(value, 64)
+		case "latency":
+			rate, delay, ok := strings.Cut(value, ":")
+			if !ok {
+				return fmt.Errorf("fault injection: latency must be <rate>:<duration>, got %q", value)
+			}
+			if f.LatencyRate, err = strconv.ParseFloat(rate, 64); err == nil {
+				var d time.Duration
+				d, err = time.ParseDuration(delay)
+				f.LatencyMs = int(d.Milliseconds())
+			}
+		case "nodes":
+			f.Nodes = strings.Split(value, "|")
+		case "seed":
+			f.Seed, err = strconv.ParseInt(value, 10, 64)
+		case "":
+			continue
+		default:
+			return fmt.Errorf("fault injection: unknown setting %q", key)
+		}
+		if err != nil {
+			return fmt.Errorf("fault injection: %s: %w", key, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFaultInjection(t *testing.T) {
+	f, err := ParseFaultInjection("error=0.3, latency=0.5:250ms,truncate=0.1,nodes=fetch|save,seed=7")
+	require.NoError(t, err)
+	assert.Equal(t, &FaultInjection{ErrorRate: 0.3, LatencyRate: 0.5, LatencyMs: 250, TruncateRate: 0.1, Nodes: []string{"fetch", "save"}, Seed: 7}, f)
+	assert.True(t, f.Targets("save"))
+	assert.False(t, f.Targets("notify"))
+
+	f, err = ParseFaultInjection(`{"error_rate": 1}`)
+	require.NoError(t, err)
+	assert.Equal(t, &FaultInjection{ErrorRate: 1}, f)
+	assert.True(t, f.Targets("any"))
+
+	for _, bad := range []string{"error=x", "latency=0.5", "latency=0.5:soon", "chaos=1", "error=2", `{"error_rate": "1"}`} {
+		_, err := ParseFaultInjection(bad)
+		assert.Error(t, err, bad)
+	}
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// Hash returns the hex SHA-256 of the process as JSON: the definition an
+// execution actually runs, including the environment it was configured for.
+// Two processes with the same hash behave the same.
+func (p *Process) Hash() string {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProcessHash(t *testing.T) {
+	p := &Process{
+		Definition: Definition{ID: "orders", Version: "1.0"},
+		Nodes:      []Node{{ID: "a", Type: "logger"}},
+	}
+	h := p.Hash()
+	assert.Len(t, h, 64)
+
+	same := *p
+	same.Definition.Revision = 7
+	assert.Equal(t, h, same.Hash(), "the revision is not part of the DSL")
+
+	changed := *p
+	changed.Nodes = []Node{{ID: "a", Type: "http"}}
+	assert.NotEqual(t, h, changed.Hash())
+
+	assert.NotEqual(t, h, p.ForEnvironment("prod").Hash(), "the environment changes what runs")
+}
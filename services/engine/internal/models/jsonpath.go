@@ -0,0 +1,845 @@
+package models
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// segmentKind is the selector of one JSONPath segment.
+type segmentKind int
+
+const (
+	segName     segmentKind = iota // .name, ['name'] or ['a','b']
+	segWildcard                    // .* or [*]
+	segIndex                       // [0], [-1] or [0,2]
+	segSlice                       // [start:end:step]
+	segFilter                      // [?(expr)]
+)
+
+// pathSegment is one step of a compiled JSONPath.
+type pathSegment struct {
+	text string
+	kind segmentKind
+	// descendant is set for segments reached through "..": the selector
+	// applies to the value and every value nested in it.
+	descendant bool
+	names      []string
+	indexes    []int
+	slice      [3]*int
+	filter     filterFunc
+}
+
+// jsonPath is a compiled JSONPath expression.
+type jsonPath struct {
+	segments []pathSegment
+	// definite is set when the path selects at most one value: it has only
+	// single names and indexes, so it resolves to a value rather than a list.
+	definite bool
+}
+
+// maxCachedPaths bounds the compiled path cache; paths come from process
+// definitions, but the execution context API also accepts arbitrary ones.
+const maxCachedPaths = 4096
+
+var pathCache = struct {
+	sync.Mutex
+	paths map[string]*jsonPath
+}{paths: make(map[string]*jsonPath)}
+
+// compileJSONPath parses path, reusing a previous compilation. A path that
+// starts with neither "$" nor "@" is relative to the root ("trigger.body"
+// is "$.trigger.body").
+func compileJSONPath(path string) (*jsonPath, error) {
+	pathCache.Lock()
+	jp, ok := pathCache.paths[path]
+	pathCache.Unlock()
+	if ok {
+		return jp, nil
+	}
+
+	src := strings.TrimSpace(path)
+	switch {
+	case strings.HasPrefix(src, "$") || strings.HasPrefix(src, "@"):
+		src = src[1:]
+	case strings.HasPrefix(src, ".") || strings.HasPrefix(src, "["):
+	case src != "":
+		src = "." + src
+	}
+	p := &pathParser{src: src}
+	jp, err := p.parse()
+	if err != nil {
+		return nil, fmt.Errorf("invalid path %s: %w", path, err)
+	}
+
+	pathCache.Lock()
+	if len(pathCache.paths) >= maxCachedPaths {
+		pathCache.paths = make(map[string]*jsonPath)
+	}
+	pathCache.paths[path] = jp
+	pathCache.Unlock()
+	return jp, nil
+}
+
+// eval returns the values path selects in root, in document order (object
+// members by key).
+func (jp *jsonPath) eval(root interface{}) []interface{} {
+	return jp.evalFrom(root, root)
+}
+
+// evalFrom evaluates the path against start; root is what "$" refers to in
+// filters.
+func (jp *jsonPath) evalFrom(start, root interface{}) []interface{} {
+	current := []interface{}{start}
+	for i := range jp.segments {
+		seg := &jp.segments[i]
+		var next []interface{}
+		for _, v := range current {
+			if seg.descendant {
+				walkValues(v, func(n interface{}) { next = seg.apply(n, root, next) })
+			} else {
+				next = seg.apply(v, root, next)
+			}
+		}
+		if len(next) == 0 {
+			return nil
+		}
+		current = next
+	}
+	return current
+}
+
+// lookup resolves a definite path, reporting the segment that did not
+// resolve.
+func (jp *jsonPath) lookup(root interface{}) (interface{}, string, bool) {
+	current := root
+	for i := range jp.segments {
+		seg := &jp.segments[i]
+		next := seg.apply(current, root, nil)
+		if len(next) == 0 {
+			return nil, seg.text, false
+		}
+		current = next[0]
+	}
+	return current, "", true
+}
+
+// apply appends the values seg selects in v to out.
+func (seg *pathSegment) apply(v, root interface{}, out []interface{}) []interface{} {
+	switch seg.kind {
+	case segName:
+		for _, name := range seg.names {
+			if val, ok := objectField(v, name); ok {
+				out = append(out, val)
+			}
+		}
+	case segWildcard:
+		out = append(out, childValues(v)...)
+	case segIndex:
+		arr, ok := arrayItems(v)
+		if !ok {
+			return out
+		}
+		for _, idx := range seg.indexes {
+			if idx < 0 {
+				idx += len(arr)
+			}
+			if idx >= 0 && idx < len(arr) {
+				out = append(out, arr[idx])
+			}
+		}
+	case segSlice:
+		if arr, ok := arrayItems(v); ok {
+			out = appendSlice(out, arr, seg.slice)
+		}
+	case segFilter:
+		for _, child := range childValues(v) {
+			if seg.filter(child, root) {
+				out = append(out, child)
+			}
+		}
+	}
+	return out
+}
+
+// appendSlice appends arr[start:end:step] to out with Python semantics:
+// negative bounds count from the end and a negative step walks backwards.
+func appendSlice(out, arr []interface{}, bounds [3]*int) []interface{} {
+	n := len(arr)
+	step := 1
+	if bounds[2] != nil {
+		step = *bounds[2]
+	}
+	if step == 0 {
+		return out
+	}
+	norm := func(b *int, def int) int {
+		if b == nil {
+			return def
+		}
+		i := *b
+		if i < 0 {
+			i += n
+		}
+		return i
+	}
+	if step > 0 {
+		start := clampInt(norm(bounds[0], 0), 0, n)
+		end := clampInt(norm(bounds[1], n), 0, n)
+		for i := start; i < end; i += step {
+			out = append(out, arr[i])
+		}
+		return out
+	}
+	start := clampInt(norm(bounds[0], n-1), -1, n-1)
+	end := clampInt(norm(bounds[1], -1), -1, n-1)
+	for i := start; i > end; i += step {
+		out = append(out, arr[i])
+	}
+	return out
+}
+
+func clampInt(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
+
+// walkValues calls fn for v and every value nested in it, depth first.
+func walkValues(v interface{}, fn func(interface{})) {
+	fn(v)
+	for _, child := range childValues(v) {
+		walkValues(child, fn)
+	}
+}
+
+// objectField returns the member key of v when v is an object.
+func objectField(v interface{}, key string) (interface{}, bool) {
+	switch m := v.(type) {
+	case map[string]interface{}:
+		val, ok := m[key]
+		return val, ok
+	case map[string]map[string]interface{}:
+		val, ok := m[key]
+		return val, ok
+	case nil, []interface{}, string, float64, bool:
+		return nil, false
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String {
+		return nil, false
+	}
+	val := rv.MapIndex(reflect.ValueOf(key).Convert(rv.Type().Key()))
+	if !val.IsValid() {
+		return nil, false
+	}
+	return val.Interface(), true
+}
+
+// arrayItems returns v as a list when it is an array.
+func arrayItems(v interface{}) ([]interface{}, bool) {
+	switch a := v.(type) {
+	case []interface{}:
+		return a, true
+	case []map[string]interface{}:
+		items := make([]interface{}, len(a))
+		for i, item := range a {
+			items[i] = item
+		}
+		return items, true
+	case nil, map[string]interface{}, string, float64, bool:
+		return nil, false
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, false
+	}
+	items := make([]interface{}, rv.Len())
+	for i := range items {
+		items[i] = rv.Index(i).Interface()
+	}
+	return items, true
+}
+
+// childValues returns the items of an array or the members of an object,
+// ordered by key.
+func childValues(v interface{}) []interface{} {
+	if arr, ok := arrayItems(v); ok {
+		return arr
+	}
+	var keys []string
+	switch m := v.(type) {
+	case map[string]interface{}:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[string]map[string]interface{}:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	default:
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String {
+			return nil
+		}
+		for _, k := range rv.MapKeys() {
+			keys = append(keys, k.String())
+		}
+	}
+	sort.Strings(keys)
+	values := make([]interface{}, 0, len(keys))
+	for _, k := range keys {
+		val, _ := objectField(v, k)
+		values = append(values, val)
+	}
+	return values
+}
+
+// pathParser parses the segments of a JSONPath after its root symbol.
+type pathParser struct {
+	src string
+	pos int
+}
+
+func (p *pathParser) parse() (*jsonPath, error) {
+	jp := &jsonPath{definite: true}
+	for p.pos < len(p.src) {
+		start := p.pos
+		var seg pathSegment
+		var err error
+		switch {
+		case strings.HasPrefix(p.src[p.pos:], ".."):
+			p.pos += 2
+			if p.pos < len(p.src) && p.src[p.pos] == '[' {
+				seg, err = p.parseBracket()
+			} else {
+				seg, err = p.parseDotted()
+			}
+			seg.descendant = true
+		case p.src[p.pos] == '.':
+			p.pos++
+			seg, err = p.parseDotted()
+		case p.src[p.pos] == '[':
+			seg, err = p.parseBracket()
+		default:
+			return nil, fmt.Errorf("unexpected %q at offset %d", p.src[p.pos], p.pos)
+		}
+		if err != nil {
+			return nil, err
+		}
+		seg.text = p.src[start:p.pos]
+		if seg.descendant || seg.kind == segWildcard || seg.kind == segSlice || seg.kind == segFilter ||
+			len(seg.names) > 1 || len(seg.indexes) > 1 {
+			jp.definite = false
+		}
+		jp.segments = append(jp.segments, seg)
+	}
+	return jp, nil
+}
+
+// parseDotted parses the name or "*" following a dot.
+func (p *pathParser) parseDotted() (pathSegment, error) {
+	if p.pos < len(p.src) && p.src[p.pos] == '*' {
+		p.pos++
+		return pathSegment{kind: segWildcard}, nil
+	}
+	end := p.pos
+	for end < len(p.src) && p.src[end] != '.' && p.src[end] != '[' {
+		end++
+	}
+	if end == p.pos {
+		return pathSegment{}, fmt.Errorf("empty name at offset %d", p.pos)
+	}
+	name := p.src[p.pos:end]
+	p.pos = end
+	return pathSegment{kind: segName, names: []string{name}}, nil
+}
+
+// parseBracket parses a [...] selector.
+func (p *pathParser) parseBracket() (pathSegment, error) {
+	open := p.pos
+	p.pos++
+	p.skipSpaces()
+	if p.pos >= len(p.src) {
+		return pathSegment{}, fmt.Errorf("unterminated [ at offset %d", open)
+	}
+	switch c := p.src[p.pos]; {
+	case c == '?':
+		p.pos++
+		p.skipSpaces()
+		if p.pos >= len(p.src) || p.src[p.pos] != '(' {
+			return pathSegment{}, fmt.Errorf("filter at offset %d must be written [?(...)]", open)
+		}
+		end := matchingParen(p.src, p.pos)
+		if end < 0 {
+			return pathSegment{}, fmt.Errorf("unterminated filter at offset %d", open)
+		}
+		filter, err := parseFilter(p.src[p.pos+1 : end])
+		if err != nil {
+			return pathSegment{}, err
+		}
+		p.pos = end + 1
+		if err := p.closeBracket(open); err != nil {
+			return pathSegment{}, err
+		}
+		return pathSegment{kind: segFilter, filter: filter}, nil
+	case c == '*':
+		p.pos++
+		if err := p.closeBracket(open); err != nil {
+			return pathSegment{}, err
+		}
+		return pathSegment{kind: segWildcard}, nil
+	case c == '\'' || c == '"':
+		var names []string
+		for {
+			p.skipSpaces()
+			name, err := p.parseQuoted()
+			if err != nil {
+				return pathSegment{}, err
+			}
+			names = append(names, name)
+			p.skipSpaces()
+			if p.pos < len(p.src) && p.src[p.pos] == ',' {
+				p.pos++
+				continue
+			}
+			break
+		}
+		if err := p.closeBracket(open); err != nil {
+			return pathSegment{}, err
+		}
+		return pathSegment{kind: segName, names: names}, nil
+	}
+
+	end := strings.IndexByte(p.src[p.pos:], ']')
+	if end < 0 {
+		return pathSegment{}, fmt.Errorf("unterminated [ at offset %d", open)
+	}
+	body := strings.TrimSpace(p.src[p.pos : p.pos+end])
+	p.pos += end + 1
+	if strings.Contains(body, ":") {
+		parts := strings.Split(body, ":")
+		if len(parts) > 3 {
+			return pathSegment{}, fmt.Errorf("invalid slice [%s]", body)
+		}
+		seg := pathSegment{kind: segSlice}
+		for i, part := range parts {
+			if part = strings.TrimSpace(part); part == "" {
+				continue
+			}
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return pathSegment{}, fmt.Errorf("invalid slice [%s]", body)
+			}
+			seg.slice[i] = &n
+		}
+		return seg, nil
+	}
+	seg := pathSegment{kind: segIndex}
+	for _, part := range strings.Split(body, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return pathSegment{}, fmt.Errorf("invalid index [%s]", body)
+		}
+		seg.indexes = append(seg.indexes, n)
+	}
+	return seg, nil
+}
+
+func (p *pathParser) closeBracket(open int) error {
+	p.skipSpaces()
+	if p.pos >= len(p.src) || p.src[p.pos] != ']' {
+		return fmt.Errorf("unterminated [ at offset %d", open)
+	}
+	p.pos++
+	return nil
+}
+
+func (p *pathParser) skipSpaces() {
+	for p.pos < len(p.src) && p.src[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+// parseQuoted parses a single- or double-quoted string with backslash
+// escapes.
+func (p *pathParser) parseQuoted() (string, error) {
+	s, n, err := readQuoted(p.src[p.pos:])
+	if err != nil {
+		return "", fmt.Errorf("%w at offset %d", err, p.pos)
+	}
+	p.pos += n
+	return s, nil
+}
+
+// readQuoted reads the quoted string src starts with and returns it with
+// the number of bytes consumed.
+func readQuoted(src string) (string, int, error) {
+	if src == "" || (src[0] != '\'' && src[0] != '"') {
+		return "", 0, fmt.Errorf("expected a quoted string")
+	}
+	quote := src[0]
+	var b strings.Builder
+	for i := 1; i < len(src); i++ {
+		switch c := src[i]; {
+		case c == '\\' && i+1 < len(src):
+			i++
+			b.WriteByte(src[i])
+		case c == quote:
+			return b.String(), i + 1, nil
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string")
+}
+
+// matchingParen returns the index of the parenthesis closing the one at
+// open, skipping quoted strings, or -1.
+func matchingParen(src string, open int) int {
+	depth := 0
+	for i := open; i < len(src); i++ {
+		switch src[i] {
+		case '\'', '"':
+			_, n, err := readQuoted(src[i:])
+			if err != nil {
+				return -1
+			}
+			i += n - 1
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// filterFunc reports whether the filter of a [?(...)] selector accepts cur.
+type filterFunc func(cur, root interface{}) bool
+
+// operandFunc returns the value of a filter operand, and whether it exists.
+type operandFunc func(cur, root interface{}) (interface{}, bool)
+
+// parseFilter compiles a filter expression: comparisons (==, !=, <, <=, >,
+// >=, =~) of @-relative paths, $-paths and literals, existence tests, and
+// !, && and || with parentheses.
+func parseFilter(src string) (filterFunc, error) {
+	f := &filterParser{src: src}
+	fn, err := f.parseOr()
+	if err != nil {
+		return nil, fmt.Errorf("filter %q: %w", src, err)
+	}
+	f.skipSpaces()
+	if f.pos < len(f.src) {
+		return nil, fmt.Errorf("filter %q: unexpected %q", src, f.src[f.pos:])
+	}
+	return fn, nil
+}
+
+type filterParser struct {
+	src string
+	pos int
+}
+
+func (f *filterParser) skipSpaces() {
+	for f.pos < len(f.src) && unicode.IsSpace(rune(f.src[f.pos])) {
+		f.pos++
+	}
+}
+
+// consume skips tok when it comes next.
+func (f *filterParser) consume(tok string) bool {
+	f.skipSpaces()
+	if strings.HasPrefix(f.src[f.pos:], tok) {
+		f.pos += len(tok)
+		return true
+	}
+	return false
+}
+
+func (f *filterParser) parseOr() (filterFunc, error) {
+	left, err := f.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for f.consume("||") {
+		right, err := f.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(cur, root interface{}) bool { return l(cur, root) || right(cur, root) }
+	}
+	return left, nil
+}
+
+func (f *filterParser) parseAnd() (filterFunc, error) {
+	left, err := f.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for f.consume("&&") {
+		right, err := f.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(cur, root interface{}) bool { return l(cur, root) && right(cur, root) }
+	}
+	return left, nil
+}
+
+func (f *filterParser) parseUnary() (filterFunc, error) {
+	if f.consume("!") {
+		if strings.HasPrefix(f.src[f.pos:], "=") {
+			return nil, fmt.Errorf("unexpected !=")
+		}
+		inner, err := f.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return func(cur, root interface{}) bool { return !inner(cur, root) }, nil
+	}
+	if f.consume("(") {
+		inner, err := f.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !f.consume(")") {
+			return nil, fmt.Errorf("missing )")
+		}
+		return inner, nil
+	}
+	return f.parseComparison()
+}
+
+// filterOps are the comparison operators, longest first.
+var filterOps = []string{"==", "!=", "<=", ">=", "=~", "<", ">"}
+
+func (f *filterParser) parseComparison() (filterFunc, error) {
+	left, literal, err := f.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	f.skipSpaces()
+	op := ""
+	for _, candidate := range filterOps {
+		if strings.HasPrefix(f.src[f.pos:], candidate) {
+			op = candidate
+			f.pos += len(candidate)
+			break
+		}
+	}
+	if op == "" {
+		// An operand alone tests that a path exists, or a literal's truth.
+		if literal {
+			v, _ := left(nil, nil)
+			return func(interface{}, interface{}) bool { return v != nil && v != false }, nil
+		}
+		return func(cur, root interface{}) bool { _, ok := left(cur, root); return ok }, nil
+	}
+	right, rightLiteral, err := f.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	if op == "=~" {
+		pattern, _ := right(nil, nil)
+		s, ok := pattern.(string)
+		if !rightLiteral || !ok {
+			return nil, fmt.Errorf("=~ needs a string pattern")
+		}
+		re, err := regexp.Compile(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", s, err)
+		}
+		return func(cur, root interface{}) bool {
+			v, ok := left(cur, root)
+			str, isString := v.(string)
+			return ok && isString && re.MatchString(str)
+		}, nil
+	}
+	return func(cur, root interface{}) bool {
+		l, lok := left(cur, root)
+		r, rok := right(cur, root)
+		return compareValues(op, l, lok, r, rok)
+	}, nil
+}
+
+// parseOperand parses a path (@... or $...) or a literal: a quoted string,
+// number, true, false or null. literal reports which.
+func (f *filterParser) parseOperand() (fn operandFunc, literal bool, err error) {
+	f.skipSpaces()
+	if f.pos >= len(f.src) {
+		return nil, false, fmt.Errorf("missing operand")
+	}
+	rest := f.src[f.pos:]
+	switch c := rest[0]; {
+	case c == '@' || c == '$':
+		end := pathOperandEnd(rest)
+		jp, err := compileJSONPath(rest[:end])
+		if err != nil {
+			return nil, false, err
+		}
+		f.pos += end
+		relative := c == '@'
+		return func(cur, root interface{}) (interface{}, bool) {
+			start := root
+			if relative {
+				start = cur
+			}
+			if jp.definite {
+				v, _, ok := jp.lookup(start)
+				return v, ok
+			}
+			matches := jp.evalFrom(start, root)
+			return matches, len(matches) > 0
+		}, false, nil
+	case c == '\'' || c == '"':
+		s, n, err := readQuoted(rest)
+		if err != nil {
+			return nil, false, err
+		}
+		f.pos += n
+		return constOperand(s), true, nil
+	}
+	end := 0
+	for end < len(rest) && (unicode.IsLetter(rune(rest[end])) || unicode.IsDigit(rune(rest[end])) || strings.ContainsRune("+-.eE", rune(rest[end]))) {
+		end++
+	}
+	word := rest[:end]
+	f.pos += end
+	switch word {
+	case "true":
+		return constOperand(true), true, nil
+	case "false":
+		return constOperand(false), true, nil
+	case "null":
+		return constOperand(nil), true, nil
+	}
+	n, err := strconv.ParseFloat(word, 64)
+	if err != nil {
+		return nil, false, fmt.Errorf("unexpected %q", rest)
+	}
+	return constOperand(n), true, nil
+}
+
+func constOperand(v interface{}) operandFunc {
+	return func(interface{}, interface{}) (interface{}, bool) { return v, true }
+}
+
+// pathOperandEnd returns the length of the path operand src starts with:
+// it ends at whitespace, an operator or a closing parenthesis outside
+// brackets.
+func pathOperandEnd(src string) int {
+	depth := 0
+	for i := 1; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case c == '\'' || c == '"':
+			if _, n, err := readQuoted(src[i:]); err == nil {
+				i += n - 1
+			}
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+		case depth == 0 && (unicode.IsSpace(rune(c)) || strings.IndexByte("=!<>&|)", c) >= 0):
+			return i
+		}
+	}
+	return len(src)
+}
+
+// compareValues applies op to two filter operands. Numbers compare
+// numerically and strings lexically; a missing operand only equals another
+// missing one.
+func compareValues(op string, l interface{}, lok bool, r interface{}, rok bool) bool {
+	switch op {
+	case "==":
+		return valuesEqual(l, lok, r, rok)
+	case "!=":
+		return !valuesEqual(l, lok, r, rok)
+	}
+	if !lok || !rok {
+		return false
+	}
+	if ln, ok := toFloat(l); ok {
+		rn, ok := toFloat(r)
+		if !ok {
+			return false
+		}
+		switch op {
+		case "<":
+			return ln < rn
+		case "<=":
+			return ln <= rn
+		case ">":
+			return ln > rn
+		case ">=":
+			return ln >= rn
+		}
+	}
+	ls, lok := l.(string)
+	rs, rok := r.(string)
+	if !lok || !rok {
+		return false
+	}
+	switch op {
+	case "<":
+		return ls < rs
+	case "<=":
+		return ls <= rs
+	case ">":
+		return ls > rs
+	case ">=":
+		return ls >= rs
+	}
+	return false
+}
+
+func valuesEqual(l interface{}, lok bool, r interface{}, rok bool) bool {
+	if !lok || !rok {
+		return lok == rok
+	}
+	if ln, ok := toFloat(l); ok {
+		rn, ok := toFloat(r)
+		return ok && ln == rn
+	}
+	return reflect.DeepEqual(l, r)
+}
+
+// toFloat returns v as a float64 when it is a number.
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	}
+	return 0, false
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func jsonPathContext() *ExecutionContext {
+	ctx := NewExecutionContext("exec-jsonpath")
+	ctx.SetTriggerData(map[string]interface{}{
+		"body": map[string]interface{}{
+			"items": []interface{}{
+				map[string]interface{}{"sku": "A-1", "qty": float64(2), "price": float64(10)},
+				map[string]interface{}{"sku": "B-2", "qty": float64(0), "price": float64(25)},
+				map[string]interface{}{"sku": "A-3", "qty": float64(5), "price": float64(7)},
+			},
+			"customer": map[string]interface{}{"name": "Ada", "tier": "gold"},
+			"limit":    float64(8),
+		},
+	})
+	ctx.SetNodeStatus("fetch", "success")
+	ctx.SetNodeStatus("store", "error")
+	ctx.SetNodeOutput("query", map[string]interface{}{
+		"rows": []map[string]interface{}{{"id": float64(1)}, {"id": float64(2)}},
+	})
+	return ctx
+}
+
+func TestGetValue_JSONPathQueries(t *testing.T) {
+	ctx := jsonPathContext()
+	tests := []struct {
+		path string
+		want interface{}
+	}{
+		{path: "$.nodes.*.status", want: []interface{}{"success", "error"}},
+		{path: "$.trigger.body.items[*].sku", want: []interface{}{"A-1", "B-2", "A-3"}},
+		{path: "$.trigger.body.items[?(@.qty > 0)].sku", want: []interface{}{"A-1", "A-3"}},
+		{path: "$.trigger.body.items[?(@.qty>0 && @.price < 9)].sku", want: []interface{}{"A-3"}},
+		{path: "$.trigger.body.items[?(@.sku =~ '^B' || !(@.qty))].sku", want: []interface{}{"B-2"}},
+		{path: "$.trigger.body.items[?(@.price > $.trigger.body.limit)].sku", want: []interface{}{"A-1", "B-2"}},
+		{path: "$.trigger.body.items[?(@.sku == 'A-3')].qty", want: []interface{}{float64(5)}},
+		{path: "$.trigger.body.items[?(@.missing != 1)].sku", want: []interface{}{"A-1", "B-2", "A-3"}},
+		{path: "$.trigger.body.items[1:].sku", want: []interface{}{"B-2", "A-3"}},
+		{path: "$.trigger.body.items[:-1].sku", want: []interface{}{"A-1", "B-2"}},
+		{path: "$.trigger.body.items[::-1].sku", want: []interface{}{"A-3", "B-2", "A-1"}},
+		{path: "$.trigger.body.items[0,2].sku", want: []interface{}{"A-1", "A-3"}},
+		{path: "$.trigger.body.customer['name','tier']", want: []interface{}{"Ada", "gold"}},
+		{path: "$.trigger..price", want: []interface{}{float64(10), float64(25), float64(7)}},
+		{path: "$.nodes.query.output.rows[*].id", want: []interface{}{float64(1), float64(2)}},
+		{path: "$.trigger.body.items[?(@.qty > 100)]", want: []interface{}{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := ctx.GetValue(tt.path)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestGetValue_DefinitePaths(t *testing.T) {
+	ctx := jsonPathContext()
+	for path, want := range map[string]interface{}{
+		"$.trigger.body.items[-1].sku":    "A-3",
+		"$.trigger.body.items[1]['sku']":  "B-2",
+		`$.trigger.body["customer"].name`: "Ada",
+		"$.nodes.query.output.rows[1].id": float64(2),
+		"trigger.body.customer.tier":      "gold",
+		"$.nodes.fetch.status":            "success",
+	} {
+		got, err := ctx.GetValue(path)
+		require.NoError(t, err, path)
+		assert.Equal(t, want, got, path)
+	}
+
+	_, err := ctx.GetValue("$.trigger.body.items[3].sku")
+	assert.ErrorContains(t, err, "at [3]")
+	_, err = ctx.GetValue("$.trigger.body.customer.email")
+	assert.ErrorContains(t, err, "path not found")
+}
+
+func TestGetValue_InvalidJSONPath(t *testing.T) {
+	ctx := jsonPathContext()
+	for _, path := range []string{
+		"$.trigger.",
+		"$.trigger.body.items[",
+		"$.trigger.body.items[x]",
+		"$.trigger.body.items[?(@.qty >)]",
+		"$.trigger.body.items[?(@.sku =~ '(')]",
+		"$.trigger.body.items[1:2:3:4]",
+	} {
+		_, err := ctx.GetValue(path)
+		assert.Error(t, err, path)
+	}
+}
+
+func TestIsQueryPath(t *testing.T) {
+	assert.False(t, IsQueryPath("$.trigger.body.items[0]"))
+	assert.True(t, IsQueryPath("$.trigger.body.items[*]"))
+	assert.True(t, IsQueryPath("$..id"))
+	assert.False(t, IsQueryPath("$.trigger["))
+}
@@ -0,0 +1,167 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// CurrentDSLVersion is the dsl_version of the model in this file. A DSL
+// without dsl_version is version 1, the model before versioning.
+const CurrentDSLVersion = 2
+
+// MigrationReport describes the upgrade of one DSL document to
+// CurrentDSLVersion.
+type MigrationReport struct {
+	FromVersion int               `json:"from_version"`
+	ToVersion   int               `json:"to_version"`
+	Changes     []MigrationChange `json:"changes"`
+}
+
+// MigrationChange is one edit a migration made.
+type MigrationChange struct {
+	// Version is the dsl_version the migration upgrades to.
+	Version int    `json:"version"`
+	NodeID  string `json:"node_id,omitempty"`
+	Change  string `json:"change"`
+}
+
+// dslMigration upgrades a decoded DSL document from version to-1 to to.
+type dslMigration struct {
+	to    int
+	apply func(doc map[string]interface{}) []MigrationChange
+}
+
+// dslMigrations are applied in order to documents older than their version.
+// Add a migration, and bump CurrentDSLVersion, with every model change that
+// stored DSLs would not decode into.
+var dslMigrations = []dslMigration{
+	{to: 2, apply: migrateToV2},
+}
+
+// renamedNodeTypes maps the node types of version 1 that were renamed to
+// their current name.
+var renamedNodeTypes = map[string]string{
+	// ADR 0001 replaced script_ts with code.
+	"script_ts": "code",
+}
+
+// migrateToV2 renames the node types of renamedNodeTypes and moves the
+// top-level script of code nodes into config.script, where the designer
+// keeps it.
+func migrateToV2(doc map[string]interface{}) []MigrationChange {
+	var changes []MigrationChange
+	nodes, _ := doc["nodes"].([]interface{})
+	for _, raw := range nodes {
+		node, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		id, _ := node["id"].(string)
+		typ, _ := node["type"].(string)
+		if renamed, ok := renamedNodeTypes[typ]; ok {
+			changes = append(changes, MigrationChange{Version: 2, NodeID: id, Change: fmt.Sprintf("node type %q renamed to %q", typ, renamed)})
+			node["type"] = renamed
+			typ = renamed
+		}
+		script, ok := node["script"].(string)
+		if !ok || typ != "code" {
+			continue
+		}
+		delete(node, "script")
+		if script == "" {
+			continue
+		}
+		config, _ := node["config"].(map[string]interface{})
+		if config == nil {
+			config = make(map[string]interface{})
+			node["config"] = config
+		}
+		// The top-level script won over config.script in version 1.
+		config["script"] = script
+		changes = append(changes, MigrationChange{Version: 2, NodeID: id, Change: "script moved into config.script"})
+	}
+	return changes
+}
+
+// MigrateDSL upgrades the JSON DSL document dsl to CurrentDSLVersion and
+// reports what changed. A document already at the current version is
+// returned as it is. It fails on invalid JSON and on a dsl_version newer
+// than this engine.
+func MigrateDSL(dsl []byte) ([]byte, *MigrationReport, error) {
+	dec := json.NewDecoder(bytes.NewReader(dsl))
+	dec.UseNumber()
+	var doc map[string]interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, nil, fmt.Errorf("migrate DSL: %w", err)
+	}
+	if doc == nil {
+		return nil, nil, fmt.Errorf("migrate DSL: the document is not an object")
+	}
+	from, err := dslVersion(doc["dsl_version"])
+	if err != nil {
+		return nil, nil, err
+	}
+	report := &MigrationReport{FromVersion: from, ToVersion: CurrentDSLVersion, Changes: []MigrationChange{}}
+	if from == CurrentDSLVersion {
+		return dsl, report, nil
+	}
+	for _, m := range dslMigrations {
+		if m.to > from {
+			report.Changes = append(report.Changes, m.apply(doc)...)
+		}
+	}
+	doc["dsl_version"] = CurrentDSLVersion
+	migrated, err := json.Marshal(doc)
+	if err != nil {
+		return nil, nil, fmt.Errorf("migrate DSL: %w", err)
+	}
+	return migrated, report, nil
+}
+
+// dslVersion reads the dsl_version of a document decoded with UseNumber.
+func dslVersion(raw interface{}) (int, error) {
+	if raw == nil {
+		return 1, nil
+	}
+	n, ok := raw.(json.Number)
+	if !ok {
+		return 0, fmt.Errorf("migrate DSL: dsl_version must be an integer, got %v", raw)
+	}
+	v, err := n.Int64()
+	if err != nil || v < 1 {
+		return 0, fmt.Errorf("migrate DSL: invalid dsl_version %s", n)
+	}
+	if v > CurrentDSLVersion {
+		return 0, fmt.Errorf("migrate DSL: dsl_version %d is newer than this engine supports (%d)", v, CurrentDSLVersion)
+	}
+	return int(v), nil
+}
+
+// UnmarshalJSON decodes a DSL document of any supported dsl_version,
+// migrating older ones (see MigrateDSL), so stored processes load into the
+// current model.
+func (p *Process) UnmarshalJSON(data []byte) error {
+	if string(bytes.TrimSpace(data)) == "null" {
+		return nil
+	}
+	// processFields has the fields of Process without its methods.
+	type processFields Process
+	var fields processFields
+	// A version 1 document may not decode into the current model: migrate
+	// it whenever the first decode fails too.
+	if err := json.Unmarshal(data, &fields); err == nil && fields.DSLVersion == CurrentDSLVersion {
+		*p = Process(fields)
+		return nil
+	}
+	migrated, _, err := MigrateDSL(data)
+	if err != nil {
+		return err
+	}
+	fields = processFields{}
+	if err := json.Unmarshal(migrated, &fields); err != nil {
+		return err
+	}
+	*p = Process(fields)
+	return nil
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const v1DSL = `{
+	"definition": {"id": "legacy", "version": "1.0.0"},
+	"trigger": {"id": "t", "type": "manual"},
+	"nodes": [
+		{"id": "a", "type": "script_ts", "script": "({ n: 1 })", "config": {"timeout_ms": 100}},
+		{"id": "b", "type": "code", "script": "({ n: 2 })"},
+		{"id": "c", "type": "code", "config": {"script": "({ n: 3 })"}},
+		{"id": "d", "type": "log", "config": {"message": "done", "big": 12345678901234567890}}
+	],
+	"transitions": []
+}`
+
+func TestMigrateDSL(t *testing.T) {
+	migrated, report, err := MigrateDSL([]byte(v1DSL))
+	require.NoError(t, err)
+	assert.Equal(t, &MigrationReport{FromVersion: 1, ToVersion: CurrentDSLVersion, Changes: []MigrationChange{
+		{Version: 2, NodeID: "a", Change: `node type "script_ts" renamed to "code"`},
+		{Version: 2, NodeID: "a", Change: "script moved into config.script"},
+		{Version: 2, NodeID: "b", Change: "script moved into config.script"},
+	}}, report)
+	assert.Contains(t, string(migrated), `"big":12345678901234567890`, "numbers keep their precision")
+
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal(migrated, &doc))
+	assert.EqualValues(t, CurrentDSLVersion, doc["dsl_version"])
+	nodes := doc["nodes"].([]interface{})
+	assert.Equal(t, map[string]interface{}{
+		"id": "a", "type": "code", "config": map[string]interface{}{"timeout_ms": float64(100), "script": "({ n: 1 })"},
+	}, nodes[0])
+	assert.Equal(t, map[string]interface{}{"script": "({ n: 2 })"}, nodes[1].(map[string]interface{})["config"])
+
+	// A current document is left as it is.
+	again, report, err := MigrateDSL(migrated)
+	require.NoError(t, err)
+	assert.Equal(t, migrated, again)
+	assert.Empty(t, report.Changes)
+}
+
+func TestMigrateDSL_Errors(t *testing.T) {
+	for name, dsl := range map[string]string{
+		"newer version": `{"dsl_version": 99, "nodes": []}`,
+		"zero version":  `{"dsl_version": 0}`,
+		"string":        `{"dsl_version": "2"}`,
+		"not an object": `[]`,
+		"null":          `null`,
+		"invalid JSON":  `{`,
+	} {
+		_, _, err := MigrateDSL([]byte(dsl))
+		assert.Error(t, err, name)
+	}
+}
+
+func TestProcess_UnmarshalJSONMigrates(t *testing.T) {
+	var p Process
+	require.NoError(t, json.Unmarshal([]byte(v1DSL), &p))
+	assert.Equal(t, CurrentDSLVersion, p.DSLVersion)
+	assert.Equal(t, "code", p.Nodes[0].Type)
+	assert.Empty(t, p.Nodes[0].Script)
+	assert.Equal(t, "({ n: 1 })", p.Nodes[0].Config["script"])
+
+	// Saving writes the current version, which loads without migrating.
+	data, err := json.Marshal(&p)
+	require.NoError(t, err)
+	var loaded Process
+	require.NoError(t, json.Unmarshal(data, &loaded))
+	assert.Equal(t, p, loaded)
+
+	err = json.Unmarshal([]byte(`{"dsl_version": 99}`), &loaded)
+	assert.ErrorContains(t, err, "newer than this engine supports")
+}
@@ -0,0 +1,285 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NodeTemplate is a reusable chain of nodes — a standard
+// "notify-ops-on-error" sequence — kept once in the node library and used by
+// processes through a "$ref" node instead of being copied into every DSL.
+// Versions are immutable: publishing a template again creates its next
+// version.
+type NodeTemplate struct {
+	Name        string `json:"name"`
+	Version     int    `json:"version"`
+	Workspace   string `json:"workspace"`
+	Description string `json:"description"`
+	// Nodes run in order unless Transitions route them; the first is the
+	// entry of the chain.
+	Nodes       []Node       `json:"nodes"`
+	Transitions []Transition `json:"transitions,omitempty"`
+	// Output is the node whose output the $ref node exposes, the exit of
+	// the chain. Empty means the last node.
+	Output    string    `json:"output,omitempty"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// nodeTemplateNameRe matches valid template names, like profile names.
+var nodeTemplateNameRe = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,255}$`)
+
+// nodeRefPathRe matches the node references of JSONPath expressions.
+var nodeRefPathRe = regexp.MustCompile(`\$\.nodes\.([A-Za-z0-9_-]+)`)
+
+// ValidNodeTemplateName reports whether name can name a node template.
+func ValidNodeTemplateName(name string) bool {
+	return nodeTemplateNameRe.MatchString(name)
+}
+
+// Validate checks the name of the template and the consistency of its
+// nodes: unique ids, a type for each (templates cannot nest $ref nodes),
+// transitions between its own nodes and an existing output node.
+func (t *NodeTemplate) Validate() error {
+	var errs []error
+	if !ValidNodeTemplateName(t.Name) {
+		errs = append(errs, errors.New("template name must be 1-255 alphanumeric characters, hyphens or underscores"))
+	}
+	if len(t.Nodes) == 0 {
+		errs = append(errs, errors.New("nodes: at least one node is required"))
+	}
+	nodes := make(map[string]bool, len(t.Nodes))
+	for i, node := range t.Nodes {
+		switch {
+		case node.ID == "":
+			errs = append(errs, fmt.Errorf("nodes[%d]: id is required", i))
+		case nodes[node.ID]:
+			errs = append(errs, fmt.Errorf("nodes[%d]: duplicate node id %q", i, node.ID))
+		}
+		switch {
+		case node.Ref != "":
+			errs = append(errs, fmt.Errorf("nodes[%d]: templates cannot use $ref", i))
+		case node.Type == "":
+			errs = append(errs, fmt.Errorf("nodes[%d]: type is required", i))
+		}
+		nodes[node.ID] = true
+	}
+	for i, tr := range t.Transitions {
+		if !nodes[tr.From] {
+			errs = append(errs, fmt.Errorf("transitions[%d]: unknown source node %q", i, tr.From))
+		}
+		for _, to := range tr.Destinations() {
+			if !nodes[to] {
+				errs = append(errs, fmt.Errorf("transitions[%d]: unknown target node %q", i, to))
+			}
+		}
+	}
+	if t.Output != "" && !nodes[t.Output] {
+		errs = append(errs, fmt.Errorf("output: unknown node %q", t.Output))
+	}
+	return errors.Join(errs...)
+}
+
+// ParseNodeRef splits the "$ref" of a node, "name@version" or "name" for the
+// latest version, into the template name and version (0 for the latest).
+func ParseNodeRef(ref string) (name string, version int, err error) {
+	name, v, pinned := strings.Cut(ref, "@")
+	if !ValidNodeTemplateName(name) {
+		return "", 0, fmt.Errorf("invalid $ref %q: want name or name@version", ref)
+	}
+	if pinned {
+		version, err = strconv.Atoi(v)
+		if err != nil || version < 1 {
+			return "", 0, fmt.Errorf("invalid $ref %q: version must be a positive integer", ref)
+		}
+	}
+	return name, version, nil
+}
+
+// NodeTemplateLookup returns version of template name, the latest when
+// version is 0.
+type NodeTemplateLookup func(name string, version int) (*NodeTemplate, error)
+
+// HasNodeRefs reports whether a node of p is a $ref node.
+func (p *Process) HasNodeRefs() bool {
+	return slices.ContainsFunc(p.Nodes, func(n Node) bool { return n.Ref != "" })
+}
+
+// ExpandNodeRefs returns a copy of p in which every $ref node is replaced by
+// the nodes of the template it references, read from lookup. p is returned
+// as is when it has no $ref node.
+//
+// The exit node of the template (its Output, else its last node) takes the
+// id of the $ref node, so transitions leaving it and $.nodes.<id> references
+// read the result of the chain; the other nodes are renamed <id>__<node> and
+// the $.nodes references between them rewritten. Transitions to the $ref
+// node lead to the entry node, whose input_mapping is layered under the one
+// of the $ref node. In a sequential process the nodes are inserted in place,
+// and only templates without transitions can be used; otherwise a template
+// without transitions is chained with success transitions.
+func (p *Process) ExpandNodeRefs(lookup NodeTemplateLookup) (*Process, error) {
+	if !p.HasNodeRefs() {
+		return p, nil
+	}
+	sequential := len(p.Transitions) == 0 && !slices.ContainsFunc(p.Nodes, func(n Node) bool { return len(n.Next) > 0 })
+
+	out := *p
+	out.Nodes = make([]Node, 0, len(p.Nodes))
+	out.Transitions = slices.Clone(p.Transitions)
+	entries := make(map[string]string)
+	for _, node := range p.Nodes {
+		if node.Ref == "" {
+			out.Nodes = append(out.Nodes, node)
+			continue
+		}
+		if node.ID == "" {
+			return nil, fmt.Errorf("$ref %q: node id is required", node.Ref)
+		}
+		name, version, err := ParseNodeRef(node.Ref)
+		if err != nil {
+			return nil, fmt.Errorf("node %s: %w", node.ID, err)
+		}
+		tmpl, err := lookup(name, version)
+		if err != nil {
+			return nil, fmt.Errorf("node %s: $ref %q: %w", node.ID, node.Ref, err)
+		}
+		if sequential && len(tmpl.Transitions) > 0 {
+			return nil, fmt.Errorf("node %s: template %s@%d has transitions and cannot be used in a process without transitions", node.ID, tmpl.Name, tmpl.Version)
+		}
+		nodes, transitions := tmpl.instantiate(node)
+		entries[node.ID] = nodes[0].ID
+		out.Nodes = append(out.Nodes, nodes...)
+		if !sequential {
+			out.Transitions = append(out.Transitions, transitions...)
+		}
+	}
+
+	for i, t := range out.Transitions[:len(p.Transitions)] {
+		if entry, ok := entries[t.To]; ok {
+			out.Transitions[i].To = entry
+		}
+		for _, target := range t.Targets {
+			if entry, ok := entries[target]; ok && entry != target {
+				return nil, fmt.Errorf("transitions[%d]: dynamic target %q is a $ref of several nodes", i, target)
+			}
+		}
+	}
+	for i := range out.Nodes {
+		for j, next := range out.Nodes[i].Next {
+			if entry, ok := entries[next]; ok {
+				if j == 0 {
+					out.Nodes[i].Next = slices.Clone(out.Nodes[i].Next)
+				}
+				out.Nodes[i].Next[j] = entry
+			}
+		}
+	}
+	return &out, nil
+}
+
+// instantiate returns the nodes and transitions of t used by the $ref node
+// ref, renamed as described by ExpandNodeRefs. Without transitions of its
+// own, the nodes are chained with success transitions.
+func (t *NodeTemplate) instantiate(ref Node) ([]Node, []Transition) {
+	exit := t.Output
+	if exit == "" {
+		exit = t.Nodes[len(t.Nodes)-1].ID
+	}
+	rename := make(map[string]string, len(t.Nodes))
+	for _, n := range t.Nodes {
+		rename[n.ID] = ref.ID + "__" + n.ID
+	}
+	rename[exit] = ref.ID
+
+	nodes := make([]Node, len(t.Nodes))
+	for i, n := range t.Nodes {
+		n.ID = rename[n.ID]
+		n.InputMapping = renameNodeRefs(n.InputMapping, rename).(map[string]interface{})
+		n.Config = renameNodeRefs(n.Config, rename).(map[string]interface{})
+		n.Script = renameNodeRefs(n.Script, rename).(string)
+		if len(n.Next) > 0 {
+			next := make([]string, len(n.Next))
+			for j, id := range n.Next {
+				next[j] = rename[id]
+			}
+			n.Next = next
+		}
+		nodes[i] = n
+	}
+	if len(ref.InputMapping) > 0 {
+		mapping := make(map[string]interface{}, len(nodes[0].InputMapping)+len(ref.InputMapping))
+		for k, v := range nodes[0].InputMapping {
+			mapping[k] = v
+		}
+		for k, v := range ref.InputMapping {
+			mapping[k] = v
+		}
+		nodes[0].InputMapping = mapping
+	}
+	for i := range nodes {
+		if nodes[i].ID == ref.ID {
+			nodes[i].Next = append(slices.Clone(nodes[i].Next), ref.Next...)
+		}
+	}
+
+	var transitions []Transition
+	if len(t.Transitions) == 0 {
+		for i := 1; i < len(nodes); i++ {
+			transitions = append(transitions, Transition{From: nodes[i-1].ID, To: nodes[i].ID, Type: "success"})
+		}
+		return nodes, transitions
+	}
+	for _, tr := range t.Transitions {
+		tr.From = rename[tr.From]
+		if tr.To != "" {
+			tr.To = rename[tr.To]
+		}
+		if len(tr.Targets) > 0 {
+			targets := make([]string, len(tr.Targets))
+			for j, id := range tr.Targets {
+				targets[j] = rename[id]
+			}
+			tr.Targets = targets
+		}
+		tr.Condition = renameNodeRefs(tr.Condition, rename).(string)
+		tr.Expression = renameNodeRefs(tr.Expression, rename).(string)
+		transitions = append(transitions, tr)
+	}
+	return nodes, transitions
+}
+
+// renameNodeRefs returns a copy of v, a JSON value, in which the
+// $.nodes.<id> references of its strings to the nodes of rename are
+// rewritten.
+func renameNodeRefs(v interface{}, rename map[string]string) interface{} {
+	switch val := v.(type) {
+	case string:
+		return nodeRefPathRe.ReplaceAllStringFunc(val, func(m string) string {
+			if id, ok := rename[strings.TrimPrefix(m, "$.nodes.")]; ok {
+				return "$.nodes." + id
+			}
+			return m
+		})
+	case map[string]interface{}:
+		if val == nil {
+			return val
+		}
+		out := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			out[k] = renameNodeRefs(item, rename)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, item := range val {
+			out[i] = renameNodeRefs(item, rename)
+		}
+		return out
+	}
+	return v
+}
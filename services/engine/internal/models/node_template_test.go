@@ -0,0 +1,141 @@
+package models
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// notifyOps is a two-node template: format a message, then post it.
+func notifyOps() *NodeTemplate {
+	return &NodeTemplate{
+		Name:    "notify-ops",
+		Version: 2,
+		Nodes: []Node{
+			{ID: "format", Type: "transform", InputMapping: map[string]interface{}{"level": "error"}},
+			{ID: "post", Type: "http", Config: map[string]interface{}{"body": map[string]interface{}{"text": "$.nodes.format.output.text"}}},
+		},
+	}
+}
+
+func lookupOf(templates ...*NodeTemplate) NodeTemplateLookup {
+	return func(name string, version int) (*NodeTemplate, error) {
+		for _, t := range templates {
+			if t.Name == name && (version == 0 || version == t.Version) {
+				return t, nil
+			}
+		}
+		return nil, fmt.Errorf("template %s@%d not found", name, version)
+	}
+}
+
+func TestParseNodeRef(t *testing.T) {
+	name, version, err := ParseNodeRef("notify-ops@3")
+	require.NoError(t, err)
+	assert.Equal(t, "notify-ops", name)
+	assert.Equal(t, 3, version)
+
+	_, version, err = ParseNodeRef("notify-ops")
+	require.NoError(t, err)
+	assert.Zero(t, version)
+
+	for _, ref := range []string{"", "@1", "notify-ops@", "notify-ops@0", "notify-ops@v1", "a/b"} {
+		_, _, err := ParseNodeRef(ref)
+		assert.Error(t, err, ref)
+	}
+}
+
+func TestNodeTemplate_Validate(t *testing.T) {
+	assert.NoError(t, notifyOps().Validate())
+
+	bad := &NodeTemplate{
+		Name:        "notify ops",
+		Nodes:       []Node{{ID: "a", Type: "log"}, {ID: "a", Ref: "other"}},
+		Transitions: []Transition{{From: "a", To: "missing", Type: "success"}},
+		Output:      "missing",
+	}
+	err := bad.Validate()
+	require.Error(t, err)
+	for _, msg := range []string{"template name", "duplicate node id", "cannot use $ref", `unknown target node "missing"`, `output: unknown node "missing"`} {
+		assert.ErrorContains(t, err, msg)
+	}
+	assert.ErrorContains(t, (&NodeTemplate{Name: "empty"}).Validate(), "at least one node")
+}
+
+// TestProcess_ExpandNodeRefsSequential verifies that a sequential process
+// gets the template nodes in place, the exit node under the $ref id.
+func TestProcess_ExpandNodeRefsSequential(t *testing.T) {
+	p := &Process{Nodes: []Node{
+		{ID: "fetch", Type: "http"},
+		{ID: "notify", Ref: "notify-ops@2", InputMapping: map[string]interface{}{"level": "warn", "order": "$.nodes.fetch.output"}},
+		{ID: "log", Type: "log", InputMapping: map[string]interface{}{"status": "$.nodes.notify.output.status"}},
+	}}
+
+	out, err := p.ExpandNodeRefs(lookupOf(notifyOps()))
+	require.NoError(t, err)
+	require.Len(t, out.Nodes, 4)
+	assert.Equal(t, []string{"fetch", "notify__format", "notify", "log"}, []string{out.Nodes[0].ID, out.Nodes[1].ID, out.Nodes[2].ID, out.Nodes[3].ID})
+	assert.Empty(t, out.Transitions, "a sequential process stays sequential")
+	assert.Equal(t, map[string]interface{}{"level": "warn", "order": "$.nodes.fetch.output"}, out.Nodes[1].InputMapping)
+	assert.Equal(t, "$.nodes.notify__format.output.text", out.Nodes[2].Config["body"].(map[string]interface{})["text"])
+	assert.Equal(t, "notify-ops@2", p.Nodes[1].Ref, "p is not modified")
+	assert.Equal(t, "$.nodes.format.output.text", notifyOps().Nodes[1].Config["body"].(map[string]interface{})["text"])
+
+	same, err := (&Process{Nodes: []Node{{ID: "a", Type: "log"}}}).ExpandNodeRefs(nil)
+	require.NoError(t, err)
+	assert.Len(t, same.Nodes, 1)
+}
+
+// TestProcess_ExpandNodeRefsTransitions verifies the rewiring of a process
+// with transitions: incoming edges reach the entry node, outgoing ones leave
+// the exit node and the template nodes are chained.
+func TestProcess_ExpandNodeRefsTransitions(t *testing.T) {
+	p := &Process{
+		Definition: Definition{ID: "orders"},
+		Trigger:    Trigger{ID: "trg", Type: "manual"},
+		Nodes: []Node{
+			{ID: "fetch", Type: "http"},
+			{ID: "notify", Ref: "notify-ops"},
+			{ID: "done", Type: "log"},
+		},
+		Transitions: []Transition{
+			{From: "fetch", To: "notify", Type: "error"},
+			{From: "notify", To: "done", Type: "success"},
+		},
+	}
+	out, err := p.ExpandNodeRefs(lookupOf(notifyOps()))
+	require.NoError(t, err)
+	assert.Equal(t, []Transition{
+		{From: "fetch", To: "notify__format", Type: "error"},
+		{From: "notify", To: "done", Type: "success"},
+		{From: "notify__format", To: "notify", Type: "success"},
+	}, out.Transitions)
+	assert.Equal(t, "notify", p.Transitions[0].To, "p is not modified")
+	require.NoError(t, out.Validate())
+
+	p.Transitions = append(p.Transitions, Transition{From: "done", Type: TransitionDynamic, Expression: "$.x", Targets: []string{"notify", "fetch"}})
+	_, err = p.ExpandNodeRefs(lookupOf(notifyOps()))
+	assert.ErrorContains(t, err, `dynamic target "notify"`)
+}
+
+func TestProcess_ExpandNodeRefsErrors(t *testing.T) {
+	routed := notifyOps()
+	routed.Name = "routed"
+	routed.Transitions = []Transition{{From: "format", To: "post", Type: "success"}}
+	routed.Output = "format"
+
+	_, err := (&Process{Nodes: []Node{{ID: "n", Ref: "routed"}}}).ExpandNodeRefs(lookupOf(routed))
+	assert.ErrorContains(t, err, "cannot be used in a process without transitions")
+
+	_, err = (&Process{Nodes: []Node{{ID: "n", Ref: "notify-ops@9"}}}).ExpandNodeRefs(lookupOf(notifyOps()))
+	assert.ErrorContains(t, err, `node n: $ref "notify-ops@9"`)
+
+	p := &Process{Nodes: []Node{{ID: "n", Ref: "routed"}}, Transitions: []Transition{{From: "trg", To: "n", Type: "success"}}}
+	out, err := p.ExpandNodeRefs(lookupOf(routed))
+	require.NoError(t, err)
+	assert.Equal(t, "n", out.Nodes[0].ID, "output names the exit node")
+	assert.Equal(t, "n__post", out.Nodes[1].ID)
+	assert.Equal(t, []Transition{{From: "trg", To: "n", Type: "success"}, {From: "n", To: "n__post", Type: "success"}}, out.Transitions)
+}
@@ -0,0 +1,31 @@
+package models
+
+import "strings"
+
+// ApplyOutputMapping reshapes the output of an activity as described by a
+// node's output_mapping. Each key of mapping becomes a key of the result:
+//
+//   - a string starting with "$" is a JSONPath into output ("$" is the whole
+//     output, "$.body.id" one field); a path that does not resolve yields null
+//   - an object is reshaped recursively, producing a nested object
+//   - any other value is copied as a literal
+//
+// Keys not named in mapping are dropped.
+func ApplyOutputMapping(mapping, output map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(mapping))
+	for key, value := range mapping {
+		switch v := value.(type) {
+		case string:
+			if strings.HasPrefix(v, "$") {
+				result[key], _ = LookupPath(output, v)
+			} else {
+				result[key] = v
+			}
+		case map[string]interface{}:
+			result[key] = ApplyOutputMapping(v, output)
+		default:
+			result[key] = v
+		}
+	}
+	return result
+}
@@ -0,0 +1,30 @@
+package models
+
+// LookupPath resolves a JSONPath ("$", "$.a.b", "$.items[0].id", "$[1]",
+// or any form GetValue accepts) against a decoded JSON document. A null
+// value counts as found; a path with wildcards, filters, slices or
+// recursive descent returns the list of values it selects and is always
+// found.
+func LookupPath(doc interface{}, path string) (interface{}, bool) {
+	jp, err := compileJSONPath(path)
+	if err != nil {
+		return nil, false
+	}
+	if !jp.definite {
+		matches := jp.eval(doc)
+		if matches == nil {
+			matches = []interface{}{}
+		}
+		return matches, true
+	}
+	val, _, ok := jp.lookup(doc)
+	return val, ok
+}
+
+// IsQueryPath reports whether path selects a list of values (it has
+// wildcards, filters, slices, unions or recursive descent) rather than
+// pointing at a single one.
+func IsQueryPath(path string) bool {
+	jp, err := compileJSONPath(path)
+	return err == nil && !jp.definite
+}
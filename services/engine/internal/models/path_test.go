@@ -0,0 +1,55 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLookupPath(t *testing.T) {
+	doc := map[string]interface{}{
+		"a":    map[string]interface{}{"b": nil},
+		"list": []interface{}{[]interface{}{"x", "y"}},
+	}
+	v, ok := LookupPath(doc, "$")
+	assert.True(t, ok)
+	assert.Equal(t, doc, v)
+
+	_, ok = LookupPath(doc, "$.a.b")
+	assert.True(t, ok, "null counts as present")
+
+	v, ok = LookupPath(doc, "$.list[0][1]")
+	assert.True(t, ok)
+	assert.Equal(t, "y", v)
+
+	v, ok = LookupPath([]interface{}{"first"}, "$[0]")
+	assert.True(t, ok)
+	assert.Equal(t, "first", v)
+
+	for _, p := range []string{"$.missing", "$.a.b.c", "$.list[3]", "$.a[0]"} {
+		_, ok = LookupPath(doc, p)
+		assert.False(t, ok, p)
+	}
+}
+
+func TestApplyOutputMapping(t *testing.T) {
+	output := map[string]interface{}{
+		"status_code": float64(201),
+		"body":        map[string]interface{}{"id": "ord-1", "items": []interface{}{"a", "b"}},
+	}
+	got := ApplyOutputMapping(map[string]interface{}{
+		"order_id": "$.body.id",
+		"whole":    "$",
+		"meta":     map[string]interface{}{"code": "$.status_code", "count": float64(2)},
+		"missing":  "$.body.nope",
+		"label":    "created",
+	}, output)
+
+	assert.Equal(t, "ord-1", got["order_id"])
+	assert.Equal(t, output, got["whole"])
+	assert.Equal(t, map[string]interface{}{"code": float64(201), "count": float64(2)}, got["meta"])
+	assert.Contains(t, got, "missing")
+	assert.Nil(t, got["missing"])
+	assert.Equal(t, "created", got["label"])
+	assert.NotContains(t, got, "status_code")
+}
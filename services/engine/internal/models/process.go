@@ -1,5 +1,11 @@
 package models
 
+import (
+	"regexp"
+	"slices"
+	"time"
+)
+
 // =============================================================================
 // flowjs-works — Core DSL Go Models
 // =============================================================================
@@ -11,6 +17,9 @@ package models
 
 // Process represents the complete workflow definition
 type Process struct {
+	// DSLVersion is the version of the DSL model the document was written
+	// for; older documents are migrated on load (see MigrateDSL).
+	DSLVersion  int          `json:"dsl_version,omitempty"`
 	Definition  Definition   `json:"definition"`
 	Trigger     Trigger      `json:"trigger"`
 	Nodes       []Node       `json:"nodes"`
@@ -24,19 +33,110 @@ type Definition struct {
 	Name        string          `json:"name"`
 	Description string          `json:"description"`
 	Settings    ProcessSettings `json:"settings"`
+	// Workspace is the tenant that owns the process. It is assigned server-side
+	// from the authenticated principal and ignored when supplied by clients.
+	Workspace string `json:"workspace,omitempty"`
+	// Environments holds the variables and secret mappings of each
+	// deployment environment (dev, staging, prod).
+	Environments map[string]EnvironmentOverrides `json:"environments,omitempty"`
+	// Environment is the deployment environment the process was loaded for.
+	// It is assigned server-side and ignored when supplied by clients.
+	Environment string `json:"environment,omitempty"`
+	// Revision is the stored revision the process was loaded from, 0 for a
+	// DSL that was never saved. It is assigned server-side and not part of
+	// the DSL.
+	Revision int `json:"-"`
 }
 
+// Persistence levels of ProcessSettings.Persistence. An empty value is full.
+const (
+	// PersistenceFull records node payloads in the audit log and keeps the
+	// whole execution context as a snapshot.
+	PersistenceFull = "full"
+	// PersistenceMinimal records payload metadata (keys and size) instead of
+	// payloads and snapshots node statuses without outputs or trigger data.
+	PersistenceMinimal = "minimal"
+	// PersistenceNone records no payloads and no snapshot.
+	PersistenceNone = "none"
+)
+
 // ProcessSettings defines execution behavior
 type ProcessSettings struct {
 	Persistence   string `json:"persistence"` // full | minimal | none
 	Timeout       int    `json:"timeout"`
 	ErrorStrategy string `json:"error_strategy"` // stop_and_rollback | continue | retry
+	// MaxConcurrency caps simultaneous executions of a deployed process across
+	// its trigger and manual runs. Zero means unlimited.
+	MaxConcurrency int `json:"max_concurrency,omitempty"`
+	// Priority orders trigger-fired runs in the execution queue when all
+	// workers are busy; higher runs first. Zero is the default priority.
+	Priority int `json:"priority,omitempty"`
+	// Lane names the execution lane, the pool of queue workers, that runs
+	// the process, so batch flows cannot take the workers of interactive
+	// ones. Empty, or a lane the engine does not run, is the default lane.
+	Lane string `json:"lane,omitempty"`
+	// MaxNodeVisits is how many times one node may run in an execution, so
+	// transitions can form bounded retry loops. Zero or one means every node
+	// runs at most once and a revisit fails as a cycle.
+	MaxNodeVisits int `json:"max_node_visits,omitempty"`
+	// MaxSteps caps the total node runs of an execution. Zero means no cap
+	// beyond MaxNodeVisits.
+	MaxSteps int `json:"max_steps,omitempty"`
+	// OnUndefinedPath decides what a transition condition reading a JSONPath
+	// that does not resolve does: "false" (default) evaluates the condition
+	// as false, "error" fails the execution.
+	OnUndefinedPath string `json:"on_undefined_path,omitempty"`
+	// MaxExecutionsPerHour caps the executions of a deployed process in any
+	// rolling hour; runs beyond it are refused as "quota_exceeded". Zero
+	// means unlimited.
+	MaxExecutionsPerHour int `json:"max_executions_per_hour,omitempty"`
+	// MaxNodeExecutionsPerDay caps the node runs of a deployed process per
+	// UTC day; once reached, new executions are refused until midnight.
+	// Zero means unlimited.
+	MaxNodeExecutionsPerDay int `json:"max_node_executions_per_day,omitempty"`
+	// ConditionMode decides how the condition transitions of a node are
+	// followed: "exclusive" (default) takes the first that matches,
+	// "inclusive" every one that matches. Node.ConditionMode overrides it.
+	ConditionMode string `json:"condition_mode,omitempty"`
+	// SecretsAllowed lists the secret ids the nodes of the process may
+	// resolve; any other secret_ref fails with secrets.ErrNotAllowed. Empty
+	// means any secret of the workspace.
+	SecretsAllowed []string `json:"secrets_allowed,omitempty"`
+	// FaultInjection injects latency, errors and truncated outputs into the
+	// activities of every execution; nil injects none. See FaultInjection.
+	FaultInjection *FaultInjection `json:"fault_injection,omitempty"`
+}
+
+// AllowsSecret reports whether the process may resolve secret id.
+func (s ProcessSettings) AllowsSecret(id string) bool {
+	return len(s.SecretsAllowed) == 0 || slices.Contains(s.SecretsAllowed, id)
+}
+
+// laneRe matches valid execution lane names.
+var laneRe = regexp.MustCompile(`^[a-z0-9_-]{1,63}$`)
+
+// ValidLane reports whether name can name an execution lane: 1-63 lowercase
+// alphanumeric characters, hyphens or underscores.
+func ValidLane(name string) bool {
+	return laneRe.MatchString(name)
 }
 
+// Values of ProcessSettings.ConditionMode and Node.ConditionMode.
+const (
+	ConditionExclusive = "exclusive"
+	ConditionInclusive = "inclusive"
+)
+
+// Values of ProcessSettings.OnUndefinedPath.
+const (
+	UndefinedPathFalse = "false"
+	UndefinedPathError = "error"
+)
+
 // ── Trigger ─────────────────────────────────────────────────────────────────
 
 // Trigger defines how the process is initiated.
-// Supported types: cron, rest, soap, rabbitmq, mcp, manual.
+// Supported types: cron, rest, soap, rabbitmq, mcp, postgres_cdc, email, manual.
 type Trigger struct {
 	ID     string                 `json:"id"`
 	Type   string                 `json:"type"`
@@ -46,7 +146,7 @@ type Trigger struct {
 // ── Node ────────────────────────────────────────────────────────────────────
 
 // Node represents a single execution step in the workflow.
-// Supported types: http, sftp, s3, smb, mail, rabbitmq, sql, code, log, transform, file.
+// Supported types: http, sftp, s3, smb, mail, rabbitmq, websocket_send, sql, code, log, transform, mapping, file, dedupe, batcher, faker, assert, mock_http.
 type Node struct {
 	ID           string                 `json:"id"`
 	Type         string                 `json:"type"`
@@ -54,9 +154,51 @@ type Node struct {
 	InputMapping map[string]interface{} `json:"input_mapping,omitempty"`
 	Config       map[string]interface{} `json:"config,omitempty"`
 	SecretRef    string                 `json:"secret_ref,omitempty"`
-	Script       string                 `json:"script,omitempty"`
-	Next         []string               `json:"next,omitempty"`
-	RetryPolicy  *RetryPolicy           `json:"retry_policy,omitempty"`
+	// Script is the source of a code node built in Go; the executor moves it
+	// into config.script. DSL documents keep it in config.script, where
+	// dsl_version 2 moved it.
+	Script      string       `json:"script,omitempty"`
+	Next        []string     `json:"next,omitempty"`
+	RetryPolicy *RetryPolicy `json:"retry_policy,omitempty"`
+	// ConditionMode overrides settings.condition_mode for the condition
+	// transitions leaving this node.
+	ConditionMode string `json:"condition_mode,omitempty"`
+	// OutputMapping reshapes the activity output before it is stored in the
+	// context, e.g. {"order_id": "$.body.id"} keeps only that field. See
+	// ApplyOutputMapping.
+	OutputMapping map[string]interface{} `json:"output_mapping,omitempty"`
+	// CircuitBreaker makes the node fail fast with status "circuit_open" while
+	// its downstream system keeps failing, instead of waiting on every call.
+	CircuitBreaker *CircuitBreaker `json:"circuit_breaker,omitempty"`
+	// SLAMs is the expected duration of the node in milliseconds. A run that
+	// takes longer emits an "sla_breach" audit event and notifies SLAAlert.
+	SLAMs int `json:"sla_ms,omitempty"`
+	// SLAAlert is where breaches of SLAMs are reported besides the audit log.
+	SLAAlert *SLAAlert `json:"sla_alert,omitempty"`
+	// InputSchema and OutputSchema name schemas of the schema registry that
+	// the resolved input and the stored output of the node must match.
+	InputSchema  string `json:"input_schema,omitempty"`
+	OutputSchema string `json:"output_schema,omitempty"`
+	// SensitiveInputs are input_mapping keys whose resolved values reach the
+	// activity but are recorded only as a SensitiveRef: in audit events, and
+	// in place of any copy in the node's output or error.
+	SensitiveInputs []string `json:"sensitive_inputs,omitempty"`
+	// Profile names a connection profile whose config the node's config is
+	// layered over, and whose secret is used when SecretRef is empty.
+	Profile string `json:"profile,omitempty"`
+	// Ref makes the node a use of a template of the node library,
+	// "name@version" or "name" for the latest version, replaced by the
+	// template's nodes when the process is loaded. See ExpandNodeRefs.
+	Ref string `json:"$ref,omitempty"`
+}
+
+// SLAAlert is the callback notified when a node exceeds its sla_ms. Either or
+// both of URL and Subject may be set.
+type SLAAlert struct {
+	// URL receives the breach as a JSON POST.
+	URL string `json:"url,omitempty"`
+	// Subject is a NATS subject the breach is published to.
+	Subject string `json:"subject,omitempty"`
 }
 
 // RetryPolicy defines retry behavior for a node
@@ -66,15 +208,80 @@ type RetryPolicy struct {
 	Type        string `json:"type"` // fixed | exponential
 }
 
+// CircuitBreaker defines when a node stops calling a failing downstream system.
+// Failures are counted across executions of the same process node.
+type CircuitBreaker struct {
+	// FailureThreshold is the number of consecutive failed runs that opens
+	// the circuit (default 5).
+	FailureThreshold int `json:"failure_threshold,omitempty"`
+	// OpenDuration is how long the circuit stays open before probing again,
+	// as a Go duration such as "30s" (default 30s).
+	OpenDuration string `json:"open_duration,omitempty"`
+	// HalfOpenProbes is the number of runs let through after OpenDuration;
+	// they must all succeed to close the circuit (default 1).
+	HalfOpenProbes int `json:"half_open_probes,omitempty"`
+}
+
+// Threshold returns FailureThreshold, or 5 when unset.
+func (c *CircuitBreaker) Threshold() int {
+	if c.FailureThreshold <= 0 {
+		return 5
+	}
+	return c.FailureThreshold
+}
+
+// Probes returns HalfOpenProbes, or 1 when unset.
+func (c *CircuitBreaker) Probes() int {
+	if c.HalfOpenProbes <= 0 {
+		return 1
+	}
+	return c.HalfOpenProbes
+}
+
+// OpenFor parses OpenDuration, returning 30s when it is unset or not a
+// positive duration.
+func (c *CircuitBreaker) OpenFor() time.Duration {
+	d, err := time.ParseDuration(c.OpenDuration)
+	if err != nil || d <= 0 {
+		return 30 * time.Second
+	}
+	return d
+}
+
 // ── Transition ──────────────────────────────────────────────────────────────
 
 // Transition defines directional flow between nodes.
-// Supported types: success, error, condition, nocondition.
+// Supported types: success, error, condition, nocondition, dynamic, chunk.
 type Transition struct {
 	From      string `json:"from"`
 	To        string `json:"to"`
-	Type      string `json:"type"` // success | error | condition | nocondition
+	Type      string `json:"type"` // success | error | condition | nocondition | dynamic | chunk
 	Condition string `json:"condition,omitempty"`
+	// Priority orders the condition transitions of a node: higher is
+	// evaluated first, equal priorities keep their JSON order.
+	Priority int `json:"priority,omitempty"`
+	// Expression and Targets define a dynamic transition, which has no To:
+	// the expression is evaluated like a condition and its value names the
+	// node to follow, one of Targets.
+	Expression string   `json:"expression,omitempty"`
+	Targets    []string `json:"targets,omitempty"`
+}
+
+// TransitionDynamic is the type of a transition whose target is computed.
+const TransitionDynamic = "dynamic"
+
+// TransitionChunk is the type of a transition that leaves a sql node
+// streaming its rows in chunks: its target chain runs once per chunk while
+// the node runs, instead of once after it.
+const TransitionChunk = "chunk"
+
+// Destinations returns the nodes t can lead to: its targets for a dynamic
+// transition, else To.
+func (t Transition) Destinations() []string {
+	if t.Type == TransitionDynamic {
+		return t.Targets
+	}
+	return []string{t.To}
 }
 
 // ── Execution Result ────────────────────────────────────────────────────────
@@ -82,7 +289,7 @@ type Transition struct {
 // NodeExecution represents the result of executing a node
 type NodeExecution struct {
 	NodeID string                 `json:"node_id"`
-	Status string                 `json:"status"` // success, error, warning
+	Status string                 `json:"status"` // success, error, warning, circuit_open
 	Output map[string]interface{} `json:"output"`
 	Error  string                 `json:"error,omitempty"`
 }
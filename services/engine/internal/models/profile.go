@@ -0,0 +1,48 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// ConnectionProfile is a named, reusable node configuration — a standard
+// SFTP server, a SQL database — that nodes reference with "profile" instead
+// of repeating the settings in every process.
+type ConnectionProfile struct {
+	Name      string `json:"name"`
+	Workspace string `json:"workspace"`
+	// Type is the node type the profile configures; nodes of another type
+	// cannot use it. Empty allows any type.
+	Type        string                 `json:"type,omitempty"`
+	Description string                 `json:"description"`
+	Config      map[string]interface{} `json:"config"`
+	// SecretRef is the secret injected into nodes using the profile that
+	// have no secret_ref of their own.
+	SecretRef string    `json:"secret_ref,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// profileNameRe matches valid profile names, like process ids.
+var profileNameRe = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,255}$`)
+
+// Validate checks the name of the profile.
+func (p *ConnectionProfile) Validate() error {
+	if !profileNameRe.MatchString(p.Name) {
+		return fmt.Errorf("profile name must be 1-255 alphanumeric characters, hyphens or underscores")
+	}
+	return nil
+}
+
+// Apply returns config layered over the profile's config: the node's own
+// fields take precedence over the profile's. config is not modified.
+func (p *ConnectionProfile) Apply(config map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(p.Config)+len(config))
+	for k, v := range p.Config {
+		out[k] = v
+	}
+	for k, v := range config {
+		out[k] = v
+	}
+	return out
+}
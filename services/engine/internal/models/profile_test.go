@@ -0,0 +1,21 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConnectionProfile_Apply(t *testing.T) {
+	p := &ConnectionProfile{Name: "partner", Config: map[string]interface{}{"server": "sftp.partner.com", "folder": "/in"}}
+	config := map[string]interface{}{"folder": "/out"}
+	assert.Equal(t, map[string]interface{}{"server": "sftp.partner.com", "folder": "/out"}, p.Apply(config))
+	assert.Equal(t, map[string]interface{}{"folder": "/out"}, config, "the node config is not modified")
+}
+
+func TestConnectionProfile_Validate(t *testing.T) {
+	assert.NoError(t, (&ConnectionProfile{Name: "sftp-partner_2"}).Validate())
+	for _, name := range []string{"", "a/b", "has space"} {
+		assert.Error(t, (&ConnectionProfile{Name: name}).Validate(), name)
+	}
+}
@@ -0,0 +1,147 @@
+package models
+
+import (
+	"sort"
+	"strings"
+)
+
+// minSensitiveLen is the shortest sensitive string scrubbed from outputs and
+// errors; shorter values would match unrelated text.
+const minSensitiveLen = 4
+
+// SensitiveRef is the reference that replaces the value of the sensitive
+// input key wherever a node run is recorded.
+func SensitiveRef(key string) string {
+	return "[sensitive:" + key + "]"
+}
+
+// SensitiveInputs holds the resolved values of the sensitive inputs of one
+// node run (Node.SensitiveInputs), so they can be kept out of what is
+// recorded about it. A nil *SensitiveInputs leaves everything unchanged.
+type SensitiveInputs struct {
+	keys map[string]bool
+	// refs maps every string found in a sensitive value to the reference
+	// replacing it, longest first in values.
+	refs   map[string]string
+	values []string
+}
+
+// NewSensitiveInputs collects the values of the keys of input, or returns
+// nil when keys is empty.
+func NewSensitiveInputs(keys []string, input map[string]interface{}) *SensitiveInputs {
+	if len(keys) == 0 {
+		return nil
+	}
+	s := &SensitiveInputs{keys: make(map[string]bool, len(keys)), refs: make(map[string]string)}
+	for _, key := range keys {
+		s.keys[key] = true
+		collectStrings(input[key], func(v string) {
+			if len(v) >= minSensitiveLen {
+				if _, seen := s.refs[v]; !seen {
+					s.values = append(s.values, v)
+				}
+				s.refs[v] = SensitiveRef(key)
+			}
+		})
+	}
+	sort.Slice(s.values, func(i, j int) bool { return len(s.values[i]) > len(s.values[j]) })
+	return s
+}
+
+// RedactInput returns a copy of input with every sensitive key replaced by
+// its reference.
+func (s *SensitiveInputs) RedactInput(input map[string]interface{}) map[string]interface{} {
+	if s == nil || input == nil {
+		return input
+	}
+	out := make(map[string]interface{}, len(input))
+	for k, v := range input {
+		if s.keys[k] {
+			v = SensitiveRef(k)
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// ScrubOutput returns a copy of output in which every occurrence of a
+// sensitive value inside a string is replaced by its reference, so an
+// activity echoing its input does not leak it into the context.
+func (s *SensitiveInputs) ScrubOutput(output map[string]interface{}) map[string]interface{} {
+	if s == nil || output == nil || len(s.values) == 0 {
+		return output
+	}
+	return s.scrub(output).(map[string]interface{})
+}
+
+// ScrubError returns err with the sensitive values in its message replaced;
+// errors.Is and errors.As still see the original error.
+func (s *SensitiveInputs) ScrubError(err error) error {
+	if err == nil || s == nil {
+		return err
+	}
+	msg := s.ScrubString(err.Error())
+	if msg == err.Error() {
+		return err
+	}
+	return &scrubbedError{msg: msg, err: err}
+}
+
+// ScrubString replaces every sensitive value inside v by its reference.
+func (s *SensitiveInputs) ScrubString(v string) string {
+	if s == nil {
+		return v
+	}
+	for _, secret := range s.values {
+		if strings.Contains(v, secret) {
+			v = strings.ReplaceAll(v, secret, s.refs[secret])
+		}
+	}
+	return v
+}
+
+func (s *SensitiveInputs) scrub(v interface{}) interface{} {
+	switch val := v.(type) {
+	case string:
+		return s.ScrubString(val)
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			out[k] = s.scrub(item)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, item := range val {
+			out[i] = s.scrub(item)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// collectStrings calls fn with every string inside v.
+func collectStrings(v interface{}, fn func(string)) {
+	switch val := v.(type) {
+	case string:
+		fn(val)
+	case map[string]interface{}:
+		for _, item := range val {
+			collectStrings(item, fn)
+		}
+	case []interface{}:
+		for _, item := range val {
+			collectStrings(item, fn)
+		}
+	}
+}
+
+// scrubbedError is an error whose message had sensitive values removed.
+type scrubbedError struct {
+	msg string
+	err error
+}
+
+func (e *scrubbedError) Error() string { return e.msg }
+func (e *scrubbedError) Unwrap() error { return e.err }
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSensitiveInputs(t *testing.T) {
+	input := map[string]interface{}{
+		"password": "hunter22",
+		"card":     map[string]interface{}{"number": "4111111111111111", "cvc": "123"},
+		"user":     "ana",
+	}
+	s := NewSensitiveInputs([]string{"password", "card"}, input)
+
+	assert.Equal(t, map[string]interface{}{
+		"password": "[sensitive:password]",
+		"card":     "[sensitive:card]",
+		"user":     "ana",
+	}, s.RedactInput(input))
+	assert.Equal(t, "hunter22", input["password"], "the input itself is not modified")
+
+	out := s.ScrubOutput(map[string]interface{}{
+		"echo":  "login ana:hunter22",
+		"cards": []interface{}{"4111111111111111"},
+		"code":  "123",
+		"n":     3,
+	})
+	assert.Equal(t, "login ana:[sensitive:password]", out["echo"])
+	assert.Equal(t, []interface{}{"[sensitive:card]"}, out["cards"])
+	assert.Equal(t, "123", out["code"], "values shorter than minSensitiveLen are left alone")
+	assert.Equal(t, 3, out["n"])
+
+	base := errors.New("auth failed for hunter22")
+	err := s.ScrubError(base)
+	assert.EqualError(t, err, "auth failed for [sensitive:password]")
+	assert.ErrorIs(t, err, base)
+}
+
+func TestSensitiveInputs_NoneDeclared(t *testing.T) {
+	input := map[string]interface{}{"password": "hunter22"}
+	s := NewSensitiveInputs(nil, input)
+	assert.Nil(t, s)
+	assert.Equal(t, input, s.RedactInput(input))
+	assert.Equal(t, input, s.ScrubOutput(input))
+	base := errors.New("hunter22")
+	assert.Same(t, base, s.ScrubError(base))
+}
@@ -0,0 +1,126 @@
+package models
+
+import (
+	"sync"
+	"time"
+)
+
+// ExecutionStats is the resource usage of one execution, published with its
+// terminal audit event as "execution_stats" so heavy flows can be charged
+// back to the teams that own them.
+type ExecutionStats struct {
+	WallMs int64 `json:"wall_ms"`
+	// CPUMs is the CPU time of the node runs, measured on the thread that ran
+	// each activity. Work an activity hands to other goroutines (e.g. TLS
+	// handshakes) is not included; it is 0 where the platform cannot
+	// measure thread CPU time.
+	CPUMs int64 `json:"cpu_ms"`
+	// BytesTransferred counts the file content downloaded and uploaded by
+	// sftp, s3, smb and file nodes.
+	BytesTransferred int64 `json:"bytes_transferred"`
+	// RowsProcessed counts the rows returned by sql nodes.
+	RowsProcessed int64                `json:"rows_processed"`
+	Nodes         map[string]NodeStats `json:"nodes"`
+}
+
+// NodeStats is the resource usage of one node across its runs (loops and
+// retries included).
+type NodeStats struct {
+	Runs   int   `json:"runs"`
+	WallMs int64 `json:"wall_ms"`
+	CPUMs  int64 `json:"cpu_ms"`
+}
+
+// usage accumulates the resource usage of an execution. Activities may
+// report from other goroutines, so it is guarded by a mutex.
+type usage struct {
+	mu      sync.Mutex
+	started time.Time
+	bytes   int64
+	rows    int64
+	nodes   map[string]*nodeUsage
+}
+
+// nodeUsage keeps full precision until Stats rounds it to milliseconds.
+type nodeUsage struct {
+	runs      int
+	wall, cpu time.Duration
+}
+
+// AddBytes counts n bytes of file content transferred by the execution.
+func (ctx *ExecutionContext) AddBytes(n int64) {
+	if ctx == nil || ctx.usage == nil || n <= 0 {
+		return
+	}
+	ctx.usage.mu.Lock()
+	ctx.usage.bytes += n
+	ctx.usage.mu.Unlock()
+}
+
+// AddRows counts n rows processed by the execution.
+func (ctx *ExecutionContext) AddRows(n int64) {
+	if ctx == nil || ctx.usage == nil || n <= 0 {
+		return
+	}
+	ctx.usage.mu.Lock()
+	ctx.usage.rows += n
+	ctx.usage.mu.Unlock()
+}
+
+// RecordNodeRun adds one run of nodeID that took wall and used cpu.
+func (ctx *ExecutionContext) RecordNodeRun(nodeID string, wall, cpu time.Duration) {
+	if ctx == nil || ctx.usage == nil {
+		return
+	}
+	ctx.usage.mu.Lock()
+	defer ctx.usage.mu.Unlock()
+	nu := ctx.usage.nodes[nodeID]
+	if nu == nil {
+		nu = &nodeUsage{}
+		ctx.usage.nodes[nodeID] = nu
+	}
+	nu.runs++
+	nu.wall += wall
+	nu.cpu += cpu
+}
+
+// Stats returns the resource usage of the execution so far.
+func (ctx *ExecutionContext) Stats() ExecutionStats {
+	stats := ExecutionStats{Nodes: map[string]NodeStats{}}
+	if ctx == nil || ctx.usage == nil {
+		return stats
+	}
+	ctx.usage.mu.Lock()
+	defer ctx.usage.mu.Unlock()
+	stats.WallMs = time.Since(ctx.usage.started).Milliseconds()
+	stats.BytesTransferred = ctx.usage.bytes
+	stats.RowsProcessed = ctx.usage.rows
+	var cpu time.Duration
+	for id, nu := range ctx.usage.nodes {
+		stats.Nodes[id] = NodeStats{Runs: nu.runs, WallMs: nu.wall.Milliseconds(), CPUMs: nu.cpu.Milliseconds()}
+		cpu += nu.cpu
+	}
+	stats.CPUMs = cpu.Milliseconds()
+	return stats
+}
+
+// NodeTiming is the run time of one node across its runs at full precision.
+type NodeTiming struct {
+	Runs      int
+	Wall, CPU time.Duration
+}
+
+// NodeTimings returns the run time of every node that ran so far, without
+// the millisecond rounding of Stats, for benchmarks of fast activities.
+func (ctx *ExecutionContext) NodeTimings() map[string]NodeTiming {
+	timings := map[string]NodeTiming{}
+	if ctx == nil || ctx.usage == nil {
+		return timings
+	}
+	ctx.usage.mu.Lock()
+	defer ctx.usage.mu.Unlock()
+	for id, nu := range ctx.usage.nodes {
+		timings[id] = NodeTiming{Runs: nu.runs, Wall: nu.wall, CPU: nu.cpu}
+	}
+	return timings
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecutionContext_Stats(t *testing.T) {
+	ctx := NewExecutionContext("exec-stats")
+	ctx.AddBytes(1024)
+	ctx.AddBytes(-5)
+	ctx.AddRows(3)
+	ctx.AddRows(2)
+	ctx.RecordNodeRun("fetch", 1500*time.Microsecond, 600*time.Microsecond)
+	ctx.RecordNodeRun("fetch", 1500*time.Microsecond, 600*time.Microsecond)
+	ctx.RecordNodeRun("store", 4*time.Millisecond, 2*time.Millisecond)
+
+	stats := ctx.Stats()
+	assert.Equal(t, int64(1024), stats.BytesTransferred)
+	assert.Equal(t, int64(5), stats.RowsProcessed)
+	assert.Equal(t, NodeStats{Runs: 2, WallMs: 3, CPUMs: 1}, stats.Nodes["fetch"])
+	assert.Equal(t, NodeStats{Runs: 1, WallMs: 4, CPUMs: 2}, stats.Nodes["store"])
+	assert.Equal(t, int64(3), stats.CPUMs)
+	assert.GreaterOrEqual(t, stats.WallMs, int64(0))
+
+	assert.Equal(t, NodeTiming{Runs: 2, Wall: 3 * time.Millisecond, CPU: 1200 * time.Microsecond}, ctx.NodeTimings()["fetch"])
+}
+
+func TestExecutionContext_StatsWithoutUsage(t *testing.T) {
+	ctx := &ExecutionContext{}
+	ctx.AddBytes(10)
+	ctx.RecordNodeRun("n", time.Second, time.Second)
+	assert.Equal(t, ExecutionStats{Nodes: map[string]NodeStats{}}, ctx.Stats())
+	assert.Empty(t, ctx.NodeTimings())
+}
@@ -0,0 +1,125 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ConditionMode returns the condition mode of the transitions leaving node:
+// its own condition_mode, else the process setting, else exclusive.
+func (p *Process) ConditionMode(node *Node) string {
+	if node != nil && node.ConditionMode != "" {
+		return node.ConditionMode
+	}
+	if p.Definition.Settings.ConditionMode != "" {
+		return p.Definition.Settings.ConditionMode
+	}
+	return ConditionExclusive
+}
+
+// SortByPriority orders condition transitions for evaluation: higher
+// priority first, JSON order among equal priorities.
+func SortByPriority(transitions []Transition) {
+	sort.SliceStable(transitions, func(i, j int) bool {
+		return transitions[i].Priority > transitions[j].Priority
+	})
+}
+
+// validConditionMode reports whether mode is a known condition mode.
+func validConditionMode(mode string) bool {
+	return mode == "" || mode == ConditionExclusive || mode == ConditionInclusive
+}
+
+// TransitionWarnings returns problems in the transitions of p that do not
+// stop it from running but probably route data other than intended:
+// conditions whose order is decided by JSON position alone, conditions that
+// repeat each other, priorities that have no effect and else branches
+// without conditions (a nocondition transition is the fallback of a dynamic
+// one too).
+func (p *Process) TransitionWarnings() []string {
+	var warnings []string
+	bySource := make(map[string][]Transition)
+	var sources []string
+	for _, t := range p.Transitions {
+		if _, seen := bySource[t.From]; !seen {
+			sources = append(sources, t.From)
+		}
+		bySource[t.From] = append(bySource[t.From], t)
+		if t.Priority != 0 && t.Type != "condition" {
+			warnings = append(warnings, fmt.Sprintf("transition %s → %s: priority only orders condition transitions", t.From, t.To))
+		}
+	}
+	nodes := make(map[string]*Node, len(p.Nodes))
+	for i := range p.Nodes {
+		nodes[p.Nodes[i].ID] = &p.Nodes[i]
+	}
+
+	for _, from := range sources {
+		var conds []Transition
+		hasElse, dynamic := false, false
+		for _, t := range bySource[from] {
+			switch t.Type {
+			case "condition":
+				conds = append(conds, t)
+			case "nocondition":
+				hasElse = true
+			case TransitionDynamic:
+				dynamic = true
+			}
+		}
+		if hasElse && len(conds) == 0 && !dynamic {
+			warnings = append(warnings, fmt.Sprintf("node %s: nocondition transition without a condition transition", from))
+		}
+		exclusive := p.ConditionMode(nodes[from]) == ConditionExclusive
+		seen := make(map[string]string)
+		for i, a := range conds {
+			expr := strings.Join(strings.Fields(a.Condition), " ")
+			if other, dup := seen[expr]; dup {
+				warnings = append(warnings, fmt.Sprintf("node %s: transitions to %s and %s have the same condition %q", from, other, a.To, a.Condition))
+			} else {
+				seen[expr] = a.To
+			}
+			if !exclusive {
+				continue
+			}
+			for _, b := range conds[i+1:] {
+				if a.Priority == b.Priority {
+					warnings = append(warnings, fmt.Sprintf("node %s: conditions to %s and %s have the same priority; if both match, only %s is followed (set priority or condition_mode \"inclusive\")", from, a.To, b.To, a.To))
+				}
+			}
+		}
+	}
+	return warnings
+}
+
+// validateDynamic checks dynamic transition i, t, of a process whose node ids
+// are nodes: it needs an expression and targets that are all existing nodes,
+// and it must be the only dynamic or condition transition leaving its node.
+func validateDynamic(i int, t Transition, nodes map[string]bool, all []Transition) []error {
+	var errs []error
+	if strings.TrimSpace(t.Expression) == "" {
+		errs = append(errs, fmt.Errorf("transitions[%d]: dynamic transition needs an expression", i))
+	}
+	if t.To != "" {
+		errs = append(errs, fmt.Errorf("transitions[%d]: dynamic transition has targets, not a to", i))
+	}
+	if len(t.Targets) == 0 {
+		errs = append(errs, fmt.Errorf("transitions[%d]: dynamic transition needs targets", i))
+	}
+	for _, target := range t.Targets {
+		if !nodes[target] {
+			errs = append(errs, fmt.Errorf("transitions[%d]: unknown target node %q", i, target))
+		}
+	}
+	for j, other := range all {
+		if j == i || other.From != t.From {
+			continue
+		}
+		if other.Type == "condition" || (other.Type == TransitionDynamic && j < i) {
+			errs = append(errs, fmt.Errorf("transitions[%d]: node %s has a dynamic transition and another %s transition", i, t.From, other.Type))
+			break
+		}
+	}
+	return errs
+}
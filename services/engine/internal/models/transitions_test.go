@@ -0,0 +1,112 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProcess_ConditionMode(t *testing.T) {
+	p := &Process{}
+	assert.Equal(t, ConditionExclusive, p.ConditionMode(nil))
+
+	p.Definition.Settings.ConditionMode = ConditionInclusive
+	assert.Equal(t, ConditionInclusive, p.ConditionMode(&Node{ID: "a"}))
+	assert.Equal(t, ConditionExclusive, p.ConditionMode(&Node{ID: "a", ConditionMode: ConditionExclusive}))
+}
+
+func TestSortByPriority_IsStable(t *testing.T) {
+	ts := []Transition{{To: "a"}, {To: "b", Priority: 5}, {To: "c"}, {To: "d", Priority: 5}}
+	SortByPriority(ts)
+	var order []string
+	for _, tr := range ts {
+		order = append(order, tr.To)
+	}
+	assert.Equal(t, []string{"b", "d", "a", "c"}, order)
+}
+
+func TestProcess_TransitionWarnings(t *testing.T) {
+	p := &Process{
+		Nodes: []Node{{ID: "a"}, {ID: "b"}, {ID: "c"}, {ID: "d"}},
+		Transitions: []Transition{
+			{From: "a", To: "b", Type: "condition", Condition: "$.x > 1"},
+			{From: "a", To: "c", Type: "condition", Condition: "$.x  > 1", Priority: 1},
+			{From: "a", To: "d", Type: "nocondition"},
+			{From: "b", To: "c", Type: "success", Priority: 3},
+			{From: "c", To: "d", Type: "nocondition"},
+		},
+	}
+	w := p.TransitionWarnings()
+	assert.Len(t, w, 3)
+	assert.Contains(t, w[0], "priority only orders condition transitions")
+	assert.Contains(t, w[1], "same condition")
+	assert.Contains(t, w[2], "node c: nocondition transition without a condition transition")
+}
+
+func TestProcess_TransitionWarnings_EqualPriority(t *testing.T) {
+	p := &Process{
+		Nodes: []Node{{ID: "a"}, {ID: "b"}, {ID: "c"}},
+		Transitions: []Transition{
+			{From: "a", To: "b", Type: "condition", Condition: "$.x > 1"},
+			{From: "a", To: "c", Type: "condition", Condition: "$.y > 1"},
+		},
+	}
+	w := p.TransitionWarnings()
+	assert.Len(t, w, 1)
+	assert.Contains(t, w[0], "same priority")
+
+	p.Nodes[0].ConditionMode = ConditionInclusive
+	assert.Empty(t, p.TransitionWarnings(), "inclusive mode follows every match")
+
+	p.Nodes[0].ConditionMode = ""
+	p.Transitions[1].Priority = 1
+	assert.Empty(t, p.TransitionWarnings())
+}
+
+func TestProcess_ValidateDynamicTransitions(t *testing.T) {
+	p := &Process{
+		Definition: Definition{ID: "p1"},
+		Trigger:    Trigger{ID: "trg", Type: "manual"},
+		Nodes:      []Node{{ID: "route", Type: "log"}, {ID: "ship_es", Type: "log"}, {ID: "ship_fr", Type: "log"}, {ID: "manual", Type: "log"}},
+		Transitions: []Transition{
+			{From: "route", Type: TransitionDynamic, Expression: `"ship_" + $.trigger.body.country`, Targets: []string{"ship_es", "ship_fr"}},
+			{From: "route", To: "manual", Type: "nocondition"},
+		},
+	}
+	assert.NoError(t, p.Validate())
+	assert.Empty(t, p.TransitionWarnings(), "nocondition is the dynamic fallback")
+	assert.Equal(t, []string{"ship_es", "ship_fr"}, p.Transitions[0].Destinations())
+	assert.Equal(t, []string{"manual"}, p.Transitions[1].Destinations())
+
+	p.Transitions = []Transition{
+		{From: "route", Type: TransitionDynamic, Targets: []string{"ship_es", "ship_de"}},
+		{From: "route", To: "manual", Type: "condition", Condition: "$.x"},
+		{From: "trg", Type: TransitionDynamic, Expression: "$.x"},
+	}
+	err := p.Validate()
+	for _, msg := range []string{
+		"transitions[0]: dynamic transition needs an expression",
+		`transitions[0]: unknown target node "ship_de"`,
+		"transitions[0]: node route has a dynamic transition and another condition transition",
+		"transitions[2]: a dynamic transition cannot leave the trigger",
+		"transitions[2]: dynamic transition needs targets",
+	} {
+		assert.ErrorContains(t, err, msg)
+	}
+}
+
+func TestProcess_ValidateChunkTransitions(t *testing.T) {
+	p := &Process{
+		Definition: Definition{ID: "p1"},
+		Trigger:    Trigger{ID: "trg", Type: "manual"},
+		Nodes:      []Node{{ID: "read", Type: "sql"}, {ID: "upsert", Type: "http"}, {ID: "report", Type: "log"}},
+		Transitions: []Transition{
+			{From: "read", To: "upsert", Type: TransitionChunk},
+			{From: "read", To: "report", Type: "success"},
+		},
+	}
+	assert.NoError(t, p.Validate())
+
+	p.Transitions = append(p.Transitions, Transition{From: "upsert", To: "report", Type: TransitionChunk})
+	assert.ErrorContains(t, p.Validate(), "transitions[2]: chunk transitions must leave a sql node")
+}
@@ -0,0 +1,84 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// YAMLToJSON converts a YAML document to JSON so it can be decoded with the
+// `json` struct tags of the DSL models. Block scalars (`|`) make multi-line
+// scripts and SQL queries readable in YAML process definitions.
+func YAMLToJSON(data []byte) ([]byte, error) {
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid YAML: %w", err)
+	}
+	normalized, err := jsonCompatible(doc)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(normalized)
+}
+
+// ProcessFromYAML parses a YAML process definition.
+func ProcessFromYAML(data []byte) (*Process, error) {
+	raw, err := YAMLToJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	var process Process
+	if err := json.Unmarshal(raw, &process); err != nil {
+		return nil, fmt.Errorf("invalid process definition: %w", err)
+	}
+	return &process, nil
+}
+
+// IsYAMLFile reports whether path has a .yaml or .yml extension.
+func IsYAMLFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return true
+	}
+	return false
+}
+
+// jsonCompatible converts YAML mappings with non-string keys, which
+// encoding/json cannot marshal, into string-keyed maps.
+func jsonCompatible(v interface{}) (interface{}, error) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, val := range t {
+			conv, err := jsonCompatible(val)
+			if err != nil {
+				return nil, err
+			}
+			t[k] = conv
+		}
+		return t, nil
+	case map[interface{}]interface{}:
+		out := make(map[string]interface{}, len(t))
+		for k, val := range t {
+			conv, err := jsonCompatible(val)
+			if err != nil {
+				return nil, err
+			}
+			out[fmt.Sprint(k)] = conv
+		}
+		return out, nil
+	case []interface{}:
+		for i, val := range t {
+			conv, err := jsonCompatible(val)
+			if err != nil {
+				return nil, err
+			}
+			t[i] = conv
+		}
+		return t, nil
+	default:
+		return v, nil
+	}
+}
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const yamlProcess = `
+definition:
+  id: yaml-flow
+  version: 1.0.0
+  settings:
+    persistence: full
+    max_concurrency: 2
+trigger:
+  id: trg_01
+  type: manual
+nodes:
+  - id: query
+    type: sql
+    config:
+      query: |
+        SELECT id, email
+        FROM users
+        WHERE active = true
+      params: [1, true]
+      200: ok
+transitions: []
+`
+
+func TestProcessFromYAML(t *testing.T) {
+	p, err := ProcessFromYAML([]byte(yamlProcess))
+	require.NoError(t, err)
+
+	assert.Equal(t, "yaml-flow", p.Definition.ID)
+	assert.Equal(t, "1.0.0", p.Definition.Version)
+	assert.Equal(t, 2, p.Definition.Settings.MaxConcurrency)
+	require.Len(t, p.Nodes, 1)
+	assert.Equal(t, "SELECT id, email\nFROM users\nWHERE active = true\n", p.Nodes[0].Config["query"])
+	assert.Equal(t, []interface{}{float64(1), true}, p.Nodes[0].Config["params"])
+	assert.Equal(t, "ok", p.Nodes[0].Config["200"])
+}
+
+func TestProcessFromYAML_Invalid(t *testing.T) {
+	_, err := ProcessFromYAML([]byte("nodes: [unclosed"))
+	assert.ErrorContains(t, err, "invalid YAML")
+
+	_, err = ProcessFromYAML([]byte("nodes: not-a-list"))
+	assert.ErrorContains(t, err, "invalid process definition")
+}
+
+func TestIsYAMLFile(t *testing.T) {
+	assert.True(t, IsYAMLFile("flows/flow.yaml"))
+	assert.True(t, IsYAMLFile("FLOW.YML"))
+	assert.False(t, IsYAMLFile("flow.json"))
+}
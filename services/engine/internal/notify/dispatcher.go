@@ -0,0 +1,191 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"flowjs-works/engine/internal/logging"
+	"flowjs-works/engine/internal/secrets"
+	"flowjs-works/engine/internal/tenant"
+)
+
+const (
+	// DefaultQueueSize is how many events may wait for delivery before new
+	// ones are dropped.
+	DefaultQueueSize = 1000
+	// lookupTimeout bounds the subscription lookup of one event.
+	lookupTimeout = 5 * time.Second
+	// closeTimeout bounds how long Close waits for queued events.
+	closeTimeout = 10 * time.Second
+)
+
+// Stats counts deliveries since the dispatcher was created.
+type Stats struct {
+	Sent      uint64 // notifications delivered to a channel
+	Failed    uint64 // deliveries that returned an error
+	Dropped   uint64 // events discarded because the queue was full or closed
+	Throttled uint64 // notifications skipped by a subscription cooldown
+}
+
+// Dispatcher queues events and delivers them to the subscribed channels on
+// a background worker. Notify never blocks; delivery errors are logged and
+// counted, never retried, so a broken channel cannot build up a backlog.
+type Dispatcher struct {
+	source   Source
+	resolver secrets.SecretResolver
+	senders  map[string]Sender
+	now      func() time.Time
+
+	mu     sync.RWMutex // guards closed against Notify racing Close
+	closed bool
+	events chan Event
+	done   chan struct{}
+
+	// lastSent is when a channel was last notified of an event type of a
+	// process, for subscription cooldowns. Only the worker touches it.
+	lastSent map[string]time.Time
+
+	sent, failed, dropped, throttled atomic.Uint64
+}
+
+// NewDispatcher creates a dispatcher reading subscriptions from source and
+// channel secrets from resolver (nil disables secret_ref). Call Start to
+// begin delivering.
+func NewDispatcher(source Source, resolver secrets.SecretResolver) *Dispatcher {
+	if resolver == nil {
+		resolver = &secrets.NoopResolver{}
+	}
+	return &Dispatcher{
+		source:   source,
+		resolver: resolver,
+		senders:  defaultSenders(),
+		now:      time.Now,
+		events:   make(chan Event, DefaultQueueSize),
+		done:     make(chan struct{}),
+		lastSent: make(map[string]time.Time),
+	}
+}
+
+// Start runs the delivery worker until Close.
+func (d *Dispatcher) Start() {
+	go func() {
+		defer close(d.done)
+		for ev := range d.events {
+			d.dispatch(ev)
+		}
+	}()
+}
+
+// Close stops accepting events and waits up to closeTimeout for the queued
+// ones to be delivered.
+func (d *Dispatcher) Close() {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return
+	}
+	d.closed = true
+	close(d.events)
+	d.mu.Unlock()
+	select {
+	case <-d.done:
+	case <-time.After(closeTimeout):
+		slog.Warn("notify: shutdown before every queued notification was delivered")
+	}
+}
+
+// Notify queues ev for delivery. Events of processes without subscriptions
+// are discarded by the worker; when the queue is full ev is dropped.
+func (d *Dispatcher) Notify(ev Event) {
+	if ev.Timestamp.IsZero() {
+		ev.Timestamp = d.now().UTC()
+	}
+	ev.Workspace = tenant.Normalize(ev.Workspace)
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.closed {
+		d.dropped.Add(1)
+		return
+	}
+	select {
+	case d.events <- ev:
+	default:
+		d.dropped.Add(1)
+		slog.Warn("notify: queue full; notification dropped", logging.KeyProcessID, ev.ProcessID, "event", ev.Type)
+	}
+}
+
+// Stats reports the delivery counters.
+func (d *Dispatcher) Stats() Stats {
+	return Stats{Sent: d.sent.Load(), Failed: d.failed.Load(), Dropped: d.dropped.Load(), Throttled: d.throttled.Load()}
+}
+
+// dispatch delivers ev to every channel subscribed to it.
+func (d *Dispatcher) dispatch(ev Event) {
+	ctx, cancel := context.WithTimeout(tenant.WithWorkspace(context.Background(), ev.Workspace), lookupTimeout)
+	targets, err := d.source.Targets(ctx, ev.ProcessID, ev.Type)
+	cancel()
+	if err != nil {
+		d.failed.Add(1)
+		slog.Error("notify: look up subscriptions", logging.KeyProcessID, ev.ProcessID, "event", ev.Type, logging.KeyError, err)
+		return
+	}
+	for _, t := range targets {
+		if !d.admit(t, ev) {
+			d.throttled.Add(1)
+			continue
+		}
+		if err := d.Send(context.Background(), t.Channel, ev); err != nil {
+			d.failed.Add(1)
+			slog.Error("notify: delivery failed", "channel", t.Channel.ID, logging.KeyProcessID, ev.ProcessID, "event", ev.Type, logging.KeyError, err)
+			continue
+		}
+		d.sent.Add(1)
+	}
+}
+
+// admit applies the cooldown of t: it reports whether ev may be sent and,
+// if so, records the send.
+func (d *Dispatcher) admit(t Target, ev Event) bool {
+	if t.Cooldown <= 0 {
+		return true
+	}
+	key := ev.Workspace + "\x00" + t.Channel.ID + "\x00" + ev.ProcessID + "\x00" + ev.Type
+	now := d.now()
+	if last, ok := d.lastSent[key]; ok && now.Sub(last) < t.Cooldown {
+		return false
+	}
+	d.lastSent[key] = now
+	return true
+}
+
+// Send delivers ev through ch right away, resolving its secret_ref in the
+// channel's workspace. It is used by the worker and to test a channel.
+func (d *Dispatcher) Send(ctx context.Context, ch Channel, ev Event) error {
+	sender, ok := d.senders[ch.Type]
+	if !ok {
+		return fmt.Errorf("unknown channel type %q", ch.Type)
+	}
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+	config := make(map[string]interface{}, len(ch.Config))
+	for k, v := range ch.Config {
+		config[k] = v
+	}
+	if ch.SecretRef != "" {
+		secretCtx := tenant.WithWorkspace(ctx, ch.Workspace)
+		secretCtx = secrets.WithUsage(secretCtx, secrets.Usage{ExecutionID: ev.ExecutionID, ProcessID: ev.ProcessID})
+		secretData, err := d.resolver.Resolve(secretCtx, ch.SecretRef)
+		if err != nil {
+			return fmt.Errorf("resolve secret %s: %w", ch.SecretRef, err)
+		}
+		for k, v := range secretData {
+			config[k] = v
+		}
+	}
+	return sender.Send(ctx, config, ev)
+}
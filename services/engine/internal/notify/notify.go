@@ -0,0 +1,198 @@
+// Package notify delivers process events — failed executions, SLA breaches
+// and deploys — to the notification channels a process subscribes to.
+// Channels (email, Slack, webhook) are configured once per workspace in the
+// config DB and shared by any number of processes. Delivery runs on the
+// Dispatcher's own worker, outside any flow, so a slow or failing channel
+// never delays or fails an execution.
+package notify
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Event types a process can subscribe to.
+const (
+	// EventFailure is sent when an execution of the process fails.
+	EventFailure = "failure"
+	// EventSLABreach is sent when a node runs longer than its sla_ms.
+	EventSLABreach = "sla_breach"
+	// EventDeploy is sent when the process is deployed, or fails to deploy.
+	EventDeploy = "deploy"
+)
+
+// Events lists the event types in documentation order.
+var Events = []string{EventFailure, EventSLABreach, EventDeploy}
+
+// ValidEvent reports whether name is one of Events.
+func ValidEvent(name string) bool {
+	for _, e := range Events {
+		if e == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Event is one occurrence delivered to the subscribed channels. Webhook
+// channels receive it as JSON; email and Slack channels a text rendering.
+type Event struct {
+	Type        string                 `json:"event"`
+	Workspace   string                 `json:"workspace"`
+	ProcessID   string                 `json:"process_id"`
+	ExecutionID string                 `json:"execution_id,omitempty"`
+	NodeID      string                 `json:"node_id,omitempty"`
+	Message     string                 `json:"message"`
+	Details     map[string]interface{} `json:"details,omitempty"`
+	Timestamp   time.Time              `json:"timestamp"`
+}
+
+// Summary is the one-line description used as email subject and Slack text.
+func (ev Event) Summary() string {
+	var what string
+	switch ev.Type {
+	case EventFailure:
+		what = "execution failed"
+	case EventSLABreach:
+		what = "SLA breached"
+	case EventDeploy:
+		what = "deployed"
+		if ev.Message != "" {
+			what = "deploy failed"
+		}
+	default:
+		what = ev.Type
+	}
+	s := fmt.Sprintf("[flowjs-works] %s: %s", ev.ProcessID, what)
+	if ev.Message != "" {
+		s += ": " + ev.Message
+	}
+	return s
+}
+
+// Text renders the event as plain text: the summary followed by its fields.
+func (ev Event) Text() string {
+	var b strings.Builder
+	b.WriteString(ev.Summary())
+	b.WriteString("\n\n")
+	line := func(k, v string) {
+		if v != "" {
+			fmt.Fprintf(&b, "%s: %s\n", k, v)
+		}
+	}
+	line("Workspace", ev.Workspace)
+	line("Process", ev.ProcessID)
+	line("Execution", ev.ExecutionID)
+	line("Node", ev.NodeID)
+	line("Time", ev.Timestamp.UTC().Format(time.RFC3339))
+	keys := make([]string, 0, len(ev.Details))
+	for k := range ev.Details {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		line(k, fmt.Sprint(ev.Details[k]))
+	}
+	return b.String()
+}
+
+// Channel types.
+const (
+	ChannelEmail   = "email"
+	ChannelSlack   = "slack"
+	ChannelWebhook = "webhook"
+)
+
+// Channel is a configured destination. Config holds the settings of its
+// type; credentials belong in the secret named by SecretRef, whose fields
+// are merged into Config when sending:
+//
+//	email:   host, port, security, auth {user, password}, from, to (as the mail node)
+//	slack:   webhook_url (incoming webhook), optional channel and username
+//	webhook: url, optional headers; the event is POSTed as JSON
+type Channel struct {
+	ID          string                 `json:"id"`
+	Workspace   string                 `json:"workspace"`
+	Type        string                 `json:"type"`
+	Description string                 `json:"description"`
+	Config      map[string]interface{} `json:"config"`
+	SecretRef   string                 `json:"secret_ref,omitempty"`
+	CreatedAt   time.Time              `json:"created_at"`
+	UpdatedAt   time.Time              `json:"updated_at"`
+}
+
+// channelIDRe matches valid channel ids, like process ids.
+var channelIDRe = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,255}$`)
+
+// requiredConfig lists, per channel type, the config fields a channel must
+// have unless they come from its secret.
+var requiredConfig = map[string][]string{
+	ChannelEmail:   {"host", "from", "to"},
+	ChannelSlack:   {"webhook_url"},
+	ChannelWebhook: {"url"},
+}
+
+// Validate checks the id, type and required config of the channel.
+func (ch *Channel) Validate() error {
+	if !channelIDRe.MatchString(ch.ID) {
+		return fmt.Errorf("channel id must be 1-255 alphanumeric characters, hyphens or underscores")
+	}
+	required, ok := requiredConfig[ch.Type]
+	if !ok {
+		return fmt.Errorf("channel type must be one of email, slack, webhook; got %q", ch.Type)
+	}
+	if ch.SecretRef != "" {
+		return nil
+	}
+	for _, k := range required {
+		if ch.Config[k] == nil || ch.Config[k] == "" {
+			return fmt.Errorf("%s channel requires config.%s (or a secret_ref providing it)", ch.Type, k)
+		}
+	}
+	return nil
+}
+
+// Subscription sends the listed events of a process to a channel, at most
+// once every CooldownS seconds per event type (0 sends every event).
+type Subscription struct {
+	ChannelID string   `json:"channel_id"`
+	Events    []string `json:"events"`
+	CooldownS int      `json:"cooldown_s,omitempty"`
+}
+
+// Validate checks the events and cooldown of the subscription.
+func (s *Subscription) Validate() error {
+	if s.ChannelID == "" {
+		return fmt.Errorf("channel_id is required")
+	}
+	if len(s.Events) == 0 {
+		return fmt.Errorf("subscription to %s: events must list at least one of %s", s.ChannelID, strings.Join(Events, ", "))
+	}
+	for _, ev := range s.Events {
+		if !ValidEvent(ev) {
+			return fmt.Errorf("subscription to %s: unknown event %q (expected one of %s)", s.ChannelID, ev, strings.Join(Events, ", "))
+		}
+	}
+	if s.CooldownS < 0 {
+		return fmt.Errorf("subscription to %s: cooldown_s must not be negative", s.ChannelID)
+	}
+	return nil
+}
+
+// Target is a channel an event is delivered to, with the cooldown of the
+// subscription that selected it.
+type Target struct {
+	Channel  Channel
+	Cooldown time.Duration
+}
+
+// Source returns the channels subscribed to event of processID in the
+// workspace carried by ctx. store.NotificationStore implements it on the
+// config DB.
+type Source interface {
+	Targets(ctx context.Context, processID, event string) ([]Target, error)
+}
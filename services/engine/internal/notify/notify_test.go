@@ -0,0 +1,151 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSource struct {
+	targets map[string][]Target // by event type
+	err     error
+}
+
+func (f *fakeSource) Targets(_ context.Context, _, event string) ([]Target, error) {
+	return f.targets[event], f.err
+}
+
+type fakeResolver struct{ data map[string]interface{} }
+
+func (f *fakeResolver) Resolve(context.Context, string) (map[string]interface{}, error) {
+	return f.data, nil
+}
+
+// recorder is an HTTP endpoint that keeps the JSON bodies posted to it.
+type recorder struct {
+	mu      sync.Mutex
+	bodies  []map[string]interface{}
+	headers []http.Header
+	status  int
+}
+
+func (rec *recorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var body map[string]interface{}
+	_ = json.NewDecoder(r.Body).Decode(&body)
+	rec.mu.Lock()
+	rec.bodies = append(rec.bodies, body)
+	rec.headers = append(rec.headers, r.Header.Clone())
+	status := rec.status
+	rec.mu.Unlock()
+	if status != 0 {
+		w.WriteHeader(status)
+	}
+}
+
+func (rec *recorder) count() int {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return len(rec.bodies)
+}
+
+func TestDispatcher_DeliversWebhookAndSlack(t *testing.T) {
+	hook, slack := &recorder{}, &recorder{}
+	hookSrv, slackSrv := httptest.NewServer(hook), httptest.NewServer(slack)
+	defer hookSrv.Close()
+	defer slackSrv.Close()
+
+	source := &fakeSource{targets: map[string][]Target{
+		EventFailure: {
+			{Channel: Channel{ID: "ops-hook", Type: ChannelWebhook, Config: map[string]interface{}{"url": hookSrv.URL, "headers": map[string]interface{}{"X-Team": "ops"}}}},
+			// The webhook URL of Slack channels usually comes from a secret.
+			{Channel: Channel{ID: "ops-slack", Type: ChannelSlack, SecretRef: "slack", Config: map[string]interface{}{"channel": "#alerts"}}},
+		},
+	}}
+	d := NewDispatcher(source, &fakeResolver{data: map[string]interface{}{"webhook_url": slackSrv.URL}})
+	d.Start()
+	d.Notify(Event{Type: EventFailure, Workspace: "team-a", ProcessID: "orders", ExecutionID: "e-1", Message: "node charge failed: 502"})
+	d.Notify(Event{Type: EventDeploy, ProcessID: "orders"}) // no subscribers
+	d.Close()
+
+	require.Equal(t, 1, hook.count())
+	assert.Equal(t, "failure", hook.bodies[0]["event"])
+	assert.Equal(t, "team-a", hook.bodies[0]["workspace"])
+	assert.Equal(t, "e-1", hook.bodies[0]["execution_id"])
+	assert.Equal(t, "ops", hook.headers[0].Get("X-Team"))
+
+	require.Equal(t, 1, slack.count())
+	assert.Equal(t, "#alerts", slack.bodies[0]["channel"])
+	assert.Contains(t, slack.bodies[0]["text"], "[flowjs-works] orders: execution failed: node charge failed: 502")
+	assert.Contains(t, slack.bodies[0]["text"], "Execution: e-1")
+	assert.Equal(t, Stats{Sent: 2}, d.Stats())
+}
+
+func TestDispatcher_CooldownAndFailures(t *testing.T) {
+	hook := &recorder{status: http.StatusInternalServerError}
+	srv := httptest.NewServer(hook)
+	defer srv.Close()
+
+	source := &fakeSource{targets: map[string][]Target{
+		EventSLABreach: {{Channel: Channel{ID: "hook", Type: ChannelWebhook, Config: map[string]interface{}{"url": srv.URL}}, Cooldown: time.Minute}},
+	}}
+	d := NewDispatcher(source, nil)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	d.now = func() time.Time { return now }
+
+	ev := Event{Type: EventSLABreach, ProcessID: "orders"}
+	d.dispatch(ev)
+	d.dispatch(ev) // within the cooldown
+	now = now.Add(time.Minute)
+	d.dispatch(ev)
+	assert.Equal(t, 2, hook.count())
+	assert.Equal(t, Stats{Failed: 2, Throttled: 1}, d.Stats(), "rejected deliveries count as failed")
+
+	source.err = errors.New("db down")
+	d.dispatch(ev)
+	assert.Equal(t, uint64(3), d.Stats().Failed)
+}
+
+func TestDispatcher_DropsWhenClosed(t *testing.T) {
+	d := NewDispatcher(&fakeSource{}, nil)
+	d.Start()
+	d.Close()
+	d.Close()
+	d.Notify(Event{Type: EventFailure, ProcessID: "orders"})
+	assert.Equal(t, uint64(1), d.Stats().Dropped)
+}
+
+func TestChannel_Validate(t *testing.T) {
+	tests := []struct {
+		ch      Channel
+		wantErr string
+	}{
+		{Channel{ID: "ops", Type: ChannelWebhook, Config: map[string]interface{}{"url": "https://x"}}, ""},
+		{Channel{ID: "ops", Type: ChannelSlack, SecretRef: "slack"}, ""},
+		{Channel{ID: "ops", Type: ChannelEmail, Config: map[string]interface{}{"host": "smtp", "from": "a@x"}}, "requires config.to"},
+		{Channel{ID: "ops", Type: "pager"}, "channel type must be one of"},
+		{Channel{ID: "a b", Type: ChannelWebhook}, "channel id must be"},
+	}
+	for _, tt := range tests {
+		err := tt.ch.Validate()
+		if tt.wantErr == "" {
+			assert.NoError(t, err)
+		} else {
+			assert.ErrorContains(t, err, tt.wantErr)
+		}
+	}
+}
+
+func TestSubscription_Validate(t *testing.T) {
+	assert.NoError(t, (&Subscription{ChannelID: "ops", Events: []string{EventFailure, EventDeploy}}).Validate())
+	assert.ErrorContains(t, (&Subscription{ChannelID: "ops"}).Validate(), "at least one")
+	assert.ErrorContains(t, (&Subscription{ChannelID: "ops", Events: []string{"success"}}).Validate(), `unknown event "success"`)
+	assert.ErrorContains(t, (&Subscription{ChannelID: "ops", Events: []string{EventFailure}, CooldownS: -1}).Validate(), "cooldown_s")
+}
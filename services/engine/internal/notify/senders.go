@@ -0,0 +1,115 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"flowjs-works/engine/internal/activities"
+)
+
+// sendTimeout bounds the delivery of one event to one channel.
+const sendTimeout = 10 * time.Second
+
+// Sender delivers an event through one channel type. config is the channel
+// config with its secret merged in.
+type Sender interface {
+	Send(ctx context.Context, config map[string]interface{}, ev Event) error
+}
+
+// defaultSenders returns the senders of the built-in channel types.
+func defaultSenders() map[string]Sender {
+	client := &http.Client{Timeout: sendTimeout}
+	return map[string]Sender{
+		ChannelEmail:   emailSender{},
+		ChannelSlack:   slackSender{client: client},
+		ChannelWebhook: webhookSender{client: client},
+	}
+}
+
+// webhookSender POSTs the event as JSON to config.url with config.headers.
+type webhookSender struct {
+	client *http.Client
+}
+
+func (s webhookSender) Send(ctx context.Context, config map[string]interface{}, ev Event) error {
+	url, _ := config["url"].(string)
+	if url == "" {
+		return fmt.Errorf("webhook channel: url is required")
+	}
+	headers := map[string]string{}
+	if h, ok := config["headers"].(map[string]interface{}); ok {
+		for k, v := range h {
+			headers[k] = fmt.Sprint(v)
+		}
+	}
+	return postJSON(ctx, s.client, url, headers, ev)
+}
+
+// slackSender posts the event text to a Slack incoming webhook.
+type slackSender struct {
+	client *http.Client
+}
+
+func (s slackSender) Send(ctx context.Context, config map[string]interface{}, ev Event) error {
+	url, _ := config["webhook_url"].(string)
+	if url == "" {
+		return fmt.Errorf("slack channel: webhook_url is required")
+	}
+	msg := map[string]interface{}{"text": ev.Text()}
+	for _, k := range []string{"channel", "username"} {
+		if v, ok := config[k].(string); ok && v != "" {
+			msg[k] = v
+		}
+	}
+	return postJSON(ctx, s.client, url, nil, msg)
+}
+
+// postJSON POSTs body as JSON and fails on a non-2xx response.
+func postJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, body interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("marshal notification: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("post notification: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("notification rejected with HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// emailSender mails the event text with the SMTP settings of the mail node.
+type emailSender struct{}
+
+func (emailSender) Send(_ context.Context, config map[string]interface{}, ev Event) error {
+	mailConfig := make(map[string]interface{}, len(config)+3)
+	for k, v := range config {
+		mailConfig[k] = v
+	}
+	// Only the event is sent; attachments would need an execution context.
+	delete(mailConfig, "attachments")
+	mailConfig["action"] = "send"
+	mailConfig["subject"] = ev.Summary()
+	mailConfig["body"] = ev.Text()
+	if _, err := (&activities.MailActivity{}).Execute(map[string]interface{}{}, mailConfig, nil); err != nil {
+		return fmt.Errorf("email channel: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package queue
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"flowjs-works/engine/internal/store"
+)
+
+// MemoryStore is a JobStore that keeps the queue in process memory. It applies
+// the same ordering as store.QueueStore but does not survive restarts; it is
+// used when DATABASE_URL is not configured.
+type MemoryStore struct {
+	mu      sync.Mutex
+	pending []*store.QueuedJob
+	running map[string]string // job ID → process ID
+}
+
+// NewMemoryStore creates an empty in-memory queue.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{running: make(map[string]string)}
+}
+
+// Enqueue appends job to the pending list.
+func (m *MemoryStore) Enqueue(_ context.Context, job *store.QueuedJob) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job.EnqueuedAt = time.Now()
+	m.pending = append(m.pending, job)
+	return nil
+}
+
+// Claim removes and returns the next job of lane by priority, running jobs
+// of the same process, then enqueue order. It returns nil when the lane has
+// no pending job.
+func (m *MemoryStore) Claim(_ context.Context, lane string) (*store.QueuedJob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	load := make(map[string]int, len(m.running))
+	for _, pid := range m.running {
+		load[pid]++
+	}
+	best := -1
+	for i, job := range m.pending {
+		if job.Lane == lane && (best < 0 || claimsBefore(job, m.pending[best], load)) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return nil, nil
+	}
+	job := m.pending[best]
+	m.pending = append(m.pending[:best], m.pending[best+1:]...)
+	m.running[job.ID] = job.ProcessID
+	return job, nil
+}
+
+// claimsBefore reports whether a should be claimed ahead of b. Pending jobs
+// are kept in enqueue order, so ties keep the earlier job.
+func claimsBefore(a, b *store.QueuedJob, load map[string]int) bool {
+	if a.Priority != b.Priority {
+		return a.Priority > b.Priority
+	}
+	return load[a.ProcessID] < load[b.ProcessID]
+}
+
+// Remove forgets a finished job, or withdraws one that is still pending.
+func (m *MemoryStore) Remove(_ context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.running, id)
+	for i, job := range m.pending {
+		if job.ID == id {
+			m.pending = append(m.pending[:i], m.pending[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// RenewClaims returns the IDs of every job: an in-memory queue is not shared,
+// so they are all held by this engine.
+func (m *MemoryStore) RenewClaims(context.Context) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ids := make([]string, 0, len(m.pending)+len(m.running))
+	for _, job := range m.pending {
+		ids = append(ids, job.ID)
+	}
+	for id := range m.running {
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// DiscardInterrupted is a no-op: an in-memory queue starts empty.
+func (m *MemoryStore) DiscardInterrupted(context.Context) (int64, error) {
+	return 0, nil
+}
+
+// DiscardExpired is a no-op: an in-memory queue is not shared.
+func (m *MemoryStore) DiscardExpired(context.Context) (int64, error) {
+	return 0, nil
+}
@@ -0,0 +1,423 @@
+// Package queue places a persistent execution queue between triggers and the
+// executor. A fixed pool of workers drains the queue in priority order, so
+// when every worker is busy an urgent webhook-driven flow overtakes a backlog
+// of low-priority batch runs instead of waiting behind it. Named lanes add
+// pools of their own: the runs of a process assigned to a lane
+// (definition.settings.lane) are only drained by that lane's workers, so a
+// nightly batch cannot occupy the workers answering REST triggers.
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"flowjs-works/engine/internal/logging"
+	"flowjs-works/engine/internal/models"
+	"flowjs-works/engine/internal/store"
+	"flowjs-works/engine/internal/tenant"
+	"flowjs-works/engine/internal/triggers"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultWorkers is the worker pool size used when none is configured.
+	DefaultWorkers = 16
+	// DefaultLane is the lane of the processes that name none, or one the
+	// queue does not run.
+	DefaultLane = "default"
+	// pollInterval is how often idle workers look for jobs enqueued by other
+	// replicas or restored after a restart.
+	pollInterval = time.Second
+	// storeTimeout bounds every queue DB round-trip.
+	storeTimeout = 10 * time.Second
+	// leaseInterval is how often the claims of running jobs are renewed and
+	// those of stopped replicas discarded, well within store.DefaultClaimLease.
+	leaseInterval = 15 * time.Second
+)
+
+// ErrStopped is returned by Execute once the queue is shutting down. The job
+// stays persisted and runs after the next start.
+var ErrStopped = errors.New("execution queue stopped")
+
+// ErrTakenOver is returned by Execute when another engine ran the job, which
+// only happens once this engine failed to renew its reservation for
+// store.DefaultClaimLease. The outcome is only recorded by the audit trail.
+var ErrTakenOver = errors.New("execution queue: job run by another engine")
+
+// JobStore is the persistence used by the queue. store.QueueStore implements
+// it on Postgres; MemoryStore is used when no database is configured.
+type JobStore interface {
+	Enqueue(ctx context.Context, job *store.QueuedJob) error
+	Claim(ctx context.Context, lane string) (*store.QueuedJob, error)
+	Remove(ctx context.Context, id string) error
+	RenewClaims(ctx context.Context) ([]string, error)
+	DiscardInterrupted(ctx context.Context) (int64, error)
+	DiscardExpired(ctx context.Context) (int64, error)
+}
+
+type result struct {
+	ctx *models.ExecutionContext
+	err error
+}
+
+// waiter is a caller blocked in ExecuteContext on its job.
+type waiter struct {
+	done chan result
+	// ctx is the caller's context, the job's execution is bound to it.
+	ctx context.Context
+	// started is set once a worker of this replica claimed the job.
+	started bool
+	// enqueued is when the job was persisted, zero before.
+	enqueued time.Time
+}
+
+// Queue implements triggers.Executor by enqueueing the run and waiting for a
+// worker to execute it.
+type Queue struct {
+	jobs     JobStore
+	executor triggers.Executor
+	// workers is the pool size of each lane, wake the channel waking its
+	// idle workers.
+	workers map[string]int
+	wake    map[string]chan struct{}
+
+	mu      sync.Mutex
+	waiters map[string]*waiter
+
+	stopOnce sync.Once
+	stopCh   chan struct{}
+	wg       sync.WaitGroup
+	// leaseStop ends maintainLeases once the in-flight jobs finished.
+	leaseStop chan struct{}
+	leaseWG   sync.WaitGroup
+}
+
+// New creates a Queue whose default lane has the given worker pool size.
+// Call Start to begin draining it.
+func New(jobs JobStore, executor triggers.Executor, workers int) *Queue {
+	q := &Queue{
+		jobs:     jobs,
+		executor: executor,
+		workers:  make(map[string]int),
+		wake:     make(map[string]chan struct{}),
+		waiters:  make(map[string]*waiter),
+		stopCh:   make(chan struct{}),
+		// Claims stay renewed while Stop drains the in-flight jobs.
+		leaseStop: make(chan struct{}),
+	}
+	q.AddLane(DefaultLane, workers)
+	return q
+}
+
+// AddLane adds lane, drained by a pool of workers of its own, or resizes it.
+// It must be called before Start.
+func (q *Queue) AddLane(lane string, workers int) {
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	q.workers[lane] = workers
+	q.wake[lane] = make(chan struct{}, workers)
+}
+
+// Lanes returns the worker pool size of each lane.
+func (q *Queue) Lanes() map[string]int {
+	return maps.Clone(q.workers)
+}
+
+// ParseLanes reads lane pool sizes written "interactive=8,batch=2", as in
+// EXECUTION_LANES.
+func ParseLanes(spec string) (map[string]int, error) {
+	lanes := make(map[string]int)
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, size, ok := strings.Cut(item, "=")
+		name = strings.TrimSpace(name)
+		if !ok || !models.ValidLane(name) {
+			return nil, fmt.Errorf("queue: invalid lane %q: want name=workers", item)
+		}
+		workers, err := strconv.Atoi(strings.TrimSpace(size))
+		if err != nil || workers < 1 {
+			return nil, fmt.Errorf("queue: lane %q: workers must be a positive integer", name)
+		}
+		lanes[name] = workers
+	}
+	return lanes, nil
+}
+
+// Start discards the jobs interrupted by a previous shutdown of this engine or
+// abandoned by a stopped replica, and starts the workers.
+func (q *Queue) Start() {
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	if n, err := q.jobs.DiscardInterrupted(ctx); err != nil {
+		slog.Error("queue: discard interrupted jobs", logging.KeyError, err)
+	} else if n > 0 {
+		slog.Warn("queue: discarded jobs interrupted by the previous shutdown", "count", n)
+	}
+	cancel()
+
+	for _, lane := range slices.Sorted(maps.Keys(q.workers)) {
+		for i := 0; i < q.workers[lane]; i++ {
+			q.wg.Add(1)
+			go q.work(lane)
+		}
+		slog.Info("queue: started execution workers", "lane", lane, "workers", q.workers[lane])
+	}
+	q.leaseWG.Add(1)
+	go q.maintainLeases()
+}
+
+// maintainLeases renews the claims of the jobs this engine runs and discards
+// those abandoned by stopped replicas until the queue is stopped.
+func (q *Queue) maintainLeases() {
+	defer q.leaseWG.Done()
+	ticker := time.NewTicker(leaseInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			q.renewLeases()
+		case <-q.leaseStop:
+			return
+		}
+	}
+}
+
+// renewLeases runs one pass of maintainLeases.
+func (q *Queue) renewLeases() {
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	since := time.Now()
+	if held, err := q.jobs.RenewClaims(ctx); err != nil {
+		slog.Error("queue: renew claims", logging.KeyError, err)
+	} else {
+		q.releaseTakenOver(since, held)
+	}
+	if n, err := q.jobs.DiscardExpired(ctx); err != nil {
+		slog.Error("queue: discard expired jobs", logging.KeyError, err)
+	} else if n > 0 {
+		slog.Warn("queue: discarded jobs abandoned by a stopped engine", "count", n)
+	}
+}
+
+// releaseTakenOver fails the callers waiting on a job enqueued before since
+// that no worker of this engine claimed and that this engine no longer holds:
+// another engine claimed it, so nothing here will report its result.
+func (q *Queue) releaseTakenOver(since time.Time, held []string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for id, w := range q.waiters {
+		if w.started || w.enqueued.IsZero() || !w.enqueued.Before(since) || slices.Contains(held, id) {
+			continue
+		}
+		delete(q.waiters, id)
+		w.done <- result{err: ErrTakenOver}
+		slog.Warn("queue: job run by another engine", "job_id", id)
+	}
+}
+
+// Stop releases blocked callers and waits for in-flight jobs to finish.
+func (q *Queue) Stop() {
+	q.stopOnce.Do(func() {
+		close(q.stopCh)
+		q.wg.Wait()
+		close(q.leaseStop)
+	})
+	q.wg.Wait()
+	q.leaseWG.Wait()
+}
+
+// Execute enqueues a run of process and blocks until a worker has executed it.
+// The priority comes from definition.settings.priority, the lane from
+// definition.settings.lane.
+func (q *Queue) Execute(process *models.Process, triggerData map[string]interface{}) (*models.ExecutionContext, error) {
+	return q.ExecuteContext(context.Background(), process, triggerData)
+}
+
+// ExecuteContext is Execute for a caller waiting on the run, such as an HTTP
+// request: the execution is bound to ctx (see triggers.ExecuteContext). The
+// job is reserved for the workers of this engine, which report its result.
+// When ctx is done before a worker claimed the job, the job is withdrawn and
+// models.ErrExecutionCancelled returned without an execution.
+func (q *Queue) ExecuteContext(ctx context.Context, process *models.Process, triggerData map[string]interface{}) (*models.ExecutionContext, error) {
+	job, err := newJob(process, triggerData)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := q.wake[job.Lane]; !ok {
+		slog.Warn("queue: unknown lane; running in the default lane", logging.KeyProcessID, job.ProcessID, "lane", job.Lane)
+		job.Lane = DefaultLane
+	}
+	w := &waiter{done: make(chan result, 1), ctx: ctx}
+	q.mu.Lock()
+	q.waiters[job.ID] = w
+	q.mu.Unlock()
+	defer func() {
+		q.mu.Lock()
+		delete(q.waiters, job.ID)
+		q.mu.Unlock()
+	}()
+
+	storeCtx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	err = q.jobs.Enqueue(storeCtx, job)
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+	q.mu.Lock()
+	w.enqueued = time.Now()
+	q.mu.Unlock()
+	select {
+	case q.wake[job.Lane] <- struct{}{}:
+	default:
+	}
+
+	select {
+	case res := <-w.done:
+		return res.ctx, res.err
+	case <-q.stopCh:
+		return nil, ErrStopped
+	case <-ctx.Done():
+	}
+
+	// A claimed job runs bound to ctx and stops shortly; one still queued
+	// would only run once nobody waits for it.
+	q.mu.Lock()
+	started := w.started
+	q.mu.Unlock()
+	if !started {
+		storeCtx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+		if err := q.jobs.Remove(storeCtx, job.ID); err != nil {
+			slog.Error("queue: withdraw job", "job_id", job.ID, logging.KeyError, err)
+		}
+		cancel()
+		return nil, fmt.Errorf("queue: %w before a worker was free: %w", models.ErrExecutionCancelled, ctx.Err())
+	}
+	select {
+	case res := <-w.done:
+		return res.ctx, res.err
+	case <-q.stopCh:
+		return nil, ErrStopped
+	}
+}
+
+// newJob snapshots process and triggerData into a pending job.
+func newJob(process *models.Process, triggerData map[string]interface{}) (*store.QueuedJob, error) {
+	proc, err := json.Marshal(process)
+	if err != nil {
+		return nil, fmt.Errorf("queue: marshal process: %w", err)
+	}
+	if triggerData == nil {
+		triggerData = map[string]interface{}{}
+	}
+	data, err := json.Marshal(triggerData)
+	if err != nil {
+		return nil, fmt.Errorf("queue: marshal trigger data: %w", err)
+	}
+	lane := process.Definition.Settings.Lane
+	if lane == "" {
+		lane = DefaultLane
+	}
+	return &store.QueuedJob{
+		ID:          uuid.New().String(),
+		Workspace:   tenant.Normalize(process.Definition.Workspace),
+		ProcessID:   process.Definition.ID,
+		Priority:    process.Definition.Settings.Priority,
+		Lane:        lane,
+		Process:     proc,
+		TriggerData: data,
+	}, nil
+}
+
+// work claims and runs the jobs of lane until the queue is stopped.
+func (q *Queue) work(lane string) {
+	defer q.wg.Done()
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		if q.runNext(lane) {
+			continue
+		}
+		select {
+		case <-q.wake[lane]:
+		case <-ticker.C:
+		case <-q.stopCh:
+			return
+		}
+	}
+}
+
+// runNext executes one claimed job of lane and reports whether there was one.
+func (q *Queue) runNext(lane string) bool {
+	select {
+	case <-q.stopCh:
+		return false
+	default:
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	job, err := q.jobs.Claim(ctx, lane)
+	cancel()
+	if err != nil {
+		slog.Error("queue: claim job", logging.KeyError, err)
+		return false
+	}
+	if job == nil {
+		return false
+	}
+
+	runCtx := context.Background()
+	q.mu.Lock()
+	if w, ok := q.waiters[job.ID]; ok {
+		w.started = true
+		runCtx = w.ctx
+	}
+	q.mu.Unlock()
+	res := q.run(runCtx, job)
+
+	ctx, cancel = context.WithTimeout(context.Background(), storeTimeout)
+	if err := q.jobs.Remove(ctx, job.ID); err != nil {
+		slog.Error("queue: remove job", "job_id", job.ID, logging.KeyError, err)
+	}
+	cancel()
+
+	q.mu.Lock()
+	w, ok := q.waiters[job.ID]
+	q.mu.Unlock()
+	if ok {
+		w.done <- res
+	} else if res.err != nil {
+		// Restored after a restart or left by a stopped replica: nobody is
+		// waiting, so the audit trail is the only record of the outcome.
+		slog.Error("queue: job failed", "job_id", job.ID, logging.KeyWorkspace, job.Workspace, logging.KeyProcessID, job.ProcessID, logging.KeyError, res.err)
+	}
+	return true
+}
+
+// run decodes the job snapshot and executes it bound to ctx.
+func (q *Queue) run(ctx context.Context, job *store.QueuedJob) result {
+	var proc models.Process
+	if err := json.Unmarshal(job.Process, &proc); err != nil {
+		return result{err: fmt.Errorf("queue: decode process snapshot of job %s: %w", job.ID, err)}
+	}
+	triggerData := map[string]interface{}{}
+	if len(job.TriggerData) > 0 {
+		_ = json.Unmarshal(job.TriggerData, &triggerData)
+	}
+	if triggerData == nil {
+		triggerData = map[string]interface{}{}
+	}
+	execCtx, err := triggers.ExecuteContext(ctx, q.executor, &proc, triggerData)
+	return result{ctx: execCtx, err: err}
+}
@@ -0,0 +1,462 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"flowjs-works/engine/internal/models"
+	"flowjs-works/engine/internal/store"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingExecutor struct {
+	mu    sync.Mutex
+	order []string
+	err   error
+}
+
+func (r *recordingExecutor) Execute(p *models.Process, triggerData map[string]interface{}) (*models.ExecutionContext, error) {
+	r.mu.Lock()
+	r.order = append(r.order, p.Definition.ID)
+	r.mu.Unlock()
+	ctx := models.NewExecutionContext("exec-" + p.Definition.ID)
+	ctx.SetTriggerData(triggerData)
+	return ctx, r.err
+}
+
+func process(id string, priority int) *models.Process {
+	return &models.Process{Definition: models.Definition{ID: id, Settings: models.ProcessSettings{Priority: priority}}}
+}
+
+func enqueue(t *testing.T, m *MemoryStore, p *models.Process) {
+	t.Helper()
+	job, err := newJob(p, nil)
+	require.NoError(t, err)
+	require.NoError(t, m.Enqueue(context.Background(), job))
+}
+
+func claimIDs(t *testing.T, m *MemoryStore, n int) []string {
+	t.Helper()
+	var ids []string
+	for i := 0; i < n; i++ {
+		job, err := m.Claim(context.Background(), DefaultLane)
+		require.NoError(t, err)
+		require.NotNil(t, job)
+		ids = append(ids, job.ProcessID)
+	}
+	return ids
+}
+
+func TestMemoryStore_PriorityFirst(t *testing.T) {
+	m := NewMemoryStore()
+	enqueue(t, m, process("batch", -1))
+	enqueue(t, m, process("normal", 0))
+	enqueue(t, m, process("webhook", 10))
+
+	assert.Equal(t, []string{"webhook", "normal", "batch"}, claimIDs(t, m, 3))
+	job, err := m.Claim(context.Background(), DefaultLane)
+	require.NoError(t, err)
+	assert.Nil(t, job)
+}
+
+func TestMemoryStore_FairWithinPriority(t *testing.T) {
+	m := NewMemoryStore()
+	for i := 0; i < 3; i++ {
+		enqueue(t, m, process("bulk", 0))
+	}
+	enqueue(t, m, process("single", 0))
+
+	// With one "bulk" job running, "single" overtakes the remaining bulk backlog.
+	assert.Equal(t, []string{"bulk", "single", "bulk", "bulk"}, claimIDs(t, m, 4))
+}
+
+func TestMemoryStore_RemoveReleasesLoad(t *testing.T) {
+	m := NewMemoryStore()
+	enqueue(t, m, process("a", 0))
+	enqueue(t, m, process("a", 0))
+	enqueue(t, m, process("b", 0))
+
+	first, err := m.Claim(context.Background(), DefaultLane)
+	require.NoError(t, err)
+	require.NoError(t, m.Remove(context.Background(), first.ID))
+
+	// "a" has nothing running any more, so FIFO order applies again.
+	assert.Equal(t, []string{"a", "b"}, claimIDs(t, m, 2))
+}
+
+func TestQueue_ExecuteReturnsResult(t *testing.T) {
+	exec := &recordingExecutor{}
+	q := New(NewMemoryStore(), exec, 2)
+	q.Start()
+	defer q.Stop()
+
+	ctx, err := q.Execute(process("orders", 0), map[string]interface{}{"id": "A-1"})
+	require.NoError(t, err)
+	assert.Equal(t, "exec-orders", ctx.ExecutionID)
+	assert.Equal(t, "A-1", ctx.Trigger["id"])
+}
+
+func TestQueue_ExecutePropagatesError(t *testing.T) {
+	q := New(NewMemoryStore(), &recordingExecutor{err: errors.New("node failed")}, 1)
+	q.Start()
+	defer q.Stop()
+
+	_, err := q.Execute(process("orders", 0), nil)
+	assert.EqualError(t, err, "node failed")
+}
+
+func TestQueue_RunsRestoredJobs(t *testing.T) {
+	m := NewMemoryStore()
+	enqueue(t, m, process("restored", 0))
+	exec := &recordingExecutor{}
+	q := New(m, exec, 1)
+	q.Start()
+	defer q.Stop()
+
+	assert.Eventually(t, func() bool {
+		exec.mu.Lock()
+		defer exec.mu.Unlock()
+		return len(exec.order) == 1
+	}, 3*time.Second, 20*time.Millisecond)
+}
+
+func TestQueue_StopReleasesWaiters(t *testing.T) {
+	// No workers are started, so the job is never claimed.
+	q := New(NewMemoryStore(), &recordingExecutor{}, 1)
+	done := make(chan error, 1)
+	go func() {
+		_, err := q.Execute(process("orders", 0), nil)
+		done <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	q.Stop()
+	assert.ErrorIs(t, <-done, ErrStopped)
+}
+
+func TestQueue_ExecuteContextWithdrawsQueuedJob(t *testing.T) {
+	// No workers are started, so the job is still queued at the deadline.
+	m := NewMemoryStore()
+	q := New(m, &recordingExecutor{}, 1)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	execCtx, err := q.ExecuteContext(ctx, process("orders", 0), nil)
+	assert.Nil(t, execCtx)
+	assert.ErrorIs(t, err, models.ErrExecutionCancelled)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	job, err := m.Claim(context.Background(), DefaultLane)
+	require.NoError(t, err)
+	assert.Nil(t, job, "the job is withdrawn")
+}
+
+func TestNewJob_SnapshotsProcess(t *testing.T) {
+	p := process("orders", 5)
+	p.Definition.Workspace = "team-a"
+	job, err := newJob(p, map[string]interface{}{"k": "v"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, job.ID)
+	assert.Equal(t, "team-a", job.Workspace)
+	assert.Equal(t, 5, job.Priority)
+	assert.Equal(t, DefaultLane, job.Lane)
+	assert.JSONEq(t, `{"k":"v"}`, string(job.TriggerData))
+
+	res := New(NewMemoryStore(), &recordingExecutor{}, 1).run(context.Background(), job)
+	require.NoError(t, res.err)
+	assert.Equal(t, "exec-orders", res.ctx.ExecutionID)
+}
+
+func TestMemoryStore_ClaimsOwnLane(t *testing.T) {
+	m := NewMemoryStore()
+	batch := process("nightly", 10)
+	batch.Definition.Settings.Lane = "batch"
+	enqueue(t, m, batch)
+	enqueue(t, m, process("webhook", 0))
+
+	assert.Equal(t, []string{"webhook"}, claimIDs(t, m, 1), "the default lane skips the batch job despite its priority")
+	job, err := m.Claim(context.Background(), DefaultLane)
+	require.NoError(t, err)
+	assert.Nil(t, job)
+	job, err = m.Claim(context.Background(), "batch")
+	require.NoError(t, err)
+	require.NotNil(t, job)
+	assert.Equal(t, "nightly", job.ProcessID)
+}
+
+// blockingExecutor holds every run of process "slow" until release is closed.
+type blockingExecutor struct {
+	recordingExecutor
+	release chan struct{}
+}
+
+func (b *blockingExecutor) Execute(p *models.Process, triggerData map[string]interface{}) (*models.ExecutionContext, error) {
+	if p.Definition.ID == "slow" {
+		<-b.release
+	}
+	return b.recordingExecutor.Execute(p, triggerData)
+}
+
+// TestQueue_LanesHaveOwnWorkers verifies that a lane whose workers are all
+// busy does not hold up the runs of another lane.
+func TestQueue_LanesHaveOwnWorkers(t *testing.T) {
+	exec := &blockingExecutor{release: make(chan struct{})}
+	q := New(NewMemoryStore(), exec, 1)
+	q.AddLane("batch", 1)
+	assert.Equal(t, map[string]int{DefaultLane: 1, "batch": 1}, q.Lanes())
+	q.Start()
+	defer q.Stop()
+	defer close(exec.release)
+
+	slow := process("slow", 0)
+	slow.Definition.Settings.Lane = "batch"
+	go func() { _, _ = q.Execute(slow, nil) }()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := q.Execute(process("webhook", 0), nil)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(3 * time.Second):
+		t.Fatal("the default lane waited for the batch lane")
+	}
+
+	unknown := process("misrouted", 0)
+	unknown.Definition.Settings.Lane = "reports"
+	_, err := q.Execute(unknown, nil)
+	assert.NoError(t, err, "a lane the queue does not run falls back to the default lane")
+}
+
+func TestParseLanes(t *testing.T) {
+	lanes, err := ParseLanes(" interactive=8, batch=2 ,")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"interactive": 8, "batch": 2}, lanes)
+
+	empty, err := ParseLanes("")
+	require.NoError(t, err)
+	assert.Empty(t, empty)
+
+	for _, spec := range []string{"batch", "batch=0", "batch=x", "Batch=2", "=2"} {
+		_, err := ParseLanes(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+// leaseStore counts the lease maintenance calls made on a MemoryStore.
+type leaseStore struct {
+	*MemoryStore
+	mu       sync.Mutex
+	renewals int
+	sweeps   int
+}
+
+func (l *leaseStore) RenewClaims(ctx context.Context) ([]string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.renewals++
+	return l.MemoryStore.RenewClaims(ctx)
+}
+
+func (l *leaseStore) DiscardExpired(context.Context) (int64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweeps++
+	return 1, nil
+}
+
+func TestQueue_RenewLeases(t *testing.T) {
+	jobs := &leaseStore{MemoryStore: NewMemoryStore()}
+	q := New(jobs, &recordingExecutor{}, 1)
+
+	q.renewLeases()
+
+	assert.Equal(t, 1, jobs.renewals, "claims of running jobs are renewed")
+	assert.Equal(t, 1, jobs.sweeps, "jobs of stopped replicas are discarded")
+}
+
+// sharedJobs is a queue table shared by the engines of a test. Each engine
+// uses it through replica, which applies the reservation rules of
+// store.QueueStore.
+type sharedJobs struct {
+	mu   sync.Mutex
+	jobs []*sharedJob
+}
+
+type sharedJob struct {
+	*store.QueuedJob
+	running bool
+	// owner is the engine running or waiting for the job; expired is set once
+	// it stopped renewing it.
+	owner   string
+	expired bool
+}
+
+func (s *sharedJobs) replica(owner string) JobStore {
+	return &replicaStore{shared: s, owner: owner}
+}
+
+// expire lapses the reservations and leases of owner, as if it had stopped.
+func (s *sharedJobs) expire(owner string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, j := range s.jobs {
+		if j.owner == owner {
+			j.expired = true
+		}
+	}
+}
+
+func (s *sharedJobs) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.jobs)
+}
+
+type replicaStore struct {
+	shared *sharedJobs
+	owner  string
+}
+
+func (r *replicaStore) Enqueue(_ context.Context, job *store.QueuedJob) error {
+	r.shared.mu.Lock()
+	defer r.shared.mu.Unlock()
+	r.shared.jobs = append(r.shared.jobs, &sharedJob{QueuedJob: job, owner: r.owner})
+	return nil
+}
+
+func (r *replicaStore) Claim(_ context.Context, lane string) (*store.QueuedJob, error) {
+	r.shared.mu.Lock()
+	defer r.shared.mu.Unlock()
+	for _, j := range r.shared.jobs {
+		if !j.running && j.Lane == lane && (j.owner == r.owner || j.expired) {
+			j.running, j.owner, j.expired = true, r.owner, false
+			return j.QueuedJob, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *replicaStore) Remove(_ context.Context, id string) error {
+	r.shared.mu.Lock()
+	defer r.shared.mu.Unlock()
+	r.shared.jobs = slices.DeleteFunc(r.shared.jobs, func(j *sharedJob) bool { return j.ID == id })
+	return nil
+}
+
+func (r *replicaStore) RenewClaims(context.Context) ([]string, error) {
+	r.shared.mu.Lock()
+	defer r.shared.mu.Unlock()
+	var ids []string
+	for _, j := range r.shared.jobs {
+		if j.owner == r.owner {
+			j.expired = false
+			ids = append(ids, j.ID)
+		}
+	}
+	return ids, nil
+}
+
+func (r *replicaStore) DiscardInterrupted(context.Context) (int64, error) { return 0, nil }
+func (r *replicaStore) DiscardExpired(context.Context) (int64, error)     { return 0, nil }
+
+// wakeIdle makes the idle workers of q look for a job now.
+func wakeIdle(q *Queue) {
+	select {
+	case q.wake[DefaultLane] <- struct{}{}:
+	default:
+	}
+}
+
+func (r *recordingExecutor) ran() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.order)
+}
+
+// TestQueue_ReplicasRunTheJobsTheyWaitFor verifies that a job someone waits
+// for is not claimed by another engine sharing the queue.
+func TestQueue_ReplicasRunTheJobsTheyWaitFor(t *testing.T) {
+	jobs := &sharedJobs{}
+	execA, execB := &recordingExecutor{}, &recordingExecutor{}
+	a := New(jobs.replica("engine-a"), execA, 1)
+	b := New(jobs.replica("engine-b"), execB, 1)
+	b.Start()
+	defer b.Stop()
+
+	type outcome struct {
+		ctx *models.ExecutionContext
+		err error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		ctx, err := a.Execute(process("orders", 0), nil)
+		done <- outcome{ctx, err}
+	}()
+	require.Eventually(t, func() bool { return jobs.len() == 1 }, time.Second, 5*time.Millisecond)
+	wakeIdle(b)
+	time.Sleep(50 * time.Millisecond)
+	assert.Empty(t, execB.ran(), "the job is reserved for the engine waiting for it")
+
+	a.Start()
+	defer a.Stop()
+	select {
+	case res := <-done:
+		require.NoError(t, res.err)
+		assert.Equal(t, "exec-orders", res.ctx.ExecutionID)
+	case <-time.After(3 * time.Second):
+		t.Fatal("the caller was not woken")
+	}
+	assert.Equal(t, []string{"orders"}, execA.ran())
+	assert.Empty(t, execB.ran())
+}
+
+// TestQueue_WaiterReleasedWhenAnotherReplicaClaims verifies that a caller
+// whose job another engine claimed after the reservation lapsed is released
+// instead of waiting forever.
+func TestQueue_WaiterReleasedWhenAnotherReplicaClaims(t *testing.T) {
+	jobs := &sharedJobs{}
+	execB := &recordingExecutor{}
+	// No workers are started on a, so only b can run the job.
+	a := New(jobs.replica("engine-a"), &recordingExecutor{}, 1)
+	b := New(jobs.replica("engine-b"), execB, 1)
+	b.Start()
+	defer b.Stop()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := a.Execute(process("orders", 0), nil)
+		done <- err
+	}()
+	require.Eventually(t, func() bool { return jobs.len() == 1 }, time.Second, 5*time.Millisecond)
+	jobs.expire("engine-a")
+	wakeIdle(b)
+	require.Eventually(t, func() bool { return jobs.len() == 0 }, 3*time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{"orders"}, execB.ran(), "the non-enqueuing engine ran the job")
+
+	var err error
+	require.Eventually(t, func() bool {
+		a.renewLeases()
+		select {
+		case err = <-done:
+			return true
+		default:
+			return false
+		}
+	}, 3*time.Second, 10*time.Millisecond)
+	assert.ErrorIs(t, err, ErrTakenOver)
+}
+
+func TestQueue_StopWithoutStart(t *testing.T) {
+	q := New(NewMemoryStore(), &recordingExecutor{}, 1)
+	q.Stop()
+	q.Stop()
+}
+
+var _ JobStore = (*store.QueueStore)(nil)
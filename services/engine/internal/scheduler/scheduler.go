@@ -0,0 +1,155 @@
+// Package scheduler executes one-shot scheduled runs ("run process X at time T")
+// persisted in the config database. A single polling loop claims due runs,
+// executes them through the same Executor used by triggers and records the
+// outcome, so pending runs survive engine restarts.
+package scheduler
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"flowjs-works/engine/internal/logging"
+	"flowjs-works/engine/internal/models"
+	"flowjs-works/engine/internal/store"
+	"flowjs-works/engine/internal/tenant"
+	"flowjs-works/engine/internal/triggers"
+)
+
+const (
+	// defaultPollInterval is how often the scheduler looks for due runs.
+	defaultPollInterval = 5 * time.Second
+	// claimBatchSize caps the number of runs claimed per poll.
+	claimBatchSize = 20
+	// storeTimeout bounds every scheduler DB round-trip.
+	storeTimeout = 10 * time.Second
+)
+
+// RunStore is the subset of store.ScheduleStore used by the scheduler.
+type RunStore interface {
+	ClaimDue(ctx context.Context, now time.Time, limit int) ([]store.ScheduledRun, error)
+	Complete(ctx context.Context, runID, executionID, errMsg string) error
+	RenewClaims(ctx context.Context) error
+	FailInterrupted(ctx context.Context) (int64, error)
+	FailExpired(ctx context.Context) (int64, error)
+}
+
+// ProcessLoader returns the process definition for processID in the workspace
+// carried by ctx.
+type ProcessLoader func(ctx context.Context, processID string) (*models.Process, error)
+
+// Scheduler polls RunStore and executes due runs.
+type Scheduler struct {
+	runs     RunStore
+	load     ProcessLoader
+	executor triggers.Executor
+	interval time.Duration
+
+	stopOnce sync.Once
+	stopCh   chan struct{}
+	wg       sync.WaitGroup
+}
+
+// New creates a Scheduler. Call Start to begin polling.
+func New(runs RunStore, load ProcessLoader, executor triggers.Executor) *Scheduler {
+	return &Scheduler{
+		runs:     runs,
+		load:     load,
+		executor: executor,
+		interval: defaultPollInterval,
+		stopCh:   make(chan struct{}),
+	}
+}
+
+// Start fails the runs interrupted by a previous shutdown of this engine or
+// abandoned by a stopped replica, and starts the polling loop.
+func (s *Scheduler) Start() {
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	if n, err := s.runs.FailInterrupted(ctx); err != nil {
+		slog.Error("scheduler: fail interrupted runs", logging.KeyError, err)
+	} else if n > 0 {
+		slog.Warn("scheduler: marked interrupted runs as failed", "count", n)
+	}
+	cancel()
+
+	s.wg.Add(1)
+	go s.loop()
+	slog.Info("scheduler: polling for scheduled runs", "interval", s.interval.String())
+}
+
+// Stop terminates the polling loop and waits for in-flight runs to finish.
+func (s *Scheduler) Stop() {
+	s.stopOnce.Do(func() { close(s.stopCh) })
+	s.wg.Wait()
+}
+
+func (s *Scheduler) loop() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		s.poll(time.Now())
+		select {
+		case <-ticker.C:
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// poll renews the claims of the runs in flight, fails those abandoned by a
+// stopped replica, then claims the runs due at now and starts each in its own
+// goroutine.
+func (s *Scheduler) poll(now time.Time) {
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	if err := s.runs.RenewClaims(ctx); err != nil {
+		slog.Error("scheduler: renew claims", logging.KeyError, err)
+	}
+	if n, err := s.runs.FailExpired(ctx); err != nil {
+		slog.Error("scheduler: fail expired runs", logging.KeyError, err)
+	} else if n > 0 {
+		slog.Warn("scheduler: marked runs abandoned by a stopped engine as failed", "count", n)
+	}
+	due, err := s.runs.ClaimDue(ctx, now, claimBatchSize)
+	if err != nil {
+		slog.Error("scheduler: claim due runs", logging.KeyError, err)
+		return
+	}
+	for i := range due {
+		run := due[i]
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.execute(&run)
+		}()
+	}
+}
+
+// execute runs a single claimed run and records its outcome.
+func (s *Scheduler) execute(run *store.ScheduledRun) {
+	ctx, cancel := context.WithTimeout(tenant.WithWorkspace(context.Background(), run.Workspace), storeTimeout)
+	proc, err := s.load(ctx, run.ProcessID)
+	cancel()
+
+	var executionID, errMsg string
+	if err != nil {
+		errMsg = err.Error()
+	} else {
+		slog.Info("scheduler: executing run", "run_id", run.ID, logging.KeyProcessID, run.ProcessID, "due", run.RunAt.Format(time.RFC3339))
+		execCtx, execErr := s.executor.Execute(proc, run.TriggerDataMap())
+		if execCtx != nil {
+			executionID = execCtx.ExecutionID
+		}
+		if execErr != nil {
+			errMsg = execErr.Error()
+		}
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	if err := s.runs.Complete(ctx, run.ID, executionID, errMsg); err != nil {
+		slog.Error("scheduler: complete run", "run_id", run.ID, logging.KeyError, err)
+	}
+}
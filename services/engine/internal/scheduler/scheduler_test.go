@@ -0,0 +1,169 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"flowjs-works/engine/internal/models"
+	"flowjs-works/engine/internal/store"
+	"flowjs-works/engine/internal/tenant"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeRunStore struct {
+	mu          sync.Mutex
+	pending     []store.ScheduledRun
+	completed   map[string]string // runID → errMsg
+	executions  map[string]string // runID → executionID
+	interrupted int64
+	renewals    int
+	// expiredChecks counts the sweeps for runs of stopped replicas.
+	expiredChecks int
+}
+
+func newFakeRunStore(runs ...store.ScheduledRun) *fakeRunStore {
+	return &fakeRunStore{pending: runs, completed: map[string]string{}, executions: map[string]string{}}
+}
+
+func (f *fakeRunStore) ClaimDue(_ context.Context, now time.Time, limit int) ([]store.ScheduledRun, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var due, rest []store.ScheduledRun
+	for _, r := range f.pending {
+		if !r.RunAt.After(now) && len(due) < limit {
+			due = append(due, r)
+		} else {
+			rest = append(rest, r)
+		}
+	}
+	f.pending = rest
+	return due, nil
+}
+
+func (f *fakeRunStore) Complete(_ context.Context, runID, executionID, errMsg string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.completed[runID] = errMsg
+	f.executions[runID] = executionID
+	return nil
+}
+
+func (f *fakeRunStore) RenewClaims(context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.renewals++
+	return nil
+}
+
+func (f *fakeRunStore) FailInterrupted(context.Context) (int64, error) {
+	return f.interrupted, nil
+}
+
+func (f *fakeRunStore) FailExpired(context.Context) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.expiredChecks++
+	return 0, nil
+}
+
+type recordingExecutor struct {
+	mu    sync.Mutex
+	calls []map[string]interface{}
+	procs []*models.Process
+	err   error
+}
+
+func (e *recordingExecutor) Execute(proc *models.Process, triggerData map[string]interface{}) (*models.ExecutionContext, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.calls = append(e.calls, triggerData)
+	e.procs = append(e.procs, proc)
+	return models.NewExecutionContext("exec-1"), e.err
+}
+
+func loaderFor(procs map[string]*models.Process) ProcessLoader {
+	return func(ctx context.Context, id string) (*models.Process, error) {
+		p, ok := procs[id]
+		if !ok {
+			return nil, errors.New("not found")
+		}
+		cp := *p
+		cp.Definition.Workspace = tenant.Workspace(ctx)
+		return &cp, nil
+	}
+}
+
+func TestPoll_ExecutesOnlyDueRuns(t *testing.T) {
+	now := time.Now()
+	runs := newFakeRunStore(
+		store.ScheduledRun{ID: "r1", ProcessID: "p1", Workspace: "team-a", RunAt: now.Add(-time.Minute), TriggerData: json.RawMessage(`{"x":1}`)},
+		store.ScheduledRun{ID: "r2", ProcessID: "p1", RunAt: now.Add(time.Hour)},
+	)
+	exec := &recordingExecutor{}
+	s := New(runs, loaderFor(map[string]*models.Process{"p1": {Definition: models.Definition{ID: "p1"}}}), exec)
+
+	s.poll(now)
+	s.wg.Wait()
+
+	require.Len(t, exec.calls, 1)
+	assert.Equal(t, float64(1), exec.calls[0]["x"])
+	assert.Equal(t, "team-a", exec.procs[0].Definition.Workspace)
+	assert.Equal(t, "", runs.completed["r1"])
+	assert.Equal(t, "exec-1", runs.executions["r1"])
+	assert.Len(t, runs.pending, 1, "future run must stay pending")
+}
+
+func TestPoll_RecordsExecutionFailure(t *testing.T) {
+	now := time.Now()
+	runs := newFakeRunStore(store.ScheduledRun{ID: "r1", ProcessID: "p1", RunAt: now})
+	exec := &recordingExecutor{err: errors.New("boom")}
+	s := New(runs, loaderFor(map[string]*models.Process{"p1": {}}), exec)
+
+	s.poll(now)
+	s.wg.Wait()
+
+	assert.Equal(t, "boom", runs.completed["r1"])
+}
+
+func TestPoll_MissingProcessFailsRun(t *testing.T) {
+	now := time.Now()
+	runs := newFakeRunStore(store.ScheduledRun{ID: "r1", ProcessID: "gone", RunAt: now})
+	exec := &recordingExecutor{}
+	s := New(runs, loaderFor(nil), exec)
+
+	s.poll(now)
+	s.wg.Wait()
+
+	assert.Empty(t, exec.calls)
+	assert.Equal(t, "not found", runs.completed["r1"])
+}
+
+func TestStartStop(t *testing.T) {
+	runs := newFakeRunStore(store.ScheduledRun{ID: "r1", ProcessID: "p1", RunAt: time.Now().Add(-time.Second)})
+	exec := &recordingExecutor{}
+	s := New(runs, loaderFor(map[string]*models.Process{"p1": {}}), exec)
+
+	s.Start()
+	s.Stop()
+
+	exec.mu.Lock()
+	defer exec.mu.Unlock()
+	assert.Len(t, exec.calls, 1, "the first poll runs immediately on Start")
+}
+
+func TestPoll_RenewsClaimsAndFailsExpiredRuns(t *testing.T) {
+	runs := newFakeRunStore()
+	s := New(runs, loaderFor(nil), &recordingExecutor{})
+
+	s.poll(time.Now())
+	s.poll(time.Now())
+
+	assert.Equal(t, 2, runs.renewals, "claims of in-flight runs are renewed on every poll")
+	assert.Equal(t, 2, runs.expiredChecks, "runs of stopped replicas are failed on every poll")
+}
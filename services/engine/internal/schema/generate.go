@@ -0,0 +1,167 @@
+package schema
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// maxGenerateDepth bounds the nesting Generate follows, so recursive or very
+// deep schemas still produce a value.
+const maxGenerateDepth = 16
+
+// Generate returns a random value that matches s, for simulated trigger
+// payloads. It honours type, const, enum, properties and required (optional
+// properties are included half the time), items with minItems/maxItems,
+// minLength/maxLength, minimum/maximum and the date-time, date, email and uuid
+// formats. pattern is not honoured: generated strings may not match it.
+func Generate(s map[string]interface{}, rng *rand.Rand) interface{} {
+	return generate(s, rng, 0)
+}
+
+func generate(s map[string]interface{}, rng *rand.Rand, depth int) interface{} {
+	if c, ok := s["const"]; ok {
+		return c
+	}
+	if enum, ok := s["enum"].([]interface{}); ok && len(enum) > 0 {
+		return enum[rng.IntN(len(enum))]
+	}
+	switch generatedType(s, rng) {
+	case "object":
+		obj := make(map[string]interface{})
+		if depth >= maxGenerateDepth {
+			return obj
+		}
+		required := make(map[string]bool)
+		if req, ok := s["required"].([]interface{}); ok {
+			for _, r := range req {
+				if name, ok := r.(string); ok {
+					required[name] = true
+				}
+			}
+		}
+		props, _ := s["properties"].(map[string]interface{})
+		for _, name := range sortedKeys(props) {
+			if !required[name] && rng.IntN(2) == 0 {
+				continue
+			}
+			sub, _ := props[name].(map[string]interface{})
+			obj[name] = generate(sub, rng, depth+1)
+		}
+		return obj
+	case "array":
+		if depth >= maxGenerateDepth {
+			return []interface{}{}
+		}
+		lo, hi := bounds(s, "minItems", "maxItems", 0, 3)
+		sub, _ := s["items"].(map[string]interface{})
+		arr := make([]interface{}, lo+rng.IntN(hi-lo+1))
+		for i := range arr {
+			arr[i] = generate(sub, rng, depth+1)
+		}
+		return arr
+	case "string":
+		return generateString(s, rng)
+	case "integer":
+		lo, hi := numberBounds(s)
+		lo, hi = math.Ceil(lo), math.Floor(hi)
+		if hi < lo {
+			return lo
+		}
+		return lo + float64(rng.IntN(int(hi-lo)+1))
+	case "number":
+		lo, hi := numberBounds(s)
+		if hi < lo {
+			return lo
+		}
+		// Two decimals keep the numbers readable in execution logs.
+		return math.Min(hi, math.Round((lo+rng.Float64()*(hi-lo))*100)/100)
+	case "boolean":
+		return rng.IntN(2) == 0
+	}
+	return nil
+}
+
+// generatedType picks one of the types of s, inferring object or array from
+// properties or items when s has no type.
+func generatedType(s map[string]interface{}, rng *rand.Rand) string {
+	ts := types(s)
+	switch {
+	case len(ts) > 0:
+		return ts[rng.IntN(len(ts))]
+	case s["properties"] != nil:
+		return "object"
+	case s["items"] != nil:
+		return "array"
+	}
+	return "string"
+}
+
+const letters = "abcdefghijklmnopqrstuvwxyz"
+
+func generateString(s map[string]interface{}, rng *rand.Rand) string {
+	switch s["format"] {
+	case "date-time":
+		return randomTime(rng).Format(time.RFC3339)
+	case "date":
+		return randomTime(rng).Format(time.DateOnly)
+	case "email":
+		return randomWord(rng, 8) + "@example.com"
+	case "uuid":
+		return fmt.Sprintf("%08x-%04x-4%03x-%04x-%012x",
+			rng.Uint32(), rng.Uint32()&0xffff, rng.Uint32()&0xfff, 0x8000|rng.Uint32()&0x3fff, rng.Uint64()&0xffffffffffff)
+	}
+	lo, hi := bounds(s, "minLength", "maxLength", 1, 12)
+	return randomWord(rng, lo+rng.IntN(hi-lo+1))
+}
+
+func randomWord(rng *rand.Rand, n int) string {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = letters[rng.IntN(len(letters))]
+	}
+	return string(b)
+}
+
+// randomTime returns a time in the year before 2025-01-01, so generated
+// payloads do not depend on the clock.
+func randomTime(rng *rand.Rand) time.Time {
+	end := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	return end.Add(-time.Duration(rng.Int64N(int64(365 * 24 * time.Hour)))).Truncate(time.Second)
+}
+
+// bounds reads an inclusive count range from the keywords min and max of s,
+// defaulting to lo and hi, with the default range widened to reach a
+// minimum above it.
+func bounds(s map[string]interface{}, min, max string, lo, hi int) (int, int) {
+	if n, ok := number(s[min]); ok && n > 0 {
+		lo = int(n)
+		if hi < lo {
+			hi = lo + 3
+		}
+	}
+	if n, ok := number(s[max]); ok && n >= 0 {
+		hi = int(n)
+	}
+	if hi < lo {
+		hi = lo
+	}
+	return lo, hi
+}
+
+// numberBounds reads minimum and maximum, defaulting to a range of 1000 from
+// whichever is set, or to [0, 1000].
+func numberBounds(s map[string]interface{}) (float64, float64) {
+	lo, hasLo := number(s["minimum"])
+	hi, hasHi := number(s["maximum"])
+	switch {
+	case hasLo && !hasHi:
+		hi = lo + 1000
+	case hasHi && !hasLo:
+		lo = hi - 1000
+	case !hasLo && !hasHi:
+		lo, hi = 0, 1000
+	}
+	return lo, hi
+}
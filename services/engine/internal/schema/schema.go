@@ -0,0 +1,270 @@
+// Package schema validates payloads against the JSON Schemas of the schema
+// registry. It implements the subset of JSON Schema used to describe flow
+// payloads: type, properties, required, additionalProperties, items, enum,
+// const, minimum/maximum, minLength/maxLength, pattern and
+// minItems/maxItems. Unknown keywords are ignored. Generate produces sample
+// payloads from the same subset for simulations.
+package schema
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ErrViolation is wrapped by the error returned when a payload does not
+// match its schema.
+var ErrViolation = errors.New("schema violation")
+
+// Source returns a registered schema by name in the workspace carried by
+// ctx. store.SchemaStore implements it on the config DB.
+type Source interface {
+	Schema(ctx context.Context, name string) (map[string]interface{}, error)
+}
+
+// validTypes are the JSON Schema type names.
+var validTypes = map[string]bool{
+	"object": true, "array": true, "string": true, "number": true,
+	"integer": true, "boolean": true, "null": true,
+}
+
+// Check reports the first problem that makes s unusable as a schema: an
+// unknown type, an invalid pattern or a malformed nested schema.
+func Check(s map[string]interface{}) error {
+	return check(s, "$")
+}
+
+func check(s map[string]interface{}, at string) error {
+	for _, t := range types(s) {
+		if !validTypes[t] {
+			return fmt.Errorf("%s: unknown type %q", at, t)
+		}
+	}
+	if p, ok := s["pattern"].(string); ok {
+		if _, err := regexp.Compile(p); err != nil {
+			return fmt.Errorf("%s: invalid pattern: %w", at, err)
+		}
+	}
+	if raw, ok := s["properties"]; ok {
+		props, ok := raw.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: properties must be an object", at)
+		}
+		for name, p := range props {
+			sub, ok := p.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("%s.%s: schema must be an object", at, name)
+			}
+			if err := check(sub, at+"."+name); err != nil {
+				return err
+			}
+		}
+	}
+	if raw, ok := s["items"]; ok {
+		sub, ok := raw.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s[*]: schema must be an object", at)
+		}
+		return check(sub, at+"[*]")
+	}
+	return nil
+}
+
+// Validate checks v against s and returns nil when it matches, or an error
+// wrapping ErrViolation that lists every mismatch by JSONPath.
+func Validate(s map[string]interface{}, v interface{}) error {
+	var problems []string
+	validate(s, v, "$", &problems)
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrViolation, strings.Join(problems, "; "))
+}
+
+func validate(s map[string]interface{}, v interface{}, at string, problems *[]string) {
+	fail := func(format string, args ...interface{}) {
+		*problems = append(*problems, at+": "+fmt.Sprintf(format, args...))
+	}
+
+	if ts := types(s); len(ts) > 0 && !matchesType(ts, v) {
+		fail("expected %s, got %s", strings.Join(ts, " or "), typeOf(v))
+		return
+	}
+	if enum, ok := s["enum"].([]interface{}); ok && !contains(enum, v) {
+		fail("value not in enum")
+	}
+	if c, ok := s["const"]; ok && !equal(c, v) {
+		fail("value must be %v", c)
+	}
+
+	switch val := v.(type) {
+	case map[string]interface{}:
+		props, _ := s["properties"].(map[string]interface{})
+		if req, ok := s["required"].([]interface{}); ok {
+			for _, r := range req {
+				name, _ := r.(string)
+				if _, present := val[name]; !present {
+					fail("missing required property %q", name)
+				}
+			}
+		}
+		for _, name := range sortedKeys(val) {
+			if sub, ok := props[name].(map[string]interface{}); ok {
+				validate(sub, val[name], at+"."+name, problems)
+			} else if s["additionalProperties"] == false {
+				fail("unexpected property %q", name)
+			}
+		}
+	case []interface{}:
+		if n, ok := number(s["minItems"]); ok && float64(len(val)) < n {
+			fail("expected at least %v items", n)
+		}
+		if n, ok := number(s["maxItems"]); ok && float64(len(val)) > n {
+			fail("expected at most %v items", n)
+		}
+		if sub, ok := s["items"].(map[string]interface{}); ok {
+			for i, item := range val {
+				validate(sub, item, fmt.Sprintf("%s[%d]", at, i), problems)
+			}
+		}
+	case string:
+		length := float64(len([]rune(val)))
+		if n, ok := number(s["minLength"]); ok && length < n {
+			fail("expected at least %v characters", n)
+		}
+		if n, ok := number(s["maxLength"]); ok && length > n {
+			fail("expected at most %v characters", n)
+		}
+		if p, ok := s["pattern"].(string); ok {
+			if re, err := regexp.Compile(p); err == nil && !re.MatchString(val) {
+				fail("does not match pattern %q", p)
+			}
+		}
+	default:
+		if f, ok := number(v); ok {
+			if n, ok := number(s["minimum"]); ok && f < n {
+				fail("must be >= %v", n)
+			}
+			if n, ok := number(s["maximum"]); ok && f > n {
+				fail("must be <= %v", n)
+			}
+		}
+	}
+}
+
+// Paths lists the JSONPaths described by s, relative to the payload root, for
+// autocomplete: "$.customer.email", "$.items[*].sku".
+func Paths(s map[string]interface{}) []string {
+	var out []string
+	collectPaths(s, "$", &out)
+	return out
+}
+
+func collectPaths(s map[string]interface{}, at string, out *[]string) {
+	if props, ok := s["properties"].(map[string]interface{}); ok {
+		for _, name := range sortedKeys(props) {
+			path := at + "." + name
+			*out = append(*out, path)
+			if sub, ok := props[name].(map[string]interface{}); ok {
+				collectPaths(sub, path, out)
+			}
+		}
+	}
+	if sub, ok := s["items"].(map[string]interface{}); ok {
+		collectPaths(sub, at+"[*]", out)
+	}
+}
+
+// types returns the type keyword of s, which may be a name or a list.
+func types(s map[string]interface{}) []string {
+	switch t := s["type"].(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		out := make([]string, 0, len(t))
+		for _, v := range t {
+			if name, ok := v.(string); ok {
+				out = append(out, name)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func matchesType(ts []string, v interface{}) bool {
+	actual := typeOf(v)
+	for _, t := range ts {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// typeOf returns the JSON Schema type name of a decoded JSON value.
+func typeOf(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	}
+	if f, ok := number(v); ok {
+		if f == math.Trunc(f) {
+			return "integer"
+		}
+		return "number"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+func contains(list []interface{}, v interface{}) bool {
+	for _, item := range list {
+		if equal(item, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func equal(a, b interface{}) bool {
+	if fa, ok := number(a); ok {
+		fb, ok := number(b)
+		return ok && fa == fb
+	}
+	return fmt.Sprint(a) == fmt.Sprint(b) && typeOf(a) == typeOf(b)
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
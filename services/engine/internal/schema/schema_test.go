@@ -0,0 +1,113 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decode(t *testing.T, s string) map[string]interface{} {
+	t.Helper()
+	var m map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(s), &m))
+	return m
+}
+
+const orderSchema = `{
+	"type": "object",
+	"required": ["id", "items"],
+	"additionalProperties": false,
+	"properties": {
+		"id": {"type": "string", "pattern": "^ord-[0-9]+$"},
+		"status": {"enum": ["new", "paid"]},
+		"items": {"type": "array", "minItems": 1, "items": {
+			"type": "object",
+			"properties": {"sku": {"type": "string"}, "qty": {"type": "integer", "minimum": 1}}
+		}}
+	}
+}`
+
+func TestValidate_Matches(t *testing.T) {
+	s := decode(t, orderSchema)
+	v := decode(t, `{"id": "ord-1", "status": "paid", "items": [{"sku": "A", "qty": 2}]}`)
+	assert.NoError(t, Validate(s, v))
+}
+
+func TestValidate_ListsEveryMismatch(t *testing.T) {
+	s := decode(t, orderSchema)
+	v := decode(t, `{"id": "x", "status": "lost", "items": [{"sku": 5, "qty": 0.5}, {"qty": 0}], "extra": true}`)
+	err := Validate(s, v)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrViolation))
+	for _, want := range []string{
+		`$: unexpected property "extra"`,
+		`$.id: does not match pattern`,
+		`$.status: value not in enum`,
+		`$.items[0].sku: expected string, got integer`,
+		`$.items[0].qty: expected integer, got number`,
+		`$.items[1].qty: must be >= 1`,
+	} {
+		assert.Contains(t, err.Error(), want)
+	}
+
+	err = Validate(s, decode(t, `{"items": []}`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `missing required property "id"`)
+	assert.Contains(t, err.Error(), "$.items: expected at least 1 items")
+}
+
+func TestValidate_TypeList(t *testing.T) {
+	s := decode(t, `{"type": ["string", "null"], "maxLength": 3}`)
+	assert.NoError(t, Validate(s, nil))
+	assert.NoError(t, Validate(s, "abc"))
+	assert.Error(t, Validate(s, "abcd"))
+	assert.Error(t, Validate(s, float64(1)))
+}
+
+func TestCheck(t *testing.T) {
+	assert.NoError(t, Check(decode(t, orderSchema)))
+	assert.ErrorContains(t, Check(decode(t, `{"type": "text"}`)), `unknown type "text"`)
+	assert.ErrorContains(t, Check(decode(t, `{"properties": {"a": {"pattern": "("}}}`)), "$.a: invalid pattern")
+	assert.ErrorContains(t, Check(decode(t, `{"items": true}`)), "schema must be an object")
+}
+
+func TestPaths(t *testing.T) {
+	assert.Equal(t, []string{"$.id", "$.items", "$.items[*].qty", "$.items[*].sku", "$.status"}, Paths(decode(t, orderSchema)))
+	assert.Empty(t, Paths(decode(t, `{"type": "string"}`)))
+}
+
+func TestGenerate_MatchesSchema(t *testing.T) {
+	s := decode(t, `{
+		"type": "object",
+		"required": ["id", "items", "placed_at"],
+		"additionalProperties": false,
+		"properties": {
+			"id": {"type": "string", "format": "uuid"},
+			"email": {"type": "string", "format": "email"},
+			"placed_at": {"type": "string", "format": "date-time"},
+			"status": {"enum": ["new", "paid"]},
+			"channel": {"const": "web"},
+			"note": {"type": ["string", "null"], "minLength": 20, "maxLength": 20},
+			"total": {"type": "number", "minimum": 0.5, "maximum": 1},
+			"items": {"type": "array", "minItems": 1, "maxItems": 4, "items": {
+				"type": "object",
+				"required": ["sku", "qty"],
+				"properties": {"sku": {"type": "string", "maxLength": 3}, "qty": {"type": "integer", "minimum": 1, "maximum": 9}}
+			}}
+		}
+	}`)
+	rng := rand.New(rand.NewPCG(1, 1))
+	for i := 0; i < 50; i++ {
+		v := Generate(s, rng)
+		require.NoError(t, Validate(s, v), "%v", v)
+	}
+
+	// The same seed generates the same values.
+	a := Generate(s, rand.New(rand.NewPCG(7, 7)))
+	b := Generate(s, rand.New(rand.NewPCG(7, 7)))
+	assert.Equal(t, a, b)
+}
@@ -0,0 +1,253 @@
+// Package scratch confines the local files of the engine's file activities
+// (file, sftp, s3, smb, sql streams and mail attachments). Every execution
+// gets a scratch directory of its own, created on first use, limited in size
+// and removed when the execution ends; relative paths resolve inside it, and
+// absolute paths outside it are refused unless they are in an allowed
+// directory.
+package scratch
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrDenied is returned when a local path is outside the scratch directory
+// of the execution and every allowed directory.
+var ErrDenied = errors.New("scratch: local path outside the execution's scratch directory")
+
+// ErrQuota is returned when writing would take an execution's scratch
+// directory past Policy.MaxBytes.
+var ErrQuota = errors.New("scratch: execution exceeds its scratch size limit")
+
+// Policy is where the file activities of an execution may read and write. A
+// nil *Policy leaves local paths unrestricted and relative to the working
+// directory of the engine.
+type Policy struct {
+	// Root holds the scratch directories, one per execution id.
+	Root string
+	// MaxBytes caps the bytes written into the scratch directory of one
+	// execution; zero is unlimited.
+	MaxBytes int64
+	// Allow lists other directories nodes may read and write, with no size
+	// limit.
+	Allow []string
+}
+
+// PolicyFromEnv builds the policy from SCRATCH_ROOT, SCRATCH_MAX_BYTES and
+// SCRATCH_ALLOW_PATHS (comma-separated absolute directories). It returns nil
+// when SCRATCH_ROOT is not set.
+func PolicyFromEnv() (*Policy, error) {
+	root := strings.TrimSpace(os.Getenv("SCRATCH_ROOT"))
+	if root == "" {
+		return nil, nil
+	}
+	p := &Policy{}
+	var err error
+	if p.Root, err = cleanDir(root); err != nil {
+		return nil, fmt.Errorf("scratch: SCRATCH_ROOT: %w", err)
+	}
+	if v := os.Getenv("SCRATCH_MAX_BYTES"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("scratch: SCRATCH_MAX_BYTES: invalid size %q", v)
+		}
+		p.MaxBytes = n
+	}
+	for _, dir := range strings.Split(os.Getenv("SCRATCH_ALLOW_PATHS"), ",") {
+		if dir = strings.TrimSpace(dir); dir == "" {
+			continue
+		}
+		if !filepath.IsAbs(dir) {
+			return nil, fmt.Errorf("scratch: SCRATCH_ALLOW_PATHS: %q is not an absolute path", dir)
+		}
+		clean, err := cleanDir(dir)
+		if err != nil {
+			return nil, fmt.Errorf("scratch: SCRATCH_ALLOW_PATHS: %w", err)
+		}
+		p.Allow = append(p.Allow, clean)
+	}
+	return p, nil
+}
+
+// cleanDir returns dir made absolute with its symlinks resolved.
+func cleanDir(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	return evalExisting(abs)
+}
+
+// current is the policy enforced by Resolve; nil allows everything.
+var current atomic.Pointer[Policy]
+
+// SetPolicy makes p the policy of every local path resolved from now on,
+// creating its root.
+func SetPolicy(p *Policy) error {
+	if p != nil {
+		if err := os.MkdirAll(p.Root, 0o700); err != nil {
+			return fmt.Errorf("scratch: create root: %w", err)
+		}
+	}
+	current.Store(p)
+	return nil
+}
+
+// Current returns the policy set by SetPolicy.
+func Current() *Policy {
+	return current.Load()
+}
+
+// usage holds the bytes written into the scratch directory of each
+// execution since it was created.
+var usage = struct {
+	mu    sync.Mutex
+	bytes map[string]int64
+}{bytes: make(map[string]int64)}
+
+// Dir returns the scratch directory of executionID under p, without
+// creating it.
+func (p *Policy) Dir(executionID string) string {
+	return filepath.Join(p.Root, executionID)
+}
+
+// Resolve returns the local path the nodes of executionID use for path.
+// Under a policy a relative or empty path is inside the execution's scratch
+// directory, which is created, and an absolute one must be inside it or an
+// allowed directory, symlinks resolved; the error wraps ErrDenied otherwise.
+// Without a policy path is returned as it is.
+func Resolve(executionID, path string) (string, error) {
+	p := Current()
+	if p == nil {
+		return path, nil
+	}
+	if executionID == "" || executionID != filepath.Base(executionID) || executionID == ".." {
+		return "", fmt.Errorf("%w: %q (no execution)", ErrDenied, path)
+	}
+	dir := p.Dir(executionID)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("scratch: create directory: %w", err)
+	}
+	abs := path
+	if !filepath.IsAbs(path) {
+		abs = filepath.Join(dir, path)
+	}
+	resolved, err := evalExisting(filepath.Clean(abs))
+	if err != nil {
+		return "", fmt.Errorf("scratch: %q: %w", path, err)
+	}
+	if within(dir, resolved) {
+		return resolved, nil
+	}
+	for _, allowed := range p.Allow {
+		if within(allowed, resolved) {
+			return resolved, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %q", ErrDenied, path)
+}
+
+// evalExisting resolves the symlinks of the longest existing prefix of the
+// absolute path p, so a path to a file not yet created is checked by the
+// directory it will be created in.
+func evalExisting(p string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(p)
+	if err == nil {
+		return resolved, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+	parent := filepath.Dir(p)
+	if parent == p {
+		return p, nil
+	}
+	resolvedParent, err := evalExisting(parent)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(resolvedParent, filepath.Base(p)), nil
+}
+
+// within reports whether p is dir or inside it.
+func within(dir, p string) bool {
+	rel, err := filepath.Rel(dir, p)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// Limit returns w, the writer of the local file path, counting what is
+// written against the MaxBytes of the execution's scratch directory when
+// path is inside it. A write that would exceed it fails with ErrQuota.
+func Limit(executionID, path string, w io.Writer) io.Writer {
+	p := Current()
+	if p == nil || p.MaxBytes <= 0 || executionID == "" || !within(p.Dir(executionID), path) {
+		return w
+	}
+	return &quotaWriter{w: w, executionID: executionID, max: p.MaxBytes}
+}
+
+type quotaWriter struct {
+	w           io.Writer
+	executionID string
+	max         int64
+}
+
+func (q *quotaWriter) Write(b []byte) (int, error) {
+	usage.mu.Lock()
+	if usage.bytes[q.executionID]+int64(len(b)) > q.max {
+		usage.mu.Unlock()
+		return 0, fmt.Errorf("%w (%d bytes)", ErrQuota, q.max)
+	}
+	usage.bytes[q.executionID] += int64(len(b))
+	usage.mu.Unlock()
+	return q.w.Write(b)
+}
+
+// Release removes the scratch directory of executionID and everything in it.
+// The executor calls it when an execution ends.
+func Release(executionID string) {
+	usage.mu.Lock()
+	delete(usage.bytes, executionID)
+	usage.mu.Unlock()
+	p := Current()
+	if p == nil || executionID == "" || executionID != filepath.Base(executionID) {
+		return
+	}
+	_ = os.RemoveAll(p.Dir(executionID))
+}
+
+// RemoveStale removes the scratch directories last modified more than
+// olderThan ago, left behind by executions the engine did not see end
+// (a crash). It returns how many it removed.
+func RemoveStale(olderThan time.Duration) (int, error) {
+	p := Current()
+	if p == nil {
+		return 0, nil
+	}
+	entries, err := os.ReadDir(p.Root)
+	if err != nil {
+		return 0, fmt.Errorf("scratch: read root: %w", err)
+	}
+	removed := 0
+	cutoff := time.Now().Add(-olderThan)
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil || !e.IsDir() || info.ModTime().After(cutoff) {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(p.Root, e.Name())); err != nil {
+			return removed, fmt.Errorf("scratch: remove %s: %w", e.Name(), err)
+		}
+		removed++
+	}
+	return removed, nil
+}
@@ -0,0 +1,137 @@
+package scratch
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setTestPolicy installs p for the test and removes it afterwards.
+func setTestPolicy(t *testing.T, p *Policy) {
+	t.Helper()
+	require.NoError(t, SetPolicy(p))
+	t.Cleanup(func() { _ = SetPolicy(nil) })
+}
+
+func TestPolicyFromEnv(t *testing.T) {
+	p, err := PolicyFromEnv()
+	require.NoError(t, err)
+	assert.Nil(t, p)
+
+	root, allowed := t.TempDir(), t.TempDir()
+	t.Setenv("SCRATCH_ROOT", root)
+	t.Setenv("SCRATCH_MAX_BYTES", "1048576")
+	t.Setenv("SCRATCH_ALLOW_PATHS", " "+allowed+", ")
+	p, err = PolicyFromEnv()
+	require.NoError(t, err)
+	wantRoot, _ := filepath.EvalSymlinks(root)
+	wantAllowed, _ := filepath.EvalSymlinks(allowed)
+	assert.Equal(t, &Policy{Root: wantRoot, MaxBytes: 1 << 20, Allow: []string{wantAllowed}}, p)
+
+	for name, env := range map[string][2]string{
+		"bad size":      {"SCRATCH_MAX_BYTES", "1MB"},
+		"negative size": {"SCRATCH_MAX_BYTES", "-1"},
+		"relative path": {"SCRATCH_ALLOW_PATHS", "data/in"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(env[0], env[1])
+			_, err := PolicyFromEnv()
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	got, err := Resolve("exec-1", "data/out.csv")
+	require.NoError(t, err)
+	assert.Equal(t, "data/out.csv", got, "no policy leaves paths as they are")
+
+	root, _ := filepath.EvalSymlinks(t.TempDir())
+	allowed, _ := filepath.EvalSymlinks(t.TempDir())
+	outside, _ := filepath.EvalSymlinks(t.TempDir())
+	setTestPolicy(t, &Policy{Root: root, Allow: []string{allowed}})
+	dir := filepath.Join(root, "exec-1")
+
+	got, err = Resolve("exec-1", "data/out.csv")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "data", "out.csv"), got)
+	assert.DirExists(t, dir)
+
+	got, err = Resolve("exec-1", ".")
+	require.NoError(t, err)
+	assert.Equal(t, dir, got)
+
+	got, err = Resolve("exec-1", filepath.Join(allowed, "in.csv"))
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(allowed, "in.csv"), got)
+
+	for name, path := range map[string]string{
+		"absolute":        filepath.Join(outside, "x"),
+		"parent":          "../exec-2/x",
+		"allowed sibling": allowed + "-other/x",
+	} {
+		_, err := Resolve("exec-1", path)
+		assert.ErrorIs(t, err, ErrDenied, name)
+	}
+	_, err = Resolve("", "x")
+	assert.ErrorIs(t, err, ErrDenied)
+	_, err = Resolve("..", "x")
+	assert.ErrorIs(t, err, ErrDenied)
+
+	// A symlink in the scratch directory does not lead out of it.
+	require.NoError(t, os.Symlink(outside, filepath.Join(dir, "link")))
+	_, err = Resolve("exec-1", "link/x")
+	assert.ErrorIs(t, err, ErrDenied)
+}
+
+func TestLimitAndRelease(t *testing.T) {
+	root, _ := filepath.EvalSymlinks(t.TempDir())
+	setTestPolicy(t, &Policy{Root: root, MaxBytes: 10})
+
+	path, err := Resolve("exec-1", "a.txt")
+	require.NoError(t, err)
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	w := Limit("exec-1", path, f)
+	_, err = w.Write([]byte("123456"))
+	require.NoError(t, err)
+	_, err = w.Write([]byte("789012"))
+	assert.ErrorIs(t, err, ErrQuota)
+	require.NoError(t, f.Close())
+
+	var sb strings.Builder
+	_, err = Limit("exec-1", filepath.Join(t.TempDir(), "b.txt"), &sb).Write([]byte("outside the scratch directory"))
+	assert.NoError(t, err)
+
+	Release("exec-1")
+	assert.NoDirExists(t, filepath.Join(root, "exec-1"))
+
+	// Usage starts over with a new directory.
+	path, err = Resolve("exec-1", "a.txt")
+	require.NoError(t, err)
+	_, err = Limit("exec-1", path, &sb).Write([]byte("123456"))
+	assert.NoError(t, err)
+	Release("exec-1")
+}
+
+func TestRemoveStale(t *testing.T) {
+	root, _ := filepath.EvalSymlinks(t.TempDir())
+	setTestPolicy(t, &Policy{Root: root})
+	_, err := Resolve("old", "x")
+	require.NoError(t, err)
+	_, err = Resolve("new", "x")
+	require.NoError(t, err)
+	past := time.Now().Add(-48 * time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(root, "old"), past, past))
+
+	n, err := RemoveStale(24 * time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.NoDirExists(t, filepath.Join(root, "old"))
+	assert.DirExists(t, filepath.Join(root, "new"))
+}
@@ -0,0 +1,151 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"flowjs-works/engine/internal/tenant"
+)
+
+// Audit actions recorded in the secrets_audit table.
+const (
+	AuditActionResolve = "resolve"
+	AuditActionUpdate  = "update"
+	AuditActionDelete  = "delete"
+	// AuditActionReveal records a request for the full value through the API,
+	// including attempts rejected for lacking the admin role.
+	AuditActionReveal = "reveal"
+)
+
+// AuditEvent is one row of the secrets_audit table. It records who used or
+// changed a secret and on behalf of which execution — never the value.
+type AuditEvent struct {
+	ID          int64     `json:"id"`
+	SecretID    string    `json:"secret_id"`
+	Action      string    `json:"action"`
+	Actor       string    `json:"actor"`
+	ExecutionID string    `json:"execution_id,omitempty"`
+	ProcessID   string    `json:"process_id,omitempty"`
+	NodeID      string    `json:"node_id,omitempty"`
+	Success     bool      `json:"success"`
+	Error       string    `json:"error,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// AuditLog persists AuditEvents in the config DB.
+type AuditLog struct {
+	db SecretDB
+}
+
+// NewAuditLog creates an AuditLog backed by db.
+func NewAuditLog(db SecretDB) *AuditLog {
+	return &AuditLog{db: db}
+}
+
+// Record stores ev in the workspace carried by ctx. The actor defaults to the
+// subject of the context principal.
+func (a *AuditLog) Record(ctx context.Context, ev AuditEvent) error {
+	if ev.Actor == "" {
+		if p, ok := tenant.PrincipalFromContext(ctx); ok {
+			ev.Actor = p.Subject
+		}
+	}
+	_, err := a.db.ExecContext(ctx, `
+		INSERT INTO secrets_audit (workspace, secret_id, action, actor, execution_id, process_id, node_id, success, error)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), NULLIF($7, ''), $8, NULLIF($9, ''))
+	`, tenant.Workspace(ctx), ev.SecretID, ev.Action, ev.Actor, ev.ExecutionID, ev.ProcessID, ev.NodeID, ev.Success, ev.Error)
+	if err != nil {
+		return fmt.Errorf("secrets: record audit for %s: %w", ev.SecretID, err)
+	}
+	return nil
+}
+
+// List returns the most recent events for secretID in the workspace carried
+// by ctx, newest first.
+func (a *AuditLog) List(ctx context.Context, secretID string, limit int) ([]AuditEvent, error) {
+	rows, err := a.db.QueryContext(ctx, `
+		SELECT id, secret_id, action, actor, COALESCE(execution_id, ''), COALESCE(process_id, ''),
+		       COALESCE(node_id, ''), success, COALESCE(error, ''), created_at
+		FROM secrets_audit WHERE workspace = $1 AND secret_id = $2
+		ORDER BY created_at DESC, id DESC LIMIT $3
+	`, tenant.Workspace(ctx), secretID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("secrets: list audit for %s: %w", secretID, err)
+	}
+	defer rows.Close()
+
+	var events []AuditEvent
+	for rows.Next() {
+		var ev AuditEvent
+		if err := rows.Scan(&ev.ID, &ev.SecretID, &ev.Action, &ev.Actor, &ev.ExecutionID, &ev.ProcessID,
+			&ev.NodeID, &ev.Success, &ev.Error, &ev.CreatedAt); err != nil {
+			return nil, fmt.Errorf("secrets: scan audit row: %w", err)
+		}
+		events = append(events, ev)
+	}
+	return events, rows.Err()
+}
+
+// Usage identifies the node execution a secret is resolved for.
+type Usage struct {
+	ExecutionID string
+	ProcessID   string
+	NodeID      string
+}
+
+type usageKey struct{}
+
+// WithUsage returns a copy of ctx recording that secrets resolved with it are
+// used by u. The executor sets it before calling SecretResolver.Resolve.
+func WithUsage(ctx context.Context, u Usage) context.Context {
+	return context.WithValue(ctx, usageKey{}, u)
+}
+
+// UsageFromContext returns the Usage stored in ctx, if any.
+func UsageFromContext(ctx context.Context) (Usage, bool) {
+	u, ok := ctx.Value(usageKey{}).(Usage)
+	return u, ok
+}
+
+// AuditRecorder is the subset of AuditLog used by AuditingResolver.
+type AuditRecorder interface {
+	Record(ctx context.Context, ev AuditEvent) error
+}
+
+// AuditingResolver wraps a SecretResolver and records every resolution,
+// successful or not, with the Usage carried by the context. A failure to
+// record is reported through onError and never fails the resolution.
+type AuditingResolver struct {
+	next    SecretResolver
+	audit   AuditRecorder
+	onError func(error)
+}
+
+// NewAuditingResolver returns a resolver that delegates to next and records
+// resolutions in audit. onError may be nil.
+func NewAuditingResolver(next SecretResolver, audit AuditRecorder, onError func(error)) *AuditingResolver {
+	return &AuditingResolver{next: next, audit: audit, onError: onError}
+}
+
+// Resolve implements SecretResolver.
+func (r *AuditingResolver) Resolve(ctx context.Context, ref string) (map[string]interface{}, error) {
+	data, err := r.next.Resolve(ctx, ref)
+
+	u, _ := UsageFromContext(ctx)
+	ev := AuditEvent{
+		SecretID:    ref,
+		Action:      AuditActionResolve,
+		ExecutionID: u.ExecutionID,
+		ProcessID:   u.ProcessID,
+		NodeID:      u.NodeID,
+		Success:     err == nil,
+	}
+	if err != nil {
+		ev.Error = err.Error()
+	}
+	if recErr := r.audit.Record(ctx, ev); recErr != nil && r.onError != nil {
+		r.onError(recErr)
+	}
+	return data, err
+}
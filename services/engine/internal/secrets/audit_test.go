@@ -0,0 +1,100 @@
+package secrets
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"flowjs-works/engine/internal/tenant"
+)
+
+// captureDB records the arguments of every ExecContext call.
+type captureDB struct {
+	execArgs [][]interface{}
+	err      error
+}
+
+func (c *captureDB) ExecContext(_ context.Context, _ string, args ...interface{}) (sql.Result, error) {
+	c.execArgs = append(c.execArgs, args)
+	return driver.RowsAffected(1), c.err
+}
+
+func (c *captureDB) QueryContext(_ context.Context, _ string, _ ...interface{}) (*sql.Rows, error) {
+	return nil, errors.New("not implemented")
+}
+
+func TestAuditLog_Record_DefaultsActorAndWorkspace(t *testing.T) {
+	db := &captureDB{}
+	ctx := tenant.WithPrincipal(context.Background(), tenant.Principal{Subject: "alice", Workspace: "team-a"})
+
+	err := NewAuditLog(db).Record(ctx, AuditEvent{SecretID: "sec_pg", Action: AuditActionUpdate, Success: true})
+	require.NoError(t, err)
+	require.Len(t, db.execArgs, 1)
+	args := db.execArgs[0]
+	assert.Equal(t, "team-a", args[0])
+	assert.Equal(t, "sec_pg", args[1])
+	assert.Equal(t, AuditActionUpdate, args[2])
+	assert.Equal(t, "alice", args[3])
+}
+
+type stubResolver struct {
+	gotCtx context.Context
+	err    error
+}
+
+func (s *stubResolver) Resolve(ctx context.Context, _ string) (map[string]interface{}, error) {
+	s.gotCtx = ctx
+	if s.err != nil {
+		return nil, s.err
+	}
+	return map[string]interface{}{"password": "hunter2"}, nil
+}
+
+type recorder struct {
+	events []AuditEvent
+	err    error
+}
+
+func (r *recorder) Record(_ context.Context, ev AuditEvent) error {
+	r.events = append(r.events, ev)
+	return r.err
+}
+
+func TestAuditingResolver_RecordsUsage(t *testing.T) {
+	rec := &recorder{}
+	r := NewAuditingResolver(&stubResolver{}, rec, nil)
+	ctx := WithUsage(context.Background(), Usage{ExecutionID: "exec-1", ProcessID: "p1", NodeID: "query"})
+
+	data, err := r.Resolve(ctx, "sec_pg")
+	require.NoError(t, err)
+	assert.Equal(t, "hunter2", data["password"])
+
+	require.Len(t, rec.events, 1)
+	ev := rec.events[0]
+	assert.Equal(t, AuditEvent{SecretID: "sec_pg", Action: AuditActionResolve, ExecutionID: "exec-1", ProcessID: "p1", NodeID: "query", Success: true}, ev)
+}
+
+func TestAuditingResolver_RecordsFailure(t *testing.T) {
+	rec := &recorder{}
+	r := NewAuditingResolver(&stubResolver{err: errors.New("secrets: secret not found: sec_pg")}, rec, nil)
+
+	_, err := r.Resolve(context.Background(), "sec_pg")
+	require.Error(t, err)
+	require.Len(t, rec.events, 1)
+	assert.False(t, rec.events[0].Success)
+	assert.Contains(t, rec.events[0].Error, "not found")
+}
+
+func TestAuditingResolver_RecordErrorDoesNotFailResolve(t *testing.T) {
+	var reported error
+	r := NewAuditingResolver(&stubResolver{}, &recorder{err: assert.AnError}, func(err error) { reported = err })
+
+	_, err := r.Resolve(context.Background(), "sec_pg")
+	require.NoError(t, err)
+	assert.ErrorIs(t, reported, assert.AnError)
+}
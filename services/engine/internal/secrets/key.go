@@ -0,0 +1,103 @@
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// devKey is the insecure AES key used when APP_ENV=development and no key is
+// configured, so a local engine and runner agree without any setup.
+const devKey = "flowjs-dev-key-00000000000000000"
+
+// KeyFromEnv reads the 32-byte AES key from the environment variable envKey
+// (longer values are truncated). When it is absent or too short the
+// development key is returned with insecure set if APP_ENV=development, and
+// an error otherwise.
+func KeyFromEnv(envKey string) (key []byte, insecure bool, err error) {
+	v := os.Getenv(envKey)
+	if len(v) >= 32 {
+		return []byte(v[:32]), false, nil
+	}
+	if os.Getenv("APP_ENV") != "development" {
+		if v == "" {
+			return nil, false, fmt.Errorf("secrets: %s is not set", envKey)
+		}
+		return nil, false, errors.New("secrets: " + envKey + " must be at least 32 bytes")
+	}
+	return []byte(devKey), true, nil
+}
+
+// DefaultKeyID is the id of the key set in SECRETS_AES_KEY when
+// SECRETS_AES_KEY_ID is not set. Secrets stored before keys were versioned
+// carry it.
+const DefaultKeyID = "v1"
+
+// Keyring holds the AES keys secrets may be encrypted with: the primary key,
+// used for every new value, and previous keys still needed to read values
+// that have not been re-encrypted yet (see SecretStore.ReEncrypt).
+type Keyring struct {
+	primary string
+	keys    map[string][]byte
+}
+
+// NewKeyring returns a keyring whose primary key is key, identified by id.
+func NewKeyring(id string, key []byte) (*Keyring, error) {
+	k := &Keyring{primary: id, keys: make(map[string][]byte)}
+	if err := k.Add(id, key); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// Add registers a previous key under id. It is an error to reuse an id.
+func (k *Keyring) Add(id string, key []byte) error {
+	if id == "" || len(id) > 32 {
+		return fmt.Errorf("secrets: key id must be 1 to 32 characters, got %q", id)
+	}
+	if len(key) != 32 {
+		return fmt.Errorf("secrets: AES key %s must be exactly 32 bytes, got %d", id, len(key))
+	}
+	if _, dup := k.keys[id]; dup {
+		return fmt.Errorf("secrets: duplicate key id %q", id)
+	}
+	k.keys[id] = key
+	return nil
+}
+
+// PrimaryID returns the id of the key new values are encrypted with.
+func (k *Keyring) PrimaryID() string { return k.primary }
+
+// KeyringFromEnv builds the keyring from the environment: the primary key is
+// read by KeyFromEnv(envKey) and identified by envKey+"_ID" (default
+// DefaultKeyID); envKey+"_PREVIOUS" lists the retired keys as comma-separated
+// id:key pairs, e.g. SECRETS_AES_KEY_PREVIOUS="v1:<32 bytes>".
+func KeyringFromEnv(envKey string) (ring *Keyring, insecure bool, err error) {
+	key, insecure, err := KeyFromEnv(envKey)
+	if err != nil {
+		return nil, false, err
+	}
+	id := os.Getenv(envKey + "_ID")
+	if id == "" {
+		id = DefaultKeyID
+	}
+	ring, err = NewKeyring(id, key)
+	if err != nil {
+		return nil, false, err
+	}
+	for _, entry := range strings.Split(os.Getenv(envKey+"_PREVIOUS"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		prevID, prevKey, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, false, fmt.Errorf("secrets: %s_PREVIOUS entries must be id:key", envKey)
+		}
+		if err := ring.Add(prevID, []byte(prevKey)); err != nil {
+			return nil, false, err
+		}
+	}
+	return ring, insecure, nil
+}
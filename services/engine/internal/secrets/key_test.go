@@ -0,0 +1,49 @@
+package secrets
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyFromEnv(t *testing.T) {
+	t.Setenv("APP_ENV", "production")
+	t.Setenv("TEST_AES_KEY", "0123456789abcdef0123456789abcdef-extra")
+	key, insecure, err := KeyFromEnv("TEST_AES_KEY")
+	require.NoError(t, err)
+	assert.False(t, insecure)
+	assert.Equal(t, "0123456789abcdef0123456789abcdef", string(key))
+
+	t.Setenv("TEST_AES_KEY", "short")
+	_, _, err = KeyFromEnv("TEST_AES_KEY")
+	assert.ErrorContains(t, err, "at least 32 bytes")
+
+	t.Setenv("APP_ENV", "development")
+	key, insecure, err = KeyFromEnv("TEST_AES_KEY")
+	require.NoError(t, err)
+	assert.True(t, insecure)
+	assert.Len(t, key, 32)
+}
+
+func TestKeyringFromEnv(t *testing.T) {
+	t.Setenv("APP_ENV", "production")
+	t.Setenv("TEST_AES_KEY", "fedcba9876543210fedcba9876543210")
+	t.Setenv("TEST_AES_KEY_ID", "v2")
+	t.Setenv("TEST_AES_KEY_PREVIOUS", "v1:0123456789abcdef0123456789abcdef")
+	ring, insecure, err := KeyringFromEnv("TEST_AES_KEY")
+	require.NoError(t, err)
+	assert.False(t, insecure)
+	assert.Equal(t, "v2", ring.PrimaryID())
+	assert.Equal(t, "0123456789abcdef0123456789abcdef", string(ring.keys["v1"]))
+
+	t.Setenv("TEST_AES_KEY_ID", "")
+	t.Setenv("TEST_AES_KEY_PREVIOUS", "")
+	ring, _, err = KeyringFromEnv("TEST_AES_KEY")
+	require.NoError(t, err)
+	assert.Equal(t, DefaultKeyID, ring.PrimaryID())
+
+	t.Setenv("TEST_AES_KEY_PREVIOUS", "not-a-pair")
+	_, _, err = KeyringFromEnv("TEST_AES_KEY")
+	assert.ErrorContains(t, err, "id:key")
+}
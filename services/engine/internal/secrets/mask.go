@@ -0,0 +1,41 @@
+package secrets
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maskVisibleChars is the number of leading and trailing characters MaskString
+// keeps, so operators can tell values apart without seeing them.
+const maskVisibleChars = 2
+
+// MaskValue returns a copy of a secret value with every field masked by
+// MaskString. Nested objects keep their keys; other values are masked in their
+// string form, so the preview shows which fields a secret holds but never
+// enough of a value to use it.
+func MaskValue(value map[string]interface{}) map[string]interface{} {
+	masked := make(map[string]interface{}, len(value))
+	for k, v := range value {
+		switch val := v.(type) {
+		case map[string]interface{}:
+			masked[k] = MaskValue(val)
+		case nil:
+			masked[k] = nil
+		case string:
+			masked[k] = MaskString(val)
+		default:
+			masked[k] = MaskString(fmt.Sprint(val))
+		}
+	}
+	return masked
+}
+
+// MaskString keeps the first and last two characters of s and replaces the
+// rest with "*". Values too short to keep anything hidden are fully masked.
+func MaskString(s string) string {
+	r := []rune(s)
+	if len(r) <= 3*maskVisibleChars {
+		return strings.Repeat("*", len(r))
+	}
+	return string(r[:maskVisibleChars]) + strings.Repeat("*", len(r)-2*maskVisibleChars) + string(r[len(r)-maskVisibleChars:])
+}
@@ -0,0 +1,32 @@
+package secrets
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaskString(t *testing.T) {
+	assert.Equal(t, "", MaskString(""))
+	assert.Equal(t, "******", MaskString("abcdef"))
+	assert.Equal(t, "s3*****23", MaskString("s3cret123"))
+	assert.Equal(t, "ñá***éü", MaskString("ñáxyzéü"))
+}
+
+func TestMaskValue_KeepsKeysAndHidesValues(t *testing.T) {
+	masked := MaskValue(map[string]interface{}{
+		"user":     "admin",
+		"password": "correct-horse",
+		"port":     float64(5432),
+		"tls":      map[string]interface{}{"cert": "-----BEGIN CERT-----"},
+		"note":     nil,
+	})
+
+	assert.Equal(t, map[string]interface{}{
+		"user":     "*****",
+		"password": "co*********se",
+		"port":     "****",
+		"tls":      map[string]interface{}{"cert": "--****************--"},
+		"note":     nil,
+	}, masked)
+}
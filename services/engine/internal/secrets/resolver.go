@@ -2,7 +2,14 @@
 // used to inject credentials into node configs at execution time.
 package secrets
 
-import "context"
+import (
+	"context"
+	"errors"
+)
+
+// ErrNotAllowed is returned when a node references a secret that is not in
+// the settings.secrets_allowed of its process.
+var ErrNotAllowed = errors.New("secrets: secret not allowed by process policy")
 
 // SecretResolver resolves a named secret reference to a map of key/value pairs
 // that are merged into the node config before execution.
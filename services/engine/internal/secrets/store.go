@@ -9,9 +9,12 @@ import (
 	"crypto/rand"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"time"
+
+	"flowjs-works/engine/internal/tenant"
 )
 
 // SecretType enumerates supported credential categories.
@@ -20,19 +23,28 @@ type SecretType string
 const (
 	SecretTypeBasicAuth        SecretType = "basic_auth"
 	SecretTypeToken            SecretType = "token"
-	SecretTypeCertificate      SecretType = "certificate"
 	SecretTypeConnectionString SecretType = "connection_string"
+	// SecretTypeCertificate is used for HTTP nodes calling mutual-TLS endpoints.
+	// Fields: cert, key (PEM), ca (optional PEM CA bundle).
+	SecretTypeCertificate SecretType = "certificate"
 	// SecretTypeAWSCredentials is used for S3 nodes.
 	// Fields: access_key_id, secret_access_key, session_token (optional).
 	SecretTypeAWSCredentials SecretType = "aws_credentials"
-	// SecretTypeSSHKey is used for SFTP nodes with private-key authentication.
+	// SecretTypeSSHKey is used for SFTP nodes with private-key authentication
+	// and for Snowflake key-pair authentication in SQL nodes.
 	// Fields: user, private_key.
 	SecretTypeSSHKey SecretType = "ssh_key"
 	// SecretTypeAMQPURL is used for RabbitMQ nodes.
 	// Fields: url_amqp (full AMQP URL including credentials).
 	SecretTypeAMQPURL SecretType = "amqp_url"
+	// SecretTypeServiceAccount is used for BigQuery SQL nodes.
+	// Fields: service_account (the JSON key of a Google service account).
+	SecretTypeServiceAccount SecretType = "service_account"
 )
 
+// ErrNotFound is returned when a secret does not exist in the caller's workspace.
+var ErrNotFound = errors.New("secrets: secret not found")
+
 // SecretMeta contains non-sensitive metadata returned by List.
 type SecretMeta struct {
 	ID        string     `json:"id"`
@@ -42,6 +54,14 @@ type SecretMeta struct {
 	UpdatedAt time.Time  `json:"updated_at"`
 }
 
+// SecretView is a secret with its value, returned by GET /api/v1/secrets/{id}.
+// Value is masked (see MaskValue) unless Masked is false.
+type SecretView struct {
+	SecretMeta
+	Value  map[string]interface{} `json:"value"`
+	Masked bool                   `json:"masked"`
+}
+
 // SecretInput is the payload used to create or update a secret.
 type SecretInput struct {
 	ID       string                 `json:"id"`
@@ -54,8 +74,8 @@ type SecretInput struct {
 // SecretStore persists secrets encrypted with AES-256-GCM and exposes
 // CRUD operations plus the SecretResolver interface for the engine.
 type SecretStore struct {
-	db  SecretDB
-	key []byte // 32-byte AES-256 key
+	db   SecretDB
+	keys *Keyring
 }
 
 // SecretDB is the minimal DB interface required by SecretStore (allows mocking).
@@ -65,20 +85,37 @@ type SecretDB interface {
 }
 
 // NewSecretStore creates a SecretStore backed by the provided DB connection and
-// 32-byte AES-256 key. Returns an error if the key length is wrong.
+// 32-byte AES-256 key, identified as DefaultKeyID. Returns an error if the key
+// length is wrong.
 func NewSecretStore(db SecretDB, key []byte) (*SecretStore, error) {
 	if len(key) != 32 {
 		return nil, fmt.Errorf("secrets: AES key must be exactly 32 bytes, got %d", len(key))
 	}
-	return &SecretStore{db: db, key: key}, nil
+	ring, err := NewKeyring(DefaultKeyID, key)
+	if err != nil {
+		return nil, err
+	}
+	return NewSecretStoreWithKeyring(db, ring), nil
 }
 
+// NewSecretStoreWithKeyring creates a SecretStore that encrypts with the
+// primary key of ring and decrypts with whichever key a value was stored
+// with.
+func NewSecretStoreWithKeyring(db SecretDB, ring *Keyring) *SecretStore {
+	return &SecretStore{db: db, keys: ring}
+}
+
+// KeyID returns the id of the key new values are encrypted with.
+func (s *SecretStore) KeyID() string { return s.keys.PrimaryID() }
+
 // ---------------------------------------------------------------------------
 // CRUD operations
 // ---------------------------------------------------------------------------
 
-// Upsert creates or updates a secret. The value is AES-256-GCM encrypted before
-// being stored. Secrets must never appear in audit logs.
+// Upsert creates or updates a secret in the workspace carried by ctx (see
+// tenant.Workspace). The value is AES-256-GCM encrypted before being stored.
+// An id already owned by another workspace is rejected. Secrets must never
+// appear in audit logs.
 func (s *SecretStore) Upsert(ctx context.Context, input SecretInput) error {
 	if input.ID == "" {
 		return fmt.Errorf("secrets: id is required")
@@ -102,26 +139,42 @@ func (s *SecretStore) Upsert(ctx context.Context, input SecretInput) error {
 		return fmt.Errorf("secrets: marshal metadata: %w", err)
 	}
 
-	_, err = s.db.ExecContext(ctx, `
-		INSERT INTO secrets (id, name, type, encrypted_val, metadata, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+	result, err := s.db.ExecContext(ctx, `
+		INSERT INTO secrets (id, workspace, name, type, encrypted_val, metadata, key_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
 		ON CONFLICT (id) DO UPDATE
 		  SET name          = EXCLUDED.name,
 		      type          = EXCLUDED.type,
 		      encrypted_val = EXCLUDED.encrypted_val,
 		      metadata      = EXCLUDED.metadata,
+		      key_id        = EXCLUDED.key_id,
 		      updated_at    = NOW()
-	`, input.ID, input.Name, string(input.Type), ciphertext, string(metaJSON))
+		  WHERE secrets.workspace = EXCLUDED.workspace
+	`, input.ID, tenant.Workspace(ctx), input.Name, string(input.Type), ciphertext, string(metaJSON), s.keys.PrimaryID())
 	if err != nil {
 		return fmt.Errorf("secrets: upsert %s: %w", input.ID, err)
 	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("secrets: id %s is already in use", input.ID)
+	}
 	return nil
 }
 
-// List returns metadata for all secrets; the encrypted value is never exposed.
+// Ping checks that the secrets table is reachable. It reads no secret.
+func (s *SecretStore) Ping(ctx context.Context) error {
+	rows, err := s.db.QueryContext(ctx, `SELECT 1 FROM secrets LIMIT 1`)
+	if err != nil {
+		return fmt.Errorf("secrets: ping: %w", err)
+	}
+	return rows.Close()
+}
+
+// List returns metadata for all secrets of the workspace carried by ctx; the
+// encrypted value is never exposed.
 func (s *SecretStore) List(ctx context.Context) ([]SecretMeta, error) {
 	rows, err := s.db.QueryContext(ctx,
-		`SELECT id, name, type, created_at, updated_at FROM secrets ORDER BY created_at DESC`)
+		`SELECT id, name, type, created_at, updated_at FROM secrets
+		 WHERE workspace = $1 ORDER BY created_at DESC`, tenant.Workspace(ctx))
 	if err != nil {
 		return nil, fmt.Errorf("secrets: list: %w", err)
 	}
@@ -141,9 +194,41 @@ func (s *SecretStore) List(ctx context.Context) ([]SecretMeta, error) {
 	return results, nil
 }
 
-// Delete removes a secret by ID. Returns nil when the secret does not exist.
+// Get returns the metadata and decrypted value of the secret id in the
+// workspace carried by ctx, or ErrNotFound. Callers exposing the value over
+// the API must mask it (see MaskValue) unless the caller may reveal it.
+func (s *SecretStore) Get(ctx context.Context, id string) (SecretMeta, map[string]interface{}, error) {
+	var meta SecretMeta
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, name, type, created_at, updated_at, key_id, encrypted_val FROM secrets
+		 WHERE id = $1 AND workspace = $2`, id, tenant.Workspace(ctx))
+	if err != nil {
+		return meta, nil, fmt.Errorf("secrets: get %s: %w", id, err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return meta, nil, fmt.Errorf("secrets: get %s: %w", id, err)
+		}
+		return meta, nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	var keyID string
+	var ciphertext []byte
+	if err := rows.Scan(&meta.ID, &meta.Name, &meta.Type, &meta.CreatedAt, &meta.UpdatedAt, &keyID, &ciphertext); err != nil {
+		return meta, nil, fmt.Errorf("secrets: scan row: %w", err)
+	}
+	value, err := s.open(id, keyID, ciphertext)
+	if err != nil {
+		return meta, nil, err
+	}
+	return meta, value, nil
+}
+
+// Delete removes a secret by ID from the workspace carried by ctx. Returns nil
+// when the secret does not exist.
 func (s *SecretStore) Delete(ctx context.Context, id string) error {
-	_, err := s.db.ExecContext(ctx, `DELETE FROM secrets WHERE id = $1`, id)
+	_, err := s.db.ExecContext(ctx, `DELETE FROM secrets WHERE id = $1 AND workspace = $2`, id, tenant.Workspace(ctx))
 	if err != nil {
 		return fmt.Errorf("secrets: delete %s: %w", id, err)
 	}
@@ -155,11 +240,11 @@ func (s *SecretStore) Delete(ctx context.Context, id string) error {
 // ---------------------------------------------------------------------------
 
 // Resolve implements the SecretResolver interface. It fetches and decrypts the
-// secret identified by ref, returning its key/value pairs for config injection.
-// Secrets must never appear in audit logs.
+// secret identified by ref within the workspace carried by ctx, returning its
+// key/value pairs for config injection. Secrets must never appear in audit logs.
 func (s *SecretStore) Resolve(ctx context.Context, ref string) (map[string]interface{}, error) {
 	rows, err := s.db.QueryContext(ctx,
-		`SELECT encrypted_val FROM secrets WHERE id = $1`, ref)
+		`SELECT key_id, encrypted_val FROM secrets WHERE id = $1 AND workspace = $2`, ref, tenant.Workspace(ctx))
 	if err != nil {
 		return nil, fmt.Errorf("secrets: resolve %s: %w", ref, err)
 	}
@@ -169,17 +254,23 @@ func (s *SecretStore) Resolve(ctx context.Context, ref string) (map[string]inter
 		if err := rows.Err(); err != nil {
 			return nil, fmt.Errorf("secrets: resolve %s: %w", ref, err)
 		}
-		return nil, fmt.Errorf("secrets: secret not found: %s", ref)
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, ref)
 	}
 
+	var keyID string
 	var ciphertext []byte
-	if err := rows.Scan(&ciphertext); err != nil {
+	if err := rows.Scan(&keyID, &ciphertext); err != nil {
 		return nil, fmt.Errorf("secrets: scan ciphertext: %w", err)
 	}
+	return s.open(ref, keyID, ciphertext)
+}
 
-	plain, err := s.decrypt(ciphertext)
+// open decrypts, with the key keyID, and decodes the stored value of secret
+// id.
+func (s *SecretStore) open(id, keyID string, ciphertext []byte) (map[string]interface{}, error) {
+	plain, err := s.decrypt(keyID, ciphertext)
 	if err != nil {
-		return nil, fmt.Errorf("secrets: decrypt %s: %w", ref, err)
+		return nil, fmt.Errorf("secrets: decrypt %s: %w", id, err)
 	}
 
 	var result map[string]interface{}
@@ -189,14 +280,82 @@ func (s *SecretStore) Resolve(ctx context.Context, ref string) (map[string]inter
 	return result, nil
 }
 
+// ---------------------------------------------------------------------------
+// Key rotation
+// ---------------------------------------------------------------------------
+
+// ReEncryptResult reports a ReEncrypt run.
+type ReEncryptResult struct {
+	KeyID   string `json:"key_id"`
+	Scanned int    `json:"scanned"`
+	Rotated int    `json:"rotated"`
+	// Failed lists the secrets left on their old key because the key is not
+	// in the keyring or the value does not decrypt with it.
+	Failed []string `json:"failed,omitempty"`
+}
+
+// ReEncrypt re-encrypts with the primary key every secret, in all workspaces,
+// stored with another key. A secret updated meanwhile is skipped, since
+// Upsert already stored it with the primary key. Once Failed is empty the
+// previous keys can be removed from the keyring.
+func (s *SecretStore) ReEncrypt(ctx context.Context) (ReEncryptResult, error) {
+	result := ReEncryptResult{KeyID: s.keys.PrimaryID()}
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, key_id, encrypted_val FROM secrets WHERE key_id <> $1 ORDER BY id`, result.KeyID)
+	if err != nil {
+		return result, fmt.Errorf("secrets: re-encrypt: %w", err)
+	}
+	type stale struct {
+		id, keyID  string
+		ciphertext []byte
+	}
+	var pending []stale
+	for rows.Next() {
+		var st stale
+		if err := rows.Scan(&st.id, &st.keyID, &st.ciphertext); err != nil {
+			rows.Close()
+			return result, fmt.Errorf("secrets: scan row: %w", err)
+		}
+		pending = append(pending, st)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return result, fmt.Errorf("secrets: rows error: %w", err)
+	}
+
+	for _, st := range pending {
+		result.Scanned++
+		plain, err := s.decrypt(st.keyID, st.ciphertext)
+		if err != nil {
+			result.Failed = append(result.Failed, st.id)
+			continue
+		}
+		ciphertext, err := s.encrypt(plain)
+		if err != nil {
+			return result, fmt.Errorf("secrets: encrypt: %w", err)
+		}
+		res, err := s.db.ExecContext(ctx,
+			`UPDATE secrets SET encrypted_val = $1, key_id = $2 WHERE id = $3 AND key_id = $4`,
+			ciphertext, result.KeyID, st.id, st.keyID)
+		if err != nil {
+			return result, fmt.Errorf("secrets: re-encrypt %s: %w", st.id, err)
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			result.Rotated++
+		}
+	}
+	return result, nil
+}
+
 // ---------------------------------------------------------------------------
 // AES-256-GCM helpers
 // ---------------------------------------------------------------------------
 
-// encrypt encrypts plaintext using AES-256-GCM. The nonce is prepended to the
-// ciphertext so that decrypt can extract it without extra storage.
+// encrypt encrypts plaintext using AES-256-GCM with the primary key. The
+// nonce is prepended to the ciphertext so that decrypt can extract it without
+// extra storage.
 func (s *SecretStore) encrypt(plaintext []byte) ([]byte, error) {
-	block, err := aes.NewCipher(s.key)
+	block, err := aes.NewCipher(s.keys.keys[s.keys.primary])
 	if err != nil {
 		return nil, err
 	}
@@ -211,9 +370,14 @@ func (s *SecretStore) encrypt(plaintext []byte) ([]byte, error) {
 	return gcm.Seal(nonce, nonce, plaintext, nil), nil
 }
 
-// decrypt reverses encrypt. It expects the nonce to be prepended to the ciphertext.
-func (s *SecretStore) decrypt(data []byte) ([]byte, error) {
-	block, err := aes.NewCipher(s.key)
+// decrypt reverses encrypt with the key keyID. It expects the nonce to be
+// prepended to the ciphertext.
+func (s *SecretStore) decrypt(keyID string, data []byte) ([]byte, error) {
+	key, ok := s.keys.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("key %q is not in the keyring", keyID)
+	}
+	block, err := aes.NewCipher(key)
 	if err != nil {
 		return nil, err
 	}
@@ -3,11 +3,14 @@ package secrets
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"flowjs-works/engine/internal/tenant"
 )
 
 // ---------------------------------------------------------------------------
@@ -16,10 +19,12 @@ import (
 
 type row struct {
 	id           string
+	workspace    string
 	name         string
 	stype        string
 	encryptedVal []byte
 	metadata     []byte
+	keyID        string
 }
 
 type mockDB struct {
@@ -33,40 +38,39 @@ func (m *mockDB) ExecContext(_ context.Context, query string, args ...interface{
 	if !m.execOK {
 		return nil, assert.AnError
 	}
-	// Simple upsert simulation
-	if len(args) >= 5 {
-		id := args[0].(string)
-		for i, r := range m.rows {
-			if r.id == id {
-				m.rows[i] = row{
-					id:           id,
-					name:         args[1].(string),
-					stype:        args[2].(string),
-					encryptedVal: args[3].([]byte),
-					metadata:     []byte(args[4].(string)),
+	// Simple upsert simulation, honouring the workspace guard on conflict.
+	if len(args) >= 6 {
+		r := row{
+			id:           args[0].(string),
+			workspace:    args[1].(string),
+			name:         args[2].(string),
+			stype:        args[3].(string),
+			encryptedVal: args[4].([]byte),
+			metadata:     []byte(args[5].(string)),
+			keyID:        args[6].(string),
+		}
+		for i, existing := range m.rows {
+			if existing.id == r.id {
+				if existing.workspace != r.workspace {
+					return driver.RowsAffected(0), nil
 				}
-				return nil, nil
+				m.rows[i] = r
+				return driver.RowsAffected(1), nil
 			}
 		}
-		m.rows = append(m.rows, row{
-			id:           id,
-			name:         args[1].(string),
-			stype:        args[2].(string),
-			encryptedVal: args[3].([]byte),
-			metadata:     []byte(args[4].(string)),
-		})
-	} else if len(args) == 1 {
+		m.rows = append(m.rows, r)
+	} else if len(args) == 2 {
 		// DELETE
-		id := args[0].(string)
+		id, ws := args[0].(string), args[1].(string)
 		updated := m.rows[:0]
 		for _, r := range m.rows {
-			if r.id != id {
+			if r.id != id || r.workspace != ws {
 				updated = append(updated, r)
 			}
 		}
 		m.rows = updated
 	}
-	return nil, nil
+	return driver.RowsAffected(1), nil
 }
 
 func (m *mockDB) QueryContext(_ context.Context, query string, args ...interface{}) (*sql.Rows, error) {
@@ -104,7 +108,7 @@ func TestEncryptDecrypt_RoundTrip(t *testing.T) {
 	require.NoError(t, err)
 	assert.NotEqual(t, plaintext, ciphertext)
 
-	recovered, err := s.decrypt(ciphertext)
+	recovered, err := s.decrypt(DefaultKeyID, ciphertext)
 	require.NoError(t, err)
 	assert.Equal(t, plaintext, recovered)
 }
@@ -123,7 +127,7 @@ func TestEncryptDecrypt_DifferentNonceEachTime(t *testing.T) {
 
 func TestDecrypt_TruncatedData(t *testing.T) {
 	s := newTestStore(t)
-	_, err := s.decrypt([]byte("short"))
+	_, err := s.decrypt(DefaultKeyID, []byte("short"))
 	assert.Error(t, err)
 }
 
@@ -171,13 +175,85 @@ func TestUpsert_StoresEncryptedValue(t *testing.T) {
 	assert.NotContains(t, string(mdb.rows[0].encryptedVal), string(plainJSON))
 
 	// But we can decrypt it back
-	recovered, err := s.decrypt(mdb.rows[0].encryptedVal)
+	recovered, err := s.decrypt(mdb.rows[0].keyID, mdb.rows[0].encryptedVal)
 	require.NoError(t, err)
 
 	var got map[string]interface{}
 	require.NoError(t, json.Unmarshal(recovered, &got))
 	assert.Equal(t, "admin", got["username"])
 	assert.Equal(t, "p@ss", got["password"])
+	assert.Equal(t, DefaultKeyID, mdb.rows[0].keyID)
+}
+
+func TestUpsert_ScopedToWorkspace(t *testing.T) {
+	mdb := newMockDB()
+	s, err := NewSecretStore(mdb, make([]byte, 32))
+	require.NoError(t, err)
+
+	teamA := tenant.WithWorkspace(context.Background(), "team-a")
+	teamB := tenant.WithWorkspace(context.Background(), "team-b")
+	input := SecretInput{ID: "sec_api", Name: "API", Type: SecretTypeToken, Value: map[string]interface{}{"token": "x"}}
+
+	require.NoError(t, s.Upsert(teamA, input))
+	require.Len(t, mdb.rows, 1)
+	assert.Equal(t, "team-a", mdb.rows[0].workspace)
+
+	// Another workspace cannot overwrite (or hijack) an existing id.
+	err = s.Upsert(teamB, input)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "already in use")
+
+	// Deleting from another workspace is a no-op.
+	require.NoError(t, s.Delete(teamB, "sec_api"))
+	assert.Len(t, mdb.rows, 1)
+	require.NoError(t, s.Delete(teamA, "sec_api"))
+	assert.Empty(t, mdb.rows)
+}
+
+func TestUpsert_DefaultWorkspaceWithoutPrincipal(t *testing.T) {
+	mdb := newMockDB()
+	s, err := NewSecretStore(mdb, make([]byte, 32))
+	require.NoError(t, err)
+
+	require.NoError(t, s.Upsert(context.Background(), SecretInput{ID: "a", Name: "A", Value: map[string]interface{}{}}))
+	assert.Equal(t, tenant.DefaultWorkspace, mdb.rows[0].workspace)
+}
+
+func TestKeyring_DecryptsWithPreviousKey(t *testing.T) {
+	oldKey := []byte("0123456789abcdef0123456789abcdef")
+	newKey := []byte("fedcba9876543210fedcba9876543210")
+	old, err := NewSecretStore(newMockDB(), oldKey)
+	require.NoError(t, err)
+	ciphertext, err := old.encrypt([]byte(`{"token":"abc"}`))
+	require.NoError(t, err)
+
+	ring, err := NewKeyring("v2", newKey)
+	require.NoError(t, err)
+	mdb := newMockDB()
+	s := NewSecretStoreWithKeyring(mdb, ring)
+
+	_, err = s.decrypt(DefaultKeyID, ciphertext)
+	assert.ErrorContains(t, err, `key "v1" is not in the keyring`)
+
+	require.NoError(t, ring.Add(DefaultKeyID, oldKey))
+	plain, err := s.decrypt(DefaultKeyID, ciphertext)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"token":"abc"}`, string(plain))
+
+	// New values are stored with the primary key.
+	require.NoError(t, s.Upsert(context.Background(), SecretInput{ID: "a", Name: "A", Value: map[string]interface{}{}}))
+	assert.Equal(t, "v2", mdb.rows[0].keyID)
+	_, err = s.decrypt("v2", mdb.rows[0].encryptedVal)
+	assert.NoError(t, err)
+}
+
+func TestKeyring_Add(t *testing.T) {
+	ring, err := NewKeyring("v2", make([]byte, 32))
+	require.NoError(t, err)
+	assert.Equal(t, "v2", ring.PrimaryID())
+	assert.ErrorContains(t, ring.Add("v2", make([]byte, 32)), "duplicate key id")
+	assert.ErrorContains(t, ring.Add("v1", []byte("short")), "exactly 32 bytes")
+	assert.ErrorContains(t, ring.Add("", make([]byte, 32)), "key id")
 }
 
 // ---------------------------------------------------------------------------
@@ -192,6 +268,7 @@ func TestSecretTypeConstants(t *testing.T) {
 	assert.Equal(t, SecretType("aws_credentials"), SecretTypeAWSCredentials)
 	assert.Equal(t, SecretType("ssh_key"), SecretTypeSSHKey)
 	assert.Equal(t, SecretType("amqp_url"), SecretTypeAMQPURL)
+	assert.Equal(t, SecretType("service_account"), SecretTypeServiceAccount)
 }
 
 // ---------------------------------------------------------------------------
@@ -0,0 +1,135 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"flowjs-works/engine/internal/logging"
+	"flowjs-works/engine/internal/models"
+	"flowjs-works/engine/internal/tenant"
+)
+
+// capturePurgeInterval is how often expired captures are deleted.
+const capturePurgeInterval = time.Hour
+
+// ErrCaptureNotFound is returned by GetCapture when no live capture exists
+// for the process in the caller's workspace.
+var ErrCaptureNotFound = errors.New("capture_store: capture not found")
+
+// CaptureSummary describes a captured request without its headers and body.
+type CaptureSummary struct {
+	ID          int64     `json:"id"`
+	TriggerType string    `json:"trigger_type"`
+	Method      string    `json:"method"`
+	Path        string    `json:"path"`
+	Query       string    `json:"query,omitempty"`
+	BodyBytes   int       `json:"body_bytes"`
+	CapturedAt  time.Time `json:"captured_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// CaptureStore persists raw REST and SOAP trigger requests in the config
+// database until their expires_at. It implements triggers.RequestCapturer.
+type CaptureStore struct {
+	db *sql.DB
+
+	mu        sync.Mutex
+	lastPurge time.Time
+}
+
+// NewCaptureStore creates a store backed by db. The caller owns the connection.
+func NewCaptureStore(db *sql.DB) *CaptureStore {
+	return &CaptureStore{db: db}
+}
+
+// Capture stores req in the workspace it names. Triggers run outside any
+// authenticated context, so the workspace is taken from req, not ctx.
+func (s *CaptureStore) Capture(ctx context.Context, req *models.CapturedRequest) error {
+	s.purgeExpired(ctx)
+
+	headers, err := json.Marshal(req.Headers)
+	if err != nil {
+		return fmt.Errorf("capture_store: marshal headers: %w", err)
+	}
+	err = s.db.QueryRowContext(ctx, `
+		INSERT INTO trigger_captures (workspace, process_id, trigger_type, method, path, query, headers, body, captured_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id`,
+		tenant.Normalize(req.Workspace), req.ProcessID, req.TriggerType, req.Method, req.Path, req.Query,
+		headers, []byte(req.Body), req.CapturedAt, req.ExpiresAt).Scan(&req.ID)
+	if err != nil {
+		return fmt.Errorf("capture_store: save capture of %q: %w", req.ProcessID, err)
+	}
+	return nil
+}
+
+// ListCaptures returns the live captures of process id, newest first, at
+// most limit of them.
+func (s *CaptureStore) ListCaptures(ctx context.Context, id string, limit int) ([]CaptureSummary, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, trigger_type, method, path, query, OCTET_LENGTH(body), captured_at, expires_at
+		FROM trigger_captures
+		WHERE process_id = $1 AND workspace = $2 AND expires_at > NOW()
+		ORDER BY captured_at DESC, id DESC
+		LIMIT $3`,
+		id, tenant.Workspace(ctx), limit)
+	if err != nil {
+		return nil, fmt.Errorf("capture_store: list captures of %q: %w", id, err)
+	}
+	defer rows.Close()
+
+	var result []CaptureSummary
+	for rows.Next() {
+		var c CaptureSummary
+		if err := rows.Scan(&c.ID, &c.TriggerType, &c.Method, &c.Path, &c.Query, &c.BodyBytes, &c.CapturedAt, &c.ExpiresAt); err != nil {
+			return nil, fmt.Errorf("capture_store: scan capture: %w", err)
+		}
+		result = append(result, c)
+	}
+	return result, rows.Err()
+}
+
+// GetCapture returns capture captureID of process id, or ErrCaptureNotFound.
+func (s *CaptureStore) GetCapture(ctx context.Context, id string, captureID int64) (*models.CapturedRequest, error) {
+	c := models.CapturedRequest{ID: captureID, ProcessID: id, Workspace: tenant.Workspace(ctx)}
+	var headers, body []byte
+	err := s.db.QueryRowContext(ctx, `
+		SELECT trigger_type, method, path, query, headers, body, captured_at, expires_at
+		FROM trigger_captures
+		WHERE id = $1 AND process_id = $2 AND workspace = $3 AND expires_at > NOW()`,
+		captureID, id, c.Workspace).Scan(&c.TriggerType, &c.Method, &c.Path, &c.Query, &headers, &body, &c.CapturedAt, &c.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCaptureNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("capture_store: get capture %d of %q: %w", captureID, id, err)
+	}
+	c.Body = string(body)
+	if err := json.Unmarshal(headers, &c.Headers); err != nil {
+		return nil, fmt.Errorf("capture_store: decode headers of capture %d: %w", captureID, err)
+	}
+	return &c, nil
+}
+
+// purgeExpired deletes expired captures at most once per
+// capturePurgeInterval. Failures are logged; they only delay cleanup.
+func (s *CaptureStore) purgeExpired(ctx context.Context) {
+	s.mu.Lock()
+	due := time.Since(s.lastPurge) >= capturePurgeInterval
+	if due {
+		s.lastPurge = time.Now()
+	}
+	s.mu.Unlock()
+	if !due {
+		return
+	}
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM trigger_captures WHERE expires_at < NOW()`); err != nil {
+		slog.Error("capture_store: purge expired captures", logging.KeyError, err)
+	}
+}
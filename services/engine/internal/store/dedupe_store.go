@@ -0,0 +1,85 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"flowjs-works/engine/internal/logging"
+	"flowjs-works/engine/internal/tenant"
+)
+
+// dedupePurgeInterval is how often expired dedupe keys are deleted.
+const dedupePurgeInterval = time.Hour
+
+// DedupeStore persists the content hashes of the dedupe node in the config
+// database so duplicates are detected across restarts and engine replicas.
+type DedupeStore struct {
+	db *sql.DB
+
+	mu        sync.Mutex
+	lastPurge time.Time
+}
+
+// NewDedupeStore creates a store backed by db. The caller owns the connection.
+func NewDedupeStore(db *sql.DB) *DedupeStore {
+	return &DedupeStore{db: db}
+}
+
+// Seen records hash under scope in the workspace carried by ctx for ttl. It
+// reports duplicate=true, with the original first_seen, when an unexpired
+// record already exists; an expired record is replaced. The upsert is a
+// single statement, so concurrent deliveries of the same event cannot both
+// be treated as new.
+func (s *DedupeStore) Seen(ctx context.Context, scope, hash string, ttl time.Duration) (bool, time.Time, error) {
+	s.purgeExpired(ctx)
+
+	workspace := tenant.Workspace(ctx)
+	var firstSeen time.Time
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO dedupe_keys (workspace, scope, hash, first_seen, expires_at)
+		VALUES ($1, $2, $3, NOW(), NOW() + $4 * INTERVAL '1 millisecond')
+		ON CONFLICT (workspace, scope, hash) DO UPDATE
+		  SET first_seen = EXCLUDED.first_seen,
+		      expires_at = EXCLUDED.expires_at
+		  WHERE dedupe_keys.expires_at <= NOW()
+		RETURNING first_seen`,
+		workspace, scope, hash, ttl.Milliseconds()).Scan(&firstSeen)
+	if err == nil {
+		return false, firstSeen, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return false, time.Time{}, fmt.Errorf("dedupe_store: record %q: %w", hash, err)
+	}
+
+	// The conflict guard skipped the update: an unexpired record exists.
+	err = s.db.QueryRowContext(ctx, `
+		SELECT first_seen FROM dedupe_keys
+		WHERE workspace = $1 AND scope = $2 AND hash = $3`,
+		workspace, scope, hash).Scan(&firstSeen)
+	if err != nil {
+		return false, time.Time{}, fmt.Errorf("dedupe_store: read %q: %w", hash, err)
+	}
+	return true, firstSeen, nil
+}
+
+// purgeExpired deletes expired keys at most once per dedupePurgeInterval.
+// Failures are logged; they only delay cleanup.
+func (s *DedupeStore) purgeExpired(ctx context.Context) {
+	s.mu.Lock()
+	due := time.Since(s.lastPurge) >= dedupePurgeInterval
+	if due {
+		s.lastPurge = time.Now()
+	}
+	s.mu.Unlock()
+	if !due {
+		return
+	}
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM dedupe_keys WHERE expires_at <= NOW()`); err != nil {
+		slog.Error("dedupe_store: purge expired keys", logging.KeyError, err)
+	}
+}
@@ -0,0 +1,11 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDedupeStore_New(t *testing.T) {
+	assert.NotNil(t, NewDedupeStore(nil))
+}
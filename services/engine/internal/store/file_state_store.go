@@ -0,0 +1,72 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"flowjs-works/engine/internal/tenant"
+)
+
+// FileStateStore persists the files sftp_poll and s3_poll triggers fired
+// the flow for in the config database, so they are not fired again after a
+// restart or on another engine replica. It implements
+// triggers.FileStateStore.
+type FileStateStore struct {
+	db *sql.DB
+}
+
+// NewFileStateStore creates a store backed by db. The caller owns the connection.
+func NewFileStateStore(db *sql.DB) *FileStateStore {
+	return &FileStateStore{db: db}
+}
+
+// SeenFiles returns the fingerprint of each file recorded for processID in
+// the workspace carried by ctx, by file key.
+func (s *FileStateStore) SeenFiles(ctx context.Context, processID string) (map[string]string, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT file_key, fingerprint FROM trigger_file_state
+		WHERE workspace = $1 AND process_id = $2`,
+		tenant.Workspace(ctx), processID)
+	if err != nil {
+		return nil, fmt.Errorf("file_state_store: list %q: %w", processID, err)
+	}
+	defer rows.Close()
+
+	seen := make(map[string]string)
+	for rows.Next() {
+		var key, fingerprint string
+		if err := rows.Scan(&key, &fingerprint); err != nil {
+			return nil, fmt.Errorf("file_state_store: scan: %w", err)
+		}
+		seen[key] = fingerprint
+	}
+	return seen, rows.Err()
+}
+
+// MarkFile records that the flow of processID ran for key at fingerprint.
+func (s *FileStateStore) MarkFile(ctx context.Context, processID, key, fingerprint string) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO trigger_file_state (workspace, process_id, file_key, fingerprint, fired_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (workspace, process_id, file_key) DO UPDATE
+		  SET fingerprint = EXCLUDED.fingerprint,
+		      fired_at    = EXCLUDED.fired_at`,
+		tenant.Workspace(ctx), processID, key, fingerprint)
+	if err != nil {
+		return fmt.Errorf("file_state_store: record %q: %w", key, err)
+	}
+	return nil
+}
+
+// ForgetFile deletes the record of key for processID.
+func (s *FileStateStore) ForgetFile(ctx context.Context, processID, key string) error {
+	_, err := s.db.ExecContext(ctx, `
+		DELETE FROM trigger_file_state
+		WHERE workspace = $1 AND process_id = $2 AND file_key = $3`,
+		tenant.Workspace(ctx), processID, key)
+	if err != nil {
+		return fmt.Errorf("file_state_store: forget %q: %w", key, err)
+	}
+	return nil
+}
@@ -0,0 +1,11 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileStateStore_New(t *testing.T) {
+	assert.NotNil(t, NewFileStateStore(nil))
+}
@@ -0,0 +1,157 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"flowjs-works/engine/internal/models"
+	"flowjs-works/engine/internal/tenant"
+)
+
+// ErrNodeTemplateNotFound is returned when no template has the requested
+// name (and version) in the caller's workspace.
+var ErrNodeTemplateNotFound = errors.New("node_template_store: template not found")
+
+// nodeTemplateBody is the part of a template stored as its definition.
+type nodeTemplateBody struct {
+	Nodes       []models.Node       `json:"nodes"`
+	Transitions []models.Transition `json:"transitions,omitempty"`
+	Output      string              `json:"output,omitempty"`
+}
+
+// NodeTemplateStore persists the node library in the config database: the
+// versions of the node templates processes use with "$ref" nodes. Template
+// names are unique per workspace and versions are never modified.
+type NodeTemplateStore struct {
+	db *sql.DB
+}
+
+// NewNodeTemplateStore creates a store backed by db. The caller owns the
+// connection.
+func NewNodeTemplateStore(db *sql.DB) *NodeTemplateStore {
+	return &NodeTemplateStore{db: db}
+}
+
+// Publish stores t as the next version of its template in the workspace
+// carried by ctx; the first version is 1. The authenticated caller (see
+// tenant.PrincipalFromContext) is recorded as its author.
+func (s *NodeTemplateStore) Publish(ctx context.Context, t *models.NodeTemplate) (*models.NodeTemplate, error) {
+	if err := t.Validate(); err != nil {
+		return nil, fmt.Errorf("node_template_store: %w", err)
+	}
+	body, err := json.Marshal(nodeTemplateBody{Nodes: t.Nodes, Transitions: t.Transitions, Output: t.Output})
+	if err != nil {
+		return nil, fmt.Errorf("node_template_store: marshal %q: %w", t.Name, err)
+	}
+	out := *t
+	out.Workspace = tenant.Workspace(ctx)
+	out.CreatedBy = ""
+	if p, ok := tenant.PrincipalFromContext(ctx); ok {
+		out.CreatedBy = p.Subject
+	}
+	// Concurrent publishes of one template conflict on the primary key
+	// instead of sharing a version.
+	err = s.db.QueryRowContext(ctx, `
+		INSERT INTO node_templates (workspace, name, version, description, definition, created_by, created_at)
+		SELECT $1, $2, COALESCE(MAX(version), 0) + 1, $3, $4, $5, NOW()
+		FROM node_templates WHERE workspace = $1 AND name = $2
+		RETURNING version, created_at`,
+		out.Workspace, out.Name, out.Description, body, out.CreatedBy).Scan(&out.Version, &out.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("node_template_store: publish %q: %w", t.Name, err)
+	}
+	return &out, nil
+}
+
+// Get returns version of template name in the workspace carried by ctx, the
+// latest when version is 0.
+func (s *NodeTemplateStore) Get(ctx context.Context, name string, version int) (*models.NodeTemplate, error) {
+	t := models.NodeTemplate{Name: name, Workspace: tenant.Workspace(ctx)}
+	var body []byte
+	err := s.db.QueryRowContext(ctx, `
+		SELECT version, description, definition, created_by, created_at FROM node_templates
+		WHERE workspace = $1 AND name = $2 AND ($3 = 0 OR version = $3)
+		ORDER BY version DESC LIMIT 1`,
+		t.Workspace, name, version).Scan(&t.Version, &t.Description, &body, &t.CreatedBy, &t.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		if version == 0 {
+			return nil, fmt.Errorf("%w: %q", ErrNodeTemplateNotFound, name)
+		}
+		return nil, fmt.Errorf("%w: %q version %d", ErrNodeTemplateNotFound, name, version)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("node_template_store: get %q: %w", name, err)
+	}
+	var def nodeTemplateBody
+	if err := json.Unmarshal(body, &def); err != nil {
+		return nil, fmt.Errorf("node_template_store: parse %q version %d: %w", name, t.Version, err)
+	}
+	t.Nodes, t.Transitions, t.Output = def.Nodes, def.Transitions, def.Output
+	return &t, nil
+}
+
+// List returns the latest version of every template of the workspace carried
+// by ctx without its nodes, ordered by name.
+func (s *NodeTemplateStore) List(ctx context.Context) ([]models.NodeTemplate, error) {
+	workspace := tenant.Workspace(ctx)
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT DISTINCT ON (name) name, version, description, created_by, created_at FROM node_templates
+		WHERE workspace = $1 ORDER BY name, version DESC`, workspace)
+	if err != nil {
+		return nil, fmt.Errorf("node_template_store: list: %w", err)
+	}
+	return scanNodeTemplates(rows, workspace)
+}
+
+// Versions returns the versions of template name in the workspace carried by
+// ctx without their nodes, newest first.
+func (s *NodeTemplateStore) Versions(ctx context.Context, name string) ([]models.NodeTemplate, error) {
+	workspace := tenant.Workspace(ctx)
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT name, version, description, created_by, created_at FROM node_templates
+		WHERE workspace = $1 AND name = $2 ORDER BY version DESC`, workspace, name)
+	if err != nil {
+		return nil, fmt.Errorf("node_template_store: list versions of %q: %w", name, err)
+	}
+	return scanNodeTemplates(rows, workspace)
+}
+
+// scanNodeTemplates reads the rows of List and Versions and closes them.
+func scanNodeTemplates(rows *sql.Rows, workspace string) ([]models.NodeTemplate, error) {
+	defer rows.Close()
+	var result []models.NodeTemplate
+	for rows.Next() {
+		t := models.NodeTemplate{Workspace: workspace}
+		if err := rows.Scan(&t.Name, &t.Version, &t.Description, &t.CreatedBy, &t.CreatedAt); err != nil {
+			return nil, fmt.Errorf("node_template_store: scan template: %w", err)
+		}
+		result = append(result, t)
+	}
+	return result, rows.Err()
+}
+
+// Delete removes every version of template name from the workspace carried
+// by ctx. Processes still referencing it fail to load until it is published
+// again.
+func (s *NodeTemplateStore) Delete(ctx context.Context, name string) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM node_templates WHERE workspace = $1 AND name = $2`,
+		tenant.Workspace(ctx), name)
+	if err != nil {
+		return fmt.Errorf("node_template_store: delete %q: %w", name, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w: %q", ErrNodeTemplateNotFound, name)
+	}
+	return nil
+}
+
+// Expand returns p with its $ref nodes replaced by the templates of the
+// workspace carried by ctx (see models.Process.ExpandNodeRefs).
+func (s *NodeTemplateStore) Expand(ctx context.Context, p *models.Process) (*models.Process, error) {
+	return p.ExpandNodeRefs(func(name string, version int) (*models.NodeTemplate, error) {
+		return s.Get(ctx, name, version)
+	})
+}
@@ -0,0 +1,11 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNodeTemplateStore_New(t *testing.T) {
+	assert.NotNil(t, NewNodeTemplateStore(nil))
+}
@@ -0,0 +1,219 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"flowjs-works/engine/internal/notify"
+	"flowjs-works/engine/internal/tenant"
+)
+
+// ErrChannelNotFound is returned when no notification channel has the
+// requested id in the caller's workspace.
+var ErrChannelNotFound = errors.New("notification_store: channel not found")
+
+// NotificationStore persists notification channels and the subscriptions
+// of processes to them in the config database. It implements notify.Source.
+type NotificationStore struct {
+	db *sql.DB
+}
+
+// NewNotificationStore creates a store backed by db. The caller owns the connection.
+func NewNotificationStore(db *sql.DB) *NotificationStore {
+	return &NotificationStore{db: db}
+}
+
+// channelCols is the column list scanned by scanChannel.
+const channelCols = `id, workspace, type, description, config, secret_ref, created_at, updated_at`
+
+// UpsertChannel creates or replaces a channel in the workspace carried by ctx.
+func (s *NotificationStore) UpsertChannel(ctx context.Context, ch *notify.Channel) (*notify.Channel, error) {
+	config, err := json.Marshal(ch.Config)
+	if err != nil {
+		return nil, fmt.Errorf("notification_store: marshal channel %q: %w", ch.ID, err)
+	}
+	row := s.db.QueryRowContext(ctx, `
+		INSERT INTO notification_channels (workspace, id, type, description, config, secret_ref, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW())
+		ON CONFLICT (workspace, id) DO UPDATE
+		  SET type        = EXCLUDED.type,
+		      description = EXCLUDED.description,
+		      config      = EXCLUDED.config,
+		      secret_ref  = EXCLUDED.secret_ref,
+		      updated_at  = NOW()
+		RETURNING `+channelCols,
+		tenant.Workspace(ctx), ch.ID, ch.Type, ch.Description, config, ch.SecretRef)
+	saved, err := scanChannel(row)
+	if err != nil {
+		return nil, fmt.Errorf("notification_store: upsert channel %q: %w", ch.ID, err)
+	}
+	return saved, nil
+}
+
+// GetChannel returns channel id, or ErrChannelNotFound.
+func (s *NotificationStore) GetChannel(ctx context.Context, id string) (*notify.Channel, error) {
+	row := s.db.QueryRowContext(ctx,
+		`SELECT `+channelCols+` FROM notification_channels WHERE workspace = $1 AND id = $2`,
+		tenant.Workspace(ctx), id)
+	ch, err := scanChannel(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %q", ErrChannelNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("notification_store: get channel %q: %w", id, err)
+	}
+	return ch, nil
+}
+
+// ListChannels returns the workspace's channels ordered by id.
+func (s *NotificationStore) ListChannels(ctx context.Context) ([]notify.Channel, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+channelCols+` FROM notification_channels WHERE workspace = $1 ORDER BY id`,
+		tenant.Workspace(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("notification_store: list channels: %w", err)
+	}
+	defer rows.Close()
+
+	var result []notify.Channel
+	for rows.Next() {
+		ch, err := scanChannel(rows)
+		if err != nil {
+			return nil, fmt.Errorf("notification_store: scan channel: %w", err)
+		}
+		result = append(result, *ch)
+	}
+	return result, rows.Err()
+}
+
+// DeleteChannel removes channel id and every subscription to it.
+func (s *NotificationStore) DeleteChannel(ctx context.Context, id string) error {
+	result, err := s.db.ExecContext(ctx,
+		`DELETE FROM notification_channels WHERE workspace = $1 AND id = $2`,
+		tenant.Workspace(ctx), id)
+	if err != nil {
+		return fmt.Errorf("notification_store: delete channel %q: %w", id, err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("%w: %q", ErrChannelNotFound, id)
+	}
+	return nil
+}
+
+// Subscriptions returns the subscriptions of processID ordered by channel.
+func (s *NotificationStore) Subscriptions(ctx context.Context, processID string) ([]notify.Subscription, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT channel_id, events, cooldown_s FROM notification_subscriptions
+		WHERE workspace = $1 AND process_id = $2
+		ORDER BY channel_id`,
+		tenant.Workspace(ctx), processID)
+	if err != nil {
+		return nil, fmt.Errorf("notification_store: list subscriptions of %q: %w", processID, err)
+	}
+	defer rows.Close()
+
+	var result []notify.Subscription
+	for rows.Next() {
+		var sub notify.Subscription
+		var events []byte
+		if err := rows.Scan(&sub.ChannelID, &events, &sub.CooldownS); err != nil {
+			return nil, fmt.Errorf("notification_store: scan subscription: %w", err)
+		}
+		if err := json.Unmarshal(events, &sub.Events); err != nil {
+			return nil, fmt.Errorf("notification_store: decode events of %q: %w", sub.ChannelID, err)
+		}
+		result = append(result, sub)
+	}
+	return result, rows.Err()
+}
+
+// SetSubscriptions replaces the subscriptions of processID with subs in one
+// transaction. Every channel must exist in the workspace.
+func (s *NotificationStore) SetSubscriptions(ctx context.Context, processID string, subs []notify.Subscription) error {
+	workspace := tenant.Workspace(ctx)
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("notification_store: set subscriptions of %q: %w", processID, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM notification_subscriptions WHERE workspace = $1 AND process_id = $2`,
+		workspace, processID); err != nil {
+		return fmt.Errorf("notification_store: set subscriptions of %q: %w", processID, err)
+	}
+	for _, sub := range subs {
+		events, err := json.Marshal(sub.Events)
+		if err != nil {
+			return fmt.Errorf("notification_store: marshal events of %q: %w", sub.ChannelID, err)
+		}
+		// The channel must belong to the workspace; a missing one inserts no row.
+		result, err := tx.ExecContext(ctx, `
+			INSERT INTO notification_subscriptions (workspace, process_id, channel_id, events, cooldown_s)
+			SELECT $1, $2, id, $4, $5 FROM notification_channels WHERE workspace = $1 AND id = $3`,
+			workspace, processID, sub.ChannelID, events, sub.CooldownS)
+		if err != nil {
+			return fmt.Errorf("notification_store: subscribe %q to %q: %w", processID, sub.ChannelID, err)
+		}
+		if rows, _ := result.RowsAffected(); rows == 0 {
+			return fmt.Errorf("%w: %q", ErrChannelNotFound, sub.ChannelID)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("notification_store: set subscriptions of %q: %w", processID, err)
+	}
+	return nil
+}
+
+// Targets returns the channels subscribed to event of processID. It
+// implements notify.Source.
+func (s *NotificationStore) Targets(ctx context.Context, processID, event string) ([]notify.Target, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT c.id, c.workspace, c.type, c.description, c.config, c.secret_ref, c.created_at, c.updated_at, s.cooldown_s
+		FROM notification_subscriptions s
+		JOIN notification_channels c ON c.workspace = s.workspace AND c.id = s.channel_id
+		WHERE s.workspace = $1 AND s.process_id = $2 AND s.events ? $3
+		ORDER BY c.id`,
+		tenant.Workspace(ctx), processID, event)
+	if err != nil {
+		return nil, fmt.Errorf("notification_store: targets of %q: %w", processID, err)
+	}
+	defer rows.Close()
+
+	var result []notify.Target
+	for rows.Next() {
+		var (
+			t        notify.Target
+			config   []byte
+			cooldown int
+		)
+		ch := &t.Channel
+		if err := rows.Scan(&ch.ID, &ch.Workspace, &ch.Type, &ch.Description, &config, &ch.SecretRef,
+			&ch.CreatedAt, &ch.UpdatedAt, &cooldown); err != nil {
+			return nil, fmt.Errorf("notification_store: scan target: %w", err)
+		}
+		if err := json.Unmarshal(config, &ch.Config); err != nil {
+			return nil, fmt.Errorf("notification_store: decode config of %q: %w", ch.ID, err)
+		}
+		t.Cooldown = time.Duration(cooldown) * time.Second
+		result = append(result, t)
+	}
+	return result, rows.Err()
+}
+
+// scanChannel reads one row of channelCols.
+func scanChannel(row rowScanner) (*notify.Channel, error) {
+	var ch notify.Channel
+	var config []byte
+	if err := row.Scan(&ch.ID, &ch.Workspace, &ch.Type, &ch.Description, &config, &ch.SecretRef, &ch.CreatedAt, &ch.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(config, &ch.Config); err != nil {
+		return nil, fmt.Errorf("decode config of %q: %w", ch.ID, err)
+	}
+	return &ch, nil
+}
@@ -0,0 +1,181 @@
+package store
+
+import (
+	"database/sql"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CacheStats counts process cache lookups since the store was created.
+type CacheStats struct {
+	Hits   uint64
+	Misses uint64
+}
+
+// processCache keeps recently read process records and promoted DSLs in
+// memory, so deploys, replays and scheduled runs of busy processes do not
+// query the config DB every time. Writes through the store invalidate the
+// entries of a process at once; the ttl bounds how long writes made by
+// another engine replica go unnoticed. A nil cache is disabled.
+type processCache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*cachedProcess // by process id (globally unique)
+
+	hits, misses atomic.Uint64
+}
+
+// cachedProcess holds the cached draft record of a process and its DSL per
+// deployment environment; either may be absent.
+type cachedProcess struct {
+	record  *ProcessRecord
+	expires time.Time
+	envs    map[string]cachedEnvironment
+}
+
+type cachedEnvironment struct {
+	record  *EnvironmentRecord
+	expires time.Time
+}
+
+func newProcessCache(ttl time.Duration) *processCache {
+	return &processCache{ttl: ttl, now: time.Now, entries: make(map[string]*cachedProcess)}
+}
+
+// record returns a copy of the cached record of id in workspace, or nil.
+func (c *processCache) record(id, workspace string) *ProcessRecord {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := c.entries[id]
+	if e == nil || e.record == nil || e.record.Workspace != workspace || !c.now().Before(e.expires) {
+		c.misses.Add(1)
+		return nil
+	}
+	c.hits.Add(1)
+	rec := *e.record
+	return &rec
+}
+
+// putRecord caches a copy of rec.
+func (c *processCache) putRecord(rec *ProcessRecord) {
+	if c == nil {
+		return
+	}
+	cp := *rec
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := c.entry(rec.ID)
+	e.record, e.expires = &cp, c.now().Add(c.ttl)
+}
+
+// environment returns a copy of the cached DSL of id in env, or nil.
+func (c *processCache) environment(id, workspace, env string) *EnvironmentRecord {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var cached cachedEnvironment
+	if e := c.entries[id]; e != nil {
+		cached = e.envs[env]
+	}
+	if cached.record == nil || cached.record.Workspace != workspace || !c.now().Before(cached.expires) {
+		c.misses.Add(1)
+		return nil
+	}
+	c.hits.Add(1)
+	rec := *cached.record
+	return &rec
+}
+
+// putEnvironment caches a copy of rec.
+func (c *processCache) putEnvironment(rec *EnvironmentRecord) {
+	if c == nil {
+		return
+	}
+	cp := *rec
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := c.entry(rec.ProcessID)
+	if e.envs == nil {
+		e.envs = make(map[string]cachedEnvironment)
+	}
+	e.envs[rec.Environment] = cachedEnvironment{record: &cp, expires: c.now().Add(c.ttl)}
+}
+
+// entry returns the entry of id, creating it. c.mu must be held.
+func (c *processCache) entry(id string) *cachedProcess {
+	e := c.entries[id]
+	if e == nil {
+		e = &cachedProcess{}
+		c.entries[id] = e
+	}
+	return e
+}
+
+// invalidate drops everything cached for id.
+func (c *processCache) invalidate(id string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	delete(c.entries, id)
+	c.mu.Unlock()
+}
+
+// recordRun updates the last_run_at of the cached record of id, so that
+// frequently running processes stay cached.
+func (c *processCache) recordRun(id string, at time.Time) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e := c.entries[id]; e != nil && e.record != nil {
+		rec := *e.record
+		rec.LastRunAt = &at
+		e.record = &rec
+	}
+}
+
+func (c *processCache) stats() CacheStats {
+	if c == nil {
+		return CacheStats{}
+	}
+	return CacheStats{Hits: c.hits.Load(), Misses: c.misses.Load()}
+}
+
+// SetReadReplica sends the store's read-only queries (Get, List, environment
+// and promotion listings, secret references) to replica instead of the
+// primary. Reads that decide a write, such as the source DSL of a promotion,
+// still use the primary. Replica lag means a read may briefly miss a write
+// made on another connection; with EnableCache, records written through this
+// store are cached, so a process saved and then deployed on the same engine
+// sees its new DSL.
+func (s *ProcessStore) SetReadReplica(replica *sql.DB) {
+	if replica != nil {
+		s.read = replica
+	}
+}
+
+// EnableCache caches process records and promoted DSLs for ttl; zero or a
+// negative ttl disables the cache. It must be called before the store is
+// used.
+func (s *ProcessStore) EnableCache(ttl time.Duration) {
+	if ttl <= 0 {
+		s.cache = nil
+		return
+	}
+	s.cache = newProcessCache(ttl)
+}
+
+// CacheStats reports the hits and misses of the process cache.
+func (s *ProcessStore) CacheStats() CacheStats {
+	return s.cache.stats()
+}
@@ -0,0 +1,62 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessCache_RecordLifecycle(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	c := newProcessCache(time.Minute)
+	c.now = func() time.Time { return now }
+
+	assert.Nil(t, c.record("p1", "default"))
+	c.putRecord(&ProcessRecord{ID: "p1", Workspace: "default", Status: "deployed"})
+
+	rec := c.record("p1", "default")
+	require.NotNil(t, rec)
+	assert.Equal(t, "deployed", rec.Status)
+	rec.Status = "mutated"
+	assert.Equal(t, "deployed", c.record("p1", "default").Status, "callers get copies")
+	assert.Nil(t, c.record("p1", "team-a"), "another workspace never sees the entry")
+
+	ran := now.Add(time.Second)
+	c.recordRun("p1", ran)
+	assert.Equal(t, &ran, c.record("p1", "default").LastRunAt)
+
+	now = now.Add(time.Minute)
+	assert.Nil(t, c.record("p1", "default"), "expired")
+	assert.Equal(t, CacheStats{Hits: 3, Misses: 3}, c.stats())
+}
+
+func TestProcessCache_InvalidateDropsEnvironments(t *testing.T) {
+	c := newProcessCache(time.Minute)
+	c.putRecord(&ProcessRecord{ID: "p1", Workspace: "default"})
+	c.putEnvironment(&EnvironmentRecord{ProcessID: "p1", Workspace: "default", Environment: "prod", Revision: 4})
+
+	env := c.environment("p1", "default", "prod")
+	require.NotNil(t, env)
+	assert.Equal(t, 4, env.Revision)
+	assert.Nil(t, c.environment("p1", "default", "staging"))
+
+	c.invalidate("p1")
+	assert.Nil(t, c.record("p1", "default"))
+	assert.Nil(t, c.environment("p1", "default", "prod"))
+}
+
+func TestProcessCache_NilIsDisabled(t *testing.T) {
+	var c *processCache
+	c.putRecord(&ProcessRecord{ID: "p1"})
+	c.invalidate("p1")
+	assert.Nil(t, c.record("p1", "default"))
+	assert.Equal(t, CacheStats{}, c.stats())
+
+	s := NewProcessStore(nil)
+	s.EnableCache(0)
+	assert.Nil(t, s.cache)
+	s.SetReadReplica(nil)
+	assert.Nil(t, s.read)
+}
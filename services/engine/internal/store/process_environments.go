@@ -0,0 +1,270 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"flowjs-works/engine/internal/models"
+	"flowjs-works/engine/internal/tenant"
+)
+
+// ErrNotPromoted is returned when a process has no DSL in the requested
+// deployment environment.
+var ErrNotPromoted = errors.New("process_store: process not promoted to environment")
+
+// ErrInvalidDSL is returned by Promote when the DSL being promoted does not
+// pass validation.
+var ErrInvalidDSL = errors.New("process_store: invalid DSL")
+
+// EnvironmentRecord is a row from the process_environments table: the DSL a
+// process runs with in one deployment environment.
+type EnvironmentRecord struct {
+	ProcessID   string          `json:"process_id"`
+	Workspace   string          `json:"workspace"`
+	Environment string          `json:"environment"`
+	DSL         json.RawMessage `json:"dsl,omitempty"`
+	Revision    int             `json:"revision"` // draft revision the DSL was promoted from
+	PromotedBy  string          `json:"promoted_by"`
+	PromotedAt  time.Time       `json:"promoted_at"`
+}
+
+// Promotion is a row from the process_promotions history table.
+type Promotion struct {
+	ID         int64     `json:"id"`
+	ProcessID  string    `json:"process_id"`
+	FromEnv    string    `json:"from"` // "draft" for promotions to dev
+	ToEnv      string    `json:"to"`
+	Revision   int       `json:"revision"`
+	PromotedBy string    `json:"promoted_by"`
+	PromotedAt time.Time `json:"promoted_at"`
+}
+
+// PromotionSourceDraft is the FromEnv of promotions that copy the draft.
+const PromotionSourceDraft = "draft"
+
+// Promote copies the DSL of the environment preceding to (or the draft, for
+// dev) into to, and appends the promotion to the history, in one
+// transaction. The copied DSL must pass models.Process.Validate.
+func (s *ProcessStore) Promote(ctx context.Context, id, to, promotedBy string) (*Promotion, error) {
+	if !models.ValidEnvironment(to) {
+		return nil, fmt.Errorf("process_store: unknown environment %q", to)
+	}
+	from := models.PreviousEnvironment(to)
+
+	var (
+		dsl      json.RawMessage
+		revision int
+	)
+	// The source is read from the primary, never from the cache or replica,
+	// so a promotion copies exactly what was last saved.
+	if from == "" {
+		rec, err := s.get(ctx, s.db, id)
+		if err != nil {
+			return nil, err
+		}
+		dsl, revision, from = rec.DSL, rec.Revision, PromotionSourceDraft
+	} else {
+		env, err := s.getEnvironment(ctx, s.db, id, from)
+		if err != nil {
+			return nil, err
+		}
+		dsl, revision = env.DSL, env.Revision
+	}
+	if err := validateDSL(id, dsl); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("process_store: promote %q: %w", id, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	workspace := tenant.Workspace(ctx)
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO process_environments (workspace, process_id, environment, dsl, revision, promoted_by, promoted_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+		ON CONFLICT (process_id, environment) DO UPDATE
+		  SET dsl         = EXCLUDED.dsl,
+		      revision    = EXCLUDED.revision,
+		      promoted_by = EXCLUDED.promoted_by,
+		      promoted_at = EXCLUDED.promoted_at`,
+		workspace, id, to, []byte(dsl), revision, promotedBy)
+	if err != nil {
+		return nil, fmt.Errorf("process_store: promote %q to %s: %w", id, to, err)
+	}
+	p := Promotion{ProcessID: id, FromEnv: from, ToEnv: to, Revision: revision, PromotedBy: promotedBy}
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO process_promotions (workspace, process_id, from_env, to_env, revision, promoted_by, promoted_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+		RETURNING id, promoted_at`,
+		workspace, id, from, to, revision, promotedBy).Scan(&p.ID, &p.PromotedAt)
+	if err != nil {
+		return nil, fmt.Errorf("process_store: record promotion of %q: %w", id, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("process_store: promote %q: %w", id, err)
+	}
+	s.cache.invalidate(id)
+	return &p, nil
+}
+
+// GetEnvironment returns the DSL of process id in env, or ErrNotPromoted.
+// Like Get it is served from the cache or the read replica.
+func (s *ProcessStore) GetEnvironment(ctx context.Context, id, env string) (*EnvironmentRecord, error) {
+	if rec := s.cache.environment(id, tenant.Workspace(ctx), env); rec != nil {
+		return rec, nil
+	}
+	rec, err := s.getEnvironment(ctx, s.read, id, env)
+	if err != nil {
+		return nil, err
+	}
+	s.cache.putEnvironment(rec)
+	return rec, nil
+}
+
+// getEnvironment reads the DSL of id in env from db, bypassing the cache.
+func (s *ProcessStore) getEnvironment(ctx context.Context, db *sql.DB, id, env string) (*EnvironmentRecord, error) {
+	rec := EnvironmentRecord{ProcessID: id, Workspace: tenant.Workspace(ctx), Environment: env}
+	err := db.QueryRowContext(ctx, `
+		SELECT dsl, revision, promoted_by, promoted_at FROM process_environments
+		WHERE process_id = $1 AND environment = $2 AND workspace = $3`,
+		id, env, rec.Workspace).Scan(&rec.DSL, &rec.Revision, &rec.PromotedBy, &rec.PromotedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %q in %s", ErrNotPromoted, id, env)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("process_store: get %q in %s: %w", id, env, err)
+	}
+	return &rec, nil
+}
+
+// Deployable returns process id as it runs in env: the DSL promoted to env,
+// configured by models.Process.ForEnvironment, or the draft when env is "",
+// with its $ref nodes expanded (see SetNodeTemplates).
+// An archived process is not deployable (ErrArchived).
+func (s *ProcessStore) Deployable(ctx context.Context, id, env string) (*models.Process, error) {
+	draft, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if draft.Status == StatusArchived {
+		return nil, fmt.Errorf("%w: %q", ErrArchived, id)
+	}
+	if env == "" {
+		proc, err := draft.ParseDSL()
+		if err != nil {
+			return nil, err
+		}
+		return s.expand(ctx, proc)
+	}
+	rec, err := s.GetEnvironment(ctx, id, env)
+	if err != nil {
+		return nil, err
+	}
+	proc, err := rec.parse()
+	if err != nil {
+		return nil, err
+	}
+	// Template nodes are configured for env like the process's own.
+	if proc, err = s.expand(ctx, proc); err != nil {
+		return nil, err
+	}
+	return proc.ForEnvironment(env), nil
+}
+
+// expand replaces the $ref nodes of proc by their templates, when the store
+// has a node library.
+func (s *ProcessStore) expand(ctx context.Context, proc *models.Process) (*models.Process, error) {
+	if s.templates == nil {
+		return proc, nil
+	}
+	return s.templates.Expand(ctx, proc)
+}
+
+// ListEnvironments returns the environments process id was promoted to,
+// without their DSL, in promotion order.
+func (s *ProcessStore) ListEnvironments(ctx context.Context, id string) ([]EnvironmentRecord, error) {
+	workspace := tenant.Workspace(ctx)
+	rows, err := s.read.QueryContext(ctx, `
+		SELECT environment, revision, promoted_by, promoted_at FROM process_environments
+		WHERE process_id = $1 AND workspace = $2
+		ORDER BY array_position(ARRAY['dev', 'staging', 'prod'], environment::text)`,
+		id, workspace)
+	if err != nil {
+		return nil, fmt.Errorf("process_store: list environments of %q: %w", id, err)
+	}
+	defer rows.Close()
+
+	var result []EnvironmentRecord
+	for rows.Next() {
+		rec := EnvironmentRecord{ProcessID: id, Workspace: workspace}
+		if err := rows.Scan(&rec.Environment, &rec.Revision, &rec.PromotedBy, &rec.PromotedAt); err != nil {
+			return nil, fmt.Errorf("process_store: scan environment: %w", err)
+		}
+		result = append(result, rec)
+	}
+	return result, rows.Err()
+}
+
+// Promotions returns the promotion history of process id, newest first.
+func (s *ProcessStore) Promotions(ctx context.Context, id string) ([]Promotion, error) {
+	rows, err := s.read.QueryContext(ctx, `
+		SELECT id, from_env, to_env, revision, promoted_by, promoted_at FROM process_promotions
+		WHERE process_id = $1 AND workspace = $2
+		ORDER BY promoted_at DESC, id DESC`,
+		id, tenant.Workspace(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("process_store: list promotions of %q: %w", id, err)
+	}
+	defer rows.Close()
+
+	var result []Promotion
+	for rows.Next() {
+		p := Promotion{ProcessID: id}
+		if err := rows.Scan(&p.ID, &p.FromEnv, &p.ToEnv, &p.Revision, &p.PromotedBy, &p.PromotedAt); err != nil {
+			return nil, fmt.Errorf("process_store: scan promotion: %w", err)
+		}
+		result = append(result, p)
+	}
+	return result, rows.Err()
+}
+
+// ParseDSL deserialises the promoted DSL configured for the record's
+// environment (see models.Process.ForEnvironment). The owning workspace is
+// taken from the record, never from the stored DSL.
+func (r *EnvironmentRecord) ParseDSL() (*models.Process, error) {
+	proc, err := r.parse()
+	if err != nil {
+		return nil, err
+	}
+	return proc.ForEnvironment(r.Environment), nil
+}
+
+// parse deserialises the promoted DSL as stored, not yet configured for the
+// environment.
+func (r *EnvironmentRecord) parse() (*models.Process, error) {
+	var proc models.Process
+	if err := json.Unmarshal(r.DSL, &proc); err != nil {
+		return nil, fmt.Errorf("process_store: parse %s DSL for %q: %w", r.Environment, r.ProcessID, err)
+	}
+	proc.Definition.Workspace = tenant.Normalize(r.Workspace)
+	proc.Definition.Revision = r.Revision
+	return &proc, nil
+}
+
+// validateDSL parses dsl and checks it with models.Process.Validate.
+func validateDSL(id string, dsl json.RawMessage) error {
+	var proc models.Process
+	if err := json.Unmarshal(dsl, &proc); err != nil {
+		return fmt.Errorf("%w: %q: %v", ErrInvalidDSL, id, err)
+	}
+	if err := proc.Validate(); err != nil {
+		return fmt.Errorf("%w: %q: %v", ErrInvalidDSL, id, err)
+	}
+	return nil
+}
@@ -1,119 +1,365 @@
 // Package store provides DB-backed persistence for process definitions.
-// It manages the lifecycle status (draft | deployed | stopped) of every flow.
+// It manages the lifecycle status (draft | deployed | stopped | archived) of
+// every flow.
+// Every query is scoped to the workspace carried by the request context
+// (see tenant.Workspace); process ids remain globally unique.
 package store
 
 import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
+	"flowjs-works/engine/internal/lint"
 	"flowjs-works/engine/internal/models"
+	"flowjs-works/engine/internal/tenant"
 )
 
+// ErrRevisionConflict is returned by Upsert when the stored revision no longer
+// matches the revision the caller based its edit on (optimistic locking).
+var ErrRevisionConflict = errors.New("process_store: revision conflict")
+
+// ErrProcessExists is returned by Create for an id that is already in use.
+var ErrProcessExists = errors.New("process_store: process id already in use")
+
+// ErrProcessNotFound is returned when no process has the requested id in the
+// caller's workspace.
+var ErrProcessNotFound = errors.New("process_store: process not found")
+
+// ErrArchived is returned by Deployable for an archived process.
+var ErrArchived = errors.New("process_store: process is archived")
+
+// ErrNotArchived is returned by Restore and Purge for a process that is not
+// archived.
+var ErrNotArchived = errors.New("process_store: process is not archived")
+
+// ErrRecentlyExecuted is returned by Purge for a process that ran within the
+// protection window, so the lineage of production flows is not lost.
+var ErrRecentlyExecuted = errors.New("process_store: process has recent executions")
+
+// ErrPlaintextCredential is returned by Upsert, with SecretScanReject, for a
+// DSL holding a credential in plain text instead of a secret_ref.
+var ErrPlaintextCredential = errors.New("process_store: plain-text credential")
+
+// Secret scan modes of SetSecretScan: what Upsert does with a DSL holding a
+// credential in plain text.
+const (
+	// SecretScanWarn saves it and returns the findings as warnings.
+	SecretScanWarn = "warn"
+	// SecretScanReject refuses to save it with ErrPlaintextCredential.
+	SecretScanReject = "reject"
+	// SecretScanOff does not scan.
+	SecretScanOff = "off"
+)
+
+// StatusArchived marks a soft-deleted process: hidden from List by default,
+// never deployed, and restorable until it is purged.
+const StatusArchived = "archived"
+
 // ProcessRecord is a row from the processes table in the config DB.
 type ProcessRecord struct {
 	ID          string          `json:"id"`
+	Workspace   string          `json:"workspace"`
 	Version     string          `json:"version"`
 	Name        string          `json:"name"`
 	Description string          `json:"description"`
 	DSL         json.RawMessage `json:"dsl"`
-	Status      string          `json:"status"` // draft | deployed | stopped
-	CreatedAt   time.Time       `json:"created_at"`
-	UpdatedAt   time.Time       `json:"updated_at"`
+	Status      string          `json:"status"`   // draft | deployed | stopped
+	Revision    int             `json:"revision"` // incremented on every save; exposed as ETag
+	// Owner and Team are the subject and team of the caller that created
+	// the process; LastModifiedBy is the subject of the latest save.
+	Owner          string    `json:"owner"`
+	Team           string    `json:"team"`
+	LastModifiedBy string    `json:"last_modified_by"`
+	ChangeNote     string    `json:"change_note"` // why the latest save was made
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	// ArchivedAt is set while the process is archived; LastRunAt is when it
+	// last started an execution.
+	ArchivedAt *time.Time `json:"archived_at,omitempty"`
+	LastRunAt  *time.Time `json:"last_run_at,omitempty"`
+	// DeployError is why the trigger of a deployed process failed to start
+	// when the engine redeployed it on startup; cleared by the next status
+	// change.
+	DeployError string `json:"deploy_error,omitempty"`
+	// Warnings are the plain-text credentials found in the DSL by the save
+	// that returned the record; never stored.
+	Warnings []string `json:"warnings,omitempty"`
 }
 
 // ProcessSummary is a lightweight view used in listing endpoints.
 type ProcessSummary struct {
-	ID          string    `json:"id"`
-	Version     string    `json:"version"`
-	Name        string    `json:"name"`
-	Status      string    `json:"status"`
-	TriggerType string    `json:"trigger_type"` // e.g. "rest", "soap", "cron"
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID             string    `json:"id"`
+	Workspace      string    `json:"workspace"`
+	Version        string    `json:"version"`
+	Name           string    `json:"name"`
+	Status         string    `json:"status"`
+	Revision       int       `json:"revision"`
+	TriggerType    string    `json:"trigger_type"` // e.g. "rest", "soap", "cron"
+	Owner          string    `json:"owner"`
+	Team           string    `json:"team"`
+	LastModifiedBy string    `json:"last_modified_by"`
+	ChangeNote     string    `json:"change_note"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	// ArchivedAt, LastRunAt and DeployError mirror ProcessRecord.
+	ArchivedAt  *time.Time `json:"archived_at,omitempty"`
+	LastRunAt   *time.Time `json:"last_run_at,omitempty"`
+	DeployError string     `json:"deploy_error,omitempty"`
+}
+
+// SecretReference is a node of a stored process that uses a secret via secret_ref.
+type SecretReference struct {
+	ProcessID   string `json:"process_id"`
+	ProcessName string `json:"process_name"`
+	Status      string `json:"status"`
+	NodeID      string `json:"node_id"`
+	NodeType    string `json:"node_type"`
 }
 
 // ProcessStore persists and retrieves flow DSLs from the config database.
+// Read-only queries go to read, the primary unless SetReadReplica is called.
 type ProcessStore struct {
-	db *sql.DB
+	db    *sql.DB
+	read  *sql.DB
+	cache *processCache // nil unless EnableCache is called
+	// secretScan is the SetSecretScan mode; "" is SecretScanWarn.
+	secretScan string
+	// templates expands the $ref nodes of deployable processes; nil leaves
+	// them as they are.
+	templates *NodeTemplateStore
 }
 
 // NewProcessStore creates a store backed by db. The caller owns the connection.
 func NewProcessStore(db *sql.DB) *ProcessStore {
-	return &ProcessStore{db: db}
+	return &ProcessStore{db: db, read: db}
 }
 
-// Upsert inserts or updates a process definition. Status is preserved when the row
-// already exists; a new row always starts as "draft".
-func (s *ProcessStore) Upsert(ctx context.Context, proc *models.Process) (*ProcessRecord, error) {
+// SetSecretScan sets what Upsert does with a DSL holding a credential in
+// plain text: SecretScanWarn (the default), SecretScanReject or
+// SecretScanOff.
+func (s *ProcessStore) SetSecretScan(mode string) error {
+	switch mode {
+	case SecretScanWarn, SecretScanReject, SecretScanOff:
+		s.secretScan = mode
+		return nil
+	}
+	return fmt.Errorf("process_store: unknown secret scan mode %q (want warn, reject or off)", mode)
+}
+
+// SetNodeTemplates makes Deployable replace the $ref nodes of the processes
+// it loads by the templates of the node library t.
+func (s *ProcessStore) SetNodeTemplates(t *NodeTemplateStore) {
+	s.templates = t
+}
+
+// Upsert inserts or updates a process definition in the workspace carried by ctx.
+// Status is preserved when the row already exists; a new row always starts as
+// "draft" at revision 1. An id already owned by another workspace is rejected.
+//
+// The authenticated caller (see tenant.PrincipalFromContext) becomes the
+// owner and team of a new process and the last_modified_by of every save;
+// note records why the change was made and replaces the previous note.
+//
+// When ifRevision is greater than zero the update only succeeds if the stored
+// revision still equals ifRevision; otherwise ErrRevisionConflict is returned
+// so concurrent editors cannot silently overwrite each other. Zero disables
+// the check.
+//
+// The DSL is scanned for credentials written in plain text (see
+// lint.CredentialFindings) as set by SetSecretScan: they are returned in the
+// record's Warnings, or the save fails with ErrPlaintextCredential.
+func (s *ProcessStore) Upsert(ctx context.Context, proc *models.Process, ifRevision int, note string) (*ProcessRecord, error) {
+	return s.save(ctx, proc, ifRevision, note, false)
+}
+
+// Create saves proc like Upsert but only as a new process: an id already in
+// use, in any workspace, fails with ErrProcessExists.
+func (s *ProcessStore) Create(ctx context.Context, proc *models.Process, note string) (*ProcessRecord, error) {
+	return s.save(ctx, proc, 0, note, true)
+}
+
+// save implements Upsert and, with createOnly, Create.
+func (s *ProcessStore) save(ctx context.Context, proc *models.Process, ifRevision int, note string, createOnly bool) (*ProcessRecord, error) {
+	workspace := tenant.Workspace(ctx)
+	var author tenant.Principal
+	if p, ok := tenant.PrincipalFromContext(ctx); ok {
+		author = p
+	}
+	var warnings []string
+	if s.secretScan != SecretScanOff {
+		warnings = credentialWarnings(proc)
+		if len(warnings) > 0 && s.secretScan == SecretScanReject {
+			return nil, fmt.Errorf("%w: %s", ErrPlaintextCredential, strings.Join(warnings, "; "))
+		}
+	}
+	proc.Definition.Workspace = workspace
+	proc.Definition.Environment = ""
 	dslBytes, err := json.Marshal(proc)
 	if err != nil {
 		return nil, fmt.Errorf("process_store: marshal DSL: %w", err)
 	}
 
 	query := `
-		INSERT INTO processes (id, version, name, description, dsl, status, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, 'draft', NOW(), NOW())
+		INSERT INTO processes (id, workspace, version, name, description, dsl, status, revision,
+		                       owner, team, last_modified_by, change_note, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, 'draft', 1, $8, $9, $8, $10, NOW(), NOW())
 		ON CONFLICT (id) DO UPDATE
-		  SET version     = EXCLUDED.version,
-		      name        = EXCLUDED.name,
-		      description = EXCLUDED.description,
-		      dsl         = EXCLUDED.dsl,
-		      updated_at  = NOW()
-		RETURNING id, version, name, description, dsl, status, created_at, updated_at`
+		  SET version          = EXCLUDED.version,
+		      name             = EXCLUDED.name,
+		      description      = EXCLUDED.description,
+		      dsl              = EXCLUDED.dsl,
+		      revision         = processes.revision + 1,
+		      team             = COALESCE(NULLIF(processes.team, ''), EXCLUDED.team),
+		      last_modified_by = EXCLUDED.last_modified_by,
+		      change_note      = EXCLUDED.change_note,
+		      updated_at       = NOW()
+		  WHERE processes.workspace = EXCLUDED.workspace
+		    AND ($7 = 0 OR processes.revision = $7)
+		    AND NOT $11
+		RETURNING ` + recordCols
 
 	row := s.db.QueryRowContext(ctx, query,
 		proc.Definition.ID,
+		workspace,
 		proc.Definition.Version,
 		proc.Definition.Name,
 		proc.Definition.Description,
 		dslBytes,
+		ifRevision,
+		author.Subject,
+		author.Team,
+		note,
+		createOnly,
 	)
-	return scanRecord(row)
+	rec, err := scanRecord(row)
+	if err == sql.ErrNoRows && createOnly {
+		return nil, fmt.Errorf("%w: %q", ErrProcessExists, proc.Definition.ID)
+	}
+	if err == sql.ErrNoRows {
+		return nil, s.upsertRejection(ctx, proc.Definition.ID, workspace)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.cache.putRecord(rec)
+	if len(warnings) > 0 {
+		withWarnings := *rec
+		withWarnings.Warnings = warnings
+		return &withWarnings, nil
+	}
+	return rec, nil
+}
+
+// credentialWarnings describes the plain-text credentials of proc, naming the
+// node and field holding each.
+func credentialWarnings(proc *models.Process) []string {
+	var warnings []string
+	for _, f := range lint.CredentialFindings(proc) {
+		if f.NodeID != "" {
+			warnings = append(warnings, "node "+f.NodeID+": "+f.Message)
+		} else {
+			warnings = append(warnings, f.Message)
+		}
+	}
+	return warnings
+}
+
+// upsertRejection explains why the guarded ON CONFLICT update touched no row:
+// either the id belongs to another workspace or the revision check failed.
+func (s *ProcessStore) upsertRejection(ctx context.Context, id, workspace string) error {
+	var owner string
+	var current int
+	err := s.db.QueryRowContext(ctx,
+		`SELECT workspace, revision FROM processes WHERE id = $1`, id).Scan(&owner, &current)
+	if err != nil {
+		return fmt.Errorf("process_store: upsert %q: %w", id, err)
+	}
+	if owner != workspace {
+		return fmt.Errorf("process_store: process id %q is already in use", id)
+	}
+	return fmt.Errorf("%w: process %q is at revision %d", ErrRevisionConflict, id, current)
 }
 
 // Get returns the full process record for id, or an error if not found.
+// It is served from the cache when enabled, otherwise from the read replica.
 func (s *ProcessStore) Get(ctx context.Context, id string) (*ProcessRecord, error) {
-	query := `
-		SELECT id, version, name, description, dsl, status, created_at, updated_at
-		FROM processes WHERE id = $1`
-	row := s.db.QueryRowContext(ctx, query, id)
+	if rec := s.cache.record(id, tenant.Workspace(ctx)); rec != nil {
+		return rec, nil
+	}
+	rec, err := s.get(ctx, s.read, id)
+	if err != nil {
+		return nil, err
+	}
+	s.cache.putRecord(rec)
+	return rec, nil
+}
+
+// get reads the record of id from db, bypassing the cache.
+func (s *ProcessStore) get(ctx context.Context, db *sql.DB, id string) (*ProcessRecord, error) {
+	query := `SELECT ` + recordCols + ` FROM processes WHERE id = $1 AND workspace = $2`
+	row := db.QueryRowContext(ctx, query, id, tenant.Workspace(ctx))
 	rec, err := scanRecord(row)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("process_store: process %q not found", id)
+			return nil, fmt.Errorf("%w: %q", ErrProcessNotFound, id)
 		}
 		return nil, fmt.Errorf("process_store: get %q: %w", id, err)
 	}
 	return rec, nil
 }
 
-// List returns summaries of all processes, optionally filtered by status.
-// An empty statusFilter returns all rows.
-func (s *ProcessStore) List(ctx context.Context, statusFilter string) ([]ProcessSummary, error) {
+// List returns summaries of the workspace's processes, optionally filtered by status.
+// An empty statusFilter returns all rows except archived ones, which are only
+// included with includeArchived (or a statusFilter of "archived").
+func (s *ProcessStore) List(ctx context.Context, statusFilter string, includeArchived bool) ([]ProcessSummary, error) {
 	var (
 		rows *sql.Rows
 		err  error
 	)
-	const baseCols = `id, version, name, status, COALESCE(dsl->'trigger'->>'type', '') AS trigger_type, updated_at`
+	workspace := tenant.Workspace(ctx)
 	if statusFilter != "" {
-		rows, err = s.db.QueryContext(ctx,
-			`SELECT `+baseCols+` FROM processes WHERE status = $1 ORDER BY updated_at DESC`,
-			statusFilter)
+		rows, err = s.read.QueryContext(ctx,
+			`SELECT `+summaryCols+` FROM processes WHERE workspace = $1 AND status = $2 ORDER BY updated_at DESC`,
+			workspace, statusFilter)
 	} else {
-		rows, err = s.db.QueryContext(ctx,
-			`SELECT `+baseCols+` FROM processes ORDER BY updated_at DESC`)
+		rows, err = s.read.QueryContext(ctx,
+			`SELECT `+summaryCols+` FROM processes WHERE workspace = $1 AND ($2 OR status <> 'archived') ORDER BY updated_at DESC`,
+			workspace, includeArchived)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("process_store: list: %w", err)
 	}
-	defer rows.Close()
+	return scanSummaries(rows)
+}
+
+// ListDeployed returns the summaries of the deployed processes of every
+// workspace, so the engine can restart their triggers on startup. It reads
+// the primary: a lagging replica could miss a recent deploy.
+func (s *ProcessStore) ListDeployed(ctx context.Context) ([]ProcessSummary, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+summaryCols+` FROM processes WHERE status = 'deployed' ORDER BY workspace, id`)
+	if err != nil {
+		return nil, fmt.Errorf("process_store: list deployed: %w", err)
+	}
+	return scanSummaries(rows)
+}
 
+// summaryCols is the column list scanned by scanSummaries.
+const summaryCols = `id, workspace, version, name, status, revision, COALESCE(dsl->'trigger'->>'type', '') AS trigger_type,
+	owner, team, last_modified_by, change_note, updated_at, archived_at, last_run_at, deploy_error`
+
+// scanSummaries reads and closes rows returned by List / ListDeployed.
+func scanSummaries(rows *sql.Rows) ([]ProcessSummary, error) {
+	defer rows.Close()
 	var result []ProcessSummary
 	for rows.Next() {
 		var s ProcessSummary
-		if err := rows.Scan(&s.ID, &s.Version, &s.Name, &s.Status, &s.TriggerType, &s.UpdatedAt); err != nil {
+		if err := rows.Scan(&s.ID, &s.Workspace, &s.Version, &s.Name, &s.Status, &s.Revision, &s.TriggerType,
+			&s.Owner, &s.Team, &s.LastModifiedBy, &s.ChangeNote, &s.UpdatedAt, &s.ArchivedAt, &s.LastRunAt, &s.DeployError); err != nil {
 			return nil, fmt.Errorf("process_store: scan summary: %w", err)
 		}
 		result = append(result, s)
@@ -121,22 +367,105 @@ func (s *ProcessStore) List(ctx context.Context, statusFilter string) ([]Process
 	return result, rows.Err()
 }
 
-// Delete removes a process from the store. It is a no-op when the id does not exist.
-func (s *ProcessStore) Delete(ctx context.Context, id string) error {
-	_, err := s.db.ExecContext(ctx, `DELETE FROM processes WHERE id = $1`, id)
+// Archive soft-deletes a process: its status becomes "archived" and the
+// status to restore is kept, with "deployed" restored as "stopped" since the
+// caller stops its trigger. Archiving an archived process is a no-op.
+func (s *ProcessStore) Archive(ctx context.Context, id string) error {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE processes
+		SET restore_status = CASE status WHEN 'archived' THEN restore_status WHEN 'deployed' THEN 'stopped' ELSE status END,
+		    archived_at    = COALESCE(archived_at, NOW()),
+		    status         = 'archived',
+		    updated_at     = NOW()
+		WHERE id = $1 AND workspace = $2`,
+		id, tenant.Workspace(ctx))
+	if err != nil {
+		return fmt.Errorf("process_store: archive %q: %w", id, err)
+	}
+	s.cache.invalidate(id)
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("%w: %q", ErrProcessNotFound, id)
+	}
+	return nil
+}
+
+// Restore returns an archived process to the status it had when it was
+// archived (a deployed process comes back stopped).
+func (s *ProcessStore) Restore(ctx context.Context, id string) (*ProcessRecord, error) {
+	row := s.db.QueryRowContext(ctx, `
+		UPDATE processes
+		SET status = COALESCE(NULLIF(restore_status, ''), 'draft'), restore_status = '',
+		    archived_at = NULL, updated_at = NOW()
+		WHERE id = $1 AND workspace = $2 AND status = 'archived'
+		RETURNING `+recordCols,
+		id, tenant.Workspace(ctx))
+	rec, err := scanRecord(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, s.notArchived(ctx, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("process_store: restore %q: %w", id, err)
+	}
+	s.cache.putRecord(rec)
+	return rec, nil
+}
+
+// Purge permanently removes an archived process. It refuses, with
+// ErrRecentlyExecuted, a process that started an execution within protect
+// of now; zero disables the check.
+func (s *ProcessStore) Purge(ctx context.Context, id string, protect time.Duration) error {
+	var lastRun sql.NullTime
+	row := s.db.QueryRowContext(ctx, `
+		SELECT last_run_at FROM processes
+		WHERE id = $1 AND workspace = $2 AND status = 'archived'`,
+		id, tenant.Workspace(ctx))
+	if err := row.Scan(&lastRun); errors.Is(err, sql.ErrNoRows) {
+		return s.notArchived(ctx, id)
+	} else if err != nil {
+		return fmt.Errorf("process_store: purge %q: %w", id, err)
+	}
+	if protect > 0 && lastRun.Valid && time.Since(lastRun.Time) < protect {
+		return fmt.Errorf("%w: %q last ran at %s", ErrRecentlyExecuted, id, lastRun.Time.UTC().Format(time.RFC3339))
+	}
+	_, err := s.db.ExecContext(ctx, `DELETE FROM processes WHERE id = $1 AND workspace = $2 AND status = 'archived'`,
+		id, tenant.Workspace(ctx))
+	if err != nil {
+		return fmt.Errorf("process_store: purge %q: %w", id, err)
+	}
+	s.cache.invalidate(id)
+	return nil
+}
+
+// notArchived explains why a Restore or Purge matched no row.
+func (s *ProcessStore) notArchived(ctx context.Context, id string) error {
+	if _, err := s.Get(ctx, id); err != nil {
+		return err
+	}
+	return fmt.Errorf("%w: %q", ErrNotArchived, id)
+}
+
+// RecordRun sets the last_run_at of process id to at. Process ids are
+// globally unique, so no workspace is needed.
+func (s *ProcessStore) RecordRun(ctx context.Context, id string, at time.Time) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE processes SET last_run_at = $2 WHERE id = $1`, id, at)
 	if err != nil {
-		return fmt.Errorf("process_store: delete %q: %w", id, err)
+		return fmt.Errorf("process_store: record run %q: %w", id, err)
 	}
+	s.cache.recordRun(id, at)
 	return nil
 }
 
-// UpdateStatus sets the status column for id (draft | deployed | stopped).
+// UpdateStatus sets the status column for id (draft | deployed | stopped)
+// and clears its deploy error. Archived processes are left untouched; use
+// Restore first.
 func (s *ProcessStore) UpdateStatus(ctx context.Context, id, status string) error {
 	result, err := s.db.ExecContext(ctx,
-		`UPDATE processes SET status = $1, updated_at = NOW() WHERE id = $2`, status, id)
+		`UPDATE processes SET status = $1, deploy_error = '', updated_at = NOW() WHERE id = $2 AND workspace = $3 AND status <> 'archived'`,
+		status, id, tenant.Workspace(ctx))
 	if err != nil {
 		return fmt.Errorf("process_store: update status %q → %q: %w", id, status, err)
 	}
+	s.cache.invalidate(id)
 	rows, _ := result.RowsAffected()
 	if rows == 0 {
 		return fmt.Errorf("process_store: process %q not found", id)
@@ -144,27 +473,109 @@ func (s *ProcessStore) UpdateStatus(ctx context.Context, id, status string) erro
 	return nil
 }
 
-// ParseDSL deserialises the stored JSON back into a models.Process.
+// SetDeployError records why the trigger of deployed process id failed to
+// start. The process stays deployed, so the next startup retries it.
+func (s *ProcessStore) SetDeployError(ctx context.Context, id, msg string) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE processes SET deploy_error = $1 WHERE id = $2 AND workspace = $3`,
+		msg, id, tenant.Workspace(ctx))
+	if err != nil {
+		return fmt.Errorf("process_store: set deploy error %q: %w", id, err)
+	}
+	s.cache.invalidate(id)
+	return nil
+}
+
+// SecretReferences lists the nodes of the workspace's stored processes whose
+// secret_ref is secretID. The JSONB containment filter narrows the rows; the
+// nodes themselves are matched in Go.
+func (s *ProcessStore) SecretReferences(ctx context.Context, secretID string) ([]SecretReference, error) {
+	filter, err := json.Marshal(map[string]interface{}{
+		"nodes": []map[string]string{{"secret_ref": secretID}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("process_store: secret references: %w", err)
+	}
+	rows, err := s.read.QueryContext(ctx,
+		`SELECT id, name, status, dsl FROM processes WHERE workspace = $1 AND dsl @> $2::jsonb ORDER BY id`,
+		tenant.Workspace(ctx), string(filter))
+	if err != nil {
+		return nil, fmt.Errorf("process_store: secret references %q: %w", secretID, err)
+	}
+	defer rows.Close()
+
+	var result []SecretReference
+	for rows.Next() {
+		var rec ProcessRecord
+		if err := rows.Scan(&rec.ID, &rec.Name, &rec.Status, &rec.DSL); err != nil {
+			return nil, fmt.Errorf("process_store: scan secret reference: %w", err)
+		}
+		refs, err := rec.SecretReferences(secretID)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, refs...)
+	}
+	return result, rows.Err()
+}
+
+// SecretReferences returns the nodes of the record's DSL whose secret_ref is secretID.
+func (r *ProcessRecord) SecretReferences(secretID string) ([]SecretReference, error) {
+	proc, err := r.ParseDSL()
+	if err != nil {
+		return nil, err
+	}
+	var refs []SecretReference
+	for _, node := range proc.Nodes {
+		if node.SecretRef == secretID {
+			refs = append(refs, SecretReference{
+				ProcessID:   r.ID,
+				ProcessName: r.Name,
+				Status:      r.Status,
+				NodeID:      node.ID,
+				NodeType:    node.Type,
+			})
+		}
+	}
+	return refs, nil
+}
+
+// ParseDSL deserialises the stored JSON back into a models.Process. The owning
+// workspace is taken from the record, never from the stored DSL.
 func (r *ProcessRecord) ParseDSL() (*models.Process, error) {
 	var proc models.Process
 	if err := json.Unmarshal(r.DSL, &proc); err != nil {
 		return nil, fmt.Errorf("process_store: parse DSL for %q: %w", r.ID, err)
 	}
+	proc.Definition.Workspace = tenant.Normalize(r.Workspace)
+	proc.Definition.Revision = r.Revision
 	return &proc, nil
 }
 
+// recordCols is the column list scanned by scanRecord.
+const recordCols = `id, workspace, version, name, description, dsl, status, revision,
+	owner, team, last_modified_by, change_note, created_at, updated_at, archived_at, last_run_at, deploy_error`
+
 // scanRecord reads one row returned by Upsert / Get.
 func scanRecord(row *sql.Row) (*ProcessRecord, error) {
 	var rec ProcessRecord
 	err := row.Scan(
 		&rec.ID,
+		&rec.Workspace,
 		&rec.Version,
 		&rec.Name,
 		&rec.Description,
 		&rec.DSL,
 		&rec.Status,
+		&rec.Revision,
+		&rec.Owner,
+		&rec.Team,
+		&rec.LastModifiedBy,
+		&rec.ChangeNote,
 		&rec.CreatedAt,
 		&rec.UpdatedAt,
+		&rec.ArchivedAt,
+		&rec.LastRunAt,
+		&rec.DeployError,
 	)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,156 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"flowjs-works/engine/internal/models"
+	"flowjs-works/engine/internal/tenant"
+)
+
+// ErrProcessTemplateNotFound is returned when no process template has the
+// requested name in the caller's workspace.
+var ErrProcessTemplateNotFound = errors.New("process_template_store: template not found")
+
+// ProcessTemplate is a standard flow of the template gallery that teams stamp
+// out per-customer variants of (see models.Process.Clone). Parameters are
+// the {{param.<name>}} placeholders of its DSL.
+type ProcessTemplate struct {
+	Name        string          `json:"name"`
+	Workspace   string          `json:"workspace"`
+	Description string          `json:"description"`
+	DSL         json.RawMessage `json:"dsl,omitempty"`
+	Parameters  []string        `json:"parameters"`
+	CreatedBy   string          `json:"created_by"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// ProcessTemplateStore persists the template gallery in the config database.
+// Template names are unique per workspace, like node template names.
+type ProcessTemplateStore struct {
+	db *sql.DB
+}
+
+// NewProcessTemplateStore creates a store backed by db. The caller owns the
+// connection.
+func NewProcessTemplateStore(db *sql.DB) *ProcessTemplateStore {
+	return &ProcessTemplateStore{db: db}
+}
+
+// Save creates or replaces template name of the workspace carried by ctx
+// with proc. The authenticated caller (see tenant.PrincipalFromContext) is
+// recorded as its author.
+func (s *ProcessTemplateStore) Save(ctx context.Context, name, description string, proc *models.Process) (*ProcessTemplate, error) {
+	if !models.ValidNodeTemplateName(name) {
+		return nil, fmt.Errorf("process_template_store: invalid template name %q", name)
+	}
+	tmpl := *proc
+	tmpl.Definition.Workspace = ""
+	tmpl.Definition.Environment = ""
+	dsl, err := json.Marshal(&tmpl)
+	if err != nil {
+		return nil, fmt.Errorf("process_template_store: marshal %q: %w", name, err)
+	}
+	params := proc.Parameters()
+	paramsJSON, err := json.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("process_template_store: marshal parameters of %q: %w", name, err)
+	}
+	t := ProcessTemplate{Name: name, Workspace: tenant.Workspace(ctx), Description: description, DSL: dsl, Parameters: params}
+	if p, ok := tenant.PrincipalFromContext(ctx); ok {
+		t.CreatedBy = p.Subject
+	}
+	err = s.db.QueryRowContext(ctx, `
+		INSERT INTO process_templates (workspace, name, description, dsl, parameters, created_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW())
+		ON CONFLICT (workspace, name) DO UPDATE
+		  SET description = EXCLUDED.description,
+		      dsl         = EXCLUDED.dsl,
+		      parameters  = EXCLUDED.parameters,
+		      created_by  = EXCLUDED.created_by,
+		      updated_at  = NOW()
+		RETURNING updated_at`,
+		t.Workspace, name, description, dsl, paramsJSON, t.CreatedBy).Scan(&t.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("process_template_store: save %q: %w", name, err)
+	}
+	return &t, nil
+}
+
+// Get returns template name of the workspace carried by ctx with its DSL.
+func (s *ProcessTemplateStore) Get(ctx context.Context, name string) (*ProcessTemplate, error) {
+	t := ProcessTemplate{Name: name, Workspace: tenant.Workspace(ctx)}
+	var params []byte
+	err := s.db.QueryRowContext(ctx, `
+		SELECT description, dsl, parameters, created_by, updated_at FROM process_templates
+		WHERE workspace = $1 AND name = $2`,
+		t.Workspace, name).Scan(&t.Description, &t.DSL, &params, &t.CreatedBy, &t.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %q", ErrProcessTemplateNotFound, name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("process_template_store: get %q: %w", name, err)
+	}
+	if err := json.Unmarshal(params, &t.Parameters); err != nil {
+		return nil, fmt.Errorf("process_template_store: parse parameters of %q: %w", name, err)
+	}
+	return &t, nil
+}
+
+// Process returns the DSL of template name of the workspace carried by ctx.
+func (s *ProcessTemplateStore) Process(ctx context.Context, name string) (*models.Process, error) {
+	t, err := s.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	var proc models.Process
+	if err := json.Unmarshal(t.DSL, &proc); err != nil {
+		return nil, fmt.Errorf("process_template_store: parse %q: %w", name, err)
+	}
+	return &proc, nil
+}
+
+// List returns the templates of the workspace carried by ctx without their
+// DSL, ordered by name.
+func (s *ProcessTemplateStore) List(ctx context.Context) ([]ProcessTemplate, error) {
+	workspace := tenant.Workspace(ctx)
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT name, description, parameters, created_by, updated_at FROM process_templates
+		WHERE workspace = $1 ORDER BY name`, workspace)
+	if err != nil {
+		return nil, fmt.Errorf("process_template_store: list: %w", err)
+	}
+	defer rows.Close()
+
+	var result []ProcessTemplate
+	for rows.Next() {
+		t := ProcessTemplate{Workspace: workspace}
+		var params []byte
+		if err := rows.Scan(&t.Name, &t.Description, &params, &t.CreatedBy, &t.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("process_template_store: scan template: %w", err)
+		}
+		if err := json.Unmarshal(params, &t.Parameters); err != nil {
+			return nil, fmt.Errorf("process_template_store: parse parameters of %q: %w", t.Name, err)
+		}
+		result = append(result, t)
+	}
+	return result, rows.Err()
+}
+
+// Delete removes template name from the workspace carried by ctx. Processes
+// created from it are not affected.
+func (s *ProcessTemplateStore) Delete(ctx context.Context, name string) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM process_templates WHERE workspace = $1 AND name = $2`,
+		tenant.Workspace(ctx), name)
+	if err != nil {
+		return fmt.Errorf("process_template_store: delete %q: %w", name, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w: %q", ErrProcessTemplateNotFound, name)
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"flowjs-works/engine/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProcessTemplateStore_New(t *testing.T) {
+	assert.NotNil(t, NewProcessTemplateStore(nil))
+}
+
+func TestProcessTemplateStore_SaveRejectsInvalidName(t *testing.T) {
+	_, err := NewProcessTemplateStore(nil).Save(context.Background(), "orders/acme", "", &models.Process{})
+	assert.ErrorContains(t, err, "invalid template name")
+}
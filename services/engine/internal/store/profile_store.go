@@ -0,0 +1,118 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"flowjs-works/engine/internal/models"
+	"flowjs-works/engine/internal/tenant"
+)
+
+// ErrProfileNotFound is returned when no connection profile has the requested
+// name in the caller's workspace.
+var ErrProfileNotFound = errors.New("profile_store: profile not found")
+
+// ProfileStore persists connection profiles in the config database. Profile
+// names are unique per workspace. It implements engine.ProfileSource.
+type ProfileStore struct {
+	db *sql.DB
+}
+
+// NewProfileStore creates a store backed by db. The caller owns the connection.
+func NewProfileStore(db *sql.DB) *ProfileStore {
+	return &ProfileStore{db: db}
+}
+
+// profileCols is the column list scanned by scanProfile.
+const profileCols = `name, workspace, type, description, config, secret_ref, updated_at`
+
+// Upsert creates or replaces a profile in the workspace carried by ctx.
+func (s *ProfileStore) Upsert(ctx context.Context, p *models.ConnectionProfile) (*models.ConnectionProfile, error) {
+	config, err := json.Marshal(p.Config)
+	if err != nil {
+		return nil, fmt.Errorf("profile_store: marshal profile %q: %w", p.Name, err)
+	}
+	row := s.db.QueryRowContext(ctx, `
+		INSERT INTO connection_profiles (workspace, name, type, description, config, secret_ref, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW())
+		ON CONFLICT (workspace, name) DO UPDATE
+		  SET type        = EXCLUDED.type,
+		      description = EXCLUDED.description,
+		      config      = EXCLUDED.config,
+		      secret_ref  = EXCLUDED.secret_ref,
+		      updated_at  = NOW()
+		RETURNING `+profileCols,
+		tenant.Workspace(ctx), p.Name, p.Type, p.Description, config, p.SecretRef)
+	saved, err := scanProfile(row)
+	if err != nil {
+		return nil, fmt.Errorf("profile_store: upsert %q: %w", p.Name, err)
+	}
+	return saved, nil
+}
+
+// Profile returns profile name in the workspace carried by ctx, or
+// ErrProfileNotFound.
+func (s *ProfileStore) Profile(ctx context.Context, name string) (*models.ConnectionProfile, error) {
+	row := s.db.QueryRowContext(ctx,
+		`SELECT `+profileCols+` FROM connection_profiles WHERE workspace = $1 AND name = $2`,
+		tenant.Workspace(ctx), name)
+	p, err := scanProfile(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %q", ErrProfileNotFound, name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("profile_store: get %q: %w", name, err)
+	}
+	return p, nil
+}
+
+// List returns the workspace's profiles ordered by name.
+func (s *ProfileStore) List(ctx context.Context) ([]models.ConnectionProfile, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+profileCols+` FROM connection_profiles WHERE workspace = $1 ORDER BY name`,
+		tenant.Workspace(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("profile_store: list: %w", err)
+	}
+	defer rows.Close()
+
+	var result []models.ConnectionProfile
+	for rows.Next() {
+		p, err := scanProfile(rows)
+		if err != nil {
+			return nil, fmt.Errorf("profile_store: scan profile: %w", err)
+		}
+		result = append(result, *p)
+	}
+	return result, rows.Err()
+}
+
+// Delete removes profile name from the workspace carried by ctx.
+func (s *ProfileStore) Delete(ctx context.Context, name string) error {
+	result, err := s.db.ExecContext(ctx,
+		`DELETE FROM connection_profiles WHERE workspace = $1 AND name = $2`,
+		tenant.Workspace(ctx), name)
+	if err != nil {
+		return fmt.Errorf("profile_store: delete %q: %w", name, err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("%w: %q", ErrProfileNotFound, name)
+	}
+	return nil
+}
+
+// scanProfile reads a row of profileCols.
+func scanProfile(row rowScanner) (*models.ConnectionProfile, error) {
+	var p models.ConnectionProfile
+	var config []byte
+	if err := row.Scan(&p.Name, &p.Workspace, &p.Type, &p.Description, &config, &p.SecretRef, &p.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(config, &p.Config); err != nil {
+		return nil, fmt.Errorf("decode config of %q: %w", p.Name, err)
+	}
+	return &p, nil
+}
@@ -0,0 +1,11 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfileStore_New(t *testing.T) {
+	assert.NotNil(t, NewProfileStore(nil))
+}
@@ -0,0 +1,170 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// QueuedJob is a trigger-fired execution waiting for (or held by) a worker.
+// The process definition is snapshotted at enqueue time so a job restored
+// after a restart runs the version that was deployed when it fired. A pending
+// job is reserved for the engine that enqueued it, where its caller waits for
+// the result, for as long as that engine renews the reservation.
+type QueuedJob struct {
+	ID        string `json:"id"`
+	Workspace string `json:"workspace"`
+	ProcessID string `json:"process_id"`
+	Priority  int    `json:"priority"`
+	// Lane is the execution lane whose workers run the job.
+	Lane        string          `json:"lane"`
+	Process     json.RawMessage `json:"process"`
+	TriggerData json.RawMessage `json:"trigger_data"`
+	EnqueuedAt  time.Time       `json:"enqueued_at"`
+}
+
+// QueueStore persists the execution queue in the config database.
+type QueueStore struct {
+	db *sql.DB
+	// owner identifies this engine instance in the claims it takes.
+	owner string
+	lease time.Duration
+}
+
+// NewQueueStore creates a store backed by db. The caller owns the connection.
+// Claims are taken in the name of the hostname until SetOwner is called.
+func NewQueueStore(db *sql.DB) *QueueStore {
+	owner, _ := os.Hostname()
+	return &QueueStore{db: db, owner: owner, lease: DefaultClaimLease}
+}
+
+// SetOwner sets the identifier of this engine instance recorded with the jobs
+// it claims. Every replica sharing the database needs its own.
+func (s *QueueStore) SetOwner(id string) {
+	s.owner = id
+}
+
+// Enqueue inserts job as pending, reserved for this engine (see Claim).
+// job.ID must be set by the caller so it can wait for the result before the
+// insert is visible to workers.
+func (s *QueueStore) Enqueue(ctx context.Context, job *QueuedJob) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO execution_queue (id, workspace, process_id, priority, lane, process, trigger_data, status, enqueued_at,
+		                             claimed_by, lease_expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, 'pending', NOW(), $8, NOW() + make_interval(secs => $9))`,
+		job.ID, job.Workspace, job.ProcessID, job.Priority, job.Lane, []byte(job.Process), []byte(job.TriggerData),
+		s.owner, s.lease.Seconds())
+	if err != nil {
+		return fmt.Errorf("queue_store: enqueue %q: %w", job.ProcessID, err)
+	}
+	return nil
+}
+
+// Claim moves the next pending job of lane to "running" and returns it, or
+// nil when the lane has no pending job. Jobs are ordered by priority (highest first), then by
+// the number of jobs of the same process already running (fewest first) so a
+// burst from one process cannot starve others of equal priority, then FIFO.
+// FOR UPDATE SKIP LOCKED lets several engine replicas share the queue. Only
+// the jobs reserved for this engine, or whose reservation lapsed because the
+// engine that enqueued them stopped, are claimed: the caller waiting for a job
+// is only woken by a worker of its own engine. The claim is leased to this
+// engine (see RenewClaims).
+func (s *QueueStore) Claim(ctx context.Context, lane string) (*QueuedJob, error) {
+	row := s.db.QueryRowContext(ctx, `
+		UPDATE execution_queue
+		SET status = 'running', started_at = NOW(), claimed_by = $2, lease_expires_at = NOW() + make_interval(secs => $3)
+		WHERE id = (
+			SELECT q.id FROM execution_queue q
+			WHERE q.status = 'pending' AND q.lane = $1
+			  AND (q.claimed_by IN ('', $2) OR q.lease_expires_at IS NULL OR q.lease_expires_at < NOW())
+			ORDER BY q.priority DESC,
+				(SELECT COUNT(*) FROM execution_queue r
+				 WHERE r.process_id = q.process_id AND r.status = 'running'),
+				q.enqueued_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED)
+		RETURNING id, workspace, process_id, priority, lane, process, trigger_data, enqueued_at`,
+		lane, s.owner, s.lease.Seconds())
+
+	var job QueuedJob
+	var proc, data []byte
+	err := row.Scan(&job.ID, &job.Workspace, &job.ProcessID, &job.Priority, &job.Lane, &proc, &data, &job.EnqueuedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("queue_store: claim job: %w", err)
+	}
+	job.Process = json.RawMessage(proc)
+	job.TriggerData = json.RawMessage(data)
+	return &job, nil
+}
+
+// Remove deletes a finished or withdrawn job. Outcomes are recorded by the audit trail, so
+// the queue only keeps work that has not completed.
+func (s *QueueStore) Remove(ctx context.Context, id string) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM execution_queue WHERE id::text = $1`, id); err != nil {
+		return fmt.Errorf("queue_store: remove %q: %w", id, err)
+	}
+	return nil
+}
+
+// RenewClaims extends the lease of every job this engine is running or
+// reserved, and returns their IDs. It must be called well within
+// DefaultClaimLease of the enqueue or claim and of the previous renewal.
+func (s *QueueStore) RenewClaims(ctx context.Context) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		UPDATE execution_queue SET lease_expires_at = NOW() + make_interval(secs => $2)
+		WHERE claimed_by = $1
+		RETURNING id::text`, s.owner, s.lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("queue_store: renew claims: %w", err)
+	}
+	defer rows.Close()
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("queue_store: renew claims: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("queue_store: renew claims: %w", err)
+	}
+	return ids, nil
+}
+
+// DiscardInterrupted deletes the jobs left in "running" by a previous process
+// of this engine, which is starting and runs nothing yet, and those whose
+// lease expired because the engine running them stopped. Jobs of other live
+// replicas are left alone. Like scheduled runs, queued jobs execute at most
+// once.
+func (s *QueueStore) DiscardInterrupted(ctx context.Context) (int64, error) {
+	return s.discardClaims(ctx, s.owner)
+}
+
+// DiscardExpired deletes the jobs whose lease expired because the engine
+// running them stopped.
+func (s *QueueStore) DiscardExpired(ctx context.Context) (int64, error) {
+	return s.discardClaims(ctx, "")
+}
+
+// discardClaims deletes the running jobs claimed by owner, unless empty, or
+// whose lease expired. Jobs claimed before leases were recorded have none and
+// count as expired.
+func (s *QueueStore) discardClaims(ctx context.Context, owner string) (int64, error) {
+	result, err := s.db.ExecContext(ctx, `
+		DELETE FROM execution_queue
+		WHERE status = 'running'
+		  AND ((claimed_by = $1 AND $1 <> '') OR lease_expires_at IS NULL OR lease_expires_at < NOW())`, owner)
+	if err != nil {
+		return 0, fmt.Errorf("queue_store: discard interrupted jobs: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return n, nil
+}
@@ -0,0 +1,92 @@
+package store
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+
+	"flowjs-works/engine/internal/store/storetest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueueStore_New(t *testing.T) {
+	assert.NotNil(t, NewQueueStore(nil))
+}
+
+func TestQueueStore_ClaimLeasesToOwner(t *testing.T) {
+	db, fake := storetest.Open(t)
+	fake.On("UPDATE execution_queue", func([]driver.Value) storetest.Result { return storetest.Result{} })
+	s := NewQueueStore(db)
+	s.SetOwner("engine-1")
+
+	job, err := s.Claim(context.Background(), "default")
+	require.NoError(t, err)
+	assert.Nil(t, job)
+
+	stmt, ok := fake.Find("UPDATE execution_queue")
+	require.True(t, ok)
+	assert.Contains(t, stmt.SQL, "claimed_by = $2")
+	assert.Contains(t, stmt.SQL, "lease_expires_at = NOW() + make_interval(secs => $3)")
+	assert.Equal(t, []driver.Value{"default", "engine-1", DefaultClaimLease.Seconds()}, stmt.Args)
+	assert.Contains(t, stmt.SQL, "AND (q.claimed_by IN ('', $2) OR q.lease_expires_at IS NULL OR q.lease_expires_at < NOW())",
+		"jobs reserved for another live engine are skipped")
+}
+
+func TestQueueStore_EnqueueReservesForOwner(t *testing.T) {
+	db, fake := storetest.Open(t)
+	fake.On("INSERT INTO execution_queue", func([]driver.Value) storetest.Result { return storetest.Result{RowsAffected: 1} })
+	s := NewQueueStore(db)
+	s.SetOwner("engine-1")
+
+	job := &QueuedJob{ID: "job-1", Workspace: "default", ProcessID: "orders", Lane: "default",
+		Process: []byte(`{}`), TriggerData: []byte(`{}`)}
+	require.NoError(t, s.Enqueue(context.Background(), job))
+
+	stmt, ok := fake.Find("INSERT INTO execution_queue")
+	require.True(t, ok)
+	assert.Contains(t, stmt.SQL, "'pending', NOW(), $8, NOW() + make_interval(secs => $9)")
+	assert.Equal(t, "engine-1", stmt.Args[7])
+	assert.Equal(t, DefaultClaimLease.Seconds(), stmt.Args[8])
+}
+
+func TestQueueStore_RenewClaimsReturnsHeldJobs(t *testing.T) {
+	db, fake := storetest.Open(t)
+	fake.On("UPDATE execution_queue SET lease_expires_at", func([]driver.Value) storetest.Result {
+		return storetest.Result{Columns: []string{"id"}, Rows: [][]driver.Value{{"job-1"}, {"job-2"}}}
+	})
+	s := NewQueueStore(db)
+	s.SetOwner("engine-1")
+
+	ids, err := s.RenewClaims(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"job-1", "job-2"}, ids)
+
+	stmt, _ := fake.Find("UPDATE execution_queue SET lease_expires_at")
+	assert.NotContains(t, stmt.SQL, "status", "reservations of pending jobs are renewed too")
+	assert.Equal(t, []driver.Value{"engine-1", DefaultClaimLease.Seconds()}, stmt.Args)
+}
+
+func TestQueueStore_DiscardInterruptedSparesLiveReplicas(t *testing.T) {
+	db, fake := storetest.Open(t)
+	fake.On("DELETE FROM execution_queue", func([]driver.Value) storetest.Result { return storetest.Result{RowsAffected: 3} })
+	s := NewQueueStore(db)
+	s.SetOwner("engine-1")
+
+	n, err := s.DiscardInterrupted(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+	_, err = s.DiscardExpired(context.Background())
+	require.NoError(t, err)
+
+	stmts := fake.Statements()
+	require.Len(t, stmts, 2)
+	for _, stmt := range stmts {
+		assert.Contains(t, stmt.SQL, "WHERE status = 'running'")
+		assert.Contains(t, stmt.SQL, "(claimed_by = $1 AND $1 <> '') OR lease_expires_at IS NULL OR lease_expires_at < NOW()",
+			"only jobs of this engine or with an expired lease are discarded")
+	}
+	assert.Equal(t, []driver.Value{"engine-1"}, stmts[0].Args, "on start the jobs of this engine are discarded")
+	assert.Equal(t, []driver.Value{""}, stmts[1].Args, "sweeps only discard expired leases")
+}
@@ -0,0 +1,262 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"flowjs-works/engine/internal/tenant"
+
+	"github.com/google/uuid"
+)
+
+// Scheduled run statuses.
+const (
+	RunStatusPending   = "pending"
+	RunStatusRunning   = "running"
+	RunStatusDone      = "done"
+	RunStatusFailed    = "failed"
+	RunStatusCancelled = "cancelled"
+)
+
+// ScheduledRun is a one-shot execution of a process planned for RunAt.
+// Rows live in the scheduled_runs table so pending runs survive restarts.
+type ScheduledRun struct {
+	ID          string          `json:"id"`
+	Workspace   string          `json:"workspace"`
+	ProcessID   string          `json:"process_id"`
+	RunAt       time.Time       `json:"run_at"`
+	TriggerData json.RawMessage `json:"trigger_data"`
+	Status      string          `json:"status"` // pending | running | done | failed | cancelled
+	ExecutionID string          `json:"execution_id,omitempty"`
+	Error       string          `json:"error,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// DefaultClaimLease is how long a claimed scheduled run or queued job stays
+// owned by the engine that claimed it without the claim being renewed.
+const DefaultClaimLease = time.Minute
+
+// ScheduleStore persists one-shot scheduled runs in the config database.
+type ScheduleStore struct {
+	db *sql.DB
+	// owner identifies this engine instance in the claims it takes.
+	owner string
+	lease time.Duration
+}
+
+// NewScheduleStore creates a store backed by db. The caller owns the
+// connection. Claims are taken in the name of the hostname until SetOwner is
+// called.
+func NewScheduleStore(db *sql.DB) *ScheduleStore {
+	owner, _ := os.Hostname()
+	return &ScheduleStore{db: db, owner: owner, lease: DefaultClaimLease}
+}
+
+// SetOwner sets the identifier of this engine instance recorded with the runs
+// it claims. Every replica sharing the database needs its own.
+func (s *ScheduleStore) SetOwner(id string) {
+	s.owner = id
+}
+
+// scheduledRunCols is the column list scanned by scanScheduledRun.
+const scheduledRunCols = `id, workspace, process_id, run_at, trigger_data, status,
+	COALESCE(execution_id, ''), COALESCE(error, ''), created_at, updated_at`
+
+// Create stores a new pending run of processID at runAt in the workspace
+// carried by ctx.
+func (s *ScheduleStore) Create(ctx context.Context, processID string, runAt time.Time, triggerData map[string]interface{}) (*ScheduledRun, error) {
+	if triggerData == nil {
+		triggerData = map[string]interface{}{}
+	}
+	data, err := json.Marshal(triggerData)
+	if err != nil {
+		return nil, fmt.Errorf("schedule_store: marshal trigger data: %w", err)
+	}
+	row := s.db.QueryRowContext(ctx, `
+		INSERT INTO scheduled_runs (id, workspace, process_id, run_at, trigger_data, status, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, 'pending', NOW(), NOW())
+		RETURNING `+scheduledRunCols,
+		uuid.New().String(), tenant.Workspace(ctx), processID, runAt.UTC(), data)
+	run, err := scanScheduledRun(row)
+	if err != nil {
+		return nil, fmt.Errorf("schedule_store: create run for %q: %w", processID, err)
+	}
+	return run, nil
+}
+
+// List returns the runs of processID in the workspace carried by ctx, most
+// recent run_at first.
+func (s *ScheduleStore) List(ctx context.Context, processID string) ([]ScheduledRun, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+scheduledRunCols+` FROM scheduled_runs
+		WHERE workspace = $1 AND process_id = $2
+		ORDER BY run_at DESC`, tenant.Workspace(ctx), processID)
+	if err != nil {
+		return nil, fmt.Errorf("schedule_store: list %q: %w", processID, err)
+	}
+	defer rows.Close()
+
+	var result []ScheduledRun
+	for rows.Next() {
+		run, err := scanScheduledRun(rows)
+		if err != nil {
+			return nil, fmt.Errorf("schedule_store: scan run: %w", err)
+		}
+		result = append(result, *run)
+	}
+	return result, rows.Err()
+}
+
+// Cancel marks a pending run as cancelled. It returns an error when the run
+// does not exist in the workspace or is no longer pending.
+func (s *ScheduleStore) Cancel(ctx context.Context, processID, runID string) error {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE scheduled_runs SET status = 'cancelled', updated_at = NOW()
+		WHERE id::text = $1 AND process_id = $2 AND workspace = $3 AND status = 'pending'`,
+		runID, processID, tenant.Workspace(ctx))
+	if err != nil {
+		return fmt.Errorf("schedule_store: cancel %q: %w", runID, err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("schedule_store: pending run %q not found", runID)
+	}
+	return nil
+}
+
+// ClaimDue atomically moves up to limit pending runs whose run_at is not after
+// now to "running" and returns them. FOR UPDATE SKIP LOCKED lets several engine
+// replicas poll the same table without executing a run twice. The claims are
+// leased to this engine (see RenewClaims).
+func (s *ScheduleStore) ClaimDue(ctx context.Context, now time.Time, limit int) ([]ScheduledRun, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		UPDATE scheduled_runs
+		SET status = 'running', claimed_by = $3, lease_expires_at = NOW() + make_interval(secs => $4), updated_at = NOW()
+		WHERE id IN (
+			SELECT id FROM scheduled_runs
+			WHERE status = 'pending' AND run_at <= $1
+			ORDER BY run_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED)
+		RETURNING `+scheduledRunCols, now.UTC(), limit, s.owner, s.lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("schedule_store: claim due runs: %w", err)
+	}
+	defer rows.Close()
+
+	var result []ScheduledRun
+	for rows.Next() {
+		run, err := scanScheduledRun(rows)
+		if err != nil {
+			return nil, fmt.Errorf("schedule_store: scan run: %w", err)
+		}
+		result = append(result, *run)
+	}
+	return result, rows.Err()
+}
+
+// Complete records the outcome of a claimed run. An empty errMsg marks the run
+// as done, otherwise as failed.
+func (s *ScheduleStore) Complete(ctx context.Context, runID, executionID, errMsg string) error {
+	status := RunStatusDone
+	if errMsg != "" {
+		status = RunStatusFailed
+	}
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE scheduled_runs
+		SET status = $1, execution_id = NULLIF($2, ''), error = NULLIF($3, ''), updated_at = NOW()
+		WHERE id::text = $4`, status, executionID, errMsg, runID)
+	if err != nil {
+		return fmt.Errorf("schedule_store: complete %q: %w", runID, err)
+	}
+	return nil
+}
+
+// RenewClaims extends the lease of every run this engine is executing. It
+// must be called well within DefaultClaimLease of the claim and of the
+// previous renewal.
+func (s *ScheduleStore) RenewClaims(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE scheduled_runs SET lease_expires_at = NOW() + make_interval(secs => $2)
+		WHERE status = 'running' AND claimed_by = $1`, s.owner, s.lease.Seconds())
+	if err != nil {
+		return fmt.Errorf("schedule_store: renew claims: %w", err)
+	}
+	return nil
+}
+
+// FailInterrupted marks as failed the runs left in "running" by a previous
+// process of this engine, which is starting and runs nothing yet, and those
+// whose lease expired because the engine executing them stopped. Runs of
+// other live replicas are left alone. Runs are executed at most once: a crash
+// mid-execution is surfaced instead of silently re-running side effects.
+func (s *ScheduleStore) FailInterrupted(ctx context.Context) (int64, error) {
+	return s.failClaims(ctx, s.owner)
+}
+
+// FailExpired marks as failed the runs whose lease expired because the engine
+// executing them stopped.
+func (s *ScheduleStore) FailExpired(ctx context.Context) (int64, error) {
+	return s.failClaims(ctx, "")
+}
+
+// failClaims fails the running runs claimed by owner, unless empty, or whose
+// lease expired. Runs claimed before leases were recorded have none and count
+// as expired.
+func (s *ScheduleStore) failClaims(ctx context.Context, owner string) (int64, error) {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE scheduled_runs
+		SET status = 'failed', error = 'interrupted by engine restart', updated_at = NOW()
+		WHERE status = 'running'
+		  AND ((claimed_by = $1 AND $1 <> '') OR lease_expires_at IS NULL OR lease_expires_at < NOW())`, owner)
+	if err != nil {
+		return 0, fmt.Errorf("schedule_store: fail interrupted runs: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return n, nil
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanScheduledRun reads one row selected with scheduledRunCols.
+func scanScheduledRun(row rowScanner) (*ScheduledRun, error) {
+	var run ScheduledRun
+	var data []byte
+	err := row.Scan(
+		&run.ID,
+		&run.Workspace,
+		&run.ProcessID,
+		&run.RunAt,
+		&data,
+		&run.Status,
+		&run.ExecutionID,
+		&run.Error,
+		&run.CreatedAt,
+		&run.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	run.TriggerData = json.RawMessage(data)
+	return &run, nil
+}
+
+// TriggerDataMap decodes the stored trigger payload; a missing or invalid
+// payload yields an empty map.
+func (r *ScheduledRun) TriggerDataMap() map[string]interface{} {
+	m := map[string]interface{}{}
+	if len(r.TriggerData) > 0 {
+		_ = json.Unmarshal(r.TriggerData, &m)
+	}
+	if m == nil {
+		m = map[string]interface{}{}
+	}
+	return m
+}
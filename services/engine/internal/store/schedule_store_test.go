@@ -0,0 +1,81 @@
+package store
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"flowjs-works/engine/internal/store/storetest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduledRun_TriggerDataMap(t *testing.T) {
+	run := &ScheduledRun{TriggerData: json.RawMessage(`{"order_id":42}`)}
+	assert.Equal(t, map[string]interface{}{"order_id": float64(42)}, run.TriggerDataMap())
+
+	for _, raw := range []string{"", "null", "not json"} {
+		run = &ScheduledRun{TriggerData: json.RawMessage(raw)}
+		assert.Equal(t, map[string]interface{}{}, run.TriggerDataMap(), "raw=%q", raw)
+	}
+}
+
+func TestScheduleStore_New(t *testing.T) {
+	assert.NotNil(t, NewScheduleStore(nil))
+}
+
+func TestScheduleStore_ClaimDueLeasesToOwner(t *testing.T) {
+	db, fake := storetest.Open(t)
+	fake.On("UPDATE scheduled_runs", func([]driver.Value) storetest.Result { return storetest.Result{} })
+	s := NewScheduleStore(db)
+	s.SetOwner("engine-1")
+
+	_, err := s.ClaimDue(context.Background(), time.Now(), 5)
+	require.NoError(t, err)
+
+	stmt, ok := fake.Find("UPDATE scheduled_runs")
+	require.True(t, ok)
+	assert.Contains(t, stmt.SQL, "claimed_by = $3")
+	assert.Contains(t, stmt.SQL, "lease_expires_at = NOW() + make_interval(secs => $4)")
+	assert.Equal(t, "engine-1", stmt.Args[2])
+	assert.Equal(t, DefaultClaimLease.Seconds(), stmt.Args[3])
+}
+
+func TestScheduleStore_FailInterruptedSparesLiveReplicas(t *testing.T) {
+	db, fake := storetest.Open(t)
+	fake.On("SET status = 'failed'", func([]driver.Value) storetest.Result { return storetest.Result{RowsAffected: 2} })
+	s := NewScheduleStore(db)
+	s.SetOwner("engine-1")
+
+	n, err := s.FailInterrupted(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+	_, err = s.FailExpired(context.Background())
+	require.NoError(t, err)
+
+	stmts := fake.Statements()
+	require.Len(t, stmts, 2)
+	for _, stmt := range stmts {
+		assert.Contains(t, stmt.SQL, "WHERE status = 'running'")
+		assert.Contains(t, stmt.SQL, "(claimed_by = $1 AND $1 <> '') OR lease_expires_at IS NULL OR lease_expires_at < NOW()",
+			"only runs of this engine or with an expired lease are failed")
+	}
+	assert.Equal(t, []driver.Value{"engine-1"}, stmts[0].Args, "on start the runs of this engine are failed")
+	assert.Equal(t, []driver.Value{""}, stmts[1].Args, "sweeps only fail expired leases")
+}
+
+func TestScheduleStore_RenewClaims(t *testing.T) {
+	db, fake := storetest.Open(t)
+	fake.On("UPDATE scheduled_runs SET lease_expires_at", func([]driver.Value) storetest.Result { return storetest.Result{} })
+	s := NewScheduleStore(db)
+	s.SetOwner("engine-1")
+
+	require.NoError(t, s.RenewClaims(context.Background()))
+	stmt, ok := fake.Find("lease_expires_at")
+	require.True(t, ok)
+	assert.Contains(t, stmt.SQL, "claimed_by = $1")
+	assert.Equal(t, []driver.Value{"engine-1", DefaultClaimLease.Seconds()}, stmt.Args)
+}
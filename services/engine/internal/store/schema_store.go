@@ -0,0 +1,131 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"flowjs-works/engine/internal/tenant"
+)
+
+// ErrSchemaNotFound is returned when no schema has the requested name in the
+// caller's workspace.
+var ErrSchemaNotFound = errors.New("schema_store: schema not found")
+
+// PayloadSchema is a named JSON Schema of the schema registry. Triggers and
+// nodes reference it by name to validate the payloads they receive or produce.
+type PayloadSchema struct {
+	Name        string                 `json:"name"`
+	Workspace   string                 `json:"workspace"`
+	Description string                 `json:"description"`
+	Schema      map[string]interface{} `json:"schema"`
+	// Paths lists the JSONPaths the schema describes, for autocomplete.
+	Paths     []string  `json:"paths,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SchemaStore persists payload schemas in the config database. Schema names
+// are unique per workspace and follow the snippet naming rules.
+type SchemaStore struct {
+	db *sql.DB
+}
+
+// NewSchemaStore creates a store backed by db. The caller owns the connection.
+func NewSchemaStore(db *sql.DB) *SchemaStore {
+	return &SchemaStore{db: db}
+}
+
+// Upsert creates or replaces a schema in the workspace carried by ctx.
+func (s *SchemaStore) Upsert(ctx context.Context, ps *PayloadSchema) (*PayloadSchema, error) {
+	if !ValidSnippetName(ps.Name) {
+		return nil, fmt.Errorf("schema_store: invalid schema name %q", ps.Name)
+	}
+	doc, err := json.Marshal(ps.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("schema_store: marshal %q: %w", ps.Name, err)
+	}
+	workspace := tenant.Workspace(ctx)
+	var updatedAt time.Time
+	err = s.db.QueryRowContext(ctx, `
+		INSERT INTO payload_schemas (workspace, name, description, schema, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, NOW(), NOW())
+		ON CONFLICT (workspace, name) DO UPDATE
+		  SET description = EXCLUDED.description,
+		      schema      = EXCLUDED.schema,
+		      updated_at  = NOW()
+		RETURNING updated_at`,
+		workspace, ps.Name, ps.Description, doc).Scan(&updatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("schema_store: upsert %q: %w", ps.Name, err)
+	}
+	return &PayloadSchema{Name: ps.Name, Workspace: workspace, Description: ps.Description, Schema: ps.Schema, UpdatedAt: updatedAt}, nil
+}
+
+// Get returns the schema name in the workspace carried by ctx.
+func (s *SchemaStore) Get(ctx context.Context, name string) (*PayloadSchema, error) {
+	ps := PayloadSchema{Name: name, Workspace: tenant.Workspace(ctx)}
+	var doc []byte
+	err := s.db.QueryRowContext(ctx, `
+		SELECT description, schema, updated_at FROM payload_schemas
+		WHERE workspace = $1 AND name = $2`,
+		ps.Workspace, name).Scan(&ps.Description, &doc, &ps.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %q", ErrSchemaNotFound, name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("schema_store: get %q: %w", name, err)
+	}
+	if err := json.Unmarshal(doc, &ps.Schema); err != nil {
+		return nil, fmt.Errorf("schema_store: decode %q: %w", name, err)
+	}
+	return &ps, nil
+}
+
+// Schema returns the JSON Schema document of name. It implements
+// schema.Source.
+func (s *SchemaStore) Schema(ctx context.Context, name string) (map[string]interface{}, error) {
+	ps, err := s.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return ps.Schema, nil
+}
+
+// List returns the schemas of the workspace carried by ctx, ordered by name.
+func (s *SchemaStore) List(ctx context.Context) ([]PayloadSchema, error) {
+	workspace := tenant.Workspace(ctx)
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT name, description, schema, updated_at FROM payload_schemas
+		WHERE workspace = $1 ORDER BY name`, workspace)
+	if err != nil {
+		return nil, fmt.Errorf("schema_store: list: %w", err)
+	}
+	defer rows.Close()
+
+	var result []PayloadSchema
+	for rows.Next() {
+		ps := PayloadSchema{Workspace: workspace}
+		var doc []byte
+		if err := rows.Scan(&ps.Name, &ps.Description, &doc, &ps.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("schema_store: scan schema: %w", err)
+		}
+		if err := json.Unmarshal(doc, &ps.Schema); err != nil {
+			return nil, fmt.Errorf("schema_store: decode %q: %w", ps.Name, err)
+		}
+		result = append(result, ps)
+	}
+	return result, rows.Err()
+}
+
+// Delete removes schema name from the workspace carried by ctx.
+func (s *SchemaStore) Delete(ctx context.Context, name string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM payload_schemas WHERE workspace = $1 AND name = $2`,
+		tenant.Workspace(ctx), name)
+	if err != nil {
+		return fmt.Errorf("schema_store: delete %q: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,11 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchemaStore_New(t *testing.T) {
+	assert.NotNil(t, NewSchemaStore(nil))
+}
@@ -0,0 +1,109 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"flowjs-works/engine/internal/logging"
+	"flowjs-works/engine/internal/models"
+	"flowjs-works/engine/internal/tenant"
+)
+
+// snapshotPurgeInterval is how often snapshots older than the retention are deleted.
+const snapshotPurgeInterval = time.Hour
+
+// ErrSnapshotNotFound is returned by Get when no snapshot exists for the
+// execution in the caller's workspace.
+var ErrSnapshotNotFound = errors.New("snapshot_store: snapshot not found")
+
+// Snapshot is the final ExecutionContext of an execution as persisted for debugging.
+type Snapshot struct {
+	ExecutionID string                   `json:"execution_id"`
+	ProcessID   string                   `json:"process_id"`
+	Context     *models.ExecutionContext `json:"context"`
+	CreatedAt   time.Time                `json:"created_at"`
+}
+
+// SnapshotStore persists final execution contexts in the config database.
+type SnapshotStore struct {
+	db        *sql.DB
+	retention time.Duration
+
+	mu        sync.Mutex
+	lastPurge time.Time
+}
+
+// NewSnapshotStore creates a store backed by db that keeps snapshots for
+// retention. The caller owns the connection.
+func NewSnapshotStore(db *sql.DB, retention time.Duration) *SnapshotStore {
+	return &SnapshotStore{db: db, retention: retention}
+}
+
+// Save stores the snapshot of execCtx, replacing any previous snapshot of
+// the same execution.
+func (s *SnapshotStore) Save(ctx context.Context, execCtx *models.ExecutionContext) error {
+	s.purgeExpired(ctx)
+
+	data, err := json.Marshal(execCtx)
+	if err != nil {
+		return fmt.Errorf("snapshot_store: marshal %q: %w", execCtx.ExecutionID, err)
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO execution_snapshots (execution_id, workspace, process_id, context, created_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (execution_id) DO UPDATE
+		  SET context = EXCLUDED.context, created_at = EXCLUDED.created_at`,
+		execCtx.ExecutionID, tenant.Workspace(ctx), execCtx.ProcessID, data)
+	if err != nil {
+		return fmt.Errorf("snapshot_store: save %q: %w", execCtx.ExecutionID, err)
+	}
+	return nil
+}
+
+// Get returns the snapshot of executionID in the workspace carried by ctx.
+func (s *SnapshotStore) Get(ctx context.Context, executionID string) (*Snapshot, error) {
+	var (
+		snap Snapshot
+		data []byte
+	)
+	err := s.db.QueryRowContext(ctx, `
+		SELECT execution_id, process_id, context, created_at FROM execution_snapshots
+		WHERE execution_id = $1 AND workspace = $2`,
+		executionID, tenant.Workspace(ctx)).Scan(&snap.ExecutionID, &snap.ProcessID, &data, &snap.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSnapshotNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("snapshot_store: get %q: %w", executionID, err)
+	}
+	if err := json.Unmarshal(data, &snap.Context); err != nil {
+		return nil, fmt.Errorf("snapshot_store: decode %q: %w", executionID, err)
+	}
+	return &snap, nil
+}
+
+// purgeExpired deletes snapshots older than the retention at most once per
+// snapshotPurgeInterval. Failures are logged; they only delay cleanup.
+func (s *SnapshotStore) purgeExpired(ctx context.Context) {
+	s.mu.Lock()
+	due := time.Since(s.lastPurge) >= snapshotPurgeInterval
+	if due {
+		s.lastPurge = time.Now()
+	}
+	s.mu.Unlock()
+	if !due {
+		return
+	}
+	_, err := s.db.ExecContext(ctx,
+		`DELETE FROM execution_snapshots WHERE created_at < NOW() - $1 * INTERVAL '1 millisecond'`,
+		s.retention.Milliseconds())
+	if err != nil {
+		slog.Error("snapshot_store: purge expired snapshots", logging.KeyError, err)
+	}
+}
@@ -0,0 +1,36 @@
+package store
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"flowjs-works/engine/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshotStore_New(t *testing.T) {
+	assert.NotNil(t, NewSnapshotStore(nil, time.Hour))
+}
+
+func TestSnapshot_ContextRoundTripResolvesPaths(t *testing.T) {
+	execCtx := models.NewExecutionContext("exec-1")
+	execCtx.ProcessID = "p1"
+	execCtx.SetTriggerData(map[string]interface{}{"body": map[string]interface{}{"id": "42"}})
+	execCtx.SetNodeOutput("fetch", map[string]interface{}{"items": []interface{}{"a", "b"}})
+	execCtx.SetNodeStatus("fetch", "success")
+
+	data, err := json.Marshal(execCtx)
+	require.NoError(t, err)
+	var snap Snapshot
+	require.NoError(t, json.Unmarshal(data, &snap.Context))
+
+	v, err := snap.Context.GetValue("$.nodes.fetch.output.items[1]")
+	require.NoError(t, err)
+	assert.Equal(t, "b", v)
+	v, err = snap.Context.GetValue("$.trigger.body.id")
+	require.NoError(t, err)
+	assert.Equal(t, "42", v)
+}
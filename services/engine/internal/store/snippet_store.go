@@ -0,0 +1,126 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"flowjs-works/engine/internal/tenant"
+)
+
+// ErrSnippetNotFound is returned when no snippet has the requested name in the
+// caller's workspace.
+var ErrSnippetNotFound = errors.New("snippet_store: snippet not found")
+
+// validSnippetName allows path-like names such as "utils/dates".
+var validSnippetName = regexp.MustCompile(`^[A-Za-z0-9_-]+(/[A-Za-z0-9_-]+)*$`)
+
+// Snippet is a shared TypeScript/JavaScript module that code nodes can import
+// by name (`import { f } from "utils/dates"`).
+type Snippet struct {
+	Name        string    `json:"name"`
+	Workspace   string    `json:"workspace"`
+	Description string    `json:"description"`
+	Source      string    `json:"source,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// SnippetStore persists script snippets in the config database. Snippet names
+// are unique per workspace.
+type SnippetStore struct {
+	db *sql.DB
+}
+
+// NewSnippetStore creates a store backed by db. The caller owns the connection.
+func NewSnippetStore(db *sql.DB) *SnippetStore {
+	return &SnippetStore{db: db}
+}
+
+// ValidSnippetName reports whether name can be used as a snippet import path.
+func ValidSnippetName(name string) bool {
+	return len(name) <= 255 && validSnippetName.MatchString(name)
+}
+
+// Upsert creates or replaces a snippet in the workspace carried by ctx.
+func (s *SnippetStore) Upsert(ctx context.Context, snip *Snippet) (*Snippet, error) {
+	if !ValidSnippetName(snip.Name) {
+		return nil, fmt.Errorf("snippet_store: invalid snippet name %q", snip.Name)
+	}
+	workspace := tenant.Workspace(ctx)
+	var updatedAt time.Time
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO script_snippets (workspace, name, description, source, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, NOW(), NOW())
+		ON CONFLICT (workspace, name) DO UPDATE
+		  SET description = EXCLUDED.description,
+		      source      = EXCLUDED.source,
+		      updated_at  = NOW()
+		RETURNING updated_at`,
+		workspace, snip.Name, snip.Description, snip.Source).Scan(&updatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("snippet_store: upsert %q: %w", snip.Name, err)
+	}
+	return &Snippet{Name: snip.Name, Workspace: workspace, Description: snip.Description, Source: snip.Source, UpdatedAt: updatedAt}, nil
+}
+
+// Get returns the snippet name in the workspace carried by ctx.
+func (s *SnippetStore) Get(ctx context.Context, name string) (*Snippet, error) {
+	snip := Snippet{Name: name, Workspace: tenant.Workspace(ctx)}
+	err := s.db.QueryRowContext(ctx, `
+		SELECT description, source, updated_at FROM script_snippets
+		WHERE workspace = $1 AND name = $2`,
+		snip.Workspace, name).Scan(&snip.Description, &snip.Source, &snip.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %q", ErrSnippetNotFound, name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("snippet_store: get %q: %w", name, err)
+	}
+	return &snip, nil
+}
+
+// Source returns the source code of snippet name. It implements
+// activities.SnippetSource.
+func (s *SnippetStore) Source(ctx context.Context, name string) (string, error) {
+	snip, err := s.Get(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	return snip.Source, nil
+}
+
+// List returns the snippets of the workspace carried by ctx without their
+// source, ordered by name.
+func (s *SnippetStore) List(ctx context.Context) ([]Snippet, error) {
+	workspace := tenant.Workspace(ctx)
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT name, description, updated_at FROM script_snippets
+		WHERE workspace = $1 ORDER BY name`, workspace)
+	if err != nil {
+		return nil, fmt.Errorf("snippet_store: list: %w", err)
+	}
+	defer rows.Close()
+
+	var result []Snippet
+	for rows.Next() {
+		snip := Snippet{Workspace: workspace}
+		if err := rows.Scan(&snip.Name, &snip.Description, &snip.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("snippet_store: scan snippet: %w", err)
+		}
+		result = append(result, snip)
+	}
+	return result, rows.Err()
+}
+
+// Delete removes snippet name from the workspace carried by ctx.
+func (s *SnippetStore) Delete(ctx context.Context, name string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM script_snippets WHERE workspace = $1 AND name = $2`,
+		tenant.Workspace(ctx), name)
+	if err != nil {
+		return fmt.Errorf("snippet_store: delete %q: %w", name, err)
+	}
+	return nil
+}
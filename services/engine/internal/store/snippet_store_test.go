@@ -0,0 +1,21 @@
+package store
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidSnippetName(t *testing.T) {
+	for _, name := range []string{"utils", "utils/dates", "my_lib-2", "a/b/c"} {
+		assert.True(t, ValidSnippetName(name), name)
+	}
+	for _, name := range []string{"", "/utils", "utils/", "../secret", "a//b", "has space", "a.b", strings.Repeat("x", 256)} {
+		assert.False(t, ValidSnippetName(name), name)
+	}
+}
+
+func TestSnippetStore_New(t *testing.T) {
+	assert.NotNil(t, NewSnippetStore(nil))
+}
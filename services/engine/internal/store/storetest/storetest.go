@@ -0,0 +1,156 @@
+// Package storetest provides an in-memory database/sql driver for tests of
+// the stores and of the handlers built on them. Statements are answered by
+// the first rule whose fragment they contain, and every statement is recorded
+// so tests can assert the SQL and arguments a store sent.
+package storetest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Result is the answer to a statement: Rows for queries, RowsAffected for
+// executions, or Err.
+type Result struct {
+	Columns      []string
+	Rows         [][]driver.Value
+	RowsAffected int64
+	Err          error
+}
+
+// Statement is a statement received by the database.
+type Statement struct {
+	SQL  string
+	Args []driver.Value
+}
+
+type rule struct {
+	fragment string
+	answer   func(args []driver.Value) Result
+}
+
+// DB scripts the answers of a database opened with Open.
+type DB struct {
+	mu         sync.Mutex
+	rules      []rule
+	statements []Statement
+}
+
+// Open returns a *sql.DB answered by the returned DB. It is closed when the
+// test ends.
+func Open(t testing.TB) (*sql.DB, *DB) {
+	t.Helper()
+	fake := &DB{}
+	db := sql.OpenDB(connector{fake})
+	t.Cleanup(func() { _ = db.Close() })
+	return db, fake
+}
+
+// On answers the statements containing fragment with answer. Rules are tried
+// in the order they were added; a statement no rule matches fails.
+func (d *DB) On(fragment string, answer func(args []driver.Value) Result) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.rules = append(d.rules, rule{fragment: fragment, answer: answer})
+}
+
+// Statements returns the statements received so far.
+func (d *DB) Statements() []Statement {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]Statement(nil), d.statements...)
+}
+
+// Find returns the first statement received containing fragment.
+func (d *DB) Find(fragment string) (Statement, bool) {
+	for _, s := range d.Statements() {
+		if strings.Contains(s.SQL, fragment) {
+			return s, true
+		}
+	}
+	return Statement{}, false
+}
+
+func (d *DB) answer(query string, named []driver.NamedValue) Result {
+	args := make([]driver.Value, len(named))
+	for i, nv := range named {
+		args[i] = nv.Value
+	}
+	d.mu.Lock()
+	d.statements = append(d.statements, Statement{SQL: query, Args: args})
+	rules := d.rules
+	d.mu.Unlock()
+	for _, r := range rules {
+		if strings.Contains(query, r.fragment) {
+			return r.answer(args)
+		}
+	}
+	return Result{Err: fmt.Errorf("storetest: unexpected statement: %s", strings.Join(strings.Fields(query), " "))}
+}
+
+type connector struct{ db *DB }
+
+func (c connector) Connect(context.Context) (driver.Conn, error) { return conn(c), nil }
+func (c connector) Driver() driver.Driver                        { return fakeDriver{} }
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) {
+	return nil, fmt.Errorf("storetest: use storetest.Open")
+}
+
+type conn struct{ db *DB }
+
+func (c conn) Prepare(string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("storetest: prepared statements are not supported")
+}
+func (c conn) Close() error              { return nil }
+func (c conn) Begin() (driver.Tx, error) { return tx{}, nil }
+
+// CheckNamedValue accepts every argument as is; stores only bind values the
+// tests compare.
+func (c conn) CheckNamedValue(*driver.NamedValue) error { return nil }
+
+func (c conn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	res := c.db.answer(query, args)
+	if res.Err != nil {
+		return nil, res.Err
+	}
+	return driver.RowsAffected(res.RowsAffected), nil
+}
+
+func (c conn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	res := c.db.answer(query, args)
+	if res.Err != nil {
+		return nil, res.Err
+	}
+	return &rows{columns: res.Columns, values: res.Rows}, nil
+}
+
+type tx struct{}
+
+func (tx) Commit() error   { return nil }
+func (tx) Rollback() error { return nil }
+
+type rows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *rows) Columns() []string { return r.columns }
+func (r *rows) Close() error      { return nil }
+
+func (r *rows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
@@ -0,0 +1,176 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"flowjs-works/engine/internal/logging"
+	"flowjs-works/engine/internal/models"
+	"flowjs-works/engine/internal/tenant"
+)
+
+const (
+	// versionPurgeInterval is how often pins older than the retention are deleted.
+	versionPurgeInterval = time.Hour
+	// versionKnownTTL is how long a stored definition is trusted to still
+	// exist without writing it again. It must stay well below the retention.
+	versionKnownTTL = time.Hour
+)
+
+// ErrVersionNotFound is returned when no definition is pinned for an
+// execution in the caller's workspace, e.g. because it ran before pinning
+// was enabled or its pin expired.
+var ErrVersionNotFound = errors.New("version_store: execution version not found")
+
+// ExecutionVersion identifies the process definition an execution ran.
+type ExecutionVersion struct {
+	ExecutionID string    `json:"execution_id"`
+	ProcessID   string    `json:"process_id"`
+	Revision    int       `json:"revision"`
+	DSLHash     string    `json:"dsl_hash"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// VersionStore keeps the definition every execution ran, deduplicated by
+// content hash, so retries and replays are not affected by a redeploy. It
+// implements engine.VersionRecorder.
+type VersionStore struct {
+	db        *sql.DB
+	retention time.Duration
+
+	mu        sync.Mutex
+	known     map[string]time.Time // workspace, process and hash of definitions already stored
+	lastPurge time.Time
+}
+
+// NewVersionStore creates a store backed by db that keeps execution pins for
+// retention. The caller owns the connection.
+func NewVersionStore(db *sql.DB, retention time.Duration) *VersionStore {
+	return &VersionStore{db: db, retention: retention, known: make(map[string]time.Time)}
+}
+
+// RecordVersion pins execCtx to process, whose hash is execCtx.DSLHash. The
+// definition itself is written once per hash.
+func (s *VersionStore) RecordVersion(ctx context.Context, execCtx *models.ExecutionContext, process *models.Process) error {
+	s.purgeExpired(ctx)
+
+	workspace := tenant.Workspace(ctx)
+	key := workspace + "\x00" + execCtx.ProcessID + "\x00" + execCtx.DSLHash
+	if !s.isKnown(key) {
+		dsl, err := json.Marshal(process)
+		if err != nil {
+			return fmt.Errorf("version_store: marshal %q: %w", execCtx.ProcessID, err)
+		}
+		_, err = s.db.ExecContext(ctx, `
+			INSERT INTO process_versions (workspace, process_id, dsl_hash, revision, dsl, created_at)
+			VALUES ($1, $2, $3, $4, $5, NOW())
+			ON CONFLICT (workspace, process_id, dsl_hash) DO NOTHING`,
+			workspace, execCtx.ProcessID, execCtx.DSLHash, execCtx.ProcessRevision, dsl)
+		if err != nil {
+			return fmt.Errorf("version_store: save definition of %q: %w", execCtx.ProcessID, err)
+		}
+		s.mu.Lock()
+		s.known[key] = time.Now()
+		s.mu.Unlock()
+	}
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO execution_versions (execution_id, workspace, process_id, dsl_hash, revision, created_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		ON CONFLICT (execution_id) DO NOTHING`,
+		execCtx.ExecutionID, workspace, execCtx.ProcessID, execCtx.DSLHash, execCtx.ProcessRevision)
+	if err != nil {
+		return fmt.Errorf("version_store: pin %q: %w", execCtx.ExecutionID, err)
+	}
+	return nil
+}
+
+// Get returns the version executionID ran in the workspace carried by ctx.
+func (s *VersionStore) Get(ctx context.Context, executionID string) (*ExecutionVersion, error) {
+	v := ExecutionVersion{ExecutionID: executionID}
+	err := s.db.QueryRowContext(ctx, `
+		SELECT process_id, revision, dsl_hash, created_at FROM execution_versions
+		WHERE execution_id = $1 AND workspace = $2`,
+		executionID, tenant.Workspace(ctx)).Scan(&v.ProcessID, &v.Revision, &v.DSLHash, &v.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %q", ErrVersionNotFound, executionID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("version_store: get %q: %w", executionID, err)
+	}
+	return &v, nil
+}
+
+// Process returns the definition executionID ran, with Definition.Revision
+// set, and its version.
+func (s *VersionStore) Process(ctx context.Context, executionID string) (*models.Process, *ExecutionVersion, error) {
+	workspace := tenant.Workspace(ctx)
+	v := ExecutionVersion{ExecutionID: executionID}
+	var dsl []byte
+	err := s.db.QueryRowContext(ctx, `
+		SELECT e.process_id, e.revision, e.dsl_hash, e.created_at, v.dsl
+		FROM execution_versions e
+		JOIN process_versions v ON v.workspace = e.workspace AND v.process_id = e.process_id AND v.dsl_hash = e.dsl_hash
+		WHERE e.execution_id = $1 AND e.workspace = $2`,
+		executionID, workspace).Scan(&v.ProcessID, &v.Revision, &v.DSLHash, &v.CreatedAt, &dsl)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, fmt.Errorf("%w: %q", ErrVersionNotFound, executionID)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("version_store: get definition of %q: %w", executionID, err)
+	}
+	var proc models.Process
+	if err := json.Unmarshal(dsl, &proc); err != nil {
+		return nil, nil, fmt.Errorf("version_store: parse definition of %q: %w", executionID, err)
+	}
+	proc.Definition.Workspace = workspace
+	proc.Definition.Revision = v.Revision
+	return &proc, &v, nil
+}
+
+// isKnown reports whether the definition of key was stored recently enough
+// to skip writing it again.
+func (s *VersionStore) isKnown(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	at, ok := s.known[key]
+	if ok && time.Since(at) >= versionKnownTTL {
+		delete(s.known, key)
+		return false
+	}
+	return ok
+}
+
+// purgeExpired deletes pins older than the retention, and the definitions no
+// remaining pin refers to, at most once per versionPurgeInterval. Failures
+// are logged; they only delay cleanup.
+func (s *VersionStore) purgeExpired(ctx context.Context) {
+	s.mu.Lock()
+	due := time.Since(s.lastPurge) >= versionPurgeInterval
+	if due {
+		s.lastPurge = time.Now()
+	}
+	s.mu.Unlock()
+	if !due {
+		return
+	}
+	_, err := s.db.ExecContext(ctx,
+		`DELETE FROM execution_versions WHERE created_at < NOW() - $1 * INTERVAL '1 millisecond'`,
+		s.retention.Milliseconds())
+	if err == nil {
+		_, err = s.db.ExecContext(ctx, `
+			DELETE FROM process_versions v
+			WHERE v.created_at < NOW() - $1 * INTERVAL '1 millisecond'
+			  AND NOT EXISTS (SELECT 1 FROM execution_versions e
+			                  WHERE e.workspace = v.workspace AND e.process_id = v.process_id AND e.dsl_hash = v.dsl_hash)`,
+			s.retention.Milliseconds())
+	}
+	if err != nil {
+		slog.Error("version_store: purge expired versions", logging.KeyError, err)
+	}
+}
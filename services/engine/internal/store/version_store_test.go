@@ -0,0 +1,24 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVersionStore_New(t *testing.T) {
+	assert.NotNil(t, NewVersionStore(nil, time.Hour))
+}
+
+func TestVersionStore_KnownDefinitionsExpire(t *testing.T) {
+	s := NewVersionStore(nil, 24*time.Hour)
+	assert.False(t, s.isKnown("ws\x00p\x00h"))
+
+	s.known["ws\x00p\x00h"] = time.Now()
+	assert.True(t, s.isKnown("ws\x00p\x00h"))
+
+	s.known["ws\x00p\x00h"] = time.Now().Add(-versionKnownTTL)
+	assert.False(t, s.isKnown("ws\x00p\x00h"), "an old entry is written again in case it was purged")
+	assert.NotContains(t, s.known, "ws\x00p\x00h")
+}
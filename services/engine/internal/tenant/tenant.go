@@ -0,0 +1,91 @@
+// Package tenant carries the authenticated principal and its workspace through
+// request and execution contexts. Every store scopes its queries to the
+// workspace returned by Workspace so that several teams can share one engine
+// without seeing each other's processes, secrets, or trigger routes.
+package tenant
+
+import (
+	"context"
+	"regexp"
+)
+
+// DefaultWorkspace is used when no principal is attached to the context
+// (development mode without API keys, CLI runner, legacy DSLs).
+const DefaultWorkspace = "default"
+
+// validWorkspaceRe restricts workspace names to URL-safe characters because
+// they are embedded in trigger route prefixes.
+var validWorkspaceRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+// RoleAdmin is the principal role allowed to perform privileged operations,
+// such as revealing secret values.
+const RoleAdmin = "admin"
+
+// Principal identifies the caller of an API request.
+type Principal struct {
+	Subject   string `json:"subject"`
+	Workspace string `json:"workspace"`
+	Role      string `json:"role"`
+	// Team is the team the caller belongs to, recorded as the team of the
+	// processes it creates. Optional.
+	Team string `json:"team,omitempty"`
+}
+
+// IsAdmin reports whether p has the admin role.
+func (p Principal) IsAdmin() bool {
+	return p.Role == RoleAdmin
+}
+
+type ctxKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying p.
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, ctxKey{}, p)
+}
+
+// PrincipalFromContext returns the principal stored in ctx, if any.
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(ctxKey{}).(Principal)
+	return p, ok
+}
+
+// WithWorkspace returns a copy of ctx scoped to workspace without an
+// authenticated subject. It is used by background work (trigger executions,
+// secret resolution) that acts on behalf of a stored process.
+func WithWorkspace(ctx context.Context, workspace string) context.Context {
+	return WithPrincipal(ctx, Principal{Subject: "system", Workspace: Normalize(workspace)})
+}
+
+// Workspace returns the workspace of the principal stored in ctx, or
+// DefaultWorkspace when no principal is present.
+func Workspace(ctx context.Context) string {
+	if p, ok := PrincipalFromContext(ctx); ok {
+		return Normalize(p.Workspace)
+	}
+	return DefaultWorkspace
+}
+
+// Normalize maps an empty workspace to DefaultWorkspace.
+func Normalize(workspace string) string {
+	if workspace == "" {
+		return DefaultWorkspace
+	}
+	return workspace
+}
+
+// Valid reports whether workspace is a legal workspace name.
+func Valid(workspace string) bool {
+	return validWorkspaceRe.MatchString(workspace)
+}
+
+// RoutePrefix returns the path prefix under which inbound trigger routes
+// (REST / SOAP) of workspace are registered. The default workspace keeps the
+// un-prefixed paths so existing deployments are unaffected; every other
+// workspace is isolated under "/ws/{workspace}".
+func RoutePrefix(workspace string) string {
+	workspace = Normalize(workspace)
+	if workspace == DefaultWorkspace {
+		return ""
+	}
+	return "/ws/" + workspace
+}
@@ -0,0 +1,46 @@
+package tenant
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkspace_DefaultsWithoutPrincipal(t *testing.T) {
+	assert.Equal(t, DefaultWorkspace, Workspace(context.Background()))
+}
+
+func TestWorkspace_FromPrincipal(t *testing.T) {
+	ctx := WithPrincipal(context.Background(), Principal{Subject: "alice", Workspace: "team-a", Role: "admin"})
+	assert.Equal(t, "team-a", Workspace(ctx))
+
+	p, ok := PrincipalFromContext(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, "alice", p.Subject)
+}
+
+func TestWithWorkspace_NormalizesEmpty(t *testing.T) {
+	ctx := WithWorkspace(context.Background(), "")
+	assert.Equal(t, DefaultWorkspace, Workspace(ctx))
+}
+
+func TestValid(t *testing.T) {
+	assert.True(t, Valid("team-a"))
+	assert.True(t, Valid("default"))
+	assert.False(t, Valid(""))
+	assert.False(t, Valid("../etc"))
+	assert.False(t, Valid("Team A"))
+}
+
+func TestRoutePrefix(t *testing.T) {
+	assert.Equal(t, "", RoutePrefix(""))
+	assert.Equal(t, "", RoutePrefix(DefaultWorkspace))
+	assert.Equal(t, "/ws/team-a", RoutePrefix("team-a"))
+}
+
+func TestPrincipal_IsAdmin(t *testing.T) {
+	assert.True(t, Principal{Role: RoleAdmin}.IsAdmin())
+	assert.False(t, Principal{Role: "member"}.IsAdmin())
+	assert.False(t, Principal{}.IsAdmin())
+}
@@ -0,0 +1,221 @@
+package triggers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"flowjs-works/engine/internal/logging"
+	"flowjs-works/engine/internal/models"
+	"flowjs-works/engine/internal/tenant"
+)
+
+// RequestCapturer stores raw REST and SOAP trigger requests so they can be
+// replayed later. store.CaptureStore implements it on the config DB.
+type RequestCapturer interface {
+	Capture(ctx context.Context, req *models.CapturedRequest) error
+}
+
+// ErrCaptureMismatch is returned by Replay when a captured request no longer
+// fits the process trigger, e.g. after its type or path changed.
+var ErrCaptureMismatch = errors.New("captured request does not match the process trigger")
+
+// maxCaptureBody is the largest request body that is captured. Larger
+// requests are served normally but not kept.
+const maxCaptureBody = 1 << 20
+
+// captureTimeout bounds the background write of one capture.
+const captureTimeout = 5 * time.Second
+
+// captureExcludedHeaders are credentials never written to the capture store.
+var captureExcludedHeaders = []string{"Authorization", "Cookie", "X-Api-Key", "Proxy-Authorization"}
+
+// captureDays reads the optional "capture_days" key of a REST or SOAP trigger
+// config: how many days inbound requests are kept for replay. Zero or absent
+// disables capture.
+func captureDays(config map[string]interface{}) (int, error) {
+	raw, ok := config["capture_days"]
+	if !ok || raw == nil {
+		return 0, nil
+	}
+	days, ok := raw.(float64)
+	if !ok || days < 0 || days != float64(int(days)) {
+		return 0, fmt.Errorf("capture_days must be a non-negative whole number of days, got %v", raw)
+	}
+	return int(days), nil
+}
+
+// captureRequest stores r, whose body was already read into body, in the
+// background. Failures are logged; capture never affects the response.
+func captureRequest(c RequestCapturer, days int, proc *models.Process, triggerType string, r *http.Request, body []byte) {
+	if c == nil {
+		return
+	}
+	if len(body) > maxCaptureBody {
+		slog.Warn("triggers: request body too large to capture", logging.KeyProcessID, proc.Definition.ID, "bytes", len(body))
+		return
+	}
+	mount := "/triggers"
+	if triggerType == "soap" {
+		mount = "/soap"
+	}
+	path := strings.TrimPrefix(r.URL.EscapedPath(), mount)
+	path = strings.TrimPrefix(path, tenant.RoutePrefix(proc.Definition.Workspace))
+
+	headers := r.Header.Clone()
+	for _, h := range captureExcludedHeaders {
+		headers.Del(h)
+	}
+	now := time.Now().UTC()
+	req := &models.CapturedRequest{
+		Workspace:   tenant.Normalize(proc.Definition.Workspace),
+		ProcessID:   proc.Definition.ID,
+		TriggerType: triggerType,
+		Method:      r.Method,
+		Path:        path,
+		Query:       r.URL.RawQuery,
+		Headers:     headers,
+		Body:        string(body),
+		CapturedAt:  now,
+		ExpiresAt:   now.AddDate(0, 0, days),
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), captureTimeout)
+		defer cancel()
+		if err := c.Capture(ctx, req); err != nil {
+			slog.Error("triggers: capture request", logging.KeyProcessID, req.ProcessID, logging.KeyError, err)
+		}
+	}()
+}
+
+// Replay re-fires the captured request c through proc, normally the DSL the
+// process deploys with now, and writes the trigger's response to w exactly
+// as the original caller would receive it. The replay is not captured again.
+// ErrCaptureMismatch is returned, before anything is written, when c does not
+// fit the trigger of proc.
+func Replay(w http.ResponseWriter, proc *models.Process, executor Executor, c *models.CapturedRequest) error {
+	if proc.Trigger.Type != c.TriggerType {
+		return fmt.Errorf("%w: trigger is %q, request was captured by %q", ErrCaptureMismatch, proc.Trigger.Type, c.TriggerType)
+	}
+	target := c.Path
+	if c.Query != "" {
+		target += "?" + c.Query
+	}
+	req, err := http.NewRequest(c.Method, "http://replay"+target, bytes.NewReader([]byte(c.Body)))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrCaptureMismatch, err)
+	}
+	req.Header = http.Header(c.Headers).Clone()
+	if req.Header == nil {
+		req.Header = http.Header{}
+	}
+
+	switch c.TriggerType {
+	case "rest":
+		path, _, err := restTriggerConfig(proc.Trigger.Config)
+		if err != nil {
+			return err
+		}
+		mapping, err := parseRESTResponseMapping(proc.Trigger.Config)
+		if err != nil {
+			return err
+		}
+		params, ok := replayParams(path, c.Path)
+		if !ok {
+			return fmt.Errorf("%w: path %q does not match trigger path %q", ErrCaptureMismatch, c.Path, path)
+		}
+		maxBody, err := restMaxBody(proc.Trigger.Config)
+		if err != nil {
+			return err
+		}
+		t := &restTrigger{executor: executor, processID: proc.Definition.ID, maxBody: maxBody}
+		t.buildHandler(proc, mapping)(w, req, params)
+	case "soap":
+		path, wsdl, err := soapTriggerConfig(proc.Trigger.Config)
+		if err != nil {
+			return err
+		}
+		if unescaped, err := url.PathUnescape(c.Path); err != nil || unescaped != path {
+			return fmt.Errorf("%w: path %q does not match trigger path %q", ErrCaptureMismatch, c.Path, path)
+		}
+		t := &soapTrigger{executor: executor, processID: proc.Definition.ID, path: path, wsdl: wsdl}
+		t.buildHandler(proc)(w, req)
+	default:
+		return fmt.Errorf("%w: %q triggers cannot be replayed", ErrCaptureMismatch, c.TriggerType)
+	}
+	return nil
+}
+
+// TriggerData returns the trigger data that replaying the captured request c
+// through proc would start an execution with, without executing anything.
+// It fails like Replay, and when the trigger rejects the request before
+// executing, e.g. on a body that does not parse.
+func TriggerData(proc *models.Process, c *models.CapturedRequest) (map[string]interface{}, error) {
+	rec := &triggerDataRecorder{}
+	w := &discardResponseWriter{header: http.Header{}}
+	if err := Replay(w, proc, rec, c); err != nil {
+		return nil, err
+	}
+	if rec.data == nil {
+		return nil, fmt.Errorf("%w: the trigger rejected the request with status %d", ErrCaptureMismatch, w.status)
+	}
+	return rec.data, nil
+}
+
+// errTriggerDataRecorded stops the trigger handler once the recorder has the
+// trigger data.
+var errTriggerDataRecorded = errors.New("trigger data recorded")
+
+// triggerDataRecorder is the Executor of TriggerData: it keeps the trigger
+// data and executes nothing.
+type triggerDataRecorder struct {
+	data map[string]interface{}
+}
+
+func (r *triggerDataRecorder) Execute(_ *models.Process, triggerData map[string]interface{}) (*models.ExecutionContext, error) {
+	r.data = triggerData
+	return nil, errTriggerDataRecorded
+}
+
+// discardResponseWriter drops the response of a trigger handler, keeping its
+// status.
+type discardResponseWriter struct {
+	header http.Header
+	status int
+}
+
+func (w *discardResponseWriter) Header() http.Header { return w.header }
+
+func (w *discardResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return len(b), nil
+}
+
+func (w *discardResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+// replayParams matches the escaped captured path against the trigger path,
+// returning its path parameters.
+func replayParams(triggerPath, capturedPath string) (map[string]interface{}, bool) {
+	pattern, err := parseRoutePattern(triggerPath)
+	if err != nil {
+		return nil, false
+	}
+	if pattern.templated() {
+		params, _, ok := pattern.match(capturedPath)
+		return params, ok
+	}
+	unescaped, err := url.PathUnescape(capturedPath)
+	return map[string]interface{}{}, err == nil && unescaped == triggerPath
+}
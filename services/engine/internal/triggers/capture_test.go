@@ -0,0 +1,193 @@
+package triggers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"flowjs-works/engine/internal/models"
+)
+
+// chanCapturer hands captured requests to the test through a channel, since
+// captures are written in the background.
+type chanCapturer chan *models.CapturedRequest
+
+func (c chanCapturer) Capture(_ context.Context, req *models.CapturedRequest) error {
+	c <- req
+	return nil
+}
+
+func (c chanCapturer) next(t *testing.T) *models.CapturedRequest {
+	t.Helper()
+	select {
+	case req := <-c:
+		return req
+	case <-time.After(2 * time.Second):
+		t.Fatal("no request captured")
+		return nil
+	}
+}
+
+func TestCaptureDays(t *testing.T) {
+	days, err := captureDays(map[string]interface{}{})
+	require.NoError(t, err)
+	assert.Zero(t, days)
+
+	days, err = captureDays(map[string]interface{}{"capture_days": float64(7)})
+	require.NoError(t, err)
+	assert.Equal(t, 7, days)
+
+	_, err = captureDays(map[string]interface{}{"capture_days": 1.5})
+	assert.Error(t, err)
+	_, err = captureDays(map[string]interface{}{"capture_days": "7"})
+	assert.Error(t, err)
+}
+
+func TestRESTTrigger_CapturesRequest(t *testing.T) {
+	exec := &mockExecutor{}
+	capt := make(chanCapturer, 1)
+	tr := newRESTTrigger(exec)
+	tr.capturer = capt
+	proc := buildProcess("p_capture", "rest", map[string]interface{}{"path": "/capture/{id}", "method": "POST", "capture_days": float64(3)})
+	proc.Definition.Workspace = "acme"
+	require.NoError(t, tr.Start(context.Background(), proc))
+	defer func() { _ = tr.Stop() }()
+
+	req := httptest.NewRequest(http.MethodPost, "/triggers/ws/acme/capture/42?debug=1", strings.NewReader(`{"amount":10}`))
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("X-Request-Id", "r-1")
+	rec := httptest.NewRecorder()
+	GetRegistryHandler().ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	c := capt.next(t)
+	assert.Equal(t, "acme", c.Workspace)
+	assert.Equal(t, "p_capture", c.ProcessID)
+	assert.Equal(t, "rest", c.TriggerType)
+	assert.Equal(t, "/capture/42", c.Path)
+	assert.Equal(t, "debug=1", c.Query)
+	assert.Equal(t, `{"amount":10}`, c.Body)
+	assert.Equal(t, []string{"r-1"}, c.Headers["X-Request-Id"])
+	assert.NotContains(t, c.Headers, "Authorization", "credentials are never captured")
+	assert.WithinDuration(t, c.CapturedAt.AddDate(0, 0, 3), c.ExpiresAt, time.Second)
+
+	// The body is still delivered to the flow.
+	require.Len(t, exec.executions, 1)
+	assert.Equal(t, map[string]interface{}{"amount": float64(10)}, exec.executions[0]["body"])
+}
+
+func TestRESTTrigger_NoCaptureWithoutCaptureDays(t *testing.T) {
+	capt := make(chanCapturer, 1)
+	tr := newRESTTrigger(&mockExecutor{})
+	tr.capturer = capt
+	proc := buildProcess("p_nocapture", "rest", map[string]interface{}{"path": "/nocapture"})
+	require.NoError(t, tr.Start(context.Background(), proc))
+	defer func() { _ = tr.Stop() }()
+
+	rec := httptest.NewRecorder()
+	GetRegistryHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/triggers/nocapture", strings.NewReader(`{}`)))
+	require.Equal(t, http.StatusOK, rec.Code)
+	select {
+	case <-capt:
+		t.Fatal("request captured without capture_days")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestSOAPTrigger_CapturesRequest(t *testing.T) {
+	capt := make(chanCapturer, 1)
+	tr := newSOAPTrigger(&mockExecutor{})
+	tr.capturer = capt
+	proc := buildProcess("p_soap_capture", "soap", map[string]interface{}{"path": "/soap-capture", "capture_days": float64(1)})
+	require.NoError(t, tr.Start(context.Background(), proc))
+	defer func() { _ = tr.Stop() }()
+
+	envelope := `<Envelope><Body><Ping/></Body></Envelope>`
+	rec := httptest.NewRecorder()
+	GetSOAPRegistryHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/soap/soap-capture", strings.NewReader(envelope)))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	c := capt.next(t)
+	assert.Equal(t, "soap", c.TriggerType)
+	assert.Equal(t, "/soap-capture", c.Path)
+	assert.Equal(t, envelope, c.Body)
+}
+
+func TestReplay_REST(t *testing.T) {
+	exec := &mockExecutor{}
+	proc := buildProcess("p_replay", "rest", map[string]interface{}{"path": "/orders/{orderId}", "method": "POST"})
+	c := &models.CapturedRequest{
+		TriggerType: "rest",
+		Method:      http.MethodPost,
+		Path:        "/orders/42",
+		Query:       "expand=items",
+		Headers:     map[string][]string{"Content-Type": {"application/json"}},
+		Body:        `{"qty":2}`,
+	}
+
+	rec := httptest.NewRecorder()
+	require.NoError(t, Replay(rec, proc, exec, c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "test-exec-id")
+
+	require.Len(t, exec.executions, 1)
+	data := exec.executions[0]
+	assert.Equal(t, map[string]interface{}{"orderId": "42"}, data["params"])
+	assert.Equal(t, map[string]interface{}{"expand": "items"}, data["query"])
+	assert.Equal(t, map[string]interface{}{"qty": float64(2)}, data["body"])
+}
+
+func TestReplay_Mismatch(t *testing.T) {
+	exec := &mockExecutor{}
+	c := &models.CapturedRequest{TriggerType: "rest", Method: http.MethodPost, Path: "/old-path"}
+
+	err := Replay(httptest.NewRecorder(), buildProcess("p", "rest", map[string]interface{}{"path": "/new-path"}), exec, c)
+	assert.ErrorIs(t, err, ErrCaptureMismatch)
+
+	err = Replay(httptest.NewRecorder(), buildProcess("p", "cron", map[string]interface{}{"expression": "* * * * *"}), exec, c)
+	assert.ErrorIs(t, err, ErrCaptureMismatch)
+	assert.Empty(t, exec.executions)
+}
+
+func TestReplay_SOAP(t *testing.T) {
+	exec := &mockExecutor{}
+	proc := buildProcess("p_soap_replay", "soap", map[string]interface{}{"path": "/svc"})
+	c := &models.CapturedRequest{
+		TriggerType: "soap",
+		Method:      http.MethodPost,
+		Path:        "/svc",
+		Headers:     map[string][]string{"Soapaction": {`"Ping"`}},
+		Body:        `<Envelope><Body><Ping/></Body></Envelope>`,
+	}
+	rec := httptest.NewRecorder()
+	require.NoError(t, Replay(rec, proc, exec, c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	require.Len(t, exec.executions, 1)
+	assert.Equal(t, "Ping", exec.executions[0]["method"])
+	assert.Equal(t, "<Ping/>", exec.executions[0]["body"])
+}
+
+func TestTriggerData(t *testing.T) {
+	proc := buildProcess("p_data", "rest", map[string]interface{}{"path": "/orders/{orderId}", "method": "POST"})
+	c := &models.CapturedRequest{
+		TriggerType: "rest",
+		Method:      http.MethodPost,
+		Path:        "/orders/7",
+		Headers:     map[string][]string{"Content-Type": {"application/json"}},
+		Body:        `{"qty":2}`,
+	}
+	data, err := TriggerData(proc, c)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"orderId": "7"}, data["params"])
+	assert.Equal(t, map[string]interface{}{"qty": float64(2)}, data["body"])
+
+	c.Body = `{"qty":`
+	_, err = TriggerData(proc, c)
+	assert.ErrorIs(t, err, ErrCaptureMismatch)
+}
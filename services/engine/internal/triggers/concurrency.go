@@ -0,0 +1,47 @@
+package triggers
+
+import (
+	"context"
+	"errors"
+
+	"flowjs-works/engine/internal/models"
+)
+
+// ErrConcurrencyLimit is returned when a process already has
+// definition.settings.max_concurrency executions in flight.
+var ErrConcurrencyLimit = errors.New("process concurrency limit reached")
+
+// gatedExecutor bounds the number of concurrent executions of one process.
+// Executions beyond the limit are rejected rather than queued so that each
+// trigger can apply its own misfire behaviour (cron skips the tick, REST
+// answers 429, RabbitMQ requeues the message).
+type gatedExecutor struct {
+	next  Executor
+	slots chan struct{} // nil means unlimited
+}
+
+func newGatedExecutor(next Executor, limit int) *gatedExecutor {
+	g := &gatedExecutor{next: next}
+	if limit > 0 {
+		g.slots = make(chan struct{}, limit)
+	}
+	return g
+}
+
+// Execute runs the process if a slot is free and returns ErrConcurrencyLimit otherwise.
+func (g *gatedExecutor) Execute(process *models.Process, triggerData map[string]interface{}) (*models.ExecutionContext, error) {
+	return g.ExecuteContext(context.Background(), process, triggerData)
+}
+
+// ExecuteContext is Execute with the run bound to ctx.
+func (g *gatedExecutor) ExecuteContext(ctx context.Context, process *models.Process, triggerData map[string]interface{}) (*models.ExecutionContext, error) {
+	if g.slots != nil {
+		select {
+		case g.slots <- struct{}{}:
+			defer func() { <-g.slots }()
+		default:
+			return nil, ErrConcurrencyLimit
+		}
+	}
+	return ExecuteContext(ctx, g.next, process, triggerData)
+}
@@ -0,0 +1,59 @@
+package triggers
+
+import (
+	"errors"
+	"testing"
+
+	"flowjs-works/engine/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingExecutor holds every execution until release is closed.
+type blockingExecutor struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func newBlockingExecutor() *blockingExecutor {
+	return &blockingExecutor{started: make(chan struct{}, 8), release: make(chan struct{})}
+}
+
+func (b *blockingExecutor) Execute(_ *models.Process, _ map[string]interface{}) (*models.ExecutionContext, error) {
+	b.started <- struct{}{}
+	<-b.release
+	return models.NewExecutionContext("blocked-exec"), nil
+}
+
+func TestGatedExecutor_RejectsBeyondLimit(t *testing.T) {
+	inner := newBlockingExecutor()
+	gate := newGatedExecutor(inner, 1)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := gate.Execute(&models.Process{}, nil)
+		done <- err
+	}()
+	<-inner.started
+
+	_, err := gate.Execute(&models.Process{}, nil)
+	assert.True(t, errors.Is(err, ErrConcurrencyLimit))
+
+	close(inner.release)
+	require.NoError(t, <-done)
+
+	// The slot is released once the first execution finishes.
+	_, err = gate.Execute(&models.Process{}, nil)
+	assert.NoError(t, err)
+}
+
+func TestGatedExecutor_ZeroIsUnlimited(t *testing.T) {
+	exec := &mockExecutor{}
+	gate := newGatedExecutor(exec, 0)
+	for i := 0; i < 3; i++ {
+		_, err := gate.Execute(&models.Process{}, nil)
+		require.NoError(t, err)
+	}
+	assert.Len(t, exec.executions, 3)
+}
@@ -2,10 +2,12 @@ package triggers
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"time"
 
+	"flowjs-works/engine/internal/logging"
 	"flowjs-works/engine/internal/models"
 
 	"github.com/robfig/cron/v3"
@@ -39,8 +41,14 @@ func (t *cronTrigger) Start(ctx context.Context, proc *models.Process) error {
 		triggerData := map[string]interface{}{
 			"datetime": time.Now().UTC().Format(time.RFC3339),
 		}
-		if _, execErr := t.executor.Execute(&procCopy, triggerData); execErr != nil {
-			log.Printf("cron_trigger: execution error for %q: %v", procCopy.Definition.ID, execErr)
+		_, execErr := t.executor.Execute(&procCopy, triggerData)
+		switch {
+		case errors.Is(execErr, ErrConcurrencyLimit), errors.Is(execErr, ErrQuotaExceeded):
+			// Misfire: the previous run is still going or the quota is used
+			// up, so this tick is skipped.
+			slog.Warn("cron_trigger: skipped tick", logging.KeyProcessID, procCopy.Definition.ID, "tick", triggerData["datetime"], logging.KeyError, execErr)
+		case execErr != nil:
+			slog.Error("cron_trigger: execution failed", logging.KeyProcessID, procCopy.Definition.ID, logging.KeyError, execErr)
 		}
 	})
 	if addErr != nil {
@@ -48,7 +56,7 @@ func (t *cronTrigger) Start(ctx context.Context, proc *models.Process) error {
 	}
 
 	t.scheduler.Start()
-	log.Printf("cron_trigger: scheduled %q with expression %q", proc.Definition.ID, expr)
+	slog.Info("cron_trigger: scheduled", logging.KeyProcessID, proc.Definition.ID, "expression", expr)
 	return nil
 }
 
@@ -60,7 +68,7 @@ func (t *cronTrigger) Stop() error {
 		select {
 		case <-ctx.Done():
 		case <-time.After(30 * time.Second):
-			log.Printf("cron_trigger: timed out waiting for job to finish")
+			slog.Warn("cron_trigger: timed out waiting for job to finish")
 		}
 		t.scheduler = nil
 	}
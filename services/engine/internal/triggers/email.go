@@ -0,0 +1,475 @@
+package triggers
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"flowjs-works/engine/internal/logging"
+	"flowjs-works/engine/internal/models"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/client"
+	"github.com/emersion/go-message"
+	"github.com/emersion/go-message/mail"
+)
+
+const (
+	// emailDefaultPollInterval is how often the mailbox is searched when
+	// poll_interval_ms is not configured.
+	emailDefaultPollInterval = time.Minute
+	// emailDefaultMaxMessages caps the messages delivered per poll.
+	emailDefaultMaxMessages = 50
+	// emailDefaultMaxAttachmentBytes is the largest attachment whose content
+	// is included in trigger_data; larger ones are listed without content.
+	emailDefaultMaxAttachmentBytes = 10 << 20
+	// emailDialTimeout bounds connecting to the IMAP server.
+	emailDialTimeout = 30 * time.Second
+)
+
+// emailTrigger fires the flow once per message found in an IMAP folder.
+//
+// Every poll connects to the server, searches the folder (by default for
+// unseen messages) and executes the flow with the parsed message as
+// trigger_data {uid, folder, message_id, from, to, cc, subject, date,
+// headers, text, html, attachments}. After a successful execution the
+// message is flagged \Seen, or moved to move_to with on_success "move". A
+// failed execution leaves the message untouched so the next poll retries it
+// (at-least-once; pair with a dedupe node on message_id for idempotency).
+type emailTrigger struct {
+	executor  Executor
+	processID string
+	done      chan struct{}
+	wg        sync.WaitGroup
+}
+
+// emailConfig is the parsed email trigger config.
+type emailConfig struct {
+	host               string
+	port               int
+	security           string // TLS | STARTTLS | NONE
+	user               string
+	password           string
+	folder             string
+	criteria           *imap.SearchCriteria
+	onSuccess          string // seen | move
+	moveTo             string
+	pollInterval       time.Duration
+	maxMessages        int
+	maxAttachmentBytes int64
+}
+
+func newEmailTrigger(executor Executor) *emailTrigger {
+	return &emailTrigger{executor: executor}
+}
+
+// Start validates the config and begins polling in a background goroutine.
+// The first poll runs immediately.
+func (t *emailTrigger) Start(_ context.Context, proc *models.Process) error {
+	cfg, err := emailTriggerConfig(proc.Trigger.Config)
+	if err != nil {
+		return fmt.Errorf("email_trigger: %w", err)
+	}
+	t.processID = proc.Definition.ID
+	t.done = make(chan struct{})
+	procCopy := *proc
+
+	t.wg.Add(1)
+	go t.poll(cfg, &procCopy)
+	slog.Info("email_trigger: polling mailbox", "host", cfg.host, "folder", cfg.folder, logging.KeyProcessID, proc.Definition.ID)
+	return nil
+}
+
+func (t *emailTrigger) poll(cfg emailConfig, proc *models.Process) {
+	defer t.wg.Done()
+	ticker := time.NewTicker(cfg.pollInterval)
+	defer ticker.Stop()
+	for {
+		if err := deliverEmails(cfg, proc, t.executor, t.done); err != nil {
+			slog.Error("email_trigger: deliver messages", logging.KeyProcessID, proc.Definition.ID, logging.KeyError, err)
+		}
+		select {
+		case <-t.done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Stop ends polling. A message being executed is allowed to finish.
+func (t *emailTrigger) Stop() error {
+	if t.done != nil {
+		close(t.done)
+		t.done = nil
+	}
+	t.wg.Wait()
+	return nil
+}
+
+func (t *emailTrigger) Type() string { return "email" }
+
+// deliverEmails runs one poll: it executes the flow for each matching message
+// and marks or moves the messages whose execution succeeded.
+func deliverEmails(cfg emailConfig, proc *models.Process, executor Executor, done <-chan struct{}) error {
+	c, err := dialIMAP(cfg)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = c.Logout() }()
+
+	if _, err := c.Select(cfg.folder, false); err != nil {
+		return fmt.Errorf("select %q: %w", cfg.folder, err)
+	}
+	uids, err := c.UidSearch(cfg.criteria)
+	if err != nil {
+		return fmt.Errorf("search %q: %w", cfg.folder, err)
+	}
+	if len(uids) > cfg.maxMessages {
+		uids = uids[:cfg.maxMessages]
+	}
+
+	for _, uid := range uids {
+		select {
+		case <-done:
+			return nil
+		default:
+		}
+		raw, err := fetchMessage(c, uid)
+		if err != nil {
+			return err
+		}
+		triggerData, err := parseEmail(raw, cfg.maxAttachmentBytes)
+		if err != nil {
+			// An unparsable message would fail every poll; skip it unmarked.
+			slog.Warn("email_trigger: skipping unparsable message", logging.KeyProcessID, proc.Definition.ID, "uid", uid, logging.KeyError, err)
+			continue
+		}
+		triggerData["uid"] = uid
+		triggerData["folder"] = cfg.folder
+
+		if _, err := executor.Execute(proc, triggerData); err != nil {
+			slog.Error("email_trigger: execution failed; message left for retry", logging.KeyProcessID, proc.Definition.ID, "uid", uid, logging.KeyError, err)
+			continue
+		}
+		if err := markProcessed(c, cfg, uid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dialIMAP connects and logs in according to cfg.security.
+func dialIMAP(cfg emailConfig) (*client.Client, error) {
+	addr := net.JoinHostPort(cfg.host, strconv.Itoa(cfg.port))
+	dialer := &net.Dialer{Timeout: emailDialTimeout}
+	tlsCfg := &tls.Config{ServerName: cfg.host}
+
+	var (
+		c   *client.Client
+		err error
+	)
+	if cfg.security == "TLS" {
+		c, err = client.DialWithDialerTLS(dialer, addr, tlsCfg)
+	} else {
+		c, err = client.DialWithDialer(dialer, addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("connect %s: %w", addr, err)
+	}
+	if cfg.security == "STARTTLS" {
+		if err := c.StartTLS(tlsCfg); err != nil {
+			_ = c.Logout()
+			return nil, fmt.Errorf("starttls %s: %w", addr, err)
+		}
+	}
+	if err := c.Login(cfg.user, cfg.password); err != nil {
+		_ = c.Logout()
+		return nil, fmt.Errorf("login %s: %w", addr, err)
+	}
+	return c, nil
+}
+
+// fetchMessage returns the raw RFC 822 message uid without setting \Seen.
+func fetchMessage(c *client.Client, uid uint32) ([]byte, error) {
+	seq := new(imap.SeqSet)
+	seq.AddNum(uid)
+	section := &imap.BodySectionName{Peek: true}
+	msgs := make(chan *imap.Message, 1)
+	if err := c.UidFetch(seq, []imap.FetchItem{imap.FetchUid, section.FetchItem()}, msgs); err != nil {
+		return nil, fmt.Errorf("fetch uid %d: %w", uid, err)
+	}
+	msg := <-msgs
+	if msg == nil {
+		return nil, fmt.Errorf("fetch uid %d: message not found", uid)
+	}
+	body := msg.GetBody(section)
+	if body == nil {
+		return nil, fmt.Errorf("fetch uid %d: empty body", uid)
+	}
+	return io.ReadAll(body)
+}
+
+// markProcessed flags message uid \Seen or moves it to cfg.moveTo.
+func markProcessed(c *client.Client, cfg emailConfig, uid uint32) error {
+	seq := new(imap.SeqSet)
+	seq.AddNum(uid)
+	if cfg.onSuccess == "move" {
+		if err := c.UidMove(seq, cfg.moveTo); err != nil {
+			return fmt.Errorf("move uid %d to %q: %w", uid, cfg.moveTo, err)
+		}
+		return nil
+	}
+	item := imap.FormatFlagsOp(imap.AddFlags, true)
+	if err := c.UidStore(seq, item, []interface{}{imap.SeenFlag}, nil); err != nil {
+		return fmt.Errorf("flag uid %d seen: %w", uid, err)
+	}
+	return nil
+}
+
+// ---------------------------------------------------------------------------
+// Message parsing
+// ---------------------------------------------------------------------------
+
+// parseEmail converts a raw message into trigger_data. Attachments larger
+// than maxAttachmentBytes are listed with skipped: true and no content.
+func parseEmail(raw []byte, maxAttachmentBytes int64) (map[string]interface{}, error) {
+	r, err := mail.CreateReader(bytes.NewReader(raw))
+	if err != nil && !message.IsUnknownCharset(err) {
+		return nil, fmt.Errorf("parse message: %w", err)
+	}
+	defer r.Close()
+
+	h := r.Header
+	data := map[string]interface{}{
+		"message_id":  "",
+		"from":        "",
+		"to":          addressList(h, "To"),
+		"cc":          addressList(h, "Cc"),
+		"subject":     "",
+		"date":        "",
+		"headers":     headerMap(h.Header),
+		"text":        "",
+		"html":        "",
+		"attachments": []interface{}{},
+	}
+	if id, err := h.MessageID(); err == nil {
+		data["message_id"] = id
+	}
+	if from := addressList(h, "From"); len(from) > 0 {
+		data["from"] = from[0]
+	}
+	if subject, err := h.Subject(); err == nil {
+		data["subject"] = subject
+	}
+	if date, err := h.Date(); err == nil && !date.IsZero() {
+		data["date"] = date.UTC().Format(time.RFC3339)
+	}
+
+	var attachments []interface{}
+	for {
+		p, err := r.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil && !message.IsUnknownCharset(err) {
+			return nil, fmt.Errorf("parse message part: %w", err)
+		}
+		if p == nil {
+			continue
+		}
+		switch ph := p.Header.(type) {
+		case *mail.InlineHeader:
+			body, err := io.ReadAll(p.Body)
+			if err != nil {
+				return nil, fmt.Errorf("read message part: %w", err)
+			}
+			contentType, _, _ := ph.ContentType()
+			key := "text"
+			if contentType == "text/html" {
+				key = "html"
+			}
+			if data[key] == "" {
+				data[key] = string(body)
+			}
+		case *mail.AttachmentHeader:
+			attachments = append(attachments, readAttachment(ph, p.Body, maxAttachmentBytes))
+		}
+	}
+	if attachments != nil {
+		data["attachments"] = attachments
+	}
+	return data, nil
+}
+
+// readAttachment returns {filename, content_type, size, content} with the
+// content base64-encoded, or skipped: true when it exceeds limit.
+func readAttachment(h *mail.AttachmentHeader, body io.Reader, limit int64) map[string]interface{} {
+	filename, _ := h.Filename()
+	contentType, _, _ := h.ContentType()
+	att := map[string]interface{}{"filename": filename, "content_type": contentType}
+
+	content, err := io.ReadAll(io.LimitReader(body, limit+1))
+	if err != nil {
+		att["error"] = err.Error()
+		return att
+	}
+	if int64(len(content)) > limit {
+		n, _ := io.Copy(io.Discard, body)
+		att["size"] = int64(len(content)) + n
+		att["skipped"] = true
+		return att
+	}
+	att["size"] = len(content)
+	att["content"] = base64.StdEncoding.EncodeToString(content)
+	return att
+}
+
+func addressList(h mail.Header, key string) []string {
+	addrs, err := h.AddressList(key)
+	if err != nil {
+		return []string{}
+	}
+	out := make([]string, 0, len(addrs))
+	for _, a := range addrs {
+		out = append(out, a.Address)
+	}
+	return out
+}
+
+// headerMap returns the first value of every header, keyed by its canonical
+// name.
+func headerMap(h message.Header) map[string]interface{} {
+	out := make(map[string]interface{})
+	fields := h.Fields()
+	for fields.Next() {
+		if _, ok := out[fields.Key()]; ok {
+			continue
+		}
+		v, err := fields.Text()
+		if err != nil {
+			v = fields.Value()
+		}
+		out[fields.Key()] = v
+	}
+	return out
+}
+
+// ---------------------------------------------------------------------------
+// Config
+// ---------------------------------------------------------------------------
+
+// emailTriggerConfig validates the trigger config.
+func emailTriggerConfig(config map[string]interface{}) (emailConfig, error) {
+	cfg := emailConfig{
+		folder:             "INBOX",
+		onSuccess:          "seen",
+		pollInterval:       emailDefaultPollInterval,
+		maxMessages:        emailDefaultMaxMessages,
+		maxAttachmentBytes: emailDefaultMaxAttachmentBytes,
+	}
+	if config == nil {
+		return cfg, errors.New("trigger config is nil; expected {\"host\":\"...\",\"auth\":{...}}")
+	}
+	cfg.host, _ = config["host"].(string)
+	if cfg.host == "" {
+		return cfg, errors.New("trigger config missing required field \"host\"")
+	}
+
+	cfg.security, _ = config["security"].(string)
+	cfg.security = strings.ToUpper(cfg.security)
+	switch cfg.security {
+	case "", "TLS":
+		cfg.security, cfg.port = "TLS", 993
+	case "STARTTLS", "NONE":
+		cfg.port = 143
+	default:
+		return cfg, fmt.Errorf("unsupported security %q (want TLS, STARTTLS or NONE)", cfg.security)
+	}
+	if v, ok := config["port"].(float64); ok && v > 0 {
+		cfg.port = int(v)
+	}
+
+	cfg.user, cfg.password = emailCredential(config, "user"), emailCredential(config, "password")
+	if cfg.user == "" {
+		return cfg, errors.New("trigger config missing required field \"auth.user\"")
+	}
+	if v, _ := config["folder"].(string); v != "" {
+		cfg.folder = v
+	}
+
+	unseen := true
+	cfg.criteria = imap.NewSearchCriteria()
+	if search, ok := config["search"].(map[string]interface{}); ok {
+		if v, ok := search["unseen"].(bool); ok {
+			unseen = v
+		}
+		for _, field := range []string{"from", "to", "subject"} {
+			if v, _ := search[field].(string); v != "" {
+				cfg.criteria.Header.Add(field, v)
+			}
+		}
+		if v, _ := search["text"].(string); v != "" {
+			cfg.criteria.Text = []string{v}
+		}
+		if v, _ := search["since"].(string); v != "" {
+			since, err := time.Parse("2006-01-02", v)
+			if err != nil {
+				return cfg, fmt.Errorf("search.since must be a date (YYYY-MM-DD): %w", err)
+			}
+			cfg.criteria.Since = since
+		}
+	}
+	if unseen {
+		cfg.criteria.WithoutFlags = []string{imap.SeenFlag}
+	}
+
+	if v, _ := config["on_success"].(string); v != "" {
+		cfg.onSuccess = v
+	}
+	switch cfg.onSuccess {
+	case "seen":
+		if !unseen {
+			return cfg, errors.New("on_success \"seen\" requires search.unseen, otherwise messages are delivered again on every poll")
+		}
+	case "move":
+		cfg.moveTo, _ = config["move_to"].(string)
+		if cfg.moveTo == "" {
+			return cfg, errors.New("trigger config missing required field \"move_to\" for on_success \"move\"")
+		}
+	default:
+		return cfg, fmt.Errorf("unsupported on_success %q (want seen or move)", cfg.onSuccess)
+	}
+
+	if v, ok := config["poll_interval_ms"].(float64); ok && v > 0 {
+		cfg.pollInterval = time.Duration(v) * time.Millisecond
+	}
+	if v, ok := config["max_messages"].(float64); ok && v > 0 {
+		cfg.maxMessages = int(v)
+	}
+	if v, ok := config["max_attachment_bytes"].(float64); ok && v > 0 {
+		cfg.maxAttachmentBytes = int64(v)
+	}
+	return cfg, nil
+}
+
+// emailCredential reads key from config["auth"], falling back to a flat
+// top-level key, like the mail node.
+func emailCredential(config map[string]interface{}, key string) string {
+	if auth, ok := config["auth"].(map[string]interface{}); ok {
+		if v, ok := auth[key].(string); ok {
+			return v
+		}
+	}
+	v, _ := config[key].(string)
+	return v
+}
@@ -0,0 +1,138 @@
+package triggers
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"flowjs-works/engine/internal/models"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/backend/memory"
+	"github.com/emersion/go-imap/client"
+	"github.com/emersion/go-imap/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testMultipartEmail = "From: Ana <ana@example.com>\r\n" +
+	"To: orders@example.com, ops@example.com\r\n" +
+	"Subject: Order 42\r\n" +
+	"Date: Mon, 02 Jan 2006 15:04:05 +0000\r\n" +
+	"Message-ID: <order-42@example.com>\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/mixed; boundary=XYZ\r\n" +
+	"\r\n" +
+	"--XYZ\r\n" +
+	"Content-Type: text/plain; charset=utf-8\r\n" +
+	"\r\n" +
+	"Please ship order 42.\r\n" +
+	"--XYZ\r\n" +
+	"Content-Type: text/csv\r\n" +
+	"Content-Disposition: attachment; filename=\"order.csv\"\r\n" +
+	"\r\n" +
+	"sku,qty\r\n" +
+	"--XYZ--\r\n"
+
+func TestEmailTriggerConfig(t *testing.T) {
+	_, err := emailTriggerConfig(nil)
+	assert.Error(t, err)
+	_, err = emailTriggerConfig(map[string]interface{}{"auth": map[string]interface{}{"user": "u"}})
+	assert.ErrorContains(t, err, "host")
+	_, err = emailTriggerConfig(map[string]interface{}{"host": "imap.example.com"})
+	assert.ErrorContains(t, err, "auth.user")
+	_, err = emailTriggerConfig(map[string]interface{}{"host": "h", "user": "u", "on_success": "move"})
+	assert.ErrorContains(t, err, "move_to")
+	_, err = emailTriggerConfig(map[string]interface{}{"host": "h", "user": "u", "search": map[string]interface{}{"unseen": false}})
+	assert.ErrorContains(t, err, "requires search.unseen")
+	_, err = emailTriggerConfig(map[string]interface{}{"host": "h", "user": "u", "security": "SSLv3"})
+	assert.ErrorContains(t, err, "security")
+
+	cfg, err := emailTriggerConfig(map[string]interface{}{
+		"host": "imap.example.com", "auth": map[string]interface{}{"user": "u", "password": "p"},
+		"search":     map[string]interface{}{"from": "billing@example.com", "since": "2024-01-31"},
+		"on_success": "move", "move_to": "Processed", "poll_interval_ms": float64(5000),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 993, cfg.port)
+	assert.Equal(t, "TLS", cfg.security)
+	assert.Equal(t, "INBOX", cfg.folder)
+	assert.Equal(t, "billing@example.com", cfg.criteria.Header.Get("From"))
+	assert.Equal(t, []string{imap.SeenFlag}, cfg.criteria.WithoutFlags)
+	assert.Equal(t, 5*time.Second, cfg.pollInterval)
+}
+
+func TestParseEmail(t *testing.T) {
+	data, err := parseEmail([]byte(testMultipartEmail), 1024)
+	require.NoError(t, err)
+	assert.Equal(t, "order-42@example.com", data["message_id"])
+	assert.Equal(t, "ana@example.com", data["from"])
+	assert.Equal(t, []string{"orders@example.com", "ops@example.com"}, data["to"])
+	assert.Equal(t, "Order 42", data["subject"])
+	assert.Equal(t, "2006-01-02T15:04:05Z", data["date"])
+	assert.Equal(t, "Please ship order 42.", data["text"])
+	assert.Equal(t, "Order 42", data["headers"].(map[string]interface{})["Subject"])
+
+	atts := data["attachments"].([]interface{})
+	require.Len(t, atts, 1)
+	att := atts[0].(map[string]interface{})
+	assert.Equal(t, "order.csv", att["filename"])
+	assert.Equal(t, "text/csv", att["content_type"])
+	assert.Equal(t, "c2t1LHF0eQ==", att["content"])
+
+	data, err = parseEmail([]byte(testMultipartEmail), 4)
+	require.NoError(t, err)
+	att = data["attachments"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, true, att["skipped"])
+	assert.NotContains(t, att, "content")
+}
+
+// startIMAPServer serves the go-imap memory backend (user "username",
+// password "password") on a local port.
+func startIMAPServer(t *testing.T) (string, int) {
+	t.Helper()
+	s := server.New(memory.New())
+	s.AllowInsecureAuth = true
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = s.Serve(ln) }()
+	t.Cleanup(func() { _ = s.Close() })
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	n, _ := strconv.Atoi(port)
+	return host, n
+}
+
+// TestDeliverEmails verifies that unseen messages fire the flow once and are
+// flagged seen, and that a failed execution leaves the message for the next poll.
+func TestDeliverEmails(t *testing.T) {
+	host, port := startIMAPServer(t)
+	c, err := client.Dial(net.JoinHostPort(host, strconv.Itoa(port)))
+	require.NoError(t, err)
+	require.NoError(t, c.Login("username", "password"))
+	require.NoError(t, c.Append("INBOX", nil, time.Now(), bytes.NewBufferString(testMultipartEmail)))
+	require.NoError(t, c.Logout())
+
+	cfg, err := emailTriggerConfig(map[string]interface{}{
+		"host": host, "port": float64(port), "security": "NONE",
+		"auth": map[string]interface{}{"user": "username", "password": "password"},
+	})
+	require.NoError(t, err)
+	proc := &models.Process{Definition: models.Definition{ID: "p_mail"}}
+
+	failing := &mockExecutor{err: errors.New("boom")}
+	require.NoError(t, deliverEmails(cfg, proc, failing, nil))
+	require.Len(t, failing.executions, 1, "the seed message is already seen")
+
+	exec := &mockExecutor{}
+	require.NoError(t, deliverEmails(cfg, proc, exec, nil))
+	require.Len(t, exec.executions, 1, "a failed message is delivered again")
+	assert.Equal(t, "Order 42", exec.executions[0]["subject"])
+	assert.Equal(t, "INBOX", exec.executions[0]["folder"])
+	assert.Contains(t, exec.executions[0]["text"], "order 42")
+
+	require.NoError(t, deliverEmails(cfg, proc, exec, nil))
+	assert.Len(t, exec.executions, 1, "a processed message is flagged seen")
+}
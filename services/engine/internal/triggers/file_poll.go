@@ -0,0 +1,338 @@
+package triggers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"flowjs-works/engine/internal/activities"
+	"flowjs-works/engine/internal/logging"
+	"flowjs-works/engine/internal/models"
+	"flowjs-works/engine/internal/tenant"
+)
+
+const (
+	// filePollDefaultInterval is how often the remote folder is listed when
+	// poll_interval_ms is not configured.
+	filePollDefaultInterval = time.Minute
+	// filePollDefaultMaxFiles caps the files delivered per poll.
+	filePollDefaultMaxFiles = 100
+	// filePollListTimeout bounds listing the remote folder.
+	filePollListTimeout = 5 * time.Minute
+)
+
+// FileStateStore remembers the files sftp_poll and s3_poll triggers fired
+// the flow for, so a restart or redeploy does not fire them again. The
+// workspace is carried by ctx.
+type FileStateStore interface {
+	// SeenFiles returns the fingerprint recorded for each file of processID,
+	// by path or object key.
+	SeenFiles(ctx context.Context, processID string) (map[string]string, error)
+	// MarkFile records that the flow ran for key at fingerprint.
+	MarkFile(ctx context.Context, processID, key, fingerprint string) error
+	// ForgetFile deletes the record of key, once it is no longer listed.
+	ForgetFile(ctx context.Context, processID, key string) error
+}
+
+// memoryFileState is the FileStateStore used without a config DB. Its
+// records are lost when the engine restarts.
+type memoryFileState struct {
+	mu    sync.Mutex
+	files map[string]map[string]string // by workspace/process id
+}
+
+func newMemoryFileState() *memoryFileState {
+	return &memoryFileState{files: make(map[string]map[string]string)}
+}
+
+func (s *memoryFileState) SeenFiles(ctx context.Context, processID string) (map[string]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	seen := make(map[string]string)
+	for k, v := range s.files[tenant.Workspace(ctx)+"/"+processID] {
+		seen[k] = v
+	}
+	return seen, nil
+}
+
+func (s *memoryFileState) MarkFile(ctx context.Context, processID, key, fingerprint string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := tenant.Workspace(ctx) + "/" + processID
+	if s.files[id] == nil {
+		s.files[id] = make(map[string]string)
+	}
+	s.files[id][key] = fingerprint
+	return nil
+}
+
+func (s *memoryFileState) ForgetFile(ctx context.Context, processID, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.files[tenant.Workspace(ctx)+"/"+processID], key)
+	return nil
+}
+
+// filePollTrigger fires the flow once per new file found in an SFTP folder
+// (sftp_poll) or under an S3 prefix (s3_poll).
+//
+// Every poll lists the folder and executes the flow for each file that the
+// state store has no record of, or whose size or modification time (the
+// ETag for S3) changed, with trigger_data {name, path or key, folder, size,
+// modified} plus server (SFTP) or bucket and etag (S3). After a successful
+// execution the file is recorded; a failed execution leaves it unrecorded
+// so the next poll retries it (at-least-once). Records of files no longer
+// listed are deleted, so a file uploaded again under the same name fires
+// again.
+type filePollTrigger struct {
+	triggerType string
+	executor    Executor
+	state       FileStateStore
+	cancel      context.CancelFunc
+	wg          sync.WaitGroup
+}
+
+// filePollConfig is the parsed sftp_poll or s3_poll trigger config.
+type filePollConfig struct {
+	// source names the listed folder in logs, e.g. "s3://bucket/prefix".
+	source string
+	// list returns the files of the folder.
+	list func(ctx context.Context) ([]activities.RemoteFile, error)
+	// pathKey is the trigger_data field holding RemoteFile.Path.
+	pathKey string
+	// base holds the trigger_data fields shared by every file.
+	base         map[string]interface{}
+	filter       *regexp.Regexp
+	minAge       time.Duration
+	pollInterval time.Duration
+	maxFiles     int
+}
+
+func newFilePollTrigger(triggerType string, executor Executor, state FileStateStore) *filePollTrigger {
+	return &filePollTrigger{triggerType: triggerType, executor: executor, state: state}
+}
+
+// Start validates the config and begins polling in a background goroutine.
+// The first poll runs immediately.
+func (t *filePollTrigger) Start(_ context.Context, proc *models.Process) error {
+	cfg, err := filePollTriggerConfig(t.triggerType, proc.Trigger.Config)
+	if err != nil {
+		return fmt.Errorf("%s_trigger: %w", t.triggerType, err)
+	}
+	procCopy := *proc
+	ctx, cancel := context.WithCancel(tenant.WithWorkspace(context.Background(), proc.Definition.Workspace))
+	t.cancel = cancel
+
+	t.wg.Add(1)
+	go t.poll(ctx, cfg, &procCopy)
+	slog.Info(t.triggerType+"_trigger: polling folder", "source", cfg.source, logging.KeyProcessID, proc.Definition.ID)
+	return nil
+}
+
+func (t *filePollTrigger) poll(ctx context.Context, cfg filePollConfig, proc *models.Process) {
+	defer t.wg.Done()
+	ticker := time.NewTicker(cfg.pollInterval)
+	defer ticker.Stop()
+	for {
+		if err := deliverFiles(ctx, cfg, proc, t.executor, t.state); err != nil && ctx.Err() == nil {
+			slog.Error(t.triggerType+"_trigger: deliver files", logging.KeyProcessID, proc.Definition.ID, "source", cfg.source, logging.KeyError, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Stop ends polling. A file being executed is allowed to finish.
+func (t *filePollTrigger) Stop() error {
+	if t.cancel != nil {
+		t.cancel()
+		t.cancel = nil
+	}
+	t.wg.Wait()
+	return nil
+}
+
+func (t *filePollTrigger) Type() string { return t.triggerType }
+
+// deliverFiles runs one poll: it executes the flow for each new file, oldest
+// first, and records the files whose execution succeeded. Cancelling ctx
+// stops the poll between files.
+func deliverFiles(ctx context.Context, cfg filePollConfig, proc *models.Process, executor Executor, state FileStateStore) error {
+	listCtx, cancel := context.WithTimeout(ctx, filePollListTimeout)
+	files, err := cfg.list(listCtx)
+	cancel()
+	if err != nil {
+		return fmt.Errorf("list %s: %w", cfg.source, err)
+	}
+	// A record must not be lost to a Stop arriving mid-execution.
+	stateCtx := context.WithoutCancel(ctx)
+	seen, err := state.SeenFiles(stateCtx, proc.Definition.ID)
+	if err != nil {
+		return fmt.Errorf("read file state: %w", err)
+	}
+
+	fresh := newFiles(files, seen, cfg.filter, cfg.minAge, time.Now())
+	listedKeys := make(map[string]bool, len(files))
+	for _, f := range files {
+		listedKeys[f.Path] = true
+	}
+	for key := range seen {
+		if !listedKeys[key] {
+			if err := state.ForgetFile(stateCtx, proc.Definition.ID, key); err != nil {
+				return fmt.Errorf("forget %q: %w", key, err)
+			}
+		}
+	}
+	if len(fresh) > cfg.maxFiles {
+		fresh = fresh[:cfg.maxFiles]
+	}
+
+	for _, f := range fresh {
+		if ctx.Err() != nil {
+			return nil
+		}
+		if _, err := executor.Execute(proc, fileTriggerData(cfg, f)); err != nil {
+			slog.Error(proc.Trigger.Type+"_trigger: execution failed; file left for retry", logging.KeyProcessID, proc.Definition.ID, "file", f.Path, logging.KeyError, err)
+			continue
+		}
+		if err := state.MarkFile(stateCtx, proc.Definition.ID, f.Path, fileFingerprint(f)); err != nil {
+			return fmt.Errorf("record %q: %w", f.Path, err)
+		}
+	}
+	return nil
+}
+
+// newFiles returns the files matching filter, older than minAge, whose
+// fingerprint differs from the one in seen, sorted oldest first.
+func newFiles(files []activities.RemoteFile, seen map[string]string, filter *regexp.Regexp, minAge time.Duration, now time.Time) []activities.RemoteFile {
+	var fresh []activities.RemoteFile
+	for _, f := range files {
+		if filter != nil && !filter.MatchString(f.Name) {
+			continue
+		}
+		if fp, ok := seen[f.Path]; ok && fp == fileFingerprint(f) {
+			continue
+		}
+		// A file modified recently may still be being written.
+		if minAge > 0 && now.Sub(f.Modified) < minAge {
+			continue
+		}
+		fresh = append(fresh, f)
+	}
+	sort.SliceStable(fresh, func(i, j int) bool {
+		if !fresh[i].Modified.Equal(fresh[j].Modified) {
+			return fresh[i].Modified.Before(fresh[j].Modified)
+		}
+		return fresh[i].Path < fresh[j].Path
+	})
+	return fresh
+}
+
+// fileFingerprint identifies the content of f: the ETag when the store has
+// one, the size and modification time otherwise.
+func fileFingerprint(f activities.RemoteFile) string {
+	if f.ETag != "" {
+		return f.ETag
+	}
+	return strconv.FormatInt(f.Size, 10) + ":" + strconv.FormatInt(f.Modified.UnixNano(), 10)
+}
+
+func fileTriggerData(cfg filePollConfig, f activities.RemoteFile) map[string]interface{} {
+	data := make(map[string]interface{}, len(cfg.base)+5)
+	for k, v := range cfg.base {
+		data[k] = v
+	}
+	data["name"] = f.Name
+	data[cfg.pathKey] = f.Path
+	data["size"] = f.Size
+	data["modified"] = f.Modified.Format(time.RFC3339)
+	if f.ETag != "" {
+		data["etag"] = f.ETag
+	}
+	return data
+}
+
+// ---------------------------------------------------------------------------
+// Config
+// ---------------------------------------------------------------------------
+
+// filePollTriggerConfig validates the config of an sftp_poll or s3_poll
+// trigger. Credentials are left in config, where the listing reads them
+// like the sftp and s3 nodes do.
+func filePollTriggerConfig(triggerType string, config map[string]interface{}) (filePollConfig, error) {
+	cfg := filePollConfig{
+		pollInterval: filePollDefaultInterval,
+		maxFiles:     filePollDefaultMaxFiles,
+	}
+	if config == nil {
+		return cfg, errors.New("trigger config is nil")
+	}
+	folder, _ := config["folder"].(string)
+
+	switch triggerType {
+	case "sftp_poll":
+		server, _ := config["server"].(string)
+		if server == "" {
+			return cfg, errors.New("trigger config missing required field \"server\"")
+		}
+		if folder == "" {
+			return cfg, errors.New("trigger config missing required field \"folder\"")
+		}
+		port := 22
+		if v, ok := config["port"].(float64); ok && v > 0 {
+			port = int(v)
+		}
+		addr := net.JoinHostPort(server, strconv.Itoa(port))
+		cfg.source = addr + ":" + folder
+		cfg.pathKey = "path"
+		cfg.base = map[string]interface{}{"server": server, "folder": folder}
+		cfg.list = func(ctx context.Context) ([]activities.RemoteFile, error) {
+			return activities.ListSFTPFolder(ctx, addr, folder, config)
+		}
+	case "s3_poll":
+		bucket, _ := config["bucket"].(string)
+		if bucket == "" {
+			return cfg, errors.New("trigger config missing required field \"bucket\"")
+		}
+		region, _ := config["region"].(string)
+		if region == "" {
+			return cfg, errors.New("trigger config missing required field \"region\"")
+		}
+		cfg.source = "s3://" + bucket + "/" + folder
+		cfg.pathKey = "key"
+		cfg.base = map[string]interface{}{"bucket": bucket, "folder": folder}
+		cfg.list = func(ctx context.Context) ([]activities.RemoteFile, error) {
+			return activities.ListS3Prefix(ctx, region, bucket, folder, config)
+		}
+	default:
+		return cfg, fmt.Errorf("unsupported file poll trigger type %q", triggerType)
+	}
+
+	if v, _ := config["regex_filter"].(string); v != "" {
+		re, err := regexp.Compile(v)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid regex_filter: %w", err)
+		}
+		cfg.filter = re
+	}
+	if v, ok := config["poll_interval_ms"].(float64); ok && v > 0 {
+		cfg.pollInterval = time.Duration(v) * time.Millisecond
+	}
+	if v, ok := config["max_files"].(float64); ok && v > 0 {
+		cfg.maxFiles = int(v)
+	}
+	if v, ok := config["min_age_ms"].(float64); ok && v > 0 {
+		cfg.minAge = time.Duration(v) * time.Millisecond
+	}
+	return cfg, nil
+}
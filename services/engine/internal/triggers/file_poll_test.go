@@ -0,0 +1,124 @@
+package triggers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"flowjs-works/engine/internal/activities"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilePollTriggerConfig(t *testing.T) {
+	_, err := filePollTriggerConfig("sftp_poll", nil)
+	assert.Error(t, err)
+	_, err = filePollTriggerConfig("sftp_poll", map[string]interface{}{"folder": "/in"})
+	assert.ErrorContains(t, err, "server")
+	_, err = filePollTriggerConfig("sftp_poll", map[string]interface{}{"server": "h"})
+	assert.ErrorContains(t, err, "folder")
+	_, err = filePollTriggerConfig("s3_poll", map[string]interface{}{"region": "eu-west-1"})
+	assert.ErrorContains(t, err, "bucket")
+	_, err = filePollTriggerConfig("s3_poll", map[string]interface{}{"bucket": "b"})
+	assert.ErrorContains(t, err, "region")
+	_, err = filePollTriggerConfig("s3_poll", map[string]interface{}{"bucket": "b", "region": "r", "regex_filter": "("})
+	assert.ErrorContains(t, err, "regex_filter")
+
+	cfg, err := filePollTriggerConfig("sftp_poll", map[string]interface{}{
+		"server": "sftp.example.com", "folder": "/in", "regex_filter": `\.csv$`,
+		"poll_interval_ms": float64(5000), "min_age_ms": float64(30000),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "sftp.example.com:22:/in", cfg.source)
+	assert.Equal(t, "path", cfg.pathKey)
+	assert.Equal(t, 5*time.Second, cfg.pollInterval)
+	assert.Equal(t, 30*time.Second, cfg.minAge)
+	assert.Equal(t, filePollDefaultMaxFiles, cfg.maxFiles)
+	assert.True(t, cfg.filter.MatchString("orders.csv"))
+
+	cfg, err = filePollTriggerConfig("s3_poll", map[string]interface{}{"bucket": "b", "region": "r", "folder": "in/", "max_files": float64(5)})
+	require.NoError(t, err)
+	assert.Equal(t, "s3://b/in/", cfg.source)
+	assert.Equal(t, "key", cfg.pathKey)
+	assert.Equal(t, 5, cfg.maxFiles)
+}
+
+func TestNewFiles(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	old := activities.RemoteFile{Name: "a.csv", Path: "/in/a.csv", Size: 10, Modified: now.Add(-time.Hour)}
+	seen := activities.RemoteFile{Name: "b.csv", Path: "/in/b.csv", Size: 20, Modified: now.Add(-2 * time.Hour)}
+	changed := activities.RemoteFile{Name: "c.csv", Path: "/in/c.csv", Size: 30, Modified: now.Add(-3 * time.Hour)}
+	recent := activities.RemoteFile{Name: "d.csv", Path: "/in/d.csv", Size: 40, Modified: now.Add(-time.Second)}
+	other := activities.RemoteFile{Name: "e.txt", Path: "/in/e.txt", Size: 50, Modified: now.Add(-time.Hour)}
+
+	state := map[string]string{seen.Path: fileFingerprint(seen), changed.Path: "1:1"}
+	fresh := newFiles([]activities.RemoteFile{old, seen, changed, recent, other}, state, nil, time.Minute, now)
+	assert.Equal(t, []activities.RemoteFile{changed, old, other}, fresh, "oldest first, without seen or recent files")
+
+	filtered, err := filePollTriggerConfig("sftp_poll", map[string]interface{}{"server": "h", "folder": "/in", "regex_filter": `\.csv$`})
+	require.NoError(t, err)
+	fresh = newFiles([]activities.RemoteFile{old, other}, nil, filtered.filter, 0, now)
+	assert.Equal(t, []activities.RemoteFile{old}, fresh)
+}
+
+func TestDeliverFiles(t *testing.T) {
+	files := []activities.RemoteFile{
+		{Name: "a.csv", Path: "in/a.csv", Size: 1, Modified: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), ETag: "e1"},
+		{Name: "b.csv", Path: "in/b.csv", Size: 2, Modified: time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC), ETag: "e2"},
+	}
+	cfg, err := filePollTriggerConfig("s3_poll", map[string]interface{}{"bucket": "invoices", "region": "r", "folder": "in/"})
+	require.NoError(t, err)
+	cfg.list = func(context.Context) ([]activities.RemoteFile, error) { return files, nil }
+
+	proc := buildProcess("p1", "s3_poll", nil)
+	state := newMemoryFileState()
+	ctx := context.Background()
+	require.NoError(t, state.MarkFile(ctx, "p1", "in/gone.csv", "e0"))
+
+	exec := &mockExecutor{}
+	require.NoError(t, deliverFiles(ctx, cfg, proc, exec, state))
+	require.Len(t, exec.executions, 2)
+	assert.Equal(t, map[string]interface{}{
+		"name": "a.csv", "key": "in/a.csv", "bucket": "invoices", "folder": "in/",
+		"size": int64(1), "modified": "2024-05-01T00:00:00Z", "etag": "e1",
+	}, exec.executions[0])
+
+	seen, err := state.SeenFiles(ctx, "p1")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"in/a.csv": "e1", "in/b.csv": "e2"}, seen, "listed files recorded, missing ones forgotten")
+
+	// Nothing is new on the next poll; a changed file fires again.
+	require.NoError(t, deliverFiles(ctx, cfg, proc, exec, state))
+	assert.Len(t, exec.executions, 2)
+	files[1].ETag = "e3"
+	require.NoError(t, deliverFiles(ctx, cfg, proc, exec, state))
+	assert.Len(t, exec.executions, 3)
+}
+
+func TestDeliverFiles_FailedExecutionRetried(t *testing.T) {
+	files := []activities.RemoteFile{{Name: "a.csv", Path: "/in/a.csv", Size: 1, Modified: time.Now().Add(-time.Hour)}}
+	cfg, err := filePollTriggerConfig("sftp_poll", map[string]interface{}{"server": "h", "folder": "/in"})
+	require.NoError(t, err)
+	cfg.list = func(context.Context) ([]activities.RemoteFile, error) { return files, nil }
+
+	proc := buildProcess("p1", "sftp_poll", nil)
+	state := newMemoryFileState()
+	exec := &mockExecutor{err: assert.AnError}
+	require.NoError(t, deliverFiles(context.Background(), cfg, proc, exec, state))
+	require.NoError(t, deliverFiles(context.Background(), cfg, proc, exec, state))
+	assert.Len(t, exec.executions, 2, "the failed file is delivered again")
+	assert.Equal(t, "/in/a.csv", exec.executions[0]["path"])
+	assert.Equal(t, "h", exec.executions[0]["server"])
+
+	seen, err := state.SeenFiles(context.Background(), "p1")
+	require.NoError(t, err)
+	assert.Empty(t, seen)
+}
+
+func TestManager_DeployFilePollTrigger(t *testing.T) {
+	mgr := NewManager(&mockExecutor{})
+	proc := buildProcess("p1", "s3_poll", map[string]interface{}{"bucket": "b"})
+	assert.ErrorContains(t, mgr.Deploy(proc), "region")
+	assert.False(t, mgr.IsRunning("p1"))
+}
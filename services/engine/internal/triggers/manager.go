@@ -5,10 +5,13 @@ package triggers
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"sync"
+	"time"
 
+	"flowjs-works/engine/internal/logging"
 	"flowjs-works/engine/internal/models"
 )
 
@@ -18,6 +21,22 @@ type Executor interface {
 	Execute(process *models.Process, triggerData map[string]interface{}) (*models.ExecutionContext, error)
 }
 
+// ContextExecutor is implemented by executors that can bind an execution to
+// the context of the request waiting for it (see ExecuteContext).
+type ContextExecutor interface {
+	ExecuteContext(ctx context.Context, process *models.Process, triggerData map[string]interface{}) (*models.ExecutionContext, error)
+}
+
+// ExecuteContext runs process through executor bound to ctx when executor
+// implements ContextExecutor, and unbound otherwise. A bound execution fails
+// with models.ErrExecutionCancelled once ctx is done.
+func ExecuteContext(ctx context.Context, executor Executor, process *models.Process, triggerData map[string]interface{}) (*models.ExecutionContext, error) {
+	if ce, ok := executor.(ContextExecutor); ok {
+		return ce.ExecuteContext(ctx, process, triggerData)
+	}
+	return executor.Execute(process, triggerData)
+}
+
 // TriggerHandler is the lifecycle interface every trigger must implement.
 type TriggerHandler interface {
 	// Start activates the trigger. For cron and queue-based triggers this
@@ -29,23 +48,62 @@ type TriggerHandler interface {
 	Type() string
 }
 
+// ErrNotDeployed is returned by Run when the process has no active trigger.
+var ErrNotDeployed = errors.New("process is not deployed")
+
 // Manager maintains a registry of running triggers, keyed by process ID.
 // It is safe for concurrent use.
 type Manager struct {
 	executor Executor
-	running  map[string]TriggerHandler
-	mu       sync.Mutex
+	capturer RequestCapturer
+	// fileState records the files sftp_poll and s3_poll triggers fired for.
+	fileState FileStateStore
+	quotas    *quotaTracker
+	running   map[string]*deployment
+	// orphanSince holds since when each orphaned route is known, by route key.
+	orphanSince map[string]time.Time
+	mu          sync.Mutex
+}
+
+// deployment is a running trigger together with the process it serves and
+// the concurrency gate and quotas shared by trigger-fired and manual runs.
+type deployment struct {
+	handler TriggerHandler
+	proc    *models.Process
+	gate    *gatedExecutor
+	// since is when the trigger started.
+	since time.Time
 }
 
 // NewManager creates a Manager that will use executor to run flows when a
 // trigger fires.
 func NewManager(executor Executor) *Manager {
 	return &Manager{
-		executor: executor,
-		running:  make(map[string]TriggerHandler),
+		executor:    executor,
+		fileState:   newMemoryFileState(),
+		quotas:      newQuotaTracker(),
+		running:     make(map[string]*deployment),
+		orphanSince: make(map[string]time.Time),
 	}
 }
 
+// SetRequestCapturer sets where REST and SOAP triggers with capture_days
+// store their inbound requests. It applies to processes deployed afterwards.
+func (m *Manager) SetRequestCapturer(c RequestCapturer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.capturer = c
+}
+
+// SetFileStateStore sets where sftp_poll and s3_poll triggers record the
+// files they fired for, replacing the in-memory default that a restart
+// loses. It applies to processes deployed afterwards.
+func (m *Manager) SetFileStateStore(s FileStateStore) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.fileState = s
+}
+
 // Deploy starts the appropriate trigger for proc. If the process is already
 // deployed, it is stopped first and then restarted (hot-reload semantics).
 func (m *Manager) Deploy(proc *models.Process) error {
@@ -53,15 +111,20 @@ func (m *Manager) Deploy(proc *models.Process) error {
 	defer m.mu.Unlock()
 
 	// Stop any existing handler for this process.
-	if h, ok := m.running[proc.Definition.ID]; ok {
-		log.Printf("triggers: redeploying %q — stopping previous %s trigger", proc.Definition.ID, h.Type())
-		if err := h.Stop(); err != nil {
-			log.Printf("triggers: warning: stop previous %q trigger: %v", proc.Definition.ID, err)
+	if d, ok := m.running[proc.Definition.ID]; ok {
+		slog.Info("triggers: redeploying; stopping previous trigger", logging.KeyProcessID, proc.Definition.ID, logging.KeyTrigger, d.handler.Type())
+		if err := d.handler.Stop(); err != nil {
+			slog.Warn("triggers: stop previous trigger", logging.KeyProcessID, proc.Definition.ID, logging.KeyError, err)
 		}
 		delete(m.running, proc.Definition.ID)
 	}
 
-	handler, err := m.newHandler(proc)
+	// The concurrency gate sits outside the quota so runs it refuses do not
+	// count against the quota.
+	quota := &quotaExecutor{next: m.executor, tracker: m.quotas}
+	quota.auditor, _ = m.executor.(QuotaAuditor)
+	gate := newGatedExecutor(quota, proc.Definition.Settings.MaxConcurrency)
+	handler, err := newHandler(proc, gate, m.capturer, m.fileState)
 	if err != nil {
 		return fmt.Errorf("triggers: create handler for %q: %w", proc.Definition.ID, err)
 	}
@@ -70,8 +133,8 @@ func (m *Manager) Deploy(proc *models.Process) error {
 		return fmt.Errorf("triggers: start %s trigger for %q: %w", proc.Trigger.Type, proc.Definition.ID, err)
 	}
 
-	m.running[proc.Definition.ID] = handler
-	log.Printf("triggers: deployed %s trigger for process %q", proc.Trigger.Type, proc.Definition.ID)
+	m.running[proc.Definition.ID] = &deployment{handler: handler, proc: proc, gate: gate, since: time.Now().UTC()}
+	slog.Info("triggers: deployed", logging.KeyProcessID, proc.Definition.ID, logging.KeyTrigger, proc.Trigger.Type)
 	return nil
 }
 
@@ -80,15 +143,15 @@ func (m *Manager) Stop(processID string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	h, ok := m.running[processID]
+	d, ok := m.running[processID]
 	if !ok {
 		return fmt.Errorf("triggers: process %q is not currently deployed", processID)
 	}
-	if err := h.Stop(); err != nil {
-		return fmt.Errorf("triggers: stop %s trigger for %q: %w", h.Type(), processID, err)
+	if err := d.handler.Stop(); err != nil {
+		return fmt.Errorf("triggers: stop %s trigger for %q: %w", d.handler.Type(), processID, err)
 	}
 	delete(m.running, processID)
-	log.Printf("triggers: stopped trigger for process %q", processID)
+	slog.Info("triggers: stopped", logging.KeyProcessID, processID)
 	return nil
 }
 
@@ -105,37 +168,78 @@ func (m *Manager) IsRunning(processID string) bool {
 func (m *Manager) TriggerType(processID string) string {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	if h, ok := m.running[processID]; ok {
-		return h.Type()
+	if d, ok := m.running[processID]; ok {
+		return d.handler.Type()
 	}
 	return ""
 }
 
+// Counts returns the number of deployed processes per trigger type.
+func (m *Manager) Counts() map[string]int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	counts := make(map[string]int)
+	for _, d := range m.running {
+		counts[d.handler.Type()]++
+	}
+	return counts
+}
+
+// Run fires a deployed process immediately, regardless of its trigger type,
+// through the same concurrency gate and quotas as its trigger. When triggerData is nil a
+// payload shaped like the trigger's own output is used where one exists.
+// It returns ErrNotDeployed, ErrConcurrencyLimit or ErrQuotaExceeded when the
+// run is refused. The run is bound to ctx (see ExecuteContext).
+func (m *Manager) Run(ctx context.Context, processID string, triggerData map[string]interface{}) (*models.ExecutionContext, error) {
+	m.mu.Lock()
+	d, ok := m.running[processID]
+	m.mu.Unlock()
+	if !ok {
+		return nil, ErrNotDeployed
+	}
+	if triggerData == nil {
+		triggerData = defaultTriggerData(d.proc.Trigger.Type)
+	}
+	return d.gate.ExecuteContext(ctx, d.proc, triggerData)
+}
+
 // StopAll deactivates every running trigger. Useful during shutdown.
 func (m *Manager) StopAll() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	for id, h := range m.running {
-		if err := h.Stop(); err != nil {
-			log.Printf("triggers: warning: stop %q: %v", id, err)
+	for id, d := range m.running {
+		if err := d.handler.Stop(); err != nil {
+			slog.Warn("triggers: stop", logging.KeyProcessID, id, logging.KeyError, err)
 		}
 	}
-	m.running = make(map[string]TriggerHandler)
+	m.running = make(map[string]*deployment)
 }
 
 // newHandler selects the correct TriggerHandler implementation for proc.
-func (m *Manager) newHandler(proc *models.Process) (TriggerHandler, error) {
+// capturer is handed to the triggers that can capture their requests, and
+// fileState to those polling remote folders.
+func newHandler(proc *models.Process, executor Executor, capturer RequestCapturer, fileState FileStateStore) (TriggerHandler, error) {
 	switch proc.Trigger.Type {
 	case "cron":
-		return newCronTrigger(m.executor), nil
+		return newCronTrigger(executor), nil
 	case "rabbitmq":
-		return newRabbitMQTrigger(m.executor), nil
+		return newRabbitMQTrigger(executor), nil
 	case "mcp":
-		return newMCPTrigger(m.executor), nil
+		return newMCPTrigger(executor), nil
 	case "rest":
-		return newRESTTrigger(m.executor), nil
+		t := newRESTTrigger(executor)
+		t.capturer = capturer
+		return t, nil
 	case "soap":
-		return newSOAPTrigger(m.executor), nil
+		t := newSOAPTrigger(executor)
+		t.capturer = capturer
+		return t, nil
+	case "postgres_cdc":
+		return newPostgresCDCTrigger(executor), nil
+	case "email":
+		return newEmailTrigger(executor), nil
+	case "sftp_poll", "s3_poll":
+		return newFilePollTrigger(proc.Trigger.Type, executor, fileState), nil
 	case "manual":
 		return &manualTrigger{}, nil
 	default:
@@ -143,6 +247,15 @@ func (m *Manager) newHandler(proc *models.Process) (TriggerHandler, error) {
 	}
 }
 
+// defaultTriggerData returns the payload used for a manual run that supplies
+// no trigger_data.
+func defaultTriggerData(triggerType string) map[string]interface{} {
+	if triggerType == "cron" {
+		return map[string]interface{}{"datetime": time.Now().UTC().Format(time.RFC3339)}
+	}
+	return map[string]interface{}{}
+}
+
 // ---------------------------------------------------------------------------
 // manualTrigger — no-op; the flow is started via the /api/v1/processes/{id}/run endpoint.
 // ---------------------------------------------------------------------------
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -21,12 +22,15 @@ import (
 const cronTickWaitDuration = 1200 * time.Millisecond
 
 type mockExecutor struct {
+	mu         sync.Mutex
 	executions []map[string]interface{}
 	err        error
 }
 
 func (m *mockExecutor) Execute(_ *models.Process, triggerData map[string]interface{}) (*models.ExecutionContext, error) {
+	m.mu.Lock()
 	m.executions = append(m.executions, triggerData)
+	m.mu.Unlock()
 	ctx := models.NewExecutionContext("test-exec-id")
 	return ctx, m.err
 }
@@ -42,6 +46,13 @@ func TestManager_DeployManualTrigger(t *testing.T) {
 	proc := buildProcess("p1", "manual", nil)
 	require.NoError(t, mgr.Deploy(proc))
 	assert.True(t, mgr.IsRunning("p1"))
+
+	status, ok := mgr.Status("p1")
+	require.True(t, ok)
+	assert.Equal(t, TriggerStatus{Type: "manual", State: TriggerRunning, Since: status.Since}, status)
+	assert.False(t, status.Since.IsZero())
+	_, ok = mgr.Status("p_missing")
+	assert.False(t, ok)
 }
 
 func TestManager_StopUnknownProcess(t *testing.T) {
@@ -102,6 +113,18 @@ func TestManager_TriggerType(t *testing.T) {
 	assert.Equal(t, "", mgr.TriggerType("p-manual"))
 }
 
+func TestManager_Counts(t *testing.T) {
+	mgr := NewManager(&mockExecutor{})
+	assert.Empty(t, mgr.Counts())
+
+	require.NoError(t, mgr.Deploy(buildProcess("p1", "manual", nil)))
+	require.NoError(t, mgr.Deploy(buildProcess("p2", "manual", nil)))
+	assert.Equal(t, map[string]int{"manual": 2}, mgr.Counts())
+
+	require.NoError(t, mgr.Stop("p1"))
+	assert.Equal(t, map[string]int{"manual": 1}, mgr.Counts())
+}
+
 // ---------------------------------------------------------------------------
 // Cron trigger tests
 // ---------------------------------------------------------------------------
@@ -132,6 +155,8 @@ func TestCronTrigger_StartStop(t *testing.T) {
 	// Give the scheduler time to fire at least once.
 	time.Sleep(cronTickWaitDuration)
 	require.NoError(t, tr.Stop())
+	exec.mu.Lock()
+	defer exec.mu.Unlock()
 	assert.GreaterOrEqual(t, len(exec.executions), 1, "expected at least one execution")
 }
 
@@ -287,6 +312,33 @@ func TestRESTTrigger_TriggerDataShape(t *testing.T) {
 	assert.Equal(t, "Bearer tok", td["auth"])
 }
 
+// TestRESTTrigger_WorkspacePrefix verifies that processes of a non-default
+// workspace are only reachable under /triggers/ws/{workspace}{path}.
+func TestRESTTrigger_WorkspacePrefix(t *testing.T) {
+	exec := &mockExecutor{}
+	tr := newRESTTrigger(exec)
+
+	const dslPath = "/test-rest-workspace"
+	proc := buildProcess("rest-ws", "rest", map[string]interface{}{"path": dslPath})
+	proc.Definition.Workspace = "team-a"
+	require.NoError(t, tr.Start(context.Background(), proc))
+	t.Cleanup(func() { _ = tr.Stop() })
+
+	srv := httptest.NewServer(GetRegistryHandler())
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL+"/triggers"+dslPath, "application/json", strings.NewReader(`{}`))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp2, err := http.Post(srv.URL+"/triggers/ws/team-a"+dslPath, "application/json", strings.NewReader(`{}`))
+	require.NoError(t, err)
+	defer resp2.Body.Close()
+	assert.Equal(t, http.StatusOK, resp2.StatusCode)
+	assert.Len(t, exec.executions, 1)
+}
+
 // TestRESTTrigger_ExecutionError verifies that when the executor returns an
 // error the REST trigger responds with HTTP 422 and a JSON error body.
 func TestRESTTrigger_ExecutionError(t *testing.T) {
@@ -555,3 +607,61 @@ func soapEnvelopeFixture(bodyContent string) string {
 		`<soap:Body>` + bodyContent + `</soap:Body>` +
 		`</soap:Envelope>`
 }
+
+// ---------------------------------------------------------------------------
+// Manager.Run tests
+// ---------------------------------------------------------------------------
+
+func TestManager_RunNotDeployed(t *testing.T) {
+	mgr := NewManager(&mockExecutor{})
+	_, err := mgr.Run(context.Background(), "ghost", nil)
+	assert.ErrorIs(t, err, ErrNotDeployed)
+}
+
+func TestManager_RunManualWithTriggerData(t *testing.T) {
+	exec := &mockExecutor{}
+	mgr := NewManager(exec)
+	require.NoError(t, mgr.Deploy(buildProcess("run-manual", "manual", nil)))
+	t.Cleanup(mgr.StopAll)
+
+	ctx, err := mgr.Run(context.Background(), "run-manual", map[string]interface{}{"order_id": "A-1"})
+	require.NoError(t, err)
+	assert.Equal(t, "test-exec-id", ctx.ExecutionID)
+	require.Len(t, exec.executions, 1)
+	assert.Equal(t, "A-1", exec.executions[0]["order_id"])
+}
+
+func TestManager_RunCronDefaultsDatetime(t *testing.T) {
+	exec := &mockExecutor{}
+	mgr := NewManager(exec)
+	// A yearly schedule never fires during the test.
+	require.NoError(t, mgr.Deploy(buildProcess("run-cron", "cron", map[string]interface{}{"expression": "0 0 0 1 1 *"})))
+	t.Cleanup(mgr.StopAll)
+
+	_, err := mgr.Run(context.Background(), "run-cron", nil)
+	require.NoError(t, err)
+	require.Len(t, exec.executions, 1)
+	assert.NotEmpty(t, exec.executions[0]["datetime"])
+}
+
+func TestManager_RunHonorsMaxConcurrency(t *testing.T) {
+	inner := newBlockingExecutor()
+	mgr := NewManager(inner)
+	proc := buildProcess("run-limited", "manual", nil)
+	proc.Definition.Settings.MaxConcurrency = 1
+	require.NoError(t, mgr.Deploy(proc))
+	t.Cleanup(mgr.StopAll)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := mgr.Run(context.Background(), "run-limited", nil)
+		done <- err
+	}()
+	<-inner.started
+
+	_, err := mgr.Run(context.Background(), "run-limited", nil)
+	assert.ErrorIs(t, err, ErrConcurrencyLimit)
+
+	close(inner.release)
+	require.NoError(t, <-done)
+}
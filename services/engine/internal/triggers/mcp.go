@@ -4,10 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"time"
 
+	"flowjs-works/engine/internal/logging"
 	"flowjs-works/engine/internal/models"
 )
 
@@ -50,11 +51,11 @@ func (t *mcpTrigger) Start(ctx context.Context, proc *models.Process) error {
 
 	go func() {
 		if err := t.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Printf("mcp_trigger: server error for %q: %v", t.processID, err)
+			slog.Error("mcp_trigger: server failed", logging.KeyProcessID, t.processID, logging.KeyError, err)
 		}
 	}()
 
-	log.Printf("mcp_trigger: listening on %s/mcp/%s for process %q", addr, proc.Definition.ID, proc.Definition.ID)
+	slog.Info("mcp_trigger: listening", "addr", addr, "path", "/mcp/"+proc.Definition.ID, logging.KeyProcessID, proc.Definition.ID)
 	return nil
 }
 
@@ -90,7 +91,7 @@ func (t *mcpTrigger) buildHandler(proc *models.Process) http.HandlerFunc {
 
 		execCtx, execErr := t.executor.Execute(proc, triggerData)
 		if execErr != nil {
-			log.Printf("mcp_trigger: execution error for %q: %v", t.processID, execErr)
+			slog.Error("mcp_trigger: execution failed", logging.KeyProcessID, t.processID, logging.KeyError, execErr)
 			writeMCPError(w, req.ID, -32000, execErr.Error())
 			return
 		}
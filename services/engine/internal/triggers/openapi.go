@@ -0,0 +1,184 @@
+package triggers
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"flowjs-works/engine/internal/logging"
+	"flowjs-works/engine/internal/models"
+	"flowjs-works/engine/internal/schema"
+	"flowjs-works/engine/internal/tenant"
+)
+
+// openAPIVersion is the OpenAPI version of the documents built by OpenAPI.
+const openAPIVersion = "3.0.3"
+
+// errorResponseSchema is the body of the error responses of REST triggers.
+var errorResponseSchema = map[string]interface{}{
+	"type":       "object",
+	"properties": map[string]interface{}{"error": map[string]interface{}{"type": "string"}},
+}
+
+// OpenAPI returns an OpenAPI document of the REST triggers deployed in m,
+// of workspace or, when it is empty, of every workspace. The request body of
+// a trigger whose config names a schema is described by that schema of the
+// registry of its workspace, read from schemas; without schemas, or when it
+// cannot be read, the body is left untyped.
+func (m *Manager) OpenAPI(ctx context.Context, schemas schema.Source, workspace string) map[string]interface{} {
+	m.mu.Lock()
+	var procs []*models.Process
+	for _, d := range m.running {
+		if d.handler.Type() != "rest" {
+			continue
+		}
+		if workspace != "" && tenant.Normalize(d.proc.Definition.Workspace) != tenant.Normalize(workspace) {
+			continue
+		}
+		procs = append(procs, d.proc)
+	}
+	m.mu.Unlock()
+	slices.SortFunc(procs, func(a, b *models.Process) int { return strings.Compare(a.Definition.ID, b.Definition.ID) })
+
+	paths := map[string]interface{}{}
+	for _, proc := range procs {
+		var body map[string]interface{}
+		if name, _ := proc.Trigger.Config["schema"].(string); name != "" && schemas != nil {
+			s, err := schemas.Schema(tenant.WithWorkspace(ctx, proc.Definition.Workspace), name)
+			if err != nil {
+				slog.Warn("triggers: openapi: load trigger schema", logging.KeyProcessID, proc.Definition.ID, "schema", name, logging.KeyError, err)
+			}
+			body = s
+		}
+		path, method, op, err := restOperation(proc, body)
+		if err != nil {
+			continue
+		}
+		item, _ := paths[path].(map[string]interface{})
+		if item == nil {
+			item = map[string]interface{}{}
+			paths[path] = item
+		}
+		item[strings.ToLower(method)] = op
+	}
+	return map[string]interface{}{
+		"openapi": openAPIVersion,
+		"info": map[string]interface{}{
+			"title":       "flowjs-works REST triggers",
+			"description": "Endpoints of the processes deployed with a REST trigger, generated from their DSL.",
+			"version":     "1.0.0",
+		},
+		"paths": paths,
+	}
+}
+
+// restOperation returns the OpenAPI fragment of the REST trigger of proc: the
+// path it is served at, under /triggers, its method and the operation object.
+// bodySchema is the JSON Schema of the request body, or nil when unknown.
+func restOperation(proc *models.Process, bodySchema map[string]interface{}) (path, method string, op map[string]interface{}, err error) {
+	dslPath, method, err := restTriggerConfig(proc.Trigger.Config)
+	if err != nil {
+		return "", "", nil, err
+	}
+	pattern, err := parseRoutePattern(dslPath)
+	if err != nil {
+		return "", "", nil, err
+	}
+	path = "/triggers" + tenant.RoutePrefix(proc.Definition.Workspace) + dslPath
+
+	op = map[string]interface{}{
+		"operationId": proc.Definition.ID,
+		"summary":     cmp.Or(proc.Definition.Name, proc.Definition.ID),
+		"tags":        []string{tenant.Normalize(proc.Definition.Workspace)},
+		"responses":   restResponses(proc.Trigger.Config),
+	}
+	if proc.Definition.Description != "" {
+		op["description"] = proc.Definition.Description
+	}
+	if proc.Definition.Version != "" {
+		op["x-process-version"] = proc.Definition.Version
+	}
+
+	var params []interface{}
+	for _, seg := range pattern.segments {
+		if seg.param != "" {
+			params = append(params, map[string]interface{}{
+				"name": seg.param, "in": "path", "required": true,
+				"schema": map[string]interface{}{"type": "string"},
+			})
+		}
+	}
+	if len(params) > 0 {
+		op["parameters"] = params
+	}
+
+	// GET, HEAD and DELETE requests only document a body their schema asks for.
+	if bodySchema != nil || (method != http.MethodGet && method != http.MethodHead && method != http.MethodDelete) {
+		s := bodySchema
+		if s == nil {
+			s = map[string]interface{}{}
+		}
+		op["requestBody"] = map[string]interface{}{
+			"required": bodySchema != nil,
+			"content":  map[string]interface{}{"application/json": map[string]interface{}{"schema": s}},
+		}
+	}
+	return path, method, op, nil
+}
+
+// restResponses describes the responses of a REST trigger: the one of its
+// response mapping, or the default {execution_id, nodes}, and its errors.
+func restResponses(config map[string]interface{}) map[string]interface{} {
+	errResp := func(description string) map[string]interface{} {
+		return map[string]interface{}{
+			"description": description,
+			"content":     map[string]interface{}{"application/json": map[string]interface{}{"schema": errorResponseSchema}},
+		}
+	}
+	responses := map[string]interface{}{
+		"400": errResp("Request body could not be parsed"),
+		"413": errResp("Request body larger than max_body_bytes"),
+		"422": errResp("Execution failed or the body does not match the trigger schema"),
+		"429": errResp("Concurrency limit or quota reached"),
+	}
+
+	spec, _ := config["response"].(map[string]interface{})
+	if spec == nil {
+		responses["200"] = map[string]interface{}{
+			"description": "Execution finished",
+			"content": map[string]interface{}{"application/json": map[string]interface{}{"schema": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"execution_id": map[string]interface{}{"type": "string"},
+					"nodes":        map[string]interface{}{"type": "object"},
+				},
+			}}},
+		}
+		return responses
+	}
+
+	// A literal status is documented as such; an expression may answer any.
+	code := "default"
+	if n, ok := spec["status"].(float64); ok {
+		code = strconv.Itoa(int(n))
+	} else if spec["status"] == nil {
+		code = "200"
+	}
+	resp := map[string]interface{}{"description": "Response mapped by the trigger"}
+	if _, ok := spec["body"]; ok {
+		resp["content"] = map[string]interface{}{"application/json": map[string]interface{}{"schema": map[string]interface{}{}}}
+	}
+	if hdrs, ok := spec["headers"].(map[string]interface{}); ok && len(hdrs) > 0 {
+		headers := map[string]interface{}{}
+		for name := range hdrs {
+			headers[name] = map[string]interface{}{"schema": map[string]interface{}{"type": "string"}}
+		}
+		resp["headers"] = headers
+	}
+	responses[code] = resp
+	return responses
+}
@@ -0,0 +1,70 @@
+package triggers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"flowjs-works/engine/internal/tenant"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSchemas serves schemas by workspace and name.
+type fakeSchemas map[string]map[string]interface{}
+
+func (f fakeSchemas) Schema(ctx context.Context, name string) (map[string]interface{}, error) {
+	if s, ok := f[tenant.Workspace(ctx)+"/"+name]; ok {
+		return s, nil
+	}
+	return nil, fmt.Errorf("schema %q not found", name)
+}
+
+// TestManager_OpenAPI verifies the operations generated for deployed REST
+// triggers: path, parameters, body schema and mapped responses.
+func TestManager_OpenAPI(t *testing.T) {
+	mgr := NewManager(&mockExecutor{})
+	orders := buildProcess("p_openapi_orders", "rest", map[string]interface{}{
+		"path": "/openapi-orders/{orderId}", "method": "PUT", "schema": "orders/update",
+		"response": map[string]interface{}{"status": float64(202), "headers": map[string]interface{}{"X-Request-Id": "$.execution_id"}},
+	})
+	orders.Definition.Name = "Update order"
+	orders.Definition.Workspace = "acme"
+	status := buildProcess("p_openapi_status", "rest", map[string]interface{}{"path": "/openapi-status", "method": "GET"})
+	require.NoError(t, mgr.Deploy(orders))
+	require.NoError(t, mgr.Deploy(status))
+	require.NoError(t, mgr.Deploy(buildProcess("p_openapi_cron", "cron", map[string]interface{}{"expression": "@every 1h"})))
+	t.Cleanup(mgr.StopAll)
+
+	bodySchema := map[string]interface{}{"type": "object", "required": []interface{}{"qty"}}
+	doc := mgr.OpenAPI(context.Background(), fakeSchemas{"acme/orders/update": bodySchema}, "")
+	assert.Equal(t, "3.0.3", doc["openapi"])
+	paths := doc["paths"].(map[string]interface{})
+	require.Len(t, paths, 2)
+
+	op := paths["/triggers/ws/acme/openapi-orders/{orderId}"].(map[string]interface{})["put"].(map[string]interface{})
+	assert.Equal(t, "p_openapi_orders", op["operationId"])
+	assert.Equal(t, "Update order", op["summary"])
+	assert.Equal(t, []string{"acme"}, op["tags"])
+	params := op["parameters"].([]interface{})
+	require.Len(t, params, 1)
+	assert.Equal(t, "orderId", params[0].(map[string]interface{})["name"])
+	body := op["requestBody"].(map[string]interface{})
+	assert.Equal(t, true, body["required"])
+	assert.Equal(t, bodySchema, body["content"].(map[string]interface{})["application/json"].(map[string]interface{})["schema"])
+	responses := op["responses"].(map[string]interface{})
+	assert.Contains(t, responses["202"].(map[string]interface{})["headers"], "X-Request-Id")
+	assert.NotContains(t, responses, "200")
+	assert.Contains(t, responses, "429")
+
+	get := paths["/triggers/openapi-status"].(map[string]interface{})["get"].(map[string]interface{})
+	assert.NotContains(t, get, "requestBody")
+	assert.NotContains(t, get, "parameters")
+	assert.Contains(t, get["responses"], "200")
+
+	acme := mgr.OpenAPI(context.Background(), nil, "acme")["paths"].(map[string]interface{})
+	require.Len(t, acme, 1)
+	untyped := acme["/triggers/ws/acme/openapi-orders/{orderId}"].(map[string]interface{})["put"].(map[string]interface{})["requestBody"].(map[string]interface{})
+	assert.Equal(t, false, untyped["required"])
+}
@@ -0,0 +1,150 @@
+package triggers
+
+import (
+	"cmp"
+	"log/slog"
+	"slices"
+	"time"
+
+	"flowjs-works/engine/internal/logging"
+	"flowjs-works/engine/internal/models"
+	"flowjs-works/engine/internal/tenant"
+)
+
+// Reasons a route is an orphan.
+const (
+	// OrphanNotRunning is a route whose process has no running trigger of
+	// the route's type in this engine, e.g. left behind by a failed stop.
+	OrphanNotRunning = "process_not_running"
+	// OrphanNotDeployed is a route whose process is not deployed in the
+	// config DB any more, e.g. stopped or archived through another engine.
+	OrphanNotDeployed = "process_not_deployed"
+)
+
+// Route is a REST or SOAP route registered by a trigger.
+type Route struct {
+	Type         string    `json:"type"`
+	Method       string    `json:"method"`
+	Path         string    `json:"path"`
+	ProcessID    string    `json:"process_id"`
+	Workspace    string    `json:"workspace"`
+	RegisteredAt time.Time `json:"registered_at"`
+}
+
+// key identifies the route across registries and sweeps.
+func (r Route) key() string {
+	return r.Type + " " + registryKey(r.Path, r.Method)
+}
+
+// Orphan is a route that no deployed process serves any more: it answers
+// with a flow nobody deployed, or shadows the 404 operators expect.
+type Orphan struct {
+	Route
+	Reason string `json:"reason"`
+	// OrphanedSince is when a sweep or listing first found the route orphaned.
+	OrphanedSince time.Time `json:"orphaned_since"`
+}
+
+// routeOwner is the process a route was registered for.
+type routeOwner struct {
+	processID string
+	workspace string
+	path      string
+	since     time.Time
+}
+
+func newRouteOwner(proc *models.Process) routeOwner {
+	return routeOwner{processID: proc.Definition.ID, workspace: tenant.Normalize(proc.Definition.Workspace), since: time.Now().UTC()}
+}
+
+func (o routeOwner) route(triggerType, method string) Route {
+	return Route{Type: triggerType, Method: method, Path: o.path, ProcessID: o.processID, Workspace: o.workspace, RegisteredAt: o.since}
+}
+
+// Routes lists the registered REST and SOAP routes, by type and path.
+func Routes() []Route {
+	routes := append(globalRESTRegistry.routes(), globalSOAPRegistry.routes()...)
+	slices.SortFunc(routes, func(a, b Route) int {
+		return cmp.Or(cmp.Compare(a.Type, b.Type), cmp.Compare(a.Path, b.Path), cmp.Compare(a.Method, b.Method))
+	})
+	return routes
+}
+
+// removeRoute deregisters r, unless another process took its path over since.
+func removeRoute(r Route) {
+	if r.Type == "soap" {
+		globalSOAPRegistry.deregister(r.Path, r.ProcessID)
+		return
+	}
+	globalRESTRegistry.deregister(r.Path, r.Method, r.ProcessID)
+}
+
+// Orphans returns the registered routes no deployed process serves.
+// deployed holds the ids of the processes deployed in the config DB; nil
+// skips that check, as when the engine runs without one.
+func (m *Manager) Orphans(deployed map[string]bool) []Orphan {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.orphansLocked(deployed, time.Now().UTC())
+}
+
+// orphansLocked finds the orphans and tracks since when each is orphaned.
+// The caller holds m.mu.
+func (m *Manager) orphansLocked(deployed map[string]bool, now time.Time) []Orphan {
+	var orphans []Orphan
+	seen := make(map[string]bool)
+	for _, r := range Routes() {
+		reason := ""
+		d, ok := m.running[r.ProcessID]
+		switch {
+		case !ok || d.handler.Type() != r.Type || tenant.Normalize(d.proc.Definition.Workspace) != r.Workspace:
+			reason = OrphanNotRunning
+		case deployed != nil && !deployed[r.ProcessID]:
+			reason = OrphanNotDeployed
+		default:
+			continue
+		}
+		key := r.key()
+		seen[key] = true
+		since, ok := m.orphanSince[key]
+		if !ok {
+			since = now
+			m.orphanSince[key] = since
+		}
+		orphans = append(orphans, Orphan{Route: r, Reason: reason, OrphanedSince: since})
+	}
+	for key := range m.orphanSince {
+		if !seen[key] {
+			delete(m.orphanSince, key)
+		}
+	}
+	return orphans
+}
+
+// RemoveOrphans removes the routes orphaned for at least grace and returns
+// them. The grace period spares routes caught between a deploy starting the
+// trigger and the config DB recording it. A route whose process is still
+// running here has its trigger stopped, as the config DB says it should be.
+func (m *Manager) RemoveOrphans(deployed map[string]bool, grace time.Duration) []Orphan {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now().UTC()
+	var removed []Orphan
+	for _, o := range m.orphansLocked(deployed, now) {
+		if now.Sub(o.OrphanedSince) < grace {
+			continue
+		}
+		if d, ok := m.running[o.ProcessID]; ok && o.Reason == OrphanNotDeployed {
+			if err := d.handler.Stop(); err != nil {
+				slog.Warn("triggers: stop orphaned trigger", logging.KeyProcessID, o.ProcessID, logging.KeyError, err)
+				continue
+			}
+			delete(m.running, o.ProcessID)
+		}
+		removeRoute(o.Route)
+		delete(m.orphanSince, o.key())
+		slog.Warn("triggers: removed orphaned route", "type", o.Type, "method", o.Method, "path", o.Path, logging.KeyProcessID, o.ProcessID, "reason", o.Reason)
+		removed = append(removed, o)
+	}
+	return removed
+}
@@ -0,0 +1,91 @@
+package triggers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// orphansOf returns the orphans of processID, ignoring the routes other
+// tests left in the shared registries.
+func orphansOf(orphans []Orphan, processID string) []Orphan {
+	var out []Orphan
+	for _, o := range orphans {
+		if o.ProcessID == processID {
+			out = append(out, o)
+		}
+	}
+	return out
+}
+
+func postTrigger(t *testing.T, path string) int {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	GetRegistryHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/triggers"+path, strings.NewReader(`{}`)))
+	return rec.Code
+}
+
+// TestRegistry_StopKeepsRouteTakenOver verifies that stopping a process does
+// not deregister a path another process registered since.
+func TestRegistry_StopKeepsRouteTakenOver(t *testing.T) {
+	mgr := NewManager(&mockExecutor{})
+	require.NoError(t, mgr.Deploy(buildProcess("p_first", "rest", map[string]interface{}{"path": "/orphan-shared"})))
+	require.NoError(t, mgr.Deploy(buildProcess("p_second", "rest", map[string]interface{}{"path": "/orphan-shared"})))
+	t.Cleanup(mgr.StopAll)
+
+	require.NoError(t, mgr.Stop("p_first"))
+	assert.Equal(t, http.StatusOK, postTrigger(t, "/orphan-shared"))
+	assert.Empty(t, orphansOf(mgr.Orphans(nil), "p_second"))
+}
+
+// TestManager_OrphanNotRunning verifies that a route without a running
+// trigger is listed and removed.
+func TestManager_OrphanNotRunning(t *testing.T) {
+	mgr := NewManager(&mockExecutor{})
+	tr := newRESTTrigger(&mockExecutor{})
+	require.NoError(t, tr.Start(t.Context(), buildProcess("p_ghost", "rest", map[string]interface{}{"path": "/orphan-ghost"})))
+	t.Cleanup(func() { _ = tr.Stop() })
+
+	orphans := orphansOf(mgr.Orphans(nil), "p_ghost")
+	require.Len(t, orphans, 1)
+	assert.Equal(t, OrphanNotRunning, orphans[0].Reason)
+	assert.Equal(t, "/orphan-ghost", orphans[0].Path)
+	assert.Equal(t, http.MethodPost, orphans[0].Method)
+	assert.Equal(t, "default", orphans[0].Workspace)
+
+	removed := orphansOf(mgr.RemoveOrphans(nil, 0), "p_ghost")
+	require.Len(t, removed, 1)
+	assert.Equal(t, http.StatusNotFound, postTrigger(t, "/orphan-ghost"))
+	assert.Empty(t, orphansOf(mgr.Orphans(nil), "p_ghost"))
+}
+
+// TestManager_OrphanNotDeployed verifies that a running trigger of a process
+// no longer deployed in the config DB is stopped once its grace has passed.
+func TestManager_OrphanNotDeployed(t *testing.T) {
+	mgr := NewManager(&mockExecutor{})
+	require.NoError(t, mgr.Deploy(buildProcess("p_undeployed", "soap", map[string]interface{}{"path": "/orphan-soap"})))
+	t.Cleanup(mgr.StopAll)
+
+	assert.Empty(t, orphansOf(mgr.Orphans(map[string]bool{"p_undeployed": true}), "p_undeployed"))
+
+	orphans := orphansOf(mgr.Orphans(map[string]bool{}), "p_undeployed")
+	require.Len(t, orphans, 1)
+	assert.Equal(t, OrphanNotDeployed, orphans[0].Reason)
+	assert.Equal(t, "soap", orphans[0].Type)
+	since := orphans[0].OrphanedSince
+
+	assert.Empty(t, orphansOf(mgr.RemoveOrphans(map[string]bool{}, time.Hour), "p_undeployed"))
+	assert.True(t, mgr.IsRunning("p_undeployed"))
+	assert.Equal(t, since, orphansOf(mgr.Orphans(map[string]bool{}), "p_undeployed")[0].OrphanedSince)
+
+	require.Len(t, orphansOf(mgr.RemoveOrphans(map[string]bool{}, 0), "p_undeployed"), 1)
+	assert.False(t, mgr.IsRunning("p_undeployed"))
+	for _, r := range Routes() {
+		assert.NotEqual(t, "p_undeployed", r.ProcessID)
+	}
+}
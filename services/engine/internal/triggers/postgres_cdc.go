@@ -0,0 +1,426 @@
+package triggers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"flowjs-works/engine/internal/logging"
+	"flowjs-works/engine/internal/models"
+
+	"github.com/lib/pq"
+)
+
+const (
+	// cdcDefaultPollInterval is how often logical mode reads the slot when
+	// poll_interval_ms is not configured.
+	cdcDefaultPollInterval = time.Second
+	// cdcDefaultBatchSize caps the changes read from the slot per poll.
+	cdcDefaultBatchSize = 100
+	// cdcListenerPing is how often notify mode pings an idle connection so a
+	// dropped connection is detected and re-established.
+	cdcListenerPing = 90 * time.Second
+	// cdcQueryTimeout bounds a single slot query.
+	cdcQueryTimeout = 30 * time.Second
+)
+
+// postgresCDCTrigger fires the flow once per row change in a Postgres database.
+//
+// Two modes are supported:
+//
+//   - notify (default): LISTEN on channel. Tables publish changes with a
+//     trigger calling pg_notify(channel, json) where json is
+//     {"table", "schema", "op", "old", "new"}. Delivery is at-most-once:
+//     notifications sent while the engine is down are lost.
+//   - logical: read a logical replication slot using the wal2json output
+//     plugin. The slot is advanced only after the flow succeeds, so changes
+//     survive restarts and a failing change is retried on the next poll
+//     (at-least-once; pair with a dedupe node for idempotency).
+//
+// Each change is delivered as trigger_data {schema, table, op, old, new}
+// where op is INSERT, UPDATE or DELETE; logical mode adds the change lsn.
+type postgresCDCTrigger struct {
+	executor  Executor
+	processID string
+	done      chan struct{}
+	wg        sync.WaitGroup
+
+	listener *pq.Listener // notify mode
+	db       *sql.DB      // logical mode
+}
+
+// cdcConfig is the parsed postgres_cdc trigger config.
+type cdcConfig struct {
+	dsn          string
+	mode         string
+	channel      string
+	slot         string
+	createSlot   bool
+	tables       map[string]bool
+	pollInterval time.Duration
+	batchSize    int
+}
+
+func newPostgresCDCTrigger(executor Executor) *postgresCDCTrigger {
+	return &postgresCDCTrigger{executor: executor}
+}
+
+// Start connects to the database and begins delivering changes in a
+// background goroutine.
+func (t *postgresCDCTrigger) Start(_ context.Context, proc *models.Process) error {
+	cfg, err := postgresCDCTriggerConfig(proc.Trigger.Config)
+	if err != nil {
+		return fmt.Errorf("postgres_cdc_trigger: %w", err)
+	}
+	t.processID = proc.Definition.ID
+	t.done = make(chan struct{})
+	procCopy := *proc
+
+	if cfg.mode == "logical" {
+		return t.startLogical(cfg, &procCopy)
+	}
+	return t.startNotify(cfg, &procCopy)
+}
+
+func (t *postgresCDCTrigger) startNotify(cfg cdcConfig, proc *models.Process) error {
+	processID := t.processID
+	listener := pq.NewListener(cfg.dsn, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			slog.Warn("postgres_cdc_trigger: listener event", logging.KeyProcessID, processID, "event", int(ev), logging.KeyError, err)
+		}
+	})
+	if err := listener.Listen(cfg.channel); err != nil {
+		listener.Close()
+		return fmt.Errorf("postgres_cdc_trigger: listen %q: %w", cfg.channel, err)
+	}
+	t.listener = listener
+
+	t.wg.Add(1)
+	go t.listen(listener, cfg, proc)
+	slog.Info("postgres_cdc_trigger: listening", "channel", cfg.channel, logging.KeyProcessID, proc.Definition.ID)
+	return nil
+}
+
+func (t *postgresCDCTrigger) listen(listener *pq.Listener, cfg cdcConfig, proc *models.Process) {
+	defer t.wg.Done()
+	for {
+		select {
+		case <-t.done:
+			return
+		case n := <-listener.Notify:
+			// A nil notification signals a reconnect; nothing to deliver.
+			if n == nil {
+				continue
+			}
+			triggerData := notifyTriggerData(n.Channel, n.Extra)
+			if !cfg.matches(triggerData) {
+				continue
+			}
+			if _, err := t.executor.Execute(proc, triggerData); err != nil {
+				slog.Error("postgres_cdc_trigger: execution failed", logging.KeyProcessID, proc.Definition.ID, logging.KeyError, err)
+			}
+		case <-time.After(cdcListenerPing):
+			go func() { _ = listener.Ping() }()
+		}
+	}
+}
+
+func (t *postgresCDCTrigger) startLogical(cfg cdcConfig, proc *models.Process) error {
+	db, err := sql.Open("postgres", cfg.dsn)
+	if err != nil {
+		return fmt.Errorf("postgres_cdc_trigger: open: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), cdcQueryTimeout)
+	defer cancel()
+	if err := ensureSlot(ctx, db, cfg.slot, cfg.createSlot); err != nil {
+		db.Close()
+		return fmt.Errorf("postgres_cdc_trigger: %w", err)
+	}
+	t.db = db
+
+	src := &slotSource{db: db, slot: cfg.slot}
+	t.wg.Add(1)
+	go t.poll(src, cfg, proc)
+	slog.Info("postgres_cdc_trigger: reading replication slot", "slot", cfg.slot, logging.KeyProcessID, proc.Definition.ID)
+	return nil
+}
+
+func (t *postgresCDCTrigger) poll(src changeSource, cfg cdcConfig, proc *models.Process) {
+	defer t.wg.Done()
+	ticker := time.NewTicker(cfg.pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-t.done:
+			return
+		case <-ticker.C:
+			if err := deliverChanges(src, cfg, proc, t.executor, t.done); err != nil {
+				slog.Error("postgres_cdc_trigger: deliver changes", logging.KeyProcessID, proc.Definition.ID, logging.KeyError, err)
+			}
+		}
+	}
+}
+
+// Stop ends delivery and closes the database connection. A change being
+// executed is allowed to finish.
+func (t *postgresCDCTrigger) Stop() error {
+	if t.done != nil {
+		close(t.done)
+		t.done = nil
+	}
+	t.wg.Wait()
+	if t.listener != nil {
+		t.listener.Close()
+		t.listener = nil
+	}
+	if t.db != nil {
+		t.db.Close()
+		t.db = nil
+	}
+	return nil
+}
+
+func (t *postgresCDCTrigger) Type() string { return "postgres_cdc" }
+
+// ---------------------------------------------------------------------------
+// Logical replication slot (wal2json)
+// ---------------------------------------------------------------------------
+
+// slotChange is one change read from a replication slot.
+type slotChange struct {
+	lsn  string
+	data string
+}
+
+// changeSource reads and acknowledges changes of a replication slot.
+type changeSource interface {
+	peek(ctx context.Context, limit int) ([]slotChange, error)
+	advance(ctx context.Context, lsn string) error
+}
+
+// slotSource is the changeSource backed by a wal2json logical slot.
+type slotSource struct {
+	db   *sql.DB
+	slot string
+}
+
+func (s *slotSource) peek(ctx context.Context, limit int) ([]slotChange, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT lsn::text, data FROM pg_logical_slot_peek_changes($1, NULL, $2,
+		   'format-version', '2', 'include-transaction', 'false')`, s.slot, limit)
+	if err != nil {
+		return nil, fmt.Errorf("peek slot %q: %w", s.slot, err)
+	}
+	defer rows.Close()
+	var changes []slotChange
+	for rows.Next() {
+		var c slotChange
+		if err := rows.Scan(&c.lsn, &c.data); err != nil {
+			return nil, fmt.Errorf("scan slot change: %w", err)
+		}
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
+
+func (s *slotSource) advance(ctx context.Context, lsn string) error {
+	if _, err := s.db.ExecContext(ctx, `SELECT pg_replication_slot_advance($1, $2::pg_lsn)`, s.slot, lsn); err != nil {
+		return fmt.Errorf("advance slot %q to %s: %w", s.slot, lsn, err)
+	}
+	return nil
+}
+
+// ensureSlot checks that slot exists, creating it with wal2json when create is set.
+func ensureSlot(ctx context.Context, db *sql.DB, slot string, create bool) error {
+	var exists bool
+	err := db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM pg_replication_slots WHERE slot_name = $1)`, slot).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("check slot %q: %w", slot, err)
+	}
+	if exists {
+		return nil
+	}
+	if !create {
+		return fmt.Errorf("replication slot %q does not exist (set create_slot to create it)", slot)
+	}
+	if _, err := db.ExecContext(ctx, `SELECT pg_create_logical_replication_slot($1, 'wal2json')`, slot); err != nil {
+		return fmt.Errorf("create slot %q: %w", slot, err)
+	}
+	return nil
+}
+
+// deliverChanges executes the flow for each pending change in order,
+// advancing the slot after each success. It stops at the first failure so
+// the failed change is retried on the next poll.
+func deliverChanges(src changeSource, cfg cdcConfig, proc *models.Process, executor Executor, done <-chan struct{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), cdcQueryTimeout)
+	changes, err := src.peek(ctx, cfg.batchSize)
+	cancel()
+	if err != nil {
+		return err
+	}
+	for _, c := range changes {
+		select {
+		case <-done:
+			return nil
+		default:
+		}
+		triggerData, ok, err := wal2jsonTriggerData(c)
+		if err != nil {
+			return err
+		}
+		if ok && cfg.matches(triggerData) {
+			if _, err := executor.Execute(proc, triggerData); err != nil {
+				return fmt.Errorf("change at %s: %w", c.lsn, err)
+			}
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), cdcQueryTimeout)
+		err = src.advance(ctx, c.lsn)
+		cancel()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// wal2jsonChange is a wal2json format-version 2 record.
+type wal2jsonChange struct {
+	Action   string           `json:"action"`
+	Schema   string           `json:"schema"`
+	Table    string           `json:"table"`
+	Columns  []wal2jsonColumn `json:"columns"`
+	Identity []wal2jsonColumn `json:"identity"`
+}
+
+type wal2jsonColumn struct {
+	Name  string      `json:"name"`
+	Value interface{} `json:"value"`
+}
+
+// wal2jsonOps maps wal2json actions to trigger ops; other actions (truncate,
+// logical messages) are skipped.
+var wal2jsonOps = map[string]string{"I": "INSERT", "U": "UPDATE", "D": "DELETE"}
+
+// wal2jsonTriggerData converts a slot change into trigger_data. ok is false
+// for records that do not describe a row change.
+func wal2jsonTriggerData(c slotChange) (map[string]interface{}, bool, error) {
+	var rec wal2jsonChange
+	if err := json.Unmarshal([]byte(c.data), &rec); err != nil {
+		return nil, false, fmt.Errorf("decode wal2json change at %s: %w", c.lsn, err)
+	}
+	op, ok := wal2jsonOps[rec.Action]
+	if !ok {
+		return nil, false, nil
+	}
+	// identity holds the old key (or full row with REPLICA IDENTITY FULL).
+	var oldRow, newRow interface{}
+	if rec.Action != "I" && len(rec.Identity) > 0 {
+		oldRow = columnsToRow(rec.Identity)
+	}
+	if rec.Action != "D" {
+		newRow = columnsToRow(rec.Columns)
+	}
+	return map[string]interface{}{
+		"schema": rec.Schema,
+		"table":  rec.Table,
+		"op":     op,
+		"old":    oldRow,
+		"new":    newRow,
+		"lsn":    c.lsn,
+	}, true, nil
+}
+
+func columnsToRow(cols []wal2jsonColumn) map[string]interface{} {
+	row := make(map[string]interface{}, len(cols))
+	for _, col := range cols {
+		row[col.Name] = col.Value
+	}
+	return row
+}
+
+// ---------------------------------------------------------------------------
+// LISTEN/NOTIFY
+// ---------------------------------------------------------------------------
+
+// notifyTriggerData converts a notification payload into trigger_data. A
+// payload that is not a JSON object is passed through as {"payload": raw}.
+func notifyTriggerData(channel, payload string) map[string]interface{} {
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(payload), &data); err != nil || data == nil {
+		data = map[string]interface{}{"payload": payload}
+	}
+	if op, ok := data["op"].(string); ok {
+		data["op"] = strings.ToUpper(op)
+	}
+	if _, ok := data["schema"]; !ok {
+		data["schema"] = "public"
+	}
+	data["channel"] = channel
+	return data
+}
+
+// ---------------------------------------------------------------------------
+// Config
+// ---------------------------------------------------------------------------
+
+// matches reports whether a change passes the tables filter. Entries are
+// "table" or "schema.table".
+func (c cdcConfig) matches(triggerData map[string]interface{}) bool {
+	if len(c.tables) == 0 {
+		return true
+	}
+	table, _ := triggerData["table"].(string)
+	schema, _ := triggerData["schema"].(string)
+	return c.tables[table] || c.tables[schema+"."+table]
+}
+
+// postgresCDCTriggerConfig validates the trigger config.
+func postgresCDCTriggerConfig(config map[string]interface{}) (cdcConfig, error) {
+	cfg := cdcConfig{pollInterval: cdcDefaultPollInterval, batchSize: cdcDefaultBatchSize}
+	if config == nil {
+		return cfg, errors.New("trigger config is nil; expected {\"dsn\":\"...\",\"channel\":\"...\"}")
+	}
+	cfg.dsn, _ = config["dsn"].(string)
+	if cfg.dsn == "" {
+		return cfg, errors.New("trigger config missing required field \"dsn\"")
+	}
+	cfg.mode, _ = config["mode"].(string)
+	switch cfg.mode {
+	case "", "notify":
+		cfg.mode = "notify"
+		cfg.channel, _ = config["channel"].(string)
+		if cfg.channel == "" {
+			return cfg, errors.New("trigger config missing required field \"channel\" for notify mode")
+		}
+	case "logical":
+		cfg.slot, _ = config["slot"].(string)
+		if cfg.slot == "" {
+			return cfg, errors.New("trigger config missing required field \"slot\" for logical mode")
+		}
+		cfg.createSlot, _ = config["create_slot"].(bool)
+	default:
+		return cfg, fmt.Errorf("unsupported mode %q (want notify or logical)", cfg.mode)
+	}
+	if v, ok := config["poll_interval_ms"].(float64); ok && v > 0 {
+		cfg.pollInterval = time.Duration(v) * time.Millisecond
+	}
+	if v, ok := config["batch_size"].(float64); ok && v > 0 {
+		cfg.batchSize = int(v)
+	}
+	if list, ok := config["tables"].([]interface{}); ok && len(list) > 0 {
+		cfg.tables = make(map[string]bool, len(list))
+		for _, item := range list {
+			if name, ok := item.(string); ok && name != "" {
+				cfg.tables[name] = true
+			}
+		}
+	}
+	return cfg, nil
+}
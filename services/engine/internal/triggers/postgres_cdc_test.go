@@ -0,0 +1,138 @@
+package triggers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"flowjs-works/engine/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSlot is an in-memory changeSource.
+type fakeSlot struct {
+	changes  []slotChange
+	advanced []string
+}
+
+func (f *fakeSlot) peek(_ context.Context, limit int) ([]slotChange, error) {
+	if len(f.changes) > limit {
+		return f.changes[:limit], nil
+	}
+	return f.changes, nil
+}
+
+func (f *fakeSlot) advance(_ context.Context, lsn string) error {
+	f.advanced = append(f.advanced, lsn)
+	f.changes = f.changes[1:]
+	return nil
+}
+
+// failingExecutor fails executions whose trigger data has op == failOp.
+type failingExecutor struct {
+	mockExecutor
+	failOp string
+}
+
+func (f *failingExecutor) Execute(p *models.Process, triggerData map[string]interface{}) (*models.ExecutionContext, error) {
+	ctx, _ := f.mockExecutor.Execute(p, triggerData)
+	if triggerData["op"] == f.failOp {
+		return ctx, errors.New("boom")
+	}
+	return ctx, nil
+}
+
+func TestPostgresCDCTriggerConfig(t *testing.T) {
+	_, err := postgresCDCTriggerConfig(nil)
+	assert.Error(t, err)
+	_, err = postgresCDCTriggerConfig(map[string]interface{}{"channel": "c"})
+	assert.ErrorContains(t, err, "dsn")
+	_, err = postgresCDCTriggerConfig(map[string]interface{}{"dsn": "postgres://x"})
+	assert.ErrorContains(t, err, "channel")
+	_, err = postgresCDCTriggerConfig(map[string]interface{}{"dsn": "postgres://x", "mode": "logical"})
+	assert.ErrorContains(t, err, "slot")
+	_, err = postgresCDCTriggerConfig(map[string]interface{}{"dsn": "postgres://x", "mode": "stream"})
+	assert.ErrorContains(t, err, "mode")
+
+	cfg, err := postgresCDCTriggerConfig(map[string]interface{}{
+		"dsn": "postgres://x", "mode": "logical", "slot": "flowjs", "create_slot": true,
+		"poll_interval_ms": float64(250), "batch_size": float64(10), "tables": []interface{}{"public.orders"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "flowjs", cfg.slot)
+	assert.True(t, cfg.createSlot)
+	assert.Equal(t, 10, cfg.batchSize)
+	assert.Equal(t, int64(250), cfg.pollInterval.Milliseconds())
+	assert.True(t, cfg.matches(map[string]interface{}{"schema": "public", "table": "orders"}))
+	assert.False(t, cfg.matches(map[string]interface{}{"schema": "public", "table": "users"}))
+}
+
+func TestWal2jsonTriggerData(t *testing.T) {
+	data, ok, err := wal2jsonTriggerData(slotChange{lsn: "0/16B3748", data: `{"action":"U","schema":"public","table":"orders",
+		"columns":[{"name":"id","type":"integer","value":1},{"name":"status","type":"text","value":"paid"}],
+		"identity":[{"name":"id","type":"integer","value":1}]}`})
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, "UPDATE", data["op"])
+	assert.Equal(t, "orders", data["table"])
+	assert.Equal(t, map[string]interface{}{"id": float64(1), "status": "paid"}, data["new"])
+	assert.Equal(t, map[string]interface{}{"id": float64(1)}, data["old"])
+	assert.Equal(t, "0/16B3748", data["lsn"])
+
+	data, ok, err = wal2jsonTriggerData(slotChange{lsn: "0/1", data: `{"action":"D","schema":"public","table":"orders","identity":[{"name":"id","value":7}]}`})
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, "DELETE", data["op"])
+	assert.Nil(t, data["new"])
+	assert.Equal(t, map[string]interface{}{"id": float64(7)}, data["old"])
+
+	_, ok, err = wal2jsonTriggerData(slotChange{lsn: "0/2", data: `{"action":"T","schema":"public","table":"orders"}`})
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	_, _, err = wal2jsonTriggerData(slotChange{lsn: "0/3", data: `not json`})
+	assert.Error(t, err)
+}
+
+func TestNotifyTriggerData(t *testing.T) {
+	data := notifyTriggerData("orders_changes", `{"table":"orders","op":"delete","old":{"id":3}}`)
+	assert.Equal(t, "DELETE", data["op"])
+	assert.Equal(t, "public", data["schema"])
+	assert.Equal(t, "orders_changes", data["channel"])
+	assert.Equal(t, map[string]interface{}{"id": float64(3)}, data["old"])
+
+	data = notifyTriggerData("c", "plain text")
+	assert.Equal(t, "plain text", data["payload"])
+}
+
+func TestDeliverChanges_AdvancesAfterEachSuccess(t *testing.T) {
+	slot := &fakeSlot{changes: []slotChange{
+		{lsn: "0/1", data: `{"action":"I","schema":"public","table":"orders","columns":[{"name":"id","value":1}]}`},
+		{lsn: "0/2", data: `{"action":"I","schema":"public","table":"users","columns":[{"name":"id","value":1}]}`},
+		{lsn: "0/3", data: `{"action":"D","schema":"public","table":"orders","identity":[{"name":"id","value":1}]}`},
+		{lsn: "0/4", data: `{"action":"I","schema":"public","table":"orders","columns":[{"name":"id","value":2}]}`},
+	}}
+	exec := &failingExecutor{failOp: "DELETE"}
+	cfg := cdcConfig{batchSize: 10, tables: map[string]bool{"orders": true}}
+	proc := buildProcess("p1", "postgres_cdc", nil)
+
+	err := deliverChanges(slot, cfg, proc, exec, make(chan struct{}))
+	require.Error(t, err)
+	// users is filtered out but still acknowledged; the failed delete is not.
+	assert.Equal(t, []string{"0/1", "0/2"}, slot.advanced)
+	assert.Len(t, exec.executions, 2)
+
+	// The failed change is retried on the next poll.
+	exec.failOp = ""
+	require.NoError(t, deliverChanges(slot, cfg, proc, exec, make(chan struct{})))
+	assert.Equal(t, []string{"0/1", "0/2", "0/3", "0/4"}, slot.advanced)
+	assert.Equal(t, "DELETE", exec.executions[2]["op"])
+}
+
+func TestManager_PostgresCDCRequiresConfig(t *testing.T) {
+	mgr := NewManager(&mockExecutor{})
+	err := mgr.Deploy(buildProcess("p1", "postgres_cdc", map[string]interface{}{"dsn": "postgres://x"}))
+	assert.ErrorContains(t, err, "channel")
+}
@@ -0,0 +1,155 @@
+package triggers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"flowjs-works/engine/internal/logging"
+	"flowjs-works/engine/internal/models"
+	"flowjs-works/engine/internal/tenant"
+)
+
+// ErrQuotaExceeded is returned when a process has used up
+// definition.settings.max_executions_per_hour or max_node_executions_per_day.
+var ErrQuotaExceeded = errors.New("process quota exceeded")
+
+// Names of the quotas, as reported in errors and audit events.
+const (
+	QuotaExecutionsPerHour    = "max_executions_per_hour"
+	QuotaNodeExecutionsPerDay = "max_node_executions_per_day"
+)
+
+// QuotaAuditor records runs refused by a quota. engine.ProcessExecutor
+// implements it; the Manager uses it when its executor does.
+type QuotaAuditor interface {
+	SendQuotaAuditLog(workspace, processID, triggerType, quota string, limit int)
+}
+
+// quotaTracker counts the executions and node runs of every process. Usage
+// is kept in memory per replica and survives redeploys, so changing a
+// definition does not reset its quota.
+type quotaTracker struct {
+	now   func() time.Time
+	mu    sync.Mutex
+	usage map[string]*quotaUsage
+}
+
+// quotaUsage is the usage of one process.
+type quotaUsage struct {
+	// starts holds the start times of executions in the last hour, oldest
+	// first.
+	starts []time.Time
+	// day is the UTC day nodeRuns counts.
+	day      string
+	nodeRuns int
+}
+
+func newQuotaTracker() *quotaTracker {
+	return &quotaTracker{now: time.Now, usage: make(map[string]*quotaUsage)}
+}
+
+// admit records the start of an execution of proc, or returns an error
+// wrapping ErrQuotaExceeded together with the name and limit of the quota
+// that refused it.
+func (q *quotaTracker) admit(proc *models.Process) (string, int, error) {
+	settings := proc.Definition.Settings
+	if settings.MaxExecutionsPerHour <= 0 && settings.MaxNodeExecutionsPerDay <= 0 {
+		return "", 0, nil
+	}
+	now := q.now()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	u := q.usageLocked(proc, now)
+
+	if limit := settings.MaxNodeExecutionsPerDay; limit > 0 && u.nodeRuns >= limit {
+		return QuotaNodeExecutionsPerDay, limit, fmt.Errorf("%w: %d node executions today (%s %d)", ErrQuotaExceeded, u.nodeRuns, QuotaNodeExecutionsPerDay, limit)
+	}
+	cutoff := now.Add(-time.Hour)
+	for len(u.starts) > 0 && !u.starts[0].After(cutoff) {
+		u.starts = u.starts[1:]
+	}
+	if limit := settings.MaxExecutionsPerHour; limit > 0 {
+		if len(u.starts) >= limit {
+			return QuotaExecutionsPerHour, limit, fmt.Errorf("%w: %d executions in the last hour (%s %d)", ErrQuotaExceeded, len(u.starts), QuotaExecutionsPerHour, limit)
+		}
+		u.starts = append(u.starts, now)
+	}
+	return "", 0, nil
+}
+
+// recordNodeRuns adds the node runs of a finished execution of proc.
+func (q *quotaTracker) recordNodeRuns(proc *models.Process, runs int) {
+	if proc.Definition.Settings.MaxNodeExecutionsPerDay <= 0 || runs == 0 {
+		return
+	}
+	now := q.now()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.usageLocked(proc, now).nodeRuns += runs
+}
+
+// usageLocked returns the usage of proc, starting a new day's node count at
+// UTC midnight.
+func (q *quotaTracker) usageLocked(proc *models.Process, now time.Time) *quotaUsage {
+	key := tenant.Normalize(proc.Definition.Workspace) + "/" + proc.Definition.ID
+	u, ok := q.usage[key]
+	if !ok {
+		u = &quotaUsage{}
+		q.usage[key] = u
+	}
+	if day := now.UTC().Format(time.DateOnly); u.day != day {
+		u.day, u.nodeRuns = day, 0
+	}
+	return u
+}
+
+// countNodeRuns returns how many node runs ctx recorded, counting the visits
+// of nodes that ran more than once.
+func countNodeRuns(ctx *models.ExecutionContext) int {
+	if ctx == nil {
+		return 0
+	}
+	runs := 0
+	for _, node := range ctx.Nodes {
+		if visits, ok := node["visits"].(int); ok && visits > 0 {
+			runs += visits
+		} else {
+			runs++
+		}
+	}
+	return runs
+}
+
+// quotaExecutor refuses executions of a process beyond its quotas, so a
+// misconfigured trigger (a cron firing every second, a chatty queue) cannot
+// flood the systems the process calls. Like gatedExecutor, refused runs are
+// rejected rather than delayed.
+type quotaExecutor struct {
+	next    Executor
+	tracker *quotaTracker
+	auditor QuotaAuditor // may be nil
+}
+
+// Execute runs the process within its quotas and returns ErrQuotaExceeded otherwise.
+func (q *quotaExecutor) Execute(process *models.Process, triggerData map[string]interface{}) (*models.ExecutionContext, error) {
+	return q.ExecuteContext(context.Background(), process, triggerData)
+}
+
+// ExecuteContext is Execute with the run bound to ctx.
+func (q *quotaExecutor) ExecuteContext(ctx context.Context, process *models.Process, triggerData map[string]interface{}) (*models.ExecutionContext, error) {
+	quota, limit, err := q.tracker.admit(process)
+	if err != nil {
+		slog.Warn("triggers: execution refused by quota", logging.KeyWorkspace, tenant.Normalize(process.Definition.Workspace), logging.KeyProcessID, process.Definition.ID, "quota", quota, "limit", limit)
+		if q.auditor != nil {
+			q.auditor.SendQuotaAuditLog(process.Definition.Workspace, process.Definition.ID, process.Trigger.Type, quota, limit)
+		}
+		return nil, err
+	}
+	execCtx, err := ExecuteContext(ctx, q.next, process, triggerData)
+	q.tracker.recordNodeRuns(process, countNodeRuns(execCtx))
+	return execCtx, err
+}
@@ -0,0 +1,122 @@
+package triggers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"flowjs-works/engine/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingAuditor records the quota events it is sent.
+type recordingAuditor struct {
+	mockExecutor
+	quotas []string
+}
+
+func (r *recordingAuditor) SendQuotaAuditLog(_, _, _, quota string, _ int) {
+	r.quotas = append(r.quotas, quota)
+}
+
+// nodeExecutor returns a context in which nodes ran once each.
+type nodeExecutor struct{ nodes int }
+
+func (n *nodeExecutor) Execute(_ *models.Process, _ map[string]interface{}) (*models.ExecutionContext, error) {
+	ctx := models.NewExecutionContext("exec")
+	for i := 0; i < n.nodes; i++ {
+		ctx.SetNodeStatus(string(rune('a'+i)), "success")
+	}
+	return ctx, nil
+}
+
+func quotaProcess(perHour, nodesPerDay int) *models.Process {
+	proc := buildProcess("quota-proc", "manual", nil)
+	proc.Definition.Settings.MaxExecutionsPerHour = perHour
+	proc.Definition.Settings.MaxNodeExecutionsPerDay = nodesPerDay
+	return proc
+}
+
+func TestQuotaExecutor_ExecutionsPerHour(t *testing.T) {
+	clock := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	tracker := newQuotaTracker()
+	tracker.now = func() time.Time { return clock }
+	auditor := &recordingAuditor{}
+	q := &quotaExecutor{next: auditor, tracker: tracker, auditor: auditor}
+	proc := quotaProcess(2, 0)
+
+	for i := 0; i < 2; i++ {
+		_, err := q.Execute(proc, nil)
+		require.NoError(t, err)
+		clock = clock.Add(10 * time.Minute)
+	}
+	_, err := q.Execute(proc, nil)
+	assert.True(t, errors.Is(err, ErrQuotaExceeded))
+	assert.Contains(t, err.Error(), QuotaExecutionsPerHour)
+	assert.Len(t, auditor.executions, 2)
+	assert.Equal(t, []string{QuotaExecutionsPerHour}, auditor.quotas)
+
+	// The first execution leaves the rolling hour.
+	clock = clock.Add(41 * time.Minute)
+	_, err = q.Execute(proc, nil)
+	assert.NoError(t, err)
+}
+
+func TestQuotaExecutor_NodeExecutionsPerDay(t *testing.T) {
+	clock := time.Date(2026, 3, 1, 22, 0, 0, 0, time.UTC)
+	tracker := newQuotaTracker()
+	tracker.now = func() time.Time { return clock }
+	q := &quotaExecutor{next: &nodeExecutor{nodes: 3}, tracker: tracker}
+	proc := quotaProcess(0, 5)
+
+	// The limit is checked before an execution, so the second one may take
+	// the day's count past it.
+	for i := 0; i < 2; i++ {
+		_, err := q.Execute(proc, nil)
+		require.NoError(t, err)
+	}
+	_, err := q.Execute(proc, nil)
+	assert.True(t, errors.Is(err, ErrQuotaExceeded))
+	assert.Contains(t, err.Error(), "6 node executions today")
+
+	clock = clock.Add(3 * time.Hour) // next UTC day
+	_, err = q.Execute(proc, nil)
+	assert.NoError(t, err)
+}
+
+func TestQuotaExecutor_UnlimitedByDefault(t *testing.T) {
+	exec := &mockExecutor{}
+	q := &quotaExecutor{next: exec, tracker: newQuotaTracker()}
+	for i := 0; i < 5; i++ {
+		_, err := q.Execute(quotaProcess(0, 0), nil)
+		require.NoError(t, err)
+	}
+	assert.Len(t, exec.executions, 5)
+}
+
+func TestCountNodeRuns_CountsVisits(t *testing.T) {
+	ctx := models.NewExecutionContext("exec")
+	ctx.SetNodeStatus("a", "success")
+	ctx.SetNodeStatus("loop", "success")
+	ctx.SetNodeVisits("loop", 4)
+	assert.Equal(t, 5, countNodeRuns(ctx))
+	assert.Equal(t, 0, countNodeRuns(nil))
+}
+
+func TestManager_QuotaSurvivesRedeploy(t *testing.T) {
+	exec := &mockExecutor{}
+	mgr := NewManager(exec)
+	proc := quotaProcess(1, 0)
+
+	require.NoError(t, mgr.Deploy(proc))
+	_, err := mgr.Run(context.Background(), proc.Definition.ID, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, mgr.Deploy(proc))
+	_, err = mgr.Run(context.Background(), proc.Definition.ID, nil)
+	assert.True(t, errors.Is(err, ErrQuotaExceeded))
+	mgr.StopAll()
+}
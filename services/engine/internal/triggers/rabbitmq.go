@@ -3,9 +3,13 @@ package triggers
 import (
 	"context"
 	"fmt"
-	"log"
+	"hash/fnv"
+	"log/slog"
+	"strings"
+	"sync"
 	"time"
 
+	"flowjs-works/engine/internal/logging"
 	"flowjs-works/engine/internal/models"
 
 	amqp "github.com/rabbitmq/amqp091-go"
@@ -15,78 +19,363 @@ import (
 // complete before closing the AMQP connection.
 const consumerDrainTimeout = 100 * time.Millisecond
 
-// message received. Each delivery is ACKed on successful execution.
+// maxRabbitMQConcurrency bounds trigger config "concurrency".
+const maxRabbitMQConcurrency = 256
+
+// rabbitMQConsumerTag is the consumer tag of the trigger on its channel.
+const rabbitMQConsumerTag = "flowjs-runner"
+
+// Delays between reconnection attempts after the broker connection or
+// channel closed: doubling from the first to the cap.
+const (
+	rabbitMQReconnectMin = time.Second
+	rabbitMQReconnectMax = time.Minute
+)
+
+// rabbitMQTrigger consumes a queue and runs the process once per
+// message received. Each delivery is ACKed on successful execution. When the
+// connection or channel closes under it, as when the broker restarts, it
+// reconnects with exponential backoff and declares its topology again.
 type rabbitMQTrigger struct {
 	executor  Executor
-	conn      *amqp.Connection
-	channel   *amqp.Channel
 	done      chan struct{}
+	consumer  sync.WaitGroup
 	processID string
+
+	url      string
+	queue    string
+	opts     rabbitMQConsumerOptions
+	topology *rabbitMQTopology
+
+	// mu guards session, stopped and status, shared with the goroutine
+	// consuming and reconnecting.
+	mu      sync.Mutex
+	session *rabbitMQSession
+	stopped bool
+	status  TriggerStatus
+}
+
+// rabbitMQSession is one connection of the trigger and its consumer.
+type rabbitMQSession struct {
+	conn       *amqp.Connection
+	channel    *amqp.Channel
+	deliveries <-chan amqp.Delivery
+	// connClosed and chanClosed receive why the connection or the channel
+	// closed (NotifyClose); they are closed without a value when it was us.
+	connClosed chan *amqp.Error
+	chanClosed chan *amqp.Error
+}
+
+// closeReason returns why the session ended, once its deliveries are closed.
+func (s *rabbitMQSession) closeReason() string {
+	for _, c := range []chan *amqp.Error{s.chanClosed, s.connClosed} {
+		select {
+		case err, ok := <-c:
+			if ok && err != nil {
+				return err.Error()
+			}
+		default:
+		}
+	}
+	return "delivery channel closed"
+}
+
+// close closes the channel and connection, ignoring those already closed.
+func (s *rabbitMQSession) close() {
+	_ = s.channel.Close()
+	_ = s.conn.Close()
+}
+
+// rabbitMQConsumerOptions control how many deliveries a rabbitmq trigger
+// processes at once.
+//
+//	concurrency: workers executing the process in parallel (default 1),
+//	             capped at definition.settings.max_concurrency
+//	prefetch:    unacknowledged deliveries the broker sends ahead (basic.qos;
+//	             default concurrency)
+//	order_key:   "routing_key" or "headers.<name>"; deliveries with the same
+//	             key are processed one at a time in arrival order
+type rabbitMQConsumerOptions struct {
+	concurrency int
+	prefetch    int
+	orderKey    string
+}
+
+// parseRabbitMQConsumerOptions reads the consumer options of a trigger config.
+func parseRabbitMQConsumerOptions(config map[string]interface{}) (rabbitMQConsumerOptions, error) {
+	o := rabbitMQConsumerOptions{concurrency: 1}
+	if v, ok := config["concurrency"].(float64); ok {
+		if v < 1 || v > maxRabbitMQConcurrency {
+			return o, fmt.Errorf("concurrency must be between 1 and %d; got %v", maxRabbitMQConcurrency, v)
+		}
+		o.concurrency = int(v)
+	}
+	if v, ok := config["prefetch"].(float64); ok {
+		if v < 0 {
+			return o, fmt.Errorf("prefetch must not be negative; got %v", v)
+		}
+		o.prefetch = int(v)
+	}
+	if o.prefetch == 0 {
+		o.prefetch = o.concurrency
+	}
+	o.orderKey, _ = config["order_key"].(string)
+	if o.orderKey != "" && o.orderKey != "routing_key" && !strings.HasPrefix(o.orderKey, "headers.") {
+		return o, fmt.Errorf("order_key must be \"routing_key\" or \"headers.<name>\"; got %q", o.orderKey)
+	}
+	return o, nil
+}
+
+// deliveryKey returns the order key of d, or false when d has none.
+func (o rabbitMQConsumerOptions) deliveryKey(d amqp.Delivery) (string, bool) {
+	if o.orderKey == "routing_key" {
+		return d.RoutingKey, d.RoutingKey != ""
+	}
+	v, ok := d.Headers[strings.TrimPrefix(o.orderKey, "headers.")]
+	if !ok || v == nil {
+		return "", false
+	}
+	return fmt.Sprint(v), true
 }
 
 func newRabbitMQTrigger(executor Executor) *rabbitMQTrigger {
 	return &rabbitMQTrigger{executor: executor}
 }
 
-// Start connects to the AMQP broker, sets up the consumer, and begins consuming
-// in a background goroutine.
+// Start connects to the AMQP broker, declares the configured topology, sets up
+// the consumer, and begins consuming in a background goroutine that also
+// reconnects when the connection is lost.
 func (t *rabbitMQTrigger) Start(ctx context.Context, proc *models.Process) error {
 	urlAMQP, queue, vhost, err := rabbitmqTriggerConfig(proc.Trigger.Config)
 	if err != nil {
 		return fmt.Errorf("rabbitmq_trigger: %w", err)
 	}
-
-	conn, err := amqp.Dial(urlAMQP)
+	opts, err := parseRabbitMQConsumerOptions(proc.Trigger.Config)
 	if err != nil {
-		return fmt.Errorf("rabbitmq_trigger: dial %q: %w", urlAMQP, err)
+		return fmt.Errorf("rabbitmq_trigger: %w", err)
 	}
-
-	ch, err := conn.Channel()
+	topology, err := parseRabbitMQTopology(proc.Trigger.Config)
 	if err != nil {
-		conn.Close()
-		return fmt.Errorf("rabbitmq_trigger: open channel: %w", err)
+		return fmt.Errorf("rabbitmq_trigger: %w", err)
+	}
+	// Workers beyond max_concurrency would only have their runs rejected
+	// and requeued.
+	if limit := proc.Definition.Settings.MaxConcurrency; limit > 0 && opts.concurrency > limit {
+		opts.concurrency = limit
 	}
 
 	_ = vhost // vhost is embedded in the AMQP URL by convention; kept for DSL completeness
 
-	deliveries, err := ch.Consume(
-		queue,           // queue name
-		"flowjs-runner", // consumer tag
-		false,           // auto-ack
-		false,           // exclusive
-		false,           // no-local
-		false,           // no-wait
-		nil,             // args
-	)
+	t.url, t.queue, t.opts, t.topology = urlAMQP, queue, opts, topology
+	// The first connection is made here so a wrong URL or topology fails
+	// the deployment instead of retrying forever.
+	session, err := t.connect()
 	if err != nil {
-		ch.Close()
-		conn.Close()
-		return fmt.Errorf("rabbitmq_trigger: consume %q: %w", queue, err)
+		return fmt.Errorf("rabbitmq_trigger: %w", err)
 	}
 
-	t.conn = conn
-	t.channel = ch
+	t.session = session
+	t.status = TriggerStatus{State: TriggerRunning, Since: time.Now().UTC()}
 	t.done = make(chan struct{})
 	t.processID = proc.Definition.ID
 
 	procCopy := *proc
-	go t.consume(deliveries, &procCopy)
+	done := t.done
+	t.consumer.Add(1)
+	go func() {
+		defer t.consumer.Done()
+		t.supervise(done, session, func(d amqp.Delivery) { t.handleDelivery(d, &procCopy) })
+	}()
 
-	log.Printf("rabbitmq_trigger: listening on queue %q for process %q", queue, proc.Definition.ID)
+	slog.Info("rabbitmq_trigger: listening", "queue", queue, "concurrency", opts.concurrency,
+		"prefetch", opts.prefetch, logging.KeyProcessID, proc.Definition.ID)
 	return nil
 }
 
-func (t *rabbitMQTrigger) consume(deliveries <-chan amqp.Delivery, proc *models.Process) {
+// connect dials the broker, opens a channel, declares the topology and
+// starts consuming the queue.
+func (t *rabbitMQTrigger) connect() (*rabbitMQSession, error) {
+	conn, err := amqp.Dial(t.url)
+	if err != nil {
+		return nil, fmt.Errorf("dial %q: %w", t.url, err)
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("open channel: %w", err)
+	}
+	s := &rabbitMQSession{
+		conn:       conn,
+		channel:    ch,
+		connClosed: conn.NotifyClose(make(chan *amqp.Error, 1)),
+		chanClosed: ch.NotifyClose(make(chan *amqp.Error, 1)),
+	}
+
+	if err := ch.Qos(t.opts.prefetch, 0, false); err != nil {
+		s.close()
+		return nil, fmt.Errorf("set prefetch %d: %w", t.opts.prefetch, err)
+	}
+	if err := t.topology.declare(ch, t.queue); err != nil {
+		s.close()
+		return nil, err
+	}
+
+	s.deliveries, err = ch.Consume(
+		t.queue,             // queue name
+		rabbitMQConsumerTag, // consumer tag
+		false,               // auto-ack
+		false,               // exclusive
+		false,               // no-local
+		false,               // no-wait
+		nil,                 // args
+	)
+	if err != nil {
+		s.close()
+		return nil, fmt.Errorf("consume %q: %w", t.queue, err)
+	}
+	return s, nil
+}
+
+// supervise consumes the deliveries of session until done is closed. When the
+// session ends first, because the connection or channel closed, it
+// reconnects and consumes the new session.
+func (t *rabbitMQTrigger) supervise(done <-chan struct{}, session *rabbitMQSession, handle func(amqp.Delivery)) {
 	for {
+		consumeDeliveries(done, session.deliveries, t.opts, handle)
 		select {
-		case <-t.done:
+		case <-done:
+			return
+		default:
+		}
+		reason := session.closeReason()
+		slog.Warn("rabbitmq_trigger: connection lost; reconnecting", "queue", t.queue, logging.KeyProcessID, t.processID, logging.KeyError, reason)
+		t.mu.Lock()
+		t.session = nil
+		t.status.State = TriggerReconnecting
+		t.status.Since = time.Now().UTC()
+		t.status.Error = reason
+		t.mu.Unlock()
+		session.close()
+		if session = t.reconnect(done); session == nil {
+			return
+		}
+	}
+}
+
+// reconnect connects again, waiting rabbitMQReconnectDelay between attempts,
+// until it succeeds or done is closed, in which case it returns nil.
+func (t *rabbitMQTrigger) reconnect(done <-chan struct{}) *rabbitMQSession {
+	for attempt := 0; ; attempt++ {
+		select {
+		case <-done:
+			return nil
+		case <-time.After(rabbitMQReconnectDelay(attempt)):
+		}
+		session, err := t.connect()
+		if err != nil {
+			slog.Warn("rabbitmq_trigger: reconnect", "attempt", attempt+1, logging.KeyProcessID, t.processID, logging.KeyError, err)
+			t.mu.Lock()
+			t.status.Error = err.Error()
+			t.mu.Unlock()
+			continue
+		}
+
+		t.mu.Lock()
+		if t.stopped {
+			t.mu.Unlock()
+			session.close()
+			return nil
+		}
+		t.session = session
+		t.status = TriggerStatus{State: TriggerRunning, Since: time.Now().UTC(), Reconnects: t.status.Reconnects + 1}
+		t.mu.Unlock()
+		slog.Info("rabbitmq_trigger: reconnected", "queue", t.queue, "attempts", attempt+1, logging.KeyProcessID, t.processID)
+		return session
+	}
+}
+
+// rabbitMQReconnectDelay is the delay before reconnection attempt attempt
+// (from 0).
+func rabbitMQReconnectDelay(attempt int) time.Duration {
+	if attempt >= 6 {
+		return rabbitMQReconnectMax
+	}
+	return min(rabbitMQReconnectMin<<attempt, rabbitMQReconnectMax)
+}
+
+// Status implements statusReporter.
+func (t *rabbitMQTrigger) Status() TriggerStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.status
+}
+
+// consumeDeliveries calls handle for every delivery on opts.concurrency
+// workers until deliveries is closed or done is, and returns once the workers
+// have finished. With an order key, deliveries sharing a key always go to the
+// same worker, so they are handled one at a time in arrival order; deliveries
+// without the key are spread over the workers.
+func consumeDeliveries(done <-chan struct{}, deliveries <-chan amqp.Delivery, opts rabbitMQConsumerOptions, handle func(amqp.Delivery)) {
+	var wg sync.WaitGroup
+	work := func(in <-chan amqp.Delivery) {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			case d, ok := <-in:
+				if !ok {
+					return
+				}
+				handle(d)
+			}
+		}
+	}
+	wg.Add(opts.concurrency)
+	if opts.orderKey == "" || opts.concurrency == 1 {
+		for i := 0; i < opts.concurrency; i++ {
+			go work(deliveries)
+		}
+		wg.Wait()
+		return
+	}
+
+	// Each worker has its own queue, buffered by the prefetch so one busy
+	// key does not hold back the others while the broker sends ahead.
+	queues := make([]chan amqp.Delivery, opts.concurrency)
+	for i := range queues {
+		queues[i] = make(chan amqp.Delivery, opts.prefetch)
+		go work(queues[i])
+	}
+	defer func() {
+		for _, q := range queues {
+			close(q)
+		}
+		wg.Wait()
+	}()
+	next := 0
+	for {
+		select {
+		case <-done:
 			return
 		case d, ok := <-deliveries:
 			if !ok {
-				log.Printf("rabbitmq_trigger: delivery channel closed for %q", t.processID)
 				return
 			}
-			t.handleDelivery(d, proc)
+			i := next % len(queues)
+			if key, ok := opts.deliveryKey(d); ok {
+				h := fnv.New32a()
+				_, _ = h.Write([]byte(key))
+				i = int(h.Sum32() % uint32(len(queues)))
+			} else {
+				next++
+			}
+			select {
+			case queues[i] <- d:
+			case <-done:
+				return
+			}
 		}
 	}
 }
@@ -101,7 +390,7 @@ func (t *rabbitMQTrigger) handleDelivery(d amqp.Delivery, proc *models.Process)
 	}
 
 	if _, err := t.executor.Execute(proc, triggerData); err != nil {
-		log.Printf("rabbitmq_trigger: execution error for %q: %v — NAcking message", proc.Definition.ID, err)
+		slog.Error("rabbitmq_trigger: execution failed; NAcking message", logging.KeyProcessID, proc.Definition.ID, logging.KeyError, err)
 		_ = d.Nack(false, true) // requeue on failure
 		return
 	}
@@ -109,25 +398,37 @@ func (t *rabbitMQTrigger) handleDelivery(d amqp.Delivery, proc *models.Process)
 	_ = d.Ack(false)
 }
 
-// Stop closes the channel, connection, and signals the consumer goroutine.
+// Stop closes the channel, connection, and signals the consumer goroutine,
+// which stops reconnecting.
 func (t *rabbitMQTrigger) Stop() error {
+	t.mu.Lock()
 	if t.done != nil {
 		close(t.done)
 		t.done = nil
 	}
-	if t.channel != nil {
-		if err := t.channel.Cancel("flowjs-runner", false); err != nil {
-			log.Printf("rabbitmq_trigger: cancel consumer: %v", err)
-		}
-		t.channel.Close()
-		t.channel = nil
+	t.stopped = true
+	session := t.session
+	t.session = nil
+	t.mu.Unlock()
+
+	if session == nil {
+		return nil
+	}
+	if err := session.channel.Cancel(rabbitMQConsumerTag, false); err != nil {
+		slog.Warn("rabbitmq_trigger: cancel consumer", logging.KeyProcessID, t.processID, logging.KeyError, err)
 	}
-	if t.conn != nil {
-		// Give the consumer goroutine a short window to drain.
-		time.Sleep(consumerDrainTimeout)
-		t.conn.Close()
-		t.conn = nil
+	session.channel.Close()
+	// Give the workers a short window to finish in-flight deliveries.
+	drained := make(chan struct{})
+	go func() {
+		t.consumer.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-time.After(consumerDrainTimeout):
 	}
+	session.conn.Close()
 	return nil
 }
 
@@ -0,0 +1,222 @@
+package triggers
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRabbitMQConsumerOptions(t *testing.T) {
+	o, err := parseRabbitMQConsumerOptions(map[string]interface{}{})
+	require.NoError(t, err)
+	assert.Equal(t, rabbitMQConsumerOptions{concurrency: 1, prefetch: 1}, o)
+
+	o, err = parseRabbitMQConsumerOptions(map[string]interface{}{"concurrency": float64(8), "order_key": "headers.customer_id"})
+	require.NoError(t, err)
+	assert.Equal(t, rabbitMQConsumerOptions{concurrency: 8, prefetch: 8, orderKey: "headers.customer_id"}, o)
+
+	o, err = parseRabbitMQConsumerOptions(map[string]interface{}{"concurrency": float64(4), "prefetch": float64(20)})
+	require.NoError(t, err)
+	assert.Equal(t, 20, o.prefetch)
+
+	for name, config := range map[string]map[string]interface{}{
+		"zero concurrency":   {"concurrency": float64(0)},
+		"huge concurrency":   {"concurrency": float64(maxRabbitMQConcurrency + 1)},
+		"negative prefetch":  {"prefetch": float64(-1)},
+		"unknown order key":  {"order_key": "body.id"},
+		"bare header prefix": {"order_key": "customer_id"},
+	} {
+		_, err := parseRabbitMQConsumerOptions(config)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestRabbitMQDeliveryKey(t *testing.T) {
+	d := amqp.Delivery{RoutingKey: "orders.eu", Headers: amqp.Table{"customer_id": int32(42)}}
+
+	key, ok := rabbitMQConsumerOptions{orderKey: "routing_key"}.deliveryKey(d)
+	assert.True(t, ok)
+	assert.Equal(t, "orders.eu", key)
+
+	key, ok = rabbitMQConsumerOptions{orderKey: "headers.customer_id"}.deliveryKey(d)
+	assert.True(t, ok)
+	assert.Equal(t, "42", key)
+
+	_, ok = rabbitMQConsumerOptions{orderKey: "headers.tenant"}.deliveryKey(d)
+	assert.False(t, ok)
+}
+
+// TestConsumeDeliveries_Concurrent verifies that deliveries are handled by
+// several workers at once.
+func TestConsumeDeliveries_Concurrent(t *testing.T) {
+	deliveries := make(chan amqp.Delivery, 4)
+	for i := 0; i < 4; i++ {
+		deliveries <- amqp.Delivery{DeliveryTag: uint64(i)}
+	}
+	close(deliveries)
+
+	var running, peak int32
+	release := make(chan struct{})
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+	}()
+	consumeDeliveries(make(chan struct{}), deliveries, rabbitMQConsumerOptions{concurrency: 4, prefetch: 4}, func(amqp.Delivery) {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		<-release
+		atomic.AddInt32(&running, -1)
+	})
+	assert.Equal(t, int32(4), peak)
+}
+
+// TestConsumeDeliveries_OrderedPerKey verifies that deliveries sharing an
+// order key are handled one at a time in arrival order.
+func TestConsumeDeliveries_OrderedPerKey(t *testing.T) {
+	deliveries := make(chan amqp.Delivery, 30)
+	for i := 0; i < 30; i++ {
+		key := []string{"a", "b", "c"}[i%3]
+		deliveries <- amqp.Delivery{DeliveryTag: uint64(i), RoutingKey: key}
+	}
+	close(deliveries)
+
+	var mu sync.Mutex
+	seen := map[string][]uint64{}
+	busy := map[string]bool{}
+	overlap := false
+	consumeDeliveries(make(chan struct{}), deliveries, rabbitMQConsumerOptions{concurrency: 3, prefetch: 3, orderKey: "routing_key"}, func(d amqp.Delivery) {
+		mu.Lock()
+		overlap = overlap || busy[d.RoutingKey]
+		busy[d.RoutingKey] = true
+		mu.Unlock()
+		time.Sleep(time.Millisecond)
+		mu.Lock()
+		busy[d.RoutingKey] = false
+		seen[d.RoutingKey] = append(seen[d.RoutingKey], d.DeliveryTag)
+		mu.Unlock()
+	})
+
+	assert.False(t, overlap, "deliveries with the same key ran concurrently")
+	for key, tags := range seen {
+		assert.Len(t, tags, 10, key)
+		assert.IsIncreasing(t, tags, key)
+	}
+}
+
+// TestConsumeDeliveries_StopsOnDone verifies that closing done stops the
+// workers even while deliveries stays open.
+func TestConsumeDeliveries_StopsOnDone(t *testing.T) {
+	done := make(chan struct{})
+	returned := make(chan struct{})
+	go func() {
+		consumeDeliveries(done, make(chan amqp.Delivery), rabbitMQConsumerOptions{concurrency: 2, prefetch: 2, orderKey: "routing_key"}, func(amqp.Delivery) {})
+		close(returned)
+	}()
+	close(done)
+	select {
+	case <-returned:
+	case <-time.After(time.Second):
+		t.Fatal("consumeDeliveries did not return after done was closed")
+	}
+}
+
+// recordingDeclarer records the declarations made on it.
+type recordingDeclarer struct {
+	calls []string
+	args  []amqp.Table
+	fail  string
+}
+
+func (r *recordingDeclarer) QueueDeclare(name string, durable, autoDelete, _, _ bool, args amqp.Table) (amqp.Queue, error) {
+	r.calls = append(r.calls, "queue "+name)
+	r.args = append(r.args, args)
+	return amqp.Queue{Name: name}, nil
+}
+
+func (r *recordingDeclarer) ExchangeDeclare(name, kind string, _, _, _, _ bool, args amqp.Table) error {
+	r.calls = append(r.calls, "exchange "+name+" "+kind)
+	if name == r.fail {
+		return errors.New("PRECONDITION_FAILED")
+	}
+	return nil
+}
+
+func (r *recordingDeclarer) QueueBind(name, key, exchange string, _ bool, _ amqp.Table) error {
+	r.calls = append(r.calls, "bind "+name+" "+exchange+" "+key)
+	return nil
+}
+
+// TestRabbitMQTopology_Declare verifies that exchanges, the queue and its
+// bindings are declared in that order, with integer arguments.
+func TestRabbitMQTopology_Declare(t *testing.T) {
+	topo, err := parseRabbitMQTopology(map[string]interface{}{"declare": map[string]interface{}{
+		"queue":     map[string]interface{}{"arguments": map[string]interface{}{"x-message-ttl": float64(60000), "x-queue-type": "quorum"}},
+		"exchanges": []interface{}{map[string]interface{}{"name": "orders", "type": "topic"}},
+		"bindings":  []interface{}{map[string]interface{}{"exchange": "orders", "routing_key": "order.*"}},
+	}})
+	require.NoError(t, err)
+	require.NotNil(t, topo.queue)
+	assert.True(t, topo.queue.durable)
+
+	ch := &recordingDeclarer{}
+	require.NoError(t, topo.declare(ch, "orders.in"))
+	assert.Equal(t, []string{"exchange orders topic", "queue orders.in", "bind orders.in orders order.*"}, ch.calls)
+	assert.Equal(t, amqp.Table{"x-message-ttl": int64(60000), "x-queue-type": "quorum"}, ch.args[0])
+
+	ch = &recordingDeclarer{fail: "orders"}
+	assert.ErrorContains(t, topo.declare(ch, "orders.in"), `declare exchange "orders"`)
+
+	none, err := parseRabbitMQTopology(map[string]interface{}{})
+	require.NoError(t, err)
+	assert.NoError(t, none.declare(&recordingDeclarer{}, "q"))
+}
+
+func TestParseRabbitMQTopology_Invalid(t *testing.T) {
+	for name, declare := range map[string]interface{}{
+		"not an object":      "yes",
+		"queue type":         map[string]interface{}{"queue": "durable"},
+		"exchange name":      map[string]interface{}{"exchanges": []interface{}{map[string]interface{}{"type": "topic"}}},
+		"exchange type":      map[string]interface{}{"exchanges": []interface{}{map[string]interface{}{"name": "x", "type": "round-robin"}}},
+		"binding exchange":   map[string]interface{}{"bindings": []interface{}{map[string]interface{}{"routing_key": "k"}}},
+		"bindings not list":  map[string]interface{}{"bindings": map[string]interface{}{}},
+		"arguments not maps": map[string]interface{}{"queue": map[string]interface{}{"arguments": []interface{}{}}},
+	} {
+		_, err := parseRabbitMQTopology(map[string]interface{}{"declare": declare})
+		assert.Error(t, err, name)
+	}
+}
+
+func TestRabbitMQReconnectDelay(t *testing.T) {
+	assert.Equal(t, time.Second, rabbitMQReconnectDelay(0))
+	assert.Equal(t, 8*time.Second, rabbitMQReconnectDelay(3))
+	assert.Equal(t, time.Minute, rabbitMQReconnectDelay(6))
+	assert.Equal(t, time.Minute, rabbitMQReconnectDelay(100))
+}
+
+// TestRabbitMQTrigger_StatusWhileReconnecting verifies the status reported
+// for a trigger that lost its connection.
+func TestRabbitMQTrigger_StatusWhileReconnecting(t *testing.T) {
+	mgr := NewManager(&mockExecutor{})
+	tr := newRabbitMQTrigger(&mockExecutor{})
+	tr.status = TriggerStatus{State: TriggerReconnecting, Error: "Exception (320) Reason: \"CONNECTION_FORCED\"", Reconnects: 2}
+	mgr.running["p_rabbit"] = &deployment{handler: tr, proc: buildProcess("p_rabbit", "rabbitmq", nil)}
+
+	status, ok := mgr.Status("p_rabbit")
+	require.True(t, ok)
+	assert.Equal(t, "rabbitmq", status.Type)
+	assert.Equal(t, TriggerReconnecting, status.State)
+	assert.Equal(t, 2, status.Reconnects)
+	assert.Contains(t, status.Error, "CONNECTION_FORCED")
+	assert.Equal(t, TriggerReconnecting, mgr.Statuses()["p_rabbit"].State)
+}
@@ -0,0 +1,206 @@
+package triggers
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// rabbitMQTopology is what a rabbitmq trigger declares on the broker before
+// consuming, on every (re)connection, so a broker restarted without its
+// definitions is set up again. It is read from trigger config "declare":
+//
+//	"declare": {
+//	  "queue":     {"durable": true, "auto_delete": false, "arguments": {"x-queue-type": "quorum"}},
+//	  "exchanges": [{"name": "orders", "type": "topic", "durable": true}],
+//	  "bindings":  [{"exchange": "orders", "routing_key": "order.*"}]
+//	}
+//
+// "queue" declares the trigger's queue ("queue": true takes the defaults);
+// bindings bind it to exchanges, declared here or not. Declarations are
+// idempotent, but one that differs from what exists on the broker fails the
+// connection.
+type rabbitMQTopology struct {
+	queue     *rabbitMQQueueDecl
+	exchanges []rabbitMQExchangeDecl
+	bindings  []rabbitMQBindingDecl
+}
+
+type rabbitMQQueueDecl struct {
+	durable    bool
+	autoDelete bool
+	args       amqp.Table
+}
+
+type rabbitMQExchangeDecl struct {
+	name       string
+	kind       string
+	durable    bool
+	autoDelete bool
+	args       amqp.Table
+}
+
+type rabbitMQBindingDecl struct {
+	exchange   string
+	routingKey string
+	args       amqp.Table
+}
+
+// amqpDeclarer is the part of *amqp.Channel declaring topology.
+type amqpDeclarer interface {
+	QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error)
+	ExchangeDeclare(name, kind string, durable, autoDelete, internal, noWait bool, args amqp.Table) error
+	QueueBind(name, key, exchange string, noWait bool, args amqp.Table) error
+}
+
+// parseRabbitMQTopology reads trigger config "declare". It returns nil when
+// nothing is to be declared.
+func parseRabbitMQTopology(config map[string]interface{}) (*rabbitMQTopology, error) {
+	raw, ok := config["declare"]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+	spec, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("declare must be an object")
+	}
+	topo := &rabbitMQTopology{}
+
+	switch q := spec["queue"].(type) {
+	case nil:
+	case bool:
+		if q {
+			topo.queue = &rabbitMQQueueDecl{durable: true}
+		}
+	case map[string]interface{}:
+		args, err := amqpArguments(q["arguments"])
+		if err != nil {
+			return nil, fmt.Errorf("declare.queue.arguments: %w", err)
+		}
+		topo.queue = &rabbitMQQueueDecl{durable: boolOr(q["durable"], true), autoDelete: boolOr(q["auto_delete"], false), args: args}
+	default:
+		return nil, fmt.Errorf("declare.queue must be a boolean or an object")
+	}
+
+	exchanges, err := objectList(spec["exchanges"], "declare.exchanges")
+	if err != nil {
+		return nil, err
+	}
+	for i, e := range exchanges {
+		decl := rabbitMQExchangeDecl{kind: "direct", durable: boolOr(e["durable"], true), autoDelete: boolOr(e["auto_delete"], false)}
+		decl.name, _ = e["name"].(string)
+		if decl.name == "" {
+			return nil, fmt.Errorf("declare.exchanges[%d].name is required", i)
+		}
+		if kind, _ := e["type"].(string); kind != "" {
+			decl.kind = kind
+		}
+		switch decl.kind {
+		case "direct", "fanout", "topic", "headers":
+		default:
+			// Plugins register their own types, all prefixed with "x-".
+			if !strings.HasPrefix(decl.kind, "x-") {
+				return nil, fmt.Errorf("declare.exchanges[%d].type must be direct, fanout, topic, headers or a plugin type x-*; got %q", i, decl.kind)
+			}
+		}
+		if decl.args, err = amqpArguments(e["arguments"]); err != nil {
+			return nil, fmt.Errorf("declare.exchanges[%d].arguments: %w", i, err)
+		}
+		topo.exchanges = append(topo.exchanges, decl)
+	}
+
+	bindings, err := objectList(spec["bindings"], "declare.bindings")
+	if err != nil {
+		return nil, err
+	}
+	for i, b := range bindings {
+		decl := rabbitMQBindingDecl{}
+		decl.exchange, _ = b["exchange"].(string)
+		if decl.exchange == "" {
+			return nil, fmt.Errorf("declare.bindings[%d].exchange is required", i)
+		}
+		decl.routingKey, _ = b["routing_key"].(string)
+		if decl.args, err = amqpArguments(b["arguments"]); err != nil {
+			return nil, fmt.Errorf("declare.bindings[%d].arguments: %w", i, err)
+		}
+		topo.bindings = append(topo.bindings, decl)
+	}
+	return topo, nil
+}
+
+// declare declares the topology on ch for queue: the exchanges first, then
+// the queue, then its bindings.
+func (t *rabbitMQTopology) declare(ch amqpDeclarer, queue string) error {
+	if t == nil {
+		return nil
+	}
+	for _, e := range t.exchanges {
+		if err := ch.ExchangeDeclare(e.name, e.kind, e.durable, e.autoDelete, false, false, e.args); err != nil {
+			return fmt.Errorf("declare exchange %q: %w", e.name, err)
+		}
+	}
+	if t.queue != nil {
+		if _, err := ch.QueueDeclare(queue, t.queue.durable, t.queue.autoDelete, false, false, t.queue.args); err != nil {
+			return fmt.Errorf("declare queue %q: %w", queue, err)
+		}
+	}
+	for _, b := range t.bindings {
+		if err := ch.QueueBind(queue, b.routingKey, b.exchange, false, b.args); err != nil {
+			return fmt.Errorf("bind queue %q to exchange %q: %w", queue, b.exchange, err)
+		}
+	}
+	return nil
+}
+
+// objectList reads an optional list of objects.
+func objectList(raw interface{}, field string) ([]map[string]interface{}, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	list, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s must be a list", field)
+	}
+	out := make([]map[string]interface{}, len(list))
+	for i, item := range list {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s[%d] must be an object", field, i)
+		}
+		out[i] = m
+	}
+	return out, nil
+}
+
+// amqpArguments converts JSON arguments to an amqp.Table. Whole numbers
+// become integers, which arguments such as x-message-ttl require.
+func amqpArguments(raw interface{}) (amqp.Table, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	m, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("must be an object")
+	}
+	args := make(amqp.Table, len(m))
+	for k, v := range m {
+		if n, ok := v.(float64); ok && n == math.Trunc(n) && math.Abs(n) < 1<<53 {
+			v = int64(n)
+		}
+		args[k] = v
+	}
+	if err := args.Validate(); err != nil {
+		return nil, err
+	}
+	return args, nil
+}
+
+// boolOr returns v when it is a boolean, else def.
+func boolOr(v interface{}, def bool) bool {
+	if b, ok := v.(bool); ok {
+		return b
+	}
+	return def
+}
@@ -0,0 +1,141 @@
+package triggers
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"flowjs-works/engine/internal/logging"
+	"flowjs-works/engine/internal/models"
+	"flowjs-works/engine/internal/tenant"
+)
+
+// Kinds of Drift.
+const (
+	// DriftNotRunning is a process the config DB records as deployed without
+	// a trigger running here, as after a crash between the status update of
+	// a deploy and its trigger starting.
+	DriftNotRunning = "not_running"
+	// DriftNotDeployed is a trigger running here for a process the config DB
+	// does not record as deployed, as after a stop whose status update failed.
+	DriftNotDeployed = "not_deployed"
+)
+
+// DeployedProcess is a process the config DB records as deployed.
+type DeployedProcess struct {
+	ID          string
+	Workspace   string
+	TriggerType string
+}
+
+// Drift is a mismatch between the config DB and the triggers running here,
+// and how Reconcile corrected it: by starting the trigger of a process
+// DriftNotRunning, by stopping the one DriftNotDeployed.
+type Drift struct {
+	ProcessID   string `json:"process_id"`
+	Workspace   string `json:"workspace"`
+	TriggerType string `json:"trigger_type"`
+	Kind        string `json:"kind"`
+	// Since is when the drift was first seen.
+	Since time.Time `json:"since"`
+	// Error is why the correction failed; the next Reconcile retries it.
+	Error string `json:"error,omitempty"`
+}
+
+// ProcessLoader loads process processID of workspace as it is deployed.
+type ProcessLoader func(ctx context.Context, workspace, processID string) (*models.Process, error)
+
+// Reconciler brings the triggers of a Manager in line with the processes the
+// config DB records as deployed, which deploys and stops update separately
+// from the triggers. A drift is only corrected once it lasted grace, so
+// deploys and stops caught between their two updates are left alone.
+type Reconciler struct {
+	mgr   *Manager
+	load  ProcessLoader
+	grace time.Duration
+	// since holds when each drift was first seen, by kind and process ID.
+	since map[string]time.Time
+	mu    sync.Mutex
+}
+
+// NewReconciler creates a Reconciler of mgr loading the processes to start
+// with load.
+func NewReconciler(mgr *Manager, load ProcessLoader, grace time.Duration) *Reconciler {
+	return &Reconciler{mgr: mgr, load: load, grace: grace, since: make(map[string]time.Time)}
+}
+
+// Reconcile compares deployed, the processes the config DB records as
+// deployed, with the running triggers and corrects the drifts that lasted
+// the grace period. It returns the drifts it corrected or failed to.
+func (r *Reconciler) Reconcile(ctx context.Context, deployed []DeployedProcess) []Drift {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now().UTC()
+
+	r.mgr.mu.Lock()
+	running := make(map[string]Drift, len(r.mgr.running))
+	for id, d := range r.mgr.running {
+		running[id] = Drift{ProcessID: id, Workspace: tenant.Normalize(d.proc.Definition.Workspace), TriggerType: d.handler.Type(), Kind: DriftNotDeployed}
+	}
+	r.mgr.mu.Unlock()
+
+	var found []Drift
+	isDeployed := make(map[string]bool, len(deployed))
+	for _, p := range deployed {
+		isDeployed[p.ID] = true
+		if _, ok := running[p.ID]; !ok {
+			found = append(found, Drift{ProcessID: p.ID, Workspace: tenant.Normalize(p.Workspace), TriggerType: p.TriggerType, Kind: DriftNotRunning})
+		}
+	}
+	for id, d := range running {
+		if !isDeployed[id] {
+			found = append(found, d)
+		}
+	}
+	slices.SortFunc(found, func(a, b Drift) int { return strings.Compare(a.ProcessID, b.ProcessID) })
+
+	seen := make(map[string]bool, len(found))
+	var corrected []Drift
+	for _, d := range found {
+		key := d.Kind + "/" + d.ProcessID
+		seen[key] = true
+		since, ok := r.since[key]
+		if !ok {
+			since = now
+			r.since[key] = since
+		}
+		d.Since = since
+		if now.Sub(since) < r.grace {
+			continue
+		}
+		if err := r.correct(ctx, d); err != nil {
+			d.Error = err.Error()
+			slog.Warn("triggers: correct drift", logging.KeyProcessID, d.ProcessID, "drift", d.Kind, logging.KeyError, err)
+		} else {
+			delete(r.since, key)
+			slog.Warn("triggers: drift corrected", logging.KeyProcessID, d.ProcessID, "drift", d.Kind)
+		}
+		corrected = append(corrected, d)
+	}
+	for key := range r.since {
+		if !seen[key] {
+			delete(r.since, key)
+		}
+	}
+	return corrected
+}
+
+// correct starts or stops the trigger of the process of d.
+func (r *Reconciler) correct(ctx context.Context, d Drift) error {
+	if d.Kind == DriftNotDeployed {
+		return r.mgr.Stop(d.ProcessID)
+	}
+	proc, err := r.load(ctx, d.Workspace, d.ProcessID)
+	if err != nil {
+		return err
+	}
+	return r.mgr.Deploy(proc)
+}
@@ -0,0 +1,86 @@
+package triggers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"flowjs-works/engine/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestReconciler_CorrectsDrift verifies that a process deployed in the config
+// DB gets its trigger started and a trigger of a process no longer deployed
+// is stopped.
+func TestReconciler_CorrectsDrift(t *testing.T) {
+	mgr := NewManager(&mockExecutor{})
+	require.NoError(t, mgr.Deploy(buildProcess("p_stale", "manual", nil)))
+	t.Cleanup(mgr.StopAll)
+	loaded := 0
+	load := func(_ context.Context, workspace, id string) (*models.Process, error) {
+		loaded++
+		assert.Equal(t, "default", workspace)
+		return buildProcess(id, "manual", nil), nil
+	}
+	r := NewReconciler(mgr, load, 0)
+
+	drifts := r.Reconcile(context.Background(), []DeployedProcess{{ID: "p_missing", TriggerType: "manual"}})
+	require.Len(t, drifts, 2)
+	assert.Equal(t, "p_missing", drifts[0].ProcessID)
+	assert.Equal(t, DriftNotRunning, drifts[0].Kind)
+	assert.Equal(t, "p_stale", drifts[1].ProcessID)
+	assert.Equal(t, DriftNotDeployed, drifts[1].Kind)
+	assert.Empty(t, drifts[0].Error)
+	assert.Equal(t, 1, loaded)
+	assert.True(t, mgr.IsRunning("p_missing"))
+	assert.False(t, mgr.IsRunning("p_stale"))
+
+	assert.Empty(t, r.Reconcile(context.Background(), []DeployedProcess{{ID: "p_missing"}}))
+}
+
+// TestReconciler_Grace verifies that a drift is left alone until it lasted
+// the grace period, and forgotten once it is gone.
+func TestReconciler_Grace(t *testing.T) {
+	mgr := NewManager(&mockExecutor{})
+	require.NoError(t, mgr.Deploy(buildProcess("p_deploying", "manual", nil)))
+	t.Cleanup(mgr.StopAll)
+	r := NewReconciler(mgr, nil, time.Hour)
+
+	assert.Empty(t, r.Reconcile(context.Background(), nil))
+	assert.True(t, mgr.IsRunning("p_deploying"))
+	assert.Len(t, r.since, 1)
+
+	assert.Empty(t, r.Reconcile(context.Background(), []DeployedProcess{{ID: "p_deploying"}}))
+	assert.Empty(t, r.since)
+}
+
+// TestReconciler_FailedStartIsRetried verifies that a trigger that cannot be
+// started is reported with its error and retried by the next pass.
+func TestReconciler_FailedStartIsRetried(t *testing.T) {
+	mgr := NewManager(&mockExecutor{})
+	t.Cleanup(mgr.StopAll)
+	fail := true
+	load := func(_ context.Context, _, id string) (*models.Process, error) {
+		if fail {
+			return nil, errors.New("process has not been promoted")
+		}
+		return buildProcess(id, "manual", nil), nil
+	}
+	r := NewReconciler(mgr, load, 0)
+	deployed := []DeployedProcess{{ID: "p_retry", Workspace: "acme"}}
+
+	drifts := r.Reconcile(context.Background(), deployed)
+	require.Len(t, drifts, 1)
+	assert.Equal(t, "acme", drifts[0].Workspace)
+	assert.Equal(t, "process has not been promoted", drifts[0].Error)
+	assert.False(t, mgr.IsRunning("p_retry"))
+
+	fail = false
+	drifts = r.Reconcile(context.Background(), deployed)
+	require.Len(t, drifts, 1)
+	assert.Empty(t, drifts[0].Error)
+	assert.True(t, mgr.IsRunning("p_retry"))
+}
@@ -3,14 +3,18 @@ package triggers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"strings"
 	"sync"
 	"time"
 
+	"flowjs-works/engine/internal/logging"
 	"flowjs-works/engine/internal/models"
+	"flowjs-works/engine/internal/tenant"
 )
 
 // restTrigger registers a dynamic HTTP route on the engine's main mux so that
@@ -24,6 +28,14 @@ type restTrigger struct {
 	processID string
 	path      string
 	method    string
+
+	// capturer stores inbound requests for replay when the trigger config
+	// sets capture_days; nil disables capture.
+	capturer    RequestCapturer
+	captureDays int
+
+	// maxBody is the largest request body accepted, after decompression.
+	maxBody int64
 }
 
 func newRESTTrigger(executor Executor) *restTrigger {
@@ -36,38 +48,86 @@ func (t *restTrigger) Start(ctx context.Context, proc *models.Process) error {
 	if err != nil {
 		return fmt.Errorf("rest_trigger: %w", err)
 	}
+	mapping, err := parseRESTResponseMapping(proc.Trigger.Config)
+	if err != nil {
+		return fmt.Errorf("rest_trigger: %w", err)
+	}
+	if t.captureDays, err = captureDays(proc.Trigger.Config); err != nil {
+		return fmt.Errorf("rest_trigger: %w", err)
+	}
+	if t.maxBody, err = restMaxBody(proc.Trigger.Config); err != nil {
+		return fmt.Errorf("rest_trigger: %w", err)
+	}
+
+	// Routes of non-default workspaces live under /ws/{workspace} so tenants
+	// cannot collide with (or hijack) each other's endpoints.
+	path = tenant.RoutePrefix(proc.Definition.Workspace) + path
 
 	t.processID = proc.Definition.ID
 	t.path = path
 	t.method = method
 
 	procCopy := *proc
-	globalRESTRegistry.register(path, method, func(w http.ResponseWriter, r *http.Request) {
-		body := map[string]interface{}{}
-		if r.Body != nil {
-			_ = json.NewDecoder(r.Body).Decode(&body)
+	globalRESTRegistry.register(path, method, newRouteOwner(proc), t.buildHandler(&procCopy, mapping))
+
+	slog.Info("rest_trigger: registered route", "method", method, "path", path, logging.KeyProcessID, proc.Definition.ID)
+	return nil
+}
+
+// buildHandler returns the route handler serving proc. mapping is the
+// optional response mapping of the trigger config.
+func (t *restTrigger) buildHandler(proc *models.Process, mapping *restResponseMapping) restHandler {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]interface{}) {
+		raw, status, err := readRESTBody(w, r, t.maxBody)
+		if err != nil {
+			writeRESTError(w, status, err)
+			return
+		}
+		if t.captureDays > 0 {
+			captureRequest(t.capturer, t.captureDays, proc, "rest", r, raw)
+		}
+		body, err := parseRESTBody(r.Header.Get("Content-Type"), raw)
+		if err != nil {
+			writeRESTError(w, http.StatusBadRequest, err)
+			return
 		}
 
 		// Build trigger data matching the REST trigger output shape in the DSL.
-		headers := map[string]interface{}{}
-		for k, vv := range r.Header {
-			if len(vv) > 0 {
-				headers[k] = vv[0]
-			}
-		}
+		headers, headerValues := headerMaps(r.Header)
 		triggerData := map[string]interface{}{
-			"method":  r.Method,
-			"headers": headers,
-			"body":    body,
-			"auth":    r.Header.Get("Authorization"),
+			"method":        r.Method,
+			"headers":       headers,
+			"header_values": headerValues,
+			"body":          body,
+			"auth":          r.Header.Get("Authorization"),
+			"params":        params,
+			"query":         queryMap(r.URL.Query()),
+			"url":           r.URL.RequestURI(),
+			"remote_addr":   r.RemoteAddr,
 		}
 
-		execCtx, execErr := t.executor.Execute(&procCopy, triggerData)
+		execCtx, execErr := ExecuteContext(r.Context(), t.executor, proc, triggerData)
 		if execErr != nil {
-			log.Printf("rest_trigger: execution error for %q: %v", t.processID, execErr)
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusUnprocessableEntity)
-			_ = json.NewEncoder(w).Encode(map[string]string{"error": execErr.Error()})
+			slog.Error("rest_trigger: execution failed", logging.KeyProcessID, t.processID, logging.KeyError, execErr)
+			if errors.Is(execErr, models.ErrExecutionCancelled) {
+				writeRESTTimeout(w, execCtx, execErr)
+				return
+			}
+			status := http.StatusUnprocessableEntity
+			if errors.Is(execErr, ErrConcurrencyLimit) || errors.Is(execErr, ErrQuotaExceeded) {
+				status = http.StatusTooManyRequests
+			}
+			writeRESTError(w, status, execErr)
+			return
+		}
+
+		if mapping != nil {
+			if err := mapping.write(w, execCtx); err != nil {
+				slog.Error("rest_trigger: response mapping failed", logging.KeyProcessID, t.processID, logging.KeyError, err)
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusInternalServerError)
+				_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error(), "execution_id": execCtx.ExecutionID})
+			}
 			return
 		}
 
@@ -76,24 +136,79 @@ func (t *restTrigger) Start(ctx context.Context, proc *models.Process) error {
 			"execution_id": execCtx.ExecutionID,
 			"nodes":        execCtx.Nodes,
 		})
-	})
-
-	log.Printf("rest_trigger: registered %s %s for process %q", method, path, proc.Definition.ID)
-	return nil
+	}
 }
 
 // Stop deregisters the route from the shared registry.
 func (t *restTrigger) Stop() error {
 	if t.path != "" {
-		globalRESTRegistry.deregister(t.path, t.method)
-		log.Printf("rest_trigger: deregistered %s %s for process %q", t.method, t.path, t.processID)
+		globalRESTRegistry.deregister(t.path, t.method, t.processID)
+		slog.Info("rest_trigger: deregistered route", "method", t.method, "path", t.path, logging.KeyProcessID, t.processID)
 	}
 	return nil
 }
 
 func (t *restTrigger) Type() string { return "rest" }
 
-// restTriggerConfig extracts path and method from trigger config.
+// writeRESTError answers a REST trigger request with status and {"error"}.
+func writeRESTError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// writeRESTTimeout answers with 504 and {"error", "execution_id"} a request
+// whose execution was cancelled because the request budget ran out. The
+// execution id is omitted when the run never started.
+func writeRESTTimeout(w http.ResponseWriter, execCtx *models.ExecutionContext, err error) {
+	body := map[string]string{"error": err.Error()}
+	if execCtx != nil {
+		body["execution_id"] = execCtx.ExecutionID
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusGatewayTimeout)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// headerMaps returns the first value of every header, as "headers" has
+// always held, and all its values in order, as "header_values" holds.
+func headerMaps(h http.Header) (first, all map[string]interface{}) {
+	first = make(map[string]interface{}, len(h))
+	all = make(map[string]interface{}, len(h))
+	for k, vv := range h {
+		if len(vv) == 0 {
+			continue
+		}
+		first[k] = vv[0]
+		values := make([]interface{}, len(vv))
+		for i, v := range vv {
+			values[i] = v
+		}
+		all[k] = values
+	}
+	return first, all
+}
+
+// queryMap converts query parameters for trigger_data.query: a key given once
+// maps to its value, a repeated key to the list of its values.
+func queryMap(values url.Values) map[string]interface{} {
+	out := make(map[string]interface{}, len(values))
+	for k, vv := range values {
+		if len(vv) == 1 {
+			out[k] = vv[0]
+			continue
+		}
+		list := make([]interface{}, len(vv))
+		for i, v := range vv {
+			list[i] = v
+		}
+		out[k] = list
+	}
+	return out
+}
+
+// restTriggerConfig extracts path and method from trigger config. The path
+// may contain {name} segments, which are validated here.
 func restTriggerConfig(config map[string]interface{}) (path, method string, err error) {
 	if config == nil {
 		return "", "", fmt.Errorf("trigger config is nil; expected {\"path\":\"...\",\"method\":\"...\"}")
@@ -102,6 +217,9 @@ func restTriggerConfig(config map[string]interface{}) (path, method string, err
 	if path == "" {
 		return "", "", fmt.Errorf("trigger config missing required field \"path\"")
 	}
+	if _, err := parseRoutePattern(path); err != nil {
+		return "", "", err
+	}
 	method, _ = config["method"].(string)
 	if method == "" {
 		method = http.MethodPost // sensible default
@@ -113,29 +231,198 @@ func restTriggerConfig(config map[string]interface{}) (path, method string, err
 // Global REST route registry
 // ---------------------------------------------------------------------------
 
+// restHandler serves a REST trigger route. params holds the values of the
+// route's {name} segments; it is empty for exact paths.
+type restHandler func(w http.ResponseWriter, r *http.Request, params map[string]interface{})
+
+// routePattern is a REST trigger path split into segments. A segment whose
+// name is set is a {name} parameter and matches any single non-empty segment.
+type routePattern struct {
+	segments []routeSegment
+}
+
+type routeSegment struct {
+	literal string
+	param   string
+}
+
+// parseRoutePattern splits path into segments. Parameters must span a whole
+// segment ("/orders/{orderId}", not "/orders/id-{orderId}") and be unique.
+func parseRoutePattern(path string) (routePattern, error) {
+	var p routePattern
+	seen := make(map[string]bool)
+	for _, seg := range strings.Split(strings.Trim(path, "/"), "/") {
+		if !strings.ContainsAny(seg, "{}") {
+			p.segments = append(p.segments, routeSegment{literal: seg})
+			continue
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(seg, "{"), "}")
+		if len(name) != len(seg)-2 || name == "" || strings.ContainsAny(name, "{}") {
+			return p, fmt.Errorf("invalid path segment %q in %q; parameters must be a whole segment like {orderId}", seg, path)
+		}
+		if seen[name] {
+			return p, fmt.Errorf("duplicate path parameter {%s} in %q", name, path)
+		}
+		seen[name] = true
+		p.segments = append(p.segments, routeSegment{param: name})
+	}
+	return p, nil
+}
+
+// templated reports whether the pattern has parameters.
+func (p routePattern) templated() bool {
+	for _, seg := range p.segments {
+		if seg.param != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// shape returns the pattern with parameter names erased, so "/orders/{id}"
+// and "/orders/{orderId}" register as the same route.
+func (p routePattern) shape() string {
+	parts := make([]string, len(p.segments))
+	for i, seg := range p.segments {
+		parts[i] = seg.literal
+		if seg.param != "" {
+			parts[i] = "{}"
+		}
+	}
+	return "/" + strings.Join(parts, "/")
+}
+
+// match returns the parameters extracted from path and the number of literal
+// segments matched, which ranks competing templates.
+func (p routePattern) match(path string) (map[string]interface{}, int, bool) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) != len(p.segments) {
+		return nil, 0, false
+	}
+	params := make(map[string]interface{})
+	literals := 0
+	for i, seg := range p.segments {
+		if seg.param == "" {
+			if parts[i] != seg.literal {
+				return nil, 0, false
+			}
+			literals++
+			continue
+		}
+		if parts[i] == "" {
+			return nil, 0, false
+		}
+		v, err := url.PathUnescape(parts[i])
+		if err != nil {
+			return nil, 0, false
+		}
+		params[seg.param] = v
+	}
+	return params, literals, true
+}
+
+// templateRoute is a registered route with parameters.
+type templateRoute struct {
+	pattern routePattern
+	handler restHandler
+}
+
 // restRegistryImpl is a mutex-protected map of dynamically registered REST
 // trigger handlers. It is safe for concurrent use by multiple goroutines.
+// Exact paths are looked up directly; templated paths are matched in turn.
 type restRegistryImpl struct {
-	mu       sync.RWMutex
-	handlers map[string]http.HandlerFunc
+	mu        sync.RWMutex
+	handlers  map[string]restHandler
+	templates map[string]templateRoute
+	// owners holds the process and path of every route, under the key of
+	// handlers or templates.
+	owners map[string]routeOwner
 }
 
 func newRESTRegistry() *restRegistryImpl {
-	return &restRegistryImpl{handlers: make(map[string]http.HandlerFunc)}
+	return &restRegistryImpl{
+		handlers:  make(map[string]restHandler),
+		templates: make(map[string]templateRoute),
+		owners:    make(map[string]routeOwner),
+	}
 }
 
 var globalRESTRegistry = newRESTRegistry()
 
-func (r *restRegistryImpl) register(path, method string, h http.HandlerFunc) {
+func (r *restRegistryImpl) register(path, method string, owner routeOwner, h restHandler) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	owner.path = path
+	if p, err := parseRoutePattern(path); err == nil && p.templated() {
+		key := registryKey(p.shape(), method)
+		r.templates[key] = templateRoute{pattern: p, handler: h}
+		r.owners[key] = owner
+		return
+	}
 	r.handlers[registryKey(path, method)] = h
+	r.owners[registryKey(path, method)] = owner
 }
 
-func (r *restRegistryImpl) deregister(path, method string) {
+// deregister removes the route of path and method when processID owns it.
+// A route taken over by another process since is left to that process, so
+// stopping the previous one does not leave the path unserved.
+func (r *restRegistryImpl) deregister(path, method, processID string) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	delete(r.handlers, registryKey(path, method))
+	key := registryKey(path, method)
+	p, err := parseRoutePattern(path)
+	templated := err == nil && p.templated()
+	if templated {
+		key = registryKey(p.shape(), method)
+	}
+	if owner, ok := r.owners[key]; ok && owner.processID != processID {
+		return
+	}
+	if templated {
+		delete(r.templates, key)
+	} else {
+		delete(r.handlers, key)
+	}
+	delete(r.owners, key)
+}
+
+// routes lists the registered routes.
+func (r *restRegistryImpl) routes() []Route {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]Route, 0, len(r.owners))
+	for key, owner := range r.owners {
+		method, _, _ := strings.Cut(key, " ")
+		out = append(out, owner.route("rest", method))
+	}
+	return out
+}
+
+// lookup finds the handler for method and path. An exact path wins over a
+// template; among templates the one with the most literal segments wins, so
+// "/orders/export" is preferred to "/orders/{orderId}". Templates are matched
+// against rawPath, the escaped form of path, so an encoded slash stays inside
+// one parameter.
+func (r *restRegistryImpl) lookup(method, path, rawPath string) (restHandler, map[string]interface{}, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if h, ok := r.handlers[registryKey(path, method)]; ok {
+		return h, map[string]interface{}{}, true
+	}
+	var (
+		best       restHandler
+		bestParams map[string]interface{}
+		bestScore  = -1
+	)
+	for key, route := range r.templates {
+		if !strings.HasPrefix(key, method+" ") {
+			continue
+		}
+		if params, score, ok := route.pattern.match(rawPath); ok && score > bestScore {
+			best, bestParams, bestScore = route.handler, params, score
+		}
+	}
+	return best, bestParams, best != nil
 }
 
 // ServeHTTP dispatches incoming requests to the registered handler for the
@@ -151,20 +438,18 @@ func (r *restRegistryImpl) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	if lookupPath == "" {
 		lookupPath = "/"
 	}
-	r.mu.RLock()
-	key := registryKey(lookupPath, req.Method)
-	h, ok := r.handlers[key]
+	rawPath := strings.TrimPrefix(req.URL.EscapedPath(), "/triggers")
+	h, params, ok := r.lookup(req.Method, lookupPath, rawPath)
 	if !ok {
 		// Fall back to method-agnostic lookup registered under POST.
-		h, ok = r.handlers[registryKey(lookupPath, "POST")]
+		h, params, ok = r.lookup(http.MethodPost, lookupPath, rawPath)
 	}
-	r.mu.RUnlock()
 
 	if !ok {
 		http.Error(w, fmt.Sprintf("no REST trigger registered for %s %s", req.Method, req.URL.Path), http.StatusNotFound)
 		return
 	}
-	h(w, req)
+	h(w, req, params)
 }
 
 // GetRegistryHandler returns the shared REST registry as an http.Handler.
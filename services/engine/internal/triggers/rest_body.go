@@ -0,0 +1,179 @@
+package triggers
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// defaultRESTMaxBody is the largest REST trigger request body accepted when
+// the trigger config sets no max_body_bytes.
+const defaultRESTMaxBody = 10 << 20
+
+// errBodyTooLarge is returned by readRESTBody for a body over the limit,
+// before or after decompression.
+var errBodyTooLarge = errors.New("request body too large")
+
+// restMaxBody reads the optional "max_body_bytes" key of a REST trigger
+// config: the largest request body accepted, after decompression.
+func restMaxBody(config map[string]interface{}) (int64, error) {
+	raw, ok := config["max_body_bytes"]
+	if !ok || raw == nil {
+		return defaultRESTMaxBody, nil
+	}
+	n, ok := raw.(float64)
+	if !ok || n < 1 || n != float64(int64(n)) {
+		return 0, fmt.Errorf("max_body_bytes must be a positive whole number of bytes, got %v", raw)
+	}
+	return int64(n), nil
+}
+
+// readRESTBody reads the body of r, decompressing a gzip or deflate
+// Content-Encoding, and returns it with the HTTP status to answer when it
+// cannot be read: 413 over limit bytes, 415 for another encoding, 400 for a
+// corrupt one. A decompressed body loses its Content-Encoding header, so
+// trigger_data and captures describe the body the flow sees.
+func readRESTBody(w http.ResponseWriter, r *http.Request, limit int64) ([]byte, int, error) {
+	if r.Body == nil {
+		return nil, 0, nil
+	}
+	raw, err := io.ReadAll(http.MaxBytesReader(w, r.Body, limit))
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		return nil, http.StatusRequestEntityTooLarge, fmt.Errorf("%w: limit is %d bytes", errBodyTooLarge, limit)
+	}
+	if err != nil {
+		return nil, http.StatusBadRequest, fmt.Errorf("read request body: %w", err)
+	}
+
+	encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+	var dec io.Reader
+	switch encoding {
+	case "", "identity":
+		return raw, 0, nil
+	case "gzip", "x-gzip":
+		dec, err = gzip.NewReader(bytes.NewReader(raw))
+	case "deflate":
+		// "deflate" is zlib-wrapped per RFC 9110, but some clients send
+		// raw DEFLATE data.
+		dec, err = zlib.NewReader(bytes.NewReader(raw))
+		if err != nil {
+			dec, err = flate.NewReader(bytes.NewReader(raw)), nil
+		}
+	default:
+		return nil, http.StatusUnsupportedMediaType, fmt.Errorf("unsupported Content-Encoding %q; use gzip or deflate", encoding)
+	}
+	if err != nil {
+		return nil, http.StatusBadRequest, fmt.Errorf("decompress %s request body: %w", encoding, err)
+	}
+	// The limit applies to the decompressed size too, so a small compressed
+	// body cannot expand without bound.
+	body, err := io.ReadAll(io.LimitReader(dec, limit+1))
+	if err != nil {
+		return nil, http.StatusBadRequest, fmt.Errorf("decompress %s request body: %w", encoding, err)
+	}
+	if int64(len(body)) > limit {
+		return nil, http.StatusRequestEntityTooLarge, fmt.Errorf("%w: limit is %d bytes after decompression", errBodyTooLarge, limit)
+	}
+	r.Header.Del("Content-Encoding")
+	r.Header.Del("Content-Length")
+	return body, 0, nil
+}
+
+// parseRESTBody returns trigger_data.body for raw by its Content-Type:
+//
+//	JSON (or no Content-Type): the decoded value
+//	application/x-www-form-urlencoded: {field: value or [values]}
+//	multipart/form-data: the same, file parts as {filename, content_type, size, content}
+//	text/*, XML: the text
+//	anything else: the bytes, base64-encoded
+//
+// An empty body is an empty object.
+func parseRESTBody(contentType string, raw []byte) (interface{}, error) {
+	if len(raw) == 0 {
+		return map[string]interface{}{}, nil
+	}
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if contentType == "" {
+		mediaType, err = "application/json", nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid Content-Type %q: %w", contentType, err)
+	}
+
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		var body interface{}
+		if err := json.Unmarshal(raw, &body); err != nil {
+			return nil, fmt.Errorf("invalid JSON body: %w", err)
+		}
+		return body, nil
+	case mediaType == "application/x-www-form-urlencoded":
+		values, err := url.ParseQuery(string(raw))
+		if err != nil {
+			return nil, fmt.Errorf("invalid form body: %w", err)
+		}
+		return queryMap(values), nil
+	case mediaType == "multipart/form-data":
+		return parseMultipartBody(raw, params["boundary"])
+	case strings.HasPrefix(mediaType, "text/"), mediaType == "application/xml", strings.HasSuffix(mediaType, "+xml"):
+		return string(raw), nil
+	default:
+		return base64.StdEncoding.EncodeToString(raw), nil
+	}
+}
+
+// parseMultipartBody returns the fields of a multipart/form-data body. A
+// name given more than once maps to the list of its values.
+func parseMultipartBody(raw []byte, boundary string) (map[string]interface{}, error) {
+	if boundary == "" {
+		return nil, fmt.Errorf("invalid multipart body: no boundary in Content-Type")
+	}
+	out := map[string]interface{}{}
+	mr := multipart.NewReader(bytes.NewReader(raw), boundary)
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			return out, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid multipart body: %w", err)
+		}
+		content, err := io.ReadAll(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid multipart body: %w", err)
+		}
+		var v interface{} = string(content)
+		if p.FileName() != "" {
+			v = map[string]interface{}{
+				"filename":     p.FileName(),
+				"content_type": p.Header.Get("Content-Type"),
+				"size":         len(content),
+				"content":      base64.StdEncoding.EncodeToString(content),
+			}
+		}
+		name := p.FormName()
+		if name == "" {
+			continue
+		}
+		switch prev := out[name].(type) {
+		case nil:
+			out[name] = v
+		case []interface{}:
+			out[name] = append(prev, v)
+		default:
+			out[name] = []interface{}{prev, v}
+		}
+	}
+}
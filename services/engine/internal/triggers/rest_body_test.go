@@ -0,0 +1,139 @@
+package triggers
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// postREST deploys a REST trigger with config and posts body to its path with
+// headers, returning the response and the trigger data executed, if any.
+func postREST(t *testing.T, config map[string]interface{}, body []byte, headers map[string]string) (*httptest.ResponseRecorder, map[string]interface{}) {
+	t.Helper()
+	exec := &mockExecutor{}
+	trig := newRESTTrigger(exec)
+	require.NoError(t, trig.Start(context.Background(), buildProcess("p_rest_body", "rest", config)))
+	defer func() { _ = trig.Stop() }()
+
+	req := httptest.NewRequest(http.MethodPost, "/triggers"+config["path"].(string), bytes.NewReader(body))
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	GetRegistryHandler().ServeHTTP(rec, req)
+	if len(exec.executions) == 0 {
+		return rec, nil
+	}
+	return rec, exec.executions[0]
+}
+
+func gzipped(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, err := zw.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+// TestRESTTrigger_BodyByContentType verifies how each Content-Type and
+// Content-Encoding becomes trigger_data.body.
+func TestRESTTrigger_BodyByContentType(t *testing.T) {
+	config := map[string]interface{}{"path": "/body"}
+
+	var raw bytes.Buffer
+	zw, err := flate.NewWriter(&raw, flate.DefaultCompression)
+	require.NoError(t, err)
+	_, _ = zw.Write([]byte(`{"n":2}`))
+	require.NoError(t, zw.Close())
+
+	var form bytes.Buffer
+	mw := multipart.NewWriter(&form)
+	require.NoError(t, mw.WriteField("tag", "a"))
+	require.NoError(t, mw.WriteField("tag", "b"))
+	part, err := mw.CreateFormFile("file", "hello.txt")
+	require.NoError(t, err)
+	_, _ = part.Write([]byte("hello"))
+	require.NoError(t, mw.Close())
+
+	cases := []struct {
+		name    string
+		body    []byte
+		headers map[string]string
+		want    interface{}
+	}{
+		{"json", []byte(`{"n":1}`), map[string]string{"Content-Type": "application/json; charset=utf-8"}, map[string]interface{}{"n": float64(1)}},
+		{"json without content type", []byte(`[1]`), nil, []interface{}{float64(1)}},
+		{"empty", nil, nil, map[string]interface{}{}},
+		{"gzip", gzipped(t, []byte(`{"n":1}`)), map[string]string{"Content-Encoding": "gzip"}, map[string]interface{}{"n": float64(1)}},
+		{"raw deflate", raw.Bytes(), map[string]string{"Content-Encoding": "deflate"}, map[string]interface{}{"n": float64(2)}},
+		{"form", []byte("a=1&b=2&b=3"), map[string]string{"Content-Type": "application/x-www-form-urlencoded"},
+			map[string]interface{}{"a": "1", "b": []interface{}{"2", "3"}}},
+		{"multipart", form.Bytes(), map[string]string{"Content-Type": mw.FormDataContentType()}, map[string]interface{}{
+			"tag":  []interface{}{"a", "b"},
+			"file": map[string]interface{}{"filename": "hello.txt", "content_type": "application/octet-stream", "size": 5, "content": "aGVsbG8="},
+		}},
+		{"text", []byte("plain"), map[string]string{"Content-Type": "text/plain"}, "plain"},
+		{"xml", []byte("<a/>"), map[string]string{"Content-Type": "application/soap+xml"}, "<a/>"},
+		{"binary", []byte{0xff, 0x00}, map[string]string{"Content-Type": "application/octet-stream"}, "/wA="},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec, data := postREST(t, config, tc.body, tc.headers)
+			require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+			assert.Equal(t, tc.want, data["body"])
+			assert.NotContains(t, data["headers"], "Content-Encoding")
+		})
+	}
+}
+
+// TestRESTTrigger_BodyErrors verifies that unreadable bodies are refused
+// before the process runs.
+func TestRESTTrigger_BodyErrors(t *testing.T) {
+	config := map[string]interface{}{"path": "/limited", "max_body_bytes": float64(16)}
+
+	cases := []struct {
+		name    string
+		body    []byte
+		headers map[string]string
+		status  int
+	}{
+		{"invalid json", []byte(`{"n":`), nil, http.StatusBadRequest},
+		{"too large", []byte(strings.Repeat("x", 17)), map[string]string{"Content-Type": "text/plain"}, http.StatusRequestEntityTooLarge},
+		{"too large decompressed", gzipped(t, []byte(strings.Repeat("x", 1000))), map[string]string{"Content-Encoding": "gzip", "Content-Type": "text/plain"}, http.StatusRequestEntityTooLarge},
+		{"corrupt gzip", []byte("not gzip"), map[string]string{"Content-Encoding": "gzip"}, http.StatusBadRequest},
+		{"unsupported encoding", []byte("x"), map[string]string{"Content-Encoding": "br"}, http.StatusUnsupportedMediaType},
+		{"multipart without boundary", []byte("x"), map[string]string{"Content-Type": "multipart/form-data"}, http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec, data := postREST(t, config, tc.body, tc.headers)
+			assert.Equal(t, tc.status, rec.Code)
+			assert.Contains(t, rec.Body.String(), `"error"`)
+			assert.Nil(t, data, "the process does not run")
+		})
+	}
+}
+
+func TestRESTMaxBody(t *testing.T) {
+	n, err := restMaxBody(map[string]interface{}{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(defaultRESTMaxBody), n)
+	n, err = restMaxBody(map[string]interface{}{"max_body_bytes": float64(1024)})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1024), n)
+	for _, bad := range []interface{}{float64(0), float64(1.5), "1kb"} {
+		_, err := restMaxBody(map[string]interface{}{"max_body_bytes": bad})
+		assert.Error(t, err, bad)
+	}
+}
@@ -0,0 +1,246 @@
+package triggers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"flowjs-works/engine/internal/logging"
+	"flowjs-works/engine/internal/models"
+
+	"github.com/dop251/goja"
+)
+
+// responseExprTimeout bounds the evaluation of a single response expression.
+const responseExprTimeout = time.Second
+
+// restResponseMapping shapes the HTTP response of a REST trigger from the
+// finished execution context. It is configured under trigger.config.response:
+//
+//	"response": {
+//	  "status":  "$.nodes.validate.output.ok ? 200 : 400",
+//	  "body":    "$.nodes.build_reply.output",
+//	  "headers": {"X-Request-Id": "$.trigger.headers['X-Request-Id']", "Cache-Control": "no-store"}
+//	}
+//
+// Any string starting with "$" is a JavaScript expression evaluated with "$"
+// bound to {execution_id, trigger, nodes}; every other value is a literal.
+// A body given as an object or array is a template whose string leaves are
+// resolved the same way. Without a mapping the legacy shape
+// {execution_id, nodes} is returned.
+type restResponseMapping struct {
+	status  interface{}
+	body    interface{}
+	headers map[string]string
+	hasBody bool
+}
+
+// parseRESTResponseMapping reads and validates trigger.config.response.
+// It returns nil when no mapping is configured. Expressions are compiled
+// here so that syntax errors fail the deployment, not the first request.
+func parseRESTResponseMapping(config map[string]interface{}) (*restResponseMapping, error) {
+	raw, ok := config["response"]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+	spec, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("response must be an object")
+	}
+	m := &restResponseMapping{status: spec["status"], headers: map[string]string{}}
+	m.body, m.hasBody = spec["body"]
+	if hdrs, ok := spec["headers"].(map[string]interface{}); ok {
+		for k, v := range hdrs {
+			s, isStr := v.(string)
+			if !isStr {
+				return nil, fmt.Errorf("response.headers.%s must be a string", k)
+			}
+			m.headers[k] = s
+		}
+	}
+	if err := compileTemplate(m.status); err != nil {
+		return nil, fmt.Errorf("response.status: %w", err)
+	}
+	if err := compileTemplate(m.body); err != nil {
+		return nil, fmt.Errorf("response.body: %w", err)
+	}
+	for k, v := range m.headers {
+		if err := compileTemplate(v); err != nil {
+			return nil, fmt.Errorf("response.headers.%s: %w", k, err)
+		}
+	}
+	return m, nil
+}
+
+// write renders the mapping against execCtx and writes the HTTP response.
+func (m *restResponseMapping) write(w http.ResponseWriter, execCtx *models.ExecutionContext) error {
+	root, err := responseRoot(execCtx)
+	if err != nil {
+		return err
+	}
+	status, err := m.resolveStatus(root)
+	if err != nil {
+		return err
+	}
+	var body interface{}
+	if m.hasBody {
+		if body, err = resolveTemplate(m.body, root); err != nil {
+			return fmt.Errorf("response.body: %w", err)
+		}
+	} else {
+		body = map[string]interface{}{"execution_id": execCtx.ExecutionID, "nodes": execCtx.Nodes}
+	}
+	for k, v := range m.headers {
+		resolved, err := resolveTemplate(v, root)
+		if err != nil {
+			return fmt.Errorf("response.headers.%s: %w", k, err)
+		}
+		w.Header().Set(k, fmt.Sprint(resolved))
+	}
+	// The status line is committed from here on, so write errors can only be logged.
+	if err := writeMappedBody(w, status, body); err != nil {
+		slog.Error("rest_trigger: write mapped response", logging.KeyError, err)
+	}
+	return nil
+}
+
+// resolveStatus evaluates the status template; absent means 200.
+func (m *restResponseMapping) resolveStatus(root map[string]interface{}) (int, error) {
+	if m.status == nil {
+		return http.StatusOK, nil
+	}
+	v, err := resolveTemplate(m.status, root)
+	if err != nil {
+		return 0, fmt.Errorf("response.status: %w", err)
+	}
+	var code int
+	switch n := v.(type) {
+	case float64:
+		code = int(n)
+	case int64:
+		code = int(n)
+	default:
+		return 0, fmt.Errorf("response.status: expected a number, got %T", v)
+	}
+	if code < 100 || code > 599 {
+		return 0, fmt.Errorf("response.status: %d is not a valid HTTP status", code)
+	}
+	return code, nil
+}
+
+// writeMappedBody writes a string body verbatim when the mapping set a
+// non-JSON Content-Type; everything else is JSON-encoded.
+func writeMappedBody(w http.ResponseWriter, status int, body interface{}) error {
+	ct := w.Header().Get("Content-Type")
+	if s, ok := body.(string); ok && ct != "" && !strings.Contains(ct, "json") {
+		w.WriteHeader(status)
+		_, err := w.Write([]byte(s))
+		return err
+	}
+	if ct == "" {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.WriteHeader(status)
+	return json.NewEncoder(w).Encode(body)
+}
+
+// responseRoot builds the plain JSON view bound to "$" in expressions.
+func responseRoot(execCtx *models.ExecutionContext) (map[string]interface{}, error) {
+	b, err := json.Marshal(map[string]interface{}{
+		"execution_id": execCtx.ExecutionID,
+		"trigger":      execCtx.Trigger,
+		"nodes":        execCtx.Nodes,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("response: marshal execution context: %w", err)
+	}
+	var root map[string]interface{}
+	if err := json.Unmarshal(b, &root); err != nil {
+		return nil, fmt.Errorf("response: unmarshal execution context: %w", err)
+	}
+	return root, nil
+}
+
+// isExpression reports whether a template string must be evaluated.
+func isExpression(s string) bool {
+	return strings.HasPrefix(strings.TrimSpace(s), "$")
+}
+
+// compileTemplate checks every expression inside tmpl for syntax errors.
+func compileTemplate(tmpl interface{}) error {
+	switch v := tmpl.(type) {
+	case string:
+		if isExpression(v) {
+			if _, err := goja.Compile("response", v, true); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		for _, item := range v {
+			if err := compileTemplate(item); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if err := compileTemplate(item); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// resolveTemplate evaluates expressions in tmpl, recursing into objects and arrays.
+func resolveTemplate(tmpl interface{}, root map[string]interface{}) (interface{}, error) {
+	switch v := tmpl.(type) {
+	case string:
+		if !isExpression(v) {
+			return v, nil
+		}
+		return evalResponseExpr(v, root)
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			r, err := resolveTemplate(item, root)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = r
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			r, err := resolveTemplate(item, root)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = r
+		}
+		return out, nil
+	default:
+		return v, nil
+	}
+}
+
+// evalResponseExpr runs expr in a fresh goja VM with "$" bound to root.
+func evalResponseExpr(expr string, root map[string]interface{}) (interface{}, error) {
+	vm := goja.New()
+	if err := vm.Set("$", root); err != nil {
+		return nil, err
+	}
+	timer := time.AfterFunc(responseExprTimeout, func() { vm.Interrupt("timeout") })
+	defer timer.Stop()
+	val, err := vm.RunString(expr)
+	if err != nil {
+		return nil, fmt.Errorf("evaluate %q: %w", expr, err)
+	}
+	if goja.IsUndefined(val) || goja.IsNull(val) {
+		return nil, nil
+	}
+	return val.Export(), nil
+}
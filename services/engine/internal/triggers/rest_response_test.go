@@ -0,0 +1,131 @@
+package triggers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"flowjs-works/engine/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testExecCtx() *models.ExecutionContext {
+	ctx := models.NewExecutionContext("exec-42")
+	ctx.SetTriggerData(map[string]interface{}{"headers": map[string]interface{}{"X-Request-Id": "req-1"}})
+	ctx.SetNodeOutput("validate", map[string]interface{}{"ok": false, "reason": "missing email"})
+	ctx.SetNodeOutput("reply", map[string]interface{}{"message": "hello"})
+	return ctx
+}
+
+func TestParseRESTResponseMapping_None(t *testing.T) {
+	m, err := parseRESTResponseMapping(map[string]interface{}{"path": "/x"})
+	require.NoError(t, err)
+	assert.Nil(t, m)
+}
+
+func TestParseRESTResponseMapping_SyntaxErrorFailsDeploy(t *testing.T) {
+	_, err := parseRESTResponseMapping(map[string]interface{}{
+		"response": map[string]interface{}{"status": "$.nodes.a.output.code ? ("},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "response.status")
+}
+
+func TestRESTResponseMapping_StatusBodyHeaders(t *testing.T) {
+	m, err := parseRESTResponseMapping(map[string]interface{}{
+		"response": map[string]interface{}{
+			"status": "$.nodes.validate.output.ok ? 200 : 400",
+			"body": map[string]interface{}{
+				"error": "$.nodes.validate.output.reason",
+				"id":    "$.execution_id",
+				"kind":  "validation",
+			},
+			"headers": map[string]interface{}{
+				"X-Request-Id":  "$.trigger.headers['X-Request-Id']",
+				"Cache-Control": "no-store",
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	require.NoError(t, m.write(rec, testExecCtx()))
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, "req-1", rec.Header().Get("X-Request-Id"))
+	assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, map[string]interface{}{"error": "missing email", "id": "exec-42", "kind": "validation"}, body)
+}
+
+func TestRESTResponseMapping_NodeOutputBody(t *testing.T) {
+	m, err := parseRESTResponseMapping(map[string]interface{}{
+		"response": map[string]interface{}{"body": "$.nodes.reply.output"},
+	})
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	require.NoError(t, m.write(rec, testExecCtx()))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"message":"hello"}`, rec.Body.String())
+}
+
+func TestRESTResponseMapping_PlainTextBody(t *testing.T) {
+	m, err := parseRESTResponseMapping(map[string]interface{}{
+		"response": map[string]interface{}{
+			"status":  float64(202),
+			"body":    "$.nodes.reply.output.message",
+			"headers": map[string]interface{}{"Content-Type": "text/plain"},
+		},
+	})
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	require.NoError(t, m.write(rec, testExecCtx()))
+
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Equal(t, "hello", rec.Body.String())
+}
+
+func TestRESTResponseMapping_InvalidStatus(t *testing.T) {
+	m, err := parseRESTResponseMapping(map[string]interface{}{
+		"response": map[string]interface{}{"status": "$.nodes.reply.output.message"},
+	})
+	require.NoError(t, err)
+
+	err = m.write(httptest.NewRecorder(), testExecCtx())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "expected a number")
+}
+
+// TestRESTTrigger_ResponseMapping exercises the mapping through the registry.
+func TestRESTTrigger_ResponseMapping(t *testing.T) {
+	exec := &mockExecutor{}
+	tr := newRESTTrigger(exec)
+
+	const dslPath = "/test-rest-response-mapping"
+	proc := buildProcess("rest-map", "rest", map[string]interface{}{
+		"path":     dslPath,
+		"response": map[string]interface{}{"status": float64(201), "body": map[string]interface{}{"id": "$.execution_id"}},
+	})
+	require.NoError(t, tr.Start(context.Background(), proc))
+	t.Cleanup(func() { _ = tr.Stop() })
+
+	srv := httptest.NewServer(GetRegistryHandler())
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL+"/triggers"+dslPath, "application/json", strings.NewReader(`{}`))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	var body map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, "test-exec-id", body["id"])
+}
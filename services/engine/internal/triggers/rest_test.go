@@ -0,0 +1,139 @@
+package triggers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"flowjs-works/engine/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRoutePattern(t *testing.T) {
+	p, err := parseRoutePattern("/orders/{orderId}/items/{itemId}")
+	require.NoError(t, err)
+	assert.True(t, p.templated())
+	assert.Equal(t, "/orders/{}/items/{}", p.shape())
+
+	p, err = parseRoutePattern("/v1/rest")
+	require.NoError(t, err)
+	assert.False(t, p.templated())
+
+	for _, path := range []string{"/orders/id-{orderId}", "/orders/{}", "/orders/{id}/{id}", "/orders/{a{b}}"} {
+		_, err := parseRoutePattern(path)
+		assert.Error(t, err, path)
+	}
+	_, _, err = restTriggerConfig(map[string]interface{}{"path": "/orders/{id"})
+	assert.Error(t, err)
+}
+
+// TestRESTRegistry_TemplatedRoutes verifies parameter extraction, that exact
+// paths and more literal templates win, and that routes deregister cleanly.
+func TestRESTRegistry_TemplatedRoutes(t *testing.T) {
+	reg := newRESTRegistry()
+	var hit string
+	var got map[string]interface{}
+	handler := func(name string) restHandler {
+		return func(w http.ResponseWriter, r *http.Request, params map[string]interface{}) {
+			hit, got = name, params
+		}
+	}
+	reg.register("/orders/{orderId}", http.MethodGet, routeOwner{}, handler("order"))
+	reg.register("/orders/export", http.MethodGet, routeOwner{}, handler("export"))
+	reg.register("/orders/{orderId}/items/{itemId}", http.MethodGet, routeOwner{}, handler("item"))
+	reg.register("/{tenant}/items/{itemId}", http.MethodGet, routeOwner{}, handler("tenant_item"))
+
+	serve := func(method, target string) int {
+		rec := httptest.NewRecorder()
+		reg.ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+		return rec.Code
+	}
+
+	require.Equal(t, http.StatusOK, serve(http.MethodGet, "/triggers/orders/42"))
+	assert.Equal(t, "order", hit)
+	assert.Equal(t, map[string]interface{}{"orderId": "42"}, got)
+
+	serve(http.MethodGet, "/triggers/orders/export")
+	assert.Equal(t, "export", hit)
+	assert.Empty(t, got)
+
+	serve(http.MethodGet, "/triggers/orders/a%2Fb/items/7")
+	assert.Equal(t, "item", hit)
+	assert.Equal(t, map[string]interface{}{"orderId": "a/b", "itemId": "7"}, got)
+
+	serve(http.MethodGet, "/triggers/acme/items/7")
+	assert.Equal(t, "tenant_item", hit)
+
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/triggers/orders/42/extra"))
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/triggers/orders//items/7"))
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodDelete, "/triggers/orders/42"))
+
+	reg.deregister("/orders/{id}", http.MethodGet, "")
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/triggers/orders/42"))
+}
+
+// TestRESTTrigger_ParamsAndQuery verifies trigger_data.params and trigger_data.query.
+func TestRESTTrigger_ParamsAndQuery(t *testing.T) {
+	exec := &mockExecutor{}
+	trig := newRESTTrigger(exec)
+	proc := buildProcess("p_rest_params", "rest", map[string]interface{}{"path": "/customers/{customerId}/orders", "method": "GET"})
+	require.NoError(t, trig.Start(context.Background(), proc))
+	defer func() { _ = trig.Stop() }()
+
+	rec := httptest.NewRecorder()
+	GetRegistryHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/triggers/customers/c-9/orders?status=open&tag=a&tag=b", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Len(t, exec.executions, 1)
+	data := exec.executions[0]
+	assert.Equal(t, map[string]interface{}{"customerId": "c-9"}, data["params"])
+	assert.Equal(t, map[string]interface{}{"status": "open", "tag": []interface{}{"a", "b"}}, data["query"])
+}
+
+// TestRESTTrigger_HeaderValuesURLAndRemoteAddr verifies that repeated headers
+// keep every value in header_values while headers keeps the first, and that
+// the request target and remote address reach the trigger data.
+func TestRESTTrigger_HeaderValuesURLAndRemoteAddr(t *testing.T) {
+	exec := &mockExecutor{}
+	trig := newRESTTrigger(exec)
+	proc := buildProcess("p_rest_headers", "rest", map[string]interface{}{"path": "/rest-headers"})
+	require.NoError(t, trig.Start(context.Background(), proc))
+	defer func() { _ = trig.Stop() }()
+
+	req := httptest.NewRequest(http.MethodPost, "/triggers/rest-headers?a=1&a=2", strings.NewReader(`{}`))
+	req.Header.Add("Accept", "application/json")
+	req.Header.Add("Accept", "text/plain")
+	req.RemoteAddr = "203.0.113.7:51234"
+	rec := httptest.NewRecorder()
+	GetRegistryHandler().ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Len(t, exec.executions, 1)
+	data := exec.executions[0]
+	assert.Equal(t, "application/json", data["headers"].(map[string]interface{})["Accept"])
+	assert.Equal(t, []interface{}{"application/json", "text/plain"}, data["header_values"].(map[string]interface{})["Accept"])
+	assert.Equal(t, "/triggers/rest-headers?a=1&a=2", data["url"])
+	assert.Equal(t, "203.0.113.7:51234", data["remote_addr"])
+}
+
+// TestRESTTrigger_CancelledExecutionTimesOut verifies that an execution
+// stopped by the request budget answers 504 with its execution id.
+func TestRESTTrigger_CancelledExecutionTimesOut(t *testing.T) {
+	exec := &mockExecutor{err: fmt.Errorf("%w before node n1: %w", models.ErrExecutionCancelled, context.DeadlineExceeded)}
+	trig := newRESTTrigger(exec)
+	proc := buildProcess("p_rest_timeout", "rest", map[string]interface{}{"path": "/rest-timeout"})
+	require.NoError(t, trig.Start(context.Background(), proc))
+	defer func() { _ = trig.Stop() }()
+
+	rec := httptest.NewRecorder()
+	GetRegistryHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/triggers/rest-timeout", strings.NewReader(`{}`)))
+	assert.Equal(t, http.StatusGatewayTimeout, rec.Code)
+	var body map[string]string
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, "test-exec-id", body["execution_id"])
+	assert.Contains(t, body["error"], "execution cancelled")
+}
@@ -3,15 +3,20 @@
 package triggers
 
 import (
+	"bytes"
 	"context"
 	"encoding/xml"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"net/http"
 	"strings"
 	"sync"
 
+	"flowjs-works/engine/internal/logging"
 	"flowjs-works/engine/internal/models"
+	"flowjs-works/engine/internal/tenant"
 )
 
 // soapTrigger registers a dynamic HTTP route on the engine's shared mux so
@@ -30,14 +35,32 @@ import (
 //     Body element is forwarded to the executor as trigger_data["body"]. The
 //     SOAPAction header (or the HTTP method when SOAPAction is absent) is
 //     forwarded as trigger_data["method"]; HTTP headers are included under
-//     trigger_data["headers"].
-//  4. On success a minimal SOAP envelope containing the execution ID is
+//     trigger_data["headers"]. The operation element of the Body is
+//     converted to a JSON map in trigger_data["payload"] and its operation
+//     name (from the WSDL, or the element name) in trigger_data["operation"].
+//  4. With "validate": true the operation element is checked against the
+//     WSDL schema, and with "operations" the execution starts at the node
+//     routed to the operation; a mismatch is a Client fault with HTTP 400.
+//  5. On success a minimal SOAP envelope containing the execution ID is
 //     returned; on execution failure a SOAP Fault with HTTP 500 is returned.
 type soapTrigger struct {
 	executor  Executor
 	processID string
 	path      string
 	wsdl      string
+
+	// service is the parsed wsdl, nil without one; validate checks requests
+	// against its schema. routes holds, per operation, the process reduced
+	// to the start node the operation is routed to; nil runs the whole
+	// process for every operation.
+	service  *wsdlService
+	validate bool
+	routes   map[string]*models.Process
+
+	// capturer stores inbound requests for replay when the trigger config
+	// sets capture_days; nil disables capture.
+	capturer    RequestCapturer
+	captureDays int
 }
 
 func newSOAPTrigger(executor Executor) *soapTrigger {
@@ -51,6 +74,12 @@ func (t *soapTrigger) Start(_ context.Context, proc *models.Process) error {
 	if err != nil {
 		return fmt.Errorf("soap_trigger: %w", err)
 	}
+	if t.captureDays, err = captureDays(proc.Trigger.Config); err != nil {
+		return fmt.Errorf("soap_trigger: %w", err)
+	}
+
+	// Non-default workspaces are isolated under /ws/{workspace}, as for REST.
+	path = tenant.RoutePrefix(proc.Definition.Workspace) + path
 
 	t.processID = proc.Definition.ID
 	t.path = path
@@ -61,8 +90,11 @@ func (t *soapTrigger) Start(_ context.Context, proc *models.Process) error {
 	// the cron, REST, and RabbitMQ triggers and prevents surprises if the
 	// caller modifies proc after Deploy returns.
 	procCopy := *proc
-	globalSOAPRegistry.register(path, t.buildHandler(&procCopy))
-	log.Printf("soap_trigger: registered POST %s for process %q", path, proc.Definition.ID)
+	if err := t.configureOperations(&procCopy); err != nil {
+		return fmt.Errorf("soap_trigger: %w", err)
+	}
+	globalSOAPRegistry.register(path, newRouteOwner(proc), t.buildHandler(&procCopy))
+	slog.Info("soap_trigger: registered route", "path", path, logging.KeyProcessID, proc.Definition.ID)
 	return nil
 }
 
@@ -89,8 +121,17 @@ func (t *soapTrigger) buildHandler(proc *models.Process) http.HandlerFunc {
 		// Parse the SOAP envelope. encoding/xml matches on local name only
 		// when no namespace URI is specified in the struct tag, making this
 		// compatible with both SOAP 1.1 and 1.2 envelopes.
+		raw, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeSoapFault(w, http.StatusBadRequest, "Client",
+				fmt.Sprintf("read request body: %v", err))
+			return
+		}
+		if t.captureDays > 0 {
+			captureRequest(t.capturer, t.captureDays, proc, "soap", r, raw)
+		}
 		var env soapRequestEnvelope
-		if err := xml.NewDecoder(r.Body).Decode(&env); err != nil {
+		if err := xml.NewDecoder(bytes.NewReader(raw)).Decode(&env); err != nil {
 			writeSoapFault(w, http.StatusBadRequest, "Client",
 				fmt.Sprintf("invalid SOAP envelope: %v", err))
 			return
@@ -120,9 +161,23 @@ func (t *soapTrigger) buildHandler(proc *models.Process) http.HandlerFunc {
 			"body":    string(env.Body.Content),
 		}
 
-		execCtx, execErr := t.executor.Execute(proc, triggerData)
+		target, fault := t.dispatch(proc, env.Body.Content, soapAction, triggerData)
+		if fault != "" {
+			writeSoapFault(w, http.StatusBadRequest, "Client", fault)
+			return
+		}
+
+		execCtx, execErr := ExecuteContext(r.Context(), t.executor, target, triggerData)
 		if execErr != nil {
-			log.Printf("soap_trigger: execution error for %q: %v", t.processID, execErr)
+			slog.Error("soap_trigger: execution failed", logging.KeyProcessID, t.processID, logging.KeyError, execErr)
+			if errors.Is(execErr, models.ErrExecutionCancelled) {
+				msg := execErr.Error()
+				if execCtx != nil {
+					msg += " (execution_id " + execCtx.ExecutionID + ")"
+				}
+				writeSoapFault(w, http.StatusGatewayTimeout, "Server", msg)
+				return
+			}
 			writeSoapFault(w, http.StatusInternalServerError, "Server", execErr.Error())
 			return
 		}
@@ -131,11 +186,151 @@ func (t *soapTrigger) buildHandler(proc *models.Process) http.HandlerFunc {
 	}
 }
 
+// configureOperations parses the WSDL and the operation routing of the
+// trigger config.
+func (t *soapTrigger) configureOperations(proc *models.Process) error {
+	config := proc.Trigger.Config
+	t.validate, _ = config["validate"].(bool)
+	if t.wsdl != "" {
+		svc, err := parseWSDL(t.wsdl)
+		switch {
+		case err == nil:
+			t.service = svc
+		case t.validate:
+			return err
+		default:
+			// The WSDL is still served at ?wsdl; requests are just not
+			// matched against it.
+			slog.Warn("soap_trigger: WSDL not understood; operations are named after the body element", logging.KeyProcessID, proc.Definition.ID, logging.KeyError, err)
+		}
+	}
+	if t.validate && t.service == nil {
+		return fmt.Errorf("\"validate\" requires a \"wsdl\"")
+	}
+
+	raw, ok := config["operations"].(map[string]interface{})
+	if !ok || len(raw) == 0 {
+		return nil
+	}
+	t.routes = make(map[string]*models.Process, len(raw))
+	for op, v := range raw {
+		start, _ := v.(string)
+		if start == "" {
+			return fmt.Errorf("operations.%s: start node id expected", op)
+		}
+		if t.service != nil && t.service.operations[op] == nil {
+			return fmt.Errorf("operations.%s: operation is not in the WSDL", op)
+		}
+		routed, err := routedProcess(proc, start)
+		if err != nil {
+			return fmt.Errorf("operations.%s: %w", op, err)
+		}
+		t.routes[op] = routed
+	}
+	return nil
+}
+
+// dispatch identifies the operation of a request, adds "operation" and
+// "payload" to triggerData and returns the process to execute, or a fault
+// message when the request must be refused.
+func (t *soapTrigger) dispatch(proc *models.Process, body []byte, soapAction string, triggerData map[string]interface{}) (*models.Process, string) {
+	node, err := parseBodyElement(body)
+	if err != nil {
+		return nil, err.Error()
+	}
+	if node == nil {
+		if t.validate || t.routes != nil {
+			return nil, "empty SOAP body"
+		}
+		return proc, ""
+	}
+
+	operation := node.name
+	var decl *xsdElement
+	if t.service != nil {
+		if op := t.service.operation(soapAction, node.name); op != nil {
+			operation = op.name
+		}
+		decl = t.service.elements[node.name]
+		if t.validate {
+			if err := t.service.validate(node); err != nil {
+				return nil, fmt.Sprintf("request does not match the WSDL: %v", err)
+			}
+		}
+	}
+	triggerData["operation"] = operation
+	triggerData["payload"] = t.service.toJSON(node, decl)
+
+	if t.routes == nil {
+		return proc, ""
+	}
+	target := t.routes[operation]
+	if target == nil {
+		return nil, fmt.Sprintf("operation %q is not supported by this endpoint", operation)
+	}
+	return target, ""
+}
+
+// routedProcess returns a copy of proc reduced to start and the nodes
+// reachable from it, so that executions begin at start.
+func routedProcess(proc *models.Process, start string) (*models.Process, error) {
+	nodes := make(map[string]*models.Node, len(proc.Nodes))
+	for i := range proc.Nodes {
+		nodes[proc.Nodes[i].ID] = &proc.Nodes[i]
+	}
+	if nodes[start] == nil {
+		return nil, fmt.Errorf("unknown start node %q", start)
+	}
+	next := make(map[string][]string)
+	for _, tr := range proc.Transitions {
+		next[tr.From] = append(next[tr.From], tr.Destinations()...)
+	}
+	reachable := map[string]bool{start: true}
+	queue := []string{start}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, targets := range [][]string{next[id], nodes[id].Next} {
+			for _, to := range targets {
+				if nodes[to] != nil && !reachable[to] {
+					reachable[to] = true
+					queue = append(queue, to)
+				}
+			}
+		}
+	}
+
+	routed := *proc
+	routed.Nodes = nil
+	for _, node := range proc.Nodes {
+		if reachable[node.ID] {
+			routed.Nodes = append(routed.Nodes, node)
+		}
+	}
+	routed.Transitions = nil
+	for _, tr := range proc.Transitions {
+		if (reachable[tr.From] || nodes[tr.From] == nil) && reachesAny(reachable, tr.Destinations()) {
+			routed.Transitions = append(routed.Transitions, tr)
+		}
+	}
+	return &routed, nil
+}
+
+// reachesAny reports whether one of ids is reachable.
+func reachesAny(reachable map[string]bool, ids []string) bool {
+	for _, id := range ids {
+		if reachable[id] {
+			return true
+		}
+	}
+	return false
+}
+
 // Stop deregisters the route from the shared SOAP registry.
 func (t *soapTrigger) Stop() error {
 	if t.path != "" {
-		globalSOAPRegistry.deregister(t.path)
-		log.Printf("soap_trigger: deregistered %s for process %q", t.path, t.processID)
+		globalSOAPRegistry.deregister(t.path, t.processID)
+		slog.Info("soap_trigger: deregistered route", "path", t.path, logging.KeyProcessID, t.processID)
 	}
 	return nil
 }
@@ -144,7 +339,8 @@ func (t *soapTrigger) Stop() error {
 func (t *soapTrigger) Type() string { return "soap" }
 
 // soapTriggerConfig extracts and validates SOAP trigger config fields.
-// path is required; wsdl is optional (static WSDL document served at ?wsdl).
+// path is required; wsdl is optional (static WSDL document served at ?wsdl,
+// also used to name operations and, with validate, check requests).
 func soapTriggerConfig(config map[string]interface{}) (path, wsdl string, err error) {
 	if config == nil {
 		return "", "", fmt.Errorf("trigger config is nil; expected {\"path\":\"...\"}")
@@ -167,24 +363,44 @@ func soapTriggerConfig(config map[string]interface{}) (path, wsdl string, err er
 type soapRegistryImpl struct {
 	mu       sync.RWMutex
 	handlers map[string]http.HandlerFunc
+	owners   map[string]routeOwner
 }
 
 func newSOAPRegistry() *soapRegistryImpl {
-	return &soapRegistryImpl{handlers: make(map[string]http.HandlerFunc)}
+	return &soapRegistryImpl{handlers: make(map[string]http.HandlerFunc), owners: make(map[string]routeOwner)}
 }
 
 var globalSOAPRegistry = newSOAPRegistry()
 
-func (r *soapRegistryImpl) register(path string, h http.HandlerFunc) {
+func (r *soapRegistryImpl) register(path string, owner routeOwner, h http.HandlerFunc) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	owner.path = path
 	r.handlers[path] = h
+	r.owners[path] = owner
 }
 
-func (r *soapRegistryImpl) deregister(path string) {
+// deregister removes the endpoint of path when processID owns it, as the
+// REST registry does.
+func (r *soapRegistryImpl) deregister(path, processID string) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	if owner, ok := r.owners[path]; ok && owner.processID != processID {
+		return
+	}
 	delete(r.handlers, path)
+	delete(r.owners, path)
+}
+
+// routes lists the registered endpoints.
+func (r *soapRegistryImpl) routes() []Route {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]Route, 0, len(r.owners))
+	for _, owner := range r.owners {
+		out = append(out, owner.route("soap", http.MethodPost))
+	}
+	return out
 }
 
 // ServeHTTP dispatches the incoming request to the handler registered for
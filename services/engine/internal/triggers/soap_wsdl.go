@@ -0,0 +1,428 @@
+package triggers
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// wsdlService is what the SOAP trigger needs from a WSDL 1.1 document: its
+// operations, how to recognise them in a request, and the XSD elements
+// their requests must match. Only the common document/literal subset of XSD
+// is understood: global elements, named and inline complex types with
+// sequence, all or choice groups, minOccurs/maxOccurs and built-in simple
+// types. Anything else (attributes, extensions, imports) is accepted as is.
+type wsdlService struct {
+	operations map[string]*wsdlOperation // by operation name
+	byElement  map[string]*wsdlOperation // by request element local name
+	byAction   map[string]*wsdlOperation // by SOAPAction
+	elements   map[string]*xsdElement    // global elements
+	types      map[string]*xsdType       // named complex types
+}
+
+// wsdlOperation is one operation of a portType.
+type wsdlOperation struct {
+	name    string
+	action  string
+	element string // request element, "" for rpc-style messages
+}
+
+// xsdElement is an element declaration. max is -1 for "unbounded"; ref is
+// set for references to a global element, which holds the type.
+type xsdElement struct {
+	name     string
+	ref      bool
+	typeName string
+	min, max int
+	complex  *xsdType
+}
+
+// xsdType is a complex type: the child elements it allows, in any order.
+type xsdType struct {
+	elements []*xsdElement
+}
+
+// ── WSDL document shape (local names only, like soapRequestEnvelope) ────────
+
+type wsdlDefinitions struct {
+	Types struct {
+		Schemas []xsdSchemaDef `xml:"schema"`
+	} `xml:"types"`
+	Messages []struct {
+		Name  string `xml:"name,attr"`
+		Parts []struct {
+			Element string `xml:"element,attr"`
+		} `xml:"part"`
+	} `xml:"message"`
+	PortTypes []struct {
+		Operations []struct {
+			Name  string `xml:"name,attr"`
+			Input struct {
+				Message string `xml:"message,attr"`
+			} `xml:"input"`
+		} `xml:"operation"`
+	} `xml:"portType"`
+	Bindings []struct {
+		Operations []struct {
+			Name string `xml:"name,attr"`
+			SOAP struct {
+				Action string `xml:"soapAction,attr"`
+			} `xml:"operation"`
+		} `xml:"operation"`
+	} `xml:"binding"`
+}
+
+type xsdSchemaDef struct {
+	Elements     []xsdElementDef     `xml:"element"`
+	ComplexTypes []xsdComplexTypeDef `xml:"complexType"`
+}
+
+type xsdElementDef struct {
+	Name        string             `xml:"name,attr"`
+	Type        string             `xml:"type,attr"`
+	Ref         string             `xml:"ref,attr"`
+	MinOccurs   string             `xml:"minOccurs,attr"`
+	MaxOccurs   string             `xml:"maxOccurs,attr"`
+	ComplexType *xsdComplexTypeDef `xml:"complexType"`
+}
+
+type xsdComplexTypeDef struct {
+	Name     string       `xml:"name,attr"`
+	Sequence *xsdGroupDef `xml:"sequence"`
+	All      *xsdGroupDef `xml:"all"`
+	Choice   *xsdGroupDef `xml:"choice"`
+}
+
+type xsdGroupDef struct {
+	Elements []xsdElementDef `xml:"element"`
+}
+
+// parseWSDL reads the operations and request schemas of a WSDL 1.1 document.
+func parseWSDL(doc string) (*wsdlService, error) {
+	var defs wsdlDefinitions
+	if err := xml.Unmarshal([]byte(doc), &defs); err != nil {
+		return nil, fmt.Errorf("invalid WSDL: %w", err)
+	}
+	svc := &wsdlService{
+		operations: make(map[string]*wsdlOperation),
+		byElement:  make(map[string]*wsdlOperation),
+		byAction:   make(map[string]*wsdlOperation),
+		elements:   make(map[string]*xsdElement),
+		types:      make(map[string]*xsdType),
+	}
+	for _, schema := range defs.Types.Schemas {
+		for _, ct := range schema.ComplexTypes {
+			if ct.Name != "" {
+				svc.types[ct.Name] = svc.complexType(&ct)
+			}
+		}
+		for _, el := range schema.Elements {
+			svc.elements[el.Name] = svc.element(el)
+		}
+	}
+
+	inputElement := make(map[string]string, len(defs.Messages))
+	for _, m := range defs.Messages {
+		if len(m.Parts) == 1 && m.Parts[0].Element != "" {
+			inputElement[m.Name] = localName(m.Parts[0].Element)
+		}
+	}
+	for _, pt := range defs.PortTypes {
+		for _, op := range pt.Operations {
+			o := &wsdlOperation{name: op.Name, element: inputElement[localName(op.Input.Message)]}
+			svc.operations[op.Name] = o
+			if o.element != "" {
+				svc.byElement[o.element] = o
+			}
+		}
+	}
+	for _, b := range defs.Bindings {
+		for _, op := range b.Operations {
+			if o := svc.operations[op.Name]; o != nil && op.SOAP.Action != "" {
+				o.action = op.SOAP.Action
+				svc.byAction[op.SOAP.Action] = o
+			}
+		}
+	}
+	if len(svc.operations) == 0 {
+		return nil, errors.New("invalid WSDL: no portType operations")
+	}
+	return svc, nil
+}
+
+func (s *wsdlService) element(def xsdElementDef) *xsdElement {
+	el := &xsdElement{name: def.Name, typeName: localName(def.Type), min: 1, max: 1}
+	if def.Ref != "" {
+		el.name, el.ref = localName(def.Ref), true
+	}
+	if def.MinOccurs != "" {
+		el.min, _ = strconv.Atoi(def.MinOccurs)
+	}
+	switch def.MaxOccurs {
+	case "":
+	case "unbounded":
+		el.max = -1
+	default:
+		el.max, _ = strconv.Atoi(def.MaxOccurs)
+	}
+	if def.ComplexType != nil {
+		el.complex = s.complexType(def.ComplexType)
+	}
+	return el
+}
+
+func (s *wsdlService) complexType(def *xsdComplexTypeDef) *xsdType {
+	t := &xsdType{}
+	for _, group := range []*xsdGroupDef{def.Sequence, def.All} {
+		if group != nil {
+			for _, el := range group.Elements {
+				t.elements = append(t.elements, s.element(el))
+			}
+		}
+	}
+	if def.Choice != nil {
+		// Any one of the choices may be present.
+		for _, el := range def.Choice.Elements {
+			choice := s.element(el)
+			choice.min = 0
+			t.elements = append(t.elements, choice)
+		}
+	}
+	return t
+}
+
+// typeOf returns the declaration holding the type of el: the global element
+// it references, or el itself.
+func (s *wsdlService) typeOf(el *xsdElement) *xsdElement {
+	if el.ref {
+		if global := s.elements[el.name]; global != nil {
+			return global
+		}
+	}
+	return el
+}
+
+// complexOf returns the complex type of el, or nil for a simple type.
+func (s *wsdlService) complexOf(el *xsdElement) *xsdType {
+	el = s.typeOf(el)
+	if el.complex != nil {
+		return el.complex
+	}
+	return s.types[el.typeName]
+}
+
+// operation identifies the operation of a request from its SOAPAction and
+// the local name of its Body element; it returns nil when neither matches.
+func (s *wsdlService) operation(action, element string) *wsdlOperation {
+	if op := s.byElement[element]; op != nil {
+		return op
+	}
+	if op := s.operations[element]; op != nil { // rpc style
+		return op
+	}
+	return s.byAction[action]
+}
+
+// validate checks node against the schema of its request element.
+func (s *wsdlService) validate(node *xmlNode) error {
+	el := s.elements[node.name]
+	if el == nil {
+		return fmt.Errorf("element %s is not declared in the WSDL", node.name)
+	}
+	return s.validateNode(node, el, node.name)
+}
+
+func (s *wsdlService) validateNode(node *xmlNode, el *xsdElement, path string) error {
+	ct := s.complexOf(el)
+	if ct == nil {
+		if len(node.children) > 0 {
+			return fmt.Errorf("%s: simple value expected, got child elements", path)
+		}
+		if _, err := xsdValue(s.typeOf(el).typeName, node.text); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		return nil
+	}
+	counts := make(map[string]int)
+	for _, child := range node.children {
+		counts[child.name]++
+	}
+	declared := make(map[string]*xsdElement, len(ct.elements))
+	for _, child := range ct.elements {
+		declared[child.name] = child
+		n := counts[child.name]
+		if n < child.min {
+			return fmt.Errorf("%s: missing required element %s", path, child.name)
+		}
+		if child.max >= 0 && n > child.max {
+			return fmt.Errorf("%s: element %s occurs %d times, at most %d allowed", path, child.name, n, child.max)
+		}
+	}
+	for _, child := range node.children {
+		def := declared[child.name]
+		if def == nil {
+			return fmt.Errorf("%s: unexpected element %s", path, child.name)
+		}
+		if err := s.validateNode(child, def, path+"."+child.name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// xsdValue converts text to the JSON value of the XSD built-in type typ.
+// Unknown types are kept as strings.
+func xsdValue(typ, text string) (interface{}, error) {
+	text = strings.TrimSpace(text)
+	switch typ {
+	case "int", "integer", "long", "short", "byte", "nonNegativeInteger", "positiveInteger", "unsignedInt", "unsignedLong", "unsignedShort":
+		n, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a valid %s", text, typ)
+		}
+		return float64(n), nil
+	case "decimal", "double", "float":
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a valid %s", text, typ)
+		}
+		return f, nil
+	case "boolean":
+		switch text {
+		case "true", "1":
+			return true, nil
+		case "false", "0":
+			return false, nil
+		}
+		return nil, fmt.Errorf("%q is not a valid boolean", text)
+	case "date":
+		if _, err := time.Parse("2006-01-02", text); err != nil {
+			return nil, fmt.Errorf("%q is not a valid date", text)
+		}
+	case "dateTime":
+		if _, err := time.Parse(time.RFC3339, text); err != nil {
+			if _, err := time.Parse("2006-01-02T15:04:05", text); err != nil {
+				return nil, fmt.Errorf("%q is not a valid dateTime", text)
+			}
+		}
+	}
+	return text, nil
+}
+
+// ── Generic XML tree ────────────────────────────────────────────────────────
+
+// xmlNode is an element of a SOAP Body, by local name.
+type xmlNode struct {
+	name     string
+	attrs    map[string]string
+	children []*xmlNode
+	text     string
+}
+
+// parseBodyElement returns the first element of the SOAP Body inner XML, or
+// nil when the Body is empty.
+func parseBodyElement(content []byte) (*xmlNode, error) {
+	dec := xml.NewDecoder(bytes.NewReader(content))
+	var stack []*xmlNode
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid SOAP body: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			node := &xmlNode{name: t.Name.Local}
+			for _, a := range t.Attr {
+				if a.Name.Space == "xmlns" || a.Name.Local == "xmlns" || a.Name.Space == "http://www.w3.org/2001/XMLSchema-instance" {
+					continue
+				}
+				if node.attrs == nil {
+					node.attrs = make(map[string]string)
+				}
+				node.attrs[a.Name.Local] = a.Value
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, node)
+			}
+			stack = append(stack, node)
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text += string(t)
+			}
+		case xml.EndElement:
+			node := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				return node, nil
+			}
+		}
+	}
+}
+
+// toJSON converts node to trigger data: child elements become keys (lists
+// when repeated or declared with maxOccurs > 1), leaves become strings, or
+// numbers and booleans when the schema types them, and attributes become
+// "@name" keys. el is the declaration of node, nil without a WSDL.
+func (s *wsdlService) toJSON(node *xmlNode, el *xsdElement) interface{} {
+	var ct *xsdType
+	if s != nil && el != nil {
+		ct = s.complexOf(el)
+	}
+	if len(node.children) == 0 && len(node.attrs) == 0 {
+		if el != nil && ct == nil {
+			if v, err := xsdValue(s.typeOf(el).typeName, node.text); err == nil {
+				return v
+			}
+		}
+		return strings.TrimSpace(node.text)
+	}
+	out := make(map[string]interface{}, len(node.children)+len(node.attrs))
+	for k, v := range node.attrs {
+		out["@"+k] = v
+	}
+	if len(node.children) == 0 {
+		out["#text"] = strings.TrimSpace(node.text)
+		return out
+	}
+	declared := make(map[string]*xsdElement)
+	if ct != nil {
+		for _, child := range ct.elements {
+			declared[child.name] = child
+		}
+	}
+	for _, child := range node.children {
+		def := declared[child.name]
+		v := s.toJSON(child, def)
+		existing, seen := out[child.name]
+		switch {
+		case seen:
+			if list, ok := existing.([]interface{}); ok {
+				out[child.name] = append(list, v)
+			} else {
+				out[child.name] = []interface{}{existing, v}
+			}
+		case def != nil && def.max != 1:
+			out[child.name] = []interface{}{v}
+		default:
+			out[child.name] = v
+		}
+	}
+	return out
+}
+
+// localName strips the namespace prefix of a QName.
+func localName(qname string) string {
+	if i := strings.LastIndexByte(qname, ':'); i >= 0 {
+		return qname[i+1:]
+	}
+	return qname
+}
@@ -0,0 +1,183 @@
+package triggers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"flowjs-works/engine/internal/models"
+)
+
+const testWSDL = `<?xml version="1.0"?>
+<definitions xmlns="http://schemas.xmlsoap.org/wsdl/" xmlns:soap="http://schemas.xmlsoap.org/wsdl/soap/"
+    xmlns:xsd="http://www.w3.org/2001/XMLSchema" xmlns:tns="urn:orders" targetNamespace="urn:orders">
+  <types>
+    <xsd:schema targetNamespace="urn:orders">
+      <xsd:complexType name="Line">
+        <xsd:sequence>
+          <xsd:element name="sku" type="xsd:string"/>
+          <xsd:element name="qty" type="xsd:int"/>
+        </xsd:sequence>
+      </xsd:complexType>
+      <xsd:element name="CreateOrder">
+        <xsd:complexType>
+          <xsd:sequence>
+            <xsd:element name="customer" type="xsd:string"/>
+            <xsd:element name="express" type="xsd:boolean" minOccurs="0"/>
+            <xsd:element name="line" type="tns:Line" maxOccurs="unbounded"/>
+          </xsd:sequence>
+        </xsd:complexType>
+      </xsd:element>
+      <xsd:element name="GetOrder">
+        <xsd:complexType>
+          <xsd:sequence><xsd:element name="id" type="xsd:long"/></xsd:sequence>
+        </xsd:complexType>
+      </xsd:element>
+    </xsd:schema>
+  </types>
+  <message name="CreateOrderRequest"><part name="body" element="tns:CreateOrder"/></message>
+  <message name="GetOrderRequest"><part name="body" element="tns:GetOrder"/></message>
+  <portType name="OrdersPort">
+    <operation name="Create"><input message="tns:CreateOrderRequest"/></operation>
+    <operation name="Get"><input message="tns:GetOrderRequest"/></operation>
+  </portType>
+  <binding name="OrdersBinding" type="tns:OrdersPort">
+    <operation name="Create"><soap:operation soapAction="urn:orders#Create"/></operation>
+    <operation name="Get"><soap:operation soapAction="urn:orders#Get"/></operation>
+  </binding>
+</definitions>`
+
+func soapEnvelope(body string) string {
+	return `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body>` + body + `</soap:Body></soap:Envelope>`
+}
+
+func TestParseWSDL(t *testing.T) {
+	svc, err := parseWSDL(testWSDL)
+	require.NoError(t, err)
+	assert.Equal(t, "Create", svc.operation("", "CreateOrder").name)
+	assert.Equal(t, "Get", svc.operation("urn:orders#Get", "Unknown").name)
+	assert.Nil(t, svc.operation("", "Unknown"))
+
+	_, err = parseWSDL(`<definitions/>`)
+	assert.ErrorContains(t, err, "no portType operations")
+}
+
+func TestWSDLService_ValidateAndConvert(t *testing.T) {
+	svc, err := parseWSDL(testWSDL)
+	require.NoError(t, err)
+
+	node, err := parseBodyElement([]byte(`<o:CreateOrder xmlns:o="urn:orders"><customer>ACME</customer><express>true</express>` +
+		`<line><sku>A-1</sku><qty>2</qty></line></o:CreateOrder>`))
+	require.NoError(t, err)
+	require.NoError(t, svc.validate(node))
+	assert.Equal(t, map[string]interface{}{
+		"customer": "ACME",
+		"express":  true,
+		"line":     []interface{}{map[string]interface{}{"sku": "A-1", "qty": float64(2)}},
+	}, svc.toJSON(node, svc.elements[node.name]))
+
+	tests := []struct {
+		body    string
+		wantErr string
+	}{
+		{`<CreateOrder><line><sku>A</sku><qty>1</qty></line></CreateOrder>`, "missing required element customer"},
+		{`<CreateOrder><customer>x</customer><line><sku>A</sku><qty>two</qty></line></CreateOrder>`, `CreateOrder.line.qty: "two" is not a valid int`},
+		{`<CreateOrder><customer>x</customer><line><sku>A</sku><qty>1</qty></line><note/></CreateOrder>`, "unexpected element note"},
+		{`<GetOrder><id>1</id><id>2</id></GetOrder>`, "occurs 2 times"},
+		{`<DeleteOrder/>`, "not declared in the WSDL"},
+	}
+	for _, tt := range tests {
+		node, err := parseBodyElement([]byte(tt.body))
+		require.NoError(t, err)
+		assert.ErrorContains(t, svc.validate(node), tt.wantErr, tt.body)
+	}
+}
+
+func TestToJSON_WithoutWSDL(t *testing.T) {
+	node, err := parseBodyElement([]byte(`<Ping id="7"><to>a</to><to>b</to><msg>hi</msg></Ping>`))
+	require.NoError(t, err)
+	var svc *wsdlService
+	assert.Equal(t, map[string]interface{}{
+		"@id": "7",
+		"to":  []interface{}{"a", "b"},
+		"msg": "hi",
+	}, svc.toJSON(node, nil))
+}
+
+// procExecutor records the process of every execution.
+type procExecutor struct {
+	procs []*models.Process
+	data  []map[string]interface{}
+}
+
+func (p *procExecutor) Execute(proc *models.Process, triggerData map[string]interface{}) (*models.ExecutionContext, error) {
+	p.procs = append(p.procs, proc)
+	p.data = append(p.data, triggerData)
+	return models.NewExecutionContext("exec-soap"), nil
+}
+
+func TestSOAPTrigger_RoutesAndValidatesOperations(t *testing.T) {
+	exec := &procExecutor{}
+	tr := newSOAPTrigger(exec)
+	proc := buildProcess("p_soap_ops", "soap", map[string]interface{}{
+		"path":       "/orders-ops",
+		"wsdl":       testWSDL,
+		"validate":   true,
+		"operations": map[string]interface{}{"Create": "create", "Get": "lookup"},
+	})
+	proc.Nodes = []models.Node{{ID: "create", Type: "log"}, {ID: "notify", Type: "log"}, {ID: "lookup", Type: "log"}}
+	proc.Transitions = []models.Transition{
+		{From: "create", To: "notify", Type: "success"},
+		{From: "lookup", To: "notify", Type: "success"},
+	}
+	require.NoError(t, tr.Start(context.Background(), proc))
+	defer func() { _ = tr.Stop() }()
+
+	post := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		GetSOAPRegistryHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/soap/orders-ops", strings.NewReader(soapEnvelope(body))))
+		return rec
+	}
+
+	rec := post(`<GetOrder><id>42</id></GetOrder>`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Len(t, exec.procs, 1)
+	var ids []string
+	for _, n := range exec.procs[0].Nodes {
+		ids = append(ids, n.ID)
+	}
+	assert.Equal(t, []string{"notify", "lookup"}, ids)
+	assert.Equal(t, "Get", exec.data[0]["operation"])
+	assert.Equal(t, map[string]interface{}{"id": float64(42)}, exec.data[0]["payload"])
+	assert.Contains(t, exec.data[0]["body"], "<GetOrder>")
+
+	rec = post(`<GetOrder><id>abc</id></GetOrder>`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "does not match the WSDL")
+	assert.Len(t, exec.procs, 1)
+}
+
+func TestSOAPTrigger_OperationConfigErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  map[string]interface{}
+		wantErr string
+	}{
+		{"validate without wsdl", map[string]interface{}{"path": "/x", "validate": true}, `"validate" requires a "wsdl"`},
+		{"unknown operation", map[string]interface{}{"path": "/x", "wsdl": testWSDL, "operations": map[string]interface{}{"Delete": "a"}}, "not in the WSDL"},
+		{"unknown node", map[string]interface{}{"path": "/x", "operations": map[string]interface{}{"Ping": "missing"}}, `unknown start node "missing"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proc := buildProcess("p_soap_cfg", "soap", tt.config)
+			proc.Nodes = []models.Node{{ID: "a", Type: "log"}}
+			err := newSOAPTrigger(&procExecutor{}).Start(context.Background(), proc)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package triggers
+
+import "time"
+
+// States of TriggerStatus.
+const (
+	// TriggerRunning is a trigger able to fire its process.
+	TriggerRunning = "running"
+	// TriggerReconnecting is a trigger that lost the connection it listens
+	// on and is reconnecting; it fires nothing meanwhile.
+	TriggerReconnecting = "reconnecting"
+)
+
+// TriggerStatus is the health of a deployed trigger.
+type TriggerStatus struct {
+	Type  string `json:"type"`
+	State string `json:"state"`
+	// Since is when the trigger entered State.
+	Since time.Time `json:"since"`
+	// Error is why a reconnecting trigger lost its connection, then why its
+	// last reconnection attempt failed.
+	Error string `json:"error,omitempty"`
+	// Reconnects counts the reconnections since the trigger was deployed.
+	Reconnects int `json:"reconnects,omitempty"`
+}
+
+// statusReporter is implemented by the triggers whose health can change
+// after they started, such as those holding a broker connection.
+type statusReporter interface {
+	Status() TriggerStatus
+}
+
+// status returns the status of d. The caller holds the manager's mutex.
+func (d *deployment) status() TriggerStatus {
+	if r, ok := d.handler.(statusReporter); ok {
+		s := r.Status()
+		s.Type = d.handler.Type()
+		return s
+	}
+	return TriggerStatus{Type: d.handler.Type(), State: TriggerRunning, Since: d.since}
+}
+
+// Status returns the status of the trigger of processID, or false when it is
+// not deployed.
+func (m *Manager) Status(processID string) (TriggerStatus, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	d, ok := m.running[processID]
+	if !ok {
+		return TriggerStatus{}, false
+	}
+	return d.status(), true
+}
+
+// Statuses returns the status of every deployed trigger, by process ID.
+func (m *Manager) Statuses() map[string]TriggerStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make(map[string]TriggerStatus, len(m.running))
+	for id, d := range m.running {
+		out[id] = d.status()
+	}
+	return out
+}
@@ -0,0 +1,167 @@
+// Package flowengine embeds the flowjs-works process executor in other Go
+// programs. It runs Process definitions (the same JSON DSL the Designer
+// saves) in-process, without the engine HTTP server, trigger manager or
+// config database, and lets the host register its own activities.
+//
+//	eng, err := flowengine.New(flowengine.Options{})
+//	if err != nil { ... }
+//	defer eng.Close()
+//	_ = eng.RegisterActivity(myActivity{})
+//	proc, err := flowengine.ParseProcess(dsl)
+//	execCtx, err := eng.Execute(proc, map[string]interface{}{"body": payload})
+//
+// The types below are aliases of the engine's own models, so values can be
+// passed to and from the engine without conversion. This package is the
+// supported API for embedding; everything under internal/ may change.
+package flowengine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"flowjs-works/engine/internal/activities"
+	"flowjs-works/engine/internal/engine"
+	"flowjs-works/engine/internal/models"
+	"flowjs-works/engine/internal/secrets"
+)
+
+// DSL types. See context/dsl-reference.md for the meaning of every field.
+type (
+	Process         = models.Process
+	Definition      = models.Definition
+	ProcessSettings = models.ProcessSettings
+	Trigger         = models.Trigger
+	Node            = models.Node
+	Transition      = models.Transition
+	RetryPolicy     = models.RetryPolicy
+	CircuitBreaker  = models.CircuitBreaker
+)
+
+// ExecutionContext is the state of one execution: the trigger data and the
+// output and status of every node that ran, addressable with JSONPath
+// ($.trigger..., $.nodes.<id>.output...) through its GetValue method.
+type ExecutionContext = models.ExecutionContext
+
+// NewExecutionContext creates an empty context, e.g. to unit-test an activity.
+func NewExecutionContext(executionID string) *ExecutionContext {
+	return models.NewExecutionContext(executionID)
+}
+
+// Activity implements a node type. Execute receives the node's resolved
+// input_mapping, its config and the execution context, and returns the node
+// output stored at $.nodes.<id>.output.
+type Activity = activities.Activity
+
+// SecretResolver resolves the secret_ref of a node into the values merged
+// into its config.
+type SecretResolver = secrets.SecretResolver
+
+// SecretResolverFunc adapts a function to SecretResolver.
+type SecretResolverFunc func(ctx context.Context, ref string) (map[string]interface{}, error)
+
+// Resolve calls f.
+func (f SecretResolverFunc) Resolve(ctx context.Context, ref string) (map[string]interface{}, error) {
+	return f(ctx, ref)
+}
+
+// Options configures an Engine. The zero value runs flows with the built-in
+// activities, no secrets and no audit log.
+type Options struct {
+	// NATSURL, when set, publishes node audit events to "audit.logs" for the
+	// audit-logger service. An unreachable server disables auditing with a
+	// warning instead of failing New.
+	NATSURL string
+	// Secrets resolves node secret_ref values. Nodes with a secret_ref get an
+	// empty secret when it is nil.
+	Secrets SecretResolver
+	// TestMode enables test-only node types such as mock_http, for
+	// self-contained integration tests of flows.
+	TestMode bool
+}
+
+// Engine executes processes in-process. It is safe for concurrent use;
+// activities may be registered while executions run.
+type Engine struct {
+	exec *engine.ProcessExecutor
+}
+
+// New creates an Engine with the built-in activities registered.
+func New(opts Options) (*Engine, error) {
+	exec, err := engine.NewProcessExecutor(opts.NATSURL)
+	if err != nil {
+		return nil, fmt.Errorf("flowengine: %w", err)
+	}
+	if opts.Secrets != nil {
+		exec.SetSecretResolver(opts.Secrets)
+	}
+	if opts.TestMode {
+		exec.EnableTestMode()
+	}
+	return &Engine{exec: exec}, nil
+}
+
+// Close flushes pending batcher nodes and closes the audit connection.
+func (e *Engine) Close() {
+	e.exec.Close()
+}
+
+// RegisterActivity makes a available as node type a.Name(). Registering a
+// built-in name (e.g. "http") replaces the built-in for this Engine.
+func (e *Engine) RegisterActivity(a Activity) error {
+	if a == nil || a.Name() == "" {
+		return errors.New("flowengine: activity must have a non-empty name")
+	}
+	e.exec.RegisterActivity(a)
+	return nil
+}
+
+// Activities returns the node types the Engine can run, sorted.
+func (e *Engine) Activities() []string {
+	return e.exec.Activities()
+}
+
+// Execute runs proc to completion with triggerData as $.trigger. The
+// returned context holds every node's output; it is returned alongside the
+// error when a node fails, so callers can inspect how far the flow got.
+func (e *Engine) Execute(proc *Process, triggerData map[string]interface{}) (*ExecutionContext, error) {
+	if proc == nil {
+		return nil, errors.New("flowengine: process is nil")
+	}
+	if triggerData == nil {
+		triggerData = map[string]interface{}{}
+	}
+	return e.exec.Execute(proc, triggerData)
+}
+
+// ExecuteJSON parses and validates dsl (see ParseProcess), then executes it.
+func (e *Engine) ExecuteJSON(dsl []byte, triggerData map[string]interface{}) (*ExecutionContext, error) {
+	proc, err := ParseProcess(dsl)
+	if err != nil {
+		return nil, err
+	}
+	return e.Execute(proc, triggerData)
+}
+
+// ExecuteFromNode runs proc starting at nodeID with nodeInput as that node's
+// already-resolved input, as the engine's replay-from endpoint does.
+func (e *Engine) ExecuteFromNode(proc *Process, nodeID string, nodeInput map[string]interface{}) (*ExecutionContext, error) {
+	if proc == nil {
+		return nil, errors.New("flowengine: process is nil")
+	}
+	return e.exec.ExecuteFromNode(proc, nodeID, nodeInput, "", "")
+}
+
+// ParseProcess decodes a JSON DSL document and checks its structure: ids,
+// node types present, transitions between known nodes.
+func ParseProcess(dsl []byte) (*Process, error) {
+	var proc Process
+	if err := json.Unmarshal(dsl, &proc); err != nil {
+		return nil, fmt.Errorf("flowengine: parse process: %w", err)
+	}
+	if err := proc.Validate(); err != nil {
+		return nil, fmt.Errorf("flowengine: invalid process: %w", err)
+	}
+	return &proc, nil
+}
@@ -0,0 +1,99 @@
+package flowengine
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// greetActivity is a custom node type registered by the host program.
+type greetActivity struct{}
+
+func (greetActivity) Name() string { return "greet" }
+
+func (greetActivity) Execute(input, config map[string]interface{}, _ *ExecutionContext) (map[string]interface{}, error) {
+	return map[string]interface{}{
+		"message": fmt.Sprintf("%s, %v", config["greeting"], input["name"]),
+		"token":   config["token"],
+	}, nil
+}
+
+type unnamedActivity struct{ greetActivity }
+
+func (unnamedActivity) Name() string { return "" }
+
+func newTestEngine(t *testing.T, opts Options) *Engine {
+	t.Helper()
+	eng, err := New(opts)
+	require.NoError(t, err)
+	t.Cleanup(eng.Close)
+	return eng
+}
+
+const greetDSL = `{
+	"definition": {"id": "embedded-greet", "version": "1.0.0", "name": "Greet"},
+	"trigger": {"id": "trg_01", "type": "manual"},
+	"nodes": [{
+		"id": "hello",
+		"type": "greet",
+		"input_mapping": {"name": "$.trigger.name"},
+		"config": {"greeting": "Hello"},
+		"secret_ref": "greeter"
+	}]
+}`
+
+func TestEngine_CustomActivity(t *testing.T) {
+	eng := newTestEngine(t, Options{
+		Secrets: SecretResolverFunc(func(_ context.Context, ref string) (map[string]interface{}, error) {
+			return map[string]interface{}{"token": "secret-of-" + ref}, nil
+		}),
+	})
+	require.NoError(t, eng.RegisterActivity(greetActivity{}))
+	assert.Contains(t, eng.Activities(), "greet")
+	assert.Contains(t, eng.Activities(), "http", "built-in activities stay registered")
+
+	execCtx, err := eng.ExecuteJSON([]byte(greetDSL), map[string]interface{}{"name": "Ada"})
+	require.NoError(t, err)
+	assert.Equal(t, "embedded-greet", execCtx.ProcessID)
+
+	msg, err := execCtx.GetValue("$.nodes.hello.output.message")
+	require.NoError(t, err)
+	assert.Equal(t, "Hello, Ada", msg)
+	token, err := execCtx.GetValue("$.nodes.hello.output.token")
+	require.NoError(t, err)
+	assert.Equal(t, "secret-of-greeter", token)
+}
+
+func TestEngine_UnknownActivityFails(t *testing.T) {
+	eng := newTestEngine(t, Options{})
+	_, err := eng.ExecuteJSON([]byte(greetDSL), nil)
+	assert.Error(t, err, "greet is not registered on this engine")
+}
+
+func TestEngine_RegisterActivityRequiresName(t *testing.T) {
+	eng := newTestEngine(t, Options{})
+	assert.Error(t, eng.RegisterActivity(nil))
+	assert.Error(t, eng.RegisterActivity(unnamedActivity{}))
+}
+
+func TestEngine_ExecuteNilProcess(t *testing.T) {
+	eng := newTestEngine(t, Options{})
+	_, err := eng.Execute(nil, nil)
+	assert.Error(t, err)
+}
+
+func TestParseProcess(t *testing.T) {
+	proc, err := ParseProcess([]byte(greetDSL))
+	require.NoError(t, err)
+	assert.Equal(t, "embedded-greet", proc.Definition.ID)
+	require.Len(t, proc.Nodes, 1)
+
+	_, err = ParseProcess([]byte(`{not json`))
+	assert.ErrorContains(t, err, "parse process")
+
+	_, err = ParseProcess([]byte(`{"definition": {"id": "x"}, "trigger": {"type": "manual"}, "nodes": [{"id": "a"}]}`))
+	assert.ErrorContains(t, err, "invalid process")
+}